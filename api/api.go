@@ -0,0 +1,146 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	_ "embed"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+	"github.com/oriser/bolt/debt"
+	"github.com/oriser/bolt/order"
+	"github.com/oriser/bolt/service"
+	"github.com/oriser/bolt/token"
+	"github.com/oriser/bolt/user"
+)
+
+//go:embed schema.graphql
+var schema string
+
+const (
+	GraphQLPath = "/graphql"
+	RESTPath    = "/api/"
+
+	maxPageSize = 100
+)
+
+type Config struct {
+	Enabled bool   `env:"API_ENABLED"`
+	Token   string `env:"API_TOKEN" json:"-"` // Deprecated: a single admin token, use issued tokens instead. Enables the API when set.
+}
+
+// Service is the part of the bot's service the API uses
+type Service interface {
+	ActiveOrders() []service.ActiveOrder
+	FormatRates(channel string, groupRate service.GroupRate, groupID, format string) (string, error)
+	IsTreasurer(transportID string) bool
+	SettleDebt(ctx context.Context, settledByTransportID, debtID string) (*debt.Debt, error)
+	ActivatePendingDebts(ctx context.Context, user *user.User) error
+	SetUserDeactivated(ctx context.Context, transportID string, deactivated bool) (*user.User, error)
+}
+
+// Viewer is the authenticated user of a request.
+// Admins, treasurers and tokens without a user can see all the debts, other users can only see their own debts.
+type Viewer struct {
+	UserID string // Slack user ID, empty when authenticated with a token without a user
+	Admin  bool
+	Scope  token.Scope // The scope of the token the request was authenticated with, empty when authenticated otherwise (e.g. the dashboard)
+}
+
+type viewerKey struct{}
+
+// WithViewer returns a context with the authenticated user of a request
+func WithViewer(ctx context.Context, viewer Viewer) context.Context {
+	return context.WithValue(ctx, viewerKey{}, viewer)
+}
+
+func viewerFromContext(ctx context.Context) Viewer {
+	viewer, _ := ctx.Value(viewerKey{}).(Viewer)
+	return viewer
+}
+
+type API struct {
+	cfg        Config
+	schema     *graphql.Schema
+	root       *rootResolver
+	tokenStore token.Store
+}
+
+// New returns the API. Issued tokens are supported when the order store implements token.Store.
+func New(cfg Config, orderStore order.Store, userStore user.Store, debtStore debt.Store, botService Service) (*API, error) {
+	tokenStore, _ := orderStore.(token.Store)
+	root := &rootResolver{
+		orderStore: orderStore,
+		userStore:  userStore,
+		debtStore:  debtStore,
+		tokenStore: tokenStore,
+		service:    botService,
+	}
+	parsedSchema, err := graphql.ParseSchema(schema, root, graphql.MaxDepth(10))
+	if err != nil {
+		return nil, fmt.Errorf("parse GraphQL schema: %w", err)
+	}
+
+	return &API{cfg: cfg, schema: parsedSchema, root: root, tokenStore: tokenStore}, nil
+}
+
+// Enabled returns whether the API is enabled, by API_ENABLED or by configuring API_TOKEN
+func (a *API) Enabled() bool {
+	return a.cfg.Enabled || a.cfg.Token != ""
+}
+
+// GraphQLHandler returns the GraphQL HTTP handler without authentication, for serving it behind another authentication
+// (the request context should have a viewer, see WithViewer).
+func (a *API) GraphQLHandler() http.Handler {
+	graphqlHandler := &relay.Handler{Schema: a.schema}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		graphqlHandler.ServeHTTP(w, r)
+	})
+}
+
+// Handler returns the GraphQL HTTP handler. Requests must be authenticated with `Authorization: Bearer <token>`, with an issued token
+// or API_TOKEN.
+func (a *API) Handler() http.Handler {
+	return a.withAuthentication(a.GraphQLHandler())
+}
+
+// RESTHandler returns the REST HTTP handler, served under RESTPath and authenticated like Handler
+func (a *API) RESTHandler() http.Handler {
+	return a.withAuthentication(a.restHandler())
+}
+
+func (a *API) withAuthentication(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		viewer, ok := a.authenticate(r.Context(), strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		if !a.Enabled() || !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte("Unauthorized"))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithViewer(r.Context(), viewer)))
+	})
+}
+
+func (a *API) authenticate(ctx context.Context, secret string) (Viewer, bool) {
+	if secret == "" {
+		return Viewer{}, false
+	}
+	if a.cfg.Token != "" && subtle.ConstantTimeCompare([]byte(secret), []byte(a.cfg.Token)) == 1 {
+		return Viewer{Admin: true, Scope: token.ScopeAdmin}, true
+	}
+	if a.tokenStore == nil {
+		return Viewer{}, false
+	}
+	t, err := token.Authenticate(ctx, a.tokenStore, secret)
+	if err != nil {
+		return Viewer{}, false
+	}
+	return Viewer{UserID: t.UserID, Admin: t.Scope == token.ScopeAdmin, Scope: t.Scope}, true
+}
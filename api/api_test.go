@@ -0,0 +1,496 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oriser/bolt/debt"
+	"github.com/oriser/bolt/order"
+	"github.com/oriser/bolt/service"
+	"github.com/oriser/bolt/token"
+	"github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStore struct {
+	orders    []*order.Order
+	users     []*user.User
+	debts     []*debt.Debt
+	activated []string
+	tokens    []*token.Token
+}
+
+func paginate(length int, limit, offset uint64) (int, int) {
+	start, end := int(offset), length
+	if start > length {
+		start = length
+	}
+	if limit > 0 && start+int(limit) < end {
+		end = start + int(limit)
+	}
+	return start, end
+}
+
+func (f *fakeStore) SaveOrder(_ context.Context, o *order.Order) error {
+	f.orders = append(f.orders, o)
+	return nil
+}
+
+func (f *fakeStore) ListOrders(_ context.Context, filter order.ListFilter) ([]*order.Order, error) {
+	filtered := make([]*order.Order, 0)
+	for _, o := range f.orders {
+		if (filter.Receiver != "" && o.Receiver != filter.Receiver) || (filter.OriginalID != "" && o.OriginalID != filter.OriginalID) {
+			continue
+		}
+		filtered = append(filtered, o)
+	}
+	start, end := paginate(len(filtered), filter.Limit, filter.Offset)
+	return filtered[start:end], nil
+}
+
+func (f *fakeStore) AddUser(_ context.Context, u *user.User) error {
+	f.users = append(f.users, u)
+	return nil
+}
+
+func (f *fakeStore) GetUser(_ context.Context, id string) (*user.User, error) {
+	for _, u := range f.users {
+		if u.ID == id {
+			return u, nil
+		}
+	}
+	return nil, fmt.Errorf("user not found")
+}
+
+func (f *fakeStore) ListUsers(_ context.Context, filter user.ListFilter) ([]*user.User, error) {
+	if filter.TransportID == "" {
+		return f.users, nil
+	}
+	users := make([]*user.User, 0)
+	for _, u := range f.users {
+		if u.TransportID == filter.TransportID {
+			users = append(users, u)
+		}
+	}
+	return users, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fakeStore) AddDebt(_ context.Context, d *debt.Debt) error {
+	f.debts = append(f.debts, d)
+	return nil
+}
+
+func (f *fakeStore) RemoveDebtInOrderID(context.Context, string, string) error {
+	return nil
+}
+
+func (f *fakeStore) ListDebtsForOrderID(ctx context.Context, orderID string) ([]*debt.Debt, error) {
+	return f.ListDebts(ctx, debt.ListFilter{OrderIDs: []string{orderID}})
+}
+
+func (f *fakeStore) ListDebts(_ context.Context, filter debt.ListFilter) ([]*debt.Debt, error) {
+	filtered := make([]*debt.Debt, 0)
+	for _, d := range f.debts {
+		if len(filter.OrderIDs) > 0 && d.OrderID != filter.OrderIDs[0] {
+			continue
+		}
+		if len(filter.UserIDs) > 0 && !contains(filter.UserIDs, d.BorrowerID) && !contains(filter.UserIDs, d.LenderID) {
+			continue
+		}
+		filtered = append(filtered, d)
+	}
+	start, end := paginate(len(filtered), filter.Limit, filter.Offset)
+	return filtered[start:end], nil
+}
+
+func (f *fakeStore) ActiveOrders() []service.ActiveOrder {
+	return []service.ActiveOrder{
+		{ID: "D", Channel: "C1", VenueName: "Burger", State: service.DeliveryStatePickup},
+		{ID: "E", Channel: "C2", VenueName: "Falafel", Rates: &service.GroupRate{Rates: []service.Rate{{WoltName: "Loki", Amount: 30}}}},
+	}
+}
+
+func (f *fakeStore) FormatRates(_ string, groupRate service.GroupRate, groupID, format string) (string, error) {
+	if format != service.RatesFormatText {
+		return "", fmt.Errorf("unknown rates format %q", format)
+	}
+	return fmt.Sprintf("Rates for %s: %s %.2f", groupID, groupRate.Rates[0].WoltName, groupRate.Rates[0].Amount), nil
+}
+
+func (f *fakeStore) IsTreasurer(transportID string) bool {
+	return transportID == "U-treasurer"
+}
+
+func (f *fakeStore) SettleDebt(_ context.Context, _, debtID string) (*debt.Debt, error) {
+	for i, d := range f.debts {
+		if d.ID == debtID {
+			f.debts = append(f.debts[:i], f.debts[i+1:]...)
+			return d, nil
+		}
+	}
+	return nil, fmt.Errorf("debt %q not found", debtID)
+}
+
+func (f *fakeStore) ActivatePendingDebts(_ context.Context, u *user.User) error {
+	f.activated = append(f.activated, u.FullName)
+	return nil
+}
+
+func (f *fakeStore) SetUserDeactivated(_ context.Context, transportID string, deactivated bool) (*user.User, error) {
+	for _, u := range f.users {
+		if u.TransportID == transportID {
+			u.DeactivatedAt = nil
+			if deactivated {
+				now := time.Now()
+				u.DeactivatedAt = &now
+			}
+			return u, nil
+		}
+	}
+	return nil, fmt.Errorf("no user with transport ID %s", transportID)
+}
+
+func (f *fakeStore) AddToken(_ context.Context, t *token.Token) error {
+	f.tokens = append(f.tokens, t)
+	return nil
+}
+
+func (f *fakeStore) GetToken(_ context.Context, id string) (*token.Token, error) {
+	for _, t := range f.tokens {
+		if t.ID == id {
+			return t, nil
+		}
+	}
+	return nil, &token.ErrNotFound{ID: id}
+}
+
+func (f *fakeStore) GetTokenByHash(_ context.Context, hash string) (*token.Token, error) {
+	for _, t := range f.tokens {
+		if t.Hash == hash {
+			return t, nil
+		}
+	}
+	return nil, &token.ErrNotFound{}
+}
+
+func (f *fakeStore) ListTokens(context.Context) ([]*token.Token, error) {
+	return f.tokens, nil
+}
+
+func (f *fakeStore) RevokeToken(_ context.Context, id string, revokedAt time.Time) error {
+	for _, t := range f.tokens {
+		if t.ID == id {
+			t.RevokedAt = &revokedAt
+		}
+	}
+	return nil
+}
+
+func newTestStore() *fakeStore {
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return &fakeStore{
+		orders: []*order.Order{
+			{ID: "1", OriginalID: "A", CreatedAt: createdAt, Receiver: "C1", VenueName: "Pizza", Status: order.StatusDone, Tags: []string{"team"},
+				Participants: []order.Participant{{Name: "Loki", ID: "U1", Amount: 40}, {Name: "Thor", ID: "U2", Amount: 60, AgeRestrictedAmount: 25}}},
+			{ID: "2", OriginalID: "B", CreatedAt: createdAt, Receiver: "C1", VenueName: "Sushi", Status: order.StatusDone, Surge: true,
+				Participants: []order.Participant{{Name: "Loki", ID: "U1", Amount: 50}}},
+			{ID: "3", OriginalID: "C", CreatedAt: createdAt, Receiver: "C2", VenueName: "Pizza", Status: order.StatusCanceled,
+				ProofURL: "https://files.example.com/C.jpg"},
+		},
+		users: []*user.User{
+			{ID: "U1", FullName: "Loki", PaymentPreferences: []user.PaymentMethod{user.PaymentMethodBit}},
+			{ID: "U2", FullName: "Thor"},
+		},
+		debts: []*debt.Debt{
+			{ID: "D1", BorrowerID: "U1", LenderID: "U2", OrderID: "A", Amount: 40, CreatedAt: createdAt},
+			{ID: "D2", BorrowerID: "U1", LenderID: "U-deleted", OrderID: "C", Amount: 5, CreatedAt: createdAt},
+		},
+	}
+}
+
+func query(t *testing.T, handler http.Handler, token, q string) (int, map[string]interface{}) {
+	t.Helper()
+	body, err := json.Marshal(map[string]string{"query": q})
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, GraphQLPath, strings.NewReader(string(body)))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		return rec.Code, nil
+	}
+
+	res := make(map[string]interface{})
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	require.Nil(t, res["errors"])
+	return rec.Code, res["data"].(map[string]interface{})
+}
+
+func TestAPI(t *testing.T) {
+	t.Parallel()
+
+	store := newTestStore()
+	api, err := New(Config{Token: "secret"}, store, store, store, store)
+	require.NoError(t, err)
+	handler := api.Handler()
+
+	code, _ := query(t, handler, "wrong", "{ users { id } }")
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	_, data := query(t, handler, "secret", `{
+		orders(filter: {receiver: "C1"}, first: 1) {
+			nodes { id status totalAmount tags surge participants { name amount ageRestrictedAmount } debts { id borrower { fullName paymentPreferences } } }
+			pageInfo { hasNextPage nextOffset }
+		}
+	}`)
+	assert.JSONEq(t, `{
+		"nodes": [{"id": "1", "status": "DONE", "totalAmount": 100, "tags": ["team"], "surge": false,
+			"participants": [{"name": "Loki", "amount": 40, "ageRestrictedAmount": 0}, {"name": "Thor", "amount": 60, "ageRestrictedAmount": 25}],
+			"debts": [{"id": "D1", "borrower": {"fullName": "Loki", "paymentPreferences": ["Bit"]}}]}],
+		"pageInfo": {"hasNextPage": true, "nextOffset": 1}
+	}`, toJSON(t, data["orders"]))
+
+	_, data = query(t, handler, "secret", `{ debts(offset: 1) { nodes { id lender { id } order { originalId proofUrl } } pageInfo { hasNextPage } } }`)
+	assert.JSONEq(t, `{"nodes": [{"id": "D2", "lender": null, "order": {"originalId": "C", "proofUrl": "https://files.example.com/C.jpg"}}],
+		"pageInfo": {"hasNextPage": false}}`, toJSON(t, data["debts"]))
+
+	_, data = query(t, handler, "secret", `{
+		all: stats { ordersCount totalAmount openDebtsCount openDebtsAmount surgeOrdersCount topVenues(first: 1) { name ordersCount totalAmount } }
+		channel: stats(receiver: "C1") { ordersCount averageAmount openDebtsAmount }
+	}`)
+	assert.JSONEq(t, `{"ordersCount": 3, "totalAmount": 150, "openDebtsCount": 2, "openDebtsAmount": 45, "surgeOrdersCount": 1,
+		"topVenues": [{"name": "Pizza", "ordersCount": 2, "totalAmount": 100}]}`, toJSON(t, data["all"]))
+	assert.JSONEq(t, `{"ordersCount": 2, "averageAmount": 75, "openDebtsAmount": 40}`, toJSON(t, data["channel"]))
+}
+
+func TestAPIActiveOrdersAndUsers(t *testing.T) {
+	t.Parallel()
+
+	store := newTestStore()
+	api, err := New(Config{}, store, store, store, store)
+	require.NoError(t, err)
+	assert.False(t, api.Enabled())
+	code, _ := query(t, api.Handler(), "", "{ users { id } }")
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	handler := api.GraphQLHandler()
+	_, data := query(t, handler, "", `{ activeOrders { id venueName deliveryState totalAmount participants { name } ratesMessage } stats { spendingByMonth { month ordersCount totalAmount } } }`)
+	assert.JSONEq(t, `[
+		{"id": "D", "venueName": "Burger", "deliveryState": "pickup", "totalAmount": null, "participants": [], "ratesMessage": null},
+		{"id": "E", "venueName": "Falafel", "deliveryState": "unknown", "totalAmount": 30, "participants": [{"name": "Loki"}],
+			"ratesMessage": "Rates for E: Loki 30.00"}
+	]`, toJSON(t, data["activeOrders"]))
+	assert.JSONEq(t, `{"spendingByMonth": [{"month": "2024-05", "ordersCount": 3, "totalAmount": 150}]}`, toJSON(t, data["stats"]))
+
+	// Non admins can't add users
+	body := strings.NewReader(`{"query": "mutation { addUser(fullName: \"Odin\", transportId: \"U3\") { id } }"}`)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, GraphQLPath, body))
+	assert.Contains(t, rec.Body.String(), "only admins can add users")
+	assert.Len(t, store.users, 2)
+
+	body = strings.NewReader(`{"query": "mutation { addUser(fullName: \"Odin\", transportId: \"U3\") { fullName transportId } }"}`)
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, GraphQLPath, body)
+	handler.ServeHTTP(rec, req.WithContext(WithViewer(req.Context(), Viewer{UserID: "U1", Admin: true})))
+	assert.JSONEq(t, `{"data": {"addUser": {"fullName": "Odin", "transportId": "U3"}}}`, rec.Body.String())
+	assert.Len(t, store.users, 3)
+	assert.Equal(t, []string{"Odin"}, store.activated)
+
+	deactivate := `mutation { setUserDeactivated(transportId: "U3", deactivated: true) { fullName deactivated } }`
+	assert.Contains(t, queryAs(t, handler, Viewer{UserID: "U2"}, deactivate), "only admins can deactivate users")
+	assert.JSONEq(t, `{"data": {"setUserDeactivated": {"fullName": "Odin", "deactivated": true}}}`,
+		queryAs(t, handler, Viewer{UserID: "U1", Admin: true}, deactivate))
+	assert.True(t, store.users[2].Deactivated())
+}
+
+func queryAs(t *testing.T, handler http.Handler, viewer Viewer, q string) string {
+	t.Helper()
+	body, err := json.Marshal(map[string]string{"query": q})
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, GraphQLPath, strings.NewReader(string(body)))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req.WithContext(WithViewer(req.Context(), viewer)))
+	return rec.Body.String()
+}
+
+func TestAPIDebtsVisibility(t *testing.T) {
+	t.Parallel()
+
+	store := newTestStore()
+	// A custom user of the same Slack user
+	store.users = append(store.users, &user.User{ID: "custom-id", FullName: "Custom Thor", TransportID: "U2"})
+	store.debts = append(store.debts, &debt.Debt{ID: "D3", BorrowerID: "custom-id", LenderID: "U1", Amount: 7})
+	api, err := New(Config{}, store, store, store, store)
+	require.NoError(t, err)
+	handler := api.GraphQLHandler()
+
+	debtsQuery := `{ viewer { treasurer } debts { nodes { id } } }`
+	assert.JSONEq(t, `{"data": {"viewer": {"treasurer": false}, "debts": {"nodes": [{"id": "D1"}, {"id": "D3"}]}}}`, queryAs(t, handler, Viewer{UserID: "U2"}, debtsQuery))
+	assert.JSONEq(t, `{"data": {"viewer": {"treasurer": false}, "debts": {"nodes": []}}}`, queryAs(t, handler, Viewer{UserID: "U-other"}, debtsQuery))
+	assert.JSONEq(t, `{"data": {"viewer": {"treasurer": true}, "debts": {"nodes": [{"id": "D1"}, {"id": "D2"}, {"id": "D3"}]}}}`, queryAs(t, handler, Viewer{UserID: "U-treasurer"}, debtsQuery))
+
+	settleQuery := `mutation { settleDebt(id: "D2") { id amount } }`
+	assert.Contains(t, queryAs(t, handler, Viewer{UserID: "U1", Admin: true}, settleQuery), "only treasurers can settle debts")
+	assert.JSONEq(t, `{"data": {"settleDebt": {"id": "D2", "amount": 5}}}`, queryAs(t, handler, Viewer{UserID: "U-treasurer"}, settleQuery))
+	assert.Len(t, store.debts, 2)
+}
+
+func rawQuery(t *testing.T, handler http.Handler, secret, q string) (int, string) {
+	t.Helper()
+	body, err := json.Marshal(map[string]string{"query": q})
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, GraphQLPath, strings.NewReader(string(body)))
+	req.Header.Set("Authorization", "Bearer "+secret)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec.Code, rec.Body.String()
+}
+
+func TestAPITokens(t *testing.T) {
+	t.Parallel()
+
+	store := newTestStore()
+	api, err := New(Config{Enabled: true}, store, store, store, store)
+	require.NoError(t, err)
+	handler := api.Handler()
+
+	issue := func(viewer Viewer, name, scope, userID string) (string, string) {
+		res := make(map[string]interface{})
+		require.NoError(t, json.Unmarshal([]byte(queryAs(t, api.GraphQLHandler(), viewer,
+			fmt.Sprintf(`mutation { issueApiToken(name: %q, scope: %s, userId: %q) { secret token { id } } }`, name, scope, userID))), &res))
+		require.Nil(t, res["errors"])
+		issued := res["data"].(map[string]interface{})["issueApiToken"].(map[string]interface{})
+		return issued["token"].(map[string]interface{})["id"].(string), issued["secret"].(string)
+	}
+
+	assert.Contains(t, queryAs(t, api.GraphQLHandler(), Viewer{UserID: "U1"}, `mutation { issueApiToken(name: "ci", scope: ADMIN) { secret } }`),
+		"only admins can manage API tokens")
+	admin := Viewer{UserID: "U1", Admin: true}
+	_, readOnlySecret := issue(admin, "reports", "READ_ONLY", "")
+	_, writeSecret := issue(admin, "bookkeeping", "DEBTS_WRITE", "U-treasurer")
+	adminID, adminSecret := issue(admin, "ops", "ADMIN", "")
+
+	code, _ := rawQuery(t, handler, "bolt_wrong", "{ users { id } }")
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	_, data := query(t, handler, readOnlySecret, `{ viewer { scope } debts { nodes { id } } }`)
+	assert.JSONEq(t, `{"viewer": {"scope": "read-only"}, "debts": {"nodes": [{"id": "D1"}, {"id": "D2"}]}}`, toJSON(t, data))
+	_, body := rawQuery(t, handler, readOnlySecret, `mutation { settleDebt(id: "D1") { id } }`)
+	assert.Contains(t, body, "the token's scope doesn't allow settling debts")
+	_, body = rawQuery(t, handler, writeSecret, `mutation { addUser(fullName: "Odin", transportId: "U3") { id } }`)
+	assert.Contains(t, body, "only admins can add users")
+	_, body = rawQuery(t, handler, writeSecret, `{ apiTokens { id } }`)
+	assert.Contains(t, body, "only admins can manage API tokens")
+	_, data = query(t, handler, writeSecret, `mutation { settleDebt(id: "D1") { id } }`)
+	assert.JSONEq(t, `{"settleDebt": {"id": "D1"}}`, toJSON(t, data))
+
+	_, data = query(t, handler, adminSecret, `{ apiTokens { name scope userId revokedAt } }`)
+	assert.JSONEq(t, `[
+		{"name": "reports", "scope": "READ_ONLY", "userId": "", "revokedAt": null},
+		{"name": "bookkeeping", "scope": "DEBTS_WRITE", "userId": "U-treasurer", "revokedAt": null},
+		{"name": "ops", "scope": "ADMIN", "userId": "", "revokedAt": null}
+	]`, toJSON(t, data["apiTokens"]))
+
+	_, data = query(t, handler, adminSecret, fmt.Sprintf(`mutation { rotateApiToken(id: %q) { secret token { name scope } } }`, adminID))
+	rotated := data["rotateApiToken"].(map[string]interface{})
+	assert.JSONEq(t, `{"name": "ops", "scope": "ADMIN"}`, toJSON(t, rotated["token"]))
+	code, _ = rawQuery(t, handler, adminSecret, "{ users { id } }")
+	assert.Equal(t, http.StatusUnauthorized, code, "rotated tokens are revoked")
+
+	rotatedSecret := rotated["secret"].(string)
+	_, data = query(t, handler, rotatedSecret, fmt.Sprintf(`mutation { revokeApiToken(id: %q) { name } }`, store.tokens[0].ID))
+	assert.JSONEq(t, `{"revokeApiToken": {"name": "reports"}}`, toJSON(t, data))
+	code, _ = rawQuery(t, handler, readOnlySecret, "{ users { id } }")
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
+
+func toJSON(t *testing.T, v interface{}) string {
+	t.Helper()
+	raw, err := json.Marshal(v)
+	require.NoError(t, err)
+	return string(raw)
+}
+
+func restRequest(t *testing.T, handler http.Handler, secret, method, target string) (int, string) {
+	t.Helper()
+	req := httptest.NewRequest(method, target, nil)
+	req.Header.Set("Authorization", "Bearer "+secret)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec.Code, rec.Body.String()
+}
+
+func TestRESTAPI(t *testing.T) {
+	t.Parallel()
+
+	store := newTestStore()
+	api, err := New(Config{Enabled: true}, store, store, store, store)
+	require.NoError(t, err)
+	handler := api.RESTHandler()
+	ctx := context.Background()
+	_, readOnlySecret, err := token.Issue(ctx, store, "reports", token.ScopeReadOnly, "")
+	require.NoError(t, err)
+	_, lokiSecret, err := token.Issue(ctx, store, "loki", token.ScopeReadOnly, "U1")
+	require.NoError(t, err)
+	_, writeSecret, err := token.Issue(ctx, store, "bookkeeping", token.ScopeDebtsWrite, "U-treasurer")
+	require.NoError(t, err)
+
+	code, _ := restRequest(t, handler, "bolt_wrong", http.MethodGet, "/api/orders")
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	code, body := restRequest(t, handler, readOnlySecret, http.MethodGet, "/api/orders?receiver=C1&first=1")
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `{"orders": [{"id": "1", "originalId": "A", "createdAt": "2024-05-01T12:00:00Z", "receiver": "C1", "venueName": "Pizza",
+		"host": "", "status": "DONE", "deliveryRate": 0, "totalAmount": 100, "currency": "", "tags": ["team"], "companyPaid": false,
+		"externalRef": "", "proofUrl": "", "participants": [{"name": "Loki", "userId": "U1", "amount": 40, "ageRestrictedAmount": 0},
+		{"name": "Thor", "userId": "U2", "amount": 60, "ageRestrictedAmount": 25}]}], "hasNextPage": true, "nextOffset": 1}`, body)
+	code, body = restRequest(t, handler, readOnlySecret, http.MethodGet, "/api/orders?sort=host")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.JSONEq(t, `{"error": "unknown sort host"}`, body)
+	code, _ = restRequest(t, handler, readOnlySecret, http.MethodGet, "/api/orders?sort=total_amount&ascending=true")
+	assert.Equal(t, http.StatusOK, code)
+	code, body = restRequest(t, handler, readOnlySecret, http.MethodGet, "/api/orders?first=many")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.JSONEq(t, `{"error": "first must be a number but got \"many\""}`, body)
+
+	code, body = restRequest(t, handler, readOnlySecret, http.MethodGet, "/api/debts?user=U-deleted")
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `{"debts": [{"id": "D2", "orderId": "C", "amount": 5, "currency": "", "createdAt": "2024-05-01T12:00:00Z",
+		"borrowerId": "U1", "lenderId": "U-deleted"}], "hasNextPage": false, "nextOffset": 20}`, body)
+	code, _ = restRequest(t, handler, lokiSecret, http.MethodGet, "/api/debts?user=U2")
+	assert.Equal(t, http.StatusForbidden, code, "users can only see their own debts")
+	code, body = restRequest(t, handler, lokiSecret, http.MethodGet, "/api/debts?user=U1&first=1")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `"hasNextPage":true`)
+
+	code, _ = restRequest(t, handler, readOnlySecret, http.MethodPost, "/api/debts/D1/settle")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = restRequest(t, handler, writeSecret, http.MethodGet, "/api/debts/D1/settle")
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+	code, body = restRequest(t, handler, writeSecret, http.MethodPost, "/api/debts/D1/settle")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `"id":"D1"`)
+	assert.Len(t, store.debts, 1)
+	code, _ = restRequest(t, handler, writeSecret, http.MethodPost, "/api/debts/D1/settle")
+	assert.Equal(t, http.StatusBadRequest, code, "the debt was already settled")
+
+	code, _ = restRequest(t, handler, readOnlySecret, http.MethodGet, "/api/users")
+	assert.Equal(t, http.StatusNotFound, code)
+}
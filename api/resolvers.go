@@ -0,0 +1,723 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/oriser/bolt/debt"
+	"github.com/oriser/bolt/order"
+	"github.com/oriser/bolt/service"
+	"github.com/oriser/bolt/token"
+	"github.com/oriser/bolt/user"
+)
+
+type rootResolver struct {
+	orderStore order.Store
+	userStore  user.Store
+	debtStore  debt.Store
+	tokenStore token.Store
+	service    Service
+}
+
+// forbiddenError is the error of a viewer who isn't allowed to do what they asked for
+type forbiddenError string
+
+func (e forbiddenError) Error() string { return string(e) }
+
+// allows returns whether the viewer's token scope allows the required scope. Viewers not authenticated with a token are limited
+// by their roles only.
+func allows(viewer Viewer, required token.Scope) bool {
+	return viewer.Scope == "" || viewer.Scope.Allows(required)
+}
+
+func (r *rootResolver) isTreasurer(viewer Viewer) bool {
+	return viewer.UserID != "" && r.service != nil && r.service.IsTreasurer(viewer.UserID)
+}
+
+// canSeeAllDebts returns whether the viewer can see debts across all channels
+func (r *rootResolver) canSeeAllDebts(viewer Viewer) bool {
+	return viewer.Admin || r.isTreasurer(viewer) || (viewer.Scope != "" && viewer.UserID == "")
+}
+
+// viewerUserIDs returns the user IDs of the viewer, as it may be stored under its Slack user ID or as a custom user
+func (r *rootResolver) viewerUserIDs(ctx context.Context, viewer Viewer) ([]string, error) {
+	return r.userIDs(ctx, viewer.UserID)
+}
+
+// userIDs returns the user IDs of the user with the given user ID or Slack user ID
+func (r *rootResolver) userIDs(ctx context.Context, id string) ([]string, error) {
+	ids := []string{id}
+	users, err := r.userStore.ListUsers(ctx, user.ListFilter{TransportID: id})
+	if err != nil {
+		return nil, fmt.Errorf("list users of %s: %w", id, err)
+	}
+	for _, u := range users {
+		if u.ID != id {
+			ids = append(ids, u.ID)
+		}
+	}
+	return ids, nil
+}
+
+type pageArgs struct {
+	First  int32
+	Offset int32
+}
+
+// limits returns the limit to query the store with (one extra item to know if there's a next page) and the offset
+func (p pageArgs) limits() (uint64, uint64, error) {
+	if p.First <= 0 || p.First > maxPageSize {
+		return 0, 0, fmt.Errorf("first must be between 1 and %d", maxPageSize)
+	}
+	if p.Offset < 0 {
+		return 0, 0, fmt.Errorf("offset can't be negative")
+	}
+	return uint64(p.First) + 1, uint64(p.Offset), nil
+}
+
+type pageInfoResolver struct {
+	hasNextPage bool
+	nextOffset  int32
+	totalCount  *int32
+}
+
+func newPageInfo(page pageArgs, fetched int) *pageInfoResolver {
+	hasNextPage := fetched > int(page.First)
+	return &pageInfoResolver{hasNextPage: hasNextPage, nextOffset: page.Offset + page.First}
+}
+
+func (p *pageInfoResolver) HasNextPage() bool  { return p.hasNextPage }
+func (p *pageInfoResolver) NextOffset() int32  { return p.nextOffset }
+func (p *pageInfoResolver) TotalCount() *int32 { return p.totalCount }
+
+// setTotalCount sets the total count of the pages, logging instead of failing the page if counting failed
+func (p *pageInfoResolver) setTotalCount(count int, err error) {
+	if err != nil {
+		log.Println("Error counting the items of the pages:", err)
+		return
+	}
+	total := int32(count)
+	p.totalCount = &total
+}
+
+// orderSortFields are the order sort fields by their GraphQL names
+var orderSortFields = map[string]order.SortField{
+	"CREATED_AT":   order.SortByCreatedAt,
+	"TOTAL_AMOUNT": order.SortByTotalAmount,
+	"VENUE_NAME":   order.SortByVenueName,
+}
+
+// debtSortFields are the debt sort fields by their GraphQL names
+var debtSortFields = map[string]debt.SortField{
+	"CREATED_AT": debt.SortByCreatedAt,
+	"AMOUNT":     debt.SortByAmount,
+}
+
+func boolValue(b *bool) bool {
+	return b != nil && *b
+}
+
+type orderFilterInput struct {
+	Receiver    *string
+	Text        *string
+	VenueName   *string
+	Participant *string
+	Tag         *string
+	MinAmount   *float64
+	MaxAmount   *float64
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func floatValue(f *float64) float64 {
+	if f == nil {
+		return 0
+	}
+	return *f
+}
+
+func (f *orderFilterInput) toListFilter() order.ListFilter {
+	if f == nil {
+		return order.ListFilter{}
+	}
+	return order.ListFilter{
+		Receiver:    stringValue(f.Receiver),
+		Text:        stringValue(f.Text),
+		VenueName:   stringValue(f.VenueName),
+		Participant: stringValue(f.Participant),
+		Tag:         stringValue(f.Tag),
+		MinAmount:   floatValue(f.MinAmount),
+		MaxAmount:   floatValue(f.MaxAmount),
+	}
+}
+
+type ordersArgs struct {
+	Filter    *orderFilterInput
+	Sort      *string
+	Ascending *bool
+	pageArgs
+}
+
+type orderConnectionResolver struct {
+	nodes    []*orderResolver
+	pageInfo *pageInfoResolver
+}
+
+func (c *orderConnectionResolver) Nodes() []*orderResolver     { return c.nodes }
+func (c *orderConnectionResolver) PageInfo() *pageInfoResolver { return c.pageInfo }
+
+func (r *rootResolver) Orders(ctx context.Context, args ordersArgs) (*orderConnectionResolver, error) {
+	limit, offset, err := args.limits()
+	if err != nil {
+		return nil, err
+	}
+	filter := args.Filter.toListFilter()
+	filter.Limit, filter.Offset, filter.Ascending = limit, offset, boolValue(args.Ascending)
+	if args.Sort != nil {
+		var ok bool
+		if filter.SortBy, ok = orderSortFields[*args.Sort]; !ok {
+			return nil, fmt.Errorf("unknown sort %s", *args.Sort)
+		}
+	}
+
+	orders, err := r.orderStore.ListOrders(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("list orders: %w", err)
+	}
+
+	connection := &orderConnectionResolver{pageInfo: newPageInfo(args.pageArgs, len(orders))}
+	if counter, ok := r.orderStore.(order.CountStore); ok {
+		connection.pageInfo.setTotalCount(counter.CountOrders(ctx, filter))
+	}
+	if len(orders) > int(args.First) {
+		orders = orders[:args.First]
+	}
+	connection.nodes = make([]*orderResolver, len(orders))
+	for i, o := range orders {
+		connection.nodes[i] = &orderResolver{root: r, order: o}
+	}
+	return connection, nil
+}
+
+type usersArgs struct {
+	Name        *string
+	TransportID *string
+}
+
+func (r *rootResolver) Users(ctx context.Context, args usersArgs) ([]*userResolver, error) {
+	filter := user.ListFilter{TransportID: stringValue(args.TransportID)}
+	if args.Name != nil {
+		filter.Names = []string{*args.Name}
+	}
+
+	users, err := r.userStore.ListUsers(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("list users: %w", err)
+	}
+
+	resolvers := make([]*userResolver, len(users))
+	for i, u := range users {
+		resolvers[i] = &userResolver{user: u}
+	}
+	return resolvers, nil
+}
+
+type debtFilterInput struct {
+	BorrowerID *string
+	LenderID   *string
+	OrderID    *string
+	UserID     *string
+}
+
+type debtsArgs struct {
+	Filter    *debtFilterInput
+	Sort      *string
+	Ascending *bool
+	pageArgs
+}
+
+type debtConnectionResolver struct {
+	nodes    []*debtResolver
+	pageInfo *pageInfoResolver
+}
+
+func (c *debtConnectionResolver) Nodes() []*debtResolver      { return c.nodes }
+func (c *debtConnectionResolver) PageInfo() *pageInfoResolver { return c.pageInfo }
+
+func (r *rootResolver) Debts(ctx context.Context, args debtsArgs) (*debtConnectionResolver, error) {
+	limit, offset, err := args.limits()
+	if err != nil {
+		return nil, err
+	}
+	filter := debt.ListFilter{Limit: limit, Offset: offset, Ascending: boolValue(args.Ascending)}
+	if args.Sort != nil {
+		var ok bool
+		if filter.SortBy, ok = debtSortFields[*args.Sort]; !ok {
+			return nil, fmt.Errorf("unknown sort %s", *args.Sort)
+		}
+	}
+	if viewer := viewerFromContext(ctx); !r.canSeeAllDebts(viewer) {
+		if filter.UserIDs, err = r.viewerUserIDs(ctx, viewer); err != nil {
+			return nil, err
+		}
+	}
+	if args.Filter != nil {
+		filter.BorrowerID = stringValue(args.Filter.BorrowerID)
+		filter.LenderID = stringValue(args.Filter.LenderID)
+		if args.Filter.OrderID != nil {
+			filter.OrderIDs = []string{*args.Filter.OrderID}
+		}
+		if args.Filter.UserID != nil {
+			if filter.UserIDs, err = r.debtsUserIDs(ctx, filter.UserIDs, *args.Filter.UserID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	debts, err := r.debtStore.ListDebts(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("list debts: %w", err)
+	}
+
+	connection := &debtConnectionResolver{pageInfo: newPageInfo(args.pageArgs, len(debts))}
+	if counter, ok := r.debtStore.(debt.CountStore); ok {
+		connection.pageInfo.setTotalCount(counter.CountDebts(ctx, filter))
+	}
+	if len(debts) > int(args.First) {
+		debts = debts[:args.First]
+	}
+	connection.nodes = r.debtResolvers(debts)
+	return connection, nil
+}
+
+// debtsUserIDs returns the user IDs to filter the debts of the user by. Viewers who can only see their own debts (viewerIDs isn't
+// empty) can only filter by themselves.
+func (r *rootResolver) debtsUserIDs(ctx context.Context, viewerIDs []string, id string) ([]string, error) {
+	if len(viewerIDs) == 0 {
+		return r.userIDs(ctx, id)
+	}
+	for _, viewerID := range viewerIDs {
+		if viewerID == id {
+			return viewerIDs, nil
+		}
+	}
+	return nil, forbiddenError("you can only see your own debts")
+}
+
+func (r *rootResolver) debtResolvers(debts []*debt.Debt) []*debtResolver {
+	resolvers := make([]*debtResolver, len(debts))
+	for i, d := range debts {
+		resolvers[i] = &debtResolver{root: r, debt: d}
+	}
+	return resolvers
+}
+
+func (r *rootResolver) Stats(ctx context.Context, args struct{ Receiver *string }) (*statsResolver, error) {
+	orders, err := r.orderStore.ListOrders(ctx, order.ListFilter{Receiver: stringValue(args.Receiver)})
+	if err != nil {
+		return nil, fmt.Errorf("list orders: %w", err)
+	}
+
+	stats := &statsResolver{ordersCount: int32(len(orders))}
+	venues := make(map[string]*venueStatsResolver)
+	orderIDs := make([]string, len(orders))
+	for i, o := range orders {
+		orderIDs[i] = o.OriginalID
+		total := o.TotalAmount()
+		stats.totalAmount += total
+		venue, ok := venues[o.VenueName]
+		if !ok {
+			venue = &venueStatsResolver{name: o.VenueName}
+			venues[o.VenueName] = venue
+		}
+		venue.ordersCount++
+		venue.totalAmount += total
+		if o.Surge {
+			stats.surgeOrdersCount++
+		}
+	}
+	if len(orders) > 0 {
+		stats.averageAmount = stats.totalAmount / float64(len(orders))
+	}
+
+	stats.months = make([]*monthStatsResolver, 0)
+	monthsIndex := make(map[string]*monthStatsResolver)
+	for i := len(orders) - 1; i >= 0; i-- {
+		month := orders[i].CreatedAt.Format("2006-01")
+		monthStats, ok := monthsIndex[month]
+		if !ok {
+			monthStats = &monthStatsResolver{month: month}
+			monthsIndex[month] = monthStats
+			stats.months = append(stats.months, monthStats)
+		}
+		monthStats.ordersCount++
+		monthStats.totalAmount += orders[i].TotalAmount()
+	}
+
+	stats.venues = make([]*venueStatsResolver, 0, len(venues))
+	for _, venue := range venues {
+		stats.venues = append(stats.venues, venue)
+	}
+	sort.Slice(stats.venues, func(i, j int) bool {
+		if stats.venues[i].ordersCount != stats.venues[j].ordersCount {
+			return stats.venues[i].ordersCount > stats.venues[j].ordersCount
+		}
+		return stats.venues[i].name < stats.venues[j].name
+	})
+
+	if args.Receiver != nil && len(orderIDs) == 0 {
+		return stats, nil
+	}
+	debtsFilter := debt.ListFilter{}
+	if args.Receiver != nil {
+		debtsFilter.OrderIDs = orderIDs
+	}
+	debts, err := r.debtStore.ListDebts(ctx, debtsFilter)
+	if err != nil {
+		return nil, fmt.Errorf("list debts: %w", err)
+	}
+	stats.openDebtsCount = int32(len(debts))
+	for _, d := range debts {
+		stats.openDebtsAmount += d.Amount
+	}
+
+	return stats, nil
+}
+
+type viewerResolver struct {
+	viewer    Viewer
+	treasurer bool
+}
+
+func (v *viewerResolver) UserID() string  { return v.viewer.UserID }
+func (v *viewerResolver) Admin() bool     { return v.viewer.Admin }
+func (v *viewerResolver) Treasurer() bool { return v.treasurer }
+func (v *viewerResolver) Scope() string   { return string(v.viewer.Scope) }
+
+func (r *rootResolver) Viewer(ctx context.Context) *viewerResolver {
+	viewer := viewerFromContext(ctx)
+	return &viewerResolver{viewer: viewer, treasurer: r.isTreasurer(viewer)}
+}
+
+func (r *rootResolver) SettleDebt(ctx context.Context, args struct{ ID graphql.ID }) (*debtResolver, error) {
+	viewer := viewerFromContext(ctx)
+	if !allows(viewer, token.ScopeDebtsWrite) {
+		return nil, forbiddenError("the token's scope doesn't allow settling debts")
+	}
+	if !r.isTreasurer(viewer) {
+		return nil, forbiddenError("only treasurers can settle debts")
+	}
+
+	settled, err := r.service.SettleDebt(ctx, viewer.UserID, string(args.ID))
+	if err != nil {
+		return nil, fmt.Errorf("settle debt: %w", err)
+	}
+	return &debtResolver{root: r, debt: settled}, nil
+}
+
+type addUserArgs struct {
+	FullName    string
+	TransportID string
+	Email       *string
+}
+
+func (r *rootResolver) AddUser(ctx context.Context, args addUserArgs) (*userResolver, error) {
+	if viewer := viewerFromContext(ctx); !viewer.Admin || !allows(viewer, token.ScopeAdmin) {
+		return nil, fmt.Errorf("only admins can add users")
+	}
+	if args.FullName == "" || args.TransportID == "" {
+		return nil, fmt.Errorf("full name and transport ID are required")
+	}
+
+	u := &user.User{
+		FullName:    args.FullName,
+		Email:       stringValue(args.Email),
+		TransportID: args.TransportID,
+	}
+	if err := r.userStore.AddUser(ctx, u); err != nil {
+		return nil, fmt.Errorf("add user: %w", err)
+	}
+	if r.service != nil {
+		if err := r.service.ActivatePendingDebts(ctx, u); err != nil {
+			log.Printf("Error activating pending debts of %q: %v\n", u.FullName, err)
+		}
+	}
+	return &userResolver{user: u}, nil
+}
+
+type setUserDeactivatedArgs struct {
+	TransportID string
+	Deactivated bool
+}
+
+func (r *rootResolver) SetUserDeactivated(ctx context.Context, args setUserDeactivatedArgs) (*userResolver, error) {
+	if viewer := viewerFromContext(ctx); !viewer.Admin || !allows(viewer, token.ScopeAdmin) {
+		return nil, fmt.Errorf("only admins can deactivate users")
+	}
+	if r.service == nil {
+		return nil, fmt.Errorf("deactivating users is not supported")
+	}
+	u, err := r.service.SetUserDeactivated(ctx, args.TransportID, args.Deactivated)
+	if err != nil {
+		return nil, fmt.Errorf("set user deactivated: %w", err)
+	}
+	return &userResolver{user: u}, nil
+}
+
+func (r *rootResolver) ActiveOrders() []*activeOrderResolver {
+	if r.service == nil {
+		return []*activeOrderResolver{}
+	}
+	activeOrders := r.service.ActiveOrders()
+	resolvers := make([]*activeOrderResolver, len(activeOrders))
+	for i := range activeOrders {
+		resolvers[i] = &activeOrderResolver{root: r, order: activeOrders[i]}
+	}
+	return resolvers
+}
+
+type activeOrderResolver struct {
+	root  *rootResolver
+	order service.ActiveOrder
+}
+
+func (a *activeOrderResolver) ID() graphql.ID        { return graphql.ID(a.order.ID) }
+func (a *activeOrderResolver) Receiver() string      { return a.order.Channel }
+func (a *activeOrderResolver) MessageID() string     { return a.order.MessageID }
+func (a *activeOrderResolver) VenueName() string     { return a.order.VenueName }
+func (a *activeOrderResolver) JoinedAt() string      { return a.order.JoinedAt.Format(time.RFC3339) }
+func (a *activeOrderResolver) DeliveryState() string { return a.order.State.String() }
+
+func (a *activeOrderResolver) TotalAmount() *float64 {
+	if a.order.Rates == nil {
+		return nil
+	}
+	total := 0.0
+	for _, rate := range a.order.Rates.Rates {
+		total += rate.Amount
+	}
+	return &total
+}
+
+func (a *activeOrderResolver) Participants() []*participantResolver {
+	if a.order.Rates == nil {
+		return []*participantResolver{}
+	}
+	resolvers := make([]*participantResolver, len(a.order.Rates.Rates))
+	for i, rate := range a.order.Rates.Rates {
+		p := order.Participant{Name: rate.WoltName, Amount: rate.Amount, AgeRestrictedAmount: rate.AgeRestrictedAmount}
+		if rate.User != nil {
+			p.ID = rate.User.ID
+		}
+		resolvers[i] = &participantResolver{participant: p}
+	}
+	return resolvers
+}
+
+func (a *activeOrderResolver) RatesMessage(args struct{ Format string }) (*string, error) {
+	if a.order.Rates == nil {
+		return nil, nil
+	}
+	message, err := a.root.service.FormatRates(a.order.Channel, *a.order.Rates, a.order.ID, args.Format)
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+type orderResolver struct {
+	root  *rootResolver
+	order *order.Order
+}
+
+func (o *orderResolver) ID() graphql.ID      { return graphql.ID(o.order.ID) }
+func (o *orderResolver) OriginalID() string  { return o.order.OriginalID }
+func (o *orderResolver) CreatedAt() string   { return o.order.CreatedAt.Format(time.RFC3339) }
+func (o *orderResolver) Receiver() string    { return o.order.Receiver }
+func (o *orderResolver) MessageID() string   { return o.order.MessageID }
+func (o *orderResolver) VenueName() string   { return o.order.VenueName }
+func (o *orderResolver) VenueID() string     { return o.order.VenueID }
+func (o *orderResolver) VenueLink() string   { return o.order.VenueLink }
+func (o *orderResolver) VenueCity() string   { return o.order.VenueCity }
+func (o *orderResolver) Host() string        { return o.order.Host }
+func (o *orderResolver) HostID() string      { return o.order.HostID }
+func (o *orderResolver) DeliveryRate() int32 { return int32(o.order.DeliveryRate) }
+func (o *orderResolver) TotalAmount() float64 {
+	return o.order.TotalAmount()
+}
+
+func (o *orderResolver) Status() string {
+	switch o.order.Status {
+	case order.StatusCanceled:
+		return "CANCELED"
+	case order.StatusDone:
+		return "DONE"
+	case order.StatusStopped:
+		return "STOPPED"
+	default:
+		return "INVALID"
+	}
+}
+
+func (o *orderResolver) Surge() bool { return o.order.Surge }
+
+func (o *orderResolver) CompanyPaid() bool   { return o.order.CompanyPaid }
+func (o *orderResolver) Note() string        { return o.order.Note }
+func (o *orderResolver) ExternalRef() string { return o.order.ExternalRef }
+func (o *orderResolver) Currency() string    { return o.order.Currency }
+func (o *orderResolver) ProofURL() string    { return o.order.ProofURL }
+
+func (o *orderResolver) Tags() []string {
+	if o.order.Tags == nil {
+		return []string{}
+	}
+	return o.order.Tags
+}
+
+func (o *orderResolver) Participants() []*participantResolver {
+	resolvers := make([]*participantResolver, len(o.order.Participants))
+	for i := range o.order.Participants {
+		resolvers[i] = &participantResolver{participant: o.order.Participants[i]}
+	}
+	return resolvers
+}
+
+func (o *orderResolver) Debts(ctx context.Context) ([]*debtResolver, error) {
+	debts, err := o.root.debtStore.ListDebtsForOrderID(ctx, o.order.OriginalID)
+	if err != nil {
+		return nil, fmt.Errorf("list debts for order %s: %w", o.order.OriginalID, err)
+	}
+	return o.root.debtResolvers(debts), nil
+}
+
+type participantResolver struct {
+	participant order.Participant
+}
+
+func (p *participantResolver) Name() string    { return p.participant.Name }
+func (p *participantResolver) UserID() string  { return p.participant.ID }
+func (p *participantResolver) Amount() float64 { return p.participant.Amount }
+func (p *participantResolver) AgeRestrictedAmount() float64 {
+	return p.participant.AgeRestrictedAmount
+}
+
+type userResolver struct {
+	user *user.User
+}
+
+func (u *userResolver) ID() graphql.ID      { return graphql.ID(u.user.ID) }
+func (u *userResolver) FullName() string    { return u.user.FullName }
+func (u *userResolver) Email() string       { return u.user.Email }
+func (u *userResolver) Phone() string       { return u.user.Phone }
+func (u *userResolver) Timezone() string    { return u.user.Timezone }
+func (u *userResolver) TransportID() string { return u.user.TransportID }
+func (u *userResolver) Deactivated() bool   { return u.user.Deactivated() }
+
+func (u *userResolver) PaymentPreferences() []string {
+	preferences := make([]string, len(u.user.PaymentPreferences))
+	for i, p := range u.user.PaymentPreferences {
+		preferences[i] = p.String()
+	}
+	return preferences
+}
+
+type debtResolver struct {
+	root *rootResolver
+	debt *debt.Debt
+}
+
+func (d *debtResolver) ID() graphql.ID     { return graphql.ID(d.debt.ID) }
+func (d *debtResolver) OrderID() string    { return d.debt.OrderID }
+func (d *debtResolver) Amount() float64    { return d.debt.Amount }
+func (d *debtResolver) Currency() string   { return d.debt.Currency }
+func (d *debtResolver) CreatedAt() string  { return d.debt.CreatedAt.Format(time.RFC3339) }
+func (d *debtResolver) BorrowerID() string { return d.debt.BorrowerID }
+func (d *debtResolver) LenderID() string   { return d.debt.LenderID }
+
+func (d *debtResolver) Borrower(ctx context.Context) *userResolver {
+	return d.root.getUser(ctx, d.debt.BorrowerID)
+}
+
+func (d *debtResolver) Lender(ctx context.Context) *userResolver {
+	return d.root.getUser(ctx, d.debt.LenderID)
+}
+
+func (d *debtResolver) Order(ctx context.Context) (*orderResolver, error) {
+	orders, err := d.root.orderStore.ListOrders(ctx, order.ListFilter{OriginalID: d.debt.OrderID, Limit: 1})
+	if err != nil {
+		return nil, fmt.Errorf("list orders of %s: %w", d.debt.OrderID, err)
+	}
+	if len(orders) == 0 {
+		return nil, nil
+	}
+	return &orderResolver{root: d.root, order: orders[0]}, nil
+}
+
+// getUser returns nil for users which can't be found, as users may be deleted from the workspace
+func (r *rootResolver) getUser(ctx context.Context, id string) *userResolver {
+	u, err := r.userStore.GetUser(ctx, id)
+	if err != nil {
+		log.Printf("Error getting user %s: %v\n", id, err)
+		return nil
+	}
+	return &userResolver{user: u}
+}
+
+type statsResolver struct {
+	ordersCount      int32
+	totalAmount      float64
+	averageAmount    float64
+	openDebtsCount   int32
+	openDebtsAmount  float64
+	surgeOrdersCount int32
+	venues           []*venueStatsResolver
+	months           []*monthStatsResolver
+}
+
+func (s *statsResolver) SpendingByMonth() []*monthStatsResolver { return s.months }
+
+func (s *statsResolver) OrdersCount() int32       { return s.ordersCount }
+func (s *statsResolver) TotalAmount() float64     { return s.totalAmount }
+func (s *statsResolver) AverageAmount() float64   { return s.averageAmount }
+func (s *statsResolver) OpenDebtsCount() int32    { return s.openDebtsCount }
+func (s *statsResolver) OpenDebtsAmount() float64 { return s.openDebtsAmount }
+func (s *statsResolver) SurgeOrdersCount() int32  { return s.surgeOrdersCount }
+
+func (s *statsResolver) TopVenues(args struct{ First int32 }) []*venueStatsResolver {
+	if args.First < 0 {
+		return []*venueStatsResolver{}
+	}
+	if int(args.First) < len(s.venues) {
+		return s.venues[:args.First]
+	}
+	return s.venues
+}
+
+type venueStatsResolver struct {
+	name        string
+	ordersCount int32
+	totalAmount float64
+}
+
+func (v *venueStatsResolver) Name() string         { return v.name }
+func (v *venueStatsResolver) OrdersCount() int32   { return v.ordersCount }
+func (v *venueStatsResolver) TotalAmount() float64 { return v.totalAmount }
+
+type monthStatsResolver struct {
+	month       string
+	ordersCount int32
+	totalAmount float64
+}
+
+func (m *monthStatsResolver) Month() string        { return m.month }
+func (m *monthStatsResolver) OrdersCount() int32   { return m.ordersCount }
+func (m *monthStatsResolver) TotalAmount() float64 { return m.totalAmount }
@@ -0,0 +1,315 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+const defaultPageSize = 20
+
+type restParticipant struct {
+	Name                string  `json:"name"`
+	UserID              string  `json:"userId"`
+	Amount              float64 `json:"amount"`
+	AgeRestrictedAmount float64 `json:"ageRestrictedAmount"`
+}
+
+type restOrder struct {
+	ID           string            `json:"id"`
+	OriginalID   string            `json:"originalId"`
+	CreatedAt    string            `json:"createdAt"` // RFC 3339
+	Receiver     string            `json:"receiver"`
+	VenueName    string            `json:"venueName"`
+	Host         string            `json:"host"`
+	Status       string            `json:"status"`
+	DeliveryRate int               `json:"deliveryRate"`
+	TotalAmount  float64           `json:"totalAmount"`
+	Currency     string            `json:"currency"`
+	Tags         []string          `json:"tags"`
+	CompanyPaid  bool              `json:"companyPaid"`
+	ExternalRef  string            `json:"externalRef"`
+	ProofURL     string            `json:"proofUrl"`
+	Participants []restParticipant `json:"participants"`
+}
+
+type restDebt struct {
+	ID         string  `json:"id"`
+	OrderID    string  `json:"orderId"`
+	Amount     float64 `json:"amount"`
+	Currency   string  `json:"currency"`
+	CreatedAt  string  `json:"createdAt"` // RFC 3339
+	BorrowerID string  `json:"borrowerId"`
+	LenderID   string  `json:"lenderId"`
+}
+
+type restOrdersPage struct {
+	Orders      []restOrder `json:"orders"`
+	HasNextPage bool        `json:"hasNextPage"`
+	NextOffset  int32       `json:"nextOffset"`
+	TotalCount  *int32      `json:"totalCount,omitempty"` // Missing if the store can't count the orders
+}
+
+type restDebtsPage struct {
+	Debts       []restDebt `json:"debts"`
+	HasNextPage bool       `json:"hasNextPage"`
+	NextOffset  int32      `json:"nextOffset"`
+	TotalCount  *int32     `json:"totalCount,omitempty"` // Missing if the store can't count the debts
+}
+
+type restError struct {
+	Error string `json:"error"`
+}
+
+func newRESTOrder(resolver *orderResolver) restOrder {
+	o := resolver.order
+	ret := restOrder{
+		ID:           o.ID,
+		OriginalID:   o.OriginalID,
+		CreatedAt:    resolver.CreatedAt(),
+		Receiver:     o.Receiver,
+		VenueName:    o.VenueName,
+		Host:         o.Host,
+		Status:       resolver.Status(),
+		DeliveryRate: o.DeliveryRate,
+		TotalAmount:  o.TotalAmount(),
+		Currency:     o.Currency,
+		Tags:         resolver.Tags(),
+		CompanyPaid:  o.CompanyPaid,
+		ExternalRef:  o.ExternalRef,
+		ProofURL:     o.ProofURL,
+		Participants: make([]restParticipant, len(o.Participants)),
+	}
+	for i, p := range o.Participants {
+		ret.Participants[i] = restParticipant{Name: p.Name, UserID: p.ID, Amount: p.Amount, AgeRestrictedAmount: p.AgeRestrictedAmount}
+	}
+	return ret
+}
+
+func newRESTDebt(resolver *debtResolver) restDebt {
+	return restDebt{
+		ID:         resolver.debt.ID,
+		OrderID:    resolver.debt.OrderID,
+		Amount:     resolver.debt.Amount,
+		Currency:   resolver.debt.Currency,
+		CreatedAt:  resolver.CreatedAt(),
+		BorrowerID: resolver.debt.BorrowerID,
+		LenderID:   resolver.debt.LenderID,
+	}
+}
+
+// restHandler serves the REST API, which is a subset of the GraphQL API for integrations which don't speak GraphQL:
+//   - GET /api/orders, filtered by the receiver, text, venue, participant, tag, minAmount and maxAmount query parameters
+//   - GET /api/debts, filtered by the user, borrower, lender and order query parameters
+//   - POST /api/debts/<debt ID>/settle
+//
+// The lists are paginated by the first and offset query parameters, like the GraphQL API.
+func (a *API) restHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.Trim(strings.TrimPrefix(r.URL.Path, RESTPath), "/")
+		parts := strings.Split(path, "/")
+		switch {
+		case path == "orders":
+			if r.Method != http.MethodGet {
+				writeRESTError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+				return
+			}
+			a.listOrders(w, r)
+		case path == "debts":
+			if r.Method != http.MethodGet {
+				writeRESTError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+				return
+			}
+			a.listDebts(w, r)
+		case len(parts) == 3 && parts[0] == "debts" && parts[2] == "settle":
+			if r.Method != http.MethodPost {
+				writeRESTError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+				return
+			}
+			a.settleDebt(w, r, parts[1])
+		default:
+			writeRESTError(w, http.StatusNotFound, fmt.Errorf("unknown endpoint %s", r.URL.Path))
+		}
+	})
+}
+
+func (a *API) listOrders(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, err := parsePageArgs(query)
+	if err != nil {
+		writeRESTError(w, http.StatusBadRequest, err)
+		return
+	}
+	filter := &orderFilterInput{
+		Receiver:    optionalString(query, "receiver"),
+		Text:        optionalString(query, "text"),
+		VenueName:   optionalString(query, "venue"),
+		Participant: optionalString(query, "participant"),
+		Tag:         optionalString(query, "tag"),
+	}
+	if filter.MinAmount, err = optionalFloat(query, "minAmount"); err != nil {
+		writeRESTError(w, http.StatusBadRequest, err)
+		return
+	}
+	if filter.MaxAmount, err = optionalFloat(query, "maxAmount"); err != nil {
+		writeRESTError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	args := ordersArgs{Filter: filter, pageArgs: page, Sort: optionalSort(query)}
+	if args.Ascending, err = optionalBool(query, "ascending"); err != nil {
+		writeRESTError(w, http.StatusBadRequest, err)
+		return
+	}
+	if args.Sort != nil {
+		if _, ok := orderSortFields[*args.Sort]; !ok {
+			writeRESTError(w, http.StatusBadRequest, fmt.Errorf("unknown sort %s", query.Get("sort")))
+			return
+		}
+	}
+
+	connection, err := a.root.Orders(r.Context(), args)
+	if err != nil {
+		writeRESTError(w, restErrorStatus(err), err)
+		return
+	}
+	response := restOrdersPage{Orders: make([]restOrder, len(connection.nodes)), HasNextPage: connection.pageInfo.hasNextPage,
+		NextOffset: connection.pageInfo.nextOffset, TotalCount: connection.pageInfo.totalCount}
+	for i, node := range connection.nodes {
+		response.Orders[i] = newRESTOrder(node)
+	}
+	writeRESTResponse(w, response)
+}
+
+func (a *API) listDebts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, err := parsePageArgs(query)
+	if err != nil {
+		writeRESTError(w, http.StatusBadRequest, err)
+		return
+	}
+	filter := &debtFilterInput{
+		BorrowerID: optionalString(query, "borrower"),
+		LenderID:   optionalString(query, "lender"),
+		OrderID:    optionalString(query, "order"),
+		UserID:     optionalString(query, "user"),
+	}
+
+	args := debtsArgs{Filter: filter, pageArgs: page, Sort: optionalSort(query)}
+	if args.Ascending, err = optionalBool(query, "ascending"); err != nil {
+		writeRESTError(w, http.StatusBadRequest, err)
+		return
+	}
+	if args.Sort != nil {
+		if _, ok := debtSortFields[*args.Sort]; !ok {
+			writeRESTError(w, http.StatusBadRequest, fmt.Errorf("unknown sort %s", query.Get("sort")))
+			return
+		}
+	}
+
+	connection, err := a.root.Debts(r.Context(), args)
+	if err != nil {
+		writeRESTError(w, restErrorStatus(err), err)
+		return
+	}
+	response := restDebtsPage{Debts: make([]restDebt, len(connection.nodes)), HasNextPage: connection.pageInfo.hasNextPage,
+		NextOffset: connection.pageInfo.nextOffset, TotalCount: connection.pageInfo.totalCount}
+	for i, node := range connection.nodes {
+		response.Debts[i] = newRESTDebt(node)
+	}
+	writeRESTResponse(w, response)
+}
+
+func (a *API) settleDebt(w http.ResponseWriter, r *http.Request, debtID string) {
+	settled, err := a.root.SettleDebt(r.Context(), struct{ ID graphql.ID }{ID: graphql.ID(debtID)})
+	if err != nil {
+		writeRESTError(w, restErrorStatus(err), err)
+		return
+	}
+	writeRESTResponse(w, newRESTDebt(settled))
+}
+
+// restErrorStatus returns the status of the error of a resolver, which is a bad request unless the viewer isn't allowed to ask for it
+func restErrorStatus(err error) int {
+	var forbidden forbiddenError
+	if errors.As(err, &forbidden) {
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
+
+func parsePageArgs(query url.Values) (pageArgs, error) {
+	page := pageArgs{First: defaultPageSize}
+	for name, value := range map[string]*int32{"first": &page.First, "offset": &page.Offset} {
+		if query.Get(name) == "" {
+			continue
+		}
+		parsed, err := strconv.ParseInt(query.Get(name), 10, 32)
+		if err != nil {
+			return pageArgs{}, fmt.Errorf("%s must be a number but got %q", name, query.Get(name))
+		}
+		*value = int32(parsed)
+	}
+	return page, nil
+}
+
+func optionalString(query url.Values, name string) *string {
+	if query.Get(name) == "" {
+		return nil
+	}
+	value := query.Get(name)
+	return &value
+}
+
+// optionalSort returns the sort field of the query (e.g. total_amount), in the GraphQL enum's form
+func optionalSort(query url.Values) *string {
+	if query.Get("sort") == "" {
+		return nil
+	}
+	sort := strings.ToUpper(query.Get("sort"))
+	return &sort
+}
+
+func optionalBool(query url.Values, name string) (*bool, error) {
+	if query.Get(name) == "" {
+		return nil, nil
+	}
+	value, err := strconv.ParseBool(query.Get(name))
+	if err != nil {
+		return nil, fmt.Errorf("%s must be true or false but got %q", name, query.Get(name))
+	}
+	return &value, nil
+}
+
+func optionalFloat(query url.Values, name string) (*float64, error) {
+	if query.Get(name) == "" {
+		return nil, nil
+	}
+	value, err := strconv.ParseFloat(query.Get(name), 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a number but got %q", name, query.Get(name))
+	}
+	return &value, nil
+}
+
+func writeRESTResponse(w http.ResponseWriter, response interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Println("Error writing REST API response:", err)
+	}
+}
+
+func writeRESTError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(restError{Error: err.Error()}); err != nil {
+		log.Println("Error writing REST API error:", err)
+	}
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/oriser/bolt/token"
+)
+
+// GraphQL enum values are the scopes in upper case, with underscores
+func scopeFromEnum(enum string) (token.Scope, error) {
+	return token.ParseScope(strings.ReplaceAll(strings.ToLower(enum), "_", "-"))
+}
+
+func scopeToEnum(scope token.Scope) string {
+	return strings.ReplaceAll(strings.ToUpper(string(scope)), "-", "_")
+}
+
+type apiTokenResolver struct {
+	token *token.Token
+}
+
+func (t *apiTokenResolver) ID() graphql.ID    { return graphql.ID(t.token.ID) }
+func (t *apiTokenResolver) Name() string      { return t.token.Name }
+func (t *apiTokenResolver) Scope() string     { return scopeToEnum(t.token.Scope) }
+func (t *apiTokenResolver) UserID() string    { return t.token.UserID }
+func (t *apiTokenResolver) CreatedAt() string { return t.token.CreatedAt.Format(time.RFC3339) }
+
+func (t *apiTokenResolver) RevokedAt() *string {
+	if t.token.RevokedAt == nil {
+		return nil
+	}
+	revokedAt := t.token.RevokedAt.Format(time.RFC3339)
+	return &revokedAt
+}
+
+type issuedAPITokenResolver struct {
+	token  *token.Token
+	secret string
+}
+
+func (i *issuedAPITokenResolver) Token() *apiTokenResolver { return &apiTokenResolver{token: i.token} }
+func (i *issuedAPITokenResolver) Secret() string           { return i.secret }
+
+// adminTokenStore returns the token store if the viewer can manage the tokens
+func (r *rootResolver) adminTokenStore(ctx context.Context) (token.Store, error) {
+	if viewer := viewerFromContext(ctx); !viewer.Admin || !allows(viewer, token.ScopeAdmin) {
+		return nil, fmt.Errorf("only admins can manage API tokens")
+	}
+	if r.tokenStore == nil {
+		return nil, fmt.Errorf("API tokens are not supported")
+	}
+	return r.tokenStore, nil
+}
+
+func (r *rootResolver) APITokens(ctx context.Context) ([]*apiTokenResolver, error) {
+	tokenStore, err := r.adminTokenStore(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := tokenStore.ListTokens(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list tokens: %w", err)
+	}
+	resolvers := make([]*apiTokenResolver, len(tokens))
+	for i := range tokens {
+		resolvers[i] = &apiTokenResolver{token: tokens[i]}
+	}
+	return resolvers, nil
+}
+
+type issueAPITokenArgs struct {
+	Name   string
+	Scope  string
+	UserID *string
+}
+
+func (r *rootResolver) IssueAPIToken(ctx context.Context, args issueAPITokenArgs) (*issuedAPITokenResolver, error) {
+	tokenStore, err := r.adminTokenStore(ctx)
+	if err != nil {
+		return nil, err
+	}
+	scope, err := scopeFromEnum(args.Scope)
+	if err != nil {
+		return nil, err
+	}
+	issued, secret, err := token.Issue(ctx, tokenStore, args.Name, scope, stringValue(args.UserID))
+	if err != nil {
+		return nil, fmt.Errorf("issue token: %w", err)
+	}
+	return &issuedAPITokenResolver{token: issued, secret: secret}, nil
+}
+
+func (r *rootResolver) RotateAPIToken(ctx context.Context, args struct{ ID graphql.ID }) (*issuedAPITokenResolver, error) {
+	tokenStore, err := r.adminTokenStore(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rotated, secret, err := token.Rotate(ctx, tokenStore, string(args.ID))
+	if err != nil {
+		return nil, fmt.Errorf("rotate token: %w", err)
+	}
+	return &issuedAPITokenResolver{token: rotated, secret: secret}, nil
+}
+
+func (r *rootResolver) RevokeAPIToken(ctx context.Context, args struct{ ID graphql.ID }) (*apiTokenResolver, error) {
+	tokenStore, err := r.adminTokenStore(ctx)
+	if err != nil {
+		return nil, err
+	}
+	revoked, err := token.Revoke(ctx, tokenStore, string(args.ID))
+	if err != nil {
+		return nil, fmt.Errorf("revoke token: %w", err)
+	}
+	return &apiTokenResolver{token: revoked}, nil
+}
@@ -0,0 +1,225 @@
+// Package archive moves the old orders out of the store to a cheaper blob storage, as a gzipped JSON file per month, while the store
+// keeps listing them by reading through to the archive
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oriser/bolt/order"
+)
+
+type Config struct {
+	// The directory of the archive, usually a mounted blob storage bucket (e.g. with gcsfuse or s3fs), empty disables archiving
+	Dir         string        `env:"ARCHIVE_DIR"`
+	AfterMonths int           `env:"ARCHIVE_AFTER_MONTHS" envDefault:"12"` // How old orders are archived, in whole months
+	Interval    time.Duration `env:"ARCHIVE_INTERVAL" envDefault:"24h"`    // How often the old orders are archived
+}
+
+const (
+	monthFilePrefix = "orders-"
+	monthFileSuffix = ".json.gz"
+)
+
+// Archive keeps the orders of each month in a file of a bucket. The orders are read once and cached, and a month is read again only
+// when its file changes, like when orders of another process are archived.
+type Archive struct {
+	cfg    Config
+	bucket Bucket
+
+	lock   sync.Mutex
+	months map[string]*archivedMonth // By file name
+}
+
+type archivedMonth struct {
+	object Object // The file the orders were read from
+	orders []*order.Order
+}
+
+func New(cfg Config, bucket Bucket) (*Archive, error) {
+	if cfg.AfterMonths <= 0 {
+		return nil, fmt.Errorf("ARCHIVE_AFTER_MONTHS must be positive but got %d", cfg.AfterMonths)
+	}
+	if cfg.Interval <= 0 {
+		return nil, fmt.Errorf("ARCHIVE_INTERVAL must be positive but got %s", cfg.Interval)
+	}
+	return &Archive{cfg: cfg, bucket: bucket, months: make(map[string]*archivedMonth)}, nil
+}
+
+func monthFileName(month time.Time) string {
+	return monthFilePrefix + month.UTC().Format("2006-01") + monthFileSuffix
+}
+
+func encodeOrders(orders []*order.Order) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(writer).Encode(orders); err != nil {
+		return nil, fmt.Errorf("encode orders: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("compress orders: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeOrders(data []byte) ([]*order.Order, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decompress orders: %w", err)
+	}
+	defer reader.Close()
+	var orders []*order.Order
+	if err = json.NewDecoder(reader).Decode(&orders); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("decode orders: %w", err)
+	}
+	return orders, nil
+}
+
+// ArchivedOrders returns the orders of all the archived months
+func (a *Archive) ArchivedOrders(ctx context.Context) ([]*order.Order, error) {
+	objects, err := a.bucket.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list archived months: %w", err)
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	orders := make([]*order.Order, 0)
+	for _, object := range objects {
+		if !strings.HasPrefix(object.Name, monthFilePrefix) || !strings.HasSuffix(object.Name, monthFileSuffix) {
+			continue
+		}
+		month, ok := a.months[object.Name]
+		if !ok || month.object.Size != object.Size || !month.object.UpdatedAt.Equal(object.UpdatedAt) {
+			if month, err = a.readMonth(ctx, object); err != nil {
+				return nil, err
+			}
+			a.months[object.Name] = month
+		}
+		orders = append(orders, month.orders...)
+	}
+	return orders, nil
+}
+
+func (a *Archive) readMonth(ctx context.Context, object Object) (*archivedMonth, error) {
+	data, err := a.bucket.Get(ctx, object.Name)
+	if err != nil {
+		return nil, fmt.Errorf("get %s: %w", object.Name, err)
+	}
+	orders, err := decodeOrders(data)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", object.Name, err)
+	}
+	return &archivedMonth{object: object, orders: orders}, nil
+}
+
+// addToMonth adds the orders to the file of their month, along with the orders archived in it before. Orders which were already
+// archived (e.g. when removing them from the store failed) replace their previous copy.
+func (a *Archive) addToMonth(ctx context.Context, name string, orders []*order.Order) error {
+	byID := make(map[string]*order.Order)
+	data, err := a.bucket.Get(ctx, name)
+	switch {
+	case err == nil:
+		previous, err := decodeOrders(data)
+		if err != nil {
+			return fmt.Errorf("read %s: %w", name, err)
+		}
+		for _, o := range previous {
+			byID[o.ID] = o
+		}
+	case err != ErrNotFound:
+		return fmt.Errorf("get %s: %w", name, err)
+	}
+	for _, o := range orders {
+		byID[o.ID] = o
+	}
+
+	merged := make([]*order.Order, 0, len(byID))
+	for _, o := range byID {
+		merged = append(merged, o)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		if !merged[i].CreatedAt.Equal(merged[j].CreatedAt) {
+			return merged[i].CreatedAt.Before(merged[j].CreatedAt)
+		}
+		return merged[i].ID < merged[j].ID
+	})
+	if data, err = encodeOrders(merged); err != nil {
+		return err
+	}
+	if err = a.bucket.Put(ctx, name, data); err != nil {
+		return fmt.Errorf("put %s: %w", name, err)
+	}
+	return nil
+}
+
+// cutoff returns the start of the oldest month which isn't archived yet
+func (a *Archive) cutoff(now time.Time) time.Time {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -a.cfg.AfterMonths, 0)
+}
+
+// ArchiveOrders moves the orders of the months which are older than ARCHIVE_AFTER_MONTHS from the store to the archive, and returns
+// how many orders it moved. An order is removed from the store only once its month is written.
+func (a *Archive) ArchiveOrders(ctx context.Context, store order.ArchiveStore, now time.Time) (int, error) {
+	orders, err := store.ListOrdersToArchive(ctx, a.cutoff(now))
+	if err != nil {
+		return 0, fmt.Errorf("list orders to archive: %w", err)
+	}
+
+	byMonth := make(map[string][]*order.Order)
+	names := make([]string, 0)
+	for _, o := range orders {
+		name := monthFileName(o.CreatedAt)
+		if _, ok := byMonth[name]; !ok {
+			names = append(names, name)
+		}
+		byMonth[name] = append(byMonth[name], o)
+	}
+
+	archived := 0
+	for _, name := range names {
+		monthOrders := byMonth[name]
+		if err := a.addToMonth(ctx, name, monthOrders); err != nil {
+			return archived, err
+		}
+		ids := make([]string, len(monthOrders))
+		for i, o := range monthOrders {
+			ids[i] = o.ID
+		}
+		if err := store.RemoveOrders(ctx, ids); err != nil {
+			return archived, fmt.Errorf("remove archived orders: %w", err)
+		}
+		archived += len(monthOrders)
+	}
+	return archived, nil
+}
+
+// Run archives the old orders of the store every ARCHIVE_INTERVAL, until ctx is done
+func (a *Archive) Run(ctx context.Context, store order.ArchiveStore) {
+	ticker := time.NewTicker(a.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		archived, err := a.ArchiveOrders(ctx, store, time.Now())
+		if err != nil {
+			slog.ErrorContext(ctx, "Error archiving orders", "archived", archived, "error", err)
+		} else if archived > 0 {
+			slog.InfoContext(ctx, "Archived old orders", "archived", archived)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
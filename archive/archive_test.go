@@ -0,0 +1,103 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/oriser/bolt/order"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStore struct {
+	orders    []*order.Order
+	removeErr error
+}
+
+func (f *fakeStore) ListOrdersToArchive(_ context.Context, before time.Time) ([]*order.Order, error) {
+	orders := make([]*order.Order, 0)
+	for _, o := range f.orders {
+		if o.CreatedAt.Before(before) {
+			orders = append(orders, o)
+		}
+	}
+	return orders, nil
+}
+
+func (f *fakeStore) RemoveOrders(_ context.Context, ids []string) error {
+	if f.removeErr != nil {
+		return f.removeErr
+	}
+	removed := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		removed[id] = true
+	}
+	kept := make([]*order.Order, 0)
+	for _, o := range f.orders {
+		if !removed[o.ID] {
+			kept = append(kept, o)
+		}
+	}
+	f.orders = kept
+	return nil
+}
+
+func orderIDs(orders []*order.Order) []string {
+	ids := make([]string, len(orders))
+	for i, o := range orders {
+		ids[i] = o.ID
+	}
+	return ids
+}
+
+func TestArchiveOrders(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	store := &fakeStore{orders: []*order.Order{
+		{ID: "1", CreatedAt: time.Date(2023, 3, 2, 12, 0, 0, 0, time.UTC)},
+		{ID: "2", CreatedAt: time.Date(2023, 3, 20, 12, 0, 0, 0, time.UTC)},
+		{ID: "3", CreatedAt: time.Date(2023, 5, 31, 12, 0, 0, 0, time.UTC)},
+		{ID: "4", CreatedAt: time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)},
+	}}
+	bucket, err := NewDirBucket(t.TempDir())
+	require.NoError(t, err)
+	a, err := New(Config{AfterMonths: 12, Interval: time.Hour}, bucket)
+	require.NoError(t, err)
+
+	store.removeErr = errors.New("store is down")
+	_, err = a.ArchiveOrders(context.Background(), store, now)
+	assert.ErrorContains(t, err, "store is down")
+	store.removeErr = nil
+	archived, err := a.ArchiveOrders(context.Background(), store, now)
+	require.NoError(t, err)
+	assert.Equal(t, 3, archived, "the orders of June 2023 are kept for 12 whole months")
+	assert.Equal(t, []string{"4"}, orderIDs(store.orders))
+
+	objects, err := bucket.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, objects, 2)
+	assert.Equal(t, "orders-2023-03.json.gz", objects[0].Name)
+	assert.Equal(t, "orders-2023-05.json.gz", objects[1].Name)
+	orders, err := a.ArchivedOrders(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "2", "3"}, orderIDs(orders), "the orders archived before removing them failed are archived once")
+
+	// Another process archives more orders of the month
+	other, err := New(Config{AfterMonths: 1, Interval: time.Hour}, bucket)
+	require.NoError(t, err)
+	store.orders = append(store.orders, &order.Order{ID: "5", CreatedAt: time.Date(2023, 3, 25, 12, 0, 0, 0, time.UTC)})
+	archived, err = other.ArchiveOrders(context.Background(), store, now)
+	require.NoError(t, err)
+	assert.Equal(t, 2, archived)
+	orders, err = a.ArchivedOrders(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "2", "5", "3", "4"}, orderIDs(orders), "the months which changed are read again")
+
+	_, err = New(Config{Interval: time.Hour}, bucket)
+	assert.EqualError(t, err, "ARCHIVE_AFTER_MONTHS must be positive but got 0")
+	_, err = bucket.Get(context.Background(), "orders-1999-01.json.gz")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,93 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// ErrNotFound is returned when getting an object which isn't in the bucket
+var ErrNotFound = errors.New("object not found")
+
+// Object is a file of a bucket
+type Object struct {
+	Name      string
+	Size      int64
+	UpdatedAt time.Time
+}
+
+// Bucket is the blob storage of the archive
+type Bucket interface {
+	// Put writes the object, replacing it if it exists
+	Put(ctx context.Context, name string, data []byte) error
+	// Get reads the object, or returns ErrNotFound if it doesn't exist
+	Get(ctx context.Context, name string) ([]byte, error)
+	// List returns the objects of the bucket, sorted by name
+	List(ctx context.Context) ([]Object, error)
+}
+
+// DirBucket is a bucket in a directory, like one a blob storage bucket is mounted on
+type DirBucket struct {
+	dir string
+}
+
+func NewDirBucket(dir string) (*DirBucket, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create archive directory: %w", err)
+	}
+	return &DirBucket{dir: dir}, nil
+}
+
+// Put writes the object to a temporary file first, so readers never see a partly written object
+func (b *DirBucket) Put(_ context.Context, name string, data []byte) error {
+	tmp, err := os.CreateTemp(b.dir, "."+name+".*")
+	if err != nil {
+		return fmt.Errorf("create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write temporary file: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("close temporary file: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), filepath.Join(b.dir, name)); err != nil {
+		return fmt.Errorf("rename temporary file: %w", err)
+	}
+	return nil
+}
+
+func (b *DirBucket) Get(_ context.Context, name string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(b.dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+func (b *DirBucket) List(_ context.Context) ([]Object, error) {
+	entries, err := os.ReadDir(b.dir)
+	if err != nil {
+		return nil, fmt.Errorf("read archive directory: %w", err)
+	}
+	objects := make([]Object, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || entry.Name()[0] == '.' {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("stat %s: %w", entry.Name(), err)
+		}
+		objects = append(objects, Object{Name: entry.Name(), Size: info.Size(), UpdatedAt: info.ModTime()})
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Name < objects[j].Name
+	})
+	return objects, nil
+}
@@ -0,0 +1,183 @@
+// Package backup defines Bolt's store dump format, for disaster recovery and migrating between storage backends
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/oriser/bolt/debt"
+	"github.com/oriser/bolt/order"
+	"github.com/oriser/bolt/token"
+	"github.com/oriser/bolt/user"
+)
+
+// Version is the current version of the dump format. It's bumped on incompatible changes, and Read upgrades older dumps.
+const Version = 1
+
+// Dump is the whole content of a store. Transient data, like the messages queue, isn't included.
+type Dump struct {
+	Version      int                 `json:"version"`
+	CreatedAt    time.Time           `json:"created_at"`
+	Users        []*user.User        `json:"users"`
+	Orders       []*order.Order      `json:"orders"`
+	Debts        []*debt.Debt        `json:"debts"`
+	Payments     []*debt.Payment     `json:"payments"`
+	PendingDebts []*debt.PendingDebt `json:"pending_debts"`
+	// The snapshots of the published rates, which the rates are explained and recomputed from
+	RatesSnapshots []*order.RatesSnapshot `json:"rates_snapshots"`
+	// The notifications waiting for the users' daily digests
+	DigestNotifications []*debt.DigestNotification `json:"digest_notifications"`
+	Config              Config                     `json:"config"`
+}
+
+// Config is the configuration kept in the store (as opposed to the environment variables configuration)
+type Config struct {
+	BlacklistedVenues   []*order.BlacklistedVenue    `json:"blacklisted_venues"`
+	ChannelSettings     []*order.ChannelSetting      `json:"channel_settings"`
+	InsightsSubscribers []string                     `json:"insights_subscribers"` // Transport IDs
+	AbroadCurrencies    map[string]string            `json:"abroad_currencies"`    // By transport ID
+	ReminderOptOuts     []string                     `json:"reminder_opt_outs"`    // Transport IDs
+	DigestHours         map[string]int               `json:"digest_hours"`         // The hours of the daily digests, by transport ID
+	PaymentMethods      map[string][]string          `json:"payment_methods"`      // The names of the users' payment methods, by transport ID
+	APITokens           []*token.Token               `json:"api_tokens"`           // Only the hashes of the secrets
+	OrderRefSequence    int64                        `json:"order_ref_sequence"`   // The last value of the orders' sequence references
+	BankAccounts        map[string]*user.BankAccount `json:"bank_accounts"`        // By transport ID
+	DebtDMs             map[string]bool              `json:"debt_dms"`             // The users' choices of the debt DMs, by transport ID
+	PrivateAmounts      map[string]bool              `json:"private_amounts"`      // The users' choices of private amounts, by transport ID
+	VenueStats          []*order.VenueStats          `json:"venue_stats"`          // The learned delivery durations and the timeout overrides
+}
+
+// Store is implemented by stores which can be backed up and restored
+type Store interface {
+	// Export returns a consistent dump of the store
+	Export(ctx context.Context) (*Dump, error)
+	// Import adds all the records of the dump to the store at once. The store must be empty.
+	Import(ctx context.Context, dump *Dump) error
+}
+
+// Write writes the dump in the current version of the format
+func Write(w io.Writer, dump *Dump) error {
+	dump.Version = Version
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(dump); err != nil {
+		return fmt.Errorf("encode dump: %w", err)
+	}
+	return nil
+}
+
+// Read reads a dump written by Write, of the current or an older version of the format
+func Read(r io.Reader) (*Dump, error) {
+	dump := &Dump{}
+	if err := json.NewDecoder(r).Decode(dump); err != nil {
+		return nil, fmt.Errorf("decode dump: %w", err)
+	}
+	if dump.Version < 1 {
+		return nil, fmt.Errorf("missing dump version")
+	}
+	if dump.Version > Version {
+		return nil, fmt.Errorf("dump version %d is newer than the supported version %d, upgrade Bolt to restore it", dump.Version, Version)
+	}
+	return dump, nil
+}
+
+// Copy copies all the records of the source store to the destination store, which must be empty. The destination is verified to
+// have the same records as the source afterwards.
+func Copy(ctx context.Context, source, destination Store) (*Dump, error) {
+	dump, err := source.Export(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("export source store: %w", err)
+	}
+	if err = destination.Import(ctx, dump); err != nil {
+		return nil, fmt.Errorf("import to destination store: %w", err)
+	}
+
+	copied, err := destination.Export(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("export destination store for verification: %w", err)
+	}
+	if err = Verify(dump, copied); err != nil {
+		return nil, fmt.Errorf("verify destination store: %w", err)
+	}
+	return dump, nil
+}
+
+// Verify returns an error describing the differences between the records of the dumps, if there are any.
+// Stores can list the records in different orders, so the order of the records isn't compared.
+func Verify(expected, actual *Dump) error {
+	sections := []struct {
+		name             string
+		expected, actual interface{}
+	}{
+		{"users", expected.Users, actual.Users},
+		{"orders", expected.Orders, actual.Orders},
+		{"debts", expected.Debts, actual.Debts},
+		{"payments", expected.Payments, actual.Payments},
+		{"pending debts", expected.PendingDebts, actual.PendingDebts},
+		{"rates snapshots", expected.RatesSnapshots, actual.RatesSnapshots},
+		{"digest notifications", expected.DigestNotifications, actual.DigestNotifications},
+		{"blacklisted venues", expected.Config.BlacklistedVenues, actual.Config.BlacklistedVenues},
+		{"channel settings", expected.Config.ChannelSettings, actual.Config.ChannelSettings},
+		{"insights subscribers", expected.Config.InsightsSubscribers, actual.Config.InsightsSubscribers},
+		{"abroad currencies", []map[string]string{expected.Config.AbroadCurrencies}, []map[string]string{actual.Config.AbroadCurrencies}},
+		{"reminder opt outs", expected.Config.ReminderOptOuts, actual.Config.ReminderOptOuts},
+		{"digest hours", []map[string]int{expected.Config.DigestHours}, []map[string]int{actual.Config.DigestHours}},
+		{"payment methods", []map[string][]string{expected.Config.PaymentMethods}, []map[string][]string{actual.Config.PaymentMethods}},
+		{"API tokens", expected.Config.APITokens, actual.Config.APITokens},
+		{"order reference sequence", []int64{expected.Config.OrderRefSequence}, []int64{actual.Config.OrderRefSequence}},
+		{"bank accounts", []map[string]*user.BankAccount{expected.Config.BankAccounts}, []map[string]*user.BankAccount{actual.Config.BankAccounts}},
+		{"debt DMs", []map[string]bool{expected.Config.DebtDMs}, []map[string]bool{actual.Config.DebtDMs}},
+		{"private amounts", []map[string]bool{expected.Config.PrivateAmounts}, []map[string]bool{actual.Config.PrivateAmounts}},
+		{"venue stats", expected.Config.VenueStats, actual.Config.VenueStats},
+	}
+
+	differences := make([]string, 0)
+	for _, section := range sections {
+		expectedRecords, err := sortedRecords(section.expected)
+		if err != nil {
+			return fmt.Errorf("%s: %w", section.name, err)
+		}
+		actualRecords, err := sortedRecords(section.actual)
+		if err != nil {
+			return fmt.Errorf("%s: %w", section.name, err)
+		}
+		if len(expectedRecords) != len(actualRecords) {
+			differences = append(differences, fmt.Sprintf("%d %s instead of %d", len(actualRecords), section.name, len(expectedRecords)))
+			continue
+		}
+		for i := range expectedRecords {
+			if expectedRecords[i] != actualRecords[i] {
+				differences = append(differences, fmt.Sprintf("different %s", section.name))
+				break
+			}
+		}
+	}
+	if len(differences) > 0 {
+		return fmt.Errorf("%s", strings.Join(differences, ", "))
+	}
+	return nil
+}
+
+// sortedRecords returns the JSON of each record of the given slice, sorted. The records are compared by their JSON, as it's what
+// a dump keeps of them.
+func sortedRecords(records interface{}) ([]string, error) {
+	marshaled, err := json.Marshal(records)
+	if err != nil {
+		return nil, fmt.Errorf("marshal records: %w", err)
+	}
+	var raw []json.RawMessage
+	if err = json.Unmarshal(marshaled, &raw); err != nil {
+		return nil, fmt.Errorf("unmarshal records: %w", err)
+	}
+	ret := make([]string, len(raw))
+	for i, record := range raw {
+		ret[i] = string(record)
+	}
+	sort.Strings(ret)
+	return ret, nil
+}
@@ -0,0 +1,77 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oriser/bolt/debt"
+	"github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryStore struct {
+	dump *Dump
+}
+
+func (m *memoryStore) Export(context.Context) (*Dump, error) {
+	return m.dump, nil
+}
+
+func (m *memoryStore) Import(_ context.Context, dump *Dump) error {
+	m.dump = dump
+	return nil
+}
+
+func TestReadWrite(t *testing.T) {
+	t.Parallel()
+
+	dump := &Dump{CreatedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), Users: []*user.User{{ID: "U1", FullName: "Loki"}}}
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, dump))
+	read, err := Read(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, Version, read.Version)
+	assert.Equal(t, dump, read)
+
+	_, err = Read(bytes.NewBufferString(`{"version": 2}`))
+	assert.EqualError(t, err, "dump version 2 is newer than the supported version 1, upgrade Bolt to restore it")
+	_, err = Read(bytes.NewBufferString(`{"users": []}`))
+	assert.EqualError(t, err, "missing dump version")
+}
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+
+	expected := &Dump{
+		Users:  []*user.User{{ID: "U1", FullName: "Loki"}, {ID: "U2", FullName: "Thor"}},
+		Debts:  []*debt.Debt{{ID: "D1", Amount: 10}},
+		Config: Config{AbroadCurrencies: map[string]string{"S1": "USD"}},
+	}
+	reordered := &Dump{
+		Users:  []*user.User{{ID: "U2", FullName: "Thor"}, {ID: "U1", FullName: "Loki"}},
+		Debts:  []*debt.Debt{{ID: "D1", Amount: 10}},
+		Config: Config{AbroadCurrencies: map[string]string{"S1": "USD"}},
+	}
+	assert.NoError(t, Verify(expected, reordered), "the order of the records isn't compared")
+
+	different := &Dump{
+		Users:  []*user.User{{ID: "U1", FullName: "Loki"}},
+		Debts:  []*debt.Debt{{ID: "D1", Amount: 12}},
+		Config: Config{AbroadCurrencies: map[string]string{"S1": "EUR"}},
+	}
+	assert.EqualError(t, Verify(expected, different), "1 users instead of 2, different debts, different abroad currencies")
+}
+
+func TestCopy(t *testing.T) {
+	t.Parallel()
+
+	source := &memoryStore{dump: &Dump{Users: []*user.User{{ID: "U1", FullName: "Loki"}}}}
+	destination := &memoryStore{}
+	dump, err := Copy(context.Background(), source, destination)
+	require.NoError(t, err)
+	assert.Equal(t, source.dump, dump)
+	assert.Equal(t, source.dump, destination.dump)
+}
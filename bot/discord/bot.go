@@ -0,0 +1,352 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/oriser/bolt/bot/transport"
+	"github.com/oriser/bolt/service"
+)
+
+const reconnectInterval = 5 * time.Second
+
+// The gateway's opcodes
+const (
+	opDispatch       = 0
+	opHeartbeat      = 1
+	opIdentify       = 2
+	opReconnect      = 7
+	opInvalidSession = 9
+	opHello          = 10
+)
+
+// intents are the gateway events the bot gets: servers, their messages and reactions, and direct messages and their reactions.
+// Reading the messages' content requires enabling the privileged message content intent of the bot.
+const intents = 1<<0 | 1<<9 | 1<<10 | 1<<12 | 1<<13 | 1<<15
+
+var (
+	urlRe         = regexp.MustCompile(`(?:https?|wolt)://[^\s<>"]+`)
+	commandRe     = regexp.MustCompile(`^/([a-z_]+)(?:\s+(.*))?$`)
+	userMentionRe = regexp.MustCompile(`<@!?(\d+)>`)
+)
+
+type user struct {
+	ID         string `json:"id"`
+	Username   string `json:"username"`
+	GlobalName string `json:"global_name"`
+	Bot        bool   `json:"bot"`
+}
+
+type channel struct {
+	ID string `json:"id"`
+}
+
+type messageReference struct {
+	MessageID string `json:"message_id"`
+}
+
+type message struct {
+	ID                string            `json:"id"`
+	ChannelID         string            `json:"channel_id"`
+	GuildID           string            `json:"guild_id"` // Empty for direct messages
+	Author            user              `json:"author"`
+	Content           string            `json:"content"`
+	Mentions          []user            `json:"mentions"`
+	MessageReference  *messageReference `json:"message_reference"`
+	ReferencedMessage *message          `json:"referenced_message"`
+}
+
+type messageReaction struct {
+	UserID          string `json:"user_id"`
+	ChannelID       string `json:"channel_id"`
+	MessageID       string `json:"message_id"`
+	GuildID         string `json:"guild_id"`
+	MessageAuthorID string `json:"message_author_id"`
+	Emoji           struct {
+		ID   string `json:"id"` // Empty for Unicode emojis
+		Name string `json:"name"`
+	} `json:"emoji"`
+}
+
+type guildDelete struct {
+	ID          string `json:"id"`
+	Unavailable bool   `json:"unavailable"` // The server is down, rather than the bot was removed from it
+}
+
+type payload struct {
+	Op       int             `json:"op"`
+	Data     json.RawMessage `json:"d,omitempty"`
+	Sequence *int64          `json:"s,omitempty"`
+	Type     string          `json:"t,omitempty"`
+}
+
+type DiscordBot struct {
+	*Client
+	*transport.Bot
+}
+
+func (c *Client) ServiceBot(serviceHandler *service.Service) *DiscordBot {
+	return &DiscordBot{Client: c, Bot: transport.NewBot(serviceHandler, c.cfg.AdminUserIDs, c.cfg.MaxConcurrentEvents)}
+}
+
+// ListenAndServe listens to the gateway events of the bot, and serves the API and the dashboard (when enabled) on DISCORD_SERVER_PORT
+func (b *DiscordBot) ListenAndServe(ctx context.Context) error {
+	go b.listenGateway(ctx)
+
+	log.Println("Server listening on port", b.cfg.Port)
+	return http.ListenAndServe(fmt.Sprintf(":%d", b.cfg.Port), nil)
+}
+
+// listenGateway keeps a gateway session open, starting a new one whenever it's closed
+func (b *DiscordBot) listenGateway(ctx context.Context) {
+	for {
+		err := b.runSession(ctx)
+		if ctx.Err() != nil {
+			log.Println("Finishing listening to Discord's gateway due to context cancellation")
+			return
+		}
+		log.Println("Discord gateway session ended, reconnecting:", err)
+		select {
+		case <-time.After(reconnectInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// runSession connects to the gateway, identifies and handles the dispatched events until the connection is closed, or Discord asks
+// to reconnect
+func (b *DiscordBot) runSession(ctx context.Context) error {
+	var gateway struct {
+		URL string `json:"url"`
+	}
+	if err := b.call(ctx, http.MethodGet, "/gateway/bot", nil, &gateway); err != nil {
+		return fmt.Errorf("get gateway: %w", err)
+	}
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, gateway.URL+"/?v=10&encoding=json", nil)
+	if err != nil {
+		return fmt.Errorf("dial gateway: %w", err)
+	}
+	sessionCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-sessionCtx.Done()
+		conn.Close()
+	}()
+
+	var writeLock sync.Mutex
+	write := func(op int, data interface{}) error {
+		writeLock.Lock()
+		defer writeLock.Unlock()
+		return conn.WriteJSON(map[string]interface{}{"op": op, "d": data})
+	}
+
+	var hello payload
+	if err := conn.ReadJSON(&hello); err != nil {
+		return fmt.Errorf("read hello: %w", err)
+	}
+	if hello.Op != opHello {
+		return fmt.Errorf("expected hello, got op %d", hello.Op)
+	}
+	var helloData struct {
+		HeartbeatInterval int64 `json:"heartbeat_interval"`
+	}
+	if err := json.Unmarshal(hello.Data, &helloData); err != nil {
+		return fmt.Errorf("unmarshal hello: %w", err)
+	}
+	err = write(opIdentify, map[string]interface{}{
+		"token":      b.cfg.Token,
+		"intents":    intents,
+		"properties": map[string]string{"os": "linux", "browser": "bolt", "device": "bolt"},
+	})
+	if err != nil {
+		return fmt.Errorf("identify: %w", err)
+	}
+
+	var seqLock sync.Mutex
+	var seq *int64
+	heartbeat := func() error {
+		seqLock.Lock()
+		defer seqLock.Unlock()
+		return write(opHeartbeat, seq)
+	}
+	go func() {
+		ticker := time.NewTicker(time.Duration(helloData.HeartbeatInterval) * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := heartbeat(); err != nil {
+					log.Println("Error sending Discord heartbeat:", err)
+					cancel()
+					return
+				}
+			case <-sessionCtx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		var p payload
+		if err := conn.ReadJSON(&p); err != nil {
+			return fmt.Errorf("read event: %w", err)
+		}
+		switch p.Op {
+		case opDispatch:
+			seqLock.Lock()
+			seq = p.Sequence
+			seqLock.Unlock()
+			p := p
+			b.Go(func() {
+				if err := b.handleEvent(p.Type, p.Data); err != nil {
+					log.Printf("Error handling Discord %s event: %v\n", p.Type, err)
+				}
+			})
+		case opHeartbeat:
+			if err := heartbeat(); err != nil {
+				return fmt.Errorf("heartbeat: %w", err)
+			}
+		case opReconnect, opInvalidSession:
+			return fmt.Errorf("gateway asked to reconnect (op %d)", p.Op)
+		}
+	}
+}
+
+func (b *DiscordBot) handleEvent(eventType string, data json.RawMessage) error {
+	switch eventType {
+	case "MESSAGE_CREATE":
+		var m message
+		if err := json.Unmarshal(data, &m); err != nil {
+			return fmt.Errorf("unmarshal message: %w", err)
+		}
+		return b.handleMessage(&m)
+	case "MESSAGE_REACTION_ADD":
+		var r messageReaction
+		if err := json.Unmarshal(data, &r); err != nil {
+			return fmt.Errorf("unmarshal reaction: %w", err)
+		}
+		req, ok := b.reactionRequest(&r)
+		if !ok {
+			return nil
+		}
+		return b.HandleReaction(req, b.replier(req.Channel, ""))
+	case "GUILD_DELETE":
+		var g guildDelete
+		if err := json.Unmarshal(data, &g); err != nil {
+			return fmt.Errorf("unmarshal guild: %w", err)
+		}
+		if g.Unavailable {
+			return nil
+		}
+		// Stopping orders notifies the fallback admin channel, the same as when the bot is removed from a Slack channel
+		for _, channelID := range b.guildChannels(g.ID) {
+			b.Service.HandleMemberLeftChannel(channelID, b.self())
+		}
+	}
+	return nil
+}
+
+// replier returns the function replying in the channel, to the message if it's given
+func (b *DiscordBot) replier(channelID, messageID string) func(text string) error {
+	return func(text string) error {
+		_, err := b.SendMessage(channelID, text, messageID)
+		return err
+	}
+}
+
+func (b *DiscordBot) handleMessage(m *message) error {
+	if m.Author.Bot {
+		return nil
+	}
+	b.rememberGuild(m.ChannelID, m.GuildID)
+	if m.GuildID == "" {
+		b.rememberDMChannel(m.Author.ID, m.ChannelID)
+	}
+
+	threadID := ""
+	if m.MessageReference != nil {
+		threadID = b.messages.ThreadOf(m.ChannelID, m.MessageReference.MessageID)
+	}
+	// The service expects mentions in Slack's format, without Discord's nickname mentions (<@!ID>)
+	text := userMentionRe.ReplaceAllString(m.Content, "<@$1>")
+	b.messages.Add(m.ChannelID, m.ID, transport.Message{UserID: m.Author.ID, ThreadID: threadID, Text: text})
+
+	if match := commandRe.FindStringSubmatch(text); match != nil {
+		return b.handleCommand(m, match[1], strings.TrimSpace(match[2]))
+	}
+
+	receiver := b.receiverOf(m.ChannelID)
+	if found := transport.Links(urlRe.FindAllString(text, -1)); len(found) > 0 {
+		req := service.LinksRequest{Links: found, MessageID: m.ID, Channel: receiver, Text: text, UserID: m.Author.ID}
+		return b.HandleLinks(req, b.replier(m.ChannelID, ""))
+	}
+
+	selfID := b.self()
+	repliesToBot := m.ReferencedMessage != nil && m.ReferencedMessage.Author.ID == selfID
+	threadCommand := threadID != "" && service.IsThreadCommand(text)
+	if !threadCommand && (selfID == "" || (!strings.Contains(text, fmt.Sprintf("<@%s>", selfID)) && !repliesToBot)) {
+		return nil
+	}
+	req := service.MentionRequest{Channel: receiver, MessageID: m.ID, ThreadID: threadID, UserID: m.Author.ID, Text: text}
+	return b.HandleMention(req, threadCommand, b.replier(m.ChannelID, m.ID))
+}
+
+// addedUser returns the user a /adduser command adds: the user it mentions or replies to, or its sender
+func (b *DiscordBot) addedUser(m *message) user {
+	for _, mentioned := range m.Mentions {
+		if mentioned.ID != b.self() && !mentioned.Bot {
+			return mentioned
+		}
+	}
+	if m.ReferencedMessage != nil && !m.ReferencedMessage.Author.Bot {
+		return m.ReferencedMessage.Author
+	}
+	return m.Author
+}
+
+// handleCommand handles the bot commands. /adduser <Wolt name> registers the sender under their Wolt name, mapping their Discord user
+// ID to the user. Admins can add other users by mentioning them in it or replying to their message with it, and hosts can link the
+// unknown participants of their orders the same way.
+func (b *DiscordBot) handleCommand(m *message, command, args string) error {
+	if command != "adduser" {
+		return nil
+	}
+	req := transport.AddUserRequest{SenderID: m.Author.ID, AddedID: b.addedUser(m).ID, Name: userMentionRe.ReplaceAllString(args, "")}
+	return b.AddUser(req, "USAGE: /adduser <your name in Wolt>, or mention the user to add in it or reply with it to their message "+
+		"(admins, or hosts for the participants of their orders I couldn't find)", b.replier(m.ChannelID, m.ID))
+}
+
+// reactionRequest returns the request of a reaction, with the message as cached when it was sent or received. Reactions with the
+// servers' custom emojis are ignored, and the reactions in direct messages are in the channel of their user.
+func (c *Client) reactionRequest(r *messageReaction) (service.ReactionAddRequest, bool) {
+	if r.Emoji.ID != "" || r.UserID == c.self() {
+		return service.ReactionAddRequest{}, false
+	}
+	name, ok := transport.ReactionName(emojis, r.Emoji.Name)
+	if !ok {
+		return service.ReactionAddRequest{}, false
+	}
+
+	cached, ok := c.messages.Get(r.ChannelID, r.MessageID)
+	if !ok {
+		log.Printf("Got a reaction to message %s of channel %s, which isn't in the recent messages\n", r.MessageID, r.ChannelID)
+		cached = &transport.Message{UserID: r.MessageAuthorID}
+	}
+	return service.ReactionAddRequest{
+		Reaction:      name,
+		FromUserID:    r.UserID,
+		Channel:       c.receiverOf(r.ChannelID),
+		MessageUserID: cached.UserID,
+		MessageID:     r.MessageID,
+		MessageText:   cached.Text,
+	}, true
+}
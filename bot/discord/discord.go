@@ -0,0 +1,415 @@
+package discord
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oriser/bolt/bot/transport"
+)
+
+const (
+	// messagesCacheSize is how many recent messages are kept for resolving the messages reactions are added to, as Discord doesn't
+	// send the reacted message with the reaction
+	messagesCacheSize   = 10000
+	maxRateLimitRetries = 3
+	maxMessageLength    = 2000 // The length of the longest message Discord accepts
+)
+
+// The JSON error codes of the Discord API for unavailable channels and users
+const (
+	codeUnknownChannel  = 10003
+	codeUnknownGuild    = 10004
+	codeUnknownUser     = 10013
+	codeMissingAccess   = 50001
+	codeCannotMessageDM = 50007
+)
+
+var (
+	emojiRe     = regexp.MustCompile(`:([a-z0-9_+-]+):`)
+	slackDateRe = regexp.MustCompile(`<!date\^(\d+)\^[^|>]*\|[^>]*>`)
+	slackLinkRe = regexp.MustCompile(`<(https?://[^|>]+)\|([^>]+)>`)
+)
+
+// emojis maps the emoji names Bolt uses (as in Slack) to their characters, as Discord renders emoji names only in messages of users
+var emojis = map[string]string{
+	"eyes":                         "👀",
+	"money_mouth_face":             "🤑",
+	"x":                            "❌",
+	"no_entry_sign":                "🚫",
+	"white_check_mark":             "✅",
+	"thumbsup":                     "👍",
+	"+1":                           "👍",
+	"fire":                         "🔥",
+	"tada":                         "🎉",
+	"pray":                         "🙏",
+	"sleeping":                     "😴",
+	"100":                          "💯",
+	"credit_card":                  "💳",
+	"gift":                         "🎁",
+	"house":                        "🏠",
+	"bike":                         "🚲",
+	"cook":                         "🧑‍🍳",
+	"underage":                     "🔞",
+	"warning":                      "⚠️",
+	"hourglass_flowing_sand":       "⏳",
+	"busts_in_silhouette":          "👥",
+	"bar_chart":                    "📊",
+	"stuck_out_tongue_winking_eye": "😜",
+	"wave":                         "👋",
+	"crown":                        "👑",
+	"zap":                          "⚡",
+	"compass":                      "🧭",
+	"trophy":                       "🏆",
+	"twisted_rightwards_arrows":    "🔀",
+	"red_circle":                   "🔴",
+	"large_yellow_circle":          "🟡",
+	"large_green_circle":           "🟢",
+}
+
+type Config struct {
+	Token               string   `env:"DISCORD_BOT_TOKEN" json:"-"`
+	APIURL              string   `env:"DISCORD_API_URL" envDefault:"https://discord.com/api/v10"`
+	Port                uint     `env:"DISCORD_SERVER_PORT" envDefault:"8080"` // Port of the API and dashboard server
+	MaxConcurrentEvents int      `env:"DISCORD_MAX_CONCURRENT_EVENTS" envDefault:"100"`
+	AdminUserIDs        []string `env:"DISCORD_ADMIN_USER_IDS"`
+}
+
+// Client is a transport for Discord servers over the bot API, implementing the service's event notification.
+// The receivers are channel IDs or user IDs (for direct messages), and the users' transport IDs are their Discord user IDs.
+type Client struct {
+	cfg    Config
+	client *http.Client
+
+	lock       sync.Mutex
+	selfID     string
+	dmChannels map[string]string // The direct messages channels by the IDs of their users
+	guilds     map[string]string // The servers of the channels by the channels' IDs, for stopping their orders when the bot is removed
+	messages   *transport.MessageCache
+}
+
+func NewClient(cfg Config) *Client {
+	return &Client{
+		cfg:        cfg,
+		client:     &http.Client{Timeout: 30 * time.Second},
+		dmChannels: make(map[string]string),
+		guilds:     make(map[string]string),
+		messages:   transport.NewMessageCache(messagesCacheSize),
+	}
+}
+
+// apiError is an error response of the Discord API
+type apiError struct {
+	Status     int     `json:"-"`
+	Code       int     `json:"code"`
+	Message    string  `json:"message"`
+	RetryAfter float64 `json:"retry_after"` // Seconds, in rate limited responses
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("discord error %d (status %d): %s", e.Code, e.Status, e.Message)
+}
+
+func (c *Client) endpoint(path string) string {
+	return strings.TrimSuffix(c.cfg.APIURL, "/") + path
+}
+
+func (c *Client) call(ctx context.Context, method, path string, params interface{}, result interface{}) error {
+	var body []byte
+	if params != nil {
+		var err error
+		if body, err = json.Marshal(params); err != nil {
+			return fmt.Errorf("marshal %s params: %w", path, err)
+		}
+	}
+	return c.do(ctx, method, path, "application/json", body, result)
+}
+
+// do sends the request, retrying it when it's rate limited
+func (c *Client) do(ctx context.Context, method, path, contentType string, body []byte, result interface{}) error {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, c.endpoint(path), bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("new request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bot "+c.cfg.Token)
+		if body != nil {
+			req.Header.Set("Content-Type", contentType)
+		}
+
+		err = c.send(req, result)
+		var apiErr *apiError
+		if !errors.As(err, &apiErr) || apiErr.Status != http.StatusTooManyRequests || attempt >= maxRateLimitRetries {
+			return err
+		}
+		select {
+		case <-time.After(time.Duration(apiErr.RetryAfter * float64(time.Second))):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+func (c *Client) send(req *http.Request, result interface{}) error {
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		apiErr := &apiError{Status: resp.StatusCode}
+		if err := json.NewDecoder(resp.Body).Decode(apiErr); err != nil {
+			apiErr.Message = http.StatusText(resp.StatusCode)
+		}
+		return apiErr
+	}
+	if result == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("decode response with status %d: %w", resp.StatusCode, err)
+	}
+	return nil
+}
+
+// transportError wraps the errors of unavailable channels and users with the service errors for them, so the orders which can't be
+// tracked anymore are stopped
+func transportError(toUser bool, err error) error {
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+	switch apiErr.Code {
+	case codeUnknownChannel, codeUnknownGuild, codeMissingAccess:
+		return transport.UnavailableError(toUser, apiErr.Message)
+	case codeUnknownUser, codeCannotMessageDM:
+		return transport.UnavailableError(true, apiErr.Message)
+	}
+	return err
+}
+
+func (c *Client) GetSelfID() (string, error) {
+	var me user
+	if err := c.call(context.Background(), http.MethodGet, "/users/@me", nil, &me); err != nil {
+		return "", fmt.Errorf("get current user: %w", err)
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.selfID = me.ID
+	return c.selfID, nil
+}
+
+// CheckConnection checks that Discord can be reached and the token is valid
+func (c *Client) CheckConnection(ctx context.Context) error {
+	var me user
+	if err := c.call(ctx, http.MethodGet, "/users/@me", nil, &me); err != nil {
+		return fmt.Errorf("get current user: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) self() string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.selfID
+}
+
+func (c *Client) rememberDMChannel(userID, channelID string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.dmChannels[userID] = channelID
+}
+
+func (c *Client) dmChannel(userID string) (string, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	channelID, ok := c.dmChannels[userID]
+	return channelID, ok
+}
+
+// receiverOf returns the receiver of the channel for the service: the user of a direct messages channel, or the channel itself
+func (c *Client) receiverOf(channelID string) string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for userID, dm := range c.dmChannels {
+		if dm == channelID {
+			return userID
+		}
+	}
+	return channelID
+}
+
+func (c *Client) openDMChannel(ctx context.Context, userID string) (string, error) {
+	var dm channel
+	if err := c.call(ctx, http.MethodPost, "/users/@me/channels", map[string]string{"recipient_id": userID}, &dm); err != nil {
+		return "", fmt.Errorf("open DM channel: %w", err)
+	}
+	c.rememberDMChannel(userID, dm.ID)
+	return dm.ID, nil
+}
+
+// withChannel calls do with the channel of the receiver: the receiver itself if it's a channel, or the direct messages channel with it
+// if it's a user. Discord's user and channel IDs look the same, so unknown receivers are tried as channels first.
+func (c *Client) withChannel(receiver string, do func(channelID string) error) error {
+	if channelID, ok := c.dmChannel(receiver); ok {
+		return transportError(true, do(channelID))
+	}
+	err := do(receiver)
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) || apiErr.Code != codeUnknownChannel {
+		return transportError(false, err)
+	}
+	channelID, dmErr := c.openDMChannel(context.Background(), receiver)
+	if dmErr != nil {
+		return transportError(false, err)
+	}
+	return transportError(true, do(channelID))
+}
+
+func (c *Client) rememberGuild(channelID, guildID string) {
+	if guildID == "" {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.guilds[channelID] = guildID
+}
+
+// guildChannels returns the channels of the server the bot got messages from
+func (c *Client) guildChannels(guildID string) []string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	channels := make([]string, 0)
+	for channelID, guild := range c.guilds {
+		if guild == guildID {
+			channels = append(channels, channelID)
+		}
+	}
+	return channels
+}
+
+// formatText converts a message in Slack's format (which the service uses) to Discord's markdown. Mentions of users (<@ID>) and
+// channels (<#ID>) are the same in Discord, and the :emoji: names, the dates and the links are converted.
+func formatText(text string) string {
+	text = slackDateRe.ReplaceAllString(text, "<t:$1:f>")
+	text = slackLinkRe.ReplaceAllString(text, "[$2](<$1>)")
+	return emojiRe.ReplaceAllStringFunc(text, func(match string) string {
+		if e, ok := emojis[strings.Trim(match, ":")]; ok {
+			return e
+		}
+		return match
+	})
+}
+
+// messageParams returns the params of a message with the text. Only the users are mentioned, so messages don't ping whole channels.
+func messageParams(text string) map[string]interface{} {
+	return map[string]interface{}{
+		"content":          formatText(text),
+		"allowed_mentions": map[string][]string{"parse": {"users"}},
+	}
+}
+
+type sentMessage struct {
+	ID        string `json:"id"`
+	ChannelID string `json:"channel_id"`
+}
+
+func (c *Client) SendMessage(receiver, event, messageID string) (string, error) {
+	params := messageParams(event)
+	if messageID != "" {
+		// Messages of a thread are sent as replies to its message, as Discord's threads are separate channels
+		params["message_reference"] = map[string]interface{}{"message_id": messageID, "fail_if_not_exists": false}
+	}
+	var sent sentMessage
+	err := c.withChannel(receiver, func(channelID string) error {
+		return c.call(context.Background(), http.MethodPost, "/channels/"+channelID+"/messages", params, &sent)
+	})
+	if err != nil {
+		return "", fmt.Errorf("posting message: %w", err)
+	}
+	c.messages.Add(sent.ChannelID, sent.ID, transport.Message{UserID: c.self(), ThreadID: c.messages.ThreadOf(sent.ChannelID, messageID), Text: event})
+	return sent.ID, nil
+}
+
+func (c *Client) EditMessage(receiver, event, messageID string) error {
+	if messageID == "" {
+		return fmt.Errorf("empty message ID")
+	}
+	var edited sentMessage
+	err := c.withChannel(receiver, func(channelID string) error {
+		return c.call(context.Background(), http.MethodPatch, "/channels/"+channelID+"/messages/"+messageID, messageParams(event), &edited)
+	})
+	if err != nil {
+		return fmt.Errorf("editing message %s: %w", messageID, err)
+	}
+	c.messages.Add(edited.ChannelID, messageID, transport.Message{UserID: c.self(), Text: event})
+	return nil
+}
+
+func (c *Client) MaxMessageLength() int {
+	return maxMessageLength
+}
+
+func (c *Client) AddReaction(receiver, messageID, reaction string) error {
+	e, ok := emojis[reaction]
+	if !ok {
+		return fmt.Errorf("add reaction: unknown emoji :%s:", reaction)
+	}
+	err := c.withChannel(receiver, func(channelID string) error {
+		path := fmt.Sprintf("/channels/%s/messages/%s/reactions/%s/@me", channelID, messageID, url.PathEscape(e))
+		return c.call(context.Background(), http.MethodPut, path, nil, nil)
+	})
+	if err != nil {
+		return fmt.Errorf("add reaction: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) UploadFile(receiver, filename, content, comment string) error {
+	payload, err := json.Marshal(messageParams(comment))
+	if err != nil {
+		return fmt.Errorf("marshal message: %w", err)
+	}
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", `form-data; name="payload_json"`)
+	header.Set("Content-Type", "application/json")
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return fmt.Errorf("create payload part: %w", err)
+	}
+	if _, err := part.Write(payload); err != nil {
+		return fmt.Errorf("write payload: %w", err)
+	}
+	part, err = writer.CreateFormFile("files[0]", filename)
+	if err != nil {
+		return fmt.Errorf("create file part: %w", err)
+	}
+	if _, err := io.WriteString(part, content); err != nil {
+		return fmt.Errorf("write file: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("close multipart writer: %w", err)
+	}
+
+	err = c.withChannel(receiver, func(channelID string) error {
+		return c.do(context.Background(), http.MethodPost, "/channels/"+channelID+"/messages", writer.FormDataContentType(), body.Bytes(), nil)
+	})
+	if err != nil {
+		return fmt.Errorf("upload file %s: %w", filename, err)
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package discord
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/oriser/bolt/bot/transport"
+	"github.com/oriser/bolt/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAPI records the requests to the Discord API by their method and path, answering them with the given responses
+type fakeAPI struct {
+	lock      sync.Mutex
+	calls     map[string][]map[string]interface{}
+	responses map[string]string
+}
+
+func newFakeAPI(t *testing.T, responses map[string]string) (*fakeAPI, *Client) {
+	api := &fakeAPI{calls: make(map[string][]map[string]interface{}), responses: responses}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Method + " " + r.URL.EscapedPath()
+		params := make(map[string]interface{})
+		_ = json.NewDecoder(r.Body).Decode(&params)
+		api.lock.Lock()
+		api.calls[key] = append(api.calls[key], params)
+		api.lock.Unlock()
+
+		response, ok := api.responses[key]
+		if !ok {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if errResponse := (apiError{}); json.Unmarshal([]byte(response), &errResponse) == nil && errResponse.Code != 0 {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		_, _ = w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+
+	return api, NewClient(Config{Token: "token", APIURL: server.URL, MaxConcurrentEvents: 1})
+}
+
+func TestFormatText(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "<@42> joined the order in <#100> 👀 `1 & 2` :unknown: [this order](<https://wolt.com/group/ABC>) at <t:1700000000:f>",
+		formatText("<@42> joined the order in <#100> :eyes: `1 & 2` :unknown: <https://wolt.com/group/ABC|this order> at "+
+			"<!date^1700000000^{time}|10:13>"))
+}
+
+func TestSendMessage(t *testing.T) {
+	t.Parallel()
+
+	api, client := newFakeAPI(t, map[string]string{
+		"GET /users/@me":              `{"id": "1", "username": "bolt", "bot": true}`,
+		"POST /channels/100/messages": `{"id": "11", "channel_id": "100"}`,
+	})
+	selfID, err := client.GetSelfID()
+	require.NoError(t, err)
+	assert.Equal(t, "1", selfID)
+
+	client.messages.Add("100", "10", transport.Message{UserID: "42", Text: "order link"})
+	id, err := client.SendMessage("100", "Joined :eyes:", "10")
+	require.NoError(t, err)
+	assert.Equal(t, "11", id)
+
+	require.Len(t, api.calls["POST /channels/100/messages"], 1)
+	params := api.calls["POST /channels/100/messages"][0]
+	assert.Equal(t, "Joined 👀", params["content"])
+	assert.Equal(t, map[string]interface{}{"message_id": "10", "fail_if_not_exists": false}, params["message_reference"])
+	assert.Equal(t, map[string]interface{}{"parse": []interface{}{"users"}}, params["allowed_mentions"])
+
+	// The messages replying to a reply are in the thread of the first message
+	cached, ok := client.messages.Get("100", "11")
+	require.True(t, ok)
+	assert.Equal(t, transport.Message{UserID: "1", ThreadID: "10", Text: "Joined :eyes:"}, *cached)
+	assert.Equal(t, "10", client.messages.ThreadOf("100", "11"))
+}
+
+func TestSendDirectMessage(t *testing.T) {
+	t.Parallel()
+
+	api, client := newFakeAPI(t, map[string]string{
+		"POST /channels/42/messages":  `{"code": 10003, "message": "Unknown Channel"}`,
+		"POST /users/@me/channels":    `{"id": "500"}`,
+		"POST /channels/500/messages": `{"id": "12", "channel_id": "500"}`,
+	})
+	id, err := client.SendMessage("42", "You owe 30 NIS", "")
+	require.NoError(t, err)
+	assert.Equal(t, "12", id)
+	assert.Equal(t, []map[string]interface{}{{"recipient_id": "42"}}, api.calls["POST /users/@me/channels"])
+
+	// The DM channel is kept, and its reactions are of the user
+	_, err = client.SendMessage("42", "Reminder", "")
+	require.NoError(t, err)
+	assert.Len(t, api.calls["POST /channels/42/messages"], 1)
+	assert.Len(t, api.calls["POST /channels/500/messages"], 2)
+	assert.Equal(t, "42", client.receiverOf("500"))
+	assert.Equal(t, "100", client.receiverOf("100"))
+}
+
+func TestAddReaction(t *testing.T) {
+	t.Parallel()
+
+	api, client := newFakeAPI(t, nil)
+	require.NoError(t, client.AddReaction("100", "10", "white_check_mark"))
+	assert.Len(t, api.calls["PUT /channels/100/messages/10/reactions/%E2%9C%85/@me"], 1)
+
+	assert.ErrorContains(t, client.AddReaction("100", "10", "unknown"), "unknown emoji")
+}
+
+func TestTransportError(t *testing.T) {
+	t.Parallel()
+
+	_, client := newFakeAPI(t, map[string]string{
+		"POST /channels/100/messages": `{"code": 50001, "message": "Missing Access"}`,
+		"POST /channels/42/messages":  `{"code": 10003, "message": "Unknown Channel"}`,
+		"POST /users/@me/channels":    `{"id": "500"}`,
+		"POST /channels/500/messages": `{"code": 50007, "message": "Cannot send messages to this user"}`,
+		"POST /channels/200/messages": `{"code": 50035, "message": "Invalid Form Body"}`,
+	})
+	_, err := client.SendMessage("100", "hello", "")
+	assert.True(t, errors.Is(err, service.ErrChannelUnavailable))
+	_, err = client.SendMessage("42", "hello", "")
+	assert.True(t, errors.Is(err, service.ErrUserUnavailable))
+	_, err = client.SendMessage("200", "hello", "")
+	assert.False(t, errors.Is(err, service.ErrChannelUnavailable))
+}
+
+func TestReactionRequest(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(Config{})
+	client.selfID = "1"
+	client.messages.Add("100", "11", transport.Message{UserID: "1", ThreadID: "10", Text: "Order of Pizza is done, <@42> owes 30 NIS"})
+
+	reaction := &messageReaction{UserID: "42", ChannelID: "100", MessageID: "11"}
+	reaction.Emoji.Name = "🤑"
+	req, ok := client.reactionRequest(reaction)
+	require.True(t, ok)
+	assert.Equal(t, service.ReactionAddRequest{
+		Reaction:      "money_mouth_face",
+		FromUserID:    "42",
+		Channel:       "100",
+		MessageUserID: "1",
+		MessageID:     "11",
+		MessageText:   "Order of Pizza is done, <@42> owes 30 NIS",
+	}, req)
+
+	reaction.Emoji.ID = "900"
+	_, ok = client.reactionRequest(reaction)
+	assert.False(t, ok, "custom emojis are ignored")
+}
+
+func TestHandleLinkMessage(t *testing.T) {
+	t.Parallel()
+
+	_, client := newFakeAPI(t, nil)
+	bot := &DiscordBot{Client: client, Bot: transport.NewBot(nil, nil, 1)}
+	var got service.LinksRequest
+	bot.SetLinkHandler(func(req service.LinksRequest) (string, error) {
+		got = req
+		return "", nil
+	})
+
+	data := `{"id": "10", "channel_id": "100", "guild_id": "7", "author": {"id": "42", "username": "dana"},
+		"content": "<@!42> join https://wolt.com/en/isr/tel-aviv/venue/pizza"}`
+	require.NoError(t, bot.handleEvent("MESSAGE_CREATE", json.RawMessage(data)))
+	assert.Equal(t, service.LinksRequest{
+		Links:     []service.Link{{Domain: "wolt.com", URL: "https://wolt.com/en/isr/tel-aviv/venue/pizza"}},
+		MessageID: "10",
+		Channel:   "100",
+		Text:      "<@42> join https://wolt.com/en/isr/tel-aviv/venue/pizza",
+		UserID:    "42",
+	}, got)
+	assert.Equal(t, []string{"100"}, client.guildChannels("7"))
+}
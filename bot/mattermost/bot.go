@@ -0,0 +1,262 @@
+package mattermost
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/oriser/bolt/bot/transport"
+	"github.com/oriser/bolt/service"
+)
+
+const (
+	reconnectInterval = 5 * time.Second
+	// AddUserKeyword is the command for adding users, sent as "@bolt adduser <Wolt name>", as Mattermost's slash commands must be
+	// registered separately
+	AddUserKeyword = "adduser"
+)
+
+var urlRe = regexp.MustCompile(`(?:https?|wolt)://[^\s<>"()\[\]]+`)
+
+// event is an event of the WebSocket API
+type event struct {
+	Event     string                     `json:"event"`
+	Data      map[string]json.RawMessage `json:"data"`
+	Broadcast struct {
+		ChannelID string `json:"channel_id"`
+		UserID    string `json:"user_id"`
+	} `json:"broadcast"`
+}
+
+// stringData returns a string field of the event's data, empty if it's missing
+func (e *event) stringData(name string) string {
+	var value string
+	_ = json.Unmarshal(e.Data[name], &value)
+	return value
+}
+
+type reaction struct {
+	UserID    string `json:"user_id"`
+	PostID    string `json:"post_id"`
+	EmojiName string `json:"emoji_name"`
+	ChannelID string `json:"channel_id"`
+}
+
+type MattermostBot struct {
+	*Client
+	*transport.Bot
+}
+
+func (c *Client) ServiceBot(serviceHandler *service.Service) *MattermostBot {
+	return &MattermostBot{Client: c, Bot: transport.NewBot(serviceHandler, c.cfg.AdminUserIDs, c.cfg.MaxConcurrentEvents)}
+}
+
+// ListenAndServe listens to the events of the bot over the WebSocket API, and serves the API and the dashboard (when enabled) on
+// MATTERMOST_SERVER_PORT
+func (b *MattermostBot) ListenAndServe(ctx context.Context) error {
+	go b.listenWebSocket(ctx)
+
+	log.Println("Server listening on port", b.cfg.Port)
+	return http.ListenAndServe(fmt.Sprintf(":%d", b.cfg.Port), nil)
+}
+
+// listenWebSocket keeps a WebSocket connection open, opening a new one whenever it's closed
+func (b *MattermostBot) listenWebSocket(ctx context.Context) {
+	for {
+		err := b.runSession(ctx)
+		if ctx.Err() != nil {
+			log.Println("Finishing listening to Mattermost's events due to context cancellation")
+			return
+		}
+		log.Println("Mattermost WebSocket connection ended, reconnecting:", err)
+		select {
+		case <-time.After(reconnectInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// webSocketURL returns the URL of the WebSocket API of the server
+func (c *Client) webSocketURL() (string, error) {
+	u, err := url.Parse(c.endpoint("/websocket"))
+	if err != nil {
+		return "", fmt.Errorf("parse URL: %w", err)
+	}
+	if u.Scheme == "https" {
+		u.Scheme = "wss"
+	} else {
+		u.Scheme = "ws"
+	}
+	return u.String(), nil
+}
+
+// runSession connects to the WebSocket API and handles its events until the connection is closed. Mattermost pings the connection,
+// and the pings are answered by the WebSocket library.
+func (b *MattermostBot) runSession(ctx context.Context) error {
+	wsURL, err := b.webSocketURL()
+	if err != nil {
+		return err
+	}
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+b.cfg.Token)
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, wsURL, header)
+	if err != nil {
+		return fmt.Errorf("dial WebSocket: %w", err)
+	}
+	sessionCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-sessionCtx.Done()
+		conn.Close()
+	}()
+
+	for {
+		var e event
+		if err := conn.ReadJSON(&e); err != nil {
+			return fmt.Errorf("read event: %w", err)
+		}
+		if e.Event == "" {
+			// A response to an action, Bolt doesn't send any
+			continue
+		}
+		b.Go(func() {
+			if err := b.handleEvent(&e); err != nil {
+				log.Printf("Error handling Mattermost %s event: %v\n", e.Event, err)
+			}
+		})
+	}
+}
+
+func (b *MattermostBot) handleEvent(e *event) error {
+	switch e.Event {
+	case "posted":
+		var p post
+		if err := json.Unmarshal([]byte(e.stringData("post")), &p); err != nil {
+			return fmt.Errorf("unmarshal post: %w", err)
+		}
+		return b.handlePost(&p, e.stringData("channel_type"))
+	case "reaction_added":
+		var r reaction
+		if err := json.Unmarshal([]byte(e.stringData("reaction")), &r); err != nil {
+			return fmt.Errorf("unmarshal reaction: %w", err)
+		}
+		req, ok := b.reactionRequest(&r)
+		if !ok {
+			return nil
+		}
+		return b.HandleReaction(req, b.replier(req.Channel, ""))
+	case "user_removed":
+		// Sent to the channel with the removed user in the data, and to the removed user with the channel in the data
+		userID, channelID := e.stringData("user_id"), e.stringData("channel_id")
+		if userID == "" {
+			userID = e.Broadcast.UserID
+		}
+		if channelID == "" {
+			channelID = e.Broadcast.ChannelID
+		}
+		b.Service.HandleMemberLeftChannel(channelID, userID)
+	case "channel_deleted", "channel_archived":
+		b.Service.HandleChannelArchived(e.stringData("channel_id"))
+	}
+	return nil
+}
+
+// replier returns the function replying to the receiver, in the thread of the post if it's given
+func (b *MattermostBot) replier(receiver, postID string) func(text string) error {
+	return func(text string) error {
+		_, err := b.SendMessage(receiver, text, postID)
+		return err
+	}
+}
+
+func (b *MattermostBot) handlePost(p *post, channelType string) error {
+	selfID := b.self()
+	if p.UserID == selfID || p.Type != "" || p.Props["from_bot"] == "true" {
+		return nil
+	}
+	if channelType == "D" {
+		b.rememberDMChannel(p.UserID, p.ChannelID)
+	}
+
+	ctx := context.Background()
+	text := b.parseText(ctx, p.Message)
+	b.messages.Add("", p.ID, transport.Message{UserID: p.UserID, ThreadID: p.RootID, Text: text})
+	receiver := b.receiverOf(p.ChannelID)
+	if found := transport.Links(urlRe.FindAllString(text, -1)); len(found) > 0 {
+		req := service.LinksRequest{Links: found, MessageID: p.ID, Channel: receiver, Text: text, UserID: p.UserID}
+		return b.HandleLinks(req, b.replier(receiver, ""))
+	}
+
+	selfMention := fmt.Sprintf("<@%s>", selfID)
+	threadCommand := p.RootID != "" && service.IsThreadCommand(text)
+	if !threadCommand {
+		if selfID == "" || !strings.Contains(text, selfMention) {
+			return nil
+		}
+		if keyword, args, _ := strings.Cut(strings.TrimSpace(strings.Replace(text, selfMention, "", 1)), " "); strings.EqualFold(keyword, AddUserKeyword) {
+			return b.handleAddUser(p, receiver, text, strings.TrimSpace(args))
+		}
+	}
+
+	return b.HandleMention(service.MentionRequest{
+		Channel:   receiver,
+		MessageID: p.ID,
+		ThreadID:  p.RootID,
+		UserID:    p.UserID,
+		Text:      text,
+	}, threadCommand, b.replier(receiver, p.ID))
+}
+
+// addedUser returns the ID of the user an adduser command adds: the user it mentions, or its sender
+func (b *MattermostBot) addedUser(p *post, text string) string {
+	for _, match := range userMentionRe.FindAllStringSubmatch(text, -1) {
+		if match[1] != b.self() {
+			return match[1]
+		}
+	}
+	return p.UserID
+}
+
+// handleAddUser handles "@bolt adduser <Wolt name>", which registers the sender under their Wolt name, mapping their Mattermost
+// user ID to the user. Admins can add other users by mentioning them in it, and hosts can link the unknown participants of their
+// orders the same way.
+func (b *MattermostBot) handleAddUser(p *post, receiver, text, args string) error {
+	req := transport.AddUserRequest{SenderID: p.UserID, AddedID: b.addedUser(p, text), Name: userMentionRe.ReplaceAllString(args, "")}
+	return b.AddUser(req, fmt.Sprintf("USAGE: <@%s> %s <your name in Wolt>, or mention the user to add in it (admins, or hosts for the "+
+		"participants of their orders I couldn't find)", b.self(), AddUserKeyword), b.replier(receiver, p.ID))
+}
+
+// reactionRequest returns the request of a reaction, with the post as cached when it was sent or received, or as read from Mattermost
+// if it isn't cached. The reactions in direct messages are in the channel of their user.
+func (c *Client) reactionRequest(r *reaction) (service.ReactionAddRequest, bool) {
+	if r.UserID == c.self() {
+		return service.ReactionAddRequest{}, false
+	}
+	cached, ok := c.messages.Get("", r.PostID)
+	if !ok {
+		ctx := context.Background()
+		var p post
+		if err := c.call(ctx, http.MethodGet, "/posts/"+r.PostID, nil, &p); err != nil {
+			log.Printf("Error getting post %s of a reaction: %v\n", r.PostID, err)
+			return service.ReactionAddRequest{}, false
+		}
+		cached = &transport.Message{UserID: p.UserID, ThreadID: p.RootID, Text: c.parseText(ctx, p.Message)}
+		c.messages.Add("", r.PostID, *cached)
+	}
+	return service.ReactionAddRequest{
+		Reaction:      r.EmojiName,
+		FromUserID:    r.UserID,
+		Channel:       c.receiverOf(r.ChannelID),
+		MessageUserID: cached.UserID,
+		MessageID:     r.PostID,
+		MessageText:   cached.Text,
+	}, true
+}
@@ -0,0 +1,422 @@
+package mattermost
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oriser/bolt/bot/transport"
+)
+
+const (
+	// messagesCacheSize is how many recent posts are kept with their text in Slack's format and their thread, for the reactions to
+	// them and the replies in their threads
+	messagesCacheSize   = 10000
+	maxRateLimitRetries = 3
+	maxMessageLength    = 16383 // The length of the longest post Mattermost accepts by default
+)
+
+var (
+	userMentionRe    = regexp.MustCompile(`<@([A-Za-z0-9]+)>`)
+	channelMentionRe = regexp.MustCompile(`<#([A-Za-z0-9]+)>`)
+	slackDateRe      = regexp.MustCompile(`<!date\^\d+\^[^|>]*\|([^>]*)>`)
+	slackLinkRe      = regexp.MustCompile(`<(https?://[^|>]+)\|([^>]+)>`)
+	// usernameRe matches the @username mentions of Mattermost, which start a word and end with a letter or a digit
+	usernameRe = regexp.MustCompile(`(^|[^A-Za-z0-9._@<-])@([A-Za-z0-9](?:[A-Za-z0-9._-]*[A-Za-z0-9])?)`)
+)
+
+type Config struct {
+	URL                 string   `env:"MATTERMOST_URL"` // The server's URL, like https://chat.example.com
+	Token               string   `env:"MATTERMOST_BOT_TOKEN" json:"-"`
+	Port                uint     `env:"MATTERMOST_SERVER_PORT" envDefault:"8080"` // Port of the API and dashboard server
+	MaxConcurrentEvents int      `env:"MATTERMOST_MAX_CONCURRENT_EVENTS" envDefault:"100"`
+	AdminUserIDs        []string `env:"MATTERMOST_ADMIN_USER_IDS"`
+}
+
+// Client is a transport for Mattermost servers over the REST API (v4), implementing the service's event notification.
+// The receivers are channel IDs or user IDs (for direct messages), and the users' transport IDs are their Mattermost user IDs.
+type Client struct {
+	cfg    Config
+	client *http.Client
+
+	lock       sync.Mutex
+	selfID     string
+	dmChannels map[string]string       // The direct messages channels by the IDs of their users
+	channels   map[string]bool         // The receivers known to be channels rather than users
+	usernames  map[string]string       // The usernames by user IDs
+	userIDs    map[string]string       // The user IDs by usernames, empty for usernames which aren't of any user
+	messages   *transport.MessageCache // The recent posts, whose IDs are unique across channels. Mattermost's threads have a single level.
+}
+
+func NewClient(cfg Config) *Client {
+	return &Client{
+		cfg:        cfg,
+		client:     &http.Client{Timeout: 30 * time.Second},
+		dmChannels: make(map[string]string),
+		channels:   make(map[string]bool),
+		usernames:  make(map[string]string),
+		userIDs:    make(map[string]string),
+		messages:   transport.NewMessageCache(messagesCacheSize),
+	}
+}
+
+// apiError is an error response of the Mattermost API
+type apiError struct {
+	Status     int           `json:"status_code"`
+	ID         string        `json:"id"`
+	Message    string        `json:"message"`
+	RetryAfter time.Duration `json:"-"` // In rate limited responses
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("mattermost error %s (status %d): %s", e.ID, e.Status, e.Message)
+}
+
+type user struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+}
+
+type channel struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"` // O for public channels, P for private ones, D for direct messages and G for group messages
+}
+
+type post struct {
+	ID        string                 `json:"id"`
+	ChannelID string                 `json:"channel_id"`
+	RootID    string                 `json:"root_id"` // Empty for posts which aren't replies
+	UserID    string                 `json:"user_id"`
+	Message   string                 `json:"message"`
+	Type      string                 `json:"type"` // Empty for posts of users, and the type of system messages
+	Props     map[string]interface{} `json:"props"`
+}
+
+func (c *Client) endpoint(path string) string {
+	return strings.TrimSuffix(c.cfg.URL, "/") + "/api/v4" + path
+}
+
+func (c *Client) call(ctx context.Context, method, path string, params interface{}, result interface{}) error {
+	var body []byte
+	if params != nil {
+		var err error
+		if body, err = json.Marshal(params); err != nil {
+			return fmt.Errorf("marshal %s params: %w", path, err)
+		}
+	}
+	return c.do(ctx, method, path, "application/json", body, result)
+}
+
+// do sends the request, retrying it when it's rate limited
+func (c *Client) do(ctx context.Context, method, path, contentType string, body []byte, result interface{}) error {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, c.endpoint(path), bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("new request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+c.cfg.Token)
+		if body != nil {
+			req.Header.Set("Content-Type", contentType)
+		}
+
+		err = c.send(req, result)
+		var apiErr *apiError
+		if !errors.As(err, &apiErr) || apiErr.Status != http.StatusTooManyRequests || attempt >= maxRateLimitRetries {
+			return err
+		}
+		select {
+		case <-time.After(apiErr.RetryAfter):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+func (c *Client) send(req *http.Request, result interface{}) error {
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		apiErr := &apiError{}
+		if err := json.NewDecoder(resp.Body).Decode(apiErr); err != nil {
+			apiErr.Message = http.StatusText(resp.StatusCode)
+		}
+		apiErr.Status = resp.StatusCode
+		if reset, err := strconv.Atoi(resp.Header.Get("X-Ratelimit-Reset")); err == nil {
+			apiErr.RetryAfter = time.Duration(reset) * time.Second
+		}
+		return apiErr
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("decode response with status %d: %w", resp.StatusCode, err)
+	}
+	return nil
+}
+
+// transportError wraps the errors of unavailable channels and users with the service errors for them, so the orders which can't be
+// tracked anymore are stopped
+func transportError(toUser bool, err error) error {
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) || (apiErr.Status != http.StatusNotFound && apiErr.Status != http.StatusForbidden) {
+		return err
+	}
+	return transport.UnavailableError(toUser, apiErr.Message)
+}
+
+func (c *Client) GetSelfID() (string, error) {
+	var me user
+	if err := c.call(context.Background(), http.MethodGet, "/users/me", nil, &me); err != nil {
+		return "", fmt.Errorf("get current user: %w", err)
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.selfID = me.ID
+	c.usernames[me.ID] = me.Username
+	c.userIDs[me.Username] = me.ID
+	return c.selfID, nil
+}
+
+// CheckConnection checks that Mattermost can be reached and the token is valid
+func (c *Client) CheckConnection(ctx context.Context) error {
+	var me user
+	if err := c.call(ctx, http.MethodGet, "/users/me", nil, &me); err != nil {
+		return fmt.Errorf("get current user: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) self() string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.selfID
+}
+
+func (c *Client) rememberUser(u user) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.usernames[u.ID] = u.Username
+	c.userIDs[u.Username] = u.ID
+}
+
+// username returns the username of the user, or false if there's no such user
+func (c *Client) username(ctx context.Context, userID string) (string, bool) {
+	c.lock.Lock()
+	username, ok := c.usernames[userID]
+	c.lock.Unlock()
+	if ok {
+		return username, username != ""
+	}
+	var u user
+	if err := c.call(ctx, http.MethodGet, "/users/"+userID, nil, &u); err != nil {
+		var apiErr *apiError
+		if errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound {
+			c.lock.Lock()
+			c.usernames[userID] = ""
+			c.lock.Unlock()
+		}
+		return "", false
+	}
+	c.rememberUser(u)
+	return u.Username, true
+}
+
+// userID returns the ID of the user with the username, or false if there's no such user
+func (c *Client) userID(ctx context.Context, username string) (string, bool) {
+	username = strings.ToLower(username)
+	c.lock.Lock()
+	id, ok := c.userIDs[username]
+	c.lock.Unlock()
+	if ok {
+		return id, id != ""
+	}
+	var u user
+	if err := c.call(ctx, http.MethodGet, "/users/username/"+username, nil, &u); err != nil {
+		var apiErr *apiError
+		if errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound {
+			c.lock.Lock()
+			c.userIDs[username] = ""
+			c.lock.Unlock()
+		}
+		return "", false
+	}
+	c.rememberUser(u)
+	return u.ID, true
+}
+
+func (c *Client) rememberDMChannel(userID, channelID string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.dmChannels[userID] = channelID
+}
+
+// receiverOf returns the receiver of the channel for the service: the user of a direct messages channel, or the channel itself
+func (c *Client) receiverOf(channelID string) string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for userID, dm := range c.dmChannels {
+		if dm == channelID {
+			return userID
+		}
+	}
+	return channelID
+}
+
+// channelOf returns the channel of the receiver: the receiver itself if it's a channel, or the direct messages channel with it if
+// it's a user, and whether the receiver is a user. Mattermost's user and channel IDs look the same, so unknown receivers are looked up
+// as users first.
+func (c *Client) channelOf(ctx context.Context, receiver string) (string, bool, error) {
+	c.lock.Lock()
+	dm, isUser := c.dmChannels[receiver]
+	isChannel := c.channels[receiver]
+	c.lock.Unlock()
+	switch {
+	case isUser:
+		return dm, true, nil
+	case isChannel:
+		return receiver, false, nil
+	}
+
+	if _, ok := c.username(ctx, receiver); !ok {
+		c.lock.Lock()
+		c.channels[receiver] = true
+		c.lock.Unlock()
+		return receiver, false, nil
+	}
+	var direct channel
+	if err := c.call(ctx, http.MethodPost, "/channels/direct", []string{c.self(), receiver}, &direct); err != nil {
+		return "", true, fmt.Errorf("open DM channel: %w", transportError(true, err))
+	}
+	c.rememberDMChannel(receiver, direct.ID)
+	return direct.ID, true, nil
+}
+
+// formatText converts a message in Slack's format (which the service uses) to Mattermost's markdown. The mentions of users
+// (<@ID>) and channels (<#ID>) become @username and ~channel mentions, and the dates and the links are converted. The :emoji: names
+// are the same in Mattermost.
+func (c *Client) formatText(ctx context.Context, text string) string {
+	text = userMentionRe.ReplaceAllStringFunc(text, func(match string) string {
+		if username, ok := c.username(ctx, userMentionRe.FindStringSubmatch(match)[1]); ok {
+			return "@" + username
+		}
+		return match
+	})
+	text = channelMentionRe.ReplaceAllStringFunc(text, func(match string) string {
+		var ch channel
+		if err := c.call(ctx, http.MethodGet, "/channels/"+channelMentionRe.FindStringSubmatch(match)[1], nil, &ch); err != nil || ch.Name == "" {
+			return match
+		}
+		return "~" + ch.Name
+	})
+	text = slackDateRe.ReplaceAllString(text, "$1")
+	return slackLinkRe.ReplaceAllString(text, "[$2]($1)")
+}
+
+// parseText converts the @username mentions of a post to Slack's mentions (<@ID>), which the service expects
+func (c *Client) parseText(ctx context.Context, text string) string {
+	return usernameRe.ReplaceAllStringFunc(text, func(match string) string {
+		groups := usernameRe.FindStringSubmatch(match)
+		if id, ok := c.userID(ctx, groups[2]); ok {
+			return groups[1] + "<@" + id + ">"
+		}
+		return match
+	})
+}
+
+func (c *Client) SendMessage(receiver, event, messageID string) (string, error) {
+	ctx := context.Background()
+	channelID, toUser, err := c.channelOf(ctx, receiver)
+	if err != nil {
+		return "", fmt.Errorf("posting message: %w", err)
+	}
+	rootID := c.messages.ThreadOf("", messageID)
+	var sent post
+	params := map[string]interface{}{"channel_id": channelID, "message": c.formatText(ctx, event), "root_id": rootID}
+	if err := c.call(ctx, http.MethodPost, "/posts", params, &sent); err != nil {
+		return "", fmt.Errorf("posting message: %w", transportError(toUser, err))
+	}
+	c.messages.Add("", sent.ID, transport.Message{UserID: c.self(), ThreadID: rootID, Text: event})
+	return sent.ID, nil
+}
+
+func (c *Client) EditMessage(_, event, messageID string) error {
+	if messageID == "" {
+		return fmt.Errorf("empty message ID")
+	}
+	ctx := context.Background()
+	var edited post
+	if err := c.call(ctx, http.MethodPut, "/posts/"+messageID+"/patch", map[string]string{"message": c.formatText(ctx, event)}, &edited); err != nil {
+		return fmt.Errorf("editing message %s: %w", messageID, transportError(false, err))
+	}
+	c.messages.Add("", messageID, transport.Message{UserID: c.self(), ThreadID: edited.RootID, Text: event})
+	return nil
+}
+
+func (c *Client) MaxMessageLength() int {
+	return maxMessageLength
+}
+
+func (c *Client) AddReaction(_, messageID, reaction string) error {
+	params := map[string]string{"user_id": c.self(), "post_id": messageID, "emoji_name": reaction}
+	if err := c.call(context.Background(), http.MethodPost, "/reactions", params, nil); err != nil {
+		return fmt.Errorf("add reaction: %w", transportError(false, err))
+	}
+	return nil
+}
+
+func (c *Client) UploadFile(receiver, filename, content, comment string) error {
+	ctx := context.Background()
+	channelID, toUser, err := c.channelOf(ctx, receiver)
+	if err != nil {
+		return fmt.Errorf("upload file %s: %w", filename, err)
+	}
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	if err := writer.WriteField("channel_id", channelID); err != nil {
+		return fmt.Errorf("write channel ID: %w", err)
+	}
+	part, err := writer.CreateFormFile("files", filename)
+	if err != nil {
+		return fmt.Errorf("create file part: %w", err)
+	}
+	if _, err := io.WriteString(part, content); err != nil {
+		return fmt.Errorf("write file: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("close multipart writer: %w", err)
+	}
+
+	var uploaded struct {
+		FileInfos []struct {
+			ID string `json:"id"`
+		} `json:"file_infos"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/files", writer.FormDataContentType(), body.Bytes(), &uploaded); err != nil {
+		return fmt.Errorf("upload file %s: %w", filename, transportError(toUser, err))
+	}
+	fileIDs := make([]string, 0, len(uploaded.FileInfos))
+	for _, info := range uploaded.FileInfos {
+		fileIDs = append(fileIDs, info.ID)
+	}
+	params := map[string]interface{}{"channel_id": channelID, "message": c.formatText(ctx, comment), "file_ids": fileIDs}
+	if err := c.call(ctx, http.MethodPost, "/posts", params, nil); err != nil {
+		return fmt.Errorf("post file %s: %w", filename, transportError(toUser, err))
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package mattermost
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/oriser/bolt/bot/transport"
+	"github.com/oriser/bolt/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAPI records the requests to the Mattermost API by their method and path, answering them with the given responses
+type fakeAPI struct {
+	lock      sync.Mutex
+	calls     map[string][]interface{}
+	responses map[string]string
+}
+
+func newFakeAPI(t *testing.T, responses map[string]string) (*fakeAPI, *Client) {
+	api := &fakeAPI{calls: make(map[string][]interface{}), responses: responses}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Method + " " + r.URL.EscapedPath()
+		var params interface{}
+		_ = json.NewDecoder(r.Body).Decode(&params)
+		api.lock.Lock()
+		api.calls[key] = append(api.calls[key], params)
+		api.lock.Unlock()
+
+		response, ok := api.responses[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"id": "api.context.404.app_error", "message": "Not found", "status_code": 404}`))
+			return
+		}
+		if errResponse := (apiError{}); json.Unmarshal([]byte(response), &errResponse) == nil && errResponse.Status != 0 {
+			w.WriteHeader(errResponse.Status)
+		}
+		_, _ = w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(Config{URL: server.URL, Token: "token", MaxConcurrentEvents: 1})
+	return api, client
+}
+
+func TestFormatText(t *testing.T) {
+	t.Parallel()
+
+	_, client := newFakeAPI(t, map[string]string{
+		"GET /api/v4/users/dana1":    `{"id": "dana1", "username": "dana"}`,
+		"GET /api/v4/channels/lunch": `{"id": "lunch", "name": "lunch-orders"}`,
+	})
+	ctx := context.Background()
+	assert.Equal(t, "@dana joined the order in ~lunch-orders :eyes: <@gone> [this order](https://wolt.com/group/ABC) at 10:13",
+		client.formatText(ctx, "<@dana1> joined the order in <#lunch> :eyes: <@gone> <https://wolt.com/group/ABC|this order> at "+
+			"<!date^1700000000^{time}|10:13>"))
+
+	assert.Equal(t, "<@dana1>, @nobody and dana@example.com: pay <@dana1>.", client.parseText(ctx, "@dana, @nobody and dana@example.com: pay @Dana."))
+}
+
+func TestSendMessage(t *testing.T) {
+	t.Parallel()
+
+	api, client := newFakeAPI(t, map[string]string{
+		"GET /api/v4/users/me": `{"id": "bot1", "username": "bolt"}`,
+		"POST /api/v4/posts":   `{"id": "post2", "channel_id": "lunch", "root_id": "post1"}`,
+	})
+	selfID, err := client.GetSelfID()
+	require.NoError(t, err)
+	assert.Equal(t, "bot1", selfID)
+
+	id, err := client.SendMessage("lunch", "Joined :eyes:", "post1")
+	require.NoError(t, err)
+	assert.Equal(t, "post2", id)
+	assert.Equal(t, []interface{}{map[string]interface{}{"channel_id": "lunch", "message": "Joined :eyes:", "root_id": "post1"}},
+		api.calls["POST /api/v4/posts"])
+
+	// The replies to a reply are in the thread of its root, as Mattermost's threads have a single level
+	cached, ok := client.messages.Get("", "post2")
+	require.True(t, ok)
+	assert.Equal(t, transport.Message{UserID: "bot1", ThreadID: "post1", Text: "Joined :eyes:"}, *cached)
+	assert.Equal(t, "post1", client.messages.ThreadOf("", "post2"))
+	assert.Equal(t, "post3", client.messages.ThreadOf("", "post3"))
+}
+
+func TestSendDirectMessage(t *testing.T) {
+	t.Parallel()
+
+	api, client := newFakeAPI(t, map[string]string{
+		"GET /api/v4/users/me":         `{"id": "bot1", "username": "bolt"}`,
+		"GET /api/v4/users/dana1":      `{"id": "dana1", "username": "dana"}`,
+		"POST /api/v4/channels/direct": `{"id": "dm1", "type": "D"}`,
+		"POST /api/v4/posts":           `{"id": "post1", "channel_id": "dm1"}`,
+	})
+	_, err := client.GetSelfID()
+	require.NoError(t, err)
+
+	_, err = client.SendMessage("dana1", "You owe 30 NIS", "")
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{[]interface{}{"bot1", "dana1"}}, api.calls["POST /api/v4/channels/direct"])
+	_, err = client.SendMessage("lunch", "Joined", "")
+	require.NoError(t, err)
+
+	// The DM channel is kept, and so are the receivers which aren't users
+	_, err = client.SendMessage("dana1", "Reminder", "")
+	require.NoError(t, err)
+	_, err = client.SendMessage("lunch", "Rates", "")
+	require.NoError(t, err)
+	assert.Len(t, api.calls["POST /api/v4/channels/direct"], 1)
+	assert.Len(t, api.calls["GET /api/v4/users/lunch"], 1)
+	channels := make([]interface{}, 0)
+	for _, params := range api.calls["POST /api/v4/posts"] {
+		channels = append(channels, params.(map[string]interface{})["channel_id"])
+	}
+	assert.Equal(t, []interface{}{"dm1", "lunch", "dm1", "lunch"}, channels)
+	assert.Equal(t, "dana1", client.receiverOf("dm1"))
+	assert.Equal(t, "lunch", client.receiverOf("lunch"))
+}
+
+func TestTransportError(t *testing.T) {
+	t.Parallel()
+
+	_, client := newFakeAPI(t, map[string]string{
+		"POST /api/v4/posts": `{"id": "api.post.create_post.channel_not_found", "message": "Forbidden", "status_code": 403}`,
+	})
+	_, err := client.SendMessage("lunch", "hello", "")
+	assert.True(t, errors.Is(err, service.ErrChannelUnavailable))
+
+	_, client = newFakeAPI(t, map[string]string{
+		"POST /api/v4/posts": `{"id": "api.post.create_post.message.app_error", "message": "Too long", "status_code": 400}`,
+	})
+	_, err = client.SendMessage("lunch", "hello", "")
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, service.ErrChannelUnavailable))
+}
+
+func TestReactionRequest(t *testing.T) {
+	t.Parallel()
+
+	_, client := newFakeAPI(t, map[string]string{
+		"GET /api/v4/posts/post9":         `{"id": "post9", "channel_id": "lunch", "user_id": "bot1", "message": "Order of Pizza is done, @dana owes 30 NIS"}`,
+		"GET /api/v4/users/username/dana": `{"id": "dana1", "username": "dana"}`,
+	})
+	client.selfID = "bot1"
+	client.messages.Add("", "post2", transport.Message{UserID: "bot1", ThreadID: "post1", Text: "Order of Pizza is done, <@dana1> owes 30 NIS"})
+
+	req, ok := client.reactionRequest(&reaction{UserID: "dana1", PostID: "post2", EmojiName: "money_mouth_face", ChannelID: "lunch"})
+	require.True(t, ok)
+	assert.Equal(t, service.ReactionAddRequest{
+		Reaction:      "money_mouth_face",
+		FromUserID:    "dana1",
+		Channel:       "lunch",
+		MessageUserID: "bot1",
+		MessageID:     "post2",
+		MessageText:   "Order of Pizza is done, <@dana1> owes 30 NIS",
+	}, req)
+
+	req, ok = client.reactionRequest(&reaction{UserID: "dana1", PostID: "post9", EmojiName: "money_mouth_face", ChannelID: "lunch"})
+	require.True(t, ok, "posts which aren't cached are read from Mattermost")
+	assert.Equal(t, "Order of Pizza is done, <@dana1> owes 30 NIS", req.MessageText)
+
+	_, ok = client.reactionRequest(&reaction{UserID: "bot1", PostID: "post2", EmojiName: "eyes", ChannelID: "lunch"})
+	assert.False(t, ok, "the bot's own reactions are ignored")
+}
+
+func TestHandleLinkPost(t *testing.T) {
+	t.Parallel()
+
+	_, client := newFakeAPI(t, map[string]string{
+		"GET /api/v4/users/username/dana": `{"id": "dana1", "username": "dana"}`,
+	})
+	client.selfID = "bot1"
+	bot := &MattermostBot{Client: client, Bot: transport.NewBot(nil, nil, 1)}
+	var got service.LinksRequest
+	bot.SetLinkHandler(func(req service.LinksRequest) (string, error) {
+		got = req
+		return "", nil
+	})
+
+	data := map[string]json.RawMessage{
+		"channel_type": json.RawMessage(`"O"`),
+		"post":         json.RawMessage(`"{\"id\": \"post1\", \"channel_id\": \"lunch\", \"user_id\": \"dana1\", \"message\": \"@dana join https://wolt.com/en/isr/tel-aviv/venue/pizza\"}"`),
+	}
+	require.NoError(t, bot.handleEvent(&event{Event: "posted", Data: data}))
+	assert.Equal(t, service.LinksRequest{
+		Links:     []service.Link{{Domain: "wolt.com", URL: "https://wolt.com/en/isr/tel-aviv/venue/pizza"}},
+		MessageID: "post1",
+		Channel:   "lunch",
+		Text:      "<@dana1> join https://wolt.com/en/isr/tel-aviv/venue/pizza",
+		UserID:    "dana1",
+	}, got)
+
+	got = service.LinksRequest{}
+	data["post"] = json.RawMessage(`"{\"id\": \"post2\", \"channel_id\": \"lunch\", \"user_id\": \"bot1\", \"message\": \"https://wolt.com/group/ABC\"}"`)
+	require.NoError(t, bot.handleEvent(&event{Event: "posted", Data: data}))
+	assert.Empty(t, got.Links, "the bot's own posts are ignored")
+}
@@ -0,0 +1,1259 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/shlex"
+	"github.com/oriser/bolt/order"
+	"github.com/oriser/bolt/service"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/slack-go/slack"
+)
+
+const boltCommandUsage = "USAGE: /bolt search <query>\n" +
+	"Query terms: `venue:<name>`, `participant:<name>`, `#<tag>`, `amount:<min>-<max>`, `><amount>`, `<<amount>` or any free text\n" +
+	"Your outstanding debts: /bolt debts, or just the debts between you and someone: /bolt owe @<user>\n" +
+	"The orders of the channel: /bolt orders [today | yesterday]\n" +
+	"Today's orders of the channel with their status, totals and who still owes: /bolt today\n" +
+	"The latest orders of the channel or of someone, with their venue, total, host and everyone's amount: /bolt history [@<user> | channel] [<count>]\n" +
+	"Who owes whom in the channel, with mutual debts netted: /bolt balances\n" +
+	"The channel's spending in a month, with the top spenders, the total per venue and the orders hosted by each person: /bolt report <YYYY-MM>\n" +
+	"Who hosts the channel's orders and who only joins them: /bolt leaderboard [<days>]\n" +
+	"The venues the channel orders from the most, and one you haven't ordered from for a while: /bolt venues [<days>]\n" +
+	"Get a CSV of the channel's orders and debts in a month or a range of days, for reconciling or an expense tool: /bolt export [<YYYY-MM> | <from YYYY-MM-DD> <to YYYY-MM-DD>]\n" +
+	"Monthly insights about your meals: /bolt insights [on | off]\n" +
+	"Stop or resume your debts reminders: /bolt reminders [off | on]\n" +
+	"Get a DM with your amount, the host to pay and how they prefer to be paid for every order you owe for: /bolt dms [on | off]\n" +
+	"Keep your amount out of the rates messages and get it in a DM instead: /bolt private [on | off]\n" +
+	"Get your reminders, receipts and insights together once a day at the given hour (0-23): /bolt digest [<hour> | off]\n" +
+	"Traveling? Get your debts reminders in your currency as well: /bolt abroad [<currency> | off]\n" +
+	"The payment apps you use, in the order you prefer them (Bit, Paybox, Pepper pay, Revolut, Bank transfer): /bolt payments [<method>, ... | off]\n" +
+	"Hosts paid by bank transfer, the account shown masked in the rates messages and in full only in a DM: /bolt bank [<IBAN> <account holder> | off]\n" +
+	"Bolt doesn't recognize your Wolt name? /bolt register <wolt name>\n" +
+	"Hosts, for participants Bolt couldn't match to a user: /bolt link \"<wolt name>\" @<user>\n" +
+	"Hosts stepping away before the delivery, let someone else cancel the debts of your orders and link their participants: /bolt cohost [@<user> | off]\n" +
+	"Hosts, to forgive a debt of your order, change its amount or move it to someone else: /bolt adjust <order ID> [forgive @<user> | amount @<user> <amount> | reassign \"<wolt name>\" @<user>]\n" +
+	"Get everything Bolt stores about you in a DM: /bolt my-data\n" +
+	"See the configuration in effect for the channel: /bolt config show\n" +
+	"While a group order is still open, post what everyone would pay from the current carts (in the order's channel): /bolt preview <group ID or link>\n" +
+	"Hosts, to hurry the participants who didn't mark ready yet before sending the order: /bolt nudge <group ID or link>\n" +
+	"Hosts, when the group order was restarted or the wrong link was posted, stop tracking it and remove its debts: /bolt cancel <group ID or link>\n" +
+	"Disputing the rates of an order? See them as they were published and what they were computed from, in the order's channel: /bolt snapshot <group ID or link>\n" +
+	"Get a link to the order's live status and amounts, for guests who aren't in the workspace, in the order's channel: /bolt statuslink <group ID or link>\n" +
+	"Choosing a venue? See its delivery rate to the office, delivery time and minimum order: /bolt estimate <venue link or slug>\n" +
+	"How much is it? The current price and options of a menu item: /bolt price <venue link or slug> <item>\n" +
+	"Treasurers: /bolt treasury [settle <debt ID> | export]\n" +
+	"Admins: /bolt blacklist [add \"<venue>\" <reason> | remove \"<venue>\"]\n" +
+	"Admins, for slow venues like catering, to see or override how long their deliveries are tracked and how often they're checked: /bolt venue-timeout \"<venue>\" [<timeout> [<check interval>] | auto]\n" +
+	"Admins, to override the cutoff hour, weekly schedule, timezone, fees split, emojis or expensive venues confirmations in the channel: /bolt config [set <setting> <value> | unset <setting>]\n" +
+	"Admins, for users who left the company: /bolt deactivate @<user> | /bolt reactivate @<user>\n" +
+	"Admins, right after deploying or rotating tokens, check that Bolt can reach Wolt, the store and the channel: /bolt selftest"
+
+// CommandHandler handles `/bolt` sub commands which aren't built in (e.g. commands of external plugins)
+type CommandHandler interface {
+	HasCommand(command string) bool
+	HandleCommand(ctx context.Context, command, args, userID, channel string) (string, error)
+}
+
+// SetCommandHandler sets the handler for `/bolt` sub commands which aren't built in
+func (s *SlackBot) SetCommandHandler(handler CommandHandler) {
+	s.commandHandler = handler
+}
+
+func (s *SlackBot) handleBoltCommand(ctx context.Context, r *http.Request, w http.ResponseWriter) (responseWritten bool, err error) {
+	if err := r.ParseForm(); err != nil {
+		return false, fmt.Errorf("parse form: %w", err)
+	}
+
+	if r.Form.Get("command") != "/bolt" {
+		return false, fmt.Errorf("unknown command %q", r.Form.Get("command"))
+	}
+
+	text := strings.TrimSpace(r.Form.Get("text"))
+	subCommand, args, _ := strings.Cut(text, " ")
+	args = strings.TrimSpace(args)
+	channel := r.Form.Get("channel_id")
+
+	switch {
+	case subCommand == "search":
+		if args == "" {
+			_, _ = w.Write([]byte(boltCommandUsage))
+			return true, fmt.Errorf("bad usage")
+		}
+		return s.handleSearchCommand(ctx, channel, args, w)
+	case subCommand == "debts" && args == "":
+		return s.handleDebtsCommand(ctx, r.Form.Get("user_id"), nil, w)
+	case subCommand == "owe":
+		return s.handleOweCommand(ctx, r.Form.Get("user_id"), args, w)
+	case subCommand == "orders":
+		return s.handleOrdersCommand(ctx, channel, args, w)
+	case subCommand == "history":
+		return s.handleHistoryCommand(ctx, channel, args, w)
+	case subCommand == "today" && args == "":
+		dayOrders, err := s.service.DayView(ctx, channel)
+		if err != nil {
+			_, _ = w.Write([]byte(fmt.Sprintf("Error getting today's orders: %v", err)))
+			return true, err
+		}
+		_, _ = w.Write([]byte(service.BuildDayViewMessage(dayOrders)))
+		return true, nil
+	case subCommand == "balances" && args == "":
+		balances, err := s.service.ChannelBalances(ctx, channel)
+		if err != nil {
+			_, _ = w.Write([]byte(fmt.Sprintf("Error getting the balances: %v", err)))
+			return true, err
+		}
+		_, _ = w.Write([]byte(service.BuildBalancesMessage(balances)))
+		return true, nil
+	case subCommand == "report":
+		month, err := time.Parse("2006-01", args)
+		if err != nil {
+			_, _ = w.Write([]byte(boltCommandUsage))
+			return true, fmt.Errorf("bad usage")
+		}
+		report, err := s.service.MonthlyReport(ctx, channel, month.Year(), month.Month())
+		if err != nil {
+			_, _ = w.Write([]byte(fmt.Sprintf("Error getting the report: %v", err)))
+			return true, err
+		}
+		_, _ = w.Write([]byte(service.BuildMonthlyReportMessage(report)))
+		return true, nil
+	case subCommand == "leaderboard":
+		days := 0
+		if args != "" {
+			if days, err = strconv.Atoi(args); err != nil || days <= 0 {
+				_, _ = w.Write([]byte(boltCommandUsage))
+				return true, fmt.Errorf("bad usage")
+			}
+		}
+		leaderboard, err := s.service.HostingLeaderboard(ctx, channel, days)
+		if err != nil {
+			_, _ = w.Write([]byte(fmt.Sprintf("Error getting the leaderboard: %v", err)))
+			return true, err
+		}
+		_, _ = w.Write([]byte(service.BuildHostingLeaderboardMessage(leaderboard)))
+		return true, nil
+	case subCommand == "venues":
+		days := 0
+		if args != "" {
+			if days, err = strconv.Atoi(args); err != nil || days <= 0 {
+				_, _ = w.Write([]byte(boltCommandUsage))
+				return true, fmt.Errorf("bad usage")
+			}
+		}
+		report, err := s.service.VenueStats(ctx, channel, r.Form.Get("user_id"), days)
+		if err != nil {
+			_, _ = w.Write([]byte(fmt.Sprintf("Error getting the venues: %v", err)))
+			return true, err
+		}
+		_, _ = w.Write([]byte(service.BuildVenuesMessage(report)))
+		return true, nil
+	case subCommand == "export":
+		return s.handleExportCommand(ctx, r.Form.Get("user_id"), channel, args, w)
+	case subCommand == "treasury":
+		return s.handleTreasuryCommand(ctx, r.Form.Get("user_id"), args, w)
+	case subCommand == "insights":
+		return s.handleInsightsCommand(ctx, r.Form.Get("user_id"), args, w)
+	case subCommand == "reminders":
+		return s.handleRemindersCommand(ctx, r.Form.Get("user_id"), args, w)
+	case subCommand == "dms":
+		return s.handleDMsCommand(ctx, r.Form.Get("user_id"), args, w)
+	case subCommand == "private":
+		return s.handlePrivateCommand(ctx, r.Form.Get("user_id"), args, w)
+	case subCommand == "digest":
+		return s.handleDigestCommand(ctx, r.Form.Get("user_id"), args, w)
+	case subCommand == "payments":
+		return s.handlePaymentsCommand(ctx, r.Form.Get("user_id"), args, w)
+	case subCommand == "bank":
+		return s.handleBankCommand(ctx, r.Form.Get("user_id"), args, w)
+	case subCommand == "abroad":
+		return s.handleAbroadCommand(ctx, r.Form.Get("user_id"), args, w)
+	case subCommand == "estimate":
+		if args == "" {
+			_, _ = w.Write([]byte(boltCommandUsage))
+			return true, fmt.Errorf("bad usage")
+		}
+		return s.handleEstimateCommand(ctx, args, w)
+	case subCommand == "price":
+		return s.handlePriceCommand(ctx, args, w)
+	case subCommand == "preview":
+		return s.handlePreviewCommand(channel, args, w)
+	case subCommand == "nudge":
+		return s.handleNudgeCommand(r.Form.Get("user_id"), args, w)
+	case subCommand == "cancel":
+		return s.handleCancelCommand(r.Form.Get("user_id"), args, w)
+	case subCommand == "snapshot":
+		return s.handleSnapshotCommand(ctx, channel, args, w)
+	case subCommand == "statuslink":
+		return s.handleStatusLinkCommand(ctx, r.Form.Get("user_id"), channel, args, w)
+	case subCommand == "config":
+		return s.handleConfigCommand(ctx, r.Form.Get("user_id"), channel, args, w)
+	case subCommand == "register":
+		return s.handleRegisterCommand(ctx, r.Form.Get("user_id"), args, w)
+	case subCommand == "cohost":
+		return s.handleCohostCommand(ctx, r.Form.Get("user_id"), args, w)
+	case subCommand == "adjust":
+		return s.handleAdjustCommand(ctx, r.Form.Get("user_id"), args, w)
+	case subCommand == "link":
+		return s.handleLinkCommand(ctx, r.Form.Get("user_id"), args, w)
+	case subCommand == "my-data":
+		return s.handleMyDataCommand(ctx, r.Form.Get("user_id"), w)
+	case subCommand == "blacklist":
+		return s.handleBlacklistCommand(ctx, r.Form.Get("user_id"), channel, args, w)
+	case subCommand == "venue-timeout":
+		return s.handleVenueTimeoutCommand(ctx, r.Form.Get("user_id"), args, w)
+	case subCommand == "deactivate" || subCommand == "reactivate":
+		return s.handleDeactivateCommand(ctx, r.Form.Get("user_id"), args, subCommand == "deactivate", w)
+	case subCommand == "selftest" && args == "":
+		return s.handleSelfTestCommand(r.Form.Get("user_id"), channel, w)
+	case s.commandHandler != nil && s.commandHandler.HasCommand(subCommand):
+		response, err := s.commandHandler.HandleCommand(ctx, subCommand, args, r.Form.Get("user_id"), channel)
+		if err != nil {
+			_, _ = w.Write([]byte(fmt.Sprintf("Error running %q: %v", subCommand, err)))
+			return true, err
+		}
+		_, _ = w.Write([]byte(response))
+		return true, nil
+	default:
+		_, _ = w.Write([]byte(boltCommandUsage))
+		return true, nil
+	}
+}
+
+func (s *SlackBot) handleSearchCommand(ctx context.Context, channel, query string, w http.ResponseWriter) (responseWritten bool, err error) {
+	orders, err := s.service.SearchOrders(ctx, channel, query)
+	if err != nil {
+		_, _ = w.Write([]byte(fmt.Sprintf("Error searching orders: %v", err)))
+		return true, err
+	}
+	if len(orders) == 0 {
+		_, _ = w.Write([]byte("No orders found"))
+		return true, nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Found %d orders:\n", len(orders)))
+	for _, o := range orders {
+		sb.WriteString(s.formatSearchResult(o))
+		sb.WriteString("\n")
+	}
+	_, _ = w.Write([]byte(sb.String()))
+	return true, nil
+}
+
+func (s *SlackBot) formatSearchResult(o *order.Order) string {
+	result := fmt.Sprintf("%s - %s (%.2f %s, %d participants)", o.CreatedAt.Format("2006-01-02"), o.VenueName, o.TotalAmount(),
+		service.CurrencyUnit(o.Currency), len(o.Participants))
+	if len(o.Tags) > 0 {
+		result += " #" + strings.Join(o.Tags, " #")
+	}
+	if o.ExternalRef != "" {
+		result += " ref " + o.ExternalRef
+	}
+	if o.MessageID == "" {
+		return result
+	}
+
+	permalink, err := s.GetPermalink(&slack.PermalinkParameters{Channel: o.Receiver, Ts: o.MessageID})
+	if err != nil {
+		log.Printf("Error getting permalink for order %s: %v\n", o.ID, err)
+		return result
+	}
+	return fmt.Sprintf("<%s|%s>", permalink, result)
+}
+
+func (s *SlackBot) handleDebtsCommand(ctx context.Context, userID string, with *slack.User, w http.ResponseWriter) (responseWritten bool, err error) {
+	withID := ""
+	if with != nil {
+		withID = with.ID
+	}
+	debts, err := s.service.UserDebts(ctx, userID, withID)
+	if err != nil {
+		_, _ = w.Write([]byte(fmt.Sprintf("Error getting your debts: %v", err)))
+		return true, err
+	}
+	if len(debts.Owes) == 0 && len(debts.Owed) == 0 {
+		if with != nil {
+			_, _ = w.Write([]byte(fmt.Sprintf("You and <@%s> don't owe each other anything :tada:", with.ID)))
+		} else {
+			_, _ = w.Write([]byte("You don't have outstanding debts :tada:"))
+		}
+		return true, nil
+	}
+	_, _ = w.Write([]byte(formatUserDebts(debts)))
+	return true, nil
+}
+
+// debtUserMention mentions the user of a debt, or shows its ID if the user can't be found
+func debtUserMention(user *userDomain.User, id string) string {
+	if user == nil {
+		return id
+	}
+	return fmt.Sprintf("<@%s>", user.TransportID)
+}
+
+func formatUserDebts(debts *service.UserDebts) string {
+	var sb strings.Builder
+	if len(debts.Owes) > 0 {
+		sb.WriteString(fmt.Sprintf("*You owe %s:*\n", service.FormatDebtsTotal(debts.Owes)))
+		for _, d := range debts.Owes {
+			sb.WriteString(fmt.Sprintf("%.2f to %s for Wolt order ID %s in <#%s> (%s)\n", d.Debt.Amount, debtUserMention(d.Lender, d.Debt.LenderID),
+				d.Debt.OrderID, d.Debt.InitiatedTransportID, d.Debt.CreatedAt.Format("2006-01-02")))
+		}
+	}
+	if len(debts.Owed) > 0 {
+		sb.WriteString(fmt.Sprintf("*You're owed %s:*\n", service.FormatDebtsTotal(debts.Owed)))
+		for _, d := range debts.Owed {
+			sb.WriteString(fmt.Sprintf("%.2f from %s for Wolt order ID %s in <#%s> (%s)\n", d.Debt.Amount, debtUserMention(d.Borrower, d.Debt.BorrowerID),
+				d.Debt.OrderID, d.Debt.InitiatedTransportID, d.Debt.CreatedAt.Format("2006-01-02")))
+		}
+	}
+	return sb.String()
+}
+
+func (s *SlackBot) handleOweCommand(ctx context.Context, userID, args string, w http.ResponseWriter) (responseWritten bool, err error) {
+	if !strings.HasPrefix(args, "@") || strings.Contains(args, " ") {
+		_, _ = w.Write([]byte(boltCommandUsage))
+		return true, fmt.Errorf("bad usage")
+	}
+	user, err := s.getUserByUserName(ctx, args[1:])
+	if err != nil {
+		if isUserNotFound(err) {
+			_, _ = w.Write([]byte(err.Error()))
+			return true, err
+		}
+		return false, fmt.Errorf("getUserByUserName: %w", err)
+	}
+	return s.handleDebtsCommand(ctx, userID, &user, w)
+}
+
+func (s *SlackBot) handleOrdersCommand(ctx context.Context, channel, args string, w http.ResponseWriter) (responseWritten bool, err error) {
+	daysAgo := 0
+	switch args {
+	case "", "today":
+	case "yesterday":
+		daysAgo = 1
+	default:
+		_, _ = w.Write([]byte(boltCommandUsage))
+		return true, fmt.Errorf("bad usage")
+	}
+
+	orders, err := s.service.OrdersOfDay(ctx, channel, daysAgo)
+	if err != nil {
+		_, _ = w.Write([]byte(fmt.Sprintf("Error listing orders: %v", err)))
+		return true, err
+	}
+	var sb strings.Builder
+	for _, activeOrder := range s.service.ActiveOrders() {
+		if activeOrder.Channel == channel && daysAgo == 0 {
+			sb.WriteString(fmt.Sprintf("%s - %s (%s)\n", activeOrder.ID, activeOrder.VenueName, activeOrder.Status()))
+		}
+	}
+	for _, o := range orders {
+		sb.WriteString(s.formatSearchResult(o))
+		sb.WriteString("\n")
+	}
+	if sb.Len() == 0 {
+		_, _ = w.Write([]byte("No orders found"))
+		return true, nil
+	}
+	_, _ = w.Write([]byte(sb.String()))
+	return true, nil
+}
+
+// handleHistoryCommand posts the latest orders of the channel, or of a user in any channel, with everyone's amount in the thread of the
+// message
+func (s *SlackBot) handleHistoryCommand(ctx context.Context, channel, args string, w http.ResponseWriter) (responseWritten bool, err error) {
+	transportID, subject, count := "", fmt.Sprintf("<#%s>", channel), 0
+	for _, arg := range strings.Fields(args) {
+		switch {
+		case arg == "channel":
+		case strings.HasPrefix(arg, "@") && transportID == "":
+			user, err := s.getUserByUserName(ctx, arg[1:])
+			if err != nil {
+				if isUserNotFound(err) {
+					_, _ = w.Write([]byte(err.Error()))
+					return true, err
+				}
+				return false, fmt.Errorf("getUserByUserName: %w", err)
+			}
+			transportID, subject = user.ID, fmt.Sprintf("<@%s>", user.ID)
+		default:
+			if count, err = strconv.Atoi(arg); err != nil || count <= 0 {
+				_, _ = w.Write([]byte(boltCommandUsage))
+				return true, fmt.Errorf("bad usage")
+			}
+		}
+	}
+
+	history, err := s.service.OrderHistory(ctx, channel, transportID, count)
+	if err != nil {
+		_, _ = w.Write([]byte(fmt.Sprintf("Error getting the order history: %v", err)))
+		return true, err
+	}
+	summary, details := service.BuildHistoryMessage(history, subject)
+	if details == "" {
+		_, _ = w.Write([]byte(summary))
+		return true, nil
+	}
+	_, ts, err := s.PostMessageContext(ctx, channel, slack.MsgOptionText(summary, false))
+	if err != nil {
+		// Bolt may not be in the channel, so the history is only shown to the user
+		_, _ = w.Write([]byte(summary + "\n" + details))
+		return true, nil
+	}
+	if _, _, err = s.PostMessageContext(ctx, channel, slack.MsgOptionText(details, false), slack.MsgOptionTS(ts)); err != nil {
+		return false, fmt.Errorf("post history details: %w", err)
+	}
+	return false, nil
+}
+
+// parseExportRange parses the range of /bolt export, either a month or the first and last days of the range. It's the current month
+// if args is empty.
+func parseExportRange(args string, now time.Time) (from, to time.Time, err error) {
+	fields := strings.Fields(args)
+	switch len(fields) {
+	case 0:
+		from = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return from, from.AddDate(0, 1, -1), nil
+	case 1:
+		if from, err = time.Parse("2006-01", fields[0]); err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		return from, from.AddDate(0, 1, -1), nil
+	case 2:
+		if from, err = time.Parse("2006-01-02", fields[0]); err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		if to, err = time.Parse("2006-01-02", fields[1]); err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		return from, to, nil
+	default:
+		return time.Time{}, time.Time{}, fmt.Errorf("too many arguments")
+	}
+}
+
+func (s *SlackBot) handleExportCommand(ctx context.Context, userID, channel, args string, w http.ResponseWriter) (responseWritten bool, err error) {
+	from, to, err := parseExportRange(args, time.Now())
+	if err != nil {
+		_, _ = w.Write([]byte(boltCommandUsage))
+		return true, fmt.Errorf("bad usage")
+	}
+	export, err := s.service.ChannelExport(ctx, channel, from, to)
+	if err != nil {
+		_, _ = w.Write([]byte(fmt.Sprintf("Error exporting the channel: %v", err)))
+		return true, err
+	}
+	var csvData strings.Builder
+	if err := export.WriteCSV(&csvData); err != nil {
+		return false, fmt.Errorf("write CSV: %w", err)
+	}
+
+	dm, _, _, err := s.OpenConversationContext(ctx, &slack.OpenConversationParameters{Users: []string{userID}})
+	if err != nil {
+		_, _ = w.Write([]byte(fmt.Sprintf("Error opening a DM with you: %v", err)))
+		return true, fmt.Errorf("open conversation: %w", err)
+	}
+	fromDate, toDate := from.Format("2006-01-02"), to.Format("2006-01-02")
+	file := slack.UploadFileV2Parameters{
+		Filename: fmt.Sprintf("bolt-export-%s-%s-%s.csv", channel, fromDate, toDate),
+		Title:    fmt.Sprintf("Bolt orders and debts of %s to %s", fromDate, toDate),
+		Content:  csvData.String(),
+		FileSize: csvData.Len(),
+		InitialComment: fmt.Sprintf("Here are the %d orders and %d debts of <#%s> from %s to %s", len(export.Orders), len(export.Debts),
+			channel, fromDate, toDate),
+		Channel: dm.ID,
+	}
+	if _, err := s.UploadFileV2Context(ctx, file); err != nil {
+		_, _ = w.Write([]byte(fmt.Sprintf("Error sending you the export: %v", err)))
+		return true, fmt.Errorf("upload %s: %w", file.Filename, err)
+	}
+	_, _ = w.Write([]byte("I sent you a DM with the orders and debts of the channel"))
+	return true, nil
+}
+
+func (s *SlackBot) handleTreasuryCommand(ctx context.Context, userID, args string, w http.ResponseWriter) (responseWritten bool, err error) {
+	if !s.service.IsTreasurer(userID) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("Unauthorized"))
+		return true, fmt.Errorf("unauthorized")
+	}
+
+	action, actionArgs, _ := strings.Cut(args, " ")
+	switch action {
+	case "settle":
+		debt, err := s.service.SettleDebt(ctx, userID, strings.TrimSpace(actionArgs))
+		if err != nil {
+			_, _ = w.Write([]byte(fmt.Sprintf("Error settling debt: %v", err)))
+			return true, err
+		}
+		_, _ = w.Write([]byte(fmt.Sprintf("OK, I settled the debt of %s from <@%s> to <@%s> for Wolt order ID %s",
+			service.FormatAmount(debt.Amount, debt.Currency), debt.BorrowerID, debt.LenderID, debt.OrderID)))
+		return true, nil
+	case "export":
+		report, err := s.service.TreasuryReport(ctx)
+		if err != nil {
+			_, _ = w.Write([]byte(fmt.Sprintf("Error getting treasury report: %v", err)))
+			return true, err
+		}
+		var sb strings.Builder
+		if err := report.WriteCSV(&sb); err != nil {
+			return false, fmt.Errorf("write CSV: %w", err)
+		}
+		_, _ = w.Write([]byte("```\n" + sb.String() + "```"))
+		return true, nil
+	case "":
+		report, err := s.service.TreasuryReport(ctx)
+		if err != nil {
+			_, _ = w.Write([]byte(fmt.Sprintf("Error getting treasury report: %v", err)))
+			return true, err
+		}
+		_, _ = w.Write([]byte(formatTreasuryReport(report)))
+		return true, nil
+	default:
+		_, _ = w.Write([]byte(boltCommandUsage))
+		return true, nil
+	}
+}
+
+func formatTreasuryReport(report service.TreasuryReport) string {
+	if len(report.Debts) == 0 {
+		return "There are no outstanding debts :tada:"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("*%d outstanding debts, %s in total*\n", len(report.Debts), service.FormatDebtsTotal(report.Debts)))
+	sb.WriteString("\nOwed to:\n")
+	for _, total := range report.TotalByLender() {
+		sb.WriteString(fmt.Sprintf("<@%s>: %.2f (%d debts)\n", total.LenderID, total.Amount, total.DebtsCount))
+	}
+
+	sb.WriteString("\nDebts:\n")
+	for _, d := range report.Debts {
+		sb.WriteString(fmt.Sprintf("`%s` <@%s> owes <@%s> %.2f for Wolt order ID %s in <#%s> (%s)\n",
+			shortDebtID(d.Debt.ID), d.Debt.BorrowerID, d.Debt.LenderID, d.Debt.Amount, d.Debt.OrderID, d.Debt.InitiatedTransportID,
+			d.Debt.CreatedAt.Format("2006-01-02")))
+	}
+	sb.WriteString("\nSettle a debt with `/bolt treasury settle <debt ID>`, or export all debts with `/bolt treasury export`")
+	return sb.String()
+}
+
+// shortDebtID returns a prefix of the debt ID, which is enough for settling it
+func shortDebtID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+func (s *SlackBot) handleInsightsCommand(ctx context.Context, userID, args string, w http.ResponseWriter) (responseWritten bool, err error) {
+	if args != "on" && args != "off" {
+		_, _ = w.Write([]byte(boltCommandUsage))
+		return true, nil
+	}
+	if err := s.service.SetInsightsSubscription(ctx, userID, args == "on"); err != nil {
+		_, _ = w.Write([]byte(fmt.Sprintf("Error setting insights: %v", err)))
+		return true, err
+	}
+	if args == "on" {
+		_, _ = w.Write([]byte("OK, I'll send you insights about your meals on the first day of every month"))
+	} else {
+		_, _ = w.Write([]byte("OK, I won't send you insights anymore"))
+	}
+	return true, nil
+}
+
+func (s *SlackBot) handleRemindersCommand(ctx context.Context, userID, args string, w http.ResponseWriter) (responseWritten bool, err error) {
+	if args != "on" && args != "off" {
+		_, _ = w.Write([]byte(boltCommandUsage))
+		return true, nil
+	}
+	if err := s.service.SetRemindersOptOut(ctx, userID, args == "off"); err != nil {
+		_, _ = w.Write([]byte(fmt.Sprintf("Error setting reminders: %v", err)))
+		return true, err
+	}
+	if args == "off" {
+		_, _ = w.Write([]byte("OK, I won't remind you about your debts anymore. Please don't forget to pay them :pray:"))
+	} else {
+		_, _ = w.Write([]byte("OK, I'll remind you about your debts again"))
+	}
+	return true, nil
+}
+
+func (s *SlackBot) handleDMsCommand(ctx context.Context, userID, args string, w http.ResponseWriter) (responseWritten bool, err error) {
+	if args != "on" && args != "off" {
+		_, _ = w.Write([]byte(boltCommandUsage))
+		return true, nil
+	}
+	if err := s.service.SetDebtDMs(ctx, userID, args == "on"); err != nil {
+		_, _ = w.Write([]byte(fmt.Sprintf("Error setting debt DMs: %v", err)))
+		return true, err
+	}
+	if args == "on" {
+		_, _ = w.Write([]byte("OK, I'll DM you your amount of every order you owe for"))
+	} else {
+		_, _ = w.Write([]byte("OK, I won't DM you your amounts anymore, you'll find them in the rates messages"))
+	}
+	return true, nil
+}
+
+func (s *SlackBot) handlePrivateCommand(ctx context.Context, userID, args string, w http.ResponseWriter) (responseWritten bool, err error) {
+	if args != "on" && args != "off" {
+		_, _ = w.Write([]byte(boltCommandUsage))
+		return true, nil
+	}
+	if err := s.service.SetPrivateAmounts(ctx, userID, args == "on"); err != nil {
+		_, _ = w.Write([]byte(fmt.Sprintf("Error setting private amounts: %v", err)))
+		return true, err
+	}
+	if args == "on" {
+		_, _ = w.Write([]byte("OK, your amounts won't show in the rates messages, I'll DM them to you instead"))
+	} else {
+		_, _ = w.Write([]byte("OK, your amounts will show in the rates messages again"))
+	}
+	return true, nil
+}
+
+func (s *SlackBot) handleDigestCommand(ctx context.Context, userID, args string, w http.ResponseWriter) (responseWritten bool, err error) {
+	hour := -1
+	if args != "off" {
+		if hour, err = strconv.Atoi(args); err != nil {
+			_, _ = w.Write([]byte(boltCommandUsage))
+			return true, nil
+		}
+	}
+	if err := s.service.SetDigest(ctx, userID, hour); err != nil {
+		_, _ = w.Write([]byte(fmt.Sprintf("Error setting digest: %v", err)))
+		return true, err
+	}
+	if hour < 0 {
+		_, _ = w.Write([]byte("OK, I'll send you your notifications right away again"))
+	} else {
+		_, _ = w.Write([]byte(fmt.Sprintf("OK, I'll send you your reminders, receipts and insights together every day at %d:00", hour)))
+	}
+	return true, nil
+}
+
+func (s *SlackBot) handlePaymentsCommand(ctx context.Context, userID, args string, w http.ResponseWriter) (responseWritten bool, err error) {
+	if args == "" {
+		methods, err := s.service.PaymentMethods(ctx, userID)
+		if err != nil {
+			_, _ = w.Write([]byte(fmt.Sprintf("Error getting your payment methods: %v", err)))
+			return true, err
+		}
+		if len(methods) == 0 {
+			_, _ = w.Write([]byte("You didn't register any payment methods, register them with /bolt payments <method>, ..."))
+			return true, nil
+		}
+		_, _ = w.Write([]byte(fmt.Sprintf("Your payment methods: %s", paymentMethodNames(methods))))
+		return true, nil
+	}
+
+	var names []string
+	if args != "off" {
+		names = strings.Split(args, ",")
+	}
+	methods, err := s.service.SetPaymentMethods(ctx, userID, names)
+	if err != nil {
+		_, _ = w.Write([]byte(fmt.Sprintf("Error setting your payment methods: %v", err)))
+		return true, err
+	}
+	if len(methods) == 0 {
+		_, _ = w.Write([]byte("OK, I removed your payment methods"))
+	} else {
+		_, _ = w.Write([]byte(fmt.Sprintf("OK, your payment methods are %s. I'll point out the one the host uses as well in the rates messages", paymentMethodNames(methods))))
+	}
+	return true, nil
+}
+
+func (s *SlackBot) handleBankCommand(ctx context.Context, userID, args string, w http.ResponseWriter) (responseWritten bool, err error) {
+	if args == "" {
+		account, err := s.service.BankAccount(ctx, userID)
+		if err != nil {
+			_, _ = w.Write([]byte(fmt.Sprintf("Error getting your bank account: %v", err)))
+			return true, err
+		}
+		if account == nil {
+			_, _ = w.Write([]byte("You didn't register a bank account, register it with /bolt bank <IBAN> <account holder>"))
+			return true, nil
+		}
+		_, _ = w.Write([]byte(fmt.Sprintf("Your bank account: %s, %s", account.Holder, account.Formatted())))
+		return true, nil
+	}
+
+	iban, holder := "", ""
+	if args != "off" {
+		var ok bool
+		if iban, holder, ok = strings.Cut(args, " "); !ok {
+			_, _ = w.Write([]byte(boltCommandUsage))
+			return true, fmt.Errorf("bad usage")
+		}
+	}
+	account, err := s.service.SetBankAccount(ctx, userID, iban, holder)
+	if err != nil {
+		_, _ = w.Write([]byte(fmt.Sprintf("Error setting your bank account: %v", err)))
+		return true, err
+	}
+	if account == nil {
+		_, _ = w.Write([]byte("OK, I removed your bank account"))
+	} else {
+		_, _ = w.Write([]byte(fmt.Sprintf("OK, your bank account is %s. The rates messages show it as %s when you prefer bank transfers "+
+			"(/bolt payments), with a button to get it in full in a DM", account.Formatted(), account.Masked())))
+	}
+	return true, nil
+}
+
+func paymentMethodNames(methods []userDomain.PaymentMethod) string {
+	names := make([]string, len(methods))
+	for i, method := range methods {
+		names[i] = method.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+func (s *SlackBot) handleAbroadCommand(ctx context.Context, userID, args string, w http.ResponseWriter) (responseWritten bool, err error) {
+	if args == "" {
+		_, _ = w.Write([]byte(boltCommandUsage))
+		return true, nil
+	}
+	currency := args
+	if args == "off" {
+		currency = ""
+	}
+	if err := s.service.SetAbroad(ctx, userID, currency); err != nil {
+		_, _ = w.Write([]byte(fmt.Sprintf("Error setting abroad: %v", err)))
+		return true, err
+	}
+	if currency == "" {
+		_, _ = w.Write([]byte("Welcome back! I'll show your debts in their own currency only"))
+	} else {
+		_, _ = w.Write([]byte(fmt.Sprintf("Have a nice trip! I'll show your debts in %s as well (the debts are still in their own currency)", strings.ToUpper(currency))))
+	}
+	return true, nil
+}
+
+func (s *SlackBot) handlePreviewCommand(channel, groupID string, w http.ResponseWriter) (responseWritten bool, err error) {
+	if groupID == "" || strings.Contains(groupID, " ") {
+		_, _ = w.Write([]byte(boltCommandUsage))
+		return true, fmt.Errorf("bad usage")
+	}
+	channel, err = s.service.PreviewSplit(channel, groupID)
+	if err != nil {
+		_, _ = w.Write([]byte(fmt.Sprintf("Error previewing the split: %v", err)))
+		return true, err
+	}
+	_, _ = w.Write([]byte(fmt.Sprintf("I posted the provisional split in the thread of the order in <#%s>", channel)))
+	return true, nil
+}
+
+func (s *SlackBot) handleNudgeCommand(userID, groupID string, w http.ResponseWriter) (responseWritten bool, err error) {
+	if groupID == "" || strings.Contains(groupID, " ") {
+		_, _ = w.Write([]byte(boltCommandUsage))
+		return true, fmt.Errorf("bad usage")
+	}
+	channel, err := s.service.NudgeParticipants(groupID, userID)
+	if err != nil {
+		_, _ = w.Write([]byte(fmt.Sprintf("Error nudging the participants: %v", err)))
+		return true, err
+	}
+	_, _ = w.Write([]byte(fmt.Sprintf("I nudged the participants in the thread of the order in <#%s>", channel)))
+	return true, nil
+}
+
+func (s *SlackBot) handleCancelCommand(userID, groupID string, w http.ResponseWriter) (responseWritten bool, err error) {
+	if groupID == "" || strings.Contains(groupID, " ") {
+		_, _ = w.Write([]byte(boltCommandUsage))
+		return true, fmt.Errorf("bad usage")
+	}
+	channel, err := s.service.CancelTracking(groupID, userID)
+	if err != nil {
+		_, _ = w.Write([]byte(fmt.Sprintf("Error canceling the tracking: %v", err)))
+		return true, err
+	}
+	_, _ = w.Write([]byte(fmt.Sprintf("I stopped tracking the order in <#%s> and removed its debts", channel)))
+	return true, nil
+}
+
+func (s *SlackBot) handleSnapshotCommand(ctx context.Context, channel, groupID string, w http.ResponseWriter) (responseWritten bool, err error) {
+	if groupID == "" || strings.Contains(groupID, " ") {
+		_, _ = w.Write([]byte(boltCommandUsage))
+		return true, fmt.Errorf("bad usage")
+	}
+	snapshot, err := s.service.RatesSnapshot(ctx, channel, groupID)
+	if err != nil {
+		_, _ = w.Write([]byte(fmt.Sprintf("Error getting the rates snapshot: %v", err)))
+		return true, err
+	}
+	_, _ = w.Write([]byte(snapshot))
+	return true, nil
+}
+
+func (s *SlackBot) handleStatusLinkCommand(ctx context.Context, userID, channel, groupID string, w http.ResponseWriter) (responseWritten bool, err error) {
+	if groupID == "" || strings.Contains(groupID, " ") {
+		_, _ = w.Write([]byte(boltCommandUsage))
+		return true, fmt.Errorf("bad usage")
+	}
+	_, admin := s.adminsUserIds[userID]
+	link, err := s.service.StatusPageURL(ctx, groupID, channel, userID, admin)
+	if err != nil {
+		_, _ = w.Write([]byte(fmt.Sprintf("Error getting the status link: %v", err)))
+		return true, err
+	}
+	_, _ = w.Write([]byte(fmt.Sprintf("Anyone with this link can see the status of the order and what everyone pays: %s", link)))
+	return true, nil
+}
+
+func (s *SlackBot) handleConfigCommand(ctx context.Context, userID, channel, args string, w http.ResponseWriter) (responseWritten bool, err error) {
+	action, actionArgs, _ := strings.Cut(args, " ")
+	if action == "show" && actionArgs == "" {
+		_, _ = w.Write([]byte(formatChannelConfig(s.service.ChannelConfig(channel))))
+		return true, nil
+	}
+	if action != "set" && action != "unset" {
+		_, _ = w.Write([]byte(boltCommandUsage))
+		return true, fmt.Errorf("bad usage")
+	}
+	if _, ok := s.adminsUserIds[userID]; !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("Unauthorized"))
+		return true, fmt.Errorf("unauthorized")
+	}
+
+	name, value, _ := strings.Cut(strings.TrimSpace(actionArgs), " ")
+	value = strings.TrimSpace(value)
+	switch {
+	case action == "set" && name != "" && value != "":
+		value, err := s.service.SetChannelSetting(ctx, channel, name, value, userID)
+		if err != nil {
+			_, _ = w.Write([]byte(fmt.Sprintf("Error setting %s: %v", name, err)))
+			return true, err
+		}
+		_, _ = w.Write([]byte(fmt.Sprintf("OK, `%s` is %s in this channel", strings.ToUpper(name), value)))
+		return true, nil
+	case action == "unset" && name != "" && value == "":
+		if err := s.service.RemoveChannelSetting(ctx, channel, name); err != nil {
+			_, _ = w.Write([]byte(fmt.Sprintf("Error unsetting %s: %v", name, err)))
+			return true, err
+		}
+		_, _ = w.Write([]byte(fmt.Sprintf("OK, this channel uses the global `%s` again", strings.ToUpper(name))))
+		return true, nil
+	default:
+		_, _ = w.Write([]byte(fmt.Sprintf("USAGE: /bolt config [show | set <setting> <value> | unset <setting>]\nSettings: %s",
+			strings.Join(service.ChannelSettingNames(), ", "))))
+		return true, fmt.Errorf("bad usage")
+	}
+}
+
+func formatChannelConfig(values []service.ConfigValue) string {
+	var sb strings.Builder
+	sb.WriteString("The configuration in effect for this channel:\n")
+	for _, value := range values {
+		source := "global"
+		if value.Override {
+			source = "channel override"
+		}
+		sb.WriteString(fmt.Sprintf("`%s`: %s _(%s)_\n", value.Name, value.Value, source))
+	}
+	return sb.String()
+}
+
+func (s *SlackBot) handleEstimateCommand(ctx context.Context, venue string, w http.ResponseWriter) (responseWritten bool, err error) {
+	estimate, err := s.service.EstimateVenue(ctx, venue)
+	if err != nil {
+		_, _ = w.Write([]byte(fmt.Sprintf("Error estimating the venue: %v", err)))
+		return true, err
+	}
+	_, _ = w.Write([]byte(formatVenueEstimate(estimate)))
+	return true, nil
+}
+
+func (s *SlackBot) handlePriceCommand(ctx context.Context, args string, w http.ResponseWriter) (responseWritten bool, err error) {
+	splitted, err := shlex.Split(args)
+	if err != nil || len(splitted) < 2 {
+		_, _ = w.Write([]byte(boltCommandUsage))
+		return true, fmt.Errorf("bad usage")
+	}
+	venue, item := splitted[0], strings.Join(splitted[1:], " ")
+	answer, err := s.service.LookupPrice(ctx, venue, item)
+	if err != nil {
+		_, _ = w.Write([]byte(fmt.Sprintf("Error looking up the price: %v", err)))
+		return true, err
+	}
+	_, _ = w.Write([]byte(service.BuildPriceMessage(answer, item)))
+	return true, nil
+}
+
+func formatVenueEstimate(estimate *service.VenueEstimate) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("*%s*", estimate.Venue.Name))
+	if !estimate.Venue.IsDelivering() {
+		sb.WriteString(" (closed for delivery right now)")
+	}
+	sb.WriteString("\n")
+
+	if estimate.DeliveryRate >= 0 {
+		sb.WriteString(fmt.Sprintf("Delivery rate to the office: %d %s\n", estimate.DeliveryRate, service.CurrencyUnit(estimate.Currency)))
+	} else {
+		sb.WriteString("Delivery rate: unknown, as the office location isn't configured\n")
+	}
+	if deliveryEstimate := estimate.Venue.DeliveryEstimate(); deliveryEstimate != "" {
+		sb.WriteString(fmt.Sprintf("Estimated delivery time: %s minutes\n", deliveryEstimate))
+	}
+	if minimum := estimate.Venue.MinimumOrder(); minimum > 0 {
+		sb.WriteString(fmt.Sprintf("Minimum order: %.2f %s\n", minimum, service.CurrencyUnit(estimate.Currency)))
+	}
+	return sb.String()
+}
+
+// handleMyDataCommand sends the user a DM with everything stored about them, as JSON and CSV files
+func (s *SlackBot) handleMyDataCommand(ctx context.Context, userID string, w http.ResponseWriter) (responseWritten bool, err error) {
+	data, err := s.service.UserData(ctx, userID)
+	if err != nil {
+		_, _ = w.Write([]byte(fmt.Sprintf("Error getting your data: %v", err)))
+		return true, err
+	}
+	var jsonData, csvData strings.Builder
+	if err := data.WriteJSON(&jsonData); err != nil {
+		return false, fmt.Errorf("write JSON: %w", err)
+	}
+	if err := data.WriteCSV(&csvData); err != nil {
+		return false, fmt.Errorf("write CSV: %w", err)
+	}
+
+	dm, _, _, err := s.OpenConversationContext(ctx, &slack.OpenConversationParameters{Users: []string{userID}})
+	if err != nil {
+		_, _ = w.Write([]byte(fmt.Sprintf("Error opening a DM with you: %v", err)))
+		return true, fmt.Errorf("open conversation: %w", err)
+	}
+	files := []slack.UploadFileV2Parameters{
+		{
+			Filename: "bolt-my-data.json",
+			Title:    "Your Bolt data",
+			Content:  jsonData.String(),
+			FileSize: jsonData.Len(),
+			InitialComment: fmt.Sprintf("Here is everything I store about you: %d user records, %d debts, %d payments and %d orders",
+				len(data.Users), len(data.Debts), len(data.Payments), len(data.Orders)),
+		},
+		{Filename: "bolt-my-data.csv", Title: "Your Bolt debts, payments and orders", Content: csvData.String(), FileSize: csvData.Len()},
+	}
+	for _, file := range files {
+		file.Channel = dm.ID
+		if _, err := s.UploadFileV2Context(ctx, file); err != nil {
+			_, _ = w.Write([]byte(fmt.Sprintf("Error sending you your data: %v", err)))
+			return true, fmt.Errorf("upload %s: %w", file.Filename, err)
+		}
+	}
+	_, _ = w.Write([]byte("I sent you a DM with everything I store about you"))
+	return true, nil
+}
+
+func (s *SlackBot) handleBlacklistCommand(ctx context.Context, userID, channel, args string, w http.ResponseWriter) (responseWritten bool, err error) {
+	if _, ok := s.adminsUserIds[userID]; !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("Unauthorized"))
+		return true, fmt.Errorf("unauthorized")
+	}
+
+	action, actionArgs, _ := strings.Cut(args, " ")
+	venueName, reason := cutVenueName(strings.TrimSpace(actionArgs))
+	switch {
+	case action == "add" && venueName != "" && reason != "":
+		if err := s.service.BlacklistVenue(ctx, channel, venueName, reason, userID); err != nil {
+			_, _ = w.Write([]byte(fmt.Sprintf("Error blacklisting venue: %v", err)))
+			return true, err
+		}
+		_, _ = w.Write([]byte(fmt.Sprintf("OK, I'll ask for a confirmation before tracking orders from [%s] in this channel", venueName)))
+		return true, nil
+	case action == "remove" && venueName != "":
+		if err := s.service.UnblacklistVenue(ctx, channel, venueName); err != nil {
+			_, _ = w.Write([]byte(fmt.Sprintf("Error removing venue from the blacklist: %v", err)))
+			return true, err
+		}
+		_, _ = w.Write([]byte(fmt.Sprintf("OK, I removed [%s] from the blacklist", venueName)))
+		return true, nil
+	case action == "":
+		venues, err := s.service.BlacklistedVenues(ctx, channel)
+		if err != nil {
+			_, _ = w.Write([]byte(fmt.Sprintf("Error listing blacklisted venues: %v", err)))
+			return true, err
+		}
+		if len(venues) == 0 {
+			_, _ = w.Write([]byte("There are no blacklisted venues in this channel"))
+			return true, nil
+		}
+		var sb strings.Builder
+		sb.WriteString("Blacklisted venues:\n")
+		for _, venue := range venues {
+			sb.WriteString(fmt.Sprintf("[%s] %s (by <@%s> on %s)\n", venue.VenueName, venue.Reason, venue.AddedBy, venue.CreatedAt.Format("2006-01-02")))
+		}
+		_, _ = w.Write([]byte(sb.String()))
+		return true, nil
+	default:
+		_, _ = w.Write([]byte(boltCommandUsage))
+		return true, nil
+	}
+}
+
+func (s *SlackBot) handleVenueTimeoutCommand(ctx context.Context, userID, args string, w http.ResponseWriter) (responseWritten bool, err error) {
+	if _, ok := s.adminsUserIds[userID]; !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("Unauthorized"))
+		return true, fmt.Errorf("unauthorized")
+	}
+
+	venueName, rest := cutVenueName(strings.TrimSpace(args))
+	if venueName == "" {
+		_, _ = w.Write([]byte(boltCommandUsage))
+		return true, nil
+	}
+	if rest != "" {
+		var doneTimeout, checkInterval time.Duration
+		if rest != "auto" {
+			timeoutValue, intervalValue, _ := strings.Cut(rest, " ")
+			if doneTimeout, err = time.ParseDuration(timeoutValue); err != nil || doneTimeout <= 0 {
+				_, _ = w.Write([]byte(fmt.Sprintf("Expected a timeout like 5h but got %q", timeoutValue)))
+				return true, nil
+			}
+			if intervalValue = strings.TrimSpace(intervalValue); intervalValue != "" {
+				if checkInterval, err = time.ParseDuration(intervalValue); err != nil || checkInterval <= 0 {
+					_, _ = w.Write([]byte(fmt.Sprintf("Expected a check interval like 1m but got %q", intervalValue)))
+					return true, nil
+				}
+			}
+		}
+		if err := s.service.SetVenueTimeouts(ctx, venueName, doneTimeout, checkInterval); err != nil {
+			_, _ = w.Write([]byte(fmt.Sprintf("Error setting the timeouts of the venue: %v", err)))
+			return true, err
+		}
+	}
+
+	timeouts, err := s.service.VenueTimeouts(ctx, venueName)
+	if err != nil {
+		_, _ = w.Write([]byte(fmt.Sprintf("Error getting the timeouts of the venue: %v", err)))
+		return true, err
+	}
+	source := "the global ones"
+	switch {
+	case timeouts.Overridden:
+		source = "set by an admin"
+	case timeouts.Learned:
+		source = "learned from its deliveries"
+	}
+	message := fmt.Sprintf("Deliveries from [%s] are tracked for %s and checked every %s (%s)", timeouts.VenueName, timeouts.DoneTimeout,
+		timeouts.StatusCheckInterval, source)
+	if timeouts.Stats != nil && timeouts.Stats.Deliveries > 0 {
+		message += fmt.Sprintf(". Its %d deliveries took %s on average, and %s at most", timeouts.Stats.Deliveries,
+			timeouts.Stats.AverageDelivery.Round(time.Minute), timeouts.Stats.LongestDelivery.Round(time.Minute))
+	}
+	_, _ = w.Write([]byte(message))
+	return true, nil
+}
+
+func (s *SlackBot) handleDeactivateCommand(ctx context.Context, userID, args string, deactivate bool, w http.ResponseWriter) (responseWritten bool, err error) {
+	if _, ok := s.adminsUserIds[userID]; !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("Unauthorized"))
+		return true, fmt.Errorf("unauthorized")
+	}
+	if !strings.HasPrefix(args, "@") || strings.Contains(args, " ") {
+		_, _ = w.Write([]byte(boltCommandUsage))
+		return true, fmt.Errorf("bad usage")
+	}
+
+	user, err := s.getUserByUserName(ctx, args[1:])
+	if err != nil {
+		if isUserNotFound(err) {
+			_, _ = w.Write([]byte(err.Error()))
+			return true, err
+		}
+		return false, fmt.Errorf("getUserByUserName: %w", err)
+	}
+	if _, err := s.service.SetUserDeactivated(ctx, user.ID, deactivate); err != nil {
+		_, _ = w.Write([]byte(fmt.Sprintf("Error updating the user: %v", err)))
+		return true, err
+	}
+	if deactivate {
+		_, _ = w.Write([]byte(fmt.Sprintf("OK, <@%s> is deactivated. I won't match them to new orders or remind them about debts", user.ID)))
+	} else {
+		_, _ = w.Write([]byte(fmt.Sprintf("OK, <@%s> is active again", user.ID)))
+	}
+	return true, nil
+}
+
+// transportUser returns the user of the Slack user, with the Wolt name as its FullName
+func transportUser(woltName string, user slack.User) *userDomain.User {
+	return &userDomain.User{
+		FullName:    woltName,
+		Email:       user.Profile.Email,
+		Phone:       user.Profile.Phone,
+		Timezone:    user.TZ,
+		TransportID: user.ID,
+	}
+}
+
+func (s *SlackBot) handleRegisterCommand(ctx context.Context, userID, woltName string, w http.ResponseWriter) (responseWritten bool, err error) {
+	if woltName == "" {
+		_, _ = w.Write([]byte(boltCommandUsage))
+		return true, fmt.Errorf("bad usage")
+	}
+	user, err := s.GetUserInfoContext(ctx, userID)
+	if err != nil {
+		return false, fmt.Errorf("get user info: %w", err)
+	}
+	if err := s.service.RegisterWoltName(ctx, transportUser(woltName, *user)); err != nil {
+		if errors.Is(err, service.ErrWoltNameTaken) {
+			_, _ = w.Write([]byte(fmt.Sprintf("%q already belongs to someone else. If it's a mistake, please ask an admin to fix it", woltName)))
+			return true, err
+		}
+		_, _ = w.Write([]byte(fmt.Sprintf("Error registering: %v", err)))
+		return true, err
+	}
+	_, _ = w.Write([]byte(fmt.Sprintf("OK, I'll know it's you when %q joins an order", woltName)))
+	return true, nil
+}
+
+func (s *SlackBot) handleLinkCommand(ctx context.Context, userID, args string, w http.ResponseWriter) (responseWritten bool, err error) {
+	splitted, err := shlex.Split(args)
+	if err != nil || len(splitted) != 2 || !strings.HasPrefix(splitted[1], "@") {
+		_, _ = w.Write([]byte(boltCommandUsage))
+		return true, fmt.Errorf("bad usage")
+	}
+	woltName := splitted[0]
+
+	user, err := s.getUserByUserName(ctx, splitted[1][1:])
+	if err != nil {
+		if isUserNotFound(err) {
+			_, _ = w.Write([]byte(err.Error()))
+			return true, err
+		}
+		return false, fmt.Errorf("getUserByUserName: %w", err)
+	}
+	if err := s.service.LinkUnknownParticipant(ctx, userID, transportUser(woltName, user)); err != nil {
+		if errors.Is(err, service.ErrWoltNameTaken) || errors.Is(err, service.ErrNotUnknownParticipant) {
+			_, _ = w.Write([]byte(fmt.Sprintf("I can't link %q: %v", woltName, err)))
+			return true, err
+		}
+		_, _ = w.Write([]byte(fmt.Sprintf("Error linking the user: %v", err)))
+		return true, err
+	}
+	_, _ = w.Write([]byte(fmt.Sprintf("OK, I linked %q to <@%s>", woltName, user.ID)))
+	return true, nil
+}
+
+func (s *SlackBot) handleAdjustCommand(ctx context.Context, userID, args string, w http.ResponseWriter) (responseWritten bool, err error) {
+	splitted, err := shlex.Split(args)
+	if err != nil || len(splitted) < 3 {
+		_, _ = w.Write([]byte(boltCommandUsage))
+		return true, fmt.Errorf("bad usage")
+	}
+	orderID, action := splitted[0], splitted[1]
+
+	var response string
+	switch {
+	case action == "forgive" && len(splitted) == 3 && strings.HasPrefix(splitted[2], "@"):
+		var borrower slack.User
+		if borrower, err = s.getUserByUserName(ctx, splitted[2][1:]); err == nil {
+			err = s.service.ForgiveDebt(ctx, userID, orderID, borrower.ID)
+			response = fmt.Sprintf("OK, I removed <@%s>'s debt for order %s", borrower.ID, orderID)
+		}
+	case action == "amount" && len(splitted) == 4 && strings.HasPrefix(splitted[2], "@"):
+		amount, parseErr := strconv.ParseFloat(splitted[3], 64)
+		if parseErr != nil {
+			_, _ = w.Write([]byte(fmt.Sprintf("Bad amount %q", splitted[3])))
+			return true, parseErr
+		}
+		var borrower slack.User
+		if borrower, err = s.getUserByUserName(ctx, splitted[2][1:]); err == nil {
+			err = s.service.EditDebtAmount(ctx, userID, orderID, borrower.ID, amount)
+			response = fmt.Sprintf("OK, <@%s> owes %.2f for order %s now", borrower.ID, amount, orderID)
+		}
+	case action == "reassign" && len(splitted) == 4 && strings.HasPrefix(splitted[3], "@"):
+		var to slack.User
+		if to, err = s.getUserByUserName(ctx, splitted[3][1:]); err == nil {
+			err = s.service.ReassignDebt(ctx, userID, orderID, splitted[2], to.ID)
+			response = fmt.Sprintf("OK, I moved %q's debt for order %s to <@%s>", splitted[2], orderID, to.ID)
+		}
+	default:
+		_, _ = w.Write([]byte(boltCommandUsage))
+		return true, fmt.Errorf("bad usage")
+	}
+	if err != nil {
+		if isUserNotFound(err) || errors.Is(err, service.ErrNotOrderHost) || errors.Is(err, service.ErrNoDebtToAdjust) {
+			_, _ = w.Write([]byte(fmt.Sprintf("I can't adjust the debt: %v", err)))
+			return true, err
+		}
+		_, _ = w.Write([]byte(fmt.Sprintf("Error adjusting the debt: %v", err)))
+		return true, err
+	}
+	_, _ = w.Write([]byte(response))
+	return true, nil
+}
+
+func (s *SlackBot) handleCohostCommand(ctx context.Context, userID, args string, w http.ResponseWriter) (responseWritten bool, err error) {
+	switch {
+	case args == "":
+		if cohost := s.service.Cohost(userID); cohost != "" {
+			_, _ = w.Write([]byte(fmt.Sprintf("Your co-host is <@%s>", cohost)))
+		} else {
+			_, _ = w.Write([]byte("You have no co-host"))
+		}
+		return true, nil
+	case args == "off":
+		if err := s.service.SetCohost(userID, ""); err != nil {
+			_, _ = w.Write([]byte(fmt.Sprintf("Error removing your co-host: %v", err)))
+			return true, err
+		}
+		_, _ = w.Write([]byte("OK, you have no co-host now"))
+		return true, nil
+	case !strings.HasPrefix(args, "@") || strings.Contains(args, " "):
+		_, _ = w.Write([]byte(boltCommandUsage))
+		return true, fmt.Errorf("bad usage")
+	}
+
+	user, err := s.getUserByUserName(ctx, args[1:])
+	if err != nil {
+		if isUserNotFound(err) {
+			_, _ = w.Write([]byte(err.Error()))
+			return true, err
+		}
+		return false, fmt.Errorf("getUserByUserName: %w", err)
+	}
+	if err := s.service.SetCohost(userID, user.ID); err != nil {
+		_, _ = w.Write([]byte(fmt.Sprintf("Error setting your co-host: %v", err)))
+		return true, err
+	}
+	_, _ = w.Write([]byte(fmt.Sprintf("OK, <@%s> is your co-host until you run /bolt cohost off", user.ID)))
+	return true, nil
+}
+
+// cutVenueName cuts the venue name from the start of the arguments. Venue names with spaces should be quoted.
+func cutVenueName(args string) (venueName, rest string) {
+	if strings.HasPrefix(args, "\"") {
+		if venueName, rest, ok := strings.Cut(args[1:], "\""); ok {
+			return strings.TrimSpace(venueName), strings.TrimSpace(rest)
+		}
+	}
+	venueName, rest, _ = strings.Cut(args, " ")
+	return venueName, strings.TrimSpace(rest)
+}
+
+func (s *SlackBot) handleSelfTestCommand(userID, channel string, w http.ResponseWriter) (responseWritten bool, err error) {
+	if _, ok := s.adminsUserIds[userID]; !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("Unauthorized"))
+		return true, fmt.Errorf("unauthorized")
+	}
+
+	// The checks take longer than Slack waits for the response, so their results are posted in the channel
+	go s.service.RunSelfTest(channel, userID)
+	_, _ = w.Write([]byte("Running the self-test, I'll post its results in the channel"))
+	return true, nil
+}
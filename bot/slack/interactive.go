@@ -0,0 +1,141 @@
+package slack
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/oriser/bolt/service"
+	"github.com/slack-go/slack"
+)
+
+// linkButtonActionID is the action ID of link buttons. Slack calls the interactivity endpoint for them as well, and they're ignored.
+const linkButtonActionID = "open_link"
+
+func buttonElement(button service.MessageButton) *slack.ButtonBlockElement {
+	actionID := button.Action
+	if button.URL != "" {
+		actionID = linkButtonActionID
+	}
+	element := slack.NewButtonBlockElement(actionID, button.Value, slack.NewTextBlockObject(slack.PlainTextType, button.Label, false, false))
+	if button.URL != "" {
+		element = element.WithURL(button.URL)
+	}
+	if button.Danger {
+		element = element.WithStyle(slack.StyleDanger)
+	}
+	return element
+}
+
+func interactiveBlocks(message service.InteractiveMessage) []slack.Block {
+	blocks := make([]slack.Block, 0, len(message.Sections)+1)
+	for _, section := range message.Sections {
+		text := strings.TrimSpace(section.Text)
+		if text == "" {
+			// Slack rejects sections without text
+			continue
+		}
+		var accessory *slack.Accessory
+		if section.Button != nil {
+			accessory = slack.NewAccessory(buttonElement(*section.Button))
+		}
+		blocks = append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, accessory))
+	}
+	if len(message.Buttons) > 0 {
+		elements := make([]slack.BlockElement, len(message.Buttons))
+		for i, button := range message.Buttons {
+			elements[i] = buttonElement(button)
+		}
+		blocks = append(blocks, slack.NewActionBlock("", elements...))
+	}
+	return blocks
+}
+
+func (c *Client) SendInteractiveMessage(receiver string, message service.InteractiveMessage, messageID string) (string, error) {
+	options := []slack.MsgOption{slack.MsgOptionText(message.Text, false), slack.MsgOptionBlocks(interactiveBlocks(message)...)}
+	if messageID != "" {
+		options = append(options, slack.MsgOptionTS(messageID))
+	}
+	_, ts, err := c.PostMessage(receiver, options...)
+	if err != nil {
+		return "", fmt.Errorf("posting interactive message: %w", transportError(receiver, err))
+	}
+	return ts, nil
+}
+
+func (c *Client) EditInteractiveMessage(receiver string, message service.InteractiveMessage, messageID string) error {
+	if messageID == "" {
+		return fmt.Errorf("empty message ID")
+	}
+
+	options := []slack.MsgOption{slack.MsgOptionText(message.Text, false), slack.MsgOptionBlocks(interactiveBlocks(message)...)}
+	if _, _, _, err := c.UpdateMessage(receiver, messageID, options...); err != nil {
+		return fmt.Errorf("editing interactive message %s: %w", messageID, transportError(receiver, err))
+	}
+	return nil
+}
+
+// interactionsEndpoint handles the clicks on the buttons of interactive messages
+func (s *SlackBot) interactionsEndpoint(w http.ResponseWriter, r *http.Request) {
+	body, err := s.verifiedBody(w, r)
+	if err != nil {
+		log.Println("Error reading interaction: ", err)
+		return
+	}
+	// The body was already read for verifying it, so the form is parsed from it
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		log.Println("Error parsing interaction form: ", err)
+		return
+	}
+
+	var callback slack.InteractionCallback
+	if err := json.Unmarshal([]byte(form.Get("payload")), &callback); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		log.Println("Error unmarshalling interaction payload: ", err)
+		return
+	}
+	if callback.Type != slack.InteractionTypeBlockActions {
+		return
+	}
+	if err := s.service.AllowCommand(callback.User.ID); err != nil {
+		go func() {
+			if _, err := s.PostEphemeral(callback.Channel.ID, callback.User.ID, slack.MsgOptionText(err.Error(), false)); err != nil {
+				log.Println("Error responding to rate limited interaction: ", err)
+			}
+		}()
+		return
+	}
+
+	// Slack expects a response within 3 seconds, so the actions are handled in the background
+	for _, action := range callback.ActionCallback.BlockActions {
+		if action.ActionID == linkButtonActionID {
+			continue
+		}
+		go s.handleButtonAction(callback, action)
+	}
+}
+
+func (s *SlackBot) handleButtonAction(callback slack.InteractionCallback, action *slack.BlockAction) {
+	response, err := s.service.HandleButtonAction(service.ButtonActionRequest{
+		Action:     action.ActionID,
+		Value:      action.Value,
+		FromUserID: callback.User.ID,
+		Channel:    callback.Channel.ID,
+		MessageID:  callback.Container.MessageTs,
+	})
+	if err != nil {
+		log.Printf("Error handling button action %s: %v\n", action.ActionID, err)
+		return
+	}
+	if response == "" {
+		return
+	}
+	if _, err := s.PostEphemeral(callback.Channel.ID, callback.User.ID, slack.MsgOptionText(response, false)); err != nil {
+		log.Printf("Error responding to button action %s: %v\n", action.ActionID, err)
+	}
+}
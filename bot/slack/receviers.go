@@ -1,17 +1,22 @@
 package slack
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/google/shlex"
 	"github.com/oriser/bolt/service"
+	"github.com/oriser/bolt/tracing"
+	userDomain "github.com/oriser/bolt/user"
 	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/slackevents"
 )
@@ -30,7 +35,11 @@ func (s *SlackBot) ListenAndServe(ctx context.Context) error {
 	}
 
 	http.HandleFunc("/events-endpoint", s.eventsEndpoint)
+	http.HandleFunc("/interactions", s.interactionsEndpoint)
 	http.HandleFunc("/add-user", func(w http.ResponseWriter, r *http.Request) {
+		if !s.allowCommand(r, w) {
+			return
+		}
 		responseWritten, err := s.handleAddUserCommand(ctx, r, w)
 		if err != nil {
 			log.Printf("handleAddUserCommand: %v\n", err)
@@ -39,11 +48,44 @@ func (s *SlackBot) ListenAndServe(ctx context.Context) error {
 			}
 		}
 	})
+	http.HandleFunc("/bolt", func(w http.ResponseWriter, r *http.Request) {
+		if !s.allowCommand(r, w) {
+			return
+		}
+		responseWritten, err := s.handleBoltCommand(ctx, r, w)
+		if err != nil {
+			log.Printf("handleBoltCommand: %v\n", err)
+			if !responseWritten {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}
+	})
 
 	log.Println("Server listening on port", s.port)
 	return http.ListenAndServe(fmt.Sprintf(":%d", s.port), nil)
 }
 
+// allowCommand verifies the signature of the slash command, responds with a cool-down message to the commands of users who sent too
+// many of them lately, and returns whether the command should be handled
+func (s *SlackBot) allowCommand(r *http.Request, w http.ResponseWriter) bool {
+	body, err := s.verifiedBody(w, r)
+	if err != nil {
+		log.Println("Error verifying command: ", err)
+		return false
+	}
+	// The body was already read for verifying it, so it's put back for parsing the form
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err := r.ParseForm(); err != nil {
+		// The handler of the command fails on it as well and responds
+		return true
+	}
+	if err := s.service.AllowCommand(r.Form.Get("user_id")); err != nil {
+		_, _ = w.Write([]byte(err.Error()))
+		return false
+	}
+	return true
+}
+
 // eventsEndpoint handles all event callbacks from Slack
 func (s *SlackBot) eventsEndpoint(w http.ResponseWriter, r *http.Request) {
 	body, event, err := s.parseMessage(w, r)
@@ -75,20 +117,94 @@ func (s *SlackBot) eventsEndpoint(w http.ResponseWriter, r *http.Request) {
 				w.WriteHeader(http.StatusTooManyRequests)
 			}
 		case *slackevents.LinkSharedEvent:
-			select {
-			case s.linksCh <- ev:
-			case <-time.After(1 * time.Second):
-				w.WriteHeader(http.StatusTooManyRequests)
+			s.queueLinks(w, ev)
+		// Stopping orders notifies the fallback admin channel, so it's done in the background
+		case *slackevents.ChannelArchiveEvent:
+			go s.service.HandleChannelArchived(ev.Channel)
+		case *slackevents.ChannelDeletedEvent:
+			go s.service.HandleChannelArchived(ev.Channel)
+		case *slackevents.MemberLeftChannelEvent:
+			go s.service.HandleMemberLeftChannel(ev.Channel, ev.User)
+		// Only files shared in threads can be proofs of purchase, only replies in threads can be commands like !delivery, and only
+		// shared messages can have links Slack sends no link shared event for, the rest of the messages aren't interesting
+		case *slackevents.MessageEvent:
+			if ev.SubType == "file_share" && ev.ThreadTimeStamp != "" {
+				go func() {
+					if err := s.handleFileShare(ev); err != nil {
+						log.Println("Error handling file share:", err)
+					}
+				}()
+			} else if ev.SubType == "" && ev.BotID == "" && ev.ThreadTimeStamp != "" && service.IsThreadCommand(ev.Text) {
+				go func() {
+					if err := s.handleThreadCommand(ev); err != nil {
+						log.Println("Error handling thread command:", err)
+					}
+				}()
+			} else if linkEvent := sharedMessageLinks(ev); linkEvent != nil {
+				s.queueLinks(w, linkEvent)
+			}
+		case *slackevents.UserChangeEvent:
+			if ev.User.Deleted {
+				go s.service.HandleUserLeft(ev.User.ID)
 			}
 		}
 	}
 }
 
+// queueLinks queues the link event for the links workers
+func (s *SlackBot) queueLinks(w http.ResponseWriter, ev *slackevents.LinkSharedEvent) {
+	// Slack retries the refused events, so the links shared while the orders queue is full are handled once there's room
+	if s.service.OrdersQueueFull() {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+	select {
+	case s.linksCh <- ev:
+	case <-time.After(1 * time.Second):
+		w.WriteHeader(http.StatusTooManyRequests)
+	}
+}
+
+// sharedMessageLinks returns a link event of the Wolt links in the attachments of a message shared or forwarded with "Share
+// message", or nil if it has none. Messages with Wolt links in their own text are skipped, as Slack sends a link shared event for
+// them, whose handling adds the links of the attachments as well.
+func sharedMessageLinks(ev *slackevents.MessageEvent) *slackevents.LinkSharedEvent {
+	if ev.SubType != "" || ev.BotID != "" || len(ev.Attachments) == 0 || len(woltLinks(messageLinks(ev.Text))) > 0 {
+		return nil
+	}
+	links := attachmentLinks(ev.Attachments)
+	if len(links) == 0 {
+		return nil
+	}
+	linkEvent := &slackevents.LinkSharedEvent{Channel: ev.Channel, User: ev.User, MessageTimeStamp: ev.TimeStamp}
+	for _, link := range links {
+		linkEvent.Links = append(linkEvent.Links, slackevents.SharedLinks{Domain: link.Domain, URL: link.URL})
+	}
+	return linkEvent
+}
+
 func (s *SlackBot) parseMessage(w http.ResponseWriter, r *http.Request) ([]byte, slackevents.EventsAPIEvent, error) {
+	body, err := s.verifiedBody(w, r)
+	if err != nil {
+		return body, slackevents.EventsAPIEvent{}, err
+	}
+
+	eventsAPIEvent, err := slackevents.ParseEvent(body, slackevents.OptionNoVerifyToken())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return body, slackevents.EventsAPIEvent{}, fmt.Errorf("parse event: %w", err)
+	}
+
+	return body, eventsAPIEvent, nil
+}
+
+// verifiedBody reads the body of a request from Slack, verifying its signature
+func (s *SlackBot) verifiedBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		return nil, slackevents.EventsAPIEvent{}, fmt.Errorf("read body: %w", err)
+		return nil, fmt.Errorf("read body: %w", err)
 	}
 
 	if !s.disableSecretVerification {
@@ -96,25 +212,19 @@ func (s *SlackBot) parseMessage(w http.ResponseWriter, r *http.Request) ([]byte,
 		sv, err := slack.NewSecretsVerifier(r.Header, s.signinSecret)
 		if err != nil {
 			w.WriteHeader(http.StatusBadRequest)
-			return body, slackevents.EventsAPIEvent{}, fmt.Errorf("create secret verifier: %w", err)
+			return body, fmt.Errorf("create secret verifier: %w", err)
 		}
 		if _, err := sv.Write(body); err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
-			return body, slackevents.EventsAPIEvent{}, fmt.Errorf("write to secret verifier: %w", err)
+			return body, fmt.Errorf("write to secret verifier: %w", err)
 		}
 		if err := sv.Ensure(); err != nil {
 			w.WriteHeader(http.StatusUnauthorized)
-			return body, slackevents.EventsAPIEvent{}, fmt.Errorf("ensure message signature: %w", err)
+			return body, fmt.Errorf("ensure message signature: %w", err)
 		}
 	}
 
-	eventsAPIEvent, err := slackevents.ParseEvent(body, slackevents.OptionNoVerifyToken())
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		return body, slackevents.EventsAPIEvent{}, fmt.Errorf("parse event: %w", err)
-	}
-
-	return body, eventsAPIEvent, nil
+	return body, nil
 }
 
 func (s *SlackBot) handleURLVerification(body []byte, w http.ResponseWriter) error {
@@ -129,8 +239,27 @@ func (s *SlackBot) handleURLVerification(body []byte, w http.ResponseWriter) err
 	return nil
 }
 
-func (s *SlackBot) handleMention(_ *slackevents.AppMentionEvent) error {
-	// Currently unimplemented
+func (s *SlackBot) handleMention(event *slackevents.AppMentionEvent) error {
+	response, err := s.service.HandleMention(service.MentionRequest{
+		Channel:   event.Channel,
+		MessageID: event.TimeStamp,
+		ThreadID:  event.ThreadTimeStamp,
+		UserID:    event.User,
+		Text:      event.Text,
+	})
+	if err != nil {
+		return fmt.Errorf("mention handler: %w", err)
+	}
+
+	if response != "" {
+		threadID := event.ThreadTimeStamp
+		if threadID == "" {
+			threadID = event.TimeStamp
+		}
+		if _, _, err := s.PostMessage(event.Channel, slack.MsgOptionText(response, false), slack.MsgOptionTS(threadID)); err != nil {
+			return fmt.Errorf("post message: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -148,12 +277,18 @@ func (s *SlackBot) mentionsWorker(ctx context.Context) {
 	}
 }
 
-func (s *SlackBot) handleLink(linkEvent *slackevents.LinkSharedEvent) error {
+func (s *SlackBot) handleLink(linkEvent *slackevents.LinkSharedEvent) (err error) {
 	if linkEvent.Channel == "COMPOSER" {
 		// COMPOSER link are the unfurl link event sent when the link is unfurled in the client.
 		// This is not interesting for us as the link didn't send yet and we don't have a valid channel.
 		return nil
 	}
+	ctx, span := tracing.Start(context.Background(), "slack.link_shared", tracing.String("channel", linkEvent.Channel),
+		tracing.String("message_id", linkEvent.MessageTimeStamp))
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
 
 	links := make([]service.Link, len(linkEvent.Links))
 	for i, l := range linkEvent.Links {
@@ -163,17 +298,39 @@ func (s *SlackBot) handleLink(linkEvent *slackevents.LinkSharedEvent) error {
 		}
 	}
 
-	response, err := s.service.HandleLinkMessage(service.LinksRequest{
-		Links:     links,
-		MessageID: linkEvent.MessageTimeStamp,
-		Channel:   linkEvent.Channel,
+	// The message text is used only for extracting the order tags, so continue without it in case of an error
+	text := ""
+	msgs, _, _, err := s.GetConversationRepliesContext(ctx, &slack.GetConversationRepliesParameters{
+		ChannelID: linkEvent.Channel,
+		Timestamp: linkEvent.MessageTimeStamp,
+		Limit:     1,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Error getting link message text", "channel", linkEvent.Channel, "message_id", linkEvent.MessageTimeStamp, "error", err)
+	} else if len(msgs) > 0 {
+		text = msgs[0].Text
+		// The links of a shared message are in its attachments, so they're added unless the event is of them already
+		for _, link := range attachmentLinks(msgs[0].Attachments) {
+			if !hasLink(links, link.URL) {
+				links = append(links, link)
+			}
+		}
+	}
+
+	response, err := s.linkHandler(service.LinksRequest{
+		Links:       links,
+		MessageID:   linkEvent.MessageTimeStamp,
+		Channel:     linkEvent.Channel,
+		Text:        text,
+		UserID:      linkEvent.User,
+		TraceParent: tracing.TraceParent(ctx),
 	})
 	if err != nil {
 		return fmt.Errorf("link handler: %w", err)
 	}
 
 	if response != "" {
-		if _, _, err := s.PostMessage(linkEvent.Channel, slack.MsgOptionText(response, false)); err != nil {
+		if _, _, err := s.PostMessageContext(ctx, linkEvent.Channel, slack.MsgOptionText(response, false)); err != nil {
 			return fmt.Errorf("post message: %w", err)
 		}
 	}
@@ -181,6 +338,15 @@ func (s *SlackBot) handleLink(linkEvent *slackevents.LinkSharedEvent) error {
 	return nil
 }
 
+func hasLink(links []service.Link, url string) bool {
+	for _, link := range links {
+		if link.URL == url {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *SlackBot) linksWorker(ctx context.Context) {
 	for {
 		select {
@@ -213,6 +379,7 @@ func (s *SlackBot) handleReactionAdd(event *slackevents.ReactionAddedEvent) erro
 		FromUserID:    event.User,
 		Channel:       event.Item.Channel,
 		MessageUserID: event.ItemUser,
+		MessageID:     event.Item.Timestamp,
 		MessageText:   msgs[0].Text,
 	})
 	if err != nil {
@@ -228,6 +395,50 @@ func (s *SlackBot) handleReactionAdd(event *slackevents.ReactionAddedEvent) erro
 	return nil
 }
 
+// handleFileShare links the first photo or PDF of the files shared in the thread of an order to the order, as its proof of purchase
+func (s *SlackBot) handleFileShare(event *slackevents.MessageEvent) error {
+	for _, file := range event.Files {
+		response, err := s.service.HandleOrderProof(context.Background(), service.ProofRequest{
+			Channel:  event.Channel,
+			ThreadID: event.ThreadTimeStamp,
+			UserID:   event.User,
+			FileURL:  file.Permalink,
+			MimeType: file.Mimetype,
+		})
+		if err != nil {
+			return fmt.Errorf("order proof handler: %w", err)
+		}
+		if response == "" {
+			continue
+		}
+		if _, _, err := s.PostMessage(event.Channel, slack.MsgOptionText(response, false), slack.MsgOptionTS(event.ThreadTimeStamp)); err != nil {
+			return fmt.Errorf("post message: %w", err)
+		}
+		return nil
+	}
+	return nil
+}
+
+// handleThreadCommand handles a command replied in the thread of an order without mentioning the bot, like "!delivery 25"
+func (s *SlackBot) handleThreadCommand(event *slackevents.MessageEvent) error {
+	response, err := s.service.HandleThreadCommand(service.MentionRequest{
+		Channel:   event.Channel,
+		MessageID: event.TimeStamp,
+		ThreadID:  event.ThreadTimeStamp,
+		UserID:    event.User,
+		Text:      event.Text,
+	})
+	if err != nil {
+		return fmt.Errorf("thread command handler: %w", err)
+	}
+	if response != "" {
+		if _, _, err := s.PostMessage(event.Channel, slack.MsgOptionText(response, false), slack.MsgOptionTS(event.ThreadTimeStamp)); err != nil {
+			return fmt.Errorf("post message: %w", err)
+		}
+	}
+	return nil
+}
+
 func (s *SlackBot) reactionsAddWorker(ctx context.Context) {
 	for {
 		select {
@@ -260,7 +471,13 @@ func (s *SlackBot) getUserByUserName(ctx context.Context, userName string) (slac
 		return slack.User{}, fmt.Errorf("list users: %w", err)
 	}
 
-	return slack.User{}, fmt.Errorf("user %q not found", userName)
+	return slack.User{}, &userDomain.ErrNotFound{Name: userName}
+}
+
+// isUserNotFound returns whether the error is of a user name which doesn't belong to any user
+func isUserNotFound(err error) bool {
+	var notFound *userDomain.ErrNotFound
+	return errors.As(err, &notFound)
 }
 
 func (s *SlackBot) handleAddUserCommand(ctx context.Context, r *http.Request, w http.ResponseWriter) (responseWritten bool, err error) {
@@ -292,7 +509,7 @@ func (s *SlackBot) handleAddUserCommand(ctx context.Context, r *http.Request, w
 	// Get user from Slack (unfortunately can't get directly, need to search for it)
 	user, err := s.getUserByUserName(ctx, splitted[1][1:])
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
+		if isUserNotFound(err) {
 			_, _ = w.Write([]byte(err.Error()))
 			return true, err
 		}
@@ -1,16 +1,30 @@
 package slack
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/oriser/bolt/service"
+	"github.com/oriser/bolt/wolt"
 	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/slackevents"
 )
 
+// maxMessageLength is the length of the longest message Slack accepts
+const maxMessageLength = 40000
+
+// maxMissedMessages is the maximal number of messages read from the history of a channel when catching up with its missed links
+const maxMissedMessages = 1000
+
 type Config struct {
-	SigninSecret              string   `env:"SLACK_SIGNIN_SECRET,required" json:"-"`
-	ClientSecret              string   `env:"SLACK_OAUTH_TOKEN,required" json:"-"`
+	SigninSecret              string   `env:"SLACK_SIGNIN_SECRET" json:"-"`
+	ClientSecret              string   `env:"SLACK_OAUTH_TOKEN" json:"-"`
 	Port                      uint     `env:"SLACK_SERVER_PORT" envDefault:"8080"`
 	MaxConcurrentLinks        int      `env:"SLACK_MAX_CONCURRENT_LINKS" envDefault:"100"`
 	MaxConcurrentMentions     int      `env:"SLACK_MAX_CONCURRENT_MENTIONS" envDefault:"100"`
@@ -33,6 +47,8 @@ type SlackBot struct {
 	mentionsCh                chan *slackevents.AppMentionEvent
 	linksCh                   chan *slackevents.LinkSharedEvent
 	reactionsAddCh            chan *slackevents.ReactionAddedEvent
+	commandHandler            CommandHandler
+	linkHandler               func(req service.LinksRequest) (string, error)
 }
 
 type Client struct {
@@ -58,6 +74,47 @@ func (c *Client) GetSelfID() (string, error) {
 	return res.UserID, nil
 }
 
+// CheckConnection checks that Slack can be reached and the token is valid
+func (c *Client) CheckConnection(ctx context.Context) error {
+	if _, err := c.AuthTestContext(ctx); err != nil {
+		return fmt.Errorf("auth test: %w", err)
+	}
+	return nil
+}
+
+// transportError wraps the errors of unavailable channels and users with the service errors for them, so the orders which can't be
+// tracked anymore are stopped
+func transportError(receiver string, err error) error {
+	var slackErr slack.SlackErrorResponse
+	if !errors.As(err, &slackErr) {
+		return err
+	}
+	switch slackErr.Err {
+	case "is_archived", "channel_not_found", "not_in_channel":
+		if isUserID(receiver) {
+			// Direct messages are sent to the user ID
+			return fmt.Errorf("%w: %s", service.ErrUserUnavailable, slackErr.Err)
+		}
+		return fmt.Errorf("%w: %s", service.ErrChannelUnavailable, slackErr.Err)
+	case "user_not_found", "user_disabled":
+		return fmt.Errorf("%w: %s", service.ErrUserUnavailable, slackErr.Err)
+	}
+	return err
+}
+
+// scopeError wraps the error of a call the app's token lacks the OAuth scope of with the service error for it, naming the scope
+func scopeError(scope string, err error) error {
+	var slackErr slack.SlackErrorResponse
+	if errors.As(err, &slackErr) && slackErr.Err == "missing_scope" {
+		return fmt.Errorf("%w: %s", &service.MissingScopeError{Scope: scope}, slackErr.Err)
+	}
+	return err
+}
+
+func isUserID(id string) bool {
+	return strings.HasPrefix(id, "U") || strings.HasPrefix(id, "W")
+}
+
 func (c *Client) SendMessage(receiver, event, messageID string) (string, error) {
 	options := []slack.MsgOption{slack.MsgOptionText(event, false)}
 	if messageID != "" {
@@ -65,7 +122,7 @@ func (c *Client) SendMessage(receiver, event, messageID string) (string, error)
 	}
 	_, ts, err := c.PostMessage(receiver, options...)
 	if err != nil {
-		return "", fmt.Errorf("posting message: %w", err)
+		return "", fmt.Errorf("posting message: %w", transportError(receiver, err))
 	}
 	return ts, nil
 }
@@ -79,17 +136,201 @@ func (c *Client) EditMessage(receiver, event, messageID string) error {
 
 	_, _, _, err := c.UpdateMessage(receiver, messageID, options...)
 	if err != nil {
-		return fmt.Errorf("editing message %s: %w", messageID, err)
+		return fmt.Errorf("editing message %s: %w", messageID, transportError(receiver, err))
 	}
 	return nil
 }
 
+func (c *Client) MaxMessageLength() int {
+	return maxMessageLength
+}
+
 func (c *Client) AddReaction(receiver, messageID, reaction string) error {
 	if err := c.Client.AddReaction(reaction, slack.ItemRef{
 		Channel:   receiver,
 		Timestamp: messageID,
 	}); err != nil {
-		return fmt.Errorf("add reaction: %w", err)
+		return fmt.Errorf("add reaction: %w", transportError(receiver, scopeError("reactions:write", err)))
+	}
+	return nil
+}
+
+func (c *Client) MessageLink(receiver, messageID string) (string, error) {
+	permalink, err := c.GetPermalink(&slack.PermalinkParameters{Channel: receiver, Ts: messageID})
+	if err != nil {
+		return "", fmt.Errorf("get permalink: %w", err)
+	}
+	return permalink, nil
+}
+
+func (c *Client) RecentMessages(channel string, limit int) ([]string, error) {
+	history, err := c.GetConversationHistory(&slack.GetConversationHistoryParameters{ChannelID: channel, Limit: limit})
+	if err != nil {
+		return nil, fmt.Errorf("get conversation history: %w", err)
+	}
+	texts := make([]string, 0, len(history.Messages))
+	for _, msg := range history.Messages {
+		texts = append(texts, msg.Text)
+	}
+	return texts, nil
+}
+
+// linkRe matches the links in the text of a message, which are formatted as <URL> or <URL|label>
+var linkRe = regexp.MustCompile(`<((?:https?|wolt)://[^|>\s]+)`)
+
+func (c *Client) MessagesSince(channel, messageID string, since time.Time) ([]service.HistoryMessage, error) {
+	oldest := fmt.Sprintf("%d.%06d", since.Unix(), since.Nanosecond()/1000)
+	if messageID != "" && timestampTime(messageID).After(since) {
+		oldest = messageID
+	}
+
+	var messages []service.HistoryMessage
+	params := &slack.GetConversationHistoryParameters{ChannelID: channel, Oldest: oldest, Limit: 200}
+	for len(messages) < maxMissedMessages {
+		history, err := c.GetConversationHistory(params)
+		if err != nil {
+			return nil, fmt.Errorf("get conversation history: %w", transportError(channel, err))
+		}
+		for _, msg := range history.Messages {
+			if msg.BotID != "" || msg.SubType == slack.MsgSubTypeBotMessage {
+				continue
+			}
+			messages = append(messages, service.HistoryMessage{
+				MessageID: msg.Timestamp,
+				Text:      msg.Text,
+				Links:     append(messageLinks(msg.Text), attachmentLinks(msg.Attachments)...),
+				SentAt:    timestampTime(msg.Timestamp),
+			})
+		}
+		if !history.HasMore || history.ResponseMetaData.NextCursor == "" {
+			break
+		}
+		params.Cursor = history.ResponseMetaData.NextCursor
+	}
+
+	// The history is newest first
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return messages, nil
+}
+
+// messageLinks returns the links in the text of a message, like the links of a link shared event
+func messageLinks(text string) []service.Link {
+	var links []service.Link
+	for _, match := range linkRe.FindAllStringSubmatch(text, -1) {
+		parsed, err := url.Parse(match[1])
+		if err != nil {
+			continue
+		}
+		links = append(links, service.Link{Domain: strings.TrimPrefix(parsed.Hostname(), "www."), URL: match[1]})
+	}
+	return links
+}
+
+// attachmentLinks returns the Wolt links in the attachments of a message, like the ones of a message shared or forwarded with
+// "Share message", which Slack sends no link shared event for
+func attachmentLinks(attachments []slack.Attachment) []service.Link {
+	var links []service.Link
+	for _, attachment := range attachments {
+		for _, text := range []string{attachment.Pretext, attachment.Text, attachment.Fallback} {
+			links = append(links, messageLinks(text)...)
+		}
+		for _, link := range []string{attachment.TitleLink, attachment.FromURL, attachment.OriginalURL} {
+			links = appendLink(links, link)
+		}
+		for _, block := range attachment.Blocks.BlockSet {
+			links = append(links, blockLinks(block)...)
+		}
+	}
+	return woltLinks(links)
+}
+
+// blockLinks returns the links of a rich text block, which is how the text of shared messages is formatted
+func blockLinks(block slack.Block) []service.Link {
+	richText, ok := block.(*slack.RichTextBlock)
+	if !ok {
+		return nil
+	}
+	var links []service.Link
+	for _, element := range richText.Elements {
+		var sections []slack.RichTextSection
+		switch element := element.(type) {
+		case *slack.RichTextSection:
+			sections = append(sections, *element)
+		case *slack.RichTextQuote:
+			sections = append(sections, slack.RichTextSection(*element))
+		case *slack.RichTextPreformatted:
+			sections = append(sections, element.RichTextSection)
+		case *slack.RichTextList:
+			for _, item := range element.Elements {
+				if section, ok := item.(*slack.RichTextSection); ok {
+					sections = append(sections, *section)
+				}
+			}
+		}
+		for _, section := range sections {
+			for _, sectionElement := range section.Elements {
+				if link, ok := sectionElement.(*slack.RichTextSectionLinkElement); ok {
+					links = appendLink(links, link.URL)
+				}
+			}
+		}
+	}
+	return links
+}
+
+func appendLink(links []service.Link, link string) []service.Link {
+	if link == "" {
+		return links
+	}
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return links
+	}
+	return append(links, service.Link{Domain: strings.TrimPrefix(parsed.Hostname(), "www."), URL: link})
+}
+
+// woltLinks returns the distinct Wolt links of the links, by their order
+func woltLinks(links []service.Link) []service.Link {
+	var filtered []service.Link
+	seen := make(map[string]bool)
+	for _, link := range links {
+		if seen[link.URL] || !wolt.IsWoltLink(link.URL) {
+			continue
+		}
+		seen[link.URL] = true
+		filtered = append(filtered, link)
+	}
+	return filtered
+}
+
+// timestampTime returns the time of a message timestamp, which is <seconds>.<microseconds>
+func timestampTime(ts string) time.Time {
+	seconds, err := strconv.ParseFloat(ts, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
+
+func (c *Client) UserActive(userID string) (bool, error) {
+	presence, err := c.GetUserPresence(userID)
+	if err != nil {
+		return false, fmt.Errorf("get user presence: %w", transportError(userID, err))
+	}
+	return presence.Presence == "active", nil
+}
+
+func (c *Client) UploadFile(receiver, filename, content, comment string) error {
+	if _, err := c.UploadFileV2(slack.UploadFileV2Parameters{
+		Channel:        receiver,
+		Filename:       filename,
+		Content:        content,
+		FileSize:       len(content),
+		InitialComment: comment,
+	}); err != nil {
+		return fmt.Errorf("upload file %s: %w", filename, transportError(receiver, err))
 	}
 	return nil
 }
@@ -109,9 +350,15 @@ func (c *Client) ServiceBot(serviceHandler *service.Service) *SlackBot {
 		adminsUserIds:             make(map[string]interface{}),
 		service:                   serviceHandler,
 	}
+	sb.linkHandler = serviceHandler.HandleLinkMessage
 
 	for _, userID := range c.cfg.AdminSlackUserID {
 		sb.adminsUserIds[userID] = nil
 	}
 	return sb
 }
+
+// SetLinkHandler replaces handling links in-process, for example for passing them to the order monitoring workers of another process
+func (s *SlackBot) SetLinkHandler(handler func(req service.LinksRequest) (string, error)) {
+	s.linkHandler = handler
+}
@@ -0,0 +1,285 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oriser/bolt/bot/transport"
+	"github.com/oriser/bolt/service"
+)
+
+const pollRetryInterval = 5 * time.Second
+
+var (
+	urlRe     = regexp.MustCompile(`(?:https?|wolt)://[^\s<>"]+`)
+	commandRe = regexp.MustCompile(`^/([a-z_]+)(@[A-Za-z0-9_]+)?(?:\s+(.*))?$`)
+	// Reactions (message_reaction) are sent only to bots which are admins of the chat
+	allowedUpdates = []string{"message", "message_reaction", "my_chat_member"}
+)
+
+type user struct {
+	ID        int64  `json:"id"`
+	IsBot     bool   `json:"is_bot"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Username  string `json:"username"`
+}
+
+func (u user) name() string {
+	if u.LastName != "" {
+		return u.FirstName + " " + u.LastName
+	}
+	return u.FirstName
+}
+
+type chat struct {
+	ID    int64  `json:"id"`
+	Type  string `json:"type"`
+	Title string `json:"title"`
+}
+
+type entity struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type message struct {
+	MessageID       int64    `json:"message_id"`
+	From            *user    `json:"from"`
+	Chat            chat     `json:"chat"`
+	Text            string   `json:"text"`
+	Caption         string   `json:"caption"`
+	Entities        []entity `json:"entities"`
+	CaptionEntities []entity `json:"caption_entities"`
+	ReplyToMessage  *message `json:"reply_to_message"`
+}
+
+type reaction struct {
+	Type  string `json:"type"`
+	Emoji string `json:"emoji"`
+}
+
+type messageReaction struct {
+	Chat        chat       `json:"chat"`
+	MessageID   int64      `json:"message_id"`
+	User        *user      `json:"user"` // Empty for anonymous reactions
+	OldReaction []reaction `json:"old_reaction"`
+	NewReaction []reaction `json:"new_reaction"`
+}
+
+type chatMemberUpdated struct {
+	Chat          chat `json:"chat"`
+	NewChatMember struct {
+		User   user   `json:"user"`
+		Status string `json:"status"`
+	} `json:"new_chat_member"`
+}
+
+type update struct {
+	UpdateID        int64              `json:"update_id"`
+	Message         *message           `json:"message"`
+	MessageReaction *messageReaction   `json:"message_reaction"`
+	MyChatMember    *chatMemberUpdated `json:"my_chat_member"`
+}
+
+func id(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
+
+type TelegramBot struct {
+	*Client
+	*transport.Bot
+}
+
+func (c *Client) ServiceBot(serviceHandler *service.Service) *TelegramBot {
+	return &TelegramBot{Client: c, Bot: transport.NewBot(serviceHandler, c.cfg.AdminUserIDs, c.cfg.MaxConcurrentUpdates)}
+}
+
+// ListenAndServe polls the updates of the bot, and serves the API and the dashboard (when enabled) on TELEGRAM_SERVER_PORT
+func (b *TelegramBot) ListenAndServe(ctx context.Context) error {
+	go b.pollUpdates(ctx)
+
+	log.Println("Server listening on port", b.cfg.Port)
+	return http.ListenAndServe(fmt.Sprintf(":%d", b.cfg.Port), nil)
+}
+
+func (b *TelegramBot) pollUpdates(ctx context.Context) {
+	var offset int64
+	for {
+		var updates []update
+		err := b.call(ctx, "getUpdates", map[string]interface{}{
+			"offset":          offset,
+			"timeout":         int(b.cfg.PollTimeout.Seconds()),
+			"allowed_updates": allowedUpdates,
+		}, &updates)
+		if ctx.Err() != nil {
+			log.Println("Finishing polling Telegram updates due to context cancellation")
+			return
+		}
+		if err != nil {
+			log.Println("Error getting Telegram updates:", err)
+			select {
+			case <-time.After(pollRetryInterval):
+			case <-ctx.Done():
+				return
+			}
+			continue
+		}
+
+		for _, u := range updates {
+			offset = u.UpdateID + 1
+			u := u
+			b.Go(func() {
+				if err := b.handleUpdate(u); err != nil {
+					log.Printf("Error handling Telegram update %d: %v\n", u.UpdateID, err)
+				}
+			})
+		}
+	}
+}
+
+func (b *TelegramBot) handleUpdate(u update) error {
+	switch {
+	case u.Message != nil:
+		return b.handleMessage(u.Message)
+	case u.MessageReaction != nil:
+		for _, req := range b.reactionRequests(u.MessageReaction) {
+			if err := b.HandleReaction(req, b.replier(req.Channel, "")); err != nil {
+				return err
+			}
+		}
+	case u.MyChatMember != nil:
+		// Stopping orders notifies the fallback admin channel, the same as when the bot is removed from a Slack channel
+		if status := u.MyChatMember.NewChatMember.Status; status == "left" || status == "kicked" {
+			selfID, _ := b.self()
+			b.Service.HandleMemberLeftChannel(id(u.MyChatMember.Chat.ID), selfID)
+		}
+	}
+	return nil
+}
+
+// links returns the links in the message, both written in its text and attached to its text (text links)
+func links(m *message) []service.Link {
+	found := urlRe.FindAllString(m.Text+"\n"+m.Caption, -1)
+	for _, e := range append(m.Entities, m.CaptionEntities...) {
+		if e.Type == "text_link" {
+			found = append(found, e.URL)
+		}
+	}
+	return transport.Links(found)
+}
+
+// replier returns the function replying in the chat, to the message if it's given
+func (b *TelegramBot) replier(chatID, messageID string) func(text string) error {
+	return func(text string) error {
+		_, err := b.SendMessage(chatID, text, messageID)
+		return err
+	}
+}
+
+func (b *TelegramBot) handleMessage(m *message) error {
+	chatID, messageID := id(m.Chat.ID), id(m.MessageID)
+	if m.Chat.Title != "" {
+		b.rememberName(chatID, m.Chat.Title)
+	}
+	if m.From == nil || m.From.IsBot {
+		return nil
+	}
+	userID := id(m.From.ID)
+	b.rememberName(userID, m.From.name())
+
+	threadID := ""
+	if m.ReplyToMessage != nil {
+		threadID = b.messages.ThreadOf(chatID, id(m.ReplyToMessage.MessageID))
+	}
+	text := m.Text
+	if text == "" {
+		text = m.Caption
+	}
+	b.messages.Add(chatID, messageID, transport.Message{UserID: userID, ThreadID: threadID, Text: text})
+
+	if match := commandRe.FindStringSubmatch(text); match != nil {
+		return b.handleCommand(m, match[1], strings.TrimSpace(match[3]))
+	}
+
+	if found := links(m); len(found) > 0 {
+		req := service.LinksRequest{Links: found, MessageID: messageID, Channel: chatID, Text: text, UserID: userID}
+		return b.HandleLinks(req, b.replier(chatID, ""))
+	}
+
+	selfID, selfUser := b.self()
+	mention := "@" + selfUser
+	repliesToBot := m.ReplyToMessage != nil && m.ReplyToMessage.From != nil && id(m.ReplyToMessage.From.ID) == selfID
+	threadCommand := threadID != "" && service.IsThreadCommand(text)
+	if !threadCommand && (selfUser == "" || (!strings.Contains(text, mention) && !repliesToBot)) {
+		return nil
+	}
+	// The service expects mentions in Slack's format
+	return b.HandleMention(service.MentionRequest{
+		Channel:   chatID,
+		MessageID: messageID,
+		ThreadID:  threadID,
+		UserID:    userID,
+		Text:      strings.ReplaceAll(text, mention, fmt.Sprintf("<@%s>", selfID)),
+	}, threadCommand, b.replier(chatID, messageID))
+}
+
+// handleCommand handles the bot commands. /adduser <Wolt name> registers the sender under their Wolt name. Admins can add other users
+// by replying to their message with it, and hosts can link the unknown participants of their orders the same way.
+func (b *TelegramBot) handleCommand(m *message, command, args string) error {
+	if command != "adduser" {
+		return nil
+	}
+	added := m.From
+	if m.ReplyToMessage != nil && m.ReplyToMessage.From != nil && m.ReplyToMessage.From.ID != m.From.ID {
+		added = m.ReplyToMessage.From
+	}
+	b.rememberName(id(added.ID), added.name())
+
+	req := transport.AddUserRequest{SenderID: id(m.From.ID), AddedID: id(added.ID), Name: args}
+	return b.AddUser(req, "USAGE: /adduser <your name in Wolt>, or reply with it to the message of the user to add (admins, or hosts "+
+		"for the participants of their orders I couldn't find)", b.replier(id(m.Chat.ID), id(m.MessageID)))
+}
+
+// reactionRequests returns the requests of the reactions added to a message, with the message as cached when it was sent or received
+func (c *Client) reactionRequests(r *messageReaction) []service.ReactionAddRequest {
+	if r.User == nil {
+		return nil
+	}
+	old := make(map[string]bool, len(r.OldReaction))
+	for _, reaction := range r.OldReaction {
+		old[reaction.Emoji] = true
+	}
+
+	chatID, messageID := id(r.Chat.ID), id(r.MessageID)
+	cached, ok := c.messages.Get(chatID, messageID)
+	if !ok {
+		log.Printf("Got a reaction to message %s of chat %s, which isn't in the recent messages\n", messageID, chatID)
+		cached = &transport.Message{}
+	}
+	requests := make([]service.ReactionAddRequest, 0, len(r.NewReaction))
+	for _, reaction := range r.NewReaction {
+		if reaction.Type != "emoji" || old[reaction.Emoji] {
+			continue
+		}
+		name, ok := transport.ReactionName(reactionEmojis, reaction.Emoji)
+		if !ok {
+			continue
+		}
+		requests = append(requests, service.ReactionAddRequest{
+			Reaction:      name,
+			FromUserID:    id(r.User.ID),
+			Channel:       chatID,
+			MessageUserID: cached.UserID,
+			MessageID:     messageID,
+			MessageText:   cached.Text,
+		})
+	}
+	return requests
+}
@@ -0,0 +1,371 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oriser/bolt/bot/transport"
+)
+
+// messagesCacheSize is how many recent messages are kept for resolving the messages reactions are added to, as Telegram doesn't
+// send the reacted message with the reaction
+const messagesCacheSize = 10000
+
+// maxMessageLength is the length of the longest message Telegram accepts, after its formatting
+const maxMessageLength = 4096
+
+var (
+	mentionRe     = regexp.MustCompile(`<@(-?[A-Za-z0-9]+)>`)
+	channelRe     = regexp.MustCompile(`<#(-?[A-Za-z0-9]+)>`)
+	emojiRe       = regexp.MustCompile(`:([a-z0-9_+-]+):`)
+	inlineCodeRe  = regexp.MustCompile("`([^`\n]+)`")
+	linkRe        = regexp.MustCompile(`<(https?://[^|>\s]+)\|([^>]+)>`)
+	formattingRe  = regexp.MustCompile("<@-?[A-Za-z0-9]+>|<#-?[A-Za-z0-9]+>|<https?://[^|>\\s]+\\|[^>]+>|`[^`\n]+`")
+	errorReasonRe = regexp.MustCompile(`(?i)chat not found|bot was kicked|bot is not a member|the group chat was deleted|bot was blocked by the user|user is deactivated`)
+)
+
+// reactionEmojis maps the emoji names Bolt uses (as in Slack) to the emojis Telegram allows as reactions. Telegram allows only a fixed
+// set of reactions, so some are substituted, and the messages mention the substitutes, so users know what to react with.
+var reactionEmojis = map[string]string{
+	"eyes":             "👀",
+	"money_mouth_face": "🤝",
+	"x":                "👎",
+	"no_entry_sign":    "🙈",
+	"white_check_mark": "👌",
+	"thumbsup":         "👍",
+	"+1":               "👍",
+	"fire":             "🔥",
+	"tada":             "🎉",
+	"pray":             "🙏",
+	"sleeping":         "😴",
+	"100":              "💯",
+	"credit_card":      "👨‍💻",
+}
+
+// textEmojis maps the other emoji names Bolt's messages use to their characters
+var textEmojis = map[string]string{
+	"house":                        "🏠",
+	"bike":                         "🚲",
+	"cook":                         "🧑‍🍳",
+	"underage":                     "🔞",
+	"warning":                      "⚠️",
+	"hourglass_flowing_sand":       "⏳",
+	"busts_in_silhouette":          "👥",
+	"bar_chart":                    "📊",
+	"stuck_out_tongue_winking_eye": "😜",
+	"wave":                         "👋",
+	"crown":                        "👑",
+	"zap":                          "⚡",
+	"compass":                      "🧭",
+	"trophy":                       "🏆",
+	"twisted_rightwards_arrows":    "🔀",
+	"red_circle":                   "🔴",
+	"large_yellow_circle":          "🟡",
+	"large_green_circle":           "🟢",
+}
+
+type Config struct {
+	Token                string        `env:"TELEGRAM_BOT_TOKEN" json:"-"`
+	APIURL               string        `env:"TELEGRAM_API_URL" envDefault:"https://api.telegram.org"`
+	Port                 uint          `env:"TELEGRAM_SERVER_PORT" envDefault:"8080"` // Port of the API and dashboard server
+	PollTimeout          time.Duration `env:"TELEGRAM_POLL_TIMEOUT" envDefault:"30s"`
+	MaxConcurrentUpdates int           `env:"TELEGRAM_MAX_CONCURRENT_UPDATES" envDefault:"100"`
+	AdminUserIDs         []string      `env:"TELEGRAM_ADMIN_USER_IDS"`
+}
+
+// Client is a transport for Telegram group chats over the Bot API, implementing the service's event notification.
+// The receivers are chat IDs (or user IDs for private chats) and the message IDs are Telegram's message IDs within the chat.
+type Client struct {
+	cfg    Config
+	client *http.Client
+
+	lock     sync.Mutex
+	selfID   string
+	names    map[string]string // The names of users and the titles of chats by their IDs, for rendering mentions
+	messages *transport.MessageCache
+	selfUser string // The username of the bot, for detecting mentions
+}
+
+func NewClient(cfg Config) *Client {
+	return &Client{
+		cfg: cfg,
+		// Long polling holds the request for up to the poll timeout
+		client:   &http.Client{Timeout: cfg.PollTimeout + 30*time.Second},
+		names:    make(map[string]string),
+		messages: transport.NewMessageCache(messagesCacheSize),
+	}
+}
+
+// apiError is an error response of the Bot API
+type apiError struct {
+	Code        int    `json:"error_code"`
+	Description string `json:"description"`
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("telegram error %d: %s", e.Code, e.Description)
+}
+
+func (c *Client) endpoint(method string) string {
+	return fmt.Sprintf("%s/bot%s/%s", strings.TrimSuffix(c.cfg.APIURL, "/"), c.cfg.Token, method)
+}
+
+func (c *Client) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("marshal %s params: %w", method, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint(method), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return c.do(req, result)
+}
+
+func (c *Client) do(req *http.Request, result interface{}) error {
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var response struct {
+		OK     bool            `json:"ok"`
+		Result json.RawMessage `json:"result"`
+		apiError
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("decode response with status %d: %w", resp.StatusCode, err)
+	}
+	if !response.OK {
+		return &response.apiError
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(response.Result, result); err != nil {
+		return fmt.Errorf("unmarshal result: %w", err)
+	}
+	return nil
+}
+
+// transportError wraps the errors of unavailable chats and users with the service errors for them, so the orders which can't be
+// tracked anymore are stopped
+func transportError(receiver string, err error) error {
+	apiErr, ok := err.(*apiError)
+	if !ok || !errorReasonRe.MatchString(apiErr.Description) {
+		return err
+	}
+	return transport.UnavailableError(isUserID(receiver), apiErr.Description)
+}
+
+// isUserID returns whether the chat ID is of a private chat, whose ID is the user ID. Group chats have negative IDs.
+func isUserID(chatID string) bool {
+	return !strings.HasPrefix(chatID, "-")
+}
+
+// CheckConnection checks that Telegram can be reached and the token is valid
+func (c *Client) CheckConnection(ctx context.Context) error {
+	var me user
+	if err := c.call(ctx, "getMe", struct{}{}, &me); err != nil {
+		return fmt.Errorf("get me: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) GetSelfID() (string, error) {
+	var me user
+	if err := c.call(context.Background(), "getMe", struct{}{}, &me); err != nil {
+		return "", fmt.Errorf("get me: %w", err)
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.selfID = strconv.FormatInt(me.ID, 10)
+	c.selfUser = me.Username
+	c.names[c.selfID] = me.name()
+	return c.selfID, nil
+}
+
+// self returns the user ID and the username of the bot
+func (c *Client) self() (string, string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.selfID, c.selfUser
+}
+
+func (c *Client) rememberName(id, name string) {
+	if name == "" {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.names[id] = name
+}
+
+func (c *Client) name(id string) (string, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	name, ok := c.names[id]
+	return name, ok
+}
+
+// emoji returns the character of a Slack emoji name, preferring the reactions' substitutes so the messages match the reactions
+func emoji(name string) (string, bool) {
+	if e, ok := reactionEmojis[name]; ok {
+		return e, true
+	}
+	e, ok := textEmojis[name]
+	return e, ok
+}
+
+// formatText converts a message in Slack's format (which the service uses) to Telegram's HTML: user mentions (<@ID>), chat mentions
+// (<#ID>), links (<URL|label>), `code` and :emoji: names
+func (c *Client) formatText(text string) string {
+	var sb strings.Builder
+	last := 0
+	for _, loc := range formattingRe.FindAllStringIndex(text, -1) {
+		sb.WriteString(c.formatPlain(text[last:loc[0]]))
+		token := text[loc[0]:loc[1]]
+		switch {
+		case mentionRe.MatchString(token):
+			id := mentionRe.FindStringSubmatch(token)[1]
+			name, ok := c.name(id)
+			if !ok {
+				name = "user " + id
+			}
+			sb.WriteString(fmt.Sprintf(`<a href="tg://user?id=%s">%s</a>`, html.EscapeString(id), html.EscapeString(name)))
+		case channelRe.MatchString(token):
+			id := channelRe.FindStringSubmatch(token)[1]
+			name, ok := c.name(id)
+			if !ok {
+				name = "chat " + id
+			}
+			sb.WriteString("<b>" + html.EscapeString(name) + "</b>")
+		case linkRe.MatchString(token):
+			link := linkRe.FindStringSubmatch(token)
+			sb.WriteString(fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(link[1]), c.formatPlain(link[2])))
+		default:
+			sb.WriteString("<code>" + html.EscapeString(inlineCodeRe.FindStringSubmatch(token)[1]) + "</code>")
+		}
+		last = loc[1]
+	}
+	sb.WriteString(c.formatPlain(text[last:]))
+	return sb.String()
+}
+
+func (c *Client) formatPlain(text string) string {
+	return emojiRe.ReplaceAllStringFunc(html.EscapeString(text), func(match string) string {
+		if e, ok := emoji(strings.Trim(match, ":")); ok {
+			return e
+		}
+		return match
+	})
+}
+
+type sentMessage struct {
+	MessageID int64 `json:"message_id"`
+}
+
+func (c *Client) SendMessage(receiver, event, messageID string) (string, error) {
+	params := map[string]interface{}{
+		"chat_id":                  receiver,
+		"text":                     c.formatText(event),
+		"parse_mode":               "HTML",
+		"disable_web_page_preview": true,
+	}
+	if messageID != "" {
+		// Telegram has no threads, so messages of a thread are sent as replies to its message
+		params["reply_to_message_id"] = messageID
+		params["allow_sending_without_reply"] = true
+	}
+	var sent sentMessage
+	if err := c.call(context.Background(), "sendMessage", params, &sent); err != nil {
+		return "", fmt.Errorf("posting message: %w", transportError(receiver, err))
+	}
+	id := strconv.FormatInt(sent.MessageID, 10)
+	selfID, _ := c.self()
+	c.messages.Add(receiver, id, transport.Message{UserID: selfID, ThreadID: c.messages.ThreadOf(receiver, messageID), Text: event})
+	return id, nil
+}
+
+func (c *Client) EditMessage(receiver, event, messageID string) error {
+	if messageID == "" {
+		return fmt.Errorf("empty message ID")
+	}
+	params := map[string]interface{}{
+		"chat_id":                  receiver,
+		"message_id":               messageID,
+		"text":                     c.formatText(event),
+		"parse_mode":               "HTML",
+		"disable_web_page_preview": true,
+	}
+	if err := c.call(context.Background(), "editMessageText", params, nil); err != nil {
+		return fmt.Errorf("editing message %s: %w", messageID, transportError(receiver, err))
+	}
+	selfID, _ := c.self()
+	c.messages.Add(receiver, messageID, transport.Message{UserID: selfID, Text: event})
+	return nil
+}
+
+func (c *Client) MaxMessageLength() int {
+	return maxMessageLength
+}
+
+func (c *Client) AddReaction(receiver, messageID, reaction string) error {
+	e, ok := reactionEmojis[reaction]
+	if !ok {
+		return fmt.Errorf("add reaction: :%s: isn't available as a Telegram reaction", reaction)
+	}
+	params := map[string]interface{}{
+		"chat_id":    receiver,
+		"message_id": messageID,
+		"reaction":   []map[string]string{{"type": "emoji", "emoji": e}},
+	}
+	if err := c.call(context.Background(), "setMessageReaction", params, nil); err != nil {
+		return fmt.Errorf("add reaction: %w", transportError(receiver, err))
+	}
+	return nil
+}
+
+func (c *Client) UploadFile(receiver, filename, content, comment string) error {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	fields := map[string]string{"chat_id": receiver, "caption": c.formatText(comment), "parse_mode": "HTML"}
+	for key, value := range fields {
+		if err := writer.WriteField(key, value); err != nil {
+			return fmt.Errorf("write %s field: %w", key, err)
+		}
+	}
+	part, err := writer.CreateFormFile("document", filename)
+	if err != nil {
+		return fmt.Errorf("create document part: %w", err)
+	}
+	if _, err := io.WriteString(part, content); err != nil {
+		return fmt.Errorf("write document: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("close multipart writer: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.endpoint("sendDocument"), &body)
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if err := c.do(req, nil); err != nil {
+		return fmt.Errorf("upload file %s: %w", filename, transportError(receiver, err))
+	}
+	return nil
+}
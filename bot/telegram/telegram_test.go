@@ -0,0 +1,179 @@
+package telegram
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/oriser/bolt/bot/transport"
+	"github.com/oriser/bolt/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBotAPI records the calls of the Bot API methods, answering them with the given results
+type fakeBotAPI struct {
+	lock    sync.Mutex
+	calls   map[string][]map[string]interface{}
+	results map[string]string
+}
+
+func newFakeBotAPI(t *testing.T, results map[string]string) (*fakeBotAPI, *Client) {
+	api := &fakeBotAPI{calls: make(map[string][]map[string]interface{}), results: results}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		params := make(map[string]interface{})
+		_ = json.NewDecoder(r.Body).Decode(&params)
+		api.lock.Lock()
+		api.calls[method] = append(api.calls[method], params)
+		api.lock.Unlock()
+
+		result, ok := api.results[method]
+		if !ok {
+			result = `{"ok": true, "result": true}`
+		}
+		_, _ = w.Write([]byte(result))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(Config{Token: "token", APIURL: server.URL, MaxConcurrentUpdates: 1})
+	return api, client
+}
+
+func TestFormatText(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(Config{})
+	client.rememberName("42", "Dana <D>")
+	client.rememberName("-100", "Lunch")
+
+	assert.Equal(t,
+		`<a href="tg://user?id=42">Dana &lt;D&gt;</a> joined the order in <b>Lunch</b> 👀 <code>1 &amp; 2</code> :unknown: 1 &lt; 2`,
+		client.formatText("<@42> joined the order in <#-100> :eyes: `1 & 2` :unknown: 1 < 2"))
+	assert.Equal(t, `<a href="tg://user?id=7">user 7</a>`, client.formatText("<@7>"))
+	assert.Equal(t, `Loki: 30.00 <a href="https://pay.example/?amount=30.00&amp;phone=1">Pay with Bit</a>`,
+		client.formatText("Loki: 30.00 <https://pay.example/?amount=30.00&phone=1|Pay with Bit>"))
+}
+
+func TestSendMessage(t *testing.T) {
+	t.Parallel()
+
+	api, client := newFakeBotAPI(t, map[string]string{
+		"getMe":       `{"ok": true, "result": {"id": 1, "is_bot": true, "first_name": "Bolt", "username": "bolt_bot"}}`,
+		"sendMessage": `{"ok": true, "result": {"message_id": 11}}`,
+	})
+	selfID, err := client.GetSelfID()
+	require.NoError(t, err)
+	assert.Equal(t, "1", selfID)
+
+	client.messages.Add("-100", "10", transport.Message{UserID: "42", Text: "order link"})
+	id, err := client.SendMessage("-100", "Joined :eyes:", "10")
+	require.NoError(t, err)
+	assert.Equal(t, "11", id)
+
+	require.Len(t, api.calls["sendMessage"], 1)
+	params := api.calls["sendMessage"][0]
+	assert.Equal(t, "-100", params["chat_id"])
+	assert.Equal(t, "Joined 👀", params["text"])
+	assert.Equal(t, "HTML", params["parse_mode"])
+	assert.Equal(t, "10", params["reply_to_message_id"])
+
+	// The messages replying to a reply are in the thread of the first message
+	cached, ok := client.messages.Get("-100", "11")
+	require.True(t, ok)
+	assert.Equal(t, transport.Message{UserID: "1", ThreadID: "10", Text: "Joined :eyes:"}, *cached)
+	assert.Equal(t, "10", client.messages.ThreadOf("-100", "11"))
+}
+
+func TestAddReaction(t *testing.T) {
+	t.Parallel()
+
+	api, client := newFakeBotAPI(t, nil)
+	require.NoError(t, client.AddReaction("-100", "10", "white_check_mark"))
+	require.Len(t, api.calls["setMessageReaction"], 1)
+	assert.Equal(t, []interface{}{map[string]interface{}{"type": "emoji", "emoji": "👌"}}, api.calls["setMessageReaction"][0]["reaction"])
+
+	assert.ErrorContains(t, client.AddReaction("-100", "10", "house"), "isn't available as a Telegram reaction")
+}
+
+func TestTransportError(t *testing.T) {
+	t.Parallel()
+
+	_, client := newFakeBotAPI(t, map[string]string{
+		"sendMessage": `{"ok": false, "error_code": 403, "description": "Forbidden: bot was kicked from the group chat"}`,
+	})
+	_, err := client.SendMessage("-100", "hello", "")
+	assert.True(t, errors.Is(err, service.ErrChannelUnavailable))
+	_, err = client.SendMessage("42", "hello", "")
+	assert.True(t, errors.Is(err, service.ErrUserUnavailable))
+
+	err = transportError("-100", &apiError{Code: 429, Description: "Too Many Requests: retry after 5"})
+	assert.False(t, errors.Is(err, service.ErrChannelUnavailable))
+}
+
+func TestReactionRequests(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(Config{})
+	client.messages.Add("-100", "11", transport.Message{UserID: "1", ThreadID: "10", Text: "Order of Pizza is done, <@42> owes 30 NIS"})
+
+	requests := client.reactionRequests(&messageReaction{
+		Chat:        chat{ID: -100},
+		MessageID:   11,
+		User:        &user{ID: 42},
+		OldReaction: []reaction{{Type: "emoji", Emoji: "👍"}},
+		NewReaction: []reaction{{Type: "emoji", Emoji: "👍"}, {Type: "emoji", Emoji: "🤝"}, {Type: "emoji", Emoji: "🤡"}},
+	})
+	assert.Equal(t, []service.ReactionAddRequest{{
+		Reaction:      "money_mouth_face",
+		FromUserID:    "42",
+		Channel:       "-100",
+		MessageUserID: "1",
+		MessageID:     "11",
+		MessageText:   "Order of Pizza is done, <@42> owes 30 NIS",
+	}}, requests)
+
+	assert.Empty(t, client.reactionRequests(&messageReaction{Chat: chat{ID: -100}, MessageID: 11,
+		NewReaction: []reaction{{Type: "emoji", Emoji: "🤝"}}}))
+}
+
+func TestHandleLinkMessage(t *testing.T) {
+	t.Parallel()
+
+	_, client := newFakeBotAPI(t, nil)
+	bot := &TelegramBot{Client: client, Bot: transport.NewBot(nil, nil, 1)}
+	var got service.LinksRequest
+	bot.SetLinkHandler(func(req service.LinksRequest) (string, error) {
+		got = req
+		return "", nil
+	})
+
+	require.NoError(t, bot.handleUpdate(update{Message: &message{
+		MessageID: 10,
+		From:      &user{ID: 42, FirstName: "Dana"},
+		Chat:      chat{ID: -100, Type: "supergroup", Title: "Lunch"},
+		Text:      "Join https://wolt.com/en/isr/tel-aviv/venue/pizza and this",
+		Entities:  []entity{{Type: "text_link", URL: "https://www.wolt.com/group/ABCD"}},
+	}}))
+	assert.Equal(t, service.LinksRequest{
+		Links: []service.Link{
+			{Domain: "wolt.com", URL: "https://wolt.com/en/isr/tel-aviv/venue/pizza"},
+			{Domain: "wolt.com", URL: "https://www.wolt.com/group/ABCD"},
+		},
+		MessageID: "10",
+		Channel:   "-100",
+		Text:      "Join https://wolt.com/en/isr/tel-aviv/venue/pizza and this",
+		UserID:    "42",
+	}, got)
+
+	name, ok := client.name("42")
+	require.True(t, ok)
+	assert.Equal(t, "Dana", name)
+	name, ok = client.name("-100")
+	require.True(t, ok)
+	assert.Equal(t, "Lunch", name)
+}
@@ -0,0 +1,85 @@
+package transport
+
+import "sync"
+
+// Message is a recent message, kept for the reactions to it, as the transports don't send the reacted message with the reaction
+type Message struct {
+	UserID   string
+	ThreadID string // The message the message replies to (directly or through other replies), empty if it's not a reply
+	Text     string
+}
+
+type messageKey struct {
+	channelID string
+	messageID string
+}
+
+// MessageCache keeps the recent messages by their channels and IDs, evicting the oldest ones. The transports whose message IDs
+// are unique across channels use an empty channel.
+type MessageCache struct {
+	lock     sync.Mutex
+	size     int
+	messages map[messageKey]*Message
+	queue    []messageKey // The keys of the cached messages from the oldest
+	evicted  func(channelID, messageID string)
+}
+
+func NewMessageCache(size int) *MessageCache {
+	return &MessageCache{size: size, messages: make(map[messageKey]*Message)}
+}
+
+// OnEvict registers a function called with every message evicted from the cache, for transports keeping more of its state
+func (c *MessageCache) OnEvict(evicted func(channelID, messageID string)) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.evicted = evicted
+}
+
+// Add keeps the message, or updates the text of a message which is already kept, as when it's edited
+func (c *MessageCache) Add(channelID, messageID string, message Message) {
+	evicted, onEvict := c.add(messageKey{channelID: channelID, messageID: messageID}, message)
+	if evicted != nil && onEvict != nil {
+		onEvict(evicted.channelID, evicted.messageID)
+	}
+}
+
+func (c *MessageCache) add(key messageKey, message Message) (*messageKey, func(channelID, messageID string)) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if cached, ok := c.messages[key]; ok {
+		cached.Text = message.Text
+		return nil, nil
+	}
+	c.messages[key] = &message
+	c.queue = append(c.queue, key)
+	if len(c.queue) <= c.size {
+		return nil, nil
+	}
+	evicted := c.queue[0]
+	delete(c.messages, evicted)
+	c.queue = c.queue[1:]
+	return &evicted, c.evicted
+}
+
+// Get returns a copy of the message, if it's kept
+func (c *MessageCache) Get(channelID, messageID string) (*Message, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	cached, ok := c.messages[messageKey{channelID: channelID, messageID: messageID}]
+	if !ok {
+		return nil, false
+	}
+	copied := *cached
+	return &copied, true
+}
+
+// ThreadOf returns the thread of a reply to the given message: the message's own thread, or the message itself if it isn't a reply
+func (c *MessageCache) ThreadOf(channelID, messageID string) string {
+	if messageID == "" {
+		return ""
+	}
+	if replied, ok := c.Get(channelID, messageID); ok && replied.ThreadID != "" {
+		return replied.ThreadID
+	}
+	return messageID
+}
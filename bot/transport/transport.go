@@ -0,0 +1,163 @@
+// Package transport has the plumbing shared by the chat transports which Bolt runs on through their bot APIs (Discord, Telegram,
+// Mattermost and WhatsApp): handling their messages, reactions and /adduser commands with the service, caching their recent
+// messages for the reactions to them and wrapping their errors of unavailable receivers. Each transport adapts its own events to it.
+package transport
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/oriser/bolt/service"
+	userDomain "github.com/oriser/bolt/user"
+)
+
+// Bot handles the messages of a transport with the service, on up to the configured number of events at a time
+type Bot struct {
+	Service     *service.Service
+	admins      map[string]bool
+	workers     chan struct{}
+	linkHandler func(req service.LinksRequest) (string, error)
+}
+
+func NewBot(serviceHandler *service.Service, adminUserIDs []string, maxConcurrent int) *Bot {
+	if maxConcurrent <= 0 {
+		maxConcurrent = 1
+	}
+	b := &Bot{
+		Service: serviceHandler,
+		admins:  make(map[string]bool),
+		workers: make(chan struct{}, maxConcurrent),
+	}
+	b.linkHandler = serviceHandler.HandleLinkMessage
+	for _, userID := range adminUserIDs {
+		b.admins[userID] = true
+	}
+	return b
+}
+
+// SetLinkHandler replaces handling links in-process, for example for passing them to the order monitoring workers of another process
+func (b *Bot) SetLinkHandler(handler func(req service.LinksRequest) (string, error)) {
+	b.linkHandler = handler
+}
+
+// Go handles an event in the background, waiting for one of the events being handled to finish if there are already as many as the
+// maximum
+func (b *Bot) Go(handle func()) {
+	b.workers <- struct{}{}
+	go func() {
+		defer func() { <-b.workers }()
+		handle()
+	}()
+}
+
+// HandleLinks passes a message with links to the link handler, and replies with its response
+func (b *Bot) HandleLinks(req service.LinksRequest, reply func(text string) error) error {
+	response, err := b.linkHandler(req)
+	if err != nil {
+		return fmt.Errorf("link handler: %w", err)
+	}
+	return sendResponse(response, reply)
+}
+
+// HandleReaction passes a reaction to the service, and replies with its response
+func (b *Bot) HandleReaction(req service.ReactionAddRequest, reply func(text string) error) error {
+	response, err := b.Service.HandleReactionAdded(req)
+	if err != nil {
+		return fmt.Errorf("reaction add handler: %w", err)
+	}
+	return sendResponse(response, reply)
+}
+
+// HandleMention passes a mention of Bolt, or a command in the thread of an order, to the service, and replies with its response
+func (b *Bot) HandleMention(req service.MentionRequest, threadCommand bool, reply func(text string) error) error {
+	handle := b.Service.HandleMention
+	if threadCommand {
+		handle = b.Service.HandleThreadCommand
+	}
+	response, err := handle(req)
+	if err != nil {
+		return fmt.Errorf("mention handler: %w", err)
+	}
+	return sendResponse(response, reply)
+}
+
+func sendResponse(response string, reply func(text string) error) error {
+	if response == "" {
+		return nil
+	}
+	if err := reply(response); err != nil {
+		return fmt.Errorf("send message: %w", err)
+	}
+	return nil
+}
+
+// AddUserRequest is an /adduser command, adding the user under their name in Wolt
+type AddUserRequest struct {
+	SenderID string
+	AddedID  string // The sender when they register themselves
+	Name     string
+}
+
+// AddUser handles an /adduser command and replies with its outcome, or with the usage if it has no name. The sender registers
+// themselves under their Wolt name, admins can add other users, and hosts can link the unknown participants of their orders.
+func (b *Bot) AddUser(req AddUserRequest, usage string, reply func(text string) error) error {
+	if err := b.Service.AllowCommand(req.SenderID); err != nil {
+		return reply(err.Error())
+	}
+	name := strings.Trim(strings.TrimSpace(req.Name), `"`)
+	if name == "" {
+		return reply(usage)
+	}
+
+	ctx := context.Background()
+	user := &userDomain.User{FullName: name, TransportID: req.AddedID}
+	var err error
+	switch {
+	case req.AddedID == req.SenderID:
+		err = b.Service.RegisterWoltName(ctx, user)
+	case b.admins[req.SenderID]:
+		err = b.Service.HandleAddTransportUser(user)
+	default:
+		err = b.Service.LinkUnknownParticipant(ctx, req.SenderID, user)
+	}
+	if err != nil {
+		_ = reply(fmt.Sprintf("Error adding user: %v", err))
+		return err
+	}
+	return reply(fmt.Sprintf("OK, got you. I added <@%s> as %q", req.AddedID, name))
+}
+
+// Links returns the links of the URLs found in a message
+func Links(urls []string) []service.Link {
+	ret := make([]service.Link, 0, len(urls))
+	for _, link := range urls {
+		u, err := url.Parse(link)
+		if err != nil {
+			continue
+		}
+		ret = append(ret, service.Link{Domain: strings.TrimPrefix(u.Hostname(), "www."), URL: link})
+	}
+	return ret
+}
+
+// ReactionName returns Bolt's (Slack) name of a reaction emoji, by the transport's emojis of the names. The "+1" alias of
+// "thumbsup" is never returned.
+func ReactionName(emojis map[string]string, emoji string) (string, bool) {
+	for name, e := range emojis {
+		if e == emoji && name != "+1" {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// UnavailableError wraps the reason a transport couldn't reach a receiver with the service error for it, so the orders which can't
+// be tracked anymore are stopped
+func UnavailableError(toUser bool, reason string) error {
+	if toUser {
+		return fmt.Errorf("%w: %s", service.ErrUserUnavailable, reason)
+	}
+	return fmt.Errorf("%w: %s", service.ErrChannelUnavailable, reason)
+}
@@ -0,0 +1,87 @@
+package transport
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/oriser/bolt/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageCache(t *testing.T) {
+	t.Parallel()
+
+	cache := NewMessageCache(2)
+	var evicted []string
+	cache.OnEvict(func(channelID, messageID string) {
+		evicted = append(evicted, channelID+"/"+messageID)
+	})
+	cache.Add("C1", "1", Message{UserID: "U1", Text: "order link"})
+	cache.Add("C1", "2", Message{UserID: "BOT", ThreadID: cache.ThreadOf("C1", "1"), Text: "Joined"})
+	cache.Add("C1", "2", Message{UserID: "BOT", Text: "Joined the order"})
+
+	cached, ok := cache.Get("C1", "2")
+	require.True(t, ok)
+	assert.Equal(t, Message{UserID: "BOT", ThreadID: "1", Text: "Joined the order"}, *cached, "an edit updates only the text")
+	assert.Equal(t, "1", cache.ThreadOf("C1", "2"), "the replies to a reply are in the thread of the first message")
+	assert.Equal(t, "5", cache.ThreadOf("C1", "5"))
+	assert.Empty(t, cache.ThreadOf("C1", ""))
+	_, ok = cache.Get("C2", "1")
+	assert.False(t, ok, "the messages are kept by their channels")
+
+	cache.Add("C1", "3", Message{UserID: "U2"})
+	_, ok = cache.Get("C1", "1")
+	assert.False(t, ok, "the oldest message is evicted")
+	assert.Equal(t, []string{"C1/1"}, evicted)
+}
+
+func TestHandleLinks(t *testing.T) {
+	t.Parallel()
+
+	b := NewBot(nil, nil, 1)
+	b.SetLinkHandler(func(req service.LinksRequest) (string, error) {
+		return "Tracking " + req.Links[0].Domain, nil
+	})
+	var replies []string
+	reply := func(text string) error {
+		replies = append(replies, text)
+		return nil
+	}
+	links := Links([]string{"https://www.wolt.com/en/isr/tel-aviv/venue/pizza", "wolt://group/ABC"})
+	assert.Equal(t, []service.Link{
+		{Domain: "wolt.com", URL: "https://www.wolt.com/en/isr/tel-aviv/venue/pizza"},
+		{Domain: "group", URL: "wolt://group/ABC"},
+	}, links)
+	require.NoError(t, b.HandleLinks(service.LinksRequest{Links: links}, reply))
+	assert.Equal(t, []string{"Tracking wolt.com"}, replies)
+
+	b.SetLinkHandler(func(service.LinksRequest) (string, error) {
+		return "", nil
+	})
+	require.NoError(t, b.HandleLinks(service.LinksRequest{Links: links}, reply))
+	assert.Len(t, replies, 1, "empty responses aren't sent")
+}
+
+func TestReactionName(t *testing.T) {
+	t.Parallel()
+
+	emojis := map[string]string{"thumbsup": "👍", "+1": "👍", "money_mouth_face": "🤑"}
+	name, ok := ReactionName(emojis, "🤑")
+	require.True(t, ok)
+	assert.Equal(t, "money_mouth_face", name)
+	name, ok = ReactionName(emojis, "👍")
+	require.True(t, ok)
+	assert.Equal(t, "thumbsup", name)
+	_, ok = ReactionName(emojis, "🦄")
+	assert.False(t, ok)
+}
+
+func TestUnavailableError(t *testing.T) {
+	t.Parallel()
+
+	err := UnavailableError(false, "Missing Access")
+	assert.True(t, errors.Is(err, service.ErrChannelUnavailable))
+	assert.ErrorContains(t, err, "Missing Access")
+	assert.True(t, errors.Is(UnavailableError(true, "blocked"), service.ErrUserUnavailable))
+}
@@ -0,0 +1,313 @@
+package whatsapp
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/oriser/bolt/bot/transport"
+	"github.com/oriser/bolt/service"
+)
+
+// maxWebhookBody is the size of the largest webhook request body which is read
+const maxWebhookBody = 1 << 20
+
+var (
+	urlRe     = regexp.MustCompile(`(?:https?|wolt)://[^\s<>"]+`)
+	commandRe = regexp.MustCompile(`^/([a-z_]+)(?:\s+(.*))?$`)
+	// phoneMentionRe matches the @<phone number> mentions of WhatsApp groups
+	phoneMentionRe = regexp.MustCompile(`(^|[^A-Za-z0-9<])@([0-9]{6,15})\b`)
+)
+
+type contact struct {
+	Profile struct {
+		Name string `json:"name"`
+	} `json:"profile"`
+	WaID string `json:"wa_id"`
+}
+
+type message struct {
+	From    string `json:"from"`
+	ID      string `json:"id"`
+	GroupID string `json:"group_id"` // Empty for messages of chats with the business number
+	Type    string `json:"type"`
+	Text    struct {
+		Body string `json:"body"`
+	} `json:"text"`
+	Context *struct {
+		From string `json:"from"`
+		ID   string `json:"id"`
+	} `json:"context"` // The message the message replies to, or the message of the clicked button
+	Reaction *struct {
+		MessageID string `json:"message_id"`
+		Emoji     string `json:"emoji"` // Empty when the reaction is removed
+	} `json:"reaction"`
+	Interactive *struct {
+		Type        string `json:"type"`
+		ButtonReply *struct {
+			ID    string `json:"id"`
+			Title string `json:"title"`
+		} `json:"button_reply"`
+	} `json:"interactive"`
+}
+
+// chatID returns the chat the message was sent in: its group, or the chat of its sender with the business number
+func (m *message) chatID() string {
+	if m.GroupID != "" {
+		return m.GroupID
+	}
+	return m.From
+}
+
+type webhookValue struct {
+	Metadata struct {
+		DisplayPhoneNumber string `json:"display_phone_number"`
+		PhoneNumberID      string `json:"phone_number_id"`
+	} `json:"metadata"`
+	Contacts []contact `json:"contacts"`
+	Messages []message `json:"messages"`
+}
+
+type webhook struct {
+	Object string `json:"object"`
+	Entry  []struct {
+		Changes []struct {
+			Field string       `json:"field"`
+			Value webhookValue `json:"value"`
+		} `json:"changes"`
+	} `json:"entry"`
+}
+
+type WhatsAppBot struct {
+	*Client
+	*transport.Bot
+}
+
+func (c *Client) ServiceBot(serviceHandler *service.Service) *WhatsAppBot {
+	return &WhatsAppBot{Client: c, Bot: transport.NewBot(serviceHandler, c.cfg.AdminUserIDs, c.cfg.MaxConcurrent)}
+}
+
+// ListenAndServe serves the webhook WhatsApp sends the messages to on WHATSAPP_WEBHOOK_PATH, and the API and the dashboard (when
+// enabled), on WHATSAPP_SERVER_PORT
+func (b *WhatsAppBot) ListenAndServe(_ context.Context) error {
+	http.HandleFunc(b.cfg.WebhookPath, b.webhookEndpoint)
+
+	log.Println("Server listening on port", b.cfg.Port)
+	return http.ListenAndServe(fmt.Sprintf(":%d", b.cfg.Port), nil)
+}
+
+func (b *WhatsAppBot) webhookEndpoint(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		b.verifySubscription(w, r)
+	case http.MethodPost:
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if !validSignature(b.cfg.AppSecret, body, r.Header.Get("X-Hub-Signature-256")) {
+			log.Println("Got a WhatsApp webhook request with an invalid signature")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var hook webhook
+		if err := json.Unmarshal(body, &hook); err != nil {
+			log.Println("Error parsing WhatsApp webhook:", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		// WhatsApp retries the requests which aren't answered quickly, while a Wolt group link holds its handler until the group is
+		// finished, so the messages are handled in the background
+		b.handleWebhook(hook)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// verifySubscription answers the challenge WhatsApp sends when the webhook is subscribed, if the verify token matches
+func (b *WhatsAppBot) verifySubscription(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("hub.mode") != "subscribe" || b.cfg.VerifyToken == "" ||
+		!hmac.Equal([]byte(query.Get("hub.verify_token")), []byte(b.cfg.VerifyToken)) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	_, _ = w.Write([]byte(query.Get("hub.challenge")))
+}
+
+// validSignature returns whether the signature header is the SHA-256 HMAC of the body with the app secret
+func validSignature(appSecret string, body []byte, header string) bool {
+	signature, err := hex.DecodeString(strings.TrimPrefix(header, "sha256="))
+	if err != nil || !strings.HasPrefix(header, "sha256=") {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(appSecret))
+	mac.Write(body)
+	return hmac.Equal(signature, mac.Sum(nil))
+}
+
+func (b *WhatsAppBot) handleWebhook(hook webhook) {
+	for _, entry := range hook.Entry {
+		for _, change := range entry.Changes {
+			if change.Field != "messages" || change.Value.Metadata.PhoneNumberID != b.cfg.PhoneNumberID {
+				continue
+			}
+			for _, c := range change.Value.Contacts {
+				b.rememberName(c.WaID, c.Profile.Name)
+			}
+			for _, m := range change.Value.Messages {
+				m := m
+				b.Go(func() {
+					if err := b.handleMessage(&m); err != nil {
+						log.Printf("Error handling WhatsApp message %s: %v\n", m.ID, err)
+					}
+				})
+			}
+		}
+	}
+}
+
+// replier returns the function replying in the chat, to the message if it's given
+func (b *WhatsAppBot) replier(chatID, messageID string) func(text string) error {
+	return func(text string) error {
+		_, err := b.SendMessage(chatID, text, messageID)
+		return err
+	}
+}
+
+// parseText converts the @<phone number> mentions of a message to Slack's mentions (<@ID>), which the service expects. The mentions of
+// the business number are of Bolt.
+func (c *Client) parseText(text string) string {
+	selfPhone := c.selfPhoneNumber()
+	return phoneMentionRe.ReplaceAllStringFunc(text, func(match string) string {
+		groups := phoneMentionRe.FindStringSubmatch(match)
+		id := groups[2]
+		if id == selfPhone {
+			id = c.cfg.PhoneNumberID
+		}
+		return groups[1] + "<@" + id + ">"
+	})
+}
+
+func (b *WhatsAppBot) handleMessage(m *message) error {
+	chatID := m.chatID()
+	if _, ok := b.messages.Get(chatID, m.ID); ok {
+		// WhatsApp may deliver a message more than once
+		return nil
+	}
+	switch m.Type {
+	case "reaction":
+		b.messages.Add(chatID, m.ID, transport.Message{UserID: m.From})
+		if m.Reaction == nil || m.Reaction.Emoji == "" {
+			return nil
+		}
+		name, ok := transport.ReactionName(emojis, m.Reaction.Emoji)
+		if !ok {
+			return nil
+		}
+		return b.handleReaction(b.reactionRequest(chatID, m.From, m.Reaction.MessageID, name))
+	case "interactive":
+		// The quick-reply buttons emulate reactions to the message they were sent with
+		b.messages.Add(chatID, m.ID, transport.Message{UserID: m.From})
+		if m.Interactive == nil || m.Interactive.ButtonReply == nil || m.Context == nil {
+			return nil
+		}
+		name := strings.TrimPrefix(m.Interactive.ButtonReply.ID, reactionButtonPrefix)
+		if _, ok := reactionButtons[name]; !ok {
+			return nil
+		}
+		return b.handleReaction(b.reactionRequest(chatID, m.From, b.buttonsTarget(chatID, m.Context.ID), name))
+	case "text":
+		return b.handleText(m)
+	}
+	return nil
+}
+
+func (b *WhatsAppBot) handleText(m *message) error {
+	chatID := m.chatID()
+	threadID := ""
+	if m.Context != nil {
+		threadID = b.messages.ThreadOf(chatID, m.Context.ID)
+	}
+	text := m.Text.Body
+	b.messages.Add(chatID, m.ID, transport.Message{UserID: m.From, ThreadID: threadID, Text: text})
+
+	if match := commandRe.FindStringSubmatch(text); match != nil {
+		return b.handleCommand(m, match[1], strings.TrimSpace(match[2]))
+	}
+
+	if found := transport.Links(urlRe.FindAllString(text, -1)); len(found) > 0 {
+		req := service.LinksRequest{Links: found, MessageID: m.ID, Channel: chatID, Text: b.parseText(text), UserID: m.From}
+		return b.HandleLinks(req, b.replier(chatID, ""))
+	}
+
+	text = b.parseText(text)
+	mention := fmt.Sprintf("<@%s>", b.cfg.PhoneNumberID)
+	repliesToBot := false
+	if m.Context != nil {
+		cached, ok := b.messages.Get(chatID, m.Context.ID)
+		repliesToBot = ok && cached.UserID == b.cfg.PhoneNumberID
+	}
+	threadCommand := threadID != "" && service.IsThreadCommand(text)
+	switch {
+	case threadCommand:
+		// The commands in the thread of an order are to Bolt without mentioning it
+	case isUserID(chatID):
+		// Every message of a chat with the business number is to Bolt
+		if !strings.Contains(text, mention) {
+			text = mention + " " + text
+		}
+	case !strings.Contains(text, mention) && !repliesToBot:
+		return nil
+	}
+	req := service.MentionRequest{Channel: chatID, MessageID: m.ID, ThreadID: threadID, UserID: m.From, Text: text}
+	return b.HandleMention(req, threadCommand, b.replier(chatID, m.ID))
+}
+
+func (b *WhatsAppBot) handleReaction(req service.ReactionAddRequest) error {
+	return b.HandleReaction(req, b.replier(req.Channel, ""))
+}
+
+// handleCommand handles the bot commands. /adduser <Wolt name> registers the sender under their Wolt name. Admins can add other users
+// by replying to their message with it, and hosts can link the unknown participants of their orders the same way.
+func (b *WhatsAppBot) handleCommand(m *message, command, args string) error {
+	chatID := m.chatID()
+	if command != "adduser" {
+		return nil
+	}
+	added := m.From
+	if m.Context != nil {
+		if replied, ok := b.messages.Get(chatID, m.Context.ID); ok && replied.UserID != m.From && replied.UserID != b.cfg.PhoneNumberID {
+			added = replied.UserID
+		}
+	}
+	req := transport.AddUserRequest{SenderID: m.From, AddedID: added, Name: args}
+	return b.AddUser(req, "USAGE: /adduser <your name in Wolt>, or reply with it to the message of the user to add (admins, or hosts "+
+		"for the participants of their orders I couldn't find)", b.replier(chatID, m.ID))
+}
+
+// reactionRequest returns the request of a reaction added to a message, with the message as cached when it was sent or received
+func (c *Client) reactionRequest(chatID, userID, messageID, reaction string) service.ReactionAddRequest {
+	cached, ok := c.messages.Get(chatID, messageID)
+	if !ok {
+		log.Printf("Got a reaction to message %s of chat %s, which isn't in the recent messages\n", messageID, chatID)
+		cached = &transport.Message{}
+	}
+	return service.ReactionAddRequest{
+		Reaction:      reaction,
+		FromUserID:    userID,
+		Channel:       chatID,
+		MessageUserID: cached.UserID,
+		MessageID:     messageID,
+		MessageText:   cached.Text,
+	}
+}
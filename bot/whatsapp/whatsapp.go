@@ -0,0 +1,474 @@
+package whatsapp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oriser/bolt/bot/transport"
+)
+
+const (
+	// messagesCacheSize is how many recent messages are kept for resolving the messages reactions and button replies are to, as
+	// WhatsApp sends only the ID of the message with them
+	messagesCacheSize = 10000
+	// maxMessageLength is the length of the longest text message WhatsApp accepts
+	maxMessageLength = 4096
+	// maxButtonsBodyLength is the length of the longest text of a message with buttons. Longer messages get their buttons in a
+	// message of their own.
+	maxButtonsBodyLength = 1024
+	maxButtons           = 3  // WhatsApp allows up to 3 quick-reply buttons in a message
+	maxButtonTitle       = 20 // The length of the longest title of a button
+	// reactionButtonPrefix prefixes the IDs of the quick-reply buttons which emulate reactions, followed by the reaction's name
+	reactionButtonPrefix = "reaction:"
+)
+
+var (
+	mentionRe    = regexp.MustCompile(`<@([A-Za-z0-9_=-]+)>`)
+	channelRe    = regexp.MustCompile(`<#([A-Za-z0-9_=-]+)>`)
+	slackDateRe  = regexp.MustCompile(`<!date\^\d+\^[^|>]*\|([^>]*)>`)
+	slackLinkRe  = regexp.MustCompile(`<(https?://[^|>\s]+)\|([^>]+)>`)
+	emojiRe      = regexp.MustCompile(`:([a-z0-9_+-]+):`)
+	phoneRe      = regexp.MustCompile(`^[0-9]+$`)
+	notInGroupRe = regexp.MustCompile(`(?i)not a (group )?participant|group (does not exist|not found)`)
+)
+
+// emojis maps the emoji names Bolt's messages use (as in Slack) to their characters. WhatsApp allows any emoji as a reaction.
+var emojis = map[string]string{
+	"eyes":                         "👀",
+	"money_mouth_face":             "🤑",
+	"x":                            "❌",
+	"no_entry_sign":                "🚫",
+	"white_check_mark":             "✅",
+	"credit_card":                  "💳",
+	"handshake":                    "🤝",
+	"thumbsup":                     "👍",
+	"+1":                           "👍",
+	"fire":                         "🔥",
+	"tada":                         "🎉",
+	"pray":                         "🙏",
+	"sleeping":                     "😴",
+	"100":                          "💯",
+	"house":                        "🏠",
+	"bike":                         "🚲",
+	"cook":                         "🧑‍🍳",
+	"underage":                     "🔞",
+	"warning":                      "⚠️",
+	"hourglass_flowing_sand":       "⏳",
+	"busts_in_silhouette":          "👥",
+	"bar_chart":                    "📊",
+	"stuck_out_tongue_winking_eye": "😜",
+	"wave":                         "👋",
+	"crown":                        "👑",
+	"zap":                          "⚡",
+	"compass":                      "🧭",
+	"trophy":                       "🏆",
+	"twisted_rightwards_arrows":    "🔀",
+	"red_circle":                   "🔴",
+	"large_yellow_circle":          "🟡",
+	"large_green_circle":           "🟢",
+	"lock":                         "🔒",
+	"receipt":                      "🧾",
+	"round_pushpin":                "📍",
+	"thinking_face":                "🤔",
+}
+
+// reactionButtons are the titles of the quick-reply buttons added to Bolt's messages which ask for these reactions, by the reactions'
+// names. Clicking a button is handled as adding its reaction to the message.
+var reactionButtons = map[string]string{
+	"money_mouth_face": "🤑 Paid",
+	"x":                "❌ Remove debts",
+	"white_check_mark": "✅ Confirm",
+	"no_entry_sign":    "🚫 Skip",
+	"credit_card":      "💳 Company paid",
+	"handshake":        "🤝 Merge",
+}
+
+type Config struct {
+	AccessToken   string   `env:"WHATSAPP_ACCESS_TOKEN" json:"-"`
+	PhoneNumberID string   `env:"WHATSAPP_PHONE_NUMBER_ID"`
+	AppSecret     string   `env:"WHATSAPP_APP_SECRET" json:"-"`   // Verifies the signatures of the webhook's requests
+	VerifyToken   string   `env:"WHATSAPP_VERIFY_TOKEN" json:"-"` // Verifies the subscription of the webhook
+	APIURL        string   `env:"WHATSAPP_API_URL" envDefault:"https://graph.facebook.com/v21.0"`
+	Port          uint     `env:"WHATSAPP_SERVER_PORT" envDefault:"8080"` // Port of the webhook, the API and the dashboard server
+	WebhookPath   string   `env:"WHATSAPP_WEBHOOK_PATH" envDefault:"/whatsapp"`
+	MaxConcurrent int      `env:"WHATSAPP_MAX_CONCURRENT_MESSAGES" envDefault:"100"`
+	AdminUserIDs  []string `env:"WHATSAPP_ADMIN_USER_IDS"`
+	ResendEdits   bool     `env:"WHATSAPP_RESEND_EDITS" envDefault:"false"` // WhatsApp can't edit messages, so edits are sent as replies
+}
+
+// Client is a transport for WhatsApp chats over the WhatsApp Business Cloud API, implementing the service's event notification.
+// The receivers are the phone numbers (WhatsApp IDs) of users for chats with the business number, or group IDs, and the message IDs
+// are WhatsApp's message IDs.
+type Client struct {
+	cfg    Config
+	client *http.Client
+
+	lock      sync.Mutex
+	selfPhone string                  // The display phone number of the business number, for detecting mentions
+	names     map[string]string       // The profile names of users by their WhatsApp IDs, for rendering mentions
+	messages  *transport.MessageCache // The recent messages, for the reactions and the button replies to them
+	// buttonsOf has the messages whose buttons didn't fit in them and were sent in messages of their own, by the keys of the messages
+	// with the buttons
+	buttonsOf  map[string]string
+	lastEdited map[string]string // The texts the edits of the messages were last resent with, by the messages' keys
+}
+
+func NewClient(cfg Config) *Client {
+	c := &Client{
+		cfg:        cfg,
+		client:     &http.Client{Timeout: 30 * time.Second},
+		names:      make(map[string]string),
+		messages:   transport.NewMessageCache(messagesCacheSize),
+		buttonsOf:  make(map[string]string),
+		lastEdited: make(map[string]string),
+	}
+	c.messages.OnEvict(c.forgetMessage)
+	return c
+}
+
+// apiError is an error response of the Graph API
+type apiError struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+	Code    int    `json:"code"`
+	Details struct {
+		Details string `json:"details"`
+	} `json:"error_data"`
+}
+
+func (e *apiError) Error() string {
+	if e.Details.Details != "" {
+		return fmt.Sprintf("whatsapp error %d: %s (%s)", e.Code, e.Message, e.Details.Details)
+	}
+	return fmt.Sprintf("whatsapp error %d: %s", e.Code, e.Message)
+}
+
+func (c *Client) endpoint(path string) string {
+	return strings.TrimSuffix(c.cfg.APIURL, "/") + "/" + strings.TrimPrefix(path, "/")
+}
+
+func (c *Client) call(ctx context.Context, method, path string, params interface{}, result interface{}) error {
+	var body []byte
+	if params != nil {
+		var err error
+		if body, err = json.Marshal(params); err != nil {
+			return fmt.Errorf("marshal params: %w", err)
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint(path), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.cfg.AccessToken)
+	if params != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var response struct {
+			Error apiError `json:"error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+			return fmt.Errorf("decode error response with status %d: %w", resp.StatusCode, err)
+		}
+		return &response.Error
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// isUserID returns whether the chat ID is of a chat with a user, whose ID is their phone number, rather than of a group
+func isUserID(chatID string) bool {
+	return phoneRe.MatchString(chatID)
+}
+
+// transportError wraps the errors of groups Bolt isn't in anymore with the service error for them, so the orders which can't be
+// tracked anymore are stopped. The errors of users aren't wrapped, as WhatsApp fails the messages to users who didn't write to the
+// business number in the last 24 hours, though they're still around.
+func transportError(receiver string, err error) error {
+	apiErr, ok := err.(*apiError)
+	if !ok || isUserID(receiver) || !notInGroupRe.MatchString(apiErr.Message+" "+apiErr.Details.Details) {
+		return err
+	}
+	return transport.UnavailableError(false, apiErr.Error())
+}
+
+type phoneNumber struct {
+	ID                 string `json:"id"`
+	DisplayPhoneNumber string `json:"display_phone_number"`
+	VerifiedName       string `json:"verified_name"`
+}
+
+func (c *Client) phoneNumber(ctx context.Context) (*phoneNumber, error) {
+	var number phoneNumber
+	if err := c.call(ctx, http.MethodGet, c.cfg.PhoneNumberID+"?fields=display_phone_number,verified_name", nil, &number); err != nil {
+		return nil, fmt.Errorf("get phone number: %w", err)
+	}
+	return &number, nil
+}
+
+// CheckConnection checks that WhatsApp can be reached and the access token is valid for the phone number
+func (c *Client) CheckConnection(ctx context.Context) error {
+	_, err := c.phoneNumber(ctx)
+	return err
+}
+
+// GetSelfID returns the ID of the business phone number, which Bolt's messages are sent from
+func (c *Client) GetSelfID() (string, error) {
+	number, err := c.phoneNumber(context.Background())
+	if err != nil {
+		return "", err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.selfPhone = digits(number.DisplayPhoneNumber)
+	c.names[c.cfg.PhoneNumberID] = number.VerifiedName
+	return c.cfg.PhoneNumberID, nil
+}
+
+// digits returns the digits of a phone number, which is how WhatsApp IDs and mentions write it
+func digits(phone string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, phone)
+}
+
+func (c *Client) selfPhoneNumber() string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.selfPhone
+}
+
+func messageKey(chatID, messageID string) string {
+	return chatID + "/" + messageID
+}
+
+// forgetMessage drops the state kept for a message evicted from the recent messages
+func (c *Client) forgetMessage(chatID, messageID string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	key := messageKey(chatID, messageID)
+	delete(c.buttonsOf, key)
+	delete(c.lastEdited, key)
+}
+
+// buttonsTarget returns the message the buttons of the given message are for: the message itself, or the message before it if the
+// buttons were sent in a message of their own
+func (c *Client) buttonsTarget(chatID, messageID string) string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if target, ok := c.buttonsOf[messageKey(chatID, messageID)]; ok {
+		return target
+	}
+	return messageID
+}
+
+func (c *Client) rememberName(id, name string) {
+	if name == "" {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.names[id] = name
+}
+
+func (c *Client) name(id string) (string, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	name, ok := c.names[id]
+	return name, ok
+}
+
+// formatText converts a message in Slack's format (which the service uses) to WhatsApp's: user mentions (<@ID>) are written with
+// the users' names, links (<URL|label>) with their URLs, dates with their fallback text and :emoji: names with their characters.
+// The *bold*, _italic_ and `code` formatting is the same in both.
+func (c *Client) formatText(text string) string {
+	text = mentionRe.ReplaceAllStringFunc(text, func(match string) string {
+		id := mentionRe.FindStringSubmatch(match)[1]
+		if name, ok := c.name(id); ok {
+			return "@" + name
+		}
+		return "@" + id
+	})
+	text = channelRe.ReplaceAllString(text, "this chat")
+	text = slackDateRe.ReplaceAllString(text, "$1")
+	text = slackLinkRe.ReplaceAllString(text, "$2 ($1)")
+	return emojiRe.ReplaceAllStringFunc(text, func(match string) string {
+		if e, ok := emojis[strings.Trim(match, ":")]; ok {
+			return e
+		}
+		return match
+	})
+}
+
+// requestedReactions returns the reactions the message asks for, which get quick-reply buttons, in the order they're mentioned
+func requestedReactions(text string) []string {
+	found := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, match := range emojiRe.FindAllStringSubmatch(text, -1) {
+		name := match[1]
+		if _, ok := reactionButtons[name]; !ok || seen[name] {
+			continue
+		}
+		seen[name] = true
+		found = append(found, name)
+		if len(found) == maxButtons {
+			break
+		}
+	}
+	return found
+}
+
+// recipient returns the fields of the message addressing the receiver, a user or a group
+func recipient(receiver string) map[string]interface{} {
+	params := map[string]interface{}{"messaging_product": "whatsapp", "to": receiver, "recipient_type": "individual"}
+	if !isUserID(receiver) {
+		params["recipient_type"] = "group"
+	}
+	return params
+}
+
+type sentMessages struct {
+	Messages []struct {
+		ID string `json:"id"`
+	} `json:"messages"`
+}
+
+// send sends a message to the receiver as a reply to the given message (if it isn't empty), and returns its ID
+func (c *Client) send(receiver, replyTo string, fields map[string]interface{}) (string, error) {
+	params := recipient(receiver)
+	for key, value := range fields {
+		params[key] = value
+	}
+	if replyTo != "" {
+		params["context"] = map[string]string{"message_id": replyTo}
+	}
+	var sent sentMessages
+	if err := c.call(context.Background(), http.MethodPost, c.cfg.PhoneNumberID+"/messages", params, &sent); err != nil {
+		return "", transportError(receiver, err)
+	}
+	if len(sent.Messages) == 0 {
+		return "", fmt.Errorf("no message ID in the response")
+	}
+	return sent.Messages[0].ID, nil
+}
+
+func textFields(text string) map[string]interface{} {
+	return map[string]interface{}{"type": "text", "text": map[string]interface{}{"body": text, "preview_url": false}}
+}
+
+func buttonsFields(text string, reactions []string) map[string]interface{} {
+	buttons := make([]map[string]interface{}, len(reactions))
+	for i, reaction := range reactions {
+		title := []rune(reactionButtons[reaction])
+		if len(title) > maxButtonTitle {
+			title = title[:maxButtonTitle]
+		}
+		buttons[i] = map[string]interface{}{
+			"type":  "reply",
+			"reply": map[string]string{"id": reactionButtonPrefix + reaction, "title": string(title)},
+		}
+	}
+	return map[string]interface{}{
+		"type": "interactive",
+		"interactive": map[string]interface{}{
+			"type":   "button",
+			"body":   map[string]string{"text": text},
+			"action": map[string]interface{}{"buttons": buttons},
+		},
+	}
+}
+
+// SendMessage sends the message, with quick-reply buttons for the reactions it asks for. WhatsApp has no threads, so the messages of
+// a thread are sent as replies to its message.
+func (c *Client) SendMessage(receiver, event, messageID string) (string, error) {
+	text := c.formatText(event)
+	reactions := requestedReactions(event)
+	fields := textFields(text)
+	if len(reactions) > 0 && len([]rune(text)) <= maxButtonsBodyLength {
+		fields = buttonsFields(text, reactions)
+	}
+	id, err := c.send(receiver, messageID, fields)
+	if err != nil {
+		return "", fmt.Errorf("posting message: %w", err)
+	}
+	c.messages.Add(receiver, id, transport.Message{UserID: c.cfg.PhoneNumberID, ThreadID: c.messages.ThreadOf(receiver, messageID), Text: event})
+
+	if len(reactions) > 0 && fields["type"] != "interactive" {
+		// The message is too long for buttons, so they're sent right after it
+		buttonsID, err := c.send(receiver, id, buttonsFields("Or tap to react to the message above:", reactions))
+		if err != nil {
+			return "", fmt.Errorf("posting the buttons of message %s: %w", id, err)
+		}
+		c.lock.Lock()
+		c.buttonsOf[messageKey(receiver, buttonsID)] = id
+		c.lock.Unlock()
+		c.messages.Add(receiver, buttonsID, transport.Message{UserID: c.cfg.PhoneNumberID, ThreadID: c.messages.ThreadOf(receiver, id)})
+	}
+	return id, nil
+}
+
+// EditMessage keeps the new text of the message for the reactions to it. WhatsApp can't edit messages, so the new text is sent as a
+// reply to the message only with WHATSAPP_RESEND_EDITS, and only when it changed since it was last sent.
+func (c *Client) EditMessage(receiver, event, messageID string) error {
+	if messageID == "" {
+		return fmt.Errorf("empty message ID")
+	}
+	c.messages.Add(receiver, messageID, transport.Message{UserID: c.cfg.PhoneNumberID, Text: event})
+	if !c.cfg.ResendEdits {
+		return nil
+	}
+
+	text := c.formatText(event)
+	key := messageKey(receiver, messageID)
+	c.lock.Lock()
+	unchanged := c.lastEdited[key] == text
+	c.lock.Unlock()
+	if unchanged {
+		return nil
+	}
+	if _, err := c.send(receiver, messageID, textFields(text)); err != nil {
+		return fmt.Errorf("editing message %s: %w", messageID, err)
+	}
+	c.lock.Lock()
+	c.lastEdited[key] = text
+	c.lock.Unlock()
+	return nil
+}
+
+func (c *Client) MaxMessageLength() int {
+	return maxMessageLength
+}
+
+func (c *Client) AddReaction(receiver, messageID, reaction string) error {
+	e, ok := emojis[reaction]
+	if !ok {
+		return fmt.Errorf("add reaction: unknown emoji :%s:", reaction)
+	}
+	fields := map[string]interface{}{"type": "reaction", "reaction": map[string]string{"message_id": messageID, "emoji": e}}
+	if _, err := c.send(receiver, "", fields); err != nil {
+		return fmt.Errorf("add reaction: %w", err)
+	}
+	return nil
+}
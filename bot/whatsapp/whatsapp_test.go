@@ -0,0 +1,223 @@
+package whatsapp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/oriser/bolt/bot/transport"
+	"github.com/oriser/bolt/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGraphAPI records the messages sent through the Graph API, answering them with increasing message IDs or with the given error
+type fakeGraphAPI struct {
+	lock     sync.Mutex
+	messages []map[string]interface{}
+	errorRes string
+}
+
+func newFakeGraphAPI(t *testing.T, cfg Config) (*fakeGraphAPI, *Client) {
+	api := &fakeGraphAPI{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(`{"id": "PN1", "display_phone_number": "+972 50-000-0000", "verified_name": "Bolt"}`))
+			return
+		}
+		params := make(map[string]interface{})
+		_ = json.NewDecoder(r.Body).Decode(&params)
+		api.lock.Lock()
+		defer api.lock.Unlock()
+		if api.errorRes != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(api.errorRes))
+			return
+		}
+		api.messages = append(api.messages, params)
+		_, _ = w.Write([]byte(`{"messaging_product": "whatsapp", "messages": [{"id": "wamid.` + string(rune('A'+len(api.messages)-1)) + `"}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	cfg.APIURL = server.URL
+	cfg.PhoneNumberID = "PN1"
+	cfg.MaxConcurrent = 1
+	client := NewClient(cfg)
+	return api, client
+}
+
+func TestFormatText(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(Config{})
+	client.rememberName("972501234567", "Dana")
+	assert.Equal(t, "@Dana joined the order in this chat 👀 `1 & 2` :unknown: @972500000000 at 10:13, pay with Bit (https://pay.example/?a=1&b=2)",
+		client.formatText("<@972501234567> joined the order in <#G1> :eyes: `1 & 2` :unknown: <@972500000000> at <!date^1700000000^{time}|10:13>, "+
+			"pay with <https://pay.example/?a=1&b=2|Bit>"))
+
+	client.selfPhone = "972500000000"
+	client.cfg.PhoneNumberID = "PN1"
+	assert.Equal(t, "<@PN1> split with <@972501234567>, not dana@972501234567", client.parseText("@972500000000 split with @972501234567, not dana@972501234567"))
+}
+
+func TestSendMessage(t *testing.T) {
+	t.Parallel()
+
+	api, client := newFakeGraphAPI(t, Config{})
+	selfID, err := client.GetSelfID()
+	require.NoError(t, err)
+	assert.Equal(t, "PN1", selfID)
+
+	id, err := client.SendMessage("972501234567", "Joined :eyes:", "wamid.X")
+	require.NoError(t, err)
+	assert.Equal(t, "wamid.A", id)
+	assert.Equal(t, map[string]interface{}{
+		"messaging_product": "whatsapp",
+		"recipient_type":    "individual",
+		"to":                "972501234567",
+		"type":              "text",
+		"text":              map[string]interface{}{"body": "Joined 👀", "preview_url": false},
+		"context":           map[string]interface{}{"message_id": "wamid.X"},
+	}, api.messages[0])
+
+	// The reactions the message asks for are buttons
+	id, err = client.SendMessage("G1", "Pay Dana and react with :money_mouth_face:, or :x: to stop tracking", "")
+	require.NoError(t, err)
+	assert.Equal(t, "wamid.B", id)
+	assert.Equal(t, "group", api.messages[1]["recipient_type"])
+	interactive := api.messages[1]["interactive"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"text": "Pay Dana and react with 🤑, or ❌ to stop tracking"}, interactive["body"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"type": "reply", "reply": map[string]interface{}{"id": "reaction:money_mouth_face", "title": "🤑 Paid"}},
+		map[string]interface{}{"type": "reply", "reply": map[string]interface{}{"id": "reaction:x", "title": "❌ Remove debts"}},
+	}, interactive["action"].(map[string]interface{})["buttons"])
+
+	// The buttons of long messages are sent right after them
+	_, err = client.SendMessage("G1", strings.Repeat("a", maxButtonsBodyLength)+" :money_mouth_face:", "")
+	require.NoError(t, err)
+	require.Len(t, api.messages, 4)
+	assert.Equal(t, "text", api.messages[2]["type"])
+	assert.Equal(t, "interactive", api.messages[3]["type"])
+	assert.Equal(t, map[string]interface{}{"message_id": "wamid.C"}, api.messages[3]["context"])
+	assert.Equal(t, "wamid.C", client.buttonsTarget("G1", "wamid.D"))
+}
+
+func TestEditMessage(t *testing.T) {
+	t.Parallel()
+
+	api, client := newFakeGraphAPI(t, Config{})
+	id, err := client.SendMessage("G1", "Rates", "")
+	require.NoError(t, err)
+	require.NoError(t, client.EditMessage("G1", "Rates, delivered", id))
+	assert.Len(t, api.messages, 1, "the edits aren't sent by default")
+	cached, ok := client.messages.Get("G1", id)
+	require.True(t, ok)
+	assert.Equal(t, "Rates, delivered", cached.Text)
+
+	api, client = newFakeGraphAPI(t, Config{ResendEdits: true})
+	id, err = client.SendMessage("G1", "Rates", "")
+	require.NoError(t, err)
+	require.NoError(t, client.EditMessage("G1", "Rates, delivered", id))
+	require.NoError(t, client.EditMessage("G1", "Rates, delivered", id))
+	require.Len(t, api.messages, 2, "the same edit is resent once")
+	assert.Equal(t, map[string]interface{}{"message_id": id}, api.messages[1]["context"])
+}
+
+func TestTransportError(t *testing.T) {
+	t.Parallel()
+
+	api, client := newFakeGraphAPI(t, Config{})
+	api.errorRes = `{"error": {"message": "Invalid parameter", "code": 100, "error_data": {"details": "The business number is not a participant of the group"}}}`
+	_, err := client.SendMessage("G1", "hello", "")
+	assert.True(t, errors.Is(err, service.ErrChannelUnavailable))
+
+	api.errorRes = `{"error": {"message": "Re-engagement message", "code": 131047, "error_data": {"details": "More than 24 hours have passed"}}}`
+	_, err = client.SendMessage("972501234567", "hello", "")
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, service.ErrUserUnavailable), "the user may write again")
+}
+
+func TestValidSignature(t *testing.T) {
+	t.Parallel()
+
+	body := []byte(`{"object": "whatsapp_business_account"}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	assert.True(t, validSignature("secret", body, signature))
+	assert.False(t, validSignature("other", body, signature))
+	assert.False(t, validSignature("secret", body, strings.TrimPrefix(signature, "sha256=")))
+	assert.False(t, validSignature("secret", body, ""))
+}
+
+func TestVerifySubscription(t *testing.T) {
+	t.Parallel()
+
+	bot := &WhatsAppBot{Client: NewClient(Config{VerifyToken: "token"})}
+	recorder := httptest.NewRecorder()
+	bot.webhookEndpoint(recorder, httptest.NewRequest(http.MethodGet, "/whatsapp?hub.mode=subscribe&hub.verify_token=token&hub.challenge=42", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "42", recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	bot.webhookEndpoint(recorder, httptest.NewRequest(http.MethodGet, "/whatsapp?hub.mode=subscribe&hub.verify_token=wrong&hub.challenge=42", nil))
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+}
+
+func TestHandleLinkMessage(t *testing.T) {
+	t.Parallel()
+
+	_, client := newFakeGraphAPI(t, Config{})
+	_, err := client.GetSelfID()
+	require.NoError(t, err)
+	bot := &WhatsAppBot{Client: client, Bot: transport.NewBot(nil, nil, 1)}
+	var got []service.LinksRequest
+	bot.SetLinkHandler(func(req service.LinksRequest) (string, error) {
+		got = append(got, req)
+		return "", nil
+	})
+
+	m := &message{From: "972501234567", ID: "wamid.1", GroupID: "G1", Type: "text"}
+	m.Text.Body = "@972500000000 join https://wolt.com/group/ABC"
+	require.NoError(t, bot.handleMessage(m))
+	require.NoError(t, bot.handleMessage(m), "a message delivered twice is handled once")
+	assert.Equal(t, []service.LinksRequest{{
+		Links:     []service.Link{{Domain: "wolt.com", URL: "https://wolt.com/group/ABC"}},
+		MessageID: "wamid.1",
+		Channel:   "G1",
+		Text:      "<@PN1> join https://wolt.com/group/ABC",
+		UserID:    "972501234567",
+	}}, got)
+}
+
+func TestReactionButtons(t *testing.T) {
+	t.Parallel()
+
+	_, client := newFakeGraphAPI(t, Config{})
+	client.messages.Add("G1", "wamid.R", transport.Message{UserID: "PN1", Text: "Order ABC is done, react with :money_mouth_face:"})
+	client.messages.Add("G1", "wamid.B", transport.Message{UserID: "PN1"})
+	client.buttonsOf[messageKey("G1", "wamid.B")] = "wamid.R"
+
+	assert.Equal(t, "wamid.R", client.buttonsTarget("G1", "wamid.B"), "the buttons sent after a long message are for it")
+	assert.Equal(t, "wamid.R", client.buttonsTarget("G1", "wamid.R"))
+	assert.Equal(t, service.ReactionAddRequest{
+		Reaction:      "money_mouth_face",
+		FromUserID:    "972501234567",
+		Channel:       "G1",
+		MessageUserID: "PN1",
+		MessageID:     "wamid.R",
+		MessageText:   "Order ABC is done, react with :money_mouth_face:",
+	}, client.reactionRequest("G1", "972501234567", client.buttonsTarget("G1", "wamid.B"), "money_mouth_face"))
+	assert.Equal(t, []string{"money_mouth_face", "x"}, requestedReactions(":eyes: :money_mouth_face: :x: :money_mouth_face:"),
+		"the reactions with buttons, once each")
+
+	client.forgetMessage("G1", "wamid.B")
+	assert.Equal(t, "wamid.B", client.buttonsTarget("G1", "wamid.B"), "the buttons are forgotten with their message")
+}
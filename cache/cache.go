@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// memoryPruneSize is how many entries the in-memory cache keeps before pruning the expired ones on the next Set
+const memoryPruneSize = 10000
+
+type Config struct {
+	RedisURL  string        `env:"CACHE_REDIS_URL" json:"-"` // redis://[:<password>@]<host>:<port>[/<db>], the cache is kept in memory without it
+	UsersTTL  time.Duration `env:"CACHE_USERS_TTL"`          // How long the user lookups are cached, 0 disables
+	VenuesTTL time.Duration `env:"CACHE_VENUES_TTL"`         // How long the Wolt venues are cached, 0 disables
+}
+
+// Enabled returns whether anything is cached
+func (c Config) Enabled() bool {
+	return c.UsersTTL > 0 || c.VenuesTTL > 0
+}
+
+// Cache keeps values for a while, for lookups which are expensive to repeat
+type Cache interface {
+	// Get returns the value of the key, and false if it isn't cached or it expired
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set caches the value of the key for the TTL
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Incr increments the counter of the key, which doesn't expire, and returns its new value. Get returns the counter in decimal.
+	Incr(ctx context.Context, key string) (int64, error)
+}
+
+// New returns the Redis cache of CACHE_REDIS_URL, shared by the instances of a multi-instance deployment, or an in-memory cache
+// without it
+func New(cfg Config) (Cache, error) {
+	if cfg.RedisURL == "" {
+		return NewMemory(), nil
+	}
+	return NewRedis(cfg.RedisURL)
+}
+
+type memoryEntry struct {
+	value   []byte
+	expires time.Time // Zero for counters, which don't expire
+}
+
+// Memory is a Cache in the memory of the process
+type Memory struct {
+	lock    sync.Mutex
+	entries map[string]memoryEntry
+}
+
+func NewMemory() *Memory {
+	return &Memory{entries: make(map[string]memoryEntry)}
+}
+
+func (m *Memory) Get(_ context.Context, key string) ([]byte, bool, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	entry, ok := m.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if !entry.expires.IsZero() && !time.Now().Before(entry.expires) {
+		delete(m.entries, key)
+		return nil, false, nil
+	}
+	return entry.value, true, nil
+}
+
+func (m *Memory) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	now := time.Now()
+	if len(m.entries) >= memoryPruneSize {
+		for key, entry := range m.entries {
+			if !entry.expires.IsZero() && !now.Before(entry.expires) {
+				delete(m.entries, key)
+			}
+		}
+	}
+	m.entries[key] = memoryEntry{value: value, expires: now.Add(ttl)}
+	return nil
+}
+
+func (m *Memory) Incr(_ context.Context, key string) (int64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	counter, _ := strconv.ParseInt(string(m.entries[key].value), 10, 64)
+	counter++
+	m.entries[key] = memoryEntry{value: []byte(strconv.FormatInt(counter, 10))}
+	return counter, nil
+}
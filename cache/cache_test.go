@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemory(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := NewMemory()
+	require.NoError(t, m.Set(ctx, "a", []byte("1"), time.Hour))
+	require.NoError(t, m.Set(ctx, "b", []byte("2"), -time.Second))
+	value, ok, err := m.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1"), value)
+	_, ok, err = m.Get(ctx, "b")
+	require.NoError(t, err)
+	assert.False(t, ok, "expired")
+
+	for i := int64(1); i <= 2; i++ {
+		counter, err := m.Incr(ctx, "counter")
+		require.NoError(t, err)
+		assert.Equal(t, i, counter)
+	}
+	value, ok, err = m.Get(ctx, "counter")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("2"), value)
+}
+
+// fakeRedis serves AUTH, SELECT, GET, SET and INCR of the Redis protocol, ignoring the expiry
+type fakeRedis struct {
+	lock     sync.Mutex
+	values   map[string]string
+	commands []string
+}
+
+func (f *fakeRedis) serve(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go f.handle(conn)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func (f *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		count, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		args := make([]string, count)
+		for i := range args {
+			line, _ = reader.ReadString('\n')
+			size, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+			arg := make([]byte, size+2)
+			if _, err := io.ReadFull(reader, arg); err != nil {
+				return
+			}
+			args[i] = string(arg[:size])
+		}
+
+		f.lock.Lock()
+		f.commands = append(f.commands, strings.Join(args, " "))
+		var reply string
+		switch args[0] {
+		case "GET":
+			value, ok := f.values[args[1]]
+			reply = "$-1\r\n"
+			if ok {
+				reply = fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+			}
+		case "SET":
+			f.values[args[1]] = args[2]
+			reply = "+OK\r\n"
+		case "AUTH", "SELECT":
+			reply = "+OK\r\n"
+		case "INCR":
+			counter, _ := strconv.Atoi(f.values[args[1]])
+			f.values[args[1]] = strconv.Itoa(counter + 1)
+			reply = fmt.Sprintf(":%d\r\n", counter+1)
+		default:
+			reply = "-ERR unknown command\r\n"
+		}
+		f.lock.Unlock()
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+func TestRedis(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	server := &fakeRedis{values: make(map[string]string)}
+	r, err := NewRedis("redis://:secret@" + server.serve(t) + "/2")
+	require.NoError(t, err)
+
+	_, ok, err := r.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	require.NoError(t, r.Set(ctx, "a", []byte("line\r\nbreak"), 1500*time.Millisecond))
+	value, ok, err := r.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("line\r\nbreak"), value)
+
+	counter, err := r.Incr(ctx, "counter")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), counter)
+
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	assert.Equal(t, []string{"AUTH secret", "SELECT 2", "GET a", "SET a line\r\nbreak PX 1500", "GET a", "INCR counter"}, server.commands,
+		"the connection is authenticated once and reused")
+}
+
+func TestNewRedisURL(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRedis("redis://cache.internal")
+	require.NoError(t, err)
+	assert.Equal(t, "cache.internal:6379", r.addr)
+	assert.Equal(t, 0, r.db)
+
+	_, err = NewRedis("http://cache.internal")
+	assert.Error(t, err)
+	_, err = NewRedis("redis://cache.internal/first")
+	assert.Error(t, err)
+}
@@ -0,0 +1,222 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	redisTimeout      = 2 * time.Second // The deadline of each command when the context has none
+	redisIdleConns    = 8
+	redisMaxReplySize = 64 << 20
+)
+
+var errRedisNil = errors.New("redis nil reply")
+
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// Redis is a Cache in Redis, shared by the instances of a multi-instance deployment. It speaks just the commands it needs of the Redis
+// protocol (RESP), keeping a few idle connections for reuse.
+type Redis struct {
+	addr     string
+	tls      bool
+	username string
+	password string
+	db       int
+	idle     chan *redisConn
+}
+
+// NewRedis returns the cache of the Redis URL, redis://[<user>:<password>@]<host>:<port>[/<db>] (or rediss:// for TLS). It connects
+// lazily, on the first command.
+func NewRedis(redisURL string) (*Redis, error) {
+	u, err := url.Parse(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse redis URL: %w", err)
+	}
+	if u.Scheme != "redis" && u.Scheme != "rediss" {
+		return nil, fmt.Errorf("expected a redis:// or rediss:// URL but got %q", u.Scheme)
+	}
+	r := &Redis{addr: u.Host, tls: u.Scheme == "rediss", idle: make(chan *redisConn, redisIdleConns)}
+	if u.Port() == "" {
+		r.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		r.username = u.User.Username()
+		r.password, _ = u.User.Password()
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if r.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("expected a database number but got %q", db)
+		}
+	}
+	return r, nil
+}
+
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := r.do(ctx, "GET", key)
+	if errors.Is(err, errRedisNil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return reply, true, nil
+}
+
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	_, err := r.do(ctx, "SET", key, string(value), "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	return err
+}
+
+func (r *Redis) Incr(ctx context.Context, key string) (int64, error) {
+	reply, err := r.do(ctx, "INCR", key)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(string(reply), 10, 64)
+}
+
+// do sends the command and returns its reply, which is nil for the simple replies (like OK) and errRedisNil for the nil one
+func (r *Redis) do(ctx context.Context, args ...string) ([]byte, error) {
+	conn, err := r.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := conn.command(ctx, args...)
+	var redisErr redisError
+	if err != nil && !errors.Is(err, errRedisNil) && !errors.As(err, &redisErr) {
+		// The connection may be in the middle of a reply
+		_ = conn.conn.Close()
+		return nil, fmt.Errorf("redis %s: %w", args[0], err)
+	}
+	r.release(conn)
+	if err != nil && !errors.Is(err, errRedisNil) {
+		return nil, fmt.Errorf("redis %s: %w", args[0], err)
+	}
+	return reply, err
+}
+
+// conn returns an idle connection, or a new one which is authenticated and selected the database
+func (r *Redis) conn(ctx context.Context) (*redisConn, error) {
+	select {
+	case conn := <-r.idle:
+		return conn, nil
+	default:
+	}
+
+	dialer := &net.Dialer{Timeout: redisTimeout}
+	var (
+		conn net.Conn
+		err  error
+	)
+	if r.tls {
+		conn, err = (&tls.Dialer{NetDialer: dialer}).DialContext(ctx, "tcp", r.addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", r.addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("connect to redis: %w", err)
+	}
+	c := &redisConn{conn: conn, reader: bufio.NewReader(conn)}
+	if r.password != "" {
+		auth := []string{"AUTH", r.password}
+		if r.username != "" {
+			auth = []string{"AUTH", r.username, r.password}
+		}
+		if _, err := c.command(ctx, auth...); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("redis auth: %w", err)
+		}
+	}
+	if r.db != 0 {
+		if _, err := c.command(ctx, "SELECT", strconv.Itoa(r.db)); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("redis select: %w", err)
+		}
+	}
+	return c, nil
+}
+
+func (r *Redis) release(conn *redisConn) {
+	select {
+	case r.idle <- conn:
+	default:
+		_ = conn.conn.Close()
+	}
+}
+
+// redisError is an error reply of Redis, after which the connection can still be used
+type redisError string
+
+func (e redisError) Error() string {
+	return string(e)
+}
+
+func (c *redisConn) command(ctx context.Context, args ...string) ([]byte, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(redisTimeout)
+	}
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("*%d\r\n", len(args)))
+	for _, arg := range args {
+		sb.WriteString(fmt.Sprintf("$%d\r\n%s\r\n", len(arg), arg))
+	}
+	if _, err := io.WriteString(c.conn, sb.String()); err != nil {
+		return nil, err
+	}
+	return c.readReply()
+}
+
+func (c *redisConn) readReply() ([]byte, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return nil, nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return []byte(line[1:]), nil
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("bad bulk reply size %q", line[1:])
+		}
+		if size < 0 {
+			return nil, errRedisNil
+		}
+		if size > redisMaxReplySize {
+			return nil, fmt.Errorf("bulk reply of %d bytes is too large", size)
+		}
+		value := make([]byte, size+2)
+		if _, err := io.ReadFull(c.reader, value); err != nil {
+			return nil, err
+		}
+		return value[:size], nil
+	default:
+		return nil, fmt.Errorf("unexpected reply %q", line)
+	}
+}
@@ -0,0 +1,275 @@
+package calendar
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	URL string `env:"QUIET_CALENDAR_URL"` // ICS feed of the meetings Bolt keeps quiet during, empty disables the quiet meetings
+	// Only the meetings whose summary contains one of these (case insensitive) keep Bolt quiet, empty for all the meetings
+	Match []string `env:"QUIET_CALENDAR_MATCH"`
+}
+
+// maxOccurrences bounds the expansion of a recurring meeting, so a feed can't make Bolt loop for long
+const maxOccurrences = 5000
+
+// Meeting is an occurrence of a meeting of the calendar
+type Meeting struct {
+	Summary string
+	Start   time.Time
+	End     time.Time
+}
+
+// Provider returns the meetings of a calendar
+type Provider interface {
+	// Meetings returns the meetings which overlap the given period, by their start
+	Meetings(ctx context.Context, from, to time.Time) ([]Meeting, error)
+}
+
+// Client is a Provider for an ICS feed, such as the secret address of a shared Google or Outlook calendar
+type Client struct {
+	cfg    Config
+	client *http.Client
+}
+
+func NewClient(cfg Config) *Client {
+	return &Client{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (c *Client) Meetings(ctx context.Context, from, to time.Time) ([]Meeting, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.cfg.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d for the calendar", resp.StatusCode)
+	}
+
+	meetings, err := Parse(resp.Body, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("parse calendar: %w", err)
+	}
+	matching := meetings[:0]
+	for _, meeting := range meetings {
+		if c.matches(meeting.Summary) {
+			matching = append(matching, meeting)
+		}
+	}
+	return matching, nil
+}
+
+func (c *Client) matches(summary string) bool {
+	if len(c.cfg.Match) == 0 {
+		return true
+	}
+	for _, match := range c.cfg.Match {
+		if strings.Contains(strings.ToLower(summary), strings.ToLower(strings.TrimSpace(match))) {
+			return true
+		}
+	}
+	return false
+}
+
+// property is a content line of an ICS event, like DTSTART;TZID=Asia/Jerusalem:20240502T120000
+type property struct {
+	params map[string]string
+	value  string
+}
+
+// Parse returns the meetings of an ICS calendar which overlap the given period, by their start. Only timed events are meetings:
+// all day events and canceled events are skipped. Daily and weekly recurring events are expanded, other recurrences only count once.
+func Parse(r io.Reader, from, to time.Time) ([]Meeting, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var meetings []Meeting
+	var event map[string]property
+	for _, line := range lines {
+		switch {
+		case line == "BEGIN:VEVENT":
+			event = make(map[string]property)
+		case line == "END:VEVENT":
+			if event != nil {
+				meetings = append(meetings, occurrences(event, from, to)...)
+			}
+			event = nil
+		case event != nil:
+			name, prop, ok := parseProperty(line)
+			if ok {
+				event[name] = prop
+			}
+		}
+	}
+	sort.Slice(meetings, func(i, j int) bool { return meetings[i].Start.Before(meetings[j].Start) })
+	return meetings, nil
+}
+
+// unfold returns the content lines of the calendar, joining the lines folded to several lines
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read calendar: %w", err)
+	}
+	return lines, nil
+}
+
+func parseProperty(line string) (string, property, bool) {
+	head, value, ok := strings.Cut(line, ":")
+	if !ok {
+		return "", property{}, false
+	}
+	parts := strings.Split(head, ";")
+	prop := property{params: make(map[string]string, len(parts)-1), value: value}
+	for _, param := range parts[1:] {
+		if key, paramValue, ok := strings.Cut(param, "="); ok {
+			prop.params[strings.ToUpper(key)] = strings.Trim(paramValue, `"`)
+		}
+	}
+	return strings.ToUpper(parts[0]), prop, true
+}
+
+// parseTime parses a DATE-TIME value, in UTC (with a Z suffix), in the timezone of its TZID or else in the local timezone. It returns
+// false for DATE values, of all day events.
+func parseTime(prop property) (time.Time, bool) {
+	if prop.params["VALUE"] == "DATE" || len(prop.value) == len("20060102") {
+		return time.Time{}, false
+	}
+	if strings.HasSuffix(prop.value, "Z") {
+		t, err := time.Parse("20060102T150405Z", prop.value)
+		return t, err == nil
+	}
+	location := time.Local
+	if tzid := prop.params["TZID"]; tzid != "" {
+		if loaded, err := time.LoadLocation(tzid); err == nil {
+			location = loaded
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", prop.value, location)
+	return t, err == nil
+}
+
+// occurrences returns the occurrences of the event which overlap the given period
+func occurrences(event map[string]property, from, to time.Time) []Meeting {
+	if strings.EqualFold(event["STATUS"].value, "CANCELLED") {
+		return nil
+	}
+	start, ok := parseTime(event["DTSTART"])
+	if !ok {
+		return nil
+	}
+	end, ok := parseTime(event["DTEND"])
+	if !ok || !end.After(start) {
+		return nil
+	}
+	duration := end.Sub(start)
+	summary := strings.NewReplacer(`\,`, ",", `\;`, ";", `\n`, " ", `\\`, `\`).Replace(event["SUMMARY"].value)
+
+	var meetings []Meeting
+	add := func(start time.Time) {
+		if start.Before(to) && start.Add(duration).After(from) {
+			meetings = append(meetings, Meeting{Summary: summary, Start: start, End: start.Add(duration)})
+		}
+	}
+	rule, ok := event["RRULE"]
+	if !ok {
+		add(start)
+		return meetings
+	}
+	rrule := parseRule(rule.value)
+	step := map[string]int{"DAILY": 1, "WEEKLY": 7}[rrule["FREQ"]]
+	if step == 0 {
+		add(start)
+		return meetings
+	}
+	interval, err := strconv.Atoi(rrule["INTERVAL"])
+	if err != nil || interval < 1 {
+		interval = 1
+	}
+	count, err := strconv.Atoi(rrule["COUNT"])
+	if err != nil || count < 1 {
+		count = maxOccurrences
+	}
+	until, hasUntil := time.Time{}, false
+	if rrule["UNTIL"] != "" {
+		until, hasUntil = parseTime(property{value: rrule["UNTIL"]})
+	}
+	// The days of a weekly event, on the days of BYDAY of the week of each occurrence, or on the day of its start
+	offsets := []int{0}
+	if step == 7 && rrule["BYDAY"] != "" {
+		offsets = weekdayOffsets(start.Weekday(), rrule["BYDAY"])
+	}
+
+	for period, seen := 0, 0; seen < count && seen < maxOccurrences; period++ {
+		base := start.AddDate(0, 0, period*step*interval)
+		if !base.Before(to) || (hasUntil && base.After(until)) {
+			break
+		}
+		for _, offset := range offsets {
+			occurrence := base.AddDate(0, 0, offset)
+			if (hasUntil && occurrence.After(until)) || seen >= count {
+				break
+			}
+			seen++
+			add(occurrence)
+		}
+	}
+	return meetings
+}
+
+func parseRule(value string) map[string]string {
+	rule := make(map[string]string)
+	for _, part := range strings.Split(value, ";") {
+		if key, partValue, ok := strings.Cut(part, "="); ok {
+			rule[strings.ToUpper(key)] = strings.ToUpper(partValue)
+		}
+	}
+	return rule
+}
+
+// weekdayOffsets returns the days from the start of a weekly event to the days of its BYDAY (like MO,TH) in the same week, which
+// starts on the day of the start
+func weekdayOffsets(startDay time.Weekday, byDay string) []int {
+	days := map[string]time.Weekday{"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+		"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday}
+	var offsets []int
+	for _, name := range strings.Split(byDay, ",") {
+		day, ok := days[name]
+		if !ok {
+			continue
+		}
+		offsets = append(offsets, (int(day)-int(startDay)+7)%7)
+	}
+	if len(offsets) == 0 {
+		return []int{0}
+	}
+	sort.Ints(offsets)
+	return offsets
+}
@@ -0,0 +1,80 @@
+package calendar
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const feed = "BEGIN:VCALENDAR\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Company all-hands\\, Q2\r\n" +
+	"DTSTART:20240502T090000Z\r\n" +
+	"DTEND:20240502T100000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Weekly town\r\n" +
+	"  hall\r\n" +
+	"DTSTART;TZID=Asia/Jerusalem:20240430T130000\r\n" +
+	"DTEND;TZID=Asia/Jerusalem:20240430T133000\r\n" +
+	"RRULE:FREQ=WEEKLY;BYDAY=TU,TH;COUNT=4\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Holiday\r\n" +
+	"DTSTART;VALUE=DATE:20240502\r\n" +
+	"DTEND;VALUE=DATE:20240503\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Canceled all-hands\r\n" +
+	"STATUS:CANCELLED\r\n" +
+	"DTSTART:20240503T100000Z\r\n" +
+	"DTEND:20240503T110000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	meetings, err := Parse(strings.NewReader(feed), from, from.AddDate(0, 0, 10))
+	require.NoError(t, err)
+
+	summaries := make([]string, len(meetings))
+	for i, meeting := range meetings {
+		summaries[i] = meeting.Summary
+	}
+	assert.Equal(t, []string{"Company all-hands, Q2", "Weekly town hall", "Weekly town hall", "Weekly town hall"}, summaries,
+		"the first town hall is before the period and the all day and canceled events aren't meetings")
+	assert.Equal(t, time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC), meetings[1].Start.UTC(), "Thursday at 13:00 in Jerusalem")
+	assert.Equal(t, 30*time.Minute, meetings[1].End.Sub(meetings[1].Start))
+	assert.Equal(t, time.Date(2024, 5, 9, 10, 0, 0, 0, time.UTC), meetings[3].Start.UTC(), "the fourth and last occurrence")
+}
+
+func TestClientMeetings(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/calendar.ics" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(feed))
+	}))
+	t.Cleanup(server.Close)
+
+	from := time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)
+	client := NewClient(Config{URL: server.URL + "/calendar.ics", Match: []string{"ALL-HANDS"}})
+	meetings, err := client.Meetings(context.Background(), from, from.Add(24*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, meetings, 1)
+	assert.Equal(t, "Company all-hands, Q2", meetings[0].Summary)
+
+	_, err = NewClient(Config{URL: server.URL}).Meetings(context.Background(), from, from.Add(time.Hour))
+	assert.ErrorContains(t, err, "unexpected status 404")
+}
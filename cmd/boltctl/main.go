@@ -0,0 +1,216 @@
+// boltctl manages Bolt's store from the command line
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/caarlos0/env/v6"
+	"github.com/oriser/bolt/backup"
+	"github.com/oriser/bolt/cmd/run"
+	"github.com/oriser/bolt/storage/db"
+	"github.com/oriser/bolt/token"
+)
+
+const usage = `USAGE:
+  boltctl tokens list
+  boltctl tokens issue -name <name> -scope <read-only|debts-write|admin> [-user <Slack user ID>]
+  boltctl tokens rotate <token ID>
+  boltctl tokens revoke <token ID>
+  boltctl backup [-o <file>]
+  boltctl restore <file>
+  boltctl migrate -to <DB location>
+
+A backup is a consistent dump of the users, orders, debts and the configuration kept in the store (venues blacklists,
+insights subscriptions, abroad currencies and API tokens), written to the standard output by default.
+It can be restored only to an empty store, of any storage backend.
+migrate copies the whole store to another, empty, store and verifies the copy has the same records.
+
+The store is configured with DB_LOCATION, like Bolt itself.
+`
+
+type Config struct {
+	DBLocation string `env:"DB_LOCATION" envDefault:"/var/sqlite/store.db"`
+}
+
+func main() {
+	if err := runCommand(os.Args[1:]); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runCommand(args []string) error {
+	if len(args) < 1 || (args[0] == "tokens" && len(args) < 2) {
+		_, _ = fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("bad usage")
+	}
+
+	cfg := Config{}
+	if err := env.Parse(&cfg); err != nil {
+		return fmt.Errorf("parsing config: %w", err)
+	}
+	store, err := run.OpenDBStore(cfg.DBLocation)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "tokens":
+		return runTokensCommand(ctx, store, args[1], args[2:])
+	case "backup":
+		return runBackup(ctx, store, args[1:])
+	case "restore":
+		if len(args) != 2 {
+			_, _ = fmt.Fprint(os.Stderr, usage)
+			return fmt.Errorf("bad usage")
+		}
+		return runRestore(ctx, store, args[1])
+	case "migrate":
+		return runMigrate(ctx, store, args[1:])
+	default:
+		_, _ = fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+func runBackup(ctx context.Context, store backup.Store, args []string) error {
+	flags := flag.NewFlagSet("backup", flag.ContinueOnError)
+	output := flags.String("o", "", "file to write the backup to, instead of the standard output")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	dump, err := store.Export(ctx)
+	if err != nil {
+		return fmt.Errorf("export store: %w", err)
+	}
+	if *output == "" {
+		return backup.Write(os.Stdout, dump)
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return fmt.Errorf("create backup file: %w", err)
+	}
+	if err = backup.Write(f, dump); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("close backup file: %w", err)
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "Backed up %d users, %d orders and %d debts to %s\n", len(dump.Users), len(dump.Orders), len(dump.Debts), *output)
+	return nil
+}
+
+func runRestore(ctx context.Context, store backup.Store, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open backup file: %w", err)
+	}
+	defer f.Close()
+
+	dump, err := backup.Read(f)
+	if err != nil {
+		return err
+	}
+	if err = store.Import(ctx, dump); err != nil {
+		return fmt.Errorf("import backup: %w", err)
+	}
+	fmt.Printf("Restored %d users, %d orders and %d debts from a backup of %s\n", len(dump.Users), len(dump.Orders), len(dump.Debts),
+		dump.CreatedAt.Format(time.RFC3339))
+	return nil
+}
+
+func runMigrate(ctx context.Context, source backup.Store, args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	to := flags.String("to", "", "location of the destination store (an SQLite file, which is created if it doesn't exist, or a postgres:// URL)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *to == "" {
+		_, _ = fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("missing destination store")
+	}
+
+	destination, err := run.OpenDBStore(*to)
+	if err != nil {
+		return fmt.Errorf("open destination store: %w", err)
+	}
+	dump, err := backup.Copy(ctx, source, destination)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Migrated and verified %d users, %d orders and %d debts to %s\n", len(dump.Users), len(dump.Orders), len(dump.Debts), *to)
+	return nil
+}
+
+func runTokensCommand(ctx context.Context, store *db.DBStore, action string, actionArgs []string) error {
+	switch action {
+	case "list":
+		tokens, err := store.ListTokens(ctx)
+		if err != nil {
+			return fmt.Errorf("list tokens: %w", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "ID\tNAME\tSCOPE\tUSER\tCREATED\tREVOKED")
+		for _, t := range tokens {
+			revoked := ""
+			if t.RevokedAt != nil {
+				revoked = t.RevokedAt.Format(time.RFC3339)
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", t.ID, t.Name, t.Scope, t.UserID, t.CreatedAt.Format(time.RFC3339), revoked)
+		}
+		return w.Flush()
+	case "issue":
+		flags := flag.NewFlagSet("issue", flag.ContinueOnError)
+		name := flags.String("name", "", "name of the token, for example the integration using it")
+		scope := flags.String("scope", string(token.ScopeReadOnly), "read-only, debts-write or admin")
+		userID := flags.String("user", "", "Slack user ID the token acts as (required for settling debts, as treasurers only can)")
+		if err := flags.Parse(actionArgs); err != nil {
+			return err
+		}
+		parsedScope, err := token.ParseScope(*scope)
+		if err != nil {
+			return err
+		}
+		t, secret, err := token.Issue(ctx, store, *name, parsedScope, *userID)
+		if err != nil {
+			return err
+		}
+		printIssued(t, secret)
+		return nil
+	case "rotate", "revoke":
+		if len(actionArgs) != 1 {
+			_, _ = fmt.Fprint(os.Stderr, usage)
+			return fmt.Errorf("bad usage")
+		}
+		if action == "revoke" {
+			t, err := token.Revoke(ctx, store, actionArgs[0])
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Revoked token %s (%s)\n", t.ID, t.Name)
+			return nil
+		}
+		t, secret, err := token.Rotate(ctx, store, actionArgs[0])
+		if err != nil {
+			return err
+		}
+		printIssued(t, secret)
+		return nil
+	default:
+		_, _ = fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown action %q", action)
+	}
+}
+
+func printIssued(t *token.Token, secret string) {
+	fmt.Printf("Issued token %s (%s, %s). It won't be shown again:\n%s\n", t.ID, t.Name, t.Scope, secret)
+}
@@ -0,0 +1,64 @@
+// loadtest simulates a lunch rush against the fake Wolt server, for tuning the tracking slots and the Wolt rate limit
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/caarlos0/env/v6"
+	"github.com/oriser/bolt/logging"
+	"github.com/oriser/bolt/testing/loadtest"
+)
+
+func main() {
+	if err := runLoadTest(os.Args[1:]); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runLoadTest(args []string) error {
+	cfg := loadtest.DefaultConfig()
+	flags := flag.NewFlagSet("loadtest", flag.ContinueOnError)
+	flags.IntVar(&cfg.Orders, "orders", cfg.Orders, "orders shared at once")
+	flags.IntVar(&cfg.Participants, "participants", cfg.Participants, "participants of each order, besides its host")
+	flags.IntVar(&cfg.Channels, "channels", cfg.Channels, "channels the orders are shared in")
+	flags.DurationVar(&cfg.PurchaseAfter, "purchase-after", cfg.PurchaseAfter, "how long after an order is shared it is purchased")
+	flags.IntVar(&cfg.ErrorRate, "error-rate", cfg.ErrorRate, "one in how many Wolt requests fails with a 502 error, 0 disables the errors")
+	flags.StringVar(&cfg.DBLocation, "db", cfg.DBLocation, "store to use (an SQLite file or a postgres:// URL), a temporary SQLite file by default")
+	flags.IntVar(&cfg.Service.MaxTrackedOrders, "max-tracked", cfg.Service.MaxTrackedOrders, "MAX_TRACKED_ORDERS, 0 disables the cap")
+	flags.IntVar(&cfg.Service.MaxQueuedOrders, "max-queued", cfg.Service.MaxQueuedOrders, "MAX_QUEUED_ORDERS, 0 disables the cap")
+	flags.Float64Var(&cfg.Service.WoltRateLimit, "wolt-rate-limit", cfg.Service.WoltRateLimit, "WOLT_RATE_LIMIT, requests a second")
+	flags.DurationVar(&cfg.Service.WaitBetweenStatusCheck, "status-interval", cfg.Service.WaitBetweenStatusCheck, "WAIT_BETWEEN_STATUS_CHECK")
+	flags.DurationVar(&cfg.Service.OrderDoneTimeout, "done-timeout", cfg.Service.OrderDoneTimeout,
+		"ORDER_DONE_TIMEOUT, how long the deliveries are followed, as the fake Wolt server doesn't deliver")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	// Bolt's logs are quiet by default, so they don't drown the report
+	logCfg := logging.Config{}
+	if err := env.Parse(&logCfg, env.Options{Environment: map[string]string{"LOG_LEVEL": "warn"}}); err != nil {
+		return fmt.Errorf("parsing log config: %w", err)
+	}
+	if level, ok := os.LookupEnv("LOG_LEVEL"); ok {
+		logCfg.Level = level
+	}
+	if _, err := logging.Setup(logCfg); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	report, err := loadtest.Run(ctx, cfg)
+	if report != nil {
+		if writeErr := report.Write(os.Stdout); writeErr != nil {
+			return writeErr
+		}
+	}
+	return err
+}
@@ -5,22 +5,115 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/caarlos0/env/v6"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
 	"github.com/golang-migrate/migrate/v4/database/sqlite3"
 	"github.com/jmoiron/sqlx"
+	"github.com/oriser/bolt/api"
+	"github.com/oriser/bolt/archive"
+	"github.com/oriser/bolt/bot/discord"
+	"github.com/oriser/bolt/bot/mattermost"
 	slack2 "github.com/oriser/bolt/bot/slack"
+	"github.com/oriser/bolt/bot/telegram"
+	"github.com/oriser/bolt/bot/whatsapp"
+	"github.com/oriser/bolt/cache"
+	"github.com/oriser/bolt/calendar"
+	"github.com/oriser/bolt/dashboard"
+	"github.com/oriser/bolt/fx"
+	"github.com/oriser/bolt/headcount"
+	"github.com/oriser/bolt/health"
+	"github.com/oriser/bolt/logging"
+	"github.com/oriser/bolt/metrics"
+	"github.com/oriser/bolt/notification"
+	"github.com/oriser/bolt/paidlink"
+	"github.com/oriser/bolt/plugin"
+	"github.com/oriser/bolt/queue"
 	"github.com/oriser/bolt/service"
+	"github.com/oriser/bolt/statuspage"
+	"github.com/oriser/bolt/storage/cached"
 	"github.com/oriser/bolt/storage/combined"
 	db2 "github.com/oriser/bolt/storage/db"
 	"github.com/oriser/bolt/storage/slack"
+	"github.com/oriser/bolt/tracing"
+	"github.com/oriser/bolt/translation"
+	"github.com/oriser/bolt/user"
+	"github.com/oriser/bolt/webhook"
 )
 
 type Config struct {
-	Bot        slack2.Config
-	Handler    service.Config
-	SlackSore  slack.Config
-	DBLocation string `env:"DB_LOCATION" envDefault:"/var/sqlite/store.db"`
+	Bot          slack2.Config
+	Handler      service.Config
+	SlackSore    slack.Config
+	Plugins      plugin.Config
+	Notification notification.Config
+	API          api.Config
+	Dashboard    dashboard.Config
+	Queue        queue.Config
+	FX           fx.Config
+	Headcount    headcount.Config
+	Calendar     calendar.Config
+	Translation  translation.Config
+	Telegram     telegram.Config
+	Discord      discord.Config
+	Mattermost   mattermost.Config
+	WhatsApp     whatsapp.Config
+	Metrics      metrics.Config
+	Health       health.Config
+	Tracing      tracing.Config
+	Logging      logging.Config
+	Archive      archive.Config
+	Cache        cache.Config
+	Webhooks     webhook.Config
+	Transport    string   `env:"TRANSPORT" envDefault:"slack"`
+	DBLocation   string   `env:"DB_LOCATION" envDefault:"/var/sqlite/store.db"`
+	Components   []string `env:"COMPONENTS" envDefault:"all"`
+	// How long to wait for the orders to stop when shutting down
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" envDefault:"30s"`
+}
+
+const (
+	ComponentAll       = "all"
+	ComponentListener  = "listener"  // The transport's events and commands, the API and the dashboard
+	ComponentMonitor   = "monitor"   // Joining and monitoring orders
+	ComponentScheduler = "scheduler" // Debts reminders
+
+	TransportSlack      = "slack"
+	TransportTelegram   = "telegram"
+	TransportDiscord    = "discord"
+	TransportMattermost = "mattermost"
+	TransportWhatsApp   = "whatsapp"
+
+	linksTopic  = "links"
+	eventsTopic = "events"
+)
+
+type components map[string]bool
+
+func parseComponents(names []string) (components, error) {
+	c := make(components)
+	for _, name := range names {
+		switch name {
+		case ComponentAll, ComponentListener, ComponentMonitor, ComponentScheduler:
+			c[name] = true
+		default:
+			return nil, fmt.Errorf("unknown component %q", name)
+		}
+	}
+	if len(c) == 0 {
+		return nil, fmt.Errorf("no components")
+	}
+	return c, nil
+}
+
+func (c components) has(name string) bool {
+	return c[ComponentAll] || c[name]
 }
 
 func (c Config) String() string {
@@ -33,39 +126,421 @@ func Run() error {
 	if err := env.Parse(&cfg); err != nil {
 		return fmt.Errorf("parsing config: %w", err)
 	}
+	logger, err := logging.Setup(cfg.Logging)
+	if err != nil {
+		return fmt.Errorf("set up logging: %w", err)
+	}
 
 	log.Printf("Starting with options: %s\n", cfg.String())
 
-	slackClient := slack2.NewClient(cfg.Bot)
-	id, err := slackClient.GetSelfID()
+	enabledComponents, err := parseComponents(cfg.Components)
+	if err != nil {
+		return fmt.Errorf("parsing COMPONENTS: %w", err)
+	}
+
+	dbStorage, err := OpenDBStore(cfg.DBLocation)
+	if err != nil {
+		return err
+	}
+	// The orders archive is read by all the components, and written by the scheduler
+	var ordersArchive *archive.Archive
+	if cfg.Archive.Dir != "" {
+		bucket, err := archive.NewDirBucket(cfg.Archive.Dir)
+		if err != nil {
+			return fmt.Errorf("new archive bucket: %w", err)
+		}
+		if ordersArchive, err = archive.New(cfg.Archive, bucket); err != nil {
+			return fmt.Errorf("new archive: %w", err)
+		}
+		dbStorage.SetArchive(ordersArchive)
+	}
+
+	chatTransport, err := newTransport(cfg, dbStorage)
+	if err != nil {
+		return fmt.Errorf("new transport: %w", err)
+	}
+
+	var lookupCache cache.Cache
+	if cfg.Cache.Enabled() {
+		if lookupCache, err = cache.New(cfg.Cache); err != nil {
+			return fmt.Errorf("new cache: %w", err)
+		}
+		if cfg.Cache.UsersTTL > 0 {
+			chatTransport.userStore = cached.NewUserStore(chatTransport.userStore, lookupCache, cfg.Cache.UsersTTL)
+		}
+	}
+
+	notificationQueue := notification.NewQueue(cfg.Notification, chatTransport.notifier)
+	serviceHandler, err := service.New(cfg.Handler, chatTransport.userStore, dbStorage, dbStorage, chatTransport.selfID, notificationQueue)
 	if err != nil {
-		return fmt.Errorf("get bot self ID: %w", err)
+		return fmt.Errorf("new service: %w", err)
+	}
+
+	serviceHandler.SetLogger(logger)
+	serviceHandler.SetFXProvider(fx.NewClient(cfg.FX))
+	if cfg.Headcount.SourceURL != "" {
+		serviceHandler.SetHeadcountProvider(headcount.NewClient(cfg.Headcount))
+	}
+	if cfg.Translation.URL != "" {
+		serviceHandler.SetTranslationProvider(translation.NewClient(cfg.Translation))
+	}
+	if cfg.Calendar.URL != "" {
+		serviceHandler.SetQuietCalendar(calendar.NewClient(cfg.Calendar))
+	}
+	if cfg.Cache.VenuesTTL > 0 {
+		serviceHandler.SetVenueCache(lookupCache, cfg.Cache.VenuesTTL)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// Every component sends notifications, so each of them holds its own during the meetings
+	go serviceHandler.RunQuietCalendar(ctx)
+	pluginManager := plugin.NewManager(cfg.Plugins, notificationQueue)
+	if err := pluginManager.Start(ctx, serviceHandler.Hooks()); err != nil {
+		return fmt.Errorf("start plugins: %w", err)
+	}
+
+	// When the components run in separate processes, the links to monitor are passed through a queue in the shared store,
+	// or in an external message broker
+	messageQueue, err := queue.New(cfg.Queue, dbStorage)
+	if err != nil {
+		return fmt.Errorf("new queue: %w", err)
+	}
+	if cfg.Queue.External() {
+		serviceHandler.Hooks().SubscribeAll(func(_ context.Context, event service.Event) {
+			go publishEvent(ctx, messageQueue, event)
+		})
+	}
+	// The events wait in the queue until they're delivered to the webhooks, so a webhook which is down gets them once it's back
+	var outbox *webhook.Outbox
+	if cfg.Webhooks.Enabled() {
+		outbox = webhook.New(cfg.Webhooks, messageQueue)
+		serviceHandler.Hooks().SubscribeAll(func(_ context.Context, event service.Event) {
+			go addWebhookEvent(ctx, outbox, event)
+		})
+	}
+	errCh := make(chan error, 6)
+
+	go func() {
+		<-ctx.Done()
+		errCh <- nil
+	}()
+
+	if cfg.Metrics.Port != 0 {
+		go func() {
+			errCh <- fmt.Errorf("serve metrics: %w", metrics.ListenAndServe(cfg.Metrics))
+		}()
+	}
+
+	if cfg.Health.Port != 0 {
+		go func() {
+			errCh <- fmt.Errorf("serve health: %w", health.ListenAndServe(cfg.Health, serviceHandler))
+		}()
+	}
+
+	if cfg.Tracing.Endpoint != "" {
+		go func() {
+			if err := tracing.Run(ctx, cfg.Tracing); err != nil {
+				errCh <- fmt.Errorf("export traces: %w", err)
+			}
+		}()
+	}
+
+	if enabledComponents.has(ComponentScheduler) && !enabledComponents.has(ComponentMonitor) {
+		go func() {
+			serviceHandler.RunDebtScheduler(ctx)
+			errCh <- nil
+		}()
+	}
+	if enabledComponents.has(ComponentScheduler) {
+		go serviceHandler.RunBadgesAnnouncer(ctx)
+		go serviceHandler.RunInsightsSender(ctx)
+		go serviceHandler.RunFinanceReporter(ctx)
+		go serviceHandler.RunSubsidyReporter(ctx)
+		go serviceHandler.RunDealsWatcher(ctx)
+		go serviceHandler.RunBalancesDigest(ctx)
+		go serviceHandler.RunMonthlyReports(ctx)
+		go serviceHandler.RunMatchingSummary(ctx)
+		go serviceHandler.RunDigestSender(ctx)
+		if outbox != nil {
+			go outbox.Run(ctx)
+		}
+		if ordersArchive != nil {
+			go ordersArchive.Run(ctx, dbStorage)
+		}
+	}
+	if enabledComponents.has(ComponentMonitor) && !enabledComponents.has(ComponentScheduler) {
+		serviceHandler.DisableDebtWorkers()
+	}
+	if enabledComponents.has(ComponentMonitor) {
+		go serviceHandler.RunWatchdog(ctx)
+		resumed, err := serviceHandler.ResumeOrders(ctx)
+		if err != nil {
+			return fmt.Errorf("resume orders: %w", err)
+		}
+		log.Printf("Resumed tracking %d orders\n", resumed)
+
+		offered, err := serviceHandler.CatchUpMissedLinks(ctx)
+		if err != nil {
+			log.Println("Error catching up with the links shared while Bolt was down:", err)
+		} else if offered > 0 {
+			log.Printf("Offered to track %d orders shared while Bolt was down\n", offered)
+		}
+	}
+
+	if enabledComponents.has(ComponentMonitor) && !enabledComponents.has(ComponentListener) {
+		go func() {
+			errCh <- consumeLinks(ctx, messageQueue, serviceHandler, cfg.Bot.MaxConcurrentLinks)
+		}()
+	}
+
+	if enabledComponents.has(ComponentListener) {
+		bot, err := newListener(cfg, serviceHandler, chatTransport, dbStorage, pluginManager)
+		if err != nil {
+			return err
+		}
+		if !enabledComponents.has(ComponentMonitor) {
+			bot.SetLinkHandler(func(req service.LinksRequest) (string, error) {
+				payload, err := json.Marshal(req)
+				if err != nil {
+					return "", fmt.Errorf("marshal links request: %w", err)
+				}
+				return "", messageQueue.Publish(ctx, linksTopic, payload)
+			})
+		}
+		go func() {
+			if err := bot.ListenAndServe(ctx); err != nil {
+				errCh <- fmt.Errorf("ListenAndServe: %w", err)
+				return
+			}
+			errCh <- nil
+		}()
 	}
 
-	slackStorage := slack.New(cfg.SlackSore)
+	err = <-errCh
+	stop()
+	log.Println("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if shutdownErr := serviceHandler.Shutdown(shutdownCtx); shutdownErr != nil {
+		log.Printf("Error shutting down: %v\n", shutdownErr)
+	}
+	return err
+}
 
-	db, err := sqlx.Connect("sqlite3", cfg.DBLocation)
+// listener is the bot of a transport, receiving the chats' messages and reactions
+type listener interface {
+	SetLinkHandler(handler func(req service.LinksRequest) (string, error))
+	ListenAndServe(ctx context.Context) error
+}
+
+// transport is the chat platform Bolt runs on: where it sends the notifications, where it gets its users from and its bot
+type transport struct {
+	selfID    string
+	notifier  notification.Notifier
+	userStore user.Store
+	newBot    func(serviceHandler *service.Service, pluginManager *plugin.Manager) listener
+}
+
+func newTransport(cfg Config, dbStorage *db2.DBStore) (*transport, error) {
+	switch cfg.Transport {
+	case TransportSlack:
+		if cfg.Bot.SigninSecret == "" || cfg.Bot.ClientSecret == "" {
+			return nil, fmt.Errorf("SLACK_SIGNIN_SECRET and SLACK_OAUTH_TOKEN are required for the slack transport")
+		}
+		slackClient := slack2.NewClient(cfg.Bot)
+		id, err := slackClient.GetSelfID()
+		if err != nil {
+			return nil, fmt.Errorf("get bot self ID: %w", err)
+		}
+		return &transport{
+			selfID:    id,
+			notifier:  slackClient,
+			userStore: combined.NewPrioritizedUserStore(dbStorage, slack.New(cfg.SlackSore)),
+			newBot: func(serviceHandler *service.Service, pluginManager *plugin.Manager) listener {
+				slackBot := slackClient.ServiceBot(serviceHandler)
+				slackBot.SetCommandHandler(pluginManager)
+				return slackBot
+			},
+		}, nil
+	case TransportTelegram:
+		if cfg.Telegram.Token == "" {
+			return nil, fmt.Errorf("TELEGRAM_BOT_TOKEN is required for the telegram transport")
+		}
+		telegramClient := telegram.NewClient(cfg.Telegram)
+		id, err := telegramClient.GetSelfID()
+		if err != nil {
+			return nil, fmt.Errorf("get bot self ID: %w", err)
+		}
+		// Telegram has no members directory, so the users are only the ones added with /adduser
+		return &transport{
+			selfID:    id,
+			notifier:  telegramClient,
+			userStore: dbStorage,
+			newBot: func(serviceHandler *service.Service, _ *plugin.Manager) listener {
+				return telegramClient.ServiceBot(serviceHandler)
+			},
+		}, nil
+	case TransportDiscord:
+		if cfg.Discord.Token == "" {
+			return nil, fmt.Errorf("DISCORD_BOT_TOKEN is required for the discord transport")
+		}
+		discordClient := discord.NewClient(cfg.Discord)
+		id, err := discordClient.GetSelfID()
+		if err != nil {
+			return nil, fmt.Errorf("get bot self ID: %w", err)
+		}
+		// The users are the ones added with /adduser, mapping their Discord user IDs to their Wolt names
+		return &transport{
+			selfID:    id,
+			notifier:  discordClient,
+			userStore: dbStorage,
+			newBot: func(serviceHandler *service.Service, _ *plugin.Manager) listener {
+				return discordClient.ServiceBot(serviceHandler)
+			},
+		}, nil
+	case TransportMattermost:
+		if cfg.Mattermost.URL == "" || cfg.Mattermost.Token == "" {
+			return nil, fmt.Errorf("MATTERMOST_URL and MATTERMOST_BOT_TOKEN are required for the mattermost transport")
+		}
+		mattermostClient := mattermost.NewClient(cfg.Mattermost)
+		id, err := mattermostClient.GetSelfID()
+		if err != nil {
+			return nil, fmt.Errorf("get bot self ID: %w", err)
+		}
+		// The users are the ones added with "@bolt adduser", mapping their Mattermost user IDs to their Wolt names
+		return &transport{
+			selfID:    id,
+			notifier:  mattermostClient,
+			userStore: dbStorage,
+			newBot: func(serviceHandler *service.Service, _ *plugin.Manager) listener {
+				return mattermostClient.ServiceBot(serviceHandler)
+			},
+		}, nil
+	case TransportWhatsApp:
+		if cfg.WhatsApp.AccessToken == "" || cfg.WhatsApp.PhoneNumberID == "" || cfg.WhatsApp.AppSecret == "" {
+			return nil, fmt.Errorf("WHATSAPP_ACCESS_TOKEN, WHATSAPP_PHONE_NUMBER_ID and WHATSAPP_APP_SECRET are required for the whatsapp transport")
+		}
+		whatsAppClient := whatsapp.NewClient(cfg.WhatsApp)
+		id, err := whatsAppClient.GetSelfID()
+		if err != nil {
+			return nil, fmt.Errorf("get bot self ID: %w", err)
+		}
+		// WhatsApp has no members directory, so the users are only the ones added with /adduser, by their phone numbers
+		return &transport{
+			selfID:    id,
+			notifier:  whatsAppClient,
+			userStore: dbStorage,
+			newBot: func(serviceHandler *service.Service, _ *plugin.Manager) listener {
+				return whatsAppClient.ServiceBot(serviceHandler)
+			},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown transport %q", cfg.Transport)
+	}
+}
+
+// OpenDBStore connects to the DB at the given location and runs its migrations. A postgres:// (or postgresql://) URL location
+// is a PostgreSQL DB, and any other location is an SQLite DB.
+func OpenDBStore(location string) (*db2.DBStore, error) {
+	if strings.HasPrefix(location, "postgres://") || strings.HasPrefix(location, "postgresql://") {
+		return openPostgresStore(location)
+	}
+
+	db, err := sqlx.Connect("sqlite3", location)
 	if err != nil {
-		return fmt.Errorf("connect DB: %w", err)
+		return nil, fmt.Errorf("connect DB: %w", err)
 	}
 	driver, err := sqlite3.WithInstance(db.DB, &sqlite3.Config{})
 	if err != nil {
-		return fmt.Errorf("new sqlite3 migration driver: %w", err)
+		return nil, fmt.Errorf("new sqlite3 migration driver: %w", err)
 	}
 	dbStorage, err := db2.New(db, driver, "")
 	if err != nil {
-		return fmt.Errorf("new dbStorage: %w", err)
+		return nil, fmt.Errorf("new dbStorage: %w", err)
 	}
+	return dbStorage, nil
+}
 
-	serviceHandler, err := service.New(cfg.Handler, combined.NewPrioritizedUserStore(dbStorage, slackStorage), dbStorage, dbStorage, id, slackClient)
+func openPostgresStore(url string) (*db2.DBStore, error) {
+	db, err := sqlx.Connect("postgres", url)
 	if err != nil {
-		return fmt.Errorf("new service: %w", err)
+		return nil, fmt.Errorf("connect DB: %w", err)
+	}
+	driver, err := postgres.WithInstance(db.DB, &postgres.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("new postgres migration driver: %w", err)
+	}
+	dbStorage, err := db2.NewWithDialect(db, driver, "", db2.DialectPostgres)
+	if err != nil {
+		return nil, fmt.Errorf("new dbStorage: %w", err)
 	}
+	return dbStorage, nil
+}
 
-	slackBot := slackClient.ServiceBot(serviceHandler)
-	if err := slackBot.ListenAndServe(context.Background()); err != nil {
-		return fmt.Errorf("ListenAndServe: %w", err)
+func newListener(cfg Config, serviceHandler *service.Service, chatTransport *transport, dbStorage *db2.DBStore,
+	pluginManager *plugin.Manager) (listener, error) {
+	graphqlAPI, err := api.New(cfg.API, dbStorage, chatTransport.userStore, dbStorage, serviceHandler)
+	if err != nil {
+		return nil, fmt.Errorf("new API: %w", err)
+	}
+	// The API, the dashboard and the order status pages are served by the same server as the transport's endpoints
+	if graphqlAPI.Enabled() {
+		http.Handle(api.GraphQLPath, graphqlAPI.Handler())
+		http.Handle(api.RESTPath, graphqlAPI.RESTHandler())
+	}
+	webDashboard, err := dashboard.New(cfg.Dashboard, graphqlAPI)
+	if err != nil {
+		return nil, fmt.Errorf("new dashboard: %w", err)
+	}
+	if webDashboard.Enabled() {
+		http.Handle(dashboard.Path, webDashboard.Handler())
+	}
+	if cfg.Handler.StatusPageURL != "" {
+		http.Handle(service.StatusPagePath, statuspage.Handler(serviceHandler))
+	}
+	if cfg.Handler.PaidLinksURL != "" {
+		http.Handle(service.PaidLinkPath, paidlink.Handler(serviceHandler))
 	}
 
-	return nil
+	return chatTransport.newBot(serviceHandler, pluginManager), nil
+}
+
+// consumeLinks monitors the orders of links published by a listener of another process
+func consumeLinks(ctx context.Context, linksQueue queue.Queue, serviceHandler *service.Service, concurrency int) error {
+	return linksQueue.Consume(ctx, linksTopic, concurrency, func(_ context.Context, msg *queue.Message) error {
+		req := service.LinksRequest{}
+		if err := json.Unmarshal(msg.Payload, &req); err != nil {
+			return fmt.Errorf("unmarshal links request: %w", err)
+		}
+		// Errors are not retried, as the order could already be partially handled (same as handling links in-process)
+		if _, err := serviceHandler.HandleLinkMessage(req); err != nil {
+			log.Println("Error handling link:", err)
+		}
+		return nil
+	})
+}
+
+// addWebhookEvent queues a lifecycle event for the webhooks, in the plugins' event format
+func addWebhookEvent(ctx context.Context, outbox *webhook.Outbox, event service.Event) {
+	payload, err := json.Marshal(plugin.NewEventMessage(event))
+	if err != nil {
+		log.Printf("Error marshaling %s event: %v\n", event.Type, err)
+		return
+	}
+	if err := outbox.Add(ctx, string(event.Type), payload); err != nil {
+		log.Printf("Error queueing %s event for the webhooks: %v\n", event.Type, err)
+	}
+}
+
+// publishEvent publishes a lifecycle event for integrations consuming the message broker, in the plugins' event format
+func publishEvent(ctx context.Context, messageQueue queue.Queue, event service.Event) {
+	payload, err := json.Marshal(plugin.NewEventMessage(event))
+	if err != nil {
+		log.Printf("Error marshaling %s event: %v\n", event.Type, err)
+		return
+	}
+	if err := messageQueue.Publish(ctx, eventsTopic, payload); err != nil {
+		log.Printf("Error publishing %s event: %v\n", event.Type, err)
+	}
 }
@@ -0,0 +1,233 @@
+package dashboard
+
+import (
+	"context"
+	"crypto/rand"
+	"embed"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/oriser/bolt/api"
+	"github.com/slack-go/slack"
+)
+
+//go:embed static
+var static embed.FS
+
+const (
+	Path = "/dashboard/"
+
+	sessionCookie  = "bolt_session"
+	stateCookie    = "bolt_oauth_state"
+	callbackPath   = Path + "oauth/callback"
+	slackAuthorize = "https://slack.com/openid/connect/authorize"
+)
+
+type Config struct {
+	Enabled           bool          `env:"DASHBOARD_ENABLED" envDefault:"false"`
+	URL               string        `env:"DASHBOARD_URL"` // The public URL of Bolt, for the "Sign in with Slack" redirect, for example https://bolt.example.com
+	SlackClientID     string        `env:"SLACK_CLIENT_ID"`
+	SlackClientSecret string        `env:"SLACK_CLIENT_SECRET" json:"-"`
+	SlackTeamID       string        `env:"DASHBOARD_SLACK_TEAM_ID"` // If defined, only users of that workspace can sign in
+	SessionSecret     string        `env:"DASHBOARD_SESSION_SECRET" json:"-"`
+	SessionDuration   time.Duration `env:"DASHBOARD_SESSION_DURATION" envDefault:"24h"`
+	AdminSlackUserID  []string      `env:"ADMIN_SLACK_USER_IDS"`
+}
+
+// identity is the Slack user which signed in
+type identity struct {
+	UserID string `json:"https://slack.com/user_id"`
+	TeamID string `json:"https://slack.com/team_id"`
+	Name   string `json:"name"`
+}
+
+type Dashboard struct {
+	cfg           Config
+	graphql       http.Handler
+	sessionSecret []byte
+	admins        map[string]bool
+	exchangeCode  func(ctx context.Context, code, redirectURI string) (identity, error)
+}
+
+func New(cfg Config, graphqlAPI *api.API) (*Dashboard, error) {
+	if cfg.Enabled && (cfg.URL == "" || cfg.SlackClientID == "" || cfg.SlackClientSecret == "") {
+		return nil, fmt.Errorf("DASHBOARD_URL, SLACK_CLIENT_ID and SLACK_CLIENT_SECRET are required for the dashboard")
+	}
+
+	sessionSecret := []byte(cfg.SessionSecret)
+	if len(sessionSecret) == 0 {
+		sessionSecret = make([]byte, 32)
+		if _, err := rand.Read(sessionSecret); err != nil {
+			return nil, fmt.Errorf("generate session secret: %w", err)
+		}
+		if cfg.Enabled {
+			log.Println("DASHBOARD_SESSION_SECRET isn't set, dashboard sessions won't survive restarts")
+		}
+	}
+
+	d := &Dashboard{
+		cfg:           cfg,
+		graphql:       graphqlAPI.GraphQLHandler(),
+		sessionSecret: sessionSecret,
+		admins:        make(map[string]bool),
+	}
+	d.exchangeCode = d.exchangeSlackCode
+	for _, userID := range cfg.AdminSlackUserID {
+		d.admins[userID] = true
+	}
+	return d, nil
+}
+
+func (d *Dashboard) Enabled() bool {
+	return d.cfg.Enabled
+}
+
+// Handler returns the dashboard HTTP handler, serving everything under Path
+func (d *Dashboard) Handler() http.Handler {
+	staticFiles, _ := fs.Sub(static, "static")
+	mux := http.NewServeMux()
+	mux.Handle(Path, http.StripPrefix(Path, http.FileServer(http.FS(staticFiles))))
+	mux.HandleFunc(Path+"login", d.login)
+	mux.HandleFunc(callbackPath, d.callback)
+	mux.HandleFunc(Path+"logout", d.logout)
+	mux.HandleFunc(Path+"graphql", d.serveGraphQL)
+	return mux
+}
+
+func (d *Dashboard) redirectURI() string {
+	return strings.TrimSuffix(d.cfg.URL, "/") + callbackPath
+}
+
+func (d *Dashboard) setCookie(w http.ResponseWriter, name, value string, maxAge time.Duration) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     Path,
+		MaxAge:   int(maxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(d.cfg.URL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (d *Dashboard) login(w http.ResponseWriter, r *http.Request) {
+	rawState := make([]byte, 16)
+	if _, err := rand.Read(rawState); err != nil {
+		log.Printf("Error generating OAuth state: %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	state := hex.EncodeToString(rawState)
+	d.setCookie(w, stateCookie, state, 10*time.Minute)
+
+	query := url.Values{
+		"response_type": {"code"},
+		"scope":         {"openid profile"},
+		"client_id":     {d.cfg.SlackClientID},
+		"state":         {state},
+		"redirect_uri":  {d.redirectURI()},
+	}
+	if d.cfg.SlackTeamID != "" {
+		query.Set("team", d.cfg.SlackTeamID)
+	}
+	http.Redirect(w, r, slackAuthorize+"?"+query.Encode(), http.StatusFound)
+}
+
+func (d *Dashboard) callback(w http.ResponseWriter, r *http.Request) {
+	state, err := r.Cookie(stateCookie)
+	if err != nil || state.Value == "" || state.Value != r.URL.Query().Get("state") {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("Invalid OAuth state, please sign in again"))
+		return
+	}
+	d.setCookie(w, stateCookie, "", -time.Second)
+
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("Sign in was canceled"))
+		return
+	}
+
+	ident, err := d.exchangeCode(r.Context(), code, d.redirectURI())
+	if err != nil {
+		log.Printf("Error signing in with Slack: %v\n", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("Error signing in with Slack"))
+		return
+	}
+	if d.cfg.SlackTeamID != "" && ident.TeamID != d.cfg.SlackTeamID {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte("Your workspace isn't allowed to use this dashboard"))
+		return
+	}
+
+	value, err := encodeSession(d.sessionSecret, session{
+		UserID:    ident.UserID,
+		TeamID:    ident.TeamID,
+		Name:      ident.Name,
+		ExpiresAt: time.Now().Add(d.cfg.SessionDuration).Unix(),
+	})
+	if err != nil {
+		log.Printf("Error encoding session: %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	d.setCookie(w, sessionCookie, value, d.cfg.SessionDuration)
+	http.Redirect(w, r, Path, http.StatusFound)
+}
+
+func (d *Dashboard) logout(w http.ResponseWriter, r *http.Request) {
+	d.setCookie(w, sessionCookie, "", -time.Second)
+	http.Redirect(w, r, Path, http.StatusFound)
+}
+
+func (d *Dashboard) serveGraphQL(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	s, err := decodeSession(d.sessionSecret, cookie.Value, time.Now())
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	viewer := api.Viewer{UserID: s.UserID, Admin: d.admins[s.UserID]}
+	d.graphql.ServeHTTP(w, r.WithContext(api.WithViewer(r.Context(), viewer)))
+}
+
+// exchangeSlackCode exchanges a "Sign in with Slack" code for the signed-in user
+func (d *Dashboard) exchangeSlackCode(ctx context.Context, code, redirectURI string) (identity, error) {
+	res, err := slack.GetOpenIDConnectTokenContext(ctx, http.DefaultClient, d.cfg.SlackClientID, d.cfg.SlackClientSecret, code, redirectURI)
+	if err != nil {
+		return identity{}, fmt.Errorf("get OpenID Connect token: %w", err)
+	}
+
+	// The ID token is received directly from Slack over TLS, so its claims can be used without validating its signature
+	parts := strings.Split(res.IdToken, ".")
+	if len(parts) != 3 {
+		return identity{}, fmt.Errorf("malformed ID token")
+	}
+	claims, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return identity{}, fmt.Errorf("decode ID token claims: %w", err)
+	}
+	ident := identity{}
+	if err = json.Unmarshal(claims, &ident); err != nil {
+		return identity{}, fmt.Errorf("unmarshal ID token claims: %w", err)
+	}
+	if ident.UserID == "" {
+		return identity{}, fmt.Errorf("no user ID in ID token")
+	}
+	return ident, nil
+}
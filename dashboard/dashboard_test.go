@@ -0,0 +1,137 @@
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oriser/bolt/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSession(t *testing.T) {
+	t.Parallel()
+
+	secret := []byte("secret")
+	now := time.Now()
+	value, err := encodeSession(secret, session{UserID: "U1", TeamID: "T1", ExpiresAt: now.Add(time.Hour).Unix()})
+	require.NoError(t, err)
+
+	s, err := decodeSession(secret, value, now)
+	require.NoError(t, err)
+	assert.Equal(t, "U1", s.UserID)
+
+	_, err = decodeSession([]byte("other secret"), value, now)
+	assert.Error(t, err)
+	_, err = decodeSession(secret, "x"+value, now)
+	assert.Error(t, err)
+	_, err = decodeSession(secret, value, now.Add(2*time.Hour))
+	assert.Error(t, err)
+}
+
+func newTestDashboard(t *testing.T) *Dashboard {
+	t.Helper()
+	graphqlAPI, err := api.New(api.Config{}, nil, nil, nil, nil)
+	require.NoError(t, err)
+	d, err := New(Config{
+		Enabled:           true,
+		URL:               "https://bolt.example.com/",
+		SlackClientID:     "client",
+		SlackClientSecret: "client-secret",
+		SlackTeamID:       "T1",
+		SessionDuration:   time.Hour,
+		AdminSlackUserID:  []string{"U-admin"},
+	}, graphqlAPI)
+	require.NoError(t, err)
+	d.exchangeCode = func(_ context.Context, code, redirectURI string) (identity, error) {
+		if redirectURI != "https://bolt.example.com/dashboard/oauth/callback" {
+			return identity{}, fmt.Errorf("unexpected redirect URI %s", redirectURI)
+		}
+		teamID, userID, _ := strings.Cut(code, ":")
+		return identity{UserID: userID, TeamID: teamID}, nil
+	}
+	return d
+}
+
+// signIn goes through the sign in flow and returns the session cookie
+func signIn(t *testing.T, handler http.Handler, code string) (*http.Cookie, int) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/dashboard/login", nil))
+	require.Equal(t, http.StatusFound, rec.Code)
+	location, err := url.Parse(rec.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "client", location.Query().Get("client_id"))
+	assert.Equal(t, "T1", location.Query().Get("team"))
+	state := location.Query().Get("state")
+	stateCookies := rec.Result().Cookies()
+	require.Len(t, stateCookies, 1)
+
+	req := httptest.NewRequest(http.MethodGet, "/dashboard/oauth/callback?"+url.Values{"code": {code}, "state": {state}}.Encode(), nil)
+	req.AddCookie(stateCookies[0])
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == sessionCookie {
+			return cookie, rec.Code
+		}
+	}
+	return nil, rec.Code
+}
+
+func queryViewer(t *testing.T, handler http.Handler, cookie *http.Cookie) (int, string) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/dashboard/graphql", strings.NewReader(`{"query": "{ viewer { userId admin } }"}`))
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec.Code, rec.Body.String()
+}
+
+func TestDashboardSignIn(t *testing.T) {
+	t.Parallel()
+
+	handler := newTestDashboard(t).Handler()
+
+	code, _ := queryViewer(t, handler, nil)
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	cookie, code := signIn(t, handler, "T1:U1")
+	assert.Equal(t, http.StatusFound, code)
+	require.NotNil(t, cookie)
+	assert.True(t, cookie.Secure)
+	code, body := queryViewer(t, handler, cookie)
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `{"data": {"viewer": {"userId": "U1", "admin": false}}}`, body)
+
+	cookie, _ = signIn(t, handler, "T1:U-admin")
+	require.NotNil(t, cookie)
+	_, body = queryViewer(t, handler, cookie)
+	assert.JSONEq(t, `{"data": {"viewer": {"userId": "U-admin", "admin": true}}}`, body)
+
+	cookie, code = signIn(t, handler, "T2:U2")
+	assert.Equal(t, http.StatusForbidden, code)
+	assert.Nil(t, cookie)
+
+	// Callback without the state from the login
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/dashboard/oauth/callback?code=T1:U1&state=forged", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestDashboardStatic(t *testing.T) {
+	t.Parallel()
+
+	rec := httptest.NewRecorder()
+	newTestDashboard(t).Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/dashboard/", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Sign in with Slack")
+}
@@ -0,0 +1,61 @@
+package dashboard
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// session is the signed-in Slack user, kept in a signed cookie
+type session struct {
+	UserID    string `json:"user_id"`
+	TeamID    string `json:"team_id"`
+	Name      string `json:"name"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+func (s session) expired(now time.Time) bool {
+	return now.Unix() >= s.ExpiresAt
+}
+
+func sign(secret []byte, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func encodeSession(secret []byte, s session) (string, error) {
+	raw, err := json.Marshal(s)
+	if err != nil {
+		return "", fmt.Errorf("marshal session: %w", err)
+	}
+	payload := base64.RawURLEncoding.EncodeToString(raw)
+	return payload + "." + sign(secret, payload), nil
+}
+
+func decodeSession(secret []byte, value string, now time.Time) (session, error) {
+	payload, signature, ok := strings.Cut(value, ".")
+	if !ok {
+		return session{}, fmt.Errorf("malformed session")
+	}
+	if !hmac.Equal([]byte(signature), []byte(sign(secret, payload))) {
+		return session{}, fmt.Errorf("invalid session signature")
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return session{}, fmt.Errorf("decode session: %w", err)
+	}
+	s := session{}
+	if err = json.Unmarshal(raw, &s); err != nil {
+		return session{}, fmt.Errorf("unmarshal session: %w", err)
+	}
+	if s.expired(now) {
+		return session{}, fmt.Errorf("session expired")
+	}
+	return s, nil
+}
@@ -0,0 +1,229 @@
+"use strict";
+
+const pageSize = 20;
+let debtsOffset = 0;
+let viewer = null;
+
+class UnauthorizedError extends Error {}
+
+async function gql(query, variables = {}) {
+    const res = await fetch("graphql", {
+        method: "POST",
+        credentials: "same-origin",
+        headers: {"Content-Type": "application/json"},
+        body: JSON.stringify({query, variables}),
+    });
+    if (res.status === 401) {
+        throw new UnauthorizedError();
+    }
+    const body = await res.json();
+    if (body.errors && body.errors.length > 0) {
+        throw new Error(body.errors.map(e => e.message).join(", "));
+    }
+    return body.data;
+}
+
+function el(tag, text, attrs = {}) {
+    const element = document.createElementNS(attrs.svg ? "http://www.w3.org/2000/svg" : "http://www.w3.org/1999/xhtml", tag);
+    if (text !== undefined && text !== null) {
+        element.textContent = text;
+    }
+    for (const [key, value] of Object.entries(attrs)) {
+        if (key !== "svg") {
+            element.setAttribute(key, value);
+        }
+    }
+    return element;
+}
+
+function fillTable(section, rows) {
+    const body = document.querySelector(`#${section} tbody`);
+    body.replaceChildren(...rows.map(cells => {
+        const row = el("tr");
+        row.append(...cells.map(cell => {
+            if (cell instanceof Node) {
+                const td = el("td");
+                td.append(cell);
+                return td;
+            }
+            return el("td", cell);
+        }));
+        return row;
+    }));
+}
+
+const amount = value => value === null || value === undefined ? "-" : value.toFixed(2);
+const date = value => new Date(value).toLocaleString();
+const userName = (user, id) => user ? user.fullName : id;
+
+async function loadActive() {
+    const data = await gql(`{ activeOrders { venueName receiver joinedAt deliveryState totalAmount participants { name } } }`);
+    fillTable("active", data.activeOrders.map(o => [
+        o.venueName, o.receiver, date(o.joinedAt), o.deliveryState, amount(o.totalAmount), o.participants.map(p => p.name).join(", "),
+    ]));
+}
+
+function drawChart(months) {
+    const chart = document.getElementById("chart");
+    const width = chart.clientWidth || 600, height = 220, labelsHeight = 20;
+    chart.setAttribute("viewBox", `0 0 ${width} ${height}`);
+    const max = Math.max(1, ...months.map(m => m.totalAmount));
+    const barWidth = width / Math.max(1, months.length);
+    chart.replaceChildren(...months.flatMap((m, i) => {
+        const barHeight = (height - 2 * labelsHeight) * m.totalAmount / max;
+        const x = i * barWidth;
+        const y = height - labelsHeight - barHeight;
+        return [
+            el("rect", null, {svg: true, x: x + barWidth * 0.1, y, width: barWidth * 0.8, height: barHeight}),
+            el("text", amount(m.totalAmount), {svg: true, x: x + barWidth / 2, y: y - 4}),
+            el("text", m.month, {svg: true, x: x + barWidth / 2, y: height - 5}),
+        ];
+    }));
+}
+
+async function loadSpending() {
+    const data = await gql(`{ stats {
+        ordersCount totalAmount averageAmount openDebtsCount openDebtsAmount
+        topVenues(first: 10) { name ordersCount totalAmount }
+        spendingByMonth { month totalAmount }
+    } }`);
+    const stats = data.stats;
+    const cards = [
+        ["Orders", stats.ordersCount], ["Total spent", amount(stats.totalAmount)], ["Average order", amount(stats.averageAmount)],
+        ["Open debts", stats.openDebtsCount], ["Open debts amount", amount(stats.openDebtsAmount)],
+    ];
+    document.querySelector("#spending .cards").replaceChildren(...cards.map(([title, value]) => {
+        const card = el("div", title, {class: "card"});
+        card.prepend(el("strong", value));
+        return card;
+    }));
+    drawChart(stats.spendingByMonth);
+    fillTable("spending", stats.topVenues.map(v => [v.name, v.ordersCount, amount(v.totalAmount)]));
+}
+
+const debtsFields = "id amount orderId createdAt borrowerId lenderId borrower { fullName } lender { fullName } order { proofUrl }";
+const proofUrl = debt => debt.order ? debt.order.proofUrl : "";
+
+function orderCell(debt) {
+    const cell = el("span", debt.orderId);
+    if (proofUrl(debt)) {
+        cell.append(" ", el("a", "proof", {href: proofUrl(debt), target: "_blank", rel: "noopener noreferrer", "aria-label": `Proof of purchase of order ${debt.orderId}`}));
+    }
+    return cell;
+}
+
+function settleButton(debt) {
+    const button = el("button", "Settle");
+    button.addEventListener("click", () => {
+        if (!confirm(`Settle the debt of ${amount(debt.amount)} from ${userName(debt.borrower, debt.borrowerId)}?`)) {
+            return;
+        }
+        run(async () => {
+            await gql(`mutation($id: ID!) { settleDebt(id: $id) { id } }`, {id: debt.id});
+            await loadDebts();
+        });
+    });
+    return button;
+}
+
+async function loadDebts() {
+    const data = await gql(`query($first: Int, $offset: Int) {
+        debts(first: $first, offset: $offset) {
+            nodes { ${debtsFields} }
+            pageInfo { hasNextPage }
+        }
+    }`, {first: pageSize, offset: debtsOffset});
+    document.querySelector("#debts h2").textContent = viewer.treasurer || viewer.admin ? "Outstanding debts" : "Your debts";
+    document.getElementById("debts-export").hidden = !viewer.treasurer;
+    document.getElementById("settle-header").hidden = !viewer.treasurer;
+    fillTable("debts", data.debts.nodes.map(d => {
+        const cells = [userName(d.borrower, d.borrowerId), userName(d.lender, d.lenderId), amount(d.amount), orderCell(d), date(d.createdAt)];
+        if (viewer.treasurer) {
+            cells.push(settleButton(d));
+        }
+        return cells;
+    }));
+    document.getElementById("debts-prev").disabled = debtsOffset === 0;
+    document.getElementById("debts-next").disabled = !data.debts.pageInfo.hasNextPage;
+}
+
+function csvField(value) {
+    const str = String(value);
+    return /[",\n]/.test(str) ? `"${str.replaceAll('"', '""')}"` : str;
+}
+
+async function exportDebts() {
+    const rows = [["debt_id", "created_at", "order_id", "borrower_id", "borrower", "lender_id", "lender", "amount", "order_proof"]];
+    for (let offset = 0, hasNextPage = true; hasNextPage; offset += 100) {
+        const data = await gql(`query($offset: Int) { debts(first: 100, offset: $offset) { nodes { ${debtsFields} } pageInfo { hasNextPage } } }`, {offset});
+        rows.push(...data.debts.nodes.map(d => [
+            d.id, d.createdAt, d.orderId, d.borrowerId, userName(d.borrower, d.borrowerId), d.lenderId, userName(d.lender, d.lenderId), d.amount.toFixed(2), proofUrl(d),
+        ]));
+        hasNextPage = data.debts.pageInfo.hasNextPage;
+    }
+    const csv = rows.map(row => row.map(csvField).join(",")).join("\n") + "\n";
+    const link = el("a", null, {href: URL.createObjectURL(new Blob([csv], {type: "text/csv"})), download: "bolt-debts.csv"});
+    link.click();
+    URL.revokeObjectURL(link.href);
+}
+
+async function loadUsers() {
+    const data = await gql(`{ users { fullName transportId email paymentPreferences } }`);
+    fillTable("users", data.users.map(u => [u.fullName, u.transportId, u.email, u.paymentPreferences.join(", ")]));
+    document.getElementById("add-user").hidden = !viewer.admin;
+}
+
+const loaders = {active: loadActive, spending: loadSpending, debts: loadDebts, users: loadUsers};
+
+async function show(view) {
+    document.querySelectorAll("nav button").forEach(b => b.classList.toggle("selected", b.dataset.view === view));
+    document.querySelectorAll(".view").forEach(v => v.hidden = v.id !== view);
+    await run(loaders[view]);
+}
+
+async function run(fn) {
+    const error = document.getElementById("error");
+    error.hidden = true;
+    try {
+        await fn();
+    } catch (e) {
+        if (e instanceof UnauthorizedError) {
+            document.querySelectorAll(".view, nav, #logout").forEach(v => v.hidden = true);
+            document.getElementById("signin").hidden = false;
+            return;
+        }
+        error.textContent = e.message;
+        error.hidden = false;
+    }
+}
+
+document.querySelectorAll("nav button").forEach(b => b.addEventListener("click", () => show(b.dataset.view)));
+document.getElementById("debts-prev").addEventListener("click", () => {
+    debtsOffset = Math.max(0, debtsOffset - pageSize);
+    run(loadDebts);
+});
+document.getElementById("debts-next").addEventListener("click", () => {
+    debtsOffset += pageSize;
+    run(loadDebts);
+});
+document.getElementById("debts-export").addEventListener("click", () => run(exportDebts));
+document.getElementById("add-user").addEventListener("submit", event => {
+    event.preventDefault();
+    const form = event.target;
+    const message = form.querySelector(".message");
+    run(async () => {
+        await gql(`mutation($fullName: String!, $transportId: String!, $email: String) {
+            addUser(fullName: $fullName, transportId: $transportId, email: $email) { id }
+        }`, {fullName: form.fullName.value, transportId: form.transportId.value, email: form.email.value || null});
+        message.textContent = `Added ${form.fullName.value}`;
+        form.reset();
+        await loadUsers();
+    });
+});
+
+run(async () => {
+    viewer = (await gql(`{ viewer { userId admin treasurer } }`)).viewer;
+    document.getElementById("tabs").hidden = false;
+    document.getElementById("logout").hidden = false;
+    await show("active");
+});
@@ -1,6 +1,7 @@
 package debt
 
 import (
+	"context"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,12 +16,143 @@ type Debt struct {
 	InitiatedTransportID string    `db:"initial_transport"`
 	MessageID            string    `db:"thread_ts"`
 	CreatedAt            time.Time `db:"created_at"`
+	Currency             string    `db:"currency"` // The ISO 4217 code of the currency of the amount, like ILS
+
+	// The audit trail of a payment the lender confirmed, see PAYMENT_CONFIRMATION
+	PaidClaimedAt      *time.Time `db:"paid_claimed_at"`      // When the borrower said they paid, nil if they didn't
+	PaymentConfirmedBy string     `db:"payment_confirmed_by"` // The user ID of the lender who confirmed the payment
+	PaymentConfirmedAt *time.Time `db:"payment_confirmed_at"`
+
+	EscalatedDays int `db:"escalated_days"` // The days of the last escalation stage the debt reached, see DEBT_ESCALATION_STAGES
 }
 
 type Store interface {
-	AddDebt(debt *Debt) error
-	RemoveDebtInOrderID(orderID, debtID string) error
-	ListDebtsForOrderID(orderID string) ([]*Debt, error)
+	AddDebt(ctx context.Context, debt *Debt) error
+	RemoveDebtInOrderID(ctx context.Context, orderID, debtID string) error
+	ListDebtsForOrderID(ctx context.Context, orderID string) ([]*Debt, error)
+	ListDebts(ctx context.Context, filter ListFilter) ([]*Debt, error)
+}
+
+// SortField is the field debts are listed by
+type SortField string
+
+const (
+	SortByCreatedAt SortField = "" // The default
+	SortByAmount    SortField = "amount"
+)
+
+// ListFilter filters debts by all the non-empty fields. Debts are returned from the newest to the oldest, unless sorted otherwise.
+type ListFilter struct {
+	BorrowerID string
+	LenderID   string
+	OrderIDs   []string
+	UserIDs    []string // Matches debts any of the users is either the borrower or the lender of
+	Channel    string   // The channel the order of the debt was sent in
+	SortBy     SortField
+	Ascending  bool // Sorts from the smallest (or oldest) to the largest, instead of from the largest
+	Limit      uint64
+	Offset     uint64
+}
+
+// CountStore counts debts without listing them, for paging through them. It's optional, and implemented by debt stores which
+// support it.
+type CountStore interface {
+	// CountDebts returns the number of debts matching the filter, ignoring its sorting and pagination
+	CountDebts(ctx context.Context, filter ListFilter) (int, error)
+}
+
+// Payment is a paid debt, kept after the debt itself was removed for statistics
+type Payment struct {
+	Debt
+	PaidAt time.Time `db:"paid_at"`
+}
+
+// PaymentStore keeps the history of paid debts. It's optional, and implemented by debt stores which support it.
+type PaymentStore interface {
+	AddPayment(ctx context.Context, payment *Payment) error
+	ListPayments(ctx context.Context, filter PaymentListFilter) ([]*Payment, error)
+}
+
+// PaymentListFilter filters payments by all the non-empty fields. Payments are returned from the newest to the oldest.
+type PaymentListFilter struct {
+	Channel    string // The channel the order of the debt was sent in
+	BorrowerID string
+	OrderID    string
+	PaidBefore time.Time
+}
+
+// PaymentClaimStore keeps the payments borrowers said they made, until their lenders confirm them. It's optional, and implemented by
+// debt stores which support it.
+type PaymentClaimStore interface {
+	// SetPaidClaimedAt records when the borrower said they paid the debt, or clears it if claimedAt is nil
+	SetPaidClaimedAt(ctx context.Context, debtID string, claimedAt *time.Time) error
+}
+
+// EscalationStore keeps the escalation stage each unpaid debt reached (Debt.EscalatedDays), so restarts don't escalate debts again.
+// It's optional, and implemented by debt stores which support it.
+type EscalationStore interface {
+	SetEscalatedDays(ctx context.Context, debtID string, days int) error
+}
+
+// PendingDebt is a debt of a participant who wasn't matched to a user, which becomes a debt once a user with the participant's name is added
+type PendingDebt struct {
+	ID                   string    `db:"id"`
+	WoltName             string    `db:"wolt_name"`
+	LenderID             string    `db:"lender_id"`
+	OrderID              string    `db:"order_id"`
+	Amount               float64   `db:"amount"`
+	InitiatedTransportID string    `db:"initial_transport"`
+	MessageID            string    `db:"thread_ts"`
+	CreatedAt            time.Time `db:"created_at"`
+	Currency             string    `db:"currency"`
+}
+
+// PendingStore keeps the pending debts. It's optional, and implemented by debt stores which support it.
+type PendingStore interface {
+	AddPendingDebt(ctx context.Context, debt *PendingDebt) error
+	// ListPendingDebts returns the pending debts of the given Wolt name (case-insensitive)
+	ListPendingDebts(ctx context.Context, woltName string) ([]*PendingDebt, error)
+	RemovePendingDebt(ctx context.Context, id string) error
+}
+
+// AbroadStore keeps the currencies of users (by transport ID) who are abroad, to show their debts reminders in. It's optional,
+// and implemented by debt stores which support it.
+type AbroadStore interface {
+	SetAbroadCurrency(ctx context.Context, transportID, currency string) error
+	// AbroadCurrency returns the currency of the user, or an empty string if the user isn't abroad
+	AbroadCurrency(ctx context.Context, transportID string) (string, error)
+	RemoveAbroadCurrency(ctx context.Context, transportID string) error
+}
+
+// ReminderStore keeps the users (by transport ID) who opted out of the debts reminders. It's optional, and implemented by debt
+// stores which support it.
+type ReminderStore interface {
+	SetRemindersOptOut(ctx context.Context, transportID string, optOut bool) error
+	RemindersOptedOut(ctx context.Context, transportID string) (bool, error)
+}
+
+// DigestNotification is a non-urgent notification of a user in digest mode, waiting for the user's daily digest
+type DigestNotification struct {
+	TransportID string    `db:"transport_id"`
+	Key         string    `db:"key"` // A newer notification with the same key, like another reminder of the same debt, replaces the older one
+	Text        string    `db:"text"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+// DigestStore keeps the users (by transport ID) who get their non-urgent notifications once a day, with the hour of their digest,
+// and the notifications waiting for their digests. It's optional, and implemented by debt stores which support it.
+type DigestStore interface {
+	SetDigestHour(ctx context.Context, transportID string, hour int) error
+	// DigestHour returns the hour of the user's digest, or -1 if the user gets the notifications right away
+	DigestHour(ctx context.Context, transportID string) (int, error)
+	RemoveDigestHour(ctx context.Context, transportID string) error
+	// ListDigestHours returns the hours of the digests of all the users in digest mode, by their transport IDs
+	ListDigestHours(ctx context.Context) (map[string]int, error)
+	// AddDigestNotification adds the notification, replacing the user's notification with the same key
+	AddDigestNotification(ctx context.Context, notification *DigestNotification) error
+	RemoveDigestNotification(ctx context.Context, transportID, key string) error
+	// PopDigestNotifications removes the notifications waiting for the user's digest and returns them, from the oldest
+	PopDigestNotifications(ctx context.Context, transportID string) ([]*DigestNotification, error)
 }
 
 func NewDebt(borrowerID, lenderID, orderID, initiatedTransportID, messageID string, amount float64) *Debt {
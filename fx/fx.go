@@ -0,0 +1,98 @@
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Config struct {
+	ProviderURL   string        `env:"FX_PROVIDER_URL" envDefault:"https://api.frankfurter.app"`
+	CacheDuration time.Duration `env:"FX_CACHE_DURATION" envDefault:"1h"`
+}
+
+// Provider returns exchange rates between currencies (ISO 4217 codes)
+type Provider interface {
+	// Rate returns how much one unit of the from currency is worth in the to currency
+	Rate(ctx context.Context, from, to string) (float64, error)
+}
+
+type cachedRate struct {
+	rate      float64
+	fetchedAt time.Time
+}
+
+// Client is a Provider for a Frankfurter compatible API (GET /latest?from=<from>&to=<to>), caching the rates for FX_CACHE_DURATION
+type Client struct {
+	cfg    Config
+	client *http.Client
+
+	lock  sync.Mutex
+	cache map[string]cachedRate
+}
+
+func NewClient(cfg Config) *Client {
+	return &Client{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+		cache:  make(map[string]cachedRate),
+	}
+}
+
+func (c *Client) Rate(ctx context.Context, from, to string) (float64, error) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	if from == to {
+		return 1, nil
+	}
+
+	key := from + "/" + to
+	c.lock.Lock()
+	cached, ok := c.cache[key]
+	c.lock.Unlock()
+	if ok && time.Since(cached.fetchedAt) < c.cfg.CacheDuration {
+		return cached.rate, nil
+	}
+
+	rate, err := c.fetchRate(ctx, from, to)
+	if err != nil {
+		return 0, err
+	}
+	c.lock.Lock()
+	c.cache[key] = cachedRate{rate: rate, fetchedAt: time.Now()}
+	c.lock.Unlock()
+	return rate, nil
+}
+
+func (c *Client) fetchRate(ctx context.Context, from, to string) (float64, error) {
+	query := url.Values{"from": {from}, "to": {to}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(c.cfg.ProviderURL, "/")+"/latest?"+query.Encode(), nil)
+	if err != nil {
+		return 0, fmt.Errorf("new request: %w", err)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status %d for rate of %s to %s", resp.StatusCode, from, to)
+	}
+
+	var body struct {
+		Rates map[string]float64 `json:"rates"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return 0, fmt.Errorf("decode response: %w", err)
+	}
+	rate, ok := body.Rates[to]
+	if !ok || rate <= 0 {
+		return 0, fmt.Errorf("no rate of %s to %s", from, to)
+	}
+	return rate, nil
+}
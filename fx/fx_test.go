@@ -0,0 +1,51 @@
+package fx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientRate(t *testing.T) {
+	t.Parallel()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/latest" || r.URL.Query().Get("from") != "ILS" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.URL.Query().Get("to") == "XYZ" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"amount":1.0,"base":"ILS","rates":{"%s":0.27}}`, r.URL.Query().Get("to"))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(Config{ProviderURL: server.URL + "/", CacheDuration: time.Hour})
+	ctx := context.Background()
+
+	rate, err := client.Rate(ctx, "ILS", "usd")
+	require.NoError(t, err)
+	assert.Equal(t, 0.27, rate)
+	rate, err = client.Rate(ctx, "ILS", "USD")
+	require.NoError(t, err)
+	assert.Equal(t, 0.27, rate)
+	assert.Equal(t, 1, requests, "rates are cached")
+
+	rate, err = client.Rate(ctx, "ILS", "ils")
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, rate)
+	assert.Equal(t, 1, requests)
+
+	_, err = client.Rate(ctx, "ILS", "XYZ")
+	assert.Error(t, err)
+}
@@ -0,0 +1,68 @@
+package headcount
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+type Config struct {
+	SourceURL string `env:"HEADCOUNT_URL"` // Empty disables the headcount based order suggestions
+}
+
+// Provider returns how many people are in the office
+type Provider interface {
+	// Headcount returns how many people are in the office on the day of the given time
+	Headcount(ctx context.Context, day time.Time) (int, error)
+}
+
+// Client is a Provider for a simple headcount API (GET <HEADCOUNT_URL>?date=<YYYY-MM-DD> returning {"headcount": <count>}), such as an
+// office booking system or a small adapter over the office calendar
+type Client struct {
+	cfg    Config
+	client *http.Client
+}
+
+func NewClient(cfg Config) *Client {
+	return &Client{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (c *Client) Headcount(ctx context.Context, day time.Time) (int, error) {
+	u, err := url.Parse(c.cfg.SourceURL)
+	if err != nil {
+		return 0, fmt.Errorf("parse HEADCOUNT_URL: %w", err)
+	}
+	query := u.Query()
+	query.Set("date", day.Format("2006-01-02"))
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return 0, fmt.Errorf("new request: %w", err)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status %d for headcount of %s", resp.StatusCode, day.Format("2006-01-02"))
+	}
+
+	var body struct {
+		Headcount *int `json:"headcount"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return 0, fmt.Errorf("decode response: %w", err)
+	}
+	if body.Headcount == nil || *body.Headcount < 0 {
+		return 0, fmt.Errorf("no headcount for %s", day.Format("2006-01-02"))
+	}
+	return *body.Headcount, nil
+}
@@ -0,0 +1,45 @@
+package headcount
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientHeadcount(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/office" || r.URL.Query().Get("site") != "tlv" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch r.URL.Query().Get("date") {
+		case "2024-05-01":
+			_, _ = w.Write([]byte(`{"headcount": 14}`))
+		case "2024-05-02":
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(Config{SourceURL: server.URL + "/office?site=tlv"})
+	ctx := context.Background()
+
+	count, err := client.Headcount(ctx, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, 14, count)
+
+	_, err = client.Headcount(ctx, time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC))
+	assert.ErrorContains(t, err, "no headcount for 2024-05-02")
+
+	_, err = client.Headcount(ctx, time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+	assert.ErrorContains(t, err, "unexpected status 404")
+}
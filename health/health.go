@@ -0,0 +1,122 @@
+// Package health serves the liveness and readiness endpoints of Bolt, for running it behind probes like the ones of Kubernetes
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/oriser/bolt/service"
+)
+
+const (
+	LivenessPath  = "/healthz"
+	ReadinessPath = "/readyz"
+
+	// readinessTimeout is the deadline of all the readiness checks
+	readinessTimeout = 10 * time.Second
+	// readinessCacheTTL is how long the result of the readiness checks is reused, so frequent probes don't call Slack and Wolt each time
+	readinessCacheTTL = 5 * time.Second
+)
+
+type Config struct {
+	Port int `env:"HEALTH_PORT"` // The port to serve /healthz and /readyz on, 0 disables serving them
+}
+
+// Service is the part of the service the endpoints report on
+type Service interface {
+	ReadinessChecks(ctx context.Context) []service.SelfTestCheck
+	TrackedOrders() service.TrackedOrdersSummary
+}
+
+// Check is the result of a readiness check
+type Check struct {
+	Name   string `json:"name"`
+	Status string `json:"status"` // ok, failed or skipped
+	Detail string `json:"detail,omitempty"`
+}
+
+// Status is the response of the endpoints
+type Status struct {
+	Status        string                       `json:"status"` // ok or failed
+	Checks        []Check                      `json:"checks,omitempty"`
+	TrackedOrders service.TrackedOrdersSummary `json:"tracked_orders"`
+}
+
+// Handler serves the endpoints
+type Handler struct {
+	service Service
+
+	lock      sync.Mutex
+	checks    []Check
+	ready     bool
+	checkedAt time.Time
+}
+
+func NewHandler(svc Service) *Handler {
+	return &Handler{service: svc}
+}
+
+// ServeHTTP serves /healthz, which only tells the process is responsive, as failing dependencies don't call for restarting it, and
+// /readyz, which answers 503 when any of the dependencies fails
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case LivenessPath:
+		writeStatus(w, http.StatusOK, Status{Status: "ok", TrackedOrders: h.service.TrackedOrders()})
+	case ReadinessPath:
+		checks, ready := h.readiness(r.Context())
+		status := Status{Status: "ok", Checks: checks, TrackedOrders: h.service.TrackedOrders()}
+		code := http.StatusOK
+		if !ready {
+			status.Status = "failed"
+			code = http.StatusServiceUnavailable
+		}
+		writeStatus(w, code, status)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// readiness returns the results of the readiness checks, running them if the last results are older than readinessCacheTTL
+func (h *Handler) readiness(ctx context.Context) ([]Check, bool) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if !h.checkedAt.IsZero() && time.Since(h.checkedAt) < readinessCacheTTL {
+		return h.checks, h.ready
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+	results := h.service.ReadinessChecks(ctx)
+	h.checks, h.ready = make([]Check, len(results)), true
+	for i, result := range results {
+		h.checks[i] = Check{Name: result.Name, Status: "ok"}
+		switch {
+		case result.Skipped != "":
+			h.checks[i].Status, h.checks[i].Detail = "skipped", result.Skipped
+		case result.Err != nil:
+			h.checks[i].Status, h.checks[i].Detail = "failed", result.Err.Error()
+			h.ready = false
+		}
+	}
+	h.checkedAt = time.Now()
+	return h.checks, h.ready
+}
+
+func writeStatus(w http.ResponseWriter, code int, status Status) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(status)
+}
+
+// ListenAndServe serves the endpoints on the configured port
+func ListenAndServe(cfg Config, svc Service) error {
+	mux := http.NewServeMux()
+	handler := NewHandler(svc)
+	mux.Handle(LivenessPath, handler)
+	mux.Handle(ReadinessPath, handler)
+	return http.ListenAndServe(fmt.Sprintf(":%d", cfg.Port), mux)
+}
@@ -0,0 +1,71 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/oriser/bolt/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeService struct {
+	checks []service.SelfTestCheck
+	calls  int
+}
+
+func (f *fakeService) ReadinessChecks(context.Context) []service.SelfTestCheck {
+	f.calls++
+	return f.checks
+}
+
+func (f *fakeService) TrackedOrders() service.TrackedOrdersSummary {
+	return service.TrackedOrdersSummary{Total: 1, ByStatus: map[string]int{"the order is on its way": 1}}
+}
+
+func get(t *testing.T, handler http.Handler, path string) (int, Status) {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	var status Status
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&status))
+	return recorder.Code, status
+}
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	svc := &fakeService{checks: []service.SelfTestCheck{
+		{Name: "Chat platform"},
+		{Name: "Wolt API", Err: errors.New("connection refused")},
+		{Name: "Store read", Skipped: "no order store"},
+	}}
+	handler := NewHandler(svc)
+
+	code, status := get(t, handler, LivenessPath)
+	assert.Equal(t, http.StatusOK, code, "failing dependencies don't fail the liveness probe")
+	assert.Equal(t, "ok", status.Status)
+	assert.Equal(t, 1, status.TrackedOrders.Total)
+	assert.Zero(t, svc.calls)
+
+	code, status = get(t, handler, ReadinessPath)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, Status{Status: "failed", Checks: []Check{
+		{Name: "Chat platform", Status: "ok"},
+		{Name: "Wolt API", Status: "failed", Detail: "connection refused"},
+		{Name: "Store read", Status: "skipped", Detail: "no order store"},
+	}, TrackedOrders: service.TrackedOrdersSummary{Total: 1, ByStatus: map[string]int{"the order is on its way": 1}}}, status)
+
+	svc.checks = svc.checks[:1]
+	code, _ = get(t, handler, ReadinessPath)
+	assert.Equal(t, http.StatusServiceUnavailable, code, "the results are cached")
+	assert.Equal(t, 1, svc.calls)
+
+	handler.checkedAt = handler.checkedAt.Add(-readinessCacheTTL)
+	code, status = get(t, handler, ReadinessPath)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", status.Status)
+}
@@ -0,0 +1,112 @@
+// Package logging sets up Bolt's structured logger, with the level and format of LOG_LEVEL and LOG_FORMAT, and carries the
+// attributes of what's being handled (like the group ID, channel and message ID of an order) in contexts, so every log line of it
+// has them
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/oriser/bolt/tracing"
+)
+
+// The formats of the log lines
+const (
+	FormatText = "text"
+	FormatJSON = "json" // For log collectors, like the ones of Kubernetes
+)
+
+type Config struct {
+	Level  string `env:"LOG_LEVEL" envDefault:"info"` // debug, info, warn or error
+	Format string `env:"LOG_FORMAT" envDefault:"text"`
+}
+
+// New returns a logger writing to w in the configured level and format, adding the attributes of the context of each line
+func New(cfg Config, w io.Writer) (*slog.Logger, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+		return nil, fmt.Errorf("LOG_LEVEL must be debug, info, warn or error but got %q", cfg.Level)
+	}
+	options := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch strings.ToLower(cfg.Format) {
+	case FormatText, "":
+		handler = slog.NewTextHandler(w, options)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, options)
+	default:
+		return nil, fmt.Errorf("LOG_FORMAT must be %s or %s but got %q", FormatText, FormatJSON, cfg.Format)
+	}
+	return slog.New(&contextHandler{Handler: handler}), nil
+}
+
+// Setup creates the logger writing to stderr and makes it the default one, so the lines of the standard log package go through it too
+func Setup(cfg Config) (*slog.Logger, error) {
+	logger, err := New(cfg, os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+	slog.SetDefault(logger)
+	return logger, nil
+}
+
+type attrsKey struct{}
+
+// With returns a context carrying the attributes (as key-value pairs, like the arguments of slog.Logger.Info) in addition to those of
+// ctx, which are added to the lines logged with it
+func With(ctx context.Context, args ...any) context.Context {
+	record := slog.NewRecord(time.Time{}, 0, "", 0)
+	record.Add(args...)
+	attrs := append([]slog.Attr{}, attrsFromContext(ctx)...)
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, attr)
+		return true
+	})
+	return context.WithValue(ctx, attrsKey{}, attrs)
+}
+
+// CopyAttrs returns ctx carrying the attributes of from as well, for contexts which outlive the request they're of, like the context
+// of a tracked order
+func CopyAttrs(ctx, from context.Context) context.Context {
+	attrs := attrsFromContext(from)
+	if len(attrs) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, attrsKey{}, append(append([]slog.Attr{}, attrsFromContext(ctx)...), attrs...))
+}
+
+func attrsFromContext(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	attrs, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+	return attrs
+}
+
+// contextHandler adds the attributes of the context of each line, and the IDs of its trace and span so the logs of a slow trace
+// can be found
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	record.AddAttrs(attrsFromContext(ctx)...)
+	if span := tracing.SpanFromContext(ctx); span != nil {
+		record.AddAttrs(slog.String("trace_id", span.TraceID()), slog.String("span_id", span.SpanID()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,66 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/oriser/bolt/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	logger, err := New(Config{Level: "info", Format: "json"}, &out)
+	require.NoError(t, err)
+
+	ctx, span := tracing.Start(context.Background(), "track_order")
+	ctx = With(ctx, "group_id", "ABC", "channel", "C1")
+	orderCtx := CopyAttrs(context.Background(), With(ctx, "message_id", "1700000000.000100"))
+	logger.DebugContext(ctx, "Polling the group")
+	logger.ErrorContext(ctx, "Error getting rate for group", "error", errors.New("timed out"))
+	logger.InfoContext(orderCtx, "Order was paid by the company")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 2, "debug lines aren't logged at the info level")
+	var line map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &line))
+	assert.Equal(t, "ERROR", line["level"])
+	assert.Equal(t, "Error getting rate for group", line["msg"])
+	assert.Equal(t, "timed out", line["error"])
+	assert.Equal(t, "ABC", line["group_id"])
+	assert.Equal(t, "C1", line["channel"])
+	assert.Equal(t, span.TraceID(), line["trace_id"])
+	assert.Equal(t, span.SpanID(), line["span_id"])
+
+	line = nil
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &line))
+	assert.Equal(t, "ABC", line["group_id"], "the copied context has the attributes of the order")
+	assert.Equal(t, "1700000000.000100", line["message_id"])
+	assert.NotContains(t, line, "trace_id", "only the attributes are copied")
+
+	out.Reset()
+	logger, err = New(Config{Level: "warn", Format: "text"}, &out)
+	require.NoError(t, err)
+	logger.InfoContext(ctx, "Already working on order")
+	logger.WarnContext(ctx, "Taking over order")
+	assert.Contains(t, out.String(), `level=WARN msg="Taking over order" group_id=ABC channel=C1`)
+	assert.NotContains(t, out.String(), "Already working")
+}
+
+func TestConfig(t *testing.T) {
+	t.Parallel()
+
+	_, err := New(Config{Level: "verbose", Format: "text"}, &bytes.Buffer{})
+	assert.EqualError(t, err, `LOG_LEVEL must be debug, info, warn or error but got "verbose"`)
+	_, err = New(Config{Level: "info", Format: "xml"}, &bytes.Buffer{})
+	assert.EqualError(t, err, `LOG_FORMAT must be text or json but got "xml"`)
+	_, err = New(Config{Level: "DEBUG", Format: "JSON"}, &bytes.Buffer{})
+	assert.NoError(t, err)
+}
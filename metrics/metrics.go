@@ -0,0 +1,314 @@
+// Package metrics keeps counters, gauges and histograms of Bolt's activity, and serves them in the Prometheus text exposition format
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Path is the path the metrics are served on
+const Path = "/metrics"
+
+type Config struct {
+	Port int `env:"METRICS_PORT"` // The port to serve the metrics on, 0 disables serving them
+}
+
+// DurationBuckets are the default buckets (in seconds) of duration histograms, from 5ms to 10s
+var DurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// metric is a family of series with the same name, one per combination of label values
+type metric interface {
+	write(sb *strings.Builder)
+}
+
+// Registry keeps the metrics to serve
+type Registry struct {
+	lock    sync.Mutex
+	metrics []metric
+	names   map[string]bool
+}
+
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+// DefaultRegistry is the registry of the metrics created with NewCounter, NewGauge and NewHistogram
+var DefaultRegistry = NewRegistry()
+
+func (r *Registry) register(name string, m metric) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.names[name] {
+		panic(fmt.Sprintf("metric %s is already registered", name))
+	}
+	r.names[name] = true
+	r.metrics = append(r.metrics, m)
+}
+
+// Write returns the metrics in the Prometheus text exposition format
+func (r *Registry) Write() string {
+	r.lock.Lock()
+	metrics := append([]metric(nil), r.metrics...)
+	r.lock.Unlock()
+
+	sb := &strings.Builder{}
+	for _, m := range metrics {
+		m.write(sb)
+	}
+	return sb.String()
+}
+
+// Handler serves the metrics of the registry
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, _ = w.Write([]byte(r.Write()))
+	})
+}
+
+// ListenAndServe serves the metrics of the default registry on METRICS_PORT
+func ListenAndServe(cfg Config) error {
+	mux := http.NewServeMux()
+	mux.Handle(Path, DefaultRegistry.Handler())
+	return http.ListenAndServe(fmt.Sprintf(":%d", cfg.Port), mux)
+}
+
+// family is the series of a metric by their label values
+type family struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+
+	lock   sync.Mutex
+	series map[string][]string // The label values of each series, by their key
+}
+
+func (f *family) init(name, help, kind string, labels []string) {
+	f.name, f.help, f.kind, f.labels = name, help, kind, labels
+	f.series = make(map[string][]string)
+}
+
+// key returns the key of the series with the label values, adding it if it's new. It must be called with the lock held.
+func (f *family) key(labelValues []string) string {
+	if len(labelValues) != len(f.labels) {
+		panic(fmt.Sprintf("metric %s has %d labels but got %d values", f.name, len(f.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	if _, ok := f.series[key]; !ok {
+		f.series[key] = append([]string(nil), labelValues...)
+	}
+	return key
+}
+
+// sortedKeys returns the keys of the series, sorted so the output is stable. It must be called with the lock held.
+func (f *family) sortedKeys() []string {
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (f *family) writeHeader(sb *strings.Builder) {
+	fmt.Fprintf(sb, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.kind)
+}
+
+// labelPairs formats the labels of a series, with the extra label pairs (like a histogram's bucket) at the end. The values are
+// quoted with %q, which escapes the backslashes, quotes and newlines like the format expects.
+func (f *family) labelPairs(labelValues []string, extra ...string) string {
+	pairs := make([]string, 0, len(labelValues)+len(extra)/2)
+	for i, value := range labelValues {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", f.labels[i], value))
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", extra[i], extra[i+1]))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// Counter is a metric which only goes up, like the number of handled requests
+type Counter struct {
+	family
+	values map[string]float64
+}
+
+// NewCounter returns a counter registered in the default registry, with the given label names
+func NewCounter(name, help string, labels ...string) *Counter {
+	return DefaultRegistry.NewCounter(name, help, labels...)
+}
+
+// NewCounter returns a counter registered in the registry, with the given label names
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{values: make(map[string]float64)}
+	c.init(name, help, "counter", labels)
+	r.register(name, c)
+	return c
+}
+
+// Inc increments the series of the label values (in the order of the counter's labels)
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds the value to the series of the label values. Negative values are ignored, as counters only go up.
+func (c *Counter) Add(value float64, labelValues ...string) {
+	if value < 0 {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.values[c.key(labelValues)] += value
+}
+
+// Value returns the value of the series of the label values
+func (c *Counter) Value(labelValues ...string) float64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.values[strings.Join(labelValues, "\xff")]
+}
+
+func (c *Counter) write(sb *strings.Builder) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.writeHeader(sb)
+	for _, key := range c.sortedKeys() {
+		fmt.Fprintf(sb, "%s%s %s\n", c.name, c.labelPairs(c.series[key]), formatFloat(c.values[key]))
+	}
+}
+
+// Gauge is a metric which goes up and down, like the number of running goroutines
+type Gauge struct {
+	family
+	values map[string]float64
+}
+
+// NewGauge returns a gauge registered in the default registry, with the given label names
+func NewGauge(name, help string, labels ...string) *Gauge {
+	return DefaultRegistry.NewGauge(name, help, labels...)
+}
+
+// NewGauge returns a gauge registered in the registry, with the given label names
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{values: make(map[string]float64)}
+	g.init(name, help, "gauge", labels)
+	r.register(name, g)
+	return g
+}
+
+// Set sets the series of the label values (in the order of the gauge's labels) to the value
+func (g *Gauge) Set(value float64, labelValues ...string) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.values[g.key(labelValues)] = value
+}
+
+// Value returns the value of the series of the label values
+func (g *Gauge) Value(labelValues ...string) float64 {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.values[strings.Join(labelValues, "\xff")]
+}
+
+func (g *Gauge) write(sb *strings.Builder) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.writeHeader(sb)
+	for _, key := range g.sortedKeys() {
+		fmt.Fprintf(sb, "%s%s %s\n", g.name, g.labelPairs(g.series[key]), formatFloat(g.values[key]))
+	}
+}
+
+type histogramSeries struct {
+	buckets []uint64 // The count of observations in each bucket (not cumulative)
+	count   uint64
+	sum     float64
+}
+
+// Histogram samples observations (like durations) in buckets
+type Histogram struct {
+	family
+	bounds []float64 // The upper bounds of the buckets, sorted
+	values map[string]*histogramSeries
+}
+
+// NewHistogram returns a histogram registered in the default registry, with the given upper bounds of buckets and label names
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return DefaultRegistry.NewHistogram(name, help, buckets, labels...)
+}
+
+// NewHistogram returns a histogram registered in the registry, with the given upper bounds of buckets and label names
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	bounds := append([]float64(nil), buckets...)
+	sort.Float64s(bounds)
+	h := &Histogram{bounds: bounds, values: make(map[string]*histogramSeries)}
+	h.init(name, help, "histogram", labels)
+	r.register(name, h)
+	return h
+}
+
+// Observe adds the value to the series of the label values
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	key := h.key(labelValues)
+	series, ok := h.values[key]
+	if !ok {
+		series = &histogramSeries{buckets: make([]uint64, len(h.bounds))}
+		h.values[key] = series
+	}
+	series.count++
+	series.sum += value
+	if i := sort.SearchFloat64s(h.bounds, value); i < len(h.bounds) {
+		series.buckets[i]++
+	}
+}
+
+// ObserveSince observes the seconds since the start time
+func (h *Histogram) ObserveSince(start time.Time, labelValues ...string) {
+	h.Observe(time.Since(start).Seconds(), labelValues...)
+}
+
+// Count returns the number of observations of the series of the label values
+func (h *Histogram) Count(labelValues ...string) uint64 {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if series, ok := h.values[strings.Join(labelValues, "\xff")]; ok {
+		return series.count
+	}
+	return 0
+}
+
+func (h *Histogram) write(sb *strings.Builder) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.writeHeader(sb)
+	for _, key := range h.sortedKeys() {
+		labelValues, series := h.series[key], h.values[key]
+		cumulative := uint64(0)
+		for i, bound := range h.bounds {
+			cumulative += series.buckets[i]
+			fmt.Fprintf(sb, "%s_bucket%s %d\n", h.name, h.labelPairs(labelValues, "le", formatFloat(bound)), cumulative)
+		}
+		fmt.Fprintf(sb, "%s_bucket%s %d\n", h.name, h.labelPairs(labelValues, "le", "+Inf"), series.count)
+		fmt.Fprintf(sb, "%s_sum%s %s\n", h.name, h.labelPairs(labelValues), formatFloat(series.sum))
+		fmt.Fprintf(sb, "%s_count%s %d\n", h.name, h.labelPairs(labelValues), series.count)
+	}
+}
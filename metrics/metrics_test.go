@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	registry := NewRegistry()
+	requests := registry.NewCounter("requests_total", "Requests by call and result", "call", "result")
+	duration := registry.NewHistogram("request_duration_seconds", "Request durations", []float64{1, 0.1}, "call")
+	orders := registry.NewCounter("orders_total", "Orders")
+	goroutines := registry.NewGauge("goroutines", "Running goroutines")
+
+	requests.Inc("details", "ok")
+	requests.Inc("details", "ok")
+	requests.Inc("join", "error")
+	requests.Add(-1, "join", "error")
+	duration.Observe(0.05, "details")
+	duration.Observe(0.5, "details")
+	duration.Observe(3, "details")
+	assert.Equal(t, 2.0, requests.Value("details", "ok"))
+	assert.Equal(t, uint64(3), duration.Count("details"))
+	assert.Panics(t, func() { requests.Inc("details") }, "all the labels should have values")
+	assert.Panics(t, func() { registry.NewCounter("orders_total", "Orders") }, "metrics names should be unique")
+	orders.Inc()
+	goroutines.Set(12)
+	goroutines.Set(7)
+	assert.Equal(t, 7.0, goroutines.Value())
+
+	recorder := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", Path, nil))
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", recorder.Header().Get("Content-Type"))
+	require.Equal(t, `# HELP requests_total Requests by call and result
+# TYPE requests_total counter
+requests_total{call="details",result="ok"} 2
+requests_total{call="join",result="error"} 1
+# HELP request_duration_seconds Request durations
+# TYPE request_duration_seconds histogram
+request_duration_seconds_bucket{call="details",le="0.1"} 1
+request_duration_seconds_bucket{call="details",le="1"} 2
+request_duration_seconds_bucket{call="details",le="+Inf"} 3
+request_duration_seconds_sum{call="details"} 3.55
+request_duration_seconds_count{call="details"} 3
+# HELP orders_total Orders
+# TYPE orders_total counter
+orders_total 1
+# HELP goroutines Running goroutines
+# TYPE goroutines gauge
+goroutines 7
+`, recorder.Body.String())
+}
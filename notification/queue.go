@@ -0,0 +1,365 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oriser/bolt/service"
+)
+
+// Notifier is the transport used to deliver messages (same as service.EventNotification)
+type Notifier interface {
+	SendMessage(receiver, event, messageID string) (string, error)
+	EditMessage(receiver, event, messageID string) error
+	AddReaction(receiver, messageID, reaction string) error
+}
+
+type Config struct {
+	MessageInterval time.Duration `env:"NOTIFICATION_MESSAGE_INTERVAL" envDefault:"1s"` // Minimum time between messages to the same receiver, 0 disables the queue
+	Batching        bool          `env:"NOTIFICATION_BATCHING" envDefault:"false"`
+	MaxBatchSize    int           `env:"NOTIFICATION_MAX_BATCH_SIZE" envDefault:"5"`
+	IdleWorkerTime  time.Duration `env:"NOTIFICATION_IDLE_WORKER_TIME" envDefault:"1m"`
+	// Messages longer than the max length (0 is the transport's limit) are split to several messages, or sent truncated with the full
+	// text as a file when they need more than the max parts
+	MaxMessageLength int `env:"NOTIFICATION_MAX_MESSAGE_LENGTH" envDefault:"0"`
+	MaxMessageParts  int `env:"NOTIFICATION_MAX_MESSAGE_PARTS" envDefault:"4"`
+}
+
+// fileUploader is implemented by notifiers which can send files
+type fileUploader interface {
+	UploadFile(receiver, filename, content, comment string) error
+}
+
+// lengthLimiter is implemented by notifiers whose messages are limited in length
+type lengthLimiter interface {
+	MaxMessageLength() int
+}
+
+type requestKind int
+
+const (
+	requestSend requestKind = iota
+	requestEdit
+)
+
+type result struct {
+	messageID string
+	err       error
+}
+
+type request struct {
+	kind        requestKind
+	receiver    string
+	text        string
+	interactive *service.InteractiveMessage // Set for messages with buttons, which are never batched
+	messageID   string
+	priority    bool
+	editable    bool // Set for messages which are edited later, which are never batched so an edit doesn't overwrite other messages
+	done        chan result
+}
+
+type receiverQueue struct {
+	pending []*request
+	wake    chan struct{}
+}
+
+// Queue is a Notifier which serializes the messages sent to each receiver (channel or user), making sure
+// no more than one message is sent every MessageInterval to the same receiver.
+// While waiting, pending edits of the same message are coalesced to the latest one, and when batching is enabled
+// pending messages to the same receiver and thread are merged into one, except for the messages which are edited later.
+// Reactions are not queued.
+// Messages longer than the transport's limit are split at safe boundaries, and edits are truncated to it.
+type Queue struct {
+	cfg    Config
+	next   Notifier
+	lock   sync.Mutex
+	queues map[string]*receiverQueue
+}
+
+func NewQueue(cfg Config, next Notifier) *Queue {
+	if cfg.MaxBatchSize <= 0 {
+		cfg.MaxBatchSize = 1
+	}
+	return &Queue{
+		cfg:    cfg,
+		next:   next,
+		queues: make(map[string]*receiverQueue),
+	}
+}
+
+func (q *Queue) SendMessage(receiver, event, messageID string) (string, error) {
+	res := q.enqueue(&request{kind: requestSend, receiver: receiver, text: event, messageID: messageID})
+	return res.messageID, res.err
+}
+
+// SendPriorityMessage sends a message ahead of the non-priority messages waiting for the same receiver.
+// It's meant for direct responses to user interactions.
+func (q *Queue) SendPriorityMessage(receiver, event, messageID string) (string, error) {
+	res := q.enqueue(&request{kind: requestSend, receiver: receiver, text: event, messageID: messageID, priority: true})
+	return res.messageID, res.err
+}
+
+// SendEditableMessage sends a message which is edited later. It's never merged with other messages, as editing the merged
+// message would overwrite them.
+func (q *Queue) SendEditableMessage(receiver, event, messageID string) (string, error) {
+	res := q.enqueue(&request{kind: requestSend, receiver: receiver, text: event, messageID: messageID, editable: true})
+	return res.messageID, res.err
+}
+
+func (q *Queue) EditMessage(receiver, event, messageID string) error {
+	return q.enqueue(&request{kind: requestEdit, receiver: receiver, text: event, messageID: messageID}).err
+}
+
+func (q *Queue) AddReaction(receiver, messageID, reaction string) error {
+	return q.next.AddReaction(receiver, messageID, reaction)
+}
+
+// MessageLink returns a link to a sent message, if the next notifier supports it
+func (q *Queue) MessageLink(receiver, messageID string) (string, error) {
+	linker, ok := q.next.(interface {
+		MessageLink(receiver, messageID string) (string, error)
+	})
+	if !ok {
+		return "", fmt.Errorf("message links are not supported")
+	}
+	return linker.MessageLink(receiver, messageID)
+}
+
+// RecentMessages returns the texts of the recent messages of a channel, if the next notifier supports it
+func (q *Queue) RecentMessages(channel string, limit int) ([]string, error) {
+	history, ok := q.next.(interface {
+		RecentMessages(channel string, limit int) ([]string, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("channel history is not supported")
+	}
+	return history.RecentMessages(channel, limit)
+}
+
+// MessagesSince returns the messages a channel got since a message, if the next notifier supports it
+func (q *Queue) MessagesSince(channel, messageID string, since time.Time) ([]service.HistoryMessage, error) {
+	reader, ok := q.next.(service.MissedMessagesReader)
+	if !ok {
+		return nil, fmt.Errorf("channel history is not supported")
+	}
+	return reader.MessagesSince(channel, messageID, since)
+}
+
+// UserActive returns whether the user is currently active, if the next notifier supports it
+func (q *Queue) UserActive(userID string) (bool, error) {
+	checker, ok := q.next.(service.PresenceChecker)
+	if !ok {
+		return false, fmt.Errorf("presence is not supported")
+	}
+	return checker.UserActive(userID)
+}
+
+// SendInteractiveMessage sends a message with buttons, if the next notifier supports it
+func (q *Queue) SendInteractiveMessage(receiver string, message service.InteractiveMessage, messageID string) (string, error) {
+	if _, ok := q.next.(service.InteractiveMessenger); !ok {
+		return "", fmt.Errorf("interactive messages are not supported")
+	}
+	res := q.enqueue(&request{kind: requestSend, receiver: receiver, text: message.Text, interactive: &message, messageID: messageID})
+	return res.messageID, res.err
+}
+
+// EditInteractiveMessage edits a message with buttons, if the next notifier supports it
+func (q *Queue) EditInteractiveMessage(receiver string, message service.InteractiveMessage, messageID string) error {
+	if _, ok := q.next.(service.InteractiveMessenger); !ok {
+		return fmt.Errorf("interactive messages are not supported")
+	}
+	return q.enqueue(&request{kind: requestEdit, receiver: receiver, text: message.Text, interactive: &message, messageID: messageID}).err
+}
+
+// CheckConnection checks the connection of the next notifier to its platform, if it supports it
+func (q *Queue) CheckConnection(ctx context.Context) error {
+	checker, ok := q.next.(service.ConnectionChecker)
+	if !ok {
+		return fmt.Errorf("connection checks are not supported")
+	}
+	return checker.CheckConnection(ctx)
+}
+
+// UploadFile sends a file to the receiver, if the next notifier supports it
+func (q *Queue) UploadFile(receiver, filename, content, comment string) error {
+	uploader, ok := q.next.(fileUploader)
+	if !ok {
+		return fmt.Errorf("file uploads are not supported")
+	}
+	return uploader.UploadFile(receiver, filename, content, comment)
+}
+
+func (q *Queue) enqueue(req *request) result {
+	if q.cfg.MessageInterval <= 0 {
+		return q.execute([]*request{req})
+	}
+
+	req.done = make(chan result, 1)
+
+	q.lock.Lock()
+	rq, ok := q.queues[req.receiver]
+	if !ok {
+		rq = &receiverQueue{wake: make(chan struct{}, 1)}
+		q.queues[req.receiver] = rq
+		go q.worker(req.receiver, rq)
+	}
+	if req.priority {
+		// Priority requests go after the other priority requests, but before all the rest
+		i := 0
+		for i < len(rq.pending) && rq.pending[i].priority {
+			i++
+		}
+		rq.pending = append(rq.pending[:i], append([]*request{req}, rq.pending[i:]...)...)
+	} else {
+		rq.pending = append(rq.pending, req)
+	}
+	q.lock.Unlock()
+
+	select {
+	case rq.wake <- struct{}{}:
+	default:
+	}
+	return <-req.done
+}
+
+// popNext takes the next requests to execute together. Must be called with the lock held.
+func (q *Queue) popNext(rq *receiverQueue) []*request {
+	first := rq.pending[0]
+	rq.pending = rq.pending[1:]
+	batch := []*request{first}
+
+	switch first.kind {
+	case requestEdit:
+		// Only the latest edit of a message matters
+		remaining := rq.pending[:0]
+		for _, req := range rq.pending {
+			if req.kind == requestEdit && req.messageID == first.messageID {
+				batch = append(batch, req)
+				continue
+			}
+			remaining = append(remaining, req)
+		}
+		rq.pending = remaining
+	case requestSend:
+		if !q.cfg.Batching || first.priority || first.interactive != nil || first.editable {
+			break
+		}
+		remaining := rq.pending[:0]
+		for _, req := range rq.pending {
+			if len(batch) < q.cfg.MaxBatchSize && req.kind == requestSend && !req.priority && req.interactive == nil && !req.editable &&
+				req.messageID == first.messageID {
+				batch = append(batch, req)
+				continue
+			}
+			remaining = append(remaining, req)
+		}
+		rq.pending = remaining
+	}
+
+	return batch
+}
+
+func (q *Queue) worker(receiver string, rq *receiverQueue) {
+	var lastSent time.Time
+	for {
+		q.lock.Lock()
+		if len(rq.pending) == 0 {
+			q.lock.Unlock()
+			select {
+			case <-rq.wake:
+				continue
+			case <-time.After(q.cfg.IdleWorkerTime):
+				q.lock.Lock()
+				if len(rq.pending) == 0 {
+					delete(q.queues, receiver)
+					q.lock.Unlock()
+					return
+				}
+				q.lock.Unlock()
+				continue
+			}
+		}
+		q.lock.Unlock()
+
+		if wait := q.cfg.MessageInterval - time.Since(lastSent); wait > 0 {
+			time.Sleep(wait)
+		}
+
+		q.lock.Lock()
+		batch := q.popNext(rq)
+		q.lock.Unlock()
+
+		res := q.execute(batch)
+		lastSent = time.Now()
+		for _, req := range batch {
+			req.done <- res
+		}
+	}
+}
+
+// execute sends a batch of requests which were already validated to be mergeable
+func (q *Queue) execute(batch []*request) result {
+	first := batch[0]
+	switch first.kind {
+	case requestEdit:
+		latest := batch[len(batch)-1]
+		if latest.interactive != nil {
+			return result{err: q.next.(service.InteractiveMessenger).EditInteractiveMessage(latest.receiver, *latest.interactive, latest.messageID)}
+		}
+		return result{err: q.next.EditMessage(latest.receiver, truncateMessage(latest.text, q.maxMessageLength(), truncatedNote), latest.messageID)}
+	case requestSend:
+		if first.interactive != nil {
+			messageID, err := q.next.(service.InteractiveMessenger).SendInteractiveMessage(first.receiver, *first.interactive, first.messageID)
+			return result{messageID: messageID, err: err}
+		}
+		texts := make([]string, len(batch))
+		for i, req := range batch {
+			texts[i] = req.text
+		}
+		return q.sendText(first.receiver, strings.Join(texts, "\n\n"), first.messageID)
+	}
+	return result{err: fmt.Errorf("unknown request kind %d", first.kind)}
+}
+
+// maxMessageLength returns NOTIFICATION_MAX_MESSAGE_LENGTH, or the next notifier's limit if it isn't set (0 is unlimited)
+func (q *Queue) maxMessageLength() int {
+	if q.cfg.MaxMessageLength > 0 {
+		return q.cfg.MaxMessageLength
+	}
+	if limiter, ok := q.next.(lengthLimiter); ok {
+		return limiter.MaxMessageLength()
+	}
+	return 0
+}
+
+// sendText sends the text, split to several messages in the same thread if it's longer than the max message length. Texts needing more
+// than NOTIFICATION_MAX_MESSAGE_PARTS messages are sent truncated instead, with the full text as a file, if the next notifier can send
+// files. The ID of the first message is returned.
+func (q *Queue) sendText(receiver, text, messageID string) result {
+	maxLength := q.maxMessageLength()
+	parts := splitMessage(text, maxLength)
+	if uploader, ok := q.next.(fileUploader); ok && q.cfg.MaxMessageParts > 0 && len(parts) > q.cfg.MaxMessageParts {
+		sentID, err := q.next.SendMessage(receiver, truncateMessage(text, maxLength, snippetNote), messageID)
+		if err != nil {
+			return result{err: err}
+		}
+		if err := uploader.UploadFile(receiver, snippetName, text, ""); err != nil {
+			return result{messageID: sentID, err: fmt.Errorf("upload the full message: %w", err)}
+		}
+		return result{messageID: sentID}
+	}
+
+	sentID, err := q.next.SendMessage(receiver, parts[0], messageID)
+	if err != nil {
+		return result{err: err}
+	}
+	for i, part := range parts[1:] {
+		if _, err := q.next.SendMessage(receiver, part, messageID); err != nil {
+			return result{messageID: sentID, err: fmt.Errorf("send part %d of %d: %w", i+2, len(parts), err)}
+		}
+	}
+	return result{messageID: sentID}
+}
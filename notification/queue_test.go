@@ -0,0 +1,279 @@
+package notification
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/oriser/bolt/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type call struct {
+	kind      string
+	receiver  string
+	text      string
+	messageID string
+	at        time.Time
+}
+
+type recordingNotifier struct {
+	lock  sync.Mutex
+	calls []call
+	block chan struct{}
+}
+
+func (r *recordingNotifier) record(c call) {
+	if r.block != nil {
+		<-r.block
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	c.at = time.Now()
+	r.calls = append(r.calls, c)
+}
+
+func (r *recordingNotifier) SendMessage(receiver, event, messageID string) (string, error) {
+	r.record(call{kind: "send", receiver: receiver, text: event, messageID: messageID})
+	return fmt.Sprintf("ts-%s", event), nil
+}
+
+func (r *recordingNotifier) EditMessage(receiver, event, messageID string) error {
+	r.record(call{kind: "edit", receiver: receiver, text: event, messageID: messageID})
+	return nil
+}
+
+func (r *recordingNotifier) AddReaction(_, _, _ string) error {
+	return nil
+}
+
+func (r *recordingNotifier) Calls() []call {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]call(nil), r.calls...)
+}
+
+func TestQueueRateLimit(t *testing.T) {
+	t.Parallel()
+
+	next := &recordingNotifier{}
+	q := NewQueue(Config{MessageInterval: 50 * time.Millisecond, IdleWorkerTime: time.Second}, next)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id, err := q.SendMessage("C1", fmt.Sprintf("msg%d", i), "")
+			assert.NoError(t, err)
+			assert.Equal(t, fmt.Sprintf("ts-msg%d", i), id)
+		}(i)
+	}
+	_, err := q.SendMessage("C2", "other channel", "")
+	require.NoError(t, err)
+	wg.Wait()
+
+	calls := next.Calls()
+	require.Len(t, calls, 4)
+	var channelCalls []call
+	for _, c := range calls {
+		if c.receiver == "C1" {
+			channelCalls = append(channelCalls, c)
+		}
+	}
+	require.Len(t, channelCalls, 3)
+	for i := 1; i < len(channelCalls); i++ {
+		assert.GreaterOrEqual(t, channelCalls[i].at.Sub(channelCalls[i-1].at), 45*time.Millisecond)
+	}
+}
+
+func TestQueueCoalescingAndBatching(t *testing.T) {
+	t.Parallel()
+
+	next := &recordingNotifier{block: make(chan struct{})}
+	q := NewQueue(Config{MessageInterval: time.Millisecond, Batching: true, MaxBatchSize: 2, IdleWorkerTime: time.Second}, next)
+
+	var wg sync.WaitGroup
+	send := func(f func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f()
+		}()
+	}
+
+	// The first message blocks the worker so the rest will pile up
+	send(func() { _, _ = q.SendMessage("C1", "first", "") })
+	require.Eventually(t, func() bool {
+		q.lock.Lock()
+		defer q.lock.Unlock()
+		return q.queues["C1"] != nil && len(q.queues["C1"].pending) == 0
+	}, time.Second, time.Millisecond)
+
+	enqueueAndWait := func(expectedPending int, f func()) {
+		send(f)
+		require.Eventually(t, func() bool {
+			q.lock.Lock()
+			defer q.lock.Unlock()
+			return len(q.queues["C1"].pending) == expectedPending
+		}, time.Second, time.Millisecond)
+	}
+	enqueueAndWait(1, func() { assert.NoError(t, q.EditMessage("C1", "edit1", "ts1")) })
+	enqueueAndWait(2, func() { _, _ = q.SendMessage("C1", "a", "thread") })
+	enqueueAndWait(3, func() { assert.NoError(t, q.EditMessage("C1", "edit2", "ts1")) })
+	enqueueAndWait(4, func() { _, _ = q.SendMessage("C1", "b", "thread") })
+	enqueueAndWait(5, func() { _, _ = q.SendMessage("C1", "c", "thread") })
+	enqueueAndWait(6, func() { _, _ = q.SendPriorityMessage("C1", "urgent", "") })
+
+	close(next.block)
+	wg.Wait()
+
+	texts := make([]string, 0)
+	for _, c := range next.Calls() {
+		texts = append(texts, c.text)
+	}
+	assert.Equal(t, []string{"first", "urgent", "edit2", "a\n\nb", "c"}, texts)
+}
+
+func TestQueueEditAfterBatchedSend(t *testing.T) {
+	t.Parallel()
+
+	next := &recordingNotifier{block: make(chan struct{})}
+	q := NewQueue(Config{MessageInterval: time.Millisecond, Batching: true, MaxBatchSize: 5, IdleWorkerTime: time.Second}, next)
+
+	var wg sync.WaitGroup
+	send := func(expectedPending int, f func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f()
+		}()
+		require.Eventually(t, func() bool {
+			q.lock.Lock()
+			defer q.lock.Unlock()
+			return q.queues["C1"] != nil && len(q.queues["C1"].pending) == expectedPending
+		}, time.Second, time.Millisecond)
+	}
+
+	// The first message blocks the worker so the rest will pile up
+	send(0, func() { _, _ = q.SendMessage("C1", "first", "thread") })
+	var editableID string
+	send(1, func() { _, _ = q.SendMessage("C1", "a", "thread") })
+	send(2, func() {
+		var err error
+		editableID, err = q.SendEditableMessage("C1", "joined", "thread")
+		assert.NoError(t, err)
+	})
+	send(3, func() { _, _ = q.SendMessage("C1", "b", "thread") })
+
+	close(next.block)
+	wg.Wait()
+	assert.Equal(t, "ts-joined", editableID, "the editable message isn't merged with the others")
+	require.NoError(t, q.EditMessage("C1", "joined and closed", editableID))
+
+	assert.Equal(t, []call{
+		{kind: "send", receiver: "C1", text: "first", messageID: "thread"},
+		{kind: "send", receiver: "C1", text: "a\n\nb", messageID: "thread"},
+		{kind: "send", receiver: "C1", text: "joined", messageID: "thread"},
+		{kind: "edit", receiver: "C1", text: "joined and closed", messageID: "ts-joined"},
+	}, withoutTimes(next.Calls()))
+}
+
+type interactiveNotifier struct {
+	recordingNotifier
+}
+
+func (i *interactiveNotifier) SendInteractiveMessage(receiver string, message service.InteractiveMessage, messageID string) (string, error) {
+	i.record(call{kind: "send-interactive", receiver: receiver, text: message.Text, messageID: messageID})
+	return fmt.Sprintf("ts-%s", message.Text), nil
+}
+
+func (i *interactiveNotifier) EditInteractiveMessage(receiver string, message service.InteractiveMessage, messageID string) error {
+	i.record(call{kind: "edit-interactive", receiver: receiver, text: message.Text, messageID: messageID})
+	return nil
+}
+
+func TestQueueInteractiveMessages(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewQueue(Config{}, &recordingNotifier{}).SendInteractiveMessage("C1", service.InteractiveMessage{Text: "rates"}, "")
+	assert.Error(t, err, "interactive messages aren't supported by the next notifier")
+
+	next := &interactiveNotifier{}
+	q := NewQueue(Config{MessageInterval: time.Millisecond, Batching: true, MaxBatchSize: 5, IdleWorkerTime: time.Second}, next)
+	id, err := q.SendInteractiveMessage("C1", service.InteractiveMessage{Text: "rates"}, "thread")
+	require.NoError(t, err)
+	assert.Equal(t, "ts-rates", id)
+	require.NoError(t, q.EditInteractiveMessage("C1", service.InteractiveMessage{Text: "rates with progress"}, id))
+	require.NoError(t, q.EditMessage("C1", "plain rates", id))
+
+	kinds := make([]string, 0)
+	for _, c := range next.Calls() {
+		kinds = append(kinds, c.kind)
+	}
+	assert.Equal(t, []string{"send-interactive", "edit-interactive", "edit"}, kinds)
+}
+
+type uploadingNotifier struct {
+	recordingNotifier
+}
+
+func (u *uploadingNotifier) UploadFile(receiver, filename, content, _ string) error {
+	u.record(call{kind: "upload " + filename, receiver: receiver, text: content})
+	return nil
+}
+
+func TestSplitMessage(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []string{"short"}, splitMessage("short", 0))
+	assert.Equal(t, []string{"short"}, splitMessage("short", 10))
+	assert.Equal(t, []string{"line 1\nline 2", "line 3"}, splitMessage("line 1\nline 2\n\nline 3", 15), "split at paragraphs first")
+	assert.Equal(t, []string{"line 1", "line 2 line 3"}, splitMessage("line 1\nline 2 line 3", 15))
+	assert.Equal(t, []string{"<@U1> <@U2>", "<@U3>"}, splitMessage("<@U1> <@U2> <@U3>", 12), "mentions aren't broken")
+	assert.Equal(t, []string{"שלום", "עולם"}, splitMessage("שלוםעולם", 4), "characters aren't broken")
+
+	assert.Equal(t, "line 1\nline 2\n…", truncateMessage("line 1\nline 2\nline 3", 15, truncatedNote))
+	assert.Equal(t, "fits", truncateMessage("fits", 4, truncatedNote))
+}
+
+func TestQueueLongMessages(t *testing.T) {
+	t.Parallel()
+
+	next := &recordingNotifier{}
+	q := NewQueue(Config{MaxMessageLength: 10, MaxMessageParts: 2}, next)
+	id, err := q.SendMessage("C1", "first line\nsecond", "thread")
+	require.NoError(t, err)
+	assert.Equal(t, "ts-first line", id, "the ID of the first part is returned")
+	require.NoError(t, q.EditMessage("C1", "edited line\nsecond", id))
+	_, err = q.SendMessage("C1", "one two three four five six", "")
+	require.NoError(t, err, "without uploads, long messages are split to more than the max parts")
+
+	assert.Equal(t, []call{
+		{kind: "send", receiver: "C1", text: "first line", messageID: "thread"},
+		{kind: "send", receiver: "C1", text: "second", messageID: "thread"},
+		{kind: "edit", receiver: "C1", text: "edited\n…", messageID: "ts-first line"},
+		{kind: "send", receiver: "C1", text: "one two"},
+		{kind: "send", receiver: "C1", text: "three four"},
+		{kind: "send", receiver: "C1", text: "five six"},
+	}, withoutTimes(next.Calls()))
+
+	uploader := &uploadingNotifier{}
+	q = NewQueue(Config{MaxMessageLength: 80, MaxMessageParts: 1}, uploader)
+	text := "Rates for Wolt order ID ABC:\n<@U1>: 10.00\n<@U2>: 20.00\n<@U3>: 30.00\n<@U4>: 40.00\n<@U5>: 50.00"
+	_, err = q.SendMessage("C1", text, "")
+	require.NoError(t, err)
+	assert.Equal(t, []call{
+		{kind: "send", receiver: "C1", text: "Rates for Wolt order ID ABC:\n… (the full message is in the attached file)"},
+		{kind: "upload message.txt", receiver: "C1", text: text},
+	}, withoutTimes(uploader.Calls()))
+}
+
+func withoutTimes(calls []call) []call {
+	for i := range calls {
+		calls[i].at = time.Time{}
+	}
+	return calls
+}
@@ -0,0 +1,63 @@
+package notification
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	truncatedNote = "\n…"
+	snippetNote   = "\n… (the full message is in the attached file)"
+	snippetName   = "message.txt"
+)
+
+// messageBoundaries are the places messages are split at, from the most preferred
+var messageBoundaries = []string{"\n\n", "\n", " "}
+
+// splitMessage splits the text to parts of up to maxLength characters (0 doesn't split). Each part ends at its last paragraph, line or
+// word boundary, so mentions, links and formatting aren't broken, and only parts without any boundary are cut in the middle.
+func splitMessage(text string, maxLength int) []string {
+	parts := make([]string, 0, 1)
+	for maxLength > 0 && utf8.RuneCountInString(text) > maxLength {
+		cut := runesPrefix(text, maxLength)
+		end, next := cut, cut
+		for _, boundary := range messageBoundaries {
+			// The boundary may start right after the part
+			searched := text[:cut]
+			if cut+len(boundary) <= len(text) {
+				searched = text[:cut+len(boundary)]
+			}
+			if i := strings.LastIndex(searched, boundary); i > 0 {
+				end, next = i, i+len(boundary)
+				break
+			}
+		}
+		parts = append(parts, text[:end])
+		text = text[next:]
+	}
+	return append(parts, text)
+}
+
+// truncateMessage returns the first part of the text which fits in maxLength characters together with the note, followed by the note.
+// Texts which fit are returned as is.
+func truncateMessage(text string, maxLength int, note string) string {
+	if maxLength <= 0 || utf8.RuneCountInString(text) <= maxLength {
+		return text
+	}
+	available := maxLength - utf8.RuneCountInString(note)
+	if available <= 0 {
+		return text[:runesPrefix(text, maxLength)]
+	}
+	return splitMessage(text, available)[0] + note
+}
+
+// runesPrefix returns the length in bytes of the first n characters of the text
+func runesPrefix(text string, n int) int {
+	for i := range text {
+		if n == 0 {
+			return i
+		}
+		n--
+	}
+	return len(text)
+}
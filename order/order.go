@@ -2,6 +2,8 @@ package order
 
 import (
 	"context"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -11,30 +13,391 @@ const (
 	StatusInvalid Status = iota
 	StatusCanceled
 	StatusDone
+	StatusStopped // Bolt stopped tracking the order before it was done, for example because its channel was archived
 )
 
 type Participant struct {
-	Name   string  `json:"name"`
-	ID     string  `json:"ID"`
-	Amount float64 `json:"amount"`
+	Name                string  `json:"name"`
+	ID                  string  `json:"ID"`
+	Amount              float64 `json:"amount"`
+	AgeRestrictedAmount float64 `json:"age_restricted_amount,omitempty"` // The part of the amount of age-restricted items (before fees)
+	Subsidy             float64 `json:"subsidy,omitempty"`               // The part of the amount covered by the company subsidy
+	Private             bool    `json:"private,omitempty"`               // The participant keeps their amount private, so it's shown only to them
+}
+
+// PersonalAmount returns the amount the participant paid after the company subsidy
+func (p Participant) PersonalAmount() float64 {
+	return p.Amount - p.Subsidy
 }
 
 type Order struct {
-	ID           string        `db:"id"`
-	OriginalID   string        `db:"original_id"`
-	CreatedAt    time.Time     `db:"created_at"`
-	Receiver     string        `db:"receiver"`
-	VenueName    string        `db:"venue_name"`
-	VenueID      string        `db:"venue_id"`
-	VenueLink    string        `db:"venue_link"`
-	VenueCity    string        `db:"venue_city"`
-	Host         string        `db:"host"`
-	HostID       string        `db:"host_id"`
-	Participants []Participant `db:"-"`
-	Status       Status        `db:"status"`
-	DeliveryRate int           `db:"delivery_rate"`
+	ID           string         `db:"id"`
+	OriginalID   string         `db:"original_id"`
+	CreatedAt    time.Time      `db:"created_at"`
+	Receiver     string         `db:"receiver"`
+	VenueName    string         `db:"venue_name"`
+	VenueID      string         `db:"venue_id"`
+	VenueLink    string         `db:"venue_link"`
+	VenueCity    string         `db:"venue_city"`
+	Host         string         `db:"host"`
+	HostID       string         `db:"host_id"`
+	Participants []Participant  `db:"-"`
+	Status       Status         `db:"status"`
+	DeliveryRate int            `db:"delivery_rate"`
+	MessageID    string         `db:"message_id"` // The message the order link was sent in
+	Tags         []string       `db:"-"`
+	Surge        bool           `db:"surge"`        // The venue was busy (with surge delivery pricing) while the group was open
+	Headcount    int            `db:"headcount"`    // How many people were in the office on the day of the order, 0 if unknown
+	Items        map[string]int `db:"-"`            // The quantity ordered of each item, by name
+	CompanyPaid  bool           `db:"company_paid"` // The host paid with a company card, so no debts were tracked
+	Note         string         `db:"note"`         // The host's note from the message with the order link, like payment instructions
+	ExternalRef  string         `db:"external_ref"` // A reference to the order for finance, independent of the Wolt group ID. Empty if not generated.
+	Currency     string         `db:"currency"`     // The ISO 4217 code of the currency of the amounts, like ILS
+	ProofURL     string         `db:"proof_url"`    // A link to the host's photo of the receipt or of the delivered order, empty if none
+	// From the purchase until the delivery, set once the order is delivered. 0 if it wasn't delivered or the duration is unknown.
+	DeliveryDuration time.Duration `db:"delivery_duration"`
+}
+
+// TotalAmount returns the sum of all participants' amounts
+// HasPrivateAmounts returns whether a participant of the order keeps their amount private, in which case the total isn't shown
+// either, as the amount could be worked out from it
+func (o *Order) HasPrivateAmounts() bool {
+	for _, p := range o.Participants {
+		if p.Private {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *Order) TotalAmount() float64 {
+	total := 0.0
+	for _, p := range o.Participants {
+		total += p.Amount
+	}
+	return total
 }
 
 type Store interface {
 	SaveOrder(ctx context.Context, order *Order) error
+	ListOrders(ctx context.Context, filter ListFilter) ([]*Order, error)
+}
+
+// ParticipantsStore updates the participants of stored orders, for orders whose rates changed after they were saved. It's optional,
+// and implemented by order stores which support it.
+type ParticipantsStore interface {
+	// UpdateOrderParticipants replaces the participants of the stored orders of the Wolt group
+	UpdateOrderParticipants(ctx context.Context, originalID string, participants []Participant) error
+}
+
+// SortField is the field orders are listed by
+type SortField string
+
+const (
+	SortByCreatedAt   SortField = "" // The default
+	SortByTotalAmount SortField = "total_amount"
+	SortByVenueName   SortField = "venue_name"
+)
+
+// ListFilter filters orders by all the non-empty fields. Orders are returned from the newest to the oldest, unless sorted otherwise.
+type ListFilter struct {
+	OriginalID  string // The Wolt group ID
+	Receiver    string
+	MessageID   string // The message the order link was sent in
+	Text        string // Matches any of venue name, participant name or tag
+	VenueName   string
+	Participant string
+	// The user IDs of which at least one participated in the order, for listing the orders of a user who may have several users
+	ParticipantIDs []string
+	Tag            string
+	MinAmount      float64 // Minimum total amount of the order
+	MaxAmount      float64 // Maximum total amount of the order
+	SortBy         SortField
+	Ascending      bool // Sorts from the smallest (or oldest) to the largest, instead of from the largest
+	Limit          uint64
+	Offset         uint64
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+func (o *Order) hasParticipant(name string) bool {
+	for _, p := range o.Participants {
+		if containsFold(p.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *Order) hasParticipantID(ids []string) bool {
+	for _, p := range o.Participants {
+		for _, id := range ids {
+			if p.ID != "" && p.ID == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (o *Order) hasTag(tag string) bool {
+	for _, t := range o.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches returns whether the order matches the filter, for stores which can't filter by themselves
+func (f ListFilter) Matches(o *Order) bool {
+	switch {
+	case f.OriginalID != "" && o.OriginalID != f.OriginalID,
+		f.Receiver != "" && o.Receiver != f.Receiver,
+		f.MessageID != "" && o.MessageID != f.MessageID,
+		f.Text != "" && !containsFold(o.VenueName, f.Text) && !o.hasTag(f.Text) && !o.hasParticipant(f.Text),
+		f.VenueName != "" && !containsFold(o.VenueName, f.VenueName),
+		f.Participant != "" && !o.hasParticipant(f.Participant),
+		len(f.ParticipantIDs) > 0 && !o.hasParticipantID(f.ParticipantIDs),
+		f.Tag != "" && !o.hasTag(f.Tag),
+		f.MinAmount > 0 && o.TotalAmount() < f.MinAmount,
+		f.MaxAmount > 0 && o.TotalAmount() > f.MaxAmount:
+		return false
+	}
+	return true
+}
+
+// Apply returns the orders matching the filter, sorted and paginated the way the filter asks, for stores which can't list them by
+// themselves
+func (f ListFilter) Apply(orders []*Order) []*Order {
+	matching := make([]*Order, 0, len(orders))
+	for _, o := range orders {
+		if f.Matches(o) {
+			matching = append(matching, o)
+		}
+	}
+	sort.SliceStable(matching, func(i, j int) bool {
+		a, b := matching[i], matching[j]
+		if !f.Ascending {
+			a, b = b, a
+		}
+		switch {
+		case f.SortBy == SortByTotalAmount && a.TotalAmount() != b.TotalAmount():
+			return a.TotalAmount() < b.TotalAmount()
+		case f.SortBy == SortByVenueName && a.VenueName != b.VenueName:
+			return a.VenueName < b.VenueName
+		case !a.CreatedAt.Equal(b.CreatedAt):
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return matching[i].ID < matching[j].ID
+	})
+	if f.Offset >= uint64(len(matching)) {
+		return []*Order{}
+	}
+	matching = matching[f.Offset:]
+	if f.Limit > 0 && f.Limit < uint64(len(matching)) {
+		matching = matching[:f.Limit]
+	}
+	return matching
+}
+
+// CountStore counts orders without listing them, for paging through them. It's optional, and implemented by order stores which
+// support it.
+type CountStore interface {
+	// CountOrders returns the number of orders matching the filter, ignoring its sorting and pagination
+	CountOrders(ctx context.Context, filter ListFilter) (int, error)
+}
+
+// Archive keeps the orders moved out of the store to a cheaper storage, so listing the orders of the store reads through to them
+type Archive interface {
+	// ArchivedOrders returns all the archived orders
+	ArchivedOrders(ctx context.Context) ([]*Order, error)
+}
+
+// ArchiveStore moves its old orders to an archive. It's optional, and implemented by order stores which support it.
+type ArchiveStore interface {
+	// ListOrdersToArchive returns the orders created before the given time which are still in the store, from the oldest
+	ListOrdersToArchive(ctx context.Context, before time.Time) ([]*Order, error)
+	// RemoveOrders removes the orders (by ID) from the store, once they're archived
+	RemoveOrders(ctx context.Context, ids []string) error
+}
+
+// ProofStore keeps the proofs of purchase of stored orders. It's optional, and implemented by order stores which support it.
+type ProofStore interface {
+	// SetOrderProof sets the proof of purchase link of the stored orders of the Wolt group, replacing the previous one
+	SetOrderProof(ctx context.Context, originalID, proofURL string) error
+}
+
+// RefSequenceStore keeps the sequence of the orders' external references. It's optional, and implemented by order stores which support it.
+type RefSequenceStore interface {
+	// NextOrderRefSequence increments the sequence and returns its new value, starting from 1
+	NextOrderRefSequence(ctx context.Context) (int64, error)
+}
+
+// BlacklistedVenue is a venue the channel had a bad experience with. Orders from it are tracked only after a confirmation.
+type BlacklistedVenue struct {
+	Channel   string    `db:"channel"`
+	VenueName string    `db:"venue_name"`
+	Reason    string    `db:"reason"`
+	AddedBy   string    `db:"added_by"` // The user ID of the admin who blacklisted the venue
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// BlacklistStore keeps the blacklisted venues of each channel. It's optional, and implemented by order stores which support it.
+type BlacklistStore interface {
+	// BlacklistVenue adds the venue to the channel's blacklist, or replaces its reason if it's already blacklisted
+	BlacklistVenue(ctx context.Context, venue *BlacklistedVenue) error
+	// UnblacklistVenue removes the venue (matched case-insensitively) from the channel's blacklist, and returns false if it wasn't blacklisted
+	UnblacklistVenue(ctx context.Context, channel, venueName string) (bool, error)
+	ListBlacklistedVenues(ctx context.Context, channel string) ([]*BlacklistedVenue, error)
+}
+
+// VenueStats are the delivery statistics of a venue, learned from its delivered orders, and its overrides of the timeouts of tracking
+// its orders. The durations are kept in nanoseconds.
+type VenueStats struct {
+	VenueName       string        `db:"venue_name"`
+	Deliveries      int           `db:"deliveries"`       // How many delivered orders the durations were learned from
+	AverageDelivery time.Duration `db:"average_delivery"` // From the purchase until the delivery
+	LongestDelivery time.Duration `db:"longest_delivery"`
+	// The venue's overrides of ORDER_DONE_TIMEOUT and WAIT_BETWEEN_STATUS_CHECK set by an admin, 0 when they're learned from the
+	// durations
+	DoneTimeout         time.Duration `db:"done_timeout"`
+	StatusCheckInterval time.Duration `db:"status_check_interval"`
+	UpdatedAt           time.Time     `db:"updated_at"`
+}
+
+// VenueStatsStore keeps the delivery statistics and the timeout overrides of the venues. It's optional, and implemented by order
+// stores which support it.
+type VenueStatsStore interface {
+	// AddVenueDelivery learns the delivery duration of an order of the venue
+	AddVenueDelivery(ctx context.Context, venueName string, duration time.Duration) error
+	// SetVenueTimeouts sets the venue's overrides of the timeouts, 0 for learning them again
+	SetVenueTimeouts(ctx context.Context, venueName string, doneTimeout, statusCheckInterval time.Duration) error
+	// GetVenueStats returns the stats of the venue (matched case-insensitively), or nil if it has none
+	GetVenueStats(ctx context.Context, venueName string) (*VenueStats, error)
+}
+
+// VenueSummary is how often a venue was ordered from, and how its orders were delivered
+type VenueSummary struct {
+	VenueName               string
+	Orders                  int
+	AverageDeliveryRate     float64
+	AverageDeliveryDuration time.Duration // Of the orders with a delivery duration, 0 if none has one
+}
+
+// VenueSummaryFilter filters the delivered orders the venues are summarized from by all the non-empty fields
+type VenueSummaryFilter struct {
+	Receiver string
+	Since    time.Time
+	Limit    uint64
+}
+
+// VenueSummaryStore keeps the delivery durations of the stored orders and summarizes the venues they were ordered from. It's
+// optional, and implemented by order stores which support it.
+type VenueSummaryStore interface {
+	// SetDeliveryDuration sets the delivery duration of the stored orders of the Wolt group
+	SetDeliveryDuration(ctx context.Context, originalID string, duration time.Duration) error
+	// ListVenueSummaries returns the summaries of the venues of the delivered orders, from the most ordered. The archived orders
+	// aren't summarized.
+	ListVenueSummaries(ctx context.Context, filter VenueSummaryFilter) ([]VenueSummary, error)
+}
+
+// ChannelSetting is the channel's override of a global configuration value
+type ChannelSetting struct {
+	Channel   string    `db:"channel"`
+	Name      string    `db:"name"` // The global configuration environment variable, e.g. DONT_JOIN_AFTER
+	Value     string    `db:"value"`
+	UpdatedBy string    `db:"updated_by"` // The user ID of the admin who set the value
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// ChannelSettingsStore keeps the configuration overrides of each channel. It's optional, and implemented by order stores which
+// support it.
+type ChannelSettingsStore interface {
+	// SetChannelSetting sets the channel's override of the setting, replacing its previous value
+	SetChannelSetting(ctx context.Context, setting *ChannelSetting) error
+	// RemoveChannelSetting removes the channel's override of the setting, and returns false if it wasn't overridden
+	RemoveChannelSetting(ctx context.Context, channel, name string) (bool, error)
+	ListChannelSettings(ctx context.Context, channel string) ([]*ChannelSetting, error)
+}
+
+// InsightsStore keeps the users (by transport ID) who opted in for the monthly insights. It's optional, and implemented by order stores
+// which support it.
+type InsightsStore interface {
+	SubscribeInsights(ctx context.Context, transportID string) error
+	UnsubscribeInsights(ctx context.Context, transportID string) error
+	ListInsightsSubscribers(ctx context.Context) ([]string, error)
+}
+
+// TrackingPhase is how far the tracking of an order got
+type TrackingPhase string
+
+const (
+	PhaseJoined   TrackingPhase = "joined"   // Bolt joined the group, and waits for it to be sent
+	PhaseDelivery TrackingPhase = "delivery" // The rates were published, and Bolt monitors the delivery
+)
+
+// TrackedOrder is the state of an order Bolt is tracking, kept for resuming tracking it after a restart
+type TrackedOrder struct {
+	GroupID         string        `db:"group_id"`
+	Provider        string        `db:"provider"` // The delivery provider of the group order
+	Channel         string        `db:"channel"`
+	MessageID       string        `db:"message_id"` // The message with the order link
+	Text            string        `db:"text"`       // The text of the message with the order link
+	Phase           TrackingPhase `db:"phase"`
+	State           string        `db:"state"`          // The state the tracking got to, empty for the orders saved before the states were kept
+	DeliveryState   string        `db:"delivery_state"` // How far the delivery got once it's monitored, empty until then
+	JoinedMessageID string        `db:"joined_message_id"`
+	RatesMessageID  string        `db:"rates_message_id"` // Empty until the rates are published
+	CompanyPaid     bool          `db:"company_paid"`
+	Session         string        `db:"session"` // The provider's session of the joined group, as JSON
+	StartedAt       time.Time     `db:"started_at"`
+}
+
+// TrackingStore keeps the orders Bolt is tracking. It's optional, and implemented by order stores which support it.
+type TrackingStore interface {
+	// SaveTrackedOrder adds the tracked order, or replaces its state if it's already tracked
+	SaveTrackedOrder(ctx context.Context, tracked *TrackedOrder) error
+	RemoveTrackedOrder(ctx context.Context, groupID string) error
+	ListTrackedOrders(ctx context.Context) ([]*TrackedOrder, error)
+}
+
+// LinkCursorStore keeps the last link message handled in each channel, for catching up with the links shared while Bolt was down.
+// It's optional, and implemented by order stores which support it.
+type LinkCursorStore interface {
+	// SetLinkCursor sets the last link message handled in the channel, replacing the previous one
+	SetLinkCursor(ctx context.Context, channel, messageID string, handledAt time.Time) error
+	// ListLinkCursors returns the last link message handled in each channel, by channel
+	ListLinkCursors(ctx context.Context) (map[string]string, error)
+}
+
+// RatesSnapshot is an order's rates as they were published, along with what they were computed from. It's never changed afterwards,
+// so disputes about the rates can be resolved even after the debts were adjusted or settled.
+type RatesSnapshot struct {
+	OriginalID  string    `db:"original_id"`
+	Receiver    string    `db:"receiver"`
+	MessageID   string    `db:"message_id"` // The rates message
+	PublishedAt time.Time `db:"published_at"`
+	Message     string    `db:"message"` // The text of the rates message as it was published
+	// The amount of each participant's items by Wolt name, before the discounts and fees, as JSON
+	ItemRates          string  `db:"item_rates"`
+	Host               string  `db:"host"` // The Wolt name of the host
+	DeliveryRate       int     `db:"delivery_rate"`
+	Discount           float64 `db:"discount"`
+	FeeAllocation      string  `db:"fee_allocation"`      // The fee allocation strategy the delivery rate was split by
+	DiscountAllocation string  `db:"discount_allocation"` // Who got the discount
+	AmountRounding     float64 `db:"amount_rounding"`     // 0 if the amounts weren't rounded
+	RoundingRemainder  string  `db:"rounding_remainder"`  // Who paid the difference of the rounding
+	Currency           string  `db:"currency"`
+}
+
+// RatesSnapshotStore keeps the snapshots of the orders' published rates. It's optional, and implemented by order stores which support it.
+type RatesSnapshotStore interface {
+	// SaveRatesSnapshot saves the snapshot of the order's rates. Snapshots are immutable, so saving a snapshot of the same order
+	// again keeps the first one.
+	SaveRatesSnapshot(ctx context.Context, snapshot *RatesSnapshot) error
+	// GetRatesSnapshot returns the snapshot of the rates of the Wolt group, or nil if there's none
+	GetRatesSnapshot(ctx context.Context, originalID string) (*RatesSnapshot, error)
 }
@@ -0,0 +1,82 @@
+package paidlink
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/oriser/bolt/service"
+)
+
+//go:embed page.html
+var pageTemplate string
+
+// Debts marks the debts of the links as paid
+type Debts interface {
+	DebtPaidLink(ctx context.Context, orderID, debtID, token string) (*service.PaidLink, error)
+	MarkPaidByLink(ctx context.Context, orderID, debtID, token string) (*service.PaidLink, error)
+}
+
+type handler struct {
+	debts    Debts
+	template *template.Template
+}
+
+// Handler returns the HTTP handler of the links in the debt reminders, serving everything under service.PaidLinkPath. Opening a link
+// shows its debt with a form which submits itself, so the debt is marked as paid in one click, but not by the link previews of the
+// chat apps, which only GET the link.
+func Handler(debts Debts) http.Handler {
+	return &handler{
+		debts:    debts,
+		template: template.Must(template.New("page").Parse(pageTemplate)),
+	}
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, service.PaidLinkPath), "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		http.NotFound(w, r)
+		return
+	}
+	orderID, debtID, token := parts[0], parts[1], parts[2]
+
+	var (
+		link *service.PaidLink
+		err  error
+	)
+	if r.Method == http.MethodPost {
+		link, err = h.debts.MarkPaidByLink(r.Context(), orderID, debtID, token)
+	} else {
+		link, err = h.debts.DebtPaidLink(r.Context(), orderID, debtID, token)
+	}
+	if errors.Is(err, service.ErrPaidLinkNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Printf("Error handling the paid link of debt %s of order %s: %v\n", debtID, orderID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	// The token is in the URL, so the page shouldn't leak it to other sites or be kept by shared caches
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("Cache-Control", "no-store")
+	data := struct {
+		*service.PaidLink
+		Action string
+		Marked bool
+	}{PaidLink: link, Action: r.URL.Path, Marked: r.Method == http.MethodPost}
+	if err := h.template.Execute(w, data); err != nil {
+		log.Printf("Error rendering the paid link of debt %s of order %s: %v\n", debtID, orderID, err)
+	}
+}
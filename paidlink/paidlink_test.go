@@ -0,0 +1,59 @@
+package paidlink
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/oriser/bolt/service"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeDebts struct {
+	paid bool
+}
+
+func (f *fakeDebts) DebtPaidLink(_ context.Context, orderID, debtID, token string) (*service.PaidLink, error) {
+	if orderID != "ABC" || debtID != "d1" || token != "token" {
+		return nil, service.ErrPaidLinkNotFound
+	}
+	return &service.PaidLink{OrderID: "ABC", LenderName: "Thor <Host>", Amount: 30, Currency: "ILS", Settled: f.paid}, nil
+}
+
+func (f *fakeDebts) MarkPaidByLink(ctx context.Context, orderID, debtID, token string) (*service.PaidLink, error) {
+	if _, err := f.DebtPaidLink(ctx, orderID, debtID, token); err != nil {
+		return nil, err
+	}
+	f.paid = true
+	return f.DebtPaidLink(ctx, orderID, debtID, token)
+}
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	debts := &fakeDebts{}
+	handler := Handler(debts)
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	rec := serve(http.MethodGet, "/paid/ABC/d1/token")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "no-referrer", rec.Header().Get("Referrer-Policy"))
+	assert.Contains(t, rec.Body.String(), "<strong>30.00 ILS</strong> to Thor &lt;Host&gt;", "the values are escaped")
+	assert.Contains(t, rec.Body.String(), `<form method="post" action="/paid/ABC/d1/token">`)
+	assert.False(t, debts.paid, "opening the link only shows the form, so link previews don't mark the debt as paid")
+
+	rec = serve(http.MethodPost, "/paid/ABC/d1/token")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Thanks! Your debt is marked as paid.")
+	assert.True(t, debts.paid)
+	assert.Contains(t, serve(http.MethodGet, "/paid/ABC/d1/token").Body.String(), "There's nothing left to pay for this order.")
+
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodPost, "/paid/ABC/d1/wrong").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/paid/ABC/token").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(http.MethodDelete, "/paid/ABC/d1/token").Code)
+}
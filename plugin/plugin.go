@@ -0,0 +1,375 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/shlex"
+	"github.com/oriser/bolt/service"
+)
+
+// External plugins are executables started by Bolt which talk with it using JSON lines over stdin/stdout.
+// Bolt writes lifecycle events and commands to the plugin's stdin, the plugin may write back messages to send,
+// command responses, and a single "hello" message declaring the commands it handles.
+
+type Config struct {
+	Commands       []string      `env:"PLUGINS" envSeparator:";"` // Each entry is a command line of a plugin executable
+	CommandTimeout time.Duration `env:"PLUGIN_COMMAND_TIMEOUT" envDefault:"10s"`
+}
+
+const (
+	// outboxSize is how many messages can wait for a plugin which is slow to read them, so Bolt doesn't wait for it
+	outboxSize = 100
+	// sendTimeout is how long sending a message to a plugin waits for room when its outbox is full, after which the message is
+	// dropped
+	sendTimeout = time.Second
+)
+
+const (
+	MessageTypeHello           = "hello"
+	MessageTypeEvent           = "event"
+	MessageTypeCommand         = "command"
+	MessageTypeCommandResponse = "command_response"
+	MessageTypeSendMessage     = "send_message"
+)
+
+type RateMessage struct {
+	WoltName    string  `json:"wolt_name"`
+	TransportID string  `json:"transport_id,omitempty"`
+	Amount      float64 `json:"amount"`
+}
+
+type DebtMessage struct {
+	ID         string  `json:"id"`
+	BorrowerID string  `json:"borrower_id"`
+	LenderID   string  `json:"lender_id"`
+	Amount     float64 `json:"amount"`
+}
+
+type EventMessage struct {
+	Type       string        `json:"type"`
+	Time       time.Time     `json:"time"`
+	OrderID    string        `json:"order_id"`
+	Channel    string        `json:"channel,omitempty"`
+	MessageID  string        `json:"message_id,omitempty"`
+	VenueName  string        `json:"venue_name,omitempty"`
+	State      string        `json:"state,omitempty"`
+	OrderState string        `json:"order_state,omitempty"`
+	Reason     string        `json:"reason,omitempty"`
+	Rates      []RateMessage `json:"rates,omitempty"`
+	Debt       *DebtMessage  `json:"debt,omitempty"`
+}
+
+// Message is the envelope of every line exchanged with a plugin
+type Message struct {
+	Type      string        `json:"type"`
+	Name      string        `json:"name,omitempty"`
+	Commands  []string      `json:"commands,omitempty"`
+	Event     *EventMessage `json:"event,omitempty"`
+	ID        string        `json:"id,omitempty"`
+	Command   string        `json:"command,omitempty"`
+	Args      string        `json:"args,omitempty"`
+	UserID    string        `json:"user_id,omitempty"`
+	Channel   string        `json:"channel,omitempty"`
+	MessageID string        `json:"message_id,omitempty"`
+	Text      string        `json:"text,omitempty"`
+}
+
+// NewEventMessage converts a lifecycle event to the JSON message sent to plugins
+func NewEventMessage(event service.Event) *EventMessage {
+	msg := &EventMessage{
+		Type:       string(event.Type),
+		Time:       event.Time,
+		OrderID:    event.OrderID,
+		Channel:    event.Channel,
+		MessageID:  event.MessageID,
+		VenueName:  event.VenueName,
+		Reason:     event.Reason,
+		OrderState: string(event.OrderState),
+	}
+	if event.State != service.DeliveryStateUnknown {
+		msg.State = event.State.String()
+	}
+	if event.Rates != nil {
+		for _, rate := range event.Rates.Rates {
+			r := RateMessage{WoltName: rate.WoltName, Amount: rate.Amount}
+			if rate.User != nil {
+				r.TransportID = rate.User.TransportID
+			}
+			msg.Rates = append(msg.Rates, r)
+		}
+	}
+	if event.Debt != nil {
+		msg.Debt = &DebtMessage{
+			ID:         event.Debt.ID,
+			BorrowerID: event.Debt.BorrowerID,
+			LenderID:   event.Debt.LenderID,
+			Amount:     event.Debt.Amount,
+		}
+	}
+	return msg
+}
+
+type process struct {
+	commandLine string
+	nameLock    sync.RWMutex
+	name        string // The executable until the plugin says its name in its hello message
+	cmd         *exec.Cmd
+	stdin       io.WriteCloser
+	outbox      chan []byte   // The lines waiting to be written to the plugin's stdin
+	exited      chan struct{} // Closed once the plugin exits
+	pending     sync.Map      // Command ID to chan string
+}
+
+func newProcess(commandLine, name string, cmd *exec.Cmd, stdin io.WriteCloser) *process {
+	return &process{commandLine: commandLine, name: name, cmd: cmd, stdin: stdin, outbox: make(chan []byte, outboxSize),
+		exited: make(chan struct{})}
+}
+
+func (p *process) getName() string {
+	p.nameLock.RLock()
+	defer p.nameLock.RUnlock()
+	return p.name
+}
+
+func (p *process) setName(name string) {
+	p.nameLock.Lock()
+	defer p.nameLock.Unlock()
+	p.name = name
+}
+
+// send queues the message to be written to the plugin's stdin, so a plugin which is slow to read doesn't hold up the caller (like
+// the emitting of the lifecycle events). If the outbox is full, it waits up to the timeout for room, and then drops the message.
+// Nothing is queued once the plugin exited, as nothing would write it.
+func (p *process) send(msg Message, timeout time.Duration) error {
+	select {
+	case <-p.exited:
+		return fmt.Errorf("plugin %s exited", p.getName())
+	default:
+	}
+
+	marshaled, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshal message: %w", err)
+	}
+	line := append(marshaled, '\n')
+	select {
+	case p.outbox <- line:
+		return nil
+	default:
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case p.outbox <- line:
+		return nil
+	case <-p.exited:
+		return fmt.Errorf("plugin %s exited", p.getName())
+	case <-timer.C:
+		return fmt.Errorf("plugin %s isn't reading its messages, dropped the %s message", p.getName(), msg.Type)
+	}
+}
+
+// writeLoop writes the queued messages to the plugin's stdin until the plugin exits
+func (p *process) writeLoop() {
+	for {
+		select {
+		case line := <-p.outbox:
+			if _, err := p.stdin.Write(line); err != nil {
+				log.Printf("Error writing to plugin %s: %v\n", p.getName(), err)
+			}
+		case <-p.exited:
+			return
+		}
+	}
+}
+
+type Manager struct {
+	cfg               Config
+	eventNotification service.EventNotification
+	processes         []*process
+	commandsLock      sync.RWMutex
+	commands          map[string]*process
+	commandCounter    uint64
+	counterLock       sync.Mutex
+}
+
+func NewManager(cfg Config, eventNotification service.EventNotification) *Manager {
+	return &Manager{
+		cfg:               cfg,
+		eventNotification: eventNotification,
+		commands:          make(map[string]*process),
+	}
+}
+
+// Start launches all configured plugins and subscribes them to the lifecycle events.
+// Plugins are stopped once the context is done.
+func (m *Manager) Start(ctx context.Context, hooks *service.Hooks) error {
+	for _, commandLine := range m.cfg.Commands {
+		p, err := m.startProcess(ctx, commandLine)
+		if err != nil {
+			return fmt.Errorf("start plugin %q: %w", commandLine, err)
+		}
+		m.processes = append(m.processes, p)
+	}
+
+	if len(m.processes) > 0 {
+		hooks.SubscribeAll(m.onEvent)
+	}
+	return nil
+}
+
+func (m *Manager) startProcess(ctx context.Context, commandLine string) (*process, error) {
+	args, err := shlex.Split(commandLine)
+	if err != nil {
+		return nil, fmt.Errorf("split command line: %w", err)
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("empty command line")
+	}
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...) // nolint:gosec // plugins are configured by the operator
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("stdin pipe: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("stdout pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start: %w", err)
+	}
+
+	p := newProcess(commandLine, args[0], cmd, stdin)
+	go m.readLoop(p, stdout)
+	go p.writeLoop()
+	go func() {
+		if err := cmd.Wait(); err != nil {
+			log.Printf("Plugin %s exited: %v\n", p.getName(), err)
+		}
+		close(p.exited)
+		m.removeCommands(p)
+	}()
+	log.Println("Started plugin", commandLine)
+	return p, nil
+}
+
+func (m *Manager) readLoop(p *process, stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		msg := Message{}
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			log.Printf("Error parsing message from plugin %s: %v\n", p.getName(), err)
+			continue
+		}
+		m.handleMessage(p, msg)
+	}
+	if err := scanner.Err(); err != nil {
+		log.Printf("Error reading from plugin %s: %v\n", p.getName(), err)
+	}
+}
+
+func (m *Manager) handleMessage(p *process, msg Message) {
+	switch msg.Type {
+	case MessageTypeHello:
+		if msg.Name != "" {
+			p.setName(msg.Name)
+		}
+		m.commandsLock.Lock()
+		select {
+		case <-p.exited:
+			// The plugin exited while its hello was read, and its commands were already dropped
+		default:
+			for _, command := range msg.Commands {
+				m.commands[command] = p
+			}
+		}
+		m.commandsLock.Unlock()
+	case MessageTypeSendMessage:
+		if m.eventNotification == nil || msg.Channel == "" || msg.Text == "" {
+			log.Printf("Plugin %s sent invalid send_message: %+v\n", p.getName(), msg)
+			return
+		}
+		if _, err := m.eventNotification.SendMessage(msg.Channel, msg.Text, msg.MessageID); err != nil {
+			log.Printf("Error sending message for plugin %s: %v\n", p.getName(), err)
+		}
+	case MessageTypeCommandResponse:
+		if ch, ok := p.pending.LoadAndDelete(msg.ID); ok {
+			ch.(chan string) <- msg.Text
+		}
+	default:
+		log.Printf("Unknown message type %q from plugin %s\n", msg.Type, p.getName())
+	}
+}
+
+// removeCommands drops the commands of a plugin which exited, so they aren't forwarded to it anymore
+func (m *Manager) removeCommands(p *process) {
+	m.commandsLock.Lock()
+	defer m.commandsLock.Unlock()
+	for command, handler := range m.commands {
+		if handler == p {
+			delete(m.commands, command)
+		}
+	}
+}
+
+func (m *Manager) onEvent(_ context.Context, event service.Event) {
+	msg := Message{Type: MessageTypeEvent, Event: NewEventMessage(event)}
+	for _, p := range m.processes {
+		if err := p.send(msg, sendTimeout); err != nil {
+			log.Println("Error sending event to plugin:", err)
+		}
+	}
+}
+
+// HasCommand returns whether any plugin declared it handles the given command
+func (m *Manager) HasCommand(command string) bool {
+	m.commandsLock.RLock()
+	defer m.commandsLock.RUnlock()
+	_, ok := m.commands[command]
+	return ok
+}
+
+// HandleCommand forwards a chat command to the plugin which declared it, and waits for its textual response
+func (m *Manager) HandleCommand(ctx context.Context, command, args, userID, channel string) (string, error) {
+	m.commandsLock.RLock()
+	p, ok := m.commands[command]
+	m.commandsLock.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("no plugin handles command %q", command)
+	}
+
+	m.counterLock.Lock()
+	m.commandCounter++
+	id := strconv.FormatUint(m.commandCounter, 10)
+	m.counterLock.Unlock()
+
+	responseCh := make(chan string, 1)
+	p.pending.Store(id, responseCh)
+	defer p.pending.Delete(id)
+
+	if err := p.send(Message{Type: MessageTypeCommand, ID: id, Command: command, Args: args, UserID: userID, Channel: channel},
+		sendTimeout); err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.CommandTimeout)
+	defer cancel()
+	select {
+	case response := <-responseCh:
+		return response, nil
+	case <-ctx.Done():
+		return "", fmt.Errorf("timeout waiting for plugin %s to respond to command %q", p.getName(), command)
+	}
+}
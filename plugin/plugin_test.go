@@ -0,0 +1,110 @@
+package plugin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oriser/bolt/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sentMessage struct {
+	receiver, event, messageID string
+}
+
+type fakeNotification struct {
+	sent chan sentMessage
+}
+
+func (f *fakeNotification) SendMessage(receiver, event, messageID string) (string, error) {
+	f.sent <- sentMessage{receiver: receiver, event: event, messageID: messageID}
+	return "ts", nil
+}
+
+func (f *fakeNotification) EditMessage(_, _, _ string) error {
+	return nil
+}
+
+func (f *fakeNotification) AddReaction(_, _, _ string) error {
+	return nil
+}
+
+// The test plugin declares the "ping" command, answers every command with "pong" and announces every event it gets
+const testPlugin = `sh -c 'echo "{\"type\":\"hello\",\"name\":\"test\",\"commands\":[\"ping\"]}"
+while read -r line; do
+  case "$line" in
+    *\"type\":\"command\"*) echo "{\"type\":\"command_response\",\"id\":\"1\",\"text\":\"pong\"}" ;;
+    *\"type\":\"event\"*) echo "{\"type\":\"send_message\",\"channel\":\"C1\",\"text\":\"got event\"}" ;;
+  esac
+done'`
+
+func TestManager(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	notification := &fakeNotification{sent: make(chan sentMessage, 1)}
+	manager := NewManager(Config{Commands: []string{testPlugin}, CommandTimeout: 5 * time.Second}, notification)
+	hooks := service.NewHooks()
+	require.NoError(t, manager.Start(ctx, hooks))
+
+	require.Eventually(t, func() bool {
+		return manager.HasCommand("ping")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	response, err := manager.HandleCommand(ctx, "ping", "", "U1", "C1")
+	require.NoError(t, err)
+	assert.Equal(t, "pong", response)
+
+	_, err = manager.HandleCommand(ctx, "unknown", "", "U1", "C1")
+	assert.Error(t, err)
+
+	hooks.Emit(ctx, service.Event{Type: service.EventOrderJoined, OrderID: "ABC"})
+	select {
+	case msg := <-notification.sent:
+		assert.Equal(t, sentMessage{receiver: "C1", event: "got event"}, msg)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for plugin message")
+	}
+}
+
+func TestManagerDropsExitedPlugin(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	const exitingPlugin = `sh -c 'echo "{\"type\":\"hello\",\"name\":\"exiting\",\"commands\":[\"ping\"]}"; sleep 0.2'`
+	manager := NewManager(Config{Commands: []string{exitingPlugin}, CommandTimeout: 5 * time.Second}, nil)
+	require.NoError(t, manager.Start(ctx, service.NewHooks()))
+	require.Eventually(t, func() bool {
+		return manager.HasCommand("ping")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	p := manager.processes[0]
+	<-p.exited
+	require.Eventually(t, func() bool {
+		return !manager.HasCommand("ping")
+	}, 5*time.Second, 10*time.Millisecond, "the commands of the exited plugin are dropped")
+	_, err := manager.HandleCommand(ctx, "ping", "", "U1", "C1")
+	assert.EqualError(t, err, `no plugin handles command "ping"`)
+
+	assert.EqualError(t, p.send(Message{Type: MessageTypeEvent}, time.Minute), "plugin exiting exited")
+	assert.Empty(t, p.outbox, "nothing is queued for the exited plugin")
+}
+
+func TestSendDoesNotWaitForSlowPlugin(t *testing.T) {
+	t.Parallel()
+
+	// Nothing writes the outbox of the plugin to its stdin, as if the plugin stopped reading it
+	p := newProcess("slow", "slow", nil, nil)
+	for i := 0; i < outboxSize; i++ {
+		require.NoError(t, p.send(Message{Type: MessageTypeEvent}, time.Millisecond))
+	}
+	start := time.Now()
+	assert.EqualError(t, p.send(Message{Type: MessageTypeEvent}, 10*time.Millisecond),
+		"plugin slow isn't reading its messages, dropped the event message")
+	assert.Less(t, time.Since(start), time.Second)
+
+	close(p.exited)
+	assert.EqualError(t, p.send(Message{Type: MessageTypeEvent}, time.Minute), "plugin slow exited")
+}
@@ -0,0 +1,124 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+type KafkaConfig struct {
+	Brokers     []string `env:"KAFKA_BROKERS" envSeparator:","`
+	TopicPrefix string   `env:"KAFKA_TOPIC_PREFIX" envDefault:"bolt."`
+}
+
+// KafkaQueue is a queue over Kafka topics. Each topic is consumed by a consumer group, and a message's offset is committed
+// only after it was handled, so messages of a consumer which stopped are consumed again by the rest of the group.
+// As the messages of a partition are handled in order, the consume concurrency is limited by the number of partitions.
+type KafkaQueue struct {
+	cfg    Config
+	writer *kafka.Writer
+}
+
+func NewKafkaQueue(cfg Config) (*KafkaQueue, error) {
+	if len(cfg.Kafka.Brokers) == 0 {
+		return nil, fmt.Errorf("no Kafka brokers")
+	}
+	return &KafkaQueue{
+		cfg: cfg,
+		writer: &kafka.Writer{
+			Addr:                   kafka.TCP(cfg.Kafka.Brokers...),
+			Balancer:               &kafka.Hash{},
+			RequiredAcks:           kafka.RequireAll,
+			AllowAutoTopicCreation: true,
+		},
+	}, nil
+}
+
+func (q *KafkaQueue) topic(topic string) string {
+	return q.cfg.Kafka.TopicPrefix + topic
+}
+
+func (q *KafkaQueue) Publish(ctx context.Context, topic string, payload []byte) error {
+	if err := q.writer.WriteMessages(ctx, kafka.Message{Topic: q.topic(topic), Value: payload}); err != nil {
+		return fmt.Errorf("publish message to %s: %w", topic, err)
+	}
+	return nil
+}
+
+func (q *KafkaQueue) Consume(ctx context.Context, topic string, concurrency int, handler Handler) error {
+	if concurrency <= 0 {
+		return fmt.Errorf("concurrency must be positive")
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < concurrency; i++ {
+		// Each reader is a member of the consumer group, handling the messages of the partitions assigned to it
+		reader := kafka.NewReader(kafka.ReaderConfig{
+			Brokers:     q.cfg.Kafka.Brokers,
+			GroupID:     q.cfg.ConsumerGroup,
+			Topic:       q.topic(topic),
+			StartOffset: kafka.FirstOffset,
+		})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer reader.Close()
+			q.consumeWorker(ctx, reader, topic, handler)
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+func (q *KafkaQueue) consumeWorker(ctx context.Context, reader *kafka.Reader, topic string, handler Handler) {
+	for {
+		kafkaMsg, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, io.EOF) {
+				return
+			}
+			log.Printf("Error fetching message from %s: %v\n", topic, err)
+			select {
+			case <-time.After(q.cfg.PollInterval):
+			case <-ctx.Done():
+			}
+			continue
+		}
+
+		q.handle(ctx, kafkaMsg, topic, handler)
+		if ctx.Err() != nil {
+			// Not committing, so the message will be consumed again by the consumer group
+			return
+		}
+		// The offset is committed even if handling failed after all the attempts, so the partition won't be blocked
+		if err := reader.CommitMessages(ctx, kafkaMsg); err != nil {
+			log.Printf("Error committing message %d/%d of %s: %v\n", kafkaMsg.Partition, kafkaMsg.Offset, topic, err)
+		}
+	}
+}
+
+// handle handles a message, retrying up to QUEUE_MAX_ATTEMPTS times, as Kafka doesn't redeliver single messages
+func (q *KafkaQueue) handle(ctx context.Context, kafkaMsg kafka.Message, topic string, handler Handler) {
+	msg := &Message{
+		ID:      fmt.Sprintf("%d/%d", kafkaMsg.Partition, kafkaMsg.Offset),
+		Topic:   topic,
+		Payload: kafkaMsg.Value,
+	}
+	for msg.Attempts = 1; ; msg.Attempts++ {
+		err := handler(ctx, msg)
+		if err == nil {
+			return
+		}
+		if msg.Attempts >= q.cfg.MaxAttempts || ctx.Err() != nil {
+			log.Printf("Error handling message %s from %s, dropping it after %d attempts: %v\n", msg.ID, topic, msg.Attempts, err)
+			return
+		}
+		log.Printf("Error handling message %s from %s (attempt %d), retrying: %v\n", msg.ID, topic, msg.Attempts, err)
+	}
+}
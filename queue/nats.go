@@ -0,0 +1,137 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// natsFetchWait is the maximum time a consumer waits for a message before fetching again
+const natsFetchWait = 30 * time.Second
+
+type NATSConfig struct {
+	URL    string `env:"NATS_URL" envDefault:"nats://localhost:4222"`
+	Stream string `env:"NATS_STREAM" envDefault:"BOLT"` // Created if missing, with the subjects <stream>.<topic>
+}
+
+// NATSQueue is a queue over a NATS JetStream stream. Each topic is consumed by a durable pull consumer per consumer group,
+// which tracks the acknowledged messages. A message which isn't acknowledged within QUEUE_CLAIM_TIMEOUT is delivered again.
+type NATSQueue struct {
+	cfg Config
+	js  nats.JetStreamContext
+}
+
+func NewNATSQueue(cfg Config) (*NATSQueue, error) {
+	conn, err := nats.Connect(cfg.NATS.URL, nats.Name("bolt"))
+	if err != nil {
+		return nil, fmt.Errorf("connect to NATS: %w", err)
+	}
+	js, err := conn.JetStream()
+	if err != nil {
+		return nil, fmt.Errorf("new JetStream context: %w", err)
+	}
+
+	if _, err = js.StreamInfo(cfg.NATS.Stream); errors.Is(err, nats.ErrStreamNotFound) {
+		_, err = js.AddStream(&nats.StreamConfig{
+			Name:     cfg.NATS.Stream,
+			Subjects: []string{cfg.NATS.Stream + ".>"},
+			Storage:  nats.FileStorage,
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ensure stream %s: %w", cfg.NATS.Stream, err)
+	}
+
+	return &NATSQueue{cfg: cfg, js: js}, nil
+}
+
+func (q *NATSQueue) subject(topic string) string {
+	return q.cfg.NATS.Stream + "." + topic
+}
+
+func (q *NATSQueue) Publish(ctx context.Context, topic string, payload []byte) error {
+	if _, err := q.js.Publish(q.subject(topic), payload, nats.Context(ctx)); err != nil {
+		return fmt.Errorf("publish message to %s: %w", topic, err)
+	}
+	return nil
+}
+
+func (q *NATSQueue) Consume(ctx context.Context, topic string, concurrency int, handler Handler) error {
+	if concurrency <= 0 {
+		return fmt.Errorf("concurrency must be positive")
+	}
+
+	sub, err := q.js.PullSubscribe(q.subject(topic), q.cfg.ConsumerGroup+"-"+topic,
+		nats.BindStream(q.cfg.NATS.Stream),
+		nats.AckExplicit(),
+		nats.AckWait(q.cfg.ClaimTimeout),
+		nats.MaxDeliver(q.cfg.MaxAttempts),
+	)
+	if err != nil {
+		return fmt.Errorf("subscribe to %s: %w", topic, err)
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.consumeWorker(ctx, sub, topic, handler)
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+func (q *NATSQueue) consumeWorker(ctx context.Context, sub *nats.Subscription, topic string, handler Handler) {
+	for ctx.Err() == nil {
+		fetchCtx, cancel := context.WithTimeout(ctx, natsFetchWait)
+		msgs, err := sub.Fetch(1, nats.Context(fetchCtx))
+		cancel()
+		if err != nil {
+			if !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, nats.ErrTimeout) && ctx.Err() == nil {
+				log.Printf("Error fetching message from %s: %v\n", topic, err)
+				select {
+				case <-time.After(q.cfg.PollInterval):
+				case <-ctx.Done():
+				}
+			}
+			continue
+		}
+		for _, natsMsg := range msgs {
+			q.handle(ctx, natsMsg, topic, handler)
+		}
+	}
+}
+
+func (q *NATSQueue) handle(ctx context.Context, natsMsg *nats.Msg, topic string, handler Handler) {
+	msg := &Message{Topic: topic, Payload: natsMsg.Data, Attempts: 1}
+	if metadata, err := natsMsg.Metadata(); err == nil {
+		msg.ID = fmt.Sprintf("%d", metadata.Sequence.Stream)
+		msg.Attempts = int(metadata.NumDelivered)
+	}
+
+	if err := handler(ctx, msg); err != nil {
+		if msg.Attempts < q.cfg.MaxAttempts {
+			log.Printf("Error handling message %s from %s (attempt %d), retrying: %v\n", msg.ID, topic, msg.Attempts, err)
+			if err := natsMsg.Nak(); err != nil {
+				log.Printf("Error releasing message %s: %v\n", msg.ID, err)
+			}
+			return
+		}
+		log.Printf("Error handling message %s from %s, dropping it after %d attempts: %v\n", msg.ID, topic, msg.Attempts, err)
+		if err := natsMsg.Term(); err != nil {
+			log.Printf("Error terminating message %s: %v\n", msg.ID, err)
+		}
+		return
+	}
+
+	if err := natsMsg.Ack(); err != nil {
+		log.Printf("Error acknowledging message %s: %v\n", msg.ID, err)
+	}
+}
@@ -0,0 +1,173 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	BackendStore = "store"
+	BackendNATS  = "nats"
+	BackendKafka = "kafka"
+)
+
+type Config struct {
+	Backend       string        `env:"QUEUE_BACKEND" envDefault:"store"`
+	PollInterval  time.Duration `env:"QUEUE_POLL_INTERVAL" envDefault:"1s"`
+	ClaimTimeout  time.Duration `env:"QUEUE_CLAIM_TIMEOUT" envDefault:"6h"`
+	MaxAttempts   int           `env:"QUEUE_MAX_ATTEMPTS" envDefault:"3"`
+	ConsumerGroup string        `env:"QUEUE_CONSUMER_GROUP" envDefault:"bolt"`
+	NATS          NATSConfig
+	Kafka         KafkaConfig
+}
+
+type Message struct {
+	ID       string
+	Topic    string
+	Payload  []byte
+	Attempts int // Including the current attempt
+}
+
+// Store persists the queued messages, so they can be shared between processes using the same store
+type Store interface {
+	EnqueueMessage(ctx context.Context, topic string, payload []byte) error
+	// ClaimMessage claims the oldest message of the topic which isn't claimed, or was claimed more than claimTimeout ago.
+	// Returns nil if there's no such message.
+	ClaimMessage(ctx context.Context, topic, consumer string, claimTimeout time.Duration) (*Message, error)
+	// AckMessage removes a handled message
+	AckMessage(ctx context.Context, id string) error
+	// ReleaseMessage un-claims a message, so it will be claimed again
+	ReleaseMessage(ctx context.Context, id string) error
+}
+
+type Handler func(ctx context.Context, msg *Message) error
+
+type Queue interface {
+	Publish(ctx context.Context, topic string, payload []byte) error
+	// Consume handles the topic messages with up to concurrency concurrent handlers, until the context is done.
+	// Messages which failed to be handled are retried up to QUEUE_MAX_ATTEMPTS times.
+	Consume(ctx context.Context, topic string, concurrency int, handler Handler) error
+}
+
+// New returns the queue of the configured backend. The store is used by the store backend.
+func New(cfg Config, store Store) (Queue, error) {
+	switch cfg.Backend {
+	case BackendStore:
+		return NewStoreQueue(cfg, store), nil
+	case BackendNATS:
+		q, err := NewNATSQueue(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("new NATS queue: %w", err)
+		}
+		return q, nil
+	case BackendKafka:
+		q, err := NewKafkaQueue(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("new Kafka queue: %w", err)
+		}
+		return q, nil
+	default:
+		return nil, fmt.Errorf("unknown queue backend %q", cfg.Backend)
+	}
+}
+
+// External returns whether the queue is of an external message broker, which other integrations can consume from
+func (c Config) External() bool {
+	return c.Backend != BackendStore
+}
+
+// StoreQueue is a queue polling a store shared by all the components. A message claimed by a consumer which didn't
+// acknowledge it (for example if it crashed) is claimed again after QUEUE_CLAIM_TIMEOUT.
+type StoreQueue struct {
+	cfg        Config
+	store      Store
+	consumerID string
+}
+
+func NewStoreQueue(cfg Config, store Store) *StoreQueue {
+	hostname, _ := os.Hostname()
+	return &StoreQueue{
+		cfg:        cfg,
+		store:      store,
+		consumerID: fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), uuid.NewString()[:8]),
+	}
+}
+
+func (q *StoreQueue) Publish(ctx context.Context, topic string, payload []byte) error {
+	if err := q.store.EnqueueMessage(ctx, topic, payload); err != nil {
+		return fmt.Errorf("enqueue message to %s: %w", topic, err)
+	}
+	return nil
+}
+
+func (q *StoreQueue) Consume(ctx context.Context, topic string, concurrency int, handler Handler) error {
+	if concurrency <= 0 {
+		return fmt.Errorf("concurrency must be positive")
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.consumeWorker(ctx, topic, handler)
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+func (q *StoreQueue) consumeWorker(ctx context.Context, topic string, handler Handler) {
+	ticker := time.NewTicker(q.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Handle all pending messages before waiting for the next poll
+		for q.consumeOne(ctx, topic, handler) {
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// consumeOne handles a single message, and returns whether a message was claimed
+func (q *StoreQueue) consumeOne(ctx context.Context, topic string, handler Handler) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	msg, err := q.store.ClaimMessage(ctx, topic, q.consumerID, q.cfg.ClaimTimeout)
+	if err != nil {
+		log.Printf("Error claiming message from %s: %v\n", topic, err)
+		return false
+	}
+	if msg == nil {
+		return false
+	}
+
+	if err = handler(ctx, msg); err != nil {
+		if msg.Attempts < q.cfg.MaxAttempts {
+			log.Printf("Error handling message %s from %s (attempt %d), retrying: %v\n", msg.ID, topic, msg.Attempts, err)
+			if err := q.store.ReleaseMessage(ctx, msg.ID); err != nil {
+				log.Printf("Error releasing message %s: %v\n", msg.ID, err)
+			}
+			return true
+		}
+		log.Printf("Error handling message %s from %s, dropping it after %d attempts: %v\n", msg.ID, topic, msg.Attempts, err)
+	}
+
+	if err = q.store.AckMessage(ctx, msg.ID); err != nil {
+		log.Printf("Error acknowledging message %s: %v\n", msg.ID, err)
+	}
+	return true
+}
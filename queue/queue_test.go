@@ -0,0 +1,131 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryStore struct {
+	lock     sync.Mutex
+	messages []*Message
+	claimed  map[string]bool
+	acked    []string
+	counter  int
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{claimed: make(map[string]bool)}
+}
+
+func (m *memoryStore) EnqueueMessage(_ context.Context, topic string, payload []byte) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.counter++
+	m.messages = append(m.messages, &Message{ID: fmt.Sprint(m.counter), Topic: topic, Payload: payload})
+	return nil
+}
+
+func (m *memoryStore) ClaimMessage(_ context.Context, topic, _ string, _ time.Duration) (*Message, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for _, msg := range m.messages {
+		if msg.Topic == topic && !m.claimed[msg.ID] {
+			m.claimed[msg.ID] = true
+			msg.Attempts++
+			claimed := *msg
+			return &claimed, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memoryStore) AckMessage(_ context.Context, id string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for i, msg := range m.messages {
+		if msg.ID == id {
+			m.messages = append(m.messages[:i], m.messages[i+1:]...)
+			m.acked = append(m.acked, id)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *memoryStore) ReleaseMessage(_ context.Context, id string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.claimed, id)
+	return nil
+}
+
+func (m *memoryStore) pending() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return len(m.messages)
+}
+
+func TestStoreQueue(t *testing.T) {
+	t.Parallel()
+
+	store := newMemoryStore()
+	q := NewStoreQueue(Config{PollInterval: 5 * time.Millisecond, ClaimTimeout: time.Hour, MaxAttempts: 2}, store)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lock := sync.Mutex{}
+	handled := make(map[string]int)
+	done := make(chan error)
+	go func() {
+		done <- q.Consume(ctx, "links", 2, func(_ context.Context, msg *Message) error {
+			lock.Lock()
+			defer lock.Unlock()
+			handled[string(msg.Payload)]++
+			if string(msg.Payload) == "failing" {
+				return fmt.Errorf("failed")
+			}
+			return nil
+		})
+	}()
+
+	require.NoError(t, q.Publish(ctx, "links", []byte("first")))
+	require.NoError(t, q.Publish(ctx, "links", []byte("failing")))
+	require.NoError(t, q.Publish(ctx, "other", []byte("other topic")))
+	require.NoError(t, q.Publish(ctx, "links", []byte("second")))
+
+	assert.Eventually(t, func() bool {
+		return store.pending() == 1
+	}, time.Second, 5*time.Millisecond, "only the other topic message should be left")
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	assert.Equal(t, map[string]int{"first": 1, "second": 1, "failing": 2}, handled)
+	assert.Len(t, store.acked, 3, "the failing message should be dropped after the maximum attempts")
+}
+
+func TestStoreQueueBadConcurrency(t *testing.T) {
+	t.Parallel()
+
+	q := NewStoreQueue(Config{}, newMemoryStore())
+	assert.Error(t, q.Consume(context.Background(), "links", 0, nil))
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	q, err := New(Config{Backend: BackendStore}, newMemoryStore())
+	require.NoError(t, err)
+	assert.IsType(t, &StoreQueue{}, q)
+
+	_, err = New(Config{Backend: BackendKafka}, nil)
+	assert.ErrorContains(t, err, "no Kafka brokers")
+
+	_, err = New(Config{Backend: "carrier-pigeon"}, nil)
+	assert.ErrorContains(t, err, "unknown queue backend")
+}
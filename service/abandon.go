@@ -0,0 +1,81 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/oriser/bolt/logging"
+)
+
+const reasonTrackingCanceled = "the host canceled its tracking"
+
+// ErrNotCancelHost is returned when someone other than the host of an order (or their co-host) or a treasurer cancels its tracking
+var ErrNotCancelHost = errors.New("only the host of the order or a treasurer can cancel its tracking")
+
+// CancelTracking stops tracking a group order which is still tracked, like when its group order was restarted in Wolt or the wrong
+// link was posted. Its monitors are stopped, its debts are removed, and its messages are edited to tell it was abandoned. It can be
+// canceled by the host (or their co-host) and by treasurers. It returns the channel of the order.
+func (h *Service) CancelTracking(groupID, fromTransportID string) (string, error) {
+	groupID = h.parseGroupIDOrLink(groupID)
+	order := h.workingOrders.get(groupID)
+	if order == nil {
+		return "", fmt.Errorf("I'm not tracking order %s", groupID)
+	}
+	if !h.IsTreasurer(fromTransportID) {
+		hostTransportID, err := h.orderHostTransportID(order)
+		if err != nil {
+			return "", err
+		}
+		if !h.actsForHost(hostTransportID, fromTransportID) {
+			return "", ErrNotCancelHost
+		}
+	}
+	if !order.stop(reasonTrackingCanceled) {
+		return "", fmt.Errorf("I already stopped tracking order %s because %s", groupID, order.stopped())
+	}
+	h.logger.InfoContext(order.ctx, "Canceled tracking order", "canceled_by", fromTransportID)
+
+	// The context of the order is canceled once it's stopped, so what's left is done in a store call context with its attributes
+	ctx, cancel := h.storeContext()
+	defer cancel()
+	ctx = logging.CopyAttrs(ctx, order.ctx)
+	if err := h.removeAllDebtsForOrder(ctx, order.id, reasonTrackingCanceled); err != nil {
+		h.logger.ErrorContext(order.ctx, "Error removing the debts of the canceled order", "error", err)
+	}
+	canceledMessage := h.text(order.channel, msgTrackingCanceled, order.id, fromTransportID)
+	for _, messageID := range []string{order.joinedMessageID, order.detailsMessageId} {
+		if messageID == "" {
+			continue
+		}
+		if err := h.editEvent(order.channel, canceledMessage, messageID); err != nil {
+			h.logger.ErrorContext(order.ctx, "Error editing the message of the canceled order", "edited_message_id", messageID, "error", err)
+		}
+	}
+
+	event := Event{Type: EventOrderStopped, OrderID: order.id, Channel: order.channel, MessageID: order.messageID, Reason: reasonTrackingCanceled}
+	if venue := order.currentVenue(); venue != nil {
+		event.VenueName = venue.Name
+	}
+	h.hooks.Emit(ctx, event)
+	return order.channel, nil
+}
+
+// orderHostTransportID returns the transport ID of the host of a tracked order, which is known once its rates are computed, or
+// matched by the host's Wolt name from the latest status poll before then
+func (h *Service) orderHostTransportID(order *groupOrder) (string, error) {
+	if hostTransportID := order.HostTransportID(); hostTransportID != "" {
+		return hostTransportID, nil
+	}
+	details, err := order.Details()
+	if err != nil {
+		return "", fmt.Errorf("get group details: %w", err)
+	}
+	hosts, err := h.listUsersByName(details.Host)
+	if err != nil {
+		return "", fmt.Errorf("list users: %w", err)
+	}
+	if len(hosts) != 1 {
+		return "", ErrNotCancelHost
+	}
+	return hosts[0].TransportID, nil
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCancelTracking(t *testing.T) {
+	t.Parallel()
+
+	store := &fakeTreasuryStore{
+		users: map[string]*userDomain.User{"u1": {ID: "u1", TransportID: "U1", FullName: "Thor"}},
+		debts: []*debtDomain.Debt{
+			{ID: "d1", OrderID: "ABC", LenderID: "u1", BorrowerID: "u2", InitiatedTransportID: "C1"},
+			{ID: "d2", OrderID: "XYZ", LenderID: "u1", BorrowerID: "u2", InitiatedTransportID: "C1"},
+		},
+	}
+	notification := &editingNotification{}
+	h, err := New(Config{Treasurers: []string{"UT"}}, store, store, nil, "UBOT", notification)
+	require.NoError(t, err)
+	entry, _, ok := h.workingOrders.start("ABC", time.Now(), 1, nil)
+	require.True(t, ok)
+	ctx, cancel := context.WithCancel(context.Background())
+	order := &groupOrder{id: "ABC", channel: "C1", messageID: "1.1", ctx: ctx, cancel: cancel, hostTransportID: "U1",
+		joinedMessageID: "1.2", detailsMessageId: "1.3"}
+	h.workingOrders.setOrder(entry, order)
+
+	_, err = h.CancelTracking("ABC", "U2")
+	assert.ErrorIs(t, err, ErrNotCancelHost)
+	assert.NoError(t, ctx.Err(), "the order is still tracked")
+
+	channel, err := h.CancelTracking("<https://wolt.com/en/group/abc>", "U1")
+	require.NoError(t, err)
+	assert.Equal(t, "C1", channel)
+	assert.Error(t, ctx.Err(), "the monitors of the order are stopped")
+	assert.Equal(t, reasonTrackingCanceled, order.stopped())
+	assert.Equal(t, []string{
+		"C1/1.2: :no_entry_sign: I stopped tracking order ABC as <@U1> canceled it, nobody owes anything for it",
+		"C1/1.3: :no_entry_sign: I stopped tracking order ABC as <@U1> canceled it, nobody owes anything for it",
+	}, notification.edits)
+	require.Len(t, store.debts, 1, "only the debts of the canceled order are removed")
+	assert.Equal(t, "XYZ", store.debts[0].OrderID)
+
+	_, err = h.CancelTracking("ABC", "UT")
+	assert.EqualError(t, err, "I already stopped tracking order ABC because the host canceled its tracking",
+		"treasurers can cancel any order, but it was already canceled")
+	_, err = h.CancelTracking("XYZ", "U1")
+	assert.EqualError(t, err, "I'm not tracking order XYZ")
+}
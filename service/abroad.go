@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	"github.com/oriser/bolt/fx"
+)
+
+var currencyRe = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// SetFXProvider sets the exchange rates provider, for converting the debts of users abroad to their currency in reminders
+func (h *Service) SetFXProvider(provider fx.Provider) {
+	h.fxProvider = provider
+}
+
+func (h *Service) abroadStore() (debtDomain.AbroadStore, error) {
+	abroadStore, ok := h.debtStore.(debtDomain.AbroadStore)
+	if !ok || h.fxProvider == nil {
+		return nil, fmt.Errorf("debts in foreign currencies are not supported")
+	}
+	return abroadStore, nil
+}
+
+// SetAbroad marks the user (by transport ID) as abroad, so the debts reminders will show the amount in the given currency as well.
+// An empty currency marks the user as back home.
+func (h *Service) SetAbroad(ctx context.Context, transportID, currency string) error {
+	abroadStore, err := h.abroadStore()
+	if err != nil {
+		return err
+	}
+	if currency == "" {
+		if err := abroadStore.RemoveAbroadCurrency(ctx, transportID); err != nil {
+			return fmt.Errorf("remove abroad currency: %w", err)
+		}
+		return nil
+	}
+
+	currency = strings.ToUpper(currency)
+	if !currencyRe.MatchString(currency) {
+		return fmt.Errorf("%q is not a currency code (for example USD)", currency)
+	}
+	if _, err := h.fxProvider.Rate(ctx, h.currency, currency); err != nil {
+		return fmt.Errorf("get rate of %s: %w", currency, err)
+	}
+	if err := abroadStore.SetAbroadCurrency(ctx, transportID, currency); err != nil {
+		return fmt.Errorf("set abroad currency: %w", err)
+	}
+	return nil
+}
+
+// formatDebtAmount formats the amount of a debt, adding its conversion to the borrower's currency if the borrower is abroad
+func (h *Service) formatDebtAmount(ctx context.Context, borrowerTransportID string, amount float64, debtCurrency string) string {
+	debtCurrency = h.currencyOrDefault(debtCurrency)
+	formatted := FormatAmount(amount, debtCurrency)
+	abroadStore, err := h.abroadStore()
+	if err != nil {
+		return formatted
+	}
+	currency, err := abroadStore.AbroadCurrency(ctx, borrowerTransportID)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "Error getting abroad currency", "transport_id", borrowerTransportID, "error", err)
+		return formatted
+	}
+	if currency == "" || currency == debtCurrency {
+		return formatted
+	}
+	rate, err := h.fxProvider.Rate(ctx, debtCurrency, currency)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "Error getting exchange rate", "currency", currency, "error", err)
+		return formatted
+	}
+	return fmt.Sprintf("%s (about %.2f %s)", formatted, amount*rate, currency)
+}
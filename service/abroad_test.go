@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeAbroadStore struct {
+	fakeTreasuryStore
+	currencies map[string]string
+}
+
+func (f *fakeAbroadStore) SetAbroadCurrency(_ context.Context, transportID, currency string) error {
+	f.currencies[transportID] = currency
+	return nil
+}
+
+func (f *fakeAbroadStore) AbroadCurrency(_ context.Context, transportID string) (string, error) {
+	return f.currencies[transportID], nil
+}
+
+func (f *fakeAbroadStore) RemoveAbroadCurrency(_ context.Context, transportID string) error {
+	delete(f.currencies, transportID)
+	return nil
+}
+
+type fakeFXProvider map[string]float64
+
+func (f fakeFXProvider) Rate(_ context.Context, from, to string) (float64, error) {
+	rate, ok := f[from+"/"+to]
+	if !ok {
+		return 0, fmt.Errorf("no rate of %s to %s", from, to)
+	}
+	return rate, nil
+}
+
+func TestAbroad(t *testing.T) {
+	t.Parallel()
+
+	store := &fakeAbroadStore{currencies: make(map[string]string)}
+	h, err := New(Config{}, store, store, nil, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	assert.Error(t, h.SetAbroad(ctx, "U1", "USD"), "not supported without an FX provider")
+	h.SetFXProvider(fakeFXProvider{"ILS/USD": 0.25})
+
+	assert.Error(t, h.SetAbroad(ctx, "U1", "dollars"))
+	assert.Error(t, h.SetAbroad(ctx, "U1", "EUR"), "currencies without a rate are rejected")
+	assert.Equal(t, "10.00 nis", h.formatDebtAmount(ctx, "U1", 10, ""))
+
+	require.NoError(t, h.SetAbroad(ctx, "U1", "usd"))
+	assert.Equal(t, "USD", store.currencies["U1"])
+	assert.Equal(t, "10.00 nis (about 2.50 USD)", h.formatDebtAmount(ctx, "U1", 10, ""))
+	assert.Equal(t, "10.00 nis", h.formatDebtAmount(ctx, "U2", 10, ""))
+
+	require.NoError(t, h.SetAbroad(ctx, "U1", ""))
+	assert.Equal(t, "10.00 nis", h.formatDebtAmount(ctx, "U1", 10, ""))
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// settingAccessibleMessages is the channel setting overriding ACCESSIBLE_MESSAGES
+const settingAccessibleMessages = "ACCESSIBLE_MESSAGES"
+
+// emojiTexts are the text equivalents of the emojis Bolt uses as signals. Other emojis are replaced by their name.
+var emojiTexts = map[string]string{
+	MarkAsPaidReaction:       "paid",
+	HostRemoveDebts:          "remove debts",
+	"white_check_mark":       "done",
+	"heavy_check_mark":       "done",
+	"no_entry_sign":          "skip",
+	"credit_card":            "company paid",
+	"warning":                "warning",
+	"rotating_light":         "alert",
+	"bell":                   "reminder",
+	"eyes":                   "tracking",
+	"sleeping":               "too late",
+	"hourglass_flowing_sand": "waiting",
+	"handshake":              "order together",
+	"receipt":                "debt",
+	"moneybag":               "cost",
+	"gift":                   "forgiven",
+	"house":                  "destination",
+	"bike":                   "courier",
+	"cook":                   "venue",
+	"lock":                   "private",
+}
+
+var (
+	// The name must have a letter, so times like 12:30:45 aren't taken for emojis
+	emojiRe      = regexp.MustCompile(`:([a-z0-9_+\-]*[a-z][a-z0-9_+\-]*):`)
+	strikeRe     = regexp.MustCompile(`~([^~\s](?:[^~\n]*[^~\s])?)~`)
+	blockQuoteRe = regexp.MustCompile(`(?m)^>\s?`)
+)
+
+func parseBoolSetting(value string) (string, error) {
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return "", fmt.Errorf("expected true or false but got %q", value)
+	}
+	return strconv.FormatBool(enabled), nil
+}
+
+// accessible returns whether the messages to the receiver are screen-reader friendly, with the channel's override of
+// ACCESSIBLE_MESSAGES if it has one
+func (h *Service) accessible(receiver string) bool {
+	if value, ok := h.channelSetting(receiver, settingAccessibleMessages); ok {
+		return value == "true"
+	}
+	return h.cfg.AccessibleMessages
+}
+
+// emojiText returns the text equivalent of the emoji, in brackets
+func emojiText(name string) string {
+	text, ok := emojiTexts[name]
+	if !ok {
+		text = strings.ReplaceAll(name, "_", " ")
+	}
+	return "[" + text + "]"
+}
+
+// accessibleText replaces the emojis of the text by their text equivalents and drops the formatting screen readers read out as
+// symbols, like bold, strikethrough, code and quotes. Links and mentions are kept as they are read well.
+func accessibleText(text string) string {
+	text = emojiRe.ReplaceAllStringFunc(text, func(emoji string) string {
+		return emojiText(strings.Trim(emoji, ":"))
+	})
+	text = boldRe.ReplaceAllString(text, "$1")
+	text = strikeRe.ReplaceAllString(text, "$1")
+	text = strings.ReplaceAll(text, "`", "")
+	return blockQuoteRe.ReplaceAllString(text, "")
+}
+
+// forReceiver returns the text as it's sent to the receiver, accessible if its messages are
+func (h *Service) forReceiver(receiver, text string) string {
+	if !h.accessible(receiver) {
+		return text
+	}
+	return accessibleText(text)
+}
+
+// editEvent edits the text of a message Bolt sent
+func (h *Service) editEvent(receiver, event, messageID string) error {
+	return h.eventNotification.EditMessage(receiver, h.forReceiver(receiver, event), messageID)
+}
+
+// buildProgressText describes the progress of the delivery in words, instead of the emoji art
+func (h *Service) buildProgressText(channel string, startedAt time.Time, deliveryEta time.Time, timezone *time.Location) string {
+	deliveryPercentage := math.Min(time.Since(startedAt).Seconds()/deliveryEta.Sub(startedAt).Seconds(), 1)
+	deliveryEtaString := SlackDate(deliveryEta, "{time}", "15:04", timezone)
+	return h.text(channel, msgDeliveryProgress, int(math.Round(math.Max(deliveryPercentage, 0)*100)), deliveryEtaString)
+}
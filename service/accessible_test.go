@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessibleText(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{":money_mouth_face: *Loki* paid", "[paid] Loki paid"},
+		{"React with :party_parrot: at 12:30:45", "React with [party parrot] at 12:30:45"},
+		{"~Old~ `IL62 **** 9999`", "Old IL62 **** 9999"},
+		{"> Note from the host\n<https://wolt.com|Wolt>: <@U1>", "Note from the host\n<https://wolt.com|Wolt>: <@U1>"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, accessibleText(test.text))
+	}
+}
+
+func TestAccessibleMessages(t *testing.T) {
+	t.Parallel()
+
+	notification := &recordingNotification{}
+	store := &fakeChannelSettingsStore{settings: make(map[string]map[string]string)}
+	h, err := New(Config{AccessibleMessages: true}, nil, nil, store, "UBOT", notification)
+	require.NoError(t, err)
+	_, err = h.SetChannelSetting(context.Background(), "C2", "accessible_messages", "no", "U1")
+	assert.Error(t, err)
+	value, err := h.SetChannelSetting(context.Background(), "C2", "accessible_messages", "0", "U1")
+	require.NoError(t, err)
+	assert.Equal(t, "false", value)
+
+	_, _ = h.informEvent("C1", ":bell: Waiting on *Loki*", "", "")
+	_, _ = h.informEvent("C2", ":bell: Waiting on *Loki*", "", "")
+	assert.Equal(t, []string{"C1: [reminder] Waiting on Loki", "C2: :bell: Waiting on *Loki*"}, notification.messages)
+
+	now := time.Now()
+	assert.Contains(t, h.buildProgressText("C1", now.Add(-10*time.Minute), now.Add(30*time.Minute), time.UTC), "Delivery progress: 25%, arriving at")
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ActiveOrder is a snapshot of an order Bolt currently tracks
+type ActiveOrder struct {
+	ID        string
+	Channel   string
+	MessageID string
+	VenueName string
+	JoinedAt  time.Time
+	// When the rates were published, zero until they are
+	RatesPublishedAt time.Time
+	State            DeliveryState
+	Rates            *GroupRate // Nil until the rates are published
+	OrderState       OrderState
+}
+
+// activeOrders keeps the state of the tracked orders, updated by the service's own lifecycle events
+type activeOrders struct {
+	lock   sync.RWMutex
+	orders map[string]*ActiveOrder
+}
+
+func newActiveOrders() *activeOrders {
+	return &activeOrders{orders: make(map[string]*ActiveOrder)}
+}
+
+func (a *activeOrders) onEvent(_ context.Context, event Event) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if event.Type == EventOrderJoined {
+		a.orders[event.OrderID] = &ActiveOrder{
+			ID:         event.OrderID,
+			Channel:    event.Channel,
+			MessageID:  event.MessageID,
+			VenueName:  event.VenueName,
+			JoinedAt:   event.Time,
+			OrderState: OrderStateJoined,
+		}
+		return
+	}
+
+	activeOrder, ok := a.orders[event.OrderID]
+	if !ok {
+		return
+	}
+	switch event.Type {
+	case EventVenueChanged:
+		activeOrder.VenueName = event.VenueName
+	case EventRatesPublished:
+		activeOrder.Rates = event.Rates
+		activeOrder.RatesPublishedAt = event.Time
+	case EventDeliveryProgress:
+		activeOrder.State = event.State
+	case EventOrderStateChanged:
+		activeOrder.OrderState = event.OrderState
+	case EventOrderDelivered, EventOrderCanceled, EventOrderStopped:
+		delete(a.orders, event.OrderID)
+	}
+}
+
+func (a *activeOrders) remove(orderID string) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	delete(a.orders, orderID)
+}
+
+// ActiveOrders returns the orders Bolt currently tracks, from the oldest to the newest
+func (h *Service) ActiveOrders() []ActiveOrder {
+	h.activeOrders.lock.RLock()
+	defer h.activeOrders.lock.RUnlock()
+
+	orders := make([]ActiveOrder, 0, len(h.activeOrders.orders))
+	for _, activeOrder := range h.activeOrders.orders {
+		orders = append(orders, *activeOrder)
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].JoinedAt.Before(orders[j].JoinedAt)
+	})
+	return orders
+}
+
+// LookupActiveOrder returns the snapshot of the order with the given Wolt group ID, if Bolt currently tracks it
+func (h *Service) LookupActiveOrder(orderID string) (ActiveOrder, bool) {
+	h.activeOrders.lock.RLock()
+	defer h.activeOrders.lock.RUnlock()
+
+	activeOrder, ok := h.activeOrders.orders[orderID]
+	if !ok {
+		return ActiveOrder{}, false
+	}
+	return *activeOrder, true
+}
+
+// Status describes the stage the order is in
+func (o ActiveOrder) Status() string {
+	if o.Rates == nil {
+		return "waiting for everyone to be ready"
+	}
+	switch o.State {
+	case DeliveryStateReceived:
+		return "the order was received by the venue"
+	case DeliveryStateProduction:
+		return "the order is being prepared"
+	case DeliveryStatePickup:
+		return "the order is on its way"
+	default:
+		return "the rates were published, waiting for the delivery"
+	}
+}
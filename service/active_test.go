@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestActiveOrders(t *testing.T) {
+	t.Parallel()
+
+	hooks := NewHooks()
+	active := newActiveOrders()
+	hooks.SubscribeAll(active.onEvent)
+	h := &Service{activeOrders: active, logger: slog.Default()}
+
+	now := time.Now()
+	ctx := context.Background()
+	hooks.Emit(ctx, Event{Type: EventOrderJoined, OrderID: "B", Channel: "C1", VenueName: "Sushi", Time: now.Add(time.Minute)})
+	hooks.Emit(ctx, Event{Type: EventOrderJoined, OrderID: "A", Channel: "C1", VenueName: "Pizza", Time: now})
+	hooks.Emit(ctx, Event{Type: EventRatesPublished, OrderID: "A", Rates: &GroupRate{DeliveryRate: 10}})
+	hooks.Emit(ctx, Event{Type: EventDeliveryProgress, OrderID: "A", State: DeliveryStatePickup})
+	hooks.Emit(ctx, Event{Type: EventDeliveryProgress, OrderID: "unknown", State: DeliveryStatePickup})
+
+	orders := h.ActiveOrders()
+	if assert.Len(t, orders, 2) {
+		assert.Equal(t, "A", orders[0].ID)
+		assert.Equal(t, "Pizza", orders[0].VenueName)
+		assert.Equal(t, DeliveryStatePickup, orders[0].State)
+		assert.Equal(t, 10, orders[0].Rates.DeliveryRate)
+		assert.Equal(t, "B", orders[1].ID)
+		assert.Nil(t, orders[1].Rates)
+	}
+
+	hooks.Emit(ctx, Event{Type: EventOrderDelivered, OrderID: "A"})
+	active.remove("B")
+	assert.Empty(t, h.ActiveOrders())
+}
+
+type linkingNotification struct {
+	recordingNotification
+}
+
+func (l *linkingNotification) MessageLink(receiver, messageID string) (string, error) {
+	return fmt.Sprintf("https://slack/%s/%s", receiver, messageID), nil
+}
+
+func TestDuplicateLink(t *testing.T) {
+	t.Parallel()
+
+	notification := &linkingNotification{}
+	h, err := New(Config{}, nil, nil, nil, "U-bot", notification)
+	require.NoError(t, err)
+
+	h.informDuplicateLink(LinksRequest{Channel: "C1", MessageID: "2.2"}, "A")
+	assert.Empty(t, notification.messages, "untracked orders are ignored")
+
+	h.hooks.Emit(context.Background(), Event{Type: EventOrderJoined, OrderID: "A", Channel: "C1", MessageID: "1.1", VenueName: "Pizza"})
+	h.informDuplicateLink(LinksRequest{Channel: "C1", MessageID: "1.1"}, "A")
+	assert.Empty(t, notification.messages, "the tracked message itself is ignored")
+
+	h.informDuplicateLink(LinksRequest{Channel: "C1", MessageID: "2.2"}, "A")
+	h.hooks.Emit(context.Background(), Event{Type: EventRatesPublished, OrderID: "A", Rates: &GroupRate{}})
+	h.hooks.Emit(context.Background(), Event{Type: EventDeliveryProgress, OrderID: "A", State: DeliveryStatePickup})
+	h.informDuplicateLink(LinksRequest{Channel: "C2", MessageID: "3.3"}, "A")
+	assert.Equal(t, []string{
+		"C1: I'm already tracking <https://slack/C1/1.1|this order> from [Pizza], waiting for everyone to be ready",
+		"C2: I'm already tracking <https://slack/C1/1.1|this order> from [Pizza], the order is on its way",
+	}, notification.messages)
+}
@@ -0,0 +1,368 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	userDomain "github.com/oriser/bolt/user"
+)
+
+var (
+	// ErrNotOrderHost is returned when someone other than the host of an order (or their co-host) adjusts its debts
+	ErrNotOrderHost = errors.New("only the host of the order can adjust its debts")
+	// ErrNoDebtToAdjust is returned when adjusting the debt of a participant who doesn't owe anything for the order
+	ErrNoDebtToAdjust = errors.New("the participant has no outstanding debt for the order")
+)
+
+// rateAdjustment is the host's adjustment of the rate of a participant
+type rateAdjustment struct {
+	forgiven bool
+	amount   *float64         // The personal amount the host set, nil if it wasn't changed
+	user     *userDomain.User // The user the host reassigned the Wolt name to, nil if it wasn't reassigned
+	private  bool             // The user the Wolt name was reassigned to keeps their amounts private
+}
+
+// feeOverride is the host's correction of the fees of an order, with the amount of each participant (by Wolt name) computed with it
+type feeOverride struct {
+	fees    Fees
+	amounts map[string]float64
+}
+
+// rateAdjustments keeps the host's adjustments of the rates of the tracked orders, by order ID and Wolt name, and the host's
+// corrections of their fees, so the edits of the rates message until the delivery keep showing them
+type rateAdjustments struct {
+	lock      sync.RWMutex
+	orders    map[string]map[string]*rateAdjustment
+	overrides map[string]*feeOverride
+}
+
+func newRateAdjustments() *rateAdjustments {
+	return &rateAdjustments{orders: make(map[string]map[string]*rateAdjustment), overrides: make(map[string]*feeOverride)}
+}
+
+// fees returns the fees the host set for the order, or the given fees if the host didn't correct them
+func (r *rateAdjustments) fees(orderID string, published Fees) Fees {
+	if fees, ok := r.overriddenFees(orderID); ok {
+		return fees
+	}
+	return published
+}
+
+// overriddenFees returns the fees the host set for the order, and false if the host didn't correct them
+func (r *rateAdjustments) overriddenFees(orderID string) (Fees, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	if override, ok := r.overrides[orderID]; ok {
+		return override.fees, true
+	}
+	return Fees{}, false
+}
+
+func (r *rateAdjustments) setFees(orderID string, fees Fees, amounts map[string]float64) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.overrides[orderID] = &feeOverride{fees: fees, amounts: amounts}
+}
+
+func (r *rateAdjustments) update(orderID, woltName string, adjust func(*rateAdjustment)) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.orders[orderID] == nil {
+		r.orders[orderID] = make(map[string]*rateAdjustment)
+	}
+	if r.orders[orderID][woltName] == nil {
+		r.orders[orderID][woltName] = &rateAdjustment{}
+	}
+	adjust(r.orders[orderID][woltName])
+}
+
+// adjusted returns whether the host forgave, changed or reassigned the rate of the Wolt name in the order
+func (r *rateAdjustments) adjusted(orderID, woltName string) bool {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	_, ok := r.orders[orderID][woltName]
+	return ok
+}
+
+// apply returns a copy of the rates of the order with the adjustments applied, and false if the order has no adjustments
+func (r *rateAdjustments) apply(orderID string, groupRate GroupRate) (GroupRate, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	adjustments, override := r.orders[orderID], r.overrides[orderID]
+	if len(adjustments) == 0 && override == nil {
+		return groupRate, false
+	}
+
+	groupRate = applyFees(groupRate, override)
+	rates := groupRate.Rates
+	for i := range rates {
+		adjustment, ok := adjustments[rates[i].WoltName]
+		if !ok {
+			continue
+		}
+		if adjustment.user != nil {
+			rates[i].User = adjustment.user
+			rates[i].Private = adjustment.private
+		}
+		if adjustment.amount != nil {
+			rates[i].Amount = *adjustment.amount + rates[i].Subsidy
+			rates[i].Adjusted = true
+		}
+		rates[i].Forgiven = adjustment.forgiven
+	}
+	return groupRate, true
+}
+
+// withFees returns a copy of the rates of the order with the fees the host set, without the host's adjustments of single rates
+func (r *rateAdjustments) withFees(orderID string, groupRate GroupRate) GroupRate {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return applyFees(groupRate, r.overrides[orderID])
+}
+
+// applyFees returns a copy of the rates with the amounts computed with the overridden fees, if any
+func applyFees(groupRate GroupRate, override *feeOverride) GroupRate {
+	rates := make([]Rate, len(groupRate.Rates))
+	copy(rates, groupRate.Rates)
+	if override != nil {
+		groupRate.DeliveryRate = int(math.Round(override.fees.Delivery))
+		groupRate.ExtraFees = Fees{Service: override.fees.Service, Tip: override.fees.Tip, Discount: override.fees.Discount}
+		groupRate.setFees(Fees{})
+		for i := range rates {
+			if amount, ok := override.amounts[rates[i].WoltName]; ok {
+				rates[i].Amount = amount
+			}
+		}
+	}
+	groupRate.Rates = rates
+	return groupRate
+}
+
+func (r *rateAdjustments) remove(orderID string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.orders, orderID)
+	delete(r.overrides, orderID)
+}
+
+// ForgiveDebt removes the debt of the borrower (by transport ID) for the order, for its host (or their co-host) to forgive it
+func (h *Service) ForgiveDebt(ctx context.Context, hostTransportID, orderID, borrowerTransportID string) error {
+	debt, _, err := h.adjustableDebt(ctx, hostTransportID, orderID, borrowerTransportID)
+	if err != nil {
+		return err
+	}
+	if err := h.debtStore.RemoveDebtInOrderID(ctx, orderID, debt.ID); err != nil {
+		return fmt.Errorf("remove debt: %w", err)
+	}
+	h.readModels.removeDebt(debt)
+	h.activity.clearDeferred(debt.ID)
+
+	h.adjustPublishedRates(orderID, func(rate Rate) bool {
+		return rate.User != nil && rate.User.ID == debt.BorrowerID
+	}, func(adjustment *rateAdjustment) {
+		adjustment.forgiven = true
+	})
+	amount := FormatAmount(debt.Amount, debt.Currency)
+	_, _ = h.informInteractiveEvent(borrowerTransportID, h.text(borrowerTransportID, msgDebtForgivenNotice, hostTransportID, amount, orderID), "")
+	note := h.text(debt.InitiatedTransportID, msgDebtForgiven, hostTransportID, borrowerTransportID, amount)
+	if h.privateAmounts(borrowerTransportID) {
+		note = h.text(debt.InitiatedTransportID, msgDebtForgivenPrivate, hostTransportID, borrowerTransportID)
+	}
+	_, _ = h.informEvent(debt.InitiatedTransportID, note, "", debt.MessageID)
+	return nil
+}
+
+// EditDebtAmount changes the amount of the debt of the borrower (by transport ID) for the order, for its host (or their co-host) to
+// account for things the rates don't, like an item the participants shared
+func (h *Service) EditDebtAmount(ctx context.Context, hostTransportID, orderID, borrowerTransportID string, amount float64) error {
+	if amount <= 0 {
+		return fmt.Errorf("the amount must be positive, forgive the debt instead of setting it to 0")
+	}
+	debt, _, err := h.adjustableDebt(ctx, hostTransportID, orderID, borrowerTransportID)
+	if err != nil {
+		return err
+	}
+	previous := debt.Amount
+	if err := h.replaceDebt(ctx, debt, func(d *debtDomain.Debt) { d.Amount = amount }); err != nil {
+		return err
+	}
+
+	h.adjustPublishedRates(orderID, func(rate Rate) bool {
+		return rate.User != nil && rate.User.ID == debt.BorrowerID
+	}, func(adjustment *rateAdjustment) {
+		adjustment.amount = &amount
+	})
+	previousAmount, newAmount := FormatAmount(previous, debt.Currency), FormatAmount(amount, debt.Currency)
+	_, _ = h.informInteractiveEvent(borrowerTransportID, h.text(borrowerTransportID, msgDebtChanged, hostTransportID, borrowerTransportID, orderID,
+		previousAmount, newAmount), "")
+	message := h.text(debt.InitiatedTransportID, msgDebtChanged, hostTransportID, borrowerTransportID, orderID, previousAmount, newAmount)
+	if h.privateAmounts(borrowerTransportID) {
+		message = h.text(debt.InitiatedTransportID, msgDebtChangedPrivate, hostTransportID, borrowerTransportID, orderID)
+	}
+	_, _ = h.informEvent(debt.InitiatedTransportID, message, "", debt.MessageID)
+	return nil
+}
+
+// ReassignDebt moves the debt of the Wolt name for the order to another user (by transport ID), for its host (or their co-host) to fix
+// a participant Bolt matched to the wrong user. The Wolt name stays mapped to its user for other orders.
+func (h *Service) ReassignDebt(ctx context.Context, hostTransportID, orderID, woltName, toTransportID string) error {
+	woltName = strings.TrimSpace(woltName)
+	borrower, err := h.participantUser(orderID, woltName)
+	if err != nil {
+		return err
+	}
+	debt, host, err := h.adjustableDebt(ctx, hostTransportID, orderID, borrower.TransportID)
+	if err != nil {
+		return err
+	}
+	if toTransportID == borrower.TransportID {
+		return nil
+	}
+	if toTransportID == host.TransportID {
+		return fmt.Errorf("the host can't owe themselves, forgive the debt instead")
+	}
+	toUsers, err := h.userStore.ListUsers(ctx, userDomain.ListFilter{TransportID: toTransportID})
+	if err != nil {
+		return fmt.Errorf("list users: %w", err)
+	}
+	if len(toUsers) == 0 {
+		return fmt.Errorf("<@%s> isn't known to Bolt yet, they can register with /bolt register <wolt name>", toTransportID)
+	}
+	to := toUsers[0]
+
+	if err := h.moveDebt(ctx, debt, to); err != nil {
+		return err
+	}
+	private := h.privateAmounts(to.TransportID)
+	h.adjustPublishedRates(orderID, func(rate Rate) bool {
+		return rate.WoltName == woltName
+	}, func(adjustment *rateAdjustment) {
+		adjustment.user = to
+		adjustment.private = private
+	})
+	amount := FormatAmount(debt.Amount, debt.Currency)
+	_, _ = h.informInteractiveEvent(to.TransportID, h.text(to.TransportID, msgDebtMovedToYou, hostTransportID, woltName, amount, orderID), "")
+	_, _ = h.informInteractiveEvent(borrower.TransportID, h.text(borrower.TransportID, msgDebtMovedFromYou, hostTransportID, woltName, amount, orderID,
+		to.TransportID), "")
+	note := h.text(debt.InitiatedTransportID, msgDebtMoved, hostTransportID, woltName, amount, borrower.TransportID, to.TransportID)
+	if private || h.privateAmounts(borrower.TransportID) {
+		note = h.text(debt.InitiatedTransportID, msgDebtMovedPrivate, hostTransportID, woltName, borrower.TransportID, to.TransportID)
+	}
+	_, _ = h.informEvent(debt.InitiatedTransportID, note, "", debt.MessageID)
+	return nil
+}
+
+// adjustableDebt returns the outstanding debt of the borrower (by transport ID) for the order and the host of the order, or
+// ErrNotOrderHost if the user (by transport ID) is neither its host nor the host's co-host
+func (h *Service) adjustableDebt(ctx context.Context, fromTransportID, orderID, borrowerTransportID string) (*debtDomain.Debt, *userDomain.User, error) {
+	if h.debtStore == nil {
+		return nil, nil, ErrNoDebtToAdjust
+	}
+	debts, err := h.debtStore.ListDebtsForOrderID(ctx, orderID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list debts: %w", err)
+	}
+	if len(debts) == 0 {
+		return nil, nil, ErrNoDebtToAdjust
+	}
+	host, err := h.getUser(debts[0].LenderID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get host user: %w", err)
+	}
+	if !h.actsForHost(host.TransportID, fromTransportID) {
+		return nil, nil, ErrNotOrderHost
+	}
+
+	borrowers, err := h.userStore.ListUsers(ctx, userDomain.ListFilter{TransportID: borrowerTransportID})
+	if err != nil {
+		return nil, nil, fmt.Errorf("list users: %w", err)
+	}
+	for _, debt := range debts {
+		for _, borrower := range borrowers {
+			if debt.BorrowerID == borrower.ID {
+				return debt, host, nil
+			}
+		}
+	}
+	return nil, nil, ErrNoDebtToAdjust
+}
+
+// replaceDebt changes the debt. The debt is replaced with the same ID, so reactions and reminders keep referring to it.
+func (h *Service) replaceDebt(ctx context.Context, debt *debtDomain.Debt, change func(*debtDomain.Debt)) error {
+	if err := h.debtStore.RemoveDebtInOrderID(ctx, debt.OrderID, debt.ID); err != nil {
+		return fmt.Errorf("remove debt: %w", err)
+	}
+	h.readModels.removeDebt(debt)
+	changed := *debt
+	change(&changed)
+	if err := h.debtStore.AddDebt(ctx, &changed); err != nil {
+		return fmt.Errorf("add changed debt: %w", err)
+	}
+	h.readModels.putDebt(&changed)
+	return nil
+}
+
+// moveDebt moves the debt to the user, adding it to the user's own debt for the order if they have one
+func (h *Service) moveDebt(ctx context.Context, debt *debtDomain.Debt, to *userDomain.User) error {
+	debts, err := h.debtStore.ListDebtsForOrderID(ctx, debt.OrderID)
+	if err != nil {
+		return fmt.Errorf("list debts: %w", err)
+	}
+	for _, existing := range debts {
+		if existing.BorrowerID != to.ID {
+			continue
+		}
+		if err := h.replaceDebt(ctx, existing, func(d *debtDomain.Debt) { d.Amount += debt.Amount }); err != nil {
+			return err
+		}
+		if err := h.debtStore.RemoveDebtInOrderID(ctx, debt.OrderID, debt.ID); err != nil {
+			return fmt.Errorf("remove moved debt: %w", err)
+		}
+		h.readModels.removeDebt(debt)
+		h.activity.clearDeferred(debt.ID)
+		return nil
+	}
+	return h.replaceDebt(ctx, debt, func(d *debtDomain.Debt) { d.BorrowerID = to.ID })
+}
+
+// participantUser returns the user the Wolt name was matched to in the order, taken from its published rates while it's tracked
+func (h *Service) participantUser(orderID, woltName string) (*userDomain.User, error) {
+	if activeOrder, ok := h.LookupActiveOrder(orderID); ok && activeOrder.Rates != nil {
+		if rate := rateByName(*activeOrder.Rates, woltName); rate != nil && rate.User != nil {
+			return rate.User, nil
+		}
+	}
+	users, err := h.listUsersByName(woltName)
+	if err != nil {
+		return nil, fmt.Errorf("list users: %w", err)
+	}
+	if len(users) != 1 {
+		return nil, ErrNoDebtToAdjust
+	}
+	return users[0], nil
+}
+
+// adjustPublishedRates records the adjustment of the rate of the participant matching the given function, and edits the rates
+// message to show it. Orders which are no longer tracked keep their rates message, and only the note in its thread shows the adjustment.
+func (h *Service) adjustPublishedRates(orderID string, matches func(Rate) bool, adjust func(*rateAdjustment)) {
+	activeOrder, ok := h.LookupActiveOrder(orderID)
+	order := h.workingOrders.get(orderID)
+	if !ok || activeOrder.Rates == nil || order == nil || order.detailsMessageId == "" {
+		return
+	}
+	groupRate := *activeOrder.Rates
+	for _, rate := range groupRate.Rates {
+		if matches(rate) {
+			h.rateAdjustments.update(orderID, rate.WoltName, adjust)
+			break
+		}
+	}
+	if err := h.editRatesMessage(activeOrder.Channel, order, groupRate, h.buildRatesMessage(activeOrder.Channel, groupRate, orderID), ""); err != nil {
+		h.logger.Error("Error editing the rates message with the host's adjustment", "group_id", orderID, "error", err)
+	}
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdjustDebts(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	notification := &recordingNotification{}
+	store := &fakeTreasuryStore{
+		users: map[string]*userDomain.User{
+			"uuid-host":  {ID: "uuid-host", FullName: "Thor", TransportID: "U-host"},
+			"uuid-loki":  {ID: "uuid-loki", FullName: "Loki", TransportID: "U-loki"},
+			"uuid-odin":  {ID: "uuid-odin", FullName: "Odin", TransportID: "U-odin"},
+			"uuid-frigg": {ID: "uuid-frigg", FullName: "Frigg", TransportID: "U-frigg"},
+		},
+		debts: []*debtDomain.Debt{
+			{ID: "d1", BorrowerID: "uuid-loki", LenderID: "uuid-host", OrderID: "ABC", Amount: 30, InitiatedTransportID: "C1", MessageID: "ts1"},
+			{ID: "d2", BorrowerID: "uuid-odin", LenderID: "uuid-host", OrderID: "ABC", Amount: 20, InitiatedTransportID: "C1", MessageID: "ts1"},
+		},
+	}
+	h, err := New(Config{}, store, store, nil, "UBOT", notification)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, h.ForgiveDebt(ctx, "U-loki", "ABC", "U-loki"), ErrNotOrderHost)
+	assert.ErrorIs(t, h.ForgiveDebt(ctx, "U-host", "ABC", "U-frigg"), ErrNoDebtToAdjust)
+
+	require.NoError(t, h.ForgiveDebt(ctx, "U-host", "ABC", "U-loki"))
+	require.Len(t, store.debts, 1)
+	assert.Equal(t, "d2", store.debts[0].ID)
+	assert.Contains(t, notification.messages, "U-loki: <@U-host> forgave your debt of 30.00 nis for order ABC :gift:")
+	assert.Contains(t, notification.messages, "C1: <@U-host> forgave <@U-loki>'s debt of 30.00 nis")
+
+	assert.Error(t, h.EditDebtAmount(ctx, "U-host", "ABC", "U-odin", 0))
+	require.NoError(t, h.EditDebtAmount(ctx, "U-host", "ABC", "U-odin", 15))
+	require.Len(t, store.debts, 1)
+	assert.Equal(t, "d2", store.debts[0].ID, "the debt keeps its ID")
+	assert.Equal(t, 15.0, store.debts[0].Amount)
+	assert.Contains(t, notification.messages, "C1: <@U-host> changed <@U-odin>'s debt for order ABC from 20.00 nis to 15.00 nis")
+
+	require.NoError(t, h.SetCohost("U-host", "U-loki"))
+	assert.Error(t, h.ReassignDebt(ctx, "U-loki", "ABC", "Odin", "U-host"), "the host can't owe themselves")
+	require.NoError(t, h.ReassignDebt(ctx, "U-loki", "ABC", "Odin", "U-frigg"))
+	require.Len(t, store.debts, 1)
+	assert.Equal(t, "uuid-frigg", store.debts[0].BorrowerID)
+	assert.Equal(t, 15.0, store.debts[0].Amount)
+	assert.Contains(t, notification.messages, "U-frigg: <@U-loki> moved \"Odin\"'s debt of 15.00 nis for order ABC to you")
+}
+
+func TestReassignDebtMerges(t *testing.T) {
+	t.Parallel()
+
+	store := &fakeTreasuryStore{
+		users: map[string]*userDomain.User{
+			"uuid-host": {ID: "uuid-host", FullName: "Thor", TransportID: "U-host"},
+			"uuid-loki": {ID: "uuid-loki", FullName: "Loki", TransportID: "U-loki"},
+			"uuid-odin": {ID: "uuid-odin", FullName: "Odin", TransportID: "U-odin"},
+		},
+		debts: []*debtDomain.Debt{
+			{ID: "d1", BorrowerID: "uuid-loki", LenderID: "uuid-host", OrderID: "ABC", Amount: 30},
+			{ID: "d2", BorrowerID: "uuid-odin", LenderID: "uuid-host", OrderID: "ABC", Amount: 20},
+		},
+	}
+	h, err := New(Config{}, store, store, nil, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+
+	require.NoError(t, h.ReassignDebt(context.Background(), "U-host", "ABC", "Odin", "U-loki"))
+	require.Len(t, store.debts, 1)
+	assert.Equal(t, "d1", store.debts[0].ID)
+	assert.Equal(t, 50.0, store.debts[0].Amount)
+}
+
+func TestRateAdjustments(t *testing.T) {
+	t.Parallel()
+
+	h, err := New(Config{}, nil, nil, nil, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+
+	odin := &userDomain.User{ID: "uuid-odin", TransportID: "U-odin"}
+	groupRate := GroupRate{
+		HostWoltUser: "Thor",
+		Rates: []Rate{
+			{WoltName: "Thor", Amount: 40},
+			{WoltName: "Loki", User: &userDomain.User{ID: "uuid-loki", TransportID: "U-loki"}, Amount: 30},
+			{WoltName: "Odin", Amount: 20},
+		},
+	}
+
+	_, ok := h.rateAdjustments.apply("ABC", groupRate)
+	assert.False(t, ok)
+
+	amount := 15.0
+	h.rateAdjustments.update("ABC", "Loki", func(adjustment *rateAdjustment) { adjustment.forgiven = true })
+	h.rateAdjustments.update("ABC", "Odin", func(adjustment *rateAdjustment) { adjustment.user = odin })
+	h.rateAdjustments.update("ABC", "Odin", func(adjustment *rateAdjustment) { adjustment.amount = &amount })
+	adjusted, ok := h.rateAdjustments.apply("ABC", groupRate)
+	require.True(t, ok)
+	assert.Nil(t, groupRate.Rates[2].User, "the given rates aren't changed")
+
+	message := h.buildRatesMessage("C1", adjusted, "ABC")
+	assert.Contains(t, message, "<@U-loki> (Loki): 30.00 (forgiven by the host)\n")
+	assert.Contains(t, message, "<@U-odin> (Odin): 15.00 (adjusted by the host)\n")
+
+	h.rateAdjustments.remove("ABC")
+	_, ok = h.rateAdjustments.apply("ABC", groupRate)
+	assert.False(t, ok)
+}
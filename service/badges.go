@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	"github.com/oriser/bolt/order"
+)
+
+const badgesSchedulerInterval = time.Minute
+
+// Badge is an achievement of a user in a channel, computed from the channel's orders and payments history
+type Badge struct {
+	Emoji       string
+	Name        string
+	Description string
+	qualifies   func(stats *badgeStats) bool
+}
+
+var Badges = []Badge{
+	{Emoji: ":crown:", Name: "Generous host", Description: "hosted 10 orders", qualifies: func(stats *badgeStats) bool { return stats.hosted >= 10 }},
+	{Emoji: ":zap:", Name: "Quick payer", Description: "paid within an hour 20 times", qualifies: func(stats *badgeStats) bool { return stats.quickPayments >= 20 }},
+	{Emoji: ":compass:", Name: "Explorer", Description: "ordered from 15 different venues", qualifies: func(stats *badgeStats) bool { return len(stats.venues) >= 15 }},
+}
+
+type badgeStats struct {
+	hosted        int
+	quickPayments int
+	venues        map[string]bool
+}
+
+// recordPayment keeps the paid debt for the badges statistics, if the debt store supports it
+func (h *Service) recordPayment(ctx context.Context, event Event) {
+	paymentStore, ok := h.debtStore.(debtDomain.PaymentStore)
+	if !ok || event.Debt == nil {
+		return
+	}
+	if err := paymentStore.AddPayment(ctx, &debtDomain.Payment{Debt: *event.Debt, PaidAt: event.Time}); err != nil {
+		h.logger.Error("Error recording payment", "debt_id", event.Debt.ID, "error", err)
+	}
+}
+
+// badgesStats returns the statistics of each user (by user ID) in the channel until the given time
+func (h *Service) badgesStats(ctx context.Context, channel string, until time.Time) (map[string]*badgeStats, error) {
+	stats := make(map[string]*badgeStats)
+	statsFor := func(userID string) *badgeStats {
+		if _, ok := stats[userID]; !ok {
+			stats[userID] = &badgeStats{venues: make(map[string]bool)}
+		}
+		return stats[userID]
+	}
+
+	orders, err := h.orderStore.ListOrders(ctx, order.ListFilter{Receiver: channel})
+	if err != nil {
+		return nil, fmt.Errorf("list orders: %w", err)
+	}
+	for _, o := range orders {
+		if !o.CreatedAt.Before(until) || o.Status != order.StatusDone {
+			continue
+		}
+		for _, p := range o.Participants {
+			if p.ID == "" {
+				continue
+			}
+			userStats := statsFor(p.ID)
+			venue := o.VenueID
+			if venue == "" {
+				venue = o.VenueName
+			}
+			userStats.venues[venue] = true
+			if p.Name == o.Host {
+				userStats.hosted++
+			}
+		}
+	}
+
+	if paymentStore, ok := h.debtStore.(debtDomain.PaymentStore); ok {
+		payments, err := paymentStore.ListPayments(ctx, debtDomain.PaymentListFilter{Channel: channel, PaidBefore: until})
+		if err != nil {
+			return nil, fmt.Errorf("list payments: %w", err)
+		}
+		for _, p := range payments {
+			if p.PaidAt.Sub(p.CreatedAt) <= time.Hour {
+				statsFor(p.BorrowerID).quickPayments++
+			}
+		}
+	}
+	return stats, nil
+}
+
+// EarnedBadges returns the badges each user (by user ID) earned in the channel between from and to
+func (h *Service) EarnedBadges(ctx context.Context, channel string, from, to time.Time) (map[string][]Badge, error) {
+	if h.orderStore == nil {
+		return nil, fmt.Errorf("no order store")
+	}
+	before, err := h.badgesStats(ctx, channel, from)
+	if err != nil {
+		return nil, fmt.Errorf("stats until %s: %w", from, err)
+	}
+	after, err := h.badgesStats(ctx, channel, to)
+	if err != nil {
+		return nil, fmt.Errorf("stats until %s: %w", to, err)
+	}
+
+	earned := make(map[string][]Badge)
+	for userID, stats := range after {
+		for _, badge := range Badges {
+			if !badge.qualifies(stats) {
+				continue
+			}
+			if previous, ok := before[userID]; ok && badge.qualifies(previous) {
+				continue
+			}
+			earned[userID] = append(earned[userID], badge)
+		}
+	}
+	return earned, nil
+}
+
+func (h *Service) announceBadges(ctx context.Context, channel string, from, to time.Time) {
+	earned, err := h.EarnedBadges(ctx, channel, from, to)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "Error getting earned badges", "channel", channel, "error", err)
+		return
+	}
+	if len(earned) == 0 {
+		return
+	}
+
+	lines := make([]string, 0, len(earned))
+	for userID, badges := range earned {
+		mention := userID
+		if u, err := h.userStore.GetUser(ctx, userID); err == nil {
+			mention = fmt.Sprintf("<@%s>", u.TransportID)
+		}
+		badgesStr := make([]string, len(badges))
+		for i, badge := range badges {
+			badgesStr[i] = fmt.Sprintf("%s %s (%s)", badge.Emoji, badge.Name, badge.Description)
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", mention, strings.Join(badgesStr, ", ")))
+	}
+	sort.Strings(lines)
+
+	message := fmt.Sprintf(":trophy: Badges earned in %s:\n%s", from.Format("January"), strings.Join(lines, "\n"))
+	if err := h.informNonUrgent(channel, message, ""); err != nil {
+		h.logger.ErrorContext(ctx, "Error announcing badges", "channel", channel, "error", err)
+	}
+}
+
+// monthStart returns the start of the month of the given time, in its location
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
+
+// RunBadgesAnnouncer announces the badges earned in the previous month, on the first day of every month at BADGES_ANNOUNCE_HOUR
+// in each of the BADGES_CHANNELS, until the context is done
+func (h *Service) RunBadgesAnnouncer(ctx context.Context) {
+	if len(h.cfg.BadgesChannels) == 0 || h.orderStore == nil {
+		return
+	}
+
+	ticker := time.NewTicker(badgesSchedulerInterval)
+	defer ticker.Stop()
+
+	h.schedulers.beat("badges announcer", badgesSchedulerInterval)
+	lastCheck := time.Now()
+	for {
+		select {
+		case now := <-ticker.C:
+			for _, channel := range h.cfg.BadgesChannels {
+				tz := h.timezoneForChannel(channel, nil)
+				announceAt := monthStart(now.In(tz)).Add(time.Duration(h.cfg.BadgesAnnounceHour) * time.Hour)
+				if announceAt.After(lastCheck) && !announceAt.After(now) {
+					h.announceBadges(ctx, channel, monthStart(announceAt.AddDate(0, -1, 0)), monthStart(announceAt))
+				}
+			}
+			lastCheck = now
+			h.schedulers.beat("badges announcer", badgesSchedulerInterval)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	"github.com/oriser/bolt/order"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEarnedBadges(t *testing.T) {
+	t.Parallel()
+
+	may := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	june := may.AddDate(0, 1, 0)
+	store := &fakeTreasuryStore{users: map[string]*userDomain.User{
+		"U1": {ID: "U1", TransportID: "S1"},
+		"U2": {ID: "U2", TransportID: "S2"},
+	}}
+	orderStore := &fakeOrderStore{}
+	for i := 0; i < 15; i++ {
+		o := &order.Order{
+			Receiver:  "C1",
+			VenueID:   fmt.Sprintf("venue-%d", i),
+			Host:      "Thor",
+			Status:    order.StatusDone,
+			CreatedAt: may.Add(time.Duration(i) * time.Hour),
+			Participants: []order.Participant{
+				{Name: "Loki", ID: "U2", Amount: 10},
+			},
+		}
+		if i < 10 {
+			o.Participants = append(o.Participants, order.Participant{Name: "Thor", ID: "U1", Amount: 10})
+		}
+		if i == 0 {
+			// The first order is before May, so the host already had some
+			o.CreatedAt = may.Add(-time.Hour)
+		}
+		orderStore.orders = append(orderStore.orders, o)
+	}
+	for i := 0; i < 21; i++ {
+		createdAt := may.Add(time.Duration(i) * time.Hour)
+		paidAfter := 10 * time.Minute
+		if i == 0 {
+			// Slow payment
+			paidAfter = 2 * time.Hour
+		}
+		store.payments = append(store.payments, &debtDomain.Payment{
+			Debt:   debtDomain.Debt{ID: fmt.Sprintf("%d", i), BorrowerID: "U2", LenderID: "U1", InitiatedTransportID: "C1", CreatedAt: createdAt},
+			PaidAt: createdAt.Add(paidAfter),
+		})
+	}
+
+	notification := &recordingNotification{}
+	h, err := New(Config{}, store, store, orderStore, "U-bot", notification)
+	require.NoError(t, err)
+
+	earned, err := h.EarnedBadges(context.Background(), "C1", may, june)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"U1": {"Generous host"}, "U2": {"Quick payer", "Explorer"}}, badgeNames(earned))
+
+	earned, err = h.EarnedBadges(context.Background(), "C1", june, june.AddDate(0, 1, 0))
+	require.NoError(t, err)
+	assert.Empty(t, earned, "badges are announced only once")
+
+	earned, err = h.EarnedBadges(context.Background(), "C2", may, june)
+	require.NoError(t, err)
+	assert.Empty(t, earned, "badges are per channel")
+
+	h.announceBadges(context.Background(), "C1", may, june)
+	assert.Equal(t, []string{"C1: :trophy: Badges earned in May:\n" +
+		"<@S1>: :crown: Generous host (hosted 10 orders)\n" +
+		"<@S2>: :zap: Quick payer (paid within an hour 20 times), :compass: Explorer (ordered from 15 different venues)"}, notification.messages)
+
+	h.hooks.Emit(context.Background(), Event{Type: EventDebtPaid, Debt: &debtDomain.Debt{ID: "new"}})
+	assert.Len(t, store.payments, 22, "paid debts should be recorded")
+}
+
+func badgeNames(earned map[string][]Badge) map[string][]string {
+	names := make(map[string][]string, len(earned))
+	for userID, badges := range earned {
+		for _, badge := range badges {
+			names[userID] = append(names[userID], badge.Name)
+		}
+	}
+	return names
+}
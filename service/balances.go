@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	userDomain "github.com/oriser/bolt/user"
+)
+
+// Balance is what a borrower owes a lender after netting their mutual debts across orders
+type Balance struct {
+	BorrowerID string
+	LenderID   string
+	Borrower   *userDomain.User // nil if the user can't be found
+	Lender     *userDomain.User // nil if the user can't be found
+	Amount     float64
+	Currency   string // The debts are netted per currency, so a pair of users has a balance in each currency they have debts in
+	DebtsCount int    // How many debts (in both directions) were netted into the balance
+}
+
+// NetDebts nets the mutual debts of every pair of users in each currency, so A owing B 30 and B owing A 20 is A owing B 10. Debts in
+// different currencies aren't netted. Pairs whose debts cancel each other out are dropped. The balances are sorted from the highest
+// amount.
+func NetDebts(debts []*debtDomain.Debt) []Balance {
+	type pair struct{ a, b, currency string }
+	balances := make(map[pair]*Balance)
+	for _, d := range debts {
+		if d.BorrowerID == d.LenderID {
+			continue
+		}
+		currency := d.Currency
+		if currency == "" {
+			currency = DefaultCurrency
+		}
+		// Each pair is kept once, in the direction of its lower user ID, and the amount is negative if it goes the other way
+		key, amount := pair{d.BorrowerID, d.LenderID, currency}, d.Amount
+		if d.LenderID < d.BorrowerID {
+			key, amount = pair{d.LenderID, d.BorrowerID, currency}, -d.Amount
+		}
+		balance, ok := balances[key]
+		if !ok {
+			balance = &Balance{BorrowerID: key.a, LenderID: key.b, Currency: currency}
+			balances[key] = balance
+		}
+		balance.Amount += amount
+		balance.DebtsCount++
+	}
+
+	ret := make([]Balance, 0, len(balances))
+	for _, balance := range balances {
+		balance.Amount = math.Round(balance.Amount*100) / 100
+		if balance.Amount == 0 {
+			continue
+		}
+		if balance.Amount < 0 {
+			balance.BorrowerID, balance.LenderID, balance.Amount = balance.LenderID, balance.BorrowerID, -balance.Amount
+		}
+		ret = append(ret, *balance)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Amount != ret[j].Amount {
+			return ret[i].Amount > ret[j].Amount
+		}
+		if ret[i].BorrowerID != ret[j].BorrowerID {
+			return ret[i].BorrowerID < ret[j].BorrowerID
+		}
+		if ret[i].LenderID != ret[j].LenderID {
+			return ret[i].LenderID < ret[j].LenderID
+		}
+		return ret[i].Currency < ret[j].Currency
+	})
+	return ret
+}
+
+// ChannelBalances returns who owes whom in the channel, after netting the mutual outstanding debts of its orders
+func (h *Service) ChannelBalances(ctx context.Context, channel string) ([]Balance, error) {
+	if h.debtStore == nil {
+		return nil, fmt.Errorf("no debt store")
+	}
+	var balances []Balance
+	err := h.readChannelDebts(ctx, channel, func(debts map[string]*debtDomain.Debt) {
+		list := make([]*debtDomain.Debt, 0, len(debts))
+		for _, debt := range debts {
+			list = append(list, debt)
+		}
+		balances = NetDebts(list)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	users := make(map[string]*userDomain.User)
+	getUser := func(id string) *userDomain.User {
+		if u, ok := users[id]; ok {
+			return u
+		}
+		u, err := h.userStore.GetUser(ctx, id)
+		if err != nil {
+			h.logger.ErrorContext(ctx, "Error getting user of the balances", "user_id", id, "error", err)
+			u = nil
+		}
+		users[id] = u
+		return u
+	}
+	for i := range balances {
+		balances[i].Borrower = getUser(balances[i].BorrowerID)
+		balances[i].Lender = getUser(balances[i].LenderID)
+	}
+	return balances, nil
+}
+
+// balanceUserMention mentions the user of a balance, or shows its ID if the user can't be found
+func balanceUserMention(user *userDomain.User, id string) string {
+	if user == nil {
+		return id
+	}
+	return fmt.Sprintf("<@%s>", user.TransportID)
+}
+
+// BuildBalancesMessage returns the "who owes whom" summary of the balances
+func BuildBalancesMessage(balances []Balance) string {
+	if len(balances) == 0 {
+		return "Nobody owes anybody anything :tada:"
+	}
+	lines := make([]string, len(balances))
+	for i, balance := range balances {
+		lines[i] = fmt.Sprintf("%s owes %s %s", balanceUserMention(balance.Borrower, balance.BorrowerID),
+			balanceUserMention(balance.Lender, balance.LenderID), FormatAmount(balance.Amount, balance.Currency))
+	}
+	return fmt.Sprintf(":ledger: Who owes whom (mutual debts are netted):\n%s", strings.Join(lines, "\n"))
+}
+
+func (h *Service) postBalancesDigest(ctx context.Context, channel string) {
+	balances, err := h.ChannelBalances(ctx, channel)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "Error getting the balances", "channel", channel, "error", err)
+		return
+	}
+	if len(balances) == 0 {
+		return
+	}
+	if err := h.informNonUrgent(channel, BuildBalancesMessage(balances), ""); err != nil {
+		h.logger.ErrorContext(ctx, "Error posting the balances digest", "channel", channel, "error", err)
+	}
+}
+
+func parseWeekday(name string) (time.Weekday, error) {
+	if name == "" {
+		return time.Sunday, nil
+	}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(day.String(), name) || strings.EqualFold(day.String()[:3], name) {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown weekday %q", name)
+}
+
+// weekStart returns the start of the week of the given time which starts on the given weekday, in its location
+func weekStart(t time.Time, weekday time.Weekday) time.Time {
+	daysSince := (int(t.Weekday()) - int(weekday) + 7) % 7
+	return dayStart(t).AddDate(0, 0, -daysSince)
+}
+
+// RunBalancesDigest posts who owes whom in each of the BALANCES_DIGEST_CHANNELS, every BALANCES_DIGEST_WEEKDAY at
+// BALANCES_DIGEST_HOUR, until the context is done
+func (h *Service) RunBalancesDigest(ctx context.Context) {
+	if len(h.cfg.BalancesDigestChannels) == 0 || h.debtStore == nil {
+		return
+	}
+
+	ticker := time.NewTicker(badgesSchedulerInterval)
+	defer ticker.Stop()
+
+	h.schedulers.beat("balances digest", badgesSchedulerInterval)
+	lastCheck := time.Now()
+	for {
+		select {
+		case now := <-ticker.C:
+			for _, channel := range h.cfg.BalancesDigestChannels {
+				tz := h.timezoneForChannel(channel, nil)
+				postAt := weekStart(now.In(tz), h.balancesDigestWeekday).Add(time.Duration(h.cfg.BalancesDigestHour) * time.Hour)
+				if postAt.After(lastCheck) && !postAt.After(now) {
+					h.postBalancesDigest(ctx, channel)
+				}
+			}
+			lastCheck = now
+			h.schedulers.beat("balances digest", badgesSchedulerInterval)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
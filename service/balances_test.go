@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetDebts(t *testing.T) {
+	t.Parallel()
+
+	balances := NetDebts([]*debtDomain.Debt{
+		{BorrowerID: "A", LenderID: "B", Amount: 30},
+		{BorrowerID: "B", LenderID: "A", Amount: 20},
+		{BorrowerID: "C", LenderID: "B", Amount: 15.5},
+		{BorrowerID: "B", LenderID: "C", Amount: 15.5},
+		{BorrowerID: "D", LenderID: "A", Amount: 12},
+		{BorrowerID: "A", LenderID: "D", Amount: 40},
+		{BorrowerID: "E", LenderID: "E", Amount: 10},
+		{BorrowerID: "B", LenderID: "A", Amount: 5, Currency: "EUR"},
+		{BorrowerID: "C", LenderID: "B", Amount: 3, Currency: "EUR"},
+		{BorrowerID: "B", LenderID: "C", Amount: 3, Currency: DefaultCurrency},
+	})
+	assert.Equal(t, []Balance{
+		{BorrowerID: "A", LenderID: "D", Amount: 28, Currency: DefaultCurrency, DebtsCount: 2},
+		{BorrowerID: "A", LenderID: "B", Amount: 10, Currency: DefaultCurrency, DebtsCount: 2},
+		{BorrowerID: "B", LenderID: "A", Amount: 5, Currency: "EUR", DebtsCount: 1},
+		{BorrowerID: "B", LenderID: "C", Amount: 3, Currency: DefaultCurrency, DebtsCount: 3},
+		{BorrowerID: "C", LenderID: "B", Amount: 3, Currency: "EUR", DebtsCount: 1},
+	}, balances, "debts in different currencies aren't netted")
+}
+
+func TestChannelBalances(t *testing.T) {
+	t.Parallel()
+
+	notification := &recordingNotification{}
+	store := &fakeTreasuryStore{
+		debts: []*debtDomain.Debt{
+			{ID: "1", BorrowerID: "U1", LenderID: "U2", OrderID: "A", Amount: 30, InitiatedTransportID: "C1"},
+			{ID: "2", BorrowerID: "U2", LenderID: "U1", OrderID: "B", Amount: 20, InitiatedTransportID: "C1"},
+			{ID: "3", BorrowerID: "U3", LenderID: "U2", OrderID: "B", Amount: 5, InitiatedTransportID: "C1"},
+			{ID: "4", BorrowerID: "U2", LenderID: "U1", OrderID: "C", Amount: 100, InitiatedTransportID: "C2"},
+		},
+		users: map[string]*userDomain.User{
+			"U1": {ID: "U1", TransportID: "S1"},
+			"U2": {ID: "U2", TransportID: "S2"},
+		},
+	}
+	h, err := New(Config{}, store, store, nil, "U-bot", notification)
+	require.NoError(t, err)
+
+	h.postBalancesDigest(context.Background(), "C1")
+	assert.Equal(t, []string{"C1: :ledger: Who owes whom (mutual debts are netted):\n<@S1> owes <@S2> 10.00 nis\nU3 owes <@S2> 5.00 nis"},
+		notification.messages)
+
+	h.postBalancesDigest(context.Background(), "C3")
+	assert.Len(t, notification.messages, 1, "no digest should be posted without outstanding debts")
+}
+
+func TestWeekStart(t *testing.T) {
+	t.Parallel()
+
+	wednesday := time.Date(2024, 5, 15, 13, 30, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2024, 5, 12, 0, 0, 0, 0, time.UTC), weekStart(wednesday, time.Sunday))
+	assert.Equal(t, time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC), weekStart(wednesday, time.Wednesday))
+	assert.Equal(t, time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC), weekStart(wednesday, time.Friday))
+
+	_, err := parseWeekday("Someday")
+	assert.ErrorContains(t, err, "unknown weekday")
+	weekday, err := parseWeekday("friday")
+	require.NoError(t, err)
+	assert.Equal(t, time.Friday, weekday)
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	userDomain "github.com/oriser/bolt/user"
+)
+
+func (h *Service) bankAccountStore() (userDomain.BankAccountStore, error) {
+	store, ok := h.userStore.(userDomain.BankAccountStore)
+	if !ok {
+		return nil, fmt.Errorf("bank accounts are not supported")
+	}
+	return store, nil
+}
+
+// SetBankAccount registers the bank account the user (by transport ID) is paid to by bank transfer, validating the IBAN. An empty
+// IBAN removes the user's account.
+func (h *Service) SetBankAccount(ctx context.Context, transportID, iban, holder string) (*userDomain.BankAccount, error) {
+	store, err := h.bankAccountStore()
+	if err != nil {
+		return nil, err
+	}
+	var account *userDomain.BankAccount
+	if iban != "" {
+		if account, err = userDomain.ParseBankAccount(holder, iban); err != nil {
+			return nil, err
+		}
+	}
+	if err := store.SetBankAccount(ctx, transportID, account); err != nil {
+		return nil, fmt.Errorf("set bank account: %w", err)
+	}
+	return account, nil
+}
+
+// BankAccount returns the bank account the user (by transport ID) registered, or nil if they didn't register one
+func (h *Service) BankAccount(ctx context.Context, transportID string) (*userDomain.BankAccount, error) {
+	store, err := h.bankAccountStore()
+	if err != nil {
+		return nil, err
+	}
+	account, err := store.BankAccount(ctx, transportID)
+	if err != nil {
+		return nil, fmt.Errorf("get bank account: %w", err)
+	}
+	return account, nil
+}
+
+// loadBankAccount sets the bank account the user registered, if the user store supports bank accounts
+func (h *Service) loadBankAccount(user *userDomain.User) {
+	store, err := h.bankAccountStore()
+	if err != nil {
+		return
+	}
+	ctx, cancel := h.storeContext()
+	defer cancel()
+	if user.BankAccount, err = store.BankAccount(ctx, user.TransportID); err != nil {
+		h.logger.Error("Error getting the bank account", "transport_id", user.TransportID, "error", err)
+	}
+}
+
+// hostBankAccount returns the host's bank account if they prefer to be paid by bank transfer, and nil otherwise
+func hostBankAccount(host *userDomain.User) *userDomain.BankAccount {
+	if host == nil || host.BankAccount == nil {
+		return nil
+	}
+	for _, method := range host.PreferredPaymentMethods() {
+		if method == userDomain.PaymentMethodBankTransfer {
+			return host.BankAccount
+		}
+	}
+	return nil
+}
+
+// bankAccountMessage returns the line of the rates message with the host's masked bank account, the full one is only sent in a
+// direct message to who clicks the reveal button, see revealBankAccount
+func (h *Service) bankAccountMessage(channel string, host *userDomain.User) string {
+	account := hostBankAccount(host)
+	if account == nil {
+		return ""
+	}
+	return h.text(channel, msgBankAccount, account.Holder, account.Masked())
+}
+
+// revealBankAccountButton returns the button sending the host's full bank account in a direct message, or nil if the host isn't paid
+// by bank transfer
+func (h *Service) revealBankAccountButton(channel, groupID string, host *userDomain.User) *MessageButton {
+	if hostBankAccount(host) == nil {
+		return nil
+	}
+	return &MessageButton{
+		Label:  h.text(channel, msgRevealBankAccount),
+		Action: ButtonActionRevealBankAccount,
+		Value:  fmt.Sprintf("%s:%s", groupID, host.TransportID),
+	}
+}
+
+// revealBankAccount sends the full bank account of the host to the user in a direct message, and returns the response to show the
+// user in the channel
+func (h *Service) revealBankAccount(channel, hostTransportID, transportID string) (string, error) {
+	store, err := h.bankAccountStore()
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := h.storeContext()
+	defer cancel()
+	account, err := store.BankAccount(ctx, hostTransportID)
+	if err != nil {
+		return "", fmt.Errorf("get bank account: %w", err)
+	}
+	if account == nil {
+		return fmt.Sprintf("<@%s> removed their bank account", hostTransportID), nil
+	}
+	details := h.text(channel, msgBankAccountDetails, fmt.Sprintf("<@%s>", hostTransportID), account.Holder, account.Formatted())
+	if _, err := h.informEvent(transportID, details, "", ""); err != nil {
+		return "", fmt.Errorf("send bank account: %w", err)
+	}
+	return "I sent you the bank details in a direct message", nil
+}
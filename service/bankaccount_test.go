@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeBankAccountStore struct {
+	*fakeTreasuryStore
+	accounts map[string]*userDomain.BankAccount
+}
+
+func (f *fakeBankAccountStore) SetBankAccount(_ context.Context, transportID string, account *userDomain.BankAccount) error {
+	if account == nil {
+		delete(f.accounts, transportID)
+		return nil
+	}
+	f.accounts[transportID] = account
+	return nil
+}
+
+func (f *fakeBankAccountStore) BankAccount(_ context.Context, transportID string) (*userDomain.BankAccount, error) {
+	return f.accounts[transportID], nil
+}
+
+func TestParseBankAccount(t *testing.T) {
+	t.Parallel()
+
+	account, err := userDomain.ParseBankAccount(" Thor Odinson ", "il62 0108 0000 0009 9999 999")
+	require.NoError(t, err)
+	assert.Equal(t, &userDomain.BankAccount{Holder: "Thor Odinson", IBAN: "IL620108000000099999999"}, account)
+	assert.Equal(t, "IL62 0108 0000 0009 9999 999", account.Formatted())
+	assert.Equal(t, "IL62 **** 9999", account.Masked())
+
+	_, err = userDomain.ParseBankAccount("Thor", "IL630108000000099999999")
+	assert.ErrorContains(t, err, "invalid check digits")
+	_, err = userDomain.ParseBankAccount("Thor", "IL62")
+	assert.ErrorContains(t, err, "between 15 and 34 characters")
+	_, err = userDomain.ParseBankAccount("", "IL620108000000099999999")
+	assert.ErrorContains(t, err, "missing account holder")
+}
+
+func TestBankAccountInRates(t *testing.T) {
+	t.Parallel()
+
+	treasuryStore := &fakeTreasuryStore{users: map[string]*userDomain.User{
+		"u1": {ID: "u1", FullName: "Thor", TransportID: "U1",
+			PaymentPreferences: []userDomain.PaymentMethod{userDomain.PaymentMethodBankTransfer}},
+		"u2": {ID: "u2", FullName: "Loki", TransportID: "U2"},
+	}}
+	store := &fakeBankAccountStore{fakeTreasuryStore: treasuryStore, accounts: make(map[string]*userDomain.BankAccount)}
+	notification := &recordingNotification{}
+	h, err := New(Config{Locale: "en"}, store, treasuryStore, nil, "UBOT", notification)
+	require.NoError(t, err)
+	_, err = h.SetBankAccount(context.Background(), "U1", "IL62 0108 0000 0009 9999 999", "Thor Odinson")
+	require.NoError(t, err)
+
+	groupRate := h.buildGroupRates(map[string]float64{"Thor": 50, "Loki": 30}, "Thor", 0)
+	message := h.buildRatesMessage("C1", groupRate, "ABC")
+	assert.Contains(t, message, "Bank transfer to Thor Odinson: IL62 **** 9999\n")
+	assert.NotContains(t, message, "0009", "the full account isn't posted")
+
+	interactive := h.buildRatesInteractiveMessage("C1", groupRate, "ABC", "")
+	require.NotEmpty(t, interactive.Buttons)
+	assert.Equal(t, MessageButton{Label: "Show bank details", Action: ButtonActionRevealBankAccount, Value: "ABC:U1"}, interactive.Buttons[0])
+
+	response, err := h.HandleButtonAction(ButtonActionRequest{Action: ButtonActionRevealBankAccount, Value: "ABC:U1", FromUserID: "U2", Channel: "C1"})
+	require.NoError(t, err)
+	assert.Equal(t, "I sent you the bank details in a direct message", response)
+	assert.Equal(t, []string{"U2: Bank transfer details of <@U1>:\nAccount holder: Thor Odinson\nIBAN: IL62 0108 0000 0009 9999 999"},
+		notification.messages)
+
+	treasuryStore.users["u1"].PaymentPreferences = []userDomain.PaymentMethod{userDomain.PaymentMethodBit}
+	groupRate = h.buildGroupRates(map[string]float64{"Thor": 50, "Loki": 30}, "Thor", 0)
+	assert.False(t, strings.Contains(h.buildRatesMessage("C1", groupRate, "ABC"), "Bank transfer to"),
+		"the account is shown only to hosts preferring bank transfers")
+}
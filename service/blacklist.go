@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oriser/bolt/order"
+)
+
+var errNotConfirmed = errors.New("blacklisted venue wasn't confirmed")
+
+// blacklistConfirmations keeps the warnings about blacklisted venues (and orders sent too late) which wait for a confirmation reaction,
+// by their channel and message ID
+type blacklistConfirmations struct {
+	lock    sync.Mutex
+	pending map[string]chan struct{}
+}
+
+func newBlacklistConfirmations() *blacklistConfirmations {
+	return &blacklistConfirmations{pending: make(map[string]chan struct{})}
+}
+
+func (b *blacklistConfirmations) add(channel, messageID string) chan struct{} {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	confirmed := make(chan struct{})
+	b.pending[skipKey(channel, messageID)] = confirmed
+	return confirmed
+}
+
+// confirm confirms the warning, and returns false if it isn't waiting for a confirmation
+func (b *blacklistConfirmations) confirm(channel, messageID string) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	key := skipKey(channel, messageID)
+	confirmed, ok := b.pending[key]
+	if !ok {
+		return false
+	}
+	close(confirmed)
+	delete(b.pending, key)
+	return true
+}
+
+func (b *blacklistConfirmations) remove(channel, messageID string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	delete(b.pending, skipKey(channel, messageID))
+}
+
+func (h *Service) blacklistStore() (order.BlacklistStore, error) {
+	blacklistStore, ok := h.orderStore.(order.BlacklistStore)
+	if !ok {
+		return nil, fmt.Errorf("venues blacklist is not supported")
+	}
+	return blacklistStore, nil
+}
+
+// BlacklistVenue adds the venue to the channel's blacklist, so orders from it will require a confirmation before being tracked
+func (h *Service) BlacklistVenue(ctx context.Context, channel, venueName, reason, userID string) error {
+	blacklistStore, err := h.blacklistStore()
+	if err != nil {
+		return err
+	}
+	venueName, reason = strings.TrimSpace(venueName), strings.TrimSpace(reason)
+	if venueName == "" || reason == "" {
+		return fmt.Errorf("venue name and reason are required")
+	}
+	if err := blacklistStore.BlacklistVenue(ctx, &order.BlacklistedVenue{
+		Channel:   channel,
+		VenueName: venueName,
+		Reason:    reason,
+		AddedBy:   userID,
+		CreatedAt: time.Now(),
+	}); err != nil {
+		return fmt.Errorf("blacklist venue: %w", err)
+	}
+	return nil
+}
+
+// UnblacklistVenue removes the venue from the channel's blacklist
+func (h *Service) UnblacklistVenue(ctx context.Context, channel, venueName string) error {
+	blacklistStore, err := h.blacklistStore()
+	if err != nil {
+		return err
+	}
+	removed, err := blacklistStore.UnblacklistVenue(ctx, channel, strings.TrimSpace(venueName))
+	if err != nil {
+		return fmt.Errorf("unblacklist venue: %w", err)
+	}
+	if !removed {
+		return fmt.Errorf("venue %q is not blacklisted", venueName)
+	}
+	return nil
+}
+
+// BlacklistedVenues returns the blacklisted venues of the channel
+func (h *Service) BlacklistedVenues(ctx context.Context, channel string) ([]*order.BlacklistedVenue, error) {
+	blacklistStore, err := h.blacklistStore()
+	if err != nil {
+		return nil, err
+	}
+	venues, err := blacklistStore.ListBlacklistedVenues(ctx, channel)
+	if err != nil {
+		return nil, fmt.Errorf("list blacklisted venues: %w", err)
+	}
+	return venues, nil
+}
+
+// blacklistedVenue returns the channel's blacklist entry of the venue, or nil if it isn't blacklisted
+func (h *Service) blacklistedVenue(channel, venueName string) *order.BlacklistedVenue {
+	blacklistStore, ok := h.orderStore.(order.BlacklistStore)
+	if !ok {
+		return nil
+	}
+	ctx, cancel := h.storeContext()
+	defer cancel()
+	venues, err := blacklistStore.ListBlacklistedVenues(ctx, channel)
+	if err != nil {
+		h.logger.Error("Error listing blacklisted venues", "channel", channel, "error", err)
+		return nil
+	}
+	for _, venue := range venues {
+		if strings.EqualFold(venue.VenueName, venueName) {
+			return venue
+		}
+	}
+	return nil
+}
+
+// confirmBlacklistedVenue warns about an order from a blacklisted venue, and waits for someone to confirm tracking it by reacting to the warning
+func (h *Service) confirmBlacklistedVenue(channel, messageID string, venue *order.BlacklistedVenue) error {
+	warning := fmt.Sprintf(":warning: [%s] is blacklisted in this channel: %s\nReact with :%s: to this message if you still want me to track this order",
+		venue.VenueName, venue.Reason, h.channelEmoji(channel, settingBlacklistConfirmEmoji, h.cfg.BlacklistConfirmationEmoji))
+	warningID, err := h.informEvent(channel, warning, "", messageID)
+	if err != nil {
+		return fmt.Errorf("inform blacklisted venue: %w", err)
+	}
+	confirmed := h.blacklistConfirmations.add(channel, warningID)
+	defer h.blacklistConfirmations.remove(channel, warningID)
+
+	select {
+	case <-confirmed:
+		return nil
+	case <-time.After(h.cfg.BlacklistConfirmationTimeout):
+		_, _ = h.informEvent(channel, h.text(channel, msgNoOneConfirmed), "", messageID)
+		return errNotConfirmed
+	}
+}
+
+func (h *Service) handleBlacklistConfirmation(req ReactionAddRequest) bool {
+	if req.FromUserID == h.selfID {
+		return false
+	}
+	return h.blacklistConfirmations.confirm(req.Channel, req.MessageID)
+}
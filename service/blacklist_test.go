@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oriser/bolt/order"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeBlacklistStore struct {
+	fakeOrderStore
+	venues []*order.BlacklistedVenue
+}
+
+func (f *fakeBlacklistStore) BlacklistVenue(_ context.Context, venue *order.BlacklistedVenue) error {
+	f.venues = append(f.venues, venue)
+	return nil
+}
+
+func (f *fakeBlacklistStore) UnblacklistVenue(_ context.Context, channel, venueName string) (bool, error) {
+	for i, venue := range f.venues {
+		if venue.Channel == channel && strings.EqualFold(venue.VenueName, venueName) {
+			f.venues = append(f.venues[:i], f.venues[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeBlacklistStore) ListBlacklistedVenues(_ context.Context, channel string) ([]*order.BlacklistedVenue, error) {
+	venues := make([]*order.BlacklistedVenue, 0)
+	for _, venue := range f.venues {
+		if venue.Channel == channel {
+			venues = append(venues, venue)
+		}
+	}
+	return venues, nil
+}
+
+func TestVenueBlacklist(t *testing.T) {
+	t.Parallel()
+
+	store := &fakeBlacklistStore{}
+	h, err := New(Config{}, nil, nil, store, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	assert.Error(t, h.BlacklistVenue(ctx, "C1", "Pizza Place", " ", "U1"), "a reason is required")
+	require.NoError(t, h.BlacklistVenue(ctx, "C1", "Pizza Place", "cold pizza", "U1"))
+	assert.Equal(t, "cold pizza", h.blacklistedVenue("C1", "pizza place").Reason)
+	assert.Nil(t, h.blacklistedVenue("C2", "Pizza Place"), "venues are blacklisted per channel")
+
+	venues, err := h.BlacklistedVenues(ctx, "C1")
+	require.NoError(t, err)
+	assert.Len(t, venues, 1)
+
+	assert.Error(t, h.UnblacklistVenue(ctx, "C2", "Pizza Place"))
+	require.NoError(t, h.UnblacklistVenue(ctx, "C1", "Pizza Place"))
+	assert.Nil(t, h.blacklistedVenue("C1", "Pizza Place"))
+
+	h, err = New(Config{}, nil, nil, &fakeOrderStore{}, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+	assert.Error(t, h.BlacklistVenue(ctx, "C1", "Pizza Place", "cold pizza", "U1"), "the blacklist isn't supported by the store")
+}
+
+func TestConfirmBlacklistedVenue(t *testing.T) {
+	t.Parallel()
+
+	notification := &editingNotification{}
+	h, err := New(Config{
+
+		BlacklistConfirmationEmoji:   "white_check_mark",
+		BlacklistConfirmationTimeout: time.Minute,
+	}, nil, nil, nil, "UBOT", notification)
+	require.NoError(t, err)
+	venue := &order.BlacklistedVenue{Channel: "C1", VenueName: "Pizza Place", Reason: "cold pizza"}
+
+	confirmed := make(chan error, 1)
+	go func() {
+		confirmed <- h.confirmBlacklistedVenue("C1", "1.1", venue)
+	}()
+	require.Eventually(t, func() bool {
+		h.blacklistConfirmations.lock.Lock()
+		defer h.blacklistConfirmations.lock.Unlock()
+		return len(h.blacklistConfirmations.pending) == 1
+	}, time.Second, time.Millisecond)
+
+	_, err = h.HandleReactionAdded(ReactionAddRequest{Reaction: "white_check_mark", FromUserID: "UBOT", Channel: "C1", MessageID: "sent-1"})
+	require.NoError(t, err)
+	_, err = h.HandleReactionAdded(ReactionAddRequest{Reaction: "white_check_mark", FromUserID: "U1", Channel: "C1", MessageID: "other"})
+	require.NoError(t, err)
+	select {
+	case <-confirmed:
+		t.Fatal("confirmed by a reaction of the bot or to another message")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	_, err = h.HandleReactionAdded(ReactionAddRequest{Reaction: "white_check_mark", FromUserID: "U1", Channel: "C1", MessageID: "sent-1"})
+	require.NoError(t, err)
+	assert.NoError(t, <-confirmed)
+	assert.Equal(t, []string{"C1: :warning: [Pizza Place] is blacklisted in this channel: cold pizza\n" +
+		"React with :white_check_mark: to this message if you still want me to track this order"}, notification.messages)
+
+	h.cfg.BlacklistConfirmationTimeout = time.Millisecond
+	assert.ErrorIs(t, h.confirmBlacklistedVenue("C1", "1.1", venue), errNotConfirmed)
+	assert.Equal(t, "C1: No one confirmed, I won't track this order", notification.messages[len(notification.messages)-1])
+}
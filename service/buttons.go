@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	userDomain "github.com/oriser/bolt/user"
+)
+
+// Actions of the buttons of interactive messages
+const (
+	ButtonActionMarkPaid          = "mark_paid"           // The value is <order ID>:<borrower transport ID>
+	ButtonActionCancelTracking    = "cancel_tracking"     // The value is the order ID
+	ButtonActionConfirmPayment    = "confirm_payment"     // The value is <order ID>:<debt ID>
+	ButtonActionRejectPayment     = "reject_payment"      // The value is <order ID>:<debt ID>
+	ButtonActionRevealBankAccount = "reveal_bank_account" // The value is <order ID>:<host transport ID>
+)
+
+// MessageButton is a button of an interactive message, either doing an action or opening a link
+type MessageButton struct {
+	Label  string
+	Action string // One of the ButtonAction values, empty for link buttons
+	Value  string // Passed back with the action
+	URL    string // The link a link button opens
+	Danger bool   // The action can't be undone
+}
+
+// MessageSection is a part of an interactive message, with an optional button next to it
+type MessageSection struct {
+	Text   string
+	Button *MessageButton
+}
+
+// InteractiveMessage is a message made of sections, with buttons below them
+type InteractiveMessage struct {
+	Text     string // The plain text of the whole message, for notifications and reading it back
+	Sections []MessageSection
+	Buttons  []MessageButton
+}
+
+// InteractiveMessenger is implemented by notification layers which can send messages with buttons. The clicks are handled with
+// HandleButtonAction.
+type InteractiveMessenger interface {
+	SendInteractiveMessage(receiver string, message InteractiveMessage, messageID string) (string, error)
+	EditInteractiveMessage(receiver string, message InteractiveMessage, messageID string) error
+}
+
+// ButtonActionRequest is a click on a button of an interactive message
+type ButtonActionRequest struct {
+	Action     string
+	Value      string
+	FromUserID string
+	Channel    string
+	MessageID  string
+}
+
+// parsePaymentLinks parses <payment method>=<URL> pairs. The URLs can include {phone}, which is replaced with the host's phone, and
+// {amount} and {currency}, which pre-fill the amount each participant pays, see ratePaymentLink.
+func parsePaymentLinks(pairs []string) (map[userDomain.PaymentMethod]string, error) {
+	links := make(map[userDomain.PaymentMethod]string, len(pairs))
+	for _, pair := range pairs {
+		name, link, ok := strings.Cut(pair, "=")
+		if !ok || name == "" || link == "" {
+			return nil, fmt.Errorf("expected <payment method>=<URL> but got %q", pair)
+		}
+		method, err := userDomain.ParsePaymentMethod(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := url.Parse(link); err != nil {
+			return nil, fmt.Errorf("link of %s: %w", method, err)
+		}
+		links[method] = link
+	}
+	return links, nil
+}
+
+// interactiveRates returns whether the rates messages are sent with buttons
+func (h *Service) interactiveRates() bool {
+	if !h.cfg.RatesButtons {
+		return false
+	}
+	_, ok := h.eventNotification.(InteractiveMessenger)
+	return ok
+}
+
+// paymentLinkButton returns the button of the link to pay the host with the first of their preferred payment methods which has a
+// link without an amount in PAYMENT_LINKS, or nil if none has. The links with amounts are next to the rates instead.
+func (h *Service) paymentLinkButton(channel string, host *userDomain.User) *MessageButton {
+	if host == nil {
+		return nil
+	}
+	for _, method := range host.PreferredPaymentMethods() {
+		template, ok := h.paymentLinks[method]
+		if !ok || linkWithAmount(template) {
+			continue
+		}
+		link, ok := buildPaymentLink(template, host, 0, "")
+		if !ok {
+			continue
+		}
+		return &MessageButton{Label: h.text(channel, msgPayWith, method), URL: link}
+	}
+	return nil
+}
+
+// buildRatesInteractiveMessage returns the rates message with a "Mark paid" button next to the rate of every participant who owes the
+// host, and the payment link and "Cancel tracking" buttons below it. Compact messages have no "Mark paid" buttons. The progress is
+// appended to the message if it isn't empty.
+func (h *Service) buildRatesInteractiveMessage(channel string, groupRate GroupRate, groupID, progress string) InteractiveMessage {
+	view := h.RatesView(channel, groupRate, groupID)
+	header, lines, footer := view.Header, view.lines(), view.Footer
+	compact := h.compactRates(groupRate)
+	// The rows continued in other messages aren't in the interactive message, see buildRatesMessages
+	rows := splitRateRows(header, h.rateRows(groupRate, lines), footer, h.text(channel, msgRatesContinued, groupID), h.cfg.RatesMessageMaxLength)[0]
+	message := InteractiveMessage{Text: header + strings.Join(rows, "") + footer}
+	if progress != "" {
+		footer = strings.TrimSuffix(footer, "\n") + "\n\n" + progress
+		message.Text = strings.TrimSuffix(message.Text, "\n") + "\n\n" + progress
+	}
+
+	message.Sections = append(message.Sections, MessageSection{Text: header})
+	for i, row := range rows {
+		section := MessageSection{Text: row}
+		if rate := groupRate.Rates[i]; !compact && !groupRate.CompanyPaid && !rate.Forgiven && rate.User != nil &&
+			(groupRate.HostUser == nil || rate.User.ID != groupRate.HostUser.ID) {
+			section.Button = &MessageButton{
+				Label:  h.text(channel, msgMarkPaid),
+				Action: ButtonActionMarkPaid,
+				Value:  fmt.Sprintf("%s:%s", groupID, rate.User.TransportID),
+			}
+		}
+		message.Sections = append(message.Sections, section)
+	}
+	message.Sections = append(message.Sections, MessageSection{Text: footer})
+
+	if groupRate.CompanyPaid {
+		return message
+	}
+	if button := h.paymentLinkButton(channel, groupRate.HostUser); button != nil {
+		message.Buttons = append(message.Buttons, *button)
+	}
+	if button := h.revealBankAccountButton(channel, groupID, groupRate.HostUser); button != nil {
+		message.Buttons = append(message.Buttons, *button)
+	}
+	message.Buttons = append(message.Buttons, MessageButton{
+		Label:  h.text(channel, msgCancelTracking),
+		Action: ButtonActionCancelTracking,
+		Value:  groupID,
+		Danger: true,
+	})
+	return message
+}
+
+// sendRatesMessage sends the rates message, with buttons if RATES_BUTTONS is set and the notification layer supports them
+func (h *Service) sendRatesMessage(ctx context.Context, channel string, groupRate GroupRate, groupID, ratesMessage, reaction, messageID string) (string, error) {
+	if !h.interactiveRates() {
+		return h.informEditableEvent(ctx, channel, ratesMessage, reaction, messageID)
+	}
+	ratesMessageID, err := h.eventNotification.(InteractiveMessenger).SendInteractiveMessage(channel,
+		h.buildRatesInteractiveMessage(channel, groupRate, groupID, ""), messageID)
+	if err != nil {
+		h.logger.Error("Error sending the rates message with buttons, sending it as text", "group_id", groupID, "channel", channel, "error", err)
+		return h.informEditableEvent(ctx, channel, ratesMessage, reaction, messageID)
+	}
+	if reaction != "" {
+		// Reacting is still supported next to the buttons
+		if err := h.eventNotification.AddReaction(channel, ratesMessageID, reaction); err != nil {
+			return ratesMessageID, fmt.Errorf("error adding reaction to message %s: %w", ratesMessageID, err)
+		}
+	}
+	return ratesMessageID, nil
+}
+
+// editRatesMessage edits the rates message of the order and the messages continuing it, appending the progress to the rates message
+// if it isn't empty. The host's adjustments of the debts of the order are applied to the rates.
+func (h *Service) editRatesMessage(channel string, order *groupOrder, groupRate GroupRate, ratesMessage, progress string) error {
+	if adjusted, ok := h.rateAdjustments.apply(order.id, groupRate); ok {
+		groupRate = adjusted
+		ratesMessage = h.buildRatesMessage(channel, groupRate, order.id)
+	}
+	h.syncRatesContinuations(channel, order, h.buildRatesMessages(channel, groupRate, order.id)[1:])
+	if h.interactiveRates() {
+		err := h.eventNotification.(InteractiveMessenger).EditInteractiveMessage(channel,
+			h.buildRatesInteractiveMessage(channel, groupRate, order.id, progress), order.detailsMessageId)
+		if err == nil {
+			return nil
+		}
+		h.logger.ErrorContext(order.ctx, "Error editing the rates message with buttons, editing it as text", "error", err)
+	}
+	if progress != "" {
+		ratesMessage = strings.TrimSuffix(ratesMessage, "\n") + "\n\n" + progress
+	}
+	return h.editEvent(channel, h.orderLabel(channel, order.messageID)+ratesMessage, order.detailsMessageId)
+}
+
+// HandleButtonAction handles a click on a button of an interactive message. It returns a response to show only to the user who
+// clicked, if any.
+func (h *Service) HandleButtonAction(req ButtonActionRequest) (string, error) {
+	h.recordActivity(req.FromUserID)
+	ctx, cancel := h.storeContext()
+	defer cancel()
+	switch req.Action {
+	case ButtonActionMarkPaid:
+		orderID, borrower, ok := strings.Cut(req.Value, ":")
+		if !ok {
+			return "", fmt.Errorf("bad mark paid value %q", req.Value)
+		}
+		if borrower != req.FromUserID {
+			return fmt.Sprintf("Only <@%s> can mark this debt as paid", borrower), nil
+		}
+		if err := h.markDebtAsPaid(ctx, orderID, req.FromUserID, req.Channel); err != nil {
+			return "", fmt.Errorf("mark debt as paid: %w", err)
+		}
+		return "", nil
+	case ButtonActionCancelTracking:
+		h.cancelDebtsTracking(ctx, req.Value, req.FromUserID)
+		return "", nil
+	case ButtonActionConfirmPayment, ButtonActionRejectPayment:
+		orderID, debtID, ok := strings.Cut(req.Value, ":")
+		if !ok {
+			return "", fmt.Errorf("bad payment confirmation value %q", req.Value)
+		}
+		response, err := h.resolvePaymentClaim(ctx, orderID, debtID, req.FromUserID, req.Action == ButtonActionConfirmPayment)
+		if err != nil {
+			return "", fmt.Errorf("resolve payment claim: %w", err)
+		}
+		return response, nil
+	case ButtonActionRevealBankAccount:
+		_, host, ok := strings.Cut(req.Value, ":")
+		if !ok {
+			return "", fmt.Errorf("bad reveal bank account value %q", req.Value)
+		}
+		response, err := h.revealBankAccount(req.Channel, host, req.FromUserID)
+		if err != nil {
+			return "", fmt.Errorf("reveal bank account: %w", err)
+		}
+		return response, nil
+	default:
+		h.logger.Warn("Got unknown button action, ignoring", "action", req.Action)
+		return "", nil
+	}
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"testing"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type interactiveNotification struct {
+	recordingNotification
+	interactive []InteractiveMessage
+}
+
+func (i *interactiveNotification) SendInteractiveMessage(_ string, message InteractiveMessage, _ string) (string, error) {
+	i.interactive = append(i.interactive, message)
+	return "ts-rates", nil
+}
+
+func (i *interactiveNotification) EditInteractiveMessage(_ string, message InteractiveMessage, _ string) error {
+	i.interactive = append(i.interactive, message)
+	return nil
+}
+
+func TestBuildRatesInteractiveMessage(t *testing.T) {
+	t.Parallel()
+
+	h, err := New(Config{RatesButtons: true, PaymentLinks: []string{"bit=https://pay.example/?phone={phone}"}},
+		nil, nil, nil, "UBOT", &interactiveNotification{})
+	require.NoError(t, err)
+	require.True(t, h.interactiveRates())
+
+	host := &userDomain.User{ID: "uuid-host", TransportID: "U-host", Phone: "+972500000000",
+		PaymentPreferences: []userDomain.PaymentMethod{userDomain.PaymentMethodPaybox, userDomain.PaymentMethodBit}}
+	groupRate := GroupRate{
+		HostWoltUser: "Thor",
+		HostUser:     host,
+		DeliveryRate: 10,
+		Rates: []Rate{
+			{WoltName: "Thor", User: host, Amount: 40},
+			{WoltName: "Loki", User: &userDomain.User{ID: "uuid-loki", TransportID: "U-loki"}, Amount: 30},
+			{WoltName: "Odin", Amount: 20},
+		},
+	}
+
+	message := h.buildRatesInteractiveMessage("C1", groupRate, "ABC", "")
+	assert.Equal(t, h.buildRatesMessage("C1", groupRate, "ABC"), message.Text)
+	require.Len(t, message.Sections, 5)
+	assert.Equal(t, "Rates for Wolt order ID ABC (including 10 NIS for delivery):\n", message.Sections[0].Text)
+	assert.Nil(t, message.Sections[1].Button, "the host doesn't owe anyone")
+	assert.Equal(t, &MessageButton{Label: "Mark paid", Action: ButtonActionMarkPaid, Value: "ABC:U-loki"}, message.Sections[2].Button)
+	assert.Nil(t, message.Sections[3].Button, "unknown participants can't be marked as paid")
+	assert.Equal(t, []MessageButton{
+		{Label: "Pay with Bit", URL: "https://pay.example/?phone=%2B972500000000"},
+		{Label: "Cancel tracking", Action: ButtonActionCancelTracking, Value: "ABC", Danger: true},
+	}, message.Buttons)
+
+	message = h.buildRatesInteractiveMessage("C1", groupRate, "ABC", "progress")
+	assert.Contains(t, message.Sections[4].Text, "\n\nprogress")
+	assert.Contains(t, message.Text, "\n\nprogress")
+
+	groupRate.CompanyPaid = true
+	message = h.buildRatesInteractiveMessage("C1", groupRate, "ABC", "")
+	assert.Nil(t, message.Sections[2].Button)
+	assert.Empty(t, message.Buttons)
+}
+
+func TestHandleButtonAction(t *testing.T) {
+	t.Parallel()
+
+	notification := &recordingNotification{}
+	store := &fakeTreasuryStore{
+		users: map[string]*userDomain.User{
+			"uuid-host": {ID: "uuid-host", FullName: "Thor", TransportID: "U-host"},
+			"uuid-loki": {ID: "uuid-loki", FullName: "Loki", TransportID: "U-loki"},
+		},
+		debts: []*debtDomain.Debt{
+			{ID: "d1", BorrowerID: "uuid-loki", LenderID: "uuid-host", OrderID: "ABC", Amount: 30},
+		},
+	}
+	h, err := New(Config{}, store, store, nil, "UBOT", notification)
+	require.NoError(t, err)
+
+	response, err := h.HandleButtonAction(ButtonActionRequest{Action: ButtonActionMarkPaid, Value: "ABC:U-loki", FromUserID: "U-host", Channel: "C1"})
+	require.NoError(t, err)
+	assert.Equal(t, "Only <@U-loki> can mark this debt as paid", response)
+	assert.Len(t, store.debts, 1)
+
+	response, err = h.HandleButtonAction(ButtonActionRequest{Action: ButtonActionCancelTracking, Value: "ABC", FromUserID: "U-loki", Channel: "C1"})
+	require.NoError(t, err)
+	assert.Empty(t, response)
+	assert.Len(t, store.debts, 1, "only the host can cancel tracking the debts")
+
+	response, err = h.HandleButtonAction(ButtonActionRequest{Action: ButtonActionMarkPaid, Value: "ABC:U-loki", FromUserID: "U-loki", Channel: "C1"})
+	require.NoError(t, err)
+	assert.Empty(t, response)
+	assert.Empty(t, store.debts)
+	assert.Contains(t, notification.messages, "U-loki: OK! I removed your debt for order ABC")
+}
+
+func TestParsePaymentLinks(t *testing.T) {
+	t.Parallel()
+
+	links, err := parsePaymentLinks([]string{"Bit=https://bit.example/{phone}", "pepper pay=https://pepper.example"})
+	require.NoError(t, err)
+	assert.Equal(t, map[userDomain.PaymentMethod]string{
+		userDomain.PaymentMethodBit:    "https://bit.example/{phone}",
+		userDomain.PaymentMethodPepper: "https://pepper.example",
+	}, links)
+
+	_, err = parsePaymentLinks([]string{"cash=https://example"})
+	assert.Error(t, err)
+	_, err = parsePaymentLinks([]string{"bit"})
+	assert.Error(t, err)
+}
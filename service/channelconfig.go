@@ -0,0 +1,142 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ConfigValue is a configuration value in effect for a channel
+type ConfigValue struct {
+	Name     string // The global configuration environment variable
+	Value    string
+	Override bool // Whether the value is the channel's override (e.g. from CHANNEL_LOCALES for LOCALE) rather than the global value
+}
+
+// ChannelConfig returns the configuration in effect for the channel: the order cutoffs, fees split, emojis, locale and reminders
+func (h *Service) ChannelConfig(channel string) []ConfigValue {
+	settings := h.channelSettings(channel)
+	overridden := func(name, global string) ConfigValue {
+		if value, ok := settings[name]; ok {
+			return ConfigValue{Name: name, Value: value, Override: true}
+		}
+		return ConfigValue{Name: name, Value: global}
+	}
+	dontJoinAfter := noCutoff
+	if h.cfg.DontJoinAfter != "" {
+		dontJoinAfter = h.cfg.DontJoinAfter
+	}
+	orderSchedule := noCutoff
+	if len(h.orderSchedule) > 0 {
+		orderSchedule = formatOrderSchedule(h.orderSchedule)
+	}
+	escalationStages := noCutoff
+	if len(h.escalationStages) > 0 {
+		escalationStages = formatEscalationStages(h.escalationStages)
+	}
+	_, timezoneOverride := h.channelTimezones[channel]
+	if _, ok := settings[settingDontJoinAfterTZ]; ok {
+		timezoneOverride = true
+	}
+	_, localeOverride := h.channelLocaleOverrides[channel]
+	configuredLocale, ok := h.channelLocaleOverrides[channel]
+	if !ok {
+		configuredLocale = h.defaultLocale
+	}
+	locale := string(configuredLocale)
+	if configuredLocale == LocaleAuto {
+		locale = fmt.Sprintf("%s (currently %s)", LocaleAuto, h.channelLocale(channel))
+	}
+	_, policyOverride := h.channelUnknownParticipantPolicies[channel]
+	subsidyExcluded := "none"
+	if len(h.subsidyExcludedCategories) > 0 {
+		categories := make([]string, len(h.subsidyExcludedCategories))
+		for i, category := range h.subsidyExcludedCategories {
+			categories[i] = string(category)
+		}
+		subsidyExcluded = strings.Join(categories, ", ")
+	}
+	badges := "off"
+	for _, badgesChannel := range h.cfg.BadgesChannels {
+		if badgesChannel == channel {
+			badges = "on"
+		}
+	}
+	balancesDigest := "off"
+	for _, digestChannel := range h.cfg.BalancesDigestChannels {
+		if digestChannel == channel {
+			balancesDigest = "on"
+		}
+	}
+	monthlyReport := "off"
+	for _, reportChannel := range h.cfg.MonthlyReportChannels {
+		if reportChannel == channel {
+			monthlyReport = "on"
+		}
+	}
+	social := "off"
+	for _, socialChannel := range h.cfg.SocialChannels {
+		if socialChannel == channel {
+			social = "on"
+		}
+	}
+	workHours := "any time"
+	if h.cfg.WorkHours != "" {
+		workHours = h.cfg.WorkHours
+	}
+	workDays := "every day"
+	if len(h.cfg.WorkDays) > 0 {
+		workDays = strings.Join(h.cfg.WorkDays, ", ")
+	}
+	rounding := "off"
+	if h.cfg.AmountRounding > 0 {
+		rounding = strconv.FormatFloat(h.cfg.AmountRounding, 'f', -1, 64)
+	}
+	deals := "off"
+	for _, dealsChannel := range h.cfg.DealsChannels {
+		if dealsChannel == channel {
+			deals = "on"
+		}
+	}
+
+	return []ConfigValue{
+		overridden(settingDontJoinAfter, dontJoinAfter),
+		{Name: "DONT_JOIN_AFTER_TZ", Value: h.timezoneForChannel(channel, nil).String(), Override: timezoneOverride},
+		overridden(settingOrderSchedule, orderSchedule),
+		{Name: "LATE_ORDER_CONFIRMATION", Value: strconv.FormatBool(h.cfg.LateOrderConfirmation)},
+		{Name: "WORK_HOURS", Value: workHours},
+		{Name: "WORK_DAYS", Value: workDays},
+		{Name: "SOCIAL_CHANNELS", Value: social},
+		{Name: "ORDER_READY_TIMEOUT", Value: h.cfg.TimeoutForReady.String()},
+		{Name: "ORDER_DONE_TIMEOUT", Value: h.cfg.OrderDoneTimeout.String()},
+		{Name: "SOLO_ORDERS", Value: strconv.FormatBool(h.cfg.SoloOrders)},
+		overridden(settingFeeAllocation, h.cfg.FeeAllocationStrategy),
+		{Name: "AMOUNT_ROUNDING", Value: rounding},
+		{Name: "ROUNDING_REMAINDER", Value: string(h.roundingRemainder)},
+		{Name: "DISCOUNT_ALLOCATION", Value: string(h.discountAllocation)},
+		{Name: "SUBSIDY_AMOUNT", Value: strconv.FormatFloat(h.cfg.SubsidyAmount, 'f', 2, 64)},
+		{Name: "SUBSIDY_EXCLUDED_CATEGORIES", Value: subsidyExcluded},
+		{Name: "SUBSIDY_PERCENT", Value: strconv.FormatFloat(h.cfg.SubsidyPercent, 'f', -1, 64)},
+		{Name: "SUBSIDY_MONTHLY_CAP", Value: strconv.FormatFloat(h.cfg.SubsidyMonthlyCap, 'f', 2, 64)},
+		{Name: "UNKNOWN_PARTICIPANT_POLICY", Value: string(h.unknownParticipantPolicy(channel)), Override: policyOverride},
+		{Name: "LOCALE", Value: locale, Override: localeOverride},
+		overridden(settingJoinedOrderEmoji, h.cfg.JoinedOrderEmoji),
+		overridden(settingSkipOrderEmoji, h.cfg.SkipOrderEmoji),
+		overridden(settingDestinationEmoji, h.cfg.OrderDestinationEmoji),
+		overridden(settingBlacklistConfirmEmoji, h.cfg.BlacklistConfirmationEmoji),
+		overridden(settingCompanyPaidEmoji, h.cfg.CompanyPaidEmoji),
+		overridden(settingPreauthThreshold, strconv.FormatFloat(h.cfg.PreauthThreshold, 'f', -1, 64)),
+		overridden(settingPreauthConfirmations, strconv.Itoa(h.cfg.PreauthConfirmations)),
+		{Name: "DEBT_REMINDER_INTERVAL", Value: h.cfg.DebtReminderInterval.String()},
+		{Name: "DEBT_MAXIMUM_DURATION", Value: h.cfg.DebtMaximumDuration.String()},
+		{Name: "DEBT_REMINDER_NOTIFY_HOST", Value: strconv.FormatBool(h.cfg.DebtReminderNotifyHost)},
+		{Name: "DEBT_DMS", Value: strconv.FormatBool(h.cfg.DebtDMs)},
+		{Name: "DEBT_REMINDER_SMART_TIMING", Value: strconv.FormatBool(h.cfg.DebtReminderSmartTiming)},
+		overridden(settingEscalationStages, escalationStages),
+		{Name: "BADGES_CHANNELS", Value: badges},
+		{Name: "DEALS_CHANNELS", Value: deals},
+		{Name: "BALANCES_DIGEST_CHANNELS", Value: balancesDigest},
+		{Name: "MONTHLY_REPORT_CHANNELS", Value: monthlyReport},
+		{Name: "HOSTING_NUDGE_ORDERS", Value: strconv.Itoa(h.cfg.HostingNudgeOrders)},
+	}
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelConfig(t *testing.T) {
+	t.Parallel()
+
+	h, err := New(Config{
+
+		DontJoinAfterTZ:          "Asia/Jerusalem",
+		ChannelTimezones:         []string{"C1=Europe/London"},
+		Locale:                   "en",
+		ChannelLocales:           []string{"C1=he"},
+		UnknownParticipantPolicy: "skip",
+		ChannelUnknownPolicies:   []string{"C1=pending"},
+		BadgesChannels:           []string{"C1"},
+	}, nil, nil, nil, "UBOT", nil)
+	require.NoError(t, err)
+
+	values := func(channel string) map[string]ConfigValue {
+		ret := make(map[string]ConfigValue)
+		for _, value := range h.ChannelConfig(channel) {
+			ret[value.Name] = value
+		}
+		return ret
+	}
+
+	overridden := values("C1")
+	assert.Equal(t, ConfigValue{Name: "DONT_JOIN_AFTER_TZ", Value: "Europe/London", Override: true}, overridden["DONT_JOIN_AFTER_TZ"])
+	assert.Equal(t, ConfigValue{Name: "LOCALE", Value: "he", Override: true}, overridden["LOCALE"])
+	assert.Equal(t, ConfigValue{Name: "UNKNOWN_PARTICIPANT_POLICY", Value: "pending", Override: true}, overridden["UNKNOWN_PARTICIPANT_POLICY"])
+	assert.Equal(t, ConfigValue{Name: "BADGES_CHANNELS", Value: "on"}, overridden["BADGES_CHANNELS"])
+	assert.Equal(t, ConfigValue{Name: "FEE_ALLOCATION_STRATEGY", Value: "equal"}, overridden["FEE_ALLOCATION_STRATEGY"])
+
+	global := values("C2")
+	assert.Equal(t, ConfigValue{Name: "DONT_JOIN_AFTER_TZ", Value: "Asia/Jerusalem"}, global["DONT_JOIN_AFTER_TZ"])
+	assert.Equal(t, ConfigValue{Name: "LOCALE", Value: "en"}, global["LOCALE"])
+	assert.Equal(t, ConfigValue{Name: "UNKNOWN_PARTICIPANT_POLICY", Value: "skip"}, global["UNKNOWN_PARTICIPANT_POLICY"])
+	assert.Equal(t, ConfigValue{Name: "BADGES_CHANNELS", Value: "off"}, global["BADGES_CHANNELS"])
+	assert.Equal(t, ConfigValue{Name: "DONT_JOIN_AFTER", Value: "none"}, global["DONT_JOIN_AFTER"])
+}
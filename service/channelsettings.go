@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oriser/bolt/order"
+)
+
+// channelSettingsTTL is how long the overrides of a channel are cached, so changes made by other Bolt processes take effect
+const channelSettingsTTL = time.Minute
+
+// Settings channels can override
+const (
+	settingDontJoinAfter         = "DONT_JOIN_AFTER"
+	settingDontJoinAfterTZ       = "DONT_JOIN_AFTER_TZ"
+	settingOrderSchedule         = "ORDER_SCHEDULE"
+	settingFeeAllocation         = "FEE_ALLOCATION_STRATEGY"
+	settingJoinedOrderEmoji      = "JOINED_ORDER_EMOJI"
+	settingSkipOrderEmoji        = "SKIP_ORDER_EMOJI"
+	settingDestinationEmoji      = "ORDER_DESTINATION_EMOJI"
+	settingBlacklistConfirmEmoji = "BLACKLIST_CONFIRMATION_EMOJI"
+	settingCompanyPaidEmoji      = "COMPANY_PAID_EMOJI"
+	settingPreauthThreshold      = "PREAUTH_THRESHOLD"
+	settingPreauthConfirmations  = "PREAUTH_CONFIRMATIONS"
+	settingEscalationStages      = "DEBT_ESCALATION_STAGES"
+)
+
+// noCutoff is the DONT_JOIN_AFTER override of channels which track orders at any hour
+const noCutoff = "none"
+
+// channelSettingParsers validate the values of the settings channels can override, returning them in their canonical form
+var channelSettingParsers = map[string]func(value string) (string, error){
+	settingDontJoinAfter: func(value string) (string, error) {
+		if strings.EqualFold(value, noCutoff) {
+			return noCutoff, nil
+		}
+		clock, err := ParseClock(value)
+		if err != nil {
+			return "", err
+		}
+		return clock.Format("15:04"), nil
+	},
+	settingDontJoinAfterTZ: func(value string) (string, error) {
+		tz, err := ParseTimezone(value)
+		if err != nil {
+			return "", err
+		}
+		return tz.String(), nil
+	},
+	settingOrderSchedule: parseOrderScheduleSetting,
+	settingFeeAllocation: func(value string) (string, error) {
+		if _, err := FeeAllocatorByName(value); err != nil {
+			return "", err
+		}
+		return value, nil
+	},
+	settingJoinedOrderEmoji:      parseEmojiName,
+	settingSkipOrderEmoji:        parseEmojiName,
+	settingDestinationEmoji:      parseEmojiName,
+	settingBlacklistConfirmEmoji: parseEmojiName,
+	settingCompanyPaidEmoji:      parseEmojiName,
+	settingPreauthThreshold:      parsePreauthThreshold,
+	settingPreauthConfirmations:  parsePreauthConfirmations,
+	settingEscalationStages:      parseEscalationSetting,
+	settingAccessibleMessages:    parseBoolSetting,
+}
+
+// ChannelSettingNames returns the names of the settings channels can override
+func ChannelSettingNames() []string {
+	names := make([]string, 0, len(channelSettingParsers))
+	for name := range channelSettingParsers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseEmojiName accepts an emoji with or without its surrounding colons, and returns its name
+func parseEmojiName(value string) (string, error) {
+	name := strings.Trim(value, ":")
+	if name == "" || strings.ContainsAny(name, ": \t") {
+		return "", fmt.Errorf("expected an emoji like :eyes: but got %q", value)
+	}
+	return name, nil
+}
+
+// channelSettingsCache caches the overrides of the channels, so handling messages and reactions doesn't read the store each time
+type channelSettingsCache struct {
+	lock     sync.Mutex
+	values   map[string]map[string]string // By channel, then by setting name
+	loadedAt map[string]time.Time
+}
+
+func newChannelSettingsCache() *channelSettingsCache {
+	return &channelSettingsCache{values: make(map[string]map[string]string), loadedAt: make(map[string]time.Time)}
+}
+
+func (c *channelSettingsCache) get(channel string) (map[string]string, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if time.Since(c.loadedAt[channel]) > channelSettingsTTL {
+		return nil, false
+	}
+	return c.values[channel], true
+}
+
+func (c *channelSettingsCache) set(channel string, values map[string]string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.values[channel] = values
+	c.loadedAt[channel] = time.Now()
+}
+
+func (c *channelSettingsCache) invalidate(channel string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.values, channel)
+	delete(c.loadedAt, channel)
+}
+
+func (h *Service) channelSettingsStore() (order.ChannelSettingsStore, error) {
+	channelSettingsStore, ok := h.orderStore.(order.ChannelSettingsStore)
+	if !ok {
+		return nil, fmt.Errorf("channel settings are not supported")
+	}
+	return channelSettingsStore, nil
+}
+
+// channelSettings returns the overrides of the channel by their names, empty if the channel has none or they can't be read
+func (h *Service) channelSettings(channel string) map[string]string {
+	store, err := h.channelSettingsStore()
+	if err != nil || channel == "" {
+		return nil
+	}
+	if values, ok := h.settingsCache.get(channel); ok {
+		return values
+	}
+
+	ctx, cancel := h.storeContext()
+	defer cancel()
+	settings, err := store.ListChannelSettings(ctx, channel)
+	if err != nil {
+		h.logger.Error("Error listing the channel settings", "channel", channel, "error", err)
+		return nil
+	}
+	values := make(map[string]string, len(settings))
+	for _, setting := range settings {
+		values[setting.Name] = setting.Value
+	}
+	h.settingsCache.set(channel, values)
+	return values
+}
+
+// channelSetting returns the channel's override of the setting, if it has one
+func (h *Service) channelSetting(channel, name string) (string, bool) {
+	value, ok := h.channelSettings(channel)[name]
+	return value, ok
+}
+
+// SetChannelSetting overrides the global configuration value in the channel, and returns the value as it was set
+func (h *Service) SetChannelSetting(ctx context.Context, channel, name, value, userID string) (string, error) {
+	store, err := h.channelSettingsStore()
+	if err != nil {
+		return "", err
+	}
+	name = strings.ToUpper(strings.TrimSpace(name))
+	parse, ok := channelSettingParsers[name]
+	if !ok {
+		return "", fmt.Errorf("%s can't be set per channel (available: %s)", name, strings.Join(ChannelSettingNames(), ", "))
+	}
+	if value, err = parse(strings.TrimSpace(value)); err != nil {
+		return "", fmt.Errorf("invalid %s: %w", name, err)
+	}
+	if name == settingEscalationStages {
+		if err := h.checkEscalationSetting(value); err != nil {
+			return "", fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+
+	if err := store.SetChannelSetting(ctx, &order.ChannelSetting{
+		Channel:   channel,
+		Name:      name,
+		Value:     value,
+		UpdatedBy: userID,
+		UpdatedAt: time.Now(),
+	}); err != nil {
+		return "", fmt.Errorf("set channel setting: %w", err)
+	}
+	h.settingsCache.invalidate(channel)
+	return value, nil
+}
+
+// RemoveChannelSetting removes the channel's override of the setting, so the global configuration value applies to it again
+func (h *Service) RemoveChannelSetting(ctx context.Context, channel, name string) error {
+	store, err := h.channelSettingsStore()
+	if err != nil {
+		return err
+	}
+	name = strings.ToUpper(strings.TrimSpace(name))
+	removed, err := store.RemoveChannelSetting(ctx, channel, name)
+	if err != nil {
+		return fmt.Errorf("remove channel setting: %w", err)
+	}
+	h.settingsCache.invalidate(channel)
+	if !removed {
+		return fmt.Errorf("%s isn't overridden in this channel", name)
+	}
+	return nil
+}
+
+// channelEmoji returns the channel's override of the emoji setting, or the global emoji
+func (h *Service) channelEmoji(channel, name, global string) string {
+	if emoji, ok := h.channelSetting(channel, name); ok {
+		return emoji
+	}
+	return global
+}
+
+// channelCutoff returns the time of day after which orders of the channel aren't tracked without a confirmation, and the timezone
+// it's in. The time is zero if the channel has no cutoff.
+func (h *Service) channelCutoff(channel string) (time.Time, *time.Location) {
+	settings := h.channelSettings(channel)
+	cutoff := h.dontJoinAfter
+	if value, ok := settings[settingDontJoinAfter]; ok {
+		cutoff = time.Time{}
+		if value != noCutoff {
+			cutoff, _ = ParseClock(value)
+		}
+	}
+	tz := h.dontJoinAfterTZ
+	if name, ok := settings[settingDontJoinAfterTZ]; ok {
+		if overridden, err := ParseTimezone(name); err == nil {
+			tz = overridden
+		}
+	}
+	return cutoff, tz
+}
+
+// channelFeeAllocator returns the fee allocator of the channel's override of the fee allocation strategy, or the global one
+func (h *Service) channelFeeAllocator(channel string) FeeAllocator {
+	if name, ok := h.channelSetting(channel, settingFeeAllocation); ok {
+		if allocator, err := FeeAllocatorByName(name); err == nil {
+			return allocator
+		}
+	}
+	return h.feeAllocator
+}
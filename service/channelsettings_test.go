@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oriser/bolt/order"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeChannelSettingsStore struct {
+	fakeOrderStore
+	settings map[string]map[string]string
+}
+
+func (f *fakeChannelSettingsStore) SetChannelSetting(_ context.Context, setting *order.ChannelSetting) error {
+	if f.settings[setting.Channel] == nil {
+		f.settings[setting.Channel] = make(map[string]string)
+	}
+	f.settings[setting.Channel][setting.Name] = setting.Value
+	return nil
+}
+
+func (f *fakeChannelSettingsStore) RemoveChannelSetting(_ context.Context, channel, name string) (bool, error) {
+	if _, ok := f.settings[channel][name]; !ok {
+		return false, nil
+	}
+	delete(f.settings[channel], name)
+	return true, nil
+}
+
+func (f *fakeChannelSettingsStore) ListChannelSettings(_ context.Context, channel string) ([]*order.ChannelSetting, error) {
+	settings := make([]*order.ChannelSetting, 0)
+	for name, value := range f.settings[channel] {
+		settings = append(settings, &order.ChannelSetting{Channel: channel, Name: name, Value: value})
+	}
+	return settings, nil
+}
+
+func TestChannelSettings(t *testing.T) {
+	t.Parallel()
+
+	store := &fakeChannelSettingsStore{settings: make(map[string]map[string]string)}
+	h, err := New(Config{
+
+		DontJoinAfter:   "23:59",
+		DontJoinAfterTZ: "Asia/Jerusalem",
+		SkipOrderEmoji:  "no_entry_sign",
+	}, nil, nil, store, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, err = h.SetChannelSetting(ctx, "C1", "LOCALE", "he", "U1")
+	assert.Error(t, err, "only some settings can be overridden per channel")
+	_, err = h.SetChannelSetting(ctx, "C1", "DONT_JOIN_AFTER", "noon", "U1")
+	assert.Error(t, err)
+	_, err = h.SetChannelSetting(ctx, "C1", "FEE_ALLOCATION_STRATEGY", "nobody", "U1")
+	assert.Error(t, err)
+
+	value, err := h.SetChannelSetting(ctx, "C1", "skip_order_emoji", ":x:", "U1")
+	require.NoError(t, err)
+	assert.Equal(t, "x", value)
+	assert.Equal(t, "x", h.channelEmoji("C1", settingSkipOrderEmoji, h.cfg.SkipOrderEmoji))
+	assert.Equal(t, "no_entry_sign", h.channelEmoji("C2", settingSkipOrderEmoji, h.cfg.SkipOrderEmoji))
+
+	value, err = h.SetChannelSetting(ctx, "C1", "DONT_JOIN_AFTER", "None", "U1")
+	require.NoError(t, err)
+	assert.Equal(t, noCutoff, value)
+	assert.True(t, h.shouldHandleOrder("C1", ""), "the channel has no cutoff")
+	_, err = h.SetChannelSetting(ctx, "C1", "DONT_JOIN_AFTER", "0:00", "U1")
+	require.NoError(t, err)
+	assert.False(t, h.shouldHandleOrder("C1", ""))
+
+	_, err = h.SetChannelSetting(ctx, "C1", "DONT_JOIN_AFTER_TZ", "Europe/London", "U1")
+	require.NoError(t, err)
+	_, tz := h.channelCutoff("C1")
+	assert.Equal(t, "Europe/London", tz.String())
+	assert.Equal(t, "Europe/London", h.timezoneForChannel("C1", time.UTC).String())
+	assert.Equal(t, "Asia/Jerusalem", h.timezoneForChannel("C2", time.UTC).String())
+
+	_, err = h.SetChannelSetting(ctx, "C1", "FEE_ALLOCATION_STRATEGY", FeeAllocationHostAbsorbs, "U1")
+	require.NoError(t, err)
+	rates := h.channelFeeAllocator("C1").Allocate(map[string]float64{"Thor": 10, "Loki": 20}, "Thor", Fees{Delivery: 10})
+	assert.Equal(t, map[string]float64{"Thor": 20, "Loki": 20}, rates)
+
+	values := make(map[string]ConfigValue)
+	for _, value := range h.ChannelConfig("C1") {
+		values[value.Name] = value
+	}
+	assert.Equal(t, ConfigValue{Name: "SKIP_ORDER_EMOJI", Value: "x", Override: true}, values["SKIP_ORDER_EMOJI"])
+	assert.Equal(t, ConfigValue{Name: "DONT_JOIN_AFTER_TZ", Value: "Europe/London", Override: true}, values["DONT_JOIN_AFTER_TZ"])
+	assert.Equal(t, ConfigValue{Name: "JOINED_ORDER_EMOJI", Value: ""}, values["JOINED_ORDER_EMOJI"])
+
+	require.NoError(t, h.RemoveChannelSetting(ctx, "C1", "skip_order_emoji"))
+	assert.Equal(t, "no_entry_sign", h.channelEmoji("C1", settingSkipOrderEmoji, h.cfg.SkipOrderEmoji))
+	assert.Error(t, h.RemoveChannelSetting(ctx, "C1", "SKIP_ORDER_EMOJI"), "the setting isn't overridden anymore")
+}
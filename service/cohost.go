@@ -0,0 +1,77 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// cohosts keeps the co-host each host (by transport ID) designated, who can also do the host actions of their orders, for when the
+// host steps away before the delivery
+type cohosts struct {
+	lock   sync.RWMutex
+	byHost map[string]string
+}
+
+func newCohosts() *cohosts {
+	return &cohosts{byHost: make(map[string]string)}
+}
+
+func (c *cohosts) set(host, cohost string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if cohost == "" {
+		delete(c.byHost, host)
+		return
+	}
+	c.byHost[host] = cohost
+}
+
+func (c *cohosts) get(host string) string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.byHost[host]
+}
+
+// hostsOf returns the hosts who designated the user as their co-host, sorted
+func (c *cohosts) hostsOf(cohost string) []string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	hosts := make([]string, 0)
+	for host, designated := range c.byHost {
+		if designated == cohost {
+			hosts = append(hosts, host)
+		}
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// SetCohost designates the co-host of the host (by transport IDs), who can also cancel the debts of the host's orders, mark them as
+// paid by the company and link their unknown participants. An empty co-host removes the designation.
+func (h *Service) SetCohost(hostTransportID, cohostTransportID string) error {
+	if cohostTransportID == hostTransportID {
+		return fmt.Errorf("the host can't be their own co-host")
+	}
+	h.cohosts.set(hostTransportID, cohostTransportID)
+	if cohostTransportID != "" {
+		_, _ = h.informInteractiveEvent(cohostTransportID, fmt.Sprintf("<@%s> made you their co-host, so you can also do the host actions of their orders (like reacting with :%s: to cancel their debts) while they're away",
+			hostTransportID, HostRemoveDebts), "")
+	}
+	return nil
+}
+
+// Cohost returns the co-host the host (by transport ID) designated, or an empty string if there's none
+func (h *Service) Cohost(hostTransportID string) string {
+	return h.cohosts.get(hostTransportID)
+}
+
+// actsForHost returns whether the user (by transport ID) is the host or the host's co-host
+func (h *Service) actsForHost(hostTransportID, transportID string) bool {
+	return transportID == hostTransportID || (transportID != "" && h.cohosts.get(hostTransportID) == transportID)
+}
+
+// hostsActedFor returns the hosts the user (by transport ID) can do the host actions of: the user and the hosts they co-host
+func (h *Service) hostsActedFor(transportID string) []string {
+	return append([]string{transportID}, h.cohosts.hostsOf(transportID)...)
+}
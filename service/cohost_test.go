@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCohost(t *testing.T) {
+	t.Parallel()
+
+	notification := &recordingNotification{}
+	store := &fakeTreasuryStore{
+		users: map[string]*userDomain.User{
+			"uuid-host": {ID: "uuid-host", FullName: "Thor", TransportID: "U-host"},
+			"uuid-loki": {ID: "uuid-loki", FullName: "Loki", TransportID: "U-loki"},
+			"uuid-odin": {ID: "uuid-odin", FullName: "Odin", TransportID: "U-odin"},
+		},
+		debts: []*debtDomain.Debt{
+			{ID: "d1", BorrowerID: "uuid-loki", LenderID: "uuid-host", OrderID: "ABC", Amount: 30},
+		},
+	}
+	h, err := New(Config{}, store, store, nil, "UBOT", notification)
+	require.NoError(t, err)
+
+	assert.Error(t, h.SetCohost("U-host", "U-host"))
+	require.NoError(t, h.SetCohost("U-host", "U-odin"))
+	assert.Equal(t, "U-odin", h.Cohost("U-host"))
+	assert.Equal(t, []string{"U-odin", "U-host"}, h.hostsActedFor("U-odin"))
+	require.Len(t, notification.messages, 1)
+	assert.Contains(t, notification.messages[0], "U-odin: <@U-host> made you their co-host")
+
+	h.cancelDebtsTracking(context.Background(), "ABC", "U-loki")
+	assert.Len(t, store.debts, 1, "only the host and their co-host can cancel the debts")
+
+	h.cancelDebtsTracking(context.Background(), "ABC", "U-odin")
+	assert.Empty(t, store.debts)
+
+	require.NoError(t, h.SetCohost("U-host", ""))
+	assert.Empty(t, h.Cohost("U-host"))
+	assert.False(t, h.actsForHost("U-host", "U-odin"))
+	assert.True(t, h.actsForHost("U-host", "U-host"))
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"strings"
+)
+
+const compactRatesPerRow = 4
+
+// compactRates returns whether the rates message of the group is compact, for groups of more than RATES_COMPACT_THRESHOLD participants
+func (h *Service) compactRates(groupRate GroupRate) bool {
+	return h.cfg.RatesCompactThreshold > 0 && len(groupRate.Rates) > h.cfg.RatesCompactThreshold
+}
+
+// rateRows returns the rows of the rates of the rates message: the given line of each rate, or compactRatesPerRow rates per row if
+// the message is compact
+func (h *Service) rateRows(groupRate GroupRate, lines []string) []string {
+	if !h.compactRates(groupRate) {
+		return lines
+	}
+	rows := make([]string, 0, (len(lines)+compactRatesPerRow-1)/compactRatesPerRow)
+	for start := 0; start < len(lines); start += compactRatesPerRow {
+		end := start + compactRatesPerRow
+		if end > len(lines) {
+			end = len(lines)
+		}
+		row := make([]string, end-start)
+		for i, line := range lines[start:end] {
+			row[i] = strings.TrimSuffix(line, "\n")
+		}
+		rows = append(rows, strings.Join(row, "  |  ")+"\n")
+	}
+	return rows
+}
+
+// splitRateRows splits the rows of the rates to the rows of the rates message and the rows of each of the messages continuing it, so
+// none of them is longer than maxLength (0 doesn't split). The rates message has the header and the footer, and the messages continuing
+// it have the continued header. Every message has at least one row, even if it's longer.
+func splitRateRows(header string, rows []string, footer, continued string, maxLength int) [][]string {
+	length := len(header) + len(footer)
+	for _, row := range rows {
+		length += len(row)
+	}
+	if maxLength <= 0 || length <= maxLength {
+		return [][]string{rows}
+	}
+
+	chunks := make([][]string, 0)
+	chunk := make([]string, 0)
+	chunkLength := len(header) + len(footer)
+	for _, row := range rows {
+		if len(chunk) > 0 && chunkLength+len(row) > maxLength {
+			chunks = append(chunks, chunk)
+			chunk = make([]string, 0)
+			chunkLength = len(continued)
+		}
+		chunk = append(chunk, row)
+		chunkLength += len(row)
+	}
+	return append(chunks, chunk)
+}
+
+// buildRatesMessages returns the rates message, followed by the messages continuing its rates if it's longer than
+// RATES_MESSAGE_MAX_LENGTH
+func (h *Service) buildRatesMessages(channel string, groupRate GroupRate, groupID string) []string {
+	view := h.RatesView(channel, groupRate, groupID)
+	header, lines, footer := view.Header, view.lines(), view.Footer
+	continued := h.text(channel, msgRatesContinued, groupID)
+	chunks := splitRateRows(header, h.rateRows(groupRate, lines), footer, continued, h.cfg.RatesMessageMaxLength)
+
+	messages := make([]string, len(chunks))
+	messages[0] = header + strings.Join(chunks[0], "") + footer
+	for i, chunk := range chunks[1:] {
+		messages[i+1] = continued + strings.Join(chunk, "")
+	}
+	return messages
+}
+
+// ratesContinuation is a posted message continuing the rates message
+type ratesContinuation struct {
+	messageID string
+	text      string
+}
+
+// syncRatesContinuations updates the messages continuing the rates message of the order to the given ones: the messages which were
+// already posted are edited if they changed, and the rest are posted in the thread of the order. Continuing messages which aren't
+// needed anymore are left with the continued header only.
+func (h *Service) syncRatesContinuations(channel string, order *groupOrder, continuations []string) {
+	order.lock.Lock()
+	defer order.lock.Unlock()
+	for i := range order.continuations {
+		text := h.text(channel, msgRatesContinued, order.id)
+		if i < len(continuations) {
+			text = continuations[i]
+		}
+		if order.continuations[i].text == text {
+			continue
+		}
+		if err := h.editEvent(channel, text, order.continuations[i].messageID); err != nil {
+			h.logger.ErrorContext(order.ctx, "Error editing a continuation of the rates message", "continuation", i+1, "error", err)
+			continue
+		}
+		order.continuations[i].text = text
+	}
+	for i := len(order.continuations); i < len(continuations); i++ {
+		messageID, err := h.informEditableEvent(order.ctx, channel, continuations[i], "", order.messageID)
+		if err != nil {
+			h.logger.ErrorContext(order.ctx, "Error posting a continuation of the rates message", "continuation", i+1, "error", err)
+			return
+		}
+		order.continuations = append(order.continuations, ratesContinuation{messageID: messageID, text: continuations[i]})
+		if !order.companyPaid {
+			h.orderMessages.add(channel, messageID, order.id)
+		}
+	}
+}
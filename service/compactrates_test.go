@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func bigGroupRate(participants int) GroupRate {
+	groupRate := GroupRate{HostWoltUser: "Participant 0", DeliveryRate: 10}
+	for i := 0; i < participants; i++ {
+		groupRate.Rates = append(groupRate.Rates, Rate{
+			WoltName: fmt.Sprintf("Participant %d", i),
+			User:     &userDomain.User{ID: fmt.Sprintf("uuid-%d", i), TransportID: fmt.Sprintf("U%d", i)},
+			Amount:   float64(10 + i),
+		})
+	}
+	groupRate.HostUser = groupRate.Rates[0].User
+	return groupRate
+}
+
+func TestCompactRatesMessage(t *testing.T) {
+	t.Parallel()
+
+	h, err := New(Config{RatesCompactThreshold: 5}, nil, nil, nil, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+
+	message := h.buildRatesMessage("C1", bigGroupRate(5), "ABC")
+	assert.Contains(t, message, "<@U1> (Participant 1): 11.00\n", "groups up to the threshold aren't compact")
+
+	message = h.buildRatesMessage("C1", bigGroupRate(6), "ABC")
+	assert.Contains(t, message, "Rates for Wolt order ID ABC (including 10 NIS for delivery):\n"+
+		"<@U0>: 10.00  |  <@U1>: 11.00  |  <@U2>: 12.00  |  <@U3>: 13.00\n"+
+		"<@U4>: 14.00  |  <@U5>: 15.00\n"+
+		"\nPay to: <@U0>\n")
+
+	interactive := h.buildRatesInteractiveMessage("C1", bigGroupRate(6), "ABC", "")
+	require.Len(t, interactive.Sections, 4)
+	for _, section := range interactive.Sections {
+		assert.Nil(t, section.Button, "compact messages have no mark paid buttons")
+	}
+}
+
+func TestSplitRateRows(t *testing.T) {
+	t.Parallel()
+
+	rows := []string{"aaaa\n", "bbbb\n", "cccc\n", "dddd\n"}
+	assert.Equal(t, [][]string{rows}, splitRateRows("head\n", rows, "foot\n", "cont\n", 0))
+	assert.Equal(t, [][]string{rows}, splitRateRows("head\n", rows, "foot\n", "cont\n", 30))
+	assert.Equal(t, [][]string{{"aaaa\n", "bbbb\n"}, {"cccc\n", "dddd\n"}}, splitRateRows("head\n", rows, "foot\n", "cont\n", 20))
+	assert.Equal(t, [][]string{{"aaaa\n"}, {"bbbb\n"}, {"cccc\n"}, {"dddd\n"}}, splitRateRows("head\n", rows, "foot\n", "cont\n", 1),
+		"every message has at least one row")
+}
+
+func TestRatesContinuations(t *testing.T) {
+	t.Parallel()
+
+	notification := &editingNotification{}
+	h, err := New(Config{RatesMessageMaxLength: 300}, nil, nil, nil, "UBOT", notification)
+	require.NoError(t, err)
+
+	groupRate := bigGroupRate(12)
+	messages := h.buildRatesMessages("C1", groupRate, "ABC")
+	require.Len(t, messages, 2)
+	for _, message := range messages {
+		assert.LessOrEqual(t, len(message), 300)
+	}
+	assert.True(t, strings.HasPrefix(messages[1], "Rates for Wolt order ID ABC (continued):\n"))
+	assert.Contains(t, messages[0], "Pay to: <@U0>")
+	assert.Equal(t, 12, strings.Count(strings.Join(messages, ""), " (Participant "))
+
+	order := &groupOrder{id: "ABC", messageID: "1.1", ctx: context.Background()}
+	h.syncRatesContinuations("C1", order, messages[1:])
+	require.Len(t, notification.messages, 1)
+	assert.Equal(t, "C1: "+messages[1], notification.messages[0])
+
+	h.syncRatesContinuations("C1", order, messages[1:])
+	assert.Empty(t, notification.edits, "unchanged continuations aren't edited")
+
+	h.syncRatesContinuations("C1", order, nil)
+	assert.Equal(t, []string{"C1/sent-1: Rates for Wolt order ID ABC (continued):\n"}, notification.edits)
+	assert.Len(t, notification.messages, 1)
+}
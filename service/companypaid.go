@@ -0,0 +1,74 @@
+package service
+
+import (
+	"fmt"
+)
+
+func (g *groupOrder) markCompanyPaid() bool {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if g.companyPaid {
+		return false
+	}
+	g.companyPaid = true
+	return true
+}
+
+func (g *groupOrder) isCompanyPaid() bool {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	return g.companyPaid
+}
+
+// hostTransportIDOfGroup returns the transport ID of the host of the group, or an empty string if the host isn't a known user
+func (h *Service) hostTransportIDOfGroup(order *groupOrder) (string, error) {
+	details, err := order.Details()
+	if err != nil {
+		return "", fmt.Errorf("get order details: %w", err)
+	}
+	users, err := h.listUsersByName(details.Host)
+	if err != nil {
+		return "", fmt.Errorf("list users: %w", err)
+	}
+	if len(users) != 1 {
+		return "", nil
+	}
+	return users[0].TransportID, nil
+}
+
+// handleCompanyPaidReaction marks the order of the link message as paid by the company, when its host (or their co-host) reacts to it
+// before the rates are published, so no debts are tracked for it
+func (h *Service) handleCompanyPaidReaction(req ReactionAddRequest) {
+	activeOrder := h.activeOrderByMessage(req.Channel, req.MessageID)
+	if activeOrder == nil {
+		// Not a link message of an order I track
+		return
+	}
+	order := h.workingOrders.get(activeOrder.ID)
+	if order == nil || h.userStore == nil {
+		return
+	}
+	if activeOrder.Rates != nil {
+		_, _ = h.informInteractiveEvent(req.FromUserID, h.text(req.FromUserID, msgCompanyPaidRatesPublished, HostRemoveDebts), "")
+		return
+	}
+
+	host, err := h.hostTransportIDOfGroup(order)
+	if err != nil {
+		h.logger.ErrorContext(order.ctx, "Error getting the host of the order", "error", err)
+		return
+	}
+	if host == "" || !h.actsForHost(host, req.FromUserID) {
+		_, _ = h.informInteractiveEvent(req.FromUserID, h.text(req.FromUserID, msgCompanyPaidHostOnly), "")
+		return
+	}
+	if !order.markCompanyPaid() {
+		return
+	}
+	h.saveTracking(order, "")
+	message := fmt.Sprintf("Got it <@%s>, the company pays for this order, so I won't track debts for it :%s:", req.FromUserID,
+		h.channelEmoji(req.Channel, settingCompanyPaidEmoji, h.cfg.CompanyPaidEmoji))
+	if _, err := h.informEvent(req.Channel, message, "", req.MessageID); err != nil {
+		h.logger.ErrorContext(order.ctx, "Error acknowledging company payment", "error", err)
+	}
+}
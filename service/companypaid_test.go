@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/oriser/bolt/order"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompanyPaidReaction(t *testing.T) {
+	t.Parallel()
+
+	notification := &recordingNotification{}
+	store := &fakeTreasuryStore{users: map[string]*userDomain.User{
+		"U-host": {ID: "U-host", FullName: "Thor", TransportID: "U-host"},
+		"U1":     {ID: "U1", FullName: "Loki", TransportID: "U1"},
+	}}
+	h, err := New(Config{CompanyPaidEmoji: "credit_card", Locale: "en"}, store, nil, nil, "U-bot", notification)
+	require.NoError(t, err)
+
+	react := func(userID string) {
+		_, err := h.HandleReactionAdded(ReactionAddRequest{Reaction: "credit_card", FromUserID: userID, Channel: "C1", MessageID: "1.1"})
+		require.NoError(t, err)
+	}
+	react("U-host")
+	assert.Empty(t, notification.messages, "reactions to messages of untracked orders should be ignored")
+
+	tracked := startWorkingOrder(t, h, "A", "C1")
+	tracked.details = &OrderDetails{Host: "Thor"}
+	h.hooks.Emit(context.Background(), Event{Type: EventOrderJoined, OrderID: "A", Channel: "C1", MessageID: "1.1"})
+
+	react("U1")
+	assert.False(t, tracked.isCompanyPaid())
+	assert.Equal(t, []string{"U1: Only the host of the order can mark it as paid by the company"}, notification.messages)
+
+	react("U-host")
+	react("U-host")
+	assert.True(t, tracked.isCompanyPaid())
+	assert.Equal(t, "C1: Got it <@U-host>, the company pays for this order, so I won't track debts for it :credit_card:", notification.messages[1])
+	require.Len(t, notification.messages, 2)
+
+	groupRate := GroupRate{HostWoltUser: "Thor", HostUser: store.users["U-host"], CompanyPaid: true, Rates: []Rate{
+		{WoltName: "Loki", User: store.users["U1"], Amount: 30},
+		{WoltName: "Thor", User: store.users["U-host"], Amount: 20},
+	}}
+	assert.Equal(t, "Rates for Wolt order ID A (including 0 NIS for delivery):\n"+
+		"<@U1> (Loki): 30.00\n"+
+		"<@U-host> (Thor): 20.00\n"+
+		"\n:credit_card: The company paid for this order, no payment needed\n", h.buildRatesMessage("C1", groupRate, "A"))
+
+	h.hooks.Emit(context.Background(), Event{Type: EventRatesPublished, OrderID: "A", Rates: &groupRate})
+	react("U-host")
+	assert.Equal(t, "U-host: The rates of this order were already published, the host can react with :x: to the rates message to cancel its debts", notification.messages[2])
+}
+
+func TestFinanceReportOfCompanyPaidOrder(t *testing.T) {
+	t.Parallel()
+
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	h := &Service{logger: slog.Default(), orderStore: &fakeOrderStore{orders: []*order.Order{
+		{OriginalID: "A", CreatedAt: createdAt, Status: order.StatusDone, CompanyPaid: true, Tags: []string{"offsite"},
+			Participants: []order.Participant{{Name: "Loki", ID: "U1", Amount: 60, Subsidy: 50}}},
+	}}}
+
+	report, err := h.FinanceReport(context.Background(), createdAt.AddDate(0, -1, 0), createdAt.AddDate(0, 1, 0))
+	require.NoError(t, err)
+	assert.Equal(t, []*FinanceReportRow{{Name: "offsite", Orders: 1, Subsidized: 60}}, report.CostCenters)
+	assert.Equal(t, []*FinanceReportRow{{Name: "Loki", ID: "U1", Orders: 1, Subsidized: 60}}, report.Users)
+}
@@ -0,0 +1,401 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/caarlos0/env/v6"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/oriser/bolt/wolt"
+)
+
+// Config is the configuration of the service. It is parsed from the environment variables in its tags, and embedders can
+// construct it programmatically starting from DefaultConfig
+type Config struct {
+	TimeoutForReady              time.Duration `env:"ORDER_READY_TIMEOUT" envDefault:"1h"`
+	OrderDoneTimeout             time.Duration `env:"ORDER_DONE_TIMEOUT" envDefault:"3h"`
+	WorkingOrderTTL              time.Duration `env:"WORKING_ORDER_TTL" envDefault:"6h"` // How long until the handling of an order is considered abandoned
+	MaxTrackedOrders             int           `env:"MAX_TRACKED_ORDERS"`                // The orders tracked at the same time, the others wait in line, 0 disables the cap
+	MaxQueuedOrders              int           `env:"MAX_QUEUED_ORDERS" envDefault:"50"` // The orders waiting in line for MAX_TRACKED_ORDERS, 0 disables the cap
+	TimeTillGetReadyMessage      time.Duration `env:"TIME_TILL_GET_READY_MESSAGE" envDefault:"7m"`
+	OrderDestinationEmoji        string        `env:"ORDER_DESTINATION_EMOJI" envDefault:"house"`
+	JoinedOrderEmoji             string        `env:"JOINED_ORDER_EMOJI" envDefault:"eyes"`
+	SkipOrderEmoji               string        `env:"SKIP_ORDER_EMOJI" envDefault:"no_entry_sign"`
+	BlacklistConfirmationEmoji   string        `env:"BLACKLIST_CONFIRMATION_EMOJI" envDefault:"white_check_mark"`
+	CompanyPaidEmoji             string        `env:"COMPANY_PAID_EMOJI" envDefault:"credit_card"`
+	MergeOffers                  bool          `env:"MERGE_OFFERS"` // Offer to tell the channels of open orders from the same venue about each other
+	MergeOfferEmoji              string        `env:"MERGE_OFFER_EMOJI" envDefault:"handshake"`
+	AccessibleMessages           bool          `env:"ACCESSIBLE_MESSAGES"` // Screen-reader friendly messages, with text instead of emojis
+	BlacklistConfirmationTimeout time.Duration `env:"BLACKLIST_CONFIRMATION_TIMEOUT" envDefault:"10m"`
+	PreauthThreshold             float64       `env:"PREAUTH_THRESHOLD"`                    // Average per person of a venue's orders above which orders need confirmations, 0 disables
+	PreauthConfirmations         int           `env:"PREAUTH_CONFIRMATIONS" envDefault:"2"` // How many participants confirm the orders of PREAUTH_THRESHOLD
+	TimeoutForDeliveryRate       time.Duration `env:"GET_DELIVERY_RATE_TIMEOUT" envDefault:"10m"`
+	WaitBetweenStatusCheck       time.Duration `env:"WAIT_BETWEEN_STATUS_CHECK" envDefault:"20s"`
+	WaitProgressInterval         time.Duration `env:"WAIT_PROGRESS_INTERVAL" envDefault:"15m"` // How often to note who the group waits for, 0 disables
+	DeliveryUpdates              string        `env:"DELIVERY_UPDATES" envDefault:"off"`
+	DeliveryUpdatesInterval      time.Duration `env:"DELIVERY_UPDATES_INTERVAL" envDefault:"5m"` // The minimal time between the ETA updates
+	SoloOrders                   bool          `env:"SOLO_ORDERS"`                               // Follow the delivery of regular (not group) orders by their tracking links
+	DebtReminderInterval         time.Duration `env:"DEBT_REMINDER_INTERVAL" envDefault:"3h"`
+	DebtMaximumDuration          time.Duration `env:"DEBT_MAXIMUM_DURATION" envDefault:"24h"`
+	DebtSchedulerInterval        time.Duration `env:"DEBT_SCHEDULER_INTERVAL" envDefault:"1m"`
+	DebtReminderNotifyHost       bool          `env:"DEBT_REMINDER_NOTIFY_HOST"`  // Tell the hosts who was reminded to pay them
+	DebtDMs                      bool          `env:"DEBT_DMS"`                   // DM every debtor their amount when the rates are published, unless they opted out
+	DebtReminderSmartTiming      bool          `env:"DEBT_REMINDER_SMART_TIMING"` // Defer the reminders until the borrowers are likely active
+	DebtEscalationStages         []string      `env:"DEBT_ESCALATION_STAGES"`     // List of <days>=<channel|admins|shame> stages unpaid debts escalate to
+	DebtReminderMaxDelay         time.Duration `env:"DEBT_REMINDER_SMART_TIMING_MAX_DELAY" envDefault:"2h"`
+	DontJoinAfter                string        `env:"DONT_JOIN_AFTER"`
+	DontJoinAfterTZ              string        `env:"DONT_JOIN_AFTER_TZ"`
+	OrderSchedule                []string      `env:"ORDER_SCHEDULE"`                        // List of <weekday>=<HH:MM|none|off> cutoffs of the days which differ from DONT_JOIN_AFTER
+	LateOrderConfirmation        bool          `env:"LATE_ORDER_CONFIRMATION"`               // Offer to track orders after DONT_JOIN_AFTER on a confirmation reaction
+	FeeCorrectionWindow          time.Duration `env:"FEE_CORRECTION_WINDOW" envDefault:"2h"` // How long hosts can change the fees after the rates are published, 0 disables
+	ChannelTimezones             []string      `env:"CHANNEL_TIMEZONES"`                     // List of <channel ID>=<timezone> pairs
+	WorkHours                    string        `env:"WORK_HOURS"`                            // <HH:MM>-<HH:MM> to track orders in, links shared outside them are ignored silently
+	WorkDays                     []string      `env:"WORK_DAYS"`                             // Weekdays to track orders on, links shared on other days are ignored silently
+	SocialChannels               []string      `env:"SOCIAL_CHANNELS"`                       // Channels to ignore the links shared in silently, as they're personal orders
+	MissedLinksLookback          time.Duration `env:"MISSED_LINKS_LOOKBACK"`                 // How far back to look for links shared while Bolt was down, 0 disables it
+	Locale                       string        `env:"LOCALE" envDefault:"en"`
+	ChannelLocales               []string      `env:"CHANNEL_LOCALES"` // List of <channel ID>=<locale> pairs
+	LocaleDetectionInterval      time.Duration `env:"LOCALE_DETECTION_INTERVAL" envDefault:"24h"`
+	MessagesFile                 string        `env:"MESSAGES_FILE"` // JSON file of messages overriding the built-in ones, by locale and name
+	FeeAllocationStrategy        string        `env:"FEE_ALLOCATION_STRATEGY" envDefault:"equal"`
+	AmountRounding               float64       `env:"AMOUNT_ROUNDING"`                               // The step to round the amounts to (e.g. 0.5 or 1), 0 disables rounding
+	RoundingRemainder            string        `env:"ROUNDING_REMAINDER" envDefault:"host"`          // Who pays the difference of the rounding: host or largest
+	DiscountAllocation           string        `env:"DISCOUNT_ALLOCATION" envDefault:"proportional"` // Who gets the discounts of orders: proportional or host
+	RatesCompactThreshold        int           `env:"RATES_COMPACT_THRESHOLD" envDefault:"15"`
+	RatesMessageMaxLength        int           `env:"RATES_MESSAGE_MAX_LENGTH" envDefault:"3500"`
+	Currency                     string        `env:"CURRENCY" envDefault:"ILS"`   // The currency of orders which Wolt doesn't tell the currency of
+	OrderRefGenerator            string        `env:"ORDER_REF_GENERATOR"`         // How to generate the external references of orders, empty disables them
+	RatesButtons                 bool          `env:"RATES_BUTTONS"`               // Send the rates messages with "Mark paid" and "Cancel tracking" buttons
+	RatesItems                   string        `env:"RATES_ITEMS"`                 // List each participant's items: off, inline or thread
+	PaymentLinks                 []string      `env:"PAYMENT_LINKS"`               // List of <payment method>=<URL> pairs for the payment link button
+	PaymentConfirmation          bool          `env:"PAYMENT_CONFIRMATION"`        // Settle the debts marked as paid only once their hosts confirm the payments
+	SubsidyAmount                float64       `env:"SUBSIDY_AMOUNT"`              // The company subsidy of each participant in an order, 0 disables the subsidy mode
+	SubsidyExcludedCategories    []string      `env:"SUBSIDY_EXCLUDED_CATEGORIES"` // Item categories the subsidy doesn't cover
+	SubsidyPercent               float64       `env:"SUBSIDY_PERCENT"`             // The percentage of each participant's amount the company pays, 0 disables it
+	SubsidyMonthlyCap            float64       `env:"SUBSIDY_MONTHLY_CAP"`         // The subsidy of each user in a month, 0 disables the cap
+	UnknownParticipantPolicy     string        `env:"UNKNOWN_PARTICIPANT_POLICY" envDefault:"skip"`
+	ChannelUnknownPolicies       []string      `env:"CHANNEL_UNKNOWN_PARTICIPANT_POLICIES"` // List of <channel ID>=<policy> pairs
+	BadgesChannels               []string      `env:"BADGES_CHANNELS"`                      // Channels to announce the monthly earned badges in
+	BadgesAnnounceHour           int           `env:"BADGES_ANNOUNCE_HOUR" envDefault:"10"`
+	InsightsHour                 int           `env:"INSIGHTS_HOUR" envDefault:"10"`
+	FinanceReportChannel         string        `env:"FINANCE_REPORT_CHANNEL"` // Channel to send the quarterly finance report to
+	FinanceReportHour            int           `env:"FINANCE_REPORT_HOUR" envDefault:"9"`
+	DealsChannels                []string      `env:"DEALS_CHANNELS"` // Channels to post the promotions of their favorite venues in
+	DealsHour                    int           `env:"DEALS_HOUR" envDefault:"9"`
+	DealsFavoriteVenues          int           `env:"DEALS_FAVORITE_VENUES" envDefault:"5"`
+	BalancesDigestChannels       []string      `env:"BALANCES_DIGEST_CHANNELS"` // Channels to post the weekly "who owes whom" digest in
+	BalancesDigestWeekday        string        `env:"BALANCES_DIGEST_WEEKDAY" envDefault:"Sunday"`
+	BalancesDigestHour           int           `env:"BALANCES_DIGEST_HOUR" envDefault:"10"`
+	MonthlyReportChannels        []string      `env:"MONTHLY_REPORT_CHANNELS"` // Channels to post the monthly spending report in
+	MonthlyReportHour            int           `env:"MONTHLY_REPORT_HOUR" envDefault:"10"`
+	MatchingSummaryChannel       string        `env:"MATCHING_SUMMARY_CHANNEL"` // Admin channel to post the weekly summary of the unmatched Wolt names in
+	MatchingSummaryHour          int           `env:"MATCHING_SUMMARY_HOUR" envDefault:"10"`
+	HostingNudgeOrders           int           `env:"HOSTING_NUDGE_ORDERS"`     // Nudge the users who joined this many orders in a row without hosting, 0 disables
+	Treasurers                   []string      `env:"TREASURER_SLACK_USER_IDS"` // Transport IDs of users who can view and settle debts across all channels
+	OfficeLocation               string        `env:"OFFICE_LOCATION"`          // <latitude>,<longitude> of the office, for estimating delivery rates
+	FallbackAdminChannel         string        `env:"FALLBACK_ADMIN_CHANNEL"`   // Channel to tell about orders Bolt stopped tracking because their channel or host became unavailable
+	StatusPageURL                string        `env:"STATUS_PAGE_URL"`
+	StatusPageSecret             string        `env:"STATUS_PAGE_SECRET" json:"-"`
+	PaidLinksURL                 string        `env:"PAID_LINKS_URL"` // The public URL of Bolt, for the links in the debt reminders which mark the debts as paid
+	PaidLinksSecret              string        `env:"PAID_LINKS_SECRET" json:"-"`
+	WoltBaseAddr                 string        `env:"WOLT_BASE_ADDR" envDefault:"https://wolt.com"`
+	WoltApiBaseAddr              string        `env:"WOLT_API_BASE_ADDR" envDefault:"https://restaurant-api.wolt.com"`
+	WoltHTTPMaxRetryCount        int           `env:"WOLT_HTTP_MAX_RETRY_COUNT" envDefault:"5"`
+	WoltHTTPMinRetryDuration     time.Duration `env:"WOLT_HTTP_MIN_RETRY_DURATION" envDefault:"1s"`
+	WoltHTTPMaxRetryDuration     time.Duration `env:"WOLT_HTTP_MAX_RETRY_DURATION" envDefault:"30s"`
+	WoltRateLimit                float64       `env:"WOLT_RATE_LIMIT" envDefault:"10"`
+	WoltBreakerFailures          int           `env:"WOLT_BREAKER_FAILURES" envDefault:"5"`
+	WoltBreakerCooldown          time.Duration `env:"WOLT_BREAKER_COOLDOWN" envDefault:"1m"`
+	CommandRateLimit             int           `env:"COMMAND_RATE_LIMIT" envDefault:"20"` // Commands and button clicks a minute per user, 0 disables the limit
+	CommandBurst                 int           `env:"COMMAND_BURST" envDefault:"5"`       // Commands a user can send in a row before COMMAND_RATE_LIMIT applies
+	WoltPollFailureTimeout       time.Duration `env:"WOLT_POLL_FAILURE_TIMEOUT" envDefault:"10m"`
+	WoltHTTPTimeout              time.Duration `env:"WOLT_HTTP_TIMEOUT" envDefault:"30s"`      // Deadline of each attempt of a request to Wolt, 0 disables
+	StoreTimeout                 time.Duration `env:"STORE_TIMEOUT" envDefault:"10s"`          // Deadline of each store call, 0 disables
+	WatchdogInterval             time.Duration `env:"WATCHDOG_INTERVAL" envDefault:"1m"`       // How often the goroutines, memory and order monitors are sampled, 0 disables
+	QuietCalendarRefresh         time.Duration `env:"QUIET_CALENDAR_REFRESH" envDefault:"15m"` // How often the meetings of QUIET_CALENDAR_URL are fetched
+	ReadModelsTTL                time.Duration `env:"READ_MODELS_TTL" envDefault:"10m"`        // How long the read models of the balances and reports are kept, 0 disables
+}
+
+// parsedConfig is the configuration values parsed into their units
+type parsedConfig struct {
+	dontJoinAfter                     time.Time
+	dontJoinAfterTZ                   *time.Location
+	orderSchedule                     map[time.Weekday]dayCutoff
+	escalationStages                  []escalationStage
+	channelTimezones                  map[string]*time.Location
+	officeLocation                    *wolt.Coordinate
+	feeAllocator                      FeeAllocator
+	roundingRemainder                 RoundingRemainder
+	ratesItems                        RatesItems
+	discountAllocation                DiscountAllocation
+	orderRefGenerator                 OrderRefGenerator
+	subsidyExcludedCategories         []ItemCategory
+	unknownParticipantPolicy          UnknownParticipantPolicy
+	channelUnknownParticipantPolicies map[string]UnknownParticipantPolicy
+	defaultLocale                     Locale
+	channelLocaleOverrides            map[string]Locale
+	messages                          map[Locale]map[messageKey]string
+	balancesDigestWeekday             time.Weekday
+	deliveryUpdates                   DeliveryUpdates
+	currency                          string
+	paymentLinks                      map[userDomain.PaymentMethod]string
+	statusPageSecret                  []byte
+	paidLinksSecret                   []byte
+	workHours                         *workHours
+	workDays                          map[time.Weekday]bool
+}
+
+// DefaultConfig returns the configuration with the default of every value, as if no environment variable was set
+func DefaultConfig() Config {
+	cfg := Config{}
+	if err := env.Parse(&cfg, env.Options{Environment: map[string]string{}}); err != nil {
+		// The defaults are constants, so this can only happen when an envDefault tag is broken
+		panic(fmt.Sprintf("parsing the default config: %v", err))
+	}
+	return cfg
+}
+
+// ParseClock parses a time of the day in the HH:MM format, like DONT_JOIN_AFTER
+func ParseClock(value string) (time.Time, error) {
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected HH:MM but got %q", value)
+	}
+	return clock, nil
+}
+
+// ParseTimezone parses an IANA timezone name, like Asia/Jerusalem
+func ParseTimezone(name string) (*time.Location, error) {
+	tz, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q: %w", name, err)
+	}
+	return tz, nil
+}
+
+// ParseDuration parses a non-negative Go duration, like 1h30m
+func ParseDuration(value string) (time.Duration, error) {
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("expected a duration like 1h30m but got %q", value)
+	}
+	if duration < 0 {
+		return 0, fmt.Errorf("expected a non-negative duration but got %q", value)
+	}
+	return duration, nil
+}
+
+// Validate returns an error describing the first invalid value of the configuration, naming its environment variable
+func (cfg Config) Validate() error {
+	_, err := cfg.parse()
+	return err
+}
+
+func (cfg Config) validateRanges() error {
+	durations := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"ORDER_READY_TIMEOUT", cfg.TimeoutForReady},
+		{"ORDER_DONE_TIMEOUT", cfg.OrderDoneTimeout},
+		{"WORKING_ORDER_TTL", cfg.WorkingOrderTTL},
+		{"TIME_TILL_GET_READY_MESSAGE", cfg.TimeTillGetReadyMessage},
+		{"BLACKLIST_CONFIRMATION_TIMEOUT", cfg.BlacklistConfirmationTimeout},
+		{"GET_DELIVERY_RATE_TIMEOUT", cfg.TimeoutForDeliveryRate},
+		{"WAIT_BETWEEN_STATUS_CHECK", cfg.WaitBetweenStatusCheck},
+		{"WAIT_PROGRESS_INTERVAL", cfg.WaitProgressInterval},
+		{"DELIVERY_UPDATES_INTERVAL", cfg.DeliveryUpdatesInterval},
+		{"DEBT_REMINDER_INTERVAL", cfg.DebtReminderInterval},
+		{"DEBT_MAXIMUM_DURATION", cfg.DebtMaximumDuration},
+		{"DEBT_SCHEDULER_INTERVAL", cfg.DebtSchedulerInterval},
+		{"DEBT_REMINDER_SMART_TIMING_MAX_DELAY", cfg.DebtReminderMaxDelay},
+		{"LOCALE_DETECTION_INTERVAL", cfg.LocaleDetectionInterval},
+		{"WOLT_HTTP_MIN_RETRY_DURATION", cfg.WoltHTTPMinRetryDuration},
+		{"WOLT_HTTP_MAX_RETRY_DURATION", cfg.WoltHTTPMaxRetryDuration},
+		{"WOLT_HTTP_TIMEOUT", cfg.WoltHTTPTimeout},
+		{"WOLT_BREAKER_COOLDOWN", cfg.WoltBreakerCooldown},
+		{"WOLT_POLL_FAILURE_TIMEOUT", cfg.WoltPollFailureTimeout},
+		{"STORE_TIMEOUT", cfg.StoreTimeout},
+		{"WATCHDOG_INTERVAL", cfg.WatchdogInterval},
+		{"FEE_CORRECTION_WINDOW", cfg.FeeCorrectionWindow},
+		{"QUIET_CALENDAR_REFRESH", cfg.QuietCalendarRefresh},
+		{"READ_MODELS_TTL", cfg.ReadModelsTTL},
+	}
+	for _, duration := range durations {
+		if duration.value < 0 {
+			return fmt.Errorf("%s must not be negative but got %s", duration.name, duration.value)
+		}
+	}
+	if cfg.WoltHTTPMaxRetryDuration < cfg.WoltHTTPMinRetryDuration {
+		return fmt.Errorf("WOLT_HTTP_MAX_RETRY_DURATION (%s) must not be shorter than WOLT_HTTP_MIN_RETRY_DURATION (%s)",
+			cfg.WoltHTTPMaxRetryDuration, cfg.WoltHTTPMinRetryDuration)
+	}
+	if cfg.WoltHTTPMaxRetryCount < 0 {
+		return fmt.Errorf("WOLT_HTTP_MAX_RETRY_COUNT must not be negative but got %d", cfg.WoltHTTPMaxRetryCount)
+	}
+	if cfg.MaxTrackedOrders < 0 {
+		return fmt.Errorf("MAX_TRACKED_ORDERS must not be negative but got %d", cfg.MaxTrackedOrders)
+	}
+	if cfg.MaxQueuedOrders < 0 {
+		return fmt.Errorf("MAX_QUEUED_ORDERS must not be negative but got %d", cfg.MaxQueuedOrders)
+	}
+	if cfg.PreauthThreshold < 0 {
+		return fmt.Errorf("PREAUTH_THRESHOLD must not be negative but got %.2f", cfg.PreauthThreshold)
+	}
+	if cfg.PreauthConfirmations < 0 {
+		return fmt.Errorf("PREAUTH_CONFIRMATIONS must not be negative but got %d", cfg.PreauthConfirmations)
+	}
+	if cfg.HostingNudgeOrders < 0 {
+		return fmt.Errorf("HOSTING_NUDGE_ORDERS must not be negative but got %d", cfg.HostingNudgeOrders)
+	}
+	if cfg.CommandRateLimit < 0 {
+		return fmt.Errorf("COMMAND_RATE_LIMIT must not be negative but got %d", cfg.CommandRateLimit)
+	}
+	if cfg.CommandBurst < 0 {
+		return fmt.Errorf("COMMAND_BURST must not be negative but got %d", cfg.CommandBurst)
+	}
+	if cfg.WoltRateLimit < 0 {
+		return fmt.Errorf("WOLT_RATE_LIMIT must not be negative but got %.2f", cfg.WoltRateLimit)
+	}
+	if cfg.WoltBreakerFailures < 0 {
+		return fmt.Errorf("WOLT_BREAKER_FAILURES must not be negative but got %d", cfg.WoltBreakerFailures)
+	}
+	if cfg.SubsidyAmount < 0 {
+		return fmt.Errorf("SUBSIDY_AMOUNT must not be negative but got %.2f", cfg.SubsidyAmount)
+	}
+	if cfg.SubsidyPercent < 0 || cfg.SubsidyPercent > 100 {
+		return fmt.Errorf("SUBSIDY_PERCENT must be between 0 and 100 but got %.2f", cfg.SubsidyPercent)
+	}
+	if cfg.SubsidyMonthlyCap < 0 {
+		return fmt.Errorf("SUBSIDY_MONTHLY_CAP must not be negative but got %.2f", cfg.SubsidyMonthlyCap)
+	}
+	if cfg.SubsidyMonthlyCap > 0 && cfg.SubsidyAmount == 0 && cfg.SubsidyPercent == 0 {
+		return fmt.Errorf("SUBSIDY_MONTHLY_CAP requires SUBSIDY_AMOUNT or SUBSIDY_PERCENT")
+	}
+	if cfg.BadgesAnnounceHour < 0 || cfg.BadgesAnnounceHour > 23 {
+		return fmt.Errorf("BADGES_ANNOUNCE_HOUR must be between 0 and 23 but got %d", cfg.BadgesAnnounceHour)
+	}
+	if cfg.InsightsHour < 0 || cfg.InsightsHour > 23 {
+		return fmt.Errorf("INSIGHTS_HOUR must be between 0 and 23 but got %d", cfg.InsightsHour)
+	}
+	if cfg.FinanceReportHour < 0 || cfg.FinanceReportHour > 23 {
+		return fmt.Errorf("FINANCE_REPORT_HOUR must be between 0 and 23 but got %d", cfg.FinanceReportHour)
+	}
+	if cfg.DealsHour < 0 || cfg.DealsHour > 23 {
+		return fmt.Errorf("DEALS_HOUR must be between 0 and 23 but got %d", cfg.DealsHour)
+	}
+	if cfg.BalancesDigestHour < 0 || cfg.BalancesDigestHour > 23 {
+		return fmt.Errorf("BALANCES_DIGEST_HOUR must be between 0 and 23 but got %d", cfg.BalancesDigestHour)
+	}
+	if cfg.MonthlyReportHour < 0 || cfg.MonthlyReportHour > 23 {
+		return fmt.Errorf("MONTHLY_REPORT_HOUR must be between 0 and 23 but got %d", cfg.MonthlyReportHour)
+	}
+	if cfg.MatchingSummaryHour < 0 || cfg.MatchingSummaryHour > 23 {
+		return fmt.Errorf("MATCHING_SUMMARY_HOUR must be between 0 and 23 but got %d", cfg.MatchingSummaryHour)
+	}
+	if cfg.RatesCompactThreshold < 0 {
+		return fmt.Errorf("RATES_COMPACT_THRESHOLD must not be negative but got %d", cfg.RatesCompactThreshold)
+	}
+	if cfg.RatesMessageMaxLength < 0 {
+		return fmt.Errorf("RATES_MESSAGE_MAX_LENGTH must not be negative but got %d", cfg.RatesMessageMaxLength)
+	}
+	if cfg.DealsFavoriteVenues < 0 {
+		return fmt.Errorf("DEALS_FAVORITE_VENUES must not be negative but got %d", cfg.DealsFavoriteVenues)
+	}
+	return nil
+}
+
+func (cfg Config) parse() (*parsedConfig, error) {
+	if err := cfg.validateRanges(); err != nil {
+		return nil, err
+	}
+
+	parsed := &parsedConfig{}
+	var err error
+	if cfg.DontJoinAfter != "" {
+		if parsed.dontJoinAfter, err = ParseClock(cfg.DontJoinAfter); err != nil {
+			return nil, fmt.Errorf("parsing DONT_JOIN_AFTER: %w", err)
+		}
+	}
+	if cfg.DontJoinAfterTZ != "" {
+		if parsed.dontJoinAfterTZ, err = ParseTimezone(cfg.DontJoinAfterTZ); err != nil {
+			return nil, fmt.Errorf("parsing DONT_JOIN_AFTER_TZ: %w", err)
+		}
+	}
+	if parsed.orderSchedule, err = parseOrderSchedule(cfg.OrderSchedule); err != nil {
+		return nil, fmt.Errorf("parsing ORDER_SCHEDULE: %w", err)
+	}
+	if parsed.escalationStages, err = parseEscalationStages(cfg.DebtEscalationStages); err != nil {
+		return nil, fmt.Errorf("parsing DEBT_ESCALATION_STAGES: %w", err)
+	}
+	if err = checkEscalationStages(parsed.escalationStages, cfg.DebtMaximumDuration); err != nil {
+		return nil, fmt.Errorf("parsing DEBT_ESCALATION_STAGES: %w", err)
+	}
+	if parsed.channelTimezones, err = parseChannelTimezones(cfg.ChannelTimezones); err != nil {
+		return nil, fmt.Errorf("parsing CHANNEL_TIMEZONES: %w", err)
+	}
+	if parsed.workHours, err = parseWorkHours(cfg.WorkHours); err != nil {
+		return nil, fmt.Errorf("parsing WORK_HOURS: %w", err)
+	}
+	if parsed.workDays, err = parseWorkDays(cfg.WorkDays); err != nil {
+		return nil, fmt.Errorf("parsing WORK_DAYS: %w", err)
+	}
+	if parsed.officeLocation, err = parseOfficeLocation(cfg.OfficeLocation); err != nil {
+		return nil, fmt.Errorf("parsing OFFICE_LOCATION: %w", err)
+	}
+	if parsed.feeAllocator, err = FeeAllocatorByName(cfg.FeeAllocationStrategy); err != nil {
+		return nil, fmt.Errorf("parsing FEE_ALLOCATION_STRATEGY: %w", err)
+	}
+	if cfg.AmountRounding < 0 {
+		return nil, fmt.Errorf("parsing AMOUNT_ROUNDING: the rounding step must not be negative")
+	}
+	if parsed.ratesItems, err = parseRatesItems(cfg.RatesItems); err != nil {
+		return nil, fmt.Errorf("parsing RATES_ITEMS: %w", err)
+	}
+	if parsed.roundingRemainder, err = parseRoundingRemainder(cfg.RoundingRemainder); err != nil {
+		return nil, fmt.Errorf("parsing ROUNDING_REMAINDER: %w", err)
+	}
+	if parsed.discountAllocation, err = parseDiscountAllocation(cfg.DiscountAllocation); err != nil {
+		return nil, fmt.Errorf("parsing DISCOUNT_ALLOCATION: %w", err)
+	}
+	if parsed.orderRefGenerator, err = OrderRefGeneratorByName(cfg.OrderRefGenerator); err != nil {
+		return nil, fmt.Errorf("parsing ORDER_REF_GENERATOR: %w", err)
+	}
+	if parsed.subsidyExcludedCategories, err = parseItemCategories(cfg.SubsidyExcludedCategories); err != nil {
+		return nil, fmt.Errorf("parsing SUBSIDY_EXCLUDED_CATEGORIES: %w", err)
+	}
+	if parsed.unknownParticipantPolicy, err = parseUnknownParticipantPolicy(cfg.UnknownParticipantPolicy); err != nil {
+		return nil, fmt.Errorf("parsing UNKNOWN_PARTICIPANT_POLICY: %w", err)
+	}
+	if parsed.channelUnknownParticipantPolicies, err = parseChannelUnknownParticipantPolicies(cfg.ChannelUnknownPolicies); err != nil {
+		return nil, fmt.Errorf("parsing CHANNEL_UNKNOWN_PARTICIPANT_POLICIES: %w", err)
+	}
+	if parsed.defaultLocale, err = parseLocale(cfg.Locale); err != nil {
+		return nil, fmt.Errorf("parsing LOCALE: %w", err)
+	}
+	if parsed.channelLocaleOverrides, err = parseChannelLocales(cfg.ChannelLocales); err != nil {
+		return nil, fmt.Errorf("parsing CHANNEL_LOCALES: %w", err)
+	}
+	if parsed.messages, err = loadMessages(cfg.MessagesFile); err != nil {
+		return nil, fmt.Errorf("parsing MESSAGES_FILE: %w", err)
+	}
+	if parsed.balancesDigestWeekday, err = parseWeekday(cfg.BalancesDigestWeekday); err != nil {
+		return nil, fmt.Errorf("parsing BALANCES_DIGEST_WEEKDAY: %w", err)
+	}
+	if parsed.deliveryUpdates, err = parseDeliveryUpdates(cfg.DeliveryUpdates); err != nil {
+		return nil, fmt.Errorf("parsing DELIVERY_UPDATES: %w", err)
+	}
+	if parsed.currency, err = parseCurrency(cfg.Currency); err != nil {
+		return nil, fmt.Errorf("parsing CURRENCY: %w", err)
+	}
+	if parsed.paymentLinks, err = parsePaymentLinks(cfg.PaymentLinks); err != nil {
+		return nil, fmt.Errorf("parsing PAYMENT_LINKS: %w", err)
+	}
+	if parsed.statusPageSecret, err = parseLinksSecret(cfg.StatusPageURL, cfg.StatusPageSecret, "STATUS_PAGE_SECRET"); err != nil {
+		return nil, fmt.Errorf("parsing STATUS_PAGE_SECRET: %w", err)
+	}
+	if parsed.paidLinksSecret, err = parseLinksSecret(cfg.PaidLinksURL, cfg.PaidLinksSecret, "PAID_LINKS_SECRET"); err != nil {
+		return nil, fmt.Errorf("parsing PAID_LINKS_SECRET: %w", err)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultConfig(t *testing.T) {
+	t.Parallel()
+
+	cfg := DefaultConfig()
+	assert.Equal(t, time.Hour, cfg.TimeoutForReady)
+	assert.Equal(t, "equal", cfg.FeeAllocationStrategy)
+	assert.Equal(t, "en", cfg.Locale)
+	assert.Equal(t, "https://wolt.com", cfg.WoltBaseAddr)
+	require.NoError(t, cfg.Validate())
+
+	_, err := New(cfg, nil, nil, nil, "UBOT", nil)
+	require.NoError(t, err)
+}
+
+func TestConfigValidate(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name   string
+		modify func(*Config)
+		err    string
+	}{
+		{"negative duration", func(cfg *Config) { cfg.OrderDoneTimeout = -time.Hour }, "ORDER_DONE_TIMEOUT must not be negative but got -1h0m0s"},
+		{"bad hour", func(cfg *Config) { cfg.InsightsHour = 24 }, "INSIGHTS_HOUR must be between 0 and 23 but got 24"},
+		{"retry durations", func(cfg *Config) { cfg.WoltHTTPMaxRetryDuration = time.Millisecond }, "WOLT_HTTP_MAX_RETRY_DURATION (1ms) must not be shorter than WOLT_HTTP_MIN_RETRY_DURATION (1s)"},
+		{"bad clock", func(cfg *Config) { cfg.DontJoinAfter = "25:00" }, `parsing DONT_JOIN_AFTER: expected HH:MM but got "25:00"`},
+		{"bad timezone", func(cfg *Config) { cfg.DontJoinAfterTZ = "Mars/Olympus" }, `parsing DONT_JOIN_AFTER_TZ: unknown timezone "Mars/Olympus"`},
+		{"bad channel timezone", func(cfg *Config) { cfg.ChannelTimezones = []string{"C1"} }, "parsing CHANNEL_TIMEZONES"},
+		{"bad strategy", func(cfg *Config) { cfg.FeeAllocationStrategy = "random" }, "parsing FEE_ALLOCATION_STRATEGY"},
+		{"bad rounding", func(cfg *Config) { cfg.AmountRounding = -1 }, "parsing AMOUNT_ROUNDING"},
+		{"bad rounding remainder", func(cfg *Config) { cfg.RoundingRemainder = "nobody" }, "parsing ROUNDING_REMAINDER"},
+		{"bad locale", func(cfg *Config) { cfg.Locale = "xx" }, "parsing LOCALE"},
+		{"bad subsidy percent", func(cfg *Config) { cfg.SubsidyPercent = 120 }, "SUBSIDY_PERCENT must be between 0 and 100 but got 120.00"},
+		{"subsidy cap without subsidy", func(cfg *Config) { cfg.SubsidyMonthlyCap = 500 }, "SUBSIDY_MONTHLY_CAP requires SUBSIDY_AMOUNT or SUBSIDY_PERCENT"},
+		{"negative tracked orders", func(cfg *Config) { cfg.MaxTrackedOrders = -1 }, "MAX_TRACKED_ORDERS must not be negative but got -1"},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := DefaultConfig()
+			tc.modify(&cfg)
+			assert.ErrorContains(t, cfg.Validate(), tc.err)
+			_, err := New(cfg, nil, nil, nil, "UBOT", nil)
+			assert.ErrorContains(t, err, tc.err)
+		})
+	}
+}
+
+func TestParseUnits(t *testing.T) {
+	t.Parallel()
+
+	clock, err := ParseClock("13:30")
+	require.NoError(t, err)
+	assert.Equal(t, 13, clock.Hour())
+	assert.Equal(t, 30, clock.Minute())
+
+	tz, err := ParseTimezone("Asia/Jerusalem")
+	require.NoError(t, err)
+	assert.Equal(t, "Asia/Jerusalem", tz.String())
+
+	duration, err := ParseDuration("1h30m")
+	require.NoError(t, err)
+	assert.Equal(t, 90*time.Minute, duration)
+	_, err = ParseDuration("-1h")
+	assert.ErrorContains(t, err, "non-negative")
+	_, err = ParseDuration("soon")
+	assert.Error(t, err)
+}
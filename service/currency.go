@@ -0,0 +1,126 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/oriser/bolt/order"
+)
+
+// DefaultCurrency is the currency of orders which Wolt doesn't tell the currency of, unless CURRENCY is set
+const DefaultCurrency = "ILS"
+
+// currencyNames are the names of currencies in the messages of each locale, by their ISO 4217 code. Other currencies are written
+// by their code.
+var currencyNames = map[Locale]map[string]string{
+	LocaleEnglish: {"ILS": "NIS"},
+	LocaleHebrew:  {"ILS": `ש"ח`},
+}
+
+func parseCurrency(code string) (string, error) {
+	if code == "" {
+		return DefaultCurrency, nil
+	}
+	code = strings.ToUpper(code)
+	if !currencyRe.MatchString(code) {
+		return "", fmt.Errorf("%q is not a currency code (for example EUR)", code)
+	}
+	return code, nil
+}
+
+// CurrencyUnit returns how the currency (by its ISO 4217 code) is written after amounts in reports, like NIS. An empty currency
+// is DefaultCurrency.
+func CurrencyUnit(currency string) string {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	if name, ok := currencyNames[LocaleEnglish][currency]; ok {
+		return name
+	}
+	return currency
+}
+
+// FormatAmount formats the amount in the currency (by its ISO 4217 code) the way debts are written, like 12.50 nis. An empty
+// currency is DefaultCurrency.
+func FormatAmount(amount float64, currency string) string {
+	unit := CurrencyUnit(currency)
+	if currency == "" || currency == DefaultCurrency {
+		unit = strings.ToLower(unit)
+	}
+	return fmt.Sprintf("%.2f %s", amount, unit)
+}
+
+// FormatDebtsTotal formats the total amount of the debts, summed per currency, like 12.50 nis + 4.00 EUR
+func FormatDebtsTotal(debts []TreasuryDebt) string {
+	totals := make(map[string]float64)
+	for _, d := range debts {
+		currency := d.Debt.Currency
+		if currency == "" {
+			currency = DefaultCurrency
+		}
+		totals[currency] += d.Debt.Amount
+	}
+	if len(totals) == 0 {
+		return FormatAmount(0, "")
+	}
+	currencies := make([]string, 0, len(totals))
+	for currency := range totals {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	formatted := make([]string, len(currencies))
+	for i, currency := range currencies {
+		formatted[i] = FormatAmount(totals[currency], currency)
+	}
+	return strings.Join(formatted, " + ")
+}
+
+// currencyOrDefault returns the currency, or CURRENCY if it's empty
+func (h *Service) currencyOrDefault(currency string) string {
+	if currency == "" {
+		return h.currency
+	}
+	return currency
+}
+
+// currencyName returns the name of the currency in the channel's locale
+func (h *Service) currencyName(channel, currency string) string {
+	currency = h.currencyOrDefault(currency)
+	if name, ok := currencyNames[h.channelLocale(channel)][currency]; ok {
+		return name
+	}
+	return currency
+}
+
+// venueCurrency returns the currency of a venue's prices, or CURRENCY if the provider doesn't tell it
+func (h *Service) venueCurrency(currency string) string {
+	return h.currencyOrDefault(strings.ToUpper(currency))
+}
+
+// orderCurrency returns the currency of the order from its details, then from its venue, or CURRENCY if the provider doesn't tell it
+func (h *Service) orderCurrency(order *groupOrder, details *OrderDetails) string {
+	if details != nil && details.Currency != "" {
+		return strings.ToUpper(details.Currency)
+	}
+	if order.venue == nil {
+		return h.currency
+	}
+	return h.venueCurrency(order.venue.Currency)
+}
+
+// ordersCurrency returns the currency the amounts of the orders are reported in: their currency if they're all in the same one,
+// or CURRENCY
+func (h *Service) ordersCurrency(orders []*order.Order) string {
+	currency := ""
+	for _, o := range orders {
+		switch {
+		case o.Currency == "":
+		case currency == "":
+			currency = o.Currency
+		case currency != o.Currency:
+			return h.currency
+		}
+	}
+	return h.currencyOrDefault(currency)
+}
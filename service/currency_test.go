@@ -0,0 +1,69 @@
+package service
+
+import (
+	"testing"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatAmount(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "12.50 nis", FormatAmount(12.5, "ILS"))
+	assert.Equal(t, "12.50 nis", FormatAmount(12.5, ""))
+	assert.Equal(t, "12.50 EUR", FormatAmount(12.5, "EUR"))
+	assert.Equal(t, "NIS", CurrencyUnit(""))
+	assert.Equal(t, "SEK", CurrencyUnit("SEK"))
+
+	assert.Equal(t, "0.00 nis", FormatDebtsTotal(nil))
+	assert.Equal(t, "4.00 EUR + 30.00 nis", FormatDebtsTotal([]TreasuryDebt{
+		{Debt: &debtDomain.Debt{Amount: 10, Currency: "ILS"}},
+		{Debt: &debtDomain.Debt{Amount: 4, Currency: "EUR"}},
+		{Debt: &debtDomain.Debt{Amount: 20}},
+	}))
+}
+
+func TestParseCurrency(t *testing.T) {
+	t.Parallel()
+
+	currency, err := parseCurrency("")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultCurrency, currency)
+
+	currency, err = parseCurrency("eur")
+	require.NoError(t, err)
+	assert.Equal(t, "EUR", currency)
+
+	_, err = parseCurrency("euro")
+	assert.Error(t, err)
+}
+
+func TestOrderCurrency(t *testing.T) {
+	t.Parallel()
+
+	h, err := New(Config{Currency: "SEK"}, &fakeTreasuryStore{}, nil, nil, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+
+	order := &groupOrder{}
+	assert.Equal(t, "SEK", h.orderCurrency(order, &OrderDetails{}), "the configured currency is the fallback")
+	order.venue = &Venue{Currency: "eur"}
+	assert.Equal(t, "EUR", h.orderCurrency(order, &OrderDetails{}))
+	assert.Equal(t, "NOK", h.orderCurrency(order, &OrderDetails{Currency: "NOK"}))
+}
+
+func TestRatesMessageCurrency(t *testing.T) {
+	t.Parallel()
+
+	h, err := New(Config{ChannelLocales: []string{"C-he=he"}}, &fakeTreasuryStore{}, nil, nil, "UBOT",
+		&recordingNotification{})
+	require.NoError(t, err)
+	groupRate := GroupRate{HostWoltUser: "Thor", DeliveryRate: 3, Currency: "EUR", Rates: []Rate{{WoltName: "Loki", Amount: 30}}}
+	assert.Equal(t, "Rates for Wolt order ID ABC (including 3 EUR for delivery):\n"+
+		"Loki: 30.00\n"+
+		"\nPay to: Thor\n", h.buildRatesMessage("C1", groupRate, "ABC"))
+
+	groupRate.Currency = "ILS"
+	assert.Contains(t, h.buildRatesMessage("C-he", groupRate, "ABC"), `(כולל 3 ש"ח משלוח)`)
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	"github.com/oriser/bolt/order"
+)
+
+// DayOrderStatus is the stage an order of the day view is in
+type DayOrderStatus string
+
+const (
+	DayOrderOpen       DayOrderStatus = "open"       // Waiting for everyone to be ready
+	DayOrderDelivering DayOrderStatus = "delivering" // The rates were published and the order is on its way
+	DayOrderDelivered  DayOrderStatus = "delivered"
+	DayOrderCanceled   DayOrderStatus = "canceled"
+	DayOrderStopped    DayOrderStatus = "stopped" // Bolt stopped tracking the order before it was delivered
+)
+
+// DayOrder is an order of the day view: a tracked order or a stored one of today
+type DayOrder struct {
+	OrderID   string // The Wolt group ID
+	VenueName string
+	Status    DayOrderStatus
+	Total     float64 // 0 until the rates of a tracked order are published
+	Currency  string
+	Owing     []TreasuryDebt // The outstanding debts of the order
+}
+
+func storedOrderStatus(status order.Status) DayOrderStatus {
+	switch status {
+	case order.StatusCanceled:
+		return DayOrderCanceled
+	case order.StatusStopped:
+		return DayOrderStopped
+	default:
+		return DayOrderDelivered
+	}
+}
+
+// DayView returns the orders of the channel Bolt currently tracks, followed by the rest of today's orders (in the channel's timezone)
+// from the newest to the oldest, each with who still owes for it
+func (h *Service) DayView(ctx context.Context, channel string) ([]DayOrder, error) {
+	dayOrders := make([]DayOrder, 0)
+	seen := make(map[string]bool)
+	for _, activeOrder := range h.ActiveOrders() {
+		if activeOrder.Channel != channel {
+			continue
+		}
+		dayOrder := DayOrder{OrderID: activeOrder.ID, VenueName: activeOrder.VenueName, Status: DayOrderOpen, Currency: h.currency}
+		if activeOrder.Rates != nil {
+			dayOrder.Status = DayOrderDelivering
+			dayOrder.Currency = h.currencyOrDefault(activeOrder.Rates.Currency)
+			if !activeOrder.Rates.hasPrivateAmounts() {
+				for _, rate := range activeOrder.Rates.Rates {
+					dayOrder.Total += rate.Amount
+				}
+			}
+		}
+		dayOrders = append(dayOrders, dayOrder)
+		seen[activeOrder.ID] = true
+	}
+
+	if h.orderStore != nil {
+		orders, err := h.OrdersOfDay(ctx, channel, 0)
+		if err != nil {
+			return nil, fmt.Errorf("orders of today: %w", err)
+		}
+		for _, o := range orders {
+			if seen[o.OriginalID] {
+				continue
+			}
+			dayOrder := DayOrder{
+				OrderID:   o.OriginalID,
+				VenueName: o.VenueName,
+				Status:    storedOrderStatus(o.Status),
+				Currency:  h.currencyOrDefault(o.Currency),
+			}
+			if !o.HasPrivateAmounts() {
+				dayOrder.Total = o.TotalAmount()
+			}
+			dayOrders = append(dayOrders, dayOrder)
+			seen[o.OriginalID] = true
+		}
+	}
+
+	if h.debtStore == nil || len(dayOrders) == 0 {
+		return dayOrders, nil
+	}
+	debts, err := h.debtStore.ListDebts(ctx, debtDomain.ListFilter{Channel: channel})
+	if err != nil {
+		return nil, fmt.Errorf("list debts: %w", err)
+	}
+	dayDebts := make([]*debtDomain.Debt, 0)
+	for _, d := range debts {
+		if seen[d.OrderID] {
+			dayDebts = append(dayDebts, d)
+		}
+	}
+	owing := make(map[string][]TreasuryDebt)
+	for _, d := range h.debtsWithUsers(ctx, dayDebts) {
+		owing[d.Debt.OrderID] = append(owing[d.Debt.OrderID], d)
+	}
+	for i := range dayOrders {
+		dayOrders[i].Owing = owing[dayOrders[i].OrderID]
+	}
+	return dayOrders, nil
+}
+
+// BuildDayViewMessage returns the summary of the orders of the day view
+func BuildDayViewMessage(orders []DayOrder) string {
+	if len(orders) == 0 {
+		return "No orders today"
+	}
+	var sb strings.Builder
+	sb.WriteString(":calendar: Today's orders:\n")
+	for _, o := range orders {
+		sb.WriteString(fmt.Sprintf("• %s (%s)", o.VenueName, o.Status))
+		if o.Total > 0 {
+			sb.WriteString(fmt.Sprintf(" - %.2f %s", o.Total, CurrencyUnit(o.Currency)))
+		}
+		if len(o.Owing) == 0 {
+			sb.WriteString("\n")
+			continue
+		}
+		owing := make([]string, len(o.Owing))
+		for i, d := range o.Owing {
+			amount := FormatAmount(d.Debt.Amount, d.Debt.Currency)
+			if d.Private {
+				amount = ":lock:"
+			}
+			owing[i] = fmt.Sprintf("%s (%s)", balanceUserMention(d.Borrower, d.Debt.BorrowerID), amount)
+		}
+		sb.WriteString(fmt.Sprintf(", still owing: %s\n", strings.Join(owing, ", ")))
+	}
+	return sb.String()
+}
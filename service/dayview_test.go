@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	"github.com/oriser/bolt/order"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDayView(t *testing.T) {
+	t.Parallel()
+
+	tz, err := time.LoadLocation("Asia/Jerusalem")
+	require.NoError(t, err)
+	today := dayStart(time.Now().In(tz))
+	orderStore := &fakeOrderStore{orders: []*order.Order{
+		{OriginalID: "A", Receiver: "C1", VenueName: "Pizza", Status: order.StatusDone, CreatedAt: today.Add(time.Minute),
+			Participants: []order.Participant{{Name: "Loki", Amount: 30}, {Name: "Thor", Amount: 20}}},
+		{OriginalID: "B", Receiver: "C1", VenueName: "Sushi", Status: order.StatusCanceled, CreatedAt: today.Add(2 * time.Minute)},
+		{OriginalID: "C", Receiver: "C1", VenueName: "Falafel", Status: order.StatusDone, CreatedAt: today.Add(-time.Minute)},
+		{OriginalID: "D", Receiver: "C2", VenueName: "Burger", Status: order.StatusDone, CreatedAt: today.Add(time.Minute)},
+	}}
+	store := &fakeTreasuryStore{
+		users: map[string]*userDomain.User{
+			"U1": {ID: "U1", FullName: "Loki", TransportID: "U1"},
+			"U2": {ID: "U2", FullName: "Thor", TransportID: "U2"},
+		},
+		debts: []*debtDomain.Debt{
+			{ID: "d1", BorrowerID: "U1", LenderID: "U2", OrderID: "A", Amount: 30, InitiatedTransportID: "C1", Currency: "ILS"},
+			{ID: "d2", BorrowerID: "U2", LenderID: "U1", OrderID: "C", Amount: 10, InitiatedTransportID: "C1", Currency: "ILS"},
+			{ID: "d3", BorrowerID: "U1", LenderID: "U2", OrderID: "E", Amount: 12, InitiatedTransportID: "C1", Currency: "ILS"},
+		},
+	}
+	h, err := New(Config{ChannelTimezones: []string{"C1=Asia/Jerusalem"}}, store, store, orderStore, "U-bot",
+		&recordingNotification{})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	now := time.Now()
+	h.activeOrders.onEvent(ctx, Event{Type: EventOrderJoined, OrderID: "E", Channel: "C1", VenueName: "Salad", Time: now})
+	h.activeOrders.onEvent(ctx, Event{Type: EventRatesPublished, OrderID: "E", Rates: &GroupRate{Rates: []Rate{{WoltName: "Loki", Amount: 12}}}})
+	h.activeOrders.onEvent(ctx, Event{Type: EventOrderJoined, OrderID: "F", Channel: "C1", VenueName: "Noodles", Time: now.Add(time.Minute)})
+	h.activeOrders.onEvent(ctx, Event{Type: EventOrderJoined, OrderID: "G", Channel: "C2", VenueName: "Tacos", Time: now})
+
+	dayOrders, err := h.DayView(ctx, "C1")
+	require.NoError(t, err)
+	require.Len(t, dayOrders, 4)
+	assert.Equal(t, "E", dayOrders[0].OrderID)
+	assert.Equal(t, DayOrderDelivering, dayOrders[0].Status)
+	assert.Equal(t, DayOrderOpen, dayOrders[1].Status)
+	assert.Equal(t, DayOrderCanceled, dayOrders[2].Status)
+	assert.Equal(t, DayOrderDelivered, dayOrders[3].Status)
+	assert.Empty(t, dayOrders[2].Owing)
+
+	assert.Equal(t, ":calendar: Today's orders:\n"+
+		"• Salad (delivering) - 12.00 NIS, still owing: <@U1> (12.00 nis)\n"+
+		"• Noodles (open)\n"+
+		"• Sushi (canceled)\n"+
+		"• Pizza (delivered) - 50.00 NIS, still owing: <@U1> (30.00 nis)\n", BuildDayViewMessage(dayOrders))
+	assert.Equal(t, "No orders today", BuildDayViewMessage(nil))
+}
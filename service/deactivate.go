@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	userDomain "github.com/oriser/bolt/user"
+)
+
+// SetUserDeactivated deactivates the user (by transport ID) who left the company, so they aren't matched to new orders or reminded
+// about their debts, or reactivates them. Their past orders and debts are kept intact.
+func (h *Service) SetUserDeactivated(ctx context.Context, transportID string, deactivated bool) (*userDomain.User, error) {
+	store, ok := h.userStore.(userDomain.DeactivationStore)
+	if !ok {
+		return nil, fmt.Errorf("deactivating users is not supported")
+	}
+	users, err := h.userStore.ListUsers(ctx, userDomain.ListFilter{TransportID: transportID})
+	if err != nil {
+		return nil, fmt.Errorf("list users: %w", err)
+	}
+	if len(users) == 0 {
+		return nil, fmt.Errorf("no user with transport ID %s", transportID)
+	}
+
+	var deactivatedAt *time.Time
+	if deactivated {
+		now := time.Now()
+		deactivatedAt = &now
+	}
+	var updated *userDomain.User
+	for _, u := range users {
+		if err := store.SetUserDeactivatedAt(ctx, u.ID, deactivatedAt); err != nil {
+			// The user may only be known to the transport (e.g. Slack) and not to the store
+			continue
+		}
+		u.DeactivatedAt = deactivatedAt
+		if updated == nil {
+			updated = u
+		}
+	}
+	if updated != nil || !deactivated {
+		if updated == nil {
+			// Users who aren't in the store are never deactivated
+			updated = users[0]
+		}
+		return updated, nil
+	}
+
+	// Keep a deactivated copy of a user who is only known to the transport, so it takes precedence over the transport's user
+	deactivatedUser := *users[0]
+	deactivatedUser.DeactivatedAt = deactivatedAt
+	if err := h.userStore.AddUser(ctx, &deactivatedUser); err != nil {
+		return nil, fmt.Errorf("add deactivated user: %w", err)
+	}
+	return &deactivatedUser, nil
+}
+
+// flagDeactivated tells the host about Wolt names in the order which belong to users who left the company, as they're treated as
+// unknown participants
+func (h *Service) flagDeactivated(channel, messageID string, groupRate GroupRate) {
+	if len(groupRate.DeactivatedParticipants) == 0 {
+		return
+	}
+
+	message := fmt.Sprintf("Heads up, %s in the order belong to users who left the company, so I don't know who they are. "+
+		"Please make sure it's not a mistake", strings.Join(groupRate.DeactivatedParticipants, ", "))
+	receiver, threadID := channel, messageID
+	if groupRate.HostUser != nil {
+		receiver, threadID = groupRate.HostUser.TransportID, ""
+	}
+	if _, err := h.informEvent(receiver, message, "", threadID); err != nil {
+		h.logger.Error("Error flagging deactivated users in order", "channel", channel, "message_id", messageID, "error", err)
+	}
+}
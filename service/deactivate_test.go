@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDeactivationStore can only deactivate the stored users, the other users are only known to the transport
+type fakeDeactivationStore struct {
+	fakeTreasuryStore
+	stored map[string]bool
+}
+
+func (f *fakeDeactivationStore) AddUser(ctx context.Context, user *userDomain.User) error {
+	f.stored[user.ID] = true
+	return f.fakeTreasuryStore.AddUser(ctx, user)
+}
+
+func (f *fakeDeactivationStore) SetUserDeactivatedAt(_ context.Context, id string, deactivatedAt *time.Time) error {
+	if !f.stored[id] {
+		return fmt.Errorf("user not found")
+	}
+	f.users[id].DeactivatedAt = deactivatedAt
+	return nil
+}
+
+func TestDeactivatedUsers(t *testing.T) {
+	t.Parallel()
+
+	notification := &recordingNotification{}
+	store := &fakeDeactivationStore{
+		fakeTreasuryStore: fakeTreasuryStore{users: map[string]*userDomain.User{
+			"U-host": {ID: "U-host", FullName: "Thor", TransportID: "U-host"},
+			"uuid-1": {ID: "uuid-1", FullName: "Loki", TransportID: "U1"},
+			"U2":     {ID: "U2", FullName: "Odin", TransportID: "U2"},
+		}},
+		stored: map[string]bool{"uuid-1": true},
+	}
+	h, err := New(Config{}, store, nil, nil, "U-bot", notification)
+	require.NoError(t, err)
+
+	deactivated, err := h.SetUserDeactivated(context.Background(), "U1", true)
+	require.NoError(t, err)
+	assert.Equal(t, "uuid-1", deactivated.ID)
+	assert.True(t, store.users["uuid-1"].Deactivated())
+
+	// Users who are only known to the transport are kept deactivated in the store
+	deactivated, err = h.SetUserDeactivated(context.Background(), "U2", true)
+	require.NoError(t, err)
+	assert.True(t, deactivated.Deactivated())
+	assert.True(t, store.stored["U2"])
+	assert.True(t, store.users["U2"].Deactivated())
+
+	_, err = h.SetUserDeactivated(context.Background(), "U3", true)
+	assert.ErrorContains(t, err, "no user with transport ID U3")
+
+	groupRate := h.buildGroupRates(map[string]float64{"Loki": 30, "Thor": 20}, "Thor", 0)
+	assert.Equal(t, []string{"Loki"}, groupRate.DeactivatedParticipants)
+	assert.Nil(t, groupRate.Rates[0].User, "deactivated users should be treated as unknown participants")
+	h.flagDeactivated("C1", "1.1", groupRate)
+	assert.Equal(t, []string{"U-host: Heads up, Loki in the order belong to users who left the company, so I don't know who they are. " +
+		"Please make sure it's not a mistake"}, notification.messages)
+
+	reminded, err := h.remindDebt(&debtDomain.Debt{BorrowerID: "uuid-1", LenderID: "U-host", OrderID: "A", Amount: 30})
+	require.NoError(t, err)
+	assert.Nil(t, reminded)
+	assert.Len(t, notification.messages, 1, "deactivated users shouldn't be reminded about their debts")
+
+	_, err = h.SetUserDeactivated(context.Background(), "U1", false)
+	require.NoError(t, err)
+	assert.False(t, store.users["uuid-1"].Deactivated())
+	assert.Empty(t, h.buildGroupRates(map[string]float64{"Loki": 30}, "Thor", 0).DeactivatedParticipants)
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/oriser/bolt/order"
+	"github.com/oriser/bolt/wolt"
+)
+
+// VenueDeal is the promotions a favorite venue of a channel currently offers
+type VenueDeal struct {
+	VenueName  string
+	Promotions []string
+}
+
+type favoriteVenue struct {
+	name   string
+	link   string
+	orders int
+}
+
+// favoriteVenues returns the venues the channel ordered from the most, up to DEALS_FAVORITE_VENUES
+func (h *Service) favoriteVenues(ctx context.Context, channel string) ([]*favoriteVenue, error) {
+	orders, err := h.orderStore.ListOrders(ctx, order.ListFilter{Receiver: channel})
+	if err != nil {
+		return nil, fmt.Errorf("list orders: %w", err)
+	}
+
+	venues := make(map[string]*favoriteVenue)
+	for _, o := range orders {
+		if o.Status != order.StatusDone || o.VenueLink == "" {
+			continue
+		}
+		slug := venueSlug(o.VenueLink)
+		if _, ok := venues[slug]; !ok {
+			venues[slug] = &favoriteVenue{name: o.VenueName, link: o.VenueLink}
+		}
+		venues[slug].orders++
+	}
+
+	favorites := make([]*favoriteVenue, 0, len(venues))
+	for _, venue := range venues {
+		favorites = append(favorites, venue)
+	}
+	sort.Slice(favorites, func(i, j int) bool {
+		if favorites[i].orders != favorites[j].orders {
+			return favorites[i].orders > favorites[j].orders
+		}
+		return favorites[i].name < favorites[j].name
+	})
+	if len(favorites) > h.cfg.DealsFavoriteVenues {
+		favorites = favorites[:h.cfg.DealsFavoriteVenues]
+	}
+	return favorites, nil
+}
+
+// TodayDeals returns the promotions the favorite venues of the channel currently offer on Wolt
+func (h *Service) TodayDeals(ctx context.Context, channel string) ([]VenueDeal, error) {
+	if h.orderStore == nil {
+		return nil, fmt.Errorf("no order store")
+	}
+	favorites, err := h.favoriteVenues(ctx, channel)
+	if err != nil {
+		return nil, fmt.Errorf("favorite venues: %w", err)
+	}
+
+	addr, retryConfig := h.woltConfig()
+	deals := make([]VenueDeal, 0)
+	for _, favorite := range favorites {
+		venue, err := wolt.VenueBySlug(ctx, addr, retryConfig, venueSlug(favorite.link))
+		if err != nil {
+			h.logger.ErrorContext(ctx, "Error getting venue for its deals", "venue", favorite.name, "error", err)
+			continue
+		}
+		if promotions := venue.Promotions(); len(promotions) > 0 {
+			deals = append(deals, VenueDeal{VenueName: venue.Name, Promotions: promotions})
+		}
+	}
+	return deals, nil
+}
+
+func buildDealsMessage(deals []VenueDeal) string {
+	lines := make([]string, len(deals))
+	for i, deal := range deals {
+		lines[i] = fmt.Sprintf(":moneybag: Deal today at [%s]: %s", deal.VenueName, strings.Join(deal.Promotions, "; "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (h *Service) postDeals(ctx context.Context, channel string) {
+	deals, err := h.TodayDeals(ctx, channel)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "Error getting the deals", "channel", channel, "error", err)
+		return
+	}
+	if len(deals) == 0 {
+		return
+	}
+	if err := h.informNonUrgent(channel, buildDealsMessage(deals), ""); err != nil {
+		h.logger.ErrorContext(ctx, "Error posting deals", "channel", channel, "error", err)
+	}
+}
+
+// dayStart returns the start of the day of the given time, in its location
+func dayStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// RunDealsWatcher posts the promotions of the favorite venues of each of the DEALS_CHANNELS, every day at DEALS_HOUR, until the
+// context is done
+func (h *Service) RunDealsWatcher(ctx context.Context) {
+	if len(h.cfg.DealsChannels) == 0 || h.cfg.DealsFavoriteVenues == 0 || h.orderStore == nil {
+		return
+	}
+
+	ticker := time.NewTicker(badgesSchedulerInterval)
+	defer ticker.Stop()
+
+	h.schedulers.beat("deals watcher", badgesSchedulerInterval)
+	lastCheck := time.Now()
+	for {
+		select {
+		case now := <-ticker.C:
+			for _, channel := range h.cfg.DealsChannels {
+				tz := h.timezoneForChannel(channel, nil)
+				postAt := dayStart(now.In(tz)).Add(time.Duration(h.cfg.DealsHour) * time.Hour)
+				if postAt.After(lastCheck) && !postAt.After(now) {
+					h.postDeals(ctx, channel)
+				}
+			}
+			lastCheck = now
+			h.schedulers.beat("deals watcher", badgesSchedulerInterval)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
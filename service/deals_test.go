@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/oriser/bolt/order"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func dealsVenueJSON(name, discounts string) string {
+	return fmt.Sprintf(`{"results": [{"location": {"coordinates": [32.08, 34.78]}, "timezone": "Asia/Jerusalem",
+		"name": [{"lang": "he", "value": "%[1]s בעברית"}, {"lang": "en", "value": "%[1]s"}], "discounts": [%[2]s]}]}`, name, discounts)
+}
+
+func TestTodayDeals(t *testing.T) {
+	t.Parallel()
+
+	venues := map[string]string{
+		"pizza-place": dealsVenueJSON("Pizza Place", `{"description": [{"lang": "he", "value": "משלוח חינם"}, {"lang": "en", "value": "Free delivery"}]},
+			{"description": [{"lang": "en", "value": "20% off pizzas"}]}`),
+		"burger-place":  dealsVenueJSON("Burger Place", ""),
+		"falafel-place": dealsVenueJSON("Falafel Place", `{"description": [{"lang": "en", "value": "1+1 on pitas"}]}`),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		venue, ok := venues[strings.TrimPrefix(r.URL.Path, "/v3/venues/slug/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(venue))
+	}))
+	defer server.Close()
+
+	doneOrder := func(channel, venueName, slug string) *order.Order {
+		return &order.Order{Receiver: channel, Status: order.StatusDone, VenueName: venueName,
+			VenueLink: "https://wolt.com/en/isr/tel-aviv/restaurant/" + slug}
+	}
+	store := &fakeOrderStore{orders: []*order.Order{
+		doneOrder("C1", "Burger Place", "burger-place"),
+		doneOrder("C1", "Pizza Place", "pizza-place"),
+		doneOrder("C1", "Burger Place", "burger-place"),
+		doneOrder("C1", "Pizza Place", "pizza-place"),
+		doneOrder("C1", "Pizza Place", "pizza-place"),
+		doneOrder("C1", "Falafel Place", "falafel-place"),
+		doneOrder("C2", "Falafel Place", "falafel-place"),
+		{Receiver: "C1", Status: order.StatusCanceled, VenueName: "Falafel Place", VenueLink: "https://wolt.com/en/isr/tel-aviv/restaurant/falafel-place"},
+	}}
+	notification := &recordingNotification{}
+	h, err := New(Config{WoltBaseAddr: server.URL, WoltApiBaseAddr: server.URL, DealsFavoriteVenues: 2},
+		nil, nil, store, "UBOT", notification)
+	require.NoError(t, err)
+
+	deals, err := h.TodayDeals(context.Background(), "C1")
+	require.NoError(t, err)
+	assert.Equal(t, []VenueDeal{{VenueName: "Pizza Place", Promotions: []string{"Free delivery", "20% off pizzas"}}}, deals,
+		"only the favorite venues with promotions should have deals")
+
+	h.postDeals(context.Background(), "C1")
+	h.postDeals(context.Background(), "C2")
+	h.postDeals(context.Background(), "C3")
+	assert.Equal(t, []string{
+		"C1: :moneybag: Deal today at [Pizza Place]: Free delivery; 20% off pizzas",
+		"C2: :moneybag: Deal today at [Falafel Place]: 1+1 on pitas",
+	}, notification.messages)
+}
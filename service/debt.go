@@ -4,10 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"time"
 
 	debtDomain "github.com/oriser/bolt/debt"
+	"github.com/oriser/bolt/logging"
 	userDomain "github.com/oriser/bolt/user"
 	"github.com/oriser/regroup"
 )
@@ -18,52 +18,58 @@ const NoMessagesAfterHour = 21
 const NoMessagesBeforeHour = 9
 
 func (h *Service) HandleReactionAdded(req ReactionAddRequest) (string, error) {
+	h.recordActivity(req.FromUserID)
+	if req.Reaction == h.channelEmoji(req.Channel, settingBlacklistConfirmEmoji, h.cfg.BlacklistConfirmationEmoji) &&
+		(h.handleBlacklistConfirmation(req) || h.handlePreauthConfirmation(req)) {
+		return "", nil
+	}
+	if req.Reaction == h.channelEmoji(req.Channel, settingSkipOrderEmoji, h.cfg.SkipOrderEmoji) {
+		h.handleSkipReaction(req)
+		return "", nil
+	}
+	if req.Reaction == h.channelEmoji(req.Channel, settingCompanyPaidEmoji, h.cfg.CompanyPaidEmoji) {
+		h.handleCompanyPaidReaction(req)
+		return "", nil
+	}
+	if req.Reaction == h.cfg.MergeOfferEmoji && h.handleMergeReaction(req) {
+		return "", nil
+	}
 	if h.debtStore == nil {
 		return "", nil
 	}
+	ctx, cancel := h.storeContext()
+	defer cancel()
+	if response, ok, err := h.handlePaymentClaimReaction(ctx, req); ok {
+		return response, err
+	}
 	// Pay attention that I may get notified about any reaction (to any message), so react just for those came to message from my user ID
 	if (req.Reaction != MarkAsPaidReaction && req.Reaction != HostRemoveDebts) || req.MessageUserID != h.selfID {
 		return "", nil
 	}
 
-	parsedID := &ParsedWoltGroupID{}
-	if err := groupFromMessageRe.MatchToTarget(req.MessageText, parsedID); err != nil {
-		if errors.Is(err, &regroup.NoMatchFoundError{}) {
-			// React to non rates message
-			log.Println("Got reaction for non rates message, ignoring")
-			return "", nil
+	// The order of the rates messages Bolt sent is known, so the reaction applies to that order alone when the channel has others
+	orderID, ok := h.orderMessages.lookup(req.Channel, req.MessageID)
+	if !ok {
+		parsedID := &ParsedWoltGroupID{}
+		if err := groupFromMessageRe.MatchToTarget(req.MessageText, parsedID); err != nil {
+			if errors.Is(err, &regroup.NoMatchFoundError{}) {
+				// React to non rates message
+				h.logger.Debug("Got reaction for non rates message, ignoring", "channel", req.Channel, "message_id", req.MessageID)
+				return "", nil
+			}
+			return "", fmt.Errorf("regroup match to target: %w", err)
 		}
-		return "", fmt.Errorf("regroup match to target: %w", err)
+		orderID = parsedID.ID
 	}
 
 	switch req.Reaction {
 	case MarkAsPaidReaction:
-		if err := h.markDebtAsPaid(parsedID.ID, req.FromUserID, req.Channel); err != nil {
-			log.Println(fmt.Sprintf("Error marking debt as paid from reaction event: %s", err.Error()))
+		if err := h.markDebtAsPaid(ctx, orderID, req.FromUserID, req.Channel); err != nil {
+			h.logger.Error("Error marking debt as paid from reaction event", "channel", req.Channel, "message_id", req.MessageID, "error", err)
 		}
 		return "", nil
 	case HostRemoveDebts:
-		hostForOrder, err := h.hostForOrderID(parsedID.ID)
-		if err != nil {
-			log.Println("Error getting host for order ID:", err)
-			return "", nil
-		}
-		if hostForOrder == "" {
-			return "", nil
-		}
-
-		hostUser, err := h.userStore.GetUser(context.Background(), hostForOrder)
-		if err != nil {
-			log.Println("Error GetUser for host for order ID:", err)
-			return "", nil
-		}
-		if hostUser.TransportID != req.FromUserID {
-			_, _ = h.informEvent(req.FromUserID, fmt.Sprintf("Nice try :stuck_out_tongue_winking_eye: Only the host (<@%s>) can cancel debts for this order", hostForOrder), "", "")
-			return "", nil
-		}
-		if err := h.removeAllDebtsForOrder(parsedID.ID, "the host requested to cancel debts tracking"); err != nil {
-			log.Println(fmt.Sprintf("Error removing all debts for order ID %s: %v", parsedID.ID, err))
-		}
+		h.cancelDebtsTracking(ctx, orderID, req.FromUserID)
 	}
 
 	return "", nil
@@ -77,87 +83,142 @@ func (h *Service) DebtWorker(ctx context.Context, orderID string) {
 	reminderInterval := time.NewTicker(h.cfg.DebtReminderInterval)
 	defer reminderInterval.Stop()
 
+	// With smart timing, the reminders deferred until the borrowers are active are retried more often
+	var retryDeferred <-chan time.Time
+	if h.cfg.DebtReminderSmartTiming {
+		retryTicker := time.NewTicker(smartTimingRetryInterval)
+		defer retryTicker.Stop()
+		retryDeferred = retryTicker.C
+	}
+
 	for {
 		select {
 		case <-reminderInterval.C:
-			debts, err := h.debtStore.ListDebtsForOrderID(orderID)
+			debts, err := h.debtStore.ListDebtsForOrderID(ctx, orderID)
 			if err != nil {
-				log.Println("Error listing debts:", err)
+				h.logger.ErrorContext(ctx, "Error listing debts", "group_id", orderID, "error", err)
 				continue
 			}
 			if len(debts) == 0 {
 				// No more debts
 				return
 			}
-			for _, debt := range debts {
-				if err := h.remindDebt(debt); err != nil {
-					log.Printf("Reminding about debt: %#v; error: %v\n", debt, err)
-				}
+			h.remindDebts(ctx, debts)
+			h.escalateDebts(ctx, debts, time.Now())
+		case <-retryDeferred:
+			debts, err := h.debtStore.ListDebtsForOrderID(ctx, orderID)
+			if err != nil {
+				h.logger.ErrorContext(ctx, "Error listing debts", "group_id", orderID, "error", err)
+				continue
 			}
+			h.remindDebts(ctx, h.deferredDebts(debts))
 		case <-ctx.Done():
-			if err := h.removeAllDebtsForOrder(orderID, "timeout has been reached"); err != nil {
-				log.Println("Error removing all debts on context cancellation:", err)
+			if h.shuttingDown() {
+				// The debts are kept, the worker only stops
+				return
+			}
+			// The worker's context is done, so the debts are removed in a store call context
+			storeCtx, cancel := h.storeContext()
+			err := h.removeAllDebtsForOrder(logging.CopyAttrs(storeCtx, ctx), orderID, "timeout has been reached")
+			cancel()
+			if err != nil {
+				h.logger.ErrorContext(ctx, "Error removing all debts on context cancellation", "group_id", orderID, "error", err)
 			}
 			return
 		}
 	}
 }
 
-func (h *Service) remindDebt(debt *debtDomain.Debt) error {
-	borrower, err := h.userStore.GetUser(context.Background(), debt.BorrowerID)
+// remindDebt reminds the borrower about the debt, and returns the borrower if they were reminded
+func (h *Service) remindDebt(debt *debtDomain.Debt) (*userDomain.User, error) {
+	if debt.PaidClaimedAt != nil {
+		// The borrower said they paid, and the host didn't answer yet
+		return nil, nil
+	}
+	ctx, cancel := h.storeContext()
+	defer cancel()
+	borrower, err := h.userStore.GetUser(ctx, debt.BorrowerID)
 	if err != nil {
-		return fmt.Errorf("get borrower user: %w", err)
+		return nil, fmt.Errorf("get borrower user: %w", err)
+	}
+	if borrower.Deactivated() {
+		h.logger.Info("Not reminding deactivated user", "name", borrower.FullName, "user_id", borrower.ID)
+		return nil, nil
+	}
+	if h.remindersOptedOut(ctx, borrower.TransportID) {
+		return nil, nil
 	}
 
-	timeAtBorrower := time.Now()
+	borrowerTimezone := h.timezoneForChannel(debt.InitiatedTransportID, nil)
 	if borrower.Timezone != "" {
 		tz, err := time.LoadLocation(borrower.Timezone)
 		if err == nil {
-			timeAtBorrower = timeAtBorrower.In(tz)
+			borrowerTimezone = tz
 		}
 	}
+	timeAtBorrower := time.Now().In(borrowerTimezone)
 
 	if timeAtBorrower.Hour() >= NoMessagesAfterHour || timeAtBorrower.Hour() < NoMessagesBeforeHour {
-		log.Printf("Not reminding in aftertimes for user %q (%s). Timezone at borrower: %s\n", borrower.FullName, borrower.ID, borrower.Timezone)
-		return nil
+		h.logger.Info("Not reminding in aftertimes", "name", borrower.FullName, "user_id", borrower.ID, "timezone", borrower.Timezone)
+		return nil, nil
+	}
+	if h.deferReminder(debt.ID, borrower) {
+		return nil, errReminderDeferred
 	}
 
-	_, _ = h.informEvent(borrower.TransportID,
-		fmt.Sprintf("Reminder, you should pay %.2f nis to <@%s> for Wolt order ID %s.\n"+
-			"If you paid, you can mark yourself as paid by adding :%s: reaction to this message \\ the original rates message.",
-			debt.Amount, debt.LenderID, debt.OrderID, MarkAsPaidReaction),
-		MarkAsPaidReaction, "")
-	return nil
+	note := ""
+	if o := h.storedOrder(ctx, debt.OrderID); o != nil && o.Note != "" {
+		note = fmt.Sprintf("Note from the host: %s\n", o.Note)
+	}
+	// A digest has the reminders of several debts, so only the rates messages can be reacted to
+	reactTo := "this message \\ the original rates message"
+	if h.inDigestMode(ctx, borrower.TransportID) {
+		reactTo = "the original rates message"
+	}
+	markAs := "you can mark yourself as paid by adding"
+	if link := h.paidLinkURL(debt); link != "" {
+		markAs = fmt.Sprintf("you can mark yourself as paid with <%s|this link> or by adding", link)
+	}
+	_ = h.notifyUser(ctx, borrower.TransportID, reminderDigestKey(debt.ID),
+		fmt.Sprintf("Reminder, you should pay %s to <@%s> for Wolt order ID %s.\n"+
+			"The debt was created at %s (%s).\n"+
+			"%s"+
+			"If you paid, %s :%s: reaction to %s.",
+			h.formatDebtAmount(ctx, borrower.TransportID, debt.Amount, debt.Currency), debt.LenderID, debt.OrderID,
+			SlackDate(debt.CreatedAt, "{date_short} {time}", "2006-01-02 15:04", borrowerTimezone), RelativeTime(debt.CreatedAt, time.Now()),
+			note, markAs, MarkAsPaidReaction, reactTo),
+		MarkAsPaidReaction)
+	h.activity.clearDeferred(debt.ID)
+	return borrower, nil
 }
 
-func (h *Service) createDebt(amount float64, initiatedTransport, orderID, messageID string, borrowerUser *userDomain.User, lenderUser *userDomain.User) error {
+func (h *Service) createDebt(ctx context.Context, amount float64, currency, initiatedTransport, orderID, messageID string, borrowerUser *userDomain.User, lenderUser *userDomain.User) (*debtDomain.Debt, error) {
 	if h.debtStore == nil {
-		return nil
+		return nil, nil
 	}
 
 	debt := debtDomain.NewDebt(borrowerUser.ID, lenderUser.ID, orderID, initiatedTransport, messageID, amount)
-	if err := h.debtStore.AddDebt(debt); err != nil {
-		return fmt.Errorf("add debt: %w", err)
+	debt.Currency = h.currencyOrDefault(currency)
+	if err := h.debtStore.AddDebt(ctx, debt); err != nil {
+		return nil, fmt.Errorf("add debt: %w", err)
 	}
+	h.hooks.Emit(ctx, Event{Type: EventDebtCreated, OrderID: orderID, Channel: initiatedTransport, MessageID: messageID, Debt: debt})
 
-	return nil
+	return debt, nil
 }
 
-func (h *Service) addDebts(initiatedTransport, orderID string, rates GroupRate, messageID string) error {
+func (h *Service) addDebts(ctx context.Context, initiatedTransport, orderID string, rates GroupRate, messageID string) error {
 	if h.debtStore == nil {
 		return nil
 	}
 
 	if rates.HostUser == nil {
-		_, _ = h.informEvent(initiatedTransport, fmt.Sprintf("I didn't find the user of the host (%s), I won't track debts for order %s", rates.HostWoltUser, orderID), "", messageID)
+		_, _ = h.informEvent(initiatedTransport, h.text(initiatedTransport, msgHostNotFound, rates.HostWoltUser, orderID), "", messageID)
 		return nil
 	}
 
 	_, _ = h.informEvent(initiatedTransport,
-		fmt.Sprintf("I'll keep reminding you to pay, when you pay you can react with :%s: to the rates message and I'll stop bothering you.\n"+
-			"<@%s>, as the host, you can react with :%s: to the rates message to cancel debts tracking for Wolt order ID %s",
-			MarkAsPaidReaction, rates.HostUser.TransportID, HostRemoveDebts, orderID),
-		"", messageID)
+		h.text(initiatedTransport, msgDebtsTracking, MarkAsPaidReaction, rates.HostUser.TransportID, HostRemoveDebts, orderID), "", messageID)
 
 	for _, rate := range rates.Rates {
 		if rate.WoltName == rates.HostWoltUser {
@@ -166,24 +227,104 @@ func (h *Service) addDebts(initiatedTransport, orderID string, rates GroupRate,
 		}
 
 		if rate.User == nil {
-			_, _ = h.informEvent(initiatedTransport, fmt.Sprintf("I won't track %q payment because I can't find his user.", rate.WoltName), "", messageID)
+			h.handleUnknownParticipant(ctx, initiatedTransport, orderID, messageID, rates.Currency, rate, rates.HostUser)
 			continue
 		}
-		if err := h.createDebt(rate.Amount, initiatedTransport, orderID, messageID, rate.User, rates.HostUser); err != nil {
-			log.Println(fmt.Sprintf("Error creating debt for user %q in order ID %q: %v", rate.WoltName, orderID, err))
+		debt, err := h.createDebt(ctx, rate.PersonalAmount(), rates.Currency, initiatedTransport, orderID, messageID, rate.User, rates.HostUser)
+		if err != nil {
+			h.logger.Error("Error creating debt", "wolt_name", rate.WoltName, "group_id", orderID, "error", err)
 			continue
 		}
+		h.sendDebtDM(ctx, initiatedTransport, orderID, rates, rate, debt)
 	}
 
-	//goland:noinspection ALL
-	ctx, _ := context.WithTimeout(context.Background(), h.cfg.DebtMaximumDuration) // nolint
-	go h.DebtWorker(ctx, orderID)
-
+	h.startDebtWorker(orderID)
 	return nil
 }
 
-func (h *Service) hostForOrderID(orderID string) (string, error) {
-	debts, err := h.debtStore.ListDebtsForOrderID(orderID)
+// startDebtWorker reminds about the debts of the order until they're paid or DEBT_MAXIMUM_DURATION passes, unless the debts are
+// handled by the debt scheduler
+func (h *Service) startDebtWorker(orderID string) {
+	if h.noDebtWorkers {
+		return
+	}
+	ctx, cancel := context.WithTimeout(h.lifetime(), h.cfg.DebtMaximumDuration)
+	go func() {
+		defer cancel()
+		defer h.trackMonitor(ctx, monitorKindDebts, orderID)()
+		h.DebtWorker(ctx, orderID)
+	}()
+}
+
+// DisableDebtWorkers stops starting a debt worker for every order, for when the debts are handled by RunDebtScheduler of another process
+func (h *Service) DisableDebtWorkers() {
+	h.noDebtWorkers = true
+}
+
+// RunDebtScheduler reminds about all the unpaid debts every DEBT_REMINDER_INTERVAL since they were created, and removes the debts
+// of orders after DEBT_MAXIMUM_DURATION, until the context is done.
+// Unlike the per-order debt workers, it only depends on the store, so it can run in a separate process.
+func (h *Service) RunDebtScheduler(ctx context.Context) {
+	if h.debtStore == nil {
+		return
+	}
+
+	ticker := time.NewTicker(h.cfg.DebtSchedulerInterval)
+	defer ticker.Stop()
+
+	h.schedulers.beat("debt scheduler", h.cfg.DebtSchedulerInterval)
+	lastCheck := time.Now()
+	for {
+		select {
+		case now := <-ticker.C:
+			h.handleScheduledDebts(ctx, lastCheck, now)
+			lastCheck = now
+			h.schedulers.beat("debt scheduler", h.cfg.DebtSchedulerInterval)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// reminderDue returns whether a reminder interval since the creation time ended between from and to
+func reminderDue(createdAt, from, to time.Time, interval time.Duration) bool {
+	if interval <= 0 || to.Sub(createdAt) < interval {
+		return false
+	}
+	return to.Sub(createdAt)/interval > from.Sub(createdAt)/interval
+}
+
+func (h *Service) handleScheduledDebts(ctx context.Context, from, to time.Time) {
+	debts, err := h.debtStore.ListDebts(ctx, debtDomain.ListFilter{})
+	if err != nil {
+		h.logger.Error("Error listing debts", "error", err)
+		return
+	}
+
+	expiredOrders := make(map[string]bool)
+	due, unexpired := make([]*debtDomain.Debt, 0), make([]*debtDomain.Debt, 0, len(debts))
+	for _, debt := range debts {
+		if to.Sub(debt.CreatedAt) >= h.cfg.DebtMaximumDuration {
+			expiredOrders[debt.OrderID] = true
+			continue
+		}
+		unexpired = append(unexpired, debt)
+		if reminderDue(debt.CreatedAt, from, to, h.cfg.DebtReminderInterval) || h.activity.reminderDeferred(debt.ID) {
+			due = append(due, debt)
+		}
+	}
+	h.remindDebts(ctx, due)
+	h.escalateDebts(ctx, unexpired, to)
+
+	for orderID := range expiredOrders {
+		if err := h.removeAllDebtsForOrder(ctx, orderID, "timeout has been reached"); err != nil {
+			h.logger.Error("Error removing all debts for expired order", "group_id", orderID, "error", err)
+		}
+	}
+}
+
+func (h *Service) hostForOrderID(ctx context.Context, orderID string) (string, error) {
+	debts, err := h.debtStore.ListDebtsForOrderID(ctx, orderID)
 	if err != nil {
 		return "", fmt.Errorf("list debts: %w", err)
 	}
@@ -193,12 +334,37 @@ func (h *Service) hostForOrderID(orderID string) (string, error) {
 	return debts[0].LenderID, nil
 }
 
-func (h *Service) removeAllDebtsForOrder(orderID, reason string) error {
+// cancelDebtsTracking removes all the debts of the order if the user (by transport ID) is its host or the host's co-host
+func (h *Service) cancelDebtsTracking(ctx context.Context, orderID, fromUserID string) {
+	hostForOrder, err := h.hostForOrderID(ctx, orderID)
+	if err != nil {
+		h.logger.Error("Error getting host of order", "group_id", orderID, "error", err)
+		return
+	}
+	if hostForOrder == "" {
+		return
+	}
+
+	hostUser, err := h.getUser(hostForOrder)
+	if err != nil {
+		h.logger.Error("Error getting the host user of order", "group_id", orderID, "error", err)
+		return
+	}
+	if !h.actsForHost(hostUser.TransportID, fromUserID) {
+		_, _ = h.informInteractiveEvent(fromUserID, h.text(fromUserID, msgCancelDebtsHostOnly, hostForOrder), "")
+		return
+	}
+	if err := h.removeAllDebtsForOrder(ctx, orderID, "the host requested to cancel debts tracking"); err != nil {
+		h.logger.Error("Error removing all debts", "group_id", orderID, "error", err)
+	}
+}
+
+func (h *Service) removeAllDebtsForOrder(ctx context.Context, orderID, reason string) error {
 	if h.debtStore == nil {
 		return nil
 	}
 
-	debts, err := h.debtStore.ListDebtsForOrderID(orderID)
+	debts, err := h.debtStore.ListDebtsForOrderID(ctx, orderID)
 	if err != nil {
 		return fmt.Errorf("list debts: %w", err)
 	}
@@ -208,21 +374,33 @@ func (h *Service) removeAllDebtsForOrder(orderID, reason string) error {
 
 	lender := debts[0].LenderID
 	for _, debt := range debts {
-		if err := h.debtStore.RemoveDebtInOrderID(orderID, debt.ID); err != nil {
+		if err := h.debtStore.RemoveDebtInOrderID(ctx, orderID, debt.ID); err != nil {
 			return fmt.Errorf("remove debt: %w", err)
 		}
+		h.activity.clearDeferred(debt.ID)
 	}
 
-	_, _ = h.informEvent(lender, fmt.Sprintf("I removed all debts for order ID %s because %s", orderID, reason), "", "")
+	_, _ = h.informEvent(lender, h.text(lender, msgDebtsRemoved, orderID, reason), "", "")
+	h.hooks.Emit(ctx, Event{Type: EventOrderDebtsRemoved, OrderID: orderID, Channel: debts[0].InitiatedTransportID, MessageID: debts[0].MessageID, Reason: reason})
 	return nil
 }
 
-func (h *Service) markDebtAsPaid(orderID, reactedTransportID, initialChannel string) error {
+// settleDebt removes the paid debt and announces its payment
+func (h *Service) settleDebt(ctx context.Context, debt *debtDomain.Debt) error {
+	if err := h.debtStore.RemoveDebtInOrderID(ctx, debt.OrderID, debt.ID); err != nil {
+		return fmt.Errorf("remove debt: %w", err)
+	}
+	h.activity.clearDeferred(debt.ID)
+	h.hooks.Emit(ctx, Event{Type: EventDebtPaid, OrderID: debt.OrderID, Channel: debt.InitiatedTransportID, MessageID: debt.MessageID, Debt: debt})
+	return nil
+}
+
+func (h *Service) markDebtAsPaid(ctx context.Context, orderID, reactedTransportID, initialChannel string) error {
 	if h.debtStore == nil {
 		return nil
 	}
 
-	debts, err := h.debtStore.ListDebtsForOrderID(orderID)
+	debts, err := h.debtStore.ListDebtsForOrderID(ctx, orderID)
 	if err != nil {
 		return fmt.Errorf("list debts: %w", err)
 	}
@@ -231,36 +409,44 @@ func (h *Service) markDebtAsPaid(orderID, reactedTransportID, initialChannel str
 	}
 
 	for _, debt := range debts {
-		borrower, err := h.userStore.GetUser(context.Background(), debt.BorrowerID)
+		borrower, err := h.getUser(debt.BorrowerID)
 		if err != nil {
-			log.Println(fmt.Sprintf("Error getting borrower user with id %s: %s", debt.BorrowerID, err.Error()))
+			h.logger.Error("Error getting borrower user", "user_id", debt.BorrowerID, "error", err)
 			continue
 		}
 		if borrower.TransportID != reactedTransportID {
 			// The reacted user is not the user owned the debt
 			continue
 		}
+		return h.payDebt(ctx, debt, borrower, initialChannel)
+	}
 
-		if err := h.debtStore.RemoveDebtInOrderID(orderID, debt.ID); err != nil {
-			return fmt.Errorf("remove debt: %w", err)
-		}
+	return nil
+}
 
-		_, _ = h.informEvent(borrower.TransportID, fmt.Sprintf("OK! I removed your debt for order %s", debt.OrderID), "", "")
+// payDebt settles the debt the borrower marked as paid and tells the lender, or asks the lender to confirm the payment first with
+// PAYMENT_CONFIRMATION
+func (h *Service) payDebt(ctx context.Context, debt *debtDomain.Debt, borrower *userDomain.User, initialChannel string) error {
+	if h.cfg.PaymentConfirmation {
+		return h.claimDebtPaid(ctx, debt, borrower)
+	}
 
-		// Notify in the initial channel of the wolt link message in case we will get error getting the host details
-		recipient := initialChannel
-		messageID := debt.MessageID
-		lender, err := h.userStore.GetUser(context.Background(), debt.LenderID)
-		if err != nil {
-			log.Println(fmt.Sprintf("Error getting lender user with id %s: %s", debt.LenderID, err.Error()))
-		} else {
-			recipient = lender.TransportID
-			messageID = ""
-		}
+	if err := h.settleDebt(ctx, debt); err != nil {
+		return err
+	}
+	_, _ = h.informInteractiveEvent(borrower.TransportID, fmt.Sprintf("OK! I removed your debt for order %s", debt.OrderID), "")
 
-		_, _ = h.informEvent(recipient, fmt.Sprintf("<@%s> marked himself as paid for order ID %s", borrower.TransportID, debt.OrderID), "", messageID)
-		return nil
+	// Notify in the initial channel of the wolt link message in case we will get error getting the host details
+	recipient := initialChannel
+	messageID := debt.MessageID
+	lender, err := h.getUser(debt.LenderID)
+	if err != nil {
+		h.logger.Error("Error getting lender user", "user_id", debt.LenderID, "error", err)
+	} else {
+		recipient = lender.TransportID
+		messageID = ""
 	}
 
+	_, _ = h.informEvent(recipient, fmt.Sprintf("<@%s> marked himself as paid for order ID %s", borrower.TransportID, debt.OrderID), "", messageID)
 	return nil
 }
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReminderDue(t *testing.T) {
+	t.Parallel()
+
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		from, to time.Duration // Since the creation time
+		expected bool
+	}{
+		{name: "Before the first interval", from: 0, to: 2 * time.Hour, expected: false},
+		{name: "First interval ended", from: 2 * time.Hour, to: 3*time.Hour + time.Minute, expected: true},
+		{name: "Within the second interval", from: 3*time.Hour + time.Minute, to: 4 * time.Hour, expected: false},
+		{name: "Second interval ended", from: 5*time.Hour + 59*time.Minute, to: 6 * time.Hour, expected: true},
+		{name: "Checked after a long pause", from: time.Hour, to: 10 * time.Hour, expected: true},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, reminderDue(createdAt, createdAt.Add(tc.from), createdAt.Add(tc.to), 3*time.Hour))
+		})
+	}
+}
+
+func TestHandleScheduledDebtsRemovesExpired(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	store := &fakeTreasuryStore{
+		debts: []*debtDomain.Debt{
+			{ID: "1", BorrowerID: "U1", LenderID: "U2", OrderID: "expired", CreatedAt: now.Add(-25 * time.Hour)},
+			{ID: "2", BorrowerID: "U3", LenderID: "U2", OrderID: "expired", CreatedAt: now.Add(-25 * time.Hour)},
+			{ID: "3", BorrowerID: "U1", LenderID: "U2", OrderID: "active", CreatedAt: now.Add(-time.Hour)},
+		},
+		users: map[string]*userDomain.User{},
+	}
+	notification := &recordingNotification{}
+	h := &Service{
+		logger:            slog.Default(),
+		cfg:               Config{DebtReminderInterval: 3 * time.Hour, DebtMaximumDuration: 24 * time.Hour},
+		debtStore:         store,
+		userStore:         store,
+		eventNotification: notification,
+		hooks:             NewHooks(),
+		activity:          newUserActivity(),
+	}
+
+	h.handleScheduledDebts(context.Background(), now.Add(-time.Minute), now)
+	if assert.Len(t, store.debts, 1) {
+		assert.Equal(t, "3", store.debts[0].ID)
+	}
+	assert.Equal(t, []string{"U2: I removed all debts for order ID expired because timeout has been reached"}, notification.messages)
+}
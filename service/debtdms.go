@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	userDomain "github.com/oriser/bolt/user"
+)
+
+func (h *Service) debtDMsStore() (userDomain.DebtDMsStore, error) {
+	store, ok := h.userStore.(userDomain.DebtDMsStore)
+	if !ok {
+		return nil, fmt.Errorf("choosing whether to get debt DMs is not supported")
+	}
+	return store, nil
+}
+
+// SetDebtDMs sets whether the user (by transport ID) gets a direct message with their amount of every order they owe for,
+// overriding DEBT_DMS
+func (h *Service) SetDebtDMs(ctx context.Context, transportID string, enabled bool) error {
+	store, err := h.debtDMsStore()
+	if err != nil {
+		return err
+	}
+	if err := store.SetDebtDMs(ctx, transportID, enabled); err != nil {
+		return fmt.Errorf("set debt DMs: %w", err)
+	}
+	return nil
+}
+
+// debtDMsEnabled returns whether the user (by transport ID) gets the debt DMs: by their choice, or by DEBT_DMS if they didn't choose
+func (h *Service) debtDMsEnabled(transportID string) bool {
+	store, err := h.debtDMsStore()
+	if err != nil {
+		return h.cfg.DebtDMs
+	}
+	ctx, cancel := h.storeContext()
+	defer cancel()
+	enabled, chosen, err := store.DebtDMs(ctx, transportID)
+	if err != nil {
+		h.logger.Error("Error checking if the user gets debt DMs", "transport_id", transportID, "error", err)
+		return h.cfg.DebtDMs
+	}
+	if !chosen {
+		return h.cfg.DebtDMs
+	}
+	return enabled
+}
+
+// sendDebtDM tells the borrower of the new debt their amount of the order, the host to pay and the host's preferred payment methods,
+// if the borrower gets the debt DMs or keeps their amounts private. The message is kept like the reminders for borrowers in digest mode, and replaced by the debt's
+// reminders.
+func (h *Service) sendDebtDM(ctx context.Context, channel, orderID string, rates GroupRate, rate Rate, debt *debtDomain.Debt) {
+	if debt == nil || rates.HostUser == nil || rate.User == nil || rate.PersonalAmount() <= 0 ||
+		(!rate.Private && !h.debtDMsEnabled(rate.User.TransportID)) {
+		return
+	}
+	host := rates.HostUser
+	methods := ""
+	if preferred := host.PreferredPaymentMethods(); len(preferred) > 0 {
+		names := make([]string, len(preferred))
+		for i, method := range preferred {
+			names[i] = method.String()
+		}
+		methods = h.text(channel, msgDebtDMMethods, host.TransportID, strings.Join(names, ", "))
+	}
+	text := h.text(channel, msgDebtDM, rate.PersonalAmount(), h.currencyName(channel, rates.Currency), host.TransportID, orderID, channel,
+		h.ratePaymentLink(channel, rates, rate)+methods, MarkAsPaidReaction)
+	if err := h.notifyUser(ctx, rate.User.TransportID, reminderDigestKey(debt.ID), text, ""); err != nil {
+		h.logger.Error("Error sending the debt DM", "transport_id", rate.User.TransportID, "group_id", orderID, "error", err)
+	}
+}
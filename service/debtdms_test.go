@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeDebtDMsStore struct {
+	*fakeTreasuryStore
+	choices map[string]bool
+}
+
+func (f *fakeDebtDMsStore) SetDebtDMs(_ context.Context, transportID string, enabled bool) error {
+	f.choices[transportID] = enabled
+	return nil
+}
+
+func (f *fakeDebtDMsStore) DebtDMs(_ context.Context, transportID string) (bool, bool, error) {
+	enabled, chosen := f.choices[transportID]
+	return enabled, chosen, nil
+}
+
+func TestDebtDMs(t *testing.T) {
+	t.Parallel()
+
+	treasuryStore := &fakeTreasuryStore{users: map[string]*userDomain.User{
+		"u1": {ID: "u1", FullName: "Thor", TransportID: "U1",
+			PaymentPreferences: []userDomain.PaymentMethod{userDomain.PaymentMethodBit, userDomain.PaymentMethodPaybox}},
+		"u2": {ID: "u2", FullName: "Loki", TransportID: "U2"},
+		"u3": {ID: "u3", FullName: "Odin", TransportID: "U3"},
+		"u4": {ID: "u4", FullName: "Frigg", TransportID: "U4"},
+	}}
+	store := &fakeDebtDMsStore{fakeTreasuryStore: treasuryStore, choices: make(map[string]bool)}
+	notification := &recordingNotification{}
+	h, err := New(Config{DebtDMs: true}, store, treasuryStore, nil, "UBOT", notification)
+	require.NoError(t, err)
+	h.DisableDebtWorkers()
+	require.NoError(t, h.SetDebtDMs(context.Background(), "U3", false))
+
+	groupRate := h.buildGroupRates(map[string]float64{"Thor": 50, "Loki": 30.5, "Odin": 20, "Frigg": 0}, "Thor", 0)
+	require.NoError(t, h.addDebts(context.Background(), "C1", "ABC", groupRate, "1.1"))
+	assert.Contains(t, notification.messages, "U2: :receipt: You owe 30.50 NIS to <@U1> for Wolt order ID ABC in <#C1>.\n"+
+		"<@U1> prefers to be paid with Bit, Paybox\nWhen you pay, react with :"+MarkAsPaidReaction+": to the rates message")
+	for _, message := range notification.messages {
+		assert.NotContains(t, message, "U3: ", "Odin opted out")
+		assert.NotContains(t, message, "U4: ", "Frigg owes nothing")
+	}
+
+	notification.messages = nil
+	h.cfg.DebtDMs = false
+	require.NoError(t, h.SetDebtDMs(context.Background(), "U3", true))
+	require.NoError(t, h.addDebts(context.Background(), "C1", "XYZ", groupRate, "2.1"))
+	var dms []string
+	for _, message := range notification.messages {
+		if message[0] == 'U' {
+			dms = append(dms, message)
+		}
+	}
+	require.Len(t, dms, 1, "only who opted in gets the DMs when they're off by default")
+	assert.Contains(t, dms[0], "U3: :receipt: You owe 20.00 NIS")
+}
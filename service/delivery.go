@@ -6,11 +6,9 @@ import (
 	"math"
 	"strings"
 	"time"
-
-	"github.com/oriser/bolt/wolt"
 )
 
-func (h *Service) buildProgressEmojiArt(startedAt time.Time, deliveryEta time.Time, timezone *time.Location) string {
+func (h *Service) buildProgressEmojiArt(channel string, startedAt time.Time, deliveryEta time.Time, timezone *time.Location) string {
 	const (
 		numberOfSpacesBetweenTimes           = 23
 		numberOfSpacesBeforeDestinationEmoji = 3
@@ -23,8 +21,8 @@ func (h *Service) buildProgressEmojiArt(startedAt time.Time, deliveryEta time.Ti
 	var sb strings.Builder
 
 	// Use Slack's date formatting to display times at the recipient's timezone
-	deliveryEtaString := fmt.Sprintf("<!date^%d^{time}|%s>", deliveryEta.Unix(), deliveryEta.In(timezone).Format("15:04"))
-	startedAtString := fmt.Sprintf("<!date^%d^{time}|%s>", startedAt.Unix(), startedAt.In(timezone).Format("15:04"))
+	deliveryEtaString := SlackDate(deliveryEta, "{time}", "15:04", timezone)
+	startedAtString := SlackDate(startedAt, "{time}", "15:04", timezone)
 
 	// Due to Slack emoji constraints, the courier advances from right (venue) to left (destination)
 	firstLine := fmt.Sprintf(
@@ -38,7 +36,7 @@ func (h *Service) buildProgressEmojiArt(startedAt time.Time, deliveryEta time.Ti
 	deliveryPercentage := math.Min(time.Since(startedAt).Seconds()/deliveryEta.Sub(startedAt).Seconds(), 1)
 	numberOfRoadTilesBehindCourier := int(math.Round(deliveryPercentage * numberOfRoadTiles))
 	secondLine := strings.Repeat(" ", numberOfSpacesBeforeDestinationEmoji) +
-		fmt.Sprintf(":%s:", h.cfg.OrderDestinationEmoji) +
+		fmt.Sprintf(":%s:", h.channelEmoji(channel, settingDestinationEmoji, h.cfg.OrderDestinationEmoji)) +
 		strings.Repeat(roadTileAsciiArt, numberOfRoadTiles-numberOfRoadTilesBehindCourier) +
 		CourierEmoji +
 		strings.Repeat(roadTileAsciiArt, numberOfRoadTilesBehindCourier) +
@@ -48,7 +46,8 @@ func (h *Service) buildProgressEmojiArt(startedAt time.Time, deliveryEta time.Ti
 	return sb.String()
 }
 
-func (h *Service) updateDeliveryProgressMessage(initiatedTransport string, order *groupOrder, details *wolt.OrderDetails, ratesMessage string) error {
+func (h *Service) updateDeliveryProgressMessage(initiatedTransport string, order *groupOrder, details *OrderDetails, groupRate GroupRate,
+	ratesMessage string) error {
 	var err error
 
 	if IsUnixZero(details.PurchaseDatetime) {
@@ -56,10 +55,9 @@ func (h *Service) updateDeliveryProgressMessage(initiatedTransport string, order
 	}
 
 	var deliveryTime time.Time
-	var deliveryTimeExists bool
 	if details.IsDelivered() {
-		deliveryTime, deliveryTimeExists = details.Purchase.DeliveryStatusLog["delivered"]
-		if !deliveryTimeExists {
+		deliveryTime = details.DeliveredAt
+		if deliveryTime.IsZero() {
 			deliveryTime = time.Now()
 		}
 	} else if !IsUnixZero(details.DeliveryEta) {
@@ -69,58 +67,89 @@ func (h *Service) updateDeliveryProgressMessage(initiatedTransport string, order
 		return nil
 	}
 
-	err = h.eventNotification.EditMessage(
-		initiatedTransport,
-		strings.TrimSuffix(ratesMessage, "\n")+"\n\n"+h.buildProgressEmojiArt(details.PurchaseDatetime, deliveryTime, order.venue.TimezoneLocation),
-		order.detailsMessageId)
+	timezone := h.timezoneForChannel(initiatedTransport, order.venue.Timezone)
+	progress := h.buildProgressEmojiArt(initiatedTransport, details.PurchaseDatetime, deliveryTime, timezone)
+	if h.accessible(initiatedTransport) {
+		progress = h.buildProgressText(initiatedTransport, details.PurchaseDatetime, deliveryTime, timezone)
+	}
+	err = h.editRatesMessage(initiatedTransport, order, groupRate, ratesMessage, progress)
 	if err != nil {
+		h.checkTransportError(initiatedTransport, err)
 		return fmt.Errorf("updating details message %s: %w", order.detailsMessageId, err)
 	}
 
 	return err
 }
 
-func (h *Service) monitorDelivery(initiatedTransport string, order *groupOrder, ctx context.Context, waitBetweenStatusCheck time.Duration, messageID string, ratesMessage string) error {
+// monitorDelivery monitors the delivery of the order with the state machine, until the delivery arrives or is canceled
+func (h *Service) monitorDelivery(initiatedTransport string, order *groupOrder, ctx context.Context, stateMachine *DeliveryStateMachine,
+	waitBetweenStatusCheck time.Duration, messageID string, groupRate *GroupRate, ratesMessage string) error {
+	defer h.trackMonitor(ctx, monitorKindDelivery, order.id)()
 	details, err := order.fetchDetails()
 	if err != nil {
 		return fmt.Errorf("get group details: %w", err)
 	}
 
-	getReadyMessageSent := false
-	for details.Status != wolt.StatusCanceled {
-		err = h.updateDeliveryProgressMessage(initiatedTransport, order, details, ratesMessage)
-		if err != nil {
-			return err
+	stateMachine.OnGetReady(func(details *OrderDetails, timeToDelivery time.Duration) {
+		var venueTimezone *time.Location
+		if order.venue != nil {
+			venueTimezone = order.venue.Timezone
+		}
+		etaString := SlackDate(details.DeliveryEta, "{time}", "15:04", h.timezoneForChannel(initiatedTransport, venueTimezone))
+		_, _ = h.informEvent(initiatedTransport, h.text(initiatedTransport, msgDeliverySoon, etaString, RelativeTime(details.DeliveryEta, time.Now())), "", messageID)
+	})
+	updater := h.newDeliveryUpdater(initiatedTransport, messageID)
+	stateMachine.OnTransition(updater.onTransition)
+	splitDelivery := h.newSplitDeliveryTracker(initiatedTransport, messageID)
+	stateMachine.OnTransition(func(transition DeliveryTransition) {
+		if transition.To == DeliveryStateDelivered && !stateMachine.GetReadySent() {
+			_, _ = h.informEvent(initiatedTransport, h.text(initiatedTransport, msgDeliveryArrived), "", messageID)
 		}
 
-		if details.IsDelivered() {
-			if !getReadyMessageSent {
-				_, _ = h.informEvent(initiatedTransport, "Delivery arrived", "", messageID)
-				getReadyMessageSent = true //nolint:ineffassign
-			}
-			return nil
-		} else if !IsUnixZero(details.DeliveryEta) {
-			timeToDelivery := time.Until(details.DeliveryEta)
-			if !getReadyMessageSent && timeToDelivery < h.cfg.TimeTillGetReadyMessage {
-				_, _ = h.informEvent(initiatedTransport, "Get ready, delivery coming soon", "", messageID)
-				getReadyMessageSent = true
+		event := Event{Type: EventDeliveryProgress, OrderID: order.id, Channel: initiatedTransport, MessageID: messageID, State: transition.To}
+		if transition.To == DeliveryStateDelivered {
+			event.Type = EventOrderDelivered
+		}
+		if order.venue != nil {
+			event.VenueName = order.venue.Name
+		}
+		h.hooks.Emit(ctx, event)
+	})
+
+	reopened := false
+	for {
+		if details.Status.Purchased() {
+			reopened = false
+			ratesMessage = h.reconcileRates(initiatedTransport, order, details, groupRate, messageID, ratesMessage)
+		} else if details.Status == OrderStatusActive && !reopened {
+			// The checkout didn't go through and the group is open again, so the participants can change their items until it's
+			// sent again. The rates are reconciled once it is, rather than on every change of the carts.
+			reopened = true
+			_, _ = h.informEvent(initiatedTransport, h.text(initiatedTransport, msgOrderReopened), "", messageID)
+		}
+		if details.Status != OrderStatusCanceled {
+			if err = h.updateDeliveryProgressMessage(initiatedTransport, order, details, *groupRate, ratesMessage); err != nil {
+				return err
 			}
 		}
 
-		select {
-		case <-time.After(waitBetweenStatusCheck):
-			details, err = order.fetchDetails()
-			if err != nil {
-				return fmt.Errorf("get group details: %w", err)
+		now := time.Now()
+		switch stateMachine.Advance(details, now) {
+		case DeliveryStateDelivered:
+			venueName := ""
+			if order.venue != nil {
+				venueName = order.venue.Name
 			}
-		case <-ctx.Done():
-			return fmt.Errorf("context canceled while waiting for group to progress")
+			h.learnDeliveryDuration(order.id, venueName, details, now)
+			return nil
+		case DeliveryStateCanceled:
+			return ErrOrderCanceled
 		}
-	}
+		splitDelivery.onDetails(details)
+		updater.onDetails(details, now)
 
-	if details.Status == wolt.StatusCanceled {
-		return fmt.Errorf("order canceled")
+		if details, err = h.pollDetails(ctx, order, waitBetweenStatusCheck); err != nil {
+			return err
+		}
 	}
-
-	return nil
 }
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"time"
+)
+
+type DeliveryState int
+
+const (
+	DeliveryStateUnknown DeliveryState = iota
+	DeliveryStateReceived
+	DeliveryStateProduction
+	DeliveryStatePickup
+	DeliveryStateDelivered
+	DeliveryStateCanceled
+)
+
+var deliveryStatesString = map[DeliveryState]string{
+	DeliveryStateUnknown:    "unknown",
+	DeliveryStateReceived:   "received",
+	DeliveryStateProduction: "production",
+	DeliveryStatePickup:     "pickup",
+	DeliveryStateDelivered:  "delivered",
+	DeliveryStateCanceled:   "canceled",
+}
+
+func (s DeliveryState) String() string {
+	return deliveryStatesString[s]
+}
+
+// ParseDeliveryState returns the delivery state by its name, or DeliveryStateUnknown if there's no such state
+func ParseDeliveryState(name string) DeliveryState {
+	for state, stateName := range deliveryStatesString {
+		if stateName == name {
+			return state
+		}
+	}
+	return DeliveryStateUnknown
+}
+
+// Final returns whether no more transitions are expected from that state
+func (s DeliveryState) Final() bool {
+	return s == DeliveryStateDelivered || s == DeliveryStateCanceled
+}
+
+// DeliveryStateFromDetails returns the delivery state the order details represent, without considering any previous state
+func DeliveryStateFromDetails(details *OrderDetails) DeliveryState {
+	if details.Status == OrderStatusCanceled {
+		return DeliveryStateCanceled
+	}
+	if details.IsDelivered() {
+		return DeliveryStateDelivered
+	}
+	if details.IsSplitDelivery() {
+		// The order is as far as its least advanced delivery
+		state := DeliveryStateDelivered
+		for _, delivery := range details.Deliveries {
+			deliveryState := delivery.State
+			if deliveryState == DeliveryStateUnknown {
+				deliveryState = DeliveryStateReceived
+			}
+			if deliveryState < state {
+				state = deliveryState
+			}
+		}
+		return state
+	}
+	if details.DeliveryState != DeliveryStateUnknown {
+		return details.DeliveryState
+	}
+	if details.Status.Purchased() {
+		return DeliveryStateReceived
+	}
+	return DeliveryStateUnknown
+}
+
+type DeliveryTransition struct {
+	From    DeliveryState
+	To      DeliveryState
+	Details *OrderDetails
+	At      time.Time
+}
+
+type DeliveryTransitionHook func(transition DeliveryTransition)
+type GetReadyHook func(details *OrderDetails, timeToDelivery time.Duration)
+
+// DeliveryStateMachine follows the delivery progress of a purchased order.
+// States only move forward (Received→Production→Pickup→Delivered, or to Canceled from any non-final state),
+// so a stale or skipped Wolt status never causes a transition back.
+type DeliveryStateMachine struct {
+	state             DeliveryState
+	getReadyThreshold time.Duration
+	getReadySent      bool
+	transitionHooks   []DeliveryTransitionHook
+	getReadyHooks     []GetReadyHook
+}
+
+func NewDeliveryStateMachine(getReadyThreshold time.Duration) *DeliveryStateMachine {
+	return &DeliveryStateMachine{
+		state:             DeliveryStateUnknown,
+		getReadyThreshold: getReadyThreshold,
+	}
+}
+
+// OnTransition registers a hook called for every state change
+func (m *DeliveryStateMachine) OnTransition(hook DeliveryTransitionHook) {
+	m.transitionHooks = append(m.transitionHooks, hook)
+}
+
+// OnGetReady registers a hook called once, when the delivery ETA is closer than the "get ready" threshold
+func (m *DeliveryStateMachine) OnGetReady(hook GetReadyHook) {
+	m.getReadyHooks = append(m.getReadyHooks, hook)
+}
+
+func (m *DeliveryStateMachine) State() DeliveryState {
+	return m.state
+}
+
+// Resume sets the state of a delivery which was monitored before a restart, so the transitions to it aren't made again
+func (m *DeliveryStateMachine) Resume(state DeliveryState) {
+	if state > m.state {
+		m.state = state
+	}
+}
+
+func (m *DeliveryStateMachine) GetReadySent() bool {
+	return m.getReadySent
+}
+
+// Advance feeds new order details to the state machine, calling the relevant hooks. It returns the current state.
+func (m *DeliveryStateMachine) Advance(details *OrderDetails, now time.Time) DeliveryState {
+	if m.state.Final() {
+		return m.state
+	}
+
+	next := DeliveryStateFromDetails(details)
+	if next > m.state {
+		transition := DeliveryTransition{From: m.state, To: next, Details: details, At: now}
+		m.state = next
+		for _, hook := range m.transitionHooks {
+			hook(transition)
+		}
+	}
+
+	if !m.getReadySent && !m.state.Final() && !IsUnixZero(details.DeliveryEta) {
+		timeToDelivery := details.DeliveryEta.Sub(now)
+		if timeToDelivery < m.getReadyThreshold {
+			m.getReadySent = true
+			for _, hook := range m.getReadyHooks {
+				hook(details, timeToDelivery)
+			}
+		}
+	}
+
+	return m.state
+}
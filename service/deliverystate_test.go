@@ -0,0 +1,125 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oriser/bolt/wolt"
+	"github.com/stretchr/testify/assert"
+)
+
+type detailsStep struct {
+	status         wolt.Status
+	deliveryStatus wolt.DeliveryStatus
+	timeToDelivery time.Duration // Zero means no ETA
+}
+
+// buildDetails returns the details of a Wolt order at the step, as the Wolt provider maps them
+func buildDetails(step detailsStep, now time.Time) *OrderDetails {
+	details := &wolt.OrderDetails{Status: step.status}
+	details.Purchase.DeliveryStatus = step.deliveryStatus
+	details.DeliveryEta = time.Unix(0, 0)
+	if step.timeToDelivery != 0 {
+		details.DeliveryEta = now.Add(step.timeToDelivery)
+	}
+	return woltOrderDetails(details)
+}
+
+func TestDeliveryStateMachine(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name                string
+		steps               []detailsStep
+		expectedTransitions []DeliveryState
+		expectedFinal       DeliveryState
+		expectGetReady      bool
+	}{
+		{
+			name: "Full delivery flow",
+			steps: []detailsStep{
+				{status: wolt.StatusPurchased, deliveryStatus: "received"},
+				{status: wolt.StatusPurchased, deliveryStatus: "production", timeToDelivery: 30 * time.Minute},
+				{status: wolt.StatusPurchased, deliveryStatus: "fetched", timeToDelivery: 5 * time.Minute},
+				{status: wolt.StatusPurchased, deliveryStatus: wolt.DeliveryStatusDelivered},
+			},
+			expectedTransitions: []DeliveryState{DeliveryStateReceived, DeliveryStateProduction, DeliveryStatePickup, DeliveryStateDelivered},
+			expectedFinal:       DeliveryStateDelivered,
+			expectGetReady:      true,
+		},
+		{
+			name: "Skipped statuses",
+			steps: []detailsStep{
+				{status: wolt.StatusPurchased, deliveryStatus: "received"},
+				{status: wolt.StatusPurchased, deliveryStatus: wolt.DeliveryStatusDelivered},
+			},
+			expectedTransitions: []DeliveryState{DeliveryStateReceived, DeliveryStateDelivered},
+			expectedFinal:       DeliveryStateDelivered,
+		},
+		{
+			name: "Stale status doesn't move backwards",
+			steps: []detailsStep{
+				{status: wolt.StatusPurchased, deliveryStatus: "production"},
+				{status: wolt.StatusPurchased, deliveryStatus: "received"},
+			},
+			expectedTransitions: []DeliveryState{DeliveryStateProduction},
+			expectedFinal:       DeliveryStateProduction,
+		},
+		{
+			name: "Unknown Wolt status on purchased order",
+			steps: []detailsStep{
+				{status: wolt.StatusPendingTrans, deliveryStatus: "something_new"},
+			},
+			expectedTransitions: []DeliveryState{DeliveryStateReceived},
+			expectedFinal:       DeliveryStateReceived,
+		},
+		{
+			name: "Canceled",
+			steps: []detailsStep{
+				{status: wolt.StatusPurchased, deliveryStatus: "production"},
+				{status: wolt.StatusCanceled},
+				{status: wolt.StatusPurchased, deliveryStatus: wolt.DeliveryStatusDelivered},
+			},
+			expectedTransitions: []DeliveryState{DeliveryStateProduction, DeliveryStateCanceled},
+			expectedFinal:       DeliveryStateCanceled,
+		},
+		{
+			name: "ETA far away",
+			steps: []detailsStep{
+				{status: wolt.StatusPurchased, deliveryStatus: "production", timeToDelivery: time.Hour},
+			},
+			expectedTransitions: []DeliveryState{DeliveryStateProduction},
+			expectedFinal:       DeliveryStateProduction,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			machine := NewDeliveryStateMachine(7 * time.Minute)
+			transitions := make([]DeliveryState, 0)
+			getReadyCount := 0
+			machine.OnTransition(func(transition DeliveryTransition) {
+				transitions = append(transitions, transition.To)
+			})
+			machine.OnGetReady(func(_ *OrderDetails, _ time.Duration) {
+				getReadyCount++
+			})
+
+			for _, step := range tc.steps {
+				machine.Advance(buildDetails(step, now), now)
+			}
+
+			assert.Equal(t, tc.expectedTransitions, transitions)
+			assert.Equal(t, tc.expectedFinal, machine.State())
+			if tc.expectGetReady {
+				assert.Equal(t, 1, getReadyCount)
+			} else {
+				assert.Zero(t, getReadyCount)
+			}
+		})
+	}
+}
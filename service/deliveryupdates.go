@@ -0,0 +1,87 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// DeliveryUpdates is how much of the delivery progress to post in the thread of the order
+type DeliveryUpdates string
+
+const (
+	DeliveryUpdatesOff    DeliveryUpdates = "off"    // Only the "get ready" and arrival messages
+	DeliveryUpdatesStates DeliveryUpdates = "states" // A message when the restaurant accepts the order, starts preparing it and the courier picks it up
+	DeliveryUpdatesETA    DeliveryUpdates = "eta"    // Same as states, and the ETA every DELIVERY_UPDATES_INTERVAL
+)
+
+func parseDeliveryUpdates(name string) (DeliveryUpdates, error) {
+	switch updates := DeliveryUpdates(name); updates {
+	case "":
+		return DeliveryUpdatesOff, nil
+	case DeliveryUpdatesOff, DeliveryUpdatesStates, DeliveryUpdatesETA:
+		return updates, nil
+	default:
+		return "", fmt.Errorf("unknown delivery updates %q", name)
+	}
+}
+
+var deliveryStateUpdates = map[DeliveryState]string{
+	DeliveryStateReceived:   ":white_check_mark: The restaurant accepted the order",
+	DeliveryStateProduction: ":cook: The restaurant is preparing the order",
+	DeliveryStatePickup:     ":bike: The courier picked up the order",
+}
+
+// deliveryUpdater posts the delivery progress of an order in its thread, throttling the ETA updates
+type deliveryUpdater struct {
+	updates    DeliveryUpdates
+	interval   time.Duration
+	lastUpdate time.Time
+	post       func(text string)
+}
+
+func (h *Service) newDeliveryUpdater(initiatedTransport, messageID string) *deliveryUpdater {
+	return &deliveryUpdater{
+		updates:  h.deliveryUpdates,
+		interval: h.cfg.DeliveryUpdatesInterval,
+		post: func(text string) {
+			_, _ = h.informEvent(initiatedTransport, text, "", messageID)
+		},
+	}
+}
+
+// etaMinutes returns the minutes left until the delivery ETA, or 0 if there's no ETA or it has passed
+func etaMinutes(details *OrderDetails, now time.Time) int {
+	if IsUnixZero(details.DeliveryEta) || !details.DeliveryEta.After(now) {
+		return 0
+	}
+	return int(math.Ceil(details.DeliveryEta.Sub(now).Minutes()))
+}
+
+func (u *deliveryUpdater) onTransition(transition DeliveryTransition) {
+	if u.updates == DeliveryUpdatesOff {
+		return
+	}
+	text, ok := deliveryStateUpdates[transition.To]
+	if !ok {
+		return
+	}
+	if minutes := etaMinutes(transition.Details, transition.At); minutes > 0 {
+		text += fmt.Sprintf(" (ETA %d minutes)", minutes)
+	}
+	u.post(text)
+	u.lastUpdate = transition.At
+}
+
+// onDetails posts the ETA if DELIVERY_UPDATES_INTERVAL passed since the last update
+func (u *deliveryUpdater) onDetails(details *OrderDetails, now time.Time) {
+	if u.updates != DeliveryUpdatesETA || now.Sub(u.lastUpdate) < u.interval {
+		return
+	}
+	minutes := etaMinutes(details, now)
+	if minutes == 0 {
+		return
+	}
+	u.post(fmt.Sprintf(":stopwatch: ETA %d minutes", minutes))
+	u.lastUpdate = now
+}
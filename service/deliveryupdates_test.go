@@ -0,0 +1,92 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oriser/bolt/wolt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeliveryUpdater(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	steps := []struct {
+		step  detailsStep
+		after time.Duration
+	}{
+		{step: detailsStep{status: wolt.StatusPurchased, deliveryStatus: "received"}},
+		{step: detailsStep{status: wolt.StatusPurchased, deliveryStatus: "production", timeToDelivery: 30 * time.Minute}, after: time.Minute},
+		{step: detailsStep{status: wolt.StatusPurchased, deliveryStatus: "production", timeToDelivery: 28 * time.Minute}, after: 3 * time.Minute},
+		{step: detailsStep{status: wolt.StatusPurchased, deliveryStatus: "production", timeToDelivery: 24 * time.Minute}, after: 7 * time.Minute},
+		{step: detailsStep{status: wolt.StatusPurchased, deliveryStatus: "fetched", timeToDelivery: 7 * time.Minute}, after: 8 * time.Minute},
+		{step: detailsStep{status: wolt.StatusPurchased, deliveryStatus: wolt.DeliveryStatusDelivered}, after: 20 * time.Minute},
+	}
+
+	tests := []struct {
+		name     string
+		updates  DeliveryUpdates
+		expected []string
+	}{
+		{
+			name:    "Off",
+			updates: DeliveryUpdatesOff,
+		},
+		{
+			name:    "States",
+			updates: DeliveryUpdatesStates,
+			expected: []string{
+				":white_check_mark: The restaurant accepted the order",
+				":cook: The restaurant is preparing the order (ETA 30 minutes)",
+				":bike: The courier picked up the order (ETA 7 minutes)",
+			},
+		},
+		{
+			name:    "ETA",
+			updates: DeliveryUpdatesETA,
+			expected: []string{
+				":white_check_mark: The restaurant accepted the order",
+				":cook: The restaurant is preparing the order (ETA 30 minutes)",
+				":stopwatch: ETA 24 minutes",
+				":bike: The courier picked up the order (ETA 7 minutes)",
+			},
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var posted []string
+			updater := &deliveryUpdater{updates: tc.updates, interval: 5 * time.Minute, post: func(text string) {
+				posted = append(posted, text)
+			}}
+			stateMachine := NewDeliveryStateMachine(0)
+			stateMachine.OnTransition(updater.onTransition)
+			for _, s := range steps {
+				at := now.Add(s.after)
+				details := buildDetails(s.step, at)
+				stateMachine.Advance(details, at)
+				updater.onDetails(details, at)
+			}
+			assert.Equal(t, tc.expected, posted)
+		})
+	}
+}
+
+func TestParseDeliveryUpdates(t *testing.T) {
+	t.Parallel()
+
+	updates, err := parseDeliveryUpdates("")
+	require.NoError(t, err)
+	assert.Equal(t, DeliveryUpdatesOff, updates)
+
+	updates, err = parseDeliveryUpdates("eta")
+	require.NoError(t, err)
+	assert.Equal(t, DeliveryUpdatesETA, updates)
+
+	_, err = parseDeliveryUpdates("verbose")
+	assert.Error(t, err)
+}
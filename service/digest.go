@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	debtDomain "github.com/oriser/bolt/debt"
+)
+
+func (h *Service) digestStore() (debtDomain.DigestStore, error) {
+	digestStore, ok := h.debtStore.(debtDomain.DigestStore)
+	if !ok {
+		return nil, fmt.Errorf("digests are not supported")
+	}
+	return digestStore, nil
+}
+
+// SetDigest puts the user (by transport ID) in digest mode, so their non-urgent notifications (debts reminders, receipts and
+// insights) are sent together once a day at the given hour. A negative hour turns digest mode off, sending the waiting
+// notifications right away.
+func (h *Service) SetDigest(ctx context.Context, transportID string, hour int) error {
+	digestStore, err := h.digestStore()
+	if err != nil {
+		return err
+	}
+	if hour < 0 {
+		if err := digestStore.RemoveDigestHour(ctx, transportID); err != nil {
+			return fmt.Errorf("remove digest hour: %w", err)
+		}
+		h.sendDigest(ctx, transportID)
+		return nil
+	}
+
+	if hour > 23 {
+		return fmt.Errorf("the hour must be between 0 and 23 but got %d", hour)
+	}
+	if err := digestStore.SetDigestHour(ctx, transportID, hour); err != nil {
+		return fmt.Errorf("set digest hour: %w", err)
+	}
+	return nil
+}
+
+// inDigestMode returns whether the user (by transport ID) gets their non-urgent notifications in a daily digest
+func (h *Service) inDigestMode(ctx context.Context, transportID string) bool {
+	digestStore, err := h.digestStore()
+	if err != nil {
+		return false
+	}
+	hour, err := digestStore.DigestHour(ctx, transportID)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "Error getting the digest hour", "transport_id", transportID, "error", err)
+		return false
+	}
+	return hour >= 0
+}
+
+// notifyUser sends a non-urgent notification to the user (by transport ID), or keeps it for the user's digest in digest mode.
+// A newer notification with the same key replaces the kept one, so the digest has only the latest reminder of each debt.
+func (h *Service) notifyUser(ctx context.Context, transportID, key, text, reactionEmoji string) error {
+	if !h.inDigestMode(ctx, transportID) {
+		return h.informNonUrgent(transportID, text, reactionEmoji)
+	}
+
+	digestStore, err := h.digestStore()
+	if err != nil {
+		return err
+	}
+	notification := &debtDomain.DigestNotification{TransportID: transportID, Key: key, Text: text, CreatedAt: time.Now()}
+	if err := digestStore.AddDigestNotification(ctx, notification); err != nil {
+		return fmt.Errorf("add digest notification: %w", err)
+	}
+	return nil
+}
+
+// dropPaidReminder removes the reminder of a paid debt from the borrower's digest
+func (h *Service) dropPaidReminder(ctx context.Context, event Event) {
+	digestStore, err := h.digestStore()
+	if err != nil || event.Debt == nil || h.userStore == nil {
+		return
+	}
+	borrower, err := h.userStore.GetUser(ctx, event.Debt.BorrowerID)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "Error getting borrower of a paid debt", "user_id", event.Debt.BorrowerID, "error", err)
+		return
+	}
+	if err := digestStore.RemoveDigestNotification(ctx, borrower.TransportID, reminderDigestKey(event.Debt.ID)); err != nil {
+		h.logger.ErrorContext(ctx, "Error removing the reminder of a paid debt from the digest", "debt_id", event.Debt.ID, "transport_id", borrower.TransportID, "error", err)
+	}
+}
+
+func reminderDigestKey(debtID string) string {
+	return "reminder/" + debtID
+}
+
+// BuildDigestMessage returns the digest of the notifications, from the oldest
+func BuildDigestMessage(notifications []*debtDomain.DigestNotification) string {
+	var sb strings.Builder
+	sb.WriteString(":newspaper: Your daily digest:")
+	for _, notification := range notifications {
+		sb.WriteString("\n\n")
+		sb.WriteString(notification.Text)
+	}
+	sb.WriteString("\n\nGet your notifications right away with `/bolt digest off`")
+	return sb.String()
+}
+
+// sendDigest sends the notifications waiting for the user's digest, if there are any
+func (h *Service) sendDigest(ctx context.Context, transportID string) {
+	digestStore, err := h.digestStore()
+	if err != nil {
+		return
+	}
+	notifications, err := digestStore.PopDigestNotifications(ctx, transportID)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "Error getting the digest notifications", "transport_id", transportID, "error", err)
+		return
+	}
+	if len(notifications) == 0 {
+		return
+	}
+	if err := h.informNonUrgent(transportID, BuildDigestMessage(notifications), ""); err != nil {
+		h.logger.ErrorContext(ctx, "Error sending the digest", "transport_id", transportID, "error", err)
+	}
+}
+
+// RunDigestSender sends the digest of every user in digest mode, every day at the user's hour, until the context is done
+func (h *Service) RunDigestSender(ctx context.Context) {
+	digestStore, err := h.digestStore()
+	if err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(badgesSchedulerInterval)
+	defer ticker.Stop()
+
+	h.schedulers.beat("digest sender", badgesSchedulerInterval)
+	lastCheck := time.Now()
+	for {
+		select {
+		case now := <-ticker.C:
+			hours, err := digestStore.ListDigestHours(ctx)
+			if err != nil {
+				h.logger.ErrorContext(ctx, "Error listing digest hours", "error", err)
+			}
+			tz := h.timezoneForChannel("", nil)
+			for transportID, hour := range hours {
+				sendAt := dayStart(now.In(tz)).Add(time.Duration(hour) * time.Hour)
+				if sendAt.After(lastCheck) && !sendAt.After(now) {
+					h.sendDigest(ctx, transportID)
+				}
+			}
+			lastCheck = now
+			h.schedulers.beat("digest sender", badgesSchedulerInterval)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
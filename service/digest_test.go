@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeDigestStore struct {
+	fakeTreasuryStore
+	hours         map[string]int
+	notifications []*debtDomain.DigestNotification
+}
+
+func (f *fakeDigestStore) SetDigestHour(_ context.Context, transportID string, hour int) error {
+	f.hours[transportID] = hour
+	return nil
+}
+
+func (f *fakeDigestStore) DigestHour(_ context.Context, transportID string) (int, error) {
+	if hour, ok := f.hours[transportID]; ok {
+		return hour, nil
+	}
+	return -1, nil
+}
+
+func (f *fakeDigestStore) RemoveDigestHour(_ context.Context, transportID string) error {
+	delete(f.hours, transportID)
+	return nil
+}
+
+func (f *fakeDigestStore) ListDigestHours(_ context.Context) (map[string]int, error) {
+	return f.hours, nil
+}
+
+func (f *fakeDigestStore) AddDigestNotification(ctx context.Context, notification *debtDomain.DigestNotification) error {
+	_ = f.RemoveDigestNotification(ctx, notification.TransportID, notification.Key)
+	f.notifications = append(f.notifications, notification)
+	return nil
+}
+
+func (f *fakeDigestStore) RemoveDigestNotification(_ context.Context, transportID, key string) error {
+	for i, notification := range f.notifications {
+		if notification.TransportID == transportID && notification.Key == key {
+			f.notifications = append(f.notifications[:i], f.notifications[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (f *fakeDigestStore) PopDigestNotifications(_ context.Context, transportID string) ([]*debtDomain.DigestNotification, error) {
+	popped := make([]*debtDomain.DigestNotification, 0)
+	kept := make([]*debtDomain.DigestNotification, 0)
+	for _, notification := range f.notifications {
+		if notification.TransportID == transportID {
+			popped = append(popped, notification)
+		} else {
+			kept = append(kept, notification)
+		}
+	}
+	f.notifications = kept
+	sort.SliceStable(popped, func(i, j int) bool {
+		return popped[i].CreatedAt.Before(popped[j].CreatedAt)
+	})
+	return popped, nil
+}
+
+func TestDigest(t *testing.T) {
+	t.Parallel()
+
+	tz := daytimeTimezone()
+	notification := &recordingNotification{}
+	store := &fakeDigestStore{
+		fakeTreasuryStore: fakeTreasuryStore{users: map[string]*userDomain.User{
+			"uuid-host": {ID: "uuid-host", FullName: "Thor", TransportID: "U-host", Timezone: tz},
+			"U1":        {ID: "U1", FullName: "Loki", TransportID: "U1", Timezone: tz},
+			"U2":        {ID: "U2", FullName: "Odin", TransportID: "U2", Timezone: tz},
+		}},
+		hours: make(map[string]int),
+	}
+	h, err := New(Config{}, store, store, nil, "U-bot", notification)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	assert.Error(t, h.SetDigest(ctx, "U1", 24))
+	require.NoError(t, h.SetDigest(ctx, "U1", 18))
+	debts := []*debtDomain.Debt{
+		{ID: "d1", BorrowerID: "U1", LenderID: "uuid-host", OrderID: "A", Amount: 30, CreatedAt: time.Now()},
+		{ID: "d2", BorrowerID: "U1", LenderID: "uuid-host", OrderID: "B", Amount: 20, CreatedAt: time.Now()},
+		{ID: "d3", BorrowerID: "U2", LenderID: "uuid-host", OrderID: "A", Amount: 10, CreatedAt: time.Now()},
+	}
+	h.remindDebts(context.Background(), debts)
+	h.remindDebts(context.Background(), debts[:1])
+	require.Len(t, notification.messages, 1, "the reminders of users in digest mode are kept for their digest")
+	assert.True(t, strings.HasPrefix(notification.messages[0], "U2: Reminder, you should pay 10.00 nis"))
+	require.Len(t, store.notifications, 2, "a newer reminder of the same debt replaces the older one")
+	assert.True(t, strings.HasSuffix(store.notifications[0].Text, "reaction to the original rates message."))
+
+	// The reminders of paid debts are removed from the digest
+	h.dropPaidReminder(ctx, Event{Type: EventDebtPaid, OrderID: "B", Debt: debts[1]})
+	h.sendDigest(context.Background(), "U1")
+	require.Len(t, notification.messages, 2)
+	assert.True(t, strings.HasPrefix(notification.messages[1], "U1: :newspaper: Your daily digest:\n\n"+
+		"Reminder, you should pay 30.00 nis to <@uuid-host> for Wolt order ID A."))
+	assert.Empty(t, store.notifications)
+	h.sendDigest(context.Background(), "U1")
+	assert.Len(t, notification.messages, 2, "empty digests aren't sent")
+
+	// Turning digest mode off sends the waiting notifications right away
+	h.remindDebts(context.Background(), debts[:1])
+	require.NoError(t, h.SetDigest(ctx, "U1", -1))
+	require.Len(t, notification.messages, 3)
+	assert.Contains(t, notification.messages[2], "Your daily digest:")
+	h.remindDebts(context.Background(), debts[:1])
+	require.Len(t, notification.messages, 4)
+	assert.True(t, strings.HasPrefix(notification.messages[3], "U1: Reminder, you should pay 30.00 nis"))
+}
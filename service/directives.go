@@ -0,0 +1,38 @@
+package service
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// maxTimeoutMultiplier caps the timeouts extension of a single order, so a typo won't keep an order tracked for days
+const maxTimeoutMultiplier = 5
+
+// timeoutDirectiveRe matches an hourglass followed by a multiplier (like ⏳x2 or :hourglass_flowing_sand: x2), which extends the
+// timeouts of the order for known-slow venues
+var timeoutDirectiveRe = regexp.MustCompile(`(?i)(?:⏳|⌛|:hourglass_flowing_sand:|:hourglass:)[ \t]*[x×][ \t]*(\d+)`)
+
+// parseTimeoutMultiplier returns the multiplier of the order timeouts from the directive in a message text, or 1 if there isn't one
+func parseTimeoutMultiplier(text string) int {
+	match := timeoutDirectiveRe.FindStringSubmatch(text)
+	if match == nil {
+		return 1
+	}
+	multiplier, err := strconv.Atoi(match[1])
+	if err != nil || multiplier < 1 {
+		return 1
+	}
+	if multiplier > maxTimeoutMultiplier {
+		return maxTimeoutMultiplier
+	}
+	return multiplier
+}
+
+// orderTimeout returns the timeout extended by the order's timeout multiplier
+func (g *groupOrder) orderTimeout(timeout time.Duration) time.Duration {
+	if g.timeoutMultiplier < 1 {
+		return timeout
+	}
+	return timeout * time.Duration(g.timeoutMultiplier)
+}
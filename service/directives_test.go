@@ -0,0 +1,32 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTimeoutMultiplier(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		text       string
+		multiplier int
+	}{
+		{"https://wolt.com/en/group/ABC123", 1},
+		{"Slow sushi ⏳x2 https://wolt.com/en/group/ABC123", 2},
+		{"Slow sushi :hourglass_flowing_sand: X3", 3},
+		{":hourglass: × 2", 2},
+		{"⏳x0", 1},
+		{"⏳x100", maxTimeoutMultiplier},
+		{"⏳ is slow", 1},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.multiplier, parseTimeoutMultiplier(test.text), test.text)
+	}
+
+	order := &groupOrder{timeoutMultiplier: 2}
+	assert.Equal(t, 2*time.Hour, order.orderTimeout(time.Hour))
+	assert.Equal(t, time.Hour, (&groupOrder{}).orderTimeout(time.Hour))
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"fmt"
+	"math"
+)
+
+// DiscountAllocation is who benefits from the discounts of an order: its promo codes and the Wolt credits the host paid with
+type DiscountAllocation string
+
+const (
+	DiscountAllocationProportional DiscountAllocation = "proportional" // The discount is split relatively to each participant's order amount
+	DiscountAllocationHost         DiscountAllocation = "host"         // The host keeps the discount
+)
+
+func parseDiscountAllocation(value string) (DiscountAllocation, error) {
+	switch allocation := DiscountAllocation(value); allocation {
+	case "":
+		return DiscountAllocationProportional, nil
+	case DiscountAllocationProportional, DiscountAllocationHost:
+		return allocation, nil
+	default:
+		return "", fmt.Errorf("unknown discount allocation %q (available: %s, %s)", value, DiscountAllocationProportional, DiscountAllocationHost)
+	}
+}
+
+// discountedRates returns the amount of each participant's items, less their share of the order's discounts by DISCOUNT_ALLOCATION
+func (h *Service) discountedRates(details *OrderDetails) (map[string]float64, error) {
+	rates, err := details.RateByPerson()
+	if err != nil {
+		return nil, err
+	}
+	return h.allocateDiscount(rates, details.Host, details.DiscountsAmount()), nil
+}
+
+// allocateDiscount takes the discount off the amounts. A proportional discount can't exceed the items' total, while a host who keeps a
+// discount larger than their own items gets a negative amount, like the host who keeps the difference of rounding.
+func (h *Service) allocateDiscount(rates map[string]float64, host string, discount float64) map[string]float64 {
+	if discount <= 0 || len(rates) == 0 {
+		return rates
+	}
+	res := copyRates(rates)
+	if h.discountAllocation == DiscountAllocationHost {
+		res[host] -= discount
+		return res
+	}
+
+	total := 0.0
+	for _, amount := range rates {
+		total += amount
+	}
+	if total == 0 {
+		return rates
+	}
+	discount = math.Min(discount, total)
+	for person, amount := range rates {
+		res[person] = amount - discount*amount/total
+	}
+	return res
+}
+
+// paidDeliveryRate returns what the host paid for the delivery of the order, which is free with Wolt+, or the venue's delivery rate
+// to the order's location if Wolt doesn't tell
+func paidDeliveryRate(order *groupOrder, details *OrderDetails) (int, error) {
+	if price, ok := details.PaidDeliveryPrice(); ok {
+		return int(math.Round(price)), nil
+	}
+	return order.CalculateDeliveryRate()
+}
+
+// discountMessage returns the line of the rates message which tells the order's discount and who got it, empty if it had none
+func (h *Service) discountMessage(channel string, groupRate GroupRate) string {
+	if groupRate.Discount <= 0 {
+		return ""
+	}
+	key := msgDiscountSplit
+	if h.discountAllocation == DiscountAllocationHost {
+		key = msgDiscountHost
+	}
+	return h.text(channel, key, groupRate.Discount, h.currencyName(channel, groupRate.Currency))
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/oriser/bolt/wolt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllocateDiscount(t *testing.T) {
+	t.Parallel()
+
+	rates := map[string]float64{"Loki": 30, "Odin": 60, "Thor": 10}
+	h, err := New(Config{}, &fakeTreasuryStore{}, nil, nil, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+	assert.Equal(t, DiscountAllocationProportional, h.discountAllocation, "the discount is split by default")
+	assert.Equal(t, map[string]float64{"Loki": 27, "Odin": 54, "Thor": 9}, h.allocateDiscount(rates, "Thor", 10))
+	assert.Equal(t, map[string]float64{"Loki": 0, "Odin": 0, "Thor": 0}, h.allocateDiscount(rates, "Thor", 150),
+		"the discount can't exceed the items")
+	assert.Equal(t, rates, h.allocateDiscount(rates, "Thor", 0))
+	assert.Equal(t, map[string]float64{"Loki": 30, "Odin": 60, "Thor": 10}, rates, "the rates aren't changed")
+
+	groupRate := h.buildGroupRates(h.allocateDiscount(rates, "Thor", 10), "Thor", 0)
+	groupRate.Discount = 10
+	assert.Contains(t, h.buildRatesMessage("C1", groupRate, "ABC"),
+		"The order got a discount of 10.00 NIS (promo codes and Wolt credits), split relatively to everyone's amount\n")
+
+	h, err = New(Config{DiscountAllocation: "host"}, &fakeTreasuryStore{}, nil, nil, "UBOT",
+		&recordingNotification{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"Loki": 30, "Odin": 60, "Thor": -5}, h.allocateDiscount(rates, "Thor", 15), "the host keeps it")
+	assert.Contains(t, h.buildRatesMessage("C1", groupRate, "ABC"), "the host keeps it\n")
+
+	_, err = New(Config{DiscountAllocation: "nobody"}, &fakeTreasuryStore{}, nil, nil, "UBOT",
+		&recordingNotification{})
+	assert.ErrorContains(t, err, "DISCOUNT_ALLOCATION")
+}
+
+func TestPaidDeliveryPrice(t *testing.T) {
+	t.Parallel()
+
+	details := &wolt.OrderDetails{}
+	_, ok := woltOrderDetails(details).PaidDeliveryPrice()
+	assert.False(t, ok, "Wolt doesn't tell the delivery price before the purchase")
+
+	price := 1490.0
+	details.Purchase.DeliveryPrice = &price
+	paid, ok := woltOrderDetails(details).PaidDeliveryPrice()
+	assert.True(t, ok)
+	assert.Equal(t, 14.9, paid)
+
+	details.Purchase.WoltPlus = true
+	paid, ok = woltOrderDetails(details).PaidDeliveryPrice()
+	assert.True(t, ok)
+	assert.Zero(t, paid, "the delivery is free with Wolt+")
+
+	details.Purchase.Credits = 500
+	details.Purchase.Discounts = []wolt.PurchaseDiscount{{Name: "WELCOME", Amount: 1000}}
+	assert.Equal(t, 15.0, woltOrderDetails(details).DiscountsAmount())
+}
@@ -0,0 +1,20 @@
+package service
+
+// informDuplicateLink replies to a link of an order which is already tracked (for example when the host bumps it)
+// with a pointer to the tracking message and the order's current status
+func (h *Service) informDuplicateLink(req LinksRequest, orderID string) {
+	activeOrder, ok := h.LookupActiveOrder(orderID)
+	if !ok || (activeOrder.Channel == req.Channel && activeOrder.MessageID == req.MessageID) {
+		return
+	}
+
+	pointer := h.orderPointer(activeOrder, "this order")
+	message := h.text(req.Channel, msgAlreadyTracking, pointer, activeOrder.Status())
+	if activeOrder.VenueName != "" {
+		message = h.text(req.Channel, msgAlreadyTrackingVenue, pointer, activeOrder.VenueName, activeOrder.Status())
+	}
+
+	if _, err := h.informEvent(req.Channel, message, "", req.MessageID); err != nil {
+		h.logger.Error("Error informing about duplicate link", "group_id", orderID, "channel", req.Channel, "message_id", req.MessageID, "error", err)
+	}
+}
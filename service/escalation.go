@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	userDomain "github.com/oriser/bolt/user"
+)
+
+// The actions of the debt escalation stages, see DEBT_ESCALATION_STAGES
+const (
+	EscalateChannel = "channel" // Remind the borrower in the thread of the order, in its channel
+	EscalateAdmins  = "admins"  // Tell the treasurers (or FALLBACK_ADMIN_CHANNEL without treasurers) about the debt
+	EscalateShame   = "shame"   // List the debt in the channel's summary of the debts unpaid for long, the wall of shame
+)
+
+// escalationStage is a stage unpaid debts escalate to after their days
+type escalationStage struct {
+	days   int
+	action string
+}
+
+func (s escalationStage) String() string {
+	return fmt.Sprintf("%dd=%s", s.days, s.action)
+}
+
+// parseEscalationStages parses <days>=<action> pairs, like 3d=channel or 14=shame, returning the stages by their days
+func parseEscalationStages(pairs []string) ([]escalationStage, error) {
+	stages := make([]escalationStage, 0, len(pairs))
+	seen := make(map[int]bool, len(pairs))
+	for _, pair := range pairs {
+		daysValue, action, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("expected <days>=<%s|%s|%s> but got %q", EscalateChannel, EscalateAdmins, EscalateShame, pair)
+		}
+		days, err := strconv.Atoi(strings.TrimSuffix(strings.ToLower(strings.TrimSpace(daysValue)), "d"))
+		if err != nil || days < 1 {
+			return nil, fmt.Errorf("expected a positive number of days but got %q", daysValue)
+		}
+		action = strings.ToLower(strings.TrimSpace(action))
+		if action != EscalateChannel && action != EscalateAdmins && action != EscalateShame {
+			return nil, fmt.Errorf("unknown escalation %q, expected %s, %s or %s", action, EscalateChannel, EscalateAdmins, EscalateShame)
+		}
+		if seen[days] {
+			return nil, fmt.Errorf("more than one stage after %d days", days)
+		}
+		seen[days] = true
+		stages = append(stages, escalationStage{days: days, action: action})
+	}
+	sort.Slice(stages, func(i, j int) bool { return stages[i].days < stages[j].days })
+	return stages, nil
+}
+
+func formatEscalationStages(stages []escalationStage) string {
+	pairs := make([]string, len(stages))
+	for i, stage := range stages {
+		pairs[i] = stage.String()
+	}
+	return strings.Join(pairs, ",")
+}
+
+// parseEscalationSetting validates a channel's DEBT_ESCALATION_STAGES override, which is a comma separated list of stages or none for
+// not escalating the debts of the channel
+func parseEscalationSetting(value string) (string, error) {
+	if strings.EqualFold(value, noCutoff) {
+		return noCutoff, nil
+	}
+	stages, err := parseEscalationStages(nonEmpty(strings.Split(value, ",")))
+	if err != nil {
+		return "", err
+	}
+	if len(stages) == 0 {
+		return "", fmt.Errorf("no escalation stages")
+	}
+	return formatEscalationStages(stages), nil
+}
+
+// checkEscalationStages returns an error if a stage is due after the debts are removed by DEBT_MAXIMUM_DURATION, as it would never
+// be reached
+func checkEscalationStages(stages []escalationStage, debtMaximumDuration time.Duration) error {
+	for _, stage := range stages {
+		if time.Duration(stage.days)*24*time.Hour >= debtMaximumDuration {
+			return fmt.Errorf("the stage after %d days isn't before DEBT_MAXIMUM_DURATION (%s), when the unpaid debts are removed",
+				stage.days, debtMaximumDuration)
+		}
+	}
+	return nil
+}
+
+// checkEscalationSetting returns an error if a channel's DEBT_ESCALATION_STAGES override has a stage which is never reached
+func (h *Service) checkEscalationSetting(value string) error {
+	if value == noCutoff {
+		return nil
+	}
+	stages, err := parseEscalationStages(strings.Split(value, ","))
+	if err != nil {
+		return err
+	}
+	return checkEscalationStages(stages, h.cfg.DebtMaximumDuration)
+}
+
+// channelEscalationStages returns the escalation stages of the debts of orders sent in the channel: its DEBT_ESCALATION_STAGES
+// override, or the global ones
+func (h *Service) channelEscalationStages(channel string) []escalationStage {
+	value, ok := h.channelSetting(channel, settingEscalationStages)
+	if !ok {
+		return h.escalationStages
+	}
+	if value == noCutoff {
+		return nil
+	}
+	stages, err := parseEscalationStages(strings.Split(value, ","))
+	if err != nil {
+		return h.escalationStages
+	}
+	return stages
+}
+
+func (h *Service) escalationStore() (debtDomain.EscalationStore, error) {
+	escalationStore, ok := h.debtStore.(debtDomain.EscalationStore)
+	if !ok {
+		return nil, fmt.Errorf("debt escalation is not supported")
+	}
+	return escalationStore, nil
+}
+
+// dueEscalation returns the latest stage the debt reached by the given time and didn't escalate to yet. Debts which skipped stages
+// (like when Bolt was down) only escalate to the latest one.
+func dueEscalation(stages []escalationStage, debt *debtDomain.Debt, now time.Time) (escalationStage, bool) {
+	days := int(now.Sub(debt.CreatedAt) / (24 * time.Hour))
+	due, ok := escalationStage{}, false
+	for _, stage := range stages {
+		if stage.days <= days && stage.days > debt.EscalatedDays {
+			due, ok = stage, true
+		}
+	}
+	return due, ok
+}
+
+// escalateDebts escalates the unpaid debts which reached a stage of the escalation of their channel, recording the stage so it
+// isn't repeated. The debts escalated to the wall of shame are listed in one summary for each channel.
+func (h *Service) escalateDebts(ctx context.Context, debts []*debtDomain.Debt, now time.Time) {
+	escalationStore, err := h.escalationStore()
+	if err != nil {
+		return
+	}
+
+	shame := make(map[string][]string)
+	for _, debt := range debts {
+		if debt.PaidClaimedAt != nil {
+			// The borrower said they paid, and the host didn't answer yet
+			continue
+		}
+		stage, ok := dueEscalation(h.channelEscalationStages(debt.InitiatedTransportID), debt, now)
+		if !ok {
+			continue
+		}
+		borrower, err := h.getUser(debt.BorrowerID)
+		if err != nil {
+			h.logger.Error("Error getting the borrower of an escalated debt", "debt_id", debt.ID, "error", err)
+			continue
+		}
+		if borrower.Deactivated() {
+			continue
+		}
+		if err := escalationStore.SetEscalatedDays(ctx, debt.ID, stage.days); err != nil {
+			h.logger.Error("Error recording the escalation of a debt", "debt_id", debt.ID, "error", err)
+			continue
+		}
+
+		// The channel isn't told the amounts of the borrowers who keep them private, only the admins are
+		channel, private := debt.InitiatedTransportID, h.privateAmounts(borrower.TransportID)
+		switch {
+		case stage.action == EscalateChannel && private:
+			_, err = h.informEvent(channel, h.text(channel, msgEscalationChannelPrivate, borrower.TransportID, h.lenderTransportID(debt),
+				debt.OrderID, stage.days), "", debt.MessageID)
+		case stage.action == EscalateChannel:
+			_, err = h.informEvent(channel, h.text(channel, msgEscalationChannel, borrower.TransportID, debt.Amount,
+				h.currencyName(channel, debt.Currency), h.lenderTransportID(debt), debt.OrderID, stage.days), "", debt.MessageID)
+		case stage.action == EscalateAdmins:
+			err = h.escalateToAdmins(debt, borrower, stage.days)
+		case stage.action == EscalateShame && private:
+			shame[channel] = append(shame[channel], h.text(channel, msgEscalationWallLinePrivate, borrower.TransportID, debt.OrderID, stage.days))
+		case stage.action == EscalateShame:
+			shame[channel] = append(shame[channel], h.text(channel, msgEscalationWallLine, borrower.TransportID, debt.Amount,
+				h.currencyName(channel, debt.Currency), debt.OrderID, stage.days))
+		}
+		if err != nil {
+			h.logger.Error("Error escalating a debt", "debt_id", debt.ID, "action", stage.action, "error", err)
+		}
+	}
+
+	for channel, lines := range shame {
+		sort.Strings(lines)
+		if _, err := h.informEvent(channel, h.text(channel, msgEscalationWall)+strings.Join(lines, ""), "", ""); err != nil {
+			h.logger.Error("Error posting the debts unpaid for long", "channel", channel, "error", err)
+		}
+	}
+}
+
+// lenderTransportID returns the transport ID of the lender of the debt, or its user ID if the lender isn't known
+func (h *Service) lenderTransportID(debt *debtDomain.Debt) string {
+	lender, err := h.getUser(debt.LenderID)
+	if err != nil {
+		return debt.LenderID
+	}
+	return lender.TransportID
+}
+
+// escalateToAdmins tells every treasurer about the debt in a direct message, or FALLBACK_ADMIN_CHANNEL if there are no treasurers
+func (h *Service) escalateToAdmins(debt *debtDomain.Debt, borrower *userDomain.User, days int) error {
+	receivers := h.cfg.Treasurers
+	if len(receivers) == 0 && h.cfg.FallbackAdminChannel != "" {
+		receivers = []string{h.cfg.FallbackAdminChannel}
+	}
+	if len(receivers) == 0 {
+		return fmt.Errorf("no treasurers or admin channel to escalate to")
+	}
+	channel := debt.InitiatedTransportID
+	text := h.text(channel, msgEscalationAdmins, borrower.TransportID, debt.Amount, h.currencyName(channel, debt.Currency),
+		h.lenderTransportID(debt), debt.OrderID, channel, days)
+	for _, receiver := range receivers {
+		if _, err := h.informEvent(receiver, text, "", ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type escalatingStore struct {
+	fakeTreasuryStore
+}
+
+func (s *escalatingStore) SetEscalatedDays(_ context.Context, debtID string, days int) error {
+	for _, debt := range s.debts {
+		if debt.ID == debtID {
+			debt.EscalatedDays = days
+		}
+	}
+	return nil
+}
+
+func TestParseEscalationStages(t *testing.T) {
+	t.Parallel()
+
+	stages, err := parseEscalationStages([]string{"14=Shame", " 3d=channel", "7D=admins"})
+	require.NoError(t, err)
+	assert.Equal(t, "3d=channel,7d=admins,14d=shame", formatEscalationStages(stages))
+
+	for _, pairs := range [][]string{{"3"}, {"0=channel"}, {"3=scold"}, {"3=channel", "3d=admins"}} {
+		_, err := parseEscalationStages(pairs)
+		assert.Error(t, err, pairs)
+	}
+	value, err := parseEscalationSetting("NONE")
+	require.NoError(t, err)
+	assert.Equal(t, noCutoff, value)
+	_, err = parseEscalationSetting(",")
+	assert.Error(t, err)
+}
+
+func TestEscalateDebts(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	store := &escalatingStore{fakeTreasuryStore{
+		users: map[string]*userDomain.User{
+			"uuid-host": {ID: "uuid-host", FullName: "Thor", TransportID: "U-host"},
+			"uuid-loki": {ID: "uuid-loki", FullName: "Loki", TransportID: "U-loki"},
+			"uuid-odin": {ID: "uuid-odin", FullName: "Odin", TransportID: "U-odin"},
+		},
+		debts: []*debtDomain.Debt{
+			{ID: "d1", BorrowerID: "uuid-loki", LenderID: "uuid-host", OrderID: "ABC", Amount: 30, InitiatedTransportID: "C1",
+				MessageID: "1.1", CreatedAt: now.Add(-4 * 24 * time.Hour)},
+			{ID: "d2", BorrowerID: "uuid-odin", LenderID: "uuid-host", OrderID: "ABC", Amount: 20, InitiatedTransportID: "C1",
+				MessageID: "1.1", CreatedAt: now.Add(-15 * 24 * time.Hour)},
+			{ID: "d3", BorrowerID: "uuid-loki", LenderID: "uuid-host", OrderID: "XYZ", Amount: 10, InitiatedTransportID: "C2",
+				MessageID: "2.1", CreatedAt: now.Add(-15 * 24 * time.Hour)},
+		},
+	}}
+	notification := &recordingNotification{}
+	cfg := Config{DebtEscalationStages: []string{"3=channel", "7=admins", "14=shame"},
+		Treasurers: []string{"U-treasurer"}, DebtMaximumDuration: 30 * 24 * time.Hour}
+	h, err := New(cfg, store, store, &fakeChannelSettingsStore{settings: make(map[string]map[string]string)}, "UBOT", notification)
+	require.NoError(t, err)
+	_, err = h.SetChannelSetting(context.Background(), "C2", settingEscalationStages, "7=admins", "U1")
+	require.NoError(t, err)
+	_, err = h.SetChannelSetting(context.Background(), "C2", settingEscalationStages, "30=shame", "U1")
+	assert.Error(t, err, "the debts are removed before the stage")
+
+	cfg.DebtMaximumDuration = 24 * time.Hour
+	_, err = New(cfg, store, store, nil, "UBOT", notification)
+	assert.Error(t, err, "the stages are after the debts are removed")
+
+	h.escalateDebts(context.Background(), store.debts, now)
+	assert.Equal(t, []string{
+		"C1: :bell: <@U-loki>, you still owe 30.00 NIS to <@U-host> for Wolt order ID ABC, it's been 3 days. Please pay and react to the rates message",
+		"U-treasurer: :rotating_light: <@U-loki> still owes 10.00 NIS to <@U-host> for Wolt order ID XYZ in <#C2>, it's been 7 days",
+		"C1: :snail: Debts unpaid for long:\n<@U-odin> owes 20.00 NIS for Wolt order ID ABC (14 days)\n",
+	}, notification.messages, "debts which skipped stages only escalate to the latest one")
+	assert.Equal(t, 3, store.debts[0].EscalatedDays)
+	assert.Equal(t, 14, store.debts[1].EscalatedDays)
+
+	h.escalateDebts(context.Background(), store.debts, now)
+	assert.Len(t, notification.messages, 3, "the stages the debts reached aren't repeated")
+	h.escalateDebts(context.Background(), store.debts, now.Add(3*24*time.Hour))
+	require.Len(t, notification.messages, 4)
+	assert.Contains(t, notification.messages[3], "<@U-loki> still owes 30.00 NIS")
+}
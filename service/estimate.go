@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/oriser/bolt/wolt"
+)
+
+// VenueEstimate is what ordering from a venue would currently cost and take, for choosing a venue before opening a group order
+type VenueEstimate struct {
+	Venue        *wolt.Venue
+	DeliveryRate int    // -1 if the office location isn't configured
+	Currency     string // The ISO 4217 code of the currency of the venue's prices
+}
+
+// parseOfficeLocation parses a "<latitude>,<longitude>" location
+func parseOfficeLocation(location string) (*wolt.Coordinate, error) {
+	if location == "" {
+		return nil, nil
+	}
+	lat, lon, ok := strings.Cut(location, ",")
+	if !ok {
+		return nil, fmt.Errorf("expected <latitude>,<longitude> but got %q", location)
+	}
+	coordinate := &wolt.Coordinate{}
+	var err error
+	if coordinate.Lat, err = strconv.ParseFloat(strings.TrimSpace(lat), 64); err != nil {
+		return nil, fmt.Errorf("parse latitude: %w", err)
+	}
+	if coordinate.Lon, err = strconv.ParseFloat(strings.TrimSpace(lon), 64); err != nil {
+		return nil, fmt.Errorf("parse longitude: %w", err)
+	}
+	return coordinate, nil
+}
+
+// venueSlug returns the slug of a venue given by its Wolt link (like https://wolt.com/en/isr/tel-aviv/restaurant/pizza-place)
+// or by its slug
+func venueSlug(venue string) string {
+	venue = strings.Trim(strings.TrimSpace(venue), "<>")
+	if u, err := url.Parse(venue); err == nil && u.Host != "" {
+		parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+		return parts[len(parts)-1]
+	}
+	return strings.ReplaceAll(strings.ToLower(venue), " ", "-")
+}
+
+// EstimateVenue returns the current delivery rate to the office, the estimated delivery time and the minimum order of a venue
+func (h *Service) EstimateVenue(ctx context.Context, venue string) (*VenueEstimate, error) {
+	slug := venueSlug(venue)
+	if slug == "" {
+		return nil, fmt.Errorf("no venue given")
+	}
+	addr, retryConfig := h.woltConfig()
+	v, err := wolt.VenueBySlug(ctx, addr, retryConfig, slug)
+	if err != nil {
+		return nil, fmt.Errorf("get venue: %w", err)
+	}
+
+	estimate := &VenueEstimate{Venue: v, DeliveryRate: -1, Currency: h.venueCurrency(v.Currency)}
+	if h.officeLocation != nil {
+		if estimate.DeliveryRate, err = v.CalculateDeliveryRate(*h.officeLocation); err != nil {
+			return nil, fmt.Errorf("calculate delivery rate: %w", err)
+		}
+	}
+	return estimate, nil
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/oriser/bolt/wolt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const estimateVenueJSON = `{"results": [{
+	"alive": 1,
+	"online": true,
+	"location": {"coordinates": [32.08, 34.78]},
+	"delivery_specs": {
+		"delivery_enabled": true,
+		"order_minimum_no_surcharge": 5000,
+		"delivery_pricing": {"base_price": 1000, "distance_ranges": [{"a": 0, "min": 0, "max": 500}, {"a": 500, "min": 500, "max": 0}]}
+	},
+	"name": [{"lang": "en", "value": "Pizza Place"}],
+	"timezone": "Asia/Jerusalem",
+	"completion_estimates": {"delivery": "20-40"}
+}]}`
+
+func TestParseOfficeLocation(t *testing.T) {
+	t.Parallel()
+
+	location, err := parseOfficeLocation("32.0853, 34.7818")
+	require.NoError(t, err)
+	assert.Equal(t, &wolt.Coordinate{Lat: 32.0853, Lon: 34.7818}, location)
+
+	location, err = parseOfficeLocation("")
+	require.NoError(t, err)
+	assert.Nil(t, location)
+
+	_, err = parseOfficeLocation("32.0853")
+	assert.Error(t, err)
+}
+
+func TestVenueSlug(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "pizza-place", venueSlug("<https://wolt.com/en/isr/tel-aviv/restaurant/pizza-place>"))
+	assert.Equal(t, "pizza-place", venueSlug("https://wolt.com/he/isr/tel-aviv/restaurant/pizza-place/"))
+	assert.Equal(t, "pizza-place", venueSlug("Pizza Place"))
+}
+
+func TestEstimateVenue(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v3/venues/slug/pizza-place" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(estimateVenueJSON))
+	}))
+	defer server.Close()
+
+	h, err := New(Config{WoltBaseAddr: server.URL, WoltApiBaseAddr: server.URL, OfficeLocation: "32.09,34.78"},
+		nil, nil, nil, "UBOT", nil)
+	require.NoError(t, err)
+
+	estimate, err := h.EstimateVenue(context.Background(), "https://wolt.com/en/isr/tel-aviv/restaurant/pizza-place")
+	require.NoError(t, err)
+	assert.Equal(t, "Pizza Place", estimate.Venue.Name)
+	assert.Equal(t, 15, estimate.DeliveryRate, "the office is more than 500 meters away")
+	assert.Equal(t, "20-40", estimate.Venue.DeliveryEstimate())
+	assert.Equal(t, 50.0, estimate.Venue.MinimumOrder())
+
+	_, err = h.EstimateVenue(context.Background(), "burger-place")
+	assert.ErrorContains(t, err, `venue "burger-place" not found`)
+	var notFound *wolt.ErrVenueNotFound
+	require.True(t, errors.As(err, &notFound))
+	assert.Equal(t, "burger-place", notFound.Slug)
+
+	h.officeLocation = nil
+	estimate, err = h.EstimateVenue(context.Background(), "pizza-place")
+	require.NoError(t, err)
+	assert.Equal(t, -1, estimate.DeliveryRate)
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	"github.com/oriser/bolt/order"
+)
+
+// maxExportDays caps the range of an export, so a typo in the dates doesn't list the orders of years
+const maxExportDays = 366
+
+// Export is the orders of a channel in a range of dates and their debts, both outstanding and paid, for reconciling them or feeding
+// them to an expense tool
+type Export struct {
+	Channel string
+	From    time.Time // The start of the first day of the range, in the channel's timezone
+	To      time.Time // The end of the last day of the range
+	Orders  []*order.Order
+	Debts   []ExportDebt
+}
+
+// ExportDebt is a debt of an exported order, outstanding or paid
+type ExportDebt struct {
+	TreasuryDebt
+	PaidAt time.Time // Zero if the debt is outstanding
+}
+
+func exportOrderStatus(status order.Status) string {
+	switch status {
+	case order.StatusCanceled:
+		return "canceled"
+	case order.StatusDone:
+		return "done"
+	case order.StatusStopped:
+		return "stopped"
+	default:
+		return "invalid"
+	}
+}
+
+// ChannelExport returns the orders of the channel sent from the date of from through the date of to (in the channel's timezone), with
+// their debts, from the oldest to the newest
+func (h *Service) ChannelExport(ctx context.Context, channel string, from, to time.Time) (*Export, error) {
+	if h.orderStore == nil || h.debtStore == nil {
+		return nil, fmt.Errorf("no order or debt store")
+	}
+	timezone := h.timezoneForChannel(channel, nil)
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, timezone)
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, timezone).AddDate(0, 0, 1)
+	if !to.After(from) {
+		return nil, fmt.Errorf("the range ends before it starts")
+	}
+	if to.Sub(from) > maxExportDays*24*time.Hour {
+		return nil, fmt.Errorf("the range is longer than %d days", maxExportDays)
+	}
+	inRange := func(t time.Time) bool {
+		return !t.Before(from) && t.Before(to)
+	}
+
+	export := &Export{Channel: channel, From: from, To: to, Orders: make([]*order.Order, 0), Debts: make([]ExportDebt, 0)}
+	orders, err := h.orderStore.ListOrders(ctx, order.ListFilter{Receiver: channel, Ascending: true})
+	if err != nil {
+		return nil, fmt.Errorf("list orders: %w", err)
+	}
+	for _, o := range orders {
+		if inRange(o.CreatedAt) {
+			export.Orders = append(export.Orders, o)
+		}
+	}
+
+	debts, err := h.debtStore.ListDebts(ctx, debtDomain.ListFilter{Channel: channel})
+	if err != nil {
+		return nil, fmt.Errorf("list debts: %w", err)
+	}
+	paidAt := make(map[string]time.Time)
+	if paymentStore, ok := h.debtStore.(debtDomain.PaymentStore); ok {
+		payments, err := paymentStore.ListPayments(ctx, debtDomain.PaymentListFilter{Channel: channel})
+		if err != nil {
+			return nil, fmt.Errorf("list payments: %w", err)
+		}
+		for _, p := range payments {
+			debt := p.Debt
+			debts = append(debts, &debt)
+			paidAt[debt.ID] = p.PaidAt
+		}
+	}
+	for _, d := range h.debtsWithUsers(ctx, debts) {
+		if inRange(d.Debt.CreatedAt) {
+			export.Debts = append(export.Debts, ExportDebt{TreasuryDebt: d, PaidAt: paidAt[d.Debt.ID]})
+		}
+	}
+	sort.SliceStable(export.Debts, func(i, j int) bool {
+		return export.Debts[i].Debt.CreatedAt.Before(export.Debts[j].Debt.CreatedAt)
+	})
+	return export, nil
+}
+
+// WriteCSV writes the orders and debts of the export as CSV, a row per order followed by a row per debt. The debts are outstanding
+// or paid, and the paid ones have the time they were paid at. The amounts participants keep private are left empty, and so are the
+// totals of their orders.
+func (e *Export) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"record", "id", "created_at", "order_id", "channel", "venue", "host", "borrower_id", "borrower",
+		"lender_id", "lender", "amount", "currency", "status", "paid_at", "order_ref"}); err != nil {
+		return fmt.Errorf("write CSV header: %w", err)
+	}
+
+	venues := make(map[string]string)
+	rows := make([][]string, 0, len(e.Orders)+len(e.Debts))
+	for _, o := range e.Orders {
+		venues[o.OriginalID] = o.VenueName
+		total := ""
+		if !o.HasPrivateAmounts() {
+			total = strconv.FormatFloat(o.TotalAmount(), 'f', 2, 64)
+		}
+		rows = append(rows, []string{"order", o.ID, o.CreatedAt.Format(time.RFC3339), o.OriginalID, o.Receiver, o.VenueName, o.Host,
+			"", "", "", "", total, currencyCode(o.Currency), exportOrderStatus(o.Status), "", o.ExternalRef})
+	}
+	for _, d := range e.Debts {
+		status, paidAt, amount := "outstanding", "", strconv.FormatFloat(d.Debt.Amount, 'f', 2, 64)
+		if d.Private {
+			amount = ""
+		}
+		if !d.PaidAt.IsZero() {
+			status, paidAt = "paid", d.PaidAt.Format(time.RFC3339)
+		}
+		rows = append(rows, []string{"debt", d.Debt.ID, d.Debt.CreatedAt.Format(time.RFC3339), d.Debt.OrderID, d.Debt.InitiatedTransportID,
+			venues[d.Debt.OrderID], "", d.Debt.BorrowerID, userName(d.Borrower, d.Debt.BorrowerID), d.Debt.LenderID,
+			userName(d.Lender, d.Debt.LenderID), amount, currencyCode(d.Debt.Currency), status,
+			paidAt, d.OrderRef})
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		return fmt.Errorf("write CSV rows: %w", err)
+	}
+	return nil
+}
+
+// currencyCode returns the ISO 4217 code of the currency of amounts, which is the default currency if they have none
+func currencyCode(currency string) string {
+	if currency == "" {
+		return DefaultCurrency
+	}
+	return currency
+}
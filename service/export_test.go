@@ -0,0 +1,63 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	"github.com/oriser/bolt/order"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelExport(t *testing.T) {
+	t.Parallel()
+
+	may := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	orders := &filteringOrderStore{fakeOrderStore{orders: []*order.Order{
+		{ID: "1", OriginalID: "g1", Receiver: "C1", VenueName: "Pizza Place", Host: "Dana", CreatedAt: may, Currency: "ILS",
+			Status: order.StatusDone, ExternalRef: "PO-1", Participants: []order.Participant{{Name: "Dana", ID: "dana", Amount: 40}, {Name: "Bob", ID: "bob", Amount: 30}}},
+		{ID: "2", OriginalID: "g2", Receiver: "C1", VenueName: "Sushi Bar", Host: "Dana", CreatedAt: may.AddDate(0, 1, 0), Status: order.StatusDone},
+		{ID: "3", OriginalID: "g3", Receiver: "C2", VenueName: "Burger Joint", Host: "Bob", CreatedAt: may, Status: order.StatusCanceled},
+	}}}
+	store := &fakeTreasuryStore{
+		users: map[string]*userDomain.User{"dana": {ID: "dana", FullName: "Dana"}, "bob": {ID: "bob", FullName: "Bob"}},
+		debts: []*debtDomain.Debt{
+			{ID: "d1", OrderID: "g1", BorrowerID: "bob", LenderID: "dana", Amount: 30, InitiatedTransportID: "C1", CreatedAt: may, Currency: "ILS"},
+			{ID: "d2", OrderID: "g2", BorrowerID: "bob", LenderID: "dana", Amount: 10, InitiatedTransportID: "C1", CreatedAt: may.AddDate(0, 1, 0)},
+		},
+		payments: []*debtDomain.Payment{{Debt: debtDomain.Debt{ID: "d0", OrderID: "g1", BorrowerID: "dana", LenderID: "bob", Amount: 5,
+			InitiatedTransportID: "C1", CreatedAt: may.Add(-time.Hour)}, PaidAt: may.Add(time.Hour)}},
+	}
+	h, err := New(Config{}, store, store, orders, "UBOT", nil)
+	require.NoError(t, err)
+
+	export, err := h.ChannelExport(context.Background(), "C1", time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Len(t, export.Orders, 1, "only the orders of the channel in the range")
+	require.Len(t, export.Debts, 2)
+	assert.Equal(t, "d0", export.Debts[0].Debt.ID, "the debts are from the oldest")
+	assert.False(t, export.Debts[0].PaidAt.IsZero())
+	assert.True(t, export.Debts[1].PaidAt.IsZero())
+
+	var buf bytes.Buffer
+	require.NoError(t, export.WriteCSV(&buf))
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+	assert.Equal(t, []string{"order", "1", "2024-05-10T12:00:00Z", "g1", "C1", "Pizza Place", "Dana", "", "", "", "", "70.00", "ILS",
+		"done", "", "PO-1"}, rows[1])
+	assert.Equal(t, []string{"debt", "d0", "2024-05-10T11:00:00Z", "g1", "C1", "Pizza Place", "", "dana", "Dana", "bob", "Bob", "5.00",
+		"ILS", "paid", "2024-05-10T13:00:00Z", "PO-1"}, rows[2])
+	assert.Equal(t, []string{"debt", "d1", "2024-05-10T12:00:00Z", "g1", "C1", "Pizza Place", "", "bob", "Bob", "dana", "Dana", "30.00",
+		"ILS", "outstanding", "", "PO-1"}, rows[3])
+
+	_, err = h.ChannelExport(context.Background(), "C1", may, may.AddDate(0, 0, -1))
+	assert.Error(t, err, "the range ends before it starts")
+	_, err = h.ChannelExport(context.Background(), "C1", may, may.AddDate(2, 0, 0))
+	assert.Error(t, err, "the range is too long")
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+const (
+	FeeAllocationEqual        = "equal"
+	FeeAllocationProportional = "proportional"
+	FeeAllocationHostAbsorbs  = "host-absorbs"
+)
+
+// Fees are the order level charges which are not part of any participant's basket
+type Fees struct {
+	Delivery float64
+	Service  float64
+	Tip      float64
+	Discount float64
+}
+
+// Total returns the net amount of the fees to allocate (discount reduces it)
+func (f Fees) Total() float64 {
+	return f.Delivery + f.Service + f.Tip - f.Discount
+}
+
+// orderFees returns the fees of the order to allocate: the delivery, Wolt's service fee and the tip. The discount isn't one of
+// them, DISCOUNT_ALLOCATION takes it off the participants' baskets before the fees are allocated.
+func orderFees(details *OrderDetails, deliveryRate int) Fees {
+	return Fees{Delivery: float64(deliveryRate), Service: details.ServiceFee, Tip: details.Tip}
+}
+
+// FeeAllocator decides how the order fees are split between participants.
+// It gets the basket total of each participant (by Wolt name) and returns the final amount each participant should pay.
+type FeeAllocator interface {
+	Allocate(rates map[string]float64, host string, fees Fees) map[string]float64
+}
+
+// FeeAllocatorFunc is an adapter to allow using ordinary functions as a FeeAllocator
+type FeeAllocatorFunc func(rates map[string]float64, host string, fees Fees) map[string]float64
+
+func (f FeeAllocatorFunc) Allocate(rates map[string]float64, host string, fees Fees) map[string]float64 {
+	return f(rates, host, fees)
+}
+
+var (
+	feeAllocatorsLock sync.RWMutex
+	feeAllocators     = map[string]FeeAllocator{
+		FeeAllocationEqual:        FeeAllocatorFunc(allocateFeesEqually),
+		FeeAllocationProportional: FeeAllocatorFunc(allocateFeesProportionally),
+		FeeAllocationHostAbsorbs:  FeeAllocatorFunc(allocateFeesToHost),
+	}
+)
+
+// RegisterFeeAllocator makes a fee allocator available by name for the FEE_ALLOCATION_STRATEGY configuration.
+// Registering an existing name replaces it.
+func RegisterFeeAllocator(name string, allocator FeeAllocator) {
+	feeAllocatorsLock.Lock()
+	defer feeAllocatorsLock.Unlock()
+	feeAllocators[name] = allocator
+}
+
+// FeeAllocatorByName returns a registered fee allocator, or the equal one if the name is empty
+func FeeAllocatorByName(name string) (FeeAllocator, error) {
+	if name == "" {
+		name = FeeAllocationEqual
+	}
+	feeAllocatorsLock.RLock()
+	defer feeAllocatorsLock.RUnlock()
+	allocator, ok := feeAllocators[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown fee allocation strategy %q (available: %v)", name, registeredFeeAllocators())
+	}
+	return allocator, nil
+}
+
+func registeredFeeAllocators() []string {
+	names := make([]string, 0, len(feeAllocators))
+	for name := range feeAllocators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func copyRates(rates map[string]float64) map[string]float64 {
+	res := make(map[string]float64, len(rates))
+	for person, rate := range rates {
+		res[person] = rate
+	}
+	return res
+}
+
+// allocateFeesEqually splits the fees evenly between everyone who ordered something
+func allocateFeesEqually(rates map[string]float64, _ string, fees Fees) map[string]float64 {
+	res := copyRates(rates)
+	if len(res) == 0 {
+		return res
+	}
+	pricePerPerson := fees.Total() / float64(len(res))
+	for person, rate := range res {
+		res[person] = rate + pricePerPerson
+	}
+	return res
+}
+
+// allocateFeesProportionally splits the fees relatively to each participant's order amount
+func allocateFeesProportionally(rates map[string]float64, host string, fees Fees) map[string]float64 {
+	total := 0.0
+	for _, rate := range rates {
+		total += rate
+	}
+	if total == 0 {
+		return allocateFeesEqually(rates, host, fees)
+	}
+
+	res := copyRates(rates)
+	for person, rate := range res {
+		res[person] = rate + fees.Total()*rate/total
+	}
+	return res
+}
+
+// allocateFeesToHost leaves the participants with just their own items, the host pays all fees
+func allocateFeesToHost(rates map[string]float64, host string, fees Fees) map[string]float64 {
+	res := copyRates(rates)
+	res[host] += fees.Total()
+	return res
+}
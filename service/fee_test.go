@@ -0,0 +1,144 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/oriser/bolt/wolt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeeAllocators(t *testing.T) {
+	t.Parallel()
+
+	rates := map[string]float64{"Loki": 30, "Odin": 60, "Thor": 10}
+	tests := []struct {
+		name     string
+		strategy string
+		rates    map[string]float64
+		fees     Fees
+		expected map[string]float64
+	}{
+		{
+			name:     "equal",
+			strategy: FeeAllocationEqual,
+			rates:    rates,
+			fees:     Fees{Delivery: 15},
+			expected: map[string]float64{"Loki": 35, "Odin": 65, "Thor": 15},
+		},
+		{
+			name:     "equal with every fee",
+			strategy: FeeAllocationEqual,
+			rates:    rates,
+			fees:     Fees{Delivery: 15, Service: 9, Tip: 6, Discount: 3},
+			expected: map[string]float64{"Loki": 39, "Odin": 69, "Thor": 19},
+		},
+		{
+			name:     "equal doesn't divide evenly",
+			strategy: FeeAllocationEqual,
+			rates:    rates,
+			fees:     Fees{Delivery: 10},
+			expected: map[string]float64{"Loki": 33.333, "Odin": 63.333, "Thor": 13.333},
+		},
+		{
+			name:     "equal without participants",
+			strategy: FeeAllocationEqual,
+			rates:    map[string]float64{},
+			fees:     Fees{Delivery: 15},
+			expected: map[string]float64{},
+		},
+		{
+			name:     "proportional",
+			strategy: FeeAllocationProportional,
+			rates:    rates,
+			fees:     Fees{Delivery: 10},
+			expected: map[string]float64{"Loki": 33, "Odin": 66, "Thor": 11},
+		},
+		{
+			name:     "proportional with every fee",
+			strategy: FeeAllocationProportional,
+			rates:    rates,
+			fees:     Fees{Delivery: 10, Service: 8, Tip: 4, Discount: 2},
+			expected: map[string]float64{"Loki": 36, "Odin": 72, "Thor": 12},
+		},
+		{
+			name:     "proportional doesn't divide evenly",
+			strategy: FeeAllocationProportional,
+			rates:    map[string]float64{"Loki": 10, "Odin": 10, "Thor": 10},
+			fees:     Fees{Delivery: 10},
+			expected: map[string]float64{"Loki": 13.333, "Odin": 13.333, "Thor": 13.333},
+		},
+		{
+			name:     "proportional falls back to equal without amounts",
+			strategy: FeeAllocationProportional,
+			rates:    map[string]float64{"Loki": 0, "Odin": 0},
+			fees:     Fees{Delivery: 10},
+			expected: map[string]float64{"Loki": 5, "Odin": 5},
+		},
+		{
+			name:     "host absorbs",
+			strategy: FeeAllocationHostAbsorbs,
+			rates:    rates,
+			fees:     Fees{Delivery: 15, Service: 5, Tip: 10, Discount: 4},
+			expected: map[string]float64{"Loki": 30, "Odin": 60, "Thor": 36},
+		},
+		{
+			name:     "host absorbs without ordering",
+			strategy: FeeAllocationHostAbsorbs,
+			rates:    map[string]float64{"Loki": 30},
+			fees:     Fees{Delivery: 15},
+			expected: map[string]float64{"Loki": 30, "Thor": 15},
+		},
+		{
+			name:     "empty name is equal",
+			strategy: "",
+			rates:    rates,
+			fees:     Fees{Delivery: 15},
+			expected: map[string]float64{"Loki": 35, "Odin": 65, "Thor": 15},
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			allocator, err := FeeAllocatorByName(tc.strategy)
+			require.NoError(t, err)
+			allocated := allocator.Allocate(tc.rates, "Thor", tc.fees)
+			require.Len(t, allocated, len(tc.expected))
+			for person, amount := range tc.expected {
+				assert.InDelta(t, amount, allocated[person], 0.001, person)
+			}
+		})
+	}
+	assert.Equal(t, map[string]float64{"Loki": 30, "Odin": 60, "Thor": 10}, rates, "the rates aren't changed")
+
+	_, err := FeeAllocatorByName("nobody")
+	assert.ErrorContains(t, err, "unknown fee allocation strategy")
+}
+
+func TestOrderFees(t *testing.T) {
+	t.Parallel()
+
+	details := &wolt.OrderDetails{}
+	assert.Equal(t, Fees{Delivery: 15}, orderFees(woltOrderDetails(details), 15))
+
+	details.Purchase.ServiceFee = 490
+	details.Purchase.Tip = 1000
+	details.Purchase.Discounts = []wolt.PurchaseDiscount{{Name: "promo", Amount: 500}}
+	assert.Equal(t, Fees{Delivery: 15, Service: 4.9, Tip: 10}, orderFees(woltOrderDetails(details), 15), "the discount is left to DISCOUNT_ALLOCATION")
+}
+
+func TestRatesWithOrderFees(t *testing.T) {
+	t.Parallel()
+
+	h, err := New(Config{}, &fakeTreasuryStore{}, nil, nil, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+	groupRate := h.buildGroupRates(map[string]float64{"Loki": 40, "Thor": 20}, "Thor", 10)
+	groupRate.setFees(Fees{Service: 5, Tip: 10})
+	assert.Equal(t, Fees{Delivery: 10, Service: 5, Tip: 10}, groupRate.fees())
+	assert.Contains(t, h.buildRatesMessage("C1", groupRate, "ABC"), "Including Wolt's fees: service 5.00, tip 10.00\n")
+
+	groupRate.setFees(Fees{})
+	assert.NotContains(t, h.buildRatesMessage("C1", groupRate, "ABC"), "Wolt's fees")
+}
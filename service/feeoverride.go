@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	debtDomain "github.com/oriser/bolt/debt"
+)
+
+const (
+	// ThreadCommandPrefix starts the commands replied in the thread of an order without mentioning Bolt, like "!delivery 25"
+	ThreadCommandPrefix = "!"
+	DeliveryKeyword     = "delivery"
+	ExtraKeyword        = "extra"
+)
+
+const feeOverrideUsage = "Usage: `!delivery <amount>` to set the delivery fee, or `!extra <tip|service|discount> <amount>` to add a " +
+	"tip, a service fee or a discount the rates don't have"
+
+// IsThreadCommand returns whether the reply in a thread is a command for Bolt, for the notification layers to pass to
+// HandleThreadCommand
+func IsThreadCommand(text string) bool {
+	return strings.HasPrefix(strings.TrimSpace(text), ThreadCommandPrefix)
+}
+
+// HandleThreadCommand handles a command replied in the thread of an order without mentioning Bolt, like "!delivery 25". It returns
+// a response to reply in the thread, if any.
+func (h *Service) HandleThreadCommand(req MentionRequest) (string, error) {
+	text := strings.TrimPrefix(strings.TrimSpace(req.Text), ThreadCommandPrefix)
+	keyword, args, _ := strings.Cut(text, " ")
+	if !strings.EqualFold(keyword, DeliveryKeyword) && !strings.EqualFold(keyword, ExtraKeyword) {
+		return "", nil
+	}
+	return h.handleFeeOverride(req, keyword, args)
+}
+
+// parseFeeOverride parses the arguments of the delivery and extra commands, returning the change of the order's fees. Setting a fee
+// again replaces it, so the host can correct a mistake.
+func parseFeeOverride(keyword, args string) (func(*Fees), error) {
+	fields := strings.Fields(args)
+	if strings.EqualFold(keyword, DeliveryKeyword) {
+		if len(fields) != 1 {
+			return nil, fmt.Errorf("expected the delivery fee")
+		}
+		amount, err := parseSplitAmount(fields[0])
+		if err != nil {
+			return nil, err
+		}
+		return func(f *Fees) { f.Delivery = amount }, nil
+	}
+
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("expected the kind of the extra and its amount")
+	}
+	amount, err := parseSplitAmount(fields[1])
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(fields[0]) {
+	case "tip":
+		return func(f *Fees) { f.Tip = amount }, nil
+	case "service":
+		return func(f *Fees) { f.Service = amount }, nil
+	case "discount":
+		return func(f *Fees) { f.Discount = amount }, nil
+	default:
+		return nil, fmt.Errorf("unknown extra %q", fields[0])
+	}
+}
+
+// describeFees returns the fees which aren't zero, like "delivery 25.00, tip 10.00"
+func describeFees(fees Fees) string {
+	parts := make([]string, 0, 4)
+	for _, fee := range []struct {
+		name   string
+		amount float64
+	}{{"delivery", fees.Delivery}, {"service", fees.Service}, {"tip", fees.Tip}, {"discount", fees.Discount}} {
+		if fee.amount != 0 {
+			parts = append(parts, fmt.Sprintf("%s %.2f", fee.name, fee.amount))
+		}
+	}
+	if len(parts) == 0 {
+		return "no fees"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ratesWithFees returns the amount of each participant by Wolt name with the given fees, computed from the amounts of their items
+// the same way the published rates were
+func (h *Service) ratesWithFees(channel string, groupRate GroupRate, fees Fees) map[string]float64 {
+	host := groupRate.HostWoltUser
+	rates := h.allocateDiscount(copyRates(groupRate.ItemRates), host, groupRate.Discount)
+	rates = h.channelFeeAllocator(channel).Allocate(rates, host, fees)
+	if _, ok := rates[host]; !ok {
+		rates[host] = 0
+	}
+	return h.roundRates(rates, host)
+}
+
+// handleFeeOverride handles the host's correction of the fees of an order whose rates were published, for when Bolt couldn't get the
+// delivery fee or the order had extras like a tip. The rates are computed again with the fees, the rates message is edited and the
+// outstanding debts are updated, leaving alone the debts the host adjusted by hand.
+func (h *Service) handleFeeOverride(req MentionRequest, keyword, args string) (string, error) {
+	change, err := parseFeeOverride(keyword, args)
+	if err != nil {
+		return fmt.Sprintf("Couldn't change the fees: %s. %s", err, feeOverrideUsage), nil
+	}
+	ctx, cancel := h.storeContext()
+	defer cancel()
+	activeOrder := h.activeOrderByMessage(req.Channel, req.ThreadID)
+	var order *groupOrder
+	if activeOrder != nil {
+		order = h.workingOrders.get(activeOrder.ID)
+	}
+	if req.ThreadID == "" || activeOrder == nil || activeOrder.Rates == nil || order == nil || order.detailsMessageId == "" {
+		return "Reply in the thread of an order I track after I publish its rates to change its fees", nil
+	}
+	window := h.cfg.FeeCorrectionWindow
+	if window <= 0 || time.Since(activeOrder.RatesPublishedAt) > window {
+		return "It's too late to change the fees of this order, adjust the debts instead", nil
+	}
+	groupRate := *activeOrder.Rates
+	if groupRate.HostUser == nil || !h.actsForHost(groupRate.HostUser.TransportID, req.UserID) {
+		return ErrNotOrderHost.Error(), nil
+	}
+
+	fees := h.rateAdjustments.fees(activeOrder.ID, groupRate.fees())
+	change(&fees)
+	h.rateAdjustments.setFees(activeOrder.ID, fees, h.ratesWithFees(req.Channel, groupRate, fees))
+	if err := h.editRatesMessage(req.Channel, order, groupRate, h.buildRatesMessage(req.Channel, groupRate, activeOrder.ID), ""); err != nil {
+		h.logger.Error("Error editing the rates message with the host's fees", "group_id", activeOrder.ID, "error", err)
+	}
+	adjusted, _ := h.rateAdjustments.apply(activeOrder.ID, groupRate)
+	updated, err := h.updateDebtsToRates(ctx, activeOrder.ID, adjusted)
+	if err != nil {
+		return "", fmt.Errorf("update debts: %w", err)
+	}
+
+	if err := h.eventNotification.AddReaction(req.Channel, req.MessageID, "white_check_mark"); err != nil {
+		h.logger.Error("Error acknowledging the fees change", "channel", req.Channel, "error", err)
+	}
+	_, _ = h.informEvent(req.Channel, h.text(req.Channel, msgFeesChanged, req.UserID, describeFees(fees), updated), "", req.ThreadID)
+	return "", nil
+}
+
+// updateDebtsToRates sets the outstanding debts of the order to the personal amounts of their borrowers in the rates, skipping the
+// debts the host forgave or changed by hand. Returns how many debts were changed.
+func (h *Service) updateDebtsToRates(ctx context.Context, orderID string, groupRate GroupRate) (int, error) {
+	if h.debtStore == nil {
+		return 0, nil
+	}
+	debts, err := h.debtStore.ListDebtsForOrderID(ctx, orderID)
+	if err != nil {
+		return 0, fmt.Errorf("list debts: %w", err)
+	}
+	updated := 0
+	for _, debt := range debts {
+		for _, rate := range groupRate.Rates {
+			if rate.User == nil || rate.User.ID != debt.BorrowerID || rate.Adjusted || rate.Forgiven {
+				continue
+			}
+			amount := math.Max(rate.PersonalAmount(), 0)
+			if amount == debt.Amount {
+				break
+			}
+			if amount == 0 {
+				if err = h.debtStore.RemoveDebtInOrderID(ctx, orderID, debt.ID); err == nil {
+					h.readModels.removeDebt(debt)
+				}
+			} else {
+				err = h.replaceDebt(ctx, debt, func(d *debtDomain.Debt) { d.Amount = amount })
+			}
+			if err != nil {
+				return updated, err
+			}
+			updated++
+			break
+		}
+	}
+	return updated, nil
+}
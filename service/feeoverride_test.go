@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFeeOverride(t *testing.T) {
+	t.Parallel()
+
+	fees := Fees{Delivery: 15, Tip: 5}
+	change, err := parseFeeOverride("Delivery", "25")
+	require.NoError(t, err)
+	change(&fees)
+	change, err = parseFeeOverride("extra", "TIP 10.5")
+	require.NoError(t, err)
+	change(&fees)
+	assert.Equal(t, Fees{Delivery: 25, Tip: 10.5}, fees, "setting a fee again replaces it")
+	assert.Equal(t, "delivery 25.00, tip 10.50", describeFees(fees))
+
+	for _, args := range [][2]string{{"delivery", ""}, {"delivery", "-5"}, {"extra", "tip"}, {"extra", "bribe 10"}, {"extra", "tip ten"}} {
+		_, err := parseFeeOverride(args[0], args[1])
+		assert.Error(t, err, args)
+	}
+	assert.True(t, IsThreadCommand(" !delivery 25"))
+	assert.False(t, IsThreadCommand("delivery 25"))
+}
+
+func TestHandleFeeOverride(t *testing.T) {
+	t.Parallel()
+
+	host := &userDomain.User{ID: "uuid-host", FullName: "Thor", TransportID: "U-host"}
+	loki := &userDomain.User{ID: "uuid-loki", FullName: "Loki", TransportID: "U-loki"}
+	odin := &userDomain.User{ID: "uuid-odin", FullName: "Odin", TransportID: "U-odin"}
+	store := &fakeTreasuryStore{
+		users: map[string]*userDomain.User{host.ID: host, loki.ID: loki, odin.ID: odin},
+		debts: []*debtDomain.Debt{
+			{ID: "d1", BorrowerID: loki.ID, LenderID: host.ID, OrderID: "ABC", Amount: 35},
+			{ID: "d2", BorrowerID: odin.ID, LenderID: host.ID, OrderID: "ABC", Amount: 20},
+		},
+	}
+	notification := &editingNotification{}
+	h, err := New(Config{FeeCorrectionWindow: time.Hour}, store, store, nil, "UBOT", notification)
+	require.NoError(t, err)
+
+	command := MentionRequest{Channel: "C1", MessageID: "2.1", ThreadID: "1.1", UserID: "U-host", Text: "!delivery 30"}
+	response, err := h.HandleThreadCommand(command)
+	require.NoError(t, err)
+	assert.Contains(t, response, "Reply in the thread of an order I track")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	entry, _, ok := h.workingOrders.start("ABC", time.Now(), 1, nil)
+	require.True(t, ok)
+	order := &groupOrder{id: "ABC", channel: "C1", messageID: "1.1", ctx: ctx, cancel: cancel, hostTransportID: "U-host",
+		detailsMessageId: "1.3"}
+	h.workingOrders.setOrder(entry, order)
+	groupRate := GroupRate{
+		HostWoltUser: "Thor",
+		HostUser:     host,
+		DeliveryRate: 15,
+		ItemRates:    map[string]float64{"Thor": 40, "Loki": 30, "Odin": 20},
+		Rates: []Rate{
+			{WoltName: "Thor", User: host, Amount: 45},
+			{WoltName: "Loki", User: loki, Amount: 35},
+			{WoltName: "Odin", User: odin, Amount: 25},
+		},
+	}
+	h.hooks.Emit(ctx, Event{Type: EventOrderJoined, OrderID: "ABC", Channel: "C1", MessageID: "1.1"})
+	h.hooks.Emit(ctx, Event{Type: EventRatesPublished, OrderID: "ABC", Rates: &groupRate})
+	// The host changed Odin's debt by hand, so the fees don't change it
+	adjusted := 20.0
+	h.rateAdjustments.update("ABC", "Odin", func(adjustment *rateAdjustment) { adjustment.amount = &adjusted })
+
+	response, err = h.HandleThreadCommand(MentionRequest{Channel: "C1", MessageID: "2.2", ThreadID: "1.1", UserID: "U-loki", Text: "!delivery 0"})
+	require.NoError(t, err)
+	assert.Equal(t, ErrNotOrderHost.Error(), response)
+
+	response, err = h.HandleThreadCommand(command)
+	require.NoError(t, err)
+	assert.Empty(t, response)
+	require.Len(t, store.debts, 2)
+	assert.Equal(t, 40.0, store.debts[1].Amount, "Loki pays a third of the new delivery fee")
+	assert.Equal(t, 20.0, store.debts[0].Amount)
+	assert.Contains(t, notification.messages, "C1: :white_check_mark: <@U-host> set the fees to delivery 30.00. I updated the rates and the debts (1 changed)\n")
+	require.NotEmpty(t, notification.edits)
+	assert.Contains(t, notification.edits[len(notification.edits)-1], "(including 30 NIS for delivery)")
+
+	response, err = h.HandleThreadCommand(MentionRequest{Channel: "C1", MessageID: "2.3", ThreadID: "1.1", UserID: "U-host", Text: "!extra tip 6"})
+	require.NoError(t, err)
+	assert.Empty(t, response)
+	assert.Equal(t, 42.0, store.debts[1].Amount)
+	assert.Contains(t, notification.edits[len(notification.edits)-1], "Including extras the host added: tip 6.00")
+
+	response, err = h.HandleThreadCommand(MentionRequest{Channel: "C1", MessageID: "2.4", ThreadID: "1.1", UserID: "U-host", Text: "!extra bribe 6"})
+	require.NoError(t, err)
+	assert.Contains(t, response, "Usage:")
+}
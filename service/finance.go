@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oriser/bolt/order"
+)
+
+// untaggedCostCenter is the cost center of orders without #tags
+const untaggedCostCenter = "untagged"
+
+// FileUploader is implemented by notification layers which can send files
+type FileUploader interface {
+	UploadFile(receiver, filename, content, comment string) error
+}
+
+// FinanceReport is the spending of the done orders in a period, aggregated per cost center (the #tags of the orders) and per user
+type FinanceReport struct {
+	From        time.Time
+	To          time.Time
+	CostCenters []*FinanceReportRow
+	Users       []*FinanceReportRow
+	Currency    string // The ISO 4217 code of the currency of the amounts
+}
+
+// FinanceReportRow is the spending of a cost center or a user
+type FinanceReportRow struct {
+	Name       string
+	ID         string // The user ID of a user's row
+	Orders     int
+	Subsidized float64 // The part of the amounts covered by the company: the subsidy, or the whole amount of company-paid orders
+	Personal   float64 // The part of the amounts the participants paid themselves
+}
+
+// Total returns the total spending of the row
+func (r *FinanceReportRow) Total() float64 {
+	return r.Subsidized + r.Personal
+}
+
+// Subsidized returns the total subsidized amount of the report
+func (r *FinanceReport) Subsidized() float64 {
+	total := 0.0
+	for _, row := range r.Users {
+		total += row.Subsidized
+	}
+	return total
+}
+
+// Personal returns the total personal amount of the report
+func (r *FinanceReport) Personal() float64 {
+	total := 0.0
+	for _, row := range r.Users {
+		total += row.Personal
+	}
+	return total
+}
+
+// quarterStart returns the start of the quarter of the given time, in its location
+func quarterStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month()-(t.Month()-1)%3, 1, 0, 0, 0, 0, t.Location())
+}
+
+// FinanceReport returns the finance report of the done orders created in [from, to)
+func (h *Service) FinanceReport(ctx context.Context, from, to time.Time) (*FinanceReport, error) {
+	if h.orderStore == nil {
+		return nil, fmt.Errorf("no order store")
+	}
+	orders, err := h.orderStore.ListOrders(ctx, order.ListFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("list orders: %w", err)
+	}
+
+	costCenters := make(map[string]*FinanceReportRow)
+	users := make(map[string]*FinanceReportRow)
+	for _, o := range orders {
+		if o.Status != order.StatusDone || o.CreatedAt.Before(from) || !o.CreatedAt.Before(to) {
+			continue
+		}
+		tags := o.Tags
+		if len(tags) == 0 {
+			tags = []string{untaggedCostCenter}
+		}
+		for _, tag := range tags {
+			if _, ok := costCenters[tag]; !ok {
+				costCenters[tag] = &FinanceReportRow{Name: tag}
+			}
+			costCenters[tag].Orders++
+		}
+		for _, p := range o.Participants {
+			subsidized, personal := p.Subsidy, p.PersonalAmount()
+			if o.CompanyPaid {
+				subsidized, personal = p.Amount, 0
+			}
+			// Every tag of the order is charged for the whole order, as there is no way to tell how it's split between them
+			for _, tag := range tags {
+				costCenters[tag].Subsidized += subsidized
+				costCenters[tag].Personal += personal
+			}
+			key := p.ID
+			if key == "" {
+				key = "name:" + p.Name
+			}
+			if _, ok := users[key]; !ok {
+				users[key] = &FinanceReportRow{Name: p.Name, ID: p.ID}
+			}
+			users[key].Orders++
+			users[key].Subsidized += subsidized
+			users[key].Personal += personal
+		}
+	}
+
+	return &FinanceReport{
+		From:        from,
+		To:          to,
+		CostCenters: sortedFinanceRows(costCenters),
+		Users:       sortedFinanceRows(users),
+		Currency:    h.ordersCurrency(orders),
+	}, nil
+}
+
+// sortedFinanceRows returns the rows from the highest total spending to the lowest
+func sortedFinanceRows(rows map[string]*FinanceReportRow) []*FinanceReportRow {
+	ret := make([]*FinanceReportRow, 0, len(rows))
+	for _, row := range rows {
+		ret = append(ret, row)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Total() != ret[j].Total() {
+			return ret[i].Total() > ret[j].Total()
+		}
+		return ret[i].Name < ret[j].Name
+	})
+	return ret
+}
+
+// WriteCSV writes the report as a spreadsheet, with the cost centers rows followed by the users rows
+func (r *FinanceReport) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"section", "name", "user_id", "orders", "subsidized", "personal", "total"}); err != nil {
+		return fmt.Errorf("write CSV header: %w", err)
+	}
+	rows := make([][]string, 0, len(r.CostCenters)+len(r.Users))
+	for _, section := range []struct {
+		name string
+		rows []*FinanceReportRow
+	}{{"cost_center", r.CostCenters}, {"user", r.Users}} {
+		for _, row := range section.rows {
+			rows = append(rows, []string{section.name, row.Name, row.ID, strconv.Itoa(row.Orders),
+				strconv.FormatFloat(row.Subsidized, 'f', 2, 64), strconv.FormatFloat(row.Personal, 'f', 2, 64),
+				strconv.FormatFloat(row.Total(), 'f', 2, 64)})
+		}
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		return fmt.Errorf("write CSV rows: %w", err)
+	}
+	return nil
+}
+
+func buildFinanceReportMessage(report *FinanceReport) string {
+	unit := CurrencyUnit(report.Currency)
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(":bar_chart: Finance report for %s - %s: %.2f %s subsidized and %.2f %s paid personally\n",
+		report.From.Format("2006-01-02"), report.To.AddDate(0, 0, -1).Format("2006-01-02"), report.Subsidized(), unit, report.Personal(), unit))
+	for _, row := range report.CostCenters {
+		sb.WriteString(fmt.Sprintf("• #%s: %d orders, %.2f %s subsidized and %.2f %s personal\n", row.Name, row.Orders, row.Subsidized, unit,
+			row.Personal, unit))
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// sendFinanceReport sends the report to FINANCE_REPORT_CHANNEL, as a spreadsheet when the notification layer can send files
+func (h *Service) sendFinanceReport(ctx context.Context, from, to time.Time) {
+	report, err := h.FinanceReport(ctx, from, to)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "Error getting finance report", "from", from.Format("2006-01-02"), "error", err)
+		return
+	}
+	message := buildFinanceReportMessage(report)
+
+	uploader, ok := h.eventNotification.(FileUploader)
+	if !ok {
+		if _, err := h.informEvent(h.cfg.FinanceReportChannel, message, "", ""); err != nil {
+			h.logger.ErrorContext(ctx, "Error sending finance report", "channel", h.cfg.FinanceReportChannel, "error", err)
+		}
+		return
+	}
+	var content strings.Builder
+	if err := report.WriteCSV(&content); err != nil {
+		h.logger.ErrorContext(ctx, "Error writing finance report", "from", from.Format("2006-01-02"), "error", err)
+		return
+	}
+	filename := fmt.Sprintf("bolt-finance-%s.csv", from.Format("2006-01"))
+	if err := uploader.UploadFile(h.cfg.FinanceReportChannel, filename, content.String(), message); err != nil {
+		h.logger.ErrorContext(ctx, "Error uploading finance report", "channel", h.cfg.FinanceReportChannel, "error", err)
+	}
+}
+
+// RunFinanceReporter sends the finance report of the previous quarter to FINANCE_REPORT_CHANNEL, on the first day of every quarter at
+// FINANCE_REPORT_HOUR, until the context is done
+func (h *Service) RunFinanceReporter(ctx context.Context) {
+	if h.cfg.FinanceReportChannel == "" || h.orderStore == nil {
+		return
+	}
+
+	ticker := time.NewTicker(badgesSchedulerInterval)
+	defer ticker.Stop()
+
+	h.schedulers.beat("finance reporter", badgesSchedulerInterval)
+	lastCheck := time.Now()
+	for {
+		select {
+		case now := <-ticker.C:
+			tz := h.timezoneForChannel(h.cfg.FinanceReportChannel, nil)
+			sendAt := quarterStart(now.In(tz)).Add(time.Duration(h.cfg.FinanceReportHour) * time.Hour)
+			if sendAt.After(lastCheck) && !sendAt.After(now) {
+				h.sendFinanceReport(ctx, quarterStart(sendAt.AddDate(0, -3, 0)), quarterStart(sendAt))
+			}
+			lastCheck = now
+			h.schedulers.beat("finance reporter", badgesSchedulerInterval)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
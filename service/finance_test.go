@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oriser/bolt/order"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type uploadingNotification struct {
+	recordingNotification
+	files []string
+}
+
+func (u *uploadingNotification) UploadFile(receiver, filename, content, comment string) error {
+	u.files = append(u.files, receiver+": "+filename+"\n"+comment+"\n"+content)
+	return nil
+}
+
+func TestQuarterStart(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), quarterStart(time.Date(2024, 5, 17, 13, 0, 0, 0, time.UTC)))
+	assert.Equal(t, time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC), quarterStart(time.Date(2024, 12, 31, 23, 0, 0, 0, time.UTC)))
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), quarterStart(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestFinanceReport(t *testing.T) {
+	t.Parallel()
+
+	inQuarter := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	notification := &uploadingNotification{}
+	h := &Service{
+		logger:            slog.Default(),
+		cfg:               Config{FinanceReportChannel: "CFINANCE"},
+		eventNotification: notification,
+		orderStore: &fakeOrderStore{orders: []*order.Order{
+			{OriginalID: "A", CreatedAt: inQuarter, Status: order.StatusDone, Tags: []string{"rnd"},
+				Participants: []order.Participant{{Name: "Loki", ID: "U1", Amount: 60, Subsidy: 50}, {Name: "Thor", ID: "U2", Amount: 40, Subsidy: 40}}},
+			{OriginalID: "B", CreatedAt: inQuarter, Status: order.StatusDone,
+				Participants: []order.Participant{{Name: "Loki", ID: "U1", Amount: 30}, {Name: "Odin", Amount: 20, Subsidy: 10}}},
+			{OriginalID: "C", CreatedAt: inQuarter, Status: order.StatusCanceled, Tags: []string{"rnd"},
+				Participants: []order.Participant{{Name: "Loki", ID: "U1", Amount: 100}}},
+			{OriginalID: "D", CreatedAt: inQuarter.AddDate(0, 2, 0), Status: order.StatusDone, Tags: []string{"rnd"},
+				Participants: []order.Participant{{Name: "Loki", ID: "U1", Amount: 100}}},
+		}},
+	}
+
+	from, to := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	report, err := h.FinanceReport(context.Background(), from, to)
+	require.NoError(t, err)
+	assert.Equal(t, []*FinanceReportRow{
+		{Name: "rnd", Orders: 1, Subsidized: 90, Personal: 10},
+		{Name: untaggedCostCenter, Orders: 1, Subsidized: 10, Personal: 40},
+	}, report.CostCenters)
+	assert.Equal(t, []*FinanceReportRow{
+		{Name: "Loki", ID: "U1", Orders: 2, Subsidized: 50, Personal: 40},
+		{Name: "Thor", ID: "U2", Orders: 1, Subsidized: 40},
+		{Name: "Odin", Orders: 1, Subsidized: 10, Personal: 10},
+	}, report.Users)
+
+	h.sendFinanceReport(context.Background(), from, to)
+	require.Len(t, notification.files, 1)
+	assert.Equal(t, "CFINANCE: bolt-finance-2024-04.csv\n"+
+		":bar_chart: Finance report for 2024-04-01 - 2024-06-30: 100.00 NIS subsidized and 50.00 NIS paid personally\n"+
+		"• #rnd: 1 orders, 90.00 NIS subsidized and 10.00 NIS personal\n"+
+		"• #untagged: 1 orders, 10.00 NIS subsidized and 40.00 NIS personal\n"+
+		"section,name,user_id,orders,subsidized,personal,total\n"+
+		"cost_center,rnd,,1,90.00,10.00,100.00\n"+
+		"cost_center,untagged,,1,10.00,40.00,50.00\n"+
+		"user,Loki,U1,2,50.00,40.00,90.00\n"+
+		"user,Thor,U2,1,40.00,0.00,40.00\n"+
+		"user,Odin,,1,10.00,10.00,20.00\n", notification.files[0])
+
+	text := &recordingNotification{}
+	h.eventNotification = text
+	h.sendFinanceReport(context.Background(), from, to)
+	require.Len(t, text.messages, 1)
+	assert.True(t, strings.HasPrefix(text.messages[0], "CFINANCE: :bar_chart: Finance report"), "without file uploads the summary is sent")
+}
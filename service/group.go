@@ -2,98 +2,263 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
+	"github.com/oriser/bolt/cache"
+	"github.com/oriser/bolt/logging"
 	"github.com/oriser/bolt/order"
+	"github.com/oriser/bolt/tracing"
 	"github.com/oriser/bolt/wolt"
 )
 
-func (h *Service) joinGroupOrder(groupID string) (*groupOrder, error) {
-	g, err := wolt.NewGroupWithExistingID(wolt.WoltAddr{
+var (
+	// ErrOrderCanceled is returned while waiting for a group order which was canceled in Wolt
+	ErrOrderCanceled = errors.New("order canceled")
+	// ErrWaitTimeout is returned when the context of a group order is done while waiting for the order to progress
+	ErrWaitTimeout = errors.New("context canceled while waiting for group to progress")
+)
+
+func (h *Service) woltConfig() (wolt.WoltAddr, wolt.RetryConfig) {
+	addr := wolt.WoltAddr{
 		BaseAddr:    h.cfg.WoltBaseAddr,
 		APIBaseAddr: h.cfg.WoltApiBaseAddr,
-	}, wolt.RetryConfig{
+	}
+	retryConfig := wolt.RetryConfig{
 		HTTPMaxRetries:       h.cfg.WoltHTTPMaxRetryCount,
 		HTTPMinRetryDuration: h.cfg.WoltHTTPMinRetryDuration,
 		HTTPMaxRetryDuration: h.cfg.WoltHTTPMaxRetryDuration,
-	}, groupID)
+		HTTPTimeout:          h.cfg.WoltHTTPTimeout,
+		Guard:                h.woltGuard,
+		VenueCache:           h.venueCache,
+		VenueCacheTTL:        h.venueCacheTTL,
+	}
+	return addr, retryConfig
+}
+
+// SetVenueCache sets the cache of the Wolt venues, which are looked up repeatedly while monitoring the orders. A closed venue is
+// noticed up to the TTL late.
+func (h *Service) SetVenueCache(c cache.Cache, ttl time.Duration) {
+	h.venueCache, h.venueCacheTTL = c, ttl
+}
+
+// joinGroupOrder joins the group order of the link in its provider, whose requests are recorded as spans of the trace of ctx, and
+// whose log lines have the attributes of ctx
+func (h *Service) joinGroupOrder(ctx context.Context, link groupLink) (*groupOrder, error) {
+	parent := logging.CopyAttrs(tracing.ContextWithSpan(h.lifetime(), tracing.SpanFromContext(ctx)), ctx)
+	order := newGroupOrder(parent, link.groupID, link.provider)
+	g, err := link.provider.Join(order.ctx, providerGroupID(link.provider, link.groupID))
 	if err != nil {
-		return nil, fmt.Errorf("new existing group: %w", err)
+		order.cancel()
+		return nil, err
 	}
+	order.group = g
+	return order, nil
+}
 
-	if err := g.Join(); err != nil {
-		return nil, fmt.Errorf("join group: %w", err)
+// restoreGroupOrder returns the group order of a provider session persisted before a restart, without joining it again
+func (h *Service) restoreGroupOrder(provider Provider, groupID string, session json.RawMessage) (*groupOrder, error) {
+	g, err := provider.Restore(providerGroupID(provider, groupID), session)
+	if err != nil {
+		return nil, err
 	}
+	order := newGroupOrder(h.lifetime(), groupID, provider)
+	order.group = g
+	return order, nil
+}
 
+// newGroupOrder returns the group order, whose requests to its provider are canceled once it's stopped or the service shuts down
+// (parent is done)
+func newGroupOrder(parent context.Context, groupID string, provider Provider) *groupOrder {
+	ctx, cancel := context.WithCancel(parent)
 	return &groupOrder{
 		deliveryPrice: -1,
 		id:            groupID,
-		woltGroup:     g,
-	}, nil
+		provider:      provider,
+		ctx:           ctx,
+		cancel:        cancel,
+	}
 }
 
 type groupOrder struct {
-	id               string
-	deliveryPrice    int
-	woltGroup        *wolt.Group
-	markedAsReady    bool
-	details          *wolt.OrderDetails
-	venue            *wolt.Venue
-	detailsMessageId string
+	// lock guards the details, venue and delivery price, which the venue monitoring updates while waiting for the group to finish,
+	// and the stop reason, host, headcount, company payment, tracking state and the messages continuing the rates message (if it's
+	// longer than RATES_MESSAGE_MAX_LENGTH)
+	lock              sync.RWMutex
+	id                string
+	deliveryPrice     int
+	provider          Provider
+	group             ProviderGroup // nil until the group is joined
+	markedAsReady     bool
+	details           *OrderDetails
+	venue             *Venue
+	detailsMessageId  string
+	joinedMessageID   string // The message announcing Bolt joined the order
+	messageID         string // The message with the order link
+	text              string // The text of the message with the order link
+	channel           string
+	startedAt         time.Time // When Bolt started tracking the order, before any restart
+	tags              []string
+	note              string // The host's note from the message with the order link
+	timeoutMultiplier int    // Extends the order timeouts, from the ⏳x<N> directive of the message with the order link
+	hostTransportID   string // Known once the rates are computed, if the host is a known user
+	ctx               context.Context
+	cancel            context.CancelFunc
+	stopReason        string
+	surge             bool          // The venue was busy (with surge delivery pricing) while the group was open
+	headcount         int           // How many people were in the office when Bolt joined, 0 if unknown
+	companyPaid       bool          // The host paid with a company card, so there are no debts
+	state             OrderState    // How far the tracking got, persisted with the tracking state
+	deliveryState     DeliveryState // How far the delivery got, once it's monitored
+	continuations     []ratesContinuation
 }
 
-func (g *groupOrder) fetchDetails() (*wolt.OrderDetails, error) {
-	details, err := g.woltGroup.Details()
+func (g *groupOrder) fetchDetails() (*OrderDetails, error) {
+	details, err := g.group.Details(g.ctx)
 	if err != nil {
 		return nil, fmt.Errorf("get order details: %w", err)
 	}
+	g.lock.Lock()
 	g.details = details
+	g.lock.Unlock()
 	return details, nil
 }
 
-func (g *groupOrder) fetchVenue() (*wolt.Venue, error) {
+func (g *groupOrder) fetchVenue() (*Venue, error) {
 	details, err := g.fetchDetails()
 	if err != nil {
 		return nil, fmt.Errorf("get group details: %w", err)
 	}
 
-	venue, err := g.woltGroup.VenueDetails(details)
+	venue, err := g.group.VenueDetails(g.ctx, details)
 	if err != nil {
 		return nil, fmt.Errorf("get venue details: %w", err)
 	}
+	g.lock.Lock()
 	g.venue = venue
+	g.lock.Unlock()
 	return venue, nil
 }
 
+// switchVenue replaces the venue of the order, after the host switched to another venue (e.g. another branch of a chain)
+func (g *groupOrder) switchVenue(venue *Venue) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.venue = venue
+	g.deliveryPrice = -1
+}
+
+// stop cancels the monitors of the order. It returns false if the order was already stopped.
+func (g *groupOrder) stop(reason string) bool {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if g.stopReason != "" {
+		return false
+	}
+	g.stopReason = reason
+	if g.cancel != nil {
+		g.cancel()
+	}
+	return true
+}
+
+// stopped returns why the order was stopped, or an empty string if it wasn't
+func (g *groupOrder) stopped() string {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	return g.stopReason
+}
+
+func (g *groupOrder) setHost(transportID string) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.hostTransportID = transportID
+}
+
+func (g *groupOrder) HostTransportID() string {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	return g.hostTransportID
+}
+
+// noteSurge records whether the venue is busy. It returns true if the venue became busy.
+func (g *groupOrder) noteSurge(venue *Venue) bool {
+	if !venue.Busy {
+		return false
+	}
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if g.surge {
+		return false
+	}
+	g.surge = true
+	return true
+}
+
+func (g *groupOrder) hadSurge() bool {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	return g.surge
+}
+
+func (g *groupOrder) setHeadcount(count int) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.headcount = count
+}
+
+func (g *groupOrder) officeHeadcount() int {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	return g.headcount
+}
+
+// currentVenue returns the venue, if it was already fetched
+func (g *groupOrder) currentVenue() *Venue {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	return g.venue
+}
+
 func (g *groupOrder) MarkAsReady() error {
-	if err := g.woltGroup.MarkAsReady(); err != nil {
-		return fmt.Errorf("wolt mark as ready: %w", err)
+	if err := g.group.MarkAsReady(g.ctx); err != nil {
+		return fmt.Errorf("mark as ready: %w", err)
 	}
 	g.markedAsReady = true
 	return nil
 }
 
-func (h *Service) WaitUntilFinished(order *groupOrder, ctx context.Context) error {
+func (h *Service) WaitUntilFinished(order *groupOrder, ctx context.Context) (err error) {
+	_, span := tracing.Start(order.ctx, "wolt.wait_until_finished", tracing.String("group_id", order.id))
+	polls := 0
+	defer func() {
+		span.SetAttributes(tracing.String("polls", fmt.Sprint(polls)))
+		span.SetError(err)
+		span.End()
+	}()
+
 	details, err := order.fetchDetails()
 	if err != nil {
 		return fmt.Errorf("get group details: %w", err)
 	}
 
-	for details.Status == wolt.StatusActive {
-		select {
-		case <-time.After(h.cfg.WaitBetweenStatusCheck):
-			details, err = order.fetchDetails()
-			if err != nil {
-				return fmt.Errorf("get group details: %w", err)
-			}
-		case <-ctx.Done():
-			return fmt.Errorf("context canceled while waiting for group to progress")
+	progress := &waitProgress{lastNoteAt: time.Now()}
+	for details.Status == OrderStatusActive {
+		polls++
+		details, err = h.pollDetails(ctx, order, h.cfg.WaitBetweenStatusCheck)
+		if err != nil {
+			return err
+		}
+		if details.Status == OrderStatusActive {
+			h.noteWaitProgress(order, details, progress, time.Now())
 		}
 	}
 
-	if details.Status == wolt.StatusCanceled {
-		return fmt.Errorf("order canceled")
+	if details.Status == OrderStatusCanceled {
+		return ErrOrderCanceled
 	}
 
 	if !details.Status.Purchased() {
@@ -103,23 +268,63 @@ func (h *Service) WaitUntilFinished(order *groupOrder, ctx context.Context) erro
 	return nil
 }
 
-func (g *groupOrder) Details() (*wolt.OrderDetails, error) {
-	if g.details == nil {
+// pollDetails waits between status checks and fetches the details of the order. Failing to fetch them doesn't stop tracking the
+// order: polling goes on, backing off while Wolt's API is degraded, until it keeps failing for WoltPollFailureTimeout.
+func (h *Service) pollDetails(ctx context.Context, order *groupOrder, waitBetweenStatusCheck time.Duration) (*OrderDetails, error) {
+	return h.pollWolt(ctx, order.ctx, waitBetweenStatusCheck, order.fetchDetails)
+}
+
+// pollWolt is pollDetails with any way of fetching the details, logging the failures with the attributes of logCtx
+func (h *Service) pollWolt(ctx, logCtx context.Context, waitBetweenStatusCheck time.Duration,
+	fetch func() (*OrderDetails, error)) (*OrderDetails, error) {
+	var failingSince time.Time
+	for {
+		select {
+		case <-time.After(h.woltGuard.PollInterval(waitBetweenStatusCheck)):
+		case <-ctx.Done():
+			return nil, ErrWaitTimeout
+		}
+
+		details, err := fetch()
+		if err == nil {
+			return details, nil
+		}
+		if failingSince.IsZero() {
+			failingSince = time.Now()
+		}
+		if time.Since(failingSince) >= h.cfg.WoltPollFailureTimeout {
+			return nil, fmt.Errorf("get order details: %w", err)
+		}
+		h.logger.WarnContext(logCtx, "Error getting the details of the order, polling it again", "error", err)
+	}
+}
+
+func (g *groupOrder) Details() (*OrderDetails, error) {
+	g.lock.RLock()
+	details := g.details
+	g.lock.RUnlock()
+	if details == nil {
 		return g.fetchDetails()
 	}
-	return g.details, nil
+	return details, nil
 }
 
-func (g *groupOrder) Venue() (*wolt.Venue, error) {
-	if g.venue == nil {
+func (g *groupOrder) Venue() (*Venue, error) {
+	g.lock.RLock()
+	venue := g.venue
+	g.lock.RUnlock()
+	if venue == nil {
 		return g.fetchVenue()
 	}
-	return g.venue, nil
+	return venue, nil
 }
 
 func (g *groupOrder) CalculateDeliveryRate() (int, error) {
-	if g.deliveryPrice >= 0 {
-		return g.deliveryPrice, nil
+	g.lock.RLock()
+	deliveryPrice := g.deliveryPrice
+	g.lock.RUnlock()
+	if deliveryPrice >= 0 {
+		return deliveryPrice, nil
 	}
 
 	venue, err := g.Venue()
@@ -127,20 +332,32 @@ func (g *groupOrder) CalculateDeliveryRate() (int, error) {
 		return 0, fmt.Errorf("get venue: %w", err)
 	}
 
-	details, err := g.Details()
-	if err != nil {
-		return 0, fmt.Errorf("get details: %w", err)
-	}
-
-	deliveryPrice, err := venue.CalculateDeliveryRate(details.ParsedDeliveryCoordinate)
-	if err != nil {
-		return 0, fmt.Errorf("get delivery price: %w", err)
-	}
-
+	deliveryPrice = venue.DeliveryRate
+	g.lock.Lock()
 	g.deliveryPrice = deliveryPrice
+	g.lock.Unlock()
 	return deliveryPrice, nil
 }
 
+// participantsOfRates returns the participants of the stored order with the given rates
+func participantsOfRates(rates []Rate) []order.Participant {
+	participants := make([]order.Participant, 0, len(rates))
+	for _, rate := range rates {
+		p := order.Participant{
+			Name:                rate.WoltName,
+			Amount:              rate.Amount,
+			AgeRestrictedAmount: rate.AgeRestrictedAmount,
+			Subsidy:             rate.Subsidy,
+			Private:             rate.Private,
+		}
+		if rate.User != nil {
+			p.ID = rate.User.ID
+		}
+		participants = append(participants, p)
+	}
+	return participants
+}
+
 func (g *groupOrder) ToOrder(rates []Rate, receiver string) (*order.Order, error) {
 	details, err := g.Details()
 	if err != nil {
@@ -158,36 +375,33 @@ func (g *groupOrder) ToOrder(rates []Rate, receiver string) (*order.Order, error
 
 	status := order.StatusInvalid
 	switch {
-	case details.Status == wolt.StatusCanceled:
+	case g.stopped() != "":
+		status = order.StatusStopped
+	case details.Status == OrderStatusCanceled:
 		status = order.StatusCanceled
 	case details.Status.Purchased():
 		status = order.StatusDone
 	}
 
-	participants := make([]order.Participant, 0, len(rates))
-	for _, rate := range rates {
-		p := order.Participant{
-			Name:   rate.WoltName,
-			Amount: rate.Amount,
-		}
-		if rate.User != nil {
-			p.ID = rate.User.ID
-		}
-		participants = append(participants, p)
-	}
-
 	return &order.Order{
 		OriginalID:   g.id,
 		CreatedAt:    details.CreatedAt,
 		Receiver:     receiver,
 		VenueName:    venue.Name,
-		VenueID:      details.Details.VenueID,
+		VenueID:      details.VenueID,
 		VenueLink:    venue.Link,
 		VenueCity:    venue.City,
 		Host:         details.Host,
 		HostID:       details.HostID,
 		Status:       status,
-		Participants: participants,
+		Participants: participantsOfRates(rates),
 		DeliveryRate: deliveryPrice,
+		MessageID:    g.messageID,
+		Tags:         g.tags,
+		Surge:        g.hadSurge(),
+		Headcount:    g.officeHeadcount(),
+		Items:        details.ItemCounts(),
+		CompanyPaid:  g.isCompanyPaid(),
+		Note:         g.note,
 	}, nil
 }
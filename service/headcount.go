@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/oriser/bolt/headcount"
+	"github.com/oriser/bolt/order"
+)
+
+const (
+	// headcountTolerance is how far (as a fraction of the headcount) the headcount of past orders can be for them to count as having
+	// the same headcount
+	headcountTolerance       = 0.1
+	headcountSuggestionItems = 3
+)
+
+// HeadcountSuggestion is what past orders from a venue with about the same office headcount averaged
+type HeadcountSuggestion struct {
+	Headcount int
+	Orders    int // How many past orders the suggestion is based on
+	Items     []SuggestedItem
+}
+
+// SuggestedItem is the average quantity of an item in the past orders
+type SuggestedItem struct {
+	Name     string
+	Quantity int
+}
+
+// SetHeadcountProvider sets the office headcount source, for suggesting hosts how much to order based on past orders with the same headcount
+func (h *Service) SetHeadcountProvider(provider headcount.Provider) {
+	h.headcountProvider = provider
+}
+
+// similarHeadcount returns whether the headcount of a past order counts as the same as the current headcount
+func similarHeadcount(past, current int) bool {
+	tolerance := math.Max(1, math.Round(float64(current)*headcountTolerance))
+	return past > 0 && math.Abs(float64(past-current)) <= tolerance
+}
+
+// HeadcountSuggestion returns the items past done orders from the venue with about the given headcount averaged, the most ordered first
+func (h *Service) HeadcountSuggestion(ctx context.Context, count int, venueName string) (*HeadcountSuggestion, error) {
+	if h.orderStore == nil {
+		return nil, fmt.Errorf("no order store")
+	}
+	orders, err := h.orderStore.ListOrders(ctx, order.ListFilter{VenueName: venueName})
+	if err != nil {
+		return nil, fmt.Errorf("list orders: %w", err)
+	}
+
+	suggestion := &HeadcountSuggestion{Headcount: count}
+	totals := make(map[string]int)
+	for _, o := range orders {
+		if o.Status != order.StatusDone || !strings.EqualFold(o.VenueName, venueName) || len(o.Items) == 0 || !similarHeadcount(o.Headcount, count) {
+			continue
+		}
+		suggestion.Orders++
+		for name, quantity := range o.Items {
+			totals[name] += quantity
+		}
+	}
+	for name, total := range totals {
+		if quantity := int(math.Round(float64(total) / float64(suggestion.Orders))); quantity > 0 {
+			suggestion.Items = append(suggestion.Items, SuggestedItem{Name: name, Quantity: quantity})
+		}
+	}
+	sort.Slice(suggestion.Items, func(i, j int) bool {
+		if suggestion.Items[i].Quantity != suggestion.Items[j].Quantity {
+			return suggestion.Items[i].Quantity > suggestion.Items[j].Quantity
+		}
+		return suggestion.Items[i].Name < suggestion.Items[j].Name
+	})
+	if len(suggestion.Items) > headcountSuggestionItems {
+		suggestion.Items = suggestion.Items[:headcountSuggestionItems]
+	}
+	return suggestion, nil
+}
+
+func (h *Service) buildHeadcountMessage(ctx context.Context, channel string, suggestion *HeadcountSuggestion) string {
+	message := h.text(channel, msgHeadcount, suggestion.Headcount)
+	if len(suggestion.Items) == 0 {
+		return message
+	}
+	names := make([]string, len(suggestion.Items))
+	for i, item := range suggestion.Items {
+		names[i] = item.Name
+	}
+	names = h.translateItemNames(ctx, channel, names)
+	items := make([]string, len(suggestion.Items))
+	for i, item := range suggestion.Items {
+		items[i] = fmt.Sprintf("%d %s", item.Quantity, names[i])
+	}
+	return message + h.text(channel, msgHeadcountSuggestion, strings.Join(items, " + "))
+}
+
+// suggestForHeadcount tells the channel how many people are in the office today and what past orders from the venue with this
+// headcount averaged, and records the headcount on the order
+func (h *Service) suggestForHeadcount(order *groupOrder, channel, messageID string, venue *Venue) {
+	if h.headcountProvider == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(order.ctx, time.Minute)
+	defer cancel()
+
+	count, err := h.headcountProvider.Headcount(ctx, time.Now().In(h.timezoneForChannel(channel, venue.Timezone)))
+	if err != nil {
+		h.logger.ErrorContext(order.ctx, "Error getting the office headcount", "error", err)
+		return
+	}
+	order.setHeadcount(count)
+	if count == 0 {
+		return
+	}
+
+	suggestion, err := h.HeadcountSuggestion(ctx, count, venue.Name)
+	if err != nil {
+		h.logger.ErrorContext(order.ctx, "Error getting headcount suggestion", "error", err)
+		suggestion = &HeadcountSuggestion{Headcount: count}
+	}
+	_, _ = h.informEvent(channel, h.buildHeadcountMessage(ctx, channel, suggestion), "", messageID)
+}
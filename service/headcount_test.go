@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oriser/bolt/order"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeHeadcountProvider int
+
+func (f fakeHeadcountProvider) Headcount(context.Context, time.Time) (int, error) {
+	return int(f), nil
+}
+
+func TestSimilarHeadcount(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, similarHeadcount(14, 14))
+	assert.True(t, similarHeadcount(13, 14))
+	assert.False(t, similarHeadcount(12, 14))
+	assert.True(t, similarHeadcount(45, 50), "the tolerance grows with the headcount")
+	assert.False(t, similarHeadcount(0, 1), "orders with unknown headcount are never similar")
+}
+
+func TestHeadcountSuggestion(t *testing.T) {
+	t.Parallel()
+
+	notification := &recordingNotification{}
+	h, err := New(Config{Locale: "en"}, nil, nil, &fakeOrderStore{orders: []*order.Order{
+		{OriginalID: "A", Status: order.StatusDone, VenueName: "Pizza Place", Headcount: 14,
+			Items: map[string]int{"Margherita": 2, "Caesar salad": 4, "Cola": 1, "Garlic bread": 1}},
+		{OriginalID: "B", Status: order.StatusDone, VenueName: "Pizza Place", Headcount: 15,
+			Items: map[string]int{"Margherita": 2, "Caesar salad": 2, "Cola": 1}},
+		{OriginalID: "C", Status: order.StatusDone, VenueName: "Pizza Place", Headcount: 30, Items: map[string]int{"Margherita": 6}},
+		{OriginalID: "D", Status: order.StatusCanceled, VenueName: "Pizza Place", Headcount: 14, Items: map[string]int{"Margherita": 10}},
+		{OriginalID: "E", Status: order.StatusDone, VenueName: "Sushi Bar", Headcount: 14, Items: map[string]int{"Salmon roll": 5}},
+	}}, "UBOT", notification)
+	require.NoError(t, err)
+
+	suggestion, err := h.HeadcountSuggestion(context.Background(), 14, "pizza place")
+	require.NoError(t, err)
+	assert.Equal(t, &HeadcountSuggestion{Headcount: 14, Orders: 2, Items: []SuggestedItem{
+		{Name: "Caesar salad", Quantity: 3},
+		{Name: "Margherita", Quantity: 2},
+		{Name: "Cola", Quantity: 1},
+	}}, suggestion)
+
+	h.SetHeadcountProvider(fakeHeadcountProvider(14))
+	joined := &groupOrder{id: "F", ctx: context.Background()}
+	h.suggestForHeadcount(joined, "C1", "", &Venue{Name: "Pizza Place"})
+	assert.Equal(t, 14, joined.officeHeadcount())
+	assert.Equal(t, []string{"C1: :busts_in_silhouette: 14 people in the office today - past orders with this headcount averaged " +
+		"3 Caesar salad + 2 Margherita + 1 Cola"}, notification.messages)
+
+	notification.messages = nil
+	h.suggestForHeadcount(&groupOrder{id: "G", ctx: context.Background()}, "C1", "", &Venue{Name: "Burger Joint"})
+	assert.Equal(t, []string{"C1: :busts_in_silhouette: 14 people in the office today"}, notification.messages)
+}
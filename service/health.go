@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/oriser/bolt/order"
+)
+
+// ConnectionChecker is implemented by notification layers which can check their connection to the chat platform, like checking their
+// token
+type ConnectionChecker interface {
+	CheckConnection(ctx context.Context) error
+}
+
+// TrackedOrdersSummary is how many orders Bolt currently tracks, by their status
+type TrackedOrdersSummary struct {
+	Total    int            `json:"total"`
+	ByStatus map[string]int `json:"by_status"`
+}
+
+// TrackedOrders returns the summary of the orders Bolt currently tracks
+func (h *Service) TrackedOrders() TrackedOrdersSummary {
+	orders := h.ActiveOrders()
+	summary := TrackedOrdersSummary{Total: len(orders), ByStatus: make(map[string]int)}
+	for _, activeOrder := range orders {
+		summary.ByStatus[activeOrder.Status()]++
+	}
+	return summary
+}
+
+// ReadinessChecks checks the dependencies Bolt needs for tracking orders, without changing anything: that the chat platform and Wolt
+// can be reached and that the store can be read. Unlike SelfTest, it doesn't post messages, so it can run as often as a readiness
+// probe does.
+func (h *Service) ReadinessChecks(ctx context.Context) []SelfTestCheck {
+	transport := SelfTestCheck{Name: "Chat platform"}
+	if checker, ok := h.eventNotification.(ConnectionChecker); ok {
+		transport.Err = checker.CheckConnection(ctx)
+	} else {
+		transport.Skipped = "the notification layer can't check its connection"
+	}
+
+	store := SelfTestCheck{Name: "Store read"}
+	if h.orderStore == nil {
+		store.Skipped = "no order store"
+	} else if _, err := h.orderStore.ListOrders(ctx, order.ListFilter{Limit: 1}); err != nil {
+		store.Err = fmt.Errorf("list orders: %w", err)
+	}
+	return []SelfTestCheck{transport, h.checkWolt(ctx), store}
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/oriser/bolt/order"
+	userDomain "github.com/oriser/bolt/user"
+)
+
+const (
+	defaultHistoryOrders = 5
+	maxHistoryOrders     = 20
+)
+
+// HistoryOrder is a stored order of the order history, with the users its participants were matched to
+type HistoryOrder struct {
+	Order        *order.Order
+	Participants []HistoryParticipant
+}
+
+type HistoryParticipant struct {
+	order.Participant
+	User *userDomain.User // nil if the participant wasn't matched to a user, or the user wasn't found
+}
+
+// mention mentions the user of the participant, or shows its Wolt name if it has no user
+func (p HistoryParticipant) mention() string {
+	if p.User == nil {
+		return p.Name
+	}
+	return fmt.Sprintf("<@%s>", p.User.TransportID)
+}
+
+// host returns the host of the order, mentioned if it's one of the matched participants
+func (o HistoryOrder) host() string {
+	for _, p := range o.Participants {
+		if p.Name == o.Order.Host {
+			return p.mention()
+		}
+	}
+	return o.Order.Host
+}
+
+// OrderHistory returns the latest limit stored orders (5 if it's 0, at most 20) from the newest. If transportID is set, they're the
+// orders the user participated in, in any channel, otherwise they're the orders of the channel.
+func (h *Service) OrderHistory(ctx context.Context, channel, transportID string, limit int) ([]HistoryOrder, error) {
+	if h.orderStore == nil {
+		return nil, fmt.Errorf("no order store")
+	}
+	if limit <= 0 {
+		limit = defaultHistoryOrders
+	}
+	if limit > maxHistoryOrders {
+		limit = maxHistoryOrders
+	}
+
+	filter := order.ListFilter{Receiver: channel, Limit: uint64(limit)}
+	if transportID != "" {
+		userIDs, err := h.userIDsOfTransport(ctx, transportID)
+		if err != nil {
+			return nil, fmt.Errorf("user IDs of %s: %w", transportID, err)
+		}
+		filter = order.ListFilter{ParticipantIDs: sortedSet(userIDs), Limit: uint64(limit)}
+	}
+	orders, err := h.orderStore.ListOrders(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("list orders: %w", err)
+	}
+
+	users := make(map[string]*userDomain.User)
+	getUser := func(id string) *userDomain.User {
+		if u, ok := users[id]; ok {
+			return u
+		}
+		var u *userDomain.User
+		if h.userStore != nil {
+			if u, err = h.userStore.GetUser(ctx, id); err != nil {
+				h.logger.ErrorContext(ctx, "Error getting user for order history", "user_id", id, "error", err)
+				u = nil
+			}
+		}
+		users[id] = u
+		return u
+	}
+
+	history := make([]HistoryOrder, len(orders))
+	for i, o := range orders {
+		history[i] = HistoryOrder{Order: o, Participants: make([]HistoryParticipant, len(o.Participants))}
+		for j, p := range o.Participants {
+			history[i].Participants[j] = HistoryParticipant{Participant: p}
+			if p.ID != "" {
+				history[i].Participants[j].User = getUser(p.ID)
+			}
+		}
+	}
+	return history, nil
+}
+
+// BuildHistoryMessage returns the summary of the order history, a line per order, and the amount of every participant of each order,
+// which is posted in the thread of the summary. subject is who or where the orders are of, like a channel mention.
+func BuildHistoryMessage(history []HistoryOrder, subject string) (summary string, details string) {
+	if len(history) == 0 {
+		return fmt.Sprintf("No orders of %s yet", subject), ""
+	}
+
+	var summaryBuilder, detailsBuilder strings.Builder
+	summaryBuilder.WriteString(fmt.Sprintf(":scroll: The last %d orders of %s:\n", len(history), subject))
+	for i, o := range history {
+		total := FormatAmount(o.Order.TotalAmount(), o.Order.Currency)
+		if o.Order.HasPrivateAmounts() {
+			total = ":lock:"
+		}
+		summaryBuilder.WriteString(fmt.Sprintf("%d. %s %s - %s, hosted by %s\n", i+1, o.Order.CreatedAt.Format("2006-01-02"),
+			o.Order.VenueName, total, o.host()))
+
+		amounts := make([]string, len(o.Participants))
+		for j, p := range o.Participants {
+			amount := FormatAmount(p.Amount, o.Order.Currency)
+			if p.Private {
+				amount = ":lock:"
+			}
+			amounts[j] = fmt.Sprintf("%s %s", p.mention(), amount)
+		}
+		detailsBuilder.WriteString(fmt.Sprintf("*%d. %s*: %s\n", i+1, o.Order.VenueName, strings.Join(amounts, ", ")))
+	}
+	return summaryBuilder.String(), detailsBuilder.String()
+}
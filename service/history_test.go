@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oriser/bolt/order"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// filteringOrderStore is an order store which filters, sorts and pages the orders like the real stores
+type filteringOrderStore struct {
+	fakeOrderStore
+}
+
+func (f *filteringOrderStore) ListOrders(_ context.Context, filter order.ListFilter) ([]*order.Order, error) {
+	return filter.Apply(f.orders), nil
+}
+
+func TestOrderHistory(t *testing.T) {
+	t.Parallel()
+
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	orders := &filteringOrderStore{fakeOrderStore{orders: []*order.Order{
+		{ID: "1", Receiver: "C1", VenueName: "Pizza Place", Host: "Dana", CreatedAt: createdAt, Currency: "ILS",
+			Participants: []order.Participant{{Name: "Dana", ID: "dana", Amount: 40}, {Name: "Bob", Amount: 30}}},
+		{ID: "2", Receiver: "C2", VenueName: "Sushi Bar", Host: "Eli", CreatedAt: createdAt.AddDate(0, 0, 1), Currency: "ILS",
+			Participants: []order.Participant{{Name: "Eli", Amount: 50}, {Name: "Dana", ID: "dana", Amount: 25.5}}},
+		{ID: "3", Receiver: "C1", VenueName: "Burger Joint", Host: "Bob", CreatedAt: createdAt.AddDate(0, 0, 2), Currency: "ILS",
+			Participants: []order.Participant{{Name: "Bob", Amount: 60}}},
+	}}}
+	users := &fakeTreasuryStore{users: map[string]*userDomain.User{"dana": {ID: "dana", FullName: "Dana", TransportID: "U1"}}}
+	h, err := New(Config{}, users, nil, orders, "UBOT", nil)
+	require.NoError(t, err)
+
+	history, err := h.OrderHistory(context.Background(), "C1", "", 0)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, "Burger Joint", history[0].Order.VenueName, "the newest order is first")
+
+	history, err = h.OrderHistory(context.Background(), "C1", "U1", 1)
+	require.NoError(t, err)
+	require.Len(t, history, 1, "the user's orders are of all the channels")
+	assert.Equal(t, "Sushi Bar", history[0].Order.VenueName)
+
+	history, err = h.OrderHistory(context.Background(), "", "U1", 0)
+	require.NoError(t, err)
+	summary, details := BuildHistoryMessage(history, "<@U1>")
+	assert.Equal(t, ":scroll: The last 2 orders of <@U1>:\n"+
+		"1. 2024-05-02 Sushi Bar - 75.50 nis, hosted by Eli\n"+
+		"2. 2024-05-01 Pizza Place - 70.00 nis, hosted by <@U1>\n", summary)
+	assert.Equal(t, "*1. Sushi Bar*: Eli 50.00 nis, <@U1> 25.50 nis\n"+
+		"*2. Pizza Place*: <@U1> 40.00 nis, Bob 30.00 nis\n", details)
+
+	summary, details = BuildHistoryMessage(nil, "<#C3>")
+	assert.Equal(t, "No orders of <#C3> yet", summary)
+	assert.Empty(t, details)
+}
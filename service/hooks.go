@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	debtDomain "github.com/oriser/bolt/debt"
+)
+
+type EventType string
+
+const (
+	EventOrderJoined       EventType = "order_joined"
+	EventVenueChanged      EventType = "venue_changed" // The host switched the order to another venue, e.g. another branch of a chain
+	EventRatesPublished    EventType = "rates_published"
+	EventDeliveryProgress  EventType = "delivery_progress"
+	EventOrderDelivered    EventType = "order_delivered"
+	EventOrderCanceled     EventType = "order_canceled"
+	EventOrderStopped      EventType = "order_stopped" // Bolt stopped tracking the order, e.g. because its channel was archived
+	EventDebtCreated       EventType = "debt_created"
+	EventDebtPaid          EventType = "debt_paid"
+	EventOrderDebtsRemoved EventType = "order_debts_removed"
+	EventOrderSettled      EventType = "order_settled" // The last debt of the order was paid
+	EventOrderStateChanged EventType = "order_state_changed"
+)
+
+// Event describes something that happened in an order's lifecycle. Fields not relevant to the event type are left empty.
+type Event struct {
+	Type      EventType
+	Time      time.Time
+	OrderID   string // Wolt group ID
+	Channel   string
+	MessageID string
+	VenueName string
+	Rates     *GroupRate
+	Debt      *debtDomain.Debt
+	State     DeliveryState
+	// The state the order moved to, for EventOrderStateChanged
+	OrderState OrderState
+	Reason     string
+}
+
+type Hook func(ctx context.Context, event Event)
+
+// Hooks is a registry of in-process subscribers to order lifecycle events.
+// Hooks are called synchronously in subscription order, so long-running work should be moved to a goroutine by the hook itself.
+type Hooks struct {
+	lock  sync.RWMutex
+	hooks map[EventType][]Hook
+	all   []Hook
+}
+
+func NewHooks() *Hooks {
+	return &Hooks{hooks: make(map[EventType][]Hook)}
+}
+
+// Subscribe registers a hook for the given event types
+func (r *Hooks) Subscribe(hook Hook, eventTypes ...EventType) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, eventType := range eventTypes {
+		r.hooks[eventType] = append(r.hooks[eventType], hook)
+	}
+}
+
+// SubscribeAll registers a hook for every event type
+func (r *Hooks) SubscribeAll(hook Hook) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.all = append(r.all, hook)
+}
+
+func (r *Hooks) Emit(ctx context.Context, event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	r.lock.RLock()
+	hooks := make([]Hook, 0, len(r.all)+len(r.hooks[event.Type]))
+	hooks = append(hooks, r.all...)
+	hooks = append(hooks, r.hooks[event.Type]...)
+	r.lock.RUnlock()
+
+	for _, hook := range hooks {
+		r.call(ctx, hook, event)
+	}
+}
+
+func (r *Hooks) call(ctx context.Context, hook Hook, event Event) {
+	defer func() {
+		if p := recover(); p != nil {
+			slog.ErrorContext(ctx, "Hook panicked", "event", event.Type, "panic", p)
+		}
+	}()
+	hook(ctx, event)
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHooksEmit(t *testing.T) {
+	t.Parallel()
+
+	hooks := NewHooks()
+	got := make([]string, 0)
+	hooks.SubscribeAll(func(_ context.Context, event Event) {
+		got = append(got, "all:"+string(event.Type))
+	})
+	hooks.Subscribe(func(_ context.Context, event Event) {
+		got = append(got, "paid:"+event.OrderID)
+	}, EventDebtPaid)
+	hooks.Subscribe(func(_ context.Context, _ Event) {
+		panic("misbehaving hook")
+	}, EventDebtPaid)
+	hooks.Subscribe(func(_ context.Context, event Event) {
+		got = append(got, "after panic:"+event.OrderID)
+	}, EventDebtPaid)
+
+	hooks.Emit(context.Background(), Event{Type: EventOrderJoined, OrderID: "A"})
+	hooks.Emit(context.Background(), Event{Type: EventDebtPaid, OrderID: "B"})
+
+	assert.Equal(t, []string{"all:order_joined", "all:debt_paid", "paid:B", "after panic:B"}, got)
+}
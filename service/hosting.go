@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/oriser/bolt/order"
+	userDomain "github.com/oriser/bolt/user"
+)
+
+const (
+	defaultLeaderboardDays = 90
+	maxLeaderboardDays     = 366
+)
+
+// HostingStat is how many of the delivered orders of a channel a person hosted and joined. People who aren't known users are
+// counted by their Wolt name.
+type HostingStat struct {
+	Name        string           // The Wolt name
+	User        *userDomain.User // nil if the person isn't a known user
+	Hosted      int
+	Joined      int // The orders the person participated in, including the hosted ones
+	SinceHosted int // The orders the person joined since they last hosted, or ever if they never hosted
+}
+
+// Share returns the share of the joined orders the person hosted
+func (s HostingStat) Share() float64 {
+	if s.Joined == 0 {
+		return 0
+	}
+	return float64(s.Hosted) / float64(s.Joined)
+}
+
+// HostingLeaderboard is who hosts the orders of a channel and who only joins them
+type HostingLeaderboard struct {
+	Days    int
+	Orders  int
+	Hosts   []HostingStat // The people who hosted, from the most orders hosted
+	Joiners []HostingStat // The people who only joined, from the most orders joined
+}
+
+// hostingKey returns the key a participant is counted by. Known users are counted by their ID, as they may have several Wolt names.
+func hostingKey(p order.Participant) string {
+	if p.ID != "" {
+		return p.ID
+	}
+	return "name:" + p.Name
+}
+
+// hostingStats counts the hosted and joined orders of each participant of the delivered orders, which are sorted from the oldest,
+// by their hosting key
+func hostingStats(orders []*order.Order) map[string]*HostingStat {
+	stats := make(map[string]*HostingStat)
+	for _, o := range orders {
+		if o.Status != order.StatusDone {
+			continue
+		}
+		for _, p := range o.Participants {
+			key := hostingKey(p)
+			stat, ok := stats[key]
+			if !ok {
+				stat = &HostingStat{Name: p.Name}
+				stats[key] = stat
+			}
+			stat.Joined++
+			if p.Name == o.Host {
+				stat.Hosted++
+				stat.SinceHosted = 0
+			} else {
+				stat.SinceHosted++
+			}
+		}
+	}
+	return stats
+}
+
+// HostingLeaderboard returns who hosted the orders delivered to the channel in the last days (90 if it's 0), and who only joined them
+func (h *Service) HostingLeaderboard(ctx context.Context, channel string, days int) (*HostingLeaderboard, error) {
+	if h.orderStore == nil {
+		return nil, fmt.Errorf("no order store")
+	}
+	if days <= 0 {
+		days = defaultLeaderboardDays
+	}
+	if days > maxLeaderboardDays {
+		days = maxLeaderboardDays
+	}
+	orders, err := h.orderStore.ListOrders(ctx, order.ListFilter{Receiver: channel, Ascending: true})
+	if err != nil {
+		return nil, fmt.Errorf("list orders: %w", err)
+	}
+
+	since := time.Now().AddDate(0, 0, -days)
+	recent := make([]*order.Order, 0)
+	for _, o := range orders {
+		if o.Status == order.StatusDone && !o.CreatedAt.Before(since) {
+			recent = append(recent, o)
+		}
+	}
+
+	leaderboard := &HostingLeaderboard{Days: days, Orders: len(recent)}
+	for key, stat := range hostingStats(recent) {
+		if !strings.HasPrefix(key, "name:") {
+			stat.User = h.reportUser(ctx, key)
+		}
+		if stat.Hosted > 0 {
+			leaderboard.Hosts = append(leaderboard.Hosts, *stat)
+		} else {
+			leaderboard.Joiners = append(leaderboard.Joiners, *stat)
+		}
+	}
+	sort.Slice(leaderboard.Hosts, func(i, j int) bool {
+		if leaderboard.Hosts[i].Hosted != leaderboard.Hosts[j].Hosted {
+			return leaderboard.Hosts[i].Hosted > leaderboard.Hosts[j].Hosted
+		}
+		return leaderboard.Hosts[i].Name < leaderboard.Hosts[j].Name
+	})
+	sort.Slice(leaderboard.Joiners, func(i, j int) bool {
+		if leaderboard.Joiners[i].Joined != leaderboard.Joiners[j].Joined {
+			return leaderboard.Joiners[i].Joined > leaderboard.Joiners[j].Joined
+		}
+		return leaderboard.Joiners[i].Name < leaderboard.Joiners[j].Name
+	})
+	return leaderboard, nil
+}
+
+// BuildHostingLeaderboardMessage returns the message of the hosting leaderboard
+func BuildHostingLeaderboardMessage(leaderboard *HostingLeaderboard) string {
+	if leaderboard.Orders == 0 {
+		return fmt.Sprintf("No orders were delivered in the last %d days", leaderboard.Days)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(":trophy: Who hosted the %d orders of the last %d days:\n", leaderboard.Orders, leaderboard.Days))
+	for i, host := range leaderboard.Hosts {
+		sb.WriteString(fmt.Sprintf("%d. %s - hosted %d of the %d orders they joined (%.0f%%)\n", i+1, balanceUserMention(host.User, host.Name),
+			host.Hosted, host.Joined, host.Share()*100))
+	}
+	if len(leaderboard.Joiners) > 0 {
+		joiners := make([]string, len(leaderboard.Joiners))
+		for i, joiner := range leaderboard.Joiners {
+			joiners[i] = fmt.Sprintf("%s (%d)", balanceUserMention(joiner.User, joiner.Name), joiner.Joined)
+		}
+		sb.WriteString(fmt.Sprintf("*Only joined:* %s\n", strings.Join(joiners, ", ")))
+	}
+	return sb.String()
+}
+
+// nudgeHosting nudges the known users who joined the saved order without hosting it in the thread of its link, once every
+// HOSTING_NUDGE_ORDERS orders they joined in a row without hosting any
+func (h *Service) nudgeHosting(ctx context.Context, saved *order.Order) {
+	if h.cfg.HostingNudgeOrders == 0 || saved.Status != order.StatusDone || saved.Receiver == "" {
+		return
+	}
+	orders, err := h.orderStore.ListOrders(ctx, order.ListFilter{Receiver: saved.Receiver, Ascending: true})
+	if err != nil {
+		h.logger.ErrorContext(ctx, "Error listing orders for the hosting nudge", "error", err)
+		return
+	}
+
+	stats := hostingStats(orders)
+	mentions := make([]string, 0)
+	for _, p := range saved.Participants {
+		if p.ID == "" || p.Name == saved.Host {
+			continue
+		}
+		stat, ok := stats[p.ID]
+		if !ok || stat.SinceHosted == 0 || stat.SinceHosted%h.cfg.HostingNudgeOrders != 0 {
+			continue
+		}
+		if user := h.reportUser(ctx, p.ID); user != nil {
+			mentions = append(mentions, balanceUserMention(user, p.Name))
+		}
+	}
+	if len(mentions) == 0 {
+		return
+	}
+	sort.Strings(mentions)
+	message := h.text(saved.Receiver, msgHostingNudge, strings.Join(mentions, ", "), h.cfg.HostingNudgeOrders)
+	if _, err := h.informEventContext(ctx, saved.Receiver, message, "", saved.MessageID); err != nil {
+		h.logger.ErrorContext(ctx, "Error posting the hosting nudge", "error", err)
+	}
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oriser/bolt/order"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func hostingOrders(createdAt time.Time, hosts ...string) []*order.Order {
+	orders := make([]*order.Order, len(hosts))
+	for i, host := range hosts {
+		orders[i] = &order.Order{ID: host + string(rune('a'+i)), Receiver: "C1", Host: host, Status: order.StatusDone,
+			CreatedAt: createdAt.Add(time.Duration(i) * time.Hour), MessageID: "M1",
+			Participants: []order.Participant{{Name: "Dana", ID: "dana", Amount: 10}, {Name: "Bob", Amount: 10}, {Name: "Eli", ID: "eli", Amount: 10}}}
+	}
+	return orders
+}
+
+func TestHostingLeaderboard(t *testing.T) {
+	t.Parallel()
+
+	orders := hostingOrders(time.Now().AddDate(0, 0, -10), "Dana", "Dana", "Bob")
+	orders = append(orders, &order.Order{ID: "old", Receiver: "C1", Host: "Eli", Status: order.StatusDone, CreatedAt: time.Now().AddDate(0, -6, 0),
+		Participants: []order.Participant{{Name: "Eli", ID: "eli"}}},
+		&order.Order{ID: "canceled", Receiver: "C1", Host: "Eli", Status: order.StatusCanceled, CreatedAt: time.Now(),
+			Participants: []order.Participant{{Name: "Eli", ID: "eli"}}})
+	users := &fakeTreasuryStore{users: map[string]*userDomain.User{"dana": {ID: "dana", TransportID: "U1"}, "eli": {ID: "eli", TransportID: "U2"}}}
+	h, err := New(Config{}, users, nil, &filteringOrderStore{fakeOrderStore{orders: orders}}, "UBOT", nil)
+	require.NoError(t, err)
+
+	leaderboard, err := h.HostingLeaderboard(context.Background(), "C1", 0)
+	require.NoError(t, err)
+	assert.Equal(t, 90, leaderboard.Days)
+	assert.Equal(t, 3, leaderboard.Orders, "only the delivered orders of the last 90 days")
+	require.Len(t, leaderboard.Hosts, 2)
+	require.Len(t, leaderboard.Joiners, 1)
+	assert.Equal(t, ":trophy: Who hosted the 3 orders of the last 90 days:\n"+
+		"1. <@U1> - hosted 2 of the 3 orders they joined (67%)\n"+
+		"2. Bob - hosted 1 of the 3 orders they joined (33%)\n"+
+		"*Only joined:* <@U2> (3)\n", BuildHostingLeaderboardMessage(leaderboard))
+
+	leaderboard, err = h.HostingLeaderboard(context.Background(), "C2", 7)
+	require.NoError(t, err)
+	assert.Equal(t, "No orders were delivered in the last 7 days", BuildHostingLeaderboardMessage(leaderboard))
+}
+
+func TestNudgeHosting(t *testing.T) {
+	t.Parallel()
+
+	orders := hostingOrders(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), "Eli", "Dana", "Bob", "Bob")
+	users := &fakeTreasuryStore{users: map[string]*userDomain.User{"dana": {ID: "dana", TransportID: "U1"}, "eli": {ID: "eli", TransportID: "U2"}}}
+	notifications := &recordingNotification{}
+	h, err := New(Config{HostingNudgeOrders: 3}, users, nil, &filteringOrderStore{fakeOrderStore{orders: orders}},
+		"UBOT", notifications)
+	require.NoError(t, err)
+
+	h.nudgeHosting(context.Background(), orders[3])
+	assert.Equal(t, []string{"C1: :wave: <@U2> hasn't hosted in 3 orders, how about hosting the next one?"}, notifications.messages,
+		"Dana hosted 2 orders ago")
+
+	notifications.messages = nil
+	h.cfg.HostingNudgeOrders = 0
+	h.nudgeHosting(context.Background(), orders[3])
+	assert.Empty(t, notifications.messages)
+}
@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/oriser/bolt/order"
+	userDomain "github.com/oriser/bolt/user"
+)
+
+const insightsTrendMonths = 3
+
+// MonthlyAverage is the average meal cost of a user in a month
+type MonthlyAverage struct {
+	Month   time.Time
+	Meals   int
+	Average float64
+}
+
+// VenueCost is the average meal cost of a user in a venue
+type VenueCost struct {
+	VenueName string
+	Meals     int
+	Average   float64
+}
+
+// Insights are the statistics of a user's meals in a month, computed from the per-person amounts of the stored orders
+type Insights struct {
+	Trend              []MonthlyAverage // From the oldest month to the insights' month
+	MostExpensiveVenue *VenueCost
+	ChannelAverages    map[string]float64 // The average meal cost of everyone in each channel the user ordered in during the month
+	Currency           string             // The ISO 4217 code of the currency of the costs
+}
+
+// Month returns the statistics of the insights' month
+func (i Insights) Month() MonthlyAverage {
+	return i.Trend[len(i.Trend)-1]
+}
+
+type costSum struct {
+	meals int
+	total float64
+}
+
+func (c *costSum) add(amount float64) {
+	c.meals++
+	c.total += amount
+}
+
+func (c *costSum) average() float64 {
+	if c.meals == 0 {
+		return 0
+	}
+	return c.total / float64(c.meals)
+}
+
+func (h *Service) insightsStore() (order.InsightsStore, error) {
+	insightsStore, ok := h.orderStore.(order.InsightsStore)
+	if !ok {
+		return nil, fmt.Errorf("insights are not supported")
+	}
+	return insightsStore, nil
+}
+
+// SetInsightsSubscription opts the user (by transport ID) in or out of the monthly insights
+func (h *Service) SetInsightsSubscription(ctx context.Context, transportID string, subscribe bool) error {
+	insightsStore, err := h.insightsStore()
+	if err != nil {
+		return err
+	}
+	if subscribe {
+		err = insightsStore.SubscribeInsights(ctx, transportID)
+	} else {
+		err = insightsStore.UnsubscribeInsights(ctx, transportID)
+	}
+	if err != nil {
+		return fmt.Errorf("set insights subscription: %w", err)
+	}
+	return nil
+}
+
+// userIDsOfTransport returns the IDs of the users with the given transport ID, as orders' participants are matched to users of any store
+func (h *Service) userIDsOfTransport(ctx context.Context, transportID string) (map[string]bool, error) {
+	ids := map[string]bool{transportID: true}
+	if h.userStore == nil {
+		return ids, nil
+	}
+	users, err := h.userStore.ListUsers(ctx, userDomain.ListFilter{TransportID: transportID})
+	if err != nil {
+		return nil, fmt.Errorf("list users: %w", err)
+	}
+	for _, u := range users {
+		ids[u.ID] = true
+	}
+	return ids, nil
+}
+
+// MonthlyInsights returns the insights of the user (by transport ID) for the month starting at the given time, or nil if the user
+// had no meals in that month
+func (h *Service) MonthlyInsights(ctx context.Context, transportID string, month time.Time) (*Insights, error) {
+	if h.orderStore == nil {
+		return nil, fmt.Errorf("no order store")
+	}
+	userIDs, err := h.userIDsOfTransport(ctx, transportID)
+	if err != nil {
+		return nil, fmt.Errorf("user IDs of %s: %w", transportID, err)
+	}
+	orders, err := h.orderStore.ListOrders(ctx, order.ListFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("list orders: %w", err)
+	}
+
+	from := month.AddDate(0, 1-insightsTrendMonths, 0)
+	to := month.AddDate(0, 1, 0)
+	monthly := make([]costSum, insightsTrendMonths)
+	venues := make(map[string]*costSum)
+	channels := make(map[string]*costSum)
+	userChannels := make(map[string]bool)
+	for _, o := range orders {
+		if o.Status != order.StatusDone || o.CreatedAt.Before(from) || !o.CreatedAt.Before(to) {
+			continue
+		}
+		inMonth := !o.CreatedAt.Before(month)
+		for _, p := range o.Participants {
+			if inMonth {
+				if _, ok := channels[o.Receiver]; !ok {
+					channels[o.Receiver] = &costSum{}
+				}
+				channels[o.Receiver].add(p.Amount)
+			}
+			if p.ID == "" || !userIDs[p.ID] {
+				continue
+			}
+			createdAt := o.CreatedAt.In(month.Location())
+			monthsAgo := (month.Year()-createdAt.Year())*12 + int(month.Month()-createdAt.Month())
+			monthly[insightsTrendMonths-1-monthsAgo].add(p.Amount)
+			if inMonth {
+				userChannels[o.Receiver] = true
+				if _, ok := venues[o.VenueName]; !ok {
+					venues[o.VenueName] = &costSum{}
+				}
+				venues[o.VenueName].add(p.Amount)
+			}
+		}
+	}
+	if monthly[insightsTrendMonths-1].meals == 0 {
+		return nil, nil
+	}
+
+	insights := &Insights{ChannelAverages: make(map[string]float64), Currency: h.ordersCurrency(orders)}
+	for i, sum := range monthly {
+		insights.Trend = append(insights.Trend, MonthlyAverage{Month: from.AddDate(0, i, 0), Meals: sum.meals, Average: sum.average()})
+	}
+	for venueName, sum := range venues {
+		if insights.MostExpensiveVenue == nil || sum.average() > insights.MostExpensiveVenue.Average ||
+			(sum.average() == insights.MostExpensiveVenue.Average && venueName < insights.MostExpensiveVenue.VenueName) {
+			insights.MostExpensiveVenue = &VenueCost{VenueName: venueName, Meals: sum.meals, Average: sum.average()}
+		}
+	}
+	for channel := range userChannels {
+		insights.ChannelAverages[channel] = channels[channel].average()
+	}
+	return insights, nil
+}
+
+func buildInsightsMessage(insights *Insights) string {
+	month := insights.Month()
+	unit := CurrencyUnit(insights.Currency)
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(":bar_chart: Your Wolt insights for %s:\n", month.Month.Format("January")))
+
+	previous := make([]string, 0, len(insights.Trend)-1)
+	for i := len(insights.Trend) - 2; i >= 0; i-- {
+		if insights.Trend[i].Meals > 0 {
+			previous = append(previous, fmt.Sprintf("%s: %.2f", insights.Trend[i].Month.Format("January"), insights.Trend[i].Average))
+		}
+	}
+	sb.WriteString(fmt.Sprintf("• You had %d meals, %.2f %s on average", month.Meals, month.Average, unit))
+	if len(previous) > 0 {
+		sb.WriteString(fmt.Sprintf(" (%s)", strings.Join(previous, ", ")))
+	}
+	sb.WriteString("\n")
+
+	if venue := insights.MostExpensiveVenue; venue != nil {
+		sb.WriteString(fmt.Sprintf("• Your most expensive venue was [%s], %.2f %s per meal\n", venue.VenueName, venue.Average, unit))
+	}
+
+	channels := make([]string, 0, len(insights.ChannelAverages))
+	for channel := range insights.ChannelAverages {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+	for _, channel := range channels {
+		average := insights.ChannelAverages[channel]
+		comparison := "the same as"
+		if average > 0 && month.Average != average {
+			diff := (month.Average - average) / average * 100
+			if diff > 0 {
+				comparison = fmt.Sprintf("%.0f%% more than", diff)
+			} else {
+				comparison = fmt.Sprintf("%.0f%% less than", -diff)
+			}
+		}
+		sb.WriteString(fmt.Sprintf("• The average meal in <#%s> was %.2f %s, you spent %s the average\n", channel, average, unit, comparison))
+	}
+	sb.WriteString("Stop these insights with `/bolt insights off`")
+	return sb.String()
+}
+
+func (h *Service) sendInsights(ctx context.Context, transportID string, month time.Time) {
+	insights, err := h.MonthlyInsights(ctx, transportID, month)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "Error getting insights", "transport_id", transportID, "error", err)
+		return
+	}
+	if insights == nil {
+		return
+	}
+	if err := h.notifyUser(ctx, transportID, "insights/"+month.Format("2006-01"), buildInsightsMessage(insights), ""); err != nil {
+		h.logger.ErrorContext(ctx, "Error sending insights", "transport_id", transportID, "error", err)
+	}
+}
+
+// RunInsightsSender sends the insights of the previous month to every subscribed user, on the first day of every month at INSIGHTS_HOUR,
+// until the context is done
+func (h *Service) RunInsightsSender(ctx context.Context) {
+	insightsStore, err := h.insightsStore()
+	if err != nil || h.orderStore == nil {
+		return
+	}
+
+	ticker := time.NewTicker(badgesSchedulerInterval)
+	defer ticker.Stop()
+
+	h.schedulers.beat("insights sender", badgesSchedulerInterval)
+	lastCheck := time.Now()
+	for {
+		select {
+		case now := <-ticker.C:
+			tz := h.timezoneForChannel("", nil)
+			sendAt := monthStart(now.In(tz)).Add(time.Duration(h.cfg.InsightsHour) * time.Hour)
+			if sendAt.After(lastCheck) && !sendAt.After(now) {
+				subscribers, err := insightsStore.ListInsightsSubscribers(ctx)
+				if err != nil {
+					h.logger.ErrorContext(ctx, "Error listing insights subscribers", "error", err)
+				}
+				for _, transportID := range subscribers {
+					h.sendInsights(ctx, transportID, monthStart(sendAt.AddDate(0, -1, 0)))
+				}
+			}
+			lastCheck = now
+			h.schedulers.beat("insights sender", badgesSchedulerInterval)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
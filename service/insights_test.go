@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oriser/bolt/order"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeInsightsStore struct {
+	fakeOrderStore
+	subscribers map[string]bool
+}
+
+func (f *fakeInsightsStore) SubscribeInsights(_ context.Context, transportID string) error {
+	f.subscribers[transportID] = true
+	return nil
+}
+
+func (f *fakeInsightsStore) UnsubscribeInsights(_ context.Context, transportID string) error {
+	delete(f.subscribers, transportID)
+	return nil
+}
+
+func (f *fakeInsightsStore) ListInsightsSubscribers(context.Context) ([]string, error) {
+	subscribers := make([]string, 0, len(f.subscribers))
+	for transportID := range f.subscribers {
+		subscribers = append(subscribers, transportID)
+	}
+	return subscribers, nil
+}
+
+func TestMonthlyInsights(t *testing.T) {
+	t.Parallel()
+
+	may := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	participants := func(amounts ...float64) []order.Participant {
+		res := []order.Participant{{Name: "Loki", ID: "U1", Amount: amounts[0]}}
+		for _, amount := range amounts[1:] {
+			res = append(res, order.Participant{Name: "Other", ID: "U2", Amount: amount})
+		}
+		return res
+	}
+	store := &fakeInsightsStore{subscribers: make(map[string]bool), fakeOrderStore: fakeOrderStore{orders: []*order.Order{
+		{Receiver: "C1", VenueName: "Pizza", Status: order.StatusDone, CreatedAt: may.AddDate(0, -2, 3), Participants: participants(40)},
+		{Receiver: "C1", VenueName: "Pizza", Status: order.StatusDone, CreatedAt: may.Add(time.Hour), Participants: participants(50, 30)},
+		{Receiver: "C1", VenueName: "Sushi", Status: order.StatusDone, CreatedAt: may.AddDate(0, 0, 10), Participants: participants(90, 70)},
+		{Receiver: "C2", VenueName: "Burger", Status: order.StatusDone, CreatedAt: may.AddDate(0, 0, 12), Participants: participants(60, 80, 100)},
+		{Receiver: "C3", VenueName: "Salad", Status: order.StatusDone, CreatedAt: may.AddDate(0, 0, 12), Participants: []order.Participant{{Name: "Other", ID: "U2", Amount: 30}}},
+		{Receiver: "C1", VenueName: "Canceled", Status: order.StatusCanceled, CreatedAt: may.AddDate(0, 0, 1), Participants: participants(500)},
+		{Receiver: "C1", VenueName: "Pizza", Status: order.StatusDone, CreatedAt: may.AddDate(0, 1, 0), Participants: participants(500)},
+	}}}
+	h, err := New(Config{}, nil, nil, store, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+
+	insights, err := h.MonthlyInsights(context.Background(), "U1", may)
+	require.NoError(t, err)
+	require.NotNil(t, insights)
+	assert.Equal(t, MonthlyAverage{Month: may, Meals: 3, Average: 200.0 / 3}, insights.Month())
+	assert.Equal(t, []MonthlyAverage{
+		{Month: may.AddDate(0, -2, 0), Meals: 1, Average: 40},
+		{Month: may.AddDate(0, -1, 0)},
+		{Month: may, Meals: 3, Average: 200.0 / 3},
+	}, insights.Trend)
+	assert.Equal(t, &VenueCost{VenueName: "Sushi", Meals: 1, Average: 90}, insights.MostExpensiveVenue)
+	assert.Equal(t, map[string]float64{"C1": 60, "C2": 80}, insights.ChannelAverages, "only the channels the user ordered in are compared")
+
+	assert.Equal(t, ":bar_chart: Your Wolt insights for May:\n"+
+		"• You had 3 meals, 66.67 NIS on average (March: 40.00)\n"+
+		"• Your most expensive venue was [Sushi], 90.00 NIS per meal\n"+
+		"• The average meal in <#C1> was 60.00 NIS, you spent 11% more than the average\n"+
+		"• The average meal in <#C2> was 80.00 NIS, you spent 17% less than the average\n"+
+		"Stop these insights with `/bolt insights off`", buildInsightsMessage(insights))
+
+	insights, err = h.MonthlyInsights(context.Background(), "U1", may.AddDate(0, -1, 0))
+	require.NoError(t, err)
+	assert.Nil(t, insights, "no insights without meals in the month")
+
+	require.NoError(t, h.SetInsightsSubscription(context.Background(), "U1", true))
+	assert.True(t, store.subscribers["U1"])
+	require.NoError(t, h.SetInsightsSubscription(context.Background(), "U1", false))
+	assert.False(t, store.subscribers["U1"])
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/oriser/bolt/wolt"
+)
+
+// The codes of the failures to join group orders, shown to the users with what they can do about it, so they can be reported to
+// the admins and looked up in the logs
+const (
+	JoinFailedUnknown = "WOLT-ERR"
+	JoinFailedAuth    = "WOLT-AUTH"
+	JoinFailedFull    = "WOLT-FULL"
+	JoinFailedClosed  = "WOLT-CLOSED"
+	JoinFailedRegion  = "WOLT-REGION"
+	JoinFailedNetwork = "WOLT-NET"
+)
+
+// joinFailure returns the code of the failure to join a group order and the message telling the users what to do about it
+func joinFailure(err error) (string, messageKey) {
+	switch {
+	case errors.Is(err, wolt.ErrGroupFull):
+		return JoinFailedFull, msgJoinFailedFull
+	case errors.Is(err, wolt.ErrGroupClosed):
+		return JoinFailedClosed, msgJoinFailedClosed
+	case errors.Is(err, wolt.ErrRegionMismatch):
+		return JoinFailedRegion, msgJoinFailedRegion
+	case errors.Is(err, wolt.ErrAuthExpired):
+		return JoinFailedAuth, msgJoinFailedAuth
+	case errors.Is(err, wolt.ErrNetwork), errors.Is(err, wolt.ErrCircuitOpen):
+		return JoinFailedNetwork, msgJoinFailedNetwork
+	default:
+		return JoinFailedUnknown, msgJoinFailed
+	}
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/oriser/bolt/wolt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJoinFailure(t *testing.T) {
+	t.Parallel()
+
+	for err, expected := range map[error]string{
+		fmt.Errorf("join group: %w", wolt.ErrGroupFull):      JoinFailedFull,
+		fmt.Errorf("join group: %w", wolt.ErrGroupClosed):    JoinFailedClosed,
+		fmt.Errorf("join group: %w", wolt.ErrRegionMismatch): JoinFailedRegion,
+		fmt.Errorf("join group: %w", wolt.ErrAuthExpired):    JoinFailedAuth,
+		fmt.Errorf("join group: %w", wolt.ErrCircuitOpen):    JoinFailedNetwork,
+		errors.New("find group id from JSON"):                JoinFailedUnknown,
+	} {
+		code, _ := joinFailure(err)
+		assert.Equal(t, expected, code, err.Error())
+	}
+
+	h, err := New(Config{Locale: "he"}, nil, nil, nil, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+	code, message := joinFailure(fmt.Errorf("join group: %w", wolt.ErrGroupFull))
+	assert.Contains(t, h.text("C1", message, code), "(שגיאה WOLT-FULL)")
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"context"
+
+	userDomain "github.com/oriser/bolt/user"
+)
+
+// lifetime returns the context of the service, which is canceled once it shuts down
+func (h *Service) lifetime() context.Context {
+	if h.ctx == nil {
+		return context.Background()
+	}
+	return h.ctx
+}
+
+// storeContext returns a context for store calls which aren't in the scope of a request, which has the STORE_TIMEOUT deadline
+// and is canceled once the service shuts down
+func (h *Service) storeContext() (context.Context, context.CancelFunc) {
+	if h.cfg.StoreTimeout > 0 {
+		return context.WithTimeout(h.lifetime(), h.cfg.StoreTimeout)
+	}
+	return context.WithCancel(h.lifetime())
+}
+
+// getUser returns the user with the ID from the user store, with a store call context
+func (h *Service) getUser(id string) (*userDomain.User, error) {
+	ctx, cancel := h.storeContext()
+	defer cancel()
+	return h.userStore.GetUser(ctx, id)
+}
+
+// listUsersByName returns the users with the Wolt name from the user store, with a store call context
+func (h *Service) listUsersByName(name string) ([]*userDomain.User, error) {
+	ctx, cancel := h.storeContext()
+	defer cancel()
+	return h.userStore.ListUsers(ctx, userDomain.ListFilter{Names: []string{name}})
+}
@@ -0,0 +1,29 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreContext(t *testing.T) {
+	t.Parallel()
+
+	h, err := New(Config{FeeAllocationStrategy: "equal", StoreTimeout: time.Minute}, nil, nil, nil, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+	ctx, cancel := h.storeContext()
+	defer cancel()
+	deadline, hasDeadline := ctx.Deadline()
+	require.True(t, hasDeadline, "store calls should have a deadline")
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+
+	h.cfg.StoreTimeout = 0
+	ctx, cancel = h.storeContext()
+	_, hasDeadline = ctx.Deadline()
+	assert.False(t, hasDeadline, "a STORE_TIMEOUT of 0 disables the deadline")
+	cancel()
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+}
@@ -0,0 +1,518 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Locale is the language of the messages Bolt sends to a channel
+type Locale string
+
+const (
+	LocaleEnglish Locale = "en"
+	LocaleHebrew  Locale = "he"
+	LocaleAuto    Locale = "auto" // Detected from the channel's recent messages
+
+	localeDetectionMessages = 50
+)
+
+type messageKey int
+
+const (
+	msgJoinedOrder messageKey = iota
+	msgTooLate
+	msgTooLateConfirmation
+	msgRatesHeader
+	msgPayTo
+	msgPreferredPayments
+	msgAgeRestricted
+	msgPersonalShare
+	msgSubsidy
+	msgSubsidyPercent
+	msgSubsidyPerOrder
+	msgSubsidyMonthlyCap
+	msgSubsidyExcluding
+	msgSubsidyCapReached
+	msgVenueBusy
+	msgHeadcount
+	msgHeadcountSuggestion
+	msgCompanyPaid
+	msgHostNote
+	msgOrderRef
+	msgMarkPaid
+	msgCancelTracking
+	msgPayWith
+	msgForgiven
+	msgAdjusted
+	msgRatesContinued
+	msgShutdownResumed
+	msgShutdownStopped
+	msgMutualPayment
+	msgPreviewHeader
+	msgRoundedByHost
+	msgRoundedByLargest
+	msgOrderQueued
+	msgOrdersQueueFull
+	msgNudge
+	msgDiscountSplit
+	msgDiscountHost
+	msgTrackingCanceled
+	msgTrackingLink
+	msgDebtDM
+	msgDebtDMMethods
+	msgSoloTracking
+	msgHostingNudge
+	msgBankAccount
+	msgRevealBankAccount
+	msgBankAccountDetails
+	msgOrderCanceled
+	msgTimedOutReady
+	msgTimedOutDone
+	msgNoOneConfirmed
+	msgNoDeliveryRate
+	msgDeliverySoon
+	msgDeliveryArrived
+	msgHostNotFound
+	msgDebtsTracking
+	msgRateLine
+	msgRateItem
+	msgItemsBreakdown
+	msgPreauthRequired
+	msgPreauthNotConfirmed
+	msgSplitHeader
+	msgSplitDebts
+	msgJoinFailed
+	msgJoinFailedAuth
+	msgJoinFailedFull
+	msgJoinFailedClosed
+	msgJoinFailedRegion
+	msgJoinFailedNetwork
+	msgExtraFees
+	msgFeesChanged
+	msgEscalationChannel
+	msgEscalationAdmins
+	msgEscalationWall
+	msgEscalationWallLine
+	msgMergeOffer
+	msgMergeSuggested
+	msgDeliveryProgress
+	msgRateLinePrivate
+	msgEscalationChannelPrivate
+	msgEscalationWallLinePrivate
+	msgOrderFees
+	msgOrderSettled
+	msgReceiptHeader
+	msgReceiptTotal
+	msgReceiptUntracked
+	msgCompanyPaidRatesPublished
+	msgCompanyPaidHostOnly
+	msgCancelDebtsHostOnly
+	msgMergeTooLate
+	msgRatesJoiners
+	msgRatesItemsRemoved
+	msgRatesAmountsIncreased
+	msgAlreadyPaid
+	msgAlreadyPaidPrivate
+	msgPayBackPrivate
+	msgRatesError
+	msgDebtForgivenNotice
+	msgDebtForgiven
+	msgDebtForgivenPrivate
+	msgDebtChanged
+	msgDebtChangedPrivate
+	msgDebtMovedToYou
+	msgDebtMovedFromYou
+	msgDebtMoved
+	msgDebtMovedPrivate
+	msgDebtsError
+	msgSoloCanceled
+	msgSoloStatusFailed
+	msgAlreadyTracking
+	msgAlreadyTrackingVenue
+	msgOrderReopened
+	msgVenueOpen
+	msgDebtsRemoved
+	msgUnknownAsHost
+	msgUnknownSkipped
+	msgUnknownPending
+	msgUnknownPrompt
+	msgPendingActivated
+	msgMissedOrderOffer
+	msgMergeNotified
+	msgDebtSettledByTreasurer
+	msgSelfTestPost
+	msgSelfTestEdited
+	msgSelfTestDM
+)
+
+// translations of the order messages. The "Wolt order ID <ID>" part of the rates header must be kept as is in all the
+// locales, as it's used for finding the order of reactions to the rates message.
+var translations = map[Locale]map[messageKey]string{
+	LocaleEnglish: {
+		msgJoinedOrder:               "Hi 👋, I've joined the order from [%s]",
+		msgTooLate:                   "It's too late for me... I won't track prices for this order :sleeping:",
+		msgTooLateConfirmation:       "It's too late for me... :sleeping: React with :%s: to this message if you still want me to track prices for this order",
+		msgRatesHeader:               "Rates for Wolt order ID %s (including %d %s for delivery):\n",
+		msgPayTo:                     "\nPay to: %s\n",
+		msgPreferredPayments:         "Preferred payments methods (in order): ",
+		msgAgeRestricted:             "%s Includes age-restricted items\n",
+		msgPersonalShare:             " (personal share %.2f)",
+		msgSubsidy:                   "The company subsidizes up to %.2f per person",
+		msgSubsidyPercent:            "The company pays %g%% of each person's amount",
+		msgSubsidyPerOrder:           ", up to %.2f per order",
+		msgSubsidyMonthlyCap:         ", up to %.2f per person a month",
+		msgSubsidyExcluding:          ", excluding %s",
+		msgSubsidyCapReached:         " (reached the monthly subsidy cap)",
+		msgVenueBusy:                 ":hourglass_flowing_sand: The venue is busy right now, so the delivery may cost more (surge pricing) and take longer than usual",
+		msgHeadcount:                 ":busts_in_silhouette: %d people in the office today",
+		msgHeadcountSuggestion:       " - past orders with this headcount averaged %s",
+		msgCompanyPaid:               "\n:%s: The company paid for this order, no payment needed\n",
+		msgHostNote:                  "\n:memo: Note from the host: %s\n",
+		msgOrderRef:                  "Order reference: %s\n",
+		msgMarkPaid:                  "Mark paid",
+		msgCancelTracking:            "Cancel tracking",
+		msgPayWith:                   "Pay with %s",
+		msgForgiven:                  " (forgiven by the host)",
+		msgAdjusted:                  " (adjusted by the host)",
+		msgRatesContinued:            "Rates for Wolt order ID %s (continued):\n",
+		msgShutdownResumed:           ":hourglass_flowing_sand: I'm restarting, I'll continue tracking order %s once I'm back",
+		msgShutdownStopped:           ":warning: I'm shutting down, so I stopped tracking order %s",
+		msgMutualPayment:             "%s: you both use %s\n",
+		msgPreviewHeader:             ":crystal_ball: Provisional split of order %s from the current carts (including %d %s for delivery). It may change until the order is sent:\n",
+		msgRoundedByHost:             "The amounts are rounded to the nearest %g %s, the host covers the difference\n",
+		msgRoundedByLargest:          "The amounts are rounded to the nearest %g %s, the largest order covers the difference\n",
+		msgOrderQueued:               ":hourglass_flowing_sand: I'm tracking a lot of orders right now, you're #%d in line. I'll join this order once I'm free",
+		msgOrdersQueueFull:           ":warning: I'm tracking too many orders right now and can't track this one, please share the link again later",
+		msgNudge:                     ":bell: Waiting on %s, please mark your selection as done in Wolt so the order can be sent",
+		msgDiscountSplit:             "The order got a discount of %.2f %s (promo codes and Wolt credits), split relatively to everyone's amount\n",
+		msgDiscountHost:              "The order got a discount of %.2f %s (promo codes and Wolt credits), the host keeps it\n",
+		msgTrackingCanceled:          ":no_entry_sign: I stopped tracking order %s as <@%s> canceled it, nobody owes anything for it",
+		msgTrackingLink:              ":eyes: I'm on it, I'll track the order of this link",
+		msgDebtDM:                    ":receipt: You owe %.2f %s to <@%s> for Wolt order ID %s in <#%s>.%s\nWhen you pay, react with :%s: to the rates message",
+		msgDebtDMMethods:             "\n<@%s> prefers to be paid with %s",
+		msgSoloTracking:              ":eyes: I'll follow the delivery of this order and let you know when it's about to arrive",
+		msgHostingNudge:              ":wave: %s hasn't hosted in %d orders, how about hosting the next one?",
+		msgBankAccount:               "Bank transfer to %s: %s\n",
+		msgRevealBankAccount:         "Show bank details",
+		msgBankAccountDetails:        "Bank transfer details of %s:\nAccount holder: %s\nIBAN: %s",
+		msgOrderCanceled:             "Order for group ID %s was canceled",
+		msgTimedOutReady:             "Timed out waiting for order to be ready",
+		msgTimedOutDone:              "Timed out waiting for order to be done",
+		msgNoOneConfirmed:            "No one confirmed, I won't track this order",
+		msgNoDeliveryRate:            "I can't find the delivery rate, I'll publish the rates without including the delivery rate",
+		msgDeliverySoon:              "Get ready, delivery coming soon (ETA %s, %s)",
+		msgDeliveryArrived:           "Delivery arrived",
+		msgHostNotFound:              "I didn't find the user of the host (%s), I won't track debts for order %s",
+		msgDebtsTracking:             "I'll keep reminding you to pay, when you pay you can react with :%s: to the rates message and I'll stop bothering you.\n<@%s>, as the host, you can react with :%s: to the rates message to cancel debts tracking for Wolt order ID %s",
+		msgRateLine:                  "%s: %.2f",
+		msgRateItem:                  "\n    • %dx %s: %.2f",
+		msgItemsBreakdown:            "Items of order %s (the amounts include the delivery, fees and discounts):\n",
+		msgPreauthRequired:           ":moneybag: Orders from [%s] averaged %.2f %s per person here, more than %.2f. I'll track this order once %d of you react with :%s: to this message",
+		msgPreauthNotConfirmed:       "Only %d of the %d people needed confirmed, I won't track this order",
+		msgSplitHeader:               ":abacus: Split of %.2f %s (including %.2f in fees):\n",
+		msgSplitDebts:                "I set the debts to <@%s> to these amounts\n",
+		msgJoinFailed:                "I had an error joining the order (error %s). Share the link again, and if it keeps happening, ask an admin to check my logs",
+		msgJoinFailedAuth:            "Wolt didn't let me join the order (error %s). Make sure the group is open to guests, then share the link again",
+		msgJoinFailedFull:            "The group order is full, so I couldn't join it (error %s). Remove someone who isn't ordering from the group, then share the link again",
+		msgJoinFailedClosed:          "The group order is already closed (error %s). Share the link of an open group order",
+		msgJoinFailedRegion:          "The group order is in a region I can't join orders in (error %s). Check that the link is of the right group order",
+		msgJoinFailedNetwork:         "I couldn't reach Wolt to join the order (error %s). Share the link again in a few minutes",
+		msgExtraFees:                 "Including extras the host added: %s\n",
+		msgFeesChanged:               ":white_check_mark: <@%s> set the fees to %s. I updated the rates and the debts (%d changed)\n",
+		msgEscalationChannel:         ":bell: <@%s>, you still owe %.2f %s to <@%s> for Wolt order ID %s, it's been %d days. Please pay and react to the rates message",
+		msgEscalationAdmins:          ":rotating_light: <@%s> still owes %.2f %s to <@%s> for Wolt order ID %s in <#%s>, it's been %d days",
+		msgEscalationWall:            ":snail: Debts unpaid for long:\n",
+		msgEscalationWallLine:        "<@%s> owes %.2f %s for Wolt order ID %s (%d days)\n",
+		msgMergeOffer:                ":handshake: <#%s> also has an open order from [%s]. Ordering together saves a delivery fee, react with :%s: to this message to let them know",
+		msgMergeSuggested:            ":handshake: <#%s> is also ordering from [%s] (order %s). Order together to save a delivery fee",
+		msgDeliveryProgress:          "Delivery progress: %d%%, arriving at %s",
+		msgRateLinePrivate:           "%s: :lock: sent privately",
+		msgEscalationChannelPrivate:  ":bell: <@%s>, you still owe <@%s> for Wolt order ID %s, it's been %d days. Please pay and react to the rates message",
+		msgEscalationWallLinePrivate: "<@%s> owes for Wolt order ID %s (%d days)\n",
+		msgOrderFees:                 "Including Wolt's fees: %s\n",
+		msgOrderSettled:              "Everyone has paid for this order, thank you all! :tada:",
+		msgReceiptHeader:             "Everyone has paid you for the order from %s (Wolt order ID %s) :tada: Here's the receipt:\n",
+		msgReceiptTotal:              "\nTotal paid to you: %.2f (the order total is %.2f, including %d %s for delivery)\n",
+		msgReceiptUntracked:          "\nI didn't track the debts of these participants, as I couldn't match them to users, so settle with them yourself:\n",
+		msgCompanyPaidRatesPublished: "The rates of this order were already published, the host can react with :%s: to the rates message to cancel its debts",
+		msgCompanyPaidHostOnly:       "Only the host of the order can mark it as paid by the company",
+		msgCancelDebtsHostOnly:       "Nice try :stuck_out_tongue_winking_eye: Only the host (<@%s>) can cancel debts for this order",
+		msgMergeTooLate:              "Too late, one of the orders was already sent",
+		msgRatesJoiners:              "%s joined the order after I published the rates, so I updated them",
+		msgRatesItemsRemoved:         "Some items were removed from the order at checkout, so I updated the rates:\n",
+		msgRatesAmountsIncreased:     "Some amounts went up after I published the rates, so I updated them:\n",
+		msgAlreadyPaid:               "<@%s> already paid %.2f, <@%s> please pay back the difference of %.2f",
+		msgAlreadyPaidPrivate:        "<@%s> already paid, <@%s> please pay back the difference (I sent it to them privately)",
+		msgPayBackPrivate:            "Please pay back <@%s> %.2f for order %s",
+		msgRatesError:                "I had an error getting rate for group ID %s",
+		msgDebtForgivenNotice:        "<@%s> forgave your debt of %s for order %s :gift:",
+		msgDebtForgiven:              "<@%s> forgave <@%s>'s debt of %s",
+		msgDebtForgivenPrivate:       "<@%s> forgave <@%s>'s debt",
+		msgDebtChanged:               "<@%s> changed <@%s>'s debt for order %s from %s to %s",
+		msgDebtChangedPrivate:        "<@%s> changed <@%s>'s debt for order %s",
+		msgDebtMovedToYou:            "<@%s> moved %q's debt of %s for order %s to you",
+		msgDebtMovedFromYou:          "<@%s> moved %q's debt of %s for order %s from you to <@%s>",
+		msgDebtMoved:                 "<@%s> moved %q's debt of %s from <@%s> to <@%s>",
+		msgDebtMovedPrivate:          "<@%s> moved %q's debt from <@%s> to <@%s>",
+		msgDebtsError:                "I had an error adding debts, I won't track this order",
+		msgSoloCanceled:              "The order was canceled",
+		msgSoloStatusFailed:          "I couldn't get the status of this order from Wolt, I stopped following it",
+		msgAlreadyTracking:           "I'm already tracking %s, %s",
+		msgAlreadyTrackingVenue:      "I'm already tracking %s from [%s], %s",
+		msgOrderReopened:             "The order was reopened before the checkout, so the rates may still change. I'll update them once it's sent again",
+		msgVenueOpen:                 ":large_green_circle: Venue is now open for delivery",
+		msgDebtsRemoved:              "I removed all debts for order ID %s because %s",
+		msgUnknownAsHost:             "I can't find %q's user, so I count their share (%.2f) as the host's (<@%s>).",
+		msgUnknownSkipped:            "I won't track %q payment because I can't find his user.",
+		msgUnknownPending:            "I can't find %q's user, I'll track their payment once their user is added.",
+		msgUnknownPrompt:             "<@%s>, I can't find %q's user. Please link it with `/bolt link \"%s\" @<user>` and I'll track their payment of %.2f.",
+		msgPendingActivated:          "I found %q's user (<@%s>), I'll keep reminding them to pay %.2f to <@%s>.",
+		msgMissedOrderOffer:          "I was offline when this order was shared :zzz: React with :%s: to this message if it's still open and you want me to track it",
+		msgMergeNotified:             "OK <@%s>, I let <#%s> know about this order",
+		msgDebtSettledByTreasurer:    "The debt of %s from <@%s> to <@%s> for Wolt order ID %s was settled by the treasurer <@%s>",
+		msgSelfTestPost:              "Self-test: checking that I can post, react to and edit messages",
+		msgSelfTestEdited:            "Self-test: I can post, react to and edit messages",
+		msgSelfTestDM:                "Self-test: I can send you DMs",
+	},
+	LocaleHebrew: {
+		msgJoinedOrder:               "היי 👋, הצטרפתי להזמנה מ-[%s]",
+		msgTooLate:                   "מאוחר מדי בשבילי... לא אעקוב אחרי הסכומים של ההזמנה הזאת :sleeping:",
+		msgTooLateConfirmation:       "מאוחר מדי בשבילי... :sleeping: הגיבו עם :%s: להודעה הזאת אם אתם עדיין רוצים שאעקוב אחרי הסכומים של ההזמנה הזאת",
+		msgRatesHeader:               "הסכומים של Wolt order ID %s (כולל %d %s משלוח):\n",
+		msgPayTo:                     "\nלשלם ל: %s\n",
+		msgPreferredPayments:         "אמצעי תשלום מועדפים (לפי הסדר): ",
+		msgAgeRestricted:             "%s כולל פריטים מוגבלי גיל\n",
+		msgPersonalShare:             " (חלק אישי %.2f)",
+		msgSubsidy:                   "החברה מסבסדת עד %.2f לאדם",
+		msgSubsidyPercent:            "החברה משלמת %g%% מהסכום של כל אחד",
+		msgSubsidyPerOrder:           ", עד %.2f להזמנה",
+		msgSubsidyMonthlyCap:         ", עד %.2f לאדם בחודש",
+		msgSubsidyExcluding:          ", לא כולל %s",
+		msgSubsidyCapReached:         " (הגיע לתקרת הסבסוד החודשית)",
+		msgVenueBusy:                 ":hourglass_flowing_sand: המסעדה עמוסה כרגע, כך שהמשלוח עשוי לעלות יותר (תמחור עומס) ולקחת יותר זמן מהרגיל",
+		msgHeadcount:                 ":busts_in_silhouette: %d אנשים במשרד היום",
+		msgHeadcountSuggestion:       " - הזמנות קודמות עם מספר אנשים כזה הזמינו בממוצע %s",
+		msgCompanyPaid:               "\n:%s: החברה שילמה על ההזמנה, אין צורך לשלם\n",
+		msgHostNote:                  "\n:memo: הערה מהמארח/ת: %s\n",
+		msgOrderRef:                  "אסמכתא להזמנה: %s\n",
+		msgMarkPaid:                  "סימון כשולם",
+		msgCancelTracking:            "ביטול המעקב",
+		msgPayWith:                   "תשלום ב-%s",
+		msgForgiven:                  " (המארח/ת ויתר/ה על החוב)",
+		msgAdjusted:                  " (עודכן על ידי המארח/ת)",
+		msgRatesContinued:            "הסכומים של Wolt order ID %s (המשך):\n",
+		msgShutdownResumed:           ":hourglass_flowing_sand: אני מופעל/ת מחדש, אמשיך לעקוב אחרי הזמנה %s כשאחזור",
+		msgShutdownStopped:           ":warning: אני נכבה/ית, אז הפסקתי לעקוב אחרי הזמנה %s",
+		msgMutualPayment:             "%s: שניכם משתמשים ב-%s\n",
+		msgPreviewHeader:             ":crystal_ball: חלוקה זמנית של ההזמנה %s לפי העגלות הנוכחיות (כולל %d %s משלוח). היא עשויה להשתנות עד שההזמנה תישלח:\n",
+		msgRoundedByHost:             "הסכומים מעוגלים ל-%g %s הקרובים, המארח/ת משלם/ת את ההפרש\n",
+		msgRoundedByLargest:          "הסכומים מעוגלים ל-%g %s הקרובים, ההזמנה הגדולה ביותר משלמת את ההפרש\n",
+		msgOrderQueued:               ":hourglass_flowing_sand: אני עוקב/ת אחרי הרבה הזמנות כרגע, אתם מספר %d בתור. אצטרף להזמנה הזאת כשאתפנה",
+		msgOrdersQueueFull:           ":warning: אני עוקב/ת אחרי יותר מדי הזמנות כרגע ולא יכול/ה לעקוב אחרי הזאת, שתפו את הקישור שוב מאוחר יותר",
+		msgNudge:                     ":bell: מחכים ל-%s, סמנו בבקשה ב-Wolt שסיימתם לבחור כדי שאפשר יהיה לשלוח את ההזמנה",
+		msgDiscountSplit:             "ההזמנה קיבלה הנחה של %.2f %s (קודי קופון וקרדיט Wolt), שמתחלקת לפי הסכום של כל אחד\n",
+		msgDiscountHost:              "ההזמנה קיבלה הנחה של %.2f %s (קודי קופון וקרדיט Wolt), המארח/ת שומר/ת אותה\n",
+		msgTrackingCanceled:          ":no_entry_sign: הפסקתי לעקוב אחרי הזמנה %s כי <@%s> ביטל/ה אותה, אף אחד לא חייב עליה כלום",
+		msgTrackingLink:              ":eyes: אני על זה, אעקוב אחרי ההזמנה של הלינק הזה",
+		msgDebtDM:                    ":receipt: את/ה חייב/ת %.2f %s ל-<@%s> על הזמנת Wolt מספר %s ב-<#%s>.%s\nכשתשלמו, הגיבו עם :%s: להודעת הסכומים",
+		msgDebtDMMethods:             "\n<@%s> מעדיף/ה לקבל תשלום ב-%s",
+		msgSoloTracking:              ":eyes: אעקוב אחרי המשלוח של ההזמנה הזאת ואעדכן כשהוא עומד להגיע",
+		msgHostingNudge:              ":wave: %s לא אירח/ה כבר %d הזמנות, אולי את ההזמנה הבאה?",
+		msgBankAccount:               "העברה בנקאית ל-%s: %s\n",
+		msgRevealBankAccount:         "הצגת פרטי הבנק",
+		msgBankAccountDetails:        "פרטי ההעברה הבנקאית של %s:\nבעל/ת החשבון: %s\nIBAN: %s",
+		msgOrderCanceled:             "ההזמנה של הקבוצה %s בוטלה",
+		msgTimedOutReady:             "נגמר הזמן לחכות שההזמנה תהיה מוכנה",
+		msgTimedOutDone:              "נגמר הזמן לחכות שההזמנה תסתיים",
+		msgNoOneConfirmed:            "אף אחד לא אישר, לא אעקוב אחרי ההזמנה הזאת",
+		msgNoDeliveryRate:            "לא מצאתי את מחיר המשלוח, אפרסם את הסכומים בלי מחיר המשלוח",
+		msgDeliverySoon:              "להתכונן, המשלוח מגיע בקרוב (הגעה משוערת %s, %s)",
+		msgDeliveryArrived:           "המשלוח הגיע",
+		msgHostNotFound:              "לא מצאתי את המשתמש של המארח/ת (%s), לא אעקוב אחרי החובות של הזמנה %s",
+		msgDebtsTracking:             "אמשיך להזכיר לשלם, אחרי התשלום אפשר להגיב עם :%s: להודעת הסכומים ואפסיק להציק.\n<@%s>, בתור המארח/ת, אפשר להגיב עם :%s: להודעת הסכומים כדי לבטל את המעקב אחרי החובות של Wolt order ID %s",
+		msgRateLine:                  "%s: %.2f",
+		msgRateItem:                  "\n    • %dx %s: %.2f",
+		msgItemsBreakdown:            "הפריטים של הזמנה %s (הסכומים כוללים את המשלוח, העמלות וההנחות):\n",
+		msgPreauthRequired:           ":moneybag: הזמנות מ-[%s] עלו כאן בממוצע %.2f %s לאדם, יותר מ-%.2f. אעקוב אחרי ההזמנה הזאת כש-%d מכם יגיבו עם :%s: להודעה הזאת",
+		msgPreauthNotConfirmed:       "רק %d מתוך %d האנשים הנדרשים אישרו, לא אעקוב אחרי ההזמנה הזאת",
+		msgSplitHeader:               ":abacus: חלוקה של %.2f %s (כולל %.2f עמלות):\n",
+		msgSplitDebts:                "עדכנתי את החובות ל-<@%s> לסכומים האלה\n",
+		msgJoinFailed:                "הייתה לי שגיאה בהצטרפות להזמנה (שגיאה %s). שתפו את הקישור שוב, ואם זה ממשיך לקרות, בקשו מאדמין לבדוק את הלוגים שלי",
+		msgJoinFailedAuth:            "וולט לא נתנו לי להצטרף להזמנה (שגיאה %s). ודאו שההזמנה הקבוצתית פתוחה לאורחים ושתפו את הקישור שוב",
+		msgJoinFailedFull:            "ההזמנה הקבוצתית מלאה ולא הצלחתי להצטרף אליה (שגיאה %s). הסירו מהקבוצה מישהו שלא מזמין ושתפו את הקישור שוב",
+		msgJoinFailedClosed:          "ההזמנה הקבוצתית כבר נסגרה (שגיאה %s). שתפו קישור של הזמנה קבוצתית פתוחה",
+		msgJoinFailedRegion:          "ההזמנה הקבוצתית באזור שאני לא יכול להצטרף בו להזמנות (שגיאה %s). בדקו שהקישור הוא של ההזמנה הנכונה",
+		msgJoinFailedNetwork:         "לא הצלחתי להגיע לוולט כדי להצטרף להזמנה (שגיאה %s). שתפו את הקישור שוב בעוד כמה דקות",
+		msgExtraFees:                 "כולל תוספות שהמארח הוסיף: %s\n",
+		msgFeesChanged:               ":white_check_mark: <@%s> עדכן את העמלות ל-%s. עדכנתי את הסכומים ואת החובות (%d השתנו)\n",
+		msgEscalationChannel:         ":bell: <@%s>, את/ה עדיין חייב/ת %.2f %s ל-<@%s> על הזמנת Wolt מספר %s, כבר %d ימים. נא לשלם ולהגיב להודעת הסכומים",
+		msgEscalationAdmins:          ":rotating_light: <@%s> עדיין חייב/ת %.2f %s ל-<@%s> על הזמנת Wolt מספר %s ב-<#%s>, כבר %d ימים",
+		msgEscalationWall:            ":snail: חובות שלא שולמו הרבה זמן:\n",
+		msgEscalationWallLine:        "<@%s> חייב/ת %.2f %s על הזמנת Wolt מספר %s (%d ימים)\n",
+		msgMergeOffer:                ":handshake: ב-<#%s> יש גם הזמנה פתוחה מ-[%s]. הזמנה משותפת חוסכת דמי משלוח, הגיבו עם :%s: להודעה הזאת כדי לעדכן אותם",
+		msgMergeSuggested:            ":handshake: גם ב-<#%s> מזמינים מ-[%s] (הזמנה %s). הזמינו יחד כדי לחסוך דמי משלוח",
+		msgDeliveryProgress:          "התקדמות המשלוח: %d%%, הגעה ב-%s",
+		msgRateLinePrivate:           "%s: :lock: נשלח בפרטי",
+		msgEscalationChannelPrivate:  ":bell: <@%s>, את/ה עדיין חייב/ת ל-<@%s> על הזמנת Wolt מספר %s, כבר %d ימים. נא לשלם ולהגיב להודעת הסכומים",
+		msgEscalationWallLinePrivate: "<@%s> חייב/ת על הזמנת Wolt מספר %s (%d ימים)\n",
+		msgOrderFees:                 "כולל העמלות של Wolt: %s\n",
+		msgOrderSettled:              "כולם שילמו על ההזמנה, תודה לכולם! :tada:",
+		msgReceiptHeader:             "כולם שילמו לך על ההזמנה מ-%s (הזמנת Wolt מספר %s) :tada: הנה הקבלה:\n",
+		msgReceiptTotal:              "\nסך הכל שולם לך: %.2f (סכום ההזמנה הוא %.2f, כולל %d %s למשלוח)\n",
+		msgReceiptUntracked:          "\nלא עקבתי אחרי החובות של המשתתפים האלה, כי לא מצאתי את המשתמשים שלהם, אז צריך להסתדר איתם ישירות:\n",
+		msgCompanyPaidRatesPublished: "הסכומים של ההזמנה כבר פורסמו, המארח/ת יכול/ה להגיב עם :%s: להודעת הסכומים כדי לבטל את החובות",
+		msgCompanyPaidHostOnly:       "רק המארח/ת של ההזמנה יכול/ה לסמן שהחברה שילמה עליה",
+		msgCancelDebtsHostOnly:       "יפה ניסית :stuck_out_tongue_winking_eye: רק המארח/ת (<@%s>) יכול/ה לבטל את החובות של ההזמנה",
+		msgMergeTooLate:              "מאוחר מדי, אחת ההזמנות כבר נשלחה",
+		msgRatesJoiners:              "%s הצטרפו להזמנה אחרי שפרסמתי את הסכומים, אז עדכנתי אותם",
+		msgRatesItemsRemoved:         "חלק מהפריטים הוסרו מההזמנה בתשלום, אז עדכנתי את הסכומים:\n",
+		msgRatesAmountsIncreased:     "חלק מהסכומים עלו אחרי שפרסמתי אותם, אז עדכנתי אותם:\n",
+		msgAlreadyPaid:               "<@%s> כבר שילם/ה %.2f, <@%s> נא להחזיר את ההפרש של %.2f",
+		msgAlreadyPaidPrivate:        "<@%s> כבר שילם/ה, <@%s> נא להחזיר את ההפרש (שלחתי אותו בפרטי)",
+		msgPayBackPrivate:            "נא להחזיר ל-<@%s> %.2f על הזמנה %s",
+		msgRatesError:                "הייתה לי שגיאה בחישוב הסכומים של הזמנה %s",
+		msgDebtForgivenNotice:        "<@%s> ויתר/ה על החוב שלך של %s על הזמנה %s :gift:",
+		msgDebtForgiven:              "<@%s> ויתר/ה על החוב של <@%s> של %s",
+		msgDebtForgivenPrivate:       "<@%s> ויתר/ה על החוב של <@%s>",
+		msgDebtChanged:               "<@%s> שינה/תה את החוב של <@%s> על הזמנה %s מ-%s ל-%s",
+		msgDebtChangedPrivate:        "<@%s> שינה/תה את החוב של <@%s> על הזמנה %s",
+		msgDebtMovedToYou:            "<@%s> העביר/ה אליך את החוב של %q של %s על הזמנה %s",
+		msgDebtMovedFromYou:          "<@%s> העביר/ה ממך את החוב של %q של %s על הזמנה %s ל-<@%s>",
+		msgDebtMoved:                 "<@%s> העביר/ה את החוב של %q של %s מ-<@%s> ל-<@%s>",
+		msgDebtMovedPrivate:          "<@%s> העביר/ה את החוב של %q מ-<@%s> ל-<@%s>",
+		msgDebtsError:                "הייתה לי שגיאה בהוספת החובות, לא אעקוב אחרי ההזמנה הזאת",
+		msgSoloCanceled:              "ההזמנה בוטלה",
+		msgSoloStatusFailed:          "לא הצלחתי לקבל מוולט את הסטטוס של ההזמנה הזאת, הפסקתי לעקוב אחריה",
+		msgAlreadyTracking:           "אני כבר עוקב/ת אחרי %s, %s",
+		msgAlreadyTrackingVenue:      "אני כבר עוקב/ת אחרי %s מ-[%s], %s",
+		msgOrderReopened:             "ההזמנה נפתחה מחדש לפני התשלום, אז הסכומים עוד עשויים להשתנות. אעדכן אותם כשהיא תישלח שוב",
+		msgVenueOpen:                 ":large_green_circle: המסעדה פתוחה עכשיו למשלוחים",
+		msgDebtsRemoved:              "הסרתי את כל החובות של הזמנה %s כי %s",
+		msgUnknownAsHost:             "אני לא מוצא/ת את המשתמש של %q, אז אני מחשב/ת את החלק שלהם (%.2f) כחלק של המארח/ת (<@%s>).",
+		msgUnknownSkipped:            "לא אעקוב אחרי התשלום של %q כי אני לא מוצא/ת את המשתמש שלהם.",
+		msgUnknownPending:            "אני לא מוצא/ת את המשתמש של %q, אעקוב אחרי התשלום שלהם כשהמשתמש שלהם יתווסף.",
+		msgUnknownPrompt:             "<@%s>, אני לא מוצא/ת את המשתמש של %q. נא לקשר אותו עם `/bolt link \"%s\" @<user>` ואעקוב אחרי התשלום שלהם של %.2f.",
+		msgPendingActivated:          "מצאתי את המשתמש של %q (<@%s>), אמשיך להזכיר להם לשלם %.2f ל-<@%s>.",
+		msgMissedOrderOffer:          "לא הייתי מחובר/ת כשההזמנה הזאת שותפה :zzz: הגיבו עם :%s: להודעה הזאת אם היא עדיין פתוחה ואתם רוצים שאעקוב אחריה",
+		msgMergeNotified:             "בסדר <@%s>, עדכנתי את <#%s> על ההזמנה הזאת",
+		msgDebtSettledByTreasurer:    "החוב של %s מ-<@%s> ל-<@%s> על הזמנת וולט %s סולק על ידי הגזבר/ית <@%s>",
+		msgSelfTestPost:              "בדיקה עצמית: בודק/ת שאני יכול/ה לפרסם הודעות, להגיב עליהן ולערוך אותן",
+		msgSelfTestEdited:            "בדיקה עצמית: אני יכול/ה לפרסם הודעות, להגיב עליהן ולערוך אותן",
+		msgSelfTestDM:                "בדיקה עצמית: אני יכול/ה לשלוח לך הודעות פרטיות",
+	},
+}
+
+// ChannelHistory is implemented by notification layers which can read the recent messages of a channel
+type ChannelHistory interface {
+	RecentMessages(channel string, limit int) ([]string, error)
+}
+
+func parseLocale(name string) (Locale, error) {
+	switch locale := Locale(name); locale {
+	case "":
+		return LocaleEnglish, nil
+	case LocaleEnglish, LocaleHebrew, LocaleAuto:
+		return locale, nil
+	default:
+		return "", fmt.Errorf("unknown locale %q", name)
+	}
+}
+
+func parseChannelLocales(pairs []string) (map[string]Locale, error) {
+	locales := make(map[string]Locale, len(pairs))
+	for _, pair := range pairs {
+		channel, name, ok := strings.Cut(pair, "=")
+		if !ok || channel == "" || name == "" {
+			return nil, fmt.Errorf("expected <channel>=<locale> but got %q", pair)
+		}
+		locale, err := parseLocale(name)
+		if err != nil {
+			return nil, fmt.Errorf("locale for channel %s: %w", channel, err)
+		}
+		locales[channel] = locale
+	}
+	return locales, nil
+}
+
+// DetectLocale returns Hebrew if most of the letters in the texts are Hebrew, and English otherwise
+func DetectLocale(texts []string) Locale {
+	hebrew, latin := 0, 0
+	for _, text := range texts {
+		for _, r := range text {
+			switch {
+			case unicode.Is(unicode.Hebrew, r):
+				hebrew++
+			case unicode.Is(unicode.Latin, r):
+				latin++
+			}
+		}
+	}
+	if hebrew > latin {
+		return LocaleHebrew
+	}
+	return LocaleEnglish
+}
+
+type detectedLocale struct {
+	locale     Locale
+	detectedAt time.Time
+}
+
+// channelLocales caches the detected locale of each channel
+type channelLocales struct {
+	lock     sync.Mutex
+	detected map[string]detectedLocale
+}
+
+func newChannelLocales() *channelLocales {
+	return &channelLocales{detected: make(map[string]detectedLocale)}
+}
+
+// channelLocale returns the locale of the channel: its CHANNEL_LOCALES override, then LOCALE, which when set to auto is detected
+// from the channel's recent messages every LOCALE_DETECTION_INTERVAL
+func (h *Service) channelLocale(channel string) Locale {
+	locale, ok := h.channelLocaleOverrides[channel]
+	if !ok {
+		locale = h.defaultLocale
+	}
+	if locale != LocaleAuto {
+		return locale
+	}
+
+	h.locales.lock.Lock()
+	defer h.locales.lock.Unlock()
+	if detected, ok := h.locales.detected[channel]; ok && time.Since(detected.detectedAt) < h.cfg.LocaleDetectionInterval {
+		return detected.locale
+	}
+
+	detected := LocaleEnglish
+	if history, ok := h.eventNotification.(ChannelHistory); ok {
+		texts, err := history.RecentMessages(channel, localeDetectionMessages)
+		if err != nil {
+			h.logger.Error("Error getting recent messages for detecting the channel's locale", "channel", channel, "error", err)
+		} else {
+			detected = DetectLocale(texts)
+		}
+	}
+	h.locales.detected[channel] = detectedLocale{locale: detected, detectedAt: time.Now()}
+	return detected
+}
+
+// text returns the message in the channel's locale, preferring the operator's message from MESSAGES_FILE over the built-in one
+func (h *Service) text(channel string, key messageKey, args ...interface{}) string {
+	locale := h.channelLocale(channel)
+	format, ok := h.messages[locale][key]
+	if !ok {
+		format, ok = translations[locale][key]
+	}
+	if !ok {
+		format = translations[LocaleEnglish][key]
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
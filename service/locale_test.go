@@ -0,0 +1,63 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectLocale(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, LocaleEnglish, DetectLocale(nil))
+	assert.Equal(t, LocaleEnglish, DetectLocale([]string{"who's ordering lunch?", "מי מזמין?"}))
+	assert.Equal(t, LocaleHebrew, DetectLocale([]string{"מי מזמין צהריים היום?", "אני בפנים", "ok"}))
+}
+
+type historyNotification struct {
+	recordingNotification
+	history map[string][]string
+	reads   int
+}
+
+func (n *historyNotification) RecentMessages(channel string, _ int) ([]string, error) {
+	n.reads++
+	return n.history[channel], nil
+}
+
+func TestChannelLocale(t *testing.T) {
+	t.Parallel()
+
+	notification := &historyNotification{history: map[string][]string{
+		"C1": {"מי מזמין צהריים היום?"},
+		"C2": {"anyone up for pizza?"},
+	}}
+	h, err := New(Config{
+
+		Locale:                  "auto",
+		ChannelLocales:          []string{"C3=he"},
+		LocaleDetectionInterval: time.Hour,
+	}, nil, nil, nil, "UBOT", notification)
+	require.NoError(t, err)
+
+	assert.Equal(t, LocaleHebrew, h.channelLocale("C1"))
+	assert.Equal(t, LocaleEnglish, h.channelLocale("C2"))
+	assert.Equal(t, LocaleHebrew, h.channelLocale("C3"))
+	assert.Equal(t, LocaleHebrew, h.channelLocale("C1"))
+	assert.Equal(t, 2, notification.reads, "detected locales are cached")
+
+	assert.Equal(t, "היי 👋, הצטרפתי להזמנה מ-[Pizza]", h.text("C1", msgJoinedOrder, "Pizza"))
+	assert.Equal(t, "Hi 👋, I've joined the order from [Pizza]", h.text("C2", msgJoinedOrder, "Pizza"))
+
+	rates := h.buildRatesMessage("C3", GroupRate{DeliveryRate: 10}, "ABC123")
+	parsedID := &ParsedWoltGroupID{}
+	require.NoError(t, groupFromMessageRe.MatchToTarget(rates, parsedID), "the order ID is parsed from localized rates messages")
+	assert.Equal(t, "ABC123", parsedID.ID)
+
+	_, err = New(Config{Locale: "fr"}, nil, nil, nil, "UBOT", notification)
+	assert.Error(t, err)
+	_, err = New(Config{ChannelLocales: []string{"C1"}}, nil, nil, nil, "UBOT", notification)
+	assert.Error(t, err)
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/oriser/bolt/order"
+)
+
+// unmatchedSummaryNames is the number of unmatched Wolt names the weekly matching summary lists
+const unmatchedSummaryNames = 10
+
+// UserMatch is how the Wolt name of a participant was matched to a user
+type UserMatch string
+
+const (
+	UserMatchExact     UserMatch = "exact"     // The user's full name is the Wolt name
+	UserMatchFuzzy     UserMatch = "fuzzy"     // The user's name is similar to the Wolt name, e.g. just the first name
+	UserMatchUnmatched UserMatch = "unmatched" // No user, several users or a deactivated user
+)
+
+// matchOfUser returns how the participant with the Wolt name was matched to the user
+func matchOfUser(woltName, fullName string) UserMatch {
+	if strings.EqualFold(strings.TrimSpace(woltName), strings.TrimSpace(fullName)) {
+		return UserMatchExact
+	}
+	return UserMatchFuzzy
+}
+
+// observeMatches counts how the participants of the published rates were matched to users
+func observeMatches(groupID string, groupRate GroupRate) {
+	counts := make(map[UserMatch]int)
+	for _, rate := range groupRate.Rates {
+		match := rate.Match
+		if match == "" {
+			match = UserMatchUnmatched
+		}
+		counts[match]++
+		participantMatchesTotal.Inc(string(match))
+	}
+	slog.Info("Matched the participants of order", "group_id", groupID, "exact", counts[UserMatchExact], "fuzzy", counts[UserMatchFuzzy],
+		"unmatched", counts[UserMatchUnmatched])
+}
+
+// UnmatchedName is a Wolt name which wasn't matched to a user
+type UnmatchedName struct {
+	Name   string
+	Orders int // The orders the name took part in
+}
+
+// UnmatchedNames returns the Wolt names which took part in orders since the given time without being matched to a user, from the
+// most frequent. The names which are matched to a user by now are left out, as they were onboarded since.
+func (h *Service) UnmatchedNames(ctx context.Context, since time.Time) ([]UnmatchedName, error) {
+	if h.orderStore == nil {
+		return nil, fmt.Errorf("orders are not supported")
+	}
+	orders, err := h.orderStore.ListOrders(ctx, order.ListFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("list orders: %w", err)
+	}
+
+	counts := make(map[string]int)
+	for _, o := range orders {
+		if o.CreatedAt.Before(since) {
+			continue
+		}
+		for _, participant := range o.Participants {
+			if participant.ID == "" {
+				counts[participant.Name]++
+			}
+		}
+	}
+
+	names := make([]UnmatchedName, 0, len(counts))
+	for name, count := range counts {
+		if h.userStore != nil {
+			if users, err := h.listUsersByName(name); err == nil && len(users) > 0 {
+				continue
+			}
+		}
+		names = append(names, UnmatchedName{Name: name, Orders: count})
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if names[i].Orders != names[j].Orders {
+			return names[i].Orders > names[j].Orders
+		}
+		return names[i].Name < names[j].Name
+	})
+	return names, nil
+}
+
+// BuildMatchingSummaryMessage returns the weekly summary of the top unmatched Wolt names
+func BuildMatchingSummaryMessage(names []UnmatchedName) string {
+	if len(names) > unmatchedSummaryNames {
+		names = names[:unmatchedSummaryNames]
+	}
+	lines := make([]string, len(names))
+	for i, name := range names {
+		lines[i] = fmt.Sprintf("%s (%d orders)", name.Name, name.Orders)
+		if name.Orders == 1 {
+			lines[i] = fmt.Sprintf("%s (1 order)", name.Name)
+		}
+	}
+	return fmt.Sprintf(":mag: These Wolt names weren't matched to anyone in the workspace this week, so their debts weren't tracked. "+
+		"Ask them to register their Wolt names with `/bolt register <Wolt name>`:\n%s", strings.Join(lines, "\n"))
+}
+
+func (h *Service) postMatchingSummary(ctx context.Context, now time.Time) {
+	names, err := h.UnmatchedNames(ctx, now.AddDate(0, 0, -7))
+	if err != nil {
+		h.logger.ErrorContext(ctx, "Error getting the unmatched Wolt names", "error", err)
+		return
+	}
+	if len(names) == 0 {
+		return
+	}
+	if err := h.informNonUrgent(h.cfg.MatchingSummaryChannel, BuildMatchingSummaryMessage(names), ""); err != nil {
+		h.logger.ErrorContext(ctx, "Error posting the matching summary", "channel", h.cfg.MatchingSummaryChannel, "error", err)
+	}
+}
+
+// RunMatchingSummary posts the top Wolt names which weren't matched to users in the past week to MATCHING_SUMMARY_CHANNEL, every
+// BALANCES_DIGEST_WEEKDAY at MATCHING_SUMMARY_HOUR, until the context is done
+func (h *Service) RunMatchingSummary(ctx context.Context) {
+	if h.cfg.MatchingSummaryChannel == "" || h.orderStore == nil {
+		return
+	}
+
+	ticker := time.NewTicker(badgesSchedulerInterval)
+	defer ticker.Stop()
+
+	h.schedulers.beat("matching summary", badgesSchedulerInterval)
+	lastCheck := time.Now()
+	for {
+		select {
+		case now := <-ticker.C:
+			tz := h.timezoneForChannel(h.cfg.MatchingSummaryChannel, nil)
+			postAt := weekStart(now.In(tz), h.balancesDigestWeekday).Add(time.Duration(h.cfg.MatchingSummaryHour) * time.Hour)
+			if postAt.After(lastCheck) && !postAt.After(now) {
+				h.postMatchingSummary(ctx, now)
+			}
+			lastCheck = now
+			h.schedulers.beat("matching summary", badgesSchedulerInterval)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oriser/bolt/order"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserMatching(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, UserMatchExact, matchOfUser("Thor Odinson", "thor odinson"))
+	assert.Equal(t, UserMatchFuzzy, matchOfUser("Thor", "Thor Odinson"))
+
+	now := time.Now()
+	users := &fakeTreasuryStore{users: map[string]*userDomain.User{
+		"U1": {ID: "U1", FullName: "Thor", TransportID: "U1"},
+		"U2": {ID: "U2", FullName: "Odin", TransportID: "U2"},
+	}}
+	orders := &fakeOrderStore{orders: []*order.Order{
+		{OriginalID: "A", CreatedAt: now.Add(-time.Hour), Participants: []order.Participant{{Name: "Thor", ID: "U1"}, {Name: "Loki"}, {Name: "Hela"}}},
+		{OriginalID: "B", CreatedAt: now.Add(-24 * time.Hour), Participants: []order.Participant{{Name: "Loki"}, {Name: "Odin"}}},
+		{OriginalID: "C", CreatedAt: now.AddDate(0, 0, -8), Participants: []order.Participant{{Name: "Frigg"}}},
+	}}
+	h, err := New(Config{}, users, nil, orders, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+
+	groupRate := h.buildGroupRates(map[string]float64{"Thor": 10, "Loki": 20}, "Thor", 0)
+	assert.Equal(t, UserMatchUnmatched, groupRate.Rates[0].Match)
+	assert.Equal(t, UserMatchExact, groupRate.Rates[1].Match)
+
+	names, err := h.UnmatchedNames(context.Background(), now.AddDate(0, 0, -7))
+	require.NoError(t, err)
+	assert.Equal(t, []UnmatchedName{{Name: "Loki", Orders: 2}, {Name: "Hela", Orders: 1}}, names,
+		"names of older orders and names which are matched by now are left out")
+	assert.Equal(t, ":mag: These Wolt names weren't matched to anyone in the workspace this week, so their debts weren't tracked. "+
+		"Ask them to register their Wolt names with `/bolt register <Wolt name>`:\n"+
+		"Loki (2 orders)\n"+
+		"Hela (1 order)", BuildMatchingSummaryMessage(names))
+}
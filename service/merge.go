@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// mergeOffer is an offer to let the channel of another open order from the same venue know about the order, so they order together
+// and pay a single delivery fee
+type mergeOffer struct {
+	orderID      string
+	messageID    string // The message with the link of the order, whose thread the offer is in
+	otherOrderID string
+}
+
+// mergeOffers keeps the offers until they're accepted, or until either order is no longer open
+type mergeOffers struct {
+	lock   sync.Mutex
+	offers map[string]mergeOffer // By the channel and the message ID of the offer
+}
+
+func newMergeOffers() *mergeOffers {
+	return &mergeOffers{offers: make(map[string]mergeOffer)}
+}
+
+func (m *mergeOffers) add(channel, messageID string, offer mergeOffer) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.offers[skipKey(channel, messageID)] = offer
+}
+
+// take returns the offer of the message and removes it, so it's accepted once
+func (m *mergeOffers) take(channel, messageID string) (mergeOffer, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	key := skipKey(channel, messageID)
+	offer, ok := m.offers[key]
+	delete(m.offers, key)
+	return offer, ok
+}
+
+// onEvent drops the offers of the orders which are no longer open
+func (m *mergeOffers) onEvent(_ context.Context, event Event) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for key, offer := range m.offers {
+		if offer.orderID == event.OrderID || offer.otherOrderID == event.OrderID {
+			delete(m.offers, key)
+		}
+	}
+}
+
+// orderPointer returns the text linked to the message of the order, or the text as is if the notification layer can't link to it
+func (h *Service) orderPointer(activeOrder ActiveOrder, text string) string {
+	linker, ok := h.eventNotification.(MessageLinker)
+	if !ok {
+		return text
+	}
+	link, err := linker.MessageLink(activeOrder.Channel, activeOrder.MessageID)
+	if err != nil {
+		h.logger.Error("Error getting link to the message of order", "group_id", activeOrder.ID, "error", err)
+		return text
+	}
+	return fmt.Sprintf("<%s|%s>", link, text)
+}
+
+// sameVenueOrder returns the oldest open order from the venue in another channel, whose group order wasn't sent yet
+func (h *Service) sameVenueOrder(orderID, channel, venueName string) (ActiveOrder, bool) {
+	for _, activeOrder := range h.ActiveOrders() {
+		if activeOrder.ID != orderID && activeOrder.Channel != channel && activeOrder.Rates == nil &&
+			strings.EqualFold(activeOrder.VenueName, venueName) {
+			return activeOrder, true
+		}
+	}
+	return ActiveOrder{}, false
+}
+
+// offerMerge offers the channel of the order to let the channel of another open order from the same venue know about it, when
+// MERGE_OFFERS is set. The other channel is only told once someone reacts to the offer, as it may not want to order together.
+func (h *Service) offerMerge(channel, messageID, orderID, venueName string) {
+	if !h.cfg.MergeOffers || venueName == "" {
+		return
+	}
+	other, ok := h.sameVenueOrder(orderID, channel, venueName)
+	if !ok {
+		return
+	}
+	offerID, err := h.informEvent(channel, h.text(channel, msgMergeOffer, other.Channel, venueName, h.cfg.MergeOfferEmoji), "", messageID)
+	if err != nil {
+		h.logger.Error("Error offering to merge orders", "group_id", orderID, "other_group_id", other.ID, "error", err)
+		return
+	}
+	h.mergeOffers.add(channel, offerID, mergeOffer{orderID: orderID, messageID: messageID, otherOrderID: other.ID})
+}
+
+// handleMergeReaction tells the channel of the other order about the order of the offer reacted to, and returns false if the reaction
+// isn't to an offer
+func (h *Service) handleMergeReaction(req ReactionAddRequest) bool {
+	offer, ok := h.mergeOffers.take(req.Channel, req.MessageID)
+	if !ok {
+		return false
+	}
+	activeOrder, ok := h.LookupActiveOrder(offer.orderID)
+	other, otherOK := h.LookupActiveOrder(offer.otherOrderID)
+	if !ok || !otherOK || other.Rates != nil {
+		_, _ = h.informEvent(req.Channel, h.text(req.Channel, msgMergeTooLate), "", offer.messageID)
+		return true
+	}
+
+	suggestion := h.text(other.Channel, msgMergeSuggested, activeOrder.Channel, activeOrder.VenueName, h.orderPointer(activeOrder, activeOrder.ID))
+	if _, err := h.informEvent(other.Channel, suggestion, "", other.MessageID); err != nil {
+		h.logger.Error("Error suggesting to merge orders", "group_id", other.ID, "other_group_id", activeOrder.ID, "error", err)
+		return true
+	}
+	_, _ = h.informEvent(req.Channel, h.text(req.Channel, msgMergeNotified, req.FromUserID, other.Channel), "", offer.messageID)
+	return true
+}
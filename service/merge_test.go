@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeOffers(t *testing.T) {
+	t.Parallel()
+
+	notification := &editingNotification{}
+	h, err := New(Config{MergeOffers: true, MergeOfferEmoji: "handshake"}, nil, nil, nil, "U-bot", notification)
+	require.NoError(t, err)
+	ctx := context.Background()
+	h.hooks.Emit(ctx, Event{Type: EventOrderJoined, OrderID: "A", Channel: "C1", MessageID: "1.1", VenueName: "Pizza"})
+	h.hooks.Emit(ctx, Event{Type: EventOrderJoined, OrderID: "B", Channel: "C2", MessageID: "2.1", VenueName: "Sushi"})
+
+	h.offerMerge("C2", "2.1", "B", "Sushi")
+	h.offerMerge("C1", "1.2", "C", "Pizza")
+	assert.Empty(t, notification.messages, "orders from other venues or in the same channel aren't offered to merge")
+
+	h.hooks.Emit(ctx, Event{Type: EventOrderJoined, OrderID: "D", Channel: "C3", MessageID: "3.1", VenueName: "pizza"})
+	h.offerMerge("C3", "3.1", "D", "pizza")
+	assert.Equal(t, []string{"C3: :handshake: <#C1> also has an open order from [pizza]. Ordering together saves a delivery fee, " +
+		"react with :handshake: to this message to let them know"}, notification.messages)
+
+	_, err = h.HandleReactionAdded(ReactionAddRequest{Reaction: "handshake", Channel: "C3", MessageID: "sent-1", FromUserID: "U1"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"C1: :handshake: <#C3> is also ordering from [pizza] (order D). Order together to save a delivery fee",
+		"C3: OK <@U1>, I let <#C1> know about this order",
+	}, notification.messages[1:])
+
+	_, err = h.HandleReactionAdded(ReactionAddRequest{Reaction: "handshake", Channel: "C3", MessageID: "sent-1", FromUserID: "U2"})
+	require.NoError(t, err)
+	assert.Len(t, notification.messages, 3, "the offer is accepted once")
+
+	h.offerMerge("C3", "3.1", "D", "pizza")
+	h.hooks.Emit(ctx, Event{Type: EventRatesPublished, OrderID: "A", Rates: &GroupRate{}})
+	_, err = h.HandleReactionAdded(ReactionAddRequest{Reaction: "handshake", Channel: "C3", MessageID: "sent-1", FromUserID: "U1"})
+	require.NoError(t, err)
+	assert.Len(t, notification.messages, 4, "the offers of sent orders are dropped")
+}
@@ -0,0 +1,246 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// messageNames are the names of the messages in MESSAGES_FILE
+var messageNames = map[messageKey]string{
+	msgJoinedOrder:               "joined_order",
+	msgTooLate:                   "too_late",
+	msgTooLateConfirmation:       "too_late_confirmation",
+	msgRatesHeader:               "rates_header",
+	msgPayTo:                     "pay_to",
+	msgPreferredPayments:         "preferred_payments",
+	msgAgeRestricted:             "age_restricted",
+	msgPersonalShare:             "personal_share",
+	msgSubsidy:                   "subsidy",
+	msgSubsidyPercent:            "subsidy_percent",
+	msgSubsidyPerOrder:           "subsidy_per_order",
+	msgSubsidyMonthlyCap:         "subsidy_monthly_cap",
+	msgSubsidyExcluding:          "subsidy_excluding",
+	msgSubsidyCapReached:         "subsidy_cap_reached",
+	msgVenueBusy:                 "venue_busy",
+	msgHeadcount:                 "headcount",
+	msgHeadcountSuggestion:       "headcount_suggestion",
+	msgCompanyPaid:               "company_paid",
+	msgHostNote:                  "host_note",
+	msgOrderRef:                  "order_ref",
+	msgMarkPaid:                  "mark_paid",
+	msgCancelTracking:            "cancel_tracking",
+	msgPayWith:                   "pay_with",
+	msgForgiven:                  "forgiven",
+	msgAdjusted:                  "adjusted",
+	msgRatesContinued:            "rates_continued",
+	msgShutdownResumed:           "shutdown_resumed",
+	msgShutdownStopped:           "shutdown_stopped",
+	msgMutualPayment:             "mutual_payment",
+	msgPreviewHeader:             "preview_header",
+	msgRoundedByHost:             "rounded_by_host",
+	msgRoundedByLargest:          "rounded_by_largest",
+	msgOrderQueued:               "order_queued",
+	msgOrdersQueueFull:           "orders_queue_full",
+	msgNudge:                     "nudge",
+	msgDiscountSplit:             "discount_split",
+	msgDiscountHost:              "discount_host",
+	msgTrackingCanceled:          "tracking_canceled",
+	msgTrackingLink:              "tracking_link",
+	msgDebtDM:                    "debt_d_m",
+	msgDebtDMMethods:             "debt_d_m_methods",
+	msgSoloTracking:              "solo_tracking",
+	msgHostingNudge:              "hosting_nudge",
+	msgBankAccount:               "bank_account",
+	msgRevealBankAccount:         "reveal_bank_account",
+	msgBankAccountDetails:        "bank_account_details",
+	msgOrderCanceled:             "order_canceled",
+	msgTimedOutReady:             "timed_out_ready",
+	msgTimedOutDone:              "timed_out_done",
+	msgNoOneConfirmed:            "no_one_confirmed",
+	msgNoDeliveryRate:            "no_delivery_rate",
+	msgDeliverySoon:              "delivery_soon",
+	msgDeliveryArrived:           "delivery_arrived",
+	msgHostNotFound:              "host_not_found",
+	msgDebtsTracking:             "debts_tracking",
+	msgRateLine:                  "rate_line",
+	msgRateItem:                  "rate_item",
+	msgItemsBreakdown:            "items_breakdown",
+	msgPreauthRequired:           "preauth_required",
+	msgPreauthNotConfirmed:       "preauth_not_confirmed",
+	msgSplitHeader:               "split_header",
+	msgSplitDebts:                "split_debts",
+	msgJoinFailed:                "join_failed",
+	msgJoinFailedAuth:            "join_failed_auth",
+	msgJoinFailedFull:            "join_failed_full",
+	msgJoinFailedClosed:          "join_failed_closed",
+	msgJoinFailedRegion:          "join_failed_region",
+	msgJoinFailedNetwork:         "join_failed_network",
+	msgExtraFees:                 "extra_fees",
+	msgFeesChanged:               "fees_changed",
+	msgEscalationChannel:         "escalation_channel",
+	msgEscalationAdmins:          "escalation_admins",
+	msgEscalationWall:            "escalation_wall",
+	msgEscalationWallLine:        "escalation_wall_line",
+	msgMergeOffer:                "merge_offer",
+	msgMergeSuggested:            "merge_suggested",
+	msgDeliveryProgress:          "delivery_progress",
+	msgRateLinePrivate:           "rate_line_private",
+	msgEscalationChannelPrivate:  "escalation_channel_private",
+	msgEscalationWallLinePrivate: "escalation_wall_line_private",
+	msgOrderFees:                 "order_fees",
+	msgOrderSettled:              "order_settled",
+	msgReceiptHeader:             "receipt_header",
+	msgReceiptTotal:              "receipt_total",
+	msgReceiptUntracked:          "receipt_untracked",
+	msgCompanyPaidRatesPublished: "company_paid_rates_published",
+	msgCompanyPaidHostOnly:       "company_paid_host_only",
+	msgCancelDebtsHostOnly:       "cancel_debts_host_only",
+	msgMergeTooLate:              "merge_too_late",
+	msgRatesJoiners:              "rates_joiners",
+	msgRatesItemsRemoved:         "rates_items_removed",
+	msgRatesAmountsIncreased:     "rates_amounts_increased",
+	msgAlreadyPaid:               "already_paid",
+	msgAlreadyPaidPrivate:        "already_paid_private",
+	msgPayBackPrivate:            "pay_back_private",
+	msgRatesError:                "rates_error",
+	msgDebtForgivenNotice:        "debt_forgiven_notice",
+	msgDebtForgiven:              "debt_forgiven",
+	msgDebtForgivenPrivate:       "debt_forgiven_private",
+	msgDebtChanged:               "debt_changed",
+	msgDebtChangedPrivate:        "debt_changed_private",
+	msgDebtMovedToYou:            "debt_moved_to_you",
+	msgDebtMovedFromYou:          "debt_moved_from_you",
+	msgDebtMoved:                 "debt_moved",
+	msgDebtMovedPrivate:          "debt_moved_private",
+	msgDebtsError:                "debts_error",
+	msgSoloCanceled:              "solo_canceled",
+	msgSoloStatusFailed:          "solo_status_failed",
+	msgAlreadyTracking:           "already_tracking",
+	msgAlreadyTrackingVenue:      "already_tracking_venue",
+	msgOrderReopened:             "order_reopened",
+	msgVenueOpen:                 "venue_open",
+	msgDebtsRemoved:              "debts_removed",
+	msgUnknownAsHost:             "unknown_as_host",
+	msgUnknownSkipped:            "unknown_skipped",
+	msgUnknownPending:            "unknown_pending",
+	msgUnknownPrompt:             "unknown_prompt",
+	msgPendingActivated:          "pending_activated",
+	msgMissedOrderOffer:          "missed_order_offer",
+	msgMergeNotified:             "merge_notified",
+	msgDebtSettledByTreasurer:    "debt_settled_by_treasurer",
+	msgSelfTestPost:              "self_test_post",
+	msgSelfTestEdited:            "self_test_edited",
+	msgSelfTestDM:                "self_test_d_m",
+}
+
+// messageVerb is a formatting verb of a message, like %s, with the index of the argument it formats
+type messageVerb struct {
+	argument int
+	verb     rune
+}
+
+// messageVerbs returns the formatting verbs of the message, supporting explicit argument indexes like %[2]s
+func messageVerbs(format string) ([]messageVerb, error) {
+	verbs := make([]messageVerb, 0)
+	argument := 0
+	runes := []rune(format)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '%' {
+			continue
+		}
+		// Flags, width and precision come before the verb, and the argument index right before it, like %.2[1]f
+		for i++; i < len(runes) && strings.ContainsRune("+-# 0123456789.[", runes[i]); i++ {
+			if runes[i] != '[' {
+				continue
+			}
+			end := strings.IndexRune(string(runes[i:]), ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed argument index")
+			}
+			if _, err := fmt.Sscanf(string(runes[i+1:i+end]), "%d", &argument); err != nil || argument < 1 {
+				return nil, fmt.Errorf("bad argument index %q", string(runes[i:i+end+1]))
+			}
+			argument--
+			if i += end; i+1 < len(runes) && strings.ContainsRune("+-# 0123456789.[", runes[i+1]) {
+				return nil, fmt.Errorf("argument index %q must come right before the verb", string(runes[i-end:i+1]))
+			}
+		}
+		if i >= len(runes) {
+			return nil, fmt.Errorf("missing verb at the end")
+		}
+		if runes[i] == '%' {
+			continue
+		}
+		verbs = append(verbs, messageVerb{argument: argument, verb: runes[i]})
+		argument++
+	}
+	return verbs, nil
+}
+
+// validateMessage returns an error if the message doesn't format the same arguments as the built-in message, as the message would
+// show %!s(MISSING) or the like otherwise
+func validateMessage(key messageKey, message string) error {
+	builtIn, err := messageVerbs(translations[LocaleEnglish][key])
+	if err != nil {
+		return fmt.Errorf("built-in message: %w", err)
+	}
+	verbs, err := messageVerbs(message)
+	if err != nil {
+		return err
+	}
+	for _, verb := range verbs {
+		if verb.argument >= len(builtIn) {
+			return fmt.Errorf("%%%c formats argument %d but the message has %d", verb.verb, verb.argument+1, len(builtIn))
+		}
+		if expected := builtIn[verb.argument].verb; verb.verb != expected && verb.verb != 'v' {
+			return fmt.Errorf("argument %d should be formatted with %%%c or %%v, not %%%c", verb.argument+1, expected, verb.verb)
+		}
+	}
+	if (key == msgRatesHeader || key == msgRatesContinued) && !strings.Contains(message, "Wolt order ID %s") {
+		return fmt.Errorf("the message must include \"Wolt order ID %%s\", for finding the order of reactions to the rates message")
+	}
+	return nil
+}
+
+// loadMessages reads the messages overriding the built-in ones from MESSAGES_FILE, a JSON object of locales to the messages by
+// their names, like {"en": {"joined_order": "Hey, I'm in the order from [%s]"}}. The messages keep the placeholders of the built-in
+// ones (%s, %d and so on, in the same order or with explicit indexes like %[2]s). An empty path overrides nothing.
+func loadMessages(path string) (map[Locale]map[messageKey]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read messages file: %w", err)
+	}
+	var named map[string]map[string]string
+	if err := json.Unmarshal(content, &named); err != nil {
+		return nil, fmt.Errorf("parse messages file: %w", err)
+	}
+
+	keys := make(map[string]messageKey, len(messageNames))
+	for key, name := range messageNames {
+		keys[name] = key
+	}
+	messages := make(map[Locale]map[messageKey]string, len(named))
+	for localeName, localeMessages := range named {
+		locale, err := parseLocale(localeName)
+		if err != nil || locale == LocaleAuto {
+			return nil, fmt.Errorf("unknown locale %q", localeName)
+		}
+		messages[locale] = make(map[messageKey]string, len(localeMessages))
+		for name, message := range localeMessages {
+			key, ok := keys[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown message %q", name)
+			}
+			if err := validateMessage(key, message); err != nil {
+				return nil, fmt.Errorf("message %s of locale %s: %w", name, locale, err)
+			}
+			messages[locale][key] = message
+		}
+	}
+	return messages, nil
+}
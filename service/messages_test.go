@@ -0,0 +1,61 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeMessagesFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "messages.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestMessageNames(t *testing.T) {
+	t.Parallel()
+
+	names := make(map[string]bool)
+	for key := msgJoinedOrder; key <= msgSelfTestDM; key++ {
+		name, ok := messageNames[key]
+		require.True(t, ok, "message %d has no name", key)
+		assert.False(t, names[name], "duplicate name %s", name)
+		names[name] = true
+		for locale, messages := range translations {
+			assert.NoError(t, validateMessage(key, messages[key]), "built-in %s message %s", locale, name)
+		}
+	}
+}
+
+func TestLoadMessages(t *testing.T) {
+	t.Parallel()
+
+	messages, err := loadMessages("")
+	require.NoError(t, err)
+	assert.Nil(t, messages)
+
+	path := writeMessagesFile(t, `{"en": {"joined_order": "Hey, I'm in the order from [%s]", "rate_line": "%[1]s owes %.2[2]f"},
+		"he": {"delivery_arrived": "האוכל פה!"}}`)
+	h, err := New(Config{MessagesFile: path, ChannelLocales: []string{"C2=he"}}, nil, nil, nil, "UBOT", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Hey, I'm in the order from [Pizza]", h.text("C1", msgJoinedOrder, "Pizza"))
+	assert.Equal(t, "<@U1> owes 12.50", h.text("C1", msgRateLine, "<@U1>", 12.5))
+	assert.Equal(t, "Delivery arrived", h.text("C1", msgDeliveryArrived), "messages which aren't overridden are built in")
+	assert.Equal(t, "האוכל פה!", h.text("C2", msgDeliveryArrived))
+
+	for content, expected := range map[string]string{
+		`{"en": {"joined": "Hi"}}`:                        `unknown message "joined"`,
+		`{"fr": {"joined_order": "Salut [%s]"}}`:          `unknown locale "fr"`,
+		`{"en": {"joined_order": "Hi [%s] %s"}}`:          "formats argument 2 but the message has 1",
+		`{"en": {"rate_line": "%s: %d"}}`:                 "argument 2 should be formatted with %f or %v, not %d",
+		`{"en": {"rates_header": "Order %s (%d %s):\n"}}`: `must include "Wolt order ID %s"`,
+		`{"en": {"rate_line": "%[2].2f"}}`:                `argument index "[2]" must come right before the verb`,
+		`{"en": `:                                         "parse messages file",
+	} {
+		_, err := loadMessages(writeMessagesFile(t, content))
+		assert.ErrorContains(t, err, expected, content)
+	}
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/oriser/bolt/metrics"
+)
+
+// monitoringBuckets are the buckets (in seconds) of the monitoring durations, from a minute to 3 hours
+var monitoringBuckets = []float64{60, 5 * 60, 10 * 60, 15 * 60, 30 * 60, 45 * 60, 60 * 60, 90 * 60, 2 * 60 * 60, 3 * 60 * 60}
+
+var (
+	linkMessagesTotal = metrics.NewCounter("bolt_link_messages_total",
+		"Handled messages with Wolt links, by result (ok, too_late or error)", "result")
+	ordersTrackedTotal      = metrics.NewCounter("bolt_orders_tracked_total", "Group orders Bolt joined and started tracking")
+	joinFailuresTotal       = metrics.NewCounter("bolt_join_failures_total", "Group orders Bolt failed to join, by the code of the failure", "code")
+	ordersCanceledTotal     = metrics.NewCounter("bolt_orders_canceled_total", "Tracked group orders which were canceled")
+	ordersDeliveredTotal    = metrics.NewCounter("bolt_orders_delivered_total", "Tracked group orders which were delivered")
+	debtsCreatedTotal       = metrics.NewCounter("bolt_debts_created_total", "Debts added for the participants of orders")
+	debtsSettledTotal       = metrics.NewCounter("bolt_debts_settled_total", "Debts which were paid or marked as paid")
+	participantMatchesTotal = metrics.NewCounter("bolt_participant_matches_total",
+		"Participants of published rates, by how they were matched to users (exact, fuzzy or unmatched)", "result")
+	monitoringDuration = metrics.NewHistogram("bolt_order_monitoring_duration_seconds",
+		"Durations of monitoring orders, by phase (group, until the order is sent, or delivery)", monitoringBuckets, "phase")
+	ordersQueuedTotal = metrics.NewCounter("bolt_orders_queued_total",
+		"Orders shared while MAX_TRACKED_ORDERS orders were tracked, by result (queued or rejected)", "result")
+	commandsRateLimitedTotal = metrics.NewCounter("bolt_commands_rate_limited_total",
+		"Commands and button clicks refused as their user exceeded COMMAND_RATE_LIMIT")
+	goroutinesGauge = metrics.NewGauge("bolt_goroutines", "Running goroutines, as of the last watchdog sample")
+	heapBytesGauge  = metrics.NewGauge("bolt_heap_bytes", "Bytes of allocated heap objects, as of the last watchdog sample")
+	monitorsGauge   = metrics.NewGauge("bolt_order_monitors",
+		"Running order monitors, by kind (venue, delivery, solo_delivery or debts)", "kind")
+	heapBytesPerMonitorGauge = metrics.NewGauge("bolt_heap_bytes_per_order_monitor",
+		"Bytes of allocated heap objects divided by the running order monitors, 0 if none is running")
+	leakedMonitorsGauge = metrics.NewGauge("bolt_leaked_order_monitors",
+		"Running order monitors which are alive past their deadline by more than WATCHDOG_INTERVAL")
+	monitorLeaksTotal = metrics.NewCounter("bolt_order_monitor_leaks_total", "Order monitors found alive past their deadline, by kind", "kind")
+)
+
+func observeLinkMessage(err error) {
+	switch {
+	case err == nil:
+		linkMessagesTotal.Inc("ok")
+	case errors.Is(err, errNotInTime):
+		linkMessagesTotal.Inc("too_late")
+	default:
+		linkMessagesTotal.Inc("error")
+	}
+}
+
+func observeMonitoring(phase string, start time.Time) {
+	monitoringDuration.ObserveSince(start, phase)
+}
+
+// recordMetrics counts the lifecycle events of orders and debts
+func recordMetrics(_ context.Context, event Event) {
+	switch event.Type {
+	case EventOrderCanceled:
+		ordersCanceledTotal.Inc()
+	case EventOrderDelivered:
+		ordersDeliveredTotal.Inc()
+	case EventDebtCreated:
+		debtsCreatedTotal.Inc()
+	case EventDebtPaid:
+		debtsSettledTotal.Inc()
+	}
+}
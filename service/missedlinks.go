@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/oriser/bolt/order"
+)
+
+// HistoryMessage is a message from the history of a channel
+type HistoryMessage struct {
+	MessageID string
+	Text      string
+	Links     []Link
+	SentAt    time.Time
+}
+
+// MissedMessagesReader is implemented by notification layers which can read the messages a channel got since a message
+type MissedMessagesReader interface {
+	// MessagesSince returns the messages of the channel after the given message which were sent after since, oldest first. The
+	// messages of bots are left out.
+	MessagesSince(channel, messageID string, since time.Time) ([]HistoryMessage, error)
+}
+
+func (h *Service) linkCursorStore() order.LinkCursorStore {
+	if h.cfg.MissedLinksLookback <= 0 {
+		return nil
+	}
+	store, _ := h.orderStore.(order.LinkCursorStore)
+	return store
+}
+
+// setLinkCursor marks the message as the last link message handled in the channel, so the links shared after it while the service
+// is down are found when it starts
+func (h *Service) setLinkCursor(ctx context.Context, channel, messageID string) {
+	store := h.linkCursorStore()
+	if store == nil || messageID == "" {
+		return
+	}
+	if err := store.SetLinkCursor(ctx, channel, messageID, time.Now()); err != nil {
+		h.logger.ErrorContext(ctx, "Error setting the link cursor", "channel", channel, "message_id", messageID, "error", err)
+	}
+}
+
+// CatchUpMissedLinks looks for the Wolt links shared while the service was down, in the channels links were handled in before, and
+// offers to track the orders Bolt doesn't know yet. The history is read since the last handled link message of each channel, up to
+// MISSED_LINKS_LOOKBACK back. It returns how many orders are offered.
+func (h *Service) CatchUpMissedLinks(ctx context.Context) (int, error) {
+	store := h.linkCursorStore()
+	if store == nil {
+		return 0, nil
+	}
+	reader, ok := h.eventNotification.(MissedMessagesReader)
+	if !ok {
+		return 0, fmt.Errorf("channel history is not supported")
+	}
+	cursors, err := store.ListLinkCursors(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list link cursors: %w", err)
+	}
+	known, err := h.knownGroupIDs(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	since := time.Now().Add(-h.cfg.MissedLinksLookback)
+	offered := 0
+	for channel, cursor := range cursors {
+		messages, err := reader.MessagesSince(channel, cursor, since)
+		if err != nil {
+			h.logger.ErrorContext(ctx, "Error reading the messages the channel got since its link cursor", "channel", channel, "cursor", cursor, "error", err)
+			continue
+		}
+		for _, msg := range messages {
+			groupIDs := h.missedGroupIDs(ctx, channel, msg, known)
+			if len(groupIDs) == 0 {
+				continue
+			}
+			h.logger.InfoContext(ctx, "Offering to track the orders shared while Bolt was down", "channel", channel, "group_ids", groupIDs)
+			offered += len(groupIDs)
+			go func(channel string, msg HistoryMessage, groupIDs []string) {
+				if err := h.offerMissedOrders(channel, msg, groupIDs); err != nil {
+					h.logger.Error("Error offering to track the missed orders", "channel", channel, "group_ids", groupIDs, "error", err)
+				}
+			}(channel, msg, groupIDs)
+		}
+	}
+	return offered, nil
+}
+
+// knownGroupIDs returns the orders which are tracked, including the ones being resumed
+func (h *Service) knownGroupIDs(ctx context.Context) (map[string]bool, error) {
+	known := make(map[string]bool)
+	if store := h.trackingStore(); store != nil {
+		trackedOrders, err := store.ListTrackedOrders(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list tracked orders: %w", err)
+		}
+		for _, tracked := range trackedOrders {
+			known[tracked.GroupID] = true
+		}
+	}
+	return known, nil
+}
+
+// missedGroupIDs returns the orders of the message which Bolt doesn't know, and which weren't shared for personal orders
+func (h *Service) missedGroupIDs(ctx context.Context, channel string, msg HistoryMessage, known map[string]bool) []string {
+	if h.isPersonalOrderLink(channel, msg.SentAt) {
+		return nil
+	}
+	var missed []string
+	for _, group := range h.groupLinks(ctx, msg.Links) {
+		groupID := group.groupID
+		if known[groupID] {
+			continue
+		}
+		known[groupID] = true
+		if _, ok := h.LookupActiveOrder(groupID); ok || h.storedOrder(ctx, groupID) != nil {
+			continue
+		}
+		missed = append(missed, groupID)
+	}
+	return missed
+}
+
+// offerMissedOrders asks in the thread of the link message whether to track its missed orders, and tracks them once confirmed.
+// Wolt tells whether a group order is still open only to its participants, so the people in the channel are the ones to tell.
+func (h *Service) offerMissedOrders(channel string, msg HistoryMessage, groupIDs []string) error {
+	emoji := h.channelEmoji(channel, settingBlacklistConfirmEmoji, h.cfg.BlacklistConfirmationEmoji)
+	offerID, err := h.informEvent(channel, h.text(channel, msgMissedOrderOffer, emoji), "", msg.MessageID)
+	if err != nil {
+		return fmt.Errorf("inform missed order: %w", err)
+	}
+	confirmed := h.blacklistConfirmations.add(channel, offerID)
+	defer h.blacklistConfirmations.remove(channel, offerID)
+
+	select {
+	case <-confirmed:
+	case <-time.After(h.cfg.BlacklistConfirmationTimeout):
+		return nil
+	}
+
+	links := make([]Link, len(groupIDs))
+	for i, groupID := range groupIDs {
+		links[i] = Link{Domain: "wolt.com", URL: fmt.Sprintf("https://wolt.com/group/%s", groupID)}
+	}
+	response, err := h.handleLinks(LinksRequest{Links: links, MessageID: msg.MessageID, Channel: channel, Text: msg.Text})
+	if response != "" {
+		if _, informErr := h.informEvent(channel, response, "", msg.MessageID); informErr != nil {
+			h.logger.Error("Error informing the response to missed orders", "channel", channel, "group_ids", groupIDs, "error", informErr)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("track orders: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oriser/bolt/order"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeLinkCursorStore struct {
+	fakeOrderStore
+	cursors map[string]string
+}
+
+func (f *fakeLinkCursorStore) SetLinkCursor(_ context.Context, channel, messageID string, _ time.Time) error {
+	f.cursors[channel] = messageID
+	return nil
+}
+
+func (f *fakeLinkCursorStore) ListLinkCursors(context.Context) (map[string]string, error) {
+	return f.cursors, nil
+}
+
+type missedMessagesNotification struct {
+	recordingNotification
+	history map[string][]HistoryMessage
+}
+
+func (n *missedMessagesNotification) MessagesSince(channel, messageID string, _ time.Time) ([]HistoryMessage, error) {
+	var messages []HistoryMessage
+	for _, msg := range n.history[channel] {
+		if msg.MessageID > messageID {
+			messages = append(messages, msg)
+		}
+	}
+	return messages, nil
+}
+
+func TestCatchUpMissedLinks(t *testing.T) {
+	t.Parallel()
+
+	store := &fakeLinkCursorStore{
+		fakeOrderStore: fakeOrderStore{orders: []*order.Order{{OriginalID: "STORED"}}},
+		cursors:        map[string]string{},
+	}
+	link := func(groupID string) Link {
+		return Link{Domain: "wolt.com", URL: "https://wolt.com/en/group/" + groupID}
+	}
+	notification := &missedMessagesNotification{history: map[string][]HistoryMessage{
+		"C1": {
+			{MessageID: "1.1", Links: []Link{link("OLD")}, SentAt: time.Now().Add(-time.Hour)},
+			{MessageID: "1.2", Links: []Link{link("MISSED"), link("STORED")}, SentAt: time.Now().Add(-time.Minute)},
+			{MessageID: "1.3", Links: []Link{link("MISSED")}, SentAt: time.Now()},
+			{MessageID: "1.4", Text: "no links", SentAt: time.Now()},
+		},
+		"C2": {
+			{MessageID: "2.1", Links: []Link{link("SOCIAL")}, SentAt: time.Now()},
+		},
+	}}
+	h, err := New(Config{MissedLinksLookback: time.Hour, SocialChannels: []string{"C2"},
+		BlacklistConfirmationTimeout: time.Millisecond},
+		&fakeTreasuryStore{}, nil, store, "UBOT", notification)
+	require.NoError(t, err)
+
+	offered, err := h.CatchUpMissedLinks(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, offered, "channels without handled links aren't caught up with")
+
+	_, err = h.HandleLinkMessage(LinksRequest{Channel: "C1", MessageID: "1.1"})
+	require.NoError(t, err)
+	_, err = h.HandleLinkMessage(LinksRequest{Channel: "C2", MessageID: "2.0"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"C1": "1.1", "C2": "2.0"}, store.cursors)
+
+	offered, err = h.CatchUpMissedLinks(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, offered, "only the orders Bolt doesn't know are offered once, and not the ones of social channels")
+
+	disabled, err := New(Config{}, &fakeTreasuryStore{}, nil, store, "UBOT", notification)
+	require.NoError(t, err)
+	_, err = disabled.HandleLinkMessage(LinksRequest{Channel: "C1", MessageID: "1.4"})
+	require.NoError(t, err)
+	assert.Equal(t, "1.1", store.cursors["C1"], "the cursors aren't moved while catching up is disabled")
+}
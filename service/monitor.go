@@ -3,7 +3,6 @@ package service
 import (
 	"context"
 	"fmt"
-	"log"
 	"strings"
 	"time"
 )
@@ -22,8 +21,8 @@ func (h *Service) buildClosedVenueMessage(offlinePeriodEnd time.Time, timezone *
 		if !IsToday(offlinePeriodEnd, timezone) {
 			timeFormatString = "{date_num} {time}"
 		}
-		offlinePeriodEndString := fmt.Sprintf("<!date^%d^%s|%s>", offlinePeriodEnd.Unix(), timeFormatString, offlinePeriodEnd.In(timezone).Format("2006-01-02 15:04"))
-		sb.WriteString(fmt.Sprintf(" (allegedly until %s)", offlinePeriodEndString))
+		offlinePeriodEndString := SlackDate(offlinePeriodEnd, timeFormatString, "2006-01-02 15:04", timezone)
+		sb.WriteString(fmt.Sprintf(" (allegedly until %s, %s)", offlinePeriodEndString, RelativeTime(offlinePeriodEnd, time.Now())))
 	}
 
 	sb.WriteString(" – I'll let you know when it comes back")
@@ -32,12 +31,14 @@ func (h *Service) buildClosedVenueMessage(offlinePeriodEnd time.Time, timezone *
 }
 
 func (h *Service) monitorVenue(ctx context.Context, order *groupOrder, receiver, initialMessageID string) {
+	defer h.trackMonitor(ctx, monitorKindVenue, order.id)()
 	details, err := order.Details()
 	if err != nil {
-		log.Printf("Error getting details for order %q: %v\n", order.id, err)
+		h.logger.ErrorContext(order.ctx, "Error getting order details", "error", err)
 		return
 	}
 
+	venueID := details.VenueID
 	waitingToOpenDeliveries := false
 	var lastOfflinePeriodEnd time.Time
 	var venueClosedMessageId string
@@ -49,24 +50,69 @@ func (h *Service) monitorVenue(ctx context.Context, order *groupOrder, receiver,
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			venue, err := order.woltGroup.VenueDetails(details)
+			// The details are refreshed while waiting for the group to finish, so the host switching the venue shows up here
+			if details, err = order.Details(); err != nil {
+				h.logger.ErrorContext(order.ctx, "Error getting order details", "error", err)
+				continue
+			}
+			venue, err := order.group.VenueDetails(ctx, details)
 			if err != nil {
-				log.Printf("Error getting venue for order %q: %v\n", order.id, err)
+				h.logger.ErrorContext(order.ctx, "Error getting venue", "error", err)
 				continue
 			}
+			if details.VenueID != venueID {
+				venueID = details.VenueID
+				h.handleVenueSwitch(ctx, order, venue, receiver, initialMessageID)
+				// The closed message was about the previous venue
+				waitingToOpenDeliveries = false
+			}
+
+			// Only recorded for the stats, as the channel was already warned if the venue was busy when joining
+			order.noteSurge(venue)
 
-			isOpenForPreorderDelivery := venue.IsOpenForPreorderDelivery()
-			if waitingToOpenDeliveries && venue.IsDelivering() {
-				_, _ = h.informEvent(receiver, ":large_green_circle: Venue is now open for delivery", "", initialMessageID)
+			isOpenForPreorderDelivery := venue.Preorders
+			if waitingToOpenDeliveries && venue.Delivering {
+				_, _ = h.informEvent(receiver, h.text(receiver, msgVenueOpen), "", initialMessageID)
 				waitingToOpenDeliveries = false
-			} else if !waitingToOpenDeliveries && !venue.IsDelivering() {
-				venueClosedMessageId, _ = h.informEvent(receiver, h.buildClosedVenueMessage(venue.OfflinePeriodEnd, venue.TimezoneLocation, isOpenForPreorderDelivery), "", initialMessageID)
+			} else if !waitingToOpenDeliveries && !venue.Delivering {
+				venueClosedMessageId, _ = h.informEditableEvent(ctx, receiver, h.buildClosedVenueMessage(venue.OfflineUntil, h.timezoneForChannel(receiver, venue.Timezone), isOpenForPreorderDelivery), "", initialMessageID)
 				waitingToOpenDeliveries = true
-				lastOfflinePeriodEnd = venue.OfflinePeriodEnd
-			} else if waitingToOpenDeliveries && lastOfflinePeriodEnd != venue.OfflinePeriodEnd {
-				_ = h.eventNotification.EditMessage(receiver, h.buildClosedVenueMessage(venue.OfflinePeriodEnd, venue.TimezoneLocation, isOpenForPreorderDelivery), venueClosedMessageId)
-				lastOfflinePeriodEnd = venue.OfflinePeriodEnd
+				lastOfflinePeriodEnd = venue.OfflineUntil
+			} else if waitingToOpenDeliveries && lastOfflinePeriodEnd != venue.OfflineUntil {
+				_ = h.editEvent(receiver, h.buildClosedVenueMessage(venue.OfflineUntil, h.timezoneForChannel(receiver, venue.Timezone), isOpenForPreorderDelivery), venueClosedMessageId)
+				lastOfflinePeriodEnd = venue.OfflineUntil
 			}
 		}
 	}
 }
+
+func (h *Service) buildVenueSwitchMessage(venue *Venue, deliveryRate int, deliveryRateErr error) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(":twisted_rightwards_arrows: The order was switched to %s", venue.Name))
+	if deliveryRateErr == nil {
+		sb.WriteString(fmt.Sprintf(", the delivery rate is now %d %s", deliveryRate, CurrencyUnit(h.venueCurrency(venue.Currency))))
+	}
+	if minimum := venue.MinimumOrder; minimum > 0 {
+		sb.WriteString(fmt.Sprintf(" and the minimum order is %.2f", minimum))
+	}
+	return sb.String()
+}
+
+// handleVenueSwitch refreshes the venue of an order after the host switched it to another venue (like another branch of a chain,
+// if the original one closed), and updates the order's messages
+func (h *Service) handleVenueSwitch(ctx context.Context, order *groupOrder, venue *Venue, receiver, initialMessageID string) {
+	h.logger.InfoContext(order.ctx, "Order was switched to another venue", "venue", venue.Name)
+	order.switchVenue(venue)
+	deliveryRate, err := order.CalculateDeliveryRate()
+	if err != nil {
+		h.logger.ErrorContext(order.ctx, "Error calculating the delivery rate after switching venue", "error", err)
+	}
+
+	_, _ = h.informEvent(receiver, h.buildVenueSwitchMessage(venue, deliveryRate, err), "", initialMessageID)
+	if order.joinedMessageID != "" {
+		if err := h.editEvent(receiver, h.text(receiver, msgJoinedOrder, venue.Name), order.joinedMessageID); err != nil {
+			h.logger.ErrorContext(order.ctx, "Error editing the joined message", "error", err)
+		}
+	}
+	h.hooks.Emit(ctx, Event{Type: EventVenueChanged, OrderID: order.id, Channel: receiver, MessageID: initialMessageID, VenueName: venue.Name})
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleVenueSwitch(t *testing.T) {
+	t.Parallel()
+
+	notification := &editingNotification{}
+	h, err := New(Config{}, nil, nil, nil, "UBOT", notification)
+	require.NoError(t, err)
+	switched := make([]Event, 0)
+	h.hooks.Subscribe(func(_ context.Context, event Event) {
+		switched = append(switched, event)
+	}, EventVenueChanged)
+
+	details := &OrderDetails{VenueID: "branch-2"}
+	previous := &Venue{Name: "Burgers North"}
+	order := &groupOrder{id: "A", details: details, venue: previous, deliveryPrice: 20, joinedMessageID: "2.1"}
+
+	venue := &Venue{Name: "Burgers South", DeliveryRate: 15, MinimumOrder: 60}
+	h.handleVenueSwitch(context.Background(), order, venue, "C1", "1.1")
+
+	current, err := order.Venue()
+	require.NoError(t, err)
+	assert.Equal(t, venue, current)
+	deliveryRate, err := order.CalculateDeliveryRate()
+	require.NoError(t, err)
+	assert.Equal(t, 15, deliveryRate, "the delivery rate is of the new venue")
+
+	assert.Equal(t, []string{"C1: :twisted_rightwards_arrows: The order was switched to Burgers South, the delivery rate is now 15 NIS and the minimum order is 60.00"},
+		notification.messages)
+	assert.Equal(t, []string{"C1/2.1: Hi 👋, I've joined the order from [Burgers South]"}, notification.edits)
+	require.Len(t, switched, 1)
+	assert.Equal(t, "Burgers South", switched[0].VenueName)
+}
+
+func TestNoteSurge(t *testing.T) {
+	t.Parallel()
+
+	order := &groupOrder{id: "A"}
+	venue := &Venue{Name: "Burgers"}
+	assert.False(t, order.noteSurge(venue))
+	assert.False(t, order.hadSurge())
+
+	venue.Busy = true
+	assert.True(t, order.noteSurge(venue), "the venue became busy")
+	assert.False(t, order.noteSurge(venue), "the venue was already busy")
+
+	venue.Busy = false
+	order.noteSurge(venue)
+	assert.True(t, order.hadSurge(), "the surge is kept after the venue isn't busy anymore")
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// shortOrderIDLength is the length of the prefix of the Wolt group ID in the label of the order
+const shortOrderIDLength = 6
+
+// orderMessages keeps the order of each rates message, so reacting to a rates message applies to its own order even when the
+// channel has several orders going on at once. Messages of orders Bolt doesn't know about, like after a restart, are still matched
+// by the order ID in their text.
+type orderMessages struct {
+	lock   sync.Mutex
+	orders map[string]string // Order ID by the channel and the message ID
+}
+
+func newOrderMessages() *orderMessages {
+	return &orderMessages{orders: make(map[string]string)}
+}
+
+func (o *orderMessages) add(channel, messageID, orderID string) {
+	if messageID == "" {
+		return
+	}
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.orders[skipKey(channel, messageID)] = orderID
+}
+
+func (o *orderMessages) lookup(channel, messageID string) (string, bool) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	orderID, ok := o.orders[skipKey(channel, messageID)]
+	return orderID, ok
+}
+
+// onEvent forgets the messages of the orders with nothing left to pay
+func (o *orderMessages) onEvent(_ context.Context, event Event) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	for key, orderID := range o.orders {
+		if orderID == event.OrderID {
+			delete(o.orders, key)
+		}
+	}
+}
+
+// shortOrderID returns the prefix of the Wolt group ID shown in the label of the order
+func shortOrderID(orderID string) string {
+	if len(orderID) <= shortOrderIDLength {
+		return orderID
+	}
+	return orderID[:shortOrderIDLength]
+}
+
+// orderLabel returns the label of the order whose thread is under the given message, when the channel has more than one order going
+// on so its messages can be told apart. It's empty otherwise.
+func (h *Service) orderLabel(channel, messageID string) string {
+	if messageID == "" {
+		return ""
+	}
+	var (
+		label  string
+		orders int
+	)
+	for _, activeOrder := range h.ActiveOrders() {
+		if activeOrder.Channel != channel {
+			continue
+		}
+		orders++
+		if activeOrder.MessageID == messageID {
+			label = fmt.Sprintf("[%s · %s] ", activeOrder.VenueName, shortOrderID(activeOrder.ID))
+			if activeOrder.VenueName == "" {
+				label = fmt.Sprintf("[%s] ", shortOrderID(activeOrder.ID))
+			}
+		}
+	}
+	if orders < 2 {
+		return ""
+	}
+	return label
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderLabel(t *testing.T) {
+	t.Parallel()
+
+	notification := &recordingNotification{}
+	h, err := New(Config{}, nil, nil, nil, "UBOT", notification)
+	require.NoError(t, err)
+	ctx := context.Background()
+	h.hooks.Emit(ctx, Event{Type: EventOrderJoined, OrderID: "ABCDEFGH", Channel: "C1", MessageID: "1.1", VenueName: "Pizza"})
+	h.hooks.Emit(ctx, Event{Type: EventOrderJoined, OrderID: "OTHER", Channel: "C2", MessageID: "2.1", VenueName: "Pizza"})
+
+	_, _ = h.informEvent("C1", "Joined", "", "1.1")
+	assert.Equal(t, []string{"C1: Joined"}, notification.messages, "the order is the only one in its channel")
+
+	h.hooks.Emit(ctx, Event{Type: EventOrderJoined, OrderID: "XYZ123", Channel: "C1", MessageID: "1.2"})
+	_, _ = h.informEvent("C1", "Delivered", "", "1.1")
+	_, _ = h.informEvent("C1", "Ready", "", "1.2")
+	_, _ = h.informEvent("C1", "Not an order", "", "")
+	assert.Equal(t, []string{"C1: Joined", "C1: [Pizza · ABCDEF] Delivered", "C1: [XYZ123] Ready", "C1: Not an order"}, notification.messages)
+
+	h.hooks.Emit(ctx, Event{Type: EventOrderDelivered, OrderID: "ABCDEFGH"})
+	assert.Empty(t, h.orderLabel("C1", "1.2"), "the other order ended")
+}
+
+func TestReactionToRatesMessageOfOrder(t *testing.T) {
+	t.Parallel()
+
+	store := &fakeTreasuryStore{
+		users: map[string]*userDomain.User{
+			"uuid-host": {ID: "uuid-host", FullName: "Thor", TransportID: "U-host"},
+			"uuid-loki": {ID: "uuid-loki", FullName: "Loki", TransportID: "U-loki"},
+		},
+		debts: []*debtDomain.Debt{
+			{ID: "d1", BorrowerID: "uuid-loki", LenderID: "uuid-host", OrderID: "FIRST", Amount: 30, InitiatedTransportID: "C1"},
+			{ID: "d2", BorrowerID: "uuid-loki", LenderID: "uuid-host", OrderID: "SECOND", Amount: 20, InitiatedTransportID: "C1"},
+		},
+	}
+	h, err := New(Config{}, store, store, nil, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+	h.orderMessages.add("C1", "r-2", "SECOND")
+
+	// The text of the message is of the first order, like a rates message quoting it
+	_, err = h.HandleReactionAdded(ReactionAddRequest{Reaction: MarkAsPaidReaction, FromUserID: "U-loki", Channel: "C1",
+		MessageUserID: "UBOT", MessageID: "r-2", MessageText: "Wolt order ID FIRST."})
+	require.NoError(t, err)
+	require.Len(t, store.debts, 1)
+	assert.Equal(t, "FIRST", store.debts[0].OrderID, "the reaction applies to the order of the rates message")
+
+	h.orderMessages.onEvent(context.Background(), Event{Type: EventOrderSettled, OrderID: "SECOND"})
+	_, ok := h.orderMessages.lookup("C1", "r-2")
+	assert.False(t, ok)
+
+	_, err = h.HandleReactionAdded(ReactionAddRequest{Reaction: MarkAsPaidReaction, FromUserID: "U-loki", Channel: "C1",
+		MessageUserID: "UBOT", MessageID: "r-1", MessageText: "Wolt order ID FIRST."})
+	require.NoError(t, err)
+	assert.Empty(t, store.debts, "messages of unknown orders are matched by their text")
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	"github.com/oriser/bolt/order"
+	userDomain "github.com/oriser/bolt/user"
+)
+
+// UserData is everything Bolt stores about a user, for privacy requests
+type UserData struct {
+	TransportID        string                    `json:"transport_id"`
+	ExportedAt         time.Time                 `json:"exported_at"`
+	Users              []*userDomain.User        `json:"users"` // Custom users added with /add-user for other Wolt names are aliases of the user
+	Debts              []*debtDomain.Debt        `json:"debts"` // Outstanding debts the user is the borrower or the lender of
+	Payments           []*debtDomain.Payment     `json:"payments"`
+	PendingDebts       []*debtDomain.PendingDebt `json:"pending_debts"` // Debts of participants with the user's names who weren't matched yet
+	Orders             []OrderParticipation      `json:"orders"`
+	AbroadCurrency     string                    `json:"abroad_currency,omitempty"`
+	InsightsSubscribed bool                      `json:"insights_subscribed"`
+	BankAccount        *userDomain.BankAccount   `json:"bank_account,omitempty"`
+	PaymentMethods     []string                  `json:"payment_methods"` // The payment methods the user registered, in the order they prefer them
+	RemindersOptedOut  bool                      `json:"reminders_opted_out"`
+	DigestHour         *int                      `json:"digest_hour,omitempty"` // The hour of the user's daily digest, nil if they get the notifications right away
+}
+
+// OrderParticipation is an order the user hosted or participated in
+type OrderParticipation struct {
+	OrderID   string    `json:"order_id"` // The Wolt group ID
+	CreatedAt time.Time `json:"created_at"`
+	Channel   string    `json:"channel"`
+	VenueName string    `json:"venue_name"`
+	Host      bool      `json:"host"`
+	Name      string    `json:"name"` // The Wolt name the user participated with
+	Amount    float64   `json:"amount"`
+}
+
+// UserData returns everything stored about the user with the given transport ID
+func (h *Service) UserData(ctx context.Context, transportID string) (*UserData, error) {
+	data := &UserData{
+		TransportID:    transportID,
+		ExportedAt:     time.Now().UTC(),
+		Users:          []*userDomain.User{},
+		Debts:          []*debtDomain.Debt{},
+		Payments:       []*debtDomain.Payment{},
+		PendingDebts:   []*debtDomain.PendingDebt{},
+		Orders:         []OrderParticipation{},
+		PaymentMethods: []string{},
+	}
+
+	if h.userStore != nil {
+		users, err := h.userStore.ListUsers(ctx, userDomain.ListFilter{TransportID: transportID})
+		if err != nil {
+			return nil, fmt.Errorf("list users: %w", err)
+		}
+		data.Users = users
+	}
+	userIDs := map[string]bool{transportID: true}
+	names := make(map[string]bool, len(data.Users))
+	for _, u := range data.Users {
+		userIDs[u.ID] = true
+		names[u.FullName] = true
+	}
+
+	if err := h.addUserSettingsData(ctx, data); err != nil {
+		return nil, err
+	}
+	if err := h.addUserDebtsData(ctx, data, userIDs, names); err != nil {
+		return nil, err
+	}
+
+	if h.orderStore != nil {
+		orders, err := h.orderStore.ListOrders(ctx, order.ListFilter{})
+		if err != nil {
+			return nil, fmt.Errorf("list orders: %w", err)
+		}
+		for _, o := range orders {
+			for _, p := range o.Participants {
+				if p.ID == "" || !userIDs[p.ID] {
+					continue
+				}
+				data.Orders = append(data.Orders, OrderParticipation{
+					OrderID:   o.OriginalID,
+					CreatedAt: o.CreatedAt,
+					Channel:   o.Receiver,
+					VenueName: o.VenueName,
+					Host:      p.Name == o.Host,
+					Name:      p.Name,
+					Amount:    p.Amount,
+				})
+			}
+		}
+		if insightsStore, ok := h.orderStore.(order.InsightsStore); ok {
+			subscribers, err := insightsStore.ListInsightsSubscribers(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("list insights subscribers: %w", err)
+			}
+			for _, subscriber := range subscribers {
+				if subscriber == transportID {
+					data.InsightsSubscribed = true
+				}
+			}
+		}
+	}
+	return data, nil
+}
+
+// addUserSettingsData adds the payment details the user registered, which the user store keeps
+func (h *Service) addUserSettingsData(ctx context.Context, data *UserData) error {
+	if bankAccountStore, ok := h.userStore.(userDomain.BankAccountStore); ok {
+		account, err := bankAccountStore.BankAccount(ctx, data.TransportID)
+		if err != nil {
+			return fmt.Errorf("get bank account: %w", err)
+		}
+		data.BankAccount = account
+	}
+	if paymentMethodsStore, ok := h.userStore.(userDomain.PaymentMethodsStore); ok {
+		methods, err := paymentMethodsStore.PaymentMethods(ctx, data.TransportID)
+		if err != nil {
+			return fmt.Errorf("get payment methods: %w", err)
+		}
+		for _, method := range methods {
+			data.PaymentMethods = append(data.PaymentMethods, method.String())
+		}
+	}
+	return nil
+}
+
+func (h *Service) addUserDebtsData(ctx context.Context, data *UserData, userIDs, names map[string]bool) error {
+	if h.debtStore == nil {
+		return nil
+	}
+
+	ids := sortedSet(userIDs)
+	debts, err := h.debtStore.ListDebts(ctx, debtDomain.ListFilter{UserIDs: ids})
+	if err != nil {
+		return fmt.Errorf("list debts: %w", err)
+	}
+	data.Debts = debts
+
+	if paymentStore, ok := h.debtStore.(debtDomain.PaymentStore); ok {
+		for _, id := range ids {
+			payments, err := paymentStore.ListPayments(ctx, debtDomain.PaymentListFilter{BorrowerID: id})
+			if err != nil {
+				return fmt.Errorf("list payments: %w", err)
+			}
+			data.Payments = append(data.Payments, payments...)
+		}
+	}
+	if pendingStore, ok := h.debtStore.(debtDomain.PendingStore); ok {
+		for _, name := range sortedSet(names) {
+			pending, err := pendingStore.ListPendingDebts(ctx, name)
+			if err != nil {
+				return fmt.Errorf("list pending debts: %w", err)
+			}
+			data.PendingDebts = append(data.PendingDebts, pending...)
+		}
+	}
+	if abroadStore, ok := h.debtStore.(debtDomain.AbroadStore); ok {
+		if data.AbroadCurrency, err = abroadStore.AbroadCurrency(ctx, data.TransportID); err != nil {
+			return fmt.Errorf("get abroad currency: %w", err)
+		}
+	}
+	if reminderStore, ok := h.debtStore.(debtDomain.ReminderStore); ok {
+		if data.RemindersOptedOut, err = reminderStore.RemindersOptedOut(ctx, data.TransportID); err != nil {
+			return fmt.Errorf("get reminders opt-out: %w", err)
+		}
+	}
+	if digestStore, ok := h.debtStore.(debtDomain.DigestStore); ok {
+		hour, err := digestStore.DigestHour(ctx, data.TransportID)
+		if err != nil {
+			return fmt.Errorf("get digest hour: %w", err)
+		}
+		if hour >= 0 {
+			data.DigestHour = &hour
+		}
+	}
+	return nil
+}
+
+func sortedSet(set map[string]bool) []string {
+	ret := make([]string, 0, len(set))
+	for key := range set {
+		ret = append(ret, key)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// WriteJSON writes all the data as JSON
+func (d *UserData) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(d); err != nil {
+		return fmt.Errorf("encode user data: %w", err)
+	}
+	return nil
+}
+
+// WriteCSV writes the debts, payments and orders of the user as CSV, one row per record
+func (d *UserData) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"record", "id", "created_at", "order_id", "channel", "venue", "borrower_id", "lender_id", "amount"}); err != nil {
+		return fmt.Errorf("write CSV header: %w", err)
+	}
+	rows := make([][]string, 0, len(d.Debts)+len(d.Payments)+len(d.PendingDebts)+len(d.Orders))
+	for _, debt := range d.Debts {
+		rows = append(rows, []string{"debt", debt.ID, debt.CreatedAt.Format(time.RFC3339), debt.OrderID, debt.InitiatedTransportID, "",
+			debt.BorrowerID, debt.LenderID, strconv.FormatFloat(debt.Amount, 'f', 2, 64)})
+	}
+	for _, payment := range d.Payments {
+		rows = append(rows, []string{"payment", payment.ID, payment.PaidAt.Format(time.RFC3339), payment.OrderID, payment.InitiatedTransportID, "",
+			payment.BorrowerID, payment.LenderID, strconv.FormatFloat(payment.Amount, 'f', 2, 64)})
+	}
+	for _, pending := range d.PendingDebts {
+		rows = append(rows, []string{"pending_debt", pending.ID, pending.CreatedAt.Format(time.RFC3339), pending.OrderID, pending.InitiatedTransportID, "",
+			pending.WoltName, pending.LenderID, strconv.FormatFloat(pending.Amount, 'f', 2, 64)})
+	}
+	for _, o := range d.Orders {
+		record := "order"
+		if o.Host {
+			record = "hosted_order"
+		}
+		rows = append(rows, []string{record, "", o.CreatedAt.Format(time.RFC3339), o.OrderID, o.Channel, o.VenueName, "", "",
+			strconv.FormatFloat(o.Amount, 'f', 2, 64)})
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		return fmt.Errorf("write CSV rows: %w", err)
+	}
+	return nil
+}
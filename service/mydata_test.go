@@ -0,0 +1,142 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	"github.com/oriser/bolt/order"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserData(t *testing.T) {
+	t.Parallel()
+
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	store := &fakeTreasuryStore{
+		debts: []*debtDomain.Debt{
+			{ID: "aaaa-1", BorrowerID: "U1", LenderID: "U2", OrderID: "A", Amount: 10, InitiatedTransportID: "C1", CreatedAt: createdAt},
+			{ID: "aaaa-2", BorrowerID: "U3", LenderID: "U2", OrderID: "A", Amount: 20, InitiatedTransportID: "C1", CreatedAt: createdAt},
+			{ID: "bbbb-1", BorrowerID: "U2", LenderID: "custom-1", OrderID: "B", Amount: 5, InitiatedTransportID: "C2", CreatedAt: createdAt},
+		},
+		users: map[string]*userDomain.User{
+			"U1":       {ID: "U1", FullName: "Loki", TransportID: "U1"},
+			"custom-1": {ID: "custom-1", FullName: "Loki Laufeyson", TransportID: "U1"},
+			"U2":       {ID: "U2", FullName: "Thor", TransportID: "U2"},
+		},
+		payments: []*debtDomain.Payment{
+			{Debt: debtDomain.Debt{ID: "cccc-1", BorrowerID: "U1", LenderID: "U2", OrderID: "C", Amount: 30, InitiatedTransportID: "C1"}, PaidAt: createdAt},
+			{Debt: debtDomain.Debt{ID: "cccc-2", BorrowerID: "U3", LenderID: "U2", OrderID: "C", Amount: 30, InitiatedTransportID: "C1"}, PaidAt: createdAt},
+		},
+		pending: []*debtDomain.PendingDebt{{ID: "pending-0", WoltName: "loki laufeyson", LenderID: "U2", OrderID: "D", Amount: 7, CreatedAt: createdAt}},
+	}
+	h := &Service{
+		logger:    slog.Default(),
+		debtStore: store,
+		userStore: store,
+		orderStore: &fakeOrderStore{orders: []*order.Order{
+			{OriginalID: "A", CreatedAt: createdAt, Receiver: "C1", VenueName: "Pizza", Host: "Thor",
+				Participants: []order.Participant{{Name: "Loki", ID: "U1", Amount: 10}, {Name: "Thor", ID: "U2", Amount: 15}}},
+			{OriginalID: "B", CreatedAt: createdAt, Receiver: "C2", VenueName: "Sushi", Host: "Loki Laufeyson",
+				Participants: []order.Participant{{Name: "Loki Laufeyson", ID: "custom-1", Amount: 12}, {Name: "Thor", ID: "U2", Amount: 5}}},
+			{OriginalID: "E", CreatedAt: createdAt, Receiver: "C1", VenueName: "Burger", Host: "Thor",
+				Participants: []order.Participant{{Name: "Thor", ID: "U2", Amount: 40}}},
+		}},
+	}
+
+	data, err := h.UserData(context.Background(), "U1")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []*userDomain.User{store.users["U1"], store.users["custom-1"]}, data.Users, "the custom users of the user are aliases of them")
+	assert.Equal(t, []*debtDomain.Debt{store.debts[0], store.debts[2]}, data.Debts)
+	assert.Equal(t, []*debtDomain.Payment{store.payments[0]}, data.Payments)
+	assert.Equal(t, store.pending, data.PendingDebts)
+	assert.Equal(t, []OrderParticipation{
+		{OrderID: "A", CreatedAt: createdAt, Channel: "C1", VenueName: "Pizza", Name: "Loki", Amount: 10},
+		{OrderID: "B", CreatedAt: createdAt, Channel: "C2", VenueName: "Sushi", Host: true, Name: "Loki Laufeyson", Amount: 12},
+	}, data.Orders)
+
+	var jsonData bytes.Buffer
+	require.NoError(t, data.WriteJSON(&jsonData))
+	decoded := &UserData{}
+	require.NoError(t, json.Unmarshal(jsonData.Bytes(), decoded))
+	assert.Len(t, decoded.Orders, 2)
+
+	var csvData strings.Builder
+	require.NoError(t, data.WriteCSV(&csvData))
+	assert.Equal(t, "record,id,created_at,order_id,channel,venue,borrower_id,lender_id,amount\n"+
+		"debt,aaaa-1,2024-05-01T12:00:00Z,A,C1,,U1,U2,10.00\n"+
+		"debt,bbbb-1,2024-05-01T12:00:00Z,B,C2,,U2,custom-1,5.00\n"+
+		"payment,cccc-1,2024-05-01T12:00:00Z,C,C1,,U1,U2,30.00\n"+
+		"pending_debt,pending-0,2024-05-01T12:00:00Z,D,,,loki laufeyson,U2,7.00\n"+
+		"order,,2024-05-01T12:00:00Z,A,C1,Pizza,,,10.00\n"+
+		"hosted_order,,2024-05-01T12:00:00Z,B,C2,Sushi,,,12.00\n", csvData.String())
+}
+
+type fakeUserSettingsStore struct {
+	*fakeBankAccountStore
+	methods map[string][]userDomain.PaymentMethod
+}
+
+func (f *fakeUserSettingsStore) SetPaymentMethods(_ context.Context, transportID string, methods []userDomain.PaymentMethod) error {
+	f.methods[transportID] = methods
+	return nil
+}
+
+func (f *fakeUserSettingsStore) PaymentMethods(_ context.Context, transportID string) ([]userDomain.PaymentMethod, error) {
+	return f.methods[transportID], nil
+}
+
+func TestUserDataPaymentDetails(t *testing.T) {
+	t.Parallel()
+
+	account := &userDomain.BankAccount{Holder: "Loki Laufeyson", IBAN: "GB82WEST12345698765432"}
+	store := &fakeUserSettingsStore{
+		fakeBankAccountStore: &fakeBankAccountStore{fakeTreasuryStore: &fakeTreasuryStore{}, accounts: map[string]*userDomain.BankAccount{"U1": account}},
+		methods:              map[string][]userDomain.PaymentMethod{"U1": {userDomain.PaymentMethodBankTransfer, userDomain.PaymentMethodBit}},
+	}
+	h := &Service{logger: slog.Default(), userStore: store}
+
+	data, err := h.UserData(context.Background(), "U1")
+	require.NoError(t, err)
+	assert.Equal(t, account, data.BankAccount)
+	assert.Equal(t, []string{"Bank transfer", "Bit"}, data.PaymentMethods)
+
+	data, err = h.UserData(context.Background(), "U2")
+	require.NoError(t, err)
+	assert.Nil(t, data.BankAccount)
+	assert.Empty(t, data.PaymentMethods)
+}
+
+func TestUserDataRemindersOptOut(t *testing.T) {
+	t.Parallel()
+
+	h := &Service{logger: slog.Default(), debtStore: &fakeReminderStore{optOuts: map[string]bool{"U1": true}}}
+	data, err := h.UserData(context.Background(), "U1")
+	require.NoError(t, err)
+	assert.True(t, data.RemindersOptedOut)
+
+	data, err = h.UserData(context.Background(), "U2")
+	require.NoError(t, err)
+	assert.False(t, data.RemindersOptedOut)
+}
+
+func TestUserDataDigestHour(t *testing.T) {
+	t.Parallel()
+
+	h := &Service{logger: slog.Default(), debtStore: &fakeDigestStore{hours: map[string]int{"U1": 9}}}
+	data, err := h.UserData(context.Background(), "U1")
+	require.NoError(t, err)
+	require.NotNil(t, data.DigestHour)
+	assert.Equal(t, 9, *data.DigestHour)
+
+	data, err = h.UserData(context.Background(), "U2")
+	require.NoError(t, err)
+	assert.Nil(t, data.DigestHour, "the user gets the notifications right away")
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrNotNudgeHost is returned when someone other than the host of an order (or their co-host) nudges its participants
+	ErrNotNudgeHost = errors.New("only the host of the order can nudge its participants")
+	// ErrNobodyToNudge is returned when nudging the participants of an order who all marked themselves as ready
+	ErrNobodyToNudge = errors.New("everyone already marked ready")
+)
+
+// NudgeParticipants mentions the participants of a tracked group order who didn't mark themselves as ready yet, in the thread of the
+// message with the order link, for its host (or their co-host) to hurry them before sending the order. The participants are taken
+// from the latest status poll of the order, and the ones which aren't matched to a user are named by their Wolt name. It returns the
+// channel the nudge was posted in.
+func (h *Service) NudgeParticipants(groupID, fromTransportID string) (string, error) {
+	groupID = h.parseGroupIDOrLink(groupID)
+	order := h.workingOrders.get(groupID)
+	if order == nil {
+		return "", fmt.Errorf("I'm not tracking order %s, post its link in the channel first", groupID)
+	}
+
+	details, err := order.Details()
+	if err != nil {
+		return "", fmt.Errorf("get group details: %w", err)
+	}
+	if details.Status != OrderStatusActive {
+		return "", fmt.Errorf("order %s was already sent", groupID)
+	}
+	hosts, err := h.listUsersByName(details.Host)
+	if err != nil {
+		return "", fmt.Errorf("list users: %w", err)
+	}
+	if len(hosts) != 1 || !h.actsForHost(hosts[0].TransportID, fromTransportID) {
+		return "", ErrNotNudgeHost
+	}
+
+	notReady := details.NotReadyParticipants()
+	if len(notReady) == 0 {
+		return "", ErrNobodyToNudge
+	}
+	mentions := make([]string, len(notReady))
+	for i, name := range notReady {
+		mentions[i] = h.participantMention(name)
+	}
+	if _, err := h.informEvent(order.channel, h.text(order.channel, msgNudge, strings.Join(mentions, ", ")), "", order.messageID); err != nil {
+		return "", fmt.Errorf("post nudge: %w", err)
+	}
+	return order.channel, nil
+}
+
+// participantMention mentions the user matched to the Wolt name, or returns the name if it isn't matched to a single active user
+func (h *Service) participantMention(woltName string) string {
+	users, err := h.listUsersByName(woltName)
+	if err != nil {
+		h.logger.Error("Error getting user from storage", "wolt_name", woltName, "error", err)
+		return woltName
+	}
+	if len(users) != 1 || users[0].Deactivated() || users[0].TransportID == "" {
+		return woltName
+	}
+	return fmt.Sprintf("<@%s>", users[0].TransportID)
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNudgeParticipants(t *testing.T) {
+	t.Parallel()
+
+	details, err := parseWoltOrderDetails([]byte(`{
+		"host_id": "1",
+		"status": "active",
+		"details": {"delivery_info": {"location": {"coordinates": {"coordinates": [34.78, 32.08]}}}},
+		"participants": [
+			{"first_name": "Thor", "user_id": "1", "basket": {"items": [{"name": "Pizza", "end_amount": 5000}]}},
+			{"first_name": "Dana", "user_id": "2", "basket": {"items": [{"name": "Salad", "end_amount": 3000}]}},
+			{"first_name": "Yossi", "user_id": "3", "basket": {"items": [{"name": "Soup", "end_amount": 2000}]}},
+			{"first_name": "Loki", "user_id": "4", "status": "ready", "basket": {"items": [{"name": "Fries", "end_amount": 1000}]}}
+		]
+	}`))
+	require.NoError(t, err)
+
+	store := &fakeTreasuryStore{users: map[string]*userDomain.User{
+		"u1": {ID: "u1", TransportID: "U1", FullName: "Thor"},
+		"u2": {ID: "u2", TransportID: "U2", FullName: "Dana"},
+		"u4": {ID: "u4", TransportID: "U4", FullName: "Loki"},
+	}}
+	notification := &recordingNotification{}
+	h, err := New(Config{}, store, nil, nil, "UBOT", notification)
+	require.NoError(t, err)
+	entry, _, ok := h.workingOrders.start("ABC", time.Now(), 1, nil)
+	require.True(t, ok)
+	order := &groupOrder{id: "ABC", channel: "C1", messageID: "1.1", details: details}
+	h.workingOrders.setOrder(entry, order)
+
+	_, err = h.NudgeParticipants("ABC", "U4")
+	assert.ErrorIs(t, err, ErrNotNudgeHost)
+	require.NoError(t, h.SetCohost("U1", "U4"))
+	channel, err := h.NudgeParticipants("<https://wolt.com/en/group/abc>", "U4")
+	require.NoError(t, err, "the co-host can nudge for the host")
+	assert.Equal(t, "C1", channel)
+	assert.Contains(t, notification.messages, "C1: :bell: Waiting on <@U2>, Yossi, please mark your selection as done in Wolt so the order can be sent",
+		"the participants who aren't matched to a user are named by their Wolt name")
+
+	ready, err := parseWoltOrderDetails([]byte(`{"host_id": "1", "status": "active",
+		"details": {"delivery_info": {"location": {"coordinates": {"coordinates": [34.78, 32.08]}}}},
+		"participants": [{"first_name": "Thor", "user_id": "1", "basket": {"items": [{"name": "Pizza", "end_amount": 5000}]}}]}`))
+	require.NoError(t, err)
+	order.details = ready
+	_, err = h.NudgeParticipants("ABC", "U1")
+	assert.ErrorIs(t, err, ErrNobodyToNudge)
+
+	_, err = h.NudgeParticipants("XYZ", "U1")
+	assert.EqualError(t, err, "I'm not tracking order XYZ, post its link in the channel first")
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"time"
+)
+
+// OrderStatus is the status of a group order: it's active until the host sends it, then it's purchased (or it's canceled)
+type OrderStatus string
+
+const (
+	OrderStatusActive    OrderStatus = "active"
+	OrderStatusPurchased OrderStatus = "purchased"
+	OrderStatusCanceled  OrderStatus = "canceled"
+)
+
+func (s OrderStatus) Purchased() bool {
+	return s == OrderStatusPurchased
+}
+
+// Location is a place on the map, like the address an order is delivered to
+type Location struct {
+	Lat float64
+	Lon float64
+}
+
+// Item is an item in the basket of a participant, or in the bag of a delivery
+type Item struct {
+	Name          string
+	Amount        float64 // What the participant pays for the item, with all of its quantity and discounts
+	Count         int
+	AgeRestricted bool
+	Alcohol       bool
+}
+
+// Quantity returns how many of the item were ordered
+func (i Item) Quantity() int {
+	if i.Count <= 0 {
+		return 1
+	}
+	return i.Count
+}
+
+// IsAgeRestricted returns whether the item is age-restricted (e.g. alcohol or tobacco)
+func (i Item) IsAgeRestricted() bool {
+	return i.AgeRestricted || i.IsAlcohol()
+}
+
+// IsAlcohol returns whether the item is an alcoholic drink
+func (i Item) IsAlcohol() bool {
+	return i.Alcohol
+}
+
+// Participant is a participant of a group order, with the items in their basket
+type Participant struct {
+	ID    string
+	Name  string
+	Ready bool // The participant marked themselves as ready
+	Items []Item
+}
+
+// Delivery is a part of a purchase the provider split into several deliveries, which large group orders sometimes ship in
+type Delivery struct {
+	State DeliveryState
+	Eta   time.Time
+	Items []Item // The items in the delivery's bag, empty if the provider doesn't tell
+}
+
+// IsDelivered returns whether the delivery arrived
+func (d Delivery) IsDelivered() bool {
+	return d.State == DeliveryStateDelivered
+}
+
+// Discount is a discount of the whole purchase, like a promo code or a campaign
+type Discount struct {
+	Name   string
+	Amount float64
+}
+
+// OrderDetails is a group order as its provider describes it. The amounts are in the currency of the order, and the times the
+// provider doesn't tell are at the Unix epoch.
+type OrderDetails struct {
+	Status           OrderStatus
+	Currency         string // The ISO 4217 code of the currency of the order (like ILS), empty if the provider doesn't tell
+	VenueID          string
+	DeliveryLocation Location
+	HostID           string
+	Host             string // The name of the host
+	Participants     []Participant
+	CreatedAt        time.Time
+	PurchaseDatetime time.Time
+	DeliveryEta      time.Time
+	DeliveryState    DeliveryState // The state of the purchase, DeliveryStateUnknown until it's purchased
+	DeliveredAt      time.Time     // Zero if the order didn't arrive or the provider doesn't tell when it did
+	Deliveries       []Delivery    // Set when the purchase was split into several deliveries
+	DeliveryPrice    *float64      // What the host paid for the delivery, nil if the provider doesn't tell
+	Discounts        []Discount
+	Credits          float64 // The provider's credits the host paid with
+	ServiceFee       float64
+	Tip              float64 // The tip the host gave the courier
+}
+
+// RateByPerson returns the amount of the items of each participant who ordered any
+func (o *OrderDetails) RateByPerson() (map[string]float64, error) {
+	return o.ItemsAmountByPerson(func(Item) bool { return true }), nil
+}
+
+// DiscountsAmount returns the amount the purchase was discounted by: its promo codes and campaigns, and the credits the host paid
+// with. The items' amounts don't include it.
+func (o *OrderDetails) DiscountsAmount() float64 {
+	total := o.Credits
+	for _, discount := range o.Discounts {
+		total += discount.Amount
+	}
+	return total
+}
+
+// PaidDeliveryPrice returns what the host paid for the delivery, and false if the provider doesn't tell (like before the purchase)
+func (o *OrderDetails) PaidDeliveryPrice() (float64, bool) {
+	if o.DeliveryPrice == nil {
+		return 0, false
+	}
+	return *o.DeliveryPrice, true
+}
+
+// AgeRestrictedByPerson returns the amount of age-restricted items of each participant who ordered any
+func (o *OrderDetails) AgeRestrictedByPerson() map[string]float64 {
+	return o.ItemsAmountByPerson(Item.IsAgeRestricted)
+}
+
+// ItemsAmountByPerson returns the amount of the items matching the given function of each participant who ordered any
+func (o *OrderDetails) ItemsAmountByPerson(match func(Item) bool) map[string]float64 {
+	output := make(map[string]float64)
+	for _, participant := range o.Participants {
+		total := 0.0
+		for _, item := range participant.Items {
+			if match(item) {
+				total += item.Amount
+			}
+		}
+		if total == 0 {
+			continue
+		}
+
+		output[participant.Name] = total
+	}
+
+	return output
+}
+
+// ItemsByPerson returns the items of each participant who ordered any
+func (o *OrderDetails) ItemsByPerson() map[string][]Item {
+	output := make(map[string][]Item)
+	for _, participant := range o.Participants {
+		if len(participant.Items) == 0 {
+			continue
+		}
+		output[participant.Name] = append(output[participant.Name], participant.Items...)
+	}
+	return output
+}
+
+// ItemCounts returns the quantity ordered of each item (by name) in all the participants' baskets
+func (o *OrderDetails) ItemCounts() map[string]int {
+	output := make(map[string]int)
+	for _, participant := range o.Participants {
+		for _, item := range participant.Items {
+			output[item.Name] += item.Quantity()
+		}
+	}
+	return output
+}
+
+// NotReadyParticipants returns the names of the participants with items in their basket who didn't mark themselves as ready yet,
+// except for the host, who sends the order
+func (o *OrderDetails) NotReadyParticipants() []string {
+	names := make([]string, 0)
+	for _, participant := range o.Participants {
+		if participant.ID == o.HostID || participant.Ready || len(participant.Items) == 0 {
+			continue
+		}
+		names = append(names, participant.Name)
+	}
+	return names
+}
+
+// IsSplitDelivery returns whether the purchase ships in several deliveries
+func (o *OrderDetails) IsSplitDelivery() bool {
+	return len(o.Deliveries) > 1
+}
+
+// IsDelivered returns whether the order arrived. An order split into several deliveries arrived when all of them did.
+func (o *OrderDetails) IsDelivered() bool {
+	if o.IsSplitDelivery() {
+		for _, delivery := range o.Deliveries {
+			if !delivery.IsDelivered() {
+				return false
+			}
+		}
+		return true
+	}
+	return o.DeliveryState == DeliveryStateDelivered
+}
+
+// Venue is the venue of a group order, as its provider describes it
+type Venue struct {
+	Name         string
+	Link         string // The venue's page in the provider
+	City         string
+	Currency     string         // The ISO 4217 code of the currency of the venue's prices, empty if the provider doesn't tell
+	Timezone     *time.Location // nil if the provider doesn't tell
+	Delivering   bool           // The venue is open and delivers right now
+	Preorders    bool           // The venue takes orders while it's closed, to deliver once it opens
+	OfflineUntil time.Time      // When the venue is expected to deliver again, if it doesn't right now
+	Busy         bool           // The venue is in rush, which comes with surge delivery pricing and longer deliveries
+	MinimumOrder float64        // The minimum order amount without a small order surcharge, 0 if there's none
+	DeliveryRate int            // The delivery rate to the address of the order
+}
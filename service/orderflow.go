@@ -0,0 +1,399 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/oriser/bolt/logging"
+	orderDomain "github.com/oriser/bolt/order"
+	"github.com/oriser/bolt/tracing"
+)
+
+// OrderState is the stage of the tracking of an order. The tracking moves through the states in order, and ends in OrderStateDone
+// or OrderStateCanceled, or in the state it got to when it's stopped, times out or fails.
+type OrderState string
+
+const (
+	OrderStateJoined          OrderState = "joined"           // Bolt joined the group, and announces the order
+	OrderStateWaitingPurchase OrderState = "waiting_purchase" // Bolt waits for the group order to be sent
+	OrderStateRatesPublished  OrderState = "rates_published"  // The order was sent, and Bolt publishes the rates and tracks the debts
+	OrderStateDelivering      OrderState = "delivering"       // Bolt monitors the delivery
+	OrderStateDone            OrderState = "done"
+	OrderStateCanceled        OrderState = "canceled"
+)
+
+// orderTransitions are the states each state can move to
+var orderTransitions = map[OrderState][]OrderState{
+	OrderStateJoined:          {OrderStateWaitingPurchase},
+	OrderStateWaitingPurchase: {OrderStateRatesPublished, OrderStateCanceled},
+	OrderStateRatesPublished:  {OrderStateDelivering},
+	OrderStateDelivering:      {OrderStateDone, OrderStateCanceled},
+}
+
+// orderStates are the states in the order the tracking moves through them
+var orderStates = []OrderState{OrderStateJoined, OrderStateWaitingPurchase, OrderStateRatesPublished, OrderStateDelivering, OrderStateDone,
+	OrderStateCanceled}
+
+// orderFlow is the tracking of a single order. Each state has a step doing its work and returning the state to move to, and every
+// transition is persisted (for the orders to resume after a restart) and emitted as an EventOrderStateChanged, so new steps attach
+// to a state or to its event rather than to one long function. While delivering, the progress of the delivery is the state of its
+// DeliveryStateMachine, whose transitions are persisted too, and whose final states end the tracking.
+type orderFlow struct {
+	h         *Service
+	ctx       context.Context
+	span      *tracing.Span
+	req       LinksRequest
+	groupID   string
+	provider  Provider                  // The provider of the link, nil for a resumed order, which is restored with its persisted provider
+	resumed   *orderDomain.TrackedOrder // The persisted state of an order whose tracking was interrupted by a restart, if it was
+	admission *linkAdmission
+	working   *workingOrder
+	startedAt time.Time
+
+	state        OrderState
+	delivery     *DeliveryStateMachine // The state of the delivery while delivering
+	order        *groupOrder
+	venueName    string
+	groupRate    GroupRate
+	ratesMessage string
+	releases     []func() // Called once the tracking ends, in reverse order
+}
+
+// resumeDelivery returns whether the rates of the resumed order were already published and its debts tracked before the restart
+func (f *orderFlow) resumeDelivery() bool {
+	return f.resumed != nil && f.resumed.Phase == orderDomain.PhaseDelivery
+}
+
+// resumedState returns the state the resumed order got to before the restart, or an empty state for an order which wasn't resumed.
+// The orders persisted before their states were kept have only their phase.
+func (f *orderFlow) resumedState() OrderState {
+	switch {
+	case f.resumed == nil:
+		return ""
+	case f.resumed.State != "":
+		return OrderState(f.resumed.State)
+	case f.resumeDelivery():
+		return OrderStateDelivering
+	default:
+		return OrderStateWaitingPurchase
+	}
+}
+
+// stateIndex returns the position of the state in the tracking, -1 for an empty state
+func stateIndex(state OrderState) int {
+	for i, s := range orderStates {
+		if s == state {
+			return i
+		}
+	}
+	return -1
+}
+
+// run joins the order, or restores the resumed one, and runs the steps of its states until the tracking ends
+func (f *orderFlow) run() (string, error) {
+	defer func() {
+		for i := len(f.releases) - 1; i >= 0; i-- {
+			f.releases[i]()
+		}
+	}()
+	if err := f.start(); err != nil {
+		return "", err
+	}
+	if err := f.transition(OrderStateJoined); err != nil {
+		return "", err
+	}
+
+	steps := map[OrderState]func() (OrderState, error){
+		OrderStateJoined:          f.announce,
+		OrderStateWaitingPurchase: f.waitForPurchase,
+		OrderStateRatesPublished:  f.publishRates,
+		OrderStateDelivering:      f.monitorDelivery,
+	}
+	for {
+		step, ok := steps[f.state]
+		if !ok {
+			return "", nil
+		}
+		next, err := step()
+		if next == "" {
+			return "", err
+		}
+		if transitionErr := f.transition(next); transitionErr != nil {
+			return "", transitionErr
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+// transition moves the order to the state, persisting it and emitting the change. The order is persisted once it's announced, as
+// a resumed order isn't announced again, and until its tracking ends, when its persisted state is removed. A resumed order goes
+// through the states it already got to again without persisting them.
+func (f *orderFlow) transition(to OrderState) error {
+	if f.state != "" && !canTransition(f.state, to) {
+		return fmt.Errorf("invalid transition of order from %s to %s", f.state, to)
+	}
+	f.state = to
+	f.order.setTrackingState(to)
+	if to != OrderStateJoined && to != OrderStateDone && to != OrderStateCanceled && stateIndex(to) > stateIndex(f.resumedState()) {
+		ratesMessageID := ""
+		if to == OrderStateDelivering {
+			ratesMessageID = f.order.detailsMessageId
+		}
+		f.h.saveTracking(f.order, ratesMessageID)
+	}
+	f.h.hooks.Emit(f.ctx, Event{Type: EventOrderStateChanged, OrderID: f.groupID, Channel: f.req.Channel,
+		MessageID: f.req.MessageID, VenueName: f.venueName, OrderState: to})
+	return nil
+}
+
+// transitionDelivery persists the progress of the delivery, which monitorDelivery emits as an EventDeliveryProgress. The final
+// states of the delivery end the tracking, moving the order to OrderStateDone or OrderStateCanceled.
+func (f *orderFlow) transitionDelivery(transition DeliveryTransition) {
+	f.order.setDeliveryState(transition.To)
+	if !transition.To.Final() {
+		f.h.saveTracking(f.order, f.order.detailsMessageId)
+	}
+}
+
+func canTransition(from, to OrderState) bool {
+	for _, state := range orderTransitions[from] {
+		if state == to {
+			return true
+		}
+	}
+	return false
+}
+
+// start admits the link message and joins the group order, or restores the order resumed after a restart
+func (f *orderFlow) start() error {
+	h, req := f.h, f.req
+	var err error
+	if f.resumed == nil {
+		if err := h.admit(f.admission, req); err != nil {
+			return err
+		}
+		release, err := h.takeOrderSlot(req)
+		if err != nil {
+			return err
+		}
+		f.releases = append(f.releases, release)
+
+		f.order, err = h.joinGroupOrder(f.ctx, groupLink{provider: f.provider, groupID: f.groupID})
+		if err != nil && h.shuttingDown() {
+			return errShuttingDown
+		}
+		if err != nil {
+			code, message := joinFailure(err)
+			joinFailuresTotal.Inc(code)
+			_, _ = h.informEventContext(f.ctx, req.Channel, h.text(req.Channel, message, code), "", req.MessageID)
+			return fmt.Errorf("join group order (%s): %w", code, err)
+		}
+		ordersTrackedTotal.Inc()
+	} else {
+		// The orders tracked before the restart don't wait in line, as they already got their slots
+		h.orderSlots.force()
+		f.releases = append(f.releases, h.orderSlots.release)
+		f.order, err = h.restoreTrackedOrder(f.resumed)
+		if err != nil {
+			return fmt.Errorf("restore tracked order: %w", err)
+		}
+		f.order.ctx = logging.CopyAttrs(tracing.ContextWithSpan(f.order.ctx, f.span), f.ctx)
+	}
+	order := f.order
+	order.messageID = req.MessageID
+	order.channel = req.Channel
+	order.text = req.Text
+	order.startedAt = f.startedAt
+	h.workingOrders.setOrder(f.working, order)
+	f.releases = append(f.releases, order.cancel)
+	order.tags = parseTags(req.Text)
+	order.note = parseNote(req.Text)
+	order.timeoutMultiplier = parseTimeoutMultiplier(req.Text)
+	if venue, err := order.Venue(); err == nil {
+		f.venueName = venue.Name
+	}
+	return nil
+}
+
+// announce confirms the venue with the channel if needed and announces the order, or ends the tracking if the channel didn't confirm
+func (f *orderFlow) announce() (OrderState, error) {
+	h, req, order := f.h, f.req, f.order
+	venue, err := order.Venue()
+	if err == nil {
+		if f.resumed == nil {
+			if blacklisted := h.blacklistedVenue(req.Channel, venue.Name); blacklisted != nil {
+				if err := h.confirmBlacklistedVenue(req.Channel, req.MessageID, blacklisted); err != nil {
+					if errors.Is(err, errNotConfirmed) {
+						return "", nil
+					}
+					return "", fmt.Errorf("confirm blacklisted venue: %w", err)
+				}
+			}
+			if err := h.confirmExpensiveVenue(f.ctx, req.Channel, req.MessageID, venue.Name); err != nil {
+				if errors.Is(err, errNotConfirmed) {
+					return "", nil
+				}
+				return "", fmt.Errorf("confirm expensive venue: %w", err)
+			}
+			order.joinedMessageID, _ = h.informEditableEvent(f.ctx, req.Channel, h.text(req.Channel, msgJoinedOrder, venue.Name), "", req.MessageID)
+			if order.noteSurge(venue) {
+				_, _ = h.informEventContext(f.ctx, req.Channel, h.text(req.Channel, msgVenueBusy), "", req.MessageID)
+			}
+			h.suggestForHeadcount(order, req.Channel, req.MessageID, venue)
+			h.offerMerge(req.Channel, req.MessageID, f.groupID, venue.Name)
+		}
+		f.venueName = venue.Name
+	}
+	h.hooks.Emit(f.ctx, Event{Type: EventOrderJoined, OrderID: f.groupID, Channel: req.Channel, MessageID: req.MessageID, VenueName: f.venueName})
+	return OrderStateWaitingPurchase, nil
+}
+
+// waitForPurchase waits for the group order to be sent and computes its rates. The rates of a resumed order in its delivery were
+// already published and its debts tracked before the restart, so they're only computed again for monitoring the delivery.
+func (f *orderFlow) waitForPurchase() (OrderState, error) {
+	h, req := f.h, f.req
+	var (
+		groupRate GroupRate
+		err       error
+	)
+	if f.resumeDelivery() {
+		groupRate, err = h.computeGroupRate(f.order, req.Channel, req.MessageID)
+		groupRate.ExternalRef = h.storedOrderRef(f.groupID)
+	} else {
+		groupStart := time.Now()
+		groupRate, err = h.getRateForGroup(f.order, req.Channel, req.MessageID)
+		observeMonitoring("group", groupStart)
+	}
+	if reason := f.order.stopped(); reason != "" {
+		h.logger.InfoContext(f.ctx, "Order was stopped while waiting for it to be ready", "reason", reason)
+		return "", nil
+	}
+	if err != nil {
+		if errors.Is(err, ErrOrderCanceled) {
+			_, _ = h.informEventContext(f.ctx, req.Channel, h.text(req.Channel, msgOrderCanceled, f.groupID), "", req.MessageID)
+			h.hooks.Emit(f.ctx, Event{Type: EventOrderCanceled, OrderID: f.groupID, Channel: req.Channel, MessageID: req.MessageID, VenueName: f.venueName})
+			return OrderStateCanceled, nil
+		}
+		if errors.Is(err, ErrWaitTimeout) {
+			_, _ = h.informEventContext(f.ctx, req.Channel, h.text(req.Channel, msgTimedOutReady), "", req.MessageID)
+			return "", nil
+		}
+		h.logger.ErrorContext(f.ctx, "Error getting rate for group", "error", err)
+		f.span.SetError(err)
+		_, _ = h.informEventContext(f.ctx, req.Channel, h.text(req.Channel, msgRatesError, f.groupID), "", req.MessageID)
+		return "", nil
+	}
+	f.groupRate = groupRate
+	return OrderStateRatesPublished, nil
+}
+
+// publishRates publishes the rates of the order and tracks its debts, unless the order was resumed after they were
+func (f *orderFlow) publishRates() (OrderState, error) {
+	h, req, order, groupID := f.h, f.req, f.order, f.groupID
+	if venue, err := order.Venue(); err == nil {
+		// The host could have switched the venue while the group was open
+		f.venueName = venue.Name
+	}
+	groupRate := &f.groupRate
+	if groupRate.HostUser != nil {
+		order.setHost(groupRate.HostUser.TransportID)
+	}
+	groupRate.CompanyPaid = order.isCompanyPaid()
+	groupRate.Note = order.note
+	f.ratesMessage = h.buildRatesMessage(req.Channel, *groupRate, groupID)
+	if !f.resumeDelivery() {
+		h.flagSkippers(req.Channel, req.MessageID, *groupRate)
+		h.flagDeactivated(req.Channel, req.MessageID, *groupRate)
+		paidReaction := MarkAsPaidReaction
+		if groupRate.CompanyPaid {
+			paidReaction = ""
+		}
+		_, ratesSpan := tracing.Start(f.ctx, "notification.send_rates_message", tracing.String("channel", req.Channel))
+		var err error
+		order.detailsMessageId, err = h.sendRatesMessage(f.ctx, req.Channel, *groupRate, groupID, f.ratesMessage, paidReaction, req.MessageID)
+		ratesSpan.SetError(err)
+		ratesSpan.End()
+		if err != nil {
+			return "", fmt.Errorf("failed sending details message: %w", err)
+		}
+		if paidReaction != "" {
+			h.orderMessages.add(req.Channel, order.detailsMessageId, groupID)
+		}
+		h.syncRatesContinuations(req.Channel, order, h.buildRatesMessages(req.Channel, *groupRate, groupID)[1:])
+		h.sendItemsBreakdown(f.ctx, req.Channel, *groupRate, groupID, req.MessageID)
+		h.saveRatesSnapshot(f.ctx, req.Channel, order.detailsMessageId, groupID, *groupRate, f.ratesMessage)
+	}
+	h.hooks.Emit(f.ctx, Event{Type: EventRatesPublished, OrderID: groupID, Channel: req.Channel, MessageID: req.MessageID, VenueName: f.venueName, Rates: groupRate})
+
+	if !f.resumeDelivery() {
+		if groupRate.CompanyPaid {
+			h.logger.InfoContext(f.ctx, "Order was paid by the company, not tracking its debts")
+		} else {
+			debtsCtx, debtsSpan := tracing.Start(f.ctx, "store.add_debts", tracing.String("group_id", groupID))
+			err := h.addDebts(debtsCtx, req.Channel, groupID, *groupRate, req.MessageID)
+			debtsSpan.SetError(err)
+			debtsSpan.End()
+			if err != nil {
+				h.logger.ErrorContext(f.ctx, "Error adding debts", "error", err)
+				_, _ = h.informEventContext(f.ctx, req.Channel, h.text(req.Channel, msgDebtsError), "", req.MessageID)
+			}
+		}
+	}
+	return OrderStateDelivering, nil
+}
+
+// monitorDelivery monitors the delivery of the order until it arrives
+func (f *orderFlow) monitorDelivery() (OrderState, error) {
+	h, req := f.h, f.req
+	timeouts := h.deliveryTimeouts(f.ctx, f.venueName)
+	deliveryCtx, cancel := context.WithTimeout(f.order.ctx, f.order.orderTimeout(timeouts.DoneTimeout))
+	defer cancel()
+	deliveryStart := time.Now()
+	defer observeMonitoring("delivery", deliveryStart)
+	f.delivery = NewDeliveryStateMachine(h.cfg.TimeTillGetReadyMessage)
+	if f.resumed != nil {
+		f.delivery.Resume(ParseDeliveryState(f.resumed.DeliveryState))
+	}
+	f.delivery.OnTransition(f.transitionDelivery)
+	err := h.monitorDelivery(req.Channel, f.order, deliveryCtx, f.delivery, timeouts.StatusCheckInterval, req.MessageID, &f.groupRate, f.ratesMessage)
+	if err == nil {
+		return OrderStateDone, nil
+	}
+	if reason := f.order.stopped(); reason != "" {
+		h.logger.InfoContext(f.ctx, "Order was stopped while monitoring its delivery", "reason", reason)
+		return "", nil
+	}
+	if errors.Is(err, ErrWaitTimeout) {
+		_, _ = h.informEventContext(f.ctx, req.Channel, h.text(req.Channel, msgTimedOutDone), "", req.MessageID)
+		return "", nil
+	}
+	err = fmt.Errorf("error in waiting for order to finish: %w", err)
+	if errors.Is(err, ErrOrderCanceled) {
+		h.hooks.Emit(f.ctx, Event{Type: EventOrderCanceled, OrderID: f.groupID, Channel: req.Channel, MessageID: req.MessageID, VenueName: f.venueName})
+		return OrderStateCanceled, err
+	}
+	return "", err
+}
+
+func (g *groupOrder) setTrackingState(state OrderState) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.state = state
+}
+
+func (g *groupOrder) setDeliveryState(state DeliveryState) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.deliveryState = state
+}
+
+// trackingState returns how far the tracking of the order and its delivery got
+func (g *groupOrder) trackingState() (OrderState, DeliveryState) {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	return g.state, g.deliveryState
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/oriser/bolt/order"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderFlowTransitions(t *testing.T) {
+	t.Parallel()
+
+	h, err := New(Config{}, nil, nil, nil, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+	var states []OrderState
+	h.hooks.Subscribe(func(_ context.Context, event Event) {
+		states = append(states, event.OrderState)
+	}, EventOrderStateChanged)
+
+	flow := &orderFlow{h: h, groupID: "ABC", req: LinksRequest{Channel: "C1", MessageID: "1.1"}, order: &groupOrder{}}
+	require.NoError(t, flow.transition(OrderStateJoined))
+	h.hooks.Emit(context.Background(), Event{Type: EventOrderJoined, OrderID: "ABC", Channel: "C1", MessageID: "1.1"})
+	require.NoError(t, flow.transition(OrderStateWaitingPurchase))
+	assert.Error(t, flow.transition(OrderStateDone), "the order must be delivered to be done")
+	assert.Equal(t, OrderStateWaitingPurchase, flow.state)
+
+	activeOrder, ok := h.LookupActiveOrder("ABC")
+	require.True(t, ok)
+	assert.Equal(t, OrderStateWaitingPurchase, activeOrder.OrderState)
+
+	require.NoError(t, flow.transition(OrderStateRatesPublished))
+	require.NoError(t, flow.transition(OrderStateDelivering))
+	require.NoError(t, flow.transition(OrderStateDone))
+	assert.Error(t, flow.transition(OrderStateCanceled), "done is final")
+	assert.Equal(t, []OrderState{OrderStateJoined, OrderStateWaitingPurchase, OrderStateRatesPublished, OrderStateDelivering, OrderStateDone}, states)
+}
+
+func TestOrderFlowPersistsStates(t *testing.T) {
+	t.Parallel()
+
+	store := &fakeTrackingStore{tracked: make(map[string]*order.TrackedOrder)}
+	h, err := New(Config{WoltApiBaseAddr: "https://restaurant-api.wolt.com"}, nil, nil, store, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+	woltProvider, err := h.providerByName(providerWolt)
+	require.NoError(t, err)
+	tracked, err := h.restoreGroupOrder(woltProvider, "ABC", json.RawMessage(`{"id": "real-abc"}`))
+	require.NoError(t, err)
+
+	flow := &orderFlow{h: h, groupID: "ABC", req: LinksRequest{Channel: "C1", MessageID: "1.1"}, order: tracked}
+	require.NoError(t, flow.transition(OrderStateJoined))
+	assert.Empty(t, store.tracked, "the order is persisted once it's announced")
+	require.NoError(t, flow.transition(OrderStateWaitingPurchase))
+	assert.Equal(t, string(OrderStateWaitingPurchase), store.tracked["ABC"].State)
+	require.NoError(t, flow.transition(OrderStateRatesPublished))
+	assert.Equal(t, string(OrderStateRatesPublished), store.tracked["ABC"].State)
+	tracked.detailsMessageId = "1.3"
+	require.NoError(t, flow.transition(OrderStateDelivering))
+	assert.Equal(t, order.PhaseDelivery, store.tracked["ABC"].Phase)
+	assert.Empty(t, store.tracked["ABC"].DeliveryState)
+
+	flow.transitionDelivery(DeliveryTransition{From: DeliveryStateReceived, To: DeliveryStatePickup})
+	saved := store.tracked["ABC"]
+	assert.Equal(t, string(OrderStateDelivering), saved.State)
+	assert.Equal(t, DeliveryStatePickup.String(), saved.DeliveryState)
+
+	resumed := &orderFlow{h: h, groupID: "ABC", req: flow.req, order: tracked, resumed: saved}
+	delete(store.tracked, "ABC")
+	for _, state := range []OrderState{OrderStateJoined, OrderStateWaitingPurchase, OrderStateRatesPublished, OrderStateDelivering} {
+		require.NoError(t, resumed.transition(state))
+	}
+	assert.Empty(t, store.tracked, "the states the order already got to aren't persisted again")
+
+	machine := NewDeliveryStateMachine(time.Minute)
+	machine.Resume(ParseDeliveryState(saved.DeliveryState))
+	assert.Equal(t, DeliveryStatePickup, machine.State())
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/oriser/bolt/order"
+)
+
+const (
+	OrderRefULID     = "ulid"
+	OrderRefSequence = "sequence"
+)
+
+// OrderRefGenerator generates the external references of orders, which finance can reference orders by independently of
+// the Wolt group IDs. It gets the order store, for generators which keep their state in it.
+type OrderRefGenerator interface {
+	NewOrderRef(ctx context.Context, store order.Store) (string, error)
+}
+
+// OrderRefGeneratorFunc is an adapter to allow using ordinary functions as an OrderRefGenerator
+type OrderRefGeneratorFunc func(ctx context.Context, store order.Store) (string, error)
+
+func (f OrderRefGeneratorFunc) NewOrderRef(ctx context.Context, store order.Store) (string, error) {
+	return f(ctx, store)
+}
+
+var (
+	orderRefGeneratorsLock sync.RWMutex
+	orderRefGenerators     = map[string]OrderRefGenerator{
+		OrderRefULID:     OrderRefGeneratorFunc(newULIDRef),
+		OrderRefSequence: OrderRefGeneratorFunc(newSequenceRef),
+	}
+)
+
+// RegisterOrderRefGenerator makes an order reference generator available by name for the ORDER_REF_GENERATOR configuration.
+// Registering an existing name replaces it.
+func RegisterOrderRefGenerator(name string, generator OrderRefGenerator) {
+	orderRefGeneratorsLock.Lock()
+	defer orderRefGeneratorsLock.Unlock()
+	orderRefGenerators[name] = generator
+}
+
+// OrderRefGeneratorByName returns a registered order reference generator, or nil for an empty name
+func OrderRefGeneratorByName(name string) (OrderRefGenerator, error) {
+	if name == "" {
+		return nil, nil
+	}
+	orderRefGeneratorsLock.RLock()
+	defer orderRefGeneratorsLock.RUnlock()
+	generator, ok := orderRefGenerators[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown order reference generator %q (available: %v)", name, registeredOrderRefGenerators())
+	}
+	return generator, nil
+}
+
+func registeredOrderRefGenerators() []string {
+	names := make([]string, 0, len(orderRefGenerators))
+	for name := range orderRefGenerators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// crockfordAlphabet is the base32 alphabet of ULIDs, without the letters which are easy to confuse with digits
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// newULIDRef generates a ULID: 48 bits of the time in milliseconds followed by 80 random bits, so the references sort by time
+func newULIDRef(context.Context, order.Store) (string, error) {
+	var id [16]byte
+	ms := uint64(time.Now().UnixMilli())
+	for i := 0; i < 6; i++ {
+		id[i] = byte(ms >> (40 - 8*i))
+	}
+	if _, err := rand.Read(id[6:]); err != nil {
+		return "", fmt.Errorf("read random bits: %w", err)
+	}
+	return encodeCrockford(id), nil
+}
+
+// encodeCrockford encodes the 128 bits as 26 base32 characters, the first of which holds only 3 bits
+func encodeCrockford(id [16]byte) string {
+	encoded := make([]byte, 26)
+	for i := 25; i >= 0; i-- {
+		encoded[i] = crockfordAlphabet[id[15]&0x1f]
+		// Shift the whole ID 5 bits to the right
+		for j := 15; j > 0; j-- {
+			id[j] = id[j]>>5 | id[j-1]<<3
+		}
+		id[0] >>= 5
+	}
+	return string(encoded)
+}
+
+// newSequenceRef generates a sequential reference like BOLT-000042, from the sequence kept in the order store
+func newSequenceRef(ctx context.Context, store order.Store) (string, error) {
+	sequenceStore, ok := store.(order.RefSequenceStore)
+	if !ok {
+		return "", fmt.Errorf("the order store doesn't support sequential references")
+	}
+	value, err := sequenceStore.NextOrderRefSequence(ctx)
+	if err != nil {
+		return "", fmt.Errorf("next sequence: %w", err)
+	}
+	return fmt.Sprintf("BOLT-%06d", value), nil
+}
+
+// newOrderRef generates the external reference of an order, or returns an empty string if no generator is configured or it failed
+func (h *Service) newOrderRef(groupID string) string {
+	if h.orderRefGenerator == nil {
+		return ""
+	}
+	ctx, cancel := h.storeContext()
+	defer cancel()
+	ref, err := h.orderRefGenerator.NewOrderRef(ctx, h.orderStore)
+	if err != nil {
+		h.logger.Error("Error generating external reference", "group_id", groupID, "error", err)
+		return ""
+	}
+	return ref
+}
+
+// storedOrderRef returns the external reference of the stored order with the given Wolt group ID, for orders resumed after a restart
+func (h *Service) storedOrderRef(groupID string) string {
+	ctx, cancel := h.storeContext()
+	defer cancel()
+	if o := h.storedOrder(ctx, groupID); o != nil {
+		return o.ExternalRef
+	}
+	return ""
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/oriser/bolt/order"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeRefSequenceStore struct {
+	fakeOrderStore
+	sequence int64
+}
+
+func (f *fakeRefSequenceStore) NextOrderRefSequence(context.Context) (int64, error) {
+	f.sequence++
+	return f.sequence, nil
+}
+
+func TestOrderRefGenerators(t *testing.T) {
+	t.Parallel()
+
+	ulid, err := OrderRefGeneratorByName(OrderRefULID)
+	require.NoError(t, err)
+	first, err := ulid.NewOrderRef(context.Background(), nil)
+	require.NoError(t, err)
+	second, err := ulid.NewOrderRef(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Regexp(t, regexp.MustCompile("^[0-7][0-9A-HJKMNP-TV-Z]{25}$"), first)
+	assert.NotEqual(t, first, second)
+	assert.Equal(t, "00000000000000000000000000", encodeCrockford([16]byte{}))
+	assert.Equal(t, "7ZZZZZZZZZZZZZZZZZZZZZZZZZ", encodeCrockford([16]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0xff, 0xff, 0xff, 0xff, 0xff}))
+
+	sequence, err := OrderRefGeneratorByName(OrderRefSequence)
+	require.NoError(t, err)
+	store := &fakeRefSequenceStore{}
+	for _, expected := range []string{"BOLT-000001", "BOLT-000002"} {
+		ref, err := sequence.NewOrderRef(context.Background(), store)
+		require.NoError(t, err)
+		assert.Equal(t, expected, ref)
+	}
+	_, err = sequence.NewOrderRef(context.Background(), &fakeOrderStore{})
+	assert.ErrorContains(t, err, "doesn't support sequential references")
+
+	none, err := OrderRefGeneratorByName("")
+	require.NoError(t, err)
+	assert.Nil(t, none)
+	_, err = OrderRefGeneratorByName("uuid")
+	assert.ErrorContains(t, err, `unknown order reference generator "uuid"`)
+
+	RegisterOrderRefGenerator("constant", OrderRefGeneratorFunc(func(context.Context, order.Store) (string, error) {
+		return "REF-1", nil
+	}))
+	_, err = New(Config{OrderRefGenerator: "constant"}, &fakeTreasuryStore{}, nil, nil, "U-bot", nil)
+	require.NoError(t, err)
+	_, err = New(Config{OrderRefGenerator: "uuid"}, &fakeTreasuryStore{}, nil, nil, "U-bot", nil)
+	assert.ErrorContains(t, err, "parsing ORDER_REF_GENERATOR")
+}
+
+func TestOrderRefInRatesMessage(t *testing.T) {
+	t.Parallel()
+
+	store := &fakeRefSequenceStore{}
+	h, err := New(Config{OrderRefGenerator: OrderRefSequence}, &fakeTreasuryStore{}, nil, store, "U-bot",
+		&recordingNotification{})
+	require.NoError(t, err)
+
+	groupRate := GroupRate{Rates: []Rate{{WoltName: "Thor", Amount: 20}}, HostWoltUser: "Thor", ExternalRef: h.newOrderRef("A")}
+	message := h.buildRatesMessage("C1", groupRate, "A")
+	assert.True(t, strings.HasPrefix(message, "Rates for Wolt order ID A (including 0 NIS for delivery):\nOrder reference: BOLT-000001\n"), message)
+
+	store.orders = append(store.orders, &order.Order{OriginalID: "A", ExternalRef: "BOLT-000001"})
+	assert.Equal(t, "BOLT-000001", h.storedOrderRef("A"))
+	assert.Empty(t, h.storedOrderRef("B"))
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// errOrdersQueueFull is returned for the orders which can't wait in line for a tracking slot, as MAX_QUEUED_ORDERS orders already wait
+var errOrdersQueueFull = errors.New("too many orders are waiting to be tracked")
+
+// orderSlots caps the orders tracked at the same time (MAX_TRACKED_ORDERS), as each of them keeps polling Wolt. The orders shared
+// while all the slots are taken wait in line for them, in the order they were shared.
+type orderSlots struct {
+	lock     sync.Mutex
+	limit    int // 0 means the tracked orders aren't capped
+	maxQueue int // 0 means the line isn't capped
+	taken    int
+	waiting  []*slotWaiter
+}
+
+type slotWaiter struct {
+	granted chan struct{}
+}
+
+func newOrderSlots(limit, maxQueue int) *orderSlots {
+	return &orderSlots{limit: limit, maxQueue: maxQueue}
+}
+
+// take takes a slot if one is free and returns nil and 0, or gets in line for one and returns the waiter to wait with and the position
+// in line (from 1). It returns errOrdersQueueFull if the line is full.
+func (s *orderSlots) take() (*slotWaiter, int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.limit == 0 || s.taken < s.limit {
+		s.taken++
+		return nil, 0, nil
+	}
+	if s.maxQueue > 0 && len(s.waiting) >= s.maxQueue {
+		return nil, 0, errOrdersQueueFull
+	}
+	waiter := &slotWaiter{granted: make(chan struct{})}
+	s.waiting = append(s.waiting, waiter)
+	return waiter, len(s.waiting), nil
+}
+
+// force takes a slot even if all of them are taken, for the orders which were already tracked before a restart
+func (s *orderSlots) force() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.taken++
+}
+
+// wait waits until the waiter is granted a slot. If ctx is done first, the waiter leaves the line and the error of ctx is returned.
+func (s *orderSlots) wait(ctx context.Context, waiter *slotWaiter) error {
+	select {
+	case <-waiter.granted:
+		return nil
+	case <-ctx.Done():
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for i, w := range s.waiting {
+		if w == waiter {
+			s.waiting = append(s.waiting[:i], s.waiting[i+1:]...)
+			return ctx.Err()
+		}
+	}
+	// The slot was granted while ctx was done, so it's given back
+	s.releaseLocked()
+	return ctx.Err()
+}
+
+// release frees a slot, handing it to the first order in line if there is one
+func (s *orderSlots) release() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.releaseLocked()
+}
+
+func (s *orderSlots) releaseLocked() {
+	if len(s.waiting) == 0 {
+		if s.taken > 0 {
+			s.taken--
+		}
+		return
+	}
+	next := s.waiting[0]
+	s.waiting = s.waiting[1:]
+	close(next.granted)
+}
+
+// full returns whether an order shared now would be refused, as all the slots are taken and the line is full
+func (s *orderSlots) full() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.limit > 0 && s.taken >= s.limit && s.maxQueue > 0 && len(s.waiting) >= s.maxQueue
+}
+
+// takeOrderSlot takes a tracking slot for a newly shared order, telling the channel its position in line if it has to wait for one.
+// The returned function frees the slot once the order isn't tracked anymore.
+func (h *Service) takeOrderSlot(req LinksRequest) (func(), error) {
+	waiter, position, err := h.orderSlots.take()
+	if err != nil {
+		ordersQueuedTotal.Inc("rejected")
+		if _, informErr := h.informEvent(req.Channel, h.text(req.Channel, msgOrdersQueueFull), "", req.MessageID); informErr != nil {
+			h.logger.Error("Error telling the channel the orders queue is full", "channel", req.Channel, "message_id", req.MessageID,
+				"error", informErr)
+		}
+		return nil, err
+	}
+	if waiter != nil {
+		ordersQueuedTotal.Inc("queued")
+		if _, err := h.informEvent(req.Channel, h.text(req.Channel, msgOrderQueued, position), "", req.MessageID); err != nil {
+			h.logger.Error("Error telling the channel the order's position in line", "channel", req.Channel, "message_id", req.MessageID,
+				"error", err)
+		}
+		if err := h.orderSlots.wait(h.lifetime(), waiter); err != nil {
+			return nil, errShuttingDown
+		}
+	}
+	return h.orderSlots.release, nil
+}
+
+// OrdersQueueFull returns whether orders shared now would be refused, as MAX_TRACKED_ORDERS orders are tracked and MAX_QUEUED_ORDERS
+// orders wait in line. Transports use it for back-pressure, refusing the link events until there's room for them.
+func (h *Service) OrdersQueueFull() bool {
+	return h.orderSlots.full()
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderSlots(t *testing.T) {
+	t.Parallel()
+
+	slots := newOrderSlots(1, 2)
+	waiter, position, err := slots.take()
+	require.NoError(t, err)
+	assert.Nil(t, waiter, "a free slot is taken right away")
+	assert.Zero(t, position)
+
+	first, position, err := slots.take()
+	require.NoError(t, err)
+	assert.Equal(t, 1, position)
+	second, position, err := slots.take()
+	require.NoError(t, err)
+	assert.Equal(t, 2, position)
+	assert.True(t, slots.full())
+	_, _, err = slots.take()
+	assert.ErrorIs(t, err, errOrdersQueueFull)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, slots.wait(ctx, first), context.Canceled, "the first order left the line")
+	assert.False(t, slots.full())
+
+	slots.release()
+	require.NoError(t, slots.wait(context.Background(), second), "the slot is handed to the next order in line")
+	waiter, _, err = slots.take()
+	require.NoError(t, err)
+	require.NotNil(t, waiter, "the slot wasn't freed in between")
+
+	slots.force()
+	slots.release()
+	slots.release()
+	require.NoError(t, slots.wait(context.Background(), waiter))
+	slots.release()
+	waiter, _, err = slots.take()
+	require.NoError(t, err)
+	assert.Nil(t, waiter, "all the slots were freed")
+
+	unlimited := newOrderSlots(0, 0)
+	for i := 0; i < 100; i++ {
+		waiter, _, err := unlimited.take()
+		require.NoError(t, err)
+		assert.Nil(t, waiter)
+	}
+	assert.False(t, unlimited.full())
+}
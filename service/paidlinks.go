@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	debtDomain "github.com/oriser/bolt/debt"
+)
+
+// PaidLinkPath is the path the links marking the debts as paid are served under, as <path><order ID>/<debt ID>/<token>
+const PaidLinkPath = "/paid/"
+
+// ErrPaidLinkNotFound is returned for links with invalid tokens, and when the links are disabled
+var ErrPaidLinkNotFound = errors.New("paid link not found")
+
+// PaidLink is the debt of a link in a reminder, which the borrower opens once they paid it to mark it as paid
+type PaidLink struct {
+	OrderID    string
+	LenderName string
+	Amount     float64
+	Currency   string
+	Claimed    bool // The borrower marked the debt as paid, and the lender didn't confirm the payment yet (see PAYMENT_CONFIRMATION)
+	Settled    bool // There's nothing left to pay, as the debt was paid or removed
+}
+
+// paidLinkToken returns the token of the link of the debt, which can't be derived without the secret
+func (h *Service) paidLinkToken(orderID, debtID string) string {
+	mac := hmac.New(sha256.New, h.paidLinksSecret)
+	mac.Write([]byte(orderID + "/" + debtID))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// paidLinkURL returns the link marking the debt as paid, or empty if PAID_LINKS_URL isn't set
+func (h *Service) paidLinkURL(debt *debtDomain.Debt) string {
+	if h.cfg.PaidLinksURL == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s%s%s/%s/%s", strings.TrimSuffix(h.cfg.PaidLinksURL, "/"), PaidLinkPath, debt.OrderID, debt.ID,
+		h.paidLinkToken(debt.OrderID, debt.ID))
+}
+
+// paidLinkDebt returns the debt of the link if the token is its token, or nil if it's no longer owed
+func (h *Service) paidLinkDebt(ctx context.Context, orderID, debtID, token string) (*debtDomain.Debt, error) {
+	if h.cfg.PaidLinksURL == "" || h.debtStore == nil || !hmac.Equal([]byte(token), []byte(h.paidLinkToken(orderID, debtID))) {
+		return nil, ErrPaidLinkNotFound
+	}
+	debts, err := h.debtStore.ListDebtsForOrderID(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("list debts: %w", err)
+	}
+	for _, debt := range debts {
+		if debt.ID == debtID {
+			return debt, nil
+		}
+	}
+	return nil, nil
+}
+
+func (h *Service) paidLink(orderID string, debt *debtDomain.Debt) *PaidLink {
+	if debt == nil {
+		return &PaidLink{OrderID: orderID, Settled: true}
+	}
+	link := &PaidLink{
+		OrderID:  orderID,
+		Amount:   debt.Amount,
+		Currency: h.currencyOrDefault(debt.Currency),
+		Claimed:  debt.PaidClaimedAt != nil,
+	}
+	if lender, err := h.getUser(debt.LenderID); err == nil {
+		link.LenderName = lender.FullName
+	}
+	return link
+}
+
+// DebtPaidLink returns the debt of the link if the token is its token, without marking it as paid
+func (h *Service) DebtPaidLink(ctx context.Context, orderID, debtID, token string) (*PaidLink, error) {
+	debt, err := h.paidLinkDebt(ctx, orderID, debtID, token)
+	if err != nil {
+		return nil, err
+	}
+	return h.paidLink(orderID, debt), nil
+}
+
+// MarkPaidByLink marks the debt of the link as paid if the token is its token, the same as the borrower reacting to its reminder.
+// Opening the link again after that does nothing.
+func (h *Service) MarkPaidByLink(ctx context.Context, orderID, debtID, token string) (*PaidLink, error) {
+	debt, err := h.paidLinkDebt(ctx, orderID, debtID, token)
+	if err != nil {
+		return nil, err
+	}
+	if debt == nil || debt.PaidClaimedAt != nil {
+		return h.paidLink(orderID, debt), nil
+	}
+	borrower, err := h.getUser(debt.BorrowerID)
+	if err != nil {
+		return nil, fmt.Errorf("get borrower: %w", err)
+	}
+	if err := h.payDebt(ctx, debt, borrower, debt.InitiatedTransportID); err != nil {
+		return nil, fmt.Errorf("pay debt: %w", err)
+	}
+
+	link := h.paidLink(orderID, debt)
+	link.Claimed = debt.PaidClaimedAt != nil
+	link.Settled = !link.Claimed
+	return link, nil
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaidLinks(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	notification := &recordingNotification{}
+	store := &fakeTreasuryStore{
+		users: map[string]*userDomain.User{
+			"uuid-host": {ID: "uuid-host", FullName: "Thor", TransportID: "U-host"},
+			"uuid-loki": {ID: "uuid-loki", FullName: "Loki", TransportID: "U-loki"},
+		},
+		debts: []*debtDomain.Debt{
+			{ID: "d1", BorrowerID: "uuid-loki", LenderID: "uuid-host", OrderID: "ABC", Amount: 30, InitiatedTransportID: "C1"},
+		},
+	}
+	h, err := New(Config{PaidLinksURL: "https://bolt.example.com/", PaidLinksSecret: "secret"}, store, store,
+		nil, "UBOT", notification)
+	require.NoError(t, err)
+
+	link := h.paidLinkURL(store.debts[0])
+	require.True(t, strings.HasPrefix(link, "https://bolt.example.com/paid/ABC/d1/"), link)
+	token := strings.TrimPrefix(link, "https://bolt.example.com/paid/ABC/d1/")
+
+	_, err = h.DebtPaidLink(ctx, "ABC", "d1", "wrong")
+	assert.ErrorIs(t, err, ErrPaidLinkNotFound)
+	_, err = h.MarkPaidByLink(ctx, "ABC", "d2", token)
+	assert.ErrorIs(t, err, ErrPaidLinkNotFound, "the token is of the debt")
+
+	paidLink, err := h.DebtPaidLink(ctx, "ABC", "d1", token)
+	require.NoError(t, err)
+	assert.Equal(t, &PaidLink{OrderID: "ABC", LenderName: "Thor", Amount: 30, Currency: "ILS"}, paidLink)
+	require.Len(t, store.debts, 1, "viewing the link doesn't mark the debt as paid")
+
+	paidLink, err = h.MarkPaidByLink(ctx, "ABC", "d1", token)
+	require.NoError(t, err)
+	assert.True(t, paidLink.Settled)
+	assert.Empty(t, store.debts)
+	assert.Contains(t, notification.messages, "U-host: <@U-loki> marked himself as paid for order ID ABC")
+
+	paidLink, err = h.MarkPaidByLink(ctx, "ABC", "d1", token)
+	require.NoError(t, err)
+	assert.Equal(t, &PaidLink{OrderID: "ABC", Settled: true}, paidLink, "opening the link again does nothing")
+}
+
+func TestPaidLinksConfirmation(t *testing.T) {
+	t.Parallel()
+
+	store := &fakeTreasuryStore{
+		users: map[string]*userDomain.User{
+			"uuid-host": {ID: "uuid-host", FullName: "Thor", TransportID: "U-host"},
+			"uuid-loki": {ID: "uuid-loki", FullName: "Loki", TransportID: "U-loki"},
+		},
+		debts: []*debtDomain.Debt{
+			{ID: "d1", BorrowerID: "uuid-loki", LenderID: "uuid-host", OrderID: "ABC", Amount: 30, InitiatedTransportID: "C1"},
+		},
+	}
+	h, err := New(Config{PaymentConfirmation: true, PaidLinksURL: "https://bolt.example.com"}, store, store,
+		nil, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+
+	paidLink, err := h.MarkPaidByLink(context.Background(), "ABC", "d1", h.paidLinkToken("ABC", "d1"))
+	require.NoError(t, err)
+	assert.True(t, paidLink.Claimed)
+	assert.False(t, paidLink.Settled)
+	require.Len(t, store.debts, 1, "the debt is kept until the host confirms the payment")
+	assert.NotNil(t, store.debts[0].PaidClaimedAt)
+}
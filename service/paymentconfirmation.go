@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/oriser/regroup"
+)
+
+// The reactions of the hosts to the payment claims, when the claims are sent without buttons
+const (
+	ConfirmPaymentReaction = "white_check_mark"
+	RejectPaymentReaction  = "x"
+)
+
+var paymentClaimRe = regroup.MustCompile(`\(payment claim (?P<order>[A-Z0-9]+)/(?P<debt>[\w-]+)\)`)
+
+type parsedPaymentClaim struct {
+	OrderID string `regroup:"order"`
+	DebtID  string `regroup:"debt"`
+}
+
+func (h *Service) paymentClaimStore() (debtDomain.PaymentClaimStore, error) {
+	paymentClaimStore, ok := h.debtStore.(debtDomain.PaymentClaimStore)
+	if !ok {
+		return nil, fmt.Errorf("payment claims are not supported")
+	}
+	return paymentClaimStore, nil
+}
+
+// claimDebtPaid records that the borrower said they paid the debt, and asks the lender to confirm the payment. The debt is settled
+// once the lender confirms it, and the borrower isn't reminded about it meanwhile.
+func (h *Service) claimDebtPaid(ctx context.Context, debt *debtDomain.Debt, borrower *userDomain.User) error {
+	paymentClaimStore, err := h.paymentClaimStore()
+	if err != nil {
+		return err
+	}
+	lender, err := h.getUser(debt.LenderID)
+	if err != nil {
+		return fmt.Errorf("get lender: %w", err)
+	}
+	if debt.PaidClaimedAt != nil {
+		_, _ = h.informInteractiveEvent(borrower.TransportID,
+			fmt.Sprintf("I already asked <@%s> to confirm your payment for order %s", lender.TransportID, debt.OrderID), "")
+		return nil
+	}
+
+	now := time.Now()
+	if err := paymentClaimStore.SetPaidClaimedAt(ctx, debt.ID, &now); err != nil {
+		return fmt.Errorf("set paid claimed at: %w", err)
+	}
+	debt.PaidClaimedAt = &now
+	if err := h.askPaymentConfirmation(lender.TransportID, debt, borrower); err != nil {
+		// Without asking the host the debt would never be settled, so the borrower can try again
+		if clearErr := paymentClaimStore.SetPaidClaimedAt(ctx, debt.ID, nil); clearErr != nil {
+			h.logger.Error("Error clearing the payment claim", "debt_id", debt.ID, "error", clearErr)
+		}
+		return fmt.Errorf("ask payment confirmation: %w", err)
+	}
+
+	_, _ = h.informInteractiveEvent(borrower.TransportID,
+		fmt.Sprintf("OK! I asked <@%s> to confirm your payment for order %s, and I'll stop reminding you meanwhile", lender.TransportID, debt.OrderID), "")
+	return nil
+}
+
+// askPaymentConfirmation sends the lender the payment claim, with "Confirm" and "Reject" buttons if RATES_BUTTONS is set and the
+// notification layer supports them, or asking them to react to it otherwise
+func (h *Service) askPaymentConfirmation(lenderTransportID string, debt *debtDomain.Debt, borrower *userDomain.User) error {
+	claim := fmt.Sprintf("<@%s> says they paid you %.2f %s for order %s (payment claim %s/%s)", borrower.TransportID, debt.Amount,
+		h.currencyName(debt.InitiatedTransportID, debt.Currency), debt.OrderID, debt.OrderID, debt.ID)
+	if h.interactiveRates() {
+		value := fmt.Sprintf("%s:%s", debt.OrderID, debt.ID)
+		_, err := h.eventNotification.(InteractiveMessenger).SendInteractiveMessage(lenderTransportID, InteractiveMessage{
+			Text:     claim,
+			Sections: []MessageSection{{Text: claim}},
+			Buttons: []MessageButton{
+				{Label: "I got it", Action: ButtonActionConfirmPayment, Value: value},
+				{Label: "I didn't get it", Action: ButtonActionRejectPayment, Value: value, Danger: true},
+			},
+		}, "")
+		if err == nil {
+			return nil
+		}
+		h.logger.Error("Error sending the payment claim with buttons, sending it as text", "debt_id", debt.ID, "error", err)
+	}
+	_, err := h.informInteractiveEvent(lenderTransportID,
+		fmt.Sprintf("%s.\nReact with :%s: if you got it, or with :%s: if you didn't", claim, ConfirmPaymentReaction, RejectPaymentReaction), "")
+	return err
+}
+
+// handlePaymentClaimReaction handles the lender's reaction to a payment claim. It returns whether the reaction was to a payment claim.
+func (h *Service) handlePaymentClaimReaction(ctx context.Context, req ReactionAddRequest) (string, bool, error) {
+	if (req.Reaction != ConfirmPaymentReaction && req.Reaction != RejectPaymentReaction) || req.MessageUserID != h.selfID {
+		return "", false, nil
+	}
+	claim := &parsedPaymentClaim{}
+	if err := paymentClaimRe.MatchToTarget(req.MessageText, claim); err != nil {
+		if errors.Is(err, &regroup.NoMatchFoundError{}) {
+			return "", false, nil
+		}
+		return "", true, fmt.Errorf("regroup match to target: %w", err)
+	}
+	response, err := h.resolvePaymentClaim(ctx, claim.OrderID, claim.DebtID, req.FromUserID, req.Reaction == ConfirmPaymentReaction)
+	return response, true, err
+}
+
+// resolvePaymentClaim settles the debt if the lender (by transport ID) confirmed its payment, or keeps it if they rejected it. It
+// returns a response to the lender.
+func (h *Service) resolvePaymentClaim(ctx context.Context, orderID, debtID, fromUserID string, confirmed bool) (string, error) {
+	debts, err := h.debtStore.ListDebtsForOrderID(ctx, orderID)
+	if err != nil {
+		return "", fmt.Errorf("list debts: %w", err)
+	}
+	var debt *debtDomain.Debt
+	for _, d := range debts {
+		if d.ID == debtID {
+			debt = d
+			break
+		}
+	}
+	if debt == nil {
+		return fmt.Sprintf("This debt for order %s was already settled or removed", orderID), nil
+	}
+
+	lender, err := h.getUser(debt.LenderID)
+	if err != nil {
+		return "", fmt.Errorf("get lender: %w", err)
+	}
+	if lender.TransportID != fromUserID {
+		return fmt.Sprintf("Only <@%s> can confirm this payment", lender.TransportID), nil
+	}
+	borrower, err := h.getUser(debt.BorrowerID)
+	if err != nil {
+		return "", fmt.Errorf("get borrower: %w", err)
+	}
+	if debt.PaidClaimedAt == nil {
+		return fmt.Sprintf("<@%s> didn't say they paid for order %s", borrower.TransportID, orderID), nil
+	}
+
+	if !confirmed {
+		paymentClaimStore, err := h.paymentClaimStore()
+		if err != nil {
+			return "", err
+		}
+		if err := paymentClaimStore.SetPaidClaimedAt(ctx, debt.ID, nil); err != nil {
+			return "", fmt.Errorf("clear paid claimed at: %w", err)
+		}
+		_, _ = h.informInteractiveEvent(borrower.TransportID,
+			fmt.Sprintf("<@%s> didn't get your payment for order %s, so I'll keep reminding you. Mark it as paid again once you pay",
+				lender.TransportID, orderID), "")
+		return fmt.Sprintf("OK, I told <@%s> you didn't get their payment for order %s", borrower.TransportID, orderID), nil
+	}
+
+	now := time.Now()
+	debt.PaymentConfirmedBy = lender.ID
+	debt.PaymentConfirmedAt = &now
+	if err := h.settleDebt(ctx, debt); err != nil {
+		return "", err
+	}
+	_, _ = h.informInteractiveEvent(borrower.TransportID,
+		fmt.Sprintf("<@%s> confirmed your payment, I removed your debt for order %s", lender.TransportID, orderID), "")
+	return fmt.Sprintf("Thanks! I removed the debt of <@%s> for order %s", borrower.TransportID, orderID), nil
+}
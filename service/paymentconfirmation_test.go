@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaymentConfirmation(t *testing.T) {
+	t.Parallel()
+
+	notification := &recordingNotification{}
+	store := &fakeTreasuryStore{
+		users: map[string]*userDomain.User{
+			"uuid-host": {ID: "uuid-host", FullName: "Thor", TransportID: "U-host"},
+			"uuid-loki": {ID: "uuid-loki", FullName: "Loki", TransportID: "U-loki"},
+		},
+		debts: []*debtDomain.Debt{
+			{ID: "d1", BorrowerID: "uuid-loki", LenderID: "uuid-host", OrderID: "ABC", Amount: 30, InitiatedTransportID: "C1"},
+		},
+	}
+	h, err := New(Config{PaymentConfirmation: true}, store, store, nil, "UBOT", notification)
+	require.NoError(t, err)
+
+	require.NoError(t, h.markDebtAsPaid(context.Background(), "ABC", "U-loki", "C1"))
+	require.Len(t, store.debts, 1, "the debt is kept until the host confirms the payment")
+	assert.NotNil(t, store.debts[0].PaidClaimedAt)
+	claim := "U-host: <@U-loki> says they paid you 30.00 NIS for order ABC (payment claim ABC/d1).\n" +
+		"React with :white_check_mark: if you got it, or with :x: if you didn't"
+	assert.Contains(t, notification.messages, claim)
+	assert.Contains(t, notification.messages, "U-loki: OK! I asked <@U-host> to confirm your payment for order ABC, and I'll stop reminding you meanwhile")
+	borrower, err := h.remindDebt(store.debts[0])
+	require.NoError(t, err)
+	assert.Nil(t, borrower, "the borrower isn't reminded while the host didn't answer")
+
+	// Only the host can answer the claim, and rejecting it keeps the debt
+	response, err := h.HandleReactionAdded(ReactionAddRequest{Reaction: RejectPaymentReaction, FromUserID: "U-loki", MessageUserID: "UBOT",
+		MessageText: claim})
+	require.NoError(t, err)
+	assert.Equal(t, "Only <@U-host> can confirm this payment", response)
+	response, err = h.HandleReactionAdded(ReactionAddRequest{Reaction: RejectPaymentReaction, FromUserID: "U-host", MessageUserID: "UBOT",
+		MessageText: claim})
+	require.NoError(t, err)
+	assert.Equal(t, "OK, I told <@U-loki> you didn't get their payment for order ABC", response)
+	require.Len(t, store.debts, 1)
+	assert.Nil(t, store.debts[0].PaidClaimedAt)
+
+	require.NoError(t, h.markDebtAsPaid(context.Background(), "ABC", "U-loki", "C1"))
+	response, err = h.HandleButtonAction(ButtonActionRequest{Action: ButtonActionConfirmPayment, Value: "ABC:d1", FromUserID: "U-host"})
+	require.NoError(t, err)
+	assert.Equal(t, "Thanks! I removed the debt of <@U-loki> for order ABC", response)
+	assert.Empty(t, store.debts)
+	assert.Contains(t, notification.messages, "U-loki: <@U-host> confirmed your payment, I removed your debt for order ABC")
+	require.Len(t, store.payments, 1, "the payment is recorded with its audit trail")
+	assert.Equal(t, "uuid-host", store.payments[0].PaymentConfirmedBy)
+	assert.NotNil(t, store.payments[0].PaidClaimedAt)
+	assert.NotNil(t, store.payments[0].PaymentConfirmedAt)
+
+	response, err = h.HandleButtonAction(ButtonActionRequest{Action: ButtonActionConfirmPayment, Value: "ABC:d1", FromUserID: "U-host"})
+	require.NoError(t, err)
+	assert.Equal(t, "This debt for order ABC was already settled or removed", response)
+}
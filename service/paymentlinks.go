@@ -0,0 +1,56 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	userDomain "github.com/oriser/bolt/user"
+)
+
+// linkWithAmount returns whether the PAYMENT_LINKS template pre-fills the amount to pay
+func linkWithAmount(template string) bool {
+	return strings.Contains(template, "{amount}")
+}
+
+// buildPaymentLink fills a PAYMENT_LINKS template for paying the host the amount, and returns false if the template needs the host's
+// phone and the host has none
+func buildPaymentLink(template string, host *userDomain.User, amount float64, currency string) (string, bool) {
+	if strings.Contains(template, "{phone}") {
+		if host.Phone == "" {
+			return "", false
+		}
+		template = strings.ReplaceAll(template, "{phone}", url.QueryEscape(host.Phone))
+	}
+	template = strings.ReplaceAll(template, "{amount}", strconv.FormatFloat(amount, 'f', 2, 64))
+	return strings.ReplaceAll(template, "{currency}", url.QueryEscape(currency)), true
+}
+
+// ratePaymentLink returns the link to pay the host the participant's amount, appended to the participant's rate, or empty if there's
+// nothing to pay or no link with an amount in PAYMENT_LINKS. The link is of the payment method the participant and the host both use,
+// or else of the first of the host's preferred methods which has one.
+func (h *Service) ratePaymentLink(channel string, groupRate GroupRate, rate Rate) string {
+	host := groupRate.HostUser
+	if host == nil || groupRate.CompanyPaid || rate.Forgiven || rate.WoltName == groupRate.HostWoltUser || rate.PersonalAmount() <= 0 {
+		return ""
+	}
+	methods := host.PreferredPaymentMethods()
+	if rate.User != nil {
+		if common, ok := userDomain.CommonPaymentMethod(host, rate.User); ok {
+			methods = append([]userDomain.PaymentMethod{common}, methods...)
+		}
+	}
+	for _, method := range methods {
+		template, ok := h.paymentLinks[method]
+		if !ok || !linkWithAmount(template) {
+			continue
+		}
+		link, ok := buildPaymentLink(template, host, rate.PersonalAmount(), h.currencyOrDefault(groupRate.Currency))
+		if !ok {
+			continue
+		}
+		return fmt.Sprintf(" <%s|%s>", link, h.text(channel, msgPayWith, method))
+	}
+	return ""
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"testing"
+
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRatePaymentLinks(t *testing.T) {
+	t.Parallel()
+
+	h, err := New(Config{PaymentLinks: []string{
+		"bit=https://bit.example/?phone={phone}&amount={amount}",
+		"revolut=https://revolut.example/thor?amount={amount}&currency={currency}",
+		"paybox=https://paybox.example/thor",
+	}}, nil, nil, nil, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+
+	host := &userDomain.User{ID: "uuid-host", TransportID: "U-host", Phone: "+972500000000",
+		PaymentPreferences: []userDomain.PaymentMethod{userDomain.PaymentMethodPaybox, userDomain.PaymentMethodBit, userDomain.PaymentMethodRevolut}}
+	groupRate := GroupRate{
+		HostWoltUser: "Thor",
+		HostUser:     host,
+		Currency:     "EUR",
+		Rates: []Rate{
+			{WoltName: "Loki", User: &userDomain.User{ID: "uuid-loki", TransportID: "U-loki",
+				PaymentMethods: []userDomain.PaymentMethod{userDomain.PaymentMethodRevolut}}, Amount: 37.5},
+			{WoltName: "Odin", Amount: 20},
+			{WoltName: "Thor", User: host, Amount: 40},
+			{WoltName: "Frigg", Amount: 10, Forgiven: true},
+		},
+	}
+	assert.Equal(t, "Rates for Wolt order ID ABC (including 0 EUR for delivery):\n"+
+		"<@U-loki> (Loki): 37.50 <https://revolut.example/thor?amount=37.50&currency=EUR|Pay with Revolut>\n"+
+		"Odin: 20.00 <https://bit.example/?phone=%2B972500000000&amount=20.00|Pay with Bit>\n"+
+		"<@U-host> (Thor): 40.00\n"+
+		"Frigg: 10.00 (forgiven by the host)\n"+
+		"\nPay to: <@U-host>\n"+
+		"Preferred payments methods (in order): Paybox, Bit, Revolut\n"+
+		"<@U-loki>: you both use Revolut\n", h.buildRatesMessage("C1", groupRate, "ABC"),
+		"the links are of the method the participant uses, or else of the host's first preferred method with an amount")
+	assert.Equal(t, &MessageButton{Label: "Pay with Paybox", URL: "https://paybox.example/thor"}, h.paymentLinkButton("C1", host),
+		"the links without amounts are buttons")
+
+	groupRate.CompanyPaid = true
+	assert.NotContains(t, h.buildRatesMessage("C1", groupRate, "ABC"), "Pay with")
+}
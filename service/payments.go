@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	userDomain "github.com/oriser/bolt/user"
+)
+
+func (h *Service) paymentMethodsStore() (userDomain.PaymentMethodsStore, error) {
+	store, ok := h.userStore.(userDomain.PaymentMethodsStore)
+	if !ok {
+		return nil, fmt.Errorf("payment methods are not supported")
+	}
+	return store, nil
+}
+
+// SetPaymentMethods registers the payment methods the user (by transport ID) pays with, by their names in the order the user prefers
+// them, so the rates messages can point out a method the user and the host both use. No names remove the user's payment methods.
+func (h *Service) SetPaymentMethods(ctx context.Context, transportID string, names []string) ([]userDomain.PaymentMethod, error) {
+	store, err := h.paymentMethodsStore()
+	if err != nil {
+		return nil, err
+	}
+	methods := make([]userDomain.PaymentMethod, 0, len(names))
+	seen := make(map[userDomain.PaymentMethod]bool)
+	for _, name := range names {
+		method, err := userDomain.ParsePaymentMethod(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		if !seen[method] {
+			seen[method] = true
+			methods = append(methods, method)
+		}
+	}
+	if err := store.SetPaymentMethods(ctx, transportID, methods); err != nil {
+		return nil, fmt.Errorf("set payment methods: %w", err)
+	}
+	return methods, nil
+}
+
+// PaymentMethods returns the payment methods the user (by transport ID) registered to pay with
+func (h *Service) PaymentMethods(ctx context.Context, transportID string) ([]userDomain.PaymentMethod, error) {
+	store, err := h.paymentMethodsStore()
+	if err != nil {
+		return nil, err
+	}
+	methods, err := store.PaymentMethods(ctx, transportID)
+	if err != nil {
+		return nil, fmt.Errorf("get payment methods: %w", err)
+	}
+	return methods, nil
+}
+
+// loadPaymentMethods sets the payment methods the user registered, if the user store supports them
+func (h *Service) loadPaymentMethods(user *userDomain.User) {
+	store, err := h.paymentMethodsStore()
+	if err != nil {
+		return
+	}
+	ctx, cancel := h.storeContext()
+	defer cancel()
+	if user.PaymentMethods, err = store.PaymentMethods(ctx, user.TransportID); err != nil {
+		h.logger.Error("Error getting the payment methods", "transport_id", user.TransportID, "error", err)
+	}
+}
+
+// mutualPayments returns the lines telling the debtors which of the host's preferred payment methods they use as well, one line for
+// each method
+func (h *Service) mutualPayments(channel string, groupRate GroupRate) string {
+	if groupRate.HostUser == nil {
+		return ""
+	}
+	methods := make([]userDomain.PaymentMethod, 0)
+	debtors := make(map[userDomain.PaymentMethod][]string)
+	for _, rate := range groupRate.Rates {
+		if rate.User == nil || rate.WoltName == groupRate.HostWoltUser || rate.Forgiven {
+			continue
+		}
+		method, ok := userDomain.CommonPaymentMethod(groupRate.HostUser, rate.User)
+		if !ok {
+			continue
+		}
+		if _, ok := debtors[method]; !ok {
+			methods = append(methods, method)
+		}
+		debtors[method] = append(debtors[method], fmt.Sprintf("<@%s>", rate.User.TransportID))
+	}
+
+	var sb strings.Builder
+	for _, method := range methods {
+		sb.WriteString(h.text(channel, msgMutualPayment, strings.Join(debtors[method], ", "), method))
+	}
+	return sb.String()
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePaymentMethodsStore struct {
+	fakeTreasuryStore
+	methods map[string][]userDomain.PaymentMethod
+}
+
+func (f *fakePaymentMethodsStore) SetPaymentMethods(_ context.Context, transportID string, methods []userDomain.PaymentMethod) error {
+	f.methods[transportID] = methods
+	return nil
+}
+
+func (f *fakePaymentMethodsStore) PaymentMethods(_ context.Context, transportID string) ([]userDomain.PaymentMethod, error) {
+	return f.methods[transportID], nil
+}
+
+func TestSetPaymentMethods(t *testing.T) {
+	t.Parallel()
+
+	store := &fakePaymentMethodsStore{methods: make(map[string][]userDomain.PaymentMethod)}
+	h, err := New(Config{}, store, nil, nil, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, err = h.SetPaymentMethods(ctx, "U1", []string{"bit", "cash"})
+	assert.ErrorContains(t, err, `unknown payment method "cash"`)
+	methods, err := h.SetPaymentMethods(ctx, "U1", []string{"paybox", " Bit", "Paybox"})
+	require.NoError(t, err)
+	assert.Equal(t, []userDomain.PaymentMethod{userDomain.PaymentMethodPaybox, userDomain.PaymentMethodBit}, methods)
+
+	user := &userDomain.User{ID: "uuid-1", TransportID: "U1"}
+	h.loadPaymentMethods(user)
+	assert.Equal(t, methods, user.PaymentMethods)
+}
+
+func TestMutualPaymentsInRates(t *testing.T) {
+	t.Parallel()
+
+	h, err := New(Config{}, &fakeTreasuryStore{}, nil, nil, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+	host := &userDomain.User{TransportID: "U-host", PaymentPreferences: []userDomain.PaymentMethod{userDomain.PaymentMethodPaybox, userDomain.PaymentMethodBit}}
+	groupRate := GroupRate{HostWoltUser: "Thor", HostUser: host, Rates: []Rate{
+		{WoltName: "Frigg", Amount: 10, User: &userDomain.User{TransportID: "U3", PaymentMethods: []userDomain.PaymentMethod{userDomain.PaymentMethodBit}}},
+		{WoltName: "Loki", Amount: 30, User: &userDomain.User{TransportID: "U1", PaymentMethods: []userDomain.PaymentMethod{userDomain.PaymentMethodBit, userDomain.PaymentMethodPaybox}}},
+		{WoltName: "Odin", Amount: 20, User: &userDomain.User{TransportID: "U2", PaymentMethods: []userDomain.PaymentMethod{userDomain.PaymentMethodBit}}},
+		{WoltName: "Sif", Amount: 15, User: &userDomain.User{TransportID: "U4", PaymentMethods: []userDomain.PaymentMethod{userDomain.PaymentMethodPepper}}},
+		{WoltName: "Thor", Amount: 25, User: host},
+	}}
+	assert.Equal(t, "Rates for Wolt order ID ABC (including 0 NIS for delivery):\n"+
+		"<@U3> (Frigg): 10.00\n"+
+		"<@U1> (Loki): 30.00\n"+
+		"<@U2> (Odin): 20.00\n"+
+		"<@U4> (Sif): 15.00\n"+
+		"<@U-host> (Thor): 25.00\n"+
+		"\nPay to: <@U-host>\n"+
+		"Preferred payments methods (in order): Paybox, Bit\n"+
+		"<@U3>, <@U2>: you both use Bit\n"+
+		"<@U1>: you both use Paybox\n", h.buildRatesMessage("C1", groupRate, "ABC"),
+		"the host's most preferred method is pointed out to debtors who use several of them")
+
+	// Hosts without preferences are paid with the methods they pay with
+	host.PaymentPreferences = nil
+	host.PaymentMethods = []userDomain.PaymentMethod{userDomain.PaymentMethodPepper}
+	assert.Contains(t, h.buildRatesMessage("C1", groupRate, "ABC"),
+		"Preferred payments methods (in order): Pepper pay\n<@U4>: you both use Pepper pay\n")
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// workHours is the part of the day group orders are shared in. It wraps around midnight if it ends before it starts.
+type workHours struct {
+	start time.Time
+	end   time.Time
+}
+
+// contains returns whether the clock of the given time is in the work hours
+func (w *workHours) contains(t time.Time) bool {
+	minutes := func(clock time.Time) int {
+		return clock.Hour()*60 + clock.Minute()
+	}
+	now, start, end := minutes(t), minutes(w.start), minutes(w.end)
+	if start <= end {
+		return now >= start && now < end
+	}
+	return now >= start || now < end
+}
+
+// parseWorkHours parses work hours in the <HH:MM>-<HH:MM> format, returning nil if the value is empty
+func parseWorkHours(value string) (*workHours, error) {
+	if value == "" {
+		return nil, nil
+	}
+	startValue, endValue, ok := strings.Cut(value, "-")
+	if !ok {
+		return nil, fmt.Errorf("expected <HH:MM>-<HH:MM> but got %q", value)
+	}
+	start, err := ParseClock(strings.TrimSpace(startValue))
+	if err != nil {
+		return nil, fmt.Errorf("parse start: %w", err)
+	}
+	end, err := ParseClock(strings.TrimSpace(endValue))
+	if err != nil {
+		return nil, fmt.Errorf("parse end: %w", err)
+	}
+	if start.Equal(end) {
+		return nil, fmt.Errorf("the work hours %q are empty", value)
+	}
+	return &workHours{start: start, end: end}, nil
+}
+
+// parseWorkDays parses weekday names, returning nil if there are none
+func parseWorkDays(names []string) (map[time.Weekday]bool, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	days := make(map[time.Weekday]bool, len(names))
+	for _, name := range names {
+		day, err := parseWeekday(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		days[day] = true
+	}
+	return days, nil
+}
+
+// isPersonalOrderLink returns whether a link shared in the channel at the given time is taken for a personal order, which is
+// ignored silently: without the joined reaction and without the "too late" message. Those are the links shared in
+// SOCIAL_CHANNELS, and outside the WORK_HOURS or WORK_DAYS in the channel's timezone.
+func (h *Service) isPersonalOrderLink(channel string, at time.Time) bool {
+	for _, socialChannel := range h.cfg.SocialChannels {
+		if socialChannel == channel {
+			return true
+		}
+	}
+	at = at.In(h.timezoneForChannel(channel, nil))
+	if h.workDays != nil && !h.workDays[at.Weekday()] {
+		return true
+	}
+	return h.workHours != nil && !h.workHours.contains(at)
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPersonalOrderLinks(t *testing.T) {
+	t.Parallel()
+
+	_, err := parseWorkHours("08:00")
+	assert.Error(t, err)
+	_, err = parseWorkHours("08:00-08:00")
+	assert.Error(t, err)
+	_, err = parseWorkDays([]string{"Sunday", "Someday"})
+	assert.Error(t, err)
+
+	notification := &recordingNotification{}
+	h, err := New(Config{
+
+		DontJoinAfterTZ: "Asia/Jerusalem",
+		WorkHours:       "08:00-20:00",
+		WorkDays:        []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday"},
+		SocialChannels:  []string{"C-social"},
+	}, nil, nil, nil, "UBOT", notification)
+	require.NoError(t, err)
+	tz, err := ParseTimezone("Asia/Jerusalem")
+	require.NoError(t, err)
+
+	monday := time.Date(2024, 5, 6, 12, 0, 0, 0, tz)
+	assert.False(t, h.isPersonalOrderLink("C1", monday))
+	assert.True(t, h.isPersonalOrderLink("C-social", monday), "links in social channels are always personal")
+	assert.True(t, h.isPersonalOrderLink("C1", monday.Add(9*time.Hour)), "after the work hours")
+	assert.True(t, h.isPersonalOrderLink("C1", time.Date(2024, 5, 6, 7, 59, 0, 0, tz)), "before the work hours")
+	assert.True(t, h.isPersonalOrderLink("C1", time.Date(2024, 5, 4, 12, 0, 0, 0, tz)), "on Saturday")
+	assert.False(t, h.isPersonalOrderLink("C1", monday.UTC()), "the time is taken in the channel's timezone")
+
+	nightShift, err := parseWorkHours("22:00-06:00")
+	require.NoError(t, err)
+	assert.True(t, nightShift.contains(time.Date(2024, 5, 6, 23, 0, 0, 0, time.UTC)))
+	assert.True(t, nightShift.contains(time.Date(2024, 5, 6, 5, 59, 0, 0, time.UTC)))
+	assert.False(t, nightShift.contains(time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC)))
+
+	response, err := h.HandleLinkMessage(LinksRequest{
+		Links:     []Link{{Domain: "wolt.com", URL: "https://wolt.com/en/group/ABC123"}},
+		MessageID: "1.1",
+		Channel:   "C-social",
+	})
+	require.NoError(t, err)
+	assert.Empty(t, response)
+	assert.Empty(t, notification.messages, "personal orders are ignored silently")
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	userDomain "github.com/oriser/bolt/user"
+)
+
+const PickupKeyword = "pickup"
+
+var mentionRe = regexp.MustCompile(`<@[A-Za-z0-9]+>`)
+
+// MentionRequest is a message mentioning Bolt
+type MentionRequest struct {
+	Channel   string
+	MessageID string
+	ThreadID  string // The parent message of the thread the mention was sent in, empty if it's not in a thread
+	UserID    string
+	Text      string
+}
+
+type pickupInstruction struct {
+	userID string
+	text   string
+}
+
+type orderPickup struct {
+	instructions []pickupInstruction
+	messageID    string // The message with the compiled instructions sent to the host
+}
+
+// orderPickups keeps the per-person pickup instructions of the tracked orders, by order ID
+type orderPickups struct {
+	lock   sync.Mutex
+	orders map[string]*orderPickup
+}
+
+func newOrderPickups() *orderPickups {
+	return &orderPickups{orders: make(map[string]*orderPickup)}
+}
+
+func (p *orderPickups) remove(orderID string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	delete(p.orders, orderID)
+}
+
+// HandleMention handles messages mentioning Bolt. Participants can reply to the thread of an order link with
+// "@Bolt pickup <instructions>" to give their own pickup instructions, which are compiled into a message for the host, and anyone
+// can split amounts by hand with "@Bolt split ...".
+func (h *Service) HandleMention(req MentionRequest) (string, error) {
+	text := strings.TrimSpace(mentionRe.ReplaceAllString(req.Text, ""))
+	keyword, instructions, _ := strings.Cut(text, " ")
+	if strings.EqualFold(keyword, SplitKeyword) {
+		// The participants of the split are mentioned, so only Bolt's own mention is removed
+		_, splitText, _ := strings.Cut(strings.TrimSpace(strings.ReplaceAll(req.Text, "<@"+h.selfID+">", "")), " ")
+		return h.handleSplitMention(req, splitText)
+	}
+	if strings.EqualFold(keyword, DeliveryKeyword) || strings.EqualFold(keyword, ExtraKeyword) {
+		return h.handleFeeOverride(req, keyword, instructions)
+	}
+	if !strings.EqualFold(keyword, PickupKeyword) {
+		return "", nil
+	}
+	instructions = strings.TrimSpace(instructions)
+
+	activeOrder := h.activeOrderByMessage(req.Channel, req.ThreadID)
+	if req.ThreadID == "" || activeOrder == nil {
+		return "Reply with pickup instructions in the thread of an order I track", nil
+	}
+	if activeOrder.Rates != nil {
+		return "It's too late, the order was already purchased", nil
+	}
+	if instructions == "" {
+		return fmt.Sprintf("Please write your pickup instructions after the %q keyword", PickupKeyword), nil
+	}
+
+	if err := h.addPickupInstruction(activeOrder, req.UserID, instructions); err != nil {
+		return "", fmt.Errorf("add pickup instruction: %w", err)
+	}
+	if err := h.eventNotification.AddReaction(req.Channel, req.MessageID, "white_check_mark"); err != nil {
+		h.logger.Error("Error acknowledging pickup instruction", "channel", req.Channel, "error", err)
+	}
+	if h.accessible(req.Channel) {
+		return emojiText("white_check_mark") + " I added your pickup instructions", nil
+	}
+	return "", nil
+}
+
+func (h *Service) addPickupInstruction(activeOrder *ActiveOrder, userID, text string) error {
+	h.pickups.lock.Lock()
+	defer h.pickups.lock.Unlock()
+
+	pickup, ok := h.pickups.orders[activeOrder.ID]
+	if !ok {
+		pickup = &orderPickup{}
+		h.pickups.orders[activeOrder.ID] = pickup
+	}
+	replaced := false
+	for i, instruction := range pickup.instructions {
+		if instruction.userID == userID {
+			pickup.instructions[i].text = text
+			replaced = true
+		}
+	}
+	if !replaced {
+		pickup.instructions = append(pickup.instructions, pickupInstruction{userID: userID, text: text})
+	}
+
+	message := buildPickupMessage(activeOrder, pickup.instructions)
+	receiver, threadID := activeOrder.Channel, activeOrder.MessageID
+	if host := h.hostUserOfOrder(activeOrder.ID); host != nil {
+		receiver, threadID = host.TransportID, ""
+	}
+	if pickup.messageID != "" {
+		return h.editEvent(receiver, message, pickup.messageID)
+	}
+	messageID, err := h.informEditableEvent(context.Background(), receiver, message, "", threadID)
+	if err != nil {
+		return err
+	}
+	pickup.messageID = messageID
+	return nil
+}
+
+func buildPickupMessage(activeOrder *ActiveOrder, instructions []pickupInstruction) string {
+	var sb strings.Builder
+	venue := ""
+	if activeOrder.VenueName != "" {
+		venue = fmt.Sprintf(" from [%s]", activeOrder.VenueName)
+	}
+	sb.WriteString(fmt.Sprintf("Pickup instructions for the order%s (Wolt order ID %s), to add before checkout:\n", venue, activeOrder.ID))
+	for _, instruction := range instructions {
+		sb.WriteString(fmt.Sprintf("<@%s>: %s\n", instruction.userID, instruction.text))
+	}
+	return sb.String()
+}
+
+// hostUserOfOrder returns the user of the host of a tracked order, or nil if it isn't found
+func (h *Service) hostUserOfOrder(orderID string) *userDomain.User {
+	order := h.workingOrders.get(orderID)
+	if order == nil {
+		return nil
+	}
+	details, err := order.Details()
+	if err != nil {
+		h.logger.Error("Error getting details of order", "group_id", orderID, "error", err)
+		return nil
+	}
+	users, err := h.listUsersByName(details.Host)
+	if err != nil || len(users) != 1 {
+		return nil
+	}
+	return users[0]
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type editingNotification struct {
+	recordingNotification
+	edits []string
+}
+
+func (e *editingNotification) SendMessage(receiver, event, _ string) (string, error) {
+	e.messages = append(e.messages, receiver+": "+event)
+	return "sent-1", nil
+}
+
+func (e *editingNotification) EditMessage(receiver, event, messageID string) error {
+	e.edits = append(e.edits, receiver+"/"+messageID+": "+event)
+	return nil
+}
+
+func TestPickupInstructions(t *testing.T) {
+	t.Parallel()
+
+	notification := &editingNotification{}
+	h, err := New(Config{}, nil, nil, nil, "U-bot", notification)
+	require.NoError(t, err)
+
+	response, err := h.HandleMention(MentionRequest{Channel: "C1", MessageID: "2.1", ThreadID: "1.1", UserID: "U1", Text: "<@UBOT> hello"})
+	require.NoError(t, err)
+	assert.Empty(t, response, "other mentions are ignored")
+
+	response, err = h.HandleMention(MentionRequest{Channel: "C1", MessageID: "2.1", ThreadID: "1.1", UserID: "U1", Text: "<@UBOT> pickup floor 3"})
+	require.NoError(t, err)
+	assert.Equal(t, "Reply with pickup instructions in the thread of an order I track", response)
+
+	h.hooks.Emit(context.Background(), Event{Type: EventOrderJoined, OrderID: "A", Channel: "C1", MessageID: "1.1", VenueName: "Pizza"})
+	for _, req := range []MentionRequest{
+		{Channel: "C1", MessageID: "2.1", ThreadID: "1.1", UserID: "U1", Text: "<@UBOT> pickup floor 3"},
+		{Channel: "C1", MessageID: "2.2", ThreadID: "1.1", UserID: "U2", Text: "<@UBOT> Pickup  entrance B "},
+		{Channel: "C1", MessageID: "2.3", ThreadID: "1.1", UserID: "U1", Text: "<@UBOT> pickup floor 4"},
+	} {
+		response, err = h.HandleMention(req)
+		require.NoError(t, err)
+		assert.Empty(t, response)
+	}
+	assert.Equal(t, []string{"C1: Pickup instructions for the order from [Pizza] (Wolt order ID A), to add before checkout:\n<@U1>: floor 3\n"}, notification.messages)
+	assert.Equal(t, "C1/sent-1: Pickup instructions for the order from [Pizza] (Wolt order ID A), to add before checkout:\n<@U1>: floor 4\n<@U2>: entrance B\n",
+		notification.edits[1])
+
+	h.hooks.Emit(context.Background(), Event{Type: EventRatesPublished, OrderID: "A", Rates: &GroupRate{}})
+	response, err = h.HandleMention(MentionRequest{Channel: "C1", MessageID: "2.4", ThreadID: "1.1", UserID: "U3", Text: "<@UBOT> pickup lobby"})
+	require.NoError(t, err)
+	assert.Equal(t, "It's too late, the order was already purchased", response)
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oriser/bolt/order"
+)
+
+// preauthorization is a warning about an order from an expensive venue, waiting for enough participants to confirm it
+type preauthorization struct {
+	needed    int
+	confirmed map[string]bool // By the transport IDs of who confirmed
+	done      chan struct{}
+}
+
+// preauthorizations keeps the warnings about orders from expensive venues which wait for confirmation reactions, by their channel and
+// message ID
+type preauthorizations struct {
+	lock    sync.Mutex
+	pending map[string]*preauthorization
+}
+
+func newPreauthorizations() *preauthorizations {
+	return &preauthorizations{pending: make(map[string]*preauthorization)}
+}
+
+func (p *preauthorizations) add(channel, messageID string, needed int) chan struct{} {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	pending := &preauthorization{needed: needed, confirmed: make(map[string]bool), done: make(chan struct{})}
+	p.pending[skipKey(channel, messageID)] = pending
+	return pending.done
+}
+
+// confirm counts the user's confirmation of the warning, and returns false if it isn't waiting for confirmations
+func (p *preauthorizations) confirm(channel, messageID, transportID string) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	key := skipKey(channel, messageID)
+	pending, ok := p.pending[key]
+	if !ok {
+		return false
+	}
+	pending.confirmed[transportID] = true
+	if len(pending.confirmed) >= pending.needed {
+		close(pending.done)
+		delete(p.pending, key)
+	}
+	return true
+}
+
+// confirmations returns how many users confirmed the warning so far
+func (p *preauthorizations) confirmations(channel, messageID string) int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if pending, ok := p.pending[skipKey(channel, messageID)]; ok {
+		return len(pending.confirmed)
+	}
+	return 0
+}
+
+func (p *preauthorizations) remove(channel, messageID string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	delete(p.pending, skipKey(channel, messageID))
+}
+
+func parsePreauthThreshold(value string) (string, error) {
+	threshold, err := strconv.ParseFloat(value, 64)
+	if err != nil || threshold < 0 {
+		return "", fmt.Errorf("expected a non-negative amount but got %q", value)
+	}
+	return strconv.FormatFloat(threshold, 'f', -1, 64), nil
+}
+
+func parsePreauthConfirmations(value string) (string, error) {
+	confirmations, err := strconv.Atoi(value)
+	if err != nil || confirmations < 1 {
+		return "", fmt.Errorf("expected a positive number but got %q", value)
+	}
+	return strconv.Itoa(confirmations), nil
+}
+
+// preauthPolicy returns the channel's PREAUTH_THRESHOLD and PREAUTH_CONFIRMATIONS, its overrides or the global ones
+func (h *Service) preauthPolicy(channel string) (threshold float64, confirmations int) {
+	threshold, confirmations = h.cfg.PreauthThreshold, h.cfg.PreauthConfirmations
+	if value, ok := h.channelSetting(channel, settingPreauthThreshold); ok {
+		if overridden, err := strconv.ParseFloat(value, 64); err == nil {
+			threshold = overridden
+		}
+	}
+	if value, ok := h.channelSetting(channel, settingPreauthConfirmations); ok {
+		if overridden, err := strconv.Atoi(value); err == nil {
+			confirmations = overridden
+		}
+	}
+	return threshold, confirmations
+}
+
+// venueAveragePerPerson returns the average amount per participant of the channel's delivered orders from the venue, and false if
+// the channel has no such orders
+func (h *Service) venueAveragePerPerson(ctx context.Context, channel, venueName string) (float64, bool, error) {
+	if h.orderStore == nil {
+		return 0, false, nil
+	}
+	orders, err := h.orderStore.ListOrders(ctx, order.ListFilter{Receiver: channel, VenueName: venueName})
+	if err != nil {
+		return 0, false, fmt.Errorf("list orders: %w", err)
+	}
+	total, participants := 0.0, 0
+	for _, o := range orders {
+		if o.Status != order.StatusDone || !strings.EqualFold(o.VenueName, venueName) {
+			continue
+		}
+		for _, participant := range o.Participants {
+			total += participant.Amount
+			participants++
+		}
+	}
+	if participants == 0 {
+		return 0, false, nil
+	}
+	return total / float64(participants), true, nil
+}
+
+// confirmExpensiveVenue asks for PREAUTH_CONFIRMATIONS participants to confirm an order from a venue whose past orders in the channel
+// averaged more per person than PREAUTH_THRESHOLD, by reacting to the warning, so expensive orders aren't left half-committed. It
+// returns errNotConfirmed if not enough participants confirmed within BLACKLIST_CONFIRMATION_TIMEOUT.
+func (h *Service) confirmExpensiveVenue(ctx context.Context, channel, messageID, venueName string) error {
+	threshold, needed := h.preauthPolicy(channel)
+	if threshold <= 0 || needed <= 0 {
+		return nil
+	}
+	storeCtx, cancel := h.storeContext()
+	average, ok, err := h.venueAveragePerPerson(storeCtx, channel, venueName)
+	cancel()
+	if err != nil {
+		h.logger.ErrorContext(ctx, "Error computing the venue's average per person, not asking to confirm it", "venue", venueName, "error", err)
+		return nil
+	}
+	if !ok || average <= threshold {
+		return nil
+	}
+
+	warning := h.text(channel, msgPreauthRequired, venueName, average, h.currencyName(channel, ""), threshold, needed,
+		h.channelEmoji(channel, settingBlacklistConfirmEmoji, h.cfg.BlacklistConfirmationEmoji))
+	warningID, err := h.informEventContext(ctx, channel, warning, "", messageID)
+	if err != nil {
+		return fmt.Errorf("inform expensive venue: %w", err)
+	}
+	confirmed := h.preauthorizations.add(channel, warningID, needed)
+	defer h.preauthorizations.remove(channel, warningID)
+
+	select {
+	case <-confirmed:
+		return nil
+	case <-time.After(h.cfg.BlacklistConfirmationTimeout):
+		_, _ = h.informEventContext(ctx, channel, h.text(channel, msgPreauthNotConfirmed, h.preauthorizations.confirmations(channel, warningID), needed),
+			"", messageID)
+		return errNotConfirmed
+	}
+}
+
+func (h *Service) handlePreauthConfirmation(req ReactionAddRequest) bool {
+	if req.FromUserID == h.selfID {
+		return false
+	}
+	return h.preauthorizations.confirm(req.Channel, req.MessageID, req.FromUserID)
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oriser/bolt/order"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfirmExpensiveVenue(t *testing.T) {
+	t.Parallel()
+
+	orders := []*order.Order{
+		{ID: "1", Receiver: "C1", VenueName: "Sushi Bar", Status: order.StatusDone,
+			Participants: []order.Participant{{Name: "Thor", Amount: 120}, {Name: "Loki", Amount: 100}}},
+		{ID: "2", Receiver: "C1", VenueName: "Sushi Bar", Status: order.StatusCanceled,
+			Participants: []order.Participant{{Name: "Thor", Amount: 500}}},
+		{ID: "3", Receiver: "C1", VenueName: "Falafel", Status: order.StatusDone,
+			Participants: []order.Participant{{Name: "Thor", Amount: 30}}},
+	}
+	notification := &editingNotification{}
+	h, err := New(Config{
+
+		BlacklistConfirmationEmoji:   "white_check_mark",
+		BlacklistConfirmationTimeout: time.Minute,
+		PreauthThreshold:             80,
+		PreauthConfirmations:         2,
+	}, nil, nil, &filteringOrderStore{fakeOrderStore{orders: orders}}, "UBOT", notification)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	assert.NoError(t, h.confirmExpensiveVenue(ctx, "C1", "1.1", "Falafel"), "cheap venues don't need confirmations")
+	assert.NoError(t, h.confirmExpensiveVenue(ctx, "C1", "1.1", "Pizza Place"), "venues without orders don't need confirmations")
+	assert.Empty(t, notification.messages)
+
+	confirmed := make(chan error, 1)
+	go func() {
+		confirmed <- h.confirmExpensiveVenue(ctx, "C1", "1.1", "Sushi Bar")
+	}()
+	require.Eventually(t, func() bool {
+		h.preauthorizations.lock.Lock()
+		defer h.preauthorizations.lock.Unlock()
+		return len(h.preauthorizations.pending) == 1
+	}, time.Second, time.Millisecond)
+
+	for _, user := range []string{"UBOT", "U1", "U1"} {
+		_, err = h.HandleReactionAdded(ReactionAddRequest{Reaction: "white_check_mark", FromUserID: user, Channel: "C1", MessageID: "sent-1"})
+		require.NoError(t, err)
+	}
+	select {
+	case <-confirmed:
+		t.Fatal("confirmed by the bot or by the same user twice")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	_, err = h.HandleReactionAdded(ReactionAddRequest{Reaction: "white_check_mark", FromUserID: "U2", Channel: "C1", MessageID: "sent-1"})
+	require.NoError(t, err)
+	assert.NoError(t, <-confirmed)
+	assert.Equal(t, []string{"C1: :moneybag: Orders from [Sushi Bar] averaged 110.00 NIS per person here, more than 80.00. " +
+		"I'll track this order once 2 of you react with :white_check_mark: to this message"}, notification.messages)
+
+	h.cfg.BlacklistConfirmationTimeout = time.Millisecond
+	assert.ErrorIs(t, h.confirmExpensiveVenue(ctx, "C1", "1.1", "Sushi Bar"), errNotConfirmed)
+	assert.Equal(t, "C1: Only 0 of the 2 people needed confirmed, I won't track this order", notification.messages[len(notification.messages)-1])
+}
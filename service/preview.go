@@ -0,0 +1,98 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrOtherChannelPreview is returned for the preview of an order asked for outside the channel it's tracked in
+var ErrOtherChannelPreview = errors.New("the order is tracked in another channel, ask for its preview there")
+
+// parseGroupIDOrLink returns the group ID of a group order link, or the given text if it's already an ID
+func (h *Service) parseGroupIDOrLink(groupID string) string {
+	groupID = strings.Trim(strings.TrimSpace(groupID), "<>")
+	if group, ok := h.groupOfLink(h.lifetime(), groupID); ok {
+		return group.groupID
+	}
+	// The orders of the providers other than Wolt are kept under their provider's name, see orderID
+	if name, _, ok := strings.Cut(groupID, ":"); ok && name != "" {
+		if _, err := h.providerByName(name); err == nil {
+			return groupID
+		}
+	}
+	return strings.ToUpper(groupID)
+}
+
+// PreviewSplit posts a provisional split of a tracked group order which is still open, computed from the current carts of its
+// participants, in the thread of the message with the order link. It's only posted when asked for in the order's channel. It returns
+// the channel it was posted in.
+func (h *Service) PreviewSplit(channel, groupID string) (string, error) {
+	groupID = h.parseGroupIDOrLink(groupID)
+	order := h.workingOrders.get(groupID)
+	if order == nil {
+		return "", fmt.Errorf("I'm not tracking order %s, post its link in the channel first", groupID)
+	}
+	if order.channel != channel {
+		return "", ErrOtherChannelPreview
+	}
+
+	details, err := order.fetchDetails()
+	if err != nil {
+		return "", fmt.Errorf("get group details: %w", err)
+	}
+	if details.Status != OrderStatusActive {
+		return "", fmt.Errorf("order %s was already sent, its rates are in the rates message", groupID)
+	}
+
+	groupRate, err := h.previewGroupRate(order, details)
+	if err != nil {
+		return "", err
+	}
+	if _, err = h.informEvent(order.channel, h.buildPreviewMessage(order.channel, groupRate, groupID), "", order.messageID); err != nil {
+		return "", fmt.Errorf("post preview: %w", err)
+	}
+	return order.channel, nil
+}
+
+// previewGroupRate computes the rates of the participants from their current carts, the way they're computed once the order is sent
+func (h *Service) previewGroupRate(order *groupOrder, details *OrderDetails) (GroupRate, error) {
+	rates, err := h.discountedRates(details)
+	if err != nil {
+		return GroupRate{}, fmt.Errorf("rate by person: %w", err)
+	}
+
+	deliveryRate, err := paidDeliveryRate(order, details)
+	if err != nil {
+		h.logger.ErrorContext(order.ctx, "Error getting the delivery rate for the preview", "error", err)
+		deliveryRate = 0
+	}
+	fees := orderFees(details, deliveryRate)
+	if err == nil || fees != (Fees{}) {
+		rates = h.channelFeeAllocator(order.channel).Allocate(rates, details.Host, fees)
+	}
+	groupRate := h.buildGroupRates(rates, details.Host, deliveryRate)
+	groupRate.setFees(fees)
+	h.setItemAmounts(&groupRate, order.id, details)
+	groupRate.Currency = h.orderCurrency(order, details)
+	return groupRate, nil
+}
+
+// buildPreviewMessage returns the provisional split. It doesn't mention the participants, so they aren't notified of every preview,
+// and doesn't include "Wolt order ID", so reactions to it aren't taken for reactions to the rates message.
+func (h *Service) buildPreviewMessage(channel string, groupRate GroupRate, groupID string) string {
+	var sb strings.Builder
+	sb.WriteString(h.text(channel, msgPreviewHeader, groupID, groupRate.DeliveryRate, h.currencyName(channel, groupRate.Currency)))
+	for _, rate := range groupRate.Rates {
+		name := rate.WoltName
+		if rate.User != nil && rate.User.FullName != "" {
+			name = rate.User.FullName
+		}
+		sb.WriteString(fmt.Sprintf("%s: %.2f", name, rate.Amount))
+		if h.subsidized() {
+			sb.WriteString(h.text(channel, msgPersonalShare, rate.PersonalAmount()))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreviewSplit(t *testing.T) {
+	t.Parallel()
+
+	details, err := parseWoltOrderDetails([]byte(`{
+		"status": "active",
+		"host_id": "1",
+		"details": {"delivery_info": {"location": {"coordinates": {"coordinates": [34.78, 32.08]}}}},
+		"participants": [
+			{"first_name": "Thor", "user_id": "1", "basket": {"items": [{"name": "Pizza", "end_amount": 5000}]}},
+			{"first_name": "Loki", "user_id": "2", "basket": {"items": [{"name": "Salad", "end_amount": 3000}]}}
+		]
+	}`))
+	require.NoError(t, err)
+
+	h, err := New(Config{}, &fakeTreasuryStore{}, nil, nil, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+	order := &groupOrder{id: "ABC", channel: "C1", deliveryPrice: 10, details: details}
+	groupRate, err := h.previewGroupRate(order, details)
+	require.NoError(t, err)
+	assert.Equal(t, ":crystal_ball: Provisional split of order ABC from the current carts (including 10 NIS for delivery). "+
+		"It may change until the order is sent:\n"+
+		"Loki: 35.00\n"+
+		"Thor: 55.00\n", h.buildPreviewMessage("C1", groupRate, "ABC"))
+	assert.NotContains(t, h.buildPreviewMessage("C1", groupRate, "ABC"), "Wolt order ID",
+		"reactions to the preview must not be taken for reactions to the rates message")
+
+	assert.Equal(t, "ABC123", h.parseGroupIDOrLink("<https://wolt.com/en/group/ABC123>"))
+	assert.Equal(t, "ABC123", h.parseGroupIDOrLink("abc123"))
+	_, err = h.PreviewSplit("C1", "XYZ")
+	assert.EqualError(t, err, "I'm not tracking order XYZ, post its link in the channel first")
+
+	startWorkingOrder(t, h, "DEF", "C1")
+	_, err = h.PreviewSplit("C2", "DEF")
+	assert.ErrorIs(t, err, ErrOtherChannelPreview, "the preview isn't posted from another channel")
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oriser/bolt/wolt"
+)
+
+const (
+	// menuCacheTTL is how long the menu of a venue is cached, so asking about several items doesn't fetch it each time
+	menuCacheTTL = 15 * time.Minute
+	// maxPriceMatches is the maximal number of menu items a price question answers with
+	maxPriceMatches = 5
+)
+
+// venueMenu is a venue with its menu, as cached
+type venueMenu struct {
+	venue    *wolt.Venue
+	menu     *wolt.Menu
+	loadedAt time.Time
+}
+
+// menuCache caches the menus of the venues by their slug
+type menuCache struct {
+	lock  sync.Mutex
+	menus map[string]venueMenu
+}
+
+func newMenuCache() *menuCache {
+	return &menuCache{menus: make(map[string]venueMenu)}
+}
+
+func (c *menuCache) get(slug string) (venueMenu, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	menu, ok := c.menus[slug]
+	if !ok || time.Since(menu.loadedAt) > menuCacheTTL {
+		return venueMenu{}, false
+	}
+	return menu, true
+}
+
+func (c *menuCache) set(slug string, menu venueMenu) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	menu.loadedAt = time.Now()
+	c.menus[slug] = menu
+}
+
+// PriceAnswer is the answer to a "how much is X" question: the menu items of the venue matching the question
+type PriceAnswer struct {
+	VenueName string
+	Currency  string // The ISO 4217 code of the currency of the venue's prices
+	Items     []wolt.MenuItem
+	More      int // How many more items matched but were left out
+}
+
+// venueMenu returns the venue with its menu, from the cache if it was fetched lately
+func (h *Service) venueMenu(ctx context.Context, slug string) (venueMenu, error) {
+	if cached, ok := h.menus.get(slug); ok {
+		return cached, nil
+	}
+	addr, retryConfig := h.woltConfig()
+	venue, err := wolt.VenueBySlug(ctx, addr, retryConfig, slug)
+	if err != nil {
+		return venueMenu{}, fmt.Errorf("get venue: %w", err)
+	}
+	menu, err := wolt.MenuBySlug(ctx, addr, retryConfig, slug)
+	if err != nil {
+		return venueMenu{}, fmt.Errorf("get menu: %w", err)
+	}
+	fetched := venueMenu{venue: venue, menu: menu}
+	h.menus.set(slug, fetched)
+	return fetched, nil
+}
+
+// LookupPrice answers "how much is X" questions with the current price and options of the menu items of the venue (given by its
+// Wolt link or slug) whose name contains the item. An exact name match is the only answer.
+func (h *Service) LookupPrice(ctx context.Context, venue, item string) (*PriceAnswer, error) {
+	slug := venueSlug(venue)
+	item = strings.TrimSpace(item)
+	if slug == "" || item == "" {
+		return nil, fmt.Errorf("no venue or item given")
+	}
+	fetched, err := h.venueMenu(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	answer := &PriceAnswer{VenueName: fetched.venue.Name, Currency: h.venueCurrency(fetched.venue.Currency)}
+	for _, menuItem := range fetched.menu.Items {
+		if strings.EqualFold(menuItem.Name, item) {
+			answer.Items, answer.More = []wolt.MenuItem{menuItem}, 0
+			return answer, nil
+		}
+		if !strings.Contains(strings.ToLower(menuItem.Name), strings.ToLower(item)) {
+			continue
+		}
+		if len(answer.Items) == maxPriceMatches {
+			answer.More++
+			continue
+		}
+		answer.Items = append(answer.Items, menuItem)
+	}
+	return answer, nil
+}
+
+// BuildPriceMessage builds the answer to a price question
+func BuildPriceMessage(answer *PriceAnswer, item string) string {
+	if len(answer.Items) == 0 {
+		return fmt.Sprintf("I couldn't find %q on the menu of %s", item, answer.VenueName)
+	}
+	unit := CurrencyUnit(answer.Currency)
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("*%s*\n", answer.VenueName))
+	for _, menuItem := range answer.Items {
+		sb.WriteString(fmt.Sprintf("%s: %.2f %s", menuItem.Name, menuItem.Price(), unit))
+		if !menuItem.Enabled {
+			sb.WriteString(" (not available right now)")
+		}
+		sb.WriteString("\n")
+		for _, option := range menuItem.Options {
+			values := make([]string, len(option.Values))
+			for i, value := range option.Values {
+				values[i] = value.Name
+				if value.Price != 0 {
+					values[i] += fmt.Sprintf(" (%+.2f)", float64(value.Price)/100)
+				}
+			}
+			sb.WriteString(fmt.Sprintf("  • %s: %s\n", option.Name, strings.Join(values, ", ")))
+		}
+	}
+	if answer.More > 0 {
+		sb.WriteString(fmt.Sprintf("And %d more, ask about a longer name to narrow it down\n", answer.More))
+	}
+	return sb.String()
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const priceMenuJSON = `{"items": [
+	{"name": "Margherita", "baseprice": 4200, "enabled": true, "options": [
+		{"name": "Size", "values": [{"name": "Personal", "price": 0}, {"name": "Family", "price": 1500}]}
+	]},
+	{"name": "Margherita Vegan", "baseprice": 4600, "enabled": false},
+	{"name": "Pizza Marinara", "baseprice": 3800, "enabled": true}
+]}`
+
+func TestLookupPrice(t *testing.T) {
+	t.Parallel()
+
+	var menuRequests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/venues/slug/pizza-place":
+			_, _ = w.Write([]byte(estimateVenueJSON))
+		case "/v4/venues/slug/pizza-place/menu":
+			atomic.AddInt32(&menuRequests, 1)
+			_, _ = w.Write([]byte(priceMenuJSON))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	h, err := New(Config{WoltBaseAddr: server.URL, WoltApiBaseAddr: server.URL, Currency: "ILS"},
+		nil, nil, nil, "UBOT", nil)
+	require.NoError(t, err)
+
+	answer, err := h.LookupPrice(context.Background(), "https://wolt.com/en/isr/tel-aviv/restaurant/pizza-place", "margherita")
+	require.NoError(t, err)
+	require.Len(t, answer.Items, 1, "an exact match is the only answer")
+	assert.Equal(t, "*Pizza Place*\nMargherita: 42.00 NIS\n  • Size: Personal, Family (+15.00)\n", BuildPriceMessage(answer, "margherita"))
+
+	answer, err = h.LookupPrice(context.Background(), "pizza-place", "Marg")
+	require.NoError(t, err)
+	assert.Equal(t, "*Pizza Place*\nMargherita: 42.00 NIS\n  • Size: Personal, Family (+15.00)\n"+
+		"Margherita Vegan: 46.00 NIS (not available right now)\n", BuildPriceMessage(answer, "Marg"))
+
+	answer, err = h.LookupPrice(context.Background(), "pizza-place", "calzone")
+	require.NoError(t, err)
+	assert.Equal(t, `I couldn't find "calzone" on the menu of Pizza Place`, BuildPriceMessage(answer, "calzone"))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&menuRequests), "the menu is cached")
+
+	_, err = h.LookupPrice(context.Background(), "burger-place", "fries")
+	assert.ErrorContains(t, err, `venue "burger-place" not found`)
+}
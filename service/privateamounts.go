@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	userDomain "github.com/oriser/bolt/user"
+)
+
+func (h *Service) privateAmountsStore() (userDomain.PrivateAmountsStore, error) {
+	store, ok := h.userStore.(userDomain.PrivateAmountsStore)
+	if !ok {
+		return nil, fmt.Errorf("private amounts are not supported")
+	}
+	return store, nil
+}
+
+// SetPrivateAmounts sets whether the user (by transport ID) keeps their amounts out of the rates messages. Their debts are still
+// tracked, and they get their amount of every order by a direct message instead.
+func (h *Service) SetPrivateAmounts(ctx context.Context, transportID string, private bool) error {
+	store, err := h.privateAmountsStore()
+	if err != nil {
+		return err
+	}
+	if err := store.SetPrivateAmounts(ctx, transportID, private); err != nil {
+		return fmt.Errorf("set private amounts: %w", err)
+	}
+	return nil
+}
+
+// privateAmounts returns whether the user (by transport ID) keeps their amounts private
+func (h *Service) privateAmounts(transportID string) bool {
+	store, err := h.privateAmountsStore()
+	if err != nil {
+		return false
+	}
+	ctx, cancel := h.storeContext()
+	defer cancel()
+	private, err := store.PrivateAmounts(ctx, transportID)
+	if err != nil {
+		h.logger.Error("Error checking if the user's amounts are private", "transport_id", transportID, "error", err)
+		return false
+	}
+	return private
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oriser/bolt/order"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePrivateAmountsStore struct {
+	*fakeTreasuryStore
+	private map[string]bool
+}
+
+func (f *fakePrivateAmountsStore) SetPrivateAmounts(_ context.Context, transportID string, private bool) error {
+	f.private[transportID] = private
+	return nil
+}
+
+func (f *fakePrivateAmountsStore) PrivateAmounts(_ context.Context, transportID string) (bool, error) {
+	return f.private[transportID], nil
+}
+
+func TestPrivateAmounts(t *testing.T) {
+	t.Parallel()
+
+	treasuryStore := &fakeTreasuryStore{users: map[string]*userDomain.User{
+		"u1": {ID: "u1", FullName: "Thor", TransportID: "U1"},
+		"u2": {ID: "u2", FullName: "Loki", TransportID: "U2"},
+		"u3": {ID: "u3", FullName: "Odin", TransportID: "U3"},
+	}}
+	store := &fakePrivateAmountsStore{fakeTreasuryStore: treasuryStore, private: make(map[string]bool)}
+	notification := &recordingNotification{}
+	h, err := New(Config{}, store, treasuryStore, nil, "UBOT", notification)
+	require.NoError(t, err)
+	h.DisableDebtWorkers()
+	require.NoError(t, h.SetPrivateAmounts(context.Background(), "U2", true))
+
+	groupRate := h.buildGroupRates(map[string]float64{"Thor": 50, "Loki": 30.5, "Odin": 20}, "Thor", 0)
+	message := h.buildRatesMessage("C1", groupRate, "ABC")
+	assert.Contains(t, message, "<@U2> (Loki): :lock: sent privately\n")
+	assert.NotContains(t, message, "30.50")
+	assert.Contains(t, message, "<@U3> (Odin): 20.00\n")
+
+	require.NoError(t, h.addDebts(context.Background(), "C1", "ABC", groupRate, "1.1"))
+	assert.Len(t, treasuryStore.debts, 2, "the debts of private amounts are tracked too")
+	var dms []string
+	for _, message := range notification.messages {
+		if message[0] == 'U' {
+			dms = append(dms, message)
+		}
+	}
+	require.Len(t, dms, 1, "only who keeps their amounts private gets a DM, as DEBT_DMS is off")
+	assert.Contains(t, dms[0], "U2: :receipt: You owe 30.50 NIS to <@U1> for Wolt order ID ABC")
+}
+
+func TestPrivateAmountsNotShown(t *testing.T) {
+	t.Parallel()
+
+	treasuryStore := &fakeTreasuryStore{users: map[string]*userDomain.User{
+		"u1": {ID: "u1", FullName: "Thor", TransportID: "U1"},
+		"u2": {ID: "u2", FullName: "Loki", TransportID: "U2"},
+	}}
+	store := &fakePrivateAmountsStore{fakeTreasuryStore: treasuryStore, private: map[string]bool{"U2": true}}
+	h, err := New(Config{}, store, treasuryStore, nil, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+
+	previous := h.buildGroupRates(map[string]float64{"Thor": 50, "Loki": 30.5}, "Thor", 0)
+	updated := h.buildGroupRates(map[string]float64{"Thor": 50, "Loki": 20}, "Thor", 0)
+	message := h.buildChangedRatesMessage("C1", "Updated:\n", previous, updated, []string{"Loki"})
+	assert.Equal(t, "Updated:\n<@U2> (Loki): :lock: sent privately\n", message)
+
+	participants := participantsOfRates(previous.Rates)
+	assert.True(t, participants[0].Private, "the privacy of the amounts is stored with the order")
+	stored := &order.Order{OriginalID: "ABC", VenueName: "Pizza", Host: "Thor", CreatedAt: time.Now(), Participants: participants}
+	summary, details := BuildHistoryMessage([]HistoryOrder{{Order: stored, Participants: []HistoryParticipant{
+		{Participant: participants[0], User: treasuryStore.users["u2"]},
+		{Participant: participants[1], User: treasuryStore.users["u1"]},
+	}}}, "<#C1>")
+	assert.Contains(t, summary, "Pizza - :lock:, hosted by <@U1>", "the total would give away the private amount")
+	assert.Equal(t, "*1. Pizza*: <@U2> :lock:, <@U1> 50.00 nis\n", details)
+
+	page := h.storedStatusPage(stored)
+	assert.Equal(t, []StatusPageAmount{{Name: "Thor", Amount: 50}}, page.Amounts)
+}
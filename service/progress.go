@@ -0,0 +1,43 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// waitProgress is the last progress note of an order waiting for the group to be sent
+type waitProgress struct {
+	lastNoteAt time.Time
+	lastNote   string
+}
+
+// waitProgressNote returns the note about who the group is waiting for
+func waitProgressNote(details *OrderDetails) string {
+	notReady := details.NotReadyParticipants()
+	switch len(notReady) {
+	case 0:
+		return "Everyone is ready, still waiting for the host to send the order :hourglass_flowing_sand:"
+	case 1:
+		return fmt.Sprintf("Still waiting for 1 participant to mark ready: %s", notReady[0])
+	default:
+		return fmt.Sprintf("Still waiting for %d participants to mark ready: %s", len(notReady), strings.Join(notReady, ", "))
+	}
+}
+
+// noteWaitProgress notes in the thread of the order who the group is waiting for, at most once every WAIT_PROGRESS_INTERVAL and
+// only when it changed since the previous note
+func (h *Service) noteWaitProgress(order *groupOrder, details *OrderDetails, progress *waitProgress, now time.Time) {
+	if h.cfg.WaitProgressInterval <= 0 || now.Sub(progress.lastNoteAt) < h.cfg.WaitProgressInterval {
+		return
+	}
+	note := waitProgressNote(details)
+	if note == progress.lastNote {
+		return
+	}
+	progress.lastNoteAt = now
+	progress.lastNote = note
+	if _, err := h.informEvent(order.channel, note, "", order.messageID); err != nil {
+		h.logger.ErrorContext(order.ctx, "Error noting the progress of the order", "error", err)
+	}
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoteWaitProgress(t *testing.T) {
+	t.Parallel()
+
+	details, err := parseWoltOrderDetails([]byte(`{
+		"host_id": "1",
+		"status": "active",
+		"details": {"delivery_info": {"location": {"coordinates": {"coordinates": [34.78, 32.08]}}}},
+		"participants": [
+			{"first_name": "Thor", "user_id": "1", "basket": {"items": [{"name": "Pizza", "end_amount": 5000}]}},
+			{"first_name": "Dana", "user_id": "2", "basket": {"items": [{"name": "Salad", "end_amount": 3000}]}},
+			{"first_name": "Yossi", "user_id": "3", "basket": {"items": [{"name": "Soup", "end_amount": 2000}]}},
+			{"first_name": "Loki", "user_id": "4", "status": "ready", "basket": {"items": [{"name": "Fries", "end_amount": 1000}]}},
+			{"first_name": "Bolt", "user_id": "5", "status": "ready", "basket": {"items": []}}
+		]
+	}`))
+	require.NoError(t, err)
+
+	notification := &recordingNotification{}
+	h, err := New(Config{WaitProgressInterval: time.Minute}, nil, nil, nil, "U-bot", notification)
+	require.NoError(t, err)
+	order := &groupOrder{id: "A", channel: "C1", messageID: "1.1"}
+	start := time.Now()
+	progress := &waitProgress{lastNoteAt: start}
+
+	h.noteWaitProgress(order, details, progress, start.Add(30*time.Second))
+	assert.Empty(t, notification.messages, "the notes should be throttled")
+	h.noteWaitProgress(order, details, progress, start.Add(time.Minute))
+	assert.Equal(t, []string{"C1: Still waiting for 2 participants to mark ready: Dana, Yossi"}, notification.messages)
+	h.noteWaitProgress(order, details, progress, start.Add(3*time.Minute))
+	assert.Len(t, notification.messages, 1, "the same note shouldn't be repeated")
+
+	details.Participants[1].Ready = true
+	h.noteWaitProgress(order, details, progress, start.Add(4*time.Minute))
+	details.Participants[2].Ready = true
+	h.noteWaitProgress(order, details, progress, start.Add(5*time.Minute))
+	assert.Equal(t, []string{
+		"C1: Still waiting for 2 participants to mark ready: Dana, Yossi",
+		"C1: Still waiting for 1 participant to mark ready: Yossi",
+		"C1: Everyone is ready, still waiting for the host to send the order :hourglass_flowing_sand:",
+	}, notification.messages)
+
+	h.cfg.WaitProgressInterval = 0
+	details.Participants[1].Ready = false
+	h.noteWaitProgress(order, details, progress, start.Add(time.Hour))
+	assert.Len(t, notification.messages, 3, "the notes are disabled")
+}
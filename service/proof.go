@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/oriser/bolt/order"
+)
+
+// ProofRequest is a file shared in a thread, which is the proof of purchase of the thread's order if its host shared it
+type ProofRequest struct {
+	Channel  string
+	ThreadID string // The message the thread is of
+	UserID   string
+	FileURL  string // A link to the file which the members of the workspace can open
+	MimeType string
+}
+
+// isProofFile returns whether the file can be a proof of purchase: a photo of the receipt or of the delivered bags, or a receipt document
+func isProofFile(mimeType string) bool {
+	return strings.HasPrefix(mimeType, "image/") || mimeType == "application/pdf"
+}
+
+func (h *Service) proofStore() (order.ProofStore, error) {
+	proofStore, ok := h.orderStore.(order.ProofStore)
+	if !ok {
+		return nil, fmt.Errorf("order proofs are not supported")
+	}
+	return proofStore, nil
+}
+
+// HandleOrderProof links the photo the host (or their co-host) shared in the thread of the order link to the stored order, so the
+// exports and the dashboard show it as the proof of purchase. Files of other users and threads of other messages are ignored, and
+// the response is empty for them.
+func (h *Service) HandleOrderProof(ctx context.Context, req ProofRequest) (string, error) {
+	if req.ThreadID == "" || !isProofFile(req.MimeType) || h.orderStore == nil || h.userStore == nil {
+		return "", nil
+	}
+	orders, err := h.orderStore.ListOrders(ctx, order.ListFilter{Receiver: req.Channel, MessageID: req.ThreadID, Limit: 1})
+	if err != nil {
+		return "", fmt.Errorf("list orders: %w", err)
+	}
+	if len(orders) == 0 {
+		// Not a thread of an order with published rates
+		return "", nil
+	}
+	o := orders[0]
+
+	hosts, err := h.listUsersByName(o.Host)
+	if err != nil {
+		return "", fmt.Errorf("list users: %w", err)
+	}
+	if len(hosts) != 1 || !h.actsForHost(hosts[0].TransportID, req.UserID) {
+		return "", nil
+	}
+
+	proofStore, err := h.proofStore()
+	if err != nil {
+		return "", err
+	}
+	replaced := o.ProofURL != ""
+	if err := proofStore.SetOrderProof(ctx, o.OriginalID, req.FileURL); err != nil {
+		return "", fmt.Errorf("set order proof: %w", err)
+	}
+	if replaced {
+		return fmt.Sprintf("Thanks <@%s>, I replaced the proof of purchase of order %s with this file :receipt:", req.UserID, o.OriginalID), nil
+	}
+	return fmt.Sprintf("Thanks <@%s>, I attached this file to order %s as its proof of purchase :receipt:", req.UserID, o.OriginalID), nil
+}
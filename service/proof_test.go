@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/oriser/bolt/order"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeProofStore struct {
+	fakeOrderStore
+}
+
+func (f *fakeProofStore) SetOrderProof(_ context.Context, originalID, proofURL string) error {
+	for _, o := range f.orders {
+		if o.OriginalID == originalID {
+			o.ProofURL = proofURL
+		}
+	}
+	return nil
+}
+
+func TestHandleOrderProof(t *testing.T) {
+	t.Parallel()
+
+	users := &fakeTreasuryStore{
+		users: map[string]*userDomain.User{
+			"uuid-host": {ID: "uuid-host", FullName: "Thor", TransportID: "U-host"},
+			"uuid-loki": {ID: "uuid-loki", FullName: "Loki", TransportID: "U-loki"},
+		},
+	}
+	orders := &fakeProofStore{fakeOrderStore{orders: []*order.Order{
+		{OriginalID: "ABC", Receiver: "C1", MessageID: "1.1", Host: "Thor"},
+	}}}
+	h, err := New(Config{}, users, nil, orders, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+	ctx := context.Background()
+	req := ProofRequest{Channel: "C1", ThreadID: "1.1", UserID: "U-host", FileURL: "https://files.example.com/receipt.jpg", MimeType: "image/jpeg"}
+
+	response, err := h.HandleOrderProof(ctx, ProofRequest{Channel: "C1", ThreadID: "1.1", UserID: "U-loki", FileURL: "https://files.example.com/pizza.jpg",
+		MimeType: "image/jpeg"})
+	require.NoError(t, err)
+	assert.Empty(t, response, "only the host attaches proofs")
+	response, err = h.HandleOrderProof(ctx, ProofRequest{Channel: "C1", ThreadID: "2.2", UserID: "U-host", FileURL: req.FileURL, MimeType: req.MimeType})
+	require.NoError(t, err)
+	assert.Empty(t, response, "not a thread of an order")
+	response, err = h.HandleOrderProof(ctx, ProofRequest{Channel: "C1", ThreadID: "1.1", UserID: "U-host", FileURL: "https://files.example.com/notes.txt",
+		MimeType: "text/plain"})
+	require.NoError(t, err)
+	assert.Empty(t, response)
+	assert.Empty(t, orders.orders[0].ProofURL)
+
+	response, err = h.HandleOrderProof(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, "Thanks <@U-host>, I attached this file to order ABC as its proof of purchase :receipt:", response)
+	assert.Equal(t, "https://files.example.com/receipt.jpg", orders.orders[0].ProofURL)
+
+	req.FileURL, req.MimeType = "https://files.example.com/receipt.pdf", "application/pdf"
+	response, err = h.HandleOrderProof(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, "Thanks <@U-host>, I replaced the proof of purchase of order ABC with this file :receipt:", response)
+	assert.Equal(t, "https://files.example.com/receipt.pdf", orders.orders[0].ProofURL)
+}
@@ -0,0 +1,302 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/oriser/bolt/wolt"
+)
+
+const (
+	// providerWolt is the name of the Wolt provider, which is also the provider of the orders tracked before there were others
+	providerWolt = "wolt"
+	// shortLinkTimeout is how long resolving a short link of the Wolt mobile app may take
+	shortLinkTimeout = 10 * time.Second
+)
+
+// Provider is a food delivery service whose group orders Bolt joins and tracks. Wolt is the built-in provider, other services are
+// added with AddProvider, and the links shared in the channels go to the provider of their domain.
+type Provider interface {
+	// Name identifies the provider, it's persisted with the tracked orders to resume them with the same provider
+	Name() string
+	// Domains are the hosts of the provider's links, a link of a subdomain is the provider's as well. A domain may also be the scheme
+	// of the deep links of the provider's app (e.g. wolt for wolt://group/ABC123).
+	Domains() []string
+	// GroupID returns the ID of the group order of the link, and false if it's not a group order link
+	GroupID(ctx context.Context, link string) (string, bool)
+	// Join joins the group order, so its details can be fetched. Its error wraps one of the wolt join errors (e.g. wolt.ErrGroupFull)
+	// for the channel to be told why Bolt couldn't join.
+	Join(ctx context.Context, groupID string) (ProviderGroup, error)
+	// Restore returns a group order joined before a restart from its persisted session, without joining it again
+	Restore(groupID string, session json.RawMessage) (ProviderGroup, error)
+}
+
+// ProviderGroup is a group order joined in a provider, which maps its details and venue to the service's OrderDetails and Venue
+type ProviderGroup interface {
+	Details(ctx context.Context) (*OrderDetails, error)
+	// VenueDetails returns the venue of the order, with its delivery rate to the address of the order
+	VenueDetails(ctx context.Context, details *OrderDetails) (*Venue, error)
+	// MarkAsReady marks Bolt as ready in the group, so it doesn't hold up the host from sending the order
+	MarkAsReady(ctx context.Context) error
+	// Session is the state needed for restoring the group after a restart, persisted as JSON
+	Session() interface{}
+}
+
+// AddProvider adds a delivery provider, whose group order links are tracked like Wolt's
+func (h *Service) AddProvider(provider Provider) {
+	h.providers = append(h.providers, provider)
+}
+
+// providerByName returns the provider with the name, Wolt if the name is empty
+func (h *Service) providerByName(name string) (Provider, error) {
+	if name == "" {
+		name = providerWolt
+	}
+	for _, provider := range h.providers {
+		if provider.Name() == name {
+			return provider, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown provider %q", name)
+}
+
+// providerOfLink returns the provider whose domain the link is of, and false if the link isn't of any provider
+func (h *Service) providerOfLink(link string) (Provider, bool) {
+	parsed, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return nil, false
+	}
+	host := strings.ToLower(parsed.Hostname())
+	scheme := strings.ToLower(parsed.Scheme)
+	for _, provider := range h.providers {
+		for _, domain := range provider.Domains() {
+			domain = strings.ToLower(domain)
+			if host == domain || strings.HasSuffix(host, "."+domain) || (scheme == domain && host != "") {
+				return provider, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// orderID returns the ID Bolt keeps a group order of the provider under, in the working orders, the debts and the stored orders. The
+// group IDs of the providers other than Wolt are prefixed with their name, so the groups of different providers can't collide, while
+// Wolt's are kept as is like the orders tracked before there were other providers.
+func orderID(provider Provider, groupID string) string {
+	if provider.Name() == providerWolt {
+		return groupID
+	}
+	return provider.Name() + ":" + groupID
+}
+
+// providerGroupID returns the provider's ID of the group order Bolt keeps under the order ID
+func providerGroupID(provider Provider, orderID string) string {
+	return strings.TrimPrefix(orderID, provider.Name()+":")
+}
+
+// groupLink is a group order shared in a link, with the provider of the link
+type groupLink struct {
+	provider Provider
+	groupID  string // The ID Bolt keeps the order under, see orderID
+}
+
+// groupOfLink returns the group order of the link, dispatching it to the provider of its domain
+func (h *Service) groupOfLink(ctx context.Context, link string) (groupLink, bool) {
+	provider, ok := h.providerOfLink(link)
+	if !ok {
+		return groupLink{}, false
+	}
+	groupID, ok := provider.GroupID(ctx, link)
+	if !ok {
+		return groupLink{}, false
+	}
+	return groupLink{provider: provider, groupID: orderID(provider, groupID)}, true
+}
+
+// woltProvider is the Wolt provider, configured by the WOLT_ settings of the service
+type woltProvider struct {
+	h *Service
+}
+
+func (p *woltProvider) Name() string {
+	return providerWolt
+}
+
+func (p *woltProvider) Domains() []string {
+	return []string{"wolt.com", "wolt.app.link", "wolt.onelink.me", "wolt"}
+}
+
+// GroupID returns the ID of the group order of a Wolt link, resolving the short links of the mobile app
+func (p *woltProvider) GroupID(ctx context.Context, link string) (string, bool) {
+	if groupID, ok := wolt.GroupIDFromLink(link); ok {
+		return groupID, true
+	}
+	if !wolt.IsShortLink(link) {
+		return "", false
+	}
+	ctx, cancel := context.WithTimeout(ctx, shortLinkTimeout)
+	defer cancel()
+	groupID, err := wolt.ResolveShortLink(ctx, http.DefaultClient, link)
+	if err != nil {
+		p.h.logger.ErrorContext(ctx, "Error resolving short link", "link", link, "error", err)
+		return "", false
+	}
+	return groupID, true
+}
+
+func (p *woltProvider) Join(ctx context.Context, groupID string) (ProviderGroup, error) {
+	addr, retryConfig := p.h.woltConfig()
+	g, err := wolt.NewGroupWithExistingID(addr, retryConfig, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("new existing group: %w", err)
+	}
+	if err := g.Join(ctx); err != nil {
+		return nil, fmt.Errorf("join group: %w", err)
+	}
+	return &woltGroup{g}, nil
+}
+
+func (p *woltProvider) Restore(groupID string, session json.RawMessage) (ProviderGroup, error) {
+	woltSession := wolt.GroupSession{}
+	if err := json.Unmarshal(session, &woltSession); err != nil {
+		return nil, fmt.Errorf("unmarshal Wolt session: %w", err)
+	}
+	addr, retryConfig := p.h.woltConfig()
+	g, err := wolt.RestoreGroup(addr, retryConfig, groupID, woltSession)
+	if err != nil {
+		return nil, fmt.Errorf("restore group: %w", err)
+	}
+	return &woltGroup{g}, nil
+}
+
+// woltGroup is a Wolt group order, as a ProviderGroup
+type woltGroup struct {
+	*wolt.Group
+}
+
+func (g *woltGroup) Details(ctx context.Context) (*OrderDetails, error) {
+	details, err := g.Group.Details(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return woltOrderDetails(details), nil
+}
+
+func (g *woltGroup) VenueDetails(ctx context.Context, details *OrderDetails) (*Venue, error) {
+	woltDetails := &wolt.OrderDetails{}
+	woltDetails.Details.VenueID = details.VenueID
+	venue, err := g.Group.VenueDetails(ctx, woltDetails)
+	if err != nil {
+		return nil, err
+	}
+	deliveryRate, err := venue.CalculateDeliveryRate(wolt.Coordinate(details.DeliveryLocation))
+	if err != nil {
+		return nil, fmt.Errorf("calculate delivery rate: %w", err)
+	}
+	return woltVenue(venue, deliveryRate), nil
+}
+
+func (g *woltGroup) Session() interface{} {
+	return g.Group.Session()
+}
+
+// woltDeliveryStates are the Wolt delivery statuses mapped to the state they represent
+var woltDeliveryStates = map[wolt.DeliveryStatus]DeliveryState{
+	"received":                   DeliveryStateReceived,
+	"acknowledged":               DeliveryStateReceived,
+	"production":                 DeliveryStateProduction,
+	"ready":                      DeliveryStatePickup,
+	"fetched":                    DeliveryStatePickup,
+	"pickup":                     DeliveryStatePickup,
+	wolt.DeliveryStatusDelivered: DeliveryStateDelivered,
+}
+
+// woltOrderDetails maps the details of a Wolt order, whose amounts are in cents, to the service's
+func woltOrderDetails(details *wolt.OrderDetails) *OrderDetails {
+	status := OrderStatus(details.Status)
+	switch {
+	case details.Status == wolt.StatusActive:
+		status = OrderStatusActive
+	case details.Status == wolt.StatusCanceled:
+		status = OrderStatusCanceled
+	case details.Status.Purchased():
+		status = OrderStatusPurchased
+	}
+	purchase := details.Purchase
+	mapped := &OrderDetails{
+		Status:           status,
+		Currency:         details.Currency,
+		VenueID:          details.Details.VenueID,
+		DeliveryLocation: Location(details.ParsedDeliveryCoordinate),
+		HostID:           details.HostID,
+		Host:             details.Host,
+		Participants:     make([]Participant, 0, len(details.Participants)),
+		CreatedAt:        details.CreatedAt,
+		PurchaseDatetime: details.PurchaseDatetime,
+		DeliveryEta:      details.DeliveryEta,
+		DeliveryState:    woltDeliveryStates[purchase.DeliveryStatus],
+		DeliveredAt:      purchase.DeliveryStatusLog[wolt.DeliveryStatusDelivered],
+		Credits:          purchase.Credits / 100,
+		ServiceFee:       purchase.ServiceFee / 100,
+		Tip:              purchase.Tip / 100,
+	}
+	for _, participant := range details.Participants {
+		mapped.Participants = append(mapped.Participants, Participant{
+			ID:    participant.UserID,
+			Name:  participant.Name(),
+			Ready: participant.Status == wolt.ParticipantStatusReady,
+			Items: woltItems(participant.Basket.Items),
+		})
+	}
+	for _, delivery := range purchase.Deliveries {
+		mapped.Deliveries = append(mapped.Deliveries, Delivery{
+			State: woltDeliveryStates[delivery.DeliveryStatus],
+			Eta:   delivery.DeliveryEta,
+			Items: woltItems(delivery.Items),
+		})
+	}
+	// The delivery of a Wolt+ subscriber is free
+	if price, ok := details.PaidDeliveryPrice(); ok {
+		mapped.DeliveryPrice = &price
+	}
+	for _, discount := range purchase.Discounts {
+		mapped.Discounts = append(mapped.Discounts, Discount{Name: discount.Name, Amount: discount.Amount / 100})
+	}
+	return mapped
+}
+
+func woltItems(items []wolt.Item) []Item {
+	mapped := make([]Item, 0, len(items))
+	for _, item := range items {
+		mapped = append(mapped, Item{
+			Name:          item.Name,
+			Amount:        item.EndAmount / 100,
+			Count:         item.Count,
+			AgeRestricted: item.AgeRestricted,
+			Alcohol:       item.IsAlcohol(),
+		})
+	}
+	return mapped
+}
+
+// woltVenue maps a Wolt venue, with its delivery rate to the address of the order, to the service's
+func woltVenue(venue *wolt.Venue, deliveryRate int) *Venue {
+	return &Venue{
+		Name:         venue.Name,
+		Link:         venue.Link,
+		City:         venue.City,
+		Currency:     venue.Currency,
+		Timezone:     venue.TimezoneLocation,
+		Delivering:   venue.IsDelivering(),
+		Preorders:    venue.IsOpenForPreorderDelivery(),
+		OfflineUntil: venue.OfflinePeriodEnd,
+		Busy:         venue.IsBusy(),
+		MinimumOrder: venue.MinimumOrder(),
+		DeliveryRate: deliveryRate,
+	}
+}
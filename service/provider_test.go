@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/oriser/bolt/order"
+	"github.com/oriser/bolt/wolt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProvider takes the last part of the path of its links as the group ID, and restores its groups from the session they were saved with
+type fakeProvider struct {
+	name    string
+	domains []string
+}
+
+func (p *fakeProvider) Name() string {
+	return p.name
+}
+
+func (p *fakeProvider) Domains() []string {
+	return p.domains
+}
+
+func (p *fakeProvider) GroupID(_ context.Context, link string) (string, bool) {
+	parsed, err := url.Parse(link)
+	if err != nil || !strings.HasPrefix(parsed.Path, "/group/") {
+		return "", false
+	}
+	return path.Base(parsed.Path), true
+}
+
+func (p *fakeProvider) Join(_ context.Context, groupID string) (ProviderGroup, error) {
+	return &fakeProviderGroup{session: groupID}, nil
+}
+
+func (p *fakeProvider) Restore(_ string, session json.RawMessage) (ProviderGroup, error) {
+	group := &fakeProviderGroup{}
+	if err := json.Unmarshal(session, &group.session); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+type fakeProviderGroup struct {
+	session string
+}
+
+func (g *fakeProviderGroup) Details(context.Context) (*OrderDetails, error) {
+	return &OrderDetails{Status: OrderStatusActive}, nil
+}
+
+func (g *fakeProviderGroup) VenueDetails(context.Context, *OrderDetails) (*Venue, error) {
+	return &Venue{Name: "Pizza"}, nil
+}
+
+func (g *fakeProviderGroup) MarkAsReady(context.Context) error {
+	return nil
+}
+
+func (g *fakeProviderGroup) Session() interface{} {
+	return g.session
+}
+
+func TestGroupLinksDispatchByDomain(t *testing.T) {
+	t.Parallel()
+
+	h, err := New(Config{}, nil, nil, nil, "UBOT", nil)
+	require.NoError(t, err)
+	h.AddProvider(&fakeProvider{name: "tenbis", domains: []string{"10bis.co.il"}})
+
+	groups := h.groupLinks(context.Background(), []Link{
+		{Domain: "www.10bis.co.il", URL: "https://www.10bis.co.il/group/T123"},
+		{Domain: "wolt.com", URL: "https://wolt.com/en/group/ABC123"},
+		{Domain: "not10bis.co.il", URL: "https://not10bis.co.il/group/X1"},
+		{Domain: "10bis.co.il", URL: "https://10bis.co.il/restaurant/pizza"},
+	})
+	require.Len(t, groups, 2)
+	assert.Equal(t, "tenbis", groups[0].provider.Name())
+	assert.Equal(t, "tenbis:T123", groups[0].groupID, "the group IDs of other providers are namespaced")
+	assert.Equal(t, providerWolt, groups[1].provider.Name())
+	assert.Equal(t, "ABC123", groups[1].groupID)
+	assert.Equal(t, "tenbis:T123", h.parseGroupIDOrLink("<https://10bis.co.il/group/T123>"))
+	assert.Equal(t, "tenbis:T123", h.parseGroupIDOrLink("tenbis:T123"))
+	assert.Equal(t, "ABC123", h.parseGroupIDOrLink("abc123"))
+}
+
+func TestRestoreTrackedOrderOfProvider(t *testing.T) {
+	t.Parallel()
+
+	store := &fakeTrackingStore{tracked: make(map[string]*order.TrackedOrder)}
+	h, err := New(Config{}, nil, nil, store, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+	provider := &fakeProvider{name: "tenbis", domains: []string{"10bis.co.il"}}
+	h.AddProvider(provider)
+
+	joined, err := h.joinGroupOrder(context.Background(), groupLink{provider: provider, groupID: "tenbis:T123"})
+	require.NoError(t, err)
+	assert.Equal(t, "T123", joined.group.Session(), "the provider joins its own group ID")
+	joined.channel, joined.messageID = "C1", "1.1"
+	h.saveTracking(joined, "")
+	require.Contains(t, store.tracked, "tenbis:T123")
+	assert.Equal(t, "tenbis", store.tracked["tenbis:T123"].Provider)
+
+	restored, err := h.restoreTrackedOrder(store.tracked["tenbis:T123"])
+	require.NoError(t, err)
+	assert.Equal(t, "tenbis:T123", restored.id)
+	assert.Equal(t, "tenbis", restored.provider.Name())
+	assert.Equal(t, "T123", restored.group.Session())
+
+	_, err = h.restoreTrackedOrder(&order.TrackedOrder{GroupID: "D1", Provider: "deliveroo", Session: `"D1"`})
+	assert.EqualError(t, err, `unknown provider "deliveroo"`)
+}
+
+// parseWoltOrderDetails parses the details of a Wolt order, as the Wolt provider maps them
+func parseWoltOrderDetails(detailsJSON []byte) (*OrderDetails, error) {
+	details, err := wolt.ParseOrderDetails(detailsJSON)
+	if err != nil {
+		return nil, err
+	}
+	return woltOrderDetails(details), nil
+}
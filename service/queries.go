@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	"github.com/oriser/bolt/order"
+)
+
+// UserDebts is the outstanding debts of a user, from the newest to the oldest
+type UserDebts struct {
+	Owes []TreasuryDebt // The debts the user is the borrower of
+	Owed []TreasuryDebt // The debts the user is the lender of
+}
+
+func totalDebts(debts []TreasuryDebt) float64 {
+	total := 0.0
+	for _, d := range debts {
+		total += d.Debt.Amount
+	}
+	return total
+}
+
+// TotalOwes returns the amount the user owes
+func (d *UserDebts) TotalOwes() float64 {
+	return totalDebts(d.Owes)
+}
+
+// TotalOwed returns the amount the user is owed
+func (d *UserDebts) TotalOwed() float64 {
+	return totalDebts(d.Owed)
+}
+
+// UserDebts returns the outstanding debts of the user (by transport ID). If withTransportID is set, only the debts between the user
+// and that user are returned.
+func (h *Service) UserDebts(ctx context.Context, transportID, withTransportID string) (*UserDebts, error) {
+	if h.debtStore == nil {
+		return nil, fmt.Errorf("no debt store")
+	}
+	userIDs, err := h.userIDsOfTransport(ctx, transportID)
+	if err != nil {
+		return nil, fmt.Errorf("user IDs of %s: %w", transportID, err)
+	}
+	var withUserIDs map[string]bool
+	if withTransportID != "" {
+		if withUserIDs, err = h.userIDsOfTransport(ctx, withTransportID); err != nil {
+			return nil, fmt.Errorf("user IDs of %s: %w", withTransportID, err)
+		}
+	}
+
+	debts, err := h.debtStore.ListDebts(ctx, debtDomain.ListFilter{UserIDs: sortedSet(userIDs)})
+	if err != nil {
+		return nil, fmt.Errorf("list debts: %w", err)
+	}
+	sort.SliceStable(debts, func(i, j int) bool {
+		return debts[i].CreatedAt.After(debts[j].CreatedAt)
+	})
+
+	userDebts := &UserDebts{Owes: make([]TreasuryDebt, 0), Owed: make([]TreasuryDebt, 0)}
+	for _, d := range h.debtsWithUsers(ctx, debts) {
+		switch {
+		case userIDs[d.Debt.BorrowerID] && (withUserIDs == nil || withUserIDs[d.Debt.LenderID]):
+			userDebts.Owes = append(userDebts.Owes, d)
+		case userIDs[d.Debt.LenderID] && (withUserIDs == nil || withUserIDs[d.Debt.BorrowerID]):
+			userDebts.Owed = append(userDebts.Owed, d)
+		}
+	}
+	return userDebts, nil
+}
+
+// OrdersOfDay returns the stored orders sent to the channel on the given number of days ago (0 is today, in the channel's timezone),
+// from the newest to the oldest
+func (h *Service) OrdersOfDay(ctx context.Context, channel string, daysAgo int) ([]*order.Order, error) {
+	if h.orderStore == nil {
+		return nil, fmt.Errorf("no order store")
+	}
+	orders, err := h.orderStore.ListOrders(ctx, order.ListFilter{Receiver: channel})
+	if err != nil {
+		return nil, fmt.Errorf("list orders: %w", err)
+	}
+
+	from := dayStart(time.Now().In(h.timezoneForChannel(channel, nil))).AddDate(0, 0, -daysAgo)
+	to := from.AddDate(0, 0, 1)
+	dayOrders := make([]*order.Order, 0)
+	for _, o := range orders {
+		if !o.CreatedAt.Before(from) && o.CreatedAt.Before(to) {
+			dayOrders = append(dayOrders, o)
+		}
+	}
+	sort.SliceStable(dayOrders, func(i, j int) bool {
+		return dayOrders[i].CreatedAt.After(dayOrders[j].CreatedAt)
+	})
+	return dayOrders, nil
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	"github.com/oriser/bolt/order"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserDebts(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	store := &fakeTreasuryStore{
+		users: map[string]*userDomain.User{
+			"uuid-1": {ID: "uuid-1", FullName: "Loki", TransportID: "U1"},
+			"U2":     {ID: "U2", FullName: "Thor", TransportID: "U2"},
+			"U3":     {ID: "U3", FullName: "Odin", TransportID: "U3"},
+		},
+		debts: []*debtDomain.Debt{
+			{ID: "d1", BorrowerID: "uuid-1", LenderID: "U2", OrderID: "A", Amount: 30, CreatedAt: now.Add(-2 * time.Hour)},
+			{ID: "d2", BorrowerID: "uuid-1", LenderID: "U3", OrderID: "B", Amount: 20, CreatedAt: now.Add(-time.Hour)},
+			{ID: "d3", BorrowerID: "U2", LenderID: "uuid-1", OrderID: "C", Amount: 15, CreatedAt: now},
+			{ID: "d4", BorrowerID: "U3", LenderID: "U2", OrderID: "D", Amount: 10, CreatedAt: now},
+		},
+	}
+	h, err := New(Config{}, store, store, nil, "U-bot", &recordingNotification{})
+	require.NoError(t, err)
+
+	debtIDs := func(debts []TreasuryDebt) []string {
+		ids := make([]string, len(debts))
+		for i, d := range debts {
+			ids[i] = d.Debt.ID
+		}
+		return ids
+	}
+
+	debts, err := h.UserDebts(context.Background(), "U1", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"d2", "d1"}, debtIDs(debts.Owes), "the debts should be sorted from the newest")
+	assert.Equal(t, []string{"d3"}, debtIDs(debts.Owed))
+	assert.Equal(t, 50.0, debts.TotalOwes())
+	assert.Equal(t, 15.0, debts.TotalOwed())
+	assert.Equal(t, "Odin", debts.Owes[0].Lender.FullName)
+
+	debts, err = h.UserDebts(context.Background(), "U1", "U2")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"d1"}, debtIDs(debts.Owes))
+	assert.Equal(t, []string{"d3"}, debtIDs(debts.Owed))
+}
+
+func TestOrdersOfDay(t *testing.T) {
+	t.Parallel()
+
+	tz, err := time.LoadLocation("Asia/Jerusalem")
+	require.NoError(t, err)
+	today := dayStart(time.Now().In(tz))
+	store := &fakeOrderStore{orders: []*order.Order{
+		{OriginalID: "A", Receiver: "C1", CreatedAt: today.Add(time.Minute)},
+		{OriginalID: "B", Receiver: "C1", CreatedAt: today.Add(-time.Minute)},
+		{OriginalID: "C", Receiver: "C1", CreatedAt: today.Add(2 * time.Minute)},
+		{OriginalID: "D", Receiver: "C2", CreatedAt: today.Add(time.Minute)},
+	}}
+	h, err := New(Config{ChannelTimezones: []string{"C1=Asia/Jerusalem"}}, nil, nil, store, "U-bot", nil)
+	require.NoError(t, err)
+
+	orderIDs := func(daysAgo int) []string {
+		orders, err := h.OrdersOfDay(context.Background(), "C1", daysAgo)
+		require.NoError(t, err)
+		ids := make([]string, len(orders))
+		for i, o := range orders {
+			ids[i] = o.OriginalID
+		}
+		return ids
+	}
+	assert.Equal(t, []string{"C", "A"}, orderIDs(0))
+	assert.Equal(t, []string{"B"}, orderIDs(1))
+}
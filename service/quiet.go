@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/oriser/bolt/calendar"
+)
+
+const (
+	quietCheckInterval = time.Minute
+	// quietLookahead is how far ahead the meetings are fetched, so a refresh failing for a while doesn't miss the next meetings
+	quietLookahead = 24 * time.Hour
+)
+
+// heldNotification is a non-urgent notification held until the end of the meeting it was sent during
+type heldNotification struct {
+	receiver      string
+	text          string
+	reactionEmoji string
+}
+
+// quietMeetings keeps the meetings of the quiet calendar (like all-hands), during which the non-urgent notifications are held so the
+// channels stay quiet, and the notifications held until the meeting ends
+type quietMeetings struct {
+	lock      sync.Mutex
+	meetings  []calendar.Meeting
+	refreshed time.Time
+	held      []heldNotification
+}
+
+func newQuietMeetings() *quietMeetings {
+	return &quietMeetings{}
+}
+
+// SetQuietCalendar sets the calendar of the meetings during which the non-urgent notifications, like the debt reminders and the
+// digests, are held until the meeting ends
+func (h *Service) SetQuietCalendar(provider calendar.Provider) {
+	h.quietCalendar = provider
+}
+
+// meetingAt returns the meeting going on at the given time, if any
+func (q *quietMeetings) meetingAt(at time.Time) (calendar.Meeting, bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	for _, meeting := range q.meetings {
+		if !at.Before(meeting.Start) && at.Before(meeting.End) {
+			return meeting, true
+		}
+	}
+	return calendar.Meeting{}, false
+}
+
+// hold keeps the notification if a meeting is going on at the given time, and returns whether it did
+func (q *quietMeetings) hold(notification heldNotification, at time.Time) bool {
+	if _, ok := q.meetingAt(at); !ok {
+		return false
+	}
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.held = append(q.held, notification)
+	return true
+}
+
+// release returns the held notifications, in the order they were sent, and forgets them
+func (q *quietMeetings) release() []heldNotification {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	held := q.held
+	q.held = nil
+	return held
+}
+
+// informNonUrgent sends a notification which can wait, holding it until the end of the meeting of the quiet calendar going on
+func (h *Service) informNonUrgent(receiver, text, reactionEmoji string) error {
+	if h.quietCalendar != nil && h.quiet.hold(heldNotification{receiver: receiver, text: text, reactionEmoji: reactionEmoji}, time.Now()) {
+		return nil
+	}
+	_, err := h.informEvent(receiver, text, reactionEmoji, "")
+	return err
+}
+
+// refreshQuietMeetings fetches the meetings of the quiet calendar which are going on or start within quietLookahead
+func (h *Service) refreshQuietMeetings(ctx context.Context, now time.Time) {
+	meetings, err := h.quietCalendar.Meetings(ctx, now, now.Add(quietLookahead))
+	if err != nil {
+		h.logger.ErrorContext(ctx, "Error fetching the quiet calendar, keeping the meetings fetched before", "error", err)
+		return
+	}
+	h.quiet.lock.Lock()
+	defer h.quiet.lock.Unlock()
+	h.quiet.meetings = meetings
+	h.quiet.refreshed = now
+}
+
+// sendHeldNotifications sends the notifications held during a meeting
+func (h *Service) sendHeldNotifications() {
+	for _, notification := range h.quiet.release() {
+		if _, err := h.informEvent(notification.receiver, notification.text, notification.reactionEmoji, ""); err != nil {
+			h.logger.Error("Error sending a notification held during a meeting", "receiver", notification.receiver, "error", err)
+		}
+	}
+}
+
+// RunQuietCalendar refreshes the meetings of the quiet calendar every QUIET_CALENDAR_REFRESH, and sends the notifications held
+// during a meeting once it ends, until the context is done. The held notifications are sent when it's done as well, so they aren't
+// lost on shutdown.
+func (h *Service) RunQuietCalendar(ctx context.Context) {
+	if h.quietCalendar == nil {
+		return
+	}
+	defer h.sendHeldNotifications()
+
+	h.refreshQuietMeetings(ctx, time.Now())
+	ticker := time.NewTicker(quietCheckInterval)
+	defer ticker.Stop()
+	h.schedulers.beat("quiet calendar", quietCheckInterval)
+	for {
+		select {
+		case now := <-ticker.C:
+			h.quiet.lock.Lock()
+			stale := now.Sub(h.quiet.refreshed) >= h.cfg.QuietCalendarRefresh
+			h.quiet.lock.Unlock()
+			if stale {
+				h.refreshQuietMeetings(ctx, now)
+			}
+			if _, ok := h.quiet.meetingAt(now); !ok {
+				h.sendHeldNotifications()
+			}
+			h.schedulers.beat("quiet calendar", quietCheckInterval)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
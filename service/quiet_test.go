@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oriser/bolt/calendar"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCalendar struct {
+	meetings []calendar.Meeting
+}
+
+func (f *fakeCalendar) Meetings(context.Context, time.Time, time.Time) ([]calendar.Meeting, error) {
+	return f.meetings, nil
+}
+
+func TestInformNonUrgent(t *testing.T) {
+	t.Parallel()
+
+	notification := &recordingNotification{}
+	h, err := New(Config{QuietCalendarRefresh: time.Minute}, nil, nil, nil, "UBOT", notification)
+	require.NoError(t, err)
+	require.NoError(t, h.informNonUrgent("C1", "no calendar", ""))
+
+	now := time.Now()
+	h.SetQuietCalendar(&fakeCalendar{meetings: []calendar.Meeting{{Summary: "All-hands", Start: now.Add(-time.Minute), End: now.Add(time.Hour)}}})
+	h.refreshQuietMeetings(context.Background(), now)
+	require.NoError(t, h.informNonUrgent("C1", "badges", ""))
+	require.NoError(t, h.informNonUrgent("U1", "reminder", ""))
+	assert.Equal(t, []string{"C1: no calendar"}, notification.messages, "the notifications are held during the meeting")
+
+	h.SetQuietCalendar(&fakeCalendar{})
+	h.refreshQuietMeetings(context.Background(), now)
+	h.sendHeldNotifications()
+	assert.Equal(t, []string{"C1: no calendar", "C1: badges", "U1: reminder"}, notification.messages)
+	require.NoError(t, h.informNonUrgent("C1", "after", ""))
+	assert.Len(t, notification.messages, 4)
+}
@@ -4,23 +4,24 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/oriser/bolt/logging"
+	orderDomain "github.com/oriser/bolt/order"
+	"github.com/oriser/bolt/tracing"
 	userDomain "github.com/oriser/bolt/user"
-	"github.com/oriser/regroup"
 )
 
-var groupLinkRe = regroup.MustCompile(`\/group(-order)?\/(?P<id>[A-Z0-9]+?)((\/join)?\/?$)`)
-
 var errWontJoin = errors.New("wont join because the channel is not accessible")
 var errNotInTime = errors.New("order not in tracking time")
 
 const (
 	MarkAsPaidReaction = "money_mouth_face"
 	HostRemoveDebts    = "x"
+	AgeRestrictedEmoji = ":underage:"
 )
 
 type ParsedWoltGroupID struct {
@@ -28,9 +29,23 @@ type ParsedWoltGroupID struct {
 }
 
 type Rate struct {
-	WoltName string
-	User     *userDomain.User
-	Amount   float64
+	WoltName            string
+	User                *userDomain.User
+	Amount              float64
+	AgeRestrictedAmount float64   // The part of the items' amount (before fees) of age-restricted items, which some subsidies exclude
+	Subsidy             float64   // The part of the amount covered by the company subsidy, see SUBSIDY_AMOUNT and SUBSIDY_PERCENT
+	SubsidyCapped       bool      // The subsidy was reduced, as the user reached the SUBSIDY_MONTHLY_CAP
+	Forgiven            bool      // The host forgave the participant's debt
+	Adjusted            bool      // The host changed the participant's amount
+	Match               UserMatch // How the Wolt name was matched to the user
+	Private             bool      // The user keeps their amount out of the rates message, and gets it by a DM instead
+	// The participant's items, set only with RATES_ITEMS
+	Items []Item
+}
+
+// PersonalAmount returns the amount the participant pays after the company subsidy
+func (r Rate) PersonalAmount() float64 {
+	return r.Amount - r.Subsidy
 }
 
 type GroupRate struct {
@@ -38,6 +53,65 @@ type GroupRate struct {
 	HostWoltUser string
 	HostUser     *userDomain.User
 	DeliveryRate int
+	CompanyPaid  bool    // The host paid with a company card, so nobody needs to pay them
+	Note         string  // The host's note from the message with the order link, like payment instructions
+	ExternalRef  string  // The reference of the order for finance, empty if ORDER_REF_GENERATOR isn't set
+	Currency     string  // The ISO 4217 code of the currency of the order
+	Rounded      bool    // The amounts were rounded to AMOUNT_ROUNDING
+	Discount     float64 // The promo codes and Wolt credits of the order, taken off the amounts by DISCOUNT_ALLOCATION
+	// The amount of each participant's items by Wolt name, before the discounts and fees, which the rates were computed from
+	ItemRates map[string]float64
+	// The Wolt names of participants who are deactivated users (who left the company), so they're treated as unknown participants
+	DeactivatedParticipants []string
+	ServiceFee              float64 // Wolt's service fee of the order, allocated with the delivery by FEE_ALLOCATION_STRATEGY
+	Tip                     float64 // The tip the host gave the courier, allocated with the delivery by FEE_ALLOCATION_STRATEGY
+	// The fees other than the delivery the host added after the rates were published, like a tip
+	ExtraFees Fees
+}
+
+// fees returns the fees the rates were allocated with
+func (g GroupRate) fees() Fees {
+	return Fees{Delivery: float64(g.DeliveryRate), Service: g.ServiceFee, Tip: g.Tip}
+}
+
+// setFees sets the fees of the order other than the delivery, which the rates were allocated with
+func (g *GroupRate) setFees(fees Fees) {
+	g.ServiceFee = fees.Service
+	g.Tip = fees.Tip
+}
+
+// setAgeRestricted sets the amount of age-restricted items of each participant, by Wolt name
+func (g *GroupRate) setAgeRestricted(amounts map[string]float64) {
+	for i := range g.Rates {
+		g.Rates[i].AgeRestrictedAmount = amounts[g.Rates[i].WoltName]
+	}
+}
+
+// setItems sets the items of each participant, by Wolt name
+func (g *GroupRate) setItems(items map[string][]Item) {
+	for i := range g.Rates {
+		g.Rates[i].Items = items[g.Rates[i].WoltName]
+	}
+}
+
+// hasPrivateAmounts returns whether a participant keeps their amount private, in which case the total of the rates isn't shown
+func (g GroupRate) hasPrivateAmounts() bool {
+	for _, rate := range g.Rates {
+		if rate.Private {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAgeRestricted returns whether any participant ordered age-restricted items
+func (g GroupRate) hasAgeRestricted() bool {
+	for _, rate := range g.Rates {
+		if rate.AgeRestrictedAmount > 0 {
+			return true
+		}
+	}
+	return false
 }
 
 func getSortedKeys(m map[string]float64) []string {
@@ -49,103 +123,212 @@ func getSortedKeys(m map[string]float64) []string {
 	return keys
 }
 
-func (h *Service) HandleLinkMessage(req LinksRequest) (string, error) {
-	// handle just one link in a message
-	groupID := h.getWoltGroupID(req.Links)
-	if groupID == nil {
-		log.Printf("No wolt links found (%+v)", req.Links)
+func (h *Service) HandleLinkMessage(req LinksRequest) (response string, err error) {
+	ctx, span := tracing.Start(tracing.Extract(context.Background(), req.TraceParent), "HandleLinkMessage",
+		tracing.String("channel", req.Channel), tracing.String("message_id", req.MessageID))
+	defer func() {
+		observeLinkMessage(err)
+		span.SetError(err)
+		span.End()
+	}()
+	req.TraceParent = tracing.TraceParent(ctx)
+	h.setLinkCursor(ctx, req.Channel, req.MessageID)
+	return h.handleLinks(req)
+}
+
+// handleLinks tracks the orders of the links, without moving the channel's link cursor
+func (h *Service) handleLinks(req LinksRequest) (string, error) {
+	ctx := logging.With(tracing.Extract(context.Background(), req.TraceParent), "channel", req.Channel, "message_id", req.MessageID)
+	groups := h.groupLinks(ctx, req.Links)
+	if len(groups) == 0 {
+		if trackingIDs := h.soloTrackingIDs(req.Links); len(trackingIDs) > 0 {
+			return "", h.followSoloOrders(ctx, req, trackingIDs)
+		}
+		h.logger.DebugContext(ctx, "No group order links found", "links", req.Links)
 		return "", nil
 	}
-
-	if _, ok := h.currentlyWorkingOrders.Load(groupID.ID); ok {
-		log.Println("Already working on order", groupID.ID)
+	if h.isPersonalOrderLink(req.Channel, time.Now()) {
+		h.logger.InfoContext(ctx, "Ignoring the links, they're taken for personal orders")
 		return "", nil
 	}
-	h.currentlyWorkingOrders.Store(groupID.ID, nil)
-	defer h.currentlyWorkingOrders.Delete(groupID.ID)
+	if len(groups) == 1 {
+		return h.trackOrder(req, groups[0], nil, &linkAdmission{})
+	}
 
-	err := h.eventNotification.AddReaction(req.Channel, req.MessageID, h.cfg.JoinedOrderEmoji)
-	if err != nil {
-		return "", errWontJoin
+	// Each order of the message is tracked separately, and they're all admitted together
+	admission := &linkAdmission{}
+	responses := make([]string, len(groups))
+	errs := make([]error, len(groups))
+	var wg sync.WaitGroup
+	for i, group := range groups {
+		wg.Add(1)
+		go func(i int, group groupLink) {
+			defer wg.Done()
+			responses[i], errs[i] = h.trackOrder(req, group, nil, admission)
+		}(i, group)
 	}
+	wg.Wait()
 
-	shouldHandleOrder := h.shouldHandleOrder()
-	if !shouldHandleOrder {
-		_, err := h.informEvent(req.Channel, "It's too late for me... I won't track prices for this order :sleeping:", "", req.MessageID)
-		if err != nil {
-			return "", errWontJoin
+	var firstErr error
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		if firstErr == nil {
+			firstErr = fmt.Errorf("order %s: %w", groups[i].groupID, err)
+		} else {
+			h.logger.ErrorContext(ctx, "Error tracking order", "group_id", groups[i].groupID, "error", err)
 		}
-
-		return "", errNotInTime
 	}
+	return strings.Join(nonEmpty(responses), "\n"), firstErr
+}
 
-	order, err := h.joinGroupOrder(groupID.ID)
-	if err != nil {
-		_, _ = h.informEvent(req.Channel, "I had an error joining the order", "", req.MessageID)
-		return "", fmt.Errorf("join group order: %w", err)
-	}
-	h.currentlyWorkingOrders.Store(groupID, order)
-	venue, err := order.Venue()
-	if err == nil {
-		h.informEvent(req.Channel, fmt.Sprintf("Hi 👋, I've joined the order from [%s]", venue.Name), "", req.MessageID)
+// linkAdmission admits the orders of a link message once, so a message with several orders gets a single reaction and
+// "too late" message
+type linkAdmission struct {
+	once sync.Once
+	err  error
+}
+
+// admit reacts to the link message and checks it's not too late to track its orders. It returns errWontJoin if the message
+// can't be reacted to or replied to and errNotInTime if it's too late.
+func (h *Service) admit(admission *linkAdmission, req LinksRequest) error {
+	admission.once.Do(func() {
+		admission.err = h.admitLinkMessage(req)
+	})
+	return admission.err
+}
+
+func (h *Service) admitLinkMessage(req LinksRequest) error {
+	if err := h.addReaction(req.Channel, req.MessageID, h.channelEmoji(req.Channel, settingJoinedOrderEmoji, h.cfg.JoinedOrderEmoji)); err != nil {
+		var scopeErr *MissingScopeError
+		if !errors.As(err, &scopeErr) {
+			h.logger.Error("Error reacting to the link message", "channel", req.Channel, "message_id", req.MessageID, "error", err)
+			return errWontJoin
+		}
+		// Bolt can't react but may still post, so the link message is acknowledged with a reply instead
+		h.reportMissingScope(req.Channel, scopeErr)
+		if _, err := h.informEvent(req.Channel, h.text(req.Channel, msgTrackingLink), "", req.MessageID); err != nil {
+			return errWontJoin
+		}
+	} else if h.accessible(req.Channel) {
+		// Screen readers don't announce the reaction, so it's followed by a reply saying the same
+		_, _ = h.informEvent(req.Channel, h.text(req.Channel, msgTrackingLink), "", req.MessageID)
 	}
 
-	groupRate, err := h.getRateForGroup(order, req.Channel, req.MessageID)
-	if err != nil {
-		if strings.Contains(err.Error(), "order canceled") {
-			_, _ = h.informEvent(req.Channel, fmt.Sprintf("Order for group ID %s was canceled", groupID.ID), "", req.MessageID)
-			return "", nil
+	shouldHandleOrder := h.shouldHandleOrder(req.Channel, req.UserID)
+	if !shouldHandleOrder && h.cfg.LateOrderConfirmation {
+		if err := h.confirmLateOrder(req.Channel, req.MessageID); err != nil {
+			if errors.Is(err, errNotInTime) {
+				return errNotInTime
+			}
+			return errWontJoin
 		}
-		if strings.Contains(err.Error(), "context canceled while waiting") {
-			_, _ = h.informEvent(req.Channel, "Timed out waiting for order to be ready", "", req.MessageID)
-			return "", nil
+	} else if !shouldHandleOrder {
+		_, err := h.informEvent(req.Channel, h.text(req.Channel, msgTooLate), "", req.MessageID)
+		if err != nil {
+			return errWontJoin
 		}
-		log.Printf("Error getting rate for group %s: %v\n", groupID.ID, err)
-		_, _ = h.informEvent(req.Channel, fmt.Sprintf("I had an error getting rate for group ID %s", groupID.ID), "", req.MessageID)
-		return "", nil
+
+		return errNotInTime
 	}
+	return nil
+}
 
-	ratesMessage := h.buildRatesMessage(groupRate, groupID.ID)
-	order.detailsMessageId, err = h.informEvent(req.Channel, ratesMessage, MarkAsPaidReaction, req.MessageID)
-	if err != nil {
-		return "", fmt.Errorf("failed sending details message: %w", err)
+func nonEmpty(values []string) []string {
+	filtered := make([]string, 0, len(values))
+	for _, value := range values {
+		if value != "" {
+			filtered = append(filtered, value)
+		}
 	}
+	return filtered
+}
 
-	if err := h.addDebts(req.Channel, groupID.ID, groupRate, req.MessageID); err != nil {
-		log.Println(fmt.Sprintf("Error adding debts: %s", err.Error()))
-		_, _ = h.informEvent(req.Channel, "I had an error adding debts, I won't track this order", "", req.MessageID)
+// trackOrder tracks the group order of the link until it's delivered, running its orderFlow. resumed is the persisted state of an order whose tracking was
+// interrupted by a restart, which is resumed from the phase it got to, or nil for a newly shared order, which is tracked once
+// its link message is admitted.
+func (h *Service) trackOrder(req LinksRequest, link groupLink, resumed *orderDomain.TrackedOrder, admission *linkAdmission) (response string, err error) {
+	groupID := link.groupID
+	if h.shuttingDown() {
+		return "", errShuttingDown
+	}
+	ctx, span := tracing.Start(tracing.Extract(context.Background(), req.TraceParent), "track_order",
+		tracing.String("group_id", groupID), tracing.String("channel", req.Channel), tracing.String("resumed", fmt.Sprint(resumed != nil)))
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+	ctx = logging.With(ctx, "group_id", groupID, "channel", req.Channel, "message_id", req.MessageID)
+	startedAt := time.Now()
+	if resumed != nil {
+		startedAt = resumed.StartedAt
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), h.cfg.OrderDoneTimeout)
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
-	if err = h.monitorDelivery(req.Channel, order, ctx, h.cfg.WaitBetweenStatusCheck, req.MessageID, ratesMessage); err != nil {
-		if strings.Contains(err.Error(), "context canceled while waiting") {
-			_, _ = h.informEvent(req.Channel, "Timed out waiting for order to be done", "", req.MessageID)
-			return "", nil
+	working, abandoned, ok := h.workingOrders.start(groupID, startedAt, parseTimeoutMultiplier(req.Text), cancel)
+	if !ok {
+		h.logger.InfoContext(ctx, "Already working on order")
+		if resumed == nil {
+			h.informDuplicateLink(req, groupID)
 		}
-		return "", fmt.Errorf("error in waiting for order to finish: %w", err)
+		return "", nil
 	}
+	if abandoned != nil {
+		h.logger.WarnContext(ctx, "Taking over order, which has been handled for longer than its WORKING_ORDER_TTL and is considered abandoned",
+			"started_at", abandoned.startedAt.Format(time.RFC3339))
+	}
+	defer func() {
+		// The skips of a message with several orders are kept until all of them are done
+		if h.activeOrderByMessage(req.Channel, req.MessageID) == nil {
+			h.skips.remove(req.Channel, req.MessageID)
+		}
+	}()
+	defer func() {
+		// If the order was taken over, its state belongs to the new handling
+		if h.workingOrders.done(groupID, working) {
+			h.activeOrders.remove(groupID)
+			h.pickups.remove(groupID)
+			h.rateAdjustments.remove(groupID)
+			// Orders interrupted by a shutdown keep their tracking state, to be resumed after the restart
+			if !h.shuttingDown() {
+				h.forgetTracking(groupID)
+			}
+		}
+	}()
 
-	return "", nil
+	flow := &orderFlow{
+		h:         h,
+		ctx:       ctx,
+		span:      span,
+		req:       req,
+		groupID:   groupID,
+		provider:  link.provider,
+		resumed:   resumed,
+		admission: admission,
+		working:   working,
+		startedAt: startedAt,
+	}
+	return flow.run()
 }
 
-func (h *Service) getWoltGroupID(links []Link) *ParsedWoltGroupID {
+// groupLinks returns the distinct group orders of the links, by their order in the message
+func (h *Service) groupLinks(ctx context.Context, links []Link) []groupLink {
+	groups := make([]groupLink, 0)
+	seen := make(map[string]bool)
 	for _, link := range links {
-		if link.Domain != "wolt.com" {
+		group, ok := h.groupOfLink(ctx, link.URL)
+		if !ok {
 			continue
 		}
 
-		parsedWoltLink := &ParsedWoltGroupID{}
-		if err := groupLinkRe.MatchToTarget(link.URL, parsedWoltLink); err != nil {
-			if !errors.Is(err, &regroup.NoMatchFoundError{}) {
-				log.Println("Error matching wolt URL regex:", err)
-			}
-			continue
+		if !seen[group.groupID] {
+			seen[group.groupID] = true
+			groups = append(groups, group)
 		}
-
-		return parsedWoltLink
 	}
-	return nil
+	return groups
 }
 
 func (h *Service) buildGroupRates(woltRates map[string]float64, host string, deliveryRate int) GroupRate {
@@ -153,11 +336,13 @@ func (h *Service) buildGroupRates(woltRates map[string]float64, host string, del
 		// The host didn't take anything, so he won't be included in the rates, add it here just to fetch his user
 		woltRates[host] = 0.0
 	}
+	woltRates = h.roundRates(woltRates, host)
 	sortedKeys := getSortedKeys(woltRates)
 	groupRate := GroupRate{
 		Rates:        make([]Rate, len(woltRates)),
 		HostWoltUser: host,
 		DeliveryRate: deliveryRate,
+		Rounded:      h.cfg.AmountRounding > 0,
 	}
 
 	for i, person := range sortedKeys {
@@ -165,22 +350,32 @@ func (h *Service) buildGroupRates(woltRates map[string]float64, host string, del
 			WoltName: person,
 			User:     nil,
 			Amount:   woltRates[person],
+			Match:    UserMatchUnmatched,
 		}
-		users, err := h.userStore.ListUsers(context.Background(), userDomain.ListFilter{Names: []string{person}})
+		users, err := h.listUsersByName(person)
 		if err != nil {
-			log.Printf("Error getting user %s from storage: %v\n", person, err)
+			h.logger.Error("Error getting user from storage", "wolt_name", person, "error", err)
 			continue
 		}
 		if len(users) == 0 {
-			log.Printf("User not found %s\n", person)
+			h.logger.Info("User not found", "wolt_name", person)
 			continue
 		}
 		if len(users) != 1 {
-			log.Printf("More than one user for %s. Taking first: %#v\n", person, users)
+			h.logger.Warn("More than one user for the Wolt name, taking the first", "wolt_name", person, "user_id", users[0].ID, "users", len(users))
+			continue
+		}
+		if users[0].Deactivated() {
+			h.logger.Info("User is deactivated, not matching it", "wolt_name", person)
+			groupRate.DeactivatedParticipants = append(groupRate.DeactivatedParticipants, person)
 			continue
 		}
 
+		h.loadPaymentMethods(users[0])
+		groupRate.Rates[i].Private = h.privateAmounts(users[0].TransportID)
+		groupRate.Rates[i].Match = matchOfUser(person, users[0].FullName)
 		if person == host {
+			h.loadBankAccount(users[0])
 			groupRate.HostUser = users[0]
 		}
 		groupRate.Rates[i].User = users[0]
@@ -189,67 +384,152 @@ func (h *Service) buildGroupRates(woltRates map[string]float64, host string, del
 	return groupRate
 }
 
-func (h *Service) buildRatesMessage(groupRate GroupRate, groupID string) string {
-	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("Rates for Wolt order ID %s (including %d NIS for delivery):\n", groupID, groupRate.DeliveryRate))
+// buildRatesMessage returns the rates message. Its rates are continued in other messages if it's longer than
+// RATES_MESSAGE_MAX_LENGTH, see buildRatesMessages.
+func (h *Service) buildRatesMessage(channel string, groupRate GroupRate, groupID string) string {
+	return h.buildRatesMessages(channel, groupRate, groupID)[0]
+}
+
+// RatesView returns the rates message of the order in the channel's locale: the header, the line of each rate and the footer. The
+// lines of compact messages leave out the Wolt names of the known participants. Every surface showing the rates renders the view
+// with a RatesFormatter, see FormatRates.
+func (h *Service) RatesView(channel string, groupRate GroupRate, groupID string) RatesView {
+	header := h.text(channel, msgRatesHeader, groupID, groupRate.DeliveryRate, h.currencyName(channel, groupRate.Currency))
+	if groupRate.ExternalRef != "" {
+		header += h.text(channel, msgOrderRef, groupRate.ExternalRef)
+	}
 
-	for _, rate := range groupRate.Rates {
+	compact := h.compactRates(groupRate)
+	lines := make([]string, len(groupRate.Rates))
+	for i, rate := range groupRate.Rates {
 		userID := rate.WoltName
-		if rate.User != nil {
+		if rate.User != nil && compact {
+			userID = fmt.Sprintf("<@%s>", rate.User.TransportID)
+		} else if rate.User != nil {
 			userID = fmt.Sprintf("<@%s> (%s)", rate.User.TransportID, rate.WoltName)
 		}
 
-		sb.WriteString(fmt.Sprintf("%s: %.2f\n", userID, rate.Amount))
+		if rate.Private {
+			lines[i] = h.text(channel, msgRateLinePrivate, userID) + "\n"
+			continue
+		}
+		line := h.text(channel, msgRateLine, userID, rate.Amount)
+		if h.subsidized() {
+			line += h.text(channel, msgPersonalShare, rate.PersonalAmount())
+			if rate.SubsidyCapped {
+				line += h.text(channel, msgSubsidyCapReached)
+			}
+		}
+		if rate.AgeRestrictedAmount > 0 {
+			line += " " + AgeRestrictedEmoji
+		}
+		if rate.Forgiven {
+			line += h.text(channel, msgForgiven)
+		} else if rate.Adjusted {
+			line += h.text(channel, msgAdjusted)
+		}
+		if !compact {
+			line += h.ratePaymentLink(channel, groupRate, rate)
+		}
+		if !compact && h.ratesItems == RatesItemsInline {
+			line += h.itemLines(channel, rate.Items)
+		}
+		lines[i] = line + "\n"
+	}
+
+	var sb strings.Builder
+	if groupRate.hasAgeRestricted() {
+		sb.WriteString(h.text(channel, msgAgeRestricted, AgeRestrictedEmoji))
+	}
+	if h.subsidized() {
+		sb.WriteString(h.subsidyMessage(channel))
+	}
+	sb.WriteString(h.discountMessage(channel, groupRate))
+	sb.WriteString(h.roundingMessage(channel, groupRate))
+	if groupRate.ServiceFee != 0 || groupRate.Tip != 0 {
+		sb.WriteString(h.text(channel, msgOrderFees, describeFees(Fees{Service: groupRate.ServiceFee, Tip: groupRate.Tip})))
+	}
+	if groupRate.ExtraFees != (Fees{}) {
+		sb.WriteString(h.text(channel, msgExtraFees, describeFees(groupRate.ExtraFees)))
+	}
+
+	if groupRate.Note != "" {
+		sb.WriteString(h.text(channel, msgHostNote, groupRate.Note))
+	}
+
+	if groupRate.CompanyPaid {
+		sb.WriteString(h.text(channel, msgCompanyPaid, h.channelEmoji(channel, settingCompanyPaidEmoji, h.cfg.CompanyPaidEmoji)))
+		return newRatesView(groupID, header, groupRate, lines, sb.String())
 	}
 
 	host := groupRate.HostWoltUser
 	if groupRate.HostUser != nil {
 		host = fmt.Sprintf("<@%s>", groupRate.HostUser.TransportID)
 	}
-	sb.WriteString(fmt.Sprintf("\nPay to: %s\n", host))
+	sb.WriteString(h.text(channel, msgPayTo, host))
 
-	if groupRate.HostUser != nil && len(groupRate.HostUser.PaymentPreferences) > 0 {
-		sb.WriteString("Preferred payments methods (in order): ")
-		strPayments := make([]string, len(groupRate.HostUser.PaymentPreferences))
-		for i, v := range groupRate.HostUser.PaymentPreferences {
+	if groupRate.HostUser != nil && len(groupRate.HostUser.PreferredPaymentMethods()) > 0 {
+		sb.WriteString(h.text(channel, msgPreferredPayments))
+		preferred := groupRate.HostUser.PreferredPaymentMethods()
+		strPayments := make([]string, len(preferred))
+		for i, v := range preferred {
 			strPayments[i] = v.String()
 		}
 		sb.WriteString(strings.Join(strPayments, ", "))
 		sb.WriteString("\n")
+		sb.WriteString(h.bankAccountMessage(channel, groupRate.HostUser))
+		sb.WriteString(h.mutualPayments(channel, groupRate))
 	}
 
-	return sb.String()
+	return newRatesView(groupID, header, groupRate, lines, sb.String())
 }
 
-func (h *Service) shouldHandleOrder() bool {
-	if h.dontJoinAfter.IsZero() {
-		return true
-	}
-
-	currentTime := time.Now()
-	if h.dontJoinAfterTZ != nil {
-		currentTime = currentTime.In(h.dontJoinAfterTZ)
+// confirmLateOrder offers to track an order which was sent after DONT_JOIN_AFTER, and returns errNotInTime if no one confirmed it
+func (h *Service) confirmLateOrder(channel, messageID string) error {
+	warningID, err := h.informEvent(channel, h.text(channel, msgTooLateConfirmation, h.channelEmoji(channel, settingBlacklistConfirmEmoji, h.cfg.BlacklistConfirmationEmoji)), "", messageID)
+	if err != nil {
+		return fmt.Errorf("inform too late: %w", err)
 	}
-
-	if (currentTime.Hour() > h.dontJoinAfter.Hour()) ||
-		(currentTime.Hour() == h.dontJoinAfter.Hour() && currentTime.Minute() >= h.dontJoinAfter.Minute()) {
-		return false
+	confirmed := h.blacklistConfirmations.add(channel, warningID)
+	defer h.blacklistConfirmations.remove(channel, warningID)
+
+	select {
+	case <-confirmed:
+		return nil
+	case <-time.After(h.cfg.BlacklistConfirmationTimeout):
+		_, _ = h.informEvent(channel, h.text(channel, msgNoOneConfirmed), "", messageID)
+		return errNotInTime
 	}
-
-	return true
 }
 
 func (h *Service) saveOrderAsync(order *groupOrder, groupRate GroupRate, receiver string) {
 	domainOrder, err := order.ToOrder(groupRate.Rates, receiver)
 	if err != nil {
-		log.Printf("Error converting order %q: %v\n", order.id, err)
+		h.logger.ErrorContext(order.ctx, "Error converting order", "error", err)
 		return
 	}
-	if err = h.orderStore.SaveOrder(context.Background(), domainOrder); err != nil {
-		log.Printf("Error saving order %q: %v\n", order.id, err)
+	domainOrder.ExternalRef = groupRate.ExternalRef
+	if domainOrder.ExternalRef == "" {
+		// The orders which weren't sent (e.g. canceled) get their reference when they're saved
+		domainOrder.ExternalRef = h.newOrderRef(order.id)
+	}
+	domainOrder.Currency = groupRate.Currency
+	if domainOrder.Currency == "" {
+		domainOrder.Currency = h.orderCurrency(order, nil)
+	}
+	ctx, cancel := h.storeContext()
+	defer cancel()
+	ctx, span := tracing.Start(logging.CopyAttrs(tracing.ContextWithSpan(ctx, tracing.SpanFromContext(order.ctx)), order.ctx), "store.save_order",
+		tracing.String("group_id", order.id))
+	defer span.End()
+	if err = h.orderStore.SaveOrder(ctx, domainOrder); err != nil {
+		span.SetError(err)
+		h.logger.ErrorContext(ctx, "Error saving order", "error", err)
 		return
 	}
-
+	h.subsidyReservations.release(order.id)
+	h.readModels.addOrder(domainOrder)
+	h.nudgeHosting(ctx, domainOrder)
 }
 
 func (h *Service) getRateForGroup(order *groupOrder, receiver, messageID string) (groupRate GroupRate, err error) {
@@ -261,7 +541,7 @@ func (h *Service) getRateForGroup(order *groupOrder, receiver, messageID string)
 		return GroupRate{}, fmt.Errorf("mark as ready in group: %w", err)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), h.cfg.TimeoutForReady)
+	ctx, cancel := context.WithTimeout(order.ctx, order.orderTimeout(h.cfg.TimeoutForReady))
 	defer cancel()
 
 	monitorCtx, monitorCancel := context.WithCancel(ctx)
@@ -271,27 +551,55 @@ func (h *Service) getRateForGroup(order *groupOrder, receiver, messageID string)
 		return GroupRate{}, fmt.Errorf("wait for group to finish: %w", err)
 	}
 	monitorCancel()
+	if groupRate, err = h.computeGroupRate(order, receiver, messageID); err != nil {
+		return GroupRate{}, err
+	}
+	groupRate.ExternalRef = h.newOrderRef(order.id)
+	observeMatches(order.id, groupRate)
+	return groupRate, nil
+}
 
+// computeGroupRate computes the rates of the participants of the sent group
+func (h *Service) computeGroupRate(order *groupOrder, receiver, messageID string) (GroupRate, error) {
 	details, err := order.Details()
 	if err != nil {
 		return GroupRate{}, fmt.Errorf("get group details for calculating rates: %w", err)
 	}
 
-	rates, err := details.RateByPerson()
+	itemRates, err := details.RateByPerson()
 	if err != nil {
 		return GroupRate{}, fmt.Errorf("rate by person: %w", err)
 	}
+	rates := h.allocateDiscount(itemRates, details.Host, details.DiscountsAmount())
 
-	deliveryRate, err := order.CalculateDeliveryRate()
+	deliveryRate, err := paidDeliveryRate(order, details)
 	if err != nil {
-		_, _ = h.informEvent(receiver, "I can't find the delivery rate, I'll publish the rates without including the delivery rate", "", messageID)
-		log.Println("Error getting delivery rate:", err)
-		return h.buildGroupRates(rates, details.Host, 0), nil
+		_, _ = h.informEvent(receiver, h.text(receiver, msgNoDeliveryRate), "", messageID)
+		h.logger.ErrorContext(order.ctx, "Error getting delivery rate", "error", err)
+		deliveryRate = 0
+	}
+
+	// Without the delivery rate, the rest of the fees are still allocated
+	fees := orderFees(details, deliveryRate)
+	if err == nil || fees != (Fees{}) {
+		rates = h.channelFeeAllocator(receiver).Allocate(rates, details.Host, fees)
 	}
+	groupRate := h.buildGroupRates(rates, details.Host, deliveryRate)
+	groupRate.setFees(fees)
+	h.setItemAmounts(&groupRate, order.id, details)
+	groupRate.Currency = h.orderCurrency(order, details)
+	groupRate.Discount = details.DiscountsAmount()
+	groupRate.ItemRates = itemRates
+	return groupRate, nil
+}
 
-	pricePerPerson := float64(deliveryRate) / float64(len(rates))
-	for person, rate := range rates {
-		rates[person] = rate + pricePerPerson
+// setItemAmounts sets the parts of the rates computed per line item: the age-restricted items and the company subsidy
+func (h *Service) setItemAmounts(groupRate *GroupRate, groupID string, details *OrderDetails) {
+	groupRate.setAgeRestricted(details.AgeRestrictedByPerson())
+	if h.ratesItems != RatesItemsOff {
+		groupRate.setItems(details.ItemsByPerson())
+	}
+	if h.subsidized() {
+		h.capSubsidies(groupRate, groupID, details.ItemsAmountByPerson(h.subsidyExcluded))
 	}
-	return h.buildGroupRates(rates, details.Host, deliveryRate), nil
 }
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgeRestrictedRates(t *testing.T) {
+	t.Parallel()
+
+	details, err := parseWoltOrderDetails([]byte(`{
+		"host_id": "1",
+		"details": {"delivery_info": {"location": {"coordinates": {"coordinates": [34.78, 32.08]}}}},
+		"participants": [
+			{"first_name": "Thor", "user_id": "1", "basket": {"items": [{"name": "Pizza", "end_amount": 5000}]}},
+			{"first_name": "Loki", "user_id": "2", "basket": {"items": [
+				{"name": "Salad", "end_amount": 3000},
+				{"name": "Beer", "end_amount": 2500, "alcohol_percentage": 4.5},
+				{"name": "Cigarettes", "end_amount": 1000, "age_restricted": true}
+			]}}
+		]
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"Loki": 35}, details.AgeRestrictedByPerson())
+
+	h, err := New(Config{}, &fakeTreasuryStore{}, nil, nil, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+	rates, err := details.RateByPerson()
+	require.NoError(t, err)
+	groupRate := h.buildGroupRates(rates, details.Host, 0)
+	groupRate.setAgeRestricted(details.AgeRestrictedByPerson())
+
+	assert.Equal(t, "Rates for Wolt order ID ABC (including 0 NIS for delivery):\n"+
+		"Loki: 65.00 :underage:\n"+
+		"Thor: 50.00\n"+
+		":underage: Includes age-restricted items\n"+
+		"\nPay to: Thor\n", h.buildRatesMessage("C1", groupRate, "ABC"))
+
+	groupRate.setAgeRestricted(nil)
+	assert.NotContains(t, h.buildRatesMessage("C1", groupRate, "ABC"), ":underage:")
+}
+
+func TestHostNoteInRates(t *testing.T) {
+	t.Parallel()
+
+	h, err := New(Config{}, &fakeTreasuryStore{}, nil, nil, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+	groupRate := GroupRate{HostWoltUser: "Thor", Note: "cash only today", Rates: []Rate{{WoltName: "Loki", Amount: 30}}}
+	assert.Equal(t, "Rates for Wolt order ID ABC (including 0 NIS for delivery):\n"+
+		"Loki: 30.00\n"+
+		"\n:memo: Note from the host: cash only today\n"+
+		"\nPay to: Thor\n", h.buildRatesMessage("C1", groupRate, "ABC"))
+}
+
+func TestConfirmLateOrder(t *testing.T) {
+	t.Parallel()
+
+	notification := &editingNotification{}
+	h, err := New(Config{
+
+		LateOrderConfirmation:        true,
+		BlacklistConfirmationEmoji:   "white_check_mark",
+		BlacklistConfirmationTimeout: time.Minute,
+	}, nil, nil, nil, "UBOT", notification)
+	require.NoError(t, err)
+
+	confirmed := make(chan error, 1)
+	go func() {
+		confirmed <- h.confirmLateOrder("C1", "1.1")
+	}()
+	require.Eventually(t, func() bool {
+		h.blacklistConfirmations.lock.Lock()
+		defer h.blacklistConfirmations.lock.Unlock()
+		return len(h.blacklistConfirmations.pending) == 1
+	}, time.Second, time.Millisecond)
+
+	_, err = h.HandleReactionAdded(ReactionAddRequest{Reaction: "white_check_mark", FromUserID: "U1", Channel: "C1", MessageID: "sent-1"})
+	require.NoError(t, err)
+	assert.NoError(t, <-confirmed)
+	assert.Equal(t, []string{"C1: It's too late for me... :sleeping: React with :white_check_mark: to this message if you still want me to " +
+		"track prices for this order"}, notification.messages)
+
+	h.cfg.BlacklistConfirmationTimeout = time.Millisecond
+	assert.ErrorIs(t, h.confirmLateOrder("C1", "1.1"), errNotInTime)
+	assert.Equal(t, "C1: No one confirmed, I won't track this order", notification.messages[len(notification.messages)-1])
+}
+
+func TestGroupLinks(t *testing.T) {
+	t.Parallel()
+
+	h, err := New(Config{}, nil, nil, nil, "UBOT", nil)
+	require.NoError(t, err)
+	groupIDs := func(links []Link) []string {
+		ids := make([]string, 0)
+		for _, group := range h.groupLinks(context.Background(), links) {
+			assert.Equal(t, providerWolt, group.provider.Name())
+			ids = append(ids, group.groupID)
+		}
+		return ids
+	}
+	assert.Equal(t, []string{"ABC123", "DEF456", "GHI789", "JKL012"}, groupIDs([]Link{
+		{Domain: "wolt.com", URL: "https://wolt.com/en/group/ABC123"},
+		{Domain: "example.com", URL: "https://example.com/group/XYZ789"},
+		{Domain: "wolt.com", URL: "https://wolt.com/en/group-order/DEF456/join"},
+		{Domain: "wolt.com", URL: "https://wolt.com/en/group/ABC123/join"},
+		{Domain: "wolt.com", URL: "https://wolt.com/he/isr/tel-aviv/group/GHI789/join"},
+		{Domain: "", URL: "wolt://group/JKL012"},
+	}), "each order should be tracked once")
+	assert.Empty(t, groupIDs([]Link{{Domain: "wolt.com", URL: "https://wolt.com/en/isr/tel-aviv/restaurant/pizza-place"}}))
+}
+
+type reactionsNotification struct {
+	recordingNotification
+	lock      sync.Mutex
+	reactions []string
+}
+
+func (r *reactionsNotification) SendMessage(receiver, event, threadID string) (string, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.recordingNotification.SendMessage(receiver, event, threadID)
+}
+
+func (r *reactionsNotification) AddReaction(receiver, messageID, reaction string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.reactions = append(r.reactions, receiver+"/"+messageID+": "+reaction)
+	return nil
+}
+
+func TestAdmitLinkMessageOnce(t *testing.T) {
+	t.Parallel()
+
+	notification := &reactionsNotification{}
+	h, err := New(Config{JoinedOrderEmoji: "eyes", DontJoinAfter: "00:00"}, nil, nil, nil, "UBOT", notification)
+	require.NoError(t, err)
+
+	// The orders of a message with several links are admitted together
+	admission := &linkAdmission{}
+	req := LinksRequest{Channel: "C1", MessageID: "1.1"}
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			errs <- h.admit(admission, req)
+		}()
+	}
+	assert.ErrorIs(t, <-errs, errNotInTime)
+	assert.ErrorIs(t, <-errs, errNotInTime)
+	assert.Equal(t, []string{"C1/1.1: eyes"}, notification.reactions)
+	assert.Equal(t, []string{"C1: It's too late for me... I won't track prices for this order :sleeping:"}, notification.messages)
+}
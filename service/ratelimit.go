@@ -0,0 +1,98 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// CommandCooldownError is returned for the commands and button clicks of a user who sent more than COMMAND_BURST of them in a row,
+// faster than COMMAND_RATE_LIMIT a minute. Its message asks the user to wait, so transports respond with it as is.
+type CommandCooldownError struct {
+	RetryAfter time.Duration
+}
+
+func (e *CommandCooldownError) Error() string {
+	return fmt.Sprintf(":hourglass: Easy there, that's a lot of requests at once. Please try again in %d seconds",
+		int(math.Ceil(e.RetryAfter.Seconds())))
+}
+
+// commandLimiter rate limits the commands of each user with a token bucket, so a misbehaving script or a prank can't flood the
+// stores and the chat API. A bucket holds up to burst commands and refills at rate commands a minute.
+type commandLimiter struct {
+	rate  float64 // Commands a minute, 0 disables the limit
+	burst float64
+
+	lock      sync.Mutex
+	buckets   map[string]*commandBucket // By user ID
+	lastSweep time.Time
+}
+
+type commandBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+func newCommandLimiter(rate, burst int) *commandLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &commandLimiter{rate: float64(rate), burst: float64(burst), buckets: make(map[string]*commandBucket)}
+}
+
+// allow takes a command of the user from its bucket, and returns how long the user has to wait if the bucket is empty
+func (l *commandLimiter) allow(userID string, now time.Time) (bool, time.Duration) {
+	if l.rate == 0 {
+		return true, 0
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.sweep(now)
+
+	bucket, ok := l.buckets[userID]
+	if !ok {
+		bucket = &commandBucket{tokens: l.burst, updated: now}
+		l.buckets[userID] = bucket
+	}
+	bucket.tokens = l.refilled(bucket, now)
+	bucket.updated = now
+	if bucket.tokens < 1 {
+		return false, time.Duration((1 - bucket.tokens) / l.rate * float64(time.Minute))
+	}
+	bucket.tokens--
+	return true, 0
+}
+
+func (l *commandLimiter) refilled(bucket *commandBucket, now time.Time) float64 {
+	tokens := bucket.tokens + now.Sub(bucket.updated).Minutes()*l.rate
+	if tokens > l.burst {
+		return l.burst
+	}
+	return tokens
+}
+
+// sweep removes the full buckets once a minute, as they're the same as no bucket
+func (l *commandLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	for userID, bucket := range l.buckets {
+		if l.refilled(bucket, now) >= l.burst {
+			delete(l.buckets, userID)
+		}
+	}
+}
+
+// AllowCommand returns a *CommandCooldownError if the user sent too many commands or button clicks lately, which transports respond
+// with instead of handling the command
+func (h *Service) AllowCommand(userID string) error {
+	allowed, retryAfter := h.commandLimiter.allow(userID, time.Now())
+	if allowed {
+		return nil
+	}
+	commandsRateLimitedTotal.Inc()
+	h.logger.Warn("Rate limiting the commands of a user", "user_id", userID, "retry_after", retryAfter)
+	return &CommandCooldownError{RetryAfter: retryAfter}
+}
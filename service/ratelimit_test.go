@@ -0,0 +1,55 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommandLimiter(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	limiter := newCommandLimiter(6, 3)
+	for i := 0; i < 3; i++ {
+		allowed, _ := limiter.allow("U1", now)
+		assert.True(t, allowed, "the burst is allowed")
+	}
+	allowed, retryAfter := limiter.allow("U1", now)
+	assert.False(t, allowed)
+	assert.Equal(t, 10*time.Second, retryAfter, "a command is refilled every 10 seconds")
+
+	allowed, _ = limiter.allow("U2", now)
+	assert.True(t, allowed, "every user has a bucket")
+
+	allowed, _ = limiter.allow("U1", now.Add(10*time.Second))
+	assert.True(t, allowed)
+	allowed, _ = limiter.allow("U1", now.Add(10*time.Second))
+	assert.False(t, allowed)
+
+	limiter.allow("U3", now.Add(2*time.Minute))
+	assert.Len(t, limiter.buckets, 1, "the full buckets are swept")
+
+	unlimited := newCommandLimiter(0, 0)
+	for i := 0; i < 100; i++ {
+		allowed, _ = unlimited.allow("U1", now)
+		require.True(t, allowed)
+	}
+}
+
+func TestAllowCommand(t *testing.T) {
+	t.Parallel()
+
+	h, err := New(Config{CommandRateLimit: 1, CommandBurst: 1}, nil, nil, nil, "UBOT", nil)
+	require.NoError(t, err)
+
+	require.NoError(t, h.AllowCommand("U1"))
+	err = h.AllowCommand("U1")
+	var cooldown *CommandCooldownError
+	require.True(t, errors.As(err, &cooldown))
+	assert.InDelta(t, time.Minute.Seconds(), cooldown.RetryAfter.Seconds(), 1)
+	assert.Contains(t, err.Error(), "try again in 60 seconds")
+}
@@ -0,0 +1,302 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	RatesFormatSlack    = "slack"
+	RatesFormatText     = "text"
+	RatesFormatBlocks   = "blocks"
+	RatesFormatMarkdown = "markdown"
+	RatesFormatHTML     = "html"
+
+	// maxBlockTextLength is the limit of the text of a Block Kit section
+	maxBlockTextLength = 3000
+)
+
+var (
+	// markupRe matches the tokens of the chat markup the messages are written in: mentions of users and channels, dates and links
+	markupRe = regexp.MustCompile(`<([@#])([A-Za-z0-9]+)>|<!date\^[^|>]*\|([^>]*)>|<((?:https?|mailto|wolt):[^|>\s]+)(?:\|([^>]*))?>`)
+	// boldRe matches *bold* text, leaving alone asterisks which don't wrap a word, like the ones masking a bank account
+	boldRe = regexp.MustCompile(`\*([^*\s](?:[^*\n]*[^*\s])?)\*`)
+)
+
+// RatesRow is the line of a participant in the rates message
+type RatesRow struct {
+	Rate Rate
+	Text string // The line in the chat markup, ending with a newline
+}
+
+// RatesView is the rates message of an order, before it's rendered for a surface. The header, the rows and the footer are in the chat
+// markup the bots send, with mentions like <@U123> and links like <https://wolt.com|Wolt>.
+type RatesView struct {
+	OrderID string
+	Header  string
+	Rows    []RatesRow
+	Footer  string
+	// The display names of the mentioned users by transport ID, for the formats which can't show mentions
+	Names map[string]string
+}
+
+func newRatesView(groupID, header string, groupRate GroupRate, lines []string, footer string) RatesView {
+	view := RatesView{
+		OrderID: groupID,
+		Header:  header,
+		Rows:    make([]RatesRow, len(lines)),
+		Footer:  footer,
+		Names:   make(map[string]string),
+	}
+	for i, line := range lines {
+		view.Rows[i] = RatesRow{Rate: groupRate.Rates[i], Text: line}
+		if user := groupRate.Rates[i].User; user != nil {
+			view.Names[user.TransportID] = user.FullName
+		}
+	}
+	if groupRate.HostUser != nil {
+		view.Names[groupRate.HostUser.TransportID] = groupRate.HostUser.FullName
+	}
+	return view
+}
+
+// lines returns the text of each row
+func (v RatesView) lines() []string {
+	lines := make([]string, len(v.Rows))
+	for i, row := range v.Rows {
+		lines[i] = row.Text
+	}
+	return lines
+}
+
+// name returns the display name of a mentioned user, or their transport ID if they aren't known
+func (v RatesView) name(transportID string) string {
+	if name := v.Names[transportID]; name != "" {
+		return name
+	}
+	return transportID
+}
+
+// RatesFormatter renders the rates message for a surface, like a chat, an email or the dashboard
+type RatesFormatter interface {
+	FormatRates(view RatesView) (string, error)
+}
+
+// RatesFormatterFunc is an adapter to allow using ordinary functions as a RatesFormatter
+type RatesFormatterFunc func(view RatesView) (string, error)
+
+func (f RatesFormatterFunc) FormatRates(view RatesView) (string, error) {
+	return f(view)
+}
+
+var (
+	ratesFormattersLock sync.RWMutex
+	ratesFormatters     = map[string]RatesFormatter{
+		RatesFormatSlack:    RatesFormatterFunc(formatRatesSlack),
+		RatesFormatText:     RatesFormatterFunc(formatRatesText),
+		RatesFormatBlocks:   RatesFormatterFunc(formatRatesBlocks),
+		RatesFormatMarkdown: RatesFormatterFunc(formatRatesMarkdown),
+		RatesFormatHTML:     RatesFormatterFunc(formatRatesHTML),
+	}
+)
+
+// RegisterRatesFormatter makes a rates formatter available by name. Registering an existing name replaces it.
+func RegisterRatesFormatter(name string, formatter RatesFormatter) {
+	ratesFormattersLock.Lock()
+	defer ratesFormattersLock.Unlock()
+	ratesFormatters[name] = formatter
+}
+
+// RatesFormatterByName returns a registered rates formatter
+func RatesFormatterByName(name string) (RatesFormatter, error) {
+	ratesFormattersLock.RLock()
+	defer ratesFormattersLock.RUnlock()
+	formatter, ok := ratesFormatters[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown rates format %q (available: %v)", name, registeredRatesFormatters())
+	}
+	return formatter, nil
+}
+
+func registeredRatesFormatters() []string {
+	names := make([]string, 0, len(ratesFormatters))
+	for name := range ratesFormatters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FormatRates renders the rates message of the order with a registered formatter
+func (h *Service) FormatRates(channel string, groupRate GroupRate, groupID, format string) (string, error) {
+	formatter, err := RatesFormatterByName(format)
+	if err != nil {
+		return "", err
+	}
+	return formatter.FormatRates(h.RatesView(channel, groupRate, groupID))
+}
+
+// markup renders the tokens of the chat markup for a format
+type markup struct {
+	text    func(text string) string // Renders the text between the tokens
+	mention func(name string) string
+	link    func(url, text string) string
+}
+
+// render renders the text, which is in the chat markup
+func (m markup) render(v RatesView, text string) string {
+	var sb strings.Builder
+	last := 0
+	for _, match := range markupRe.FindAllStringSubmatchIndex(text, -1) {
+		sb.WriteString(m.text(text[last:match[0]]))
+		last = match[1]
+		group := func(i int) string {
+			if match[2*i] < 0 {
+				return ""
+			}
+			return text[match[2*i]:match[2*i+1]]
+		}
+		switch {
+		case group(1) == "@":
+			sb.WriteString(m.mention(v.name(group(2))))
+		case group(1) == "#":
+			sb.WriteString(m.text("#" + group(2)))
+		case group(4) != "":
+			sb.WriteString(m.link(group(4), group(5)))
+		default:
+			sb.WriteString(m.text(group(3)))
+		}
+	}
+	sb.WriteString(m.text(text[last:]))
+	return sb.String()
+}
+
+var textMarkup = markup{
+	text:    func(text string) string { return boldRe.ReplaceAllString(text, "$1") },
+	mention: func(name string) string { return "@" + name },
+	link: func(url, text string) string {
+		if text == "" {
+			return url
+		}
+		return fmt.Sprintf("%s (%s)", text, url)
+	},
+}
+
+var markdownMarkup = markup{
+	text:    func(text string) string { return boldRe.ReplaceAllString(text, "**$1**") },
+	mention: func(name string) string { return "**@" + name + "**" },
+	link: func(url, text string) string {
+		if text == "" {
+			return "<" + url + ">"
+		}
+		return fmt.Sprintf("[%s](%s)", text, url)
+	},
+}
+
+var htmlMarkup = markup{
+	text:    func(text string) string { return boldRe.ReplaceAllString(html.EscapeString(text), "<b>$1</b>") },
+	mention: func(name string) string { return "<b>@" + html.EscapeString(name) + "</b>" },
+	link: func(url, text string) string {
+		if text == "" {
+			text = url
+		}
+		return fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(url), html.EscapeString(text))
+	},
+}
+
+// formatRatesSlack renders the view as the bots send it
+func formatRatesSlack(view RatesView) (string, error) {
+	return view.Header + strings.Join(view.lines(), "") + view.Footer, nil
+}
+
+// formatRatesText renders the view as plain text, with the names of the mentioned users and the addresses of the links
+func formatRatesText(view RatesView) (string, error) {
+	slack, _ := formatRatesSlack(view)
+	return textMarkup.render(view, slack), nil
+}
+
+// formatRatesMarkdown renders the view as Markdown, with the rows as a list
+func formatRatesMarkdown(view RatesView) (string, error) {
+	var sb strings.Builder
+	sb.WriteString(markdownMarkup.render(view, view.Header))
+	sb.WriteString("\n")
+	for _, row := range view.Rows {
+		sb.WriteString("- ")
+		sb.WriteString(strings.ReplaceAll(markdownMarkup.render(view, strings.TrimSuffix(row.Text, "\n")), "\n", "\n  "))
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\n")
+	sb.WriteString(markdownMarkup.render(view, view.Footer))
+	return sb.String(), nil
+}
+
+// formatRatesHTML renders the view as an HTML fragment for emails and the dashboard, with the rows as a list
+func formatRatesHTML(view RatesView) (string, error) {
+	paragraph := func(text string) string {
+		text = strings.TrimSuffix(htmlMarkup.render(view, text), "\n")
+		return strings.ReplaceAll(text, "\n", "<br>\n")
+	}
+	var sb strings.Builder
+	sb.WriteString("<div class=\"bolt-rates\">\n<p>")
+	sb.WriteString(paragraph(view.Header))
+	sb.WriteString("</p>\n<ul>\n")
+	for _, row := range view.Rows {
+		sb.WriteString("<li>")
+		sb.WriteString(paragraph(row.Text))
+		sb.WriteString("</li>\n")
+	}
+	sb.WriteString("</ul>\n")
+	if footer := paragraph(view.Footer); footer != "" {
+		sb.WriteString("<p>")
+		sb.WriteString(footer)
+		sb.WriteString("</p>\n")
+	}
+	sb.WriteString("</div>\n")
+	return sb.String(), nil
+}
+
+type blockText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type block struct {
+	Type string     `json:"type"`
+	Text *blockText `json:"text,omitempty"`
+}
+
+// formatRatesBlocks renders the view as a Slack Block Kit payload: a section for the header, sections of the rows split by the limit
+// of a section's text, and a section for the footer
+func formatRatesBlocks(view RatesView) (string, error) {
+	blocks := make([]block, 0)
+	section := func(text string) {
+		if text = strings.TrimSpace(text); text != "" {
+			blocks = append(blocks, block{Type: "section", Text: &blockText{Type: "mrkdwn", Text: text}})
+		}
+	}
+	section(view.Header)
+	rows := ""
+	for _, line := range view.lines() {
+		if len(rows)+len(line) > maxBlockTextLength {
+			section(rows)
+			rows = ""
+		}
+		rows += line
+	}
+	section(rows)
+	if strings.TrimSpace(view.Footer) != "" {
+		blocks = append(blocks, block{Type: "divider"})
+		section(view.Footer)
+	}
+
+	payload, err := json.Marshal(map[string][]block{"blocks": blocks})
+	if err != nil {
+		return "", fmt.Errorf("marshal blocks: %w", err)
+	}
+	return string(payload), nil
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testRatesView() RatesView {
+	return RatesView{
+		OrderID: "ABC",
+		Header:  "Rates for Wolt order ID ABC:\n",
+		Rows: []RatesRow{
+			{Rate: Rate{WoltName: "Loki", Amount: 30}, Text: "<@U1> (Loki): 30.00 <https://pay.example.com/1?a=b&c=d|Pay>\n"},
+			{Rate: Rate{WoltName: "Bob", Amount: 20}, Text: "Bob: 20.00\n"},
+		},
+		Footer: "\nPay to: <@U0> in <#C1> until <!date^1700000000^{time}|10:13>\nAccount: *****1234 of *Thor & Sons*\n",
+		Names:  map[string]string{"U0": "Thor", "U1": "Loki Laufeyson"},
+	}
+}
+
+func TestFormatRates(t *testing.T) {
+	t.Parallel()
+
+	view := testRatesView()
+	format := func(name string) string {
+		formatter, err := RatesFormatterByName(name)
+		require.NoError(t, err)
+		formatted, err := formatter.FormatRates(view)
+		require.NoError(t, err)
+		return formatted
+	}
+
+	assert.Equal(t, view.Header+view.Rows[0].Text+view.Rows[1].Text+view.Footer, format(RatesFormatSlack))
+	assert.Equal(t, "Rates for Wolt order ID ABC:\n@Loki Laufeyson (Loki): 30.00 Pay (https://pay.example.com/1?a=b&c=d)\nBob: 20.00\n"+
+		"\nPay to: @Thor in #C1 until 10:13\nAccount: *****1234 of Thor & Sons\n", format(RatesFormatText))
+	assert.Equal(t, "Rates for Wolt order ID ABC:\n\n- **@Loki Laufeyson** (Loki): 30.00 [Pay](https://pay.example.com/1?a=b&c=d)\n- Bob: 20.00\n\n"+
+		"\nPay to: **@Thor** in #C1 until 10:13\nAccount: *****1234 of **Thor & Sons**\n", format(RatesFormatMarkdown))
+	assert.Equal(t, "<div class=\"bolt-rates\">\n<p>Rates for Wolt order ID ABC:</p>\n<ul>\n"+
+		"<li><b>@Loki Laufeyson</b> (Loki): 30.00 <a href=\"https://pay.example.com/1?a=b&amp;c=d\">Pay</a></li>\n<li>Bob: 20.00</li>\n</ul>\n"+
+		"<p><br>\nPay to: <b>@Thor</b> in #C1 until 10:13<br>\nAccount: *****1234 of <b>Thor &amp; Sons</b></p>\n</div>\n", format(RatesFormatHTML))
+
+	var payload struct {
+		Blocks []block `json:"blocks"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(format(RatesFormatBlocks)), &payload))
+	require.Len(t, payload.Blocks, 4)
+	assert.Equal(t, "Rates for Wolt order ID ABC:", payload.Blocks[0].Text.Text)
+	assert.Equal(t, "mrkdwn", payload.Blocks[1].Text.Type)
+	assert.Equal(t, "<@U1> (Loki): 30.00 <https://pay.example.com/1?a=b&c=d|Pay>\nBob: 20.00", payload.Blocks[1].Text.Text)
+	assert.Equal(t, "divider", payload.Blocks[2].Type)
+
+	_, err := RatesFormatterByName("pdf")
+	assert.Error(t, err)
+}
+
+func TestFormatRatesBlocksSplitsRows(t *testing.T) {
+	t.Parallel()
+
+	h, err := New(Config{}, nil, nil, nil, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+	formatted, err := h.FormatRates("C1", bigGroupRate(150), "ABC", RatesFormatBlocks)
+	require.NoError(t, err)
+
+	var payload struct {
+		Blocks []block `json:"blocks"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(formatted), &payload))
+	assert.Greater(t, len(payload.Blocks), 4)
+	for _, b := range payload.Blocks {
+		if b.Text != nil {
+			assert.LessOrEqual(t, len(b.Text.Text), maxBlockTextLength)
+		}
+	}
+}
+
+func TestServiceFormatRates(t *testing.T) {
+	t.Parallel()
+
+	h, err := New(Config{}, nil, nil, nil, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+	host := &userDomain.User{ID: "uuid-host", FullName: "Thor Odinson", TransportID: "UHOST"}
+	groupRate := GroupRate{
+		HostWoltUser: "Thor",
+		HostUser:     host,
+		DeliveryRate: 10,
+		Rates: []Rate{
+			{WoltName: "Thor", User: host, Amount: 40},
+			{WoltName: "Bob", Amount: 25},
+		},
+	}
+
+	slack, err := h.FormatRates("C1", groupRate, "ABC", RatesFormatSlack)
+	require.NoError(t, err)
+	assert.Equal(t, h.buildRatesMessage("C1", groupRate, "ABC"), slack, "the bots send the slack format")
+
+	text, err := h.FormatRates("C1", groupRate, "ABC", RatesFormatText)
+	require.NoError(t, err)
+	assert.Contains(t, text, "@Thor Odinson (Thor): 40.00\nBob: 25.00\n")
+	assert.Contains(t, text, "Pay to: @Thor Odinson\n")
+	assert.NotContains(t, text, "<@")
+
+	_, err = h.FormatRates("C1", groupRate, "ABC", "pdf")
+	assert.Error(t, err)
+
+	RegisterRatesFormatter("count", RatesFormatterFunc(func(view RatesView) (string, error) {
+		return view.OrderID + ": " + view.Rows[1].Rate.WoltName, nil
+	}))
+	custom, err := h.FormatRates("C1", groupRate, "ABC", "count")
+	require.NoError(t, err)
+	assert.Equal(t, "ABC: Bob", custom)
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// RatesItems is where the items of each participant are listed, so they can check their amount before paying
+type RatesItems string
+
+const (
+	RatesItemsOff    RatesItems = "off"
+	RatesItemsInline RatesItems = "inline" // Under each participant's rate in the rates message
+	RatesItemsThread RatesItems = "thread" // In a message following the rates message in its thread
+)
+
+func parseRatesItems(value string) (RatesItems, error) {
+	switch items := RatesItems(value); items {
+	case "":
+		return RatesItemsOff, nil
+	case RatesItemsOff, RatesItemsInline, RatesItemsThread:
+		return items, nil
+	default:
+		return "", fmt.Errorf("unknown rates items %q (available: %s, %s, %s)", value, RatesItemsOff, RatesItemsInline, RatesItemsThread)
+	}
+}
+
+// itemLines returns a line for every item, with its quantity, name (in the channel's locale) and price
+func (h *Service) itemLines(channel string, items []Item) string {
+	names := make([]string, len(items))
+	for i, item := range items {
+		names[i] = item.Name
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), translationTimeout)
+	defer cancel()
+	names = h.translateItemNames(ctx, channel, names)
+
+	var sb strings.Builder
+	for i, item := range items {
+		sb.WriteString(h.text(channel, msgRateItem, item.Quantity(), names[i], item.Amount))
+	}
+	return sb.String()
+}
+
+// buildItemsBreakdownMessage returns the message listing the items of every participant, or an empty message if no participant has
+// items
+func (h *Service) buildItemsBreakdownMessage(channel string, groupRate GroupRate, groupID string) string {
+	var sb strings.Builder
+	for _, rate := range groupRate.Rates {
+		if len(rate.Items) == 0 || rate.Private {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("*%s*: %.2f", rate.WoltName, rate.Amount))
+		sb.WriteString(h.itemLines(channel, rate.Items))
+		sb.WriteString("\n")
+	}
+	if sb.Len() == 0 {
+		return ""
+	}
+	return h.text(channel, msgItemsBreakdown, groupID) + sb.String()
+}
+
+// sendItemsBreakdown sends the items of every participant in the thread of the rates message, when RATES_ITEMS is thread. The
+// amounts include the fees and discounts, so they're more than the sum of the items.
+func (h *Service) sendItemsBreakdown(ctx context.Context, channel string, groupRate GroupRate, groupID, messageID string) {
+	if h.ratesItems != RatesItemsThread {
+		return
+	}
+	message := h.buildItemsBreakdownMessage(channel, groupRate, groupID)
+	if message == "" {
+		return
+	}
+	if _, err := h.informEventContext(ctx, channel, message, "", messageID); err != nil {
+		h.logger.ErrorContext(ctx, "Error sending the items breakdown", "group_id", groupID, "error", err)
+	}
+}
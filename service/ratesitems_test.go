@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func itemsOrderDetails(t *testing.T) *OrderDetails {
+	details, err := parseWoltOrderDetails([]byte(`{
+		"host_id": "1",
+		"details": {"delivery_info": {"location": {"coordinates": {"coordinates": [34.78, 32.08]}}}},
+		"participants": [
+			{"first_name": "Thor", "user_id": "1", "basket": {"items": [{"name": "Pizza", "end_amount": 3000}]}},
+			{"first_name": "Loki", "user_id": "2", "basket": {"items": [
+				{"name": "Salad", "end_amount": 3000},
+				{"name": "Beer", "end_amount": 5000, "count": 2}
+			]}}
+		]
+	}`))
+	require.NoError(t, err)
+	return details
+}
+
+func TestRatesItemsInline(t *testing.T) {
+	t.Parallel()
+
+	details := itemsOrderDetails(t)
+	h, err := New(Config{RatesItems: "inline"}, &fakeTreasuryStore{}, nil, nil, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+	rates, err := details.RateByPerson()
+	require.NoError(t, err)
+	groupRate := h.buildGroupRates(rates, details.Host, 0)
+	h.setItemAmounts(&groupRate, "ABC", details)
+
+	assert.Equal(t, "Rates for Wolt order ID ABC (including 0 NIS for delivery):\n"+
+		"Loki: 80.00\n"+
+		"    • 1x Salad: 30.00\n"+
+		"    • 2x Beer: 50.00\n"+
+		"Thor: 30.00\n"+
+		"    • 1x Pizza: 30.00\n"+
+		"\nPay to: Thor\n", h.buildRatesMessage("C1", groupRate, "ABC"))
+}
+
+func TestRatesItemsThread(t *testing.T) {
+	t.Parallel()
+
+	details := itemsOrderDetails(t)
+	notifications := &recordingNotification{}
+	h, err := New(Config{RatesItems: "thread"}, &fakeTreasuryStore{}, nil, nil, "UBOT", notifications)
+	require.NoError(t, err)
+	rates, err := details.RateByPerson()
+	require.NoError(t, err)
+	groupRate := h.buildGroupRates(rates, details.Host, 0)
+	h.setItemAmounts(&groupRate, "ABC", details)
+	assert.NotContains(t, h.buildRatesMessage("C1", groupRate, "ABC"), "Salad", "the items are only in the thread")
+
+	h.sendItemsBreakdown(context.Background(), "C1", groupRate, "ABC", "1.1")
+	assert.Equal(t, []string{"C1: Items of order ABC (the amounts include the delivery, fees and discounts):\n" +
+		"*Loki*: 80.00\n    • 1x Salad: 30.00\n    • 2x Beer: 50.00\n" +
+		"*Thor*: 30.00\n    • 1x Pizza: 30.00\n"}, notifications.messages)
+
+	_, err = New(Config{RatesItems: "table"}, nil, nil, nil, "UBOT", nil)
+	assert.ErrorContains(t, err, `unknown rates items "table"`)
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	"github.com/oriser/bolt/order"
+)
+
+// debtsView is the read model of the outstanding debts of a channel's orders, which are netted into its balances
+type debtsView struct {
+	builtAt time.Time
+	debts   map[string]*debtDomain.Debt // By ID
+}
+
+// monthsView is the read model of the totals of the orders delivered to a channel, by month in the channel's timezone
+type monthsView struct {
+	builtAt  time.Time
+	location *time.Location
+	counted  map[string]bool         // The Wolt group IDs of the orders counted in the totals
+	months   map[string]*monthTotals // By monthKey
+}
+
+// readModels keeps denormalized views of the channels, so the balances and the monthly reports don't aggregate the stores during the
+// lunch peak. The view of a channel is built from the stores the first time it's read, and kept up to date by the lifecycle events
+// and the changes of debts and orders of the service. Views are rebuilt after READ_MODELS_TTL, as other components (like the
+// scheduler) change the stores as well, and aren't kept at all if it's 0.
+type readModels struct {
+	lock     sync.Mutex
+	ttl      time.Duration
+	debts    map[string]*debtsView
+	months   map[string]*monthsView
+	versions map[string]int // Bumped on every change of a channel, so a view built from the stores while it changed isn't kept
+}
+
+func newReadModels(ttl time.Duration) *readModels {
+	return &readModels{ttl: ttl, debts: make(map[string]*debtsView), months: make(map[string]*monthsView), versions: make(map[string]int)}
+}
+
+func monthKey(t time.Time) string {
+	return t.Format("2006-01")
+}
+
+func (r *readModels) fresh(builtAt time.Time) bool {
+	return r.ttl > 0 && time.Since(builtAt) < r.ttl
+}
+
+func (r *readModels) onEvent(_ context.Context, event Event) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.versions[event.Channel]++
+
+	view, ok := r.debts[event.Channel]
+	if !ok {
+		return
+	}
+	switch event.Type {
+	case EventDebtCreated:
+		debt := *event.Debt
+		view.debts[debt.ID] = &debt
+	case EventDebtPaid:
+		delete(view.debts, event.Debt.ID)
+	case EventOrderDebtsRemoved:
+		for id, debt := range view.debts {
+			if debt.OrderID == event.OrderID {
+				delete(view.debts, id)
+			}
+		}
+	}
+}
+
+// putDebt adds or replaces a debt changed without a lifecycle event, like when its amount is adjusted
+func (r *readModels) putDebt(debt *debtDomain.Debt) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	channel := debt.InitiatedTransportID
+	r.versions[channel]++
+	if view, ok := r.debts[channel]; ok {
+		copied := *debt
+		view.debts[debt.ID] = &copied
+	}
+}
+
+// removeDebt removes a debt removed without a lifecycle event, like when it's forgiven
+func (r *readModels) removeDebt(debt *debtDomain.Debt) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	channel := debt.InitiatedTransportID
+	r.versions[channel]++
+	if view, ok := r.debts[channel]; ok {
+		delete(view.debts, debt.ID)
+	}
+}
+
+// addOrder counts a saved order in the totals of its channel, if it was delivered. Orders saved again (which shouldn't happen) drop
+// the totals, so they're rebuilt from the store.
+func (r *readModels) addOrder(o *order.Order) {
+	if o.Receiver == "" || o.Status != order.StatusDone {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.versions[o.Receiver]++
+	view, ok := r.months[o.Receiver]
+	if !ok {
+		return
+	}
+	if view.counted[o.OriginalID] {
+		delete(r.months, o.Receiver)
+		return
+	}
+	view.add(o)
+}
+
+// dropOrders drops the totals of the channel, so they're rebuilt from the store, like when the participants of an order changed
+func (r *readModels) dropOrders(channel string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.versions[channel]++
+	delete(r.months, channel)
+}
+
+func (v *monthsView) add(o *order.Order) {
+	key := monthKey(o.CreatedAt.In(v.location))
+	if _, ok := v.months[key]; !ok {
+		v.months[key] = newMonthTotals()
+	}
+	v.months[key].add(o)
+	v.counted[o.OriginalID] = true
+}
+
+// readChannelDebts calls read with the outstanding debts of the channel's orders, building their view from the debt store if it isn't
+// kept. The view is locked while it's read, so read mustn't call the stores.
+func (h *Service) readChannelDebts(ctx context.Context, channel string, read func(debts map[string]*debtDomain.Debt)) error {
+	r := h.readModels
+	r.lock.Lock()
+	if view, ok := r.debts[channel]; ok && r.fresh(view.builtAt) {
+		defer r.lock.Unlock()
+		read(view.debts)
+		return nil
+	}
+	version := r.versions[channel]
+	r.lock.Unlock()
+
+	debts, err := h.debtStore.ListDebts(ctx, debtDomain.ListFilter{Channel: channel})
+	if err != nil {
+		return fmt.Errorf("list debts: %w", err)
+	}
+	view := &debtsView{builtAt: time.Now(), debts: make(map[string]*debtDomain.Debt, len(debts))}
+	for _, debt := range debts {
+		view.debts[debt.ID] = debt
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.ttl > 0 && r.versions[channel] == version {
+		r.debts[channel] = view
+	}
+	read(view.debts)
+	return nil
+}
+
+// readChannelMonths calls read with the totals of the orders delivered to the channel by month in the given timezone (the channel's),
+// building their view from the order store if it isn't kept or is in another timezone. The view is locked while it's read, so read
+// mustn't call the stores.
+func (h *Service) readChannelMonths(ctx context.Context, channel string, location *time.Location, read func(months map[string]*monthTotals)) error {
+	r := h.readModels
+	r.lock.Lock()
+	if view, ok := r.months[channel]; ok && r.fresh(view.builtAt) && view.location.String() == location.String() {
+		defer r.lock.Unlock()
+		read(view.months)
+		return nil
+	}
+	version := r.versions[channel]
+	r.lock.Unlock()
+
+	orders, err := h.orderStore.ListOrders(ctx, order.ListFilter{Receiver: channel})
+	if err != nil {
+		return fmt.Errorf("list orders: %w", err)
+	}
+	view := &monthsView{builtAt: time.Now(), location: location, counted: make(map[string]bool), months: make(map[string]*monthTotals)}
+	for _, o := range orders {
+		if o.Status == order.StatusDone {
+			view.add(o)
+		}
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.ttl > 0 && r.versions[channel] == version {
+		r.months[channel] = view
+	}
+	read(view.months)
+	return nil
+}
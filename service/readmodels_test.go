@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	"github.com/oriser/bolt/order"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadModelsBalances(t *testing.T) {
+	t.Parallel()
+
+	store := &fakeTreasuryStore{debts: []*debtDomain.Debt{
+		{ID: "1", BorrowerID: "U1", LenderID: "U2", OrderID: "A", Amount: 30, InitiatedTransportID: "C1"},
+		{ID: "2", BorrowerID: "U3", LenderID: "U2", OrderID: "B", Amount: 5, InitiatedTransportID: "C1"},
+	}}
+	h, err := New(Config{ReadModelsTTL: time.Hour}, store, store, nil, "U-bot", &recordingNotification{})
+	require.NoError(t, err)
+	amounts := func() map[string]float64 {
+		balances, err := h.ChannelBalances(context.Background(), "C1")
+		require.NoError(t, err)
+		ret := make(map[string]float64, len(balances))
+		for _, balance := range balances {
+			ret[balance.BorrowerID+">"+balance.LenderID] = balance.Amount
+		}
+		return ret
+	}
+	assert.Equal(t, map[string]float64{"U1>U2": 30, "U3>U2": 5}, amounts())
+
+	store.debts = append(store.debts, &debtDomain.Debt{ID: "3", BorrowerID: "U2", LenderID: "U1", OrderID: "C", Amount: 10, InitiatedTransportID: "C1"})
+	assert.Equal(t, map[string]float64{"U1>U2": 30, "U3>U2": 5}, amounts(), "the balances are read from the read model, not the store")
+
+	h.hooks.Emit(context.Background(), Event{Type: EventDebtCreated, OrderID: "C", Channel: "C1", Debt: store.debts[2]})
+	h.hooks.Emit(context.Background(), Event{Type: EventDebtPaid, OrderID: "B", Channel: "C1", Debt: store.debts[1]})
+	assert.Equal(t, map[string]float64{"U1>U2": 20}, amounts())
+
+	require.NoError(t, h.replaceDebt(context.Background(), store.debts[0], func(d *debtDomain.Debt) { d.Amount = 50 }))
+	assert.Equal(t, map[string]float64{"U1>U2": 40}, amounts(), "debts changed without an event are updated as well")
+
+	h.hooks.Emit(context.Background(), Event{Type: EventOrderDebtsRemoved, OrderID: "A", Channel: "C1"})
+	assert.Equal(t, map[string]float64{"U2>U1": 10}, amounts())
+}
+
+func TestReadModelsMonthlyReport(t *testing.T) {
+	t.Parallel()
+
+	may := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	orderStore := &fakeOrderStore{orders: []*order.Order{
+		{OriginalID: "A", Receiver: "C1", VenueName: "Pizza", Host: "Thor", Status: order.StatusDone, CreatedAt: may,
+			Participants: []order.Participant{{Name: "Thor", Amount: 40}}},
+	}}
+	h, err := New(Config{ReadModelsTTL: time.Hour}, nil, nil, orderStore, "U-bot", &recordingNotification{})
+	require.NoError(t, err)
+	report, err := h.MonthlyReport(context.Background(), "C1", 2024, time.May)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Orders)
+
+	sushi := &order.Order{OriginalID: "B", Receiver: "C1", VenueName: "Sushi", Host: "Loki", Status: order.StatusDone, CreatedAt: may,
+		Participants: []order.Participant{{Name: "Loki", Amount: 50}, {Name: "Thor", Amount: 10}}}
+	orderStore.orders = append(orderStore.orders, sushi)
+	h.readModels.addOrder(sushi)
+	h.readModels.addOrder(&order.Order{OriginalID: "C", Receiver: "C1", VenueName: "Burger", Status: order.StatusCanceled, CreatedAt: may})
+	report, err = h.MonthlyReport(context.Background(), "C1", 2024, time.May)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Orders)
+	assert.Equal(t, 100.0, report.Total)
+	assert.Equal(t, []PersonTotal{{Name: "Loki", Total: 50, Orders: 1}, {Name: "Thor", Total: 50, Orders: 2}}, report.TopSpenders)
+	assert.Equal(t, []VenueTotal{{VenueName: "Sushi", Total: 60, Orders: 1}, {VenueName: "Pizza", Total: 40, Orders: 1}}, report.Venues)
+
+	sushi.Participants[1].Amount = 20
+	report, err = h.MonthlyReport(context.Background(), "C1", 2024, time.May)
+	require.NoError(t, err)
+	assert.Equal(t, 100.0, report.Total, "the totals are read from the read model, not the store")
+	h.readModels.dropOrders("C1")
+	report, err = h.MonthlyReport(context.Background(), "C1", 2024, time.May)
+	require.NoError(t, err)
+	assert.Equal(t, 110.0, report.Total, "the totals are rebuilt from the store once dropped")
+}
@@ -0,0 +1,284 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	"github.com/oriser/bolt/order"
+)
+
+// ratesDelta is the difference between the published rates of an order and the rates computed from its purchase details
+type ratesDelta struct {
+	joiners   []string // Participants who joined after the rates were computed
+	reduced   []string // Participants whose items were (partially or fully) removed
+	increased []string // Participants who added items, or whose share of the fees grew
+	changed   bool
+}
+
+// diffRates compares the published rates to the given rates (by Wolt name, including fees)
+func diffRates(groupRate GroupRate, woltRates map[string]float64) ratesDelta {
+	delta := ratesDelta{joiners: make([]string, 0), reduced: make([]string, 0), increased: make([]string, 0)}
+	published := make(map[string]float64, len(groupRate.Rates))
+	for _, rate := range groupRate.Rates {
+		published[rate.WoltName] = rate.Amount
+	}
+
+	for _, name := range getSortedKeys(woltRates) {
+		amount, ok := published[name]
+		if !ok {
+			delta.joiners = append(delta.joiners, name)
+			delta.changed = true
+			continue
+		}
+		if !sameAmount(amount, woltRates[name]) {
+			delta.changed = true
+		}
+	}
+	for _, rate := range groupRate.Rates {
+		if rate.WoltName == groupRate.HostWoltUser {
+			// The host's amount changes with the others', and they don't owe anything anyway
+			continue
+		}
+		if sameAmount(woltRates[rate.WoltName], rate.Amount) {
+			continue
+		}
+		if woltRates[rate.WoltName] < rate.Amount {
+			delta.reduced = append(delta.reduced, rate.WoltName)
+		} else {
+			delta.increased = append(delta.increased, rate.WoltName)
+		}
+		delta.changed = true
+	}
+	return delta
+}
+
+func sameAmount(a, b float64) bool {
+	return math.Abs(a-b) < 0.005
+}
+
+// reconcileRates compares the order's purchase details to its published rates. Participants can join between marking the group
+// as ready and the purchase, the host can remove items the restaurant rejected, and participants can change their items if the
+// order is reopened before the checkout. If the rates changed, it recomputes them, edits the rates message, tracks the debts of the
+// late joiners, adjusts the outstanding debts and updates the stored order.
+// It returns the rates message to show, which is the given one if nothing changed.
+func (h *Service) reconcileRates(channel string, order *groupOrder, details *OrderDetails, groupRate *GroupRate,
+	messageID, ratesMessage string) string {
+	itemRates, err := details.RateByPerson()
+	if err != nil {
+		h.logger.ErrorContext(order.ctx, "Error getting rates for reconciling them", "error", err)
+		return ratesMessage
+	}
+	woltRates := h.allocateDiscount(itemRates, details.Host, details.DiscountsAmount())
+	// The rates are computed with the fees the host corrected with !delivery or !extra, and compared with the rates shown with them
+	fees, overridden := h.rateAdjustments.overriddenFees(order.id)
+	if !overridden {
+		fees = orderFees(details, groupRate.DeliveryRate)
+	}
+	allocated := woltRates
+	if fees != (Fees{}) {
+		allocated = h.channelFeeAllocator(channel).Allocate(woltRates, details.Host, fees)
+	}
+	allocated = h.roundRates(allocated, details.Host)
+	previous := h.rateAdjustments.withFees(order.id, *groupRate)
+	delta := diffRates(previous, allocated)
+	if !delta.changed {
+		return ratesMessage
+	}
+	h.logger.InfoContext(order.ctx, "Rates of order changed after they were published", "joined", delta.joiners, "reduced", delta.reduced,
+		"increased", delta.increased)
+
+	updated := h.buildGroupRates(allocated, details.Host, groupRate.DeliveryRate)
+	h.setItemAmounts(&updated, order.id, details)
+	updated.CompanyPaid = groupRate.CompanyPaid
+	updated.ExternalRef = groupRate.ExternalRef
+	updated.Currency = groupRate.Currency
+	updated.Discount = details.DiscountsAmount()
+	updated.ItemRates = itemRates
+	updated.setFees(orderFees(details, groupRate.DeliveryRate))
+	*groupRate = updated
+	if overridden {
+		h.rateAdjustments.setFees(order.id, fees, h.ratesWithFees(channel, updated, fees))
+	}
+
+	// The host's adjustments stay in place, the participants they forgave, changed or reassigned keep what the host set
+	shown := updated
+	if adjusted, ok := h.rateAdjustments.apply(order.id, updated); ok {
+		shown = adjusted
+	}
+	updatedMessage := h.buildRatesMessage(channel, shown, order.id)
+	if err := h.editRatesMessage(channel, order, updated, updatedMessage, ""); err != nil {
+		h.logger.ErrorContext(order.ctx, "Error editing the rates message", "error", err)
+	}
+	if len(delta.joiners) > 0 {
+		_, _ = h.informEvent(channel, h.text(channel, msgRatesJoiners, strings.Join(delta.joiners, ", ")), "", messageID)
+	}
+	if reduced := h.unadjustedNames(order.id, delta.reduced); len(reduced) > 0 {
+		_, _ = h.informEvent(channel, h.buildChangedRatesMessage(channel, h.text(channel, msgRatesItemsRemoved),
+			previous, updated, reduced), "", messageID)
+	}
+	if increased := h.unadjustedNames(order.id, delta.increased); len(increased) > 0 {
+		_, _ = h.informEvent(channel, h.buildChangedRatesMessage(channel, h.text(channel, msgRatesAmountsIncreased),
+			previous, updated, increased), "", messageID)
+	}
+	h.updateStoredParticipants(channel, order.id, updated)
+
+	event := Event{Type: EventRatesPublished, OrderID: order.id, Channel: channel, MessageID: messageID, Rates: &shown}
+	if order.venue != nil {
+		event.VenueName = order.venue.Name
+	}
+	h.hooks.Emit(order.ctx, event)
+
+	if updated.CompanyPaid {
+		return updatedMessage
+	}
+	if err := h.updateDebts(order.ctx, channel, order.id, previous, updated, delta.joiners, messageID); err != nil {
+		h.logger.ErrorContext(order.ctx, "Error updating debts", "error", err)
+	}
+	return updatedMessage
+}
+
+// buildChangedRatesMessage returns the header followed by the previous and updated amounts of the given participants, leaving out the
+// amounts of the participants who keep them private
+func (h *Service) buildChangedRatesMessage(channel, header string, previous, updated GroupRate, names []string) string {
+	var sb strings.Builder
+	sb.WriteString(header)
+	for _, name := range names {
+		before, after := rateByName(previous, name), rateByName(updated, name)
+		who := name
+		if before != nil && before.User != nil {
+			who = fmt.Sprintf("<@%s> (%s)", before.User.TransportID, name)
+		}
+		if before.Private || (after != nil && after.Private) {
+			sb.WriteString(h.text(channel, msgRateLinePrivate, who) + "\n")
+			continue
+		}
+		afterAmount := 0.0
+		if after != nil {
+			afterAmount = after.PersonalAmount()
+		}
+		sb.WriteString(fmt.Sprintf("%s: %.2f → %.2f\n", who, before.PersonalAmount(), afterAmount))
+	}
+	return sb.String()
+}
+
+// updateStoredParticipants updates the participants of the stored order to the recomputed rates, if the order store supports it
+func (h *Service) updateStoredParticipants(channel, orderID string, updated GroupRate) {
+	participantsStore, ok := h.orderStore.(order.ParticipantsStore)
+	if !ok {
+		return
+	}
+	ctx, cancel := h.storeContext()
+	defer cancel()
+	if err := participantsStore.UpdateOrderParticipants(ctx, orderID, participantsOfRates(updated.Rates)); err != nil {
+		h.logger.Error("Error updating the participants of stored order", "group_id", orderID, "error", err)
+		return
+	}
+	h.readModels.dropOrders(channel)
+}
+
+// unadjustedNames returns the Wolt names whose rates the host didn't adjust in the order
+func (h *Service) unadjustedNames(orderID string, names []string) []string {
+	var res []string
+	for _, name := range names {
+		if !h.rateAdjustments.adjusted(orderID, name) {
+			res = append(res, name)
+		}
+	}
+	return res
+}
+
+func rateByName(groupRate GroupRate, name string) *Rate {
+	for i := range groupRate.Rates {
+		if groupRate.Rates[i].WoltName == name {
+			return &groupRate.Rates[i]
+		}
+	}
+	return nil
+}
+
+// updateDebts tracks the debts of the given new participants, and updates the outstanding debts of the other participants whose
+// share changed (for example, when the delivery fee is split between more participants or their items were removed)
+func (h *Service) updateDebts(ctx context.Context, channel, orderID string, previous, updated GroupRate, newParticipants []string, messageID string) error {
+	if h.debtStore == nil || updated.HostUser == nil {
+		return nil
+	}
+
+	isNew := make(map[string]bool, len(newParticipants))
+	for _, name := range newParticipants {
+		isNew[name] = true
+	}
+	updatedAmounts := make(map[string]float64, len(updated.Rates))
+	for _, rate := range updated.Rates {
+		updatedAmounts[rate.WoltName] = rate.PersonalAmount()
+	}
+
+	debts, err := h.debtStore.ListDebtsForOrderID(ctx, orderID)
+	if err != nil {
+		return fmt.Errorf("list debts: %w", err)
+	}
+	outstanding := make(map[string]*debtDomain.Debt, len(debts))
+	for _, debt := range debts {
+		outstanding[debt.BorrowerID] = debt
+	}
+
+	for _, rate := range updated.Rates {
+		if rate.WoltName == updated.HostWoltUser || !isNew[rate.WoltName] {
+			continue
+		}
+		if rate.User == nil {
+			h.handleUnknownParticipant(ctx, channel, orderID, messageID, updated.Currency, rate, updated.HostUser)
+			continue
+		}
+		debt, err := h.createDebt(ctx, rate.PersonalAmount(), updated.Currency, channel, orderID, messageID, rate.User, updated.HostUser)
+		if err != nil {
+			h.logger.Error("Error creating debt for late joiner", "wolt_name", rate.WoltName, "group_id", orderID, "error", err)
+			continue
+		}
+		h.sendDebtDM(ctx, channel, orderID, updated, rate, debt)
+	}
+
+	// Participants whose items were all removed aren't in the updated rates, so their amount is 0. The debts the host forgave, changed
+	// or reassigned stay as the host left them, like when the host corrects the fees.
+	for _, rate := range previous.Rates {
+		amount := updatedAmounts[rate.WoltName]
+		if rate.WoltName == previous.HostWoltUser || rate.User == nil || sameAmount(rate.PersonalAmount(), amount) ||
+			h.rateAdjustments.adjusted(orderID, rate.WoltName) {
+			continue
+		}
+		debt, ok := outstanding[rate.User.ID]
+		if !ok {
+			if amount < rate.PersonalAmount() && rate.Private {
+				_, _ = h.informEvent(channel, h.text(channel, msgAlreadyPaidPrivate, rate.User.TransportID, updated.HostUser.TransportID), "", messageID)
+				_, _ = h.informInteractiveEvent(updated.HostUser.TransportID, h.text(updated.HostUser.TransportID, msgPayBackPrivate,
+					rate.User.TransportID, rate.PersonalAmount()-amount, orderID), "")
+			} else if amount < rate.PersonalAmount() {
+				_, _ = h.informEvent(channel, h.text(channel, msgAlreadyPaid,
+					rate.User.TransportID, rate.PersonalAmount(), updated.HostUser.TransportID, rate.PersonalAmount()-amount), "", messageID)
+			}
+			continue
+		}
+		if err := h.debtStore.RemoveDebtInOrderID(ctx, orderID, debt.ID); err != nil {
+			h.logger.Error("Error removing debt for updating its amount", "debt_id", debt.ID, "error", err)
+			continue
+		}
+		h.readModels.removeDebt(debt)
+		if amount <= 0 {
+			continue
+		}
+		// The debt is replaced with the same ID, so reactions and reminders keep referring to it
+		debt.Amount = amount
+		if err := h.debtStore.AddDebt(ctx, debt); err != nil {
+			h.logger.Error("Error adding debt with its updated amount", "debt_id", debt.ID, "error", err)
+			continue
+		}
+		h.readModels.putDebt(debt)
+		// The participants who keep their amounts private don't see the updated amount in the channel
+		if updatedRate := rateByName(updated, rate.WoltName); updatedRate != nil && updatedRate.Private {
+			h.sendDebtDM(ctx, channel, orderID, updated, *updatedRate, debt)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,331 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	"github.com/oriser/bolt/order"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconcileRatesLateJoiners(t *testing.T) {
+	t.Parallel()
+
+	store := &fakeTreasuryStore{
+		users: map[string]*userDomain.User{
+			"U1": {ID: "U1", FullName: "Thor", TransportID: "S1"},
+			"U2": {ID: "U2", FullName: "Loki", TransportID: "S2"},
+			"U3": {ID: "U3", FullName: "Odin", TransportID: "S3"},
+		},
+	}
+	notification := &editingNotification{}
+	h, err := New(Config{}, store, store, nil, "UBOT", notification)
+	require.NoError(t, err)
+	h.DisableDebtWorkers()
+
+	participants := `
+			{"first_name": "Thor", "user_id": "1", "basket": {"items": [{"name": "Pizza", "end_amount": 5000}]}},
+			{"first_name": "Loki", "user_id": "2", "basket": {"items": [{"name": "Salad", "end_amount": 3000}]}}`
+	details, err := parseWoltOrderDetails([]byte(`{"status": "pending_transaction", "host_id": "1",
+		"details": {"delivery_info": {"location": {"coordinates": {"coordinates": [34.78, 32.08]}}}},
+		"participants": [` + participants + `]}`))
+	require.NoError(t, err)
+	rates, err := details.RateByPerson()
+	require.NoError(t, err)
+	rates = h.feeAllocator.Allocate(rates, details.Host, Fees{Delivery: 10})
+	groupRate := h.buildGroupRates(rates, details.Host, 10)
+	require.NoError(t, h.addDebts(context.Background(), "C1", "A", groupRate, "1.1"))
+	require.Len(t, store.debts, 1)
+	lokiDebt := store.debts[0]
+	assert.Equal(t, 35.0, lokiDebt.Amount)
+
+	order := &groupOrder{id: "A", detailsMessageId: "2.1"}
+	ratesMessage := h.buildRatesMessage("C1", groupRate, "A")
+	assert.Equal(t, ratesMessage, h.reconcileRates("C1", order, details, &groupRate, "1.1", ratesMessage), "nobody joined late")
+	assert.Empty(t, notification.edits)
+
+	details, err = parseWoltOrderDetails([]byte(`{"status": "purchased", "host_id": "1",
+		"details": {"delivery_info": {"location": {"coordinates": {"coordinates": [34.78, 32.08]}}}},
+		"participants": [` + participants + `,
+			{"first_name": "Odin", "user_id": "3", "basket": {"items": [{"name": "Bread", "end_amount": 1000}]}}]}`))
+	require.NoError(t, err)
+	updatedMessage := h.reconcileRates("C1", order, details, &groupRate, "1.1", ratesMessage)
+
+	assert.Equal(t, "Rates for Wolt order ID A (including 10 NIS for delivery):\n"+
+		"<@S2> (Loki): 33.33\n"+
+		"<@S3> (Odin): 13.33\n"+
+		"<@S1> (Thor): 53.33\n"+
+		"\nPay to: <@S1>\n", updatedMessage)
+	assert.Equal(t, []string{"C1/2.1: " + updatedMessage}, notification.edits)
+	assert.Contains(t, notification.messages, "C1: Odin joined the order after I published the rates, so I updated them")
+	assert.Len(t, groupRate.Rates, 3)
+
+	amounts := make(map[string]float64)
+	for _, debt := range store.debts {
+		amounts[debt.BorrowerID] = debt.Amount
+	}
+	assert.InDeltaMapValues(t, map[string]float64{"U2": 33.33, "U3": 13.33}, amounts, 0.01)
+	assert.Contains(t, store.debts, &debtDomain.Debt{ID: lokiDebt.ID, BorrowerID: "U2", LenderID: "U1", OrderID: "A", Amount: lokiDebt.Amount,
+		InitiatedTransportID: "C1", MessageID: "1.1", CreatedAt: lokiDebt.CreatedAt, Currency: "ILS"}, "the debt keeps its ID")
+
+	assert.Equal(t, updatedMessage, h.reconcileRates("C1", order, details, &groupRate, "1.1", updatedMessage), "late joiners are handled once")
+	assert.Len(t, store.debts, 2)
+}
+
+func TestReconcileRatesRemovedItems(t *testing.T) {
+	t.Parallel()
+
+	store := &fakeTreasuryStore{
+		users: map[string]*userDomain.User{
+			"U1": {ID: "U1", FullName: "Thor", TransportID: "S1"},
+			"U2": {ID: "U2", FullName: "Loki", TransportID: "S2"},
+			"U3": {ID: "U3", FullName: "Odin", TransportID: "S3"},
+			"U4": {ID: "U4", FullName: "Frigg", TransportID: "S4"},
+		},
+	}
+	notification := &editingNotification{}
+	h, err := New(Config{FeeAllocationStrategy: "host-absorbs"}, store, store, nil, "UBOT", notification)
+	require.NoError(t, err)
+	h.DisableDebtWorkers()
+
+	details, err := parseWoltOrderDetails([]byte(`{"status": "pending_transaction", "host_id": "1",
+		"details": {"delivery_info": {"location": {"coordinates": {"coordinates": [34.78, 32.08]}}}},
+		"participants": [
+			{"first_name": "Thor", "user_id": "1", "basket": {"items": [{"name": "Pizza", "end_amount": 5000}]}},
+			{"first_name": "Loki", "user_id": "2", "basket": {"items": [{"name": "Salad", "end_amount": 3000}, {"name": "Soup", "end_amount": 2000}]}},
+			{"first_name": "Odin", "user_id": "3", "basket": {"items": [{"name": "Bread", "end_amount": 1000}]}},
+			{"first_name": "Frigg", "user_id": "4", "basket": {"items": [{"name": "Pasta", "end_amount": 4000}, {"name": "Cola", "end_amount": 1000}]}}
+		]}`))
+	require.NoError(t, err)
+	rates, err := details.RateByPerson()
+	require.NoError(t, err)
+	groupRate := h.buildGroupRates(rates, details.Host, 0)
+	require.NoError(t, h.addDebts(context.Background(), "C1", "A", groupRate, "1.1"))
+	require.Len(t, store.debts, 3)
+	// Frigg has already paid
+	for _, debt := range store.debts {
+		if debt.BorrowerID == "U4" {
+			require.NoError(t, store.RemoveDebtInOrderID(context.Background(), "A", debt.ID))
+		}
+	}
+
+	details, err = parseWoltOrderDetails([]byte(`{"status": "purchased", "host_id": "1",
+		"details": {"delivery_info": {"location": {"coordinates": {"coordinates": [34.78, 32.08]}}}},
+		"participants": [
+			{"first_name": "Thor", "user_id": "1", "basket": {"items": [{"name": "Pizza", "end_amount": 5000}]}},
+			{"first_name": "Loki", "user_id": "2", "basket": {"items": [{"name": "Salad", "end_amount": 3000}]}},
+			{"first_name": "Odin", "user_id": "3", "basket": {"items": []}},
+			{"first_name": "Frigg", "user_id": "4", "basket": {"items": [{"name": "Pasta", "end_amount": 4000}]}}
+		]}`))
+	require.NoError(t, err)
+	order := &groupOrder{id: "A", detailsMessageId: "2.1"}
+	updatedMessage := h.reconcileRates("C1", order, details, &groupRate, "1.1", h.buildRatesMessage("C1", groupRate, "A"))
+
+	assert.Equal(t, "Rates for Wolt order ID A (including 0 NIS for delivery):\n"+
+		"<@S4> (Frigg): 40.00\n"+
+		"<@S2> (Loki): 30.00\n"+
+		"<@S1> (Thor): 50.00\n"+
+		"\nPay to: <@S1>\n", updatedMessage)
+	assert.Equal(t, []string{"C1/2.1: " + updatedMessage}, notification.edits)
+	assert.Contains(t, notification.messages, "C1: Some items were removed from the order at checkout, so I updated the rates:\n"+
+		"<@S4> (Frigg): 50.00 → 40.00\n"+
+		"<@S2> (Loki): 50.00 → 30.00\n"+
+		"<@S3> (Odin): 10.00 → 0.00\n")
+	assert.Contains(t, notification.messages, "C1: <@S4> already paid 50.00, <@S1> please pay back the difference of 10.00")
+
+	require.Len(t, store.debts, 1, "Odin doesn't owe anything anymore")
+	assert.Equal(t, "U2", store.debts[0].BorrowerID)
+	assert.Equal(t, 30.0, store.debts[0].Amount)
+}
+
+func TestReconcileRatesKeepsAdjustments(t *testing.T) {
+	t.Parallel()
+
+	store := &fakeTreasuryStore{
+		users: map[string]*userDomain.User{
+			"U1": {ID: "U1", FullName: "Thor", TransportID: "S1"},
+			"U2": {ID: "U2", FullName: "Loki", TransportID: "S2"},
+			"U3": {ID: "U3", FullName: "Odin", TransportID: "S3"},
+			"U4": {ID: "U4", FullName: "Frigg", TransportID: "S4"},
+		},
+	}
+	notification := &editingNotification{}
+	h, err := New(Config{FeeAllocationStrategy: "host-absorbs"}, store, store, nil, "UBOT", notification)
+	require.NoError(t, err)
+	h.DisableDebtWorkers()
+
+	details, err := parseWoltOrderDetails([]byte(`{"status": "pending_transaction", "host_id": "1",
+		"details": {"delivery_info": {"location": {"coordinates": {"coordinates": [34.78, 32.08]}}}},
+		"participants": [
+			{"first_name": "Thor", "user_id": "1", "basket": {"items": [{"name": "Pizza", "end_amount": 5000}]}},
+			{"first_name": "Loki", "user_id": "2", "basket": {"items": [{"name": "Salad", "end_amount": 3000}, {"name": "Soup", "end_amount": 2000}]}},
+			{"first_name": "Odin", "user_id": "3", "basket": {"items": [{"name": "Bread", "end_amount": 1000}]}},
+			{"first_name": "Frigg", "user_id": "4", "basket": {"items": [{"name": "Pasta", "end_amount": 4000}, {"name": "Cola", "end_amount": 1000}]}}
+		]}`))
+	require.NoError(t, err)
+	rates, err := details.RateByPerson()
+	require.NoError(t, err)
+	groupRate := h.buildGroupRates(rates, details.Host, 0)
+	require.NoError(t, h.addDebts(context.Background(), "C1", "A", groupRate, "1.1"))
+	require.Len(t, store.debts, 3)
+	// The host forgave Frigg and changed Loki's debt to 45
+	amount := 45.0
+	for _, debt := range store.debts {
+		if debt.BorrowerID == "U2" {
+			debt.Amount = amount
+		}
+	}
+	for _, debt := range store.debts {
+		if debt.BorrowerID == "U4" {
+			require.NoError(t, store.RemoveDebtInOrderID(context.Background(), "A", debt.ID))
+		}
+	}
+	h.rateAdjustments.update("A", "Frigg", func(adjustment *rateAdjustment) { adjustment.forgiven = true })
+	h.rateAdjustments.update("A", "Loki", func(adjustment *rateAdjustment) { adjustment.amount = &amount })
+
+	details, err = parseWoltOrderDetails([]byte(`{"status": "purchased", "host_id": "1",
+		"details": {"delivery_info": {"location": {"coordinates": {"coordinates": [34.78, 32.08]}}}},
+		"participants": [
+			{"first_name": "Thor", "user_id": "1", "basket": {"items": [{"name": "Pizza", "end_amount": 5000}]}},
+			{"first_name": "Loki", "user_id": "2", "basket": {"items": [{"name": "Salad", "end_amount": 3000}]}},
+			{"first_name": "Odin", "user_id": "3", "basket": {"items": []}},
+			{"first_name": "Frigg", "user_id": "4", "basket": {"items": [{"name": "Pasta", "end_amount": 4000}]}}
+		]}`))
+	require.NoError(t, err)
+	order := &groupOrder{id: "A", detailsMessageId: "2.1"}
+	h.reconcileRates("C1", order, details, &groupRate, "1.1", h.buildRatesMessage("C1", groupRate, "A"))
+
+	assert.Contains(t, notification.messages, "C1: Some items were removed from the order at checkout, so I updated the rates:\n"+
+		"<@S3> (Odin): 10.00 → 0.00\n")
+	for _, message := range notification.messages {
+		assert.NotContains(t, message, "already paid", "Frigg's debt was forgiven")
+	}
+	require.Len(t, store.debts, 1, "Odin doesn't owe anything anymore")
+	assert.Equal(t, "U2", store.debts[0].BorrowerID)
+	assert.Equal(t, 45.0, store.debts[0].Amount, "the host's amount stays")
+}
+
+func TestReconcileRatesWithOverriddenFees(t *testing.T) {
+	t.Parallel()
+
+	store := &fakeTreasuryStore{
+		users: map[string]*userDomain.User{
+			"U1": {ID: "U1", FullName: "Thor", TransportID: "S1"},
+			"U2": {ID: "U2", FullName: "Loki", TransportID: "S2"},
+			"U3": {ID: "U3", FullName: "Odin", TransportID: "S3"},
+		},
+	}
+	notification := &editingNotification{}
+	h, err := New(Config{}, store, store, nil, "UBOT", notification)
+	require.NoError(t, err)
+	h.DisableDebtWorkers()
+
+	details, err := parseWoltOrderDetails([]byte(`{"status": "pending_transaction", "host_id": "1",
+		"details": {"delivery_info": {"location": {"coordinates": {"coordinates": [34.78, 32.08]}}}},
+		"participants": [
+			{"first_name": "Thor", "user_id": "1", "basket": {"items": [{"name": "Pizza", "end_amount": 5000}]}},
+			{"first_name": "Loki", "user_id": "2", "basket": {"items": [{"name": "Salad", "end_amount": 3000}, {"name": "Soup", "end_amount": 2000}]}},
+			{"first_name": "Odin", "user_id": "3", "basket": {"items": [{"name": "Bread", "end_amount": 1000}]}}
+		]}`))
+	require.NoError(t, err)
+	itemRates, err := details.RateByPerson()
+	require.NoError(t, err)
+	groupRate := h.buildGroupRates(itemRates, details.Host, 0)
+	groupRate.ItemRates = itemRates
+	// Bolt couldn't get the delivery fee, and the host set it with !delivery 30
+	fees := Fees{Delivery: 30}
+	h.rateAdjustments.setFees("A", fees, h.ratesWithFees("C1", groupRate, fees))
+	published, _ := h.rateAdjustments.apply("A", groupRate)
+	require.NoError(t, h.addDebts(context.Background(), "C1", "A", published, "1.1"))
+	require.Len(t, store.debts, 2)
+
+	details, err = parseWoltOrderDetails([]byte(`{"status": "purchased", "host_id": "1",
+		"details": {"delivery_info": {"location": {"coordinates": {"coordinates": [34.78, 32.08]}}}},
+		"participants": [
+			{"first_name": "Thor", "user_id": "1", "basket": {"items": [{"name": "Pizza", "end_amount": 5000}]}},
+			{"first_name": "Loki", "user_id": "2", "basket": {"items": [{"name": "Salad", "end_amount": 3000}]}},
+			{"first_name": "Odin", "user_id": "3", "basket": {"items": [{"name": "Bread", "end_amount": 1000}]}}
+		]}`))
+	require.NoError(t, err)
+	order := &groupOrder{id: "A", detailsMessageId: "2.1"}
+	h.reconcileRates("C1", order, details, &groupRate, "1.1", h.buildRatesMessage("C1", groupRate, "A"))
+
+	assert.Contains(t, notification.messages, "C1: Some items were removed from the order at checkout, so I updated the rates:\n"+
+		"<@S2> (Loki): 60.00 → 40.00\n", "only Loki's items changed, the host's delivery fee is still split")
+	for _, debt := range store.debts {
+		switch debt.BorrowerID {
+		case "U2":
+			assert.Equal(t, 40.0, debt.Amount)
+		case "U3":
+			assert.Equal(t, 20.0, debt.Amount)
+		}
+	}
+	shown, _ := h.rateAdjustments.apply("A", groupRate)
+	assert.Equal(t, 40.0, rateByName(shown, "Loki").Amount)
+	assert.Equal(t, 30, shown.DeliveryRate)
+}
+
+type fakeParticipantsStore struct {
+	fakeOrderStore
+}
+
+func (f *fakeParticipantsStore) UpdateOrderParticipants(_ context.Context, originalID string, participants []order.Participant) error {
+	for _, o := range f.orders {
+		if o.OriginalID == originalID {
+			o.Participants = participants
+		}
+	}
+	return nil
+}
+
+func TestReconcileRatesAmendedOrder(t *testing.T) {
+	t.Parallel()
+
+	store := &fakeTreasuryStore{
+		users: map[string]*userDomain.User{
+			"U1": {ID: "U1", FullName: "Thor", TransportID: "S1"},
+			"U2": {ID: "U2", FullName: "Loki", TransportID: "S2"},
+		},
+	}
+	orderStore := &fakeParticipantsStore{fakeOrderStore{orders: []*order.Order{{OriginalID: "A", Receiver: "C1"}}}}
+	notification := &editingNotification{}
+	h, err := New(Config{FeeAllocationStrategy: "host-absorbs"}, store, store, orderStore, "UBOT", notification)
+	require.NoError(t, err)
+	h.DisableDebtWorkers()
+
+	details, err := parseWoltOrderDetails([]byte(`{"status": "pending_transaction", "host_id": "1",
+		"details": {"delivery_info": {"location": {"coordinates": {"coordinates": [34.78, 32.08]}}}},
+		"participants": [
+			{"first_name": "Thor", "user_id": "1", "basket": {"items": [{"name": "Pizza", "end_amount": 5000}]}},
+			{"first_name": "Loki", "user_id": "2", "basket": {"items": [{"name": "Salad", "end_amount": 3000}]}}
+		]}`))
+	require.NoError(t, err)
+	rates, err := details.RateByPerson()
+	require.NoError(t, err)
+	groupRate := h.buildGroupRates(rates, details.Host, 0)
+	require.NoError(t, h.addDebts(context.Background(), "C1", "A", groupRate, "1.1"))
+	require.Len(t, store.debts, 1)
+
+	// The order was reopened before the checkout, and Loki added an item
+	details, err = parseWoltOrderDetails([]byte(`{"status": "purchased", "host_id": "1",
+		"details": {"delivery_info": {"location": {"coordinates": {"coordinates": [34.78, 32.08]}}}},
+		"participants": [
+			{"first_name": "Thor", "user_id": "1", "basket": {"items": [{"name": "Pizza", "end_amount": 5000}]}},
+			{"first_name": "Loki", "user_id": "2", "basket": {"items": [{"name": "Salad", "end_amount": 3000}, {"name": "Cola", "end_amount": 1000}]}}
+		]}`))
+	require.NoError(t, err)
+	groupOrder := &groupOrder{id: "A", detailsMessageId: "2.1"}
+	updatedMessage := h.reconcileRates("C1", groupOrder, details, &groupRate, "1.1", h.buildRatesMessage("C1", groupRate, "A"))
+
+	assert.Contains(t, updatedMessage, "<@S2> (Loki): 40.00\n")
+	assert.Equal(t, []string{"C1/2.1: " + updatedMessage}, notification.edits)
+	assert.Contains(t, notification.messages, "C1: Some amounts went up after I published the rates, so I updated them:\n"+
+		"<@S2> (Loki): 30.00 → 40.00\n")
+	require.Len(t, store.debts, 1)
+	assert.Equal(t, 40.0, store.debts[0].Amount)
+	assert.Equal(t, []order.Participant{{Name: "Loki", ID: "U2", Amount: 40}, {Name: "Thor", ID: "U1", Amount: 50}},
+		orderStore.orders[0].Participants, "the stored order has the updated rates")
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/oriser/bolt/order"
+	userDomain "github.com/oriser/bolt/user"
+)
+
+var (
+	// ErrWoltNameTaken is returned when mapping a Wolt name which already belongs to another user
+	ErrWoltNameTaken = errors.New("the Wolt name already belongs to another user")
+	// ErrNotUnknownParticipant is returned when a host links a Wolt name which wasn't an unknown participant in any of their orders
+	ErrNotUnknownParticipant = errors.New("the Wolt name isn't an unknown participant in any of your recent orders")
+)
+
+// RegisterWoltName maps the Wolt name (the FullName of the user) to the user, for users to register themselves. Registering a
+// name which is already mapped to the user does nothing.
+func (h *Service) RegisterWoltName(ctx context.Context, registered *userDomain.User) error {
+	registered.FullName = strings.TrimSpace(registered.FullName)
+	if registered.FullName == "" {
+		return fmt.Errorf("empty Wolt name")
+	}
+	mapped, err := h.woltNameMapped(ctx, registered.FullName, registered.TransportID)
+	if err != nil || mapped {
+		return err
+	}
+	return h.HandleAddTransportUser(registered)
+}
+
+// LinkUnknownParticipant maps the Wolt name of an unknown participant (the FullName of the user) to the user, for hosts (by
+// transport ID) and their co-hosts to link the participants of their recent orders which Bolt couldn't match to a user
+func (h *Service) LinkUnknownParticipant(ctx context.Context, hostTransportID string, linked *userDomain.User) error {
+	linked.FullName = strings.TrimSpace(linked.FullName)
+	if linked.FullName == "" {
+		return fmt.Errorf("empty Wolt name")
+	}
+	hosted, err := h.hostedUnknownParticipant(ctx, hostTransportID, linked.FullName)
+	if err != nil {
+		return err
+	}
+	if !hosted {
+		return ErrNotUnknownParticipant
+	}
+
+	mapped, err := h.woltNameMapped(ctx, linked.FullName, linked.TransportID)
+	if err != nil || mapped {
+		return err
+	}
+	return h.HandleAddTransportUser(linked)
+}
+
+// woltNameMapped returns whether the Wolt name is already mapped to the user, or ErrWoltNameTaken if it's mapped to another user
+func (h *Service) woltNameMapped(ctx context.Context, woltName, transportID string) (bool, error) {
+	users, err := h.userStore.ListUsers(ctx, userDomain.ListFilter{Names: []string{woltName}})
+	if err != nil {
+		return false, fmt.Errorf("list users: %w", err)
+	}
+	mapped := false
+	for _, u := range users {
+		if u.FullName != woltName {
+			// Transport users may be matched by a similar name
+			continue
+		}
+		if u.TransportID != transportID {
+			return false, ErrWoltNameTaken
+		}
+		mapped = true
+	}
+	return mapped, nil
+}
+
+// hostedUnknownParticipant returns whether the Wolt name was an unknown participant in an order hosted by the user or by a host they
+// co-host, within DEBT_MAXIMUM_DURATION
+func (h *Service) hostedUnknownParticipant(ctx context.Context, hostTransportID, woltName string) (bool, error) {
+	if h.orderStore == nil {
+		return false, nil
+	}
+	hostNames := make(map[string]bool)
+	for _, host := range h.hostsActedFor(hostTransportID) {
+		hostUsers, err := h.userStore.ListUsers(ctx, userDomain.ListFilter{TransportID: host})
+		if err != nil {
+			return false, fmt.Errorf("list host users: %w", err)
+		}
+		for _, u := range hostUsers {
+			hostNames[u.FullName] = true
+		}
+	}
+
+	orders, err := h.orderStore.ListOrders(ctx, order.ListFilter{Participant: woltName})
+	if err != nil {
+		return false, fmt.Errorf("list orders: %w", err)
+	}
+	for _, o := range orders {
+		if !hostNames[o.Host] || time.Since(o.CreatedAt) > h.cfg.DebtMaximumDuration {
+			continue
+		}
+		for _, p := range o.Participants {
+			if p.Name == woltName && p.ID == "" {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oriser/bolt/order"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterWoltName(t *testing.T) {
+	t.Parallel()
+
+	store := &fakeTreasuryStore{users: map[string]*userDomain.User{
+		"U1": {ID: "U1", FullName: "Loki", TransportID: "U1"},
+	}}
+	h, err := New(Config{}, store, nil, nil, "U-bot", &recordingNotification{})
+	require.NoError(t, err)
+
+	require.NoError(t, h.RegisterWoltName(context.Background(), &userDomain.User{ID: "uuid-2", FullName: " Thor ", TransportID: "U2"}))
+	assert.Equal(t, "Thor", store.users["uuid-2"].FullName)
+	assert.Equal(t, "U2", store.users["uuid-2"].TransportID)
+
+	// Registering again is a no-op
+	require.NoError(t, h.RegisterWoltName(context.Background(), &userDomain.User{ID: "uuid-3", FullName: "Thor", TransportID: "U2"}))
+	assert.NotContains(t, store.users, "uuid-3")
+
+	err = h.RegisterWoltName(context.Background(), &userDomain.User{ID: "uuid-4", FullName: "Loki", TransportID: "U2"})
+	assert.ErrorIs(t, err, ErrWoltNameTaken)
+	assert.NotContains(t, store.users, "uuid-4")
+
+	assert.ErrorContains(t, h.RegisterWoltName(context.Background(), &userDomain.User{FullName: " ", TransportID: "U2"}), "empty Wolt name")
+}
+
+func TestLinkUnknownParticipant(t *testing.T) {
+	t.Parallel()
+
+	notification := &recordingNotification{}
+	store := &fakeTreasuryStore{users: map[string]*userDomain.User{
+		"U-host": {ID: "U-host", FullName: "Thor", TransportID: "U-host"},
+		"U1":     {ID: "U1", FullName: "Loki", TransportID: "U1"},
+		"U2":     {ID: "U2", FullName: "Odin", TransportID: "U2"},
+	}}
+	orderStore := &fakeOrderStore{orders: []*order.Order{
+		{OriginalID: "A", Host: "Thor", CreatedAt: time.Now(), Participants: []order.Participant{
+			{Name: "Thor", ID: "U-host"}, {Name: "Loki", ID: "U1"}, {Name: "Frigg"},
+		}},
+		{OriginalID: "B", Host: "Odin", CreatedAt: time.Now(), Participants: []order.Participant{{Name: "Odin", ID: "U2"}, {Name: "Baldr"}}},
+		{OriginalID: "C", Host: "Thor", CreatedAt: time.Now().Add(-48 * time.Hour), Participants: []order.Participant{{Name: "Heimdall"}}},
+	}}
+	h, err := New(Config{DebtMaximumDuration: 24 * time.Hour}, store, store, orderStore, "U-bot", notification)
+	require.NoError(t, err)
+
+	require.NoError(t, h.LinkUnknownParticipant(context.Background(), "U-host", &userDomain.User{ID: "uuid-1", FullName: "Frigg", TransportID: "U3"}))
+	assert.Equal(t, "Frigg", store.users["uuid-1"].FullName)
+
+	for name, participant := range map[string]string{
+		"known participant":              "Loki",
+		"participant of another host":    "Baldr",
+		"participant of an old order":    "Heimdall",
+		"not a participant of any order": "Freyr",
+	} {
+		err := h.LinkUnknownParticipant(context.Background(), "U-host", &userDomain.User{FullName: participant, TransportID: "U4"})
+		assert.ErrorIs(t, err, ErrNotUnknownParticipant, name)
+	}
+
+	// Linking a name which was since registered by someone else
+	store.users["uuid-5"] = &userDomain.User{ID: "uuid-5", FullName: "Baldr", TransportID: "U5"}
+	err = h.LinkUnknownParticipant(context.Background(), "U2", &userDomain.User{FullName: "Baldr", TransportID: "U4"})
+	assert.ErrorIs(t, err, ErrWoltNameTaken)
+}
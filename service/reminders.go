@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	debtDomain "github.com/oriser/bolt/debt"
+)
+
+func (h *Service) reminderStore() (debtDomain.ReminderStore, error) {
+	reminderStore, ok := h.debtStore.(debtDomain.ReminderStore)
+	if !ok {
+		return nil, fmt.Errorf("opting out of reminders is not supported")
+	}
+	return reminderStore, nil
+}
+
+// SetRemindersOptOut opts the user (by transport ID) out of the debts reminders, or back in
+func (h *Service) SetRemindersOptOut(ctx context.Context, transportID string, optOut bool) error {
+	reminderStore, err := h.reminderStore()
+	if err != nil {
+		return err
+	}
+	if err := reminderStore.SetRemindersOptOut(ctx, transportID, optOut); err != nil {
+		return fmt.Errorf("set reminders opt out: %w", err)
+	}
+	return nil
+}
+
+func (h *Service) remindersOptedOut(ctx context.Context, transportID string) bool {
+	reminderStore, err := h.reminderStore()
+	if err != nil {
+		return false
+	}
+	optedOut, err := reminderStore.RemindersOptedOut(ctx, transportID)
+	if err != nil {
+		h.logger.Error("Error checking if the user opted out of reminders", "transport_id", transportID, "error", err)
+		return false
+	}
+	return optedOut
+}
+
+// remindDebts reminds the borrowers about their debts. With DEBT_REMINDER_NOTIFY_HOST, it also tells each host who was reminded
+// to pay them.
+func (h *Service) remindDebts(ctx context.Context, debts []*debtDomain.Debt) {
+	type hostOrder struct {
+		lenderID string
+		orderID  string
+	}
+	hostOrders := make([]hostOrder, 0)
+	reminded := make(map[hostOrder][]string)
+	for _, debt := range debts {
+		borrower, err := h.remindDebt(debt)
+		if errors.Is(err, errReminderDeferred) {
+			continue
+		}
+		if err != nil {
+			h.logger.Error("Error reminding about debt", "debt_id", debt.ID, "group_id", debt.OrderID, "error", err)
+			continue
+		}
+		if borrower == nil {
+			continue
+		}
+		key := hostOrder{lenderID: debt.LenderID, orderID: debt.OrderID}
+		if _, ok := reminded[key]; !ok {
+			hostOrders = append(hostOrders, key)
+		}
+		reminded[key] = append(reminded[key], fmt.Sprintf("<@%s>", borrower.TransportID))
+	}
+	if !h.cfg.DebtReminderNotifyHost {
+		return
+	}
+
+	for _, key := range hostOrders {
+		host := key.lenderID
+		if lender, err := h.getUser(key.lenderID); err == nil {
+			host = lender.TransportID
+		}
+		message := fmt.Sprintf("I reminded %s to pay you for Wolt order ID %s", strings.Join(reminded[key], ", "), key.orderID)
+		if err := h.notifyUser(ctx, host, "reminded/"+key.orderID, message, ""); err != nil {
+			h.logger.Error("Error telling host about reminders", "transport_id", host, "error", err)
+		}
+	}
+}
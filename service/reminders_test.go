@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeReminderStore struct {
+	fakeTreasuryStore
+	optOuts map[string]bool
+}
+
+func (f *fakeReminderStore) SetRemindersOptOut(_ context.Context, transportID string, optOut bool) error {
+	f.optOuts[transportID] = optOut
+	return nil
+}
+
+func (f *fakeReminderStore) RemindersOptedOut(_ context.Context, transportID string) (bool, error) {
+	return f.optOuts[transportID], nil
+}
+
+// daytimeTimezone returns a timezone in which it's currently noon, so reminders aren't held back for the night
+func daytimeTimezone() string {
+	offset := 12 - time.Now().UTC().Hour()
+	switch {
+	case offset > 0:
+		return fmt.Sprintf("Etc/GMT-%d", offset)
+	case offset < 0:
+		return fmt.Sprintf("Etc/GMT+%d", -offset)
+	default:
+		return "Etc/GMT"
+	}
+}
+
+func TestRemindDebts(t *testing.T) {
+	t.Parallel()
+
+	tz := daytimeTimezone()
+	notification := &recordingNotification{}
+	store := &fakeReminderStore{
+		fakeTreasuryStore: fakeTreasuryStore{users: map[string]*userDomain.User{
+			"uuid-host": {ID: "uuid-host", FullName: "Thor", TransportID: "U-host", Timezone: tz},
+			"U1":        {ID: "U1", FullName: "Loki", TransportID: "U1", Timezone: tz},
+			"U2":        {ID: "U2", FullName: "Odin", TransportID: "U2", Timezone: tz},
+			"U3":        {ID: "U3", FullName: "Frigg", TransportID: "U3", Timezone: tz},
+		}},
+		optOuts: make(map[string]bool),
+	}
+	h, err := New(Config{DebtReminderNotifyHost: true}, store, store, nil, "U-bot", notification)
+	require.NoError(t, err)
+
+	require.NoError(t, h.SetRemindersOptOut(context.Background(), "U3", true))
+	debts := []*debtDomain.Debt{
+		{ID: "d1", BorrowerID: "U1", LenderID: "uuid-host", OrderID: "A", Amount: 30, CreatedAt: time.Now()},
+		{ID: "d2", BorrowerID: "U2", LenderID: "uuid-host", OrderID: "A", Amount: 20, CreatedAt: time.Now()},
+		{ID: "d3", BorrowerID: "U3", LenderID: "uuid-host", OrderID: "A", Amount: 10, CreatedAt: time.Now()},
+	}
+	h.remindDebts(context.Background(), debts)
+	require.Len(t, notification.messages, 3)
+	assert.True(t, strings.HasPrefix(notification.messages[0], "U1: Reminder, you should pay 30.00 nis"))
+	assert.True(t, strings.HasPrefix(notification.messages[1], "U2: Reminder, you should pay 20.00 nis"))
+	assert.Equal(t, "U-host: I reminded <@U1>, <@U2> to pay you for Wolt order ID A", notification.messages[2],
+		"users who opted out shouldn't be reminded")
+
+	require.NoError(t, h.SetRemindersOptOut(context.Background(), "U3", false))
+	h.cfg.DebtReminderNotifyHost = false
+	h.remindDebts(context.Background(), debts[2:])
+	require.Len(t, notification.messages, 4)
+	assert.True(t, strings.HasPrefix(notification.messages[3], "U3: Reminder, you should pay 10.00 nis"))
+}
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/oriser/bolt/order"
+	userDomain "github.com/oriser/bolt/user"
+)
+
+const reportTopSpenders = 5
+
+// PersonTotal is what a person spent on, or how many orders they hosted in, a month. People who aren't known users are counted by
+// their Wolt name.
+type PersonTotal struct {
+	Name   string           // The Wolt name
+	User   *userDomain.User // nil if the person isn't a known user
+	Total  float64
+	Orders int
+}
+
+// VenueTotal is what was spent on a venue in a month
+type VenueTotal struct {
+	VenueName string
+	Total     float64
+	Orders    int
+}
+
+// MonthlyReport summarizes the delivered orders of a channel in a month
+type MonthlyReport struct {
+	Month       time.Time // The start of the month, in the channel's timezone
+	Orders      int
+	Total       float64
+	TopSpenders []PersonTotal // The reportTopSpenders people who spent the most
+	Venues      []VenueTotal  // From the highest total
+	Hosts       []PersonTotal // From the most orders hosted, without totals
+	Currency    string        // The ISO 4217 code of the currency of the totals
+}
+
+// monthTotals are the totals of the orders delivered to a channel in a month, kept by the read models of the channel
+type monthTotals struct {
+	orders   int
+	total    float64
+	currency string // The currency of the orders, empty if none was stored
+	mixed    bool   // Whether the orders are in different currencies
+	spenders map[string]*PersonTotal
+	hosts    map[string]*PersonTotal
+	venues   map[string]*VenueTotal
+}
+
+func newMonthTotals() *monthTotals {
+	return &monthTotals{spenders: make(map[string]*PersonTotal), hosts: make(map[string]*PersonTotal), venues: make(map[string]*VenueTotal)}
+}
+
+// personKey returns the key of the participant in the totals. Known users are counted by their ID, as they may have several Wolt
+// names.
+func personKey(p order.Participant) string {
+	if p.ID == "" {
+		return "name:" + p.Name
+	}
+	return p.ID
+}
+
+// add counts the delivered order in the totals
+func (m *monthTotals) add(o *order.Order) {
+	m.orders++
+	m.total += o.TotalAmount()
+	switch {
+	case o.Currency == "" || m.mixed:
+	case m.currency == "":
+		m.currency = o.Currency
+	case m.currency != o.Currency:
+		m.currency, m.mixed = "", true
+	}
+
+	if _, ok := m.venues[o.VenueName]; !ok {
+		m.venues[o.VenueName] = &VenueTotal{VenueName: o.VenueName}
+	}
+	m.venues[o.VenueName].Total += o.TotalAmount()
+	m.venues[o.VenueName].Orders++
+
+	personOf := func(people map[string]*PersonTotal, p order.Participant) *PersonTotal {
+		key := personKey(p)
+		if _, ok := people[key]; !ok {
+			people[key] = &PersonTotal{Name: p.Name}
+		}
+		return people[key]
+	}
+	for _, p := range o.Participants {
+		spender := personOf(m.spenders, p)
+		spender.Total += p.Amount
+		spender.Orders++
+		if p.Name == o.Host {
+			personOf(m.hosts, p).Orders++
+		}
+	}
+}
+
+// MonthlyReport returns the summary of the orders delivered to the channel in the month, in the channel's timezone
+func (h *Service) MonthlyReport(ctx context.Context, channel string, year int, month time.Month) (*MonthlyReport, error) {
+	if h.orderStore == nil {
+		return nil, fmt.Errorf("no order store")
+	}
+	from := time.Date(year, month, 1, 0, 0, 0, 0, h.timezoneForChannel(channel, nil))
+	report := &MonthlyReport{Month: from}
+	var spenders, hosts map[string]*PersonTotal
+	err := h.readChannelMonths(ctx, channel, from.Location(), func(months map[string]*monthTotals) {
+		totals, ok := months[monthKey(from)]
+		if !ok {
+			totals = newMonthTotals()
+		}
+		report.Orders = totals.orders
+		report.Total = totals.total
+		report.Currency = totals.currency
+		spenders, hosts = make(map[string]*PersonTotal, len(totals.spenders)), make(map[string]*PersonTotal, len(totals.hosts))
+		for key, spender := range totals.spenders {
+			spenders[key] = &PersonTotal{Name: spender.Name, Total: spender.Total, Orders: spender.Orders}
+		}
+		for key, host := range totals.hosts {
+			hosts[key] = &PersonTotal{Name: host.Name, Orders: host.Orders}
+		}
+		for _, venue := range totals.venues {
+			report.Venues = append(report.Venues, *venue)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	report.Currency = h.currencyOrDefault(report.Currency)
+
+	// The users are looked up outside the read models, which are locked while they're read
+	userOf := func(key string) *userDomain.User {
+		if strings.HasPrefix(key, "name:") {
+			return nil
+		}
+		return h.reportUser(ctx, key)
+	}
+	for key, spender := range spenders {
+		spender.User = userOf(key)
+		report.TopSpenders = append(report.TopSpenders, *spender)
+	}
+	sort.Slice(report.TopSpenders, func(i, j int) bool {
+		if report.TopSpenders[i].Total != report.TopSpenders[j].Total {
+			return report.TopSpenders[i].Total > report.TopSpenders[j].Total
+		}
+		return report.TopSpenders[i].Name < report.TopSpenders[j].Name
+	})
+	if len(report.TopSpenders) > reportTopSpenders {
+		report.TopSpenders = report.TopSpenders[:reportTopSpenders]
+	}
+
+	sort.Slice(report.Venues, func(i, j int) bool {
+		if report.Venues[i].Total != report.Venues[j].Total {
+			return report.Venues[i].Total > report.Venues[j].Total
+		}
+		return report.Venues[i].VenueName < report.Venues[j].VenueName
+	})
+
+	for key, host := range hosts {
+		host.User = userOf(key)
+		report.Hosts = append(report.Hosts, *host)
+	}
+	sort.Slice(report.Hosts, func(i, j int) bool {
+		if report.Hosts[i].Orders != report.Hosts[j].Orders {
+			return report.Hosts[i].Orders > report.Hosts[j].Orders
+		}
+		return report.Hosts[i].Name < report.Hosts[j].Name
+	})
+	return report, nil
+}
+
+// reportUser returns the user with the ID, or nil if the ID is empty or the user can't be found
+func (h *Service) reportUser(ctx context.Context, id string) *userDomain.User {
+	if id == "" || h.userStore == nil {
+		return nil
+	}
+	u, err := h.userStore.GetUser(ctx, id)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "Error getting user of the report", "user_id", id, "error", err)
+		return nil
+	}
+	return u
+}
+
+// BuildMonthlyReportMessage returns the message of the monthly report
+func BuildMonthlyReportMessage(report *MonthlyReport) string {
+	month := report.Month.Format("January 2006")
+	if report.Orders == 0 {
+		return fmt.Sprintf("No orders were delivered in %s", month)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(":bar_chart: The orders of %s: %d orders, %s in total\n", month, report.Orders, FormatAmount(report.Total, report.Currency)))
+	sb.WriteString("*Top spenders:*\n")
+	for i, spender := range report.TopSpenders {
+		sb.WriteString(fmt.Sprintf("%d. %s - %s (%d meals)\n", i+1, balanceUserMention(spender.User, spender.Name),
+			FormatAmount(spender.Total, report.Currency), spender.Orders))
+	}
+	sb.WriteString("*Total per venue:*\n")
+	for _, venue := range report.Venues {
+		sb.WriteString(fmt.Sprintf("• [%s] - %s (%d orders)\n", venue.VenueName, FormatAmount(venue.Total, report.Currency), venue.Orders))
+	}
+	if len(report.Hosts) > 0 {
+		sb.WriteString("*Orders hosted:*\n")
+		for _, host := range report.Hosts {
+			sb.WriteString(fmt.Sprintf("• %s - %d\n", balanceUserMention(host.User, host.Name), host.Orders))
+		}
+	}
+	return sb.String()
+}
+
+func (h *Service) postMonthlyReport(ctx context.Context, channel string, month time.Time) {
+	report, err := h.MonthlyReport(ctx, channel, month.Year(), month.Month())
+	if err != nil {
+		h.logger.ErrorContext(ctx, "Error getting the monthly report", "channel", channel, "error", err)
+		return
+	}
+	if report.Orders == 0 {
+		return
+	}
+	if err := h.informNonUrgent(channel, BuildMonthlyReportMessage(report), ""); err != nil {
+		h.logger.ErrorContext(ctx, "Error posting the monthly report", "channel", channel, "error", err)
+	}
+}
+
+// RunMonthlyReports posts the report of the previous month in each of the MONTHLY_REPORT_CHANNELS, on the first day of every month
+// at MONTHLY_REPORT_HOUR, until the context is done
+func (h *Service) RunMonthlyReports(ctx context.Context) {
+	if len(h.cfg.MonthlyReportChannels) == 0 || h.orderStore == nil {
+		return
+	}
+
+	ticker := time.NewTicker(badgesSchedulerInterval)
+	defer ticker.Stop()
+
+	h.schedulers.beat("monthly reports", badgesSchedulerInterval)
+	lastCheck := time.Now()
+	for {
+		select {
+		case now := <-ticker.C:
+			for _, channel := range h.cfg.MonthlyReportChannels {
+				tz := h.timezoneForChannel(channel, nil)
+				postAt := monthStart(now.In(tz)).Add(time.Duration(h.cfg.MonthlyReportHour) * time.Hour)
+				if postAt.After(lastCheck) && !postAt.After(now) {
+					h.postMonthlyReport(ctx, channel, monthStart(postAt.AddDate(0, -1, 0)))
+				}
+			}
+			lastCheck = now
+			h.schedulers.beat("monthly reports", badgesSchedulerInterval)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oriser/bolt/order"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMonthlyReport(t *testing.T) {
+	t.Parallel()
+
+	may := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	users := &fakeTreasuryStore{users: map[string]*userDomain.User{
+		"uuid-thor": {ID: "uuid-thor", FullName: "Thor", TransportID: "U-thor"},
+		"uuid-loki": {ID: "uuid-loki", FullName: "Loki", TransportID: "U-loki"},
+	}}
+	orderStore := &fakeOrderStore{orders: []*order.Order{
+		{Receiver: "C1", VenueName: "Pizza", Host: "Thor", Status: order.StatusDone, CreatedAt: may, Participants: []order.Participant{
+			{Name: "Thor", ID: "uuid-thor", Amount: 40}, {Name: "Loki", ID: "uuid-loki", Amount: 30}, {Name: "Odin", Amount: 20},
+		}},
+		{Receiver: "C1", VenueName: "Sushi", Host: "Loki", Status: order.StatusDone, CreatedAt: may.AddDate(0, 0, 5), Participants: []order.Participant{
+			{Name: "Loki", ID: "uuid-loki", Amount: 50}, {Name: "Odin", Amount: 25},
+		}},
+		{Receiver: "C1", VenueName: "Pizza", Host: "Thor", Status: order.StatusDone, CreatedAt: may.AddDate(0, 0, 6), Participants: []order.Participant{
+			{Name: "Thor", ID: "uuid-thor", Amount: 10},
+		}},
+		{Receiver: "C1", VenueName: "Canceled", Host: "Thor", Status: order.StatusCanceled, CreatedAt: may, Participants: []order.Participant{
+			{Name: "Thor", ID: "uuid-thor", Amount: 500},
+		}},
+		{Receiver: "C1", VenueName: "Pizza", Host: "Thor", Status: order.StatusDone, CreatedAt: may.AddDate(0, 1, 0), Participants: []order.Participant{
+			{Name: "Thor", ID: "uuid-thor", Amount: 500},
+		}},
+		{Receiver: "C2", VenueName: "Burger", Host: "Thor", Status: order.StatusDone, CreatedAt: may, Participants: []order.Participant{
+			{Name: "Thor", ID: "uuid-thor", Amount: 500},
+		}},
+	}}
+	h, err := New(Config{}, users, nil, orderStore, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+
+	report, err := h.MonthlyReport(context.Background(), "C1", 2024, time.May)
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Orders)
+	assert.Equal(t, 175.0, report.Total)
+	require.Len(t, report.TopSpenders, 3)
+	assert.Equal(t, "Loki", report.TopSpenders[0].Name)
+	assert.Equal(t, 80.0, report.TopSpenders[0].Total)
+	assert.Equal(t, 2, report.TopSpenders[0].Orders)
+	assert.Equal(t, 50.0, report.TopSpenders[1].Total)
+	assert.Equal(t, PersonTotal{Name: "Odin", Total: 45, Orders: 2}, report.TopSpenders[2])
+	assert.Equal(t, []VenueTotal{{VenueName: "Pizza", Total: 100, Orders: 2}, {VenueName: "Sushi", Total: 75, Orders: 1}}, report.Venues)
+	require.Len(t, report.Hosts, 2)
+	assert.Equal(t, "Thor", report.Hosts[0].Name)
+	assert.Equal(t, 2, report.Hosts[0].Orders)
+
+	message := BuildMonthlyReportMessage(report)
+	assert.Contains(t, message, ":bar_chart: The orders of May 2024: 3 orders, 175.00 nis in total\n")
+	assert.Contains(t, message, "1. <@U-loki> - 80.00 nis (2 meals)\n")
+	assert.Contains(t, message, "3. Odin - 45.00 nis (2 meals)\n")
+	assert.Contains(t, message, "• [Pizza] - 100.00 nis (2 orders)\n")
+	assert.Contains(t, message, "• <@U-thor> - 2\n")
+
+	report, err = h.MonthlyReport(context.Background(), "C1", 2024, time.April)
+	require.NoError(t, err)
+	assert.Equal(t, "No orders were delivered in April 2024", BuildMonthlyReportMessage(report))
+}
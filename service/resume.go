@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/oriser/bolt/logging"
+	"github.com/oriser/bolt/order"
+	"github.com/oriser/bolt/tracing"
+)
+
+func (h *Service) trackingStore() order.TrackingStore {
+	store, _ := h.orderStore.(order.TrackingStore)
+	return store
+}
+
+// saveTracking persists the state of the order, so its tracking is resumed if the service restarts from the state it got to.
+// ratesMessageID is the rates message once the rates are published, or empty while waiting for the group to be sent.
+func (h *Service) saveTracking(g *groupOrder, ratesMessageID string) {
+	store := h.trackingStore()
+	if store == nil || g.group == nil {
+		return
+	}
+	session, err := json.Marshal(g.group.Session())
+	if err != nil {
+		h.logger.ErrorContext(g.ctx, "Error marshaling the provider session", "error", err)
+		return
+	}
+	phase := order.PhaseJoined
+	if ratesMessageID != "" {
+		phase = order.PhaseDelivery
+	}
+	state, deliveryState := g.trackingState()
+	tracked := &order.TrackedOrder{
+		GroupID:         g.id,
+		Provider:        g.provider.Name(),
+		Channel:         g.channel,
+		MessageID:       g.messageID,
+		Text:            g.text,
+		Phase:           phase,
+		State:           string(state),
+		JoinedMessageID: g.joinedMessageID,
+		RatesMessageID:  ratesMessageID,
+		CompanyPaid:     g.isCompanyPaid(),
+		Session:         string(session),
+		StartedAt:       g.startedAt,
+	}
+	if deliveryState != DeliveryStateUnknown {
+		tracked.DeliveryState = deliveryState.String()
+	}
+	ctx, cancel := h.storeContext()
+	defer cancel()
+	ctx, span := tracing.Start(logging.CopyAttrs(tracing.ContextWithSpan(ctx, tracing.SpanFromContext(g.ctx)), g.ctx), "store.save_tracking",
+		tracing.String("group_id", g.id), tracing.String("phase", string(phase)))
+	defer span.End()
+	if err := store.SaveTrackedOrder(ctx, tracked); err != nil {
+		span.SetError(err)
+		h.logger.ErrorContext(ctx, "Error saving the tracking state", "error", err)
+	}
+}
+
+// forgetTracking removes the persisted state of an order whose tracking is over
+func (h *Service) forgetTracking(groupID string) {
+	store := h.trackingStore()
+	if store == nil {
+		return
+	}
+	ctx, cancel := h.storeContext()
+	defer cancel()
+	if err := store.RemoveTrackedOrder(ctx, groupID); err != nil {
+		h.logger.Error("Error removing the tracking state", "group_id", groupID, "error", err)
+	}
+}
+
+// restoreTrackedOrder returns the group order of an order whose tracking was interrupted by a restart, with its persisted state
+func (h *Service) restoreTrackedOrder(tracked *order.TrackedOrder) (*groupOrder, error) {
+	provider, err := h.providerByName(tracked.Provider)
+	if err != nil {
+		return nil, err
+	}
+	restored, err := h.restoreGroupOrder(provider, tracked.GroupID, json.RawMessage(tracked.Session))
+	if err != nil {
+		return nil, err
+	}
+	restored.joinedMessageID = tracked.JoinedMessageID
+	restored.detailsMessageId = tracked.RatesMessageID
+	if tracked.CompanyPaid {
+		restored.markCompanyPaid()
+	}
+	return restored, nil
+}
+
+// ResumeOrders resumes tracking the orders which were tracked when the service stopped, as if their links were just shared (without
+// announcing them again). Orders tracked for longer than WORKING_ORDER_TTL (extended by their ⏳x<N> directive) are considered abandoned
+// and aren't resumed.
+// It returns how many orders are resumed.
+func (h *Service) ResumeOrders(ctx context.Context) (int, error) {
+	store := h.trackingStore()
+	if store == nil {
+		return 0, nil
+	}
+	trackedOrders, err := store.ListTrackedOrders(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list tracked orders: %w", err)
+	}
+
+	resumed := 0
+	for _, tracked := range trackedOrders {
+		ttl := scaledTTL(h.cfg.WorkingOrderTTL, parseTimeoutMultiplier(tracked.Text))
+		if ttl > 0 && time.Since(tracked.StartedAt) >= ttl {
+			h.logger.InfoContext(ctx, "Not resuming order, which has been tracked for longer than WORKING_ORDER_TTL", "group_id", tracked.GroupID,
+				"started_at", tracked.StartedAt.Format(time.RFC3339))
+			h.forgetTracking(tracked.GroupID)
+			continue
+		}
+		h.logger.InfoContext(ctx, "Resuming tracking order", "group_id", tracked.GroupID, "phase", tracked.Phase)
+		resumed++
+		go func(tracked *order.TrackedOrder) {
+			req := LinksRequest{MessageID: tracked.MessageID, Channel: tracked.Channel, Text: tracked.Text}
+			if _, err := h.trackOrder(req, groupLink{groupID: tracked.GroupID}, tracked, nil); err != nil {
+				h.logger.ErrorContext(ctx, "Error resuming order", "group_id", tracked.GroupID, "error", err)
+			}
+		}(tracked)
+	}
+	return resumed, nil
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/oriser/bolt/order"
+	"github.com/oriser/bolt/wolt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTrackingStore struct {
+	fakeOrderStore
+	tracked map[string]*order.TrackedOrder
+}
+
+func (f *fakeTrackingStore) SaveTrackedOrder(_ context.Context, tracked *order.TrackedOrder) error {
+	f.tracked[tracked.GroupID] = tracked
+	return nil
+}
+
+func (f *fakeTrackingStore) RemoveTrackedOrder(_ context.Context, groupID string) error {
+	delete(f.tracked, groupID)
+	return nil
+}
+
+func (f *fakeTrackingStore) ListTrackedOrders(context.Context) ([]*order.TrackedOrder, error) {
+	tracked := make([]*order.TrackedOrder, 0, len(f.tracked))
+	for _, t := range f.tracked {
+		tracked = append(tracked, t)
+	}
+	return tracked, nil
+}
+
+func TestSaveAndRestoreTracking(t *testing.T) {
+	t.Parallel()
+
+	store := &fakeTrackingStore{tracked: make(map[string]*order.TrackedOrder)}
+	h, err := New(Config{WoltApiBaseAddr: "https://restaurant-api.wolt.com"}, nil, nil, store, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+
+	woltProvider, err := h.providerByName(providerWolt)
+	require.NoError(t, err)
+	tracked, err := h.restoreGroupOrder(woltProvider, "ABC", json.RawMessage(`{"id": "real-abc", "cookies": [{"Name": "session", "Value": "guest"}]}`))
+	require.NoError(t, err)
+	startedAt := time.Now().Add(-time.Hour)
+	tracked.channel, tracked.messageID, tracked.text, tracked.joinedMessageID, tracked.startedAt = "C1", "1.1", "lunch #rnd", "1.2", startedAt
+	tracked.markCompanyPaid()
+
+	h.saveTracking(tracked, "")
+	assert.Equal(t, order.PhaseJoined, store.tracked["ABC"].Phase)
+	h.saveTracking(tracked, "1.3")
+	saved := store.tracked["ABC"]
+	assert.Equal(t, order.PhaseDelivery, saved.Phase)
+	assert.Equal(t, "1.3", saved.RatesMessageID)
+	assert.Equal(t, providerWolt, saved.Provider)
+	assert.True(t, saved.CompanyPaid)
+	assert.Equal(t, startedAt, saved.StartedAt)
+
+	session := wolt.GroupSession{}
+	require.NoError(t, json.Unmarshal([]byte(saved.Session), &session))
+	assert.Equal(t, "real-abc", session.ID)
+	require.Len(t, session.Cookies, 1)
+	assert.Equal(t, "guest", session.Cookies[0].Value)
+
+	restored, err := h.restoreTrackedOrder(saved)
+	require.NoError(t, err)
+	assert.Equal(t, "1.2", restored.joinedMessageID)
+	assert.Equal(t, "1.3", restored.detailsMessageId)
+	assert.True(t, restored.isCompanyPaid())
+	assert.Equal(t, "real-abc", restored.group.Session().(wolt.GroupSession).ID)
+
+	h.forgetTracking("ABC")
+	assert.Empty(t, store.tracked)
+}
+
+func TestResumeOrdersSkipsAbandoned(t *testing.T) {
+	t.Parallel()
+
+	store := &fakeTrackingStore{tracked: map[string]*order.TrackedOrder{
+		"A": {GroupID: "A", Channel: "C1", MessageID: "1.1", Phase: order.PhaseJoined, StartedAt: time.Now().Add(-7 * time.Hour)},
+	}}
+	h, err := New(Config{WorkingOrderTTL: 6 * time.Hour}, nil, nil, store, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+
+	resumed, err := h.ResumeOrders(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, resumed)
+	assert.Empty(t, store.tracked, "abandoned orders are forgotten")
+
+	resumed, err = (&Service{logger: slog.Default(), orderStore: &fakeOrderStore{}}).ResumeOrders(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, resumed, "order stores without tracking support have nothing to resume")
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"fmt"
+	"math"
+)
+
+// RoundingRemainder is who pays the difference between the rounded amounts and the order's total
+type RoundingRemainder string
+
+const (
+	RoundingRemainderHost    RoundingRemainder = "host"    // The host covers the difference, or keeps it
+	RoundingRemainderLargest RoundingRemainder = "largest" // The participant with the largest amount covers the difference
+)
+
+func parseRoundingRemainder(value string) (RoundingRemainder, error) {
+	switch remainder := RoundingRemainder(value); remainder {
+	case "":
+		return RoundingRemainderHost, nil
+	case RoundingRemainderHost, RoundingRemainderLargest:
+		return remainder, nil
+	default:
+		return "", fmt.Errorf("unknown rounding remainder %q (available: %s, %s)", value, RoundingRemainderHost, RoundingRemainderLargest)
+	}
+}
+
+// roundRates rounds the amount of each participant to the nearest AMOUNT_ROUNDING, except for the participant of
+// ROUNDING_REMAINDER who pays the difference, so the amounts still add up to the order's total
+func (h *Service) roundRates(rates map[string]float64, host string) map[string]float64 {
+	step := h.cfg.AmountRounding
+	if step <= 0 || len(rates) == 0 {
+		return rates
+	}
+	absorber := host
+	if h.roundingRemainder == RoundingRemainderLargest {
+		absorber = largestRate(rates)
+	}
+
+	res := copyRates(rates)
+	total, rounded := 0.0, 0.0
+	for person, amount := range rates {
+		total += amount
+		if person == absorber {
+			continue
+		}
+		res[person] = math.Round(amount/step) * step
+		rounded += res[person]
+	}
+	res[absorber] = math.Round((total-rounded)*100) / 100
+	return res
+}
+
+// largestRate returns the participant with the largest amount, the first by name if several have it
+func largestRate(rates map[string]float64) string {
+	largest := ""
+	for _, person := range getSortedKeys(rates) {
+		if largest == "" || rates[person] > rates[largest] {
+			largest = person
+		}
+	}
+	return largest
+}
+
+// roundingMessage returns the line of the rates message which tells how the amounts were rounded, empty if they weren't
+func (h *Service) roundingMessage(channel string, groupRate GroupRate) string {
+	if !groupRate.Rounded {
+		return ""
+	}
+	key := msgRoundedByHost
+	if h.roundingRemainder == RoundingRemainderLargest {
+		key = msgRoundedByLargest
+	}
+	return h.text(channel, key, h.cfg.AmountRounding, h.currencyName(channel, groupRate.Currency))
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundRates(t *testing.T) {
+	t.Parallel()
+
+	rates := map[string]float64{"Loki": 37.333333, "Odin": 37.333333, "Thor": 37.333334}
+	h, err := New(Config{AmountRounding: 0.5}, &fakeTreasuryStore{}, nil, nil, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"Loki": 37.5, "Odin": 37.5, "Thor": 37}, h.roundRates(rates, "Thor"))
+
+	groupRate := h.buildGroupRates(map[string]float64{"Loki": 37.333333, "Odin": 37.333333}, "Thor", 0)
+	assert.Equal(t, "Rates for Wolt order ID ABC (including 0 NIS for delivery):\n"+
+		"Loki: 37.50\n"+
+		"Odin: 37.50\n"+
+		"Thor: -0.33\n"+
+		"The amounts are rounded to the nearest 0.5 NIS, the host covers the difference\n"+
+		"\nPay to: Thor\n", h.buildRatesMessage("C1", groupRate, "ABC"), "a host who didn't order keeps the difference")
+
+	h, err = New(Config{AmountRounding: 1, RoundingRemainder: "largest"}, &fakeTreasuryStore{}, nil, nil,
+		"UBOT", &recordingNotification{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"Loki": 20, "Odin": 12, "Thor": 37.3},
+		h.roundRates(map[string]float64{"Loki": 20.4, "Odin": 11.6, "Thor": 37.3}, "Loki"), "the largest order covers the difference")
+	groupRate = h.buildGroupRates(map[string]float64{"Loki": 20.4, "Thor": 11.6}, "Thor", 0)
+	assert.Contains(t, h.buildRatesMessage("C1", groupRate, "ABC"), "the largest order covers the difference")
+
+	h, err = New(Config{}, &fakeTreasuryStore{}, nil, nil, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+	assert.Equal(t, rates, h.roundRates(rates, "Thor"), "the amounts aren't rounded by default")
+	assert.NotContains(t, h.buildRatesMessage("C1", h.buildGroupRates(rates, "Thor", 0), "ABC"), "rounded")
+}
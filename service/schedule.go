@@ -0,0 +1,161 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	userDomain "github.com/oriser/bolt/user"
+)
+
+// orderScheduleOff is the cutoff of the days of ORDER_SCHEDULE on which orders aren't tracked at all, like the weekend
+const orderScheduleOff = "off"
+
+// dayCutoff is the cutoff of a day of the weekly order schedule
+type dayCutoff struct {
+	clock time.Time // Zero if orders are tracked at any hour of the day
+	off   bool      // Orders aren't tracked on the day
+}
+
+// String returns the cutoff as it's configured: HH:MM, none or off
+func (c dayCutoff) String() string {
+	switch {
+	case c.off:
+		return orderScheduleOff
+	case c.clock.IsZero():
+		return noCutoff
+	default:
+		return c.clock.Format("15:04")
+	}
+}
+
+func parseDayCutoff(value string) (dayCutoff, error) {
+	switch strings.ToLower(value) {
+	case orderScheduleOff:
+		return dayCutoff{off: true}, nil
+	case noCutoff:
+		return dayCutoff{}, nil
+	}
+	clock, err := ParseClock(value)
+	if err != nil {
+		return dayCutoff{}, fmt.Errorf("expected HH:MM, %s or %s but got %q", noCutoff, orderScheduleOff, value)
+	}
+	return dayCutoff{clock: clock}, nil
+}
+
+// parseOrderSchedule parses <weekday>=<HH:MM|none|off> pairs, like fri=12:00 or sat=off, returning nil if there are none
+func parseOrderSchedule(pairs []string) (map[time.Weekday]dayCutoff, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	schedule := make(map[time.Weekday]dayCutoff, len(pairs))
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("expected <weekday>=<HH:MM|%s|%s> but got %q", noCutoff, orderScheduleOff, pair)
+		}
+		day, err := parseWeekday(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := schedule[day]; ok {
+			return nil, fmt.Errorf("%s is in the schedule more than once", day)
+		}
+		if schedule[day], err = parseDayCutoff(strings.TrimSpace(value)); err != nil {
+			return nil, fmt.Errorf("%s: %w", day, err)
+		}
+	}
+	return schedule, nil
+}
+
+// formatOrderSchedule returns the schedule in its canonical form, from Sunday to Saturday
+func formatOrderSchedule(schedule map[time.Weekday]dayCutoff) string {
+	pairs := make([]string, 0, len(schedule))
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if cutoff, ok := schedule[day]; ok {
+			pairs = append(pairs, fmt.Sprintf("%s=%s", strings.ToLower(day.String()[:3]), cutoff))
+		}
+	}
+	return strings.Join(pairs, ",")
+}
+
+// parseOrderScheduleSetting validates a channel's ORDER_SCHEDULE override, which is a comma separated schedule or none for no
+// schedule (so DONT_JOIN_AFTER applies every day)
+func parseOrderScheduleSetting(value string) (string, error) {
+	if strings.EqualFold(value, noCutoff) {
+		return noCutoff, nil
+	}
+	schedule, err := parseOrderSchedule(nonEmpty(strings.Split(value, ",")))
+	if err != nil {
+		return "", err
+	}
+	if len(schedule) == 0 {
+		return "", fmt.Errorf("the schedule is empty")
+	}
+	return formatOrderSchedule(schedule), nil
+}
+
+// channelOrderSchedule returns the weekly schedule of the channel: its ORDER_SCHEDULE override, or the global one
+func (h *Service) channelOrderSchedule(channel string) map[time.Weekday]dayCutoff {
+	value, ok := h.channelSetting(channel, settingOrderSchedule)
+	if !ok {
+		return h.orderSchedule
+	}
+	if value == noCutoff {
+		return nil
+	}
+	schedule, err := parseOrderSchedule(strings.Split(value, ","))
+	if err != nil {
+		return h.orderSchedule
+	}
+	return schedule
+}
+
+// userTimezone returns the timezone of the user with the transport ID, nil if they aren't known or have no timezone
+func (h *Service) userTimezone(transportID string) *time.Location {
+	if transportID == "" || h.userStore == nil {
+		return nil
+	}
+	ctx, cancel := h.storeContext()
+	defer cancel()
+	users, err := h.userStore.ListUsers(ctx, userDomain.ListFilter{TransportID: transportID})
+	if err != nil || len(users) == 0 || users[0].Timezone == "" {
+		return nil
+	}
+	tz, err := ParseTimezone(users[0].Timezone)
+	if err != nil {
+		return nil
+	}
+	return tz
+}
+
+// cutoffAt returns the cutoff of orders shared in the channel by the user at the given time: the cutoff of the day in the channel's
+// schedule, or the channel's DONT_JOIN_AFTER if the day isn't in it. The day and the cutoff are in the channel's DONT_JOIN_AFTER_TZ
+// override, then in the timezone of the user who shared the order, then in the global DONT_JOIN_AFTER_TZ.
+func (h *Service) cutoffAt(channel, userID string, at time.Time) (dayCutoff, time.Time) {
+	cutoff, tz := h.channelCutoff(channel)
+	if _, ok := h.channelSetting(channel, settingDontJoinAfterTZ); !ok {
+		if userTZ := h.userTimezone(userID); userTZ != nil {
+			tz = userTZ
+		}
+	}
+	if tz != nil {
+		at = at.In(tz)
+	}
+	if scheduled, ok := h.channelOrderSchedule(channel)[at.Weekday()]; ok {
+		return scheduled, at
+	}
+	return dayCutoff{clock: cutoff}, at
+}
+
+// shouldHandleOrder returns whether an order shared in the channel by the user now is before the cutoff
+func (h *Service) shouldHandleOrder(channel, userID string) bool {
+	cutoff, now := h.cutoffAt(channel, userID, time.Now())
+	if cutoff.off {
+		return false
+	}
+	if cutoff.clock.IsZero() {
+		return true
+	}
+	return now.Hour() < cutoff.clock.Hour() || (now.Hour() == cutoff.clock.Hour() && now.Minute() < cutoff.clock.Minute())
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOrderSchedule(t *testing.T) {
+	t.Parallel()
+
+	schedule, err := parseOrderSchedule([]string{"fri=12:00", " Saturday=off", "sun=None"})
+	require.NoError(t, err)
+	assert.Equal(t, "sun=none,fri=12:00,sat=off", formatOrderSchedule(schedule))
+	assert.True(t, schedule[time.Saturday].off)
+
+	for _, pairs := range [][]string{{"fri"}, {"someday=12:00"}, {"fri=noon"}, {"fri=12:00", "Friday=13:00"}} {
+		_, err := parseOrderSchedule(pairs)
+		assert.Error(t, err, pairs)
+	}
+
+	value, err := parseOrderScheduleSetting("sat=off, fri=12:00,")
+	require.NoError(t, err)
+	assert.Equal(t, "fri=12:00,sat=off", value)
+	value, err = parseOrderScheduleSetting("NONE")
+	require.NoError(t, err)
+	assert.Equal(t, noCutoff, value)
+	_, err = parseOrderScheduleSetting(",")
+	assert.Error(t, err)
+}
+
+func TestCutoffAt(t *testing.T) {
+	t.Parallel()
+
+	settings := &fakeChannelSettingsStore{settings: make(map[string]map[string]string)}
+	users := &fakeTreasuryStore{users: map[string]*userDomain.User{
+		"uuid-loki": {ID: "uuid-loki", FullName: "Loki", TransportID: "ULOKI", Timezone: "America/New_York"},
+		"uuid-thor": {ID: "uuid-thor", FullName: "Thor", TransportID: "UTHOR"},
+	}}
+	h, err := New(Config{
+
+		DontJoinAfter:   "14:00",
+		DontJoinAfterTZ: "Asia/Jerusalem",
+		OrderSchedule:   []string{"fri=11:00", "sat=off"},
+	}, users, users, settings, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+
+	// Thursday 2024-05-02 at 12:00 in Jerusalem, 05:00 in New York
+	thursday := time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC)
+	cutoff, at := h.cutoffAt("C1", "UTHOR", thursday)
+	assert.Equal(t, "14:00", cutoff.String())
+	assert.Equal(t, 12, at.Hour())
+	_, at = h.cutoffAt("C1", "ULOKI", thursday)
+	assert.Equal(t, 5, at.Hour(), "the cutoff is in the timezone of the user who shared the order")
+
+	cutoff, _ = h.cutoffAt("C1", "UTHOR", thursday.AddDate(0, 0, 1))
+	assert.Equal(t, "11:00", cutoff.String())
+	cutoff, _ = h.cutoffAt("C1", "UTHOR", thursday.AddDate(0, 0, 2))
+	assert.True(t, cutoff.off)
+	// Friday at 23:00 in Jerusalem is already Saturday, but it's Friday in New York
+	cutoff, _ = h.cutoffAt("C1", "ULOKI", time.Date(2024, 5, 3, 21, 0, 0, 0, time.UTC))
+	assert.Equal(t, "11:00", cutoff.String())
+
+	ctx := context.Background()
+	_, err = h.SetChannelSetting(ctx, "C1", settingDontJoinAfterTZ, "Europe/London", "U1")
+	require.NoError(t, err)
+	_, at = h.cutoffAt("C1", "ULOKI", thursday)
+	assert.Equal(t, 10, at.Hour(), "the channel's timezone takes precedence over the user's")
+
+	_, err = h.SetChannelSetting(ctx, "C1", settingOrderSchedule, "thu=none", "U1")
+	require.NoError(t, err)
+	cutoff, _ = h.cutoffAt("C1", "UTHOR", thursday)
+	assert.Equal(t, noCutoff, cutoff.String())
+	cutoff, _ = h.cutoffAt("C1", "UTHOR", thursday.AddDate(0, 0, 2))
+	assert.Equal(t, "14:00", cutoff.String(), "the channel's schedule replaces the global one")
+	_, err = h.SetChannelSetting(ctx, "C2", settingOrderSchedule, "none", "U1")
+	require.NoError(t, err)
+	cutoff, _ = h.cutoffAt("C2", "UTHOR", thursday.AddDate(0, 0, 2))
+	assert.Equal(t, "14:00", cutoff.String())
+
+	_, err = h.SetChannelSetting(ctx, "C3", settingOrderSchedule, "mon=off,tue=off,wed=off,thu=off,fri=off,sat=off,sun=off", "U1")
+	require.NoError(t, err)
+	assert.False(t, h.shouldHandleOrder("C3", "UTHOR"))
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	reactionAttempts   = 3
+	reactionRetryDelay = 500 * time.Millisecond
+)
+
+// MissingScopeError is wrapped by transport errors of calls Bolt isn't permitted to make, as the token of its app lacks their OAuth
+// scope, like reacting to messages without reactions:write on Slack
+type MissingScopeError struct {
+	Scope string
+}
+
+func (e *MissingScopeError) Error() string {
+	return fmt.Sprintf("missing the %s OAuth scope", e.Scope)
+}
+
+// missingScopes keeps the scopes the admins were told are missing, so they're told about each of them once
+type missingScopes struct {
+	lock     sync.Mutex
+	reported map[string]bool
+}
+
+func newMissingScopes() *missingScopes {
+	return &missingScopes{reported: make(map[string]bool)}
+}
+
+// report marks the scope as reported, and returns false if it already was
+func (m *missingScopes) report(scope string) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.reported[scope] {
+		return false
+	}
+	m.reported[scope] = true
+	return true
+}
+
+// addReaction reacts to the message, retrying the errors which may be transient. Errors of missing scopes and of unavailable
+// channels aren't retried, as they won't go away by themselves.
+func (h *Service) addReaction(channel, messageID, reaction string) error {
+	var err error
+	for attempt := 1; attempt <= reactionAttempts; attempt++ {
+		if err = h.eventNotification.AddReaction(channel, messageID, reaction); err == nil {
+			return nil
+		}
+		var scopeErr *MissingScopeError
+		if errors.As(err, &scopeErr) || errors.Is(err, ErrChannelUnavailable) || errors.Is(err, ErrUserUnavailable) || attempt == reactionAttempts {
+			break
+		}
+		h.logger.Warn("Error adding reaction, retrying", "channel", channel, "message_id", messageID, "attempt", attempt, "error", err)
+		time.Sleep(reactionRetryDelay)
+	}
+	return err
+}
+
+// reportMissingScope tells the admins in the fallback admin channel which OAuth scope Bolt's app is missing, once per scope
+func (h *Service) reportMissingScope(channel string, scopeErr *MissingScopeError) {
+	h.logger.Error("Bolt's app is missing an OAuth scope", "channel", channel, "scope", scopeErr.Scope)
+	if !h.missingScopes.report(scopeErr.Scope) || h.cfg.FallbackAdminChannel == "" || h.eventNotification == nil {
+		return
+	}
+	message := fmt.Sprintf(":warning: My app is missing the `%s` OAuth scope, so I can't do everything in <#%s> and fall back to messages where I can. Add the scope to the app and reinstall it.",
+		scopeErr.Scope, channel)
+	if _, err := h.eventNotification.SendMessage(h.cfg.FallbackAdminChannel, message, ""); err != nil {
+		h.logger.Error("Error notifying the fallback admin channel about the missing scope", "error", err)
+	}
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingReactionsNotification struct {
+	recordingNotification
+	errs     []error // The errors of the next reactions, after which reacting succeeds
+	attempts int
+}
+
+func (f *failingReactionsNotification) AddReaction(string, string, string) error {
+	f.attempts++
+	if len(f.errs) == 0 {
+		return nil
+	}
+	err := f.errs[0]
+	f.errs = f.errs[1:]
+	return err
+}
+
+func TestAdmitWithoutReactions(t *testing.T) {
+	t.Parallel()
+
+	scopeErr := fmt.Errorf("add reaction: %w", &MissingScopeError{Scope: "reactions:write"})
+	notification := &failingReactionsNotification{errs: []error{scopeErr, scopeErr}}
+	h, err := New(Config{FallbackAdminChannel: "CADMIN"}, nil, nil, nil, "UBOT", notification)
+	require.NoError(t, err)
+
+	require.NoError(t, h.admitLinkMessage(LinksRequest{Channel: "C1", MessageID: "1.1"}), "the link message is replied to instead")
+	require.NoError(t, h.admitLinkMessage(LinksRequest{Channel: "C1", MessageID: "2.1"}))
+	assert.Equal(t, 2, notification.attempts, "missing scopes aren't retried")
+	assert.Equal(t, []string{
+		"CADMIN: :warning: My app is missing the `reactions:write` OAuth scope, so I can't do everything in <#C1> and fall back to messages where I can. Add the scope to the app and reinstall it.",
+		"C1: :eyes: I'm on it, I'll track the order of this link",
+		"C1: :eyes: I'm on it, I'll track the order of this link",
+	}, notification.messages, "the admins are told about the missing scope once")
+
+	notification = &failingReactionsNotification{errs: []error{errors.New("timeout")}}
+	h, err = New(Config{}, nil, nil, nil, "UBOT", notification)
+	require.NoError(t, err)
+	require.NoError(t, h.admitLinkMessage(LinksRequest{Channel: "C1", MessageID: "1.1"}))
+	assert.Equal(t, 2, notification.attempts, "transient errors are retried")
+	assert.Empty(t, notification.messages)
+
+	notification = &failingReactionsNotification{errs: []error{fmt.Errorf("add reaction: %w", ErrChannelUnavailable)}}
+	h, err = New(Config{}, nil, nil, nil, "UBOT", notification)
+	require.NoError(t, err)
+	assert.ErrorIs(t, h.admitLinkMessage(LinksRequest{Channel: "C1", MessageID: "1.1"}), errWontJoin)
+	assert.Equal(t, 1, notification.attempts)
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/oriser/bolt/order"
+)
+
+const SearchResultsLimit = 10
+
+var tagRe = regexp.MustCompile(`(?:^|\s)#([\p{L}\p{N}_-]+)`)
+var noteRe = regexp.MustCompile(`(?i)(?:^|\s)note:[ \t]*([^\n]+)`)
+var amountRangeRe = regexp.MustCompile(`^(\d+(?:\.\d+)?)?-(\d+(?:\.\d+)?)?$`)
+
+// parseTags returns the #hashtags in a message text
+func parseTags(text string) []string {
+	matches := tagRe.FindAllStringSubmatch(text, -1)
+	tags := make([]string, 0, len(matches))
+	seen := make(map[string]bool)
+	for _, match := range matches {
+		tag := strings.ToLower(match[1])
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// parseNote returns the host's note in a message text, written after "note:" until the end of the line
+func parseNote(text string) string {
+	match := noteRe.FindStringSubmatch(text)
+	if match == nil {
+		return ""
+	}
+	return strings.TrimSpace(match[1])
+}
+
+func parseAmount(s string) (float64, error) {
+	amount, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	return amount, nil
+}
+
+// splitQuery splits a query by spaces, keeping double-quoted values together.
+// shlex isn't used as it treats '#' as a start of a comment.
+func splitQuery(query string) ([]string, error) {
+	terms := make([]string, 0)
+	var current strings.Builder
+	inQuotes, inTerm := false, false
+	for _, r := range query {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			inTerm = true
+		case unicode.IsSpace(r) && !inQuotes:
+			if inTerm {
+				terms = append(terms, current.String())
+				current.Reset()
+				inTerm = false
+			}
+		default:
+			current.WriteRune(r)
+			inTerm = true
+		}
+	}
+	if inQuotes {
+		return nil, fmt.Errorf("unclosed quote in query %q", query)
+	}
+	if inTerm {
+		terms = append(terms, current.String())
+	}
+	return terms, nil
+}
+
+// ParseSearchQuery parses a search query into an orders filter. Supported terms are:
+// venue:<name>, participant:<name>, #<tag>, amount:<min>-<max>, ><amount>, <<amount>.
+// The rest of the words are matched as free text against venue names, participants and tags.
+// Values with spaces can be quoted (venue:"Pizza Place").
+func ParseSearchQuery(query string) (order.ListFilter, error) {
+	terms, err := splitQuery(query)
+	if err != nil {
+		return order.ListFilter{}, err
+	}
+
+	filter := order.ListFilter{}
+	freeText := make([]string, 0)
+	for _, term := range terms {
+		key, value, hasKey := strings.Cut(term, ":")
+		switch {
+		case hasKey && key == "venue":
+			filter.VenueName = value
+		case hasKey && key == "participant":
+			filter.Participant = value
+		case hasKey && key == "amount":
+			match := amountRangeRe.FindStringSubmatch(value)
+			if match == nil {
+				return order.ListFilter{}, fmt.Errorf("invalid amount range %q, expected <min>-<max>", value)
+			}
+			if match[1] != "" {
+				if filter.MinAmount, err = parseAmount(match[1]); err != nil {
+					return order.ListFilter{}, err
+				}
+			}
+			if match[2] != "" {
+				if filter.MaxAmount, err = parseAmount(match[2]); err != nil {
+					return order.ListFilter{}, err
+				}
+			}
+		case strings.HasPrefix(term, ">"):
+			if filter.MinAmount, err = parseAmount(term[1:]); err != nil {
+				return order.ListFilter{}, err
+			}
+		case strings.HasPrefix(term, "<"):
+			if filter.MaxAmount, err = parseAmount(term[1:]); err != nil {
+				return order.ListFilter{}, err
+			}
+		case strings.HasPrefix(term, "#") && len(term) > 1:
+			filter.Tag = strings.ToLower(term[1:])
+		default:
+			freeText = append(freeText, term)
+		}
+	}
+	filter.Text = strings.Join(freeText, " ")
+
+	return filter, nil
+}
+
+// SearchOrders returns the newest orders sent to the given channel matching the query (see ParseSearchQuery)
+func (h *Service) SearchOrders(ctx context.Context, channel, query string) ([]*order.Order, error) {
+	if h.orderStore == nil {
+		return nil, fmt.Errorf("no order store")
+	}
+
+	filter, err := ParseSearchQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	filter.Receiver = channel
+	filter.Limit = SearchResultsLimit
+
+	orders, err := h.orderStore.ListOrders(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("list orders: %w", err)
+	}
+	return orders, nil
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/oriser/bolt/order"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		query   string
+		want    order.ListFilter
+		wantErr bool
+	}{
+		{
+			name:  "free text",
+			query: "pizza place",
+			want:  order.ListFilter{Text: "pizza place"},
+		},
+		{
+			name:  "all terms",
+			query: `venue:"Pizza Place" participant:Loki #Team-Lunch amount:50-100.5 extra`,
+			want:  order.ListFilter{VenueName: "Pizza Place", Participant: "Loki", Tag: "team-lunch", MinAmount: 50, MaxAmount: 100.5, Text: "extra"},
+		},
+		{
+			name:  "open amount ranges",
+			query: ">50 <70",
+			want:  order.ListFilter{MinAmount: 50, MaxAmount: 70},
+		},
+		{
+			name:  "range without max",
+			query: "amount:30-",
+			want:  order.ListFilter{MinAmount: 30},
+		},
+		{
+			name:    "bad range",
+			query:   "amount:abc",
+			wantErr: true,
+		},
+		{
+			name:    "unclosed quote",
+			query:   `venue:"Pizza`,
+			wantErr: true,
+		},
+		{
+			name:    "bad amount",
+			query:   ">abc",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := ParseSearchQuery(tt.query)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []string{"team-lunch", "friday"}, parseTags("#Team-Lunch order https://wolt.com/group/ABC #friday #team-lunch <#C123|general>"))
+	assert.Empty(t, parseTags("no tags here"))
+}
+
+func TestParseNote(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "cash only today", parseNote("Lunch <https://wolt.com/group/ABC> Note: cash only today \n#friday"))
+	assert.Equal(t, "ordering at 11:30 sharp", parseNote("https://wolt.com/group/ABC\nnote:ordering at 11:30 sharp"))
+	assert.Empty(t, parseNote("keynote: not a note https://wolt.com/group/ABC"))
+	assert.Empty(t, parseNote("no note here"))
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oriser/bolt/order"
+	"github.com/oriser/bolt/wolt"
+)
+
+const (
+	selfTestTimeout = time.Minute
+	// selfTestVenueSlug is a venue which doesn't exist, as checking Wolt only needs it to answer
+	selfTestVenueSlug = "bolt-self-test"
+	selfTestVenueName = "Bolt self-test"
+	// schedulerBeatsMissed is how many intervals of a scheduler can pass without a beat before it's considered stuck
+	schedulerBeatsMissed = 2
+)
+
+// schedulerBeat is the last time a scheduler ran
+type schedulerBeat struct {
+	at       time.Time
+	interval time.Duration
+}
+
+// schedulerBeats are the last runs of the schedulers of this process, by their names
+type schedulerBeats struct {
+	lock  sync.Mutex
+	beats map[string]schedulerBeat
+}
+
+func newSchedulerBeats() *schedulerBeats {
+	return &schedulerBeats{beats: make(map[string]schedulerBeat)}
+}
+
+// beat records that the scheduler runs, once it starts and on every tick
+func (s *schedulerBeats) beat(name string, interval time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.beats[name] = schedulerBeat{at: time.Now(), interval: interval}
+}
+
+func (s *schedulerBeats) list() map[string]schedulerBeat {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	beats := make(map[string]schedulerBeat, len(s.beats))
+	for name, beat := range s.beats {
+		beats[name] = beat
+	}
+	return beats
+}
+
+// SelfTestCheck is the result of one of the checks of the self-test
+type SelfTestCheck struct {
+	Name    string
+	Err     error  // nil if the check passed
+	Skipped string // Why the check didn't run, empty if it ran
+}
+
+// SelfTest checks that Bolt can work: that Wolt answers, that the store can be read and written, that Bolt can post, react to and
+// edit messages in the channel and DM the user, and that the schedulers of this process are running. It returns the results of the
+// checks and the message posted in the channel, empty if posting failed.
+func (h *Service) SelfTest(ctx context.Context, channel, userID string) ([]SelfTestCheck, string) {
+	checks := []SelfTestCheck{h.checkWolt(ctx)}
+	checks = append(checks, h.checkStore(ctx, channel)...)
+	transportChecks, messageID := h.checkTransport(channel, userID)
+	checks = append(checks, transportChecks...)
+	return append(checks, h.checkSchedulers()...), messageID
+}
+
+// RunSelfTest runs the self-test and posts its results in the channel, in the thread of its test message
+func (h *Service) RunSelfTest(channel, userID string) {
+	ctx, cancel := context.WithTimeout(h.lifetime(), selfTestTimeout)
+	defer cancel()
+	checks, messageID := h.SelfTest(ctx, channel, userID)
+	if _, err := h.informEvent(channel, BuildSelfTestMessage(checks), "", messageID); err != nil {
+		h.logger.Error("Error posting the self-test results", "channel", channel, "error", err)
+	}
+}
+
+func (h *Service) checkWolt(ctx context.Context) SelfTestCheck {
+	check := SelfTestCheck{Name: "Wolt API"}
+	addr, retryConfig := h.woltConfig()
+	// The venue doesn't exist, so Wolt answering that it isn't found means its API can be reached
+	var notFound *wolt.ErrVenueNotFound
+	if _, err := wolt.VenueBySlug(ctx, addr, retryConfig, selfTestVenueSlug); err != nil && !errors.As(err, &notFound) {
+		check.Err = err
+	}
+	return check
+}
+
+// checkStore reads the orders of the channel, and writes the store by blacklisting a venue in the channel and removing it right away
+func (h *Service) checkStore(ctx context.Context, channel string) []SelfTestCheck {
+	read := SelfTestCheck{Name: "Store read"}
+	write := SelfTestCheck{Name: "Store write"}
+	if h.orderStore == nil {
+		read.Skipped, write.Skipped = "no order store", "no order store"
+		return []SelfTestCheck{read, write}
+	}
+	if _, err := h.orderStore.ListOrders(ctx, order.ListFilter{Receiver: channel, Limit: 1}); err != nil {
+		read.Err = fmt.Errorf("list orders: %w", err)
+	}
+
+	store, ok := h.orderStore.(order.BlacklistStore)
+	if !ok {
+		write.Skipped = "the order store doesn't support blacklists"
+		return []SelfTestCheck{read, write}
+	}
+	venue := &order.BlacklistedVenue{Channel: channel, VenueName: selfTestVenueName, Reason: "self-test", AddedBy: h.selfID, CreatedAt: time.Now()}
+	if err := store.BlacklistVenue(ctx, venue); err != nil {
+		write.Err = fmt.Errorf("blacklist venue: %w", err)
+		return []SelfTestCheck{read, write}
+	}
+	removed, err := store.UnblacklistVenue(ctx, channel, selfTestVenueName)
+	if err != nil {
+		write.Err = fmt.Errorf("remove venue from the blacklist: %w", err)
+	} else if !removed {
+		write.Err = fmt.Errorf("the blacklisted venue wasn't found")
+	}
+	return []SelfTestCheck{read, write}
+}
+
+// checkTransport posts a message in the channel, reacts to it and edits it, and DMs the user
+func (h *Service) checkTransport(channel, userID string) ([]SelfTestCheck, string) {
+	post := SelfTestCheck{Name: "Post a message"}
+	react := SelfTestCheck{Name: "React to a message"}
+	edit := SelfTestCheck{Name: "Edit a message"}
+	dm := SelfTestCheck{Name: "Send a DM"}
+
+	messageID, err := h.messageSender(true)(channel, h.text(channel, msgSelfTestPost), "")
+	if err != nil {
+		post.Err = err
+		react.Skipped, edit.Skipped = "no message was posted", "no message was posted"
+	} else {
+		if err := h.eventNotification.AddReaction(channel, messageID, "white_check_mark"); err != nil {
+			react.Err = err
+		}
+		if err := h.eventNotification.EditMessage(channel, h.text(channel, msgSelfTestEdited), messageID); err != nil {
+			edit.Err = err
+		}
+	}
+	if _, err := h.eventNotification.SendMessage(userID, h.text(userID, msgSelfTestDM), ""); err != nil {
+		dm.Err = err
+	}
+	return []SelfTestCheck{post, react, edit, dm}, messageID
+}
+
+// checkSchedulers checks that each of the schedulers of this process ran in its last intervals
+func (h *Service) checkSchedulers() []SelfTestCheck {
+	beats := h.schedulers.list()
+	if len(beats) == 0 {
+		return []SelfTestCheck{{Name: "Schedulers", Skipped: "no schedulers run in this process"}}
+	}
+	names := make([]string, 0, len(beats))
+	for name := range beats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	checks := make([]SelfTestCheck, 0, len(names))
+	for _, name := range names {
+		check := SelfTestCheck{Name: fmt.Sprintf("Scheduler: %s", name)}
+		if since := time.Since(beats[name].at); since > schedulerBeatsMissed*beats[name].interval {
+			check.Err = fmt.Errorf("didn't run for %s", since.Round(time.Second))
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// BuildSelfTestMessage returns the checklist of the self-test results
+func BuildSelfTestMessage(checks []SelfTestCheck) string {
+	failed := 0
+	var sb strings.Builder
+	for _, check := range checks {
+		switch {
+		case check.Skipped != "":
+			sb.WriteString(fmt.Sprintf(":heavy_minus_sign: %s (skipped: %s)\n", check.Name, check.Skipped))
+		case check.Err != nil:
+			failed++
+			sb.WriteString(fmt.Sprintf(":x: %s: %v\n", check.Name, check.Err))
+		default:
+			sb.WriteString(fmt.Sprintf(":white_check_mark: %s\n", check.Name))
+		}
+	}
+	if failed > 0 {
+		return fmt.Sprintf("*Self-test: %d of %d checks failed*\n", failed, len(checks)) + sb.String()
+	}
+	return "*Self-test passed*\n" + sb.String()
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelfTest(t *testing.T) {
+	t.Parallel()
+
+	woltStatus := http.StatusNotFound
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(woltStatus)
+	}))
+	defer server.Close()
+
+	notification := &editingNotification{}
+	store := &fakeBlacklistStore{}
+	h, err := New(Config{WoltBaseAddr: server.URL, WoltApiBaseAddr: server.URL}, nil, nil, store, "UBOT", notification)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	checks, messageID := h.SelfTest(ctx, "C1", "U1")
+	assert.Equal(t, "sent-1", messageID)
+	assert.Equal(t, "*Self-test passed*\n"+
+		":white_check_mark: Wolt API\n"+
+		":white_check_mark: Store read\n"+
+		":white_check_mark: Store write\n"+
+		":white_check_mark: Post a message\n"+
+		":white_check_mark: React to a message\n"+
+		":white_check_mark: Edit a message\n"+
+		":white_check_mark: Send a DM\n"+
+		":heavy_minus_sign: Schedulers (skipped: no schedulers run in this process)\n", BuildSelfTestMessage(checks))
+	assert.Equal(t, []string{"C1/sent-1: Self-test: I can post, react to and edit messages"}, notification.edits)
+	assert.Equal(t, "U1: Self-test: I can send you DMs", notification.messages[1])
+	assert.Empty(t, store.venues, "the test venue is removed from the blacklist")
+
+	// Stuck schedulers and Wolt errors fail the self-test
+	woltStatus = http.StatusInternalServerError
+	h.schedulers.beat("debt scheduler", time.Minute)
+	h.schedulers.beats["deals watcher"] = schedulerBeat{at: time.Now().Add(-time.Hour), interval: time.Minute}
+	checks, _ = h.SelfTest(ctx, "C1", "U1")
+	message := BuildSelfTestMessage(checks)
+	assert.Contains(t, message, "*Self-test: 2 of 9 checks failed*\n")
+	assert.Contains(t, message, ":x: Wolt API: ")
+	assert.Contains(t, message, ":white_check_mark: Scheduler: debt scheduler\n")
+	assert.Contains(t, message, ":x: Scheduler: deals watcher: didn't run for 1h0m0s\n")
+}
@@ -1,13 +1,21 @@
 package service
 
 import (
+	"context"
 	"fmt"
-	"sync"
+	"log/slog"
 	"time"
 
+	"github.com/oriser/bolt/cache"
+	"github.com/oriser/bolt/calendar"
 	"github.com/oriser/bolt/debt"
+	"github.com/oriser/bolt/fx"
+	"github.com/oriser/bolt/headcount"
 	"github.com/oriser/bolt/order"
+	"github.com/oriser/bolt/tracing"
+	"github.com/oriser/bolt/translation"
 	"github.com/oriser/bolt/user"
+	"github.com/oriser/bolt/wolt"
 )
 
 type EventNotification interface {
@@ -16,35 +24,91 @@ type EventNotification interface {
 	AddReaction(receiver, messageID, reaction string) error
 }
 
-type Config struct {
-	TimeoutForReady          time.Duration `env:"ORDER_READY_TIMEOUT" envDefault:"1h"`
-	OrderDoneTimeout         time.Duration `env:"ORDER_DONE_TIMEOUT" envDefault:"3h"`
-	TimeTillGetReadyMessage  time.Duration `env:"TIME_TILL_GET_READY_MESSAGE" envDefault:"7m"`
-	OrderDestinationEmoji    string        `env:"ORDER_DESTINATION_EMOJI" envDefault:"house"`
-	JoinedOrderEmoji         string        `env:"JOINED_ORDER_EMOJI" envDefault:"eyes"`
-	TimeoutForDeliveryRate   time.Duration `env:"GET_DELIVERY_RATE_TIMEOUT" envDefault:"10m"`
-	WaitBetweenStatusCheck   time.Duration `env:"WAIT_BETWEEN_STATUS_CHECK" envDefault:"20s"`
-	DebtReminderInterval     time.Duration `env:"DEBT_REMINDER_INTERVAL" envDefault:"3h"`
-	DebtMaximumDuration      time.Duration `env:"DEBT_MAXIMUM_DURATION" envDefault:"24h"`
-	DontJoinAfter            string        `env:"DONT_JOIN_AFTER"`
-	DontJoinAfterTZ          string        `env:"DONT_JOIN_AFTER_TZ"`
-	WoltBaseAddr             string        `env:"WOLT_BASE_ADDR" envDefault:"https://wolt.com"`
-	WoltApiBaseAddr          string        `env:"WOLT_API_BASE_ADDR" envDefault:"https://restaurant-api.wolt.com"`
-	WoltHTTPMaxRetryCount    int           `env:"WOLT_HTTP_MAX_RETRY_COUNT" envDefault:"5"`
-	WoltHTTPMinRetryDuration time.Duration `env:"WOLT_HTTP_MIN_RETRY_DURATION" envDefault:"1s"`
-	WoltHTTPMaxRetryDuration time.Duration `env:"WOLT_HTTP_MAX_RETRY_DURATION" envDefault:"30s"`
+// PriorityEventNotification is implemented by notification layers which queue messages, to let direct responses to user interactions skip the queue
+type PriorityEventNotification interface {
+	SendPriorityMessage(receiver, event, messageID string) (string, error)
+}
+
+// EditableEventNotification is implemented by notification layers which batch messages, to send the messages which are edited later
+// on their own
+type EditableEventNotification interface {
+	SendEditableMessage(receiver, event, messageID string) (string, error)
+}
+
+// MessageLinker is implemented by notification layers which can link to a sent message
+type MessageLinker interface {
+	MessageLink(receiver, messageID string) (string, error)
 }
 
 type Service struct {
-	cfg                    Config
-	eventNotification      EventNotification
-	currentlyWorkingOrders sync.Map
-	userStore              user.Store
-	debtStore              debt.Store
-	orderStore             order.Store
-	selfID                 string
-	dontJoinAfter          time.Time
-	dontJoinAfterTZ        *time.Location
+	cfg                               Config
+	logger                            *slog.Logger
+	eventNotification                 EventNotification
+	workingOrders                     *workingOrders
+	orderSlots                        *orderSlots
+	missingScopes                     *missingScopes
+	soloOrders                        *soloOrders
+	commandLimiter                    *commandLimiter
+	monitors                          *liveMonitors
+	activeOrders                      *activeOrders
+	skips                             *orderSkips
+	mergeOffers                       *mergeOffers
+	orderMessages                     *orderMessages
+	blacklistConfirmations            *blacklistConfirmations
+	preauthorizations                 *preauthorizations
+	fxProvider                        fx.Provider
+	headcountProvider                 headcount.Provider
+	translationProvider               translation.Provider
+	quietCalendar                     calendar.Provider
+	venueCache                        cache.Cache
+	venueCacheTTL                     time.Duration
+	quiet                             *quietMeetings
+	readModels                        *readModels
+	itemTranslations                  *itemTranslations
+	pickups                           *orderPickups
+	cohosts                           *cohosts
+	rateAdjustments                   *rateAdjustments
+	subsidyReservations               *subsidyReservations
+	defaultLocale                     Locale
+	channelLocaleOverrides            map[string]Locale
+	messages                          map[Locale]map[messageKey]string // Overrides of the built-in messages, see MESSAGES_FILE
+	locales                           *channelLocales
+	userStore                         user.Store
+	debtStore                         debt.Store
+	orderStore                        order.Store
+	selfID                            string
+	dontJoinAfter                     time.Time
+	dontJoinAfterTZ                   *time.Location
+	orderSchedule                     map[time.Weekday]dayCutoff
+	escalationStages                  []escalationStage
+	channelTimezones                  map[string]*time.Location
+	officeLocation                    *wolt.Coordinate
+	feeAllocator                      FeeAllocator
+	roundingRemainder                 RoundingRemainder
+	ratesItems                        RatesItems
+	discountAllocation                DiscountAllocation
+	orderRefGenerator                 OrderRefGenerator
+	subsidyExcludedCategories         []ItemCategory
+	unknownParticipantPolicyDefault   UnknownParticipantPolicy
+	channelUnknownParticipantPolicies map[string]UnknownParticipantPolicy
+	balancesDigestWeekday             time.Weekday
+	deliveryUpdates                   DeliveryUpdates
+	currency                          string
+	paymentLinks                      map[user.PaymentMethod]string
+	statusPageSecret                  []byte
+	paidLinksSecret                   []byte
+	workHours                         *workHours
+	workDays                          map[time.Weekday]bool
+	hooks                             *Hooks
+	activity                          *userActivity
+	schedulers                        *schedulerBeats
+	woltGuard                         *wolt.Guard
+	providers                         []Provider // The delivery providers, Wolt first
+	settingsCache                     *channelSettingsCache
+	menus                             *menuCache
+	noDebtWorkers                     bool
+	ctx                               context.Context // Canceled once the service shuts down
+	shutdown                          context.CancelFunc
 }
 
 type ReactionAddRequest struct {
@@ -52,6 +116,7 @@ type ReactionAddRequest struct {
 	FromUserID    string
 	Channel       string
 	MessageUserID string
+	MessageID     string
 	MessageText   string
 }
 
@@ -63,44 +128,142 @@ type LinksRequest struct {
 	Links     []Link
 	MessageID string
 	Channel   string
+	Text      string // The text of the message with the links, if available
+	UserID    string // The user who shared the links, if known, whose timezone the cutoff is in unless the channel sets one
+	// The W3C traceparent of the span of the incoming event, so the handling of the links continues its trace (also when it's
+	// passed to the monitor component through the queue)
+	TraceParent string
 }
 
 func New(cfg Config, userStore user.Store, debtStore debt.Store, orderStore order.Store, selfID string, eventNotification EventNotification) (*Service, error) {
-	var dontJoinAfter time.Time
-	var err error
-	if cfg.DontJoinAfter != "" {
-		dontJoinAfter, err = time.Parse("15:04", cfg.DontJoinAfter)
-		if err != nil {
-			return nil, fmt.Errorf("parsing DONT_JOIN_AFTER (HH:MM format): %w", err)
-		}
+	parsed, err := cfg.parse()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.FeeAllocationStrategy == "" {
+		cfg.FeeAllocationStrategy = FeeAllocationEqual
 	}
+	hooks := NewHooks()
+	active := newActiveOrders()
+	hooks.SubscribeAll(active.onEvent)
 
-	var dontJoinAfterTZ *time.Location
-	if cfg.DontJoinAfterTZ != "" {
-		dontJoinAfterTZ, err = time.LoadLocation(cfg.DontJoinAfterTZ)
-		if err != nil {
-			return nil, fmt.Errorf("parsing DONT_JOIN_AFTER_TZ: %w", err)
-		}
+	ctx, shutdown := context.WithCancel(context.Background())
+	h := &Service{
+		ctx:                               ctx,
+		shutdown:                          shutdown,
+		cfg:                               cfg,
+		logger:                            slog.Default(),
+		eventNotification:                 eventNotification,
+		userStore:                         userStore,
+		debtStore:                         debtStore,
+		orderStore:                        orderStore,
+		selfID:                            selfID,
+		dontJoinAfter:                     parsed.dontJoinAfter,
+		dontJoinAfterTZ:                   parsed.dontJoinAfterTZ,
+		orderSchedule:                     parsed.orderSchedule,
+		escalationStages:                  parsed.escalationStages,
+		channelTimezones:                  parsed.channelTimezones,
+		officeLocation:                    parsed.officeLocation,
+		feeAllocator:                      parsed.feeAllocator,
+		roundingRemainder:                 parsed.roundingRemainder,
+		ratesItems:                        parsed.ratesItems,
+		discountAllocation:                parsed.discountAllocation,
+		orderRefGenerator:                 parsed.orderRefGenerator,
+		subsidyExcludedCategories:         parsed.subsidyExcludedCategories,
+		unknownParticipantPolicyDefault:   parsed.unknownParticipantPolicy,
+		channelUnknownParticipantPolicies: parsed.channelUnknownParticipantPolicies,
+		balancesDigestWeekday:             parsed.balancesDigestWeekday,
+		deliveryUpdates:                   parsed.deliveryUpdates,
+		currency:                          parsed.currency,
+		paymentLinks:                      parsed.paymentLinks,
+		statusPageSecret:                  parsed.statusPageSecret,
+		paidLinksSecret:                   parsed.paidLinksSecret,
+		workHours:                         parsed.workHours,
+		workDays:                          parsed.workDays,
+		hooks:                             hooks,
+		activeOrders:                      active,
+		skips:                             newOrderSkips(),
+		mergeOffers:                       newMergeOffers(),
+		orderMessages:                     newOrderMessages(),
+		workingOrders:                     newWorkingOrders(cfg.WorkingOrderTTL),
+		orderSlots:                        newOrderSlots(cfg.MaxTrackedOrders, cfg.MaxQueuedOrders),
+		missingScopes:                     newMissingScopes(),
+		soloOrders:                        newSoloOrders(),
+		commandLimiter:                    newCommandLimiter(cfg.CommandRateLimit, cfg.CommandBurst),
+		monitors:                          newLiveMonitors(),
+		blacklistConfirmations:            newBlacklistConfirmations(),
+		preauthorizations:                 newPreauthorizations(),
+		pickups:                           newOrderPickups(),
+		cohosts:                           newCohosts(),
+		rateAdjustments:                   newRateAdjustments(),
+		subsidyReservations:               newSubsidyReservations(),
+		defaultLocale:                     parsed.defaultLocale,
+		channelLocaleOverrides:            parsed.channelLocaleOverrides,
+		messages:                          parsed.messages,
+		locales:                           newChannelLocales(),
+		activity:                          newUserActivity(),
+		schedulers:                        newSchedulerBeats(),
+		quiet:                             newQuietMeetings(),
+		readModels:                        newReadModels(cfg.ReadModelsTTL),
+		settingsCache:                     newChannelSettingsCache(),
+		menus:                             newMenuCache(),
+		itemTranslations:                  newItemTranslations(),
+		woltGuard: wolt.NewGuard(wolt.GuardConfig{
+			RateLimit:       cfg.WoltRateLimit,
+			BreakerFailures: cfg.WoltBreakerFailures,
+			BreakerCooldown: cfg.WoltBreakerCooldown,
+		}),
 	}
-	return &Service{
-		cfg:               cfg,
-		eventNotification: eventNotification,
-		userStore:         userStore,
-		debtStore:         debtStore,
-		orderStore:        orderStore,
-		selfID:            selfID,
-		dontJoinAfter:     dontJoinAfter,
-		dontJoinAfterTZ:   dontJoinAfterTZ,
-	}, nil
+	h.providers = []Provider{&woltProvider{h: h}}
+	hooks.Subscribe(h.recordPayment, EventDebtPaid)
+	hooks.Subscribe(h.onDebtPaid, EventDebtPaid)
+	hooks.Subscribe(h.dropPaidReminder, EventDebtPaid)
+	hooks.Subscribe(h.readModels.onEvent, EventDebtCreated, EventDebtPaid, EventOrderDebtsRemoved)
+	hooks.Subscribe(h.mergeOffers.onEvent, EventRatesPublished, EventOrderDelivered, EventOrderCanceled, EventOrderStopped)
+	hooks.Subscribe(h.orderMessages.onEvent, EventOrderSettled, EventOrderDebtsRemoved)
+	hooks.Subscribe(recordMetrics, EventOrderCanceled, EventOrderDelivered, EventDebtCreated, EventDebtPaid)
+	return h, nil
+}
+
+// SetLogger sets the structured logger of the service, which logs with the default logger otherwise
+func (h *Service) SetLogger(logger *slog.Logger) {
+	h.logger = logger
+}
+
+// Hooks returns the lifecycle events registry, for subscribing to order events
+func (h *Service) Hooks() *Hooks {
+	return h.hooks
 }
 
 func (h *Service) informEvent(receiver, event, reactionEmoji, initialMessageID string) (string, error) {
+	return h.informEventContext(context.Background(), receiver, event, reactionEmoji, initialMessageID)
+}
+
+// informEventContext is informEvent recording the notification as a span of the trace of ctx
+func (h *Service) informEventContext(ctx context.Context, receiver, event, reactionEmoji, initialMessageID string) (string, error) {
+	return h.sendEvent(ctx, false, receiver, event, reactionEmoji, initialMessageID)
+}
+
+// informEditableEvent is informEventContext for messages which are edited later, which mustn't be merged with other messages the edit
+// would overwrite
+func (h *Service) informEditableEvent(ctx context.Context, receiver, event, reactionEmoji, initialMessageID string) (string, error) {
+	return h.sendEvent(ctx, true, receiver, event, reactionEmoji, initialMessageID)
+}
+
+func (h *Service) sendEvent(ctx context.Context, editable bool, receiver, event, reactionEmoji, initialMessageID string) (messageID string, err error) {
 	if h.eventNotification == nil {
 		return "", fmt.Errorf("nil eventNotification")
 	}
+	send := h.messageSender(editable)
+	_, span := tracing.Start(ctx, "notification.send_message", tracing.String("channel", receiver))
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
 
-	messageID, err := h.eventNotification.SendMessage(receiver, event, initialMessageID)
+	messageID, err = send(receiver, h.orderLabel(receiver, initialMessageID)+h.forReceiver(receiver, event), initialMessageID)
 	if err != nil {
+		h.checkTransportError(receiver, err)
 		return "", fmt.Errorf("error replying to message %s: %w", receiver, err)
 	}
 
@@ -113,3 +276,25 @@ func (h *Service) informEvent(receiver, event, reactionEmoji, initialMessageID s
 
 	return messageID, nil
 }
+
+// messageSender returns the function sending messages through the notification layer, which sends the editable messages on their own
+func (h *Service) messageSender(editable bool) func(receiver, event, messageID string) (string, error) {
+	if editableNotification, ok := h.eventNotification.(EditableEventNotification); ok && editable {
+		return editableNotification.SendEditableMessage
+	}
+	return h.eventNotification.SendMessage
+}
+
+// informInteractiveEvent sends a direct response to a user interaction, ahead of queued notifications when the notification layer supports it
+func (h *Service) informInteractiveEvent(receiver, event, initialMessageID string) (string, error) {
+	priorityNotification, ok := h.eventNotification.(PriorityEventNotification)
+	if !ok {
+		return h.informEvent(receiver, event, "", initialMessageID)
+	}
+
+	messageID, err := priorityNotification.SendPriorityMessage(receiver, event, initialMessageID)
+	if err != nil {
+		return "", fmt.Errorf("error replying to message %s: %w", receiver, err)
+	}
+	return messageID, nil
+}
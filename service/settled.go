@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	"github.com/oriser/bolt/order"
+)
+
+// onDebtPaid closes the loop of an order once its last debt was paid. If every debt of the order was paid, and not removed
+// otherwise (like expired or forgiven), it thanks everyone in the order's thread and sends the host a receipt.
+func (h *Service) onDebtPaid(ctx context.Context, event Event) {
+	if h.debtStore == nil || event.Debt == nil {
+		return
+	}
+
+	debts, err := h.debtStore.ListDebtsForOrderID(ctx, event.OrderID)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "Error listing debts of order after a debt was paid", "group_id", event.OrderID, "error", err)
+		return
+	}
+	if len(debts) > 0 {
+		return
+	}
+
+	if o, paid := h.paidOrder(ctx, event.OrderID); o != nil && everyonePaid(o, paid) {
+		_, _ = h.informEvent(event.Channel, h.text(event.Channel, msgOrderSettled), "", event.MessageID)
+		host, err := h.userStore.GetUser(ctx, event.Debt.LenderID)
+		if err != nil {
+			h.logger.ErrorContext(ctx, "Error getting host of order for a receipt", "user_id", event.Debt.LenderID, "group_id", event.OrderID, "error", err)
+		} else {
+			_ = h.notifyUser(ctx, host.TransportID, "receipt/"+event.OrderID, h.buildReceiptMessage(ctx, host.TransportID, o), "")
+		}
+	}
+	h.hooks.Emit(ctx, Event{Type: EventOrderSettled, OrderID: event.OrderID, Channel: event.Channel, MessageID: event.MessageID})
+}
+
+// paidOrder returns the stored order and the IDs of the borrowers who paid their debts of it, or a nil order if it isn't
+// stored or the payments aren't kept
+func (h *Service) paidOrder(ctx context.Context, orderID string) (*order.Order, map[string]bool) {
+	paymentStore, ok := h.debtStore.(debtDomain.PaymentStore)
+	if !ok || h.orderStore == nil {
+		return nil, nil
+	}
+	orders, err := h.orderStore.ListOrders(ctx, order.ListFilter{OriginalID: orderID, Limit: 1})
+	if err != nil {
+		h.logger.ErrorContext(ctx, "Error getting order for a receipt", "group_id", orderID, "error", err)
+		return nil, nil
+	}
+	if len(orders) == 0 {
+		return nil, nil
+	}
+	payments, err := paymentStore.ListPayments(ctx, debtDomain.PaymentListFilter{OrderID: orderID})
+	if err != nil {
+		h.logger.ErrorContext(ctx, "Error listing payments of order for a receipt", "group_id", orderID, "error", err)
+		return nil, nil
+	}
+	paid := make(map[string]bool, len(payments))
+	for _, payment := range payments {
+		paid[payment.BorrowerID] = true
+	}
+	return orders[0], paid
+}
+
+// everyonePaid returns whether every participant of the order who had a debt paid it. Participants who weren't matched to a user
+// had no debt to pay.
+func everyonePaid(o *order.Order, paid map[string]bool) bool {
+	for _, p := range o.Participants {
+		if p.Name == o.Host || p.ID == "" || p.PersonalAmount() <= 0 {
+			continue
+		}
+		if !paid[p.ID] {
+			return false
+		}
+	}
+	return true
+}
+
+// buildReceiptMessage summarizes the paid amounts of the order. The participants who weren't matched to a user had no debt, so
+// they're listed apart for the host to settle with them.
+func (h *Service) buildReceiptMessage(ctx context.Context, receiver string, o *order.Order) string {
+	var sb, untracked strings.Builder
+	sb.WriteString(h.text(receiver, msgReceiptHeader, o.VenueName, o.OriginalID))
+	paid := 0.0
+	for _, p := range o.Participants {
+		if p.Name == o.Host {
+			continue
+		}
+		if p.ID == "" {
+			untracked.WriteString(fmt.Sprintf("%s: %.2f\n", p.Name, p.PersonalAmount()))
+			continue
+		}
+		name := p.Name
+		if u, err := h.userStore.GetUser(ctx, p.ID); err == nil {
+			name = fmt.Sprintf("<@%s> (%s)", u.TransportID, p.Name)
+		}
+		sb.WriteString(fmt.Sprintf("%s: %.2f\n", name, p.PersonalAmount()))
+		paid += p.PersonalAmount()
+	}
+	sb.WriteString(h.text(receiver, msgReceiptTotal, paid, o.TotalAmount(), o.DeliveryRate, CurrencyUnit(h.currencyOrDefault(o.Currency))))
+	if untracked.Len() > 0 {
+		sb.WriteString(h.text(receiver, msgReceiptUntracked))
+		sb.WriteString(untracked.String())
+	}
+	return sb.String()
+}
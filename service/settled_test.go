@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	"github.com/oriser/bolt/order"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeOrderStore struct {
+	orders []*order.Order
+}
+
+func (f *fakeOrderStore) SaveOrder(_ context.Context, o *order.Order) error {
+	f.orders = append(f.orders, o)
+	return nil
+}
+
+func (f *fakeOrderStore) ListOrders(_ context.Context, filter order.ListFilter) ([]*order.Order, error) {
+	orders := make([]*order.Order, 0)
+	for _, o := range f.orders {
+		if (filter.OriginalID == "" || o.OriginalID == filter.OriginalID) && (filter.Receiver == "" || o.Receiver == filter.Receiver) &&
+			(filter.MessageID == "" || o.MessageID == filter.MessageID) {
+			orders = append(orders, o)
+		}
+	}
+	return orders, nil
+}
+
+func TestOrderSettled(t *testing.T) {
+	t.Parallel()
+
+	store := &fakeTreasuryStore{
+		debts: []*debtDomain.Debt{
+			{ID: "1", BorrowerID: "U1", LenderID: "U2", OrderID: "A", Amount: 10, InitiatedTransportID: "C1", MessageID: "1.1"},
+			{ID: "2", BorrowerID: "U3", LenderID: "U2", OrderID: "A", Amount: 20, InitiatedTransportID: "C1", MessageID: "1.1"},
+		},
+		users: map[string]*userDomain.User{
+			"U1": {ID: "U1", TransportID: "U1"},
+			"U2": {ID: "U2", TransportID: "U2"},
+			"U3": {ID: "U3", TransportID: "U3"},
+		},
+	}
+	orderStore := &fakeOrderStore{orders: []*order.Order{{
+		OriginalID:   "A",
+		VenueName:    "Pizza Place",
+		Host:         "Thor",
+		DeliveryRate: 15,
+		Participants: []order.Participant{
+			{Name: "Loki", ID: "U1", Amount: 10},
+			{Name: "Odin", ID: "U3", Amount: 20},
+			{Name: "Freya", Amount: 15},
+			{Name: "Thor", ID: "U2", Amount: 30},
+		},
+	}}}
+	notification := &recordingNotification{}
+	h, err := New(Config{}, store, store, orderStore, "U-bot", notification)
+	require.NoError(t, err)
+	settled := make([]Event, 0)
+	h.hooks.Subscribe(func(_ context.Context, event Event) {
+		settled = append(settled, event)
+	}, EventOrderSettled)
+
+	require.NoError(t, h.markDebtAsPaid(context.Background(), "A", "U1", "C1"))
+	assert.Empty(t, settled, "the order isn't settled while it has debts")
+
+	require.NoError(t, h.markDebtAsPaid(context.Background(), "A", "U3", "C1"))
+	require.Len(t, settled, 1)
+	assert.Equal(t, "A", settled[0].OrderID)
+	assert.Contains(t, notification.messages, "C1: Everyone has paid for this order, thank you all! :tada:")
+	assert.Contains(t, notification.messages, "U2: Everyone has paid you for the order from Pizza Place (Wolt order ID A) :tada: Here's the receipt:\n"+
+		"<@U1> (Loki): 10.00\n<@U3> (Odin): 20.00\n\nTotal paid to you: 30.00 (the order total is 75.00, including 15 NIS for delivery)\n"+
+		"\nI didn't track the debts of these participants, as I couldn't match them to users, so settle with them yourself:\nFreya: 15.00\n")
+}
+
+func TestOrderSettledWithoutPayingEveryDebt(t *testing.T) {
+	t.Parallel()
+
+	store := &fakeTreasuryStore{
+		debts: []*debtDomain.Debt{
+			{ID: "1", BorrowerID: "U1", LenderID: "U2", OrderID: "A", Amount: 10, InitiatedTransportID: "C1", MessageID: "1.1"},
+			{ID: "2", BorrowerID: "U3", LenderID: "U2", OrderID: "A", Amount: 20, InitiatedTransportID: "C1", MessageID: "1.1"},
+		},
+		users: map[string]*userDomain.User{
+			"U1": {ID: "U1", TransportID: "U1"},
+			"U2": {ID: "U2", TransportID: "U2"},
+		},
+	}
+	orderStore := &fakeOrderStore{orders: []*order.Order{{
+		OriginalID: "A",
+		Host:       "Thor",
+		Participants: []order.Participant{
+			{Name: "Loki", ID: "U1", Amount: 10},
+			{Name: "Odin", ID: "U3", Amount: 20},
+			{Name: "Thor", ID: "U2", Amount: 30},
+		},
+	}}}
+	notification := &recordingNotification{}
+	h, err := New(Config{}, store, store, orderStore, "U-bot", notification)
+	require.NoError(t, err)
+	settled := make([]Event, 0)
+	h.hooks.Subscribe(func(_ context.Context, event Event) {
+		settled = append(settled, event)
+	}, EventOrderSettled)
+
+	require.NoError(t, store.RemoveDebtInOrderID(context.Background(), "A", "2"), "the debt expired")
+	require.NoError(t, h.markDebtAsPaid(context.Background(), "A", "U1", "C1"))
+	require.Len(t, settled, 1, "the order has no debts left")
+	for _, message := range notification.messages {
+		assert.NotContains(t, message, "Everyone has paid", "Odin didn't pay")
+	}
+}
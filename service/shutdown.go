@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const reasonShutdown = "Bolt is shutting down"
+
+// errShuttingDown is returned for link messages handled once the service started shutting down, so they're handled again after a
+// restart
+var errShuttingDown = fmt.Errorf("the service is shutting down")
+
+// shuttingDown returns whether Shutdown was called
+func (h *Service) shuttingDown() bool {
+	return h.lifetime().Err() != nil
+}
+
+// Shutdown stops tracking the orders and cancels their requests to Wolt and the store, and waits until their handling is over or
+// ctx is done. The stopped orders keep their tracking state, which is saved once more with their latest Wolt session, so they're
+// resumed once Bolt starts again, and their channels are told about it. The debts are kept too.
+func (h *Service) Shutdown(ctx context.Context) error {
+	// The orders are stopped before canceling their context, so they know why it's canceled
+	for _, order := range h.workingOrders.list() {
+		if order.stop(reasonShutdown) {
+			h.handOffOrder(order)
+		}
+	}
+	if h.shutdown != nil {
+		h.shutdown()
+	}
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for h.workingOrders.count() > 0 {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%d orders are still being handled: %w", h.workingOrders.count(), ctx.Err())
+		case <-ticker.C:
+		}
+	}
+	h.logger.InfoContext(ctx, "All the orders stopped for the shutdown")
+	return nil
+}
+
+// handOffOrder saves the tracking state of an order stopped by the shutdown, and tells its channel whether its tracking is resumed
+// after the restart
+func (h *Service) handOffOrder(order *groupOrder) {
+	message := msgShutdownStopped
+	if h.trackingStore() != nil && order.group != nil {
+		h.saveTracking(order, order.detailsMessageId)
+		message = msgShutdownResumed
+	}
+	if _, err := h.informEvent(order.channel, h.text(order.channel, message, order.id), "", order.messageID); err != nil {
+		h.logger.ErrorContext(order.ctx, "Error telling the channel about stopping the order for the shutdown", "error", err)
+	}
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	orderDomain "github.com/oriser/bolt/order"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShutdown(t *testing.T) {
+	t.Parallel()
+
+	notification := &recordingNotification{}
+	h, err := New(Config{StoreTimeout: time.Minute}, nil, nil, nil, "UBOT", notification)
+	require.NoError(t, err)
+	entry, _, ok := h.workingOrders.start("A", time.Now(), 1, nil)
+	require.True(t, ok)
+	ctx, cancelOrder := context.WithCancel(h.lifetime())
+	order := &groupOrder{id: "A", channel: "C1", messageID: "1.1", ctx: ctx, cancel: cancelOrder}
+	h.workingOrders.setOrder(entry, order)
+	storeCtx, cancel := h.storeContext()
+	defer cancel()
+	deadline, hasDeadline := storeCtx.Deadline()
+	require.True(t, hasDeadline, "store calls should have a deadline")
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+
+	// The order's handling is over shortly after it's stopped
+	go func() {
+		<-order.ctx.Done()
+		time.Sleep(50 * time.Millisecond)
+		h.workingOrders.done("A", entry)
+	}()
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelShutdown()
+	require.NoError(t, h.Shutdown(shutdownCtx))
+	assert.Equal(t, reasonShutdown, order.stopped())
+	assert.Equal(t, []string{"C1: :warning: I'm shutting down, so I stopped tracking order A"}, notification.messages,
+		"orders can't be resumed without a tracking store")
+	assert.ErrorIs(t, storeCtx.Err(), context.Canceled, "store calls are canceled by the shutdown")
+	assert.True(t, h.shuttingDown())
+
+	_, err = h.trackOrder(LinksRequest{Channel: "C1", MessageID: "1.1"}, groupLink{groupID: "B"}, nil, &linkAdmission{})
+	assert.ErrorIs(t, err, errShuttingDown, "no new orders should be tracked while shutting down")
+
+	// Orders which don't stop in time fail the shutdown
+	startWorkingOrder(t, h, "C", "C1")
+	expired, cancelExpired := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancelExpired()
+	assert.ErrorContains(t, h.Shutdown(expired), "1 orders are still being handled")
+}
+
+func TestShutdownHandsOffOrders(t *testing.T) {
+	t.Parallel()
+
+	store := &fakeTrackingStore{tracked: make(map[string]*orderDomain.TrackedOrder)}
+	notification := &recordingNotification{}
+	h, err := New(Config{WoltApiBaseAddr: "https://restaurant-api.wolt.com"}, nil, nil, store, "UBOT", notification)
+	require.NoError(t, err)
+
+	woltProvider, err := h.providerByName(providerWolt)
+	require.NoError(t, err)
+	restored, err := h.restoreGroupOrder(woltProvider, "ABC", json.RawMessage(`{"id": "real-abc"}`))
+	require.NoError(t, err)
+	order := startWorkingOrder(t, h, "ABC", "C1")
+	order.provider, order.group, order.detailsMessageId = restored.provider, restored.group, "1.3"
+
+	// The order's handling isn't over in the test, so the shutdown times out
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+	assert.Error(t, h.Shutdown(shutdownCtx))
+
+	require.Contains(t, store.tracked, "ABC")
+	assert.Equal(t, orderDomain.PhaseDelivery, store.tracked["ABC"].Phase)
+	assert.Equal(t, "1.3", store.tracked["ABC"].RatesMessageID)
+	assert.Equal(t, []string{"C1: :hourglass_flowing_sand: I'm restarting, I'll continue tracking order ABC once I'm back"}, notification.messages)
+
+	// Orders are handed off once
+	assert.Error(t, h.Shutdown(shutdownCtx))
+	assert.Len(t, notification.messages, 1)
+}
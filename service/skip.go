@@ -0,0 +1,110 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// orderSkips keeps the users who reacted that they're skipping an order, by the channel and ID of the order's link message
+type orderSkips struct {
+	lock  sync.Mutex
+	users map[string][]string
+}
+
+func newOrderSkips() *orderSkips {
+	return &orderSkips{users: make(map[string][]string)}
+}
+
+func skipKey(channel, messageID string) string {
+	return channel + "/" + messageID
+}
+
+// add adds the user to the skippers of the order, and returns false if the user already skipped it
+func (s *orderSkips) add(channel, messageID, userID string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	key := skipKey(channel, messageID)
+	for _, skipper := range s.users[key] {
+		if skipper == userID {
+			return false
+		}
+	}
+	s.users[key] = append(s.users[key], userID)
+	return true
+}
+
+func (s *orderSkips) get(channel, messageID string) []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]string(nil), s.users[skipKey(channel, messageID)]...)
+}
+
+func (s *orderSkips) remove(channel, messageID string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.users, skipKey(channel, messageID))
+}
+
+// activeOrderByMessage returns the tracked order of the given link message, or nil if there's no such order
+func (h *Service) activeOrderByMessage(channel, messageID string) *ActiveOrder {
+	for _, activeOrder := range h.ActiveOrders() {
+		if activeOrder.Channel == channel && activeOrder.MessageID == messageID {
+			return &activeOrder
+		}
+	}
+	return nil
+}
+
+func (h *Service) handleSkipReaction(req ReactionAddRequest) {
+	activeOrder := h.activeOrderByMessage(req.Channel, req.MessageID)
+	if activeOrder == nil {
+		// Not a link message of an order I track
+		return
+	}
+	if !h.skips.add(req.Channel, req.MessageID, req.FromUserID) {
+		return
+	}
+
+	message := fmt.Sprintf("Got it <@%s>, you're skipping this order :wave:", req.FromUserID)
+	if activeOrder.Rates != nil {
+		if rate := skipperRate(*activeOrder.Rates, req.FromUserID); rate != nil {
+			message = fmt.Sprintf("<@%s>, you're already in this order as %s, please let the host know if it's a mistake", req.FromUserID, rate.WoltName)
+		}
+	}
+	if _, err := h.informEvent(req.Channel, message, "", req.MessageID); err != nil {
+		h.logger.Error("Error acknowledging skip", "group_id", activeOrder.ID, "error", err)
+	}
+}
+
+// skipperRate returns the rate of the skipping user in the order, or nil if the user isn't in it
+func skipperRate(groupRate GroupRate, userID string) *Rate {
+	for i, rate := range groupRate.Rates {
+		if rate.User != nil && rate.User.TransportID == userID {
+			return &groupRate.Rates[i]
+		}
+	}
+	return nil
+}
+
+// flagSkippers tells the host about users who reacted that they're skipping the order, but are in it anyway
+func (h *Service) flagSkippers(channel, messageID string, groupRate GroupRate) {
+	mismatches := make([]string, 0)
+	for _, skipper := range h.skips.get(channel, messageID) {
+		if rate := skipperRate(groupRate, skipper); rate != nil {
+			mismatches = append(mismatches, fmt.Sprintf("<@%s> (as %s)", skipper, rate.WoltName))
+		}
+	}
+	if len(mismatches) == 0 {
+		return
+	}
+
+	message := fmt.Sprintf("Heads up, %s said they're skipping the order, but they're in it. Please make sure it's not a mistake", strings.Join(mismatches, ", "))
+	receiver, threadID := channel, messageID
+	if groupRate.HostUser != nil {
+		receiver, threadID = groupRate.HostUser.TransportID, ""
+	}
+	if _, err := h.informEvent(receiver, message, "", threadID); err != nil {
+		h.logger.Error("Error flagging skippers in order", "channel", channel, "message_id", messageID, "error", err)
+	}
+}
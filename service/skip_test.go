@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSkipOrder(t *testing.T) {
+	t.Parallel()
+
+	notification := &recordingNotification{}
+	h, err := New(Config{SkipOrderEmoji: "no_entry_sign"}, nil, nil, nil, "U-bot", notification)
+	require.NoError(t, err)
+
+	skip := ReactionAddRequest{Reaction: "no_entry_sign", FromUserID: "U1", Channel: "C1", MessageUserID: "U-host", MessageID: "1.1"}
+	_, err = h.HandleReactionAdded(skip)
+	require.NoError(t, err)
+	assert.Empty(t, notification.messages, "reactions to messages of untracked orders should be ignored")
+
+	h.hooks.Emit(context.Background(), Event{Type: EventOrderJoined, OrderID: "A", Channel: "C1", MessageID: "1.1"})
+	_, err = h.HandleReactionAdded(skip)
+	require.NoError(t, err)
+	_, err = h.HandleReactionAdded(skip)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"C1: Got it <@U1>, you're skipping this order :wave:"}, notification.messages)
+
+	host := &userDomain.User{ID: "U-host", TransportID: "U-host"}
+	groupRate := GroupRate{
+		HostWoltUser: "Thor",
+		HostUser:     host,
+		Rates: []Rate{
+			{WoltName: "Loki", User: &userDomain.User{ID: "U1", TransportID: "U1"}, Amount: 10},
+			{WoltName: "Thor", User: host, Amount: 20},
+		},
+	}
+	h.flagSkippers("C1", "1.1", groupRate)
+	require.Len(t, notification.messages, 2)
+	assert.Equal(t, "U-host: Heads up, <@U1> (as Loki) said they're skipping the order, but they're in it. Please make sure it's not a mistake", notification.messages[1])
+
+	h.hooks.Emit(context.Background(), Event{Type: EventRatesPublished, OrderID: "A", Rates: &groupRate})
+	_, err = h.HandleReactionAdded(ReactionAddRequest{Reaction: "no_entry_sign", FromUserID: "U-host", Channel: "C1", MessageID: "1.1"})
+	require.NoError(t, err)
+	assert.Equal(t, "C1: <@U-host>, you're already in this order as Thor, please let the host know if it's a mistake", notification.messages[2])
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	userDomain "github.com/oriser/bolt/user"
+)
+
+// smartTimingRetryInterval is how often the debt workers retry the reminders deferred until the borrowers are active
+const smartTimingRetryInterval = 10 * time.Minute
+
+// errReminderDeferred is returned when reminding a borrower who isn't likely to be active right now
+var errReminderDeferred = errors.New("the reminder is deferred until the borrower is active")
+
+// PresenceChecker is implemented by notification layers which can tell whether a user is currently active
+type PresenceChecker interface {
+	UserActive(userID string) (bool, error)
+}
+
+// userActivity keeps the hours (in UTC) each user was seen active in, for guessing when users are active without their presence,
+// and the reminders deferred until the borrowers are active
+type userActivity struct {
+	lock     sync.Mutex
+	hours    map[string]*[24]int
+	deferred map[string]time.Time // When the reminder of each debt (by ID) was first deferred
+}
+
+func newUserActivity() *userActivity {
+	return &userActivity{hours: make(map[string]*[24]int), deferred: make(map[string]time.Time)}
+}
+
+func (a *userActivity) record(transportID string, at time.Time) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	hours, ok := a.hours[transportID]
+	if !ok {
+		hours = &[24]int{}
+		a.hours[transportID] = hours
+	}
+	hours[at.UTC().Hour()]++
+}
+
+// typicallyActive returns whether the user was seen active in the hour of the given time, or true if the user was never seen active
+func (a *userActivity) typicallyActive(transportID string, at time.Time) bool {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	hours, ok := a.hours[transportID]
+	if !ok {
+		return true
+	}
+	return hours[at.UTC().Hour()] > 0
+}
+
+// deferReminder marks the reminder of the debt as deferred, and returns how long it's been deferred
+func (a *userActivity) deferReminder(debtID string, now time.Time) time.Duration {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	since, ok := a.deferred[debtID]
+	if !ok {
+		a.deferred[debtID] = now
+		return 0
+	}
+	return now.Sub(since)
+}
+
+func (a *userActivity) reminderDeferred(debtID string) bool {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	_, ok := a.deferred[debtID]
+	return ok
+}
+
+func (a *userActivity) clearDeferred(debtID string) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	delete(a.deferred, debtID)
+}
+
+// deferredDebts returns the debts whose reminders are deferred until the borrowers are active
+func (h *Service) deferredDebts(debts []*debtDomain.Debt) []*debtDomain.Debt {
+	deferred := make([]*debtDomain.Debt, 0)
+	for _, debt := range debts {
+		if h.activity.reminderDeferred(debt.ID) {
+			deferred = append(deferred, debt)
+		}
+	}
+	return deferred
+}
+
+// recordActivity notes that the user (by transport ID) is active now
+func (h *Service) recordActivity(transportID string) {
+	if transportID == "" || transportID == h.selfID {
+		return
+	}
+	h.activity.record(transportID, time.Now())
+}
+
+// borrowerLikelyActive returns whether the borrower is active according to their presence, or, if the notification layer
+// can't tell, whether they're usually active at this hour
+func (h *Service) borrowerLikelyActive(borrower *userDomain.User) bool {
+	if checker, ok := h.eventNotification.(PresenceChecker); ok {
+		active, err := checker.UserActive(borrower.TransportID)
+		if err == nil {
+			if active {
+				h.recordActivity(borrower.TransportID)
+			}
+			return active
+		}
+		h.logger.Error("Error checking the presence", "transport_id", borrower.TransportID, "error", err)
+	}
+	return h.activity.typicallyActive(borrower.TransportID, time.Now())
+}
+
+// deferReminder returns whether to defer the reminder of the debt until the borrower is active, with
+// DEBT_REMINDER_SMART_TIMING. Reminders aren't deferred for more than DEBT_REMINDER_SMART_TIMING_MAX_DELAY.
+func (h *Service) deferReminder(debtID string, borrower *userDomain.User) bool {
+	if !h.cfg.DebtReminderSmartTiming {
+		return false
+	}
+	if h.borrowerLikelyActive(borrower) {
+		return false
+	}
+	return h.activity.deferReminder(debtID, time.Now()) < h.cfg.DebtReminderMaxDelay
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type presenceNotification struct {
+	recordingNotification
+	active map[string]bool
+}
+
+func (p *presenceNotification) UserActive(userID string) (bool, error) {
+	return p.active[userID], nil
+}
+
+func TestRemindDebtsSmartTiming(t *testing.T) {
+	t.Parallel()
+
+	tz := daytimeTimezone()
+	notification := &presenceNotification{active: map[string]bool{"U1": true}}
+	store := &fakeTreasuryStore{users: map[string]*userDomain.User{
+		"uuid-host": {ID: "uuid-host", FullName: "Thor", TransportID: "U-host", Timezone: tz},
+		"U1":        {ID: "U1", FullName: "Loki", TransportID: "U1", Timezone: tz},
+		"U2":        {ID: "U2", FullName: "Odin", TransportID: "U2", Timezone: tz},
+	}}
+	h, err := New(Config{DebtReminderSmartTiming: true, DebtReminderMaxDelay: time.Hour},
+		store, store, nil, "U-bot", notification)
+	require.NoError(t, err)
+
+	debts := []*debtDomain.Debt{
+		{ID: "d1", BorrowerID: "U1", LenderID: "uuid-host", OrderID: "A", Amount: 30, CreatedAt: time.Now()},
+		{ID: "d2", BorrowerID: "U2", LenderID: "uuid-host", OrderID: "A", Amount: 20, CreatedAt: time.Now()},
+	}
+	h.remindDebts(context.Background(), debts)
+	require.Len(t, notification.messages, 1)
+	assert.True(t, strings.HasPrefix(notification.messages[0], "U1: Reminder"))
+	assert.Equal(t, []*debtDomain.Debt{debts[1]}, h.deferredDebts(debts), "the reminder of the away borrower should be deferred")
+
+	notification.active["U2"] = true
+	h.remindDebts(context.Background(), h.deferredDebts(debts))
+	require.Len(t, notification.messages, 2)
+	assert.True(t, strings.HasPrefix(notification.messages[1], "U2: Reminder"))
+	assert.Empty(t, h.deferredDebts(debts))
+
+	// Reminders aren't deferred for more than the maximum delay
+	notification.active["U2"] = false
+	h.remindDebts(context.Background(), debts[1:])
+	require.Len(t, notification.messages, 2)
+	h.activity.deferred["d2"] = time.Now().Add(-2 * time.Hour)
+	h.remindDebts(context.Background(), h.deferredDebts(debts))
+	require.Len(t, notification.messages, 3)
+	assert.True(t, strings.HasPrefix(notification.messages[2], "U2: Reminder"))
+}
+
+func TestUserActivityTypicallyActive(t *testing.T) {
+	t.Parallel()
+
+	activity := newUserActivity()
+	morning := time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)
+	assert.True(t, activity.typicallyActive("U1", morning), "users who were never seen active shouldn't be held back")
+
+	activity.record("U1", morning)
+	assert.True(t, activity.typicallyActive("U1", morning.Add(24*time.Hour+10*time.Minute)))
+	assert.False(t, activity.typicallyActive("U1", morning.Add(5*time.Hour)))
+	assert.True(t, activity.typicallyActive("U2", morning.Add(5*time.Hour)))
+}
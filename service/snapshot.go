@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oriser/bolt/order"
+)
+
+// ErrOtherChannelSnapshot is returned for the snapshot of an order asked for outside the channel its rates were published in
+var ErrOtherChannelSnapshot = errors.New("the rates of the order were published in another channel, ask for its snapshot there")
+
+func (h *Service) ratesSnapshotStore() (order.RatesSnapshotStore, error) {
+	snapshotStore, ok := h.orderStore.(order.RatesSnapshotStore)
+	if !ok {
+		return nil, fmt.Errorf("rates snapshots are not supported")
+	}
+	return snapshotStore, nil
+}
+
+// saveRatesSnapshot keeps the published rates message of the order along with what its rates were computed from, for resolving
+// disputes about them later. Order stores which don't keep snapshots are skipped.
+func (h *Service) saveRatesSnapshot(ctx context.Context, channel, ratesMessageID, groupID string, groupRate GroupRate, message string) {
+	snapshotStore, err := h.ratesSnapshotStore()
+	if err != nil {
+		return
+	}
+	// The amounts of the participants who keep them private are left out, as the snapshot can be shown to anyone in the channel
+	public := make(map[string]float64, len(groupRate.ItemRates))
+	for name, amount := range groupRate.ItemRates {
+		if rate := rateByName(groupRate, name); rate == nil || !rate.Private {
+			public[name] = amount
+		}
+	}
+	itemRates, err := json.Marshal(public)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "Error encoding the item rates of the snapshot", "error", err)
+		return
+	}
+	feeAllocation := h.cfg.FeeAllocationStrategy
+	if name, ok := h.channelSetting(channel, settingFeeAllocation); ok {
+		feeAllocation = name
+	}
+	snapshot := &order.RatesSnapshot{
+		OriginalID:         groupID,
+		Receiver:           channel,
+		MessageID:          ratesMessageID,
+		PublishedAt:        time.Now(),
+		Message:            message,
+		ItemRates:          string(itemRates),
+		Host:               groupRate.HostWoltUser,
+		DeliveryRate:       groupRate.DeliveryRate,
+		Discount:           groupRate.Discount,
+		FeeAllocation:      feeAllocation,
+		DiscountAllocation: string(h.discountAllocation),
+		AmountRounding:     h.cfg.AmountRounding,
+		RoundingRemainder:  string(h.roundingRemainder),
+		Currency:           groupRate.Currency,
+	}
+
+	storeCtx, cancel := h.storeContext()
+	defer cancel()
+	if err := snapshotStore.SaveRatesSnapshot(storeCtx, snapshot); err != nil {
+		h.logger.ErrorContext(ctx, "Error saving the rates snapshot", "error", err)
+	}
+}
+
+// RatesSnapshot returns the snapshot of the rates of the order as they were published: the rates message, the amounts of the
+// participants' items and the settings they were split by. It's only shown in the channel the rates were published in.
+func (h *Service) RatesSnapshot(ctx context.Context, channel, groupID string) (string, error) {
+	snapshotStore, err := h.ratesSnapshotStore()
+	if err != nil {
+		return "", err
+	}
+	groupID = h.parseGroupIDOrLink(groupID)
+	snapshot, err := snapshotStore.GetRatesSnapshot(ctx, groupID)
+	if err != nil {
+		return "", fmt.Errorf("get rates snapshot: %w", err)
+	}
+	if snapshot == nil {
+		return "", fmt.Errorf("I have no snapshot of the rates of order %s", groupID)
+	}
+	if snapshot.Receiver != channel {
+		return "", ErrOtherChannelSnapshot
+	}
+
+	itemRates := make(map[string]float64)
+	if err := json.Unmarshal([]byte(snapshot.ItemRates), &itemRates); err != nil {
+		return "", fmt.Errorf("decode item rates: %w", err)
+	}
+	currency := h.currencyName(snapshot.Receiver, snapshot.Currency)
+	publishedAt := snapshot.PublishedAt.In(h.timezoneForChannel(snapshot.Receiver, time.UTC))
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("The rates of order %s as published in <#%s> on %s:\n", groupID, snapshot.Receiver, publishedAt.Format("2006-01-02 15:04")))
+	for _, line := range strings.Split(strings.TrimSuffix(snapshot.Message, "\n"), "\n") {
+		sb.WriteString("> " + line + "\n")
+	}
+	sb.WriteString("\nThe amounts of the items, before the discounts and fees:\n")
+	for _, person := range getSortedKeys(itemRates) {
+		sb.WriteString(fmt.Sprintf("%s: %.2f\n", person, itemRates[person]))
+	}
+	rounding := "off"
+	if snapshot.AmountRounding > 0 {
+		rounding = fmt.Sprintf("to %s %s, the difference by %s", strconv.FormatFloat(snapshot.AmountRounding, 'f', -1, 64), currency,
+			snapshot.RoundingRemainder)
+	}
+	sb.WriteString(fmt.Sprintf("\nHost: %s\nDelivery: %d %s, split by %s\nDiscount: %.2f %s, by %s\nRounding: %s\n", snapshot.Host,
+		snapshot.DeliveryRate, currency, snapshot.FeeAllocation, snapshot.Discount, currency, snapshot.DiscountAllocation, rounding))
+	return sb.String(), nil
+}
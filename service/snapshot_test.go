@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/oriser/bolt/order"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSnapshotStore struct {
+	fakeOrderStore
+	snapshots map[string]*order.RatesSnapshot
+}
+
+func (f *fakeSnapshotStore) SaveRatesSnapshot(_ context.Context, snapshot *order.RatesSnapshot) error {
+	if _, ok := f.snapshots[snapshot.OriginalID]; !ok {
+		f.snapshots[snapshot.OriginalID] = snapshot
+	}
+	return nil
+}
+
+func (f *fakeSnapshotStore) GetRatesSnapshot(_ context.Context, originalID string) (*order.RatesSnapshot, error) {
+	return f.snapshots[originalID], nil
+}
+
+func TestRatesSnapshot(t *testing.T) {
+	t.Parallel()
+
+	store := &fakeSnapshotStore{snapshots: make(map[string]*order.RatesSnapshot)}
+	h, err := New(Config{FeeAllocationStrategy: "proportional", DontJoinAfterTZ: "UTC"}, &fakeTreasuryStore{}, nil, store, "UBOT",
+		&recordingNotification{})
+	require.NoError(t, err)
+
+	groupRate := h.buildGroupRates(map[string]float64{"Loki": 27, "Thor": 45}, "Thor", 10)
+	groupRate.ItemRates = map[string]float64{"Loki": 30, "Thor": 50}
+	groupRate.Discount = 8
+	message := h.buildRatesMessage("C1", groupRate, "ABC")
+	h.saveRatesSnapshot(context.Background(), "C1", "1.3", "ABC", groupRate, message)
+	h.saveRatesSnapshot(context.Background(), "C1", "1.4", "ABC", groupRate, "changed")
+	require.Contains(t, store.snapshots, "ABC")
+	snapshot := store.snapshots["ABC"]
+	assert.Equal(t, "1.3", snapshot.MessageID, "a snapshot isn't replaced")
+	assert.Equal(t, message, snapshot.Message)
+	assert.Equal(t, `{"Loki":30,"Thor":50}`, snapshot.ItemRates)
+
+	text, err := h.RatesSnapshot(context.Background(), "C1", "<https://wolt.com/en/group/abc>")
+	require.NoError(t, err)
+	assert.Contains(t, text, "The rates of order ABC as published in <#C1> on "+snapshot.PublishedAt.UTC().Format("2006-01-02 15:04")+":\n> Rates for Wolt order ID ABC")
+	assert.Contains(t, text, "\nThe amounts of the items, before the discounts and fees:\nLoki: 30.00\nThor: 50.00\n")
+	assert.Contains(t, text, "\nHost: Thor\nDelivery: 10 NIS, split by proportional\nDiscount: 8.00 NIS, by proportional\nRounding: off\n")
+
+	_, err = h.RatesSnapshot(context.Background(), "C1", "XYZ")
+	assert.EqualError(t, err, "I have no snapshot of the rates of order XYZ")
+
+	_, err = h.RatesSnapshot(context.Background(), "C2", "ABC")
+	assert.ErrorIs(t, err, ErrOtherChannelSnapshot, "the snapshot isn't shown outside the order's channel")
+}
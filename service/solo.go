@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/oriser/bolt/logging"
+	"github.com/oriser/bolt/tracing"
+	"github.com/oriser/bolt/wolt"
+)
+
+// soloOrders keeps the tracking IDs of the solo (not group) orders being followed, so a tracking link shared twice is followed once
+type soloOrders struct {
+	lock      sync.Mutex
+	following map[string]bool
+}
+
+func newSoloOrders() *soloOrders {
+	return &soloOrders{following: make(map[string]bool)}
+}
+
+// start marks the order as followed, and returns false if it already was
+func (s *soloOrders) start(trackingID string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.following[trackingID] {
+		return false
+	}
+	s.following[trackingID] = true
+	return true
+}
+
+func (s *soloOrders) done(trackingID string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.following, trackingID)
+}
+
+// getWoltTrackingIDs returns the distinct tracking IDs of the solo orders in the links, by their order in the message
+func getWoltTrackingIDs(links []Link) []string {
+	trackingIDs := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, link := range links {
+		trackingID, ok := wolt.TrackingIDFromLink(link.URL)
+		if !ok || seen[trackingID] {
+			continue
+		}
+		seen[trackingID] = true
+		trackingIDs = append(trackingIDs, trackingID)
+	}
+	return trackingIDs
+}
+
+// followSoloOrders follows the delivery of the solo orders of the tracking links, each of them separately
+func (h *Service) followSoloOrders(ctx context.Context, req LinksRequest, trackingIDs []string) error {
+	errs := make([]error, len(trackingIDs))
+	var wg sync.WaitGroup
+	for i, trackingID := range trackingIDs {
+		wg.Add(1)
+		go func(i int, trackingID string) {
+			defer wg.Done()
+			errs[i] = h.followSoloOrder(req, trackingID)
+		}(i, trackingID)
+	}
+	wg.Wait()
+
+	var firstErr error
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		if firstErr == nil {
+			firstErr = fmt.Errorf("solo order %s: %w", trackingIDs[i], err)
+		} else {
+			h.logger.ErrorContext(ctx, "Error following solo order", "tracking_id", trackingIDs[i], "error", err)
+		}
+	}
+	return firstErr
+}
+
+// followSoloOrder follows the delivery of a solo order until it arrives, posting the "get ready" and arrival messages (and the
+// progress of DELIVERY_UPDATES) in the thread of its tracking link. Solo orders have a single participant, so there are no rates
+// or debts.
+func (h *Service) followSoloOrder(req LinksRequest, trackingID string) error {
+	if !h.soloOrders.start(trackingID) {
+		return nil
+	}
+	defer h.soloOrders.done(trackingID)
+
+	ctx, span := tracing.Start(tracing.Extract(context.Background(), req.TraceParent), "followSoloOrder", tracing.String("tracking_id", trackingID))
+	defer span.End()
+	ctx = logging.With(ctx, "channel", req.Channel, "message_id", req.MessageID, "tracking_id", trackingID)
+
+	if err := h.addReaction(req.Channel, req.MessageID, h.channelEmoji(req.Channel, settingJoinedOrderEmoji, h.cfg.JoinedOrderEmoji)); err != nil {
+		var scopeErr *MissingScopeError
+		if !errors.As(err, &scopeErr) {
+			h.logger.ErrorContext(ctx, "Error reacting to the tracking link message", "error", err)
+			return errWontJoin
+		}
+		h.reportMissingScope(req.Channel, scopeErr)
+	}
+	if _, err := h.informEventContext(ctx, req.Channel, h.text(req.Channel, msgSoloTracking), "", req.MessageID); err != nil {
+		return errWontJoin
+	}
+
+	addr, retryConfig := h.woltConfig()
+	tracking, err := wolt.NewTracking(addr, retryConfig, trackingID)
+	if err != nil {
+		return fmt.Errorf("new tracking: %w", err)
+	}
+
+	deliveryCtx, cancel := context.WithTimeout(logging.CopyAttrs(tracing.ContextWithSpan(h.lifetime(), span), ctx), h.cfg.OrderDoneTimeout)
+	defer cancel()
+	defer h.trackMonitor(deliveryCtx, monitorKindSoloDelivery, trackingID)()
+	deliveryStart := time.Now()
+	defer observeMonitoring("solo_delivery", deliveryStart)
+	err = h.monitorSoloDelivery(deliveryCtx, req.Channel, req.MessageID, tracking)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrWaitTimeout):
+		_, _ = h.informEventContext(ctx, req.Channel, h.text(req.Channel, msgTimedOutDone), "", req.MessageID)
+		return nil
+	case errors.Is(err, ErrOrderCanceled):
+		_, _ = h.informEventContext(ctx, req.Channel, h.text(req.Channel, msgSoloCanceled), "", req.MessageID)
+		return nil
+	}
+	span.SetError(err)
+	_, _ = h.informEventContext(ctx, req.Channel, h.text(req.Channel, msgSoloStatusFailed), "", req.MessageID)
+	return fmt.Errorf("error in waiting for solo order to arrive: %w", err)
+}
+
+func (h *Service) monitorSoloDelivery(ctx context.Context, channel, messageID string, tracking *wolt.Tracking) error {
+	fetch := func() (*OrderDetails, error) {
+		details, err := tracking.Details(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return woltOrderDetails(details), nil
+	}
+	details, err := fetch()
+	if err != nil {
+		return fmt.Errorf("get tracking details: %w", err)
+	}
+
+	stateMachine := NewDeliveryStateMachine(h.cfg.TimeTillGetReadyMessage)
+	stateMachine.OnGetReady(func(details *OrderDetails, timeToDelivery time.Duration) {
+		etaString := SlackDate(details.DeliveryEta, "{time}", "15:04", h.timezoneForChannel(channel, nil))
+		_, _ = h.informEvent(channel, h.text(channel, msgDeliverySoon, etaString, RelativeTime(details.DeliveryEta, time.Now())), "", messageID)
+	})
+	updater := h.newDeliveryUpdater(channel, messageID)
+	stateMachine.OnTransition(updater.onTransition)
+	stateMachine.OnTransition(func(transition DeliveryTransition) {
+		if transition.To == DeliveryStateDelivered && !stateMachine.GetReadySent() {
+			_, _ = h.informEvent(channel, h.text(channel, msgDeliveryArrived), "", messageID)
+		}
+	})
+
+	for {
+		now := time.Now()
+		switch stateMachine.Advance(details, now) {
+		case DeliveryStateDelivered:
+			return nil
+		case DeliveryStateCanceled:
+			return ErrOrderCanceled
+		}
+		updater.onDetails(details, now)
+
+		if details, err = h.pollWolt(ctx, ctx, h.cfg.WaitBetweenStatusCheck, fetch); err != nil {
+			return err
+		}
+	}
+}
+
+// soloTrackingIDs returns the tracking IDs of the solo orders of the links, if SOLO_ORDERS is enabled
+func (h *Service) soloTrackingIDs(links []Link) []string {
+	if !h.cfg.SoloOrders {
+		return nil
+	}
+	return getWoltTrackingIDs(links)
+}
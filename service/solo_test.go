@@ -0,0 +1,67 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetWoltTrackingIDs(t *testing.T) {
+	t.Parallel()
+
+	links := []Link{
+		{URL: "https://wolt.com/en/s/track/aB3x9"},
+		{URL: "https://wolt.com/en/group/ABC123"},
+		{URL: "https://wolt.com/he/s/track/aB3x9"},
+		{URL: "https://wolt.com/en/s/track/Zz7"},
+	}
+	assert.Equal(t, []string{"aB3x9", "Zz7"}, getWoltTrackingIDs(links))
+}
+
+func TestFollowSoloOrder(t *testing.T) {
+	t.Parallel()
+
+	var polls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/order_tracking/aB3x9" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		status := "production"
+		if atomic.AddInt32(&polls, 1) > 1 {
+			status = "delivered"
+		}
+		_, _ = w.Write([]byte(`{"status": "purchased", "purchase": {"delivery_status": "` + status + `"}}`))
+	}))
+	defer server.Close()
+
+	notification := &recordingNotification{}
+	h, err := New(Config{WoltBaseAddr: server.URL, WoltApiBaseAddr: server.URL, SoloOrders: true,
+		WaitBetweenStatusCheck: time.Millisecond, OrderDoneTimeout: time.Minute, WoltPollFailureTimeout: time.Minute},
+		nil, nil, nil, "UBOT", notification)
+	require.NoError(t, err)
+
+	response, err := h.handleLinks(LinksRequest{Channel: "C1", MessageID: "1.1", Links: []Link{{URL: "https://wolt.com/en/s/track/aB3x9"}}})
+	require.NoError(t, err)
+	assert.Empty(t, response)
+	assert.Equal(t, []string{
+		"C1: " + h.text("C1", msgSoloTracking),
+		"C1: Delivery arrived",
+	}, notification.messages)
+
+	notification.messages = nil
+	_, err = h.handleLinks(LinksRequest{Channel: "C1", MessageID: "2.2", Links: []Link{{URL: "https://wolt.com/en/s/track/missing"}}})
+	assert.Error(t, err)
+	assert.Len(t, notification.messages, 2, "the channel is told the order can't be followed")
+
+	h.cfg.SoloOrders = false
+	notification.messages = nil
+	_, err = h.handleLinks(LinksRequest{Channel: "C1", MessageID: "3.3", Links: []Link{{URL: "https://wolt.com/en/s/track/aB3x9"}}})
+	require.NoError(t, err)
+	assert.Empty(t, notification.messages)
+}
@@ -0,0 +1,298 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	"github.com/oriser/bolt/order"
+	userDomain "github.com/oriser/bolt/user"
+)
+
+const (
+	SplitKeyword = "split"
+	// splitDebtsFlag makes the split set the debts of the order in whose thread it was sent
+	splitDebtsFlag = "+debts"
+)
+
+const splitUsage = "Usage: `@Bolt split <total> between @a @b @c [+delivery <amount>] [+service <amount>] [+tip <amount>] " +
+	"[-discount <amount>] [+debts]`, or `@Bolt split @a <amount> @b <amount> ...` for the amount of each"
+
+var participantMentionRe = regexp.MustCompile(`^<@([A-Za-z0-9]+)>$`)
+
+// splitParticipant is a participant of an ad-hoc split, mentioned or written by name
+type splitParticipant struct {
+	name        string // How the participant is shown, the mention itself for mentioned participants
+	transportID string // Empty for participants written by name
+	amount      *float64
+}
+
+// splitRequest is an ad-hoc split, like "split 230 between @a @b @c +delivery 25"
+type splitRequest struct {
+	total        *float64 // Split evenly between the participants, nil if each participant has their own amount
+	participants []splitParticipant
+	fees         Fees
+	debts        bool
+}
+
+// parseSplit parses the text following the split keyword
+func parseSplit(text string) (splitRequest, error) {
+	var split splitRequest
+	seen := make(map[string]bool)
+	fields := strings.Fields(text)
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		switch lower := strings.ToLower(field); lower {
+		case "between", "among":
+			continue
+		case splitDebtsFlag:
+			split.debts = true
+			continue
+		case "+delivery", "+service", "+tip", "-discount":
+			if i+1 == len(fields) {
+				return splitRequest{}, fmt.Errorf("%s needs an amount", lower)
+			}
+			i++
+			amount, err := parseSplitAmount(fields[i])
+			if err != nil {
+				return splitRequest{}, err
+			}
+			switch lower {
+			case "+delivery":
+				split.fees.Delivery += amount
+			case "+service":
+				split.fees.Service += amount
+			case "+tip":
+				split.fees.Tip += amount
+			case "-discount":
+				split.fees.Discount += amount
+			}
+			continue
+		}
+
+		if amount, err := parseSplitAmount(field); err == nil {
+			last := len(split.participants) - 1
+			switch {
+			case last < 0 && split.total == nil:
+				split.total = &amount
+			case last >= 0 && split.participants[last].amount == nil:
+				split.participants[last].amount = &amount
+			default:
+				return splitRequest{}, fmt.Errorf("%s doesn't belong to anyone", field)
+			}
+			continue
+		}
+		if strings.HasPrefix(field, "+") || strings.HasPrefix(field, "-") {
+			return splitRequest{}, fmt.Errorf("unknown option %s", field)
+		}
+
+		participant := splitParticipant{name: strings.TrimPrefix(field, "@")}
+		if match := participantMentionRe.FindStringSubmatch(field); match != nil {
+			participant.transportID = match[1]
+		}
+		if seen[participant.name] {
+			return splitRequest{}, fmt.Errorf("%s is in the split more than once", participant.name)
+		}
+		seen[participant.name] = true
+		split.participants = append(split.participants, participant)
+	}
+
+	if len(split.participants) == 0 {
+		return splitRequest{}, fmt.Errorf("no one to split between")
+	}
+	for _, participant := range split.participants {
+		if (split.total == nil) == (participant.amount == nil) {
+			return splitRequest{}, fmt.Errorf("either split a total between everyone, or give the amount of each of them")
+		}
+	}
+	return split, nil
+}
+
+func parseSplitAmount(value string) (float64, error) {
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil || amount < 0 {
+		return 0, fmt.Errorf("%q isn't an amount", value)
+	}
+	return amount, nil
+}
+
+// splitRates returns the amount of each participant, with the fees allocated by the channel's FEE_ALLOCATION_STRATEGY like the
+// fees of orders. The one who sent the split is the host of the allocation.
+func (h *Service) splitRates(channel, host string, split splitRequest) map[string]float64 {
+	rates := make(map[string]float64, len(split.participants))
+	for _, participant := range split.participants {
+		if split.total != nil {
+			rates[participant.name] = *split.total / float64(len(split.participants))
+		} else {
+			rates[participant.name] = *participant.amount
+		}
+	}
+	return h.channelFeeAllocator(channel).Allocate(rates, host, split.fees)
+}
+
+// handleSplitMention handles "@Bolt split ...", a calculator for correcting the split of an order by hand, like an item the
+// participants shared. It replies with a mini rates message, and with the +debts flag in the thread of an order, sets the
+// debts of the mentioned participants for the order to the split amounts.
+func (h *Service) handleSplitMention(req MentionRequest, text string) (string, error) {
+	split, err := parseSplit(text)
+	if err != nil {
+		return fmt.Sprintf("Couldn't split: %s. %s", err, splitUsage), nil
+	}
+
+	ctx, cancel := h.storeContext()
+	defer cancel()
+	threadID := req.ThreadID
+	if threadID == "" {
+		threadID = req.MessageID
+	}
+	orderID, currency := h.threadOrder(ctx, req.Channel, req.ThreadID)
+	host := "<@" + req.UserID + ">"
+	rates := h.splitRates(req.Channel, host, split)
+
+	var sb strings.Builder
+	total := 0.0
+	for _, amount := range rates {
+		total += amount
+	}
+	sb.WriteString(h.text(req.Channel, msgSplitHeader, total, h.currencyName(req.Channel, currency), split.fees.Total()))
+	names := make([]string, 0, len(rates))
+	for _, participant := range split.participants {
+		names = append(names, participant.name)
+	}
+	if _, ok := rates[host]; ok && len(rates) > len(split.participants) {
+		// The one who sent the split absorbed the fees without being one of the participants
+		names = append(names, host)
+	}
+	for _, name := range names {
+		sb.WriteString(h.text(req.Channel, msgRateLine, name, rates[name]))
+		sb.WriteString("\n")
+	}
+
+	if split.debts {
+		if orderID == "" {
+			return "Send the split in the thread of an order to set its debts", nil
+		}
+		lender, skipped, err := h.setSplitDebts(ctx, req, orderID, currency, split, rates)
+		if errors.Is(err, ErrNotOrderHost) {
+			return fmt.Sprintf("Couldn't set the debts: %s", err), nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("set split debts: %w", err)
+		}
+		sb.WriteString(h.text(req.Channel, msgSplitDebts, lender.TransportID))
+		if len(skipped) > 0 {
+			sb.WriteString(fmt.Sprintf("I didn't set the debts of %s, mention them to set their debts\n", strings.Join(skipped, ", ")))
+		}
+	}
+
+	if _, err := h.informEvent(req.Channel, sb.String(), "", threadID); err != nil {
+		return "", fmt.Errorf("post split: %w", err)
+	}
+	return "", nil
+}
+
+// threadOrder returns the Wolt group ID and the currency of the order the thread is of, or empty strings if it isn't of an order
+func (h *Service) threadOrder(ctx context.Context, channel, threadID string) (string, string) {
+	if threadID == "" {
+		return "", ""
+	}
+	if activeOrder := h.activeOrderByMessage(channel, threadID); activeOrder != nil {
+		if activeOrder.Rates != nil {
+			return activeOrder.ID, activeOrder.Rates.Currency
+		}
+		return activeOrder.ID, ""
+	}
+	if h.orderStore == nil {
+		return "", ""
+	}
+	orders, err := h.orderStore.ListOrders(ctx, order.ListFilter{Receiver: channel, MessageID: threadID, Limit: 1})
+	if err != nil {
+		h.logger.ErrorContext(ctx, "Error listing the order of a thread", "channel", channel, "message_id", threadID, "error", err)
+		return "", ""
+	}
+	if len(orders) == 0 {
+		return "", ""
+	}
+	return orders[0].OriginalID, orders[0].Currency
+}
+
+// setSplitDebts sets the debts of the mentioned participants for the order to their split amounts, adding the debts of those who
+// don't owe anything for it yet. The debts are to the host of the order's debts, who must be the one who sent the split (or their
+// co-host), or to the one who sent the split if the order has no debts. Returns the lender and the participants written by name,
+// whose debts can't be set.
+func (h *Service) setSplitDebts(ctx context.Context, req MentionRequest, orderID, currency string, split splitRequest,
+	rates map[string]float64) (*userDomain.User, []string, error) {
+	if h.debtStore == nil {
+		return nil, nil, fmt.Errorf("debts aren't tracked")
+	}
+	debts, err := h.debtStore.ListDebtsForOrderID(ctx, orderID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list debts: %w", err)
+	}
+	var lender *userDomain.User
+	if len(debts) > 0 {
+		if lender, err = h.getUser(debts[0].LenderID); err != nil {
+			return nil, nil, fmt.Errorf("get host user: %w", err)
+		}
+		if !h.actsForHost(lender.TransportID, req.UserID) {
+			return nil, nil, ErrNotOrderHost
+		}
+	} else {
+		lenders, err := h.userStore.ListUsers(ctx, userDomain.ListFilter{TransportID: req.UserID})
+		if err != nil {
+			return nil, nil, fmt.Errorf("list users: %w", err)
+		}
+		if len(lenders) == 0 {
+			return nil, nil, fmt.Errorf("<@%s> isn't known to Bolt", req.UserID)
+		}
+		lender = lenders[0]
+	}
+
+	existing := make(map[string]*debtDomain.Debt, len(debts))
+	for _, debt := range debts {
+		existing[debt.BorrowerID] = debt
+	}
+	skipped := make([]string, 0)
+	for _, participant := range split.participants {
+		if participant.transportID == lender.TransportID {
+			continue
+		}
+		if participant.transportID == "" {
+			skipped = append(skipped, participant.name)
+			continue
+		}
+		borrowers, err := h.userStore.ListUsers(ctx, userDomain.ListFilter{TransportID: participant.transportID})
+		if err != nil || len(borrowers) == 0 {
+			skipped = append(skipped, participant.name)
+			continue
+		}
+		amount := rates[participant.name]
+		if debt, ok := existing[borrowers[0].ID]; ok {
+			if amount > 0 {
+				err = h.replaceDebt(ctx, debt, func(d *debtDomain.Debt) { d.Amount = amount })
+			} else if err = h.debtStore.RemoveDebtInOrderID(ctx, orderID, debt.ID); err != nil {
+				err = fmt.Errorf("remove debt: %w", err)
+			} else {
+				h.readModels.removeDebt(debt)
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		if amount <= 0 {
+			continue
+		}
+		if _, err := h.createDebt(ctx, amount, currency, req.Channel, orderID, req.ThreadID, borrowers[0], lender); err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(debts) == 0 {
+		h.startDebtWorker(orderID)
+	}
+	return lender, skipped, nil
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"testing"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	"github.com/oriser/bolt/order"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSplit(t *testing.T) {
+	t.Parallel()
+
+	split, err := parseSplit("230 between <@U1> <@U2> Bob +delivery 25 +tip 5 -discount 10 +debts")
+	require.NoError(t, err)
+	require.NotNil(t, split.total)
+	assert.Equal(t, 230.0, *split.total)
+	require.Len(t, split.participants, 3)
+	assert.Equal(t, "U1", split.participants[0].transportID)
+	assert.Equal(t, "Bob", split.participants[2].name)
+	assert.Empty(t, split.participants[2].transportID)
+	assert.Equal(t, Fees{Delivery: 25, Tip: 5, Discount: 10}, split.fees)
+	assert.True(t, split.debts)
+
+	split, err = parseSplit("<@U1> 80 @Bob 70.5")
+	require.NoError(t, err)
+	assert.Nil(t, split.total)
+	assert.Equal(t, 70.5, *split.participants[1].amount)
+	assert.Equal(t, "Bob", split.participants[1].name)
+
+	for _, text := range []string{"", "230", "230 between <@U1> <@U1>", "230 between <@U1> 20", "<@U1> 20 <@U2>",
+		"230 between <@U1> +delivery", "230 between <@U1> +fee 5", "<@U1> -5"} {
+		_, err := parseSplit(text)
+		assert.Error(t, err, text)
+	}
+}
+
+func TestHandleSplitMention(t *testing.T) {
+	t.Parallel()
+
+	notification := &recordingNotification{}
+	store := &fakeTreasuryStore{
+		users: map[string]*userDomain.User{
+			"uuid-host": {ID: "uuid-host", FullName: "Thor", TransportID: "UHOST"},
+			"uuid-loki": {ID: "uuid-loki", FullName: "Loki", TransportID: "ULOKI"},
+			"uuid-odin": {ID: "uuid-odin", FullName: "Odin", TransportID: "UODIN"},
+		},
+		debts: []*debtDomain.Debt{
+			{ID: "d1", BorrowerID: "uuid-loki", LenderID: "uuid-host", OrderID: "ABC", Amount: 30, InitiatedTransportID: "C1", MessageID: "ts1"},
+		},
+	}
+	orders := &fakeOrderStore{orders: []*order.Order{{ID: "1", OriginalID: "ABC", Receiver: "C1", MessageID: "ts1", Currency: "ILS"}}}
+	h, err := New(Config{}, store, store, orders, "UBOT", notification)
+	require.NoError(t, err)
+	h.DisableDebtWorkers()
+
+	response, err := h.HandleMention(MentionRequest{Channel: "C1", MessageID: "ts2", UserID: "ULOKI",
+		Text: "<@UBOT> split 90 between <@ULOKI> <@UODIN> Bob +delivery 15"})
+	require.NoError(t, err)
+	assert.Empty(t, response)
+	assert.Equal(t, []string{"C1: :abacus: Split of 105.00 NIS (including 15.00 in fees):\n<@ULOKI>: 35.00\n<@UODIN>: 35.00\nBob: 35.00\n"},
+		notification.messages)
+	assert.Len(t, store.debts, 1, "debts are only set with +debts")
+
+	response, err = h.HandleMention(MentionRequest{Channel: "C1", MessageID: "ts2", UserID: "ULOKI", Text: "<@UBOT> split between"})
+	require.NoError(t, err)
+	assert.Contains(t, response, "Couldn't split: no one to split between")
+
+	response, err = h.HandleMention(MentionRequest{Channel: "C1", MessageID: "ts2", UserID: "ULOKI", Text: "<@UBOT> split 10 between <@UODIN> +debts"})
+	require.NoError(t, err)
+	assert.Equal(t, "Send the split in the thread of an order to set its debts", response)
+
+	response, err = h.HandleMention(MentionRequest{Channel: "C1", MessageID: "ts3", ThreadID: "ts1", UserID: "ULOKI",
+		Text: "<@UBOT> split 10 between <@UODIN> +debts"})
+	require.NoError(t, err)
+	assert.Equal(t, "Couldn't set the debts: "+ErrNotOrderHost.Error(), response)
+
+	notification.messages = nil
+	response, err = h.HandleMention(MentionRequest{Channel: "C1", MessageID: "ts3", ThreadID: "ts1", UserID: "UHOST",
+		Text: "<@UBOT> split <@UHOST> 20 <@ULOKI> 25 <@UODIN> 15 Bob 10 -discount 10 +debts"})
+	require.NoError(t, err)
+	assert.Empty(t, response)
+	assert.Equal(t, []string{"C1: :abacus: Split of 60.00 NIS (including -10.00 in fees):\n" +
+		"<@UHOST>: 17.50\n<@ULOKI>: 22.50\n<@UODIN>: 12.50\nBob: 7.50\n" +
+		"I set the debts to <@UHOST> to these amounts\n" +
+		"I didn't set the debts of Bob, mention them to set their debts\n"}, notification.messages)
+	require.Len(t, store.debts, 2)
+	assert.Equal(t, "d1", store.debts[0].ID, "the existing debt keeps its ID")
+	assert.Equal(t, 22.5, store.debts[0].Amount)
+	assert.Equal(t, "uuid-odin", store.debts[1].BorrowerID)
+	assert.Equal(t, 12.5, store.debts[1].Amount)
+	assert.Equal(t, "ILS", store.debts[1].Currency)
+	assert.Equal(t, "ts1", store.debts[1].MessageID)
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+)
+
+// splitDeliveryTracker posts in the thread of an order Wolt split into several deliveries which items are in each of them, and
+// each delivery which arrives before the last one. The arrival of the last one is the arrival of the order.
+type splitDeliveryTracker struct {
+	announced bool
+	arrived   map[int]bool
+	post      func(text string)
+	translate func(names []string) []string // Translates the item names to the channel's locale, nil keeps them as they are
+}
+
+func (h *Service) newSplitDeliveryTracker(initiatedTransport, messageID string) *splitDeliveryTracker {
+	return &splitDeliveryTracker{
+		arrived: make(map[int]bool),
+		post: func(text string) {
+			_, _ = h.informEvent(initiatedTransport, text, "", messageID)
+		},
+		translate: func(names []string) []string {
+			return h.translateItemNames(h.lifetime(), initiatedTransport, names)
+		},
+	}
+}
+
+func (t *splitDeliveryTracker) formatItems(items []Item) string {
+	names := make([]string, len(items))
+	for i, item := range items {
+		names[i] = item.Name
+	}
+	if t.translate != nil {
+		names = t.translate(names)
+	}
+	formatted := make([]string, len(items))
+	for i, item := range items {
+		formatted[i] = fmt.Sprintf("%d %s", item.Quantity(), names[i])
+	}
+	return strings.Join(formatted, ", ")
+}
+
+func (t *splitDeliveryTracker) onDetails(details *OrderDetails) {
+	if !details.IsSplitDelivery() {
+		return
+	}
+	deliveries := details.Deliveries
+
+	if !t.announced {
+		t.announced = true
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf(":package: The order ships in %d deliveries, Bolt will let you know when all of them arrive", len(deliveries)))
+		for i, delivery := range deliveries {
+			if len(delivery.Items) > 0 {
+				sb.WriteString(fmt.Sprintf("\nDelivery %d: %s", i+1, t.formatItems(delivery.Items)))
+			}
+		}
+		t.post(sb.String())
+	}
+
+	if details.IsDelivered() {
+		return
+	}
+	for i, delivery := range deliveries {
+		if !delivery.IsDelivered() || t.arrived[i] {
+			continue
+		}
+		t.arrived[i] = true
+		text := fmt.Sprintf(":package: Delivery %d of %d arrived", i+1, len(deliveries))
+		if len(delivery.Items) > 0 {
+			text += fmt.Sprintf(" (%s)", t.formatItems(delivery.Items))
+		}
+		t.post(text + ", waiting for the rest")
+	}
+}
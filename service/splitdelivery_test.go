@@ -0,0 +1,73 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oriser/bolt/wolt"
+	"github.com/stretchr/testify/assert"
+)
+
+func splitDetails(statuses ...wolt.DeliveryStatus) *OrderDetails {
+	details := &wolt.OrderDetails{Status: wolt.StatusPurchased}
+	details.DeliveryEta = time.Unix(0, 0)
+	details.Purchase.DeliveryStatus = statuses[0]
+	for i, status := range statuses {
+		delivery := wolt.Delivery{DeliveryStatus: status}
+		if i == 0 {
+			delivery.Items = []wolt.Item{{Name: "Pizza", Count: 2}, {Name: "Cola"}}
+		}
+		details.Purchase.Deliveries = append(details.Purchase.Deliveries, delivery)
+	}
+	return woltOrderDetails(details)
+}
+
+func TestSplitDelivery(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var posted []string
+	tracker := &splitDeliveryTracker{arrived: make(map[int]bool), post: func(text string) {
+		posted = append(posted, text)
+	}}
+	var transitions []DeliveryState
+	stateMachine := NewDeliveryStateMachine(0)
+	stateMachine.OnTransition(func(transition DeliveryTransition) {
+		transitions = append(transitions, transition.To)
+	})
+
+	steps := []*OrderDetails{
+		splitDetails("production", "production"),
+		splitDetails("fetched", "production"),
+		splitDetails(wolt.DeliveryStatusDelivered, "fetched"),
+		splitDetails(wolt.DeliveryStatusDelivered, "fetched"),
+		splitDetails(wolt.DeliveryStatusDelivered, wolt.DeliveryStatusDelivered),
+	}
+	for i, details := range steps {
+		at := now.Add(time.Duration(i) * time.Minute)
+		if stateMachine.Advance(details, at).Final() {
+			break
+		}
+		tracker.onDetails(details)
+	}
+
+	assert.Equal(t, []DeliveryState{DeliveryStateProduction, DeliveryStatePickup, DeliveryStateDelivered}, transitions,
+		"the order should be delivered only when all of its deliveries arrive")
+	assert.Equal(t, []string{
+		":package: The order ships in 2 deliveries, Bolt will let you know when all of them arrive\nDelivery 1: 2 Pizza, 1 Cola",
+		":package: Delivery 1 of 2 arrived (2 Pizza, 1 Cola), waiting for the rest",
+	}, posted)
+}
+
+func TestSplitDeliveryIgnoresSingleDelivery(t *testing.T) {
+	t.Parallel()
+
+	var posted []string
+	tracker := &splitDeliveryTracker{arrived: make(map[int]bool), post: func(text string) {
+		posted = append(posted, text)
+	}}
+	details := buildDetails(detailsStep{status: wolt.StatusPurchased, deliveryStatus: "production"}, time.Now())
+	tracker.onDetails(details)
+	assert.Empty(t, posted)
+	assert.Equal(t, DeliveryStateProduction, DeliveryStateFromDetails(details))
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/oriser/bolt/order"
+)
+
+// StatusPagePath is the path the public status pages of the orders are served under, as <path><order ID>/<token>
+const StatusPagePath = "/status/"
+
+// ErrStatusPageNotFound is returned for status pages of unknown orders and for invalid tokens, which aren't told apart so tokens can't
+// be guessed order by order
+var ErrStatusPageNotFound = errors.New("status page not found")
+
+// ErrStatusLinkNotAllowed is returned when the status link of an order is asked for outside its channel by someone other than its
+// host (or their co-host), an admin or a treasurer, since its page shows what everyone in the order pays
+var ErrStatusLinkNotAllowed = errors.New("the status link of an order is only given in its channel, to its host or to an admin")
+
+// StatusPage is what the public status page of an order shows, for guests who aren't in the chat workspace. It has the Wolt names
+// of the participants, and nothing which identifies them in the workspace.
+type StatusPage struct {
+	OrderID     string
+	VenueName   string
+	Status      string    // Describes the stage the order is in
+	DeliveryETA time.Time // Zero if unknown, or once the order was delivered
+	Provisional bool      // The group order is still open, so the amounts are from the current carts and may change
+	Currency    string
+	Amounts     []StatusPageAmount // Empty until the amounts are known
+	UpdatedAt   time.Time
+}
+
+// StatusPageAmount is what a participant pays for the order
+type StatusPageAmount struct {
+	Name   string // The participant's Wolt name
+	Amount float64
+}
+
+// Total returns the sum of the amounts
+func (p *StatusPage) Total() float64 {
+	total := 0.0
+	for _, amount := range p.Amounts {
+		total += amount.Amount
+	}
+	return total
+}
+
+// parseLinksSecret returns the secret the tokens of the links (like the status pages) are signed with. Without a secret a random one
+// is generated, so the links stop working after a restart.
+func parseLinksSecret(linksURL, secret, secretEnv string) ([]byte, error) {
+	if linksURL == "" {
+		return nil, nil
+	}
+	if secret != "" {
+		return []byte(secret), nil
+	}
+	generated := make([]byte, 32)
+	if _, err := rand.Read(generated); err != nil {
+		return nil, fmt.Errorf("generate secret: %w", err)
+	}
+	slog.Warn(secretEnv + " isn't set, the links signed with it won't survive restarts")
+	return generated, nil
+}
+
+// statusPageToken returns the token of the order's status page, which can't be derived without the secret
+func (h *Service) statusPageToken(groupID string) string {
+	mac := hmac.New(sha256.New, h.statusPageSecret)
+	mac.Write([]byte(groupID))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// StatusPageURL returns the public status page link of the tracked or stored order, which can be shared with guests. It's given in
+// the channel of the order, and elsewhere only to its host (or their co-host), to admins and to treasurers.
+func (h *Service) StatusPageURL(ctx context.Context, groupID, channel, fromTransportID string, admin bool) (string, error) {
+	if h.cfg.StatusPageURL == "" {
+		return "", fmt.Errorf("order status links are disabled, set STATUS_PAGE_URL to enable them")
+	}
+	groupID = h.parseGroupIDOrLink(groupID)
+	orderChannel, hostTransportID, ok := h.statusPageOrder(ctx, groupID)
+	if !ok {
+		return "", fmt.Errorf("I don't know order %s", groupID)
+	}
+	if channel != orderChannel && !admin && !h.IsTreasurer(fromTransportID) &&
+		(hostTransportID == "" || !h.actsForHost(hostTransportID, fromTransportID)) {
+		return "", ErrStatusLinkNotAllowed
+	}
+	return fmt.Sprintf("%s%s%s/%s", strings.TrimSuffix(h.cfg.StatusPageURL, "/"), StatusPagePath, groupID, h.statusPageToken(groupID)), nil
+}
+
+// statusPageOrder returns the channel of the tracked or stored order and the transport ID of its host (empty if it's unknown), or
+// false if there's no such order
+func (h *Service) statusPageOrder(ctx context.Context, groupID string) (channel, hostTransportID string, ok bool) {
+	if activeOrder, ok := h.LookupActiveOrder(groupID); ok {
+		if working := h.workingOrders.get(groupID); working != nil {
+			hostTransportID, _ = h.orderHostTransportID(working)
+		}
+		return activeOrder.Channel, hostTransportID, true
+	}
+	o := h.storedOrder(ctx, groupID)
+	if o == nil {
+		return "", "", false
+	}
+	if hosts, err := h.listUsersByName(o.Host); err == nil && len(hosts) == 1 {
+		hostTransportID = hosts[0].TransportID
+	}
+	return o.Receiver, hostTransportID, true
+}
+
+// OrderStatusPage returns the status page of the order if the token is its token, with the live delivery status while the order
+// is tracked
+func (h *Service) OrderStatusPage(ctx context.Context, groupID, token string) (*StatusPage, error) {
+	if h.cfg.StatusPageURL == "" || !hmac.Equal([]byte(token), []byte(h.statusPageToken(groupID))) {
+		return nil, ErrStatusPageNotFound
+	}
+	if activeOrder, ok := h.LookupActiveOrder(groupID); ok {
+		return h.activeStatusPage(activeOrder), nil
+	}
+	if o := h.storedOrder(ctx, groupID); o != nil {
+		return h.storedStatusPage(o), nil
+	}
+	return nil, ErrStatusPageNotFound
+}
+
+func (h *Service) activeStatusPage(activeOrder ActiveOrder) *StatusPage {
+	page := &StatusPage{
+		OrderID:   activeOrder.ID,
+		VenueName: activeOrder.VenueName,
+		Status:    activeOrder.Status(),
+		Currency:  h.currency,
+		UpdatedAt: time.Now().In(h.timezoneForChannel(activeOrder.Channel, nil)),
+	}
+
+	// Only the details the monitoring already fetched are used, so viewing the page doesn't send requests to Wolt
+	working := h.workingOrders.get(activeOrder.ID)
+	if working != nil {
+		working.lock.RLock()
+		details := working.details
+		working.lock.RUnlock()
+		if details != nil && details.Status.Purchased() && !details.IsDelivered() && !IsUnixZero(details.DeliveryEta) {
+			page.DeliveryETA = details.DeliveryEta.In(page.UpdatedAt.Location())
+		}
+		if activeOrder.Rates == nil && details != nil {
+			if groupRate, err := h.previewGroupRate(working, details); err == nil {
+				activeOrder.Rates = &groupRate
+				page.Provisional = true
+			}
+		}
+	}
+
+	if activeOrder.Rates != nil {
+		page.Currency = h.currencyOrDefault(activeOrder.Rates.Currency)
+		for _, rate := range activeOrder.Rates.Rates {
+			if rate.Amount > 0 && !rate.Private {
+				page.Amounts = append(page.Amounts, StatusPageAmount{Name: rate.WoltName, Amount: rate.PersonalAmount()})
+			}
+		}
+	}
+	return page
+}
+
+func (h *Service) storedStatusPage(o *order.Order) *StatusPage {
+	page := &StatusPage{
+		OrderID:   o.OriginalID,
+		VenueName: o.VenueName,
+		Currency:  h.currencyOrDefault(o.Currency),
+		UpdatedAt: time.Now().In(h.timezoneForChannel(o.Receiver, nil)),
+	}
+	switch storedOrderStatus(o.Status) {
+	case DayOrderCanceled:
+		page.Status = "the order was canceled"
+	case DayOrderStopped:
+		page.Status = "Bolt stopped tracking the order"
+	default:
+		page.Status = "the order was delivered"
+	}
+	for _, participant := range o.Participants {
+		if participant.Amount > 0 && !participant.Private {
+			page.Amounts = append(page.Amounts, StatusPageAmount{Name: participant.Name, Amount: participant.PersonalAmount()})
+		}
+	}
+	return page
+}
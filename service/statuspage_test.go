@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/oriser/bolt/order"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderStatusPage(t *testing.T) {
+	t.Parallel()
+
+	orderStore := &fakeOrderStore{orders: []*order.Order{{
+		OriginalID: "ABC",
+		Receiver:   "C1",
+		Host:       "Thor",
+		VenueName:  "Pizza Place",
+		Status:     order.StatusDone,
+		Currency:   "ILS",
+		Participants: []order.Participant{
+			{Name: "Thor", ID: "U1", Amount: 50},
+			{Name: "Loki", ID: "U2", Amount: 30, Subsidy: 10},
+		},
+	}}}
+	ctx := context.Background()
+
+	disabled, err := New(Config{}, nil, nil, orderStore, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+	_, err = disabled.StatusPageURL(ctx, "ABC", "C1", "U1", false)
+	assert.Error(t, err, "the status pages are disabled without STATUS_PAGE_URL")
+
+	users := &fakeTreasuryStore{users: map[string]*userDomain.User{
+		"1": {ID: "1", FullName: "Thor", TransportID: "U1"},
+		"2": {ID: "2", FullName: "Loki", TransportID: "U2"},
+	}}
+	h, err := New(Config{
+		StatusPageURL:    "https://bolt.example.com/",
+		StatusPageSecret: "secret",
+		Treasurers:       []string{"U3"},
+	}, users, nil, orderStore, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+
+	link, err := h.StatusPageURL(ctx, "<https://wolt.com/en/group/ABC>", "C1", "U2", false)
+	require.NoError(t, err)
+	token := h.statusPageToken("ABC")
+	assert.Equal(t, "https://bolt.example.com/status/ABC/"+token, link)
+	_, err = h.StatusPageURL(ctx, "XYZ", "C1", "U2", false)
+	assert.EqualError(t, err, "I don't know order XYZ")
+
+	// Outside the order's channel, only its host, admins and treasurers get the link
+	_, err = h.StatusPageURL(ctx, "ABC", "C2", "U2", false)
+	assert.ErrorIs(t, err, ErrStatusLinkNotAllowed)
+	link, err = h.StatusPageURL(ctx, "ABC", "C2", "U1", false)
+	require.NoError(t, err, "the host gets the link anywhere")
+	assert.Equal(t, "https://bolt.example.com/status/ABC/"+token, link)
+	_, err = h.StatusPageURL(ctx, "ABC", "C2", "U2", true)
+	assert.NoError(t, err, "admins get the link anywhere")
+	_, err = h.StatusPageURL(ctx, "ABC", "C2", "U3", false)
+	assert.NoError(t, err, "treasurers get the link anywhere")
+
+	page, err := h.OrderStatusPage(ctx, "ABC", token)
+	require.NoError(t, err)
+	assert.Equal(t, "Pizza Place", page.VenueName)
+	assert.Equal(t, "the order was delivered", page.Status)
+	assert.Equal(t, []StatusPageAmount{{Name: "Thor", Amount: 50}, {Name: "Loki", Amount: 20}}, page.Amounts)
+	assert.Equal(t, 70.0, page.Total())
+
+	_, err = h.OrderStatusPage(ctx, "ABC", token[1:])
+	assert.ErrorIs(t, err, ErrStatusPageNotFound)
+	_, err = h.OrderStatusPage(ctx, "XYZ", h.statusPageToken("XYZ"))
+	assert.ErrorIs(t, err, ErrStatusPageNotFound)
+	_, err = disabled.OrderStatusPage(ctx, "ABC", disabled.statusPageToken("ABC"))
+	assert.ErrorIs(t, err, ErrStatusPageNotFound)
+
+	h.hooks.Emit(ctx, Event{Type: EventOrderJoined, OrderID: "DEF", Channel: "C1", VenueName: "Sushi"})
+	page, err = h.OrderStatusPage(ctx, "DEF", h.statusPageToken("DEF"))
+	require.NoError(t, err)
+	assert.Equal(t, "waiting for everyone to be ready", page.Status)
+	assert.Empty(t, page.Amounts)
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ItemCategory is a category of items which can be excluded from the company subsidy
+type ItemCategory string
+
+const (
+	ItemCategoryAlcohol  ItemCategory = "alcohol"
+	ItemCategoryTobacco  ItemCategory = "tobacco"
+	ItemCategoryDesserts ItemCategory = "desserts"
+)
+
+// Wolt doesn't categorize the items of a basket, so desserts are detected by their names
+var dessertKeywords = []string{"dessert", "cake", "ice cream", "gelato", "cookie", "brownie", "pudding", "mousse", "waffle",
+	"קינוח", "עוגה", "עוגת", "גלידה", "עוגיות", "בראוניז", "מלבי", "וופל"}
+
+func parseItemCategories(categories []string) ([]ItemCategory, error) {
+	ret := make([]ItemCategory, 0, len(categories))
+	for _, category := range categories {
+		switch c := ItemCategory(strings.ToLower(strings.TrimSpace(category))); c {
+		case ItemCategoryAlcohol, ItemCategoryTobacco, ItemCategoryDesserts:
+			ret = append(ret, c)
+		default:
+			return nil, fmt.Errorf("unknown item category %q", category)
+		}
+	}
+	return ret, nil
+}
+
+// isItemInCategory returns whether the item belongs to the category.
+// Wolt marks alcohol with its percentage, so age-restricted items without it are considered tobacco.
+func isItemInCategory(item Item, category ItemCategory) bool {
+	switch category {
+	case ItemCategoryAlcohol:
+		return item.IsAlcohol()
+	case ItemCategoryTobacco:
+		return item.AgeRestricted && !item.IsAlcohol()
+	case ItemCategoryDesserts:
+		name := strings.ToLower(item.Name)
+		for _, keyword := range dessertKeywords {
+			if strings.Contains(name, keyword) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// subsidyExcluded returns whether the item belongs to any of the categories excluded from the subsidy
+func (h *Service) subsidyExcluded(item Item) bool {
+	for _, category := range h.subsidyExcludedCategories {
+		if isItemInCategory(item, category) {
+			return true
+		}
+	}
+	return false
+}
+
+// subsidyPolicy is how much of the amount of each participant in an order the company pays
+type subsidyPolicy struct {
+	amount  float64 // The most the company pays for each participant in an order, 0 if it isn't capped per order
+	percent float64 // The percentage of the amount the company pays, 0 if it pays the whole amount (up to the amount)
+}
+
+// of returns the subsidy of the part of the amount the subsidy covers
+func (p subsidyPolicy) of(covered float64) float64 {
+	if covered <= 0 {
+		return 0
+	}
+	subsidy := covered
+	if p.percent > 0 {
+		subsidy = math.Round(covered*p.percent) / 100
+	}
+	if p.amount > 0 && subsidy > p.amount {
+		subsidy = p.amount
+	}
+	return subsidy
+}
+
+// subsidized returns whether the company subsidizes the orders, by SUBSIDY_AMOUNT or SUBSIDY_PERCENT
+func (h *Service) subsidized() bool {
+	return h.cfg.SubsidyAmount > 0 || h.cfg.SubsidyPercent > 0
+}
+
+func (h *Service) subsidyPolicy() subsidyPolicy {
+	return subsidyPolicy{amount: h.cfg.SubsidyAmount, percent: h.cfg.SubsidyPercent}
+}
+
+// setSubsidy sets the subsidy of each participant by the policy.
+// The excluded items' amounts (by Wolt name) are paid in full by the participant, so the subsidy covers only the rest of the amount.
+// The subsidy of the participants with a remaining monthly subsidy (by Wolt name) is capped by it, and nil remaining means there's
+// no monthly cap.
+func (g *GroupRate) setSubsidy(policy subsidyPolicy, excluded map[string]float64, remaining map[string]float64) {
+	for i := range g.Rates {
+		g.Rates[i].Subsidy = policy.of(g.Rates[i].Amount - excluded[g.Rates[i].WoltName])
+		g.Rates[i].SubsidyCapped = false
+		if left, ok := remaining[g.Rates[i].WoltName]; ok && g.Rates[i].Subsidy > left {
+			g.Rates[i].Subsidy = math.Max(left, 0)
+			g.Rates[i].SubsidyCapped = true
+		}
+	}
+}
+
+func (h *Service) subsidyMessage(channel string) string {
+	var sb strings.Builder
+	if h.cfg.SubsidyPercent > 0 {
+		sb.WriteString(h.text(channel, msgSubsidyPercent, h.cfg.SubsidyPercent))
+		if h.cfg.SubsidyAmount > 0 {
+			sb.WriteString(h.text(channel, msgSubsidyPerOrder, h.cfg.SubsidyAmount))
+		}
+	} else {
+		sb.WriteString(h.text(channel, msgSubsidy, h.cfg.SubsidyAmount))
+	}
+	if h.cfg.SubsidyMonthlyCap > 0 {
+		sb.WriteString(h.text(channel, msgSubsidyMonthlyCap, h.cfg.SubsidyMonthlyCap))
+	}
+	if len(h.subsidyExcludedCategories) > 0 {
+		categories := make([]string, len(h.subsidyExcludedCategories))
+		for i, category := range h.subsidyExcludedCategories {
+			categories[i] = string(category)
+		}
+		sb.WriteString(h.text(channel, msgSubsidyExcluding, strings.Join(categories, ", ")))
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
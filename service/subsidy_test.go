@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oriser/bolt/order"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseItemCategories(t *testing.T) {
+	t.Parallel()
+
+	categories, err := parseItemCategories([]string{"alcohol", " Desserts"})
+	require.NoError(t, err)
+	assert.Equal(t, []ItemCategory{ItemCategoryAlcohol, ItemCategoryDesserts}, categories)
+
+	_, err = parseItemCategories([]string{"vegetables"})
+	assert.Error(t, err)
+}
+
+func TestIsItemInCategory(t *testing.T) {
+	t.Parallel()
+
+	beer := Item{Name: "Beer", AgeRestricted: true, Alcohol: true}
+	cigarettes := Item{Name: "Cigarettes", AgeRestricted: true}
+	cake := Item{Name: "Chocolate Cake"}
+	malabi := Item{Name: "מלבי"}
+	salad := Item{Name: "Salad"}
+
+	assert.True(t, isItemInCategory(beer, ItemCategoryAlcohol))
+	assert.False(t, isItemInCategory(beer, ItemCategoryTobacco))
+	assert.True(t, isItemInCategory(cigarettes, ItemCategoryTobacco))
+	assert.False(t, isItemInCategory(cigarettes, ItemCategoryAlcohol))
+	assert.True(t, isItemInCategory(cake, ItemCategoryDesserts))
+	assert.True(t, isItemInCategory(malabi, ItemCategoryDesserts))
+	for _, category := range []ItemCategory{ItemCategoryAlcohol, ItemCategoryTobacco, ItemCategoryDesserts} {
+		assert.False(t, isItemInCategory(salad, category))
+	}
+}
+
+func TestSubsidyExcludedCategories(t *testing.T) {
+	t.Parallel()
+
+	details, err := parseWoltOrderDetails([]byte(`{
+		"host_id": "1",
+		"details": {"delivery_info": {"location": {"coordinates": {"coordinates": [34.78, 32.08]}}}},
+		"participants": [
+			{"first_name": "Thor", "user_id": "1", "basket": {"items": [{"name": "Pizza", "end_amount": 3000}]}},
+			{"first_name": "Loki", "user_id": "2", "basket": {"items": [
+				{"name": "Salad", "end_amount": 3000},
+				{"name": "Beer", "end_amount": 2500, "alcohol_percentage": 4.5},
+				{"name": "Ice Cream", "end_amount": 1500}
+			]}},
+			{"first_name": "Odin", "user_id": "3", "basket": {"items": [
+				{"name": "Wine", "end_amount": 6000, "alcohol_percentage": 12},
+				{"name": "Bread", "end_amount": 1000}
+			]}}
+		]
+	}`))
+	require.NoError(t, err)
+
+	h, err := New(Config{SubsidyAmount: 40, SubsidyExcludedCategories: []string{"alcohol", "desserts"}},
+		&fakeTreasuryStore{}, nil, nil, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+	rates, err := details.RateByPerson()
+	require.NoError(t, err)
+	groupRate := h.buildGroupRates(rates, details.Host, 0)
+	h.setItemAmounts(&groupRate, "ABC", details)
+
+	personal := make(map[string]float64)
+	for _, rate := range groupRate.Rates {
+		personal[rate.WoltName] = rate.PersonalAmount()
+	}
+	// Thor's amount is fully covered, Loki's salad is covered and Odin's bread is covered
+	assert.Equal(t, map[string]float64{"Thor": 0, "Loki": 40, "Odin": 60}, personal)
+
+	assert.Equal(t, "Rates for Wolt order ID ABC (including 0 NIS for delivery):\n"+
+		"Loki: 70.00 (personal share 40.00) :underage:\n"+
+		"Odin: 70.00 (personal share 60.00) :underage:\n"+
+		"Thor: 30.00 (personal share 0.00)\n"+
+		":underage: Includes age-restricted items\n"+
+		"The company subsidizes up to 40.00 per person, excluding alcohol, desserts\n"+
+		"\nPay to: Thor\n", h.buildRatesMessage("C1", groupRate, "ABC"))
+}
+
+func TestSubsidyPolicy(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, 40.0, subsidyPolicy{amount: 40}.of(55))
+	assert.Equal(t, 30.0, subsidyPolicy{amount: 40}.of(30))
+	assert.Equal(t, 38.5, subsidyPolicy{percent: 70}.of(55))
+	assert.Equal(t, 30.0, subsidyPolicy{amount: 30, percent: 70}.of(55), "the percentage is capped by the amount per order")
+	assert.Equal(t, 0.0, subsidyPolicy{percent: 70}.of(-5))
+}
+
+func TestSubsidyMonthlyCap(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	orderStore := &fakeOrderStore{orders: []*order.Order{
+		{OriginalID: "OLD", CreatedAt: now, Status: order.StatusDone, Participants: []order.Participant{
+			{ID: "uuid-loki", Name: "Loki", Amount: 700, Subsidy: 450},
+			{ID: "uuid-thor", Name: "Thor", Amount: 100, Subsidy: 70},
+		}},
+		{OriginalID: "CANCELED", CreatedAt: now, Status: order.StatusCanceled, Participants: []order.Participant{
+			{ID: "uuid-thor", Name: "Thor", Amount: 1000, Subsidy: 500},
+		}},
+		{OriginalID: "LAST_MONTH", CreatedAt: monthStart(now).AddDate(0, 0, -1), Status: order.StatusDone, Participants: []order.Participant{
+			{ID: "uuid-thor", Name: "Thor", Amount: 1000, Subsidy: 500},
+		}},
+		{OriginalID: "ABC", CreatedAt: now, Status: order.StatusDone, Participants: []order.Participant{
+			{ID: "uuid-loki", Name: "Loki", Amount: 100, Subsidy: 50},
+		}},
+	}}
+	h, err := New(Config{SubsidyPercent: 70, SubsidyMonthlyCap: 500}, nil, nil, orderStore, "UBOT",
+		&recordingNotification{})
+	require.NoError(t, err)
+
+	groupRate := GroupRate{HostWoltUser: "Thor", Rates: []Rate{
+		{WoltName: "Loki", User: &userDomain.User{ID: "uuid-loki", TransportID: "U-loki"}, Amount: 100},
+		{WoltName: "Thor", User: &userDomain.User{ID: "uuid-thor", TransportID: "U-thor"}, Amount: 100},
+		{WoltName: "Guest", Amount: 1000},
+	}}
+	groupRate.setSubsidy(h.subsidyPolicy(), nil, h.remainingSubsidies("ABC", groupRate.Rates))
+	assert.Equal(t, "Rates for Wolt order ID ABC (including 0 NIS for delivery):\n"+
+		"<@U-loki> (Loki): 100.00 (personal share 50.00) (reached the monthly subsidy cap)\n"+
+		"<@U-thor> (Thor): 100.00 (personal share 30.00)\n"+
+		"Guest: 1000.00 (personal share 500.00) (reached the monthly subsidy cap)\n"+
+		"The company pays 70% of each person's amount, up to 500.00 per person a month\n"+
+		"\nPay to: Thor\n", h.buildRatesMessage("C1", groupRate, "ABC"),
+		"the order itself doesn't count, as its rates can be computed again")
+
+	report, err := h.SubsidyReport(context.Background(), monthStart(now.In(h.subsidyTimezone())))
+	require.NoError(t, err)
+	assert.Equal(t, []*SubsidyUsage{
+		{UserID: "uuid-loki", Name: "Loki", Used: 500, Orders: 2},
+		{UserID: "uuid-thor", Name: "Thor", Used: 70, Orders: 1},
+	}, report.Users)
+	month := monthStart(now.In(h.subsidyTimezone()))
+	assert.Equal(t, ":moneybag: Subsidy for "+month.Format("January 2006")+": 570.00 NIS used by 2 people, with a monthly cap of 500.00 NIS per person\n"+
+		"Reached the cap: Loki\n"+
+		"The subsidies start over for "+month.AddDate(0, 1, 0).Format("January"), buildSubsidyReportMessage(report))
+
+	var csv strings.Builder
+	require.NoError(t, report.WriteCSV(&csv))
+	assert.Equal(t, "user_id,name,orders,subsidy,remaining\nuuid-loki,Loki,2,500.00,0.00\nuuid-thor,Thor,1,70.00,430.00\n", csv.String())
+}
+
+type failingOrderStore struct {
+	fakeOrderStore
+}
+
+func (f *failingOrderStore) ListOrders(context.Context, order.ListFilter) ([]*order.Order, error) {
+	return nil, fmt.Errorf("database is down")
+}
+
+func TestSubsidyMonthlyCapFailsClosed(t *testing.T) {
+	t.Parallel()
+
+	h, err := New(Config{SubsidyPercent: 70, SubsidyMonthlyCap: 500}, nil, nil, &failingOrderStore{}, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+	groupRate := GroupRate{HostWoltUser: "Thor", Rates: []Rate{
+		{WoltName: "Loki", User: &userDomain.User{ID: "uuid-loki", TransportID: "U-loki"}, Amount: 100},
+		{WoltName: "Guest", Amount: 100},
+	}}
+	h.capSubsidies(&groupRate, "ABC", nil)
+	for _, rate := range groupRate.Rates {
+		assert.Zero(t, rate.Subsidy, "no one gets a subsidy when the cap can't be checked")
+	}
+}
+
+func TestSubsidyMonthlyCapReservations(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	orderStore := &fakeOrderStore{orders: []*order.Order{
+		{OriginalID: "OLD", CreatedAt: now, Status: order.StatusDone, Participants: []order.Participant{
+			{ID: "uuid-thor", Name: "Thor", Amount: 100, Subsidy: 70},
+		}},
+	}}
+	h, err := New(Config{SubsidyPercent: 70, SubsidyMonthlyCap: 500}, nil, nil, orderStore, "UBOT", &recordingNotification{})
+	require.NoError(t, err)
+	ratesOf := func() GroupRate {
+		return GroupRate{HostWoltUser: "Odin", Rates: []Rate{{WoltName: "Thor", User: &userDomain.User{ID: "uuid-thor"}, Amount: 500}}}
+	}
+
+	first := ratesOf()
+	h.capSubsidies(&first, "FIRST", nil)
+	assert.Equal(t, 350.0, first.Rates[0].Subsidy)
+	second := ratesOf()
+	h.capSubsidies(&second, "SECOND", nil)
+	assert.Equal(t, 80.0, second.Rates[0].Subsidy, "the subsidy of the first order is reserved until it's stored")
+	again := ratesOf()
+	h.capSubsidies(&again, "FIRST", nil)
+	assert.Equal(t, 350.0, again.Rates[0].Subsidy, "the order's own reservation isn't counted")
+
+	orderStore.orders = append(orderStore.orders, &order.Order{OriginalID: "FIRST", CreatedAt: now, Status: order.StatusDone,
+		Participants: []order.Participant{{ID: "uuid-thor", Name: "Thor", Amount: 500, Subsidy: 350}}})
+	second = ratesOf()
+	h.capSubsidies(&second, "SECOND", nil)
+	assert.Equal(t, 80.0, second.Rates[0].Subsidy, "the stored order isn't counted twice")
+	h.subsidyReservations.release("FIRST")
+	second = ratesOf()
+	h.capSubsidies(&second, "SECOND", nil)
+	assert.Equal(t, 80.0, second.Rates[0].Subsidy)
+
+	orderStore.orders = orderStore.orders[:1]
+	second = ratesOf()
+	h.capSubsidies(&second, "SECOND", nil)
+	assert.Equal(t, 350.0, second.Rates[0].Subsidy, "the released order doesn't hold its subsidy anymore")
+}
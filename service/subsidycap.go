@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oriser/bolt/order"
+)
+
+// SubsidyUsage is the subsidy a user got in a month
+type SubsidyUsage struct {
+	UserID string
+	Name   string // The Wolt name of the user's latest order
+	Used   float64
+	Orders int
+}
+
+// SubsidyReport is the subsidy each user got in a month, for finance to settle with the SUBSIDY_MONTHLY_CAP in mind
+type SubsidyReport struct {
+	Month    time.Time // The start of the month
+	Cap      float64
+	Users    []*SubsidyUsage // From the highest subsidy
+	Currency string          // The ISO 4217 code of the currency of the amounts
+}
+
+// Used returns the total subsidy of the month
+func (r *SubsidyReport) Used() float64 {
+	total := 0.0
+	for _, usage := range r.Users {
+		total += usage.Used
+	}
+	return total
+}
+
+// ReachedCap returns the users who used all of their monthly subsidy
+func (r *SubsidyReport) ReachedCap() []*SubsidyUsage {
+	reached := make([]*SubsidyUsage, 0)
+	for _, usage := range r.Users {
+		if r.Cap > 0 && usage.Used >= r.Cap-0.005 {
+			reached = append(reached, usage)
+		}
+	}
+	return reached
+}
+
+// subsidyTimezone is the timezone of the months the subsidy is capped by, which is the one of the finance reports
+func (h *Service) subsidyTimezone() *time.Location {
+	return h.timezoneForChannel(h.cfg.FinanceReportChannel, nil)
+}
+
+// monthlySubsidies returns the subsidy each known user got in the done orders created in [from, to), by user ID. The orders of the
+// excluded group are left out, so the rates of an order which is computed again (e.g. after a restart) aren't capped by themselves.
+func (h *Service) monthlySubsidies(ctx context.Context, from, to time.Time, excludedGroupID string) (map[string]*SubsidyUsage, []*order.Order, error) {
+	orders, err := h.orderStore.ListOrders(ctx, order.ListFilter{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("list orders: %w", err)
+	}
+	usages := make(map[string]*SubsidyUsage)
+	monthOrders := make([]*order.Order, 0)
+	for _, o := range orders {
+		if o.Status != order.StatusDone || o.CompanyPaid || o.OriginalID == excludedGroupID || o.CreatedAt.Before(from) || !o.CreatedAt.Before(to) {
+			continue
+		}
+		monthOrders = append(monthOrders, o)
+		for _, p := range o.Participants {
+			if p.ID == "" || p.Subsidy <= 0 {
+				continue
+			}
+			if _, ok := usages[p.ID]; !ok {
+				usages[p.ID] = &SubsidyUsage{UserID: p.ID}
+			}
+			usages[p.ID].Name = p.Name
+			usages[p.ID].Used += p.Subsidy
+			usages[p.ID].Orders++
+		}
+	}
+	return usages, monthOrders, nil
+}
+
+// subsidyReservations keeps the capped subsidies of the orders whose rates were computed but which weren't stored yet, so orders
+// computed at the same time don't both get what's left of a participant's SUBSIDY_MONTHLY_CAP
+type subsidyReservations struct {
+	lock   sync.Mutex
+	orders map[string]map[string]float64 // The subsidy of each user ID, by group ID
+}
+
+func newSubsidyReservations() *subsidyReservations {
+	return &subsidyReservations{orders: make(map[string]map[string]float64)}
+}
+
+// release drops the reservation of the order once it's stored, as the stored order counts from then on
+func (r *subsidyReservations) release(groupID string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.orders, groupID)
+}
+
+// capSubsidies sets the subsidies of the rates, capped by what's left of the SUBSIDY_MONTHLY_CAP of each participant, and reserves
+// them until the order is stored. Checking what's left and reserving it is atomic, so the cap holds for orders computed at the same time.
+func (h *Service) capSubsidies(groupRate *GroupRate, groupID string, excluded map[string]float64) {
+	if h.cfg.SubsidyMonthlyCap <= 0 {
+		groupRate.setSubsidy(h.subsidyPolicy(), excluded, nil)
+		return
+	}
+	h.subsidyReservations.lock.Lock()
+	defer h.subsidyReservations.lock.Unlock()
+	groupRate.setSubsidy(h.subsidyPolicy(), excluded, h.remainingSubsidies(groupID, groupRate.Rates))
+	reserved := make(map[string]float64)
+	for _, rate := range groupRate.Rates {
+		if rate.User != nil && rate.Subsidy > 0 {
+			reserved[rate.User.ID] += rate.Subsidy
+		}
+	}
+	h.subsidyReservations.orders[groupID] = reserved
+}
+
+// remainingSubsidies returns what's left of the SUBSIDY_MONTHLY_CAP of each participant this month (by Wolt name), or nil if the
+// subsidy isn't capped. Participants who aren't known users can't be tracked, so they get the whole cap. If this month's subsidies
+// can't be listed, no one gets a subsidy, rather than going over the cap.
+// It must be called with the lock of the subsidy reservations held, as it counts the reservations of the other orders.
+func (h *Service) remainingSubsidies(groupID string, rates []Rate) map[string]float64 {
+	if h.cfg.SubsidyMonthlyCap <= 0 {
+		return nil
+	}
+	usages := make(map[string]*SubsidyUsage)
+	stored := make(map[string]bool)
+	if h.orderStore != nil {
+		ctx, cancel := h.storeContext()
+		defer cancel()
+		from := monthStart(time.Now().In(h.subsidyTimezone()))
+		var orders []*order.Order
+		var err error
+		if usages, orders, err = h.monthlySubsidies(ctx, from, from.AddDate(0, 1, 0), groupID); err != nil {
+			h.logger.Error("Error getting this month's subsidies, not subsidizing the order", "group_id", groupID, "error", err)
+			none := make(map[string]float64, len(rates))
+			for _, rate := range rates {
+				none[rate.WoltName] = 0
+			}
+			return none
+		}
+		for _, o := range orders {
+			stored[o.OriginalID] = true
+		}
+	}
+
+	remaining := make(map[string]float64, len(rates))
+	for _, rate := range rates {
+		remaining[rate.WoltName] = h.cfg.SubsidyMonthlyCap
+		if rate.User == nil {
+			continue
+		}
+		if usage, ok := usages[rate.User.ID]; ok {
+			remaining[rate.WoltName] -= usage.Used
+		}
+		// The reservations of the orders which were stored already are counted by their stored subsidies
+		for reservedID, reserved := range h.subsidyReservations.orders {
+			if reservedID != groupID && !stored[reservedID] {
+				remaining[rate.WoltName] -= reserved[rate.User.ID]
+			}
+		}
+	}
+	return remaining
+}
+
+// SubsidyReport returns the subsidy each user got in the month starting at the given time
+func (h *Service) SubsidyReport(ctx context.Context, month time.Time) (*SubsidyReport, error) {
+	if h.orderStore == nil {
+		return nil, fmt.Errorf("no order store")
+	}
+	usages, orders, err := h.monthlySubsidies(ctx, month, month.AddDate(0, 1, 0), "")
+	if err != nil {
+		return nil, err
+	}
+	report := &SubsidyReport{Month: month, Cap: h.cfg.SubsidyMonthlyCap, Users: make([]*SubsidyUsage, 0, len(usages)),
+		Currency: h.ordersCurrency(orders)}
+	for _, usage := range usages {
+		report.Users = append(report.Users, usage)
+	}
+	sort.Slice(report.Users, func(i, j int) bool {
+		if report.Users[i].Used != report.Users[j].Used {
+			return report.Users[i].Used > report.Users[j].Used
+		}
+		return report.Users[i].Name < report.Users[j].Name
+	})
+	return report, nil
+}
+
+// WriteCSV writes the report as a spreadsheet, with a row per user
+func (r *SubsidyReport) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"user_id", "name", "orders", "subsidy", "remaining"}); err != nil {
+		return fmt.Errorf("write CSV header: %w", err)
+	}
+	rows := make([][]string, len(r.Users))
+	for i, usage := range r.Users {
+		remaining := ""
+		if r.Cap > 0 {
+			remaining = strconv.FormatFloat(r.Cap-usage.Used, 'f', 2, 64)
+		}
+		rows[i] = []string{usage.UserID, usage.Name, strconv.Itoa(usage.Orders), strconv.FormatFloat(usage.Used, 'f', 2, 64), remaining}
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		return fmt.Errorf("write CSV rows: %w", err)
+	}
+	return nil
+}
+
+func buildSubsidyReportMessage(report *SubsidyReport) string {
+	unit := CurrencyUnit(report.Currency)
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(":moneybag: Subsidy for %s: %.2f %s used by %d people, with a monthly cap of %.2f %s per person",
+		report.Month.Format("January 2006"), report.Used(), unit, len(report.Users), report.Cap, unit))
+	if reached := report.ReachedCap(); len(reached) > 0 {
+		names := make([]string, len(reached))
+		for i, usage := range reached {
+			names[i] = usage.Name
+		}
+		sb.WriteString(fmt.Sprintf("\nReached the cap: %s", strings.Join(names, ", ")))
+	}
+	sb.WriteString(fmt.Sprintf("\nThe subsidies start over for %s", report.Month.AddDate(0, 1, 0).Format("January")))
+	return sb.String()
+}
+
+// sendSubsidyReport sends the subsidy report of the month to FINANCE_REPORT_CHANNEL, as a spreadsheet when the notification layer
+// can send files
+func (h *Service) sendSubsidyReport(ctx context.Context, month time.Time) {
+	report, err := h.SubsidyReport(ctx, month)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "Error getting subsidy report", "month", month.Format("2006-01"), "error", err)
+		return
+	}
+	message := buildSubsidyReportMessage(report)
+
+	uploader, ok := h.eventNotification.(FileUploader)
+	if !ok {
+		if _, err := h.informEvent(h.cfg.FinanceReportChannel, message, "", ""); err != nil {
+			h.logger.ErrorContext(ctx, "Error sending subsidy report", "channel", h.cfg.FinanceReportChannel, "error", err)
+		}
+		return
+	}
+	var content strings.Builder
+	if err := report.WriteCSV(&content); err != nil {
+		h.logger.ErrorContext(ctx, "Error writing subsidy report", "month", month.Format("2006-01"), "error", err)
+		return
+	}
+	filename := fmt.Sprintf("bolt-subsidy-%s.csv", month.Format("2006-01"))
+	if err := uploader.UploadFile(h.cfg.FinanceReportChannel, filename, content.String(), message); err != nil {
+		h.logger.ErrorContext(ctx, "Error uploading subsidy report", "channel", h.cfg.FinanceReportChannel, "error", err)
+	}
+}
+
+// RunSubsidyReporter sends the subsidy report of the previous month to FINANCE_REPORT_CHANNEL, on the first day of every month at
+// FINANCE_REPORT_HOUR, when the subsidy is capped by SUBSIDY_MONTHLY_CAP, until the context is done. The monthly subsidies start over
+// with the month.
+func (h *Service) RunSubsidyReporter(ctx context.Context) {
+	if h.cfg.FinanceReportChannel == "" || h.cfg.SubsidyMonthlyCap <= 0 || h.orderStore == nil {
+		return
+	}
+
+	ticker := time.NewTicker(badgesSchedulerInterval)
+	defer ticker.Stop()
+
+	h.schedulers.beat("subsidy reporter", badgesSchedulerInterval)
+	lastCheck := time.Now()
+	for {
+		select {
+		case now := <-ticker.C:
+			sendAt := monthStart(now.In(h.subsidyTimezone())).Add(time.Duration(h.cfg.FinanceReportHour) * time.Hour)
+			if sendAt.After(lastCheck) && !sendAt.After(now) {
+				h.sendSubsidyReport(ctx, monthStart(sendAt.AddDate(0, -1, 0)))
+			}
+			lastCheck = now
+			h.schedulers.beat("subsidy reporter", badgesSchedulerInterval)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
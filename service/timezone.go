@@ -0,0 +1,44 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+func parseChannelTimezones(pairs []string) (map[string]*time.Location, error) {
+	timezones := make(map[string]*time.Location, len(pairs))
+	for _, pair := range pairs {
+		channel, tzName, ok := strings.Cut(pair, "=")
+		if !ok || channel == "" || tzName == "" {
+			return nil, fmt.Errorf("expected <channel>=<timezone> but got %q", pair)
+		}
+		tz, err := ParseTimezone(tzName)
+		if err != nil {
+			return nil, fmt.Errorf("load timezone for channel %s: %w", channel, err)
+		}
+		timezones[channel] = tz
+	}
+	return timezones, nil
+}
+
+// timezoneForChannel returns the timezone times should be rendered in for the given channel.
+// The channel's own timezone is preferred (its DONT_JOIN_AFTER_TZ override, then CHANNEL_TIMEZONES), then the global
+// DONT_JOIN_AFTER_TZ, then the given fallback (usually the venue's timezone).
+func (h *Service) timezoneForChannel(channel string, fallback *time.Location) *time.Location {
+	if name, ok := h.channelSetting(channel, settingDontJoinAfterTZ); ok {
+		if tz, err := ParseTimezone(name); err == nil {
+			return tz
+		}
+	}
+	if tz, ok := h.channelTimezones[channel]; ok {
+		return tz
+	}
+	if h.dontJoinAfterTZ != nil {
+		return h.dontJoinAfterTZ
+	}
+	if fallback != nil {
+		return fallback
+	}
+	return time.Local
+}
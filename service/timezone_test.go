@@ -0,0 +1,51 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseChannelTimezones(t *testing.T) {
+	t.Parallel()
+
+	timezones, err := parseChannelTimezones([]string{"C1=Asia/Jerusalem", "C2=Europe/London"})
+	require.NoError(t, err)
+	require.Len(t, timezones, 2)
+	assert.Equal(t, "Asia/Jerusalem", timezones["C1"].String())
+	assert.Equal(t, "Europe/London", timezones["C2"].String())
+
+	timezones, err = parseChannelTimezones(nil)
+	require.NoError(t, err)
+	assert.Empty(t, timezones)
+
+	for _, pairs := range [][]string{{"C1"}, {"=Asia/Jerusalem"}, {"C1="}} {
+		_, err = parseChannelTimezones(pairs)
+		require.Error(t, err, pairs)
+		assert.Contains(t, err.Error(), "expected <channel>=<timezone>")
+	}
+	_, err = parseChannelTimezones([]string{"C1=Nowhere/Land"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "load timezone for channel C1")
+}
+
+func TestTimezoneForChannel(t *testing.T) {
+	t.Parallel()
+
+	jerusalem, err := time.LoadLocation("Asia/Jerusalem")
+	require.NoError(t, err)
+	london, err := time.LoadLocation("Europe/London")
+	require.NoError(t, err)
+	venue := time.FixedZone("venue", 3*60*60)
+
+	h := &Service{channelTimezones: map[string]*time.Location{"C1": jerusalem}}
+	assert.Equal(t, jerusalem, h.timezoneForChannel("C1", venue), "the channel's timezone is preferred")
+	assert.Equal(t, venue, h.timezoneForChannel("C2", venue), "falls back to the given timezone")
+	assert.Equal(t, time.Local, h.timezoneForChannel("C2", nil), "falls back to the local timezone")
+
+	h.dontJoinAfterTZ = london
+	assert.Equal(t, jerusalem, h.timezoneForChannel("C1", venue), "the channel's timezone is preferred over DONT_JOIN_AFTER_TZ")
+	assert.Equal(t, london, h.timezoneForChannel("C2", venue), "DONT_JOIN_AFTER_TZ is preferred over the given timezone")
+}
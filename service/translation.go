@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/oriser/bolt/translation"
+)
+
+// translationTimeout is the deadline of translating the item names of a message, after which they're shown as they are in Wolt
+const translationTimeout = 10 * time.Second
+
+// itemTranslations caches the translated item names by their locale, as the same items show up in order after order
+type itemTranslations struct {
+	lock       sync.Mutex
+	translated map[Locale]map[string]string
+}
+
+func newItemTranslations() *itemTranslations {
+	return &itemTranslations{translated: make(map[Locale]map[string]string)}
+}
+
+func (t *itemTranslations) get(locale Locale, name string) (string, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	translated, ok := t.translated[locale][name]
+	return translated, ok
+}
+
+func (t *itemTranslations) add(locale Locale, name, translated string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.translated[locale] == nil {
+		t.translated[locale] = make(map[string]string)
+	}
+	t.translated[locale][name] = translated
+}
+
+// SetTranslationProvider sets the translation provider, for showing the names of Wolt items in the channel's locale, like Hebrew
+// item names in an English channel
+func (h *Service) SetTranslationProvider(provider translation.Provider) {
+	h.translationProvider = provider
+}
+
+// translateItemNames returns the item names in the channel's locale. Names which are already in it are kept, and so are all the
+// names if there's no translation provider or translating them fails.
+func (h *Service) translateItemNames(ctx context.Context, channel string, names []string) []string {
+	if h.translationProvider == nil || len(names) == 0 {
+		return names
+	}
+	locale := h.channelLocale(channel)
+	translated := make([]string, len(names))
+	missing := make([]string, 0)
+	missingIndexes := make(map[string][]int)
+	for i, name := range names {
+		translated[i] = name
+		if DetectLocale([]string{name}) == locale {
+			continue
+		}
+		if cached, ok := h.itemTranslations.get(locale, name); ok {
+			translated[i] = cached
+			continue
+		}
+		if _, ok := missingIndexes[name]; !ok {
+			missing = append(missing, name)
+		}
+		missingIndexes[name] = append(missingIndexes[name], i)
+	}
+	if len(missing) == 0 {
+		return translated
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, translationTimeout)
+	defer cancel()
+	results, err := h.translationProvider.Translate(ctx, missing, string(locale))
+	if err == nil && len(results) != len(missing) {
+		err = fmt.Errorf("got %d translations for %d names", len(results), len(missing))
+	}
+	if err != nil {
+		h.logger.ErrorContext(ctx, "Error translating item names", "channel", channel, "locale", locale, "error", err)
+		return translated
+	}
+	for i, name := range missing {
+		h.itemTranslations.add(locale, name, results[i])
+		for _, index := range missingIndexes[name] {
+			translated[index] = results[i]
+		}
+	}
+	return translated
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTranslationProvider translates the texts it knows, and records the texts it was asked to translate
+type fakeTranslationProvider struct {
+	translations map[string]string
+	requested    []string
+}
+
+func (f *fakeTranslationProvider) Translate(_ context.Context, texts []string, language string) ([]string, error) {
+	f.requested = append(f.requested, texts...)
+	translated := make([]string, len(texts))
+	for i, text := range texts {
+		var ok bool
+		if translated[i], ok = f.translations[language+":"+text]; !ok {
+			return nil, fmt.Errorf("unknown text %q", text)
+		}
+	}
+	return translated, nil
+}
+
+func TestTranslateItemNames(t *testing.T) {
+	t.Parallel()
+
+	h, err := New(Config{Locale: "en"}, nil, nil, nil, "UBOT", nil)
+	require.NoError(t, err)
+	names := []string{"שניצל", "Cola", "שניצל"}
+	assert.Equal(t, names, h.translateItemNames(context.Background(), "C1", names), "no provider keeps the names")
+
+	provider := &fakeTranslationProvider{translations: map[string]string{"en:שניצל": "Schnitzel"}}
+	h.SetTranslationProvider(provider)
+	assert.Equal(t, []string{"Schnitzel", "Cola", "Schnitzel"}, h.translateItemNames(context.Background(), "C1", names))
+	assert.Equal(t, []string{"שניצל"}, provider.requested, "names in the channel's locale aren't translated")
+
+	assert.Equal(t, []string{"Schnitzel"}, h.translateItemNames(context.Background(), "C1", []string{"שניצל"}))
+	assert.Len(t, provider.requested, 1, "translations are cached")
+
+	assert.Equal(t, []string{"סלט", "Schnitzel"}, h.translateItemNames(context.Background(), "C1", []string{"סלט", "שניצל"}),
+		"names are kept if translating fails")
+}
+
+func TestSplitDeliveryTranslatedItems(t *testing.T) {
+	t.Parallel()
+
+	h, err := New(Config{Locale: "en"}, nil, nil, nil, "UBOT", nil)
+	require.NoError(t, err)
+	h.SetTranslationProvider(&fakeTranslationProvider{translations: map[string]string{"en:שניצל": "Schnitzel"}})
+	tracker := h.newSplitDeliveryTracker("C1", "M1")
+	assert.Equal(t, "2 Schnitzel, 1 Cola", tracker.formatItems([]Item{{Name: "שניצל", Count: 2}, {Name: "Cola", Count: 1}}))
+}
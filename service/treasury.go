@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	"github.com/oriser/bolt/order"
+	userDomain "github.com/oriser/bolt/user"
+)
+
+// TreasuryDebt is an outstanding debt with its users. The users are nil if they can't be found.
+type TreasuryDebt struct {
+	Debt                *debtDomain.Debt
+	Borrower            *userDomain.User
+	Lender              *userDomain.User
+	AgeRestrictedAmount float64 // The borrower's amount of age-restricted items in the order, see Rate
+	OrderRef            string  // The external reference of the order, empty if it has none
+	OrderProof          string  // The link to the proof of purchase of the order, empty if the host didn't attach one
+	Private             bool    // The borrower kept their amount of the order private, so it's shown only to them and the lender
+}
+
+// TreasuryReport is the outstanding debts across all channels, from the newest to the oldest
+type TreasuryReport struct {
+	Debts []TreasuryDebt
+}
+
+// Total returns the amount of all the outstanding debts
+func (r TreasuryReport) Total() float64 {
+	total := 0.0
+	for _, d := range r.Debts {
+		total += d.Debt.Amount
+	}
+	return total
+}
+
+// TotalByLender returns the outstanding amount owed to each lender ID, sorted from the highest amount
+func (r TreasuryReport) TotalByLender() []LenderTotal {
+	totals := make(map[string]*LenderTotal)
+	for _, d := range r.Debts {
+		total, ok := totals[d.Debt.LenderID]
+		if !ok {
+			total = &LenderTotal{LenderID: d.Debt.LenderID, Lender: d.Lender}
+			totals[d.Debt.LenderID] = total
+		}
+		total.Amount += d.Debt.Amount
+		total.DebtsCount++
+	}
+
+	ret := make([]LenderTotal, 0, len(totals))
+	for _, total := range totals {
+		ret = append(ret, *total)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Amount != ret[j].Amount {
+			return ret[i].Amount > ret[j].Amount
+		}
+		return ret[i].LenderID < ret[j].LenderID
+	})
+	return ret
+}
+
+type LenderTotal struct {
+	LenderID   string
+	Lender     *userDomain.User
+	Amount     float64
+	DebtsCount int
+}
+
+func userName(user *userDomain.User, id string) string {
+	if user == nil {
+		return id
+	}
+	return user.FullName
+}
+
+// WriteCSV writes the report debts as CSV
+func (r TreasuryReport) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"debt_id", "created_at", "order_id", "channel", "borrower_id", "borrower", "lender_id", "lender", "amount",
+		"age_restricted", "age_restricted_amount", "order_ref", "order_proof"}); err != nil {
+		return fmt.Errorf("write CSV header: %w", err)
+	}
+	for _, d := range r.Debts {
+		if err := writer.Write([]string{
+			d.Debt.ID,
+			d.Debt.CreatedAt.Format(time.RFC3339),
+			d.Debt.OrderID,
+			d.Debt.InitiatedTransportID,
+			d.Debt.BorrowerID,
+			userName(d.Borrower, d.Debt.BorrowerID),
+			d.Debt.LenderID,
+			userName(d.Lender, d.Debt.LenderID),
+			strconv.FormatFloat(d.Debt.Amount, 'f', 2, 64),
+			strconv.FormatBool(d.AgeRestrictedAmount > 0),
+			strconv.FormatFloat(d.AgeRestrictedAmount, 'f', 2, 64),
+			d.OrderRef,
+			d.OrderProof,
+		}); err != nil {
+			return fmt.Errorf("write CSV row: %w", err)
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// IsTreasurer returns whether the user with the given transport ID can view and settle debts across all channels
+func (h *Service) IsTreasurer(transportID string) bool {
+	for _, treasurer := range h.cfg.Treasurers {
+		if treasurer == transportID {
+			return true
+		}
+	}
+	return false
+}
+
+// TreasuryReport returns the outstanding debts across all channels
+func (h *Service) TreasuryReport(ctx context.Context) (TreasuryReport, error) {
+	if h.debtStore == nil {
+		return TreasuryReport{}, fmt.Errorf("no debt store")
+	}
+
+	debts, err := h.debtStore.ListDebts(ctx, debtDomain.ListFilter{})
+	if err != nil {
+		return TreasuryReport{}, fmt.Errorf("list debts: %w", err)
+	}
+
+	return TreasuryReport{Debts: h.debtsWithUsers(ctx, debts)}, nil
+}
+
+// debtsWithUsers returns the debts with their users, the borrowers' age-restricted amounts and the references and proofs of their
+// orders
+func (h *Service) debtsWithUsers(ctx context.Context, debts []*debtDomain.Debt) []TreasuryDebt {
+	users := make(map[string]*userDomain.User)
+	getUser := func(id string) *userDomain.User {
+		if u, ok := users[id]; ok {
+			return u
+		}
+		u, err := h.userStore.GetUser(ctx, id)
+		if err != nil {
+			h.logger.ErrorContext(ctx, "Error getting user for debts", "user_id", id, "error", err)
+			u = nil
+		}
+		users[id] = u
+		return u
+	}
+
+	orders := make(map[string]*order.Order)
+	getOrder := func(orderID string) *order.Order {
+		if o, ok := orders[orderID]; ok {
+			return o
+		}
+		orders[orderID] = h.storedOrder(ctx, orderID)
+		return orders[orderID]
+	}
+
+	withUsers := make([]TreasuryDebt, len(debts))
+	for i, d := range debts {
+		withUsers[i] = TreasuryDebt{Debt: d, Borrower: getUser(d.BorrowerID), Lender: getUser(d.LenderID)}
+		o := getOrder(d.OrderID)
+		if o == nil {
+			continue
+		}
+		withUsers[i].OrderRef = o.ExternalRef
+		withUsers[i].OrderProof = o.ProofURL
+		for _, p := range o.Participants {
+			if p.ID == d.BorrowerID {
+				withUsers[i].AgeRestrictedAmount = p.AgeRestrictedAmount
+				withUsers[i].Private = p.Private
+			}
+		}
+	}
+	return withUsers
+}
+
+// storedOrder returns the stored order with the given Wolt group ID, or nil if it isn't stored
+func (h *Service) storedOrder(ctx context.Context, orderID string) *order.Order {
+	if h.orderStore == nil {
+		return nil
+	}
+	orders, err := h.orderStore.ListOrders(ctx, order.ListFilter{OriginalID: orderID})
+	if err != nil {
+		h.logger.ErrorContext(ctx, "Error listing orders", "group_id", orderID, "error", err)
+		return nil
+	}
+	if len(orders) == 0 {
+		return nil
+	}
+	return orders[0]
+}
+
+// SettleDebt removes an outstanding debt by its ID (or a unique prefix of it) on behalf of a treasurer, and notifies the borrower and the lender
+func (h *Service) SettleDebt(ctx context.Context, settledByTransportID, debtID string) (*debtDomain.Debt, error) {
+	if !h.IsTreasurer(settledByTransportID) {
+		return nil, fmt.Errorf("only treasurers can settle debts")
+	}
+	if h.debtStore == nil {
+		return nil, fmt.Errorf("no debt store")
+	}
+	if debtID == "" {
+		return nil, fmt.Errorf("empty debt ID")
+	}
+
+	debts, err := h.debtStore.ListDebts(ctx, debtDomain.ListFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("list debts: %w", err)
+	}
+	var found *debtDomain.Debt
+	for _, d := range debts {
+		if !strings.HasPrefix(d.ID, debtID) {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("more than one debt starts with %q", debtID)
+		}
+		found = d
+	}
+	if found == nil {
+		return nil, fmt.Errorf("debt %q not found", debtID)
+	}
+
+	if err = h.debtStore.RemoveDebtInOrderID(ctx, found.OrderID, found.ID); err != nil {
+		return nil, fmt.Errorf("remove debt: %w", err)
+	}
+
+	reason := fmt.Sprintf("settled by the treasurer <@%s>", settledByTransportID)
+	for _, userID := range []string{found.BorrowerID, found.LenderID} {
+		u, err := h.userStore.GetUser(ctx, userID)
+		if err != nil {
+			h.logger.ErrorContext(ctx, "Error getting user to notify about settled debt", "user_id", userID, "error", err)
+			continue
+		}
+		amount := FormatAmount(found.Amount, h.currencyOrDefault(found.Currency))
+		_, _ = h.informEvent(u.TransportID, h.text(u.TransportID, msgDebtSettledByTreasurer, amount, found.BorrowerID, found.LenderID,
+			found.OrderID, settledByTransportID), "", "")
+	}
+	h.hooks.Emit(ctx, Event{Type: EventDebtPaid, OrderID: found.OrderID, Channel: found.InitiatedTransportID, MessageID: found.MessageID, Debt: found, Reason: reason})
+
+	return found, nil
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	"github.com/oriser/bolt/order"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTreasuryStore struct {
+	debts    []*debtDomain.Debt
+	users    map[string]*userDomain.User
+	payments []*debtDomain.Payment
+	pending  []*debtDomain.PendingDebt
+}
+
+func (f *fakeTreasuryStore) AddPendingDebt(_ context.Context, pending *debtDomain.PendingDebt) error {
+	pending.ID = fmt.Sprintf("pending-%d", len(f.pending))
+	f.pending = append(f.pending, pending)
+	return nil
+}
+
+func (f *fakeTreasuryStore) ListPendingDebts(_ context.Context, woltName string) ([]*debtDomain.PendingDebt, error) {
+	pending := make([]*debtDomain.PendingDebt, 0)
+	for _, p := range f.pending {
+		if strings.EqualFold(p.WoltName, woltName) {
+			pending = append(pending, p)
+		}
+	}
+	return pending, nil
+}
+
+func (f *fakeTreasuryStore) RemovePendingDebt(_ context.Context, id string) error {
+	for i, p := range f.pending {
+		if p.ID == id {
+			f.pending = append(f.pending[:i], f.pending[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (f *fakeTreasuryStore) AddPayment(_ context.Context, payment *debtDomain.Payment) error {
+	f.payments = append(f.payments, payment)
+	return nil
+}
+
+func (f *fakeTreasuryStore) ListPayments(_ context.Context, filter debtDomain.PaymentListFilter) ([]*debtDomain.Payment, error) {
+	payments := make([]*debtDomain.Payment, 0)
+	for _, p := range f.payments {
+		if (filter.Channel == "" || p.InitiatedTransportID == filter.Channel) && (filter.PaidBefore.IsZero() || p.PaidAt.Before(filter.PaidBefore)) &&
+			(filter.BorrowerID == "" || p.BorrowerID == filter.BorrowerID) && (filter.OrderID == "" || p.OrderID == filter.OrderID) {
+			payments = append(payments, p)
+		}
+	}
+	return payments, nil
+}
+
+func (f *fakeTreasuryStore) AddDebt(_ context.Context, debt *debtDomain.Debt) error {
+	f.debts = append(f.debts, debt)
+	return nil
+}
+
+func (f *fakeTreasuryStore) RemoveDebtInOrderID(_ context.Context, orderID, debtID string) error {
+	for i, d := range f.debts {
+		if d.OrderID == orderID && d.ID == debtID {
+			f.debts = append(f.debts[:i], f.debts[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (f *fakeTreasuryStore) SetPaidClaimedAt(_ context.Context, debtID string, claimedAt *time.Time) error {
+	for _, d := range f.debts {
+		if d.ID == debtID {
+			d.PaidClaimedAt = claimedAt
+			return nil
+		}
+	}
+	return fmt.Errorf("debt not found")
+}
+
+func (f *fakeTreasuryStore) ListDebtsForOrderID(ctx context.Context, orderID string) ([]*debtDomain.Debt, error) {
+	return f.ListDebts(ctx, debtDomain.ListFilter{OrderIDs: []string{orderID}})
+}
+
+func (f *fakeTreasuryStore) ListDebts(_ context.Context, filter debtDomain.ListFilter) ([]*debtDomain.Debt, error) {
+	debts := make([]*debtDomain.Debt, 0)
+	for _, d := range f.debts {
+		if len(filter.OrderIDs) != 0 && d.OrderID != filter.OrderIDs[0] {
+			continue
+		}
+		if len(filter.UserIDs) != 0 && !containsString(filter.UserIDs, d.BorrowerID) && !containsString(filter.UserIDs, d.LenderID) {
+			continue
+		}
+		if filter.Channel != "" && d.InitiatedTransportID != filter.Channel {
+			continue
+		}
+		debts = append(debts, d)
+	}
+	return debts, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fakeTreasuryStore) AddUser(_ context.Context, user *userDomain.User) error {
+	f.users[user.ID] = user
+	return nil
+}
+
+func (f *fakeTreasuryStore) GetUser(_ context.Context, id string) (*userDomain.User, error) {
+	user, ok := f.users[id]
+	if !ok {
+		return nil, fmt.Errorf("user not found")
+	}
+	return user, nil
+}
+
+func (f *fakeTreasuryStore) ListUsers(_ context.Context, filter userDomain.ListFilter) ([]*userDomain.User, error) {
+	users := make([]*userDomain.User, 0)
+	for _, user := range f.users {
+		if filter.TransportID != "" && user.TransportID == filter.TransportID {
+			users = append(users, user)
+		}
+		for _, name := range filter.Names {
+			if user.FullName == name {
+				users = append(users, user)
+			}
+		}
+	}
+	return users, nil
+}
+
+type recordingNotification struct {
+	messages []string
+}
+
+func (r *recordingNotification) SendMessage(receiver, event, _ string) (string, error) {
+	r.messages = append(r.messages, receiver+": "+event)
+	return "", nil
+}
+
+func (r *recordingNotification) EditMessage(string, string, string) error {
+	return nil
+}
+
+func (r *recordingNotification) AddReaction(string, string, string) error {
+	return nil
+}
+
+func TestTreasury(t *testing.T) {
+	t.Parallel()
+
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	store := &fakeTreasuryStore{
+		debts: []*debtDomain.Debt{
+			{ID: "aaaa-1", BorrowerID: "U1", LenderID: "U2", OrderID: "A", Amount: 10, InitiatedTransportID: "C1", CreatedAt: createdAt},
+			{ID: "aaaa-2", BorrowerID: "U3", LenderID: "U2", OrderID: "A", Amount: 20, InitiatedTransportID: "C1", CreatedAt: createdAt},
+			{ID: "bbbb-1", BorrowerID: "U2", LenderID: "U1", OrderID: "B", Amount: 5, InitiatedTransportID: "C2", CreatedAt: createdAt},
+		},
+		users: map[string]*userDomain.User{
+			"U1": {ID: "U1", FullName: "Loki", TransportID: "U1"},
+			"U2": {ID: "U2", FullName: "Thor", TransportID: "U2"},
+		},
+	}
+	notification := &recordingNotification{}
+	h := &Service{
+		logger:    slog.Default(),
+		cfg:       Config{Treasurers: []string{"U-treasurer"}},
+		debtStore: store,
+		userStore: store,
+		orderStore: &fakeOrderStore{orders: []*order.Order{
+			{OriginalID: "A", ExternalRef: "BOLT-000007", ProofURL: "https://files.example.com/A.jpg",
+				Participants: []order.Participant{{Name: "Loki", ID: "U1", Amount: 10}, {Name: "Odin", ID: "U3", Amount: 20, AgeRestrictedAmount: 12}}},
+		}},
+		eventNotification: notification,
+		hooks:             NewHooks(),
+	}
+	paid := make([]Event, 0)
+	h.hooks.Subscribe(func(_ context.Context, event Event) {
+		paid = append(paid, event)
+	}, EventDebtPaid)
+
+	report, err := h.TreasuryReport(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, report.Debts, 3)
+	assert.Nil(t, report.Debts[1].Borrower)
+	assert.Equal(t, 35.0, report.Total())
+	assert.Equal(t, []LenderTotal{
+		{LenderID: "U2", Lender: store.users["U2"], Amount: 30, DebtsCount: 2},
+		{LenderID: "U1", Lender: store.users["U1"], Amount: 5, DebtsCount: 1},
+	}, report.TotalByLender())
+
+	csv := &bytes.Buffer{}
+	require.NoError(t, report.WriteCSV(csv))
+	lines := strings.Split(strings.TrimSpace(csv.String()), "\n")
+	assert.Len(t, lines, 4)
+	assert.Equal(t, "aaaa-2,2024-05-01T12:00:00Z,A,C1,U3,U3,U2,Thor,20.00,true,12.00,BOLT-000007,https://files.example.com/A.jpg", lines[2])
+	assert.Equal(t, "bbbb-1,2024-05-01T12:00:00Z,B,C2,U2,Thor,U1,Loki,5.00,false,0.00,,", lines[3])
+
+	_, err = h.SettleDebt(context.Background(), "U1", "bbbb")
+	assert.Error(t, err, "only treasurers can settle")
+	_, err = h.SettleDebt(context.Background(), "U-treasurer", "aaaa")
+	assert.Error(t, err, "ambiguous prefix")
+	_, err = h.SettleDebt(context.Background(), "U-treasurer", "cccc")
+	assert.Error(t, err, "not found")
+
+	settled, err := h.SettleDebt(context.Background(), "U-treasurer", "bbbb")
+	require.NoError(t, err)
+	assert.Equal(t, "bbbb-1", settled.ID)
+	assert.Len(t, store.debts, 2)
+	assert.Len(t, notification.messages, 2)
+	assert.True(t, strings.HasPrefix(notification.messages[0], "U2: The debt of 5.00 nis from <@U2> to <@U1> for Wolt order ID B was settled by the treasurer <@U-treasurer>"))
+	require.Len(t, paid, 1)
+	assert.Equal(t, "bbbb-1", paid[0].Debt.ID)
+}
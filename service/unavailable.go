@@ -0,0 +1,90 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/oriser/bolt/logging"
+)
+
+var (
+	// ErrChannelUnavailable is wrapped by transport errors of channels Bolt can't post to anymore,
+	// like archived channels or channels Bolt was removed from
+	ErrChannelUnavailable = errors.New("channel unavailable")
+	// ErrUserUnavailable is wrapped by transport errors of users who left the workspace
+	ErrUserUnavailable = errors.New("user unavailable")
+)
+
+const (
+	reasonChannelArchived = "the channel was archived"
+	reasonBotRemoved      = "I was removed from the channel"
+	reasonHostLeft        = "the host left the workspace"
+	reasonCantPost        = "I can't post to the channel anymore"
+)
+
+// checkTransportError stops tracking the orders which can't be tracked anymore, if the error says the receiver is unavailable
+func (h *Service) checkTransportError(receiver string, err error) {
+	switch {
+	case errors.Is(err, ErrChannelUnavailable):
+		h.stopChannelOrders(receiver, reasonCantPost)
+	case errors.Is(err, ErrUserUnavailable):
+		h.HandleUserLeft(receiver)
+	}
+}
+
+// HandleChannelArchived stops tracking the orders of an archived (or deleted) channel
+func (h *Service) HandleChannelArchived(channel string) {
+	h.stopChannelOrders(channel, reasonChannelArchived)
+}
+
+// HandleMemberLeftChannel stops tracking the orders of a channel Bolt was removed from
+func (h *Service) HandleMemberLeftChannel(channel, userID string) {
+	if userID != h.selfID {
+		return
+	}
+	h.stopChannelOrders(channel, reasonBotRemoved)
+}
+
+// stopChannelOrders stops tracking the orders of a channel Bolt can't post to anymore
+func (h *Service) stopChannelOrders(channel, reason string) {
+	for _, order := range h.workingOrders.list() {
+		if order.channel == channel {
+			h.stopOrder(order, reason)
+		}
+	}
+}
+
+// HandleUserLeft stops tracking the orders hosted by a user who left the workspace
+func (h *Service) HandleUserLeft(transportID string) {
+	for _, order := range h.workingOrders.list() {
+		if order.HostTransportID() == transportID {
+			h.stopOrder(order, reasonHostLeft)
+		}
+	}
+}
+
+// stopOrder stops the monitors of an order and tells the fallback admin channel about it, since the order's channel may not be
+// reachable anymore. The debts of the order are kept, for treasurers to settle them.
+func (h *Service) stopOrder(order *groupOrder, reason string) {
+	if !order.stop(reason) {
+		return
+	}
+	h.logger.InfoContext(order.ctx, "Stopped tracking order", "reason", reason)
+
+	event := Event{Type: EventOrderStopped, OrderID: order.id, Channel: order.channel, MessageID: order.messageID, Reason: reason}
+	if venue := order.currentVenue(); venue != nil {
+		event.VenueName = venue.Name
+	}
+	// The context of the order is canceled once it's stopped
+	h.hooks.Emit(logging.CopyAttrs(h.lifetime(), order.ctx), event)
+
+	if h.cfg.FallbackAdminChannel == "" || h.eventNotification == nil {
+		return
+	}
+	// Sent directly rather than with informEvent, so an unavailable fallback channel doesn't stop anything else
+	message := fmt.Sprintf(":warning: I stopped tracking order %s in <#%s> because %s. Its outstanding debts are kept, treasurers can settle them with `/bolt treasury`",
+		order.id, order.channel, reason)
+	if _, err := h.eventNotification.SendMessage(h.cfg.FallbackAdminChannel, message, ""); err != nil {
+		h.logger.ErrorContext(order.ctx, "Error notifying the fallback admin channel about stopping the order", "error", err)
+	}
+}
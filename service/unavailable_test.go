@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// archivedNotification fails posting to the archived channels like the transport does
+type archivedNotification struct {
+	recordingNotification
+	archived map[string]bool
+}
+
+func (a *archivedNotification) SendMessage(receiver, event, messageID string) (string, error) {
+	if a.archived[receiver] {
+		return "", fmt.Errorf("posting message: %w: is_archived", ErrChannelUnavailable)
+	}
+	return a.recordingNotification.SendMessage(receiver, event, messageID)
+}
+
+func startWorkingOrder(t *testing.T, h *Service, id, channel string) *groupOrder {
+	entry, _, ok := h.workingOrders.start(id, time.Now(), 1, nil)
+	require.True(t, ok)
+	ctx, cancel := context.WithCancel(context.Background())
+	order := &groupOrder{id: id, channel: channel, messageID: "1.1", ctx: ctx, cancel: cancel}
+	h.workingOrders.setOrder(entry, order)
+	return order
+}
+
+func TestStopOrdersOfUnavailableChannel(t *testing.T) {
+	t.Parallel()
+
+	notification := &archivedNotification{archived: map[string]bool{"C1": true}}
+	h, err := New(Config{FallbackAdminChannel: "C-admins"}, nil, nil, nil, "UBOT", notification)
+	require.NoError(t, err)
+	stopped := make([]Event, 0)
+	h.hooks.Subscribe(func(_ context.Context, event Event) {
+		stopped = append(stopped, event)
+	}, EventOrderStopped)
+
+	archived := startWorkingOrder(t, h, "A", "C1")
+	other := startWorkingOrder(t, h, "B", "C2")
+
+	_, err = h.informEvent("C1", "Delivery arrived", "", "1.1")
+	require.ErrorIs(t, err, ErrChannelUnavailable)
+	assert.Error(t, archived.ctx.Err(), "the monitors of the order are stopped")
+	assert.Equal(t, reasonCantPost, archived.stopped())
+	assert.NoError(t, other.ctx.Err(), "orders of other channels keep being tracked")
+
+	// Other messages failing for the same order don't stop it again
+	_, _ = h.informEvent("C1", "Delivery arrived", "", "1.1")
+	require.Len(t, stopped, 1)
+	assert.Equal(t, "A", stopped[0].OrderID)
+	assert.Equal(t, reasonCantPost, stopped[0].Reason)
+	assert.Equal(t, []string{
+		"C-admins: :warning: I stopped tracking order A in <#C1> because I can't post to the channel anymore. " +
+			"Its outstanding debts are kept, treasurers can settle them with `/bolt treasury`",
+	}, notification.messages)
+
+	h.HandleMemberLeftChannel("C2", "U1")
+	assert.NoError(t, other.ctx.Err(), "only Bolt leaving the channel stops its orders")
+	h.HandleMemberLeftChannel("C2", "UBOT")
+	assert.Equal(t, reasonBotRemoved, other.stopped())
+}
+
+func TestStopOrdersOfHostWhoLeft(t *testing.T) {
+	t.Parallel()
+
+	notification := &recordingNotification{}
+	h, err := New(Config{}, nil, nil, nil, "UBOT", notification)
+	require.NoError(t, err)
+
+	hosted := startWorkingOrder(t, h, "A", "C1")
+	hosted.setHost("U1")
+	other := startWorkingOrder(t, h, "B", "C1")
+	other.setHost("U2")
+
+	h.HandleUserLeft("U1")
+	assert.Equal(t, reasonHostLeft, hosted.stopped())
+	assert.Empty(t, other.stopped())
+	assert.Empty(t, notification.messages, "there's no fallback admin channel to notify")
+
+	_, err = h.informEvent("C1", "Hi", "", "")
+	require.NoError(t, err)
+	h.checkTransportError("U2", fmt.Errorf("posting message: %w: user_disabled", ErrUserUnavailable))
+	assert.Equal(t, reasonHostLeft, other.stopped())
+}
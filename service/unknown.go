@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	userDomain "github.com/oriser/bolt/user"
+)
+
+// UnknownParticipantPolicy is what to do with the share of a participant who wasn't matched to any user
+type UnknownParticipantPolicy string
+
+const (
+	UnknownParticipantSkip    UnknownParticipantPolicy = "skip"    // Don't track the participant's payment
+	UnknownParticipantHost    UnknownParticipantPolicy = "host"    // Count the participant's share as the host's
+	UnknownParticipantPending UnknownParticipantPolicy = "pending" // Track the payment once a user with the participant's name is added
+	UnknownParticipantPrompt  UnknownParticipantPolicy = "prompt"  // Same as pending, and ask the host to add the user
+)
+
+func parseUnknownParticipantPolicy(name string) (UnknownParticipantPolicy, error) {
+	switch policy := UnknownParticipantPolicy(name); policy {
+	case "":
+		return UnknownParticipantSkip, nil
+	case UnknownParticipantSkip, UnknownParticipantHost, UnknownParticipantPending, UnknownParticipantPrompt:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown policy %q", name)
+	}
+}
+
+func parseChannelUnknownParticipantPolicies(pairs []string) (map[string]UnknownParticipantPolicy, error) {
+	policies := make(map[string]UnknownParticipantPolicy, len(pairs))
+	for _, pair := range pairs {
+		channel, name, ok := strings.Cut(pair, "=")
+		if !ok || channel == "" || name == "" {
+			return nil, fmt.Errorf("expected <channel>=<policy> but got %q", pair)
+		}
+		policy, err := parseUnknownParticipantPolicy(name)
+		if err != nil {
+			return nil, fmt.Errorf("policy for channel %s: %w", channel, err)
+		}
+		policies[channel] = policy
+	}
+	return policies, nil
+}
+
+func (h *Service) unknownParticipantPolicy(channel string) UnknownParticipantPolicy {
+	if policy, ok := h.channelUnknownParticipantPolicies[channel]; ok {
+		return policy
+	}
+	return h.unknownParticipantPolicyDefault
+}
+
+func (h *Service) handleUnknownParticipant(ctx context.Context, initiatedTransport, orderID, messageID, currency string, rate Rate, hostUser *userDomain.User) {
+	policy := h.unknownParticipantPolicy(initiatedTransport)
+	pendingStore, ok := h.debtStore.(debtDomain.PendingStore)
+	if !ok && (policy == UnknownParticipantPending || policy == UnknownParticipantPrompt) {
+		policy = UnknownParticipantSkip
+	}
+
+	switch policy {
+	case UnknownParticipantHost:
+		_, _ = h.informEvent(initiatedTransport, h.text(initiatedTransport, msgUnknownAsHost, rate.WoltName, rate.PersonalAmount(),
+			hostUser.TransportID), "", messageID)
+	case UnknownParticipantPending, UnknownParticipantPrompt:
+		if err := pendingStore.AddPendingDebt(ctx, &debtDomain.PendingDebt{
+			WoltName:             rate.WoltName,
+			LenderID:             hostUser.ID,
+			OrderID:              orderID,
+			Amount:               rate.PersonalAmount(),
+			InitiatedTransportID: initiatedTransport,
+			MessageID:            messageID,
+			CreatedAt:            time.Now(),
+			Currency:             h.currencyOrDefault(currency),
+		}); err != nil {
+			h.logger.Error("Error adding pending debt", "wolt_name", rate.WoltName, "group_id", orderID, "error", err)
+			_, _ = h.informEvent(initiatedTransport, h.text(initiatedTransport, msgUnknownSkipped, rate.WoltName), "", messageID)
+			return
+		}
+		message := h.text(initiatedTransport, msgUnknownPending, rate.WoltName)
+		if policy == UnknownParticipantPrompt {
+			message = h.text(initiatedTransport, msgUnknownPrompt, hostUser.TransportID, rate.WoltName, rate.WoltName, rate.PersonalAmount())
+		}
+		_, _ = h.informEvent(initiatedTransport, message, "", messageID)
+	default:
+		_, _ = h.informEvent(initiatedTransport, h.text(initiatedTransport, msgUnknownSkipped, rate.WoltName), "", messageID)
+	}
+}
+
+// ActivatePendingDebts turns the pending debts of the Wolt name of a newly added user into debts.
+// Pending debts of orders older than DEBT_MAXIMUM_DURATION are dropped.
+func (h *Service) ActivatePendingDebts(ctx context.Context, user *userDomain.User) error {
+	pendingStore, ok := h.debtStore.(debtDomain.PendingStore)
+	if !ok {
+		return nil
+	}
+
+	pendingDebts, err := pendingStore.ListPendingDebts(ctx, user.FullName)
+	if err != nil {
+		return fmt.Errorf("list pending debts: %w", err)
+	}
+	for _, pending := range pendingDebts {
+		if err := pendingStore.RemovePendingDebt(ctx, pending.ID); err != nil {
+			return fmt.Errorf("remove pending debt: %w", err)
+		}
+		if time.Since(pending.CreatedAt) > h.cfg.DebtMaximumDuration {
+			continue
+		}
+
+		lender, err := h.userStore.GetUser(ctx, pending.LenderID)
+		if err != nil {
+			h.logger.ErrorContext(ctx, "Error getting lender of pending debt", "user_id", pending.LenderID, "pending_debt_id", pending.ID, "error", err)
+			continue
+		}
+		if _, err := h.createDebt(ctx, pending.Amount, pending.Currency, pending.InitiatedTransportID, pending.OrderID, pending.MessageID, user, lender); err != nil {
+			h.logger.ErrorContext(ctx, "Error creating debt from pending debt", "pending_debt_id", pending.ID, "error", err)
+			continue
+		}
+		_, _ = h.informEvent(pending.InitiatedTransportID, h.text(pending.InitiatedTransportID, msgPendingActivated, pending.WoltName,
+			user.TransportID, pending.Amount, lender.TransportID), "", pending.MessageID)
+	}
+	return nil
+}
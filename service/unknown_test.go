@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnknownParticipantPolicies(t *testing.T) {
+	t.Parallel()
+
+	_, err := New(Config{UnknownParticipantPolicy: "ignore"}, nil, nil, nil, "", nil)
+	assert.Error(t, err)
+	_, err = New(Config{UnknownParticipantPolicy: "skip", ChannelUnknownPolicies: []string{"C1"}}, nil, nil, nil, "", nil)
+	assert.Error(t, err)
+
+	host := &userDomain.User{ID: "U-host", TransportID: "S-host"}
+	store := &fakeTreasuryStore{users: map[string]*userDomain.User{host.ID: host}}
+	notification := &recordingNotification{}
+	h, err := New(Config{
+
+		UnknownParticipantPolicy: "skip",
+		ChannelUnknownPolicies:   []string{"C-host=host", "C-pending=pending", "C-prompt=prompt"},
+		DebtMaximumDuration:      time.Hour,
+	}, store, store, nil, "U-bot", notification)
+	require.NoError(t, err)
+
+	rate := Rate{WoltName: "Loki", Amount: 10}
+	for _, channel := range []string{"C-skip", "C-host", "C-pending", "C-prompt"} {
+		h.handleUnknownParticipant(context.Background(), channel, "A", "1.1", "ILS", rate, host)
+	}
+	assert.Equal(t, []string{
+		`C-skip: I won't track "Loki" payment because I can't find his user.`,
+		`C-host: I can't find "Loki"'s user, so I count their share (10.00) as the host's (<@S-host>).`,
+		`C-pending: I can't find "Loki"'s user, I'll track their payment once their user is added.`,
+		"C-prompt: <@S-host>, I can't find \"Loki\"'s user. Please link it with `/bolt link \"Loki\" @<user>` and I'll track their payment of 10.00.",
+	}, notification.messages)
+	require.Len(t, store.pending, 2)
+
+	// An expired pending debt is dropped
+	store.pending[1].CreatedAt = time.Now().Add(-2 * time.Hour)
+	loki := &userDomain.User{ID: "U-loki", FullName: "loki", TransportID: "S-loki"}
+	require.NoError(t, h.ActivatePendingDebts(context.Background(), loki))
+	assert.Empty(t, store.pending)
+	require.Len(t, store.debts, 1)
+	assert.Equal(t, debtDomain.Debt{ID: store.debts[0].ID, BorrowerID: "U-loki", LenderID: "U-host", OrderID: "A", Amount: 10,
+		InitiatedTransportID: "C-pending", MessageID: "1.1", CreatedAt: store.debts[0].CreatedAt, Currency: "ILS"}, *store.debts[0])
+	assert.Equal(t, `C-pending: I found "Loki"'s user (<@S-loki>), I'll keep reminding them to pay 10.00 to <@S-host>.`, notification.messages[4])
+}
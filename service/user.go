@@ -1,7 +1,6 @@
 package service
 
 import (
-	"context"
 	"fmt"
 
 	userDomain "github.com/oriser/bolt/user"
@@ -9,15 +8,25 @@ import (
 )
 
 func (h *Service) HandleAddUser(name string, user slack.User) error {
-	if err := h.userStore.AddUser(context.Background(), &userDomain.User{
+	return h.HandleAddTransportUser(&userDomain.User{
 		FullName:           name,
 		Email:              user.Profile.Email,
 		Phone:              user.Profile.Phone,
 		PaymentPreferences: nil,
 		Timezone:           user.TZ,
 		TransportID:        user.ID,
-	}); err != nil {
+	})
+}
+
+// HandleAddTransportUser adds a user of any transport, whose FullName is their Wolt name
+func (h *Service) HandleAddTransportUser(added *userDomain.User) error {
+	ctx, cancel := h.storeContext()
+	defer cancel()
+	if err := h.userStore.AddUser(ctx, added); err != nil {
 		return fmt.Errorf("add user: %w", err)
 	}
+	if err := h.ActivatePendingDebts(ctx, added); err != nil {
+		h.logger.Error("Error activating pending debts", "name", added.FullName, "error", err)
+	}
 	return nil
 }
@@ -1,6 +1,10 @@
 package service
 
-import "time"
+import (
+	"fmt"
+	"math"
+	"time"
+)
 
 func IsUnixZero(t time.Time) bool {
 	return t.Equal(time.Unix(0, 0))
@@ -9,3 +13,41 @@ func IsUnixZero(t time.Time) bool {
 func IsToday(t time.Time, timezone *time.Location) bool {
 	return t.In(timezone).Format("2006-01-02") == time.Now().In(timezone).Format("2006-01-02")
 }
+
+// RelativeTime returns a human-readable distance between t and now, like "in 12 minutes" or "3 hours ago"
+func RelativeTime(t time.Time, now time.Time) string {
+	diff := t.Sub(now)
+	future := diff > 0
+	diff = time.Duration(math.Abs(float64(diff)))
+
+	// The unit is chosen by the rounded amount, so 59.6 minutes are "1 hour" rather than "60 minutes"
+	minutes := int(math.Round(diff.Minutes()))
+	hours := int(math.Round(diff.Hours()))
+	var amount int
+	var unit string
+	switch {
+	case diff < time.Minute:
+		return "now"
+	case minutes < 60:
+		amount, unit = minutes, "minute"
+	case hours < 24:
+		amount, unit = hours, "hour"
+	default:
+		amount, unit = int(math.Round(diff.Hours()/24)), "day"
+	}
+	if amount != 1 {
+		unit += "s"
+	}
+
+	if future {
+		return fmt.Sprintf("in %d %s", amount, unit)
+	}
+	return fmt.Sprintf("%d %s ago", amount, unit)
+}
+
+// SlackDate formats t with Slack's date token. Slack renders the token in the timezone of each reader, not in the given one, so on
+// Slack the channel's timezone only decides the format (like whether the date is shown). The fallback text, shown by the clients
+// which can't render the token and by the other transports, is formatted in the given timezone.
+func SlackDate(t time.Time, tokenFormat, fallbackLayout string, timezone *time.Location) string {
+	return fmt.Sprintf("<!date^%d^%s|%s>", t.Unix(), tokenFormat, t.In(timezone).Format(fallbackLayout))
+}
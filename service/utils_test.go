@@ -0,0 +1,65 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelativeTime(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		diff     time.Duration
+		expected string
+	}{
+		{name: "now", diff: 20 * time.Second, expected: "now"},
+		{name: "just passed", diff: -59 * time.Second, expected: "now"},
+		{name: "a minute", diff: time.Minute, expected: "in 1 minute"},
+		{name: "minutes", diff: 12*time.Minute + 20*time.Second, expected: "in 12 minutes"},
+		{name: "minutes ago", diff: -3 * time.Minute, expected: "3 minutes ago"},
+		{name: "rounded up to an hour", diff: 59*time.Minute + 40*time.Second, expected: "in 1 hour"},
+		{name: "hours", diff: 2*time.Hour + 20*time.Minute, expected: "in 2 hours"},
+		{name: "hours ago", diff: -5 * time.Hour, expected: "5 hours ago"},
+		{name: "rounded up to a day", diff: 23*time.Hour + 40*time.Minute, expected: "in 1 day"},
+		{name: "days ago", diff: -3 * 24 * time.Hour, expected: "3 days ago"},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expected, RelativeTime(now.Add(tc.diff), now))
+		})
+	}
+}
+
+func TestSlackDate(t *testing.T) {
+	t.Parallel()
+
+	london, err := time.LoadLocation("Europe/London")
+	require.NoError(t, err)
+	at := time.Unix(1700000000, 0)
+	tests := []struct {
+		name     string
+		format   string
+		layout   string
+		timezone *time.Location
+		expected string
+	}{
+		{name: "time", format: "{time}", layout: "15:04", timezone: time.UTC, expected: "<!date^1700000000^{time}|22:13>"},
+		{name: "fallback in the timezone", format: "{time}", layout: "15:04", timezone: london, expected: "<!date^1700000000^{time}|22:13>"},
+		{name: "date and time", format: "{date_num} {time}", layout: "2006-01-02 15:04", timezone: time.FixedZone("UTC+2", 2*60*60),
+			expected: "<!date^1700000000^{date_num} {time}|2023-11-15 00:13>"},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expected, SlackDate(at, tc.format, tc.layout, tc.timezone))
+		})
+	}
+}
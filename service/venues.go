@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/oriser/bolt/order"
+)
+
+const (
+	defaultVenuesDays = 90
+	maxVenuesDays     = 366
+	topVenues         = 10
+	// venueSuggestionMinOrders is how many times a user ordered from a venue for it to be suggested to them
+	venueSuggestionMinOrders = 3
+	// venueSuggestionAfter is how long a user didn't order from a venue for it to be suggested to them
+	venueSuggestionAfter = 30 * 24 * time.Hour
+)
+
+// VenueSuggestion is a venue the user used to order from, but didn't for a while
+type VenueSuggestion struct {
+	VenueName   string
+	Orders      int
+	LastOrdered time.Time
+}
+
+// VenueStatsReport is which venues the orders of a channel were ordered from, and how they were delivered
+type VenueStatsReport struct {
+	Days       int
+	Currency   string
+	Venues     []order.VenueSummary // From the most ordered
+	Suggestion *VenueSuggestion     // nil if there's no venue to suggest
+}
+
+func (h *Service) venueSummaryStore() (order.VenueSummaryStore, error) {
+	venueSummaryStore, ok := h.orderStore.(order.VenueSummaryStore)
+	if !ok {
+		return nil, fmt.Errorf("venue stats are not supported")
+	}
+	return venueSummaryStore, nil
+}
+
+// recordDeliveryDuration keeps the delivery duration of the stored orders of the Wolt group, for the venue stats
+func (h *Service) recordDeliveryDuration(groupID string, duration time.Duration) {
+	venueSummaryStore, err := h.venueSummaryStore()
+	if err != nil {
+		return
+	}
+	ctx, cancel := h.storeContext()
+	defer cancel()
+	if err := venueSummaryStore.SetDeliveryDuration(ctx, groupID, duration); err != nil {
+		h.logger.Error("Error recording the delivery duration of the order", "group_id", groupID, "error", err)
+	}
+}
+
+// VenueStats returns the venues most ordered from by the channel in the last days (90 if it's 0), and a venue the user (by transport
+// ID) used to order from in the channel but didn't for a while, if there's one
+func (h *Service) VenueStats(ctx context.Context, channel, transportID string, days int) (*VenueStatsReport, error) {
+	venueSummaryStore, err := h.venueSummaryStore()
+	if err != nil {
+		return nil, err
+	}
+	if days <= 0 {
+		days = defaultVenuesDays
+	}
+	if days > maxVenuesDays {
+		days = maxVenuesDays
+	}
+	venues, err := venueSummaryStore.ListVenueSummaries(ctx, order.VenueSummaryFilter{
+		Receiver: channel,
+		Since:    time.Now().AddDate(0, 0, -days),
+		Limit:    topVenues,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list venue summaries: %w", err)
+	}
+
+	report := &VenueStatsReport{Days: days, Currency: h.currencyOrDefault(""), Venues: venues}
+	if transportID != "" {
+		if report.Suggestion, err = h.venueSuggestion(ctx, channel, transportID, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// venueSuggestion returns the venue the user ordered from in the channel at least venueSuggestionMinOrders times, and the longest
+// ago if it's more than venueSuggestionAfter ago. It's nil if there's no such venue.
+func (h *Service) venueSuggestion(ctx context.Context, channel, transportID string, now time.Time) (*VenueSuggestion, error) {
+	userIDs, err := h.userIDsOfTransport(ctx, transportID)
+	if err != nil {
+		return nil, fmt.Errorf("user IDs of %s: %w", transportID, err)
+	}
+	orders, err := h.orderStore.ListOrders(ctx, order.ListFilter{Receiver: channel, ParticipantIDs: sortedSet(userIDs)})
+	if err != nil {
+		return nil, fmt.Errorf("list orders: %w", err)
+	}
+
+	venues := make(map[string]*VenueSuggestion)
+	for _, o := range orders {
+		if o.Status != order.StatusDone || o.VenueName == "" {
+			continue
+		}
+		venue, ok := venues[o.VenueName]
+		if !ok {
+			venue = &VenueSuggestion{VenueName: o.VenueName}
+			venues[o.VenueName] = venue
+		}
+		venue.Orders++
+		if o.CreatedAt.After(venue.LastOrdered) {
+			venue.LastOrdered = o.CreatedAt
+		}
+	}
+
+	candidates := make([]*VenueSuggestion, 0)
+	for _, venue := range venues {
+		if venue.Orders >= venueSuggestionMinOrders && now.Sub(venue.LastOrdered) > venueSuggestionAfter {
+			candidates = append(candidates, venue)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if !candidates[i].LastOrdered.Equal(candidates[j].LastOrdered) {
+			return candidates[i].LastOrdered.Before(candidates[j].LastOrdered)
+		}
+		return candidates[i].VenueName < candidates[j].VenueName
+	})
+	return candidates[0], nil
+}
+
+// BuildVenuesMessage returns the message of the venue stats
+func BuildVenuesMessage(report *VenueStatsReport) string {
+	var sb strings.Builder
+	if len(report.Venues) == 0 {
+		sb.WriteString(fmt.Sprintf("No orders were delivered in the last %d days\n", report.Days))
+	} else {
+		sb.WriteString(fmt.Sprintf(":round_pushpin: The venues most ordered from in the last %d days:\n", report.Days))
+		for i, venue := range report.Venues {
+			sb.WriteString(fmt.Sprintf("%d. %s - %d orders, %s delivery fee on average", i+1, venue.VenueName, venue.Orders,
+				FormatAmount(venue.AverageDeliveryRate, report.Currency)))
+			if venue.AverageDeliveryDuration > 0 {
+				sb.WriteString(fmt.Sprintf(", delivered in %d minutes on average", int(venue.AverageDeliveryDuration.Round(time.Minute)/time.Minute)))
+			}
+			sb.WriteString("\n")
+		}
+	}
+	if report.Suggestion != nil {
+		sb.WriteString(fmt.Sprintf(":thinking_face: It's been a while since you ordered from *%s* (last on %s), maybe it's time?\n",
+			report.Suggestion.VenueName, report.Suggestion.LastOrdered.Format("January 2")))
+	}
+	return sb.String()
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oriser/bolt/order"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeVenueSummaryStore struct {
+	filteringOrderStore
+	filter    order.VenueSummaryFilter
+	summaries []order.VenueSummary
+	durations map[string]time.Duration
+}
+
+func (f *fakeVenueSummaryStore) SetDeliveryDuration(_ context.Context, originalID string, duration time.Duration) error {
+	f.durations[originalID] = duration
+	return nil
+}
+
+func (f *fakeVenueSummaryStore) ListVenueSummaries(_ context.Context, filter order.VenueSummaryFilter) ([]order.VenueSummary, error) {
+	f.filter = filter
+	return f.summaries, nil
+}
+
+func TestVenueStats(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	venueOrder := func(venue string, daysAgo int, status order.Status) *order.Order {
+		return &order.Order{Receiver: "C1", VenueName: venue, Status: status, CreatedAt: now.AddDate(0, 0, -daysAgo),
+			Participants: []order.Participant{{Name: "Dana", ID: "dana"}}}
+	}
+	store := &fakeVenueSummaryStore{
+		filteringOrderStore: filteringOrderStore{fakeOrderStore{orders: []*order.Order{
+			venueOrder("Pizza", 40, order.StatusDone), venueOrder("Pizza", 50, order.StatusDone), venueOrder("Pizza", 60, order.StatusDone),
+			venueOrder("Sushi", 100, order.StatusDone), venueOrder("Sushi", 110, order.StatusDone), venueOrder("Sushi", 120, order.StatusDone),
+			venueOrder("Burger", 5, order.StatusDone), venueOrder("Burger", 50, order.StatusDone), venueOrder("Burger", 60, order.StatusDone),
+			venueOrder("Salad", 300, order.StatusDone), venueOrder("Salad", 310, order.StatusCanceled), venueOrder("Salad", 320, order.StatusCanceled),
+		}}},
+		summaries: []order.VenueSummary{
+			{VenueName: "Burger", Orders: 3, AverageDeliveryRate: 12, AverageDeliveryDuration: 35 * time.Minute},
+			{VenueName: "Pizza", Orders: 2, AverageDeliveryRate: 10},
+		},
+	}
+	users := &fakeTreasuryStore{users: map[string]*userDomain.User{"dana": {ID: "dana", TransportID: "U1"}}}
+	h, err := New(Config{Currency: "ILS"}, users, nil, store, "UBOT", nil)
+	require.NoError(t, err)
+
+	report, err := h.VenueStats(context.Background(), "C1", "U1", 0)
+	require.NoError(t, err)
+	assert.Equal(t, 90, report.Days)
+	assert.Equal(t, "C1", store.filter.Receiver)
+	assert.Equal(t, uint64(topVenues), store.filter.Limit)
+	assert.WithinDuration(t, now.AddDate(0, 0, -90), store.filter.Since, time.Minute)
+	require.NotNil(t, report.Suggestion)
+	assert.Equal(t, "Sushi", report.Suggestion.VenueName, "the venue ordered the longest ago, of those ordered enough times")
+	assert.Equal(t, 3, report.Suggestion.Orders)
+
+	message := BuildVenuesMessage(report)
+	assert.Contains(t, message, "1. Burger - 3 orders, 12.00 nis delivery fee on average, delivered in 35 minutes on average\n")
+	assert.Contains(t, message, "2. Pizza - 2 orders, 10.00 nis delivery fee on average\n")
+	assert.Contains(t, message, "It's been a while since you ordered from *Sushi*")
+
+	report, err = h.VenueStats(context.Background(), "C1", "U2", 1000)
+	require.NoError(t, err)
+	assert.Equal(t, maxVenuesDays, report.Days)
+	assert.Nil(t, report.Suggestion, "the user didn't order from any venue")
+}
+
+func TestRecordDeliveryDuration(t *testing.T) {
+	t.Parallel()
+
+	store := &fakeVenueSummaryStore{durations: make(map[string]time.Duration)}
+	h, err := New(Config{}, nil, nil, store, "UBOT", nil)
+	require.NoError(t, err)
+
+	h.recordDeliveryDuration("G1", 40*time.Minute)
+	assert.Equal(t, map[string]time.Duration{"G1": 40 * time.Minute}, store.durations)
+}
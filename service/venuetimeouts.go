@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/oriser/bolt/order"
+)
+
+const (
+	// venueTimeoutMinDeliveries is how many delivered orders of a venue its timeouts are learned from, so a single slow delivery
+	// doesn't extend them
+	venueTimeoutMinDeliveries = 3
+	// venueTimeoutHeadroom is how many times its average delivery duration the learned ORDER_DONE_TIMEOUT of a venue is
+	venueTimeoutHeadroom = 2
+)
+
+// VenueTimeouts are the timeouts of tracking the delivery of the orders of a venue
+type VenueTimeouts struct {
+	VenueName           string
+	DoneTimeout         time.Duration
+	StatusCheckInterval time.Duration
+	Overridden          bool              // Whether an admin set the timeouts of the venue
+	Learned             bool              // Whether the timeouts were learned from the delivery durations of the venue
+	Stats               *order.VenueStats // nil if the venue has no stats
+}
+
+func (h *Service) venueStatsStore() (order.VenueStatsStore, error) {
+	venueStatsStore, ok := h.orderStore.(order.VenueStatsStore)
+	if !ok {
+		return nil, fmt.Errorf("venue stats are not supported")
+	}
+	return venueStatsStore, nil
+}
+
+// timeoutsOfVenue returns the timeouts of the venue from its stats: its overrides, or the ones learned from its delivery durations
+// when the venue is slower than ORDER_DONE_TIMEOUT allows, capped at maxTimeoutMultiplier times it. The status check
+// interval grows with the done timeout unless it's overridden, so the slow venues are polled less often.
+func (h *Service) timeoutsOfVenue(venueName string, stats *order.VenueStats) VenueTimeouts {
+	timeouts := VenueTimeouts{VenueName: venueName, DoneTimeout: h.cfg.OrderDoneTimeout, StatusCheckInterval: h.cfg.WaitBetweenStatusCheck, Stats: stats}
+	if stats == nil {
+		return timeouts
+	}
+	learned := stats.AverageDelivery * venueTimeoutHeadroom
+	switch {
+	case stats.DoneTimeout > 0:
+		timeouts.DoneTimeout = stats.DoneTimeout
+	case stats.Deliveries >= venueTimeoutMinDeliveries && learned > h.cfg.OrderDoneTimeout && h.cfg.OrderDoneTimeout > 0:
+		timeouts.Learned = true
+		timeouts.DoneTimeout = learned
+		if maxTimeout := h.cfg.OrderDoneTimeout * maxTimeoutMultiplier; timeouts.DoneTimeout > maxTimeout {
+			timeouts.DoneTimeout = maxTimeout
+		}
+	}
+
+	timeouts.Overridden = stats.DoneTimeout > 0 || stats.StatusCheckInterval > 0
+	if stats.StatusCheckInterval > 0 {
+		timeouts.StatusCheckInterval = stats.StatusCheckInterval
+	} else if h.cfg.OrderDoneTimeout > 0 && timeouts.DoneTimeout > h.cfg.OrderDoneTimeout {
+		timeouts.StatusCheckInterval = time.Duration(float64(h.cfg.WaitBetweenStatusCheck) * float64(timeouts.DoneTimeout) / float64(h.cfg.OrderDoneTimeout))
+	}
+	return timeouts
+}
+
+// deliveryTimeouts returns the timeouts of tracking the delivery of an order of the venue, which are the global ones if the venue
+// has no stats or they can't be read
+func (h *Service) deliveryTimeouts(ctx context.Context, venueName string) VenueTimeouts {
+	venueStatsStore, err := h.venueStatsStore()
+	if err != nil || venueName == "" {
+		return h.timeoutsOfVenue(venueName, nil)
+	}
+	stats, err := venueStatsStore.GetVenueStats(ctx, venueName)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "Error getting the venue stats, using the global timeouts", "venue", venueName, "error", err)
+	}
+	return h.timeoutsOfVenue(venueName, stats)
+}
+
+// learnDeliveryDuration adds the delivery duration of the delivered order, from its purchase until it was delivered, to the stats of
+// its venue and records it on the stored order
+func (h *Service) learnDeliveryDuration(groupID, venueName string, details *OrderDetails, now time.Time) {
+	if IsUnixZero(details.PurchaseDatetime) {
+		return
+	}
+	deliveredAt := details.DeliveredAt
+	if deliveredAt.IsZero() {
+		deliveredAt = now
+	}
+	duration := deliveredAt.Sub(details.PurchaseDatetime)
+	if duration <= 0 {
+		return
+	}
+	h.recordDeliveryDuration(groupID, duration)
+
+	venueStatsStore, err := h.venueStatsStore()
+	if err != nil || venueName == "" {
+		return
+	}
+	ctx, cancel := h.storeContext()
+	defer cancel()
+	if err := venueStatsStore.AddVenueDelivery(ctx, venueName, duration); err != nil {
+		h.logger.Error("Error learning the delivery duration of the venue", "venue", venueName, "error", err)
+	}
+}
+
+// VenueTimeouts returns the timeouts of tracking the delivery of the orders of the venue, and where they come from
+func (h *Service) VenueTimeouts(ctx context.Context, venueName string) (VenueTimeouts, error) {
+	venueStatsStore, err := h.venueStatsStore()
+	if err != nil {
+		return VenueTimeouts{}, err
+	}
+	venueName = strings.TrimSpace(venueName)
+	stats, err := venueStatsStore.GetVenueStats(ctx, venueName)
+	if err != nil {
+		return VenueTimeouts{}, fmt.Errorf("get venue stats: %w", err)
+	}
+	return h.timeoutsOfVenue(venueName, stats), nil
+}
+
+// SetVenueTimeouts overrides ORDER_DONE_TIMEOUT and WAIT_BETWEEN_STATUS_CHECK for the orders of the venue, like catering which takes
+// hours. A 0 timeout is learned from the delivery durations of the venue again.
+func (h *Service) SetVenueTimeouts(ctx context.Context, venueName string, doneTimeout, statusCheckInterval time.Duration) error {
+	venueStatsStore, err := h.venueStatsStore()
+	if err != nil {
+		return err
+	}
+	venueName = strings.TrimSpace(venueName)
+	if venueName == "" {
+		return fmt.Errorf("venue name is required")
+	}
+	if doneTimeout < 0 || statusCheckInterval < 0 {
+		return fmt.Errorf("the timeouts must not be negative")
+	}
+	if statusCheckInterval > 0 && statusCheckInterval < time.Second {
+		return fmt.Errorf("the status check interval must be at least a second")
+	}
+	if err := venueStatsStore.SetVenueTimeouts(ctx, venueName, doneTimeout, statusCheckInterval); err != nil {
+		return fmt.Errorf("set venue timeouts: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oriser/bolt/order"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeoutsOfVenue(t *testing.T) {
+	t.Parallel()
+
+	h, err := New(Config{OrderDoneTimeout: 3 * time.Hour, WaitBetweenStatusCheck: 20 * time.Second}, nil, nil, nil,
+		"UBOT", &recordingNotification{})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		stats         *order.VenueStats
+		doneTimeout   time.Duration
+		checkInterval time.Duration
+		learned       bool
+		overridden    bool
+	}{
+		{name: "no stats", doneTimeout: 3 * time.Hour, checkInterval: 20 * time.Second},
+		{name: "fast venue", stats: &order.VenueStats{Deliveries: 10, AverageDelivery: 40 * time.Minute},
+			doneTimeout: 3 * time.Hour, checkInterval: 20 * time.Second},
+		{name: "too few deliveries", stats: &order.VenueStats{Deliveries: 2, AverageDelivery: 3 * time.Hour},
+			doneTimeout: 3 * time.Hour, checkInterval: 20 * time.Second},
+		{name: "slow venue", stats: &order.VenueStats{Deliveries: 3, AverageDelivery: 3 * time.Hour},
+			doneTimeout: 6 * time.Hour, checkInterval: 40 * time.Second, learned: true},
+		{name: "capped", stats: &order.VenueStats{Deliveries: 3, AverageDelivery: 24 * time.Hour},
+			doneTimeout: 15 * time.Hour, checkInterval: 100 * time.Second, learned: true},
+		{name: "overridden timeout", stats: &order.VenueStats{Deliveries: 3, AverageDelivery: 3 * time.Hour, DoneTimeout: 4 * time.Hour},
+			doneTimeout: 4 * time.Hour, checkInterval: 80 * time.Second / 3, overridden: true},
+		{name: "overridden interval", stats: &order.VenueStats{Deliveries: 3, AverageDelivery: 3 * time.Hour, StatusCheckInterval: time.Minute},
+			doneTimeout: 6 * time.Hour, checkInterval: time.Minute, learned: true, overridden: true},
+	}
+	for _, tt := range tests {
+		timeouts := h.timeoutsOfVenue("Catering", tt.stats)
+		assert.Equal(t, tt.doneTimeout, timeouts.DoneTimeout, tt.name)
+		assert.Equal(t, tt.checkInterval, timeouts.StatusCheckInterval, tt.name)
+		assert.Equal(t, tt.learned, timeouts.Learned, tt.name)
+		assert.Equal(t, tt.overridden, timeouts.Overridden, tt.name)
+	}
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// The kinds of the order monitors the watchdog keeps track of
+const (
+	monitorKindVenue        = "venue"         // Watches the venue while the group order is open
+	monitorKindDelivery     = "delivery"      // Follows the delivery of a group order
+	monitorKindSoloDelivery = "solo_delivery" // Follows the delivery of a solo order
+	monitorKindDebts        = "debts"         // Reminds about the debts of an order until they're paid
+)
+
+var monitorKinds = []string{monitorKindVenue, monitorKindDelivery, monitorKindSoloDelivery, monitorKindDebts}
+
+// liveMonitor is an order monitor which is running
+type liveMonitor struct {
+	kind      string
+	orderID   string
+	startedAt time.Time
+	deadline  time.Time // The deadline of the monitor's context, zero if it has none
+	leaked    bool      // Already reported as leaked
+}
+
+// liveMonitors keeps the running order monitors, so the watchdog can tell the monitors which outlived their context's deadline
+type liveMonitors struct {
+	lock     sync.Mutex
+	nextID   int
+	monitors map[int]*liveMonitor
+}
+
+func newLiveMonitors() *liveMonitors {
+	return &liveMonitors{monitors: make(map[int]*liveMonitor)}
+}
+
+// start adds a running monitor of the order, and returns the function to call once it returns
+func (l *liveMonitors) start(ctx context.Context, kind, orderID string, now time.Time) func() {
+	deadline, _ := ctx.Deadline()
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	id := l.nextID
+	l.nextID++
+	l.monitors[id] = &liveMonitor{kind: kind, orderID: orderID, startedAt: now, deadline: deadline}
+	return func() {
+		l.lock.Lock()
+		defer l.lock.Unlock()
+		delete(l.monitors, id)
+	}
+}
+
+// MonitorLeak is an order monitor which is still running long after its context's deadline, which means it doesn't return when its
+// context is done
+type MonitorLeak struct {
+	Kind      string
+	OrderID   string
+	StartedAt time.Time
+	Deadline  time.Time
+}
+
+// WatchdogSample is a sample of the process's goroutines and memory, and of the order monitors running at the time
+type WatchdogSample struct {
+	Goroutines int
+	HeapBytes  uint64
+	Monitors   map[string]int // The running monitors by their kind
+	Leaked     int            // The running monitors which outlived their deadline by more than the grace
+	NewLeaks   []MonitorLeak  // The leaked monitors which weren't reported by a previous sample
+}
+
+// sample counts the running monitors and finds the ones which are still running grace after their deadline. Each leaked monitor is
+// in the new leaks of a single sample.
+func (l *liveMonitors) sample(now time.Time, grace time.Duration) WatchdogSample {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	sample := WatchdogSample{Monitors: make(map[string]int), NewLeaks: make([]MonitorLeak, 0)}
+	for _, monitor := range l.monitors {
+		sample.Monitors[monitor.kind]++
+		if monitor.deadline.IsZero() || now.Sub(monitor.deadline) <= grace {
+			continue
+		}
+		sample.Leaked++
+		if !monitor.leaked {
+			monitor.leaked = true
+			sample.NewLeaks = append(sample.NewLeaks, MonitorLeak{Kind: monitor.kind, OrderID: monitor.orderID,
+				StartedAt: monitor.startedAt, Deadline: monitor.deadline})
+		}
+	}
+	sort.Slice(sample.NewLeaks, func(i, j int) bool {
+		return sample.NewLeaks[i].StartedAt.Before(sample.NewLeaks[j].StartedAt)
+	})
+	return sample
+}
+
+// trackMonitor adds a monitor of the order for the watchdog, until the returned function is called once the monitor returns
+func (h *Service) trackMonitor(ctx context.Context, kind, orderID string) func() {
+	return h.monitors.start(ctx, kind, orderID, time.Now())
+}
+
+// WatchdogSample samples the goroutines and memory of the process and the running order monitors, and updates their metrics. A
+// monitor is leaked once it runs for longer than WATCHDOG_INTERVAL after its deadline.
+func (h *Service) WatchdogSample(now time.Time) WatchdogSample {
+	sample := h.monitors.sample(now, h.cfg.WatchdogInterval)
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	sample.Goroutines = runtime.NumGoroutine()
+	sample.HeapBytes = memStats.HeapAlloc
+
+	goroutinesGauge.Set(float64(sample.Goroutines))
+	heapBytesGauge.Set(float64(sample.HeapBytes))
+	running := 0
+	for _, kind := range monitorKinds {
+		monitorsGauge.Set(float64(sample.Monitors[kind]), kind)
+		running += sample.Monitors[kind]
+	}
+	if running > 0 {
+		heapBytesPerMonitorGauge.Set(float64(sample.HeapBytes) / float64(running))
+	} else {
+		heapBytesPerMonitorGauge.Set(0)
+	}
+	leakedMonitorsGauge.Set(float64(sample.Leaked))
+	for _, leak := range sample.NewLeaks {
+		monitorLeaksTotal.Inc(leak.Kind)
+	}
+	return sample
+}
+
+// RunWatchdog samples the goroutines, memory and order monitors every WATCHDOG_INTERVAL until the context is done, exposing them
+// as metrics and logging the monitors which are alive past their deadline
+func (h *Service) RunWatchdog(ctx context.Context) {
+	if h.cfg.WatchdogInterval == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(h.cfg.WatchdogInterval)
+	defer ticker.Stop()
+
+	h.schedulers.beat("watchdog", h.cfg.WatchdogInterval)
+	for {
+		select {
+		case now := <-ticker.C:
+			sample := h.WatchdogSample(now)
+			for _, leak := range sample.NewLeaks {
+				h.logger.WarnContext(ctx, "Order monitor is alive past its deadline", "kind", leak.Kind, "group_id", leak.OrderID,
+					"started_at", leak.StartedAt, "deadline", leak.Deadline, "goroutines", sample.Goroutines)
+			}
+			h.schedulers.beat("watchdog", h.cfg.WatchdogInterval)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLiveMonitorsSample(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	monitors := newLiveMonitors()
+	leakingCtx, cancelLeaking := context.WithDeadline(context.Background(), now.Add(time.Minute))
+	defer cancelLeaking()
+	monitors.start(leakingCtx, monitorKindDelivery, "leaking", now)
+	doneVenue := monitors.start(leakingCtx, monitorKindVenue, "returned", now)
+	monitors.start(context.Background(), monitorKindDebts, "no-deadline", now)
+
+	sample := monitors.sample(now.Add(90*time.Second), time.Minute)
+	assert.Equal(t, map[string]int{monitorKindDelivery: 1, monitorKindVenue: 1, monitorKindDebts: 1}, sample.Monitors)
+	assert.Zero(t, sample.Leaked, "the monitors are within the grace after their deadline")
+
+	doneVenue()
+	sample = monitors.sample(now.Add(3*time.Minute), time.Minute)
+	assert.Equal(t, map[string]int{monitorKindDelivery: 1, monitorKindDebts: 1}, sample.Monitors)
+	assert.Equal(t, 1, sample.Leaked)
+	require.Len(t, sample.NewLeaks, 1)
+	assert.Equal(t, MonitorLeak{Kind: monitorKindDelivery, OrderID: "leaking", StartedAt: now, Deadline: now.Add(time.Minute)}, sample.NewLeaks[0])
+
+	sample = monitors.sample(now.Add(4*time.Minute), time.Minute)
+	assert.Equal(t, 1, sample.Leaked)
+	assert.Empty(t, sample.NewLeaks, "a leak is reported once")
+}
+
+func TestWatchdogSample(t *testing.T) {
+	t.Parallel()
+
+	h, err := New(Config{WatchdogInterval: time.Minute}, nil, nil, nil, "UBOT", nil)
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	done := h.trackMonitor(ctx, monitorKindSoloDelivery, "tracking")
+	defer done()
+
+	sample := h.WatchdogSample(time.Now().Add(2 * time.Minute))
+	assert.Positive(t, sample.Goroutines)
+	assert.Positive(t, sample.HeapBytes)
+	assert.Equal(t, 1, sample.Monitors[monitorKindSoloDelivery])
+	require.Len(t, sample.NewLeaks, 1)
+	assert.Equal(t, "tracking", sample.NewLeaks[0].OrderID)
+}
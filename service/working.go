@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const reasonTakenOver = "its link was posted again after it was considered abandoned"
+
+// workingOrders keeps the orders being handled, so a re-posted link of an order isn't handled twice.
+// An order handled for longer than the TTL is considered abandoned (for example, its handling got stuck after a failure),
+// so a re-posted link of it takes it over.
+type workingOrders struct {
+	lock   sync.Mutex
+	ttl    time.Duration // 0 means orders are never considered abandoned
+	orders map[string]*workingOrder
+}
+
+type workingOrder struct {
+	order             *groupOrder // nil until the order is joined
+	startedAt         time.Time
+	timeoutMultiplier int                // The ⏳x<N> directive of the order extends its TTL like its timeouts
+	cancel            context.CancelFunc // Cancels the handling, for a takeover to stop the abandoned one
+}
+
+func newWorkingOrders(ttl time.Duration) *workingOrders {
+	return &workingOrders{ttl: ttl, orders: make(map[string]*workingOrder)}
+}
+
+// start marks the order as working, with the timeout multiplier of the order and the cancel func of its handling. If it's already
+// working, it returns false, unless the existing handling is older than its TTL, in which case it's taken over: the abandoned handling
+// is canceled and returned.
+// The returned entry should be passed to done once the handling is over.
+func (w *workingOrders) start(id string, now time.Time, timeoutMultiplier int, cancel context.CancelFunc) (entry, abandoned *workingOrder,
+	ok bool) {
+	w.lock.Lock()
+	var abandonedOrder *groupOrder
+	if existing, exists := w.orders[id]; exists {
+		if w.ttl == 0 || now.Sub(existing.startedAt) < scaledTTL(w.ttl, existing.timeoutMultiplier) {
+			w.lock.Unlock()
+			return nil, nil, false
+		}
+		abandoned, abandonedOrder = existing, existing.order
+	}
+	entry = &workingOrder{startedAt: now, timeoutMultiplier: timeoutMultiplier, cancel: cancel}
+	w.orders[id] = entry
+	w.lock.Unlock()
+
+	// The abandoned handling is stopped, so it doesn't go on beside the one taking it over
+	if abandoned != nil && abandoned.cancel != nil {
+		abandoned.cancel()
+	}
+	if abandonedOrder != nil {
+		abandonedOrder.stop(reasonTakenOver)
+	}
+	return entry, abandoned, true
+}
+
+// scaledTTL returns the TTL extended by the timeout multiplier of an order, so an order whose timeouts were extended isn't considered
+// abandoned while it's still waiting
+func scaledTTL(ttl time.Duration, timeoutMultiplier int) time.Duration {
+	if timeoutMultiplier < 1 {
+		return ttl
+	}
+	return ttl * time.Duration(timeoutMultiplier)
+}
+
+func (w *workingOrders) setOrder(entry *workingOrder, order *groupOrder) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	entry.order = order
+}
+
+// done removes the order, unless it was taken over by another handling. It returns whether it was removed.
+func (w *workingOrders) done(id string, entry *workingOrder) bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.orders[id] != entry {
+		return false
+	}
+	delete(w.orders, id)
+	return true
+}
+
+// get returns the joined order, or nil if the order isn't working or wasn't joined yet
+func (w *workingOrders) get(id string) *groupOrder {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if entry, ok := w.orders[id]; ok {
+		return entry.order
+	}
+	return nil
+}
+
+// list returns the joined orders being handled
+func (w *workingOrders) list() []*groupOrder {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	orders := make([]*groupOrder, 0, len(w.orders))
+	for _, entry := range w.orders {
+		if entry.order != nil {
+			orders = append(orders, entry.order)
+		}
+	}
+	return orders
+}
+
+// count returns the number of orders being handled, including the ones which weren't joined yet
+func (w *workingOrders) count() int {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return len(w.orders)
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkingOrders(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	working := newWorkingOrders(time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	first, abandoned, ok := working.start("A", now, 1, cancel)
+	require.True(t, ok)
+	assert.Nil(t, abandoned)
+	order := newGroupOrder(context.Background(), "A", nil)
+	working.setOrder(first, order)
+	assert.Equal(t, order, working.get("A"))
+
+	_, _, ok = working.start("A", now.Add(30*time.Minute), 1, nil)
+	assert.False(t, ok, "the order is already working")
+
+	second, abandoned, ok := working.start("A", now.Add(2*time.Hour), 1, nil)
+	require.True(t, ok, "the order is taken over after the TTL")
+	assert.Equal(t, first, abandoned)
+	assert.Error(t, ctx.Err(), "the abandoned handling is canceled")
+	assert.Equal(t, reasonTakenOver, order.stopped())
+	assert.Error(t, order.ctx.Err())
+	assert.Nil(t, working.get("A"), "the new handling didn't join the order yet")
+
+	assert.False(t, working.done("A", first), "the abandoned handling doesn't remove the new one")
+	_, _, ok = working.start("A", now.Add(2*time.Hour), 1, nil)
+	assert.False(t, ok)
+	assert.True(t, working.done("A", second))
+	_, _, ok = working.start("A", now.Add(2*time.Hour), 1, nil)
+	assert.True(t, ok)
+
+	slow := newWorkingOrders(time.Hour)
+	_, _, ok = slow.start("A", now, 3, nil)
+	require.True(t, ok)
+	_, _, ok = slow.start("A", now.Add(2*time.Hour), 1, nil)
+	assert.False(t, ok, "the TTL of an order with ⏳x3 is 3 hours")
+	_, _, ok = slow.start("A", now.Add(3*time.Hour), 1, nil)
+	assert.True(t, ok)
+
+	neverExpires := newWorkingOrders(0)
+	_, _, ok = neverExpires.start("A", now, 1, nil)
+	require.True(t, ok)
+	_, _, ok = neverExpires.start("A", now.Add(1000*time.Hour), 1, nil)
+	assert.False(t, ok)
+}
@@ -0,0 +1,75 @@
+package statuspage
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/oriser/bolt/service"
+)
+
+//go:embed page.html
+var pageTemplate string
+
+// refreshInterval is how often the page reloads itself, so guests see the live delivery status
+const refreshInterval = 30 * time.Second
+
+// Pages returns the status pages of the orders
+type Pages interface {
+	OrderStatusPage(ctx context.Context, groupID, token string) (*service.StatusPage, error)
+}
+
+type handler struct {
+	pages    Pages
+	template *template.Template
+}
+
+// Handler returns the HTTP handler of the public order status pages, serving everything under service.StatusPagePath
+func Handler(pages Pages) http.Handler {
+	return &handler{
+		pages: pages,
+		template: template.Must(template.New("page").Funcs(template.FuncMap{
+			"clock": func(t time.Time) string { return t.Format("15:04") },
+		}).Parse(pageTemplate)),
+	}
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	groupID, token, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, service.StatusPagePath), "/")
+	if !ok || groupID == "" || token == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	page, err := h.pages.OrderStatusPage(r.Context(), groupID, token)
+	if errors.Is(err, service.ErrStatusPageNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting the status page of order %s: %v\n", groupID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	// The token is in the URL, so the page shouldn't leak it to other sites or be kept by shared caches
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("Cache-Control", "no-store")
+	data := struct {
+		*service.StatusPage
+		Refresh int
+	}{StatusPage: page, Refresh: int(refreshInterval.Seconds())}
+	if err := h.template.Execute(w, data); err != nil {
+		log.Printf("Error rendering the status page of order %s: %v\n", groupID, err)
+	}
+}
@@ -0,0 +1,56 @@
+package statuspage
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/oriser/bolt/service"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakePages struct{}
+
+func (fakePages) OrderStatusPage(_ context.Context, groupID, token string) (*service.StatusPage, error) {
+	if groupID != "ABC" || token != "token" {
+		return nil, service.ErrStatusPageNotFound
+	}
+	return &service.StatusPage{
+		OrderID:     "ABC",
+		VenueName:   "Pizza <Place>",
+		Status:      "the order is on its way",
+		DeliveryETA: time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC),
+		Currency:    "ILS",
+		Amounts:     []service.StatusPageAmount{{Name: "Thor", Amount: 50}, {Name: "Loki", Amount: 20}},
+		UpdatedAt:   time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}, nil
+}
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	handler := Handler(fakePages{})
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/status/ABC/token")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "no-referrer", rec.Header().Get("Referrer-Policy"))
+	body := rec.Body.String()
+	assert.Contains(t, body, "Pizza &lt;Place&gt;", "the values are escaped")
+	assert.Contains(t, body, "the order is on its way")
+	assert.Contains(t, body, "Expected delivery: <strong>12:30</strong>")
+	assert.Contains(t, body, "<td>Loki</td><td class=\"amount\">20.00</td>")
+	assert.Contains(t, body, "70.00")
+
+	assert.Equal(t, http.StatusNotFound, get("/status/ABC/wrong").Code)
+	assert.Equal(t, http.StatusNotFound, get("/status/ABC").Code)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/status/ABC/token", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
@@ -0,0 +1,176 @@
+package cached
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/oriser/bolt/cache"
+	userDomain "github.com/oriser/bolt/user"
+)
+
+// usersGenerationKey counts the changes of the users. It prefixes the keys of the cached lookups, so a change through any instance
+// sharing the cache invalidates all of them at once.
+const usersGenerationKey = "users:generation"
+
+// The UserStore caches the lookups of a user store for a TTL, since they are repeated for every participant of every order:
+// 1. GetUser and ListUsers are cached, but not their errors
+// 2. Every change of a user invalidates all the cached lookups
+// 3. The optional user stores are forwarded to the underlying store, like in the UserStoreCombined
+// Errors of the cache itself are ignored, looking the users up in the underlying store.
+
+type UserStore struct {
+	store userDomain.Store
+	cache cache.Cache
+	ttl   time.Duration
+}
+
+func NewUserStore(store userDomain.Store, c cache.Cache, ttl time.Duration) *UserStore {
+	return &UserStore{
+		store: store,
+		cache: c,
+		ttl:   ttl,
+	}
+}
+
+func (s *UserStore) AddUser(ctx context.Context, user *userDomain.User) error {
+	defer s.invalidate(ctx)
+	return s.store.AddUser(ctx, user)
+}
+
+func (s *UserStore) GetUser(ctx context.Context, id string) (*userDomain.User, error) {
+	var user *userDomain.User
+	err := s.lookup(ctx, "get:"+id, &user, func() (any, error) {
+		var err error
+		user, err = s.store.GetUser(ctx, id)
+		return user, err
+	})
+	return user, err
+}
+
+func (s *UserStore) ListUsers(ctx context.Context, filter userDomain.ListFilter) ([]*userDomain.User, error) {
+	key, err := json.Marshal(filter)
+	if err != nil {
+		return s.store.ListUsers(ctx, filter)
+	}
+	var users []*userDomain.User
+	err = s.lookup(ctx, "list:"+string(key), &users, func() (any, error) {
+		var err error
+		users, err = s.store.ListUsers(ctx, filter)
+		return users, err
+	})
+	return users, err
+}
+
+// lookup decodes the cached value of the key into value, or sets it by calling fetch and caches it
+func (s *UserStore) lookup(ctx context.Context, key string, value any, fetch func() (any, error)) error {
+	generation, _, err := s.cache.Get(ctx, usersGenerationKey)
+	if err != nil {
+		_, err = fetch()
+		return err
+	}
+	key = fmt.Sprintf("users:%s:%s", generation, key)
+	if cached, ok, err := s.cache.Get(ctx, key); err == nil && ok && json.Unmarshal(cached, value) == nil {
+		return nil
+	}
+
+	fetched, err := fetch()
+	if err != nil {
+		return err
+	}
+	if encoded, err := json.Marshal(fetched); err == nil {
+		_ = s.cache.Set(ctx, key, encoded, s.ttl)
+	}
+	return nil
+}
+
+// invalidate drops all the cached lookups by moving to the next generation
+func (s *UserStore) invalidate(ctx context.Context) {
+	_, _ = s.cache.Incr(ctx, usersGenerationKey)
+}
+
+// SetUserDeactivatedAt deactivates the user in the underlying storage, if it supports deactivation
+func (s *UserStore) SetUserDeactivatedAt(ctx context.Context, id string, deactivatedAt *time.Time) error {
+	store, ok := s.store.(userDomain.DeactivationStore)
+	if !ok {
+		return fmt.Errorf("deactivating users is not supported")
+	}
+	defer s.invalidate(ctx)
+	return store.SetUserDeactivatedAt(ctx, id, deactivatedAt)
+}
+
+// SetPaymentMethods sets the payment methods of the user in the underlying storage, if it supports payment methods
+func (s *UserStore) SetPaymentMethods(ctx context.Context, transportID string, methods []userDomain.PaymentMethod) error {
+	store, ok := s.store.(userDomain.PaymentMethodsStore)
+	if !ok {
+		return fmt.Errorf("payment methods are not supported")
+	}
+	defer s.invalidate(ctx)
+	return store.SetPaymentMethods(ctx, transportID, methods)
+}
+
+// PaymentMethods returns the payment methods of the user from the underlying storage, or none if it doesn't support payment methods
+func (s *UserStore) PaymentMethods(ctx context.Context, transportID string) ([]userDomain.PaymentMethod, error) {
+	store, ok := s.store.(userDomain.PaymentMethodsStore)
+	if !ok {
+		return nil, nil
+	}
+	return store.PaymentMethods(ctx, transportID)
+}
+
+// SetBankAccount sets the bank account of the user in the underlying storage, if it supports bank accounts
+func (s *UserStore) SetBankAccount(ctx context.Context, transportID string, account *userDomain.BankAccount) error {
+	store, ok := s.store.(userDomain.BankAccountStore)
+	if !ok {
+		return fmt.Errorf("bank accounts are not supported")
+	}
+	defer s.invalidate(ctx)
+	return store.SetBankAccount(ctx, transportID, account)
+}
+
+// BankAccount returns the bank account of the user from the underlying storage, or none if it doesn't support bank accounts
+func (s *UserStore) BankAccount(ctx context.Context, transportID string) (*userDomain.BankAccount, error) {
+	store, ok := s.store.(userDomain.BankAccountStore)
+	if !ok {
+		return nil, nil
+	}
+	return store.BankAccount(ctx, transportID)
+}
+
+// SetDebtDMs sets whether the user gets the debt direct messages in the underlying storage, if it supports them
+func (s *UserStore) SetDebtDMs(ctx context.Context, transportID string, enabled bool) error {
+	store, ok := s.store.(userDomain.DebtDMsStore)
+	if !ok {
+		return fmt.Errorf("debt direct messages are not supported")
+	}
+	return store.SetDebtDMs(ctx, transportID, enabled)
+}
+
+// DebtDMs returns whether the user gets the debt direct messages from the underlying storage, or that they didn't choose if it
+// doesn't support them
+func (s *UserStore) DebtDMs(ctx context.Context, transportID string) (bool, bool, error) {
+	store, ok := s.store.(userDomain.DebtDMsStore)
+	if !ok {
+		return false, false, nil
+	}
+	return store.DebtDMs(ctx, transportID)
+}
+
+// SetPrivateAmounts sets whether the user's amounts are private in the underlying storage, if it supports private amounts
+func (s *UserStore) SetPrivateAmounts(ctx context.Context, transportID string, private bool) error {
+	store, ok := s.store.(userDomain.PrivateAmountsStore)
+	if !ok {
+		return fmt.Errorf("private amounts are not supported")
+	}
+	return store.SetPrivateAmounts(ctx, transportID, private)
+}
+
+// PrivateAmounts returns whether the user's amounts are private from the underlying storage, or false if it doesn't support them
+func (s *UserStore) PrivateAmounts(ctx context.Context, transportID string) (bool, error) {
+	store, ok := s.store.(userDomain.PrivateAmountsStore)
+	if !ok {
+		return false, nil
+	}
+	return store.PrivateAmounts(ctx, transportID)
+}
@@ -0,0 +1,69 @@
+package cached
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oriser/bolt/cache"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type countingUserStore struct {
+	users []*userDomain.User
+	lists int
+}
+
+func (s *countingUserStore) AddUser(_ context.Context, user *userDomain.User) error {
+	s.users = append(s.users, user)
+	return nil
+}
+
+func (s *countingUserStore) GetUser(_ context.Context, id string) (*userDomain.User, error) {
+	for _, user := range s.users {
+		if user.ID == id {
+			return user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *countingUserStore) ListUsers(_ context.Context, filter userDomain.ListFilter) ([]*userDomain.User, error) {
+	s.lists++
+	var ret []*userDomain.User
+	for _, user := range s.users {
+		if len(filter.Names) == 0 || filter.Names[0] == user.FullName {
+			ret = append(ret, user)
+		}
+	}
+	return ret, nil
+}
+
+func TestUserStore(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	underlying := &countingUserStore{users: []*userDomain.User{{ID: "1", FullName: "Thor", TransportID: "U1"}}}
+	store := NewUserStore(underlying, cache.NewMemory(), time.Hour)
+
+	for i := 0; i < 3; i++ {
+		users, err := store.ListUsers(ctx, userDomain.ListFilter{Names: []string{"Thor"}})
+		require.NoError(t, err)
+		require.Len(t, users, 1)
+		assert.Equal(t, "U1", users[0].TransportID)
+	}
+	assert.Equal(t, 1, underlying.lists, "the lookups are cached")
+
+	require.NoError(t, store.AddUser(ctx, &userDomain.User{ID: "2", FullName: "Loki", TransportID: "U2"}))
+	users, err := store.ListUsers(ctx, userDomain.ListFilter{})
+	require.NoError(t, err)
+	assert.Len(t, users, 2, "adding a user invalidates the cached lookups")
+
+	user, err := store.GetUser(ctx, "2")
+	require.NoError(t, err)
+	assert.Equal(t, "Loki", user.FullName)
+
+	assert.EqualError(t, store.SetUserDeactivatedAt(ctx, "1", nil), "deactivating users is not supported")
+}
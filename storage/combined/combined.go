@@ -3,6 +3,7 @@ package combined
 import (
 	"context"
 	"fmt"
+	"time"
 
 	userDomain "github.com/oriser/bolt/user"
 )
@@ -10,7 +11,9 @@ import (
 // The UserStoreCombined combines basically just listing users. it takes 2 user stores and do the following:
 // 1. For AddUser, adding just to the first
 // 2. For GetUser, try to get from the first, if had an error, takes from the second
-// 3. For ListUsers, listing the first, then listing the second and combines them
+// 3. For ListUsers, listing the first, then listing the second and combines them, paginating the combined users
+// 4. For SetUserDeactivatedAt, deactivating just in the first
+// 5. For the payment methods, bank accounts, debt DMs and private amounts preferences, keeping them just in the first
 
 type UserStoreCombined struct {
 	first  userDomain.Store
@@ -29,6 +32,10 @@ func (p *UserStoreCombined) AddUser(ctx context.Context, user *userDomain.User)
 }
 
 func (p *UserStoreCombined) ListUsers(ctx context.Context, filter userDomain.ListFilter) ([]*userDomain.User, error) {
+	// The page is of the users of both storages, so it's taken after listing all of them
+	page := filter
+	filter.Limit, filter.Offset = 0, 0
+
 	users, err := p.first.ListUsers(ctx, filter)
 	if err != nil {
 		return nil, fmt.Errorf("listing users from first storage: %w", err)
@@ -36,7 +43,7 @@ func (p *UserStoreCombined) ListUsers(ctx context.Context, filter userDomain.Lis
 
 	if len(filter.Names) == 1 && filter.TransportID == "" && len(users) == 1 {
 		// If we only asked to search for a single user, and we got it from the first storage, no need to list from second storage
-		return users, nil
+		return page.Page(users), nil
 	}
 
 	secondUsers, err := p.second.ListUsers(ctx, filter)
@@ -44,7 +51,7 @@ func (p *UserStoreCombined) ListUsers(ctx context.Context, filter userDomain.Lis
 		return nil, fmt.Errorf("listing users from second storage: %w", err)
 	}
 	users = append(users, secondUsers...)
-	return users, nil
+	return page.Page(users), nil
 }
 
 func (p *UserStoreCombined) GetUser(ctx context.Context, id string) (*userDomain.User, error) {
@@ -54,3 +61,85 @@ func (p *UserStoreCombined) GetUser(ctx context.Context, id string) (*userDomain
 	}
 	return user, err
 }
+
+// SetUserDeactivatedAt deactivates the user in the first storage, if it supports deactivation
+func (p *UserStoreCombined) SetUserDeactivatedAt(ctx context.Context, id string, deactivatedAt *time.Time) error {
+	store, ok := p.first.(userDomain.DeactivationStore)
+	if !ok {
+		return fmt.Errorf("deactivating users is not supported")
+	}
+	return store.SetUserDeactivatedAt(ctx, id, deactivatedAt)
+}
+
+// SetPaymentMethods sets the payment methods of the user in the first storage, if it supports payment methods
+func (p *UserStoreCombined) SetPaymentMethods(ctx context.Context, transportID string, methods []userDomain.PaymentMethod) error {
+	store, ok := p.first.(userDomain.PaymentMethodsStore)
+	if !ok {
+		return fmt.Errorf("payment methods are not supported")
+	}
+	return store.SetPaymentMethods(ctx, transportID, methods)
+}
+
+// PaymentMethods returns the payment methods of the user from the first storage, or none if it doesn't support payment methods
+func (p *UserStoreCombined) PaymentMethods(ctx context.Context, transportID string) ([]userDomain.PaymentMethod, error) {
+	store, ok := p.first.(userDomain.PaymentMethodsStore)
+	if !ok {
+		return nil, nil
+	}
+	return store.PaymentMethods(ctx, transportID)
+}
+
+// SetBankAccount sets the bank account of the user in the first storage, if it supports bank accounts
+func (p *UserStoreCombined) SetBankAccount(ctx context.Context, transportID string, account *userDomain.BankAccount) error {
+	store, ok := p.first.(userDomain.BankAccountStore)
+	if !ok {
+		return fmt.Errorf("bank accounts are not supported")
+	}
+	return store.SetBankAccount(ctx, transportID, account)
+}
+
+// BankAccount returns the bank account of the user from the first storage, or none if it doesn't support bank accounts
+func (p *UserStoreCombined) BankAccount(ctx context.Context, transportID string) (*userDomain.BankAccount, error) {
+	store, ok := p.first.(userDomain.BankAccountStore)
+	if !ok {
+		return nil, nil
+	}
+	return store.BankAccount(ctx, transportID)
+}
+
+// SetDebtDMs sets whether the user gets the debt direct messages in the first storage, if it supports them
+func (p *UserStoreCombined) SetDebtDMs(ctx context.Context, transportID string, enabled bool) error {
+	store, ok := p.first.(userDomain.DebtDMsStore)
+	if !ok {
+		return fmt.Errorf("debt direct messages are not supported")
+	}
+	return store.SetDebtDMs(ctx, transportID, enabled)
+}
+
+// DebtDMs returns whether the user gets the debt direct messages from the first storage, or that they didn't choose if it doesn't
+// support them
+func (p *UserStoreCombined) DebtDMs(ctx context.Context, transportID string) (bool, bool, error) {
+	store, ok := p.first.(userDomain.DebtDMsStore)
+	if !ok {
+		return false, false, nil
+	}
+	return store.DebtDMs(ctx, transportID)
+}
+
+// SetPrivateAmounts sets whether the user's amounts are private in the first storage, if it supports private amounts
+func (p *UserStoreCombined) SetPrivateAmounts(ctx context.Context, transportID string, private bool) error {
+	store, ok := p.first.(userDomain.PrivateAmountsStore)
+	if !ok {
+		return fmt.Errorf("private amounts are not supported")
+	}
+	return store.SetPrivateAmounts(ctx, transportID, private)
+}
+
+// PrivateAmounts returns whether the user's amounts are private from the first storage, or false if it doesn't support them
+func (p *UserStoreCombined) PrivateAmounts(ctx context.Context, transportID string) (bool, error) {
+	store, ok := p.first.(userDomain.PrivateAmountsStore)
+	if !ok {
+		return false, nil
+	}
+	return store.PrivateAmounts(ctx, transportID)
+}
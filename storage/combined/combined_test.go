@@ -0,0 +1,25 @@
+package combined
+
+import (
+	"testing"
+
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptionalStores(t *testing.T) {
+	t.Parallel()
+
+	// The service finds the optional features of the user store by type assertions, so the combined store has to forward all of them
+	var store userDomain.Store = NewPrioritizedUserStore(nil, nil)
+	_, ok := store.(userDomain.DeactivationStore)
+	assert.True(t, ok, "deactivation")
+	_, ok = store.(userDomain.PaymentMethodsStore)
+	assert.True(t, ok, "payment methods")
+	_, ok = store.(userDomain.BankAccountStore)
+	assert.True(t, ok, "bank accounts")
+	_, ok = store.(userDomain.DebtDMsStore)
+	assert.True(t, ok, "debt DMs")
+	_, ok = store.(userDomain.PrivateAmountsStore)
+	assert.True(t, ok, "private amounts")
+}
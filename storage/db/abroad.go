@@ -0,0 +1,52 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+func (d *DBStore) SetAbroadCurrency(ctx context.Context, transportID, currency string) error {
+	query, args, err := d.builder.Insert("abroad_users").Values(transportID, currency, time.Now().UTC()).
+		Suffix(onConflictUpdate([]string{"transport_id"}, "currency", "created_at")).ToSql()
+	if err != nil {
+		return fmt.Errorf("generating insert SQL: %w", err)
+	}
+
+	if _, err = d.db.ExecContext(ctx, query, args...); err != nil {
+		return newExecError("setting abroad currency", query, err, args...)
+	}
+	return nil
+}
+
+func (d *DBStore) AbroadCurrency(ctx context.Context, transportID string) (string, error) {
+	query, args, err := d.builder.Select("currency").From("abroad_users").Where(sq.Eq{"transport_id": transportID}).ToSql()
+	if err != nil {
+		return "", fmt.Errorf("generating select SQL: %w", err)
+	}
+
+	currency := ""
+	if err = d.db.GetContext(ctx, &currency, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return "", newExecError("selecting abroad currency", query, err, args...)
+	}
+	return currency, nil
+}
+
+func (d *DBStore) RemoveAbroadCurrency(ctx context.Context, transportID string) error {
+	query, args, err := d.builder.Delete("abroad_users").Where(sq.Eq{"transport_id": transportID}).ToSql()
+	if err != nil {
+		return fmt.Errorf("generating delete SQL: %w", err)
+	}
+
+	if _, err = d.db.ExecContext(ctx, query, args...); err != nil {
+		return newExecError("removing abroad currency", query, err, args...)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/oriser/bolt/order"
+)
+
+// SetArchive sets the archive of the orders moved out of the store, so listing and counting the orders reads through to it
+func (d *DBStore) SetArchive(archive order.Archive) {
+	d.archive = archive
+}
+
+// listOrdersWithArchive lists the orders of the store and of the archive together. Orders which were archived but not removed from
+// the store yet are listed once.
+func (d *DBStore) listOrdersWithArchive(ctx context.Context, filter order.ListFilter) ([]*order.Order, error) {
+	storedFilter := filter
+	storedFilter.Offset = 0
+	if filter.Limit > 0 {
+		storedFilter.Limit = filter.Offset + filter.Limit
+	}
+	stored, err := d.listStoredOrders(ctx, storedFilter)
+	if err != nil {
+		return nil, err
+	}
+	archived, err := d.archive.ArchivedOrders(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list archived orders: %w", err)
+	}
+
+	storedIDs := make(map[string]bool, len(stored))
+	for _, o := range stored {
+		storedIDs[o.ID] = true
+	}
+	for _, o := range archived {
+		if !storedIDs[o.ID] {
+			stored = append(stored, o)
+		}
+	}
+	return filter.Apply(stored), nil
+}
+
+// ListOrdersToArchive returns the orders created before the given time, from the oldest
+func (d *DBStore) ListOrdersToArchive(ctx context.Context, before time.Time) ([]*order.Order, error) {
+	sql, args, err := d.builder.Select("*").From("orders").Where(sq.Lt{"created_at": before}).OrderBy("created_at", "id").ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("generating select SQL: %w", err)
+	}
+
+	var models []*orderModel
+	if err = d.db.SelectContext(ctx, &models, sql, args...); err != nil {
+		return nil, newExecError("selecting orders to archive", sql, err, args...)
+	}
+	return ordersOfModels(models)
+}
+
+// RemoveOrders removes the orders with their participants
+func (d *DBStore) RemoveOrders(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	sql, args, err := d.builder.Delete("order_participants").Where(sq.Eq{"order_id": ids}).ToSql()
+	if err != nil {
+		return fmt.Errorf("generating delete SQL: %w", err)
+	}
+	if _, err = tx.ExecContext(ctx, sql, args...); err != nil {
+		return newExecError("deleting order participants", sql, err, args...)
+	}
+	sql, args, err = d.builder.Delete("orders").Where(sq.Eq{"id": ids}).ToSql()
+	if err != nil {
+		return fmt.Errorf("generating delete SQL: %w", err)
+	}
+	if _, err = tx.ExecContext(ctx, sql, args...); err != nil {
+		return newExecError("deleting orders", sql, err, args...)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
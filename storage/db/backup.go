@@ -0,0 +1,393 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/oriser/bolt/backup"
+	"github.com/oriser/bolt/debt"
+	"github.com/oriser/bolt/order"
+	"github.com/oriser/bolt/token"
+	"github.com/oriser/bolt/user"
+)
+
+// backupTables are the tables included in a dump, which must be empty for importing one. The order_ref_sequence table always has
+// its single row, which the dump keeps the value of.
+var backupTables = []string{"users", "orders", "order_participants", "debts", "debt_payments", "pending_debts", "venue_blacklist",
+	"channel_settings", "insights_subscribers", "abroad_users", "reminder_opt_outs", "digest_users", "digest_notifications",
+	"payment_methods", "api_tokens", "rates_snapshots", "debt_dm_preferences", "bank_accounts", "venue_stats",
+	"private_amount_preferences"}
+
+// transientTables are the tables which aren't included in a dump, as they only hold the state of the running Bolt: the links in
+// transit between the components, the orders being tracked and the last handled link of each channel
+var transientTables = []string{"queue_messages", "tracked_orders", "link_cursors", "order_ref_sequence"}
+
+func (d *DBStore) selectAll(tx *sqlx.Tx, dest interface{}, table, orderBy string) error {
+	sql, args, err := d.builder.Select("*").From(table).OrderBy(orderBy).ToSql()
+	if err != nil {
+		return fmt.Errorf("generating select SQL: %w", err)
+	}
+	if err = tx.Select(dest, sql, args...); err != nil {
+		return newExecError("selecting "+table, sql, err, args...)
+	}
+	return nil
+}
+
+// Export returns a dump of the store. All the tables are read in a single transaction, so the dump is consistent.
+func (d *DBStore) Export(ctx context.Context) (*backup.Dump, error) {
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	dump := &backup.Dump{Version: backup.Version, CreatedAt: time.Now().UTC()}
+	var users []*userModel
+	if err = d.selectAll(tx, &users, "users", "created_at"); err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		dump.Users = append(dump.Users, u.User)
+	}
+
+	var orders []*orderModel
+	if err = d.selectAll(tx, &orders, "orders", "created_at"); err != nil {
+		return nil, err
+	}
+	if dump.Orders, err = ordersOfModels(orders); err != nil {
+		return nil, err
+	}
+	for _, o := range dump.Orders {
+		o.CreatedAt = o.CreatedAt.UTC()
+	}
+
+	dump.Debts = []*debt.Debt{}
+	if err = d.selectAll(tx, &dump.Debts, "debts", "created_at"); err != nil {
+		return nil, err
+	}
+	for _, debt := range dump.Debts {
+		// Debts used to be saved in the local time
+		debt.CreatedAt = debt.CreatedAt.UTC()
+	}
+	dump.Payments = []*debt.Payment{}
+	if err = d.selectAll(tx, &dump.Payments, "debt_payments", "paid_at"); err != nil {
+		return nil, err
+	}
+	dump.PendingDebts = []*debt.PendingDebt{}
+	if err = d.selectAll(tx, &dump.PendingDebts, "pending_debts", "created_at"); err != nil {
+		return nil, err
+	}
+	dump.Config.BlacklistedVenues = []*order.BlacklistedVenue{}
+	if err = d.selectAll(tx, &dump.Config.BlacklistedVenues, "venue_blacklist", "created_at"); err != nil {
+		return nil, err
+	}
+	dump.Config.ChannelSettings = []*order.ChannelSetting{}
+	if err = d.selectAll(tx, &dump.Config.ChannelSettings, "channel_settings", "updated_at"); err != nil {
+		return nil, err
+	}
+	var subscribers []struct {
+		TransportID string    `db:"transport_id"`
+		CreatedAt   time.Time `db:"created_at"`
+	}
+	if err = d.selectAll(tx, &subscribers, "insights_subscribers", "created_at"); err != nil {
+		return nil, err
+	}
+	dump.Config.InsightsSubscribers = make([]string, len(subscribers))
+	for i, subscriber := range subscribers {
+		dump.Config.InsightsSubscribers[i] = subscriber.TransportID
+	}
+	var abroad []struct {
+		TransportID string    `db:"transport_id"`
+		Currency    string    `db:"currency"`
+		CreatedAt   time.Time `db:"created_at"`
+	}
+	if err = d.selectAll(tx, &abroad, "abroad_users", "created_at"); err != nil {
+		return nil, err
+	}
+	dump.Config.AbroadCurrencies = make(map[string]string, len(abroad))
+	for _, a := range abroad {
+		dump.Config.AbroadCurrencies[a.TransportID] = a.Currency
+	}
+	var optOuts []struct {
+		TransportID string    `db:"transport_id"`
+		CreatedAt   time.Time `db:"created_at"`
+	}
+	if err = d.selectAll(tx, &optOuts, "reminder_opt_outs", "created_at"); err != nil {
+		return nil, err
+	}
+	dump.Config.ReminderOptOuts = make([]string, len(optOuts))
+	for i, optOut := range optOuts {
+		dump.Config.ReminderOptOuts[i] = optOut.TransportID
+	}
+	var digests []struct {
+		TransportID string    `db:"transport_id"`
+		Hour        int       `db:"hour"`
+		CreatedAt   time.Time `db:"created_at"`
+	}
+	if err = d.selectAll(tx, &digests, "digest_users", "created_at"); err != nil {
+		return nil, err
+	}
+	dump.Config.DigestHours = make(map[string]int, len(digests))
+	for _, digest := range digests {
+		dump.Config.DigestHours[digest.TransportID] = digest.Hour
+	}
+	var paymentMethods []struct {
+		TransportID string    `db:"transport_id"`
+		Methods     string    `db:"methods"`
+		CreatedAt   time.Time `db:"created_at"`
+	}
+	if err = d.selectAll(tx, &paymentMethods, "payment_methods", "created_at"); err != nil {
+		return nil, err
+	}
+	dump.Config.PaymentMethods = make(map[string][]string, len(paymentMethods))
+	for _, p := range paymentMethods {
+		dump.Config.PaymentMethods[p.TransportID] = strings.Split(p.Methods, paymentMethodsSeparator)
+	}
+	dump.Config.APITokens = []*token.Token{}
+	if err = d.selectAll(tx, &dump.Config.APITokens, "api_tokens", "created_at"); err != nil {
+		return nil, err
+	}
+	sql, args, err := d.builder.Select("value").From("order_ref_sequence").Where(sq.Eq{"id": 1}).ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("generating select SQL: %w", err)
+	}
+	if err = tx.Get(&dump.Config.OrderRefSequence, sql, args...); err != nil {
+		return nil, newExecError("selecting order ref sequence", sql, err, args...)
+	}
+	var bankAccounts []struct {
+		TransportID string    `db:"transport_id"`
+		Holder      string    `db:"holder"`
+		IBAN        string    `db:"iban"`
+		UpdatedAt   time.Time `db:"updated_at"`
+	}
+	if err = d.selectAll(tx, &bankAccounts, "bank_accounts", "updated_at"); err != nil {
+		return nil, err
+	}
+	dump.Config.BankAccounts = make(map[string]*user.BankAccount, len(bankAccounts))
+	for _, account := range bankAccounts {
+		dump.Config.BankAccounts[account.TransportID] = &user.BankAccount{Holder: account.Holder, IBAN: account.IBAN}
+	}
+	var debtDMs []struct {
+		TransportID string    `db:"transport_id"`
+		Enabled     bool      `db:"enabled"`
+		UpdatedAt   time.Time `db:"updated_at"`
+	}
+	if err = d.selectAll(tx, &debtDMs, "debt_dm_preferences", "updated_at"); err != nil {
+		return nil, err
+	}
+	dump.Config.DebtDMs = make(map[string]bool, len(debtDMs))
+	for _, preference := range debtDMs {
+		dump.Config.DebtDMs[preference.TransportID] = preference.Enabled
+	}
+	var privateAmounts []struct {
+		TransportID string    `db:"transport_id"`
+		Private     bool      `db:"private"`
+		UpdatedAt   time.Time `db:"updated_at"`
+	}
+	if err = d.selectAll(tx, &privateAmounts, "private_amount_preferences", "updated_at"); err != nil {
+		return nil, err
+	}
+	dump.Config.PrivateAmounts = make(map[string]bool, len(privateAmounts))
+	for _, preference := range privateAmounts {
+		dump.Config.PrivateAmounts[preference.TransportID] = preference.Private
+	}
+	dump.Config.VenueStats = []*order.VenueStats{}
+	if err = d.selectAll(tx, &dump.Config.VenueStats, "venue_stats", "venue_name"); err != nil {
+		return nil, err
+	}
+	for _, stats := range dump.Config.VenueStats {
+		stats.UpdatedAt = stats.UpdatedAt.UTC()
+	}
+	dump.RatesSnapshots = []*order.RatesSnapshot{}
+	if err = d.selectAll(tx, &dump.RatesSnapshots, "rates_snapshots", "published_at"); err != nil {
+		return nil, err
+	}
+	for _, snapshot := range dump.RatesSnapshots {
+		snapshot.PublishedAt = snapshot.PublishedAt.UTC()
+	}
+	dump.DigestNotifications = []*debt.DigestNotification{}
+	if err = d.selectAll(tx, &dump.DigestNotifications, "digest_notifications", "created_at"); err != nil {
+		return nil, err
+	}
+	for _, notification := range dump.DigestNotifications {
+		notification.CreatedAt = notification.CreatedAt.UTC()
+	}
+
+	return dump, nil
+}
+
+func (d *DBStore) isEmpty(tx *sqlx.Tx) (bool, error) {
+	for _, table := range backupTables {
+		sql, args, err := d.builder.Select("COUNT(*)").From(table).ToSql()
+		if err != nil {
+			return false, fmt.Errorf("generating count SQL: %w", err)
+		}
+		count := 0
+		if err = tx.Get(&count, sql, args...); err != nil {
+			return false, newExecError("counting "+table, sql, err, args...)
+		}
+		if count > 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (d *DBStore) insertRow(tx *sqlx.Tx, table string, values ...interface{}) error {
+	sql, args, err := d.builder.Insert(table).Values(values...).ToSql()
+	if err != nil {
+		return fmt.Errorf("generating insert SQL: %w", err)
+	}
+	if _, err = tx.Exec(sql, args...); err != nil {
+		return newExecError("inserting into "+table, sql, err, args...)
+	}
+	return nil
+}
+
+// Import adds all the records of the dump in a single transaction, so a failed import leaves the store empty
+func (d *DBStore) Import(ctx context.Context, dump *backup.Dump) error {
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	empty, err := d.isEmpty(tx)
+	if err != nil {
+		return err
+	}
+	if !empty {
+		return fmt.Errorf("the store isn't empty")
+	}
+
+	now := time.Now().UTC()
+	for _, u := range dump.Users {
+		if err = d.insertRow(tx, "users", u.ID, u.FullName, u.Email, u.Phone, u.Timezone, u.TransportID, now, u.DeactivatedAt); err != nil {
+			return err
+		}
+	}
+	for _, o := range dump.Orders {
+		if err = d.insertOrder(tx, o, now); err != nil {
+			return err
+		}
+	}
+	for _, debt := range dump.Debts {
+		if err = d.insertRow(tx, "debts", debt.ID, debt.BorrowerID, debt.LenderID, debt.OrderID, debt.Amount, debt.InitiatedTransportID,
+			debt.MessageID, debt.CreatedAt.UTC(), debt.Currency, utcTimePtr(debt.PaidClaimedAt), debt.PaymentConfirmedBy,
+			utcTimePtr(debt.PaymentConfirmedAt), debt.EscalatedDays); err != nil {
+			return err
+		}
+	}
+	for _, payment := range dump.Payments {
+		if err = d.insertRow(tx, "debt_payments", payment.ID, payment.BorrowerID, payment.LenderID, payment.OrderID, payment.Amount,
+			payment.InitiatedTransportID, payment.MessageID, payment.CreatedAt.UTC(), payment.PaidAt.UTC(), payment.Currency,
+			utcTimePtr(payment.PaidClaimedAt), payment.PaymentConfirmedBy, utcTimePtr(payment.PaymentConfirmedAt)); err != nil {
+			return err
+		}
+	}
+	for _, pending := range dump.PendingDebts {
+		if err = d.insertRow(tx, "pending_debts", pending.ID, pending.WoltName, pending.LenderID, pending.OrderID, pending.Amount,
+			pending.InitiatedTransportID, pending.MessageID, pending.CreatedAt.UTC(), pending.Currency); err != nil {
+			return err
+		}
+	}
+	for _, venue := range dump.Config.BlacklistedVenues {
+		if err = d.insertRow(tx, "venue_blacklist", venue.Channel, venue.VenueName, venue.Reason, venue.AddedBy, venue.CreatedAt.UTC()); err != nil {
+			return err
+		}
+	}
+	for _, setting := range dump.Config.ChannelSettings {
+		if err = d.insertRow(tx, "channel_settings", setting.Channel, setting.Name, setting.Value, setting.UpdatedBy, setting.UpdatedAt.UTC()); err != nil {
+			return err
+		}
+	}
+	for _, transportID := range dump.Config.InsightsSubscribers {
+		if err = d.insertRow(tx, "insights_subscribers", transportID, now); err != nil {
+			return err
+		}
+	}
+	for transportID, currency := range dump.Config.AbroadCurrencies {
+		if err = d.insertRow(tx, "abroad_users", transportID, currency, now); err != nil {
+			return err
+		}
+	}
+	for _, transportID := range dump.Config.ReminderOptOuts {
+		if err = d.insertRow(tx, "reminder_opt_outs", transportID, now); err != nil {
+			return err
+		}
+	}
+	for transportID, hour := range dump.Config.DigestHours {
+		if err = d.insertRow(tx, "digest_users", transportID, hour, now); err != nil {
+			return err
+		}
+	}
+	for transportID, methods := range dump.Config.PaymentMethods {
+		if err = d.insertRow(tx, "payment_methods", transportID, strings.Join(methods, paymentMethodsSeparator), now); err != nil {
+			return err
+		}
+	}
+	for _, t := range dump.Config.APITokens {
+		var revokedAt interface{}
+		if t.RevokedAt != nil {
+			revokedAt = t.RevokedAt.UTC()
+		}
+		if err = d.insertRow(tx, "api_tokens", t.ID, t.Name, t.Hash, t.Scope, t.UserID, t.CreatedAt.UTC(), revokedAt); err != nil {
+			return err
+		}
+	}
+	for transportID, account := range dump.Config.BankAccounts {
+		if err = d.insertRow(tx, "bank_accounts", transportID, account.Holder, account.IBAN, now); err != nil {
+			return err
+		}
+	}
+	for transportID, enabled := range dump.Config.DebtDMs {
+		if err = d.insertRow(tx, "debt_dm_preferences", transportID, enabled, now); err != nil {
+			return err
+		}
+	}
+	for transportID, private := range dump.Config.PrivateAmounts {
+		if err = d.insertRow(tx, "private_amount_preferences", transportID, private, now); err != nil {
+			return err
+		}
+	}
+	for _, stats := range dump.Config.VenueStats {
+		if err = d.insertRow(tx, "venue_stats", stats.VenueName, stats.Deliveries, int64(stats.AverageDelivery), int64(stats.LongestDelivery),
+			int64(stats.DoneTimeout), int64(stats.StatusCheckInterval), stats.UpdatedAt.UTC()); err != nil {
+			return err
+		}
+	}
+	for _, snapshot := range dump.RatesSnapshots {
+		if err = d.insertRow(tx, "rates_snapshots", snapshot.OriginalID, snapshot.Receiver, snapshot.MessageID, snapshot.PublishedAt.UTC(),
+			snapshot.Message, snapshot.ItemRates, snapshot.Host, snapshot.DeliveryRate, snapshot.Discount, snapshot.FeeAllocation,
+			snapshot.DiscountAllocation, snapshot.AmountRounding, snapshot.RoundingRemainder, snapshot.Currency); err != nil {
+			return err
+		}
+	}
+	for _, notification := range dump.DigestNotifications {
+		if err = d.insertRow(tx, "digest_notifications", notification.TransportID, notification.Key, notification.Text,
+			notification.CreatedAt.UTC()); err != nil {
+			return err
+		}
+	}
+	// The sequence always has its single row, so the references of the orders added after the import continue it
+	sql, args, err := d.builder.Update("order_ref_sequence").Set("value", dump.Config.OrderRefSequence).Where(sq.Eq{"id": 1}).ToSql()
+	if err != nil {
+		return fmt.Errorf("generating update SQL: %w", err)
+	}
+	if _, err = tx.Exec(sql, args...); err != nil {
+		return newExecError("setting order ref sequence", sql, err, args...)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oriser/bolt/backup"
+	"github.com/oriser/bolt/debt"
+	"github.com/oriser/bolt/order"
+	"github.com/oriser/bolt/token"
+	userDomain "github.com/oriser/bolt/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupRestore(t *testing.T) {
+	t.Parallel()
+
+	source := NewDBTest(t)
+	target := NewDBTest(t)
+	migrated := NewDBTest(t)
+	t.Cleanup(func() {
+		source.Cleanup(t)
+		target.Cleanup(t)
+		migrated.Cleanup(t)
+	})
+	ctx := context.Background()
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	require.NoError(t, source.db.AddUser(ctx, &userDomain.User{ID: "U1", FullName: "Loki", Email: "loki@asgard.com", Timezone: "UTC", TransportID: "S1"}))
+	require.NoError(t, source.db.SaveOrder(ctx, getDummyOrder()))
+	require.NoError(t, source.db.AddDebt(ctx, debt.NewDebt("U1", "U2", "ABCD", "C1", "1.1", 12.5)))
+	require.NoError(t, source.db.AddPayment(ctx, &debt.Payment{Debt: debt.Debt{ID: "P1", BorrowerID: "U2", LenderID: "U1", OrderID: "ABCD",
+		Amount: 7, InitiatedTransportID: "C1", MessageID: "1.1", CreatedAt: createdAt}, PaidAt: createdAt.Add(time.Hour)}))
+	require.NoError(t, source.db.AddPendingDebt(ctx, &debt.PendingDebt{WoltName: "Thor", LenderID: "U1", OrderID: "ABCD", Amount: 3,
+		InitiatedTransportID: "C1", MessageID: "1.1", CreatedAt: createdAt}))
+	require.NoError(t, source.db.BlacklistVenue(ctx, &order.BlacklistedVenue{Channel: "C1", VenueName: "Pizza", Reason: "slow", AddedBy: "S1", CreatedAt: createdAt}))
+	require.NoError(t, source.db.SetChannelSetting(ctx, &order.ChannelSetting{Channel: "C1", Name: "DONT_JOIN_AFTER", Value: "12:30", UpdatedBy: "S1", UpdatedAt: createdAt}))
+	require.NoError(t, source.db.SubscribeInsights(ctx, "S1"))
+	require.NoError(t, source.db.SetAbroadCurrency(ctx, "S1", "USD"))
+	require.NoError(t, source.db.SetRemindersOptOut(ctx, "S1", true))
+	require.NoError(t, source.db.SetDigestHour(ctx, "S1", 18))
+	require.NoError(t, source.db.SetPaymentMethods(ctx, "S1", []userDomain.PaymentMethod{userDomain.PaymentMethodPaybox, userDomain.PaymentMethodPepper}))
+	issued, _, err := token.Issue(ctx, source.db, "ci", token.ScopeReadOnly, "")
+	require.NoError(t, err)
+	require.NoError(t, source.db.RevokeToken(ctx, issued.ID, createdAt))
+	_, err = source.db.NextOrderRefSequence(ctx)
+	require.NoError(t, err)
+	_, err = source.db.NextOrderRefSequence(ctx)
+	require.NoError(t, err)
+	require.NoError(t, source.db.SetBankAccount(ctx, "S1", &userDomain.BankAccount{Holder: "Loki", IBAN: "IL620108000000099999999"}))
+	require.NoError(t, source.db.SetDebtDMs(ctx, "S1", false))
+	require.NoError(t, source.db.SetPrivateAmounts(ctx, "S1", true))
+	require.NoError(t, source.db.AddVenueDelivery(ctx, "Pizza", 40*time.Minute))
+	require.NoError(t, source.db.SaveRatesSnapshot(ctx, &order.RatesSnapshot{OriginalID: "ABCD", Receiver: "C1", MessageID: "1.2",
+		PublishedAt: createdAt, Message: "Rates", ItemRates: `{"Loki":12.5}`, Host: "Thor", DeliveryRate: 10, FeeAllocation: "equal",
+		DiscountAllocation: "proportional", Currency: "NIS"}))
+	require.NoError(t, source.db.AddDigestNotification(ctx, &debt.DigestNotification{TransportID: "S1", Key: "debt", Text: "Pay", CreatedAt: createdAt}))
+
+	dump, err := source.db.Export(ctx)
+	require.NoError(t, err)
+	assert.Len(t, dump.Users, 1)
+	assert.Len(t, dump.Orders, 1)
+	assert.Len(t, dump.Debts, 1)
+	assert.Len(t, dump.Payments, 1)
+	assert.Len(t, dump.PendingDebts, 1)
+	assert.Len(t, dump.Config.ChannelSettings, 1)
+	assert.Equal(t, map[string]string{"S1": "USD"}, dump.Config.AbroadCurrencies)
+	assert.Equal(t, []string{"S1"}, dump.Config.InsightsSubscribers)
+	assert.Equal(t, []string{"S1"}, dump.Config.ReminderOptOuts)
+	assert.Equal(t, map[string]int{"S1": 18}, dump.Config.DigestHours)
+	assert.Equal(t, map[string][]string{"S1": {"Paybox", "Pepper pay"}}, dump.Config.PaymentMethods)
+	assert.Equal(t, int64(2), dump.Config.OrderRefSequence)
+	assert.Equal(t, map[string]*userDomain.BankAccount{"S1": {Holder: "Loki", IBAN: "IL620108000000099999999"}}, dump.Config.BankAccounts)
+	assert.Equal(t, map[string]bool{"S1": false}, dump.Config.DebtDMs)
+	assert.Equal(t, map[string]bool{"S1": true}, dump.Config.PrivateAmounts)
+	assert.Len(t, dump.Config.VenueStats, 1)
+	assert.Len(t, dump.RatesSnapshots, 1)
+	assert.Len(t, dump.DigestNotifications, 1)
+
+	var buf bytes.Buffer
+	require.NoError(t, backup.Write(&buf, dump))
+	restored, err := backup.Read(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, backup.Version, restored.Version)
+	require.NoError(t, target.db.Import(ctx, restored))
+
+	restoredDump, err := target.db.Export(ctx)
+	require.NoError(t, err)
+	restoredDump.CreatedAt = dump.CreatedAt
+	assert.Equal(t, dump, restoredDump)
+
+	assert.EqualError(t, target.db.Import(ctx, restored), "the store isn't empty")
+	next, err := target.db.NextOrderRefSequence(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), next, "the restored store continues the sequence")
+
+	copied, err := backup.Copy(ctx, source.db, migrated.db)
+	require.NoError(t, err)
+	assert.Len(t, copied.Orders, 1)
+	_, err = backup.Copy(ctx, source.db, migrated.db)
+	assert.Error(t, err, "the destination isn't empty anymore")
+}
+
+func TestBackupTablesCoverMigrations(t *testing.T) {
+	t.Parallel()
+
+	store := NewDBTest(t)
+	t.Cleanup(func() { store.Cleanup(t) })
+	query := "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'"
+	if store.db.dialect == DialectPostgres {
+		query = "SELECT table_name FROM information_schema.tables WHERE table_schema = current_schema()"
+	}
+	var tables []string
+	require.NoError(t, store.db.db.Select(&tables, query))
+	require.NotEmpty(t, tables)
+
+	known := make(map[string]bool)
+	for _, table := range append(append([]string{"schema_migrations"}, backupTables...), transientTables...) {
+		known[table] = true
+	}
+	for _, table := range tables {
+		assert.True(t, known[table], "table %s is neither backed up nor transient, add it to backupTables (and the dump) or to transientTables", table)
+	}
+}
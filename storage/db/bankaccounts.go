@@ -0,0 +1,50 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	userDomain "github.com/oriser/bolt/user"
+)
+
+func (d *DBStore) SetBankAccount(ctx context.Context, transportID string, account *userDomain.BankAccount) error {
+	var (
+		query string
+		args  []interface{}
+		err   error
+	)
+	if account != nil {
+		query, args, err = d.builder.Insert("bank_accounts").Values(transportID, account.Holder, account.IBAN, time.Now().UTC()).
+			Suffix(onConflictUpdate([]string{"transport_id"}, "holder", "iban", "updated_at")).ToSql()
+	} else {
+		query, args, err = d.builder.Delete("bank_accounts").Where(sq.Eq{"transport_id": transportID}).ToSql()
+	}
+	if err != nil {
+		return fmt.Errorf("generating SQL: %w", err)
+	}
+
+	if _, err = d.db.ExecContext(ctx, query, args...); err != nil {
+		return newExecError("setting bank account", query, err, args...)
+	}
+	return nil
+}
+
+func (d *DBStore) BankAccount(ctx context.Context, transportID string) (*userDomain.BankAccount, error) {
+	query, args, err := d.builder.Select("holder", "iban").From("bank_accounts").Where(sq.Eq{"transport_id": transportID}).ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("generating select SQL: %w", err)
+	}
+
+	account := &userDomain.BankAccount{}
+	if err = d.db.QueryRowxContext(ctx, query, args...).Scan(&account.Holder, &account.IBAN); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, newExecError("selecting bank account", query, err, args...)
+	}
+	return account, nil
+}
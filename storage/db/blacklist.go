@@ -0,0 +1,58 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/oriser/bolt/order"
+)
+
+func (d *DBStore) BlacklistVenue(ctx context.Context, venue *order.BlacklistedVenue) error {
+	if venue == nil {
+		return fmt.Errorf("nil venue")
+	}
+
+	sql, args, err := d.builder.Insert("venue_blacklist").
+		Values(venue.Channel, venue.VenueName, venue.Reason, venue.AddedBy, venue.CreatedAt.UTC()).
+		Suffix(onConflictUpdate([]string{"channel", "venue_name"}, "venue_name", "reason", "added_by", "created_at")).ToSql()
+	if err != nil {
+		return fmt.Errorf("generating insert SQL: %w", err)
+	}
+
+	if _, err = d.db.ExecContext(ctx, sql, args...); err != nil {
+		return newExecError("blacklisting venue", sql, err, args...)
+	}
+	return nil
+}
+
+func (d *DBStore) UnblacklistVenue(ctx context.Context, channel, venueName string) (bool, error) {
+	// The venue name column is case-insensitive
+	sql, args, err := d.builder.Delete("venue_blacklist").Where(sq.Eq{"channel": channel, "venue_name": venueName}).ToSql()
+	if err != nil {
+		return false, fmt.Errorf("generating delete SQL: %w", err)
+	}
+
+	res, err := d.db.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return false, newExecError("unblacklisting venue", sql, err, args...)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("rows affected: %w", err)
+	}
+	return affected > 0, nil
+}
+
+func (d *DBStore) ListBlacklistedVenues(ctx context.Context, channel string) ([]*order.BlacklistedVenue, error) {
+	sql, args, err := d.builder.Select("*").From("venue_blacklist").Where(sq.Eq{"channel": channel}).OrderBy("venue_name").ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("generating list SQL: %w", err)
+	}
+
+	venues := []*order.BlacklistedVenue{}
+	if err = d.db.SelectContext(ctx, &venues, sql, args...); err != nil {
+		return nil, newExecError("selecting blacklisted venues", sql, err, args...)
+	}
+	return venues, nil
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/oriser/bolt/order"
+)
+
+func (d *DBStore) SetChannelSetting(ctx context.Context, setting *order.ChannelSetting) error {
+	if setting == nil {
+		return fmt.Errorf("nil channel setting")
+	}
+
+	sql, args, err := d.builder.Insert("channel_settings").
+		Values(setting.Channel, setting.Name, setting.Value, setting.UpdatedBy, setting.UpdatedAt.UTC()).
+		Suffix(onConflictUpdate([]string{"channel", "name"}, "value", "updated_by", "updated_at")).ToSql()
+	if err != nil {
+		return fmt.Errorf("generating insert SQL: %w", err)
+	}
+
+	if _, err = d.db.ExecContext(ctx, sql, args...); err != nil {
+		return newExecError("setting channel setting", sql, err, args...)
+	}
+	return nil
+}
+
+func (d *DBStore) RemoveChannelSetting(ctx context.Context, channel, name string) (bool, error) {
+	sql, args, err := d.builder.Delete("channel_settings").Where(sq.Eq{"channel": channel, "name": name}).ToSql()
+	if err != nil {
+		return false, fmt.Errorf("generating delete SQL: %w", err)
+	}
+
+	res, err := d.db.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return false, newExecError("removing channel setting", sql, err, args...)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("rows affected: %w", err)
+	}
+	return affected > 0, nil
+}
+
+func (d *DBStore) ListChannelSettings(ctx context.Context, channel string) ([]*order.ChannelSetting, error) {
+	sql, args, err := d.builder.Select("*").From("channel_settings").Where(sq.Eq{"channel": channel}).OrderBy("name").ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("generating list SQL: %w", err)
+	}
+
+	settings := []*order.ChannelSetting{}
+	if err = d.db.SelectContext(ctx, &settings, sql, args...); err != nil {
+		return nil, newExecError("selecting channel settings", sql, err, args...)
+	}
+	for _, setting := range settings {
+		setting.UpdatedAt = setting.UpdatedAt.UTC()
+	}
+	return settings, nil
+}
@@ -1,13 +1,21 @@
 package db
 
 import (
+	"context"
+	"database/sql"
 	"embed"
+	"errors"
 	"fmt"
+	"math"
+	"strings"
 
+	sq "github.com/Masterminds/squirrel"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
 	"github.com/jmoiron/sqlx"
+	"github.com/oriser/bolt/metrics"
+	"github.com/oriser/bolt/order"
 )
 
 //go:embed migrations
@@ -20,20 +28,78 @@ type ExecError struct {
 	args []interface{}
 }
 
-func newExecError(msg, sql string, err error, args ...interface{}) *ExecError {
-	return &ExecError{sql: sql, err: err, msg: msg, args: args}
+var storeErrorsTotal = metrics.NewCounter("bolt_store_errors_total", "Failed SQL statements of the store, by operation", "operation")
+
+// newExecError returns the error of executing the SQL, counting it in the store errors of the operation (msg). Rows that weren't
+// found and canceled statements aren't counted, as they're usually expected.
+func newExecError(msg, query string, err error, args ...interface{}) *ExecError {
+	if !errors.Is(err, sql.ErrNoRows) && !errors.Is(err, context.Canceled) {
+		storeErrorsTotal.Inc(msg)
+	}
+	return &ExecError{sql: query, err: err, msg: msg, args: args}
 }
 
 func (e *ExecError) Error() string {
 	return fmt.Sprintf("%s: executing SQL:\n%s\nargs:%#v\nerror:%v", e.msg, e.sql, e.args, e.err)
 }
 
+func (e *ExecError) Unwrap() error {
+	return e.err
+}
+
+// withPagination adds limit and offset to the query. SQLite doesn't support OFFSET without LIMIT, so an offset without a limit
+// uses the maximum limit.
+func withPagination(query sq.SelectBuilder, limit, offset uint64) sq.SelectBuilder {
+	if offset > 0 && limit == 0 {
+		limit = math.MaxInt64
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+	return query
+}
+
+// Dialect is the SQL database a store runs on
+type Dialect string
+
+const (
+	DialectSQLite   Dialect = "sqlite"
+	DialectPostgres Dialect = "postgres"
+)
+
 type DBStore struct {
-	db *sqlx.DB
+	db      *sqlx.DB
+	dialect Dialect
+	builder sq.StatementBuilderType // Builds the statements with the placeholders of the dialect
+	archive order.Archive           // The orders moved out of the store, nil if they aren't archived
 }
 
+// New returns a store on an SQLite DB, after running its migrations
 func New(db *sqlx.DB, migrationDriver database.Driver, dbName string) (*DBStore, error) {
-	d, err := iofs.New(migrations, "migrations")
+	return NewWithDialect(db, migrationDriver, dbName, DialectSQLite)
+}
+
+// NewWithDialect returns a store on a DB of the dialect, after running the migrations of the dialect
+func NewWithDialect(db *sqlx.DB, migrationDriver database.Driver, dbName string, dialect Dialect) (*DBStore, error) {
+	store := &DBStore{
+		db:      db,
+		dialect: dialect,
+		builder: sq.StatementBuilder,
+	}
+	migrationsPath := "migrations"
+	switch dialect {
+	case DialectSQLite:
+	case DialectPostgres:
+		migrationsPath = "migrations/postgres"
+		store.builder = sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	default:
+		return nil, fmt.Errorf("unknown dialect %q", dialect)
+	}
+
+	d, err := iofs.New(migrations, migrationsPath)
 	if err != nil {
 		return nil, fmt.Errorf("new iofs: %w", err)
 	}
@@ -48,7 +114,28 @@ func New(db *sqlx.DB, migrationDriver database.Driver, dbName string) (*DBStore,
 		return nil, fmt.Errorf("running migrations: %w", err)
 	}
 
-	return &DBStore{
-		db: db,
-	}, nil
+	if err = store.backfillOrdersSearch(); err != nil {
+		return nil, fmt.Errorf("backfill orders search: %w", err)
+	}
+	return store, nil
+}
+
+// like matches the column against the pattern case-insensitively, like SQLite's LIKE does
+func (d *DBStore) like(column, pattern string) sq.Sqlizer {
+	if d.dialect == DialectPostgres {
+		return sq.ILike{column: pattern}
+	}
+	return sq.Like{column: pattern}
+}
+
+// onConflictUpdate returns the suffix of an insert replacing the row which conflicts with the inserted one on the key columns
+func onConflictUpdate(key []string, columns ...string) string {
+	updates := make([]string, len(columns))
+	for i, column := range columns {
+		updates[i] = fmt.Sprintf("%s = excluded.%s", column, column)
+	}
+	return fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(key, ", "), strings.Join(updates, ", "))
 }
+
+// onConflictIgnore is the suffix of an insert which is ignored if the row already exists
+const onConflictIgnore = "ON CONFLICT DO NOTHING"
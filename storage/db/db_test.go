@@ -1,20 +1,37 @@
 package db
 
 import (
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
 	"testing"
 
+	sq "github.com/Masterminds/squirrel"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
 	"github.com/golang-migrate/migrate/v4/database/sqlite3"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+// postgresTestURLEnv is the environment variable of a PostgreSQL DB to run the store tests on instead of an in-memory SQLite DB.
+// Each test runs in its own schema, which is dropped once it's done.
+const postgresTestURLEnv = "BOLT_TEST_POSTGRES_URL"
+
 type DBTest struct {
-	db *DBStore
+	db     *DBStore
+	schema string // The schema of the test in the PostgreSQL DB
 }
 
 func NewDBTest(t *testing.T) *DBTest {
+	if url := os.Getenv(postgresTestURLEnv); url != "" {
+		return newPostgresDBTest(t, url)
+	}
+
 	db, err := sqlx.Connect("sqlite3", ":memory:")
 	require.NoError(t, err)
 
@@ -27,7 +44,85 @@ func NewDBTest(t *testing.T) *DBTest {
 	return &DBTest{db: dbStore}
 }
 
+func newPostgresDBTest(t *testing.T, url string) *DBTest {
+	admin, err := sqlx.Connect("postgres", url)
+	require.NoError(t, err)
+	defer admin.Close()
+	schema := "test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	_, err = admin.Exec("CREATE EXTENSION IF NOT EXISTS citext SCHEMA public")
+	require.NoError(t, err)
+	_, err = admin.Exec("CREATE SCHEMA " + schema)
+	require.NoError(t, err)
+
+	separator := "?"
+	if strings.Contains(url, "?") {
+		separator = "&"
+	}
+	db, err := sqlx.Connect("postgres", fmt.Sprintf("%s%ssearch_path=%s,public", url, separator, schema))
+	require.NoError(t, err)
+
+	driver, err := postgres.WithInstance(db.DB, &postgres.Config{SchemaName: schema})
+	require.NoError(t, err)
+
+	dbStore, err := NewWithDialect(db, driver, "", DialectPostgres)
+	require.NoError(t, err)
+
+	return &DBTest{db: dbStore, schema: schema}
+}
+
 func (d *DBTest) Cleanup(t *testing.T) {
+	if d.schema != "" {
+		_, err := d.db.db.Exec("DROP SCHEMA " + d.schema + " CASCADE")
+		assert.NoError(t, err)
+	}
 	err := d.db.db.Close()
 	assert.NoError(t, err)
 }
+
+func TestPostgresMigrations(t *testing.T) {
+	t.Parallel()
+
+	versions := func(path string) map[uint]bool {
+		entries, err := fs.ReadDir(migrations, path)
+		require.NoError(t, err)
+		found := make(map[uint]bool)
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			m, err := source.DefaultParse(entry.Name())
+			require.NoError(t, err)
+			found[m.Version] = true
+		}
+		return found
+	}
+
+	// The PostgreSQL schema starts from 000018, and every later SQLite migration should have a PostgreSQL counterpart
+	postgresVersions := versions("migrations/postgres")
+	require.True(t, postgresVersions[18])
+	for version := range versions("migrations") {
+		if version >= 18 {
+			assert.True(t, postgresVersions[version], "migration %06d is missing for PostgreSQL", version)
+		}
+	}
+}
+
+func TestDialectStatements(t *testing.T) {
+	t.Parallel()
+
+	sqliteStore := &DBStore{dialect: DialectSQLite, builder: sq.StatementBuilder}
+	postgresStore := &DBStore{dialect: DialectPostgres, builder: sq.StatementBuilder.PlaceholderFormat(sq.Dollar)}
+
+	query, _, err := sqliteStore.builder.Select("*").From("orders").Where(sqliteStore.like("venue_name", "%pizza%")).ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM orders WHERE venue_name LIKE ?", query)
+	query, _, err = postgresStore.builder.Select("*").From("orders").
+		Where(postgresStore.like("venue_name", "%pizza%")).Where(postgresStore.participantLike("thor")).ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM orders WHERE venue_name ILIKE $1 AND "+
+		"EXISTS (SELECT 1 FROM order_participants p WHERE p.order_id = orders.id AND p.name ILIKE $2)", query,
+		"PostgreSQL's LIKE is case-sensitive, unlike SQLite's")
+
+	assert.Equal(t, "ON CONFLICT (channel, venue_name) DO UPDATE SET reason = excluded.reason, added_by = excluded.added_by",
+		onConflictUpdate([]string{"channel", "venue_name"}, "reason", "added_by"))
+}
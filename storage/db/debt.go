@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	_ "embed"
 	"fmt"
 	"time"
@@ -10,7 +11,7 @@ import (
 	"github.com/oriser/bolt/debt"
 )
 
-func (d *DBStore) AddDebt(debt *debt.Debt) error {
+func (d *DBStore) AddDebt(ctx context.Context, debt *debt.Debt) error {
 	if debt == nil {
 		return fmt.Errorf("nil debt")
 	}
@@ -19,42 +20,140 @@ func (d *DBStore) AddDebt(debt *debt.Debt) error {
 	}
 	debt.CreatedAt = time.Now()
 
-	sql, args, err := sq.Insert("debts").Values(debt.ID, debt.BorrowerID, debt.LenderID, debt.OrderID,
-		debt.Amount, debt.InitiatedTransportID, debt.MessageID, debt.CreatedAt).ToSql()
+	sql, args, err := d.builder.Insert("debts").Values(debt.ID, debt.BorrowerID, debt.LenderID, debt.OrderID,
+		debt.Amount, debt.InitiatedTransportID, debt.MessageID, debt.CreatedAt, debt.Currency, utcTimePtr(debt.PaidClaimedAt),
+		debt.PaymentConfirmedBy, utcTimePtr(debt.PaymentConfirmedAt), debt.EscalatedDays).ToSql()
 	if err != nil {
 		return fmt.Errorf("generating insert SQL: %w", err)
 	}
 
-	if _, err = d.db.Exec(sql, args...); err != nil {
+	if _, err = d.db.ExecContext(ctx, sql, args...); err != nil {
 		return newExecError("adding debt", sql, err, args...)
 	}
 	return nil
 }
 
-func (d *DBStore) RemoveDebtInOrderID(orderID, debtID string) error {
-	sql, args, err := sq.Delete("debts").Where("order_id=? AND id=?", orderID, debtID).ToSql()
+// SetPaidClaimedAt records when the borrower said they paid the debt, or clears it if claimedAt is nil
+func (d *DBStore) SetPaidClaimedAt(ctx context.Context, debtID string, claimedAt *time.Time) error {
+	sql, args, err := d.builder.Update("debts").Set("paid_claimed_at", utcTimePtr(claimedAt)).Where(sq.Eq{"id": debtID}).ToSql()
+	if err != nil {
+		return fmt.Errorf("generating update SQL: %w", err)
+	}
+
+	res, err := d.db.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return newExecError("setting debt paid claim", sql, err, args...)
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("debt not found")
+	}
+	return nil
+}
+
+// SetEscalatedDays records the days of the last escalation stage the debt reached
+func (d *DBStore) SetEscalatedDays(ctx context.Context, debtID string, days int) error {
+	sql, args, err := d.builder.Update("debts").Set("escalated_days", days).Where(sq.Eq{"id": debtID}).ToSql()
+	if err != nil {
+		return fmt.Errorf("generating update SQL: %w", err)
+	}
+
+	if _, err := d.db.ExecContext(ctx, sql, args...); err != nil {
+		return newExecError("setting debt escalation", sql, err, args...)
+	}
+	return nil
+}
+
+// utcTimePtr returns the time in UTC, so times are compared as strings correctly, or nil if it's nil
+func utcTimePtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
+
+func (d *DBStore) RemoveDebtInOrderID(ctx context.Context, orderID, debtID string) error {
+	sql, args, err := d.builder.Delete("debts").Where("order_id=? AND id=?", orderID, debtID).ToSql()
 	if err != nil {
 		return fmt.Errorf("generating delete SQL: %w", err)
 	}
 
-	if _, err = d.db.Exec(sql, args...); err != nil {
+	if _, err = d.db.ExecContext(ctx, sql, args...); err != nil {
 		return newExecError("deleting debt", sql, err, args...)
 	}
 
 	return nil
 }
 
-func (d *DBStore) ListDebtsForOrderID(orderID string) ([]*debt.Debt, error) {
-	sql, args, err := sq.Select("*").From("debts").Where("order_id=?", orderID).ToSql()
+func (d *DBStore) ListDebtsForOrderID(ctx context.Context, orderID string) ([]*debt.Debt, error) {
+	sql, args, err := d.builder.Select("*").From("debts").Where("order_id=?", orderID).ToSql()
 	if err != nil {
 		return nil, fmt.Errorf("generating delete SQL: %w", err)
 	}
 
 	debts := []*debt.Debt{}
-	err = d.db.Select(&debts, sql, args...)
+	err = d.db.SelectContext(ctx, &debts, sql, args...)
+	if err != nil {
+		return nil, newExecError("selecting debts", sql, err, args...)
+	}
+
+	return debts, nil
+}
+
+func (d *DBStore) ListDebts(ctx context.Context, filter debt.ListFilter) ([]*debt.Debt, error) {
+	query := filterDebts(d.builder.Select("*").From("debts"), filter)
+	switch filter.SortBy {
+	case debt.SortByCreatedAt:
+	case debt.SortByAmount:
+		query = query.OrderBy("amount " + sortDirection(filter.Ascending))
+	default:
+		return nil, fmt.Errorf("unknown debts sort field %q", filter.SortBy)
+	}
+	query = query.OrderBy("created_at "+sortDirection(filter.Ascending), "id")
+	query = withPagination(query, filter.Limit, filter.Offset)
+
+	sql, args, err := query.ToSql()
 	if err != nil {
+		return nil, fmt.Errorf("generating list SQL: %w", err)
+	}
+
+	debts := []*debt.Debt{}
+	if err = d.db.SelectContext(ctx, &debts, sql, args...); err != nil {
 		return nil, newExecError("selecting debts", sql, err, args...)
 	}
 
 	return debts, nil
 }
+
+func (d *DBStore) CountDebts(ctx context.Context, filter debt.ListFilter) (int, error) {
+	sql, args, err := filterDebts(d.builder.Select("COUNT(*)").From("debts"), filter).ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("generating count SQL: %w", err)
+	}
+
+	var count int
+	if err = d.db.GetContext(ctx, &count, sql, args...); err != nil {
+		return 0, newExecError("counting debts", sql, err, args...)
+	}
+	return count, nil
+}
+
+// filterDebts adds the conditions of the filter to the query
+func filterDebts(query sq.SelectBuilder, filter debt.ListFilter) sq.SelectBuilder {
+	if filter.BorrowerID != "" {
+		query = query.Where(sq.Eq{"borrower_id": filter.BorrowerID})
+	}
+	if filter.LenderID != "" {
+		query = query.Where(sq.Eq{"lender_id": filter.LenderID})
+	}
+	if len(filter.OrderIDs) > 0 {
+		query = query.Where(sq.Eq{"order_id": filter.OrderIDs})
+	}
+	if len(filter.UserIDs) > 0 {
+		query = query.Where(sq.Or{sq.Eq{"borrower_id": filter.UserIDs}, sq.Eq{"lender_id": filter.UserIDs}})
+	}
+	if filter.Channel != "" {
+		query = query.Where(sq.Eq{"initial_transport": filter.Channel})
+	}
+	return query
+}
@@ -2,6 +2,7 @@ package db
 
 import (
 	"bytes"
+	"context"
 	_ "embed"
 	"testing"
 	"text/template"
@@ -111,7 +112,7 @@ func TestAddListForOrderAndRemove(t *testing.T) {
 			for _, debt := range tc.debts {
 				currentOrderID := debt.OrderID
 
-				err := dbTest.db.AddDebt(debt)
+				err := dbTest.db.AddDebt(context.Background(), debt)
 				assert.NoError(t, err)
 
 				assert.NotEmpty(t, debt.ID)
@@ -123,7 +124,7 @@ func TestAddListForOrderAndRemove(t *testing.T) {
 
 			// Going over per-order debts
 			for orderID, expectedDebts := range expectedOrderIDs {
-				debts, err := dbTest.db.ListDebtsForOrderID(orderID)
+				debts, err := dbTest.db.ListDebtsForOrderID(context.Background(), orderID)
 				assert.NoError(t, err)
 				assert.Len(t, debts, len(expectedDebts))
 
@@ -153,15 +154,294 @@ func TestAddListForOrderAndRemove(t *testing.T) {
 
 				// Removing debts for current order ID
 				for _, debt := range debts {
-					err = dbTest.db.RemoveDebtInOrderID(orderID, debt.ID)
+					err = dbTest.db.RemoveDebtInOrderID(context.Background(), orderID, debt.ID)
 					assert.NoError(t, err)
 				}
 
 				// Checking that indeed it deleted
-				debts, err = dbTest.db.ListDebtsForOrderID(orderID)
+				debts, err = dbTest.db.ListDebtsForOrderID(context.Background(), orderID)
 				assert.NoError(t, err)
 				assert.Len(t, debts, 0)
 			}
 		})
 	}
 }
+
+func TestListDebts(t *testing.T) {
+	t.Parallel()
+
+	dbTest := NewDBTest(t)
+	t.Cleanup(func() {
+		dbTest.Cleanup(t)
+	})
+
+	first := getDummyDebt().WithOrderID("order1").Debt()
+	second := getDummyDebt().WithOrderID("order2").Debt()
+	second.BorrowerID = first.BorrowerID
+	second.Amount = 30
+	third := getDummyDebt().WithOrderID("order2").Debt()
+	third.InitiatedTransportID = "other-channel"
+	third.Amount = 20
+	for i, d := range []*debtDomain.Debt{first, second, third} {
+		require.NoError(t, dbTest.db.AddDebt(context.Background(), d))
+		// AddDebt sets the creation time to now, spread them for a deterministic order
+		_, err := dbTest.db.db.Exec("UPDATE debts SET created_at=? WHERE id=?", d.CreatedAt.Add(-time.Duration(i)*time.Hour), d.ID)
+		require.NoError(t, err)
+	}
+
+	tests := []struct {
+		name     string
+		filter   debtDomain.ListFilter
+		expected []string
+	}{
+		{name: "By borrower", filter: debtDomain.ListFilter{BorrowerID: first.BorrowerID}, expected: []string{first.ID, second.ID}},
+		{name: "By lender", filter: debtDomain.ListFilter{LenderID: third.LenderID}, expected: []string{third.ID}},
+		{name: "By order IDs", filter: debtDomain.ListFilter{OrderIDs: []string{"order1"}}, expected: []string{first.ID}},
+		{name: "By user IDs", filter: debtDomain.ListFilter{UserIDs: []string{first.LenderID, third.BorrowerID}}, expected: []string{first.ID, third.ID}},
+		{name: "By channel", filter: debtDomain.ListFilter{Channel: "other-channel"}, expected: []string{third.ID}},
+		{name: "With limit and offset", filter: debtDomain.ListFilter{BorrowerID: first.BorrowerID, Limit: 1, Offset: 1}, expected: []string{second.ID}},
+		{name: "Oldest first", filter: debtDomain.ListFilter{OrderIDs: []string{"order2"}, Ascending: true}, expected: []string{third.ID, second.ID}},
+		{name: "By amount", filter: debtDomain.ListFilter{SortBy: debtDomain.SortByAmount}, expected: []string{second.ID, third.ID, first.ID}},
+		{name: "By amount, ascending", filter: debtDomain.ListFilter{SortBy: debtDomain.SortByAmount, Ascending: true, Limit: 2}, expected: []string{first.ID, third.ID}},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			debts, err := dbTest.db.ListDebts(context.Background(), tc.filter)
+			require.NoError(t, err)
+			ids := make([]string, len(debts))
+			for i, d := range debts {
+				ids[i] = d.ID
+			}
+			assert.Equal(t, tc.expected, ids)
+		})
+	}
+
+	count, err := dbTest.db.CountDebts(context.Background(), debtDomain.ListFilter{OrderIDs: []string{"order2"}, Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 2, count, "the count ignores the pagination")
+}
+
+func TestPayments(t *testing.T) {
+	t.Parallel()
+
+	dbTest := NewDBTest(t)
+	t.Cleanup(func() {
+		dbTest.Cleanup(t)
+	})
+
+	paidAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	first := &debtDomain.Payment{Debt: *getDummyDebt().Debt(), PaidAt: paidAt}
+	first.ID, first.CreatedAt = "first", paidAt.Add(-time.Hour)
+	second := &debtDomain.Payment{Debt: *getDummyDebt().Debt(), PaidAt: paidAt.Add(time.Hour)}
+	second.ID, second.CreatedAt = "second", paidAt.Add(-time.Hour)
+	second.InitiatedTransportID = first.InitiatedTransportID
+	third := &debtDomain.Payment{Debt: *getDummyDebt().Debt(), PaidAt: paidAt}
+	third.ID, third.CreatedAt = "third", paidAt.Add(-time.Hour)
+	for _, p := range []*debtDomain.Payment{first, second, third} {
+		require.NoError(t, dbTest.db.AddPayment(context.Background(), p))
+	}
+
+	payments, err := dbTest.db.ListPayments(context.Background(), debtDomain.PaymentListFilter{Channel: first.InitiatedTransportID})
+	require.NoError(t, err)
+	require.Len(t, payments, 2)
+	assert.Equal(t, second.ID, payments[0].ID)
+	assert.Equal(t, first.BorrowerID, payments[1].BorrowerID)
+	assert.True(t, first.PaidAt.Equal(payments[1].PaidAt))
+	assert.True(t, first.CreatedAt.Equal(payments[1].CreatedAt))
+
+	payments, err = dbTest.db.ListPayments(context.Background(), debtDomain.PaymentListFilter{Channel: first.InitiatedTransportID, PaidBefore: paidAt.Add(time.Minute)})
+	require.NoError(t, err)
+	require.Len(t, payments, 1)
+	assert.Equal(t, first.ID, payments[0].ID)
+
+	payments, err = dbTest.db.ListPayments(context.Background(), debtDomain.PaymentListFilter{BorrowerID: third.BorrowerID})
+	require.NoError(t, err)
+	require.Len(t, payments, 1)
+	assert.Equal(t, third.ID, payments[0].ID)
+
+	payments, err = dbTest.db.ListPayments(context.Background(), debtDomain.PaymentListFilter{OrderID: third.OrderID})
+	require.NoError(t, err)
+	require.Len(t, payments, 1)
+	assert.Equal(t, third.ID, payments[0].ID)
+}
+
+func TestPaymentConfirmation(t *testing.T) {
+	t.Parallel()
+
+	dbTest := NewDBTest(t)
+	t.Cleanup(func() {
+		dbTest.Cleanup(t)
+	})
+
+	d := getDummyDebt().Debt()
+	require.NoError(t, dbTest.db.AddDebt(context.Background(), d))
+	claimedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.FixedZone("IDT", 3*60*60))
+	require.NoError(t, dbTest.db.SetPaidClaimedAt(context.Background(), d.ID, &claimedAt))
+	assert.EqualError(t, dbTest.db.SetPaidClaimedAt(context.Background(), "missing", &claimedAt), "debt not found")
+
+	debts, err := dbTest.db.ListDebtsForOrderID(context.Background(), d.OrderID)
+	require.NoError(t, err)
+	require.Len(t, debts, 1)
+	require.NotNil(t, debts[0].PaidClaimedAt)
+	assert.True(t, claimedAt.Equal(*debts[0].PaidClaimedAt))
+	assert.Nil(t, debts[0].PaymentConfirmedAt)
+
+	// The payment keeps the audit trail of its confirmation
+	confirmedAt := claimedAt.Add(time.Hour)
+	payment := &debtDomain.Payment{Debt: *debts[0], PaidAt: confirmedAt}
+	payment.PaymentConfirmedBy, payment.PaymentConfirmedAt = d.LenderID, &confirmedAt
+	require.NoError(t, dbTest.db.AddPayment(context.Background(), payment))
+	payments, err := dbTest.db.ListPayments(context.Background(), debtDomain.PaymentListFilter{BorrowerID: d.BorrowerID})
+	require.NoError(t, err)
+	require.Len(t, payments, 1)
+	assert.Equal(t, d.LenderID, payments[0].PaymentConfirmedBy)
+	require.NotNil(t, payments[0].PaidClaimedAt)
+	assert.True(t, claimedAt.Equal(*payments[0].PaidClaimedAt))
+	require.NotNil(t, payments[0].PaymentConfirmedAt)
+	assert.True(t, confirmedAt.Equal(*payments[0].PaymentConfirmedAt))
+
+	require.NoError(t, dbTest.db.SetPaidClaimedAt(context.Background(), d.ID, nil))
+	debts, err = dbTest.db.ListDebtsForOrderID(context.Background(), d.OrderID)
+	require.NoError(t, err)
+	assert.Nil(t, debts[0].PaidClaimedAt)
+}
+
+func TestPendingDebts(t *testing.T) {
+	t.Parallel()
+
+	dbTest := NewDBTest(t)
+	t.Cleanup(func() {
+		dbTest.Cleanup(t)
+	})
+
+	first := &debtDomain.PendingDebt{WoltName: "Loki Laufeyson", LenderID: "lender", OrderID: "order", Amount: 10, CreatedAt: time.Now()}
+	second := &debtDomain.PendingDebt{WoltName: "Thor", LenderID: "lender", OrderID: "order", Amount: 20, CreatedAt: time.Now()}
+	for _, p := range []*debtDomain.PendingDebt{first, second} {
+		require.NoError(t, dbTest.db.AddPendingDebt(context.Background(), p))
+	}
+
+	pending, err := dbTest.db.ListPendingDebts(context.Background(), "loki laufeyson")
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, first.ID, pending[0].ID)
+	assert.Equal(t, 10.0, pending[0].Amount)
+
+	require.NoError(t, dbTest.db.RemovePendingDebt(context.Background(), first.ID))
+	pending, err = dbTest.db.ListPendingDebts(context.Background(), "Loki Laufeyson")
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestAbroadCurrency(t *testing.T) {
+	t.Parallel()
+
+	dbTest := NewDBTest(t)
+	t.Cleanup(func() {
+		dbTest.Cleanup(t)
+	})
+
+	currency, err := dbTest.db.AbroadCurrency(context.Background(), "U1")
+	require.NoError(t, err)
+	assert.Empty(t, currency)
+
+	require.NoError(t, dbTest.db.SetAbroadCurrency(context.Background(), "U1", "USD"))
+	require.NoError(t, dbTest.db.SetAbroadCurrency(context.Background(), "U1", "EUR"))
+	currency, err = dbTest.db.AbroadCurrency(context.Background(), "U1")
+	require.NoError(t, err)
+	assert.Equal(t, "EUR", currency)
+
+	require.NoError(t, dbTest.db.RemoveAbroadCurrency(context.Background(), "U1"))
+	currency, err = dbTest.db.AbroadCurrency(context.Background(), "U1")
+	require.NoError(t, err)
+	assert.Empty(t, currency)
+}
+
+func TestEscalatedDays(t *testing.T) {
+	t.Parallel()
+
+	dbTest := NewDBTest(t)
+	t.Cleanup(func() {
+		dbTest.Cleanup(t)
+	})
+
+	d := getDummyDebt().Debt()
+	require.NoError(t, dbTest.db.AddDebt(context.Background(), d))
+	require.NoError(t, dbTest.db.SetEscalatedDays(context.Background(), d.ID, 7))
+
+	debts, err := dbTest.db.ListDebtsForOrderID(context.Background(), d.OrderID)
+	require.NoError(t, err)
+	require.Len(t, debts, 1)
+	assert.Equal(t, 7, debts[0].EscalatedDays)
+}
+
+func TestRemindersOptOut(t *testing.T) {
+	t.Parallel()
+
+	dbTest := NewDBTest(t)
+	t.Cleanup(func() {
+		dbTest.Cleanup(t)
+	})
+
+	optedOut, err := dbTest.db.RemindersOptedOut(context.Background(), "U1")
+	require.NoError(t, err)
+	assert.False(t, optedOut)
+
+	require.NoError(t, dbTest.db.SetRemindersOptOut(context.Background(), "U1", true))
+	require.NoError(t, dbTest.db.SetRemindersOptOut(context.Background(), "U1", true))
+	optedOut, err = dbTest.db.RemindersOptedOut(context.Background(), "U1")
+	require.NoError(t, err)
+	assert.True(t, optedOut)
+
+	require.NoError(t, dbTest.db.SetRemindersOptOut(context.Background(), "U1", false))
+	optedOut, err = dbTest.db.RemindersOptedOut(context.Background(), "U1")
+	require.NoError(t, err)
+	assert.False(t, optedOut)
+}
+
+func TestDigests(t *testing.T) {
+	t.Parallel()
+
+	dbTest := NewDBTest(t)
+	t.Cleanup(func() {
+		dbTest.Cleanup(t)
+	})
+
+	hour, err := dbTest.db.DigestHour(context.Background(), "U1")
+	require.NoError(t, err)
+	assert.Equal(t, -1, hour)
+
+	require.NoError(t, dbTest.db.SetDigestHour(context.Background(), "U1", 9))
+	require.NoError(t, dbTest.db.SetDigestHour(context.Background(), "U1", 18))
+	require.NoError(t, dbTest.db.SetDigestHour(context.Background(), "U2", 8))
+	hour, err = dbTest.db.DigestHour(context.Background(), "U1")
+	require.NoError(t, err)
+	assert.Equal(t, 18, hour)
+	hours, err := dbTest.db.ListDigestHours(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"U1": 18, "U2": 8}, hours)
+
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, dbTest.db.AddDigestNotification(context.Background(), &debtDomain.DigestNotification{TransportID: "U1", Key: "reminder/1", Text: "first", CreatedAt: createdAt}))
+	require.NoError(t, dbTest.db.AddDigestNotification(context.Background(), &debtDomain.DigestNotification{TransportID: "U1", Key: "receipt/A", Text: "receipt", CreatedAt: createdAt.Add(time.Hour)}))
+	require.NoError(t, dbTest.db.AddDigestNotification(context.Background(), &debtDomain.DigestNotification{TransportID: "U1", Key: "reminder/1", Text: "second", CreatedAt: createdAt.Add(2 * time.Hour)}))
+	require.NoError(t, dbTest.db.AddDigestNotification(context.Background(), &debtDomain.DigestNotification{TransportID: "U1", Key: "reminder/2", Text: "paid", CreatedAt: createdAt}))
+	require.NoError(t, dbTest.db.AddDigestNotification(context.Background(), &debtDomain.DigestNotification{TransportID: "U2", Key: "reminder/3", Text: "other", CreatedAt: createdAt}))
+	require.NoError(t, dbTest.db.RemoveDigestNotification(context.Background(), "U1", "reminder/2"))
+
+	notifications, err := dbTest.db.PopDigestNotifications(context.Background(), "U1")
+	require.NoError(t, err)
+	assert.Equal(t, []*debtDomain.DigestNotification{
+		{TransportID: "U1", Key: "receipt/A", Text: "receipt", CreatedAt: createdAt.Add(time.Hour)},
+		{TransportID: "U1", Key: "reminder/1", Text: "second", CreatedAt: createdAt.Add(2 * time.Hour)},
+	}, notifications, "a newer notification with the same key replaces the older one")
+	notifications, err = dbTest.db.PopDigestNotifications(context.Background(), "U1")
+	require.NoError(t, err)
+	assert.Empty(t, notifications)
+
+	require.NoError(t, dbTest.db.RemoveDigestHour(context.Background(), "U1"))
+	hours, err = dbTest.db.ListDigestHours(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"U2": 8}, hours)
+}
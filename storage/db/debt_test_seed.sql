@@ -1,31 +1,31 @@
 INSERT INTO debts
 VALUES
-    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}"),
-    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}"),
-    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}"),
-    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}"),
-    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}"),
-    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}"),
-    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}"),
-    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}"),
-    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}"),
-    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}"),
-    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}"),
-    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}"),
-    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}"),
-    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}"),
-    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}"),
-    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}"),
-    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}"),
-    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}"),
-    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}"),
-    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}"),
-    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}"),
-    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}"),
-    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}"),
-    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}"),
-    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}"),
-    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}"),
-    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}"),
-    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}")
+    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}", "ILS", NULL, "", NULL, 0),
+    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}", "ILS", NULL, "", NULL, 0),
+    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}", "ILS", NULL, "", NULL, 0),
+    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}", "ILS", NULL, "", NULL, 0),
+    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}", "ILS", NULL, "", NULL, 0),
+    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}", "ILS", NULL, "", NULL, 0),
+    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}", "ILS", NULL, "", NULL, 0),
+    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}", "ILS", NULL, "", NULL, 0),
+    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}", "ILS", NULL, "", NULL, 0),
+    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}", "ILS", NULL, "", NULL, 0),
+    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}", "ILS", NULL, "", NULL, 0),
+    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}", "ILS", NULL, "", NULL, 0),
+    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}", "ILS", NULL, "", NULL, 0),
+    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}", "ILS", NULL, "", NULL, 0),
+    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}", "ILS", NULL, "", NULL, 0),
+    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}", "ILS", NULL, "", NULL, 0),
+    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}", "ILS", NULL, "", NULL, 0),
+    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}", "ILS", NULL, "", NULL, 0),
+    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}", "ILS", NULL, "", NULL, 0),
+    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}", "ILS", NULL, "", NULL, 0),
+    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}", "ILS", NULL, "", NULL, 0),
+    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}", "ILS", NULL, "", NULL, 0),
+    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}", "ILS", NULL, "", NULL, 0),
+    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}", "ILS", NULL, "", NULL, 0),
+    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}", "ILS", NULL, "", NULL, 0),
+    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}", "ILS", NULL, "", NULL, 0),
+    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}", "ILS", NULL, "", NULL, 0),
+    ("{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", "{{ uuidv4 }}", {{ randNumeric 3 | add1 }}, "{{ randAlpha 5 | lower }}", "{{ randAlpha 5 | upper }}", "{{ now | date_modify "-1h" | date "2006-01-02 15:04:05" }}", "ILS", NULL, "", NULL, 0)
 ;
\ No newline at end of file
@@ -0,0 +1,40 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+func (d *DBStore) SetDebtDMs(ctx context.Context, transportID string, enabled bool) error {
+	query, args, err := d.builder.Insert("debt_dm_preferences").Values(transportID, enabled, time.Now().UTC()).
+		Suffix(onConflictUpdate([]string{"transport_id"}, "enabled", "updated_at")).ToSql()
+	if err != nil {
+		return fmt.Errorf("generating insert SQL: %w", err)
+	}
+
+	if _, err = d.db.ExecContext(ctx, query, args...); err != nil {
+		return newExecError("setting debt DMs", query, err, args...)
+	}
+	return nil
+}
+
+func (d *DBStore) DebtDMs(ctx context.Context, transportID string) (bool, bool, error) {
+	query, args, err := d.builder.Select("enabled").From("debt_dm_preferences").Where(sq.Eq{"transport_id": transportID}).ToSql()
+	if err != nil {
+		return false, false, fmt.Errorf("generating select SQL: %w", err)
+	}
+
+	enabled := false
+	if err = d.db.GetContext(ctx, &enabled, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, false, nil
+		}
+		return false, false, newExecError("selecting debt DMs", query, err, args...)
+	}
+	return enabled, true, nil
+}
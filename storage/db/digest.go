@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/oriser/bolt/debt"
+)
+
+func (d *DBStore) SetDigestHour(ctx context.Context, transportID string, hour int) error {
+	query, args, err := d.builder.Insert("digest_users").Values(transportID, hour, time.Now().UTC()).
+		Suffix(onConflictUpdate([]string{"transport_id"}, "hour")).ToSql()
+	if err != nil {
+		return fmt.Errorf("generating insert SQL: %w", err)
+	}
+
+	if _, err = d.db.ExecContext(ctx, query, args...); err != nil {
+		return newExecError("setting digest hour", query, err, args...)
+	}
+	return nil
+}
+
+func (d *DBStore) DigestHour(ctx context.Context, transportID string) (int, error) {
+	query, args, err := d.builder.Select("hour").From("digest_users").Where(sq.Eq{"transport_id": transportID}).ToSql()
+	if err != nil {
+		return -1, fmt.Errorf("generating select SQL: %w", err)
+	}
+
+	hour := -1
+	if err = d.db.GetContext(ctx, &hour, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return -1, nil
+		}
+		return -1, newExecError("selecting digest hour", query, err, args...)
+	}
+	return hour, nil
+}
+
+func (d *DBStore) RemoveDigestHour(ctx context.Context, transportID string) error {
+	query, args, err := d.builder.Delete("digest_users").Where(sq.Eq{"transport_id": transportID}).ToSql()
+	if err != nil {
+		return fmt.Errorf("generating delete SQL: %w", err)
+	}
+
+	if _, err = d.db.ExecContext(ctx, query, args...); err != nil {
+		return newExecError("removing digest hour", query, err, args...)
+	}
+	return nil
+}
+
+func (d *DBStore) ListDigestHours(ctx context.Context) (map[string]int, error) {
+	query, args, err := d.builder.Select("transport_id", "hour").From("digest_users").ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("generating list SQL: %w", err)
+	}
+
+	var users []struct {
+		TransportID string `db:"transport_id"`
+		Hour        int    `db:"hour"`
+	}
+	if err = d.db.SelectContext(ctx, &users, query, args...); err != nil {
+		return nil, newExecError("selecting digest hours", query, err, args...)
+	}
+	hours := make(map[string]int, len(users))
+	for _, u := range users {
+		hours[u.TransportID] = u.Hour
+	}
+	return hours, nil
+}
+
+func (d *DBStore) AddDigestNotification(ctx context.Context, notification *debt.DigestNotification) error {
+	if notification == nil {
+		return fmt.Errorf("nil digest notification")
+	}
+
+	query, args, err := d.builder.Insert("digest_notifications").Columns("transport_id", "key", "text", "created_at").
+		Values(notification.TransportID, notification.Key, notification.Text, notification.CreatedAt.UTC()).
+		Suffix(onConflictUpdate([]string{"transport_id", "key"}, "text", "created_at")).ToSql()
+	if err != nil {
+		return fmt.Errorf("generating insert SQL: %w", err)
+	}
+
+	if _, err = d.db.ExecContext(ctx, query, args...); err != nil {
+		return newExecError("adding digest notification", query, err, args...)
+	}
+	return nil
+}
+
+func (d *DBStore) RemoveDigestNotification(ctx context.Context, transportID, key string) error {
+	query, args, err := d.builder.Delete("digest_notifications").Where(sq.Eq{"transport_id": transportID, "key": key}).ToSql()
+	if err != nil {
+		return fmt.Errorf("generating delete SQL: %w", err)
+	}
+
+	if _, err = d.db.ExecContext(ctx, query, args...); err != nil {
+		return newExecError("removing digest notification", query, err, args...)
+	}
+	return nil
+}
+
+// PopDigestNotifications removes and returns the notifications in a single statement, so notifications added meanwhile aren't lost
+func (d *DBStore) PopDigestNotifications(ctx context.Context, transportID string) ([]*debt.DigestNotification, error) {
+	query, args, err := d.builder.Delete("digest_notifications").Where(sq.Eq{"transport_id": transportID}).
+		Suffix("RETURNING transport_id, key, text, created_at").ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("generating delete SQL: %w", err)
+	}
+
+	notifications := []*debt.DigestNotification{}
+	if err = d.db.SelectContext(ctx, &notifications, query, args...); err != nil {
+		return nil, newExecError("popping digest notifications", query, err, args...)
+	}
+	sort.Slice(notifications, func(i, j int) bool {
+		return notifications[i].CreatedAt.Before(notifications[j].CreatedAt)
+	})
+	for _, notification := range notifications {
+		notification.CreatedAt = notification.CreatedAt.UTC()
+	}
+	return notifications, nil
+}
@@ -0,0 +1,46 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+func (d *DBStore) SubscribeInsights(ctx context.Context, transportID string) error {
+	sql, args, err := d.builder.Insert("insights_subscribers").Values(transportID, time.Now().UTC()).Suffix(onConflictIgnore).ToSql()
+	if err != nil {
+		return fmt.Errorf("generating insert SQL: %w", err)
+	}
+
+	if _, err = d.db.ExecContext(ctx, sql, args...); err != nil {
+		return newExecError("subscribing to insights", sql, err, args...)
+	}
+	return nil
+}
+
+func (d *DBStore) UnsubscribeInsights(ctx context.Context, transportID string) error {
+	sql, args, err := d.builder.Delete("insights_subscribers").Where(sq.Eq{"transport_id": transportID}).ToSql()
+	if err != nil {
+		return fmt.Errorf("generating delete SQL: %w", err)
+	}
+
+	if _, err = d.db.ExecContext(ctx, sql, args...); err != nil {
+		return newExecError("unsubscribing from insights", sql, err, args...)
+	}
+	return nil
+}
+
+func (d *DBStore) ListInsightsSubscribers(ctx context.Context) ([]string, error) {
+	sql, args, err := d.builder.Select("transport_id").From("insights_subscribers").OrderBy("created_at").ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("generating list SQL: %w", err)
+	}
+
+	subscribers := []string{}
+	if err = d.db.SelectContext(ctx, &subscribers, sql, args...); err != nil {
+		return nil, newExecError("selecting insights subscribers", sql, err, args...)
+	}
+	return subscribers, nil
+}
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS order_participants;
+ALTER TABLE orders DROP COLUMN total_amount;
+ALTER TABLE orders DROP COLUMN tags;
+ALTER TABLE orders DROP COLUMN message_id;
@@ -0,0 +1,12 @@
+ALTER TABLE orders ADD COLUMN message_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE orders ADD COLUMN tags TEXT NOT NULL DEFAULT '';
+-- NULL total amount means the order was saved before participants were indexed, and it should be backfilled
+ALTER TABLE orders ADD COLUMN total_amount REAL NULL;
+
+CREATE TABLE IF NOT EXISTS order_participants (
+    order_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    amount REAL NOT NULL
+);
+CREATE INDEX IF NOT EXISTS order_participants_order_id ON order_participants (order_id);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS debt_payments (
+    id TEXT PRIMARY KEY,
+    borrower_id TEXT NOT NULL,
+    lender_id TEXT NOT NULL,
+    order_id TEXT NOT NULL,
+    amount REAL NOT NULL,
+    initial_transport TEXT NOT NULL,
+    thread_ts TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    paid_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS debt_payments_initial_transport ON debt_payments (initial_transport);
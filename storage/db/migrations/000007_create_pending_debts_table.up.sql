@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS pending_debts (
+    id TEXT PRIMARY KEY,
+    wolt_name TEXT NOT NULL COLLATE NOCASE,
+    lender_id TEXT NOT NULL,
+    order_id TEXT NOT NULL,
+    amount REAL NOT NULL,
+    initial_transport TEXT NOT NULL,
+    thread_ts TEXT NOT NULL,
+    created_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS pending_debts_wolt_name ON pending_debts (wolt_name);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS venue_blacklist (
+    channel TEXT NOT NULL,
+    venue_name TEXT NOT NULL COLLATE NOCASE,
+    reason TEXT NOT NULL,
+    added_by TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    PRIMARY KEY (channel, venue_name)
+);
@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS insights_subscribers (
+    transport_id TEXT PRIMARY KEY,
+    created_at DATETIME NOT NULL
+);
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS abroad_users (
+    transport_id TEXT PRIMARY KEY,
+    currency TEXT NOT NULL,
+    created_at DATETIME NOT NULL
+);
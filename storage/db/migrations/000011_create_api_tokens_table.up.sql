@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    hash TEXT NOT NULL UNIQUE,
+    scope TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    revoked_at DATETIME NULL
+);
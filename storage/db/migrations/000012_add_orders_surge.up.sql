@@ -0,0 +1 @@
+ALTER TABLE orders ADD COLUMN surge BOOLEAN NOT NULL DEFAULT FALSE;
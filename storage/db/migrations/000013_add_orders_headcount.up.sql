@@ -0,0 +1,2 @@
+ALTER TABLE orders ADD COLUMN headcount INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN items JSON NULL;
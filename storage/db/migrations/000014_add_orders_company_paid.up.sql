@@ -0,0 +1 @@
+ALTER TABLE orders ADD COLUMN company_paid BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS tracked_orders (
+    group_id TEXT NOT NULL PRIMARY KEY,
+    channel TEXT NOT NULL,
+    message_id TEXT NOT NULL,
+    text TEXT NOT NULL,
+    phase TEXT NOT NULL,
+    joined_message_id TEXT NOT NULL,
+    rates_message_id TEXT NOT NULL,
+    company_paid BOOLEAN NOT NULL DEFAULT FALSE,
+    session TEXT NOT NULL,
+    started_at DATETIME NOT NULL
+);
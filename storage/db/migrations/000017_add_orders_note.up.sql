@@ -0,0 +1 @@
+ALTER TABLE orders ADD COLUMN note TEXT NOT NULL DEFAULT '';
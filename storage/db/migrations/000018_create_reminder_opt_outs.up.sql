@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS reminder_opt_outs (
+    transport_id TEXT PRIMARY KEY,
+    created_at DATETIME NOT NULL
+);
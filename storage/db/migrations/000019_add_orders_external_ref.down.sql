@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS order_ref_sequence;
+ALTER TABLE orders DROP COLUMN external_ref;
@@ -0,0 +1,4 @@
+ALTER TABLE pending_debts DROP COLUMN currency;
+ALTER TABLE debt_payments DROP COLUMN currency;
+ALTER TABLE debts DROP COLUMN currency;
+ALTER TABLE orders DROP COLUMN currency;
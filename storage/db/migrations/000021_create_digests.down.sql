@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS digest_notifications;
+DROP TABLE IF EXISTS digest_users;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS digest_users (
+    transport_id TEXT PRIMARY KEY,
+    hour INTEGER NOT NULL,
+    created_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS digest_notifications (
+    transport_id TEXT NOT NULL,
+    key TEXT NOT NULL,
+    text TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    PRIMARY KEY (transport_id, key)
+);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS channel_settings (
+    channel TEXT NOT NULL,
+    name TEXT NOT NULL,
+    value TEXT NOT NULL,
+    updated_by TEXT NOT NULL,
+    updated_at DATETIME NOT NULL,
+    PRIMARY KEY (channel, name)
+);
@@ -0,0 +1 @@
+ALTER TABLE orders ADD COLUMN proof_url TEXT NOT NULL DEFAULT '';
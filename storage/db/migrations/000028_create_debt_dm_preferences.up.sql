@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS debt_dm_preferences (
+    transport_id TEXT PRIMARY KEY,
+    enabled BOOLEAN NOT NULL,
+    updated_at DATETIME NOT NULL
+);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS bank_accounts (
+    transport_id TEXT PRIMARY KEY,
+    holder TEXT NOT NULL,
+    iban TEXT NOT NULL,
+    updated_at DATETIME NOT NULL
+);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS venue_stats (
+    venue_name TEXT PRIMARY KEY COLLATE NOCASE,
+    deliveries INTEGER NOT NULL,
+    average_delivery INTEGER NOT NULL,
+    longest_delivery INTEGER NOT NULL,
+    done_timeout INTEGER NOT NULL,
+    status_check_interval INTEGER NOT NULL,
+    updated_at DATETIME NOT NULL
+);
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS private_amount_preferences (
+    transport_id TEXT PRIMARY KEY,
+    private BOOLEAN NOT NULL,
+    updated_at DATETIME NOT NULL
+);
@@ -0,0 +1 @@
+ALTER TABLE orders ADD COLUMN delivery_duration INTEGER NOT NULL DEFAULT 0;
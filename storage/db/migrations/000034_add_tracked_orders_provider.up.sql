@@ -0,0 +1 @@
+ALTER TABLE tracked_orders ADD COLUMN provider TEXT NOT NULL DEFAULT 'wolt';
@@ -0,0 +1,2 @@
+ALTER TABLE tracked_orders DROP COLUMN delivery_state;
+ALTER TABLE tracked_orders DROP COLUMN state;
@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS reminder_opt_outs;
+DROP TABLE IF EXISTS tracked_orders;
+DROP TABLE IF EXISTS api_tokens;
+DROP TABLE IF EXISTS abroad_users;
+DROP TABLE IF EXISTS insights_subscribers;
+DROP TABLE IF EXISTS venue_blacklist;
+DROP TABLE IF EXISTS pending_debts;
+DROP TABLE IF EXISTS debt_payments;
+DROP TABLE IF EXISTS queue_messages;
+DROP TABLE IF EXISTS order_participants;
+DROP TABLE IF EXISTS orders;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS debts;
@@ -0,0 +1,143 @@
+-- The PostgreSQL schema starts from the SQLite schema of the same version, so the versions of both stay aligned.
+-- The case-insensitive columns (COLLATE NOCASE in SQLite) are CITEXT, and the JSON columns keep the marshaled bytes like in SQLite.
+CREATE EXTENSION IF NOT EXISTS citext;
+
+CREATE TABLE IF NOT EXISTS debts (
+    id TEXT PRIMARY KEY,
+    borrower_id TEXT NOT NULL,
+    lender_id TEXT NOT NULL,
+    order_id TEXT NOT NULL,
+    amount DOUBLE PRECISION NOT NULL,
+    initial_transport TEXT NOT NULL,
+    thread_ts TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS users (
+    id TEXT PRIMARY KEY,
+    full_name TEXT NOT NULL,
+    email TEXT NOT NULL,
+    phone TEXT NULL,
+    timezone TEXT NOT NULL,
+    transport_id TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    deactivated_at TIMESTAMPTZ NULL
+);
+
+CREATE TABLE IF NOT EXISTS orders (
+    id TEXT PRIMARY KEY,
+    original_id TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    db_created_at TIMESTAMPTZ NOT NULL,
+    receiver TEXT NOT NULL,
+    venue_name TEXT NOT NULL,
+    venue_id TEXT NOT NULL,
+    venue_link TEXT NULL,
+    venue_city TEXT NULL,
+    host TEXT NOT NULL,
+    host_id TEXT NULL,
+    status INTEGER NOT NULL,
+    participants BYTEA NULL,
+    delivery_rate INTEGER NULL,
+    message_id TEXT NOT NULL DEFAULT '',
+    tags TEXT NOT NULL DEFAULT '',
+    -- NULL total amount means the order was saved before participants were indexed, and it should be backfilled
+    total_amount DOUBLE PRECISION NULL,
+    surge BOOLEAN NOT NULL DEFAULT FALSE,
+    headcount INTEGER NOT NULL DEFAULT 0,
+    items BYTEA NULL,
+    company_paid BOOLEAN NOT NULL DEFAULT FALSE,
+    note TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS order_participants (
+    order_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    amount DOUBLE PRECISION NOT NULL
+);
+CREATE INDEX IF NOT EXISTS order_participants_order_id ON order_participants (order_id);
+
+CREATE TABLE IF NOT EXISTS queue_messages (
+    id TEXT PRIMARY KEY,
+    topic TEXT NOT NULL,
+    payload BYTEA NOT NULL,
+    created_at BIGINT NOT NULL, -- Unix nanoseconds
+    claimed_by TEXT NOT NULL DEFAULT '',
+    claimed_at BIGINT NOT NULL DEFAULT 0, -- Unix nanoseconds, 0 when not claimed
+    attempts INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS queue_messages_topic_created_at ON queue_messages (topic, created_at);
+
+CREATE TABLE IF NOT EXISTS debt_payments (
+    id TEXT PRIMARY KEY,
+    borrower_id TEXT NOT NULL,
+    lender_id TEXT NOT NULL,
+    order_id TEXT NOT NULL,
+    amount DOUBLE PRECISION NOT NULL,
+    initial_transport TEXT NOT NULL,
+    thread_ts TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    paid_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS debt_payments_initial_transport ON debt_payments (initial_transport);
+
+CREATE TABLE IF NOT EXISTS pending_debts (
+    id TEXT PRIMARY KEY,
+    wolt_name CITEXT NOT NULL,
+    lender_id TEXT NOT NULL,
+    order_id TEXT NOT NULL,
+    amount DOUBLE PRECISION NOT NULL,
+    initial_transport TEXT NOT NULL,
+    thread_ts TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS pending_debts_wolt_name ON pending_debts (wolt_name);
+
+CREATE TABLE IF NOT EXISTS venue_blacklist (
+    channel TEXT NOT NULL,
+    venue_name CITEXT NOT NULL,
+    reason TEXT NOT NULL,
+    added_by TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (channel, venue_name)
+);
+
+CREATE TABLE IF NOT EXISTS insights_subscribers (
+    transport_id TEXT PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS abroad_users (
+    transport_id TEXT PRIMARY KEY,
+    currency TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    hash TEXT NOT NULL UNIQUE,
+    scope TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ NULL
+);
+
+CREATE TABLE IF NOT EXISTS tracked_orders (
+    group_id TEXT NOT NULL PRIMARY KEY,
+    channel TEXT NOT NULL,
+    message_id TEXT NOT NULL,
+    text TEXT NOT NULL,
+    phase TEXT NOT NULL,
+    joined_message_id TEXT NOT NULL,
+    rates_message_id TEXT NOT NULL,
+    company_paid BOOLEAN NOT NULL DEFAULT FALSE,
+    session TEXT NOT NULL,
+    started_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS reminder_opt_outs (
+    transport_id TEXT PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL
+);
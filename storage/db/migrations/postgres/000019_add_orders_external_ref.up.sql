@@ -0,0 +1,6 @@
+ALTER TABLE orders ADD COLUMN external_ref TEXT NOT NULL DEFAULT '';
+CREATE TABLE IF NOT EXISTS order_ref_sequence (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    value INTEGER NOT NULL
+);
+INSERT INTO order_ref_sequence (id, value) VALUES (1, 0);
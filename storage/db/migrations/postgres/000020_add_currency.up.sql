@@ -0,0 +1,4 @@
+ALTER TABLE orders ADD COLUMN currency TEXT NOT NULL DEFAULT 'ILS';
+ALTER TABLE debts ADD COLUMN currency TEXT NOT NULL DEFAULT 'ILS';
+ALTER TABLE debt_payments ADD COLUMN currency TEXT NOT NULL DEFAULT 'ILS';
+ALTER TABLE pending_debts ADD COLUMN currency TEXT NOT NULL DEFAULT 'ILS';
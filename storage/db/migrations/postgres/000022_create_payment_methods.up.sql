@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS payment_methods (
+    transport_id TEXT PRIMARY KEY,
+    methods TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
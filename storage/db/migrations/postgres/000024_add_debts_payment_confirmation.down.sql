@@ -0,0 +1,6 @@
+ALTER TABLE debt_payments DROP COLUMN payment_confirmed_at;
+ALTER TABLE debt_payments DROP COLUMN payment_confirmed_by;
+ALTER TABLE debt_payments DROP COLUMN paid_claimed_at;
+ALTER TABLE debts DROP COLUMN payment_confirmed_at;
+ALTER TABLE debts DROP COLUMN payment_confirmed_by;
+ALTER TABLE debts DROP COLUMN paid_claimed_at;
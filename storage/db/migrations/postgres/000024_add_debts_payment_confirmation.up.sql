@@ -0,0 +1,6 @@
+ALTER TABLE debts ADD COLUMN paid_claimed_at TIMESTAMPTZ NULL;
+ALTER TABLE debts ADD COLUMN payment_confirmed_by TEXT NOT NULL DEFAULT '';
+ALTER TABLE debts ADD COLUMN payment_confirmed_at TIMESTAMPTZ NULL;
+ALTER TABLE debt_payments ADD COLUMN paid_claimed_at TIMESTAMPTZ NULL;
+ALTER TABLE debt_payments ADD COLUMN payment_confirmed_by TEXT NOT NULL DEFAULT '';
+ALTER TABLE debt_payments ADD COLUMN payment_confirmed_at TIMESTAMPTZ NULL;
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS link_cursors (
+    channel TEXT PRIMARY KEY,
+    message_id TEXT NOT NULL,
+    handled_at TIMESTAMPTZ NOT NULL
+);
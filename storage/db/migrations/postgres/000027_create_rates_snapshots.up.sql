@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS rates_snapshots (
+    original_id TEXT PRIMARY KEY,
+    receiver TEXT NOT NULL,
+    message_id TEXT NOT NULL,
+    published_at TIMESTAMPTZ NOT NULL,
+    message TEXT NOT NULL,
+    item_rates TEXT NOT NULL,
+    host TEXT NOT NULL,
+    delivery_rate INTEGER NOT NULL,
+    discount DOUBLE PRECISION NOT NULL,
+    fee_allocation TEXT NOT NULL,
+    discount_allocation TEXT NOT NULL,
+    amount_rounding DOUBLE PRECISION NOT NULL,
+    rounding_remainder TEXT NOT NULL,
+    currency TEXT NOT NULL
+);
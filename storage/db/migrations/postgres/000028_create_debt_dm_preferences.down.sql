@@ -0,0 +1 @@
+DROP TABLE IF EXISTS debt_dm_preferences;
@@ -0,0 +1 @@
+ALTER TABLE debts DROP COLUMN escalated_days;
@@ -0,0 +1 @@
+ALTER TABLE debts ADD COLUMN escalated_days INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS venue_stats (
+    venue_name CITEXT PRIMARY KEY,
+    deliveries INTEGER NOT NULL,
+    average_delivery BIGINT NOT NULL,
+    longest_delivery BIGINT NOT NULL,
+    done_timeout BIGINT NOT NULL,
+    status_check_interval BIGINT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
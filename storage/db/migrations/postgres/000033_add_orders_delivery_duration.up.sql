@@ -0,0 +1 @@
+ALTER TABLE orders ADD COLUMN delivery_duration BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE tracked_orders DROP COLUMN provider;
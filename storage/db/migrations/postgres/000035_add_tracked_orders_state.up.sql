@@ -0,0 +1,2 @@
+ALTER TABLE tracked_orders ADD COLUMN state TEXT NOT NULL DEFAULT '';
+ALTER TABLE tracked_orders ADD COLUMN delivery_state TEXT NOT NULL DEFAULT '';
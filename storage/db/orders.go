@@ -4,41 +4,396 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 	"github.com/oriser/bolt/order"
 )
 
 type orderModel struct {
 	*order.Order
 	MarshaledParticipants []byte    `db:"participants"`
+	MarshaledItems        []byte    `db:"items"`
 	DBCreatedAt           time.Time `db:"db_created_at"`
+	JoinedTags            string    `db:"tags"`
+	TotalAmount           *float64  `db:"total_amount"`
 }
 
-func (d *DBStore) SaveOrder(_ context.Context, order *order.Order) error {
+// Tags are saved with surrounding separators so a single tag can be matched with LIKE
+func joinTags(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	return "," + strings.Join(tags, ",") + ","
+}
+
+func splitTags(joined string) []string {
+	joined = strings.Trim(joined, ",")
+	if joined == "" {
+		return nil
+	}
+	return strings.Split(joined, ",")
+}
+
+func (d *DBStore) SaveOrder(ctx context.Context, order *order.Order) error {
 	if order == nil {
 		return fmt.Errorf("nil order")
 	}
 	order.ID = uuid.NewString()
-	model := &orderModel{Order: order, DBCreatedAt: time.Now()}
 
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if err = d.insertOrder(tx, order, time.Now()); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+// insertOrder inserts the order with its participants
+func (d *DBStore) insertOrder(tx *sqlx.Tx, order *order.Order, dbCreatedAt time.Time) error {
+	model := &orderModel{Order: order, DBCreatedAt: dbCreatedAt}
 	marshaledParticipants, err := json.Marshal(order.Participants)
 	if err != nil {
 		return fmt.Errorf("marshal participants: %w", err)
 	}
 	model.MarshaledParticipants = marshaledParticipants
+	if len(order.Items) > 0 {
+		if model.MarshaledItems, err = json.Marshal(order.Items); err != nil {
+			return fmt.Errorf("marshal items: %w", err)
+		}
+	}
 
-	sql, args, err := sq.Insert("orders").Values(model.ID, model.OriginalID, model.CreatedAt, model.DBCreatedAt, model.Receiver, //nolint // it doesn't recognize the embedded struct
-		model.VenueName, model.VenueID, model.VenueLink, model.VenueCity, model.Host, model.HostID, model.Status, model.MarshaledParticipants, model.DeliveryRate).ToSql() // nolint // it doesn't recognize the embedded struct
+	sql, args, err := d.builder.Insert("orders").
+		Columns("id", "original_id", "created_at", "db_created_at", "receiver", "venue_name", "venue_id", "venue_link", "venue_city",
+													"host", "host_id", "status", "participants", "delivery_rate", "message_id", "tags", "total_amount", "surge", "headcount", "items", "company_paid", "note", "external_ref", "currency", "proof_url", "delivery_duration").
+		Values(model.ID, model.OriginalID, model.CreatedAt, model.DBCreatedAt, model.Receiver, //nolint // it doesn't recognize the embedded struct
+			model.VenueName, model.VenueID, model.VenueLink, model.VenueCity, model.Host, model.HostID, model.Status, model.MarshaledParticipants, model.DeliveryRate, // nolint // it doesn't recognize the embedded struct
+			model.MessageID, joinTags(model.Tags), order.TotalAmount(), model.Surge, model.Headcount, model.MarshaledItems, model.CompanyPaid, model.Note,
+			model.ExternalRef, model.Currency, model.ProofURL, int64(model.DeliveryDuration)).ToSql() // nolint // it doesn't recognize the embedded struct
 	if err != nil {
 		return fmt.Errorf("generating insert SQL: %w", err)
 	}
 
-	if _, err = d.db.Exec(sql, args...); err != nil {
+	if _, err = tx.Exec(sql, args...); err != nil {
 		return newExecError("saving order", sql, err, args...)
 	}
 
+	return d.insertOrderParticipants(tx, order)
+}
+
+// UpdateOrderParticipants replaces the participants of the stored orders of the Wolt group, and the total amount they're searched by
+func (d *DBStore) UpdateOrderParticipants(ctx context.Context, originalID string, participants []order.Participant) error {
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	sql, args, err := d.builder.Select("id").From("orders").Where(sq.Eq{"original_id": originalID}).ToSql()
+	if err != nil {
+		return fmt.Errorf("generating select SQL: %w", err)
+	}
+	var ids []string
+	if err = tx.Select(&ids, sql, args...); err != nil {
+		return newExecError("selecting orders", sql, err, args...)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	updated := &order.Order{Participants: participants}
+	marshaledParticipants, err := json.Marshal(participants)
+	if err != nil {
+		return fmt.Errorf("marshal participants: %w", err)
+	}
+	sql, args, err = d.builder.Update("orders").Set("participants", marshaledParticipants).Set("total_amount", updated.TotalAmount()).
+		Where(sq.Eq{"id": ids}).ToSql()
+	if err != nil {
+		return fmt.Errorf("generating update SQL: %w", err)
+	}
+	if _, err = tx.Exec(sql, args...); err != nil {
+		return newExecError("updating order participants", sql, err, args...)
+	}
+
+	sql, args, err = d.builder.Delete("order_participants").Where(sq.Eq{"order_id": ids}).ToSql()
+	if err != nil {
+		return fmt.Errorf("generating delete SQL: %w", err)
+	}
+	if _, err = tx.Exec(sql, args...); err != nil {
+		return newExecError("deleting order participants", sql, err, args...)
+	}
+	for _, id := range ids {
+		updated.ID = id
+		if err = d.insertOrderParticipants(tx, updated); err != nil {
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+// SetOrderProof sets the proof of purchase link of the stored orders of the Wolt group
+func (d *DBStore) SetOrderProof(ctx context.Context, originalID, proofURL string) error {
+	sql, args, err := d.builder.Update("orders").Set("proof_url", proofURL).Where(sq.Eq{"original_id": originalID}).ToSql()
+	if err != nil {
+		return fmt.Errorf("generating update SQL: %w", err)
+	}
+	res, err := d.db.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return newExecError("setting order proof", sql, err, args...)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("order not found")
+	}
+	return nil
+}
+
+// NextOrderRefSequence increments the sequence of the orders' external references and returns its new value
+func (d *DBStore) NextOrderRefSequence(ctx context.Context) (int64, error) {
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	sql, args, err := d.builder.Update("order_ref_sequence").Set("value", sq.Expr("value + 1")).Where(sq.Eq{"id": 1}).ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("generating update SQL: %w", err)
+	}
+	if _, err = tx.ExecContext(ctx, sql, args...); err != nil {
+		return 0, newExecError("incrementing order ref sequence", sql, err, args...)
+	}
+
+	sql, args, err = d.builder.Select("value").From("order_ref_sequence").Where(sq.Eq{"id": 1}).ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("generating select SQL: %w", err)
+	}
+	var value int64
+	if err = tx.GetContext(ctx, &value, sql, args...); err != nil {
+		return 0, newExecError("selecting order ref sequence", sql, err, args...)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit: %w", err)
+	}
+	return value, nil
+}
+
+func (d *DBStore) insertOrderParticipants(tx *sqlx.Tx, order *order.Order) error {
+	for _, participant := range order.Participants {
+		sql, args, err := d.builder.Insert("order_participants").Columns("order_id", "name", "user_id", "amount").
+			Values(order.ID, participant.Name, participant.ID, participant.Amount).ToSql()
+		if err != nil {
+			return fmt.Errorf("generating participant insert SQL: %w", err)
+		}
+		if _, err = tx.Exec(sql, args...); err != nil {
+			return newExecError("saving order participant", sql, err, args...)
+		}
+	}
+	return nil
+}
+
+// backfillOrdersSearch indexes the participants and total amount of orders saved before they were indexed
+func (d *DBStore) backfillOrdersSearch() error {
+	sql, args, err := d.builder.Select("*").From("orders").Where(sq.Eq{"total_amount": nil}).ToSql()
+	if err != nil {
+		return fmt.Errorf("generating select SQL: %w", err)
+	}
+
+	var models []*orderModel
+	if err = d.db.Select(&models, sql, args...); err != nil {
+		return newExecError("selecting orders to backfill", sql, err, args...)
+	}
+
+	for _, model := range models {
+		if len(model.MarshaledParticipants) > 0 {
+			if err := json.Unmarshal(model.MarshaledParticipants, &model.Order.Participants); err != nil {
+				return fmt.Errorf("unmarshal participants of order %s: %w", model.ID, err) //nolint // it doesn't recognize the embedded struct
+			}
+		}
+
+		tx, err := d.db.Beginx()
+		if err != nil {
+			return fmt.Errorf("begin transaction: %w", err)
+		}
+		if err = d.insertOrderParticipants(tx, model.Order); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		sql, args, err := d.builder.Update("orders").Set("total_amount", model.Order.TotalAmount()).Where(sq.Eq{"id": model.Order.ID}).ToSql()
+		if err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("generating update SQL: %w", err)
+		}
+		if _, err = tx.Exec(sql, args...); err != nil {
+			_ = tx.Rollback()
+			return newExecError("updating order total amount", sql, err, args...)
+		}
+		if err = tx.Commit(); err != nil {
+			return fmt.Errorf("commit: %w", err)
+		}
+	}
 	return nil
 }
+
+func likeContains(s string) string {
+	return "%" + s + "%"
+}
+
+func (d *DBStore) participantLike(name string) sq.Sqlizer {
+	operator := "LIKE"
+	if d.dialect == DialectPostgres {
+		operator = "ILIKE"
+	}
+	return sq.Expr("EXISTS (SELECT 1 FROM order_participants p WHERE p.order_id = orders.id AND p.name "+operator+" ?)", likeContains(name))
+}
+
+// sortDirection returns the SQL direction of a sort
+func sortDirection(ascending bool) string {
+	if ascending {
+		return "ASC"
+	}
+	return "DESC"
+}
+
+func (d *DBStore) ListOrders(ctx context.Context, filter order.ListFilter) ([]*order.Order, error) {
+	if d.archive != nil {
+		return d.listOrdersWithArchive(ctx, filter)
+	}
+	return d.listStoredOrders(ctx, filter)
+}
+
+// listStoredOrders lists the orders of the store, without the archived ones
+func (d *DBStore) listStoredOrders(ctx context.Context, filter order.ListFilter) ([]*order.Order, error) {
+	query := d.filterOrders(d.builder.Select("*").From("orders"), filter)
+	switch filter.SortBy {
+	case order.SortByCreatedAt:
+	case order.SortByTotalAmount, order.SortByVenueName:
+		query = query.OrderBy(fmt.Sprintf("%s %s", filter.SortBy, sortDirection(filter.Ascending)))
+	default:
+		return nil, fmt.Errorf("unknown orders sort field %q", filter.SortBy)
+	}
+	query = query.OrderBy("created_at "+sortDirection(filter.Ascending), "id")
+	query = withPagination(query, filter.Limit, filter.Offset)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("generating list SQL: %w", err)
+	}
+
+	var models []*orderModel
+	if err = d.db.SelectContext(ctx, &models, sql, args...); err != nil {
+		return nil, newExecError("selecting orders", sql, err, args...)
+	}
+
+	return ordersOfModels(models)
+}
+
+func (d *DBStore) CountOrders(ctx context.Context, filter order.ListFilter) (int, error) {
+	if d.archive != nil {
+		filter.Limit, filter.Offset = 0, 0
+		orders, err := d.listOrdersWithArchive(ctx, filter)
+		if err != nil {
+			return 0, err
+		}
+		return len(orders), nil
+	}
+	sql, args, err := d.filterOrders(d.builder.Select("COUNT(*)").From("orders"), filter).ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("generating count SQL: %w", err)
+	}
+
+	var count int
+	if err = d.db.GetContext(ctx, &count, sql, args...); err != nil {
+		return 0, newExecError("counting orders", sql, err, args...)
+	}
+	return count, nil
+}
+
+// filterOrders adds the conditions of the filter to the query
+func (d *DBStore) filterOrders(query sq.SelectBuilder, filter order.ListFilter) sq.SelectBuilder {
+
+	if filter.OriginalID != "" {
+		query = query.Where(sq.Eq{"original_id": filter.OriginalID})
+	}
+	if filter.Receiver != "" {
+		query = query.Where(sq.Eq{"receiver": filter.Receiver})
+	}
+	if filter.MessageID != "" {
+		query = query.Where(sq.Eq{"message_id": filter.MessageID})
+	}
+	if filter.Text != "" {
+		query = query.Where(sq.Or{
+			d.like("venue_name", likeContains(filter.Text)),
+			d.like("tags", likeContains(","+filter.Text+",")),
+			d.participantLike(filter.Text),
+		})
+	}
+	if filter.VenueName != "" {
+		query = query.Where(d.like("venue_name", likeContains(filter.VenueName)))
+	}
+	if filter.Participant != "" {
+		query = query.Where(d.participantLike(filter.Participant))
+	}
+	if len(filter.ParticipantIDs) > 0 {
+		args := make([]interface{}, len(filter.ParticipantIDs))
+		for i, id := range filter.ParticipantIDs {
+			args[i] = id
+		}
+		query = query.Where(sq.Expr("EXISTS (SELECT 1 FROM order_participants p WHERE p.order_id = orders.id AND p.user_id IN ("+
+			sq.Placeholders(len(args))+"))", args...))
+	}
+	if filter.Tag != "" {
+		query = query.Where(d.like("tags", likeContains(","+filter.Tag+",")))
+	}
+	if filter.MinAmount > 0 {
+		query = query.Where(sq.GtOrEq{"total_amount": filter.MinAmount})
+	}
+	if filter.MaxAmount > 0 {
+		query = query.Where(sq.LtOrEq{"total_amount": filter.MaxAmount})
+	}
+	return query
+}
+
+func ordersOfModels(models []*orderModel) ([]*order.Order, error) {
+	orders := make([]*order.Order, len(models))
+	for i, model := range models {
+		if len(model.MarshaledParticipants) > 0 {
+			if err := json.Unmarshal(model.MarshaledParticipants, &model.Order.Participants); err != nil {
+				return nil, fmt.Errorf("unmarshal participants of order %s: %w", model.ID, err) //nolint // it doesn't recognize the embedded struct
+			}
+		}
+		if len(model.MarshaledItems) > 0 {
+			if err := json.Unmarshal(model.MarshaledItems, &model.Order.Items); err != nil {
+				return nil, fmt.Errorf("unmarshal items of order %s: %w", model.ID, err) //nolint // it doesn't recognize the embedded struct
+			}
+		}
+		model.Order.Tags = splitTags(model.JoinedTags)
+		orders[i] = model.Order
+	}
+	return orders, nil
+}
@@ -8,6 +8,7 @@ import (
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/google/uuid"
+	"github.com/oriser/bolt/archive"
 	"github.com/oriser/bolt/order"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -38,6 +39,13 @@ func getDummyOrder() *order.Order {
 		},
 		Status:       order.StatusDone,
 		DeliveryRate: 50,
+		Surge:        true,
+		Headcount:    14,
+		CompanyPaid:  true,
+		Note:         "cash only today",
+		ExternalRef:  "BOLT-000001",
+		MessageID:    "1700000000.000100",
+		Items:        map[string]int{"Margherita": 2, "Caesar salad": 3},
 	}
 }
 
@@ -81,6 +89,8 @@ func TestSaveOrder(t *testing.T) {
 
 				err = json.Unmarshal(listedOrders[0].MarshaledParticipants, &listedOrders[0].Order.Participants)
 				require.NoError(t, err)
+				err = json.Unmarshal(listedOrders[0].MarshaledItems, &listedOrders[0].Order.Items)
+				require.NoError(t, err)
 				listedOrders[0].CreatedAt = formatTime(t, listedOrders[0].CreatedAt) // nolint // it doesn't recognize the embedded struct
 				savedOrder.CreatedAt = formatTime(t, listedOrders[0].CreatedAt)      // nolint // it doesn't recognize the embedded struct
 				assert.Equal(t, savedOrder, listedOrders[0].Order)
@@ -88,3 +98,443 @@ func TestSaveOrder(t *testing.T) {
 		})
 	}
 }
+
+func TestListOrders(t *testing.T) {
+	t.Parallel()
+
+	dbTest := NewDBTest(t)
+	t.Cleanup(func() {
+		dbTest.Cleanup(t)
+	})
+
+	pizza := getDummyOrder()
+	pizza.VenueName = "Pizza Place"
+	pizza.Tags = []string{"team", "friday"}
+	pizza.CreatedAt = time.Now().Add(-time.Hour)
+	sushi := getDummyOrder()
+	sushi.VenueName = "Sushi Bar"
+	sushi.Receiver = "other-receiver"
+	sushi.Participants = []order.Participant{{Name: "Freya", ID: "freya-id", Amount: 150}}
+	sushi.OriginalID = "SUSHI"
+	for _, o := range []*order.Order{pizza, sushi} {
+		require.NoError(t, dbTest.db.SaveOrder(context.Background(), o))
+	}
+
+	tests := []struct {
+		name     string
+		filter   order.ListFilter
+		expected []string
+	}{
+		{name: "No filter, newest first", filter: order.ListFilter{}, expected: []string{sushi.ID, pizza.ID}},
+		{name: "By venue name", filter: order.ListFilter{VenueName: "pizza"}, expected: []string{pizza.ID}},
+		{name: "By participant", filter: order.ListFilter{Participant: "frey"}, expected: []string{sushi.ID}},
+		{name: "By participant IDs", filter: order.ListFilter{ParticipantIDs: []string{"other-id", "freya-id"}}, expected: []string{sushi.ID}},
+		{name: "By tag", filter: order.ListFilter{Tag: "friday"}, expected: []string{pizza.ID}},
+		{name: "Partial tag doesn't match", filter: order.ListFilter{Tag: "fri"}, expected: []string{}},
+		{name: "By text matching participant", filter: order.ListFilter{Text: "Test2"}, expected: []string{pizza.ID}},
+		{name: "By text matching venue", filter: order.ListFilter{Text: "sushi"}, expected: []string{sushi.ID}},
+		{name: "By amount range", filter: order.ListFilter{MinAmount: 100, MaxAmount: 200}, expected: []string{sushi.ID}},
+		{name: "By original ID", filter: order.ListFilter{OriginalID: "SUSHI"}, expected: []string{sushi.ID}},
+		{name: "By receiver", filter: order.ListFilter{Receiver: "receiver"}, expected: []string{pizza.ID}},
+		{name: "With limit", filter: order.ListFilter{Limit: 1}, expected: []string{sushi.ID}},
+		{name: "With limit and offset", filter: order.ListFilter{Limit: 1, Offset: 1}, expected: []string{pizza.ID}},
+		{name: "With offset only", filter: order.ListFilter{Offset: 1}, expected: []string{pizza.ID}},
+		{name: "Oldest first", filter: order.ListFilter{Ascending: true}, expected: []string{pizza.ID, sushi.ID}},
+		{name: "By total amount", filter: order.ListFilter{SortBy: order.SortByTotalAmount}, expected: []string{sushi.ID, pizza.ID}},
+		{name: "By venue name, ascending", filter: order.ListFilter{SortBy: order.SortByVenueName, Ascending: true, Limit: 1}, expected: []string{pizza.ID}},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			orders, err := dbTest.db.ListOrders(context.Background(), tc.filter)
+			require.NoError(t, err)
+			ids := make([]string, len(orders))
+			for i, o := range orders {
+				ids[i] = o.ID
+			}
+			assert.Equal(t, tc.expected, ids)
+		})
+	}
+
+	orders, err := dbTest.db.ListOrders(context.Background(), order.ListFilter{Tag: "team"})
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	assert.Equal(t, pizza.Tags, orders[0].Tags)
+	assert.Equal(t, pizza.Participants, orders[0].Participants)
+
+	count, err := dbTest.db.CountOrders(context.Background(), order.ListFilter{MinAmount: 50, Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 2, count, "the count ignores the pagination")
+	count, err = dbTest.db.CountOrders(context.Background(), order.ListFilter{Receiver: "other-receiver"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	_, err = dbTest.db.ListOrders(context.Background(), order.ListFilter{SortBy: "host; DROP TABLE orders"})
+	assert.Error(t, err)
+}
+
+func TestArchiveOrders(t *testing.T) {
+	t.Parallel()
+
+	dbTest := NewDBTest(t)
+	t.Cleanup(func() {
+		dbTest.Cleanup(t)
+	})
+
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	old := getDummyOrder()
+	old.VenueName = "Pizza Place"
+	old.CreatedAt = now.AddDate(-2, 0, 0)
+	recent := getDummyOrder()
+	recent.VenueName = "Sushi Bar"
+	recent.CreatedAt = now.AddDate(0, -1, 0)
+	for _, o := range []*order.Order{old, recent} {
+		require.NoError(t, dbTest.db.SaveOrder(context.Background(), o))
+	}
+
+	bucket, err := archive.NewDirBucket(t.TempDir())
+	require.NoError(t, err)
+	ordersArchive, err := archive.New(archive.Config{AfterMonths: 12, Interval: time.Hour}, bucket)
+	require.NoError(t, err)
+	dbTest.db.SetArchive(ordersArchive)
+	archived, err := ordersArchive.ArchiveOrders(context.Background(), dbTest.db, now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, archived)
+	toArchive, err := dbTest.db.ListOrdersToArchive(context.Background(), now)
+	require.NoError(t, err)
+	require.Len(t, toArchive, 1, "the old order was removed from the store")
+	assert.Equal(t, recent.ID, toArchive[0].ID)
+
+	orders, err := dbTest.db.ListOrders(context.Background(), order.ListFilter{})
+	require.NoError(t, err)
+	require.Len(t, orders, 2, "the archived orders are still listed")
+	assert.Equal(t, recent.ID, orders[0].ID)
+	assert.Equal(t, old.ID, orders[1].ID)
+	assert.Equal(t, old.Participants, orders[1].Participants)
+	orders, err = dbTest.db.ListOrders(context.Background(), order.ListFilter{VenueName: "pizza"})
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	assert.Equal(t, old.ID, orders[0].ID)
+	orders, err = dbTest.db.ListOrders(context.Background(), order.ListFilter{Limit: 1, Offset: 1})
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	assert.Equal(t, old.ID, orders[0].ID)
+	count, err := dbTest.db.CountOrders(context.Background(), order.ListFilter{Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	archived, err = ordersArchive.ArchiveOrders(context.Background(), dbTest.db, now)
+	require.NoError(t, err)
+	assert.Zero(t, archived)
+}
+
+func TestUpdateOrderParticipants(t *testing.T) {
+	t.Parallel()
+
+	dbTest := NewDBTest(t)
+	t.Cleanup(func() {
+		dbTest.Cleanup(t)
+	})
+	ctx := context.Background()
+
+	saved := getDummyOrder()
+	require.NoError(t, dbTest.db.SaveOrder(ctx, saved))
+	participants := []order.Participant{{Name: "Test 1", Amount: 40}, {Name: "Freya", ID: "id456", Amount: 150}}
+	require.NoError(t, dbTest.db.UpdateOrderParticipants(ctx, saved.OriginalID, participants))
+	require.NoError(t, dbTest.db.UpdateOrderParticipants(ctx, "MISSING", participants), "orders which weren't saved are skipped")
+
+	orders, err := dbTest.db.ListOrders(ctx, order.ListFilter{OriginalID: saved.OriginalID})
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	assert.Equal(t, participants, orders[0].Participants)
+
+	orders, err = dbTest.db.ListOrders(ctx, order.ListFilter{Participant: "freya", MinAmount: 190})
+	require.NoError(t, err)
+	assert.Len(t, orders, 1, "the order is searched by its updated participants and total")
+	orders, err = dbTest.db.ListOrders(ctx, order.ListFilter{Participant: "Test2"})
+	require.NoError(t, err)
+	assert.Empty(t, orders)
+}
+
+func TestSetOrderProof(t *testing.T) {
+	t.Parallel()
+
+	dbTest := NewDBTest(t)
+	t.Cleanup(func() {
+		dbTest.Cleanup(t)
+	})
+	ctx := context.Background()
+
+	saved := getDummyOrder()
+	require.NoError(t, dbTest.db.SaveOrder(ctx, saved))
+	require.NoError(t, dbTest.db.SetOrderProof(ctx, saved.OriginalID, "https://files.example.com/receipt.jpg"))
+	assert.EqualError(t, dbTest.db.SetOrderProof(ctx, "MISSING", "https://files.example.com/receipt.jpg"), "order not found")
+
+	orders, err := dbTest.db.ListOrders(ctx, order.ListFilter{Receiver: saved.Receiver, MessageID: saved.MessageID})
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	assert.Equal(t, "https://files.example.com/receipt.jpg", orders[0].ProofURL)
+
+	orders, err = dbTest.db.ListOrders(ctx, order.ListFilter{MessageID: "other"})
+	require.NoError(t, err)
+	assert.Empty(t, orders)
+}
+
+func TestNextOrderRefSequence(t *testing.T) {
+	t.Parallel()
+
+	dbTest := NewDBTest(t)
+	t.Cleanup(func() {
+		dbTest.Cleanup(t)
+	})
+
+	for expected := int64(1); expected <= 3; expected++ {
+		value, err := dbTest.db.NextOrderRefSequence(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, expected, value)
+	}
+}
+
+func TestVenueBlacklist(t *testing.T) {
+	t.Parallel()
+
+	dbTest := NewDBTest(t)
+	t.Cleanup(func() {
+		dbTest.Cleanup(t)
+	})
+	ctx := context.Background()
+
+	require.NoError(t, dbTest.db.BlacklistVenue(ctx, &order.BlacklistedVenue{Channel: "C1", VenueName: "Pizza Place", Reason: "cold", AddedBy: "U1", CreatedAt: time.Now()}))
+	require.NoError(t, dbTest.db.BlacklistVenue(ctx, &order.BlacklistedVenue{Channel: "C1", VenueName: "pizza place", Reason: "cold and late", AddedBy: "U2", CreatedAt: time.Now()}))
+	require.NoError(t, dbTest.db.BlacklistVenue(ctx, &order.BlacklistedVenue{Channel: "C2", VenueName: "Sushi", Reason: "raw", AddedBy: "U1", CreatedAt: time.Now()}))
+
+	venues, err := dbTest.db.ListBlacklistedVenues(ctx, "C1")
+	require.NoError(t, err)
+	require.Len(t, venues, 1, "blacklisting a venue again replaces it")
+	assert.Equal(t, "cold and late", venues[0].Reason)
+	assert.Equal(t, "U2", venues[0].AddedBy)
+
+	removed, err := dbTest.db.UnblacklistVenue(ctx, "C1", "PIZZA PLACE")
+	require.NoError(t, err)
+	assert.True(t, removed)
+	removed, err = dbTest.db.UnblacklistVenue(ctx, "C1", "Sushi")
+	require.NoError(t, err)
+	assert.False(t, removed, "venues are blacklisted per channel")
+
+	venues, err = dbTest.db.ListBlacklistedVenues(ctx, "C1")
+	require.NoError(t, err)
+	assert.Empty(t, venues)
+}
+
+func TestChannelSettings(t *testing.T) {
+	t.Parallel()
+
+	dbTest := NewDBTest(t)
+	t.Cleanup(func() {
+		dbTest.Cleanup(t)
+	})
+	ctx := context.Background()
+
+	require.NoError(t, dbTest.db.SetChannelSetting(ctx, &order.ChannelSetting{Channel: "C1", Name: "DONT_JOIN_AFTER", Value: "12:00", UpdatedBy: "U1", UpdatedAt: time.Now()}))
+	require.NoError(t, dbTest.db.SetChannelSetting(ctx, &order.ChannelSetting{Channel: "C1", Name: "DONT_JOIN_AFTER", Value: "13:30", UpdatedBy: "U2", UpdatedAt: time.Now()}))
+	require.NoError(t, dbTest.db.SetChannelSetting(ctx, &order.ChannelSetting{Channel: "C1", Name: "SKIP_ORDER_EMOJI", Value: "x", UpdatedBy: "U1", UpdatedAt: time.Now()}))
+	require.NoError(t, dbTest.db.SetChannelSetting(ctx, &order.ChannelSetting{Channel: "C2", Name: "DONT_JOIN_AFTER", Value: "11:00", UpdatedBy: "U1", UpdatedAt: time.Now()}))
+
+	settings, err := dbTest.db.ListChannelSettings(ctx, "C1")
+	require.NoError(t, err)
+	require.Len(t, settings, 2, "setting a value again replaces it")
+	assert.Equal(t, "DONT_JOIN_AFTER", settings[0].Name)
+	assert.Equal(t, "13:30", settings[0].Value)
+	assert.Equal(t, "U2", settings[0].UpdatedBy)
+
+	removed, err := dbTest.db.RemoveChannelSetting(ctx, "C1", "SKIP_ORDER_EMOJI")
+	require.NoError(t, err)
+	assert.True(t, removed)
+	removed, err = dbTest.db.RemoveChannelSetting(ctx, "C2", "SKIP_ORDER_EMOJI")
+	require.NoError(t, err)
+	assert.False(t, removed, "settings are per channel")
+
+	settings, err = dbTest.db.ListChannelSettings(ctx, "C1")
+	require.NoError(t, err)
+	assert.Len(t, settings, 1)
+}
+
+func TestInsightsSubscribers(t *testing.T) {
+	t.Parallel()
+
+	dbTest := NewDBTest(t)
+	t.Cleanup(func() {
+		dbTest.Cleanup(t)
+	})
+	ctx := context.Background()
+
+	require.NoError(t, dbTest.db.SubscribeInsights(ctx, "U1"))
+	require.NoError(t, dbTest.db.SubscribeInsights(ctx, "U2"))
+	require.NoError(t, dbTest.db.SubscribeInsights(ctx, "U1"), "subscribing again is ignored")
+
+	subscribers, err := dbTest.db.ListInsightsSubscribers(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"U1", "U2"}, subscribers)
+
+	require.NoError(t, dbTest.db.UnsubscribeInsights(ctx, "U1"))
+	subscribers, err = dbTest.db.ListInsightsSubscribers(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"U2"}, subscribers)
+}
+
+func TestTrackedOrders(t *testing.T) {
+	t.Parallel()
+
+	dbTest := NewDBTest(t)
+	t.Cleanup(func() {
+		dbTest.Cleanup(t)
+	})
+	ctx := context.Background()
+
+	startedAt := time.Now().UTC().Truncate(time.Second)
+	joined := &order.TrackedOrder{GroupID: "A", Provider: "wolt", Channel: "C1", MessageID: "1.1", Text: "lunch #rnd", Phase: order.PhaseJoined,
+		State: "waiting_purchase", JoinedMessageID: "1.2", Session: `{"id":"real-a"}`, StartedAt: startedAt}
+	require.NoError(t, dbTest.db.SaveTrackedOrder(ctx, joined))
+	require.NoError(t, dbTest.db.SaveTrackedOrder(ctx, &order.TrackedOrder{GroupID: "B", Channel: "C2", MessageID: "2.1", Phase: order.PhaseJoined,
+		StartedAt: startedAt.Add(time.Minute)}))
+
+	delivery := *joined
+	delivery.Phase = order.PhaseDelivery
+	delivery.State = "delivering"
+	delivery.DeliveryState = "pickup"
+	delivery.RatesMessageID = "1.3"
+	delivery.CompanyPaid = true
+	require.NoError(t, dbTest.db.SaveTrackedOrder(ctx, &delivery))
+
+	tracked, err := dbTest.db.ListTrackedOrders(ctx)
+	require.NoError(t, err)
+	require.Len(t, tracked, 2, "saving a tracked order again replaces its state")
+	assert.Equal(t, delivery, *tracked[0])
+	assert.Equal(t, "B", tracked[1].GroupID)
+
+	require.NoError(t, dbTest.db.RemoveTrackedOrder(ctx, "A"))
+	tracked, err = dbTest.db.ListTrackedOrders(ctx)
+	require.NoError(t, err)
+	require.Len(t, tracked, 1)
+	assert.Equal(t, "B", tracked[0].GroupID)
+}
+
+func TestLinkCursors(t *testing.T) {
+	t.Parallel()
+
+	dbTest := NewDBTest(t)
+	t.Cleanup(func() {
+		dbTest.Cleanup(t)
+	})
+	ctx := context.Background()
+
+	cursors, err := dbTest.db.ListLinkCursors(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, cursors)
+
+	require.NoError(t, dbTest.db.SetLinkCursor(ctx, "C1", "1.1", time.Now()))
+	require.NoError(t, dbTest.db.SetLinkCursor(ctx, "C2", "2.1", time.Now()))
+	require.NoError(t, dbTest.db.SetLinkCursor(ctx, "C1", "1.2", time.Now()))
+
+	cursors, err = dbTest.db.ListLinkCursors(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"C1": "1.2", "C2": "2.1"}, cursors, "setting a cursor again replaces it")
+}
+
+func TestRatesSnapshots(t *testing.T) {
+	t.Parallel()
+
+	dbTest := NewDBTest(t)
+	t.Cleanup(func() {
+		dbTest.Cleanup(t)
+	})
+	ctx := context.Background()
+
+	snapshot, err := dbTest.db.GetRatesSnapshot(ctx, "A")
+	require.NoError(t, err)
+	assert.Nil(t, snapshot)
+
+	published := &order.RatesSnapshot{OriginalID: "A", Receiver: "C1", MessageID: "1.3", PublishedAt: time.Now().UTC().Truncate(time.Second),
+		Message: "Rates for Wolt order ID A", ItemRates: `{"Loki":30,"Thor":50}`, Host: "Thor", DeliveryRate: 10, Discount: 5.5,
+		FeeAllocation: "equal", DiscountAllocation: "proportional", RoundingRemainder: "host", Currency: "ILS"}
+	require.NoError(t, dbTest.db.SaveRatesSnapshot(ctx, published))
+	changed := *published
+	changed.Message = "Changed rates"
+	require.NoError(t, dbTest.db.SaveRatesSnapshot(ctx, &changed))
+
+	snapshot, err = dbTest.db.GetRatesSnapshot(ctx, "A")
+	require.NoError(t, err)
+	assert.Equal(t, published, snapshot, "a snapshot isn't replaced")
+}
+
+func TestVenueStats(t *testing.T) {
+	t.Parallel()
+
+	dbTest := NewDBTest(t)
+	t.Cleanup(func() {
+		dbTest.Cleanup(t)
+	})
+	ctx := context.Background()
+
+	stats, err := dbTest.db.GetVenueStats(ctx, "Catering")
+	require.NoError(t, err)
+	assert.Nil(t, stats)
+
+	require.NoError(t, dbTest.db.AddVenueDelivery(ctx, "Catering", 3*time.Hour))
+	require.NoError(t, dbTest.db.AddVenueDelivery(ctx, "catering", time.Hour))
+	require.NoError(t, dbTest.db.SetVenueTimeouts(ctx, "CATERING", 6*time.Hour, time.Minute))
+	stats, err = dbTest.db.GetVenueStats(ctx, "Catering")
+	require.NoError(t, err)
+	require.NotNil(t, stats)
+	assert.Equal(t, "Catering", stats.VenueName, "the venue names are matched case-insensitively")
+	assert.Equal(t, 2, stats.Deliveries)
+	assert.Equal(t, 2*time.Hour, stats.AverageDelivery)
+	assert.Equal(t, 3*time.Hour, stats.LongestDelivery)
+	assert.Equal(t, 6*time.Hour, stats.DoneTimeout)
+	assert.Equal(t, time.Minute, stats.StatusCheckInterval)
+}
+
+func TestVenueSummaries(t *testing.T) {
+	t.Parallel()
+
+	dbTest := NewDBTest(t)
+	t.Cleanup(func() {
+		dbTest.Cleanup(t)
+	})
+	ctx := context.Background()
+
+	save := func(originalID, receiver, venue string, deliveryRate int, createdAt time.Time, status order.Status) {
+		saved := getDummyOrder()
+		saved.OriginalID, saved.Receiver, saved.VenueName, saved.DeliveryRate, saved.CreatedAt, saved.Status =
+			originalID, receiver, venue, deliveryRate, createdAt, status
+		require.NoError(t, dbTest.db.SaveOrder(ctx, saved))
+	}
+	now := time.Now()
+	save("P1", "C1", "Pizza", 10, now.Add(-time.Hour), order.StatusDone)
+	save("P2", "C1", "Pizza", 20, now.Add(-2*time.Hour), order.StatusDone)
+	save("P3", "C1", "Pizza", 30, now.Add(-3*time.Hour), order.StatusCanceled)
+	save("S1", "C1", "Sushi", 15, now.Add(-time.Hour), order.StatusDone)
+	save("S2", "C1", "Sushi", 15, now.AddDate(0, 0, -100), order.StatusDone)
+	save("B1", "C2", "Burger", 5, now.Add(-time.Hour), order.StatusDone)
+	require.NoError(t, dbTest.db.SetDeliveryDuration(ctx, "P1", 30*time.Minute))
+
+	summaries, err := dbTest.db.ListVenueSummaries(ctx, order.VenueSummaryFilter{Receiver: "C1", Since: now.AddDate(0, 0, -30)})
+	require.NoError(t, err)
+	assert.Equal(t, []order.VenueSummary{
+		{VenueName: "Pizza", Orders: 2, AverageDeliveryRate: 15, AverageDeliveryDuration: 30 * time.Minute},
+		{VenueName: "Sushi", Orders: 1, AverageDeliveryRate: 15},
+	}, summaries)
+
+	summaries, err = dbTest.db.ListVenueSummaries(ctx, order.VenueSummaryFilter{Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []order.VenueSummary{
+		{VenueName: "Pizza", Orders: 2, AverageDeliveryRate: 15, AverageDeliveryDuration: 30 * time.Minute},
+		{VenueName: "Sushi", Orders: 2, AverageDeliveryRate: 15},
+	}, summaries, "sorted by the orders, then by the venue name")
+
+	orders, err := dbTest.db.ListOrders(ctx, order.ListFilter{OriginalID: "P1"})
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	assert.Equal(t, 30*time.Minute, orders[0].DeliveryDuration)
+}
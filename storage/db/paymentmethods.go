@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	userDomain "github.com/oriser/bolt/user"
+)
+
+// paymentMethodsSeparator separates the names of the payment methods in the methods column
+const paymentMethodsSeparator = ","
+
+func joinPaymentMethods(methods []userDomain.PaymentMethod) string {
+	names := make([]string, len(methods))
+	for i, method := range methods {
+		names[i] = method.String()
+	}
+	return strings.Join(names, paymentMethodsSeparator)
+}
+
+func splitPaymentMethods(joined string) ([]userDomain.PaymentMethod, error) {
+	names := strings.Split(joined, paymentMethodsSeparator)
+	methods := make([]userDomain.PaymentMethod, len(names))
+	for i, name := range names {
+		method, err := userDomain.ParsePaymentMethod(name)
+		if err != nil {
+			return nil, err
+		}
+		methods[i] = method
+	}
+	return methods, nil
+}
+
+func (d *DBStore) SetPaymentMethods(ctx context.Context, transportID string, methods []userDomain.PaymentMethod) error {
+	var (
+		query string
+		args  []interface{}
+		err   error
+	)
+	if len(methods) > 0 {
+		query, args, err = d.builder.Insert("payment_methods").Values(transportID, joinPaymentMethods(methods), time.Now().UTC()).
+			Suffix(onConflictUpdate([]string{"transport_id"}, "methods")).ToSql()
+	} else {
+		query, args, err = d.builder.Delete("payment_methods").Where(sq.Eq{"transport_id": transportID}).ToSql()
+	}
+	if err != nil {
+		return fmt.Errorf("generating SQL: %w", err)
+	}
+
+	if _, err = d.db.ExecContext(ctx, query, args...); err != nil {
+		return newExecError("setting payment methods", query, err, args...)
+	}
+	return nil
+}
+
+func (d *DBStore) PaymentMethods(ctx context.Context, transportID string) ([]userDomain.PaymentMethod, error) {
+	query, args, err := d.builder.Select("methods").From("payment_methods").Where(sq.Eq{"transport_id": transportID}).ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("generating select SQL: %w", err)
+	}
+
+	joined := ""
+	if err = d.db.GetContext(ctx, &joined, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, newExecError("selecting payment methods", query, err, args...)
+	}
+	return splitPaymentMethods(joined)
+}
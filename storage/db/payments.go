@@ -0,0 +1,55 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/oriser/bolt/debt"
+)
+
+func (d *DBStore) AddPayment(ctx context.Context, payment *debt.Payment) error {
+	if payment == nil {
+		return fmt.Errorf("nil payment")
+	}
+
+	sql, args, err := d.builder.Insert("debt_payments").Values(payment.ID, payment.BorrowerID, payment.LenderID, payment.OrderID,
+		payment.Amount, payment.InitiatedTransportID, payment.MessageID, payment.CreatedAt.UTC(), payment.PaidAt.UTC(), payment.Currency,
+		utcTimePtr(payment.PaidClaimedAt), payment.PaymentConfirmedBy, utcTimePtr(payment.PaymentConfirmedAt)).ToSql()
+	if err != nil {
+		return fmt.Errorf("generating insert SQL: %w", err)
+	}
+
+	if _, err = d.db.ExecContext(ctx, sql, args...); err != nil {
+		return newExecError("adding payment", sql, err, args...)
+	}
+	return nil
+}
+
+func (d *DBStore) ListPayments(ctx context.Context, filter debt.PaymentListFilter) ([]*debt.Payment, error) {
+	query := d.builder.Select("*").From("debt_payments").OrderBy("paid_at DESC")
+	if filter.Channel != "" {
+		query = query.Where(sq.Eq{"initial_transport": filter.Channel})
+	}
+	if filter.BorrowerID != "" {
+		query = query.Where(sq.Eq{"borrower_id": filter.BorrowerID})
+	}
+	if filter.OrderID != "" {
+		query = query.Where(sq.Eq{"order_id": filter.OrderID})
+	}
+	if !filter.PaidBefore.IsZero() {
+		// The times are stored in UTC, so they are compared as strings correctly
+		query = query.Where(sq.Lt{"paid_at": filter.PaidBefore.UTC()})
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("generating list SQL: %w", err)
+	}
+
+	payments := []*debt.Payment{}
+	if err = d.db.SelectContext(ctx, &payments, sql, args...); err != nil {
+		return nil, newExecError("selecting payments", sql, err, args...)
+	}
+	return payments, nil
+}
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/oriser/bolt/debt"
+)
+
+func (d *DBStore) AddPendingDebt(ctx context.Context, pending *debt.PendingDebt) error {
+	if pending == nil {
+		return fmt.Errorf("nil pending debt")
+	}
+	if pending.ID == "" {
+		pending.ID = uuid.NewString()
+	}
+
+	sql, args, err := d.builder.Insert("pending_debts").Values(pending.ID, pending.WoltName, pending.LenderID, pending.OrderID,
+		pending.Amount, pending.InitiatedTransportID, pending.MessageID, pending.CreatedAt, pending.Currency).ToSql()
+	if err != nil {
+		return fmt.Errorf("generating insert SQL: %w", err)
+	}
+
+	if _, err = d.db.ExecContext(ctx, sql, args...); err != nil {
+		return newExecError("adding pending debt", sql, err, args...)
+	}
+	return nil
+}
+
+func (d *DBStore) ListPendingDebts(ctx context.Context, woltName string) ([]*debt.PendingDebt, error) {
+	// The column is case-insensitive
+	sql, args, err := d.builder.Select("*").From("pending_debts").Where(sq.Eq{"wolt_name": woltName}).OrderBy("created_at").ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("generating list SQL: %w", err)
+	}
+
+	pending := []*debt.PendingDebt{}
+	if err = d.db.SelectContext(ctx, &pending, sql, args...); err != nil {
+		return nil, newExecError("selecting pending debts", sql, err, args...)
+	}
+	return pending, nil
+}
+
+func (d *DBStore) RemovePendingDebt(ctx context.Context, id string) error {
+	sql, args, err := d.builder.Delete("pending_debts").Where(sq.Eq{"id": id}).ToSql()
+	if err != nil {
+		return fmt.Errorf("generating delete SQL: %w", err)
+	}
+
+	if _, err = d.db.ExecContext(ctx, sql, args...); err != nil {
+		return newExecError("deleting pending debt", sql, err, args...)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+func (d *DBStore) SetPrivateAmounts(ctx context.Context, transportID string, private bool) error {
+	query, args, err := d.builder.Insert("private_amount_preferences").Values(transportID, private, time.Now().UTC()).
+		Suffix(onConflictUpdate([]string{"transport_id"}, "private", "updated_at")).ToSql()
+	if err != nil {
+		return fmt.Errorf("generating insert SQL: %w", err)
+	}
+
+	if _, err = d.db.ExecContext(ctx, query, args...); err != nil {
+		return newExecError("setting private amounts", query, err, args...)
+	}
+	return nil
+}
+
+func (d *DBStore) PrivateAmounts(ctx context.Context, transportID string) (bool, error) {
+	query, args, err := d.builder.Select("private").From("private_amount_preferences").Where(sq.Eq{"transport_id": transportID}).ToSql()
+	if err != nil {
+		return false, fmt.Errorf("generating select SQL: %w", err)
+	}
+
+	private := false
+	if err = d.db.GetContext(ctx, &private, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, newExecError("selecting private amounts", query, err, args...)
+	}
+	return private, nil
+}
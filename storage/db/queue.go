@@ -0,0 +1,95 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/oriser/bolt/queue"
+)
+
+type queueMessageModel struct {
+	ID       string `db:"id"`
+	Topic    string `db:"topic"`
+	Payload  []byte `db:"payload"`
+	Attempts int    `db:"attempts"`
+}
+
+func (d *DBStore) EnqueueMessage(ctx context.Context, topic string, payload []byte) error {
+	query, args, err := d.builder.Insert("queue_messages").Columns("id", "topic", "payload", "created_at").
+		Values(uuid.NewString(), topic, payload, time.Now().UnixNano()).ToSql()
+	if err != nil {
+		return fmt.Errorf("generating insert SQL: %w", err)
+	}
+
+	if _, err = d.db.ExecContext(ctx, query, args...); err != nil {
+		return newExecError("enqueuing message", query, err, args...)
+	}
+	return nil
+}
+
+func (d *DBStore) ClaimMessage(ctx context.Context, topic, consumer string, claimTimeout time.Duration) (*queue.Message, error) {
+	now := time.Now()
+	// Claiming in a single statement, so concurrent consumers (also from other processes) won't claim the same message
+	// The subquery is embedded in the update, which sets the placeholders of the dialect for both
+	oldest := sq.Select("id").From("queue_messages").
+		Where(sq.Eq{"topic": topic}).
+		Where(sq.Or{sq.Eq{"claimed_at": 0}, sq.Lt{"claimed_at": now.Add(-claimTimeout).UnixNano()}}).
+		OrderBy("created_at").Limit(1)
+	if d.dialect == DialectPostgres {
+		// Unlike SQLite, PostgreSQL doesn't lock the whole DB for the update, so the message is locked to not be claimed twice
+		oldest = oldest.Suffix("FOR UPDATE SKIP LOCKED")
+	}
+	oldestSQL, oldestArgs, err := oldest.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("generating select SQL: %w", err)
+	}
+
+	query, args, err := d.builder.Update("queue_messages").
+		Set("claimed_by", consumer).
+		Set("claimed_at", now.UnixNano()).
+		Set("attempts", sq.Expr("attempts + 1")).
+		Where("id = ("+oldestSQL+")", oldestArgs...).
+		Suffix("RETURNING id, topic, payload, attempts").ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("generating update SQL: %w", err)
+	}
+
+	model := queueMessageModel{}
+	if err = d.db.QueryRowxContext(ctx, query, args...).StructScan(&model); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, newExecError("claiming message", query, err, args...)
+	}
+
+	return &queue.Message{ID: model.ID, Topic: model.Topic, Payload: model.Payload, Attempts: model.Attempts}, nil
+}
+
+func (d *DBStore) AckMessage(ctx context.Context, id string) error {
+	query, args, err := d.builder.Delete("queue_messages").Where(sq.Eq{"id": id}).ToSql()
+	if err != nil {
+		return fmt.Errorf("generating delete SQL: %w", err)
+	}
+
+	if _, err = d.db.ExecContext(ctx, query, args...); err != nil {
+		return newExecError("acknowledging message", query, err, args...)
+	}
+	return nil
+}
+
+func (d *DBStore) ReleaseMessage(ctx context.Context, id string) error {
+	query, args, err := d.builder.Update("queue_messages").Set("claimed_by", "").Set("claimed_at", 0).Where(sq.Eq{"id": id}).ToSql()
+	if err != nil {
+		return fmt.Errorf("generating update SQL: %w", err)
+	}
+
+	if _, err = d.db.ExecContext(ctx, query, args...); err != nil {
+		return newExecError("releasing message", query, err, args...)
+	}
+	return nil
+}
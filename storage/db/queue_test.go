@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueueMessages(t *testing.T) {
+	t.Parallel()
+
+	dbTest := NewDBTest(t)
+	t.Cleanup(func() {
+		dbTest.Cleanup(t)
+	})
+	ctx := context.Background()
+
+	msg, err := dbTest.db.ClaimMessage(ctx, "links", "consumer1", time.Hour)
+	require.NoError(t, err)
+	assert.Nil(t, msg, "empty queue")
+
+	require.NoError(t, dbTest.db.EnqueueMessage(ctx, "links", []byte("first")))
+	require.NoError(t, dbTest.db.EnqueueMessage(ctx, "other", []byte("other topic")))
+	require.NoError(t, dbTest.db.EnqueueMessage(ctx, "links", []byte("second")))
+
+	first, err := dbTest.db.ClaimMessage(ctx, "links", "consumer1", time.Hour)
+	require.NoError(t, err)
+	require.NotNil(t, first)
+	assert.Equal(t, "first", string(first.Payload))
+	assert.Equal(t, 1, first.Attempts)
+
+	second, err := dbTest.db.ClaimMessage(ctx, "links", "consumer2", time.Hour)
+	require.NoError(t, err)
+	require.NotNil(t, second)
+	assert.Equal(t, "second", string(second.Payload))
+
+	msg, err = dbTest.db.ClaimMessage(ctx, "links", "consumer3", time.Hour)
+	require.NoError(t, err)
+	assert.Nil(t, msg, "all messages are claimed")
+
+	// Released messages are claimed again
+	require.NoError(t, dbTest.db.ReleaseMessage(ctx, first.ID))
+	msg, err = dbTest.db.ClaimMessage(ctx, "links", "consumer3", time.Hour)
+	require.NoError(t, err)
+	require.NotNil(t, msg)
+	assert.Equal(t, first.ID, msg.ID)
+	assert.Equal(t, 2, msg.Attempts)
+
+	// Acknowledged messages are gone, and messages claimed before the claim timeout are claimed again
+	require.NoError(t, dbTest.db.AckMessage(ctx, first.ID))
+	msg, err = dbTest.db.ClaimMessage(ctx, "links", "consumer3", -time.Second)
+	require.NoError(t, err)
+	require.NotNil(t, msg)
+	assert.Equal(t, second.ID, msg.ID)
+	require.NoError(t, dbTest.db.AckMessage(ctx, second.ID))
+
+	msg, err = dbTest.db.ClaimMessage(ctx, "links", "consumer3", -time.Second)
+	require.NoError(t, err)
+	assert.Nil(t, msg)
+}
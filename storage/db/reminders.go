@@ -0,0 +1,43 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+func (d *DBStore) SetRemindersOptOut(ctx context.Context, transportID string, optOut bool) error {
+	var (
+		query string
+		args  []interface{}
+		err   error
+	)
+	if optOut {
+		query, args, err = d.builder.Insert("reminder_opt_outs").Values(transportID, time.Now().UTC()).Suffix(onConflictIgnore).ToSql()
+	} else {
+		query, args, err = d.builder.Delete("reminder_opt_outs").Where(sq.Eq{"transport_id": transportID}).ToSql()
+	}
+	if err != nil {
+		return fmt.Errorf("generating SQL: %w", err)
+	}
+
+	if _, err = d.db.ExecContext(ctx, query, args...); err != nil {
+		return newExecError("setting reminders opt out", query, err, args...)
+	}
+	return nil
+}
+
+func (d *DBStore) RemindersOptedOut(ctx context.Context, transportID string) (bool, error) {
+	query, args, err := d.builder.Select("COUNT(*)").From("reminder_opt_outs").Where(sq.Eq{"transport_id": transportID}).ToSql()
+	if err != nil {
+		return false, fmt.Errorf("generating select SQL: %w", err)
+	}
+
+	count := 0
+	if err = d.db.GetContext(ctx, &count, query, args...); err != nil {
+		return false, newExecError("selecting reminders opt out", query, err, args...)
+	}
+	return count > 0, nil
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/oriser/bolt/order"
+)
+
+func (d *DBStore) SaveRatesSnapshot(ctx context.Context, snapshot *order.RatesSnapshot) error {
+	if snapshot == nil {
+		return fmt.Errorf("nil snapshot")
+	}
+
+	query, args, err := d.builder.Insert("rates_snapshots").
+		Columns("original_id", "receiver", "message_id", "published_at", "message", "item_rates", "host", "delivery_rate", "discount",
+			"fee_allocation", "discount_allocation", "amount_rounding", "rounding_remainder", "currency").
+		Values(snapshot.OriginalID, snapshot.Receiver, snapshot.MessageID, snapshot.PublishedAt.UTC(), snapshot.Message, snapshot.ItemRates,
+			snapshot.Host, snapshot.DeliveryRate, snapshot.Discount, snapshot.FeeAllocation, snapshot.DiscountAllocation,
+			snapshot.AmountRounding, snapshot.RoundingRemainder, snapshot.Currency).
+		Suffix(onConflictIgnore).ToSql()
+	if err != nil {
+		return fmt.Errorf("generating insert SQL: %w", err)
+	}
+
+	if _, err = d.db.ExecContext(ctx, query, args...); err != nil {
+		return newExecError("saving rates snapshot", query, err, args...)
+	}
+	return nil
+}
+
+func (d *DBStore) GetRatesSnapshot(ctx context.Context, originalID string) (*order.RatesSnapshot, error) {
+	query, args, err := d.builder.Select("*").From("rates_snapshots").Where(sq.Eq{"original_id": originalID}).ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("generating select SQL: %w", err)
+	}
+
+	snapshot := &order.RatesSnapshot{}
+	if err = d.db.GetContext(ctx, snapshot, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, newExecError("selecting rates snapshot", query, err, args...)
+	}
+	return snapshot, nil
+}
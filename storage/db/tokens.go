@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/oriser/bolt/token"
+)
+
+func (d *DBStore) AddToken(ctx context.Context, t *token.Token) error {
+	if t == nil {
+		return fmt.Errorf("nil token")
+	}
+
+	sql, args, err := d.builder.Insert("api_tokens").Values(t.ID, t.Name, t.Hash, t.Scope, t.UserID, t.CreatedAt.UTC(), nil).ToSql()
+	if err != nil {
+		return fmt.Errorf("generating insert SQL: %w", err)
+	}
+
+	if _, err = d.db.ExecContext(ctx, sql, args...); err != nil {
+		return newExecError("adding token", sql, err, args...)
+	}
+	return nil
+}
+
+func (d *DBStore) getToken(ctx context.Context, where sq.Eq, notFoundID string) (*token.Token, error) {
+	sql, args, err := d.builder.Select("*").From("api_tokens").Where(where).ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("generating select SQL: %w", err)
+	}
+
+	tokens := []*token.Token{}
+	if err = d.db.SelectContext(ctx, &tokens, sql, args...); err != nil {
+		return nil, newExecError("selecting token", sql, err, args...)
+	}
+	if len(tokens) == 0 {
+		return nil, &token.ErrNotFound{ID: notFoundID}
+	}
+	return tokens[0], nil
+}
+
+func (d *DBStore) GetToken(ctx context.Context, id string) (*token.Token, error) {
+	return d.getToken(ctx, sq.Eq{"id": id}, id)
+}
+
+func (d *DBStore) GetTokenByHash(ctx context.Context, hash string) (*token.Token, error) {
+	return d.getToken(ctx, sq.Eq{"hash": hash}, "with the given secret")
+}
+
+func (d *DBStore) ListTokens(ctx context.Context) ([]*token.Token, error) {
+	sql, args, err := d.builder.Select("*").From("api_tokens").OrderBy("created_at").ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("generating list SQL: %w", err)
+	}
+
+	tokens := []*token.Token{}
+	if err = d.db.SelectContext(ctx, &tokens, sql, args...); err != nil {
+		return nil, newExecError("selecting tokens", sql, err, args...)
+	}
+	return tokens, nil
+}
+
+func (d *DBStore) RevokeToken(ctx context.Context, id string, revokedAt time.Time) error {
+	sql, args, err := d.builder.Update("api_tokens").Set("revoked_at", revokedAt.UTC()).Where(sq.Eq{"id": id}).ToSql()
+	if err != nil {
+		return fmt.Errorf("generating update SQL: %w", err)
+	}
+
+	if _, err = d.db.ExecContext(ctx, sql, args...); err != nil {
+		return newExecError("revoking token", sql, err, args...)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/oriser/bolt/token"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokens(t *testing.T) {
+	t.Parallel()
+
+	dbTest := NewDBTest(t)
+	t.Cleanup(func() {
+		dbTest.Cleanup(t)
+	})
+	ctx := context.Background()
+
+	issued, secret, err := token.Issue(ctx, dbTest.db, "ci", token.ScopeReadOnly, "U1")
+	require.NoError(t, err)
+	authenticated, err := token.Authenticate(ctx, dbTest.db, secret)
+	require.NoError(t, err)
+	assert.Equal(t, issued.ID, authenticated.ID)
+	assert.Equal(t, token.ScopeReadOnly, authenticated.Scope)
+	assert.Equal(t, "U1", authenticated.UserID)
+	assert.Nil(t, authenticated.RevokedAt)
+
+	rotated, rotatedSecret, err := token.Rotate(ctx, dbTest.db, issued.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "ci", rotated.Name)
+	_, err = token.Authenticate(ctx, dbTest.db, secret)
+	assert.Error(t, err, "the rotated token is revoked")
+	_, err = token.Authenticate(ctx, dbTest.db, rotatedSecret)
+	require.NoError(t, err)
+
+	tokens, err := dbTest.db.ListTokens(ctx)
+	require.NoError(t, err)
+	require.Len(t, tokens, 2)
+	assert.True(t, tokens[0].Revoked())
+	assert.False(t, tokens[1].Revoked())
+
+	_, err = dbTest.db.GetToken(ctx, "unknown")
+	var notFound *token.ErrNotFound
+	assert.True(t, errors.As(err, &notFound))
+}
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/oriser/bolt/order"
+)
+
+func (d *DBStore) SaveTrackedOrder(ctx context.Context, tracked *order.TrackedOrder) error {
+	if tracked == nil {
+		return fmt.Errorf("nil tracked order")
+	}
+
+	sql, args, err := d.builder.Insert("tracked_orders").
+		Columns("group_id", "provider", "channel", "message_id", "text", "phase", "state", "delivery_state", "joined_message_id", "rates_message_id",
+			"company_paid", "session", "started_at").
+		Values(tracked.GroupID, tracked.Provider, tracked.Channel, tracked.MessageID, tracked.Text, tracked.Phase, tracked.State, tracked.DeliveryState,
+			tracked.JoinedMessageID, tracked.RatesMessageID, tracked.CompanyPaid, tracked.Session, tracked.StartedAt.UTC()).
+		Suffix(onConflictUpdate([]string{"group_id"}, "provider", "channel", "message_id", "text", "phase", "state", "delivery_state", "joined_message_id",
+			"rates_message_id", "company_paid", "session", "started_at")).ToSql()
+	if err != nil {
+		return fmt.Errorf("generating insert SQL: %w", err)
+	}
+
+	if _, err = d.db.ExecContext(ctx, sql, args...); err != nil {
+		return newExecError("saving tracked order", sql, err, args...)
+	}
+	return nil
+}
+
+func (d *DBStore) RemoveTrackedOrder(ctx context.Context, groupID string) error {
+	sql, args, err := d.builder.Delete("tracked_orders").Where(sq.Eq{"group_id": groupID}).ToSql()
+	if err != nil {
+		return fmt.Errorf("generating delete SQL: %w", err)
+	}
+
+	if _, err = d.db.ExecContext(ctx, sql, args...); err != nil {
+		return newExecError("removing tracked order", sql, err, args...)
+	}
+	return nil
+}
+
+func (d *DBStore) ListTrackedOrders(ctx context.Context) ([]*order.TrackedOrder, error) {
+	sql, args, err := d.builder.Select("*").From("tracked_orders").OrderBy("started_at").ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("generating list SQL: %w", err)
+	}
+
+	tracked := []*order.TrackedOrder{}
+	if err = d.db.SelectContext(ctx, &tracked, sql, args...); err != nil {
+		return nil, newExecError("selecting tracked orders", sql, err, args...)
+	}
+	return tracked, nil
+}
+
+func (d *DBStore) SetLinkCursor(ctx context.Context, channel, messageID string, handledAt time.Time) error {
+	sql, args, err := d.builder.Insert("link_cursors").
+		Columns("channel", "message_id", "handled_at").
+		Values(channel, messageID, handledAt.UTC()).
+		Suffix(onConflictUpdate([]string{"channel"}, "message_id", "handled_at")).ToSql()
+	if err != nil {
+		return fmt.Errorf("generating insert SQL: %w", err)
+	}
+
+	if _, err = d.db.ExecContext(ctx, sql, args...); err != nil {
+		return newExecError("setting link cursor", sql, err, args...)
+	}
+	return nil
+}
+
+func (d *DBStore) ListLinkCursors(ctx context.Context) (map[string]string, error) {
+	sql, args, err := d.builder.Select("channel", "message_id").From("link_cursors").ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("generating list SQL: %w", err)
+	}
+
+	var rows []struct {
+		Channel   string `db:"channel"`
+		MessageID string `db:"message_id"`
+	}
+	if err = d.db.SelectContext(ctx, &rows, sql, args...); err != nil {
+		return nil, newExecError("selecting link cursors", sql, err, args...)
+	}
+	cursors := make(map[string]string, len(rows))
+	for _, row := range rows {
+		cursors[row.Channel] = row.MessageID
+	}
+	return cursors, nil
+}
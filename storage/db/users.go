@@ -15,7 +15,7 @@ type userModel struct {
 	CreatedAt time.Time `db:"created_at"`
 }
 
-func (d *DBStore) AddUser(_ context.Context, user *userDomain.User) error {
+func (d *DBStore) AddUser(ctx context.Context, user *userDomain.User) error {
 	if user == nil {
 		return fmt.Errorf("nil user")
 	}
@@ -24,27 +24,27 @@ func (d *DBStore) AddUser(_ context.Context, user *userDomain.User) error {
 	}
 	model := &userModel{User: user, CreatedAt: time.Now()}
 
-	sql, args, err := sq.Insert("users").Values(model.ID, model.FullName, model.Email, model.Phone,
-		model.Timezone, model.TransportID, model.CreatedAt).ToSql()
+	sql, args, err := d.builder.Insert("users").Values(model.ID, model.FullName, model.Email, model.Phone,
+		model.Timezone, model.TransportID, model.CreatedAt, model.DeactivatedAt).ToSql()
 	if err != nil {
 		return fmt.Errorf("generating insert SQL: %w", err)
 	}
 
-	if _, err = d.db.Exec(sql, args...); err != nil {
+	if _, err = d.db.ExecContext(ctx, sql, args...); err != nil {
 		return newExecError("adding user", sql, err, args...)
 	}
 
 	return nil
 }
 
-func (d *DBStore) GetUser(_ context.Context, id string) (*userDomain.User, error) {
-	sql, args, err := sq.Select("*").From("users").Where("id=?", id).ToSql()
+func (d *DBStore) GetUser(ctx context.Context, id string) (*userDomain.User, error) {
+	sql, args, err := d.builder.Select("*").From("users").Where("id=?", id).ToSql()
 	if err != nil {
 		return nil, fmt.Errorf("generating select SQL: %w", err)
 	}
 
 	var user []*userModel
-	err = d.db.Select(&user, sql, args...)
+	err = d.db.SelectContext(ctx, &user, sql, args...)
 	if err != nil {
 		return nil, newExecError("selecting user", sql, err, args...)
 	}
@@ -59,19 +59,10 @@ func (d *DBStore) GetUser(_ context.Context, id string) (*userDomain.User, error
 	return user[0].User, nil
 }
 
-func (d *DBStore) ListUsers(_ context.Context, filter userDomain.ListFilter) ([]*userDomain.User, error) {
-	baseSql := sq.Select("*").From("users")
-
-	sqFilter := sq.Or{}
-	if len(filter.Names) > 0 {
-		sqFilter = append(sqFilter, sq.Eq{"full_name": filter.Names})
-	}
-	if filter.TransportID != "" {
-		sqFilter = append(sqFilter, sq.Eq{"transport_id": filter.TransportID})
-	}
-
-	if len(sqFilter) > 0 {
-		baseSql = baseSql.Where(sqFilter)
+func (d *DBStore) ListUsers(ctx context.Context, filter userDomain.ListFilter) ([]*userDomain.User, error) {
+	baseSql := filterUsers(d.builder.Select("*").From("users"), filter)
+	if filter.Paginated() {
+		baseSql = withPagination(baseSql.OrderBy("full_name", "id"), filter.Limit, filter.Offset)
 	}
 
 	sql, args, err := baseSql.ToSql()
@@ -80,7 +71,7 @@ func (d *DBStore) ListUsers(_ context.Context, filter userDomain.ListFilter) ([]
 	}
 
 	var users []*userModel
-	err = d.db.Select(&users, sql, args...)
+	err = d.db.SelectContext(ctx, &users, sql, args...)
 	if err != nil {
 		return nil, newExecError("selecting users", sql, err, args...)
 	}
@@ -92,3 +83,52 @@ func (d *DBStore) ListUsers(_ context.Context, filter userDomain.ListFilter) ([]
 
 	return ret, nil
 }
+
+func (d *DBStore) CountUsers(ctx context.Context, filter userDomain.ListFilter) (int, error) {
+	sql, args, err := filterUsers(d.builder.Select("COUNT(*)").From("users"), filter).ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("generating count SQL: %w", err)
+	}
+
+	var count int
+	if err = d.db.GetContext(ctx, &count, sql, args...); err != nil {
+		return 0, newExecError("counting users", sql, err, args...)
+	}
+	return count, nil
+}
+
+// filterUsers adds the conditions of the filter to the query
+func filterUsers(query sq.SelectBuilder, filter userDomain.ListFilter) sq.SelectBuilder {
+	sqFilter := sq.Or{}
+	if len(filter.Names) > 0 {
+		sqFilter = append(sqFilter, sq.Eq{"full_name": filter.Names})
+	}
+	if filter.TransportID != "" {
+		sqFilter = append(sqFilter, sq.Eq{"transport_id": filter.TransportID})
+	}
+
+	if len(sqFilter) > 0 {
+		query = query.Where(sqFilter)
+	}
+	return query
+}
+
+func (d *DBStore) SetUserDeactivatedAt(ctx context.Context, id string, deactivatedAt *time.Time) error {
+	if deactivatedAt != nil {
+		utc := deactivatedAt.UTC()
+		deactivatedAt = &utc
+	}
+	sql, args, err := d.builder.Update("users").Set("deactivated_at", deactivatedAt).Where(sq.Eq{"id": id}).ToSql()
+	if err != nil {
+		return fmt.Errorf("generating update SQL: %w", err)
+	}
+
+	res, err := d.db.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return newExecError("setting user deactivation", sql, err, args...)
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
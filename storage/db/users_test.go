@@ -204,3 +204,154 @@ func TestAddUser(t *testing.T) {
 		})
 	}
 }
+
+func TestListUsersPaginated(t *testing.T) {
+	t.Parallel()
+
+	dbTest := NewDBTest(t)
+	t.Cleanup(func() {
+		dbTest.Cleanup(t)
+	})
+	ctx := context.Background()
+
+	thor, loki, freya := getDummyUser().User(), getDummyUser().User(), getDummyUser().User()
+	thor.FullName, loki.FullName, freya.FullName = "Thor", "Loki", "Freya"
+	for _, u := range []*userDomain.User{thor, loki, freya} {
+		require.NoError(t, dbTest.db.AddUser(ctx, u))
+	}
+
+	users, err := dbTest.db.ListUsers(ctx, userDomain.ListFilter{Limit: 2, Offset: 1})
+	require.NoError(t, err)
+	testExpectedUsers(t, []*userDomain.User{loki, thor}, users)
+	assert.Equal(t, loki.ID, users[0].ID, "pages are sorted by full name")
+
+	count, err := dbTest.db.CountUsers(ctx, userDomain.ListFilter{Names: []string{"Thor", "Loki"}, Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 2, count, "the count ignores the pagination")
+}
+
+func TestSetUserDeactivatedAt(t *testing.T) {
+	t.Parallel()
+
+	dbTest := NewDBTest(t)
+	t.Cleanup(func() {
+		dbTest.Cleanup(t)
+	})
+	ctx := context.Background()
+
+	user := getDummyUser().User()
+	require.NoError(t, dbTest.db.AddUser(ctx, user))
+
+	deactivatedAt := time.Now().Add(-time.Hour)
+	require.NoError(t, dbTest.db.SetUserDeactivatedAt(ctx, user.ID, &deactivatedAt))
+	got, err := dbTest.db.GetUser(ctx, user.ID)
+	require.NoError(t, err)
+	require.True(t, got.Deactivated())
+	assert.WithinDuration(t, deactivatedAt, *got.DeactivatedAt, time.Second)
+
+	require.NoError(t, dbTest.db.SetUserDeactivatedAt(ctx, user.ID, nil))
+	got, err = dbTest.db.GetUser(ctx, user.ID)
+	require.NoError(t, err)
+	assert.False(t, got.Deactivated())
+
+	assert.ErrorContains(t, dbTest.db.SetUserDeactivatedAt(ctx, "no-such-user", &deactivatedAt), "user not found")
+}
+
+func TestPaymentMethods(t *testing.T) {
+	t.Parallel()
+
+	dbTest := NewDBTest(t)
+	t.Cleanup(func() {
+		dbTest.Cleanup(t)
+	})
+	ctx := context.Background()
+
+	methods, err := dbTest.db.PaymentMethods(ctx, "U1")
+	require.NoError(t, err)
+	assert.Empty(t, methods)
+
+	require.NoError(t, dbTest.db.SetPaymentMethods(ctx, "U1", []userDomain.PaymentMethod{userDomain.PaymentMethodBit}))
+	require.NoError(t, dbTest.db.SetPaymentMethods(ctx, "U1", []userDomain.PaymentMethod{userDomain.PaymentMethodPepper, userDomain.PaymentMethodBit}))
+	methods, err = dbTest.db.PaymentMethods(ctx, "U1")
+	require.NoError(t, err)
+	assert.Equal(t, []userDomain.PaymentMethod{userDomain.PaymentMethodPepper, userDomain.PaymentMethodBit}, methods)
+
+	require.NoError(t, dbTest.db.SetPaymentMethods(ctx, "U1", nil))
+	methods, err = dbTest.db.PaymentMethods(ctx, "U1")
+	require.NoError(t, err)
+	assert.Empty(t, methods)
+}
+
+func TestDebtDMs(t *testing.T) {
+	t.Parallel()
+
+	dbTest := NewDBTest(t)
+	t.Cleanup(func() {
+		dbTest.Cleanup(t)
+	})
+	ctx := context.Background()
+
+	_, chosen, err := dbTest.db.DebtDMs(ctx, "U1")
+	require.NoError(t, err)
+	assert.False(t, chosen)
+
+	require.NoError(t, dbTest.db.SetDebtDMs(ctx, "U1", true))
+	require.NoError(t, dbTest.db.SetDebtDMs(ctx, "U2", true))
+	require.NoError(t, dbTest.db.SetDebtDMs(ctx, "U1", false))
+	enabled, chosen, err := dbTest.db.DebtDMs(ctx, "U1")
+	require.NoError(t, err)
+	assert.True(t, chosen)
+	assert.False(t, enabled, "choosing again replaces the previous choice")
+	enabled, _, err = dbTest.db.DebtDMs(ctx, "U2")
+	require.NoError(t, err)
+	assert.True(t, enabled)
+}
+
+func TestPrivateAmounts(t *testing.T) {
+	t.Parallel()
+
+	dbTest := NewDBTest(t)
+	t.Cleanup(func() {
+		dbTest.Cleanup(t)
+	})
+	ctx := context.Background()
+
+	private, err := dbTest.db.PrivateAmounts(ctx, "U1")
+	require.NoError(t, err)
+	assert.False(t, private)
+
+	require.NoError(t, dbTest.db.SetPrivateAmounts(ctx, "U1", true))
+	require.NoError(t, dbTest.db.SetPrivateAmounts(ctx, "U2", true))
+	require.NoError(t, dbTest.db.SetPrivateAmounts(ctx, "U2", false))
+	private, err = dbTest.db.PrivateAmounts(ctx, "U1")
+	require.NoError(t, err)
+	assert.True(t, private)
+	private, err = dbTest.db.PrivateAmounts(ctx, "U2")
+	require.NoError(t, err)
+	assert.False(t, private, "choosing again replaces the previous choice")
+}
+
+func TestBankAccount(t *testing.T) {
+	t.Parallel()
+
+	dbTest := NewDBTest(t)
+	t.Cleanup(func() {
+		dbTest.Cleanup(t)
+	})
+	ctx := context.Background()
+
+	account, err := dbTest.db.BankAccount(ctx, "U1")
+	require.NoError(t, err)
+	assert.Nil(t, account)
+
+	require.NoError(t, dbTest.db.SetBankAccount(ctx, "U1", &userDomain.BankAccount{Holder: "Dana", IBAN: "GB82WEST12345698765432"}))
+	require.NoError(t, dbTest.db.SetBankAccount(ctx, "U1", &userDomain.BankAccount{Holder: "Dana Levi", IBAN: "IL620108000000099999999"}))
+	account, err = dbTest.db.BankAccount(ctx, "U1")
+	require.NoError(t, err)
+	assert.Equal(t, &userDomain.BankAccount{Holder: "Dana Levi", IBAN: "IL620108000000099999999"}, account, "setting again replaces the account")
+
+	require.NoError(t, dbTest.db.SetBankAccount(ctx, "U1", nil))
+	account, err = dbTest.db.BankAccount(ctx, "U1")
+	require.NoError(t, err)
+	assert.Nil(t, account)
+}
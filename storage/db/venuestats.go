@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/oriser/bolt/order"
+)
+
+func (d *DBStore) saveVenueStats(ctx context.Context, stats *order.VenueStats) error {
+	query, args, err := d.builder.Insert("venue_stats").
+		Values(stats.VenueName, stats.Deliveries, int64(stats.AverageDelivery), int64(stats.LongestDelivery), int64(stats.DoneTimeout),
+			int64(stats.StatusCheckInterval), stats.UpdatedAt.UTC()).
+		Suffix(onConflictUpdate([]string{"venue_name"}, "deliveries", "average_delivery", "longest_delivery", "done_timeout",
+			"status_check_interval", "updated_at")).ToSql()
+	if err != nil {
+		return fmt.Errorf("generating insert SQL: %w", err)
+	}
+
+	if _, err = d.db.ExecContext(ctx, query, args...); err != nil {
+		return newExecError("saving venue stats", query, err, args...)
+	}
+	return nil
+}
+
+func (d *DBStore) AddVenueDelivery(ctx context.Context, venueName string, duration time.Duration) error {
+	stats, err := d.GetVenueStats(ctx, venueName)
+	if err != nil {
+		return err
+	}
+	if stats == nil {
+		stats = &order.VenueStats{VenueName: venueName}
+	}
+	stats.AverageDelivery = (stats.AverageDelivery*time.Duration(stats.Deliveries) + duration) / time.Duration(stats.Deliveries+1)
+	stats.Deliveries++
+	if duration > stats.LongestDelivery {
+		stats.LongestDelivery = duration
+	}
+	stats.UpdatedAt = time.Now()
+	return d.saveVenueStats(ctx, stats)
+}
+
+func (d *DBStore) SetVenueTimeouts(ctx context.Context, venueName string, doneTimeout, statusCheckInterval time.Duration) error {
+	stats, err := d.GetVenueStats(ctx, venueName)
+	if err != nil {
+		return err
+	}
+	if stats == nil {
+		stats = &order.VenueStats{VenueName: venueName}
+	}
+	stats.DoneTimeout, stats.StatusCheckInterval = doneTimeout, statusCheckInterval
+	stats.UpdatedAt = time.Now()
+	return d.saveVenueStats(ctx, stats)
+}
+
+func (d *DBStore) GetVenueStats(ctx context.Context, venueName string) (*order.VenueStats, error) {
+	// The venue name column is case-insensitive
+	query, args, err := d.builder.Select("*").From("venue_stats").Where(sq.Eq{"venue_name": venueName}).ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("generating select SQL: %w", err)
+	}
+
+	stats := &order.VenueStats{}
+	if err = d.db.GetContext(ctx, stats, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, newExecError("selecting venue stats", query, err, args...)
+	}
+	return stats, nil
+}
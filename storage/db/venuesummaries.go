@@ -0,0 +1,65 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/oriser/bolt/order"
+)
+
+type venueSummaryModel struct {
+	VenueName               string          `db:"venue_name"`
+	Orders                  int             `db:"orders"`
+	AverageDeliveryRate     float64         `db:"average_delivery_rate"`
+	AverageDeliveryDuration sql.NullFloat64 `db:"average_delivery_duration"`
+}
+
+func (d *DBStore) SetDeliveryDuration(ctx context.Context, originalID string, duration time.Duration) error {
+	query, args, err := d.builder.Update("orders").Set("delivery_duration", int64(duration)).Where(sq.Eq{"original_id": originalID}).ToSql()
+	if err != nil {
+		return fmt.Errorf("generating update SQL: %w", err)
+	}
+
+	if _, err = d.db.ExecContext(ctx, query, args...); err != nil {
+		return newExecError("setting delivery duration", query, err, args...)
+	}
+	return nil
+}
+
+func (d *DBStore) ListVenueSummaries(ctx context.Context, filter order.VenueSummaryFilter) ([]order.VenueSummary, error) {
+	query := d.builder.Select("venue_name", "COUNT(*) AS orders", "AVG(delivery_rate) AS average_delivery_rate",
+		"AVG(CASE WHEN delivery_duration > 0 THEN delivery_duration END) AS average_delivery_duration").
+		From("orders").
+		Where(sq.Eq{"status": order.StatusDone}).
+		Where(sq.NotEq{"venue_name": ""})
+	if filter.Receiver != "" {
+		query = query.Where(sq.Eq{"receiver": filter.Receiver})
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where(sq.GtOrEq{"created_at": filter.Since})
+	}
+	query = withPagination(query.GroupBy("venue_name").OrderBy("orders DESC", "venue_name"), filter.Limit, 0)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("generating select SQL: %w", err)
+	}
+	var models []venueSummaryModel
+	if err = d.db.SelectContext(ctx, &models, sql, args...); err != nil {
+		return nil, newExecError("selecting venue summaries", sql, err, args...)
+	}
+
+	summaries := make([]order.VenueSummary, len(models))
+	for i, model := range models {
+		summaries[i] = order.VenueSummary{
+			VenueName:               model.VenueName,
+			Orders:                  model.Orders,
+			AverageDeliveryRate:     model.AverageDeliveryRate,
+			AverageDeliveryDuration: time.Duration(model.AverageDeliveryDuration.Float64),
+		}
+	}
+	return summaries, nil
+}
@@ -19,7 +19,7 @@ const (
 )
 
 type Config struct {
-	OauthToken        string        `env:"SLACK_OAUTH_TOKEN,required" json:"-"`
+	OauthToken        string        `env:"SLACK_OAUTH_TOKEN" json:"-"`
 	MaxCacheEntryTime time.Duration `env:"SLACK_STORE_MAX_CACHE_ENTRY_TIME" envDefault:"144h"` // 6 days
 	SlackAPIUrl       string        `env:"SLACK_API_URL"`                                      // only for testing
 }
@@ -222,7 +222,7 @@ func (s *SlackStorage) ListUsers(ctx context.Context, filter userDomain.ListFilt
 
 	if filter.TransportID != "" && !filterByNames {
 		// If we asked to filter just by TransportID and the names filter is empty, returning here to avoid listing all users
-		return ret, nil
+		return filter.Page(ret), nil
 	}
 
 	usersToFilter := make([]string, 0, len(filter.Names))
@@ -238,7 +238,7 @@ func (s *SlackStorage) ListUsers(ctx context.Context, filter userDomain.ListFilt
 
 	if len(usersToFilter) == 0 && filterByNames {
 		// Everything in cache
-		return ret, nil
+		return filter.Page(ret), nil
 	}
 
 	var err error
@@ -279,11 +279,11 @@ func (s *SlackStorage) ListUsers(ctx context.Context, filter userDomain.ListFilt
 		ret = append(ret, user)
 	}
 
-	return ret, nil
+	return filter.Page(ret), nil
 }
 
-func (s *SlackStorage) GetUser(_ context.Context, id string) (*userDomain.User, error) {
-	user, err := s.client.GetUserInfo(id)
+func (s *SlackStorage) GetUser(ctx context.Context, id string) (*userDomain.User, error) {
+	user, err := s.client.GetUserInfoContext(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("get user info: %w", err)
 	}
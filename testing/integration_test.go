@@ -50,6 +50,8 @@ const (
 	MinHttpRetryWait       = time.Millisecond
 	MaxHttpRetryWait       = 5 * time.Millisecond
 
+	NotificationMessageInterval = 10 * time.Millisecond
+
 	DefaultNonBotUserID     = "W012A3CDE" // From slack test package, it's not exposed, and it's constant
 	MessageChannel          = "some-channel"
 	DefaultExpectedDelivery = 10
@@ -104,6 +106,9 @@ func initEnvs(t *testing.T, tdata testData) {
 	require.NoError(t, os.Setenv("WOLT_HTTP_MIN_RETRY_DURATION", MinHttpRetryWait.String()))
 	require.NoError(t, os.Setenv("WOLT_HTTP_MAX_RETRY_DURATION", MaxHttpRetryWait.String()))
 
+	// Notification
+	require.NoError(t, os.Setenv("NOTIFICATION_MESSAGE_INTERVAL", NotificationMessageInterval.String()))
+
 	// main
 	require.NoError(t, os.Setenv("DB_LOCATION", path.Join(tmpDir, "db.sqlite")))
 }
@@ -372,6 +377,7 @@ func validateDebts(t *testing.T,
 	t.Log("Waiting for a debt cycle")
 	time.Sleep(DebtReminderInterval)
 	willRemainDebts := make([]string, 0)
+	paid := make(map[string]bool)
 
 	for participant, slackUser := range slackUsers {
 		if slackUser.Deleted {
@@ -405,6 +411,26 @@ func validateDebts(t *testing.T,
 			fmt.Sprintf("<@%s> marked himself as paid for order ID %s", participantIDsMapping[participant], orderID),
 			participantIDsMapping[host], "", EqualMatch)
 		require.NoError(t, err)
+		paid[participant] = true
+	}
+
+	// Once the last debt was paid, everyone is thanked and the host gets a receipt
+	allPaid := len(paid) > 0
+	for participant := range participantIDsMapping {
+		if participant != host && !paid[participant] {
+			allPaid = false
+		}
+	}
+	if allPaid {
+		_, err := WaitForOutboundSlackMessage(WaitForMessageTimeout, tdata.slackServer,
+			"Everyone has paid for this order, thank you all! :tada:",
+			MessageChannel, timestamp, EqualMatch)
+		require.NoError(t, err)
+
+		_, err = WaitForOutboundSlackMessage(WaitForMessageTimeout, tdata.slackServer,
+			fmt.Sprintf("Everyone has paid you for the order from A Tasty Venue (Wolt order ID %s) :tada: Here's the receipt:\n", orderID),
+			participantIDsMapping[host], "", ContainsMatch)
+		require.NoError(t, err)
 	}
 
 	// To make sure debts won't be sent to paid users anymore
@@ -0,0 +1,228 @@
+// Package loadtest simulates a lunch rush: many group orders shared at the same time, replayed through the service against the fake
+// Wolt server and a transport which drops the messages. It reports the throughput, the requests to Wolt and the latencies of the store,
+// for tuning MAX_TRACKED_ORDERS, MAX_QUEUED_ORDERS and WOLT_RATE_LIMIT.
+package loadtest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/oriser/bolt/cmd/run"
+	"github.com/oriser/bolt/service"
+	"github.com/oriser/bolt/testing/woltserver"
+	userDomain "github.com/oriser/bolt/user"
+)
+
+// botID is the transport ID of Bolt in the simulated channels
+const botID = "UBOT"
+
+var (
+	orderLocation = woltserver.Coordinate{Lat: 32.0707244997673, Lon: 34.78343904018402}
+	venueLocation = woltserver.Coordinate{Lat: 32.072447331148844, Lon: 34.77900266647339}
+)
+
+type Config struct {
+	Orders        int           // The orders shared at once
+	Participants  int           // The participants of each order, besides its host
+	Channels      int           // The orders are shared round robin in this many channels
+	PurchaseAfter time.Duration // How long after an order is shared its host purchases it
+	ErrorRate     int           // One in how many requests to Wolt fails with a 502 error, 0 disables the errors
+	DBLocation    string        // The store of the simulation, a temporary SQLite file if empty
+	Service       service.Config
+}
+
+// DefaultConfig returns a rush of 50 orders whose deliveries are followed for a short while, so the tracking slots are released quickly.
+// The Wolt addresses of the service are set by Run.
+func DefaultConfig() Config {
+	cfg := service.DefaultConfig()
+	cfg.TimeoutForReady = time.Minute
+	cfg.OrderDoneTimeout = 2 * time.Second
+	cfg.WaitBetweenStatusCheck = 200 * time.Millisecond
+	cfg.WoltHTTPMinRetryDuration = 10 * time.Millisecond
+	cfg.WoltHTTPMaxRetryDuration = 100 * time.Millisecond
+	return Config{
+		Orders:        50,
+		Participants:  5,
+		Channels:      5,
+		PurchaseAfter: 2 * time.Second,
+		Service:       cfg,
+	}
+}
+
+func (c Config) validate() error {
+	if c.Orders <= 0 {
+		return fmt.Errorf("the orders must be positive but got %d", c.Orders)
+	}
+	if c.Participants < 0 {
+		return fmt.Errorf("the participants must not be negative but got %d", c.Participants)
+	}
+	if c.Channels <= 0 {
+		return fmt.Errorf("the channels must be positive but got %d", c.Channels)
+	}
+	if c.ErrorRate < 0 {
+		return fmt.Errorf("the error rate must not be negative but got %d", c.ErrorRate)
+	}
+	return nil
+}
+
+// rushOrder is an order of the simulation, shared as a link message
+type rushOrder struct {
+	woltID    string
+	shortID   string
+	channel   string
+	messageID string
+	sharedAt  time.Time
+}
+
+// Run shares the orders at once and waits until the service is done with all of them, or until ctx is done
+func Run(ctx context.Context, cfg Config) (*Report, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	woltServer := woltserver.NewWoltServer(discardLogger{})
+	woltServer.SetErrorRate(cfg.ErrorRate)
+	woltServer.Start()
+	defer woltServer.Stop()
+
+	dbLocation := cfg.DBLocation
+	if dbLocation == "" {
+		tmpDir, err := os.MkdirTemp("", "bolt-loadtest")
+		if err != nil {
+			return nil, fmt.Errorf("create temp dir: %w", err)
+		}
+		defer os.RemoveAll(tmpDir)
+		dbLocation = path.Join(tmpDir, "db.sqlite")
+	}
+	dbStore, err := run.OpenDBStore(dbLocation)
+	if err != nil {
+		return nil, err
+	}
+	store := newTimedStore(dbStore)
+
+	orders, err := prepareOrders(ctx, cfg, woltServer, store)
+	if err != nil {
+		return nil, err
+	}
+	// The store latencies are of the rush, not of preparing it
+	store.latencies.reset()
+
+	serviceCfg := cfg.Service
+	serviceCfg.WoltBaseAddr = "http://" + woltServer.Addr()
+	serviceCfg.WoltApiBaseAddr = "http://" + woltServer.Addr()
+	notification := &noopNotification{}
+	svc, err := service.New(serviceCfg, store, store, store, botID, notification)
+	if err != nil {
+		return nil, fmt.Errorf("new service: %w", err)
+	}
+
+	progress := newRushProgress()
+	svc.Hooks().Subscribe(progress.onEvent, service.EventOrderJoined, service.EventRatesPublished)
+	stopSampling := progress.sampleTracked(svc)
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for _, order := range orders {
+		order := order
+		order.sharedAt = time.Now()
+		progress.shared(order)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := svc.HandleLinkMessage(service.LinksRequest{
+				Links:     []service.Link{{URL: "https://wolt.com/group/" + order.shortID}},
+				Channel:   order.channel,
+				MessageID: order.messageID,
+			})
+			progress.finished(err)
+		}()
+		go func() {
+			defer wg.Done()
+			select {
+			case <-time.After(cfg.PurchaseAfter):
+			case <-ctx.Done():
+				return
+			}
+			_ = woltServer.UpdateOrderStatus(order.woltID, woltserver.StatusPurchased)
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+	elapsed := time.Since(start)
+	stopSampling()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := svc.Shutdown(shutdownCtx); err != nil {
+		return nil, fmt.Errorf("shut down service: %w", err)
+	}
+
+	report := progress.report(cfg.Orders, elapsed)
+	report.WoltRequests = woltServer.Requests()
+	report.WoltErrors = woltServer.Errors()
+	report.Store = store.latencies.summaries()
+	report.Messages, report.Edits, report.Reactions = notification.counts()
+	return report, ctx.Err()
+}
+
+// prepareOrders creates the orders in the fake Wolt server, with users for their hosts and participants so debts are created for them
+func prepareOrders(ctx context.Context, cfg Config, woltServer *woltserver.WoltServer, store *timedStore) ([]*rushOrder, error) {
+	users := make(map[string]bool)
+	addUser := func(name string) error {
+		if users[name] {
+			return nil
+		}
+		users[name] = true
+		return store.AddUser(ctx, &userDomain.User{FullName: name, TransportID: fmt.Sprintf("U%d", len(users))})
+	}
+
+	// The links are shared a bit before the rush, so their message IDs don't collide with those of Bolt's messages
+	sharedAt := time.Now().Add(-time.Minute).Unix()
+	orders := make([]*rushOrder, 0, cfg.Orders)
+	for i := 0; i < cfg.Orders; i++ {
+		host := fmt.Sprintf("Host %d", i+1)
+		if err := addUser(host); err != nil {
+			return nil, fmt.Errorf("add user: %w", err)
+		}
+		venueID := woltServer.CreateVenue(orderLocation)
+		shortID, woltID := woltServer.CreateOrder(host, venueID, venueLocation)
+		for p := 0; p < cfg.Participants; p++ {
+			// The participants are shared between the orders, like colleagues ordering in turns
+			name := fmt.Sprintf("Diner %d", (i+p)%(cfg.Participants*2)+1)
+			if err := addUser(name); err != nil {
+				return nil, fmt.Errorf("add user: %w", err)
+			}
+			participantID, err := woltServer.AddParticipant(woltID, name)
+			if err != nil {
+				return nil, err
+			}
+			if err = woltServer.AddParticipantItem(woltID, participantID, 4000+100*p); err != nil {
+				return nil, err
+			}
+		}
+		orders = append(orders, &rushOrder{
+			woltID:    woltID,
+			shortID:   shortID,
+			channel:   fmt.Sprintf("C%d", i%cfg.Channels+1),
+			messageID: fmt.Sprintf("%d.%06d", sharedAt, i),
+		})
+	}
+	return orders, nil
+}
+
+// discardLogger drops the logs of the fake Wolt server, which counts its errors anyway
+type discardLogger struct{}
+
+func (discardLogger) Log(...interface{}) {}
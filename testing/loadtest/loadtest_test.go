@@ -0,0 +1,54 @@
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunCapsTrackedOrders(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Orders = 3
+	cfg.Participants = 2
+	cfg.PurchaseAfter = 100 * time.Millisecond
+	cfg.Service.OrderDoneTimeout = 300 * time.Millisecond
+	cfg.Service.WaitBetweenStatusCheck = 50 * time.Millisecond
+	cfg.Service.MaxTrackedOrders = 1
+	cfg.Service.MaxQueuedOrders = 1
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	report, err := Run(ctx, cfg)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, report.Completed, "one order is tracked and one waits in line")
+	require.Len(t, report.Errors, 1)
+	for text, count := range report.Errors {
+		assert.Contains(t, text, "too many orders are waiting to be tracked")
+		assert.Equal(t, 1, count)
+	}
+	assert.Equal(t, 1, report.PeakTracked)
+	assert.Equal(t, 2, report.RatesLatency.Count)
+	assert.Equal(t, 4, report.Store["AddDebt"].Count, "the participants of the two orders owe their hosts")
+	assert.NotEmpty(t, report.WoltRequests)
+
+	var out bytes.Buffer
+	require.NoError(t, report.Write(&out))
+	assert.Contains(t, out.String(), "2 completed of 3")
+}
+
+func TestSummarize(t *testing.T) {
+	t.Parallel()
+
+	var durations []time.Duration
+	for i := 100; i > 0; i-- {
+		durations = append(durations, time.Duration(i)*time.Millisecond)
+	}
+	assert.Equal(t, Latencies{Count: 100, P50: 50 * time.Millisecond, P95: 95 * time.Millisecond, Max: 100 * time.Millisecond},
+		summarize(durations))
+	assert.Equal(t, Latencies{}, summarize(nil))
+}
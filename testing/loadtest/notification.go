@@ -0,0 +1,42 @@
+package loadtest
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// noopNotification is a transport which drops the messages, counting them
+type noopNotification struct {
+	lock      sync.Mutex
+	messages  int
+	edits     int
+	reactions int
+}
+
+func (n *noopNotification) SendMessage(_, _, _ string) (string, error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.messages++
+	return fmt.Sprintf("%d.%06d", time.Now().Unix(), n.messages), nil
+}
+
+func (n *noopNotification) EditMessage(_, _, _ string) error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.edits++
+	return nil
+}
+
+func (n *noopNotification) AddReaction(_, _, _ string) error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.reactions++
+	return nil
+}
+
+func (n *noopNotification) counts() (messages, edits, reactions int) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	return n.messages, n.edits, n.reactions
+}
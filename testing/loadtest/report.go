@@ -0,0 +1,172 @@
+package loadtest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/oriser/bolt/service"
+)
+
+// Report is the outcome of a simulated rush
+type Report struct {
+	Orders       int
+	Completed    int            // The orders the service was done with without an error
+	Errors       map[string]int // The errors of the other orders, like a full line of MAX_QUEUED_ORDERS, by their text
+	Elapsed      time.Duration
+	PeakTracked  int       // The most orders tracked at the same time, which MAX_TRACKED_ORDERS caps
+	JoinLatency  Latencies // From sharing an order to joining it, which includes waiting in line for a tracking slot
+	RatesLatency Latencies // From sharing an order to publishing its rates
+	WoltRequests map[string]int
+	WoltErrors   int
+	Store        map[string]Latencies
+	Messages     int
+	Edits        int
+	Reactions    int
+}
+
+// Throughput returns the orders completed a second
+func (r *Report) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Completed) / r.Elapsed.Seconds()
+}
+
+// Write writes the report in aligned columns
+func (r *Report) Write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "Orders\t%d completed of %d in %s (%.2f orders/s)\n", r.Completed, r.Orders, r.Elapsed.Round(time.Millisecond), r.Throughput())
+	for _, text := range sortedKeys(r.Errors) {
+		_, _ = fmt.Fprintf(tw, "Failed\t%d: %s\n", r.Errors[text], text)
+	}
+	_, _ = fmt.Fprintf(tw, "Peak tracked orders\t%d\n", r.PeakTracked)
+	_, _ = fmt.Fprintf(tw, "Messages\t%d sent, %d edited, %d reactions\n", r.Messages, r.Edits, r.Reactions)
+
+	_, _ = fmt.Fprintln(tw, "\nLatency\tcount\tp50\tp95\tmax")
+	writeLatencies(tw, "Join", r.JoinLatency)
+	writeLatencies(tw, "Rates published", r.RatesLatency)
+	operations := make([]string, 0, len(r.Store))
+	for operation := range r.Store {
+		operations = append(operations, operation)
+	}
+	sort.Strings(operations)
+	for _, operation := range operations {
+		writeLatencies(tw, "Store "+operation, r.Store[operation])
+	}
+
+	total := 0
+	_, _ = fmt.Fprintln(tw, "\nWolt endpoint\trequests")
+	for _, pattern := range sortedKeys(r.WoltRequests) {
+		total += r.WoltRequests[pattern]
+		_, _ = fmt.Fprintf(tw, "%s\t%d\n", pattern, r.WoltRequests[pattern])
+	}
+	_, _ = fmt.Fprintf(tw, "Total\t%d (%d failed on purpose)\n", total, r.WoltErrors)
+	return tw.Flush()
+}
+
+func writeLatencies(w io.Writer, name string, latencies Latencies) {
+	_, _ = fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n", name, latencies.Count, latencies.P50.Round(time.Microsecond),
+		latencies.P95.Round(time.Microsecond), latencies.Max.Round(time.Microsecond))
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// rushProgress follows the orders of the rush through the lifecycle events of the service
+type rushProgress struct {
+	lock        sync.Mutex
+	sharedAt    map[string]time.Time // Message ID to when the order was shared
+	joined      []time.Duration
+	published   []time.Duration
+	completed   int
+	errors      map[string]int
+	peakTracked int
+}
+
+func newRushProgress() *rushProgress {
+	return &rushProgress{sharedAt: make(map[string]time.Time), errors: make(map[string]int)}
+}
+
+func (p *rushProgress) shared(order *rushOrder) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.sharedAt[order.messageID] = order.sharedAt
+}
+
+func (p *rushProgress) finished(err error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if err != nil {
+		p.errors[err.Error()]++
+		return
+	}
+	p.completed++
+}
+
+func (p *rushProgress) onEvent(_ context.Context, event service.Event) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	sharedAt, ok := p.sharedAt[event.MessageID]
+	if !ok {
+		return
+	}
+	switch event.Type {
+	case service.EventOrderJoined:
+		p.joined = append(p.joined, time.Since(sharedAt))
+	case service.EventRatesPublished:
+		p.published = append(p.published, time.Since(sharedAt))
+	}
+}
+
+// sampleTracked samples the orders the service tracks until the returned function is called
+func (p *rushProgress) sampleTracked(svc *service.Service) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(20 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			tracked := svc.TrackedOrders().Total
+			p.lock.Lock()
+			if tracked > p.peakTracked {
+				p.peakTracked = tracked
+			}
+			p.lock.Unlock()
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func (p *rushProgress) report(orders int, elapsed time.Duration) *Report {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return &Report{
+		Orders:       orders,
+		Completed:    p.completed,
+		Errors:       p.errors,
+		Elapsed:      elapsed,
+		PeakTracked:  p.peakTracked,
+		JoinLatency:  summarize(p.joined),
+		RatesLatency: summarize(p.published),
+	}
+}
@@ -0,0 +1,121 @@
+package loadtest
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	debtDomain "github.com/oriser/bolt/debt"
+	"github.com/oriser/bolt/order"
+	"github.com/oriser/bolt/storage/db"
+	userDomain "github.com/oriser/bolt/user"
+)
+
+// Latencies summarizes the durations of an operation
+type Latencies struct {
+	Count int
+	P50   time.Duration
+	P95   time.Duration
+	Max   time.Duration
+}
+
+func summarize(durations []time.Duration) Latencies {
+	if len(durations) == 0 {
+		return Latencies{}
+	}
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	percentile := func(p float64) time.Duration {
+		return sorted[int(p*float64(len(sorted)-1))]
+	}
+	return Latencies{Count: len(sorted), P50: percentile(0.5), P95: percentile(0.95), Max: sorted[len(sorted)-1]}
+}
+
+// latencyRecorder records the durations of the operations by their names
+type latencyRecorder struct {
+	lock      sync.Mutex
+	durations map[string][]time.Duration
+}
+
+func newLatencyRecorder() *latencyRecorder {
+	return &latencyRecorder{durations: make(map[string][]time.Duration)}
+}
+
+func (r *latencyRecorder) observeSince(operation string, start time.Time) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.durations[operation] = append(r.durations[operation], time.Since(start))
+}
+
+func (r *latencyRecorder) reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.durations = make(map[string][]time.Duration)
+}
+
+func (r *latencyRecorder) summaries() map[string]Latencies {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	summaries := make(map[string]Latencies, len(r.durations))
+	for operation, durations := range r.durations {
+		summaries[operation] = summarize(durations)
+	}
+	return summaries
+}
+
+// timedStore times the store calls of tracking an order. The DB store is embedded, so the optional stores it implements are still
+// detected by the service.
+type timedStore struct {
+	*db.DBStore
+	latencies *latencyRecorder
+}
+
+func newTimedStore(store *db.DBStore) *timedStore {
+	return &timedStore{DBStore: store, latencies: newLatencyRecorder()}
+}
+
+func (s *timedStore) GetUser(ctx context.Context, id string) (*userDomain.User, error) {
+	defer s.latencies.observeSince("GetUser", time.Now())
+	return s.DBStore.GetUser(ctx, id)
+}
+
+func (s *timedStore) ListUsers(ctx context.Context, filter userDomain.ListFilter) ([]*userDomain.User, error) {
+	defer s.latencies.observeSince("ListUsers", time.Now())
+	return s.DBStore.ListUsers(ctx, filter)
+}
+
+func (s *timedStore) AddDebt(ctx context.Context, debt *debtDomain.Debt) error {
+	defer s.latencies.observeSince("AddDebt", time.Now())
+	return s.DBStore.AddDebt(ctx, debt)
+}
+
+func (s *timedStore) ListDebtsForOrderID(ctx context.Context, orderID string) ([]*debtDomain.Debt, error) {
+	defer s.latencies.observeSince("ListDebtsForOrderID", time.Now())
+	return s.DBStore.ListDebtsForOrderID(ctx, orderID)
+}
+
+func (s *timedStore) ListDebts(ctx context.Context, filter debtDomain.ListFilter) ([]*debtDomain.Debt, error) {
+	defer s.latencies.observeSince("ListDebts", time.Now())
+	return s.DBStore.ListDebts(ctx, filter)
+}
+
+func (s *timedStore) SaveOrder(ctx context.Context, order *order.Order) error {
+	defer s.latencies.observeSince("SaveOrder", time.Now())
+	return s.DBStore.SaveOrder(ctx, order)
+}
+
+func (s *timedStore) ListOrders(ctx context.Context, filter order.ListFilter) ([]*order.Order, error) {
+	defer s.latencies.observeSince("ListOrders", time.Now())
+	return s.DBStore.ListOrders(ctx, filter)
+}
+
+func (s *timedStore) SaveTrackedOrder(ctx context.Context, tracked *order.TrackedOrder) error {
+	defer s.latencies.observeSince("SaveTrackedOrder", time.Now())
+	return s.DBStore.SaveTrackedOrder(ctx, tracked)
+}
+
+func (s *timedStore) RemoveTrackedOrder(ctx context.Context, groupID string) error {
+	defer s.latencies.observeSince("RemoveTrackedOrder", time.Now())
+	return s.DBStore.RemoveTrackedOrder(ctx, groupID)
+}
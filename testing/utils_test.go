@@ -15,6 +15,10 @@ import (
 
 var seenMessages sync.Map
 
+// orderLabelRe matches the label Bolt prefixes the messages of an order with when the channel has several orders going on, which
+// the tests ignore as the thread of the message already tells its order
+var orderLabelRe = regexp.MustCompile(`^\[(?:[^\]]* · )?[A-Z0-9]+\] `)
+
 type MessageMatchedFunc func(text string, searchFor string) (bool, error)
 
 func RegexMatch(text string, searchFor string) (bool, error) {
@@ -55,6 +59,7 @@ func WaitForOutboundSlackMessage(timeout time.Duration, slackServer *slacktest.S
 					continue
 				}
 
+				m.Text = orderLabelRe.ReplaceAllString(m.Text, "")
 				match, err := matchFunc(m.Text, searchFor)
 				if err != nil {
 					return nil, fmt.Errorf("match func: %w", err)
@@ -6,7 +6,6 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"sync"
-	"testing"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -16,6 +15,11 @@ var ErrNoSuchOrder = fmt.Errorf("no such order. Make sure you created an order f
 var ErrNoSuchParticipant = fmt.Errorf("no such participant. Make sure you created a participant in that order fist")
 var ErrNoSuchVenue = fmt.Errorf("no such venue. Make sure you created a venue fist")
 
+// Logger logs the errors the server returns on purpose, like *testing.T
+type Logger interface {
+	Log(args ...interface{})
+}
+
 type WoltServer struct {
 	router       *mux.Router
 	server       *httptest.Server
@@ -23,10 +27,14 @@ type WoltServer struct {
 	orders       map[string]*Order // ID to order
 	shortIDOrder map[string]string // Order short ID to ID
 	venues       map[string]*Venue
-	t            *testing.T
+	logger       Logger
+	errorRate    int // One in how many requests fails with a 502, 0 disables the errors
+	requestsLock sync.Mutex
+	requests     map[string]int // Endpoint pattern to the requests it got
+	errors       int
 }
 
-func NewWoltServer(t *testing.T) *WoltServer {
+func NewWoltServer(logger Logger) *WoltServer {
 	rand.Seed(time.Now().UnixNano()) // For random 50x http errors
 
 	router := mux.NewRouter()
@@ -38,7 +46,9 @@ func NewWoltServer(t *testing.T) *WoltServer {
 		orders:       make(map[string]*Order),
 		shortIDOrder: make(map[string]string),
 		venues:       make(map[string]*Venue),
-		t:            t,
+		logger:       logger,
+		errorRate:    7,
+		requests:     make(map[string]int),
 	}
 	ws.registerDefaults()
 	return ws
@@ -69,11 +79,42 @@ func (ws *WoltServer) Stop() {
 	ws.server.Close()
 }
 
+// SetErrorRate sets one in how many requests fails with a 502 error, 0 disables the errors. It must be called before Start.
+func (ws *WoltServer) SetErrorRate(oneIn int) {
+	ws.errorRate = oneIn
+}
+
+// Requests returns how many requests each endpoint pattern got, including the ones which failed on purpose
+func (ws *WoltServer) Requests() map[string]int {
+	ws.requestsLock.Lock()
+	defer ws.requestsLock.Unlock()
+	requests := make(map[string]int, len(ws.requests))
+	for pattern, count := range ws.requests {
+		requests[pattern] = count
+	}
+	return requests
+}
+
+// Errors returns how many requests failed on purpose
+func (ws *WoltServer) Errors() int {
+	ws.requestsLock.Lock()
+	defer ws.requestsLock.Unlock()
+	return ws.errors
+}
+
 func (ws *WoltServer) RegisterEndpoint(pattern string, handler http.HandlerFunc) {
 	ws.router.HandleFunc(pattern, func(writer http.ResponseWriter, request *http.Request) {
-		if 0 == rand.Intn(7) {
+		failed := ws.errorRate > 0 && rand.Intn(ws.errorRate) == 0
+		ws.requestsLock.Lock()
+		ws.requests[pattern]++
+		if failed {
+			ws.errors++
+		}
+		ws.requestsLock.Unlock()
+
+		if failed {
 			// Randomly return some 502 errors to simulate wolt server errors
-			ws.t.Log("Returning 502 error")
+			ws.logger.Log("Returning 502 error")
 			ws.writeError(writer, http.StatusBadGateway, fmt.Errorf("random error"))
 			return
 		}
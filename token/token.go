@@ -0,0 +1,173 @@
+package token
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Scope is what an API token is allowed to do
+type Scope string
+
+const (
+	ScopeReadOnly   Scope = "read-only"   // Queries only
+	ScopeDebtsWrite Scope = "debts-write" // Queries and settling debts (as the token's user)
+	ScopeAdmin      Scope = "admin"       // Everything, including managing users and tokens
+
+	secretPrefix = "bolt_"
+)
+
+func ParseScope(name string) (Scope, error) {
+	switch scope := Scope(name); scope {
+	case ScopeReadOnly, ScopeDebtsWrite, ScopeAdmin:
+		return scope, nil
+	default:
+		return "", fmt.Errorf("unknown scope %q (expected %s, %s or %s)", name, ScopeReadOnly, ScopeDebtsWrite, ScopeAdmin)
+	}
+}
+
+// Allows returns whether the scope allows what the required scope does
+func (s Scope) Allows(required Scope) bool {
+	switch required {
+	case ScopeReadOnly:
+		return s == ScopeReadOnly || s == ScopeDebtsWrite || s == ScopeAdmin
+	case ScopeDebtsWrite:
+		return s == ScopeDebtsWrite || s == ScopeAdmin
+	default:
+		return s == required
+	}
+}
+
+// Token is an API token. Only the hash of its secret is stored, the secret itself is shown once when the token is issued.
+type Token struct {
+	ID        string     `db:"id"`
+	Name      string     `db:"name"`
+	Hash      string     `db:"hash"`
+	Scope     Scope      `db:"scope"`
+	UserID    string     `db:"user_id"` // Slack user ID the token acts as, optional
+	CreatedAt time.Time  `db:"created_at"`
+	RevokedAt *time.Time `db:"revoked_at"`
+}
+
+func (t *Token) Revoked() bool {
+	return t.RevokedAt != nil
+}
+
+type ErrNotFound struct {
+	ID string
+}
+
+func (e *ErrNotFound) Error() string {
+	return fmt.Sprintf("token %s not found", e.ID)
+}
+
+type Store interface {
+	AddToken(ctx context.Context, token *Token) error
+	// GetToken returns the token with the given ID, or ErrNotFound
+	GetToken(ctx context.Context, id string) (*Token, error)
+	// GetTokenByHash returns the token with the given secret hash, or ErrNotFound
+	GetTokenByHash(ctx context.Context, hash string) (*Token, error)
+	// ListTokens returns all the tokens, including the revoked ones, from the oldest to the newest
+	ListTokens(ctx context.Context) ([]*Token, error)
+	RevokeToken(ctx context.Context, id string, revokedAt time.Time) error
+}
+
+// Hash returns the hash of a token secret, as stored
+func Hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("read random: %w", err)
+	}
+	return secretPrefix + hex.EncodeToString(b), nil
+}
+
+// Issue creates a new token, and returns it with its secret
+func Issue(ctx context.Context, store Store, name string, scope Scope, userID string) (*Token, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", fmt.Errorf("token name is required")
+	}
+	if _, err := ParseScope(string(scope)); err != nil {
+		return nil, "", err
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return nil, "", fmt.Errorf("new secret: %w", err)
+	}
+	t := &Token{
+		ID:        uuid.NewString(),
+		Name:      name,
+		Hash:      Hash(secret),
+		Scope:     scope,
+		UserID:    userID,
+		CreatedAt: time.Now(),
+	}
+	if err := store.AddToken(ctx, t); err != nil {
+		return nil, "", fmt.Errorf("add token: %w", err)
+	}
+	return t, secret, nil
+}
+
+// Rotate issues a new token with the name, scope and user of the given token, and revokes the given token
+func Rotate(ctx context.Context, store Store, id string) (*Token, string, error) {
+	old, err := store.GetToken(ctx, id)
+	if err != nil {
+		return nil, "", fmt.Errorf("get token: %w", err)
+	}
+	if old.Revoked() {
+		return nil, "", fmt.Errorf("token %s is revoked", id)
+	}
+
+	t, secret, err := Issue(ctx, store, old.Name, old.Scope, old.UserID)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := store.RevokeToken(ctx, old.ID, time.Now()); err != nil {
+		return nil, "", fmt.Errorf("revoke rotated token: %w", err)
+	}
+	return t, secret, nil
+}
+
+// Revoke revokes the token, so it can't be used anymore
+func Revoke(ctx context.Context, store Store, id string) (*Token, error) {
+	t, err := store.GetToken(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get token: %w", err)
+	}
+	if t.Revoked() {
+		return t, nil
+	}
+	now := time.Now()
+	if err := store.RevokeToken(ctx, t.ID, now); err != nil {
+		return nil, fmt.Errorf("revoke token: %w", err)
+	}
+	t.RevokedAt = &now
+	return t, nil
+}
+
+// Authenticate returns the token of the given secret, or an error if there's no such token or it's revoked
+func Authenticate(ctx context.Context, store Store, secret string) (*Token, error) {
+	if !strings.HasPrefix(secret, secretPrefix) {
+		return nil, fmt.Errorf("invalid token")
+	}
+	t, err := store.GetTokenByHash(ctx, Hash(secret))
+	if err != nil {
+		return nil, fmt.Errorf("get token: %w", err)
+	}
+	if t.Revoked() {
+		return nil, fmt.Errorf("token %s is revoked", t.ID)
+	}
+	return t, nil
+}
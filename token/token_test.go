@@ -0,0 +1,25 @@
+package token
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScope(t *testing.T) {
+	t.Parallel()
+
+	scope, err := ParseScope("debts-write")
+	require.NoError(t, err)
+	assert.Equal(t, ScopeDebtsWrite, scope)
+	_, err = ParseScope("write")
+	assert.Error(t, err)
+
+	assert.True(t, ScopeReadOnly.Allows(ScopeReadOnly))
+	assert.False(t, ScopeReadOnly.Allows(ScopeDebtsWrite))
+	assert.True(t, ScopeDebtsWrite.Allows(ScopeReadOnly))
+	assert.False(t, ScopeDebtsWrite.Allows(ScopeAdmin))
+	assert.True(t, ScopeAdmin.Allows(ScopeDebtsWrite))
+	assert.True(t, ScopeAdmin.Allows(ScopeAdmin))
+}
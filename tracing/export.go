@@ -0,0 +1,202 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// tracesPath is the path of the OTLP/HTTP receiver of the spans
+	tracesPath = "/v1/traces"
+	// maxQueuedSpans caps the spans waiting to be exported, the spans ended while it's full are dropped
+	maxQueuedSpans = 4096
+	// exportTimeout is the deadline of sending a batch of spans to the collector
+	exportTimeout = 10 * time.Second
+	// statusCodeError is the OTLP status of failed spans
+	statusCodeError = 2
+)
+
+// exporter queues the ended spans and sends them to the collector in batches
+type exporter struct {
+	cfg    Config
+	client *http.Client
+
+	lock  sync.Mutex
+	spans []*Span
+}
+
+var (
+	exporterLock   sync.RWMutex
+	activeExporter *exporter
+)
+
+// record queues the span for exporting, if spans are exported
+func record(span *Span) {
+	exporterLock.RLock()
+	e := activeExporter
+	exporterLock.RUnlock()
+	if e == nil {
+		return
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if len(e.spans) < maxQueuedSpans {
+		e.spans = append(e.spans, span)
+	}
+}
+
+// Run exports the spans to the collector at OTEL_EXPORTER_OTLP_ENDPOINT every OTEL_EXPORT_INTERVAL until ctx is done, and exports
+// the last of them before returning. Without an endpoint it returns right away, and the spans are only used for the IDs in the logs.
+func Run(ctx context.Context, cfg Config) error {
+	if cfg.Endpoint == "" {
+		return nil
+	}
+	if cfg.ExportInterval <= 0 {
+		return fmt.Errorf("OTEL_EXPORT_INTERVAL must be positive but got %s", cfg.ExportInterval)
+	}
+	e := &exporter{cfg: cfg, client: &http.Client{Timeout: exportTimeout}}
+	exporterLock.Lock()
+	activeExporter = e
+	exporterLock.Unlock()
+	defer func() {
+		exporterLock.Lock()
+		activeExporter = nil
+		exporterLock.Unlock()
+	}()
+
+	ticker := time.NewTicker(cfg.ExportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := e.flush(context.Background()); err != nil {
+				log.Println("Error exporting spans:", err)
+			}
+		case <-ctx.Done():
+			if err := e.flush(context.Background()); err != nil {
+				log.Println("Error exporting the last spans:", err)
+			}
+			return nil
+		}
+	}
+}
+
+// flush sends the queued spans to the collector
+func (e *exporter) flush(ctx context.Context) error {
+	e.lock.Lock()
+	spans := e.spans
+	e.spans = nil
+	e.lock.Unlock()
+	if len(spans) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(newExportRequest(e.cfg.ServiceName, spans))
+	if err != nil {
+		return fmt.Errorf("marshal spans: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(e.cfg.Endpoint, "/")+tracesPath, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send %d spans: %w", len(spans), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("send %d spans: got %d response", len(spans), resp.StatusCode)
+	}
+	return nil
+}
+
+// The OTLP/HTTP JSON encoding of spans, see https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              spanKind        `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpExportRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+func otlpAttributes(attributes []Attribute) []otlpAttribute {
+	converted := make([]otlpAttribute, len(attributes))
+	for i, attribute := range attributes {
+		converted[i] = otlpAttribute{Key: attribute.Key, Value: otlpValue{StringValue: attribute.Value}}
+	}
+	return converted
+}
+
+func newExportRequest(serviceName string, spans []*Span) otlpExportRequest {
+	scopeSpans := otlpScopeSpans{Spans: make([]otlpSpan, len(spans))}
+	scopeSpans.Scope.Name = "github.com/oriser/bolt/tracing"
+	for i, span := range spans {
+		span.lock.Lock()
+		converted := otlpSpan{
+			TraceID:           span.TraceID(),
+			SpanID:            span.SpanID(),
+			Name:              span.name,
+			Kind:              span.kind,
+			StartTimeUnixNano: fmt.Sprint(span.start.UnixNano()),
+			EndTimeUnixNano:   fmt.Sprint(span.end.UnixNano()),
+			Attributes:        otlpAttributes(span.attributes),
+		}
+		if span.parentID != [8]byte{} {
+			converted.ParentSpanID = fmt.Sprintf("%x", span.parentID)
+		}
+		if span.err != "" {
+			converted.Status = otlpStatus{Code: statusCodeError, Message: span.err}
+		}
+		span.lock.Unlock()
+		scopeSpans.Spans[i] = converted
+	}
+
+	resourceSpans := otlpResourceSpans{ScopeSpans: []otlpScopeSpans{scopeSpans}}
+	resourceSpans.Resource.Attributes = otlpAttributes([]Attribute{String("service.name", serviceName)})
+	return otlpExportRequest{ResourceSpans: []otlpResourceSpans{resourceSpans}}
+}
@@ -0,0 +1,209 @@
+// Package tracing records spans of Bolt's handling of orders, from the incoming chat event through polling Wolt, the store writes
+// and the outgoing notifications, and exports them to an OpenTelemetry collector in the OTLP/HTTP JSON format
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Config struct {
+	// The base address of the OpenTelemetry collector's OTLP/HTTP receiver (e.g. http://otel-collector:4318), empty disables exporting
+	Endpoint       string        `env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	ServiceName    string        `env:"OTEL_SERVICE_NAME" envDefault:"bolt"`
+	ExportInterval time.Duration `env:"OTEL_EXPORT_INTERVAL" envDefault:"5s"`
+}
+
+type spanKind int
+
+// The kinds of spans, as numbered by OTLP
+const (
+	kindInternal spanKind = 1
+	kindClient   spanKind = 3
+)
+
+// Attribute is a key and value describing a span, like the ID of the order it handles
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Span is a timed operation of a trace. A nil span is valid and records nothing.
+type Span struct {
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	name     string
+	kind     spanKind
+	start    time.Time
+
+	lock       sync.Mutex
+	end        time.Time
+	attributes []Attribute
+	err        string
+	ended      bool
+}
+
+type spanKey struct{}
+
+// Start starts a span which is a child of the span of ctx, or the root of a new trace if ctx has none, and returns a context carrying
+// it. The span has to be ended.
+func Start(ctx context.Context, name string, attributes ...Attribute) (context.Context, *Span) {
+	span := newSpan(ctx, name, kindInternal, attributes)
+	return ContextWithSpan(ctx, span), span
+}
+
+func newSpan(ctx context.Context, name string, kind spanKind, attributes []Attribute) *Span {
+	span := &Span{name: name, kind: kind, start: time.Now(), attributes: attributes}
+	if parent := SpanFromContext(ctx); parent != nil {
+		span.traceID = parent.traceID
+		span.parentID = parent.spanID
+	} else {
+		_, _ = rand.Read(span.traceID[:])
+	}
+	_, _ = rand.Read(span.spanID[:])
+	return span
+}
+
+// SpanFromContext returns the span carried by ctx, or nil if it has none
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// ContextWithSpan returns a context carrying the span, so the spans started with it are its children. It's used for contexts
+// which outlive the request the span is of, like the context of a tracked order.
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	if span == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SetAttributes adds attributes to the span
+func (s *Span) SetAttributes(attributes ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.attributes = append(s.attributes, attributes...)
+}
+
+// SetError marks the span as failed with the error, if it isn't nil
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.err = err.Error()
+}
+
+// End ends the span and queues it for exporting. Ending a span again does nothing.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	if s.ended {
+		s.lock.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.lock.Unlock()
+	record(s)
+}
+
+// TraceID returns the hex encoded ID of the span's trace
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.traceID[:])
+}
+
+// SpanID returns the hex encoded ID of the span
+func (s *Span) SpanID() string {
+	if s == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.spanID[:])
+}
+
+// TraceParent returns the W3C traceparent value of the span of ctx, for continuing its trace in another process, or an empty
+// string if ctx has no span
+func TraceParent(ctx context.Context) string {
+	span := SpanFromContext(ctx)
+	if span == nil {
+		return ""
+	}
+	return fmt.Sprintf("00-%s-%s-01", span.TraceID(), span.SpanID())
+}
+
+// Extract returns a context carrying the remote span of a W3C traceparent value, so the spans started with it continue its
+// trace. ctx is returned as is if the value is empty or malformed.
+func Extract(ctx context.Context, traceParent string) context.Context {
+	parts := strings.Split(traceParent, "-")
+	if len(parts) != 4 || parts[0] != "00" {
+		return ctx
+	}
+	remote := &Span{ended: true}
+	traceID, err := hex.DecodeString(parts[1])
+	if err != nil || len(traceID) != len(remote.traceID) {
+		return ctx
+	}
+	spanID, err := hex.DecodeString(parts[2])
+	if err != nil || len(spanID) != len(remote.spanID) {
+		return ctx
+	}
+	copy(remote.traceID[:], traceID)
+	copy(remote.spanID[:], spanID)
+	return ContextWithSpan(ctx, remote)
+}
+
+// Transport returns a round tripper recording a client span of each request. The trace isn't passed on to the server, as the
+// requests are to third parties which don't take part in it.
+func Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{next: next}
+}
+
+type transport struct {
+	next http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	span := newSpan(req.Context(), fmt.Sprintf("HTTP %s", req.Method), kindClient, []Attribute{
+		String("http.method", req.Method),
+		String("http.url", req.URL.Redacted()),
+	})
+	defer span.End()
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		span.SetError(err)
+		return resp, err
+	}
+	span.SetAttributes(String("http.status_code", fmt.Sprint(resp.StatusCode)))
+	if resp.StatusCode >= http.StatusInternalServerError {
+		span.SetError(fmt.Errorf("got %d response", resp.StatusCode))
+	}
+	return resp, err
+}
@@ -0,0 +1,107 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpans(t *testing.T) {
+	t.Parallel()
+
+	ctx, root := Start(context.Background(), "root")
+	_, child := Start(ctx, "child", String("group_id", "ABC"))
+	assert.Len(t, root.TraceID(), 32)
+	assert.Len(t, root.SpanID(), 16)
+	assert.Equal(t, root.TraceID(), child.TraceID(), "the child continues the trace of its parent")
+	assert.NotEqual(t, root.SpanID(), child.SpanID())
+	assert.Equal(t, root.spanID, child.parentID)
+
+	_, other := Start(context.Background(), "other")
+	assert.NotEqual(t, root.TraceID(), other.TraceID())
+
+	var nilSpan *Span
+	assert.NotPanics(t, func() {
+		nilSpan.SetError(errors.New("failed"))
+		nilSpan.End()
+	}, "a nil span records nothing")
+	assert.Nil(t, SpanFromContext(context.Background()))
+}
+
+func TestTraceParent(t *testing.T) {
+	t.Parallel()
+
+	ctx, span := Start(context.Background(), "listener")
+	traceParent := TraceParent(ctx)
+	assert.Equal(t, "00-"+span.TraceID()+"-"+span.SpanID()+"-01", traceParent)
+
+	_, remoteChild := Start(Extract(context.Background(), traceParent), "monitor")
+	assert.Equal(t, span.TraceID(), remoteChild.TraceID(), "the trace continues in the other process")
+	assert.Equal(t, span.spanID, remoteChild.parentID)
+
+	assert.Empty(t, TraceParent(context.Background()))
+	for _, malformed := range []string{"", "00-abc-def-01", "01-" + span.TraceID() + "-" + span.SpanID() + "-01"} {
+		assert.Nil(t, SpanFromContext(Extract(context.Background(), malformed)), malformed)
+	}
+}
+
+func TestExport(t *testing.T) {
+	var received otlpExportRequest
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, tracesPath, r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+	}))
+	defer collector.Close()
+	wolt := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer wolt.Close()
+
+	e := &exporter{cfg: Config{Endpoint: collector.URL + "/", ServiceName: "bolt"}, client: collector.Client()}
+	exporterLock.Lock()
+	activeExporter = e
+	exporterLock.Unlock()
+	defer func() {
+		exporterLock.Lock()
+		activeExporter = nil
+		exporterLock.Unlock()
+	}()
+
+	ctx, span := Start(context.Background(), "track_order", String("group_id", "ABC"))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wolt.URL+"/order", nil)
+	require.NoError(t, err)
+	resp, err := (&http.Client{Transport: Transport(nil)}).Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	span.SetError(errors.New("timed out"))
+	span.End()
+	span.End()
+
+	require.NoError(t, e.flush(context.Background()))
+	require.Len(t, received.ResourceSpans, 1)
+	assert.Equal(t, []otlpAttribute{{Key: "service.name", Value: otlpValue{StringValue: "bolt"}}}, received.ResourceSpans[0].Resource.Attributes)
+	spans := received.ResourceSpans[0].ScopeSpans[0].Spans
+	require.Len(t, spans, 2, "each span is exported once")
+
+	request, order := spans[0], spans[1]
+	assert.Equal(t, "HTTP GET", request.Name)
+	assert.Equal(t, kindClient, request.Kind)
+	assert.Equal(t, order.SpanID, request.ParentSpanID)
+	assert.Equal(t, order.TraceID, request.TraceID)
+	assert.Contains(t, request.Attributes, otlpAttribute{Key: "http.status_code", Value: otlpValue{StringValue: "503"}})
+	assert.Equal(t, statusCodeError, request.Status.Code)
+
+	assert.Equal(t, "track_order", order.Name)
+	assert.Empty(t, order.ParentSpanID)
+	assert.Equal(t, []otlpAttribute{{Key: "group_id", Value: otlpValue{StringValue: "ABC"}}}, order.Attributes)
+	assert.Equal(t, otlpStatus{Code: statusCodeError, Message: "timed out"}, order.Status)
+
+	require.NoError(t, e.flush(context.Background()), "there's nothing left to export")
+}
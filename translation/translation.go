@@ -0,0 +1,68 @@
+package translation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type Config struct {
+	URL string `env:"TRANSLATION_URL"` // Empty disables translating item names
+}
+
+// Provider translates short texts, like the names of menu items
+type Provider interface {
+	// Translate returns the texts translated to the language (an ISO 639-1 code, like "en" or "he"), in the order of the texts
+	Translate(ctx context.Context, texts []string, language string) ([]string, error)
+}
+
+// Client is a Provider for a simple translation API (POST <TRANSLATION_URL> with {"texts": [...], "target": "<language>"} returning
+// {"translations": [...]}), such as a small adapter over a cloud translation service
+type Client struct {
+	cfg    Config
+	client *http.Client
+}
+
+func NewClient(cfg Config) *Client {
+	return &Client{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (c *Client) Translate(ctx context.Context, texts []string, language string) ([]string, error) {
+	body, err := json.Marshal(struct {
+		Texts  []string `json:"texts"`
+		Target string   `json:"target"`
+	}{Texts: texts, Target: language})
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d for translating to %s", resp.StatusCode, language)
+	}
+
+	var translated struct {
+		Translations []string `json:"translations"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&translated); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	if len(translated.Translations) != len(texts) {
+		return nil, fmt.Errorf("got %d translations for %d texts", len(translated.Translations), len(texts))
+	}
+	return translated.Translations, nil
+}
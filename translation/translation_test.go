@@ -0,0 +1,53 @@
+package translation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientTranslate(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Texts  []string `json:"texts"`
+			Target string   `json:"target"`
+		}
+		if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&req) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch req.Target {
+		case "en":
+			translations := make([]string, len(req.Texts))
+			for i, text := range req.Texts {
+				translations[i] = "en:" + text
+			}
+			_ = json.NewEncoder(w).Encode(map[string][]string{"translations": translations})
+		case "he":
+			_, _ = w.Write([]byte(`{"translations": []}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(Config{URL: server.URL})
+	ctx := context.Background()
+
+	translated, err := client.Translate(ctx, []string{"שניצל", "סלט"}, "en")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"en:שניצל", "en:סלט"}, translated)
+
+	_, err = client.Translate(ctx, []string{"Salad"}, "he")
+	assert.ErrorContains(t, err, "got 0 translations for 1 texts")
+
+	_, err = client.Translate(ctx, []string{"Salad"}, "fr")
+	assert.ErrorContains(t, err, "unexpected status 404")
+}
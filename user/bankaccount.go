@@ -0,0 +1,86 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// BankAccount is the account a user is paid to by bank transfer
+type BankAccount struct {
+	Holder string // The name of the account holder
+	IBAN   string // Normalized, without spaces and in upper case
+}
+
+// ParseBankAccount returns the account of the holder, validating the IBAN's length and check digits. The IBAN may include spaces.
+func ParseBankAccount(holder, iban string) (*BankAccount, error) {
+	holder = strings.TrimSpace(holder)
+	if holder == "" {
+		return nil, fmt.Errorf("missing account holder")
+	}
+	iban = strings.ToUpper(strings.Join(strings.Fields(iban), ""))
+	if len(iban) < 15 || len(iban) > 34 {
+		return nil, fmt.Errorf("IBAN %q should have between 15 and 34 characters", iban)
+	}
+	if !isUpperLetter(iban[0]) || !isUpperLetter(iban[1]) || !isDigit(iban[2]) || !isDigit(iban[3]) {
+		return nil, fmt.Errorf("IBAN %q should start with a country code and two check digits", iban)
+	}
+	// The check digits make the number, with the first four characters moved to the end and letters as 10-35, mod 97 equal 1
+	var digits strings.Builder
+	rearranged := iban[4:] + iban[:4]
+	for i := 0; i < len(rearranged); i++ {
+		switch c := rearranged[i]; {
+		case isDigit(c):
+			digits.WriteByte(c)
+		case isUpperLetter(c):
+			fmt.Fprintf(&digits, "%d", c-'A'+10)
+		default:
+			return nil, fmt.Errorf("invalid character %q in IBAN %q", c, iban)
+		}
+	}
+	number, _ := new(big.Int).SetString(digits.String(), 10)
+	if new(big.Int).Mod(number, big.NewInt(97)).Int64() != 1 {
+		return nil, fmt.Errorf("invalid check digits of IBAN %q", iban)
+	}
+	return &BankAccount{Holder: holder, IBAN: iban}, nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isUpperLetter(c byte) bool {
+	return c >= 'A' && c <= 'Z'
+}
+
+// Formatted returns the full IBAN in groups of four characters, like IL62 0108 0000 0009 9999 999
+func (a *BankAccount) Formatted() string {
+	groups := make([]string, 0, len(a.IBAN)/4+1)
+	for i := 0; i < len(a.IBAN); i += 4 {
+		end := i + 4
+		if end > len(a.IBAN) {
+			end = len(a.IBAN)
+		}
+		groups = append(groups, a.IBAN[i:end])
+	}
+	return strings.Join(groups, " ")
+}
+
+// Masked returns the IBAN with only its country, check digits and last four digits shown, like IL62 **** 9999, for posting it in
+// channels
+func (a *BankAccount) Masked() string {
+	if len(a.IBAN) <= 8 {
+		return strings.Repeat("*", len(a.IBAN))
+	}
+	return a.IBAN[:4] + " **** " + a.IBAN[len(a.IBAN)-4:]
+}
+
+// BankAccountStore keeps the bank account each user (by transport ID) is paid to by bank transfer. It's optional, and implemented by
+// user stores which support it.
+type BankAccountStore interface {
+	// SetBankAccount replaces the bank account of the user, removing it if the account is nil
+	SetBankAccount(ctx context.Context, transportID string, account *BankAccount) error
+	// BankAccount returns the bank account of the user, or nil if the user didn't set one
+	BankAccount(ctx context.Context, transportID string) (*BankAccount, error)
+}
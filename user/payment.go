@@ -1,5 +1,10 @@
 package user
 
+import (
+	"fmt"
+	"strings"
+)
+
 type PaymentMethod int
 
 //goland:noinspection ALL
@@ -8,17 +13,53 @@ const (
 	PaymentMethodBit
 	PaymentMethodPaybox
 	PaymentMethodPepper
+	PaymentMethodRevolut
+	PaymentMethodBankTransfer // To the user's BankAccount
 )
 
 var paymentsString = map[PaymentMethod]string{
-	PaymentMethodBit:    "Bit",
-	PaymentMethodPaybox: "Paybox",
-	PaymentMethodPepper: "Pepper pay",
+	PaymentMethodBit:          "Bit",
+	PaymentMethodPaybox:       "Paybox",
+	PaymentMethodPepper:       "Pepper pay",
+	PaymentMethodRevolut:      "Revolut",
+	PaymentMethodBankTransfer: "Bank transfer",
 }
 
 func (p PaymentMethod) String() string {
 	return paymentsString[p]
 }
 
+// ParsePaymentMethod returns the payment method by its name (case-insensitive), like Bit
+func ParsePaymentMethod(name string) (PaymentMethod, error) {
+	for method, methodName := range paymentsString {
+		if strings.EqualFold(methodName, name) {
+			return method, nil
+		}
+	}
+	return PaymentMethodInvalid, fmt.Errorf("unknown payment method %q", name)
+}
+
+// PreferredPaymentMethods returns the payment methods the user prefers to be paid with: their preferences, or the methods they pay
+// with if they have no preferences
+func (u *User) PreferredPaymentMethods() []PaymentMethod {
+	if len(u.PaymentPreferences) > 0 {
+		return u.PaymentPreferences
+	}
+	return u.PaymentMethods
+}
+
+// CommonPaymentMethod returns the first of the host's preferred payment methods the payer pays with as well, and false if they have
+// no method in common
+func CommonPaymentMethod(host, payer *User) (PaymentMethod, bool) {
+	for _, preferred := range host.PreferredPaymentMethods() {
+		for _, method := range payer.PaymentMethods {
+			if method == preferred {
+				return method, true
+			}
+		}
+	}
+	return PaymentMethodInvalid, false
+}
+
 type Payment struct {
 }
@@ -3,6 +3,8 @@ package user
 import (
 	"context"
 	"fmt"
+	"sort"
+	"time"
 )
 
 type User struct {
@@ -11,8 +13,16 @@ type User struct {
 	Email              string `db:"email"`
 	Phone              string `db:"phone"`
 	PaymentPreferences []PaymentMethod
-	Timezone           string `db:"timezone"`
-	TransportID        string `db:"transport_id"` // For example slack user ID
+	PaymentMethods     []PaymentMethod
+	BankAccount        *BankAccount // Set for hosts who registered one, see BankAccountStore
+	Timezone           string       `db:"timezone"`
+	TransportID        string       `db:"transport_id"`   // For example slack user ID
+	DeactivatedAt      *time.Time   `db:"deactivated_at"` // Set when the user left the company
+}
+
+// Deactivated returns whether the user left the company, so they aren't matched to new orders or reminded about debts
+func (u *User) Deactivated() bool {
+	return u.DeactivatedAt != nil
 }
 
 type ErrNotFound struct {
@@ -29,7 +39,75 @@ type Store interface {
 	ListUsers(ctx context.Context, filter ListFilter) ([]*User, error)
 }
 
+// DeactivationStore is implemented by stores which can deactivate users who left the company, keeping their history intact
+type DeactivationStore interface {
+	// SetUserDeactivatedAt deactivates the user, or reactivates them if deactivatedAt is nil
+	SetUserDeactivatedAt(ctx context.Context, id string, deactivatedAt *time.Time) error
+}
+
+// PaymentMethodsStore keeps the payment methods (User.PaymentMethods) each user (by transport ID) registered to pay with, in the order
+// they prefer them. It's optional, and implemented by user stores which support it.
+type PaymentMethodsStore interface {
+	// SetPaymentMethods replaces the payment methods of the user, removing them if there are none
+	SetPaymentMethods(ctx context.Context, transportID string, methods []PaymentMethod) error
+	// PaymentMethods returns the payment methods of the user, or none if the user didn't register any
+	PaymentMethods(ctx context.Context, transportID string) ([]PaymentMethod, error)
+}
+
+// DebtDMsStore keeps whether each user (by transport ID) chose to get a direct message with their amount of every order they owe
+// for. It's optional, and implemented by user stores which support it.
+type DebtDMsStore interface {
+	// SetDebtDMs sets whether the user gets the direct messages, replacing their previous choice
+	SetDebtDMs(ctx context.Context, transportID string, enabled bool) error
+	// DebtDMs returns whether the user gets the direct messages, and false for chosen if the user didn't choose
+	DebtDMs(ctx context.Context, transportID string) (enabled, chosen bool, err error)
+}
+
+// PrivateAmountsStore keeps the users (by transport ID) who chose to keep their amounts out of the rates messages, and get them by a
+// direct message instead. It's optional, and implemented by user stores which support it.
+type PrivateAmountsStore interface {
+	// SetPrivateAmounts sets whether the user's amounts are private, replacing their previous choice
+	SetPrivateAmounts(ctx context.Context, transportID string, private bool) error
+	// PrivateAmounts returns whether the user's amounts are private, false if the user didn't choose
+	PrivateAmounts(ctx context.Context, transportID string) (bool, error)
+}
+
+// ListFilter filters users by any of the non-empty fields. When paginated, users are returned sorted by their full names.
 type ListFilter struct {
 	Names       []string
 	TransportID string
+	Limit       uint64
+	Offset      uint64
+}
+
+// Paginated returns whether the filter asks for a page of the users rather than all of them
+func (f ListFilter) Paginated() bool {
+	return f.Limit > 0 || f.Offset > 0
+}
+
+// Page returns the page of the users the filter asks for, sorted by their full names, for stores which can't paginate by themselves
+func (f ListFilter) Page(users []*User) []*User {
+	if !f.Paginated() {
+		return users
+	}
+	sorted := make([]*User, len(users))
+	copy(sorted, users)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].FullName < sorted[j].FullName
+	})
+	if f.Offset >= uint64(len(sorted)) {
+		return []*User{}
+	}
+	sorted = sorted[f.Offset:]
+	if f.Limit > 0 && f.Limit < uint64(len(sorted)) {
+		sorted = sorted[:f.Limit]
+	}
+	return sorted
+}
+
+// CountStore counts users without listing them, for paging through them. It's optional, and implemented by user stores which
+// support it.
+type CountStore interface {
+	// CountUsers returns the number of users matching the filter, ignoring its pagination
+	CountUsers(ctx context.Context, filter ListFilter) (int, error)
 }
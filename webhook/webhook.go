@@ -0,0 +1,132 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/oriser/bolt/queue"
+)
+
+// Topic is the topic of the queue the events wait in until they are delivered to the webhooks
+const Topic = "webhooks"
+
+// The headers of the webhook requests
+const (
+	HeaderEvent     = "X-Bolt-Event"     // The type of the event
+	HeaderDelivery  = "X-Bolt-Delivery"  // The ID of the delivery, which is the same in its retries
+	HeaderSignature = "X-Bolt-Signature" // sha256=<hex HMAC-SHA256 of the body with WEBHOOK_SECRET>, if it's set
+)
+
+type Config struct {
+	URLs    []string      `env:"WEBHOOK_URLS" envSeparator:"," json:"-"` // The URLs of the webhooks often have their tokens
+	Events  []string      `env:"WEBHOOK_EVENTS" envSeparator:","`        // The types of the events to deliver, all of them if empty
+	Secret  string        `env:"WEBHOOK_SECRET" json:"-"`
+	Timeout time.Duration `env:"WEBHOOK_TIMEOUT" envDefault:"10s"`
+}
+
+// Enabled returns whether there are webhooks to deliver the events to
+func (c Config) Enabled() bool {
+	return len(c.URLs) > 0
+}
+
+// delivery is a queued event for a single webhook, so a webhook which fails doesn't get the event again when another one is retried
+type delivery struct {
+	URL   string          `json:"url"`
+	Type  string          `json:"type"`
+	Event json.RawMessage `json:"event"`
+}
+
+// The Outbox delivers the events to the webhooks through the queue, which keeps them until they're delivered and retries the failed
+// deliveries up to QUEUE_MAX_ATTEMPTS times. With the store queue the events are kept in the store, so they survive restarts.
+type Outbox struct {
+	cfg    Config
+	queue  queue.Queue
+	client *http.Client
+	events map[string]bool
+}
+
+func New(cfg Config, q queue.Queue) *Outbox {
+	events := make(map[string]bool, len(cfg.Events))
+	for _, eventType := range cfg.Events {
+		events[eventType] = true
+	}
+	return &Outbox{
+		cfg:    cfg,
+		queue:  q,
+		client: &http.Client{Timeout: cfg.Timeout},
+		events: events,
+	}
+}
+
+// Add queues the event of the type for every webhook, unless WEBHOOK_EVENTS doesn't include the type
+func (o *Outbox) Add(ctx context.Context, eventType string, event []byte) error {
+	if len(o.events) > 0 && !o.events[eventType] {
+		return nil
+	}
+	for _, url := range o.cfg.URLs {
+		payload, err := json.Marshal(delivery{URL: url, Type: eventType, Event: event})
+		if err != nil {
+			return fmt.Errorf("marshal delivery: %w", err)
+		}
+		if err := o.queue.Publish(ctx, Topic, payload); err != nil {
+			return fmt.Errorf("queue delivery: %w", err)
+		}
+	}
+	return nil
+}
+
+// Run delivers the queued events until the context is done
+func (o *Outbox) Run(ctx context.Context) {
+	if err := o.queue.Consume(ctx, Topic, 1, o.deliver); err != nil && !errors.Is(err, context.Canceled) {
+		log.Println("Error delivering the webhooks:", err)
+	}
+}
+
+func (o *Outbox) deliver(ctx context.Context, msg *queue.Message) error {
+	d := delivery{}
+	if err := json.Unmarshal(msg.Payload, &d); err != nil {
+		// It will never be delivered
+		log.Printf("Error unmarshaling webhook delivery %s, dropping it: %v\n", msg.ID, err)
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Event))
+	if err != nil {
+		log.Printf("Error preparing webhook delivery %s, dropping it: %v\n", msg.ID, err)
+		return nil
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, d.Type)
+	req.Header.Set(HeaderDelivery, msg.ID)
+	if o.cfg.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign(o.cfg.Secret, d.Event))
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send %s webhook: %w", d.Type, err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s webhook responded with status %d", d.Type, resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign returns the signature of the body, which receivers compare to the HeaderSignature header to know the event is from Bolt
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,64 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/oriser/bolt/queue"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeQueue struct {
+	messages []*queue.Message
+}
+
+func (f *fakeQueue) Publish(_ context.Context, topic string, payload []byte) error {
+	f.messages = append(f.messages, &queue.Message{ID: fmt.Sprint(len(f.messages) + 1), Topic: topic, Payload: payload})
+	return nil
+}
+
+func (f *fakeQueue) Consume(context.Context, string, int, queue.Handler) error {
+	return nil
+}
+
+func TestOutbox(t *testing.T) {
+	t.Parallel()
+
+	type received struct {
+		event, delivery, signature, body string
+	}
+	var requests []received
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, received{r.Header.Get(HeaderEvent), r.Header.Get(HeaderDelivery), r.Header.Get(HeaderSignature), string(body)})
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	q := &fakeQueue{}
+	outbox := New(Config{URLs: []string{server.URL + "/a", server.URL + "/b"}, Events: []string{"debt_created"}, Secret: "secret"}, q)
+	ctx := context.Background()
+	require.NoError(t, outbox.Add(ctx, "order_joined", []byte(`{"type":"order_joined"}`)))
+	assert.Empty(t, q.messages, "only the events of WEBHOOK_EVENTS are delivered")
+	require.NoError(t, outbox.Add(ctx, "debt_created", []byte(`{"type":"debt_created"}`)))
+	require.Len(t, q.messages, 2, "the event is queued for every webhook")
+	assert.Equal(t, Topic, q.messages[0].Topic)
+
+	require.NoError(t, outbox.deliver(ctx, q.messages[0]))
+	require.Len(t, requests, 1)
+	assert.Equal(t, received{
+		event:     "debt_created",
+		delivery:  q.messages[0].ID,
+		signature: Sign("secret", []byte(`{"type":"debt_created"}`)),
+		body:      `{"type":"debt_created"}`,
+	}, requests[0])
+
+	status = http.StatusBadGateway
+	assert.Error(t, outbox.deliver(ctx, q.messages[1]), "the failed deliveries are retried by the queue")
+}
@@ -15,8 +15,30 @@ type DeliveryInfo struct {
 }
 
 type Item struct {
-	BasePrice float64 `json:"baseprice"`
-	EndAmount float64 `json:"end_amount"`
+	Name              string  `json:"name"`
+	BasePrice         float64 `json:"baseprice"`
+	EndAmount         float64 `json:"end_amount"`
+	AgeRestricted     bool    `json:"age_restricted"`
+	AlcoholPercentage float64 `json:"alcohol_percentage"`
+	Count             int     `json:"count"`
+}
+
+// Quantity returns how many of the item were ordered
+func (i Item) Quantity() int {
+	if i.Count <= 0 {
+		return 1
+	}
+	return i.Count
+}
+
+// IsAgeRestricted returns whether Wolt marks the item as age-restricted (e.g. alcohol or tobacco)
+func (i Item) IsAgeRestricted() bool {
+	return i.AgeRestricted || i.IsAlcohol()
+}
+
+// IsAlcohol returns whether Wolt lists an alcohol percentage for the item
+func (i Item) IsAlcohol() bool {
+	return i.AlcoholPercentage > 0
 }
 
 type Participant struct {
@@ -36,6 +58,31 @@ func (p *Participant) Name() string {
 	return p.FirstName
 }
 
+// ParticipantStatusReady is the status of a participant who marked themselves as ready
+const ParticipantStatusReady = "ready"
+
+// Delivery is a part of a purchase Wolt split into several deliveries, which large group orders sometimes ship in
+type Delivery struct {
+	DeliveryStatus  DeliveryStatus `json:"delivery_status"`
+	DeliveryEtaUnix struct {
+		DateUnix int64 `json:"$date"`
+	} `json:"delivery_eta"`
+	Items []Item `json:"items"` // The items in the delivery's bag, empty if Wolt doesn't tell
+
+	DeliveryEta time.Time `json:"-"`
+}
+
+// IsDelivered returns whether the delivery arrived
+func (d Delivery) IsDelivered() bool {
+	return d.DeliveryStatus == DeliveryStatusDelivered
+}
+
+// PurchaseDiscount is a discount of the whole purchase, like a promo code or a campaign
+type PurchaseDiscount struct {
+	Name   string  `json:"name"`
+	Amount float64 `json:"amount"` // In cents
+}
+
 type Status string
 type DeliveryStatus string
 type DeliveryStatusToTimeMap map[DeliveryStatus]time.Time
@@ -76,6 +123,7 @@ func (s Status) Purchased() bool {
 
 type OrderDetails struct {
 	Status        Status `json:"status"`
+	Currency      string `json:"currency"` // The ISO 4217 code of the currency of the order, like ILS
 	CreatedAtUnix struct {
 		DateUnix int64 `json:"$date"`
 	} `json:"created_at"`
@@ -91,9 +139,16 @@ type OrderDetails struct {
 		} `json:"delivery_eta"`
 		DeliveryStatus       DeliveryStatus          `json:"delivery_status"`
 		DeliveryStatusLog    DeliveryStatusToTimeMap `json:"delivery_status_log"`
+		Deliveries           []Delivery              `json:"deliveries"` // Set when the purchase was split into several deliveries
 		PurchaseDatetimeUnix struct {
 			DateUnix int64 `json:"$date"`
 		} `json:"purchase_datetime"`
+		DeliveryPrice *float64           `json:"delivery_price"` // What the host paid for the delivery in cents, nil if Wolt doesn't tell
+		WoltPlus      bool               `json:"wolt_plus"`      // The host's Wolt+ subscription made the delivery free
+		Discounts     []PurchaseDiscount `json:"discounts"`
+		Credits       float64            `json:"credits"`     // The Wolt credits the host paid with, in cents
+		ServiceFee    float64            `json:"service_fee"` // Wolt's service fee of the purchase, in cents
+		Tip           float64            `json:"tip"`         // The tip the host gave the courier, in cents
 	} `json:"purchase"`
 
 	CreatedAt                time.Time  `json:"-"`
@@ -135,11 +190,28 @@ func ParseOrderDetails(orderDetailsJSON []byte) (*OrderDetails, error) {
 		return nil, fmt.Errorf("get host: %w", err)
 	}
 
+	o.parseTimes()
+	return o, nil
+}
+
+// ParseTrackingDetails parses the details of a regular (not group) order from its tracking page, which has its status and
+// delivery progress but no participants
+func ParseTrackingDetails(trackingJSON []byte) (*OrderDetails, error) {
+	o := &OrderDetails{}
+	if err := json.Unmarshal(trackingJSON, o); err != nil {
+		return nil, fmt.Errorf("unmarshal tracking details: %w", err)
+	}
+	o.parseTimes()
+	return o, nil
+}
+
+func (o *OrderDetails) parseTimes() {
 	o.CreatedAt = time.UnixMilli(o.CreatedAtUnix.DateUnix)
 	o.DeliveryEta = time.UnixMilli(o.Purchase.DeliveryEtaUnix.DateUnix)
 	o.PurchaseDatetime = time.UnixMilli(o.Purchase.PurchaseDatetimeUnix.DateUnix)
-
-	return o, nil
+	for i := range o.Purchase.Deliveries {
+		o.Purchase.Deliveries[i].DeliveryEta = time.UnixMilli(o.Purchase.Deliveries[i].DeliveryEtaUnix.DateUnix)
+	}
 }
 
 func (o *OrderDetails) host() (string, error) {
@@ -169,6 +241,113 @@ func (o *OrderDetails) RateByPerson() (map[string]float64, error) {
 	return output, nil
 }
 
+// DiscountsAmount returns the amount the purchase was discounted by: its promo codes and campaigns, and the Wolt credits the host paid
+// with. The items' amounts don't include it.
+func (o *OrderDetails) DiscountsAmount() float64 {
+	total := o.Purchase.Credits
+	for _, discount := range o.Purchase.Discounts {
+		total += discount.Amount
+	}
+	return total / 100
+}
+
+// ServiceFeeAmount returns Wolt's service fee of the purchase, 0 if it had none
+func (o *OrderDetails) ServiceFeeAmount() float64 {
+	return o.Purchase.ServiceFee / 100
+}
+
+// TipAmount returns the tip the host gave the courier, 0 if they gave none
+func (o *OrderDetails) TipAmount() float64 {
+	return o.Purchase.Tip / 100
+}
+
+// PaidDeliveryPrice returns what the host paid for the delivery, which is free with Wolt+, and false if Wolt doesn't tell (like
+// before the purchase)
+func (o *OrderDetails) PaidDeliveryPrice() (float64, bool) {
+	if o.Purchase.WoltPlus {
+		return 0, true
+	}
+	if o.Purchase.DeliveryPrice == nil {
+		return 0, false
+	}
+	return *o.Purchase.DeliveryPrice / 100, true
+}
+
+// AgeRestrictedByPerson returns the amount of age-restricted items of each participant who ordered any
+func (o *OrderDetails) AgeRestrictedByPerson() map[string]float64 {
+	return o.ItemsAmountByPerson(Item.IsAgeRestricted)
+}
+
+// ItemsAmountByPerson returns the amount of the items matching the given function of each participant who ordered any
+func (o *OrderDetails) ItemsAmountByPerson(match func(Item) bool) map[string]float64 {
+	output := make(map[string]float64)
+	for _, participant := range o.Participants {
+		total := 0.0
+		for _, item := range participant.Basket.Items {
+			if match(item) {
+				total += item.EndAmount / 100
+			}
+		}
+		if total == 0 {
+			continue
+		}
+
+		output[participant.Name()] = total
+	}
+
+	return output
+}
+
+// ItemsByPerson returns the items of each participant who ordered any
+func (o *OrderDetails) ItemsByPerson() map[string][]Item {
+	output := make(map[string][]Item)
+	for _, participant := range o.Participants {
+		if len(participant.Basket.Items) == 0 {
+			continue
+		}
+		output[participant.Name()] = append(output[participant.Name()], participant.Basket.Items...)
+	}
+	return output
+}
+
+// ItemCounts returns the quantity ordered of each item (by name) in all the participants' baskets
+func (o *OrderDetails) ItemCounts() map[string]int {
+	output := make(map[string]int)
+	for _, participant := range o.Participants {
+		for _, item := range participant.Basket.Items {
+			output[item.Name] += item.Quantity()
+		}
+	}
+	return output
+}
+
+// NotReadyParticipants returns the names of the participants with items in their basket who didn't mark themselves as ready yet,
+// except for the host, who sends the order
+func (o *OrderDetails) NotReadyParticipants() []string {
+	names := make([]string, 0)
+	for _, participant := range o.Participants {
+		if participant.UserID == o.HostID || participant.Status == ParticipantStatusReady || len(participant.Basket.Items) == 0 {
+			continue
+		}
+		names = append(names, participant.Name())
+	}
+	return names
+}
+
+// IsSplitDelivery returns whether the purchase ships in several deliveries
+func (o *OrderDetails) IsSplitDelivery() bool {
+	return len(o.Purchase.Deliveries) > 1
+}
+
+// IsDelivered returns whether the order arrived. An order split into several deliveries arrived when all of them did.
 func (o *OrderDetails) IsDelivered() bool {
+	if o.IsSplitDelivery() {
+		for _, delivery := range o.Purchase.Deliveries {
+			if !delivery.IsDelivered() {
+				return false
+			}
+		}
+		return true
+	}
 	return o.Purchase.DeliveryStatus == DeliveryStatusDelivered
 }
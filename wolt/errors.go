@@ -0,0 +1,96 @@
+package wolt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// The categories of the errors of requests to Wolt, matched with errors.Is
+var (
+	ErrAuthExpired    = errors.New("wolt rejected the session")
+	ErrGroupFull      = errors.New("wolt group order is full")
+	ErrGroupClosed    = errors.New("wolt group order is closed")
+	ErrRegionMismatch = errors.New("wolt group order is in another region")
+	ErrNetwork        = errors.New("wolt is unreachable")
+)
+
+// maxErrorBodySize is how much of the body of an error response is read for Wolt's error code
+const maxErrorBodySize = 64 * 1024
+
+// APIError is a response of Wolt's API other than 200. It matches the category of the error (like ErrGroupFull) by its status and
+// the error code in its body.
+type APIError struct {
+	Call       string
+	StatusCode int
+	Code       string // Wolt's error code from the body of the response, empty if it had none
+}
+
+// newAPIError returns the error of the response, reading Wolt's error code from its body
+func newAPIError(call string, resp *http.Response) *APIError {
+	apiErr := &APIError{Call: call, StatusCode: resp.StatusCode}
+	var body map[string]interface{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxErrorBodySize)).Decode(&body); err == nil {
+		for _, field := range []string{"error_code", "code", "error"} {
+			if value, ok := body[field]; ok && value != nil {
+				apiErr.Code = fmt.Sprint(value)
+				break
+			}
+		}
+	}
+	return apiErr
+}
+
+func (e *APIError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("got non 200 response: %d (%s)", e.StatusCode, e.Code)
+	}
+	return fmt.Sprintf("got non 200 response: %d", e.StatusCode)
+}
+
+func (e *APIError) Is(target error) bool {
+	category := e.category()
+	return category != nil && target == category
+}
+
+// category returns the category of the error, nil if it has none
+func (e *APIError) category() error {
+	code := strings.ToLower(e.Code)
+	containsAny := func(words ...string) bool {
+		for _, word := range words {
+			if strings.Contains(code, word) {
+				return true
+			}
+		}
+		return false
+	}
+	switch {
+	case containsAny("country", "region", "city", "location"):
+		return ErrRegionMismatch
+	case containsAny("full", "max_participants", "participant_limit"):
+		return ErrGroupFull
+	case containsAny("closed", "sent", "expired", "purchase", "locked", "cancel"):
+		return ErrGroupClosed
+	}
+	switch {
+	case e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden:
+		return ErrAuthExpired
+	case e.StatusCode == http.StatusNotFound || e.StatusCode == http.StatusGone:
+		return ErrGroupClosed
+	case e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError:
+		return ErrNetwork
+	}
+	return nil
+}
+
+// networkError is a request which didn't get a response from Wolt, including the requests the circuit breaker didn't send
+type networkError struct {
+	err error
+}
+
+func (e *networkError) Error() string        { return e.err.Error() }
+func (e *networkError) Unwrap() error        { return e.err }
+func (e *networkError) Is(target error) bool { return target == ErrNetwork }
@@ -0,0 +1,48 @@
+package wolt
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIErrorCategory(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		status   int
+		body     string
+		expected error
+	}{
+		{http.StatusUnauthorized, ``, ErrAuthExpired},
+		{http.StatusForbidden, `{"error_code": "GUESTS_NOT_ALLOWED"}`, ErrAuthExpired},
+		{http.StatusForbidden, `{"error_code": "COUNTRY_MISMATCH"}`, ErrRegionMismatch},
+		{http.StatusConflict, `{"error_code": "GROUP_ORDER_FULL", "msg": "Too many participants"}`, ErrGroupFull},
+		{http.StatusConflict, `{"code": "group_order_already_sent"}`, ErrGroupClosed},
+		{http.StatusNotFound, `not json`, ErrGroupClosed},
+		{http.StatusServiceUnavailable, ``, ErrNetwork},
+		{http.StatusBadRequest, `{"error_code": 42}`, nil},
+	} {
+		err := newAPIError("join", &http.Response{StatusCode: tt.status, Body: io.NopCloser(strings.NewReader(tt.body))})
+		wrapped := fmt.Errorf("join request http res: %w", err)
+		for _, category := range []error{ErrAuthExpired, ErrGroupFull, ErrGroupClosed, ErrRegionMismatch, ErrNetwork} {
+			assert.Equal(t, category == tt.expected, errors.Is(wrapped, category), "%d %s is %v", tt.status, tt.body, category)
+		}
+	}
+
+	err := newAPIError("join", &http.Response{StatusCode: http.StatusBadRequest, Body: io.NopCloser(strings.NewReader(`{"error_code": 42}`))})
+	assert.Equal(t, "got non 200 response: 400 (42)", err.Error())
+
+	var apiErr *APIError
+	assert.True(t, errors.As(fmt.Errorf("getting real group ID: %w", err), &apiErr))
+	assert.Equal(t, "42", apiErr.Code)
+
+	netErr := fmt.Errorf("sending https req: %w", &networkError{err: ErrCircuitOpen})
+	assert.True(t, errors.Is(netErr, ErrNetwork))
+	assert.True(t, errors.Is(netErr, ErrCircuitOpen))
+}
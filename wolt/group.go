@@ -2,6 +2,7 @@ package wolt
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -13,6 +14,8 @@ import (
 
 	"github.com/Jeffail/gabs/v2"
 	retryablehttp "github.com/hashicorp/go-retryablehttp"
+	"github.com/oriser/bolt/cache"
+	"github.com/oriser/bolt/tracing"
 	"github.com/prometheus/common/log"
 	"golang.org/x/net/html"
 )
@@ -29,6 +32,10 @@ type RetryConfig struct {
 	HTTPMaxRetries       int
 	HTTPMinRetryDuration time.Duration
 	HTTPMaxRetryDuration time.Duration
+	HTTPTimeout          time.Duration // The deadline of each attempt, 0 disables
+	Guard                *Guard        // Rate limits the requests and stops them while Wolt keeps failing, nil disables
+	VenueCache           cache.Cache   // Caches the venues for VenueCacheTTL, nil disables
+	VenueCacheTTL        time.Duration
 }
 
 func (w *WoltAddr) parse() error {
@@ -47,12 +54,14 @@ func (w *WoltAddr) parse() error {
 }
 
 type Group struct {
-	woltAddrs WoltAddr
-	prettyID  string
-	id        string
-	auth      string
-	client    *http.Client
-	headers   map[string]string
+	woltAddrs   WoltAddr
+	retryConfig RetryConfig
+	prettyID    string
+	id          string
+	auth        string
+	client      *http.Client
+	jar         http.CookieJar
+	headers     map[string]string
 }
 
 func newGroup(woltAddrs WoltAddr, retryConfig RetryConfig, id string) (*Group, error) {
@@ -61,31 +70,47 @@ func newGroup(woltAddrs WoltAddr, retryConfig RetryConfig, id string) (*Group, e
 		return nil, fmt.Errorf("cookiejar: %w", err)
 	}
 
-	client := retryablehttp.NewClient()
+	client := newRetryClient(retryConfig)
 	client.HTTPClient.Jar = jar
+
+	if err = woltAddrs.parse(); err != nil {
+		return nil, fmt.Errorf("parse wolt addrs: %w", err)
+	}
+
+	return &Group{
+		woltAddrs:   woltAddrs,
+		retryConfig: retryConfig,
+		prettyID:    id,
+		client:      client.StandardClient(),
+		jar:         jar,
+		headers:     defaultHeaders(woltAddrs),
+	}, nil
+}
+
+func newRetryClient(retryConfig RetryConfig) *retryablehttp.Client {
+	client := retryablehttp.NewClient()
 	client.RetryWaitMax = retryConfig.HTTPMaxRetryDuration
 	client.RetryWaitMin = retryConfig.HTTPMinRetryDuration
 	client.RetryMax = retryConfig.HTTPMaxRetries
+	client.HTTPClient.Timeout = retryConfig.HTTPTimeout
+	client.HTTPClient.Transport = tracing.Transport(&guardTransport{guard: retryConfig.Guard, next: client.HTTPClient.Transport})
+	client.CheckRetry = checkRetry
+	client.Backoff = jitteredBackoff
 	client.Logger = nil
 	client.RequestLogHook = func(logger retryablehttp.Logger, request *http.Request, i int) {
 		if i != 0 {
 			log.Errorf("Retrying request for %s (attempt %d)", request.URL.String(), i)
 		}
 	}
+	return client
+}
 
-	if err = woltAddrs.parse(); err != nil {
-		return nil, fmt.Errorf("parse wolt addrs: %w", err)
+func defaultHeaders(woltAddrs WoltAddr) map[string]string {
+	return map[string]string{
+		"User-Agent":   "Mozilla/5.0 (Macintosh; Intel Mac OS X 10.16; rv:84.0) Gecko/20100101 Firefox/84.0",
+		"Origin":       woltAddrs.BaseAddr,
+		"Content-Type": "application/json;charset=utf-8",
 	}
-
-	return &Group{
-		woltAddrs: woltAddrs,
-		prettyID:  id,
-		client:    client.StandardClient(),
-		headers: map[string]string{
-			"User-Agent":   "Mozilla/5.0 (Macintosh; Intel Mac OS X 10.16; rv:84.0) Gecko/20100101 Firefox/84.0",
-			"Origin":       woltAddrs.BaseAddr,
-			"Content-Type": "application/json;charset=utf-8",
-		}}, nil
 }
 
 func NewGroupWithExistingID(woltAddrs WoltAddr, retryConfig RetryConfig, id string) (*Group, error) {
@@ -134,13 +159,13 @@ func (g *Group) joinApiAddr(p string) string {
 
 // This function is used to get the real group ID (instead of the short one) from the Wolt API.
 // It sends a GET request to the Wolt API to get the group details JSON and extracts the ID from it.
-func (g *Group) assignIDFromExternalScript() error {
-	req, err := g.prepareReq("GET", g.joinApiAddr(fmt.Sprintf("/v1/group_order/guest/code/%s", g.prettyID)), nil, nil)
+func (g *Group) assignIDFromExternalScript(ctx context.Context) error {
+	req, err := g.prepareReq(ctx, "GET", g.joinApiAddr(fmt.Sprintf("/v1/group_order/guest/code/%s", g.prettyID)), nil, nil)
 	if err != nil {
 		return fmt.Errorf("new request: %w", err)
 	}
 
-	resp, err := g.sendReq(req)
+	resp, err := g.sendReq("bootstrap", req)
 	if err != nil {
 		return fmt.Errorf("getting http response: %w", err)
 	}
@@ -194,8 +219,8 @@ func (g *Group) assignIDFromHTML(data io.Reader) error {
 	return nil
 }
 
-func (g *Group) prepareReq(method, url string, body io.Reader, extraHeaders map[string]string) (*http.Request, error) {
-	req, err := http.NewRequest(method, url, body)
+func (g *Group) prepareReq(ctx context.Context, method, url string, body io.Reader, extraHeaders map[string]string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return nil, err
 	}
@@ -211,20 +236,24 @@ func (g *Group) prepareReq(method, url string, body io.Reader, extraHeaders map[
 	return req, nil
 }
 
-func (g *Group) sendReq(req *http.Request) (*http.Response, error) {
+// sendReq sends the request, recording its duration and result in the metrics of the call
+func (g *Group) sendReq(call string, req *http.Request) (*http.Response, error) {
+	start := time.Now()
 	resp, err := g.client.Do(req)
+	observeRequest(call, start, resp, err)
 	if err != nil {
-		return nil, fmt.Errorf("sending https req: %w", err)
+		return nil, fmt.Errorf("sending https req: %w", &networkError{err: err})
 	}
 
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("got non 200 response: %d", resp.StatusCode)
+		defer resp.Body.Close()
+		return nil, newAPIError(call, resp)
 	}
 
 	return resp, nil
 }
 
-func (g *Group) joinByRealID() error {
+func (g *Group) joinByRealID(ctx context.Context) error {
 	body := bytes.NewBuffer([]byte(`{"first_name":"Wolt Bot"}`))
 
 	reqURL := g.joinApiAddr(fmt.Sprintf("/v1/group_order/guest/join/%s", g.id))
@@ -233,6 +262,7 @@ func (g *Group) joinByRealID() error {
 	}
 
 	req, err := g.prepareReq(
+		ctx,
 		"POST",
 		reqURL,
 		body,
@@ -241,7 +271,7 @@ func (g *Group) joinByRealID() error {
 		return fmt.Errorf("new request: %w", err)
 	}
 
-	_, err = g.sendReq(req)
+	_, err = g.sendReq("join", req)
 	if err != nil {
 		return fmt.Errorf("join request http res: %w", err)
 	}
@@ -249,27 +279,27 @@ func (g *Group) joinByRealID() error {
 	return nil
 }
 
-func (g *Group) Join() error {
-	if err := g.assignIDFromExternalScript(); err != nil {
+func (g *Group) Join(ctx context.Context) error {
+	if err := g.assignIDFromExternalScript(ctx); err != nil {
 		return fmt.Errorf("getting real group ID: %w", err)
 	}
 
-	return g.joinByRealID()
+	return g.joinByRealID(ctx)
 }
 
-func (g *Group) Details() (*OrderDetails, error) {
+func (g *Group) Details(ctx context.Context) (*OrderDetails, error) {
 	reqURL := g.joinApiAddr(fmt.Sprintf("/v1/group_order/guest/%s/participants/me", g.id))
 	if g.auth != "" {
 		reqURL = g.joinApiAddr(fmt.Sprintf("/v1/group_order/%s/participants/me", g.id))
 	}
 
 	b := bytes.NewBuffer([]byte(`{"subscribed":false}`))
-	req, err := g.prepareReq("PATCH", reqURL, b, nil)
+	req, err := g.prepareReq(ctx, "PATCH", reqURL, b, nil)
 	if err != nil {
 		return nil, fmt.Errorf("new request: %w", err)
 	}
 
-	resp, err := g.sendReq(req)
+	resp, err := g.sendReq("details", req)
 	if err != nil {
 		return nil, fmt.Errorf("details http res: %w", err)
 	}
@@ -282,20 +312,26 @@ func (g *Group) Details() (*OrderDetails, error) {
 	return ParseOrderDetails(output)
 }
 
-func (g *Group) VenueDetails(details *OrderDetails) (*Venue, error) {
-	req, err := g.prepareReq("GET", g.joinApiAddr(fmt.Sprintf("/v3/venues/%s", details.Details.VenueID)), nil, nil)
-	if err != nil {
-		return nil, fmt.Errorf("prepare venue request: %w", err)
-	}
+func (g *Group) VenueDetails(ctx context.Context, details *OrderDetails) (*Venue, error) {
+	output, err := cachedVenue(ctx, g.retryConfig, "venues:id:"+details.Details.VenueID, func() ([]byte, error) {
+		req, err := g.prepareReq(ctx, "GET", g.joinApiAddr(fmt.Sprintf("/v3/venues/%s", details.Details.VenueID)), nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("prepare venue request: %w", err)
+		}
 
-	resp, err := g.sendReq(req)
-	if err != nil {
-		return nil, fmt.Errorf("send venue details request: %w", err)
-	}
+		resp, err := g.sendReq("venue", req)
+		if err != nil {
+			return nil, fmt.Errorf("send venue details request: %w", err)
+		}
 
-	output, err := io.ReadAll(resp.Body)
+		output, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("reading output: %w", err)
+		}
+		return output, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("reading output: %w", err)
+		return nil, err
 	}
 
 	v, err := ParseVenue(output)
@@ -305,19 +341,19 @@ func (g *Group) VenueDetails(details *OrderDetails) (*Venue, error) {
 	return v, nil
 }
 
-func (g *Group) MarkAsReady() error {
+func (g *Group) MarkAsReady(ctx context.Context) error {
 	reqURL := g.joinApiAddr(fmt.Sprintf("/v1/group_order/guest/%s/participants/me", g.id))
 	if g.auth != "" {
 		reqURL = g.joinApiAddr(fmt.Sprintf("/v1/group_order/%s/participants/me", g.id))
 	}
 
 	b := bytes.NewBuffer([]byte(`{"status":"ready"}`))
-	req, err := g.prepareReq("PATCH", reqURL, b, nil)
+	req, err := g.prepareReq(ctx, "PATCH", reqURL, b, nil)
 	if err != nil {
 		return fmt.Errorf("new request: %w", err)
 	}
 
-	_, err = g.sendReq(req)
+	_, err = g.sendReq("mark_ready", req)
 	if err != nil {
 		return fmt.Errorf("mark as ready http res: %w", err)
 	}
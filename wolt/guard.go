@@ -0,0 +1,204 @@
+package wolt
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	retryablehttp "github.com/hashicorp/go-retryablehttp"
+	"github.com/prometheus/common/log"
+)
+
+const (
+	// maxPollBackoffShift caps how many times the polling interval is doubled while Wolt's API is degraded
+	maxPollBackoffShift = 3
+)
+
+// ErrCircuitOpen is returned without sending the request while Wolt's API keeps failing, until the breaker's cooldown passes
+var ErrCircuitOpen = errors.New("wolt API circuit breaker is open")
+
+type GuardConfig struct {
+	RateLimit       float64       // Requests per second sent to Wolt, 0 disables
+	BreakerFailures int           // Consecutive failed requests which open the circuit breaker, 0 disables
+	BreakerCooldown time.Duration // How long the open breaker fails requests before letting a trial request through
+}
+
+// Guard rate limits the requests to Wolt and stops sending them while Wolt's API keeps failing. A single guard is shared by the
+// clients of all the orders, as they all hit the same API. A nil guard lets every request through.
+type Guard struct {
+	cfg      GuardConfig
+	interval time.Duration // between requests, 0 if they aren't rate limited
+
+	lock     sync.Mutex
+	next     time.Time // the earliest time the next request can be sent
+	failures int       // consecutive failed requests
+	openedAt time.Time // when the breaker opened, zero while it's closed
+	probing  bool      // whether the trial request of the half-open breaker is in flight
+}
+
+func NewGuard(cfg GuardConfig) *Guard {
+	g := &Guard{cfg: cfg}
+	if cfg.RateLimit > 0 {
+		g.interval = time.Duration(float64(time.Second) / cfg.RateLimit)
+	}
+	return g
+}
+
+// wait blocks until the rate limit lets the request be sent. Delayed requests get a jitter, so the polls of concurrent orders
+// don't keep hitting Wolt together.
+func (g *Guard) wait(req *http.Request) error {
+	if g == nil || g.interval == 0 {
+		return nil
+	}
+
+	g.lock.Lock()
+	now := time.Now()
+	if g.next.Before(now) {
+		g.next = now
+	}
+	delay := g.next.Sub(now)
+	g.next = g.next.Add(g.interval)
+	g.lock.Unlock()
+	if delay == 0 {
+		return nil
+	}
+
+	delay += time.Duration(rand.Int63n(int64(g.interval/2) + 1))
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
+
+// allow returns ErrCircuitOpen while the breaker is open. Once the cooldown passes, a single trial request is let through, which
+// closes the breaker if it succeeds.
+func (g *Guard) allow() error {
+	if g == nil || g.cfg.BreakerFailures == 0 {
+		return nil
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if g.openedAt.IsZero() {
+		return nil
+	}
+	if g.probing || time.Since(g.openedAt) < g.cfg.BreakerCooldown {
+		return ErrCircuitOpen
+	}
+	g.probing = true
+	return nil
+}
+
+// record counts the result of a request sent to Wolt, opening the breaker after too many consecutive failures
+func (g *Guard) record(failed bool) {
+	if g == nil {
+		return
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.probing = false
+	if !failed {
+		if !g.openedAt.IsZero() {
+			log.Infof("Wolt API recovered, closing the circuit breaker")
+		}
+		g.failures = 0
+		g.openedAt = time.Time{}
+		return
+	}
+
+	g.failures++
+	if g.cfg.BreakerFailures == 0 || g.failures < g.cfg.BreakerFailures {
+		return
+	}
+	if g.openedAt.IsZero() {
+		log.Errorf("Wolt API failed %d times in a row, opening the circuit breaker", g.failures)
+		breakerOpened.Inc()
+	}
+	g.openedAt = time.Now()
+}
+
+// release lets another trial request through, when the trial request was canceled before Wolt answered
+func (g *Guard) release() {
+	if g == nil {
+		return
+	}
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.probing = false
+}
+
+// Degraded returns whether the last request sent to Wolt failed
+func (g *Guard) Degraded() bool {
+	if g == nil {
+		return false
+	}
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.failures > 0
+}
+
+// PollInterval returns the interval between polls of Wolt. While Wolt's API is degraded it's doubled for each consecutive
+// failure, up to 8 times the base interval.
+func (g *Guard) PollInterval(base time.Duration) time.Duration {
+	if g == nil {
+		return base
+	}
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	shift := g.failures
+	if shift > maxPollBackoffShift {
+		shift = maxPollBackoffShift
+	}
+	return base << shift
+}
+
+// guardTransport sends the requests through the guard
+type guardTransport struct {
+	guard *Guard
+	next  http.RoundTripper
+}
+
+func (t *guardTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.guard.wait(req); err != nil {
+		return nil, err
+	}
+	if err := t.guard.allow(); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil && req.Context().Err() != nil {
+		// The request was canceled, which says nothing about Wolt
+		t.guard.release()
+		return resp, err
+	}
+	t.guard.record(err != nil || resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests)
+	return resp, err
+}
+
+// checkRetry doesn't retry requests which the open breaker failed, as they'd fail again until its cooldown passes
+func checkRetry(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if errors.Is(err, ErrCircuitOpen) {
+		return false, err
+	}
+	return retryablehttp.DefaultRetryPolicy(ctx, resp, err)
+}
+
+// jitteredBackoff backs off exponentially between the retries, picking a random wait in the upper half of each step so
+// requests which failed together aren't retried together. A Retry-After header of Wolt is respected as is.
+func jitteredBackoff(min, max time.Duration, attemptNum int, resp *http.Response) time.Duration {
+	if resp != nil && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) &&
+		resp.Header.Get("Retry-After") != "" {
+		return retryablehttp.DefaultBackoff(min, max, attemptNum, resp)
+	}
+	backoff := retryablehttp.DefaultBackoff(min, max, attemptNum, nil)
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
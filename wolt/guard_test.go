@@ -0,0 +1,95 @@
+package wolt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGuardCircuitBreaker(t *testing.T) {
+	t.Parallel()
+
+	var status, requests int32 = http.StatusInternalServerError, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+	}))
+	defer server.Close()
+
+	guard := NewGuard(GuardConfig{BreakerFailures: 3, BreakerCooldown: 50 * time.Millisecond})
+	client := newRetryClient(RetryConfig{HTTPMaxRetries: 5, HTTPMinRetryDuration: time.Millisecond, HTTPMaxRetryDuration: time.Millisecond, Guard: guard})
+	get := func() (*http.Response, error) {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		resp, err := client.StandardClient().Do(req)
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return resp, err
+	}
+
+	assert.Equal(t, time.Second, guard.PollInterval(time.Second))
+	_, err := get()
+	assert.True(t, errors.Is(err, ErrCircuitOpen), "the breaker opens after 3 failures, stopping the retries")
+	assert.EqualValues(t, 3, atomic.LoadInt32(&requests))
+	assert.True(t, guard.Degraded())
+	assert.Equal(t, 8*time.Second, guard.PollInterval(time.Second), "polling backs off while Wolt is degraded")
+
+	_, err = get()
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+	assert.EqualValues(t, 3, atomic.LoadInt32(&requests), "the open breaker doesn't send requests")
+
+	// Once the cooldown passes a trial request is sent, and its success closes the breaker
+	time.Sleep(60 * time.Millisecond)
+	atomic.StoreInt32(&status, http.StatusOK)
+	resp, err := get()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.False(t, guard.Degraded())
+	assert.Equal(t, time.Second, guard.PollInterval(time.Second))
+}
+
+func TestGuardRateLimit(t *testing.T) {
+	t.Parallel()
+
+	guard := NewGuard(GuardConfig{RateLimit: 50})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		require.NoError(t, guard.wait(req))
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 80*time.Millisecond, "requests are spaced by the rate limit")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, guard.wait(req.WithContext(ctx)), context.Canceled)
+
+	var nilGuard *Guard
+	assert.NoError(t, nilGuard.wait(req))
+	assert.NoError(t, nilGuard.allow())
+	assert.Equal(t, time.Second, nilGuard.PollInterval(time.Second))
+}
+
+func TestJitteredBackoff(t *testing.T) {
+	t.Parallel()
+
+	for attempt := 0; attempt < 5; attempt++ {
+		backoff := jitteredBackoff(time.Second, 10*time.Second, attempt, nil)
+		step := time.Second << attempt
+		if step > 10*time.Second {
+			step = 10 * time.Second
+		}
+		assert.GreaterOrEqual(t, backoff, step/2)
+		assert.LessOrEqual(t, backoff, step)
+	}
+
+	resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": []string{"7"}}}
+	assert.Equal(t, 7*time.Second, jitteredBackoff(time.Second, 10*time.Second, 0, resp))
+}
@@ -0,0 +1,105 @@
+package wolt
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// maxLinkRedirects is the maximal number of redirects followed when resolving a short link
+const maxLinkRedirects = 10
+
+// groupPathRe matches the path of a group order link, after an optional locale and city (e.g. /he/isr/tel-aviv), with or without /join
+var groupPathRe = regexp.MustCompile(`(?i)/group(?:-order)?/([A-Z0-9]+)(?:/join)?/?$`)
+
+// shortLinkHosts are the hosts of the links the mobile app shares, which redirect to the group order link
+var shortLinkHosts = []string{"wolt.app.link", "wolt.onelink.me"}
+
+// GroupIDFromLink returns the ID of the group order of a Wolt link, and whether it's a group order link. It supports the web links
+// of any locale (e.g. https://wolt.com/he/isr/tel-aviv/group/ABC123/join) and the app's deep links (e.g. wolt://group/ABC123), but
+// not short links, see ResolveShortLink.
+func GroupIDFromLink(link string) (string, bool) {
+	parsed, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return "", false
+	}
+	path := parsed.Path
+	switch {
+	case strings.EqualFold(parsed.Scheme, "wolt"):
+		// The host of a deep link is its first path part, as in wolt://group/ABC123
+		path = "/" + parsed.Host + parsed.Path
+	case isWoltHost(parsed.Hostname()):
+	default:
+		return "", false
+	}
+	match := groupPathRe.FindStringSubmatch(path)
+	if match == nil {
+		return "", false
+	}
+	return strings.ToUpper(match[1]), true
+}
+
+// isWoltHost returns whether the host is wolt.com or one of its subdomains (e.g. www.wolt.com)
+func isWoltHost(host string) bool {
+	host = strings.ToLower(host)
+	return host == "wolt.com" || strings.HasSuffix(host, ".wolt.com")
+}
+
+// IsWoltLink returns whether the link is of Wolt, as a web link, a deep link of the app or a short link
+func IsWoltLink(link string) bool {
+	parsed, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(parsed.Scheme, "wolt") || isWoltHost(parsed.Hostname()) || IsShortLink(link)
+}
+
+// IsShortLink returns whether the link is a short link shared by the mobile app, which has to be resolved for its group ID
+func IsShortLink(link string) bool {
+	parsed, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return false
+	}
+	for _, host := range shortLinkHosts {
+		if strings.EqualFold(parsed.Hostname(), host) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveShortLink follows the redirects of a short link until it gets to a group order link, and returns the ID of its group order.
+// The redirects to deep links aren't followed, as they only open the app.
+func ResolveShortLink(ctx context.Context, client *http.Client, link string) (string, error) {
+	groupID := ""
+	redirects := 0
+	resolver := *client
+	resolver.CheckRedirect = func(req *http.Request, _ []*http.Request) error {
+		if id, ok := GroupIDFromLink(req.URL.String()); ok {
+			groupID = id
+			return http.ErrUseLastResponse
+		}
+		if redirects++; redirects > maxLinkRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxLinkRedirects)
+		}
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return "", fmt.Errorf("new request: %w", err)
+	}
+	resp, err := resolver.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("follow short link: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if groupID == "" {
+		return "", fmt.Errorf("the short link %s doesn't lead to a group order", link)
+	}
+	return groupID, nil
+}
@@ -0,0 +1,79 @@
+package wolt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupIDFromLink(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		link    string
+		groupID string
+	}{
+		{"https://wolt.com/group/ABC123", "ABC123"},
+		{"https://wolt.com/en/group/ABC123/", "ABC123"},
+		{"https://wolt.com/he/isr/tel-aviv/group/ABC123/join", "ABC123"},
+		{"https://www.wolt.com/en/group-order/abc123/join?utm_source=share", "ABC123"},
+		{"wolt://group/ABC123", "ABC123"},
+		{"wolt://group-order/ABC123/join", "ABC123"},
+		{"https://wolt.com/en/isr/tel-aviv/restaurant/pizza-place", ""},
+		{"https://example.com/group/ABC123", ""},
+		{"https://notwolt.com/group/ABC123", ""},
+		{"https://wolt.app.link/aBcD3", ""},
+	} {
+		groupID, ok := GroupIDFromLink(tc.link)
+		assert.Equal(t, tc.groupID, groupID, tc.link)
+		assert.Equal(t, tc.groupID != "", ok, tc.link)
+	}
+
+	assert.True(t, IsShortLink("https://wolt.app.link/aBcD3"))
+	assert.False(t, IsShortLink("https://wolt.com/group/ABC123"))
+}
+
+func TestResolveShortLink(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/web", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/intermediate", http.StatusFound)
+	})
+	mux.HandleFunc("/intermediate", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://wolt.com/he/isr/tel-aviv/group/ABC123/join", http.StatusFound)
+	})
+	mux.HandleFunc("/app", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "wolt://group/DEF456", http.StatusFound)
+	})
+	mux.HandleFunc("/venue", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	groupID, err := ResolveShortLink(context.Background(), server.Client(), server.URL+"/web")
+	require.NoError(t, err)
+	assert.Equal(t, "ABC123", groupID, "the group order link isn't requested, so it's resolved without reaching Wolt")
+
+	groupID, err = ResolveShortLink(context.Background(), server.Client(), server.URL+"/app")
+	require.NoError(t, err)
+	assert.Equal(t, "DEF456", groupID)
+
+	_, err = ResolveShortLink(context.Background(), server.Client(), server.URL+"/venue")
+	assert.ErrorContains(t, err, "doesn't lead to a group order")
+}
+
+func TestIsWoltLink(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, IsWoltLink("https://wolt.com/he/isr/tel-aviv/group/ABC123"))
+	assert.True(t, IsWoltLink("https://www.wolt.com/en/s/track/aB3x9"))
+	assert.True(t, IsWoltLink("wolt://group/ABC123"))
+	assert.True(t, IsWoltLink("https://wolt.app.link/abc"))
+	assert.False(t, IsWoltLink("https://example.com/group/ABC123"))
+}
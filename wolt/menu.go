@@ -0,0 +1,59 @@
+package wolt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// MenuOptionValue is a choice of a menu item's option, like the large size
+type MenuOptionValue struct {
+	Name  string `json:"name"`
+	Price int    `json:"price"` // Added to the item's price, in cents
+}
+
+// MenuOption is a choice offered with a menu item, like its size or toppings
+type MenuOption struct {
+	Name   string            `json:"name"`
+	Values []MenuOptionValue `json:"values"`
+}
+
+// MenuItem is an item on a venue's menu
+type MenuItem struct {
+	Name      string       `json:"name"`
+	BasePrice int          `json:"baseprice"` // In cents
+	Enabled   bool         `json:"enabled"`
+	Options   []MenuOption `json:"options"`
+}
+
+// Price returns the price of the item without options
+func (i MenuItem) Price() float64 {
+	return float64(i.BasePrice) / 100
+}
+
+// Menu is the current menu of a venue
+type Menu struct {
+	Items []MenuItem `json:"items"`
+}
+
+// ParseMenu parses the menu JSON of a venue
+func ParseMenu(menuJSON []byte) (*Menu, error) {
+	menu := &Menu{}
+	if err := json.Unmarshal(menuJSON, menu); err != nil {
+		return nil, fmt.Errorf("parse menu JSON: %w", err)
+	}
+	return menu, nil
+}
+
+// MenuBySlug fetches the current menu of the venue with the given slug
+func MenuBySlug(ctx context.Context, woltAddrs WoltAddr, retryConfig RetryConfig, slug string) (*Menu, error) {
+	output, err := getBySlug(ctx, woltAddrs, retryConfig, "menu_by_slug", "/v4/venues/slug", slug, "menu")
+	if err != nil {
+		return nil, err
+	}
+	menu, err := ParseMenu(output)
+	if err != nil {
+		return nil, fmt.Errorf("parse menu: %w", err)
+	}
+	return menu, nil
+}
@@ -0,0 +1,27 @@
+package wolt
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/oriser/bolt/metrics"
+)
+
+var (
+	requestsTotal = metrics.NewCounter("bolt_wolt_requests_total",
+		"Requests sent to Wolt, by call and result (ok or error)", "call", "result")
+	requestDuration = metrics.NewHistogram("bolt_wolt_request_duration_seconds",
+		"Durations of the requests sent to Wolt, including the retries, by call", metrics.DurationBuckets, "call")
+	breakerOpened = metrics.NewCounter("bolt_wolt_circuit_breaker_opened_total",
+		"Times the circuit breaker of the requests to Wolt opened, after Wolt's API kept failing")
+)
+
+// observeRequest records the duration and result of a request to Wolt. Any response other than 200 counts as an error.
+func observeRequest(call string, start time.Time, resp *http.Response, err error) {
+	requestDuration.ObserveSince(start, call)
+	result := "ok"
+	if err != nil || resp.StatusCode != http.StatusOK {
+		result = "error"
+	}
+	requestsTotal.Inc(call, result)
+}
@@ -0,0 +1,32 @@
+package wolt
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// GroupSession is the state of a joined group: its real ID and the cookies of the guest session Bolt joined it with. It's kept
+// for restoring the group after a restart, as the group can't be joined again once it was sent.
+type GroupSession struct {
+	ID      string         `json:"id"`
+	Cookies []*http.Cookie `json:"cookies"`
+}
+
+// Session returns the session of the joined group
+func (g *Group) Session() GroupSession {
+	return GroupSession{ID: g.id, Cookies: g.jar.Cookies(g.woltAddrs.apiAddrParsed)}
+}
+
+// RestoreGroup returns the group of a session, without joining it again
+func RestoreGroup(woltAddrs WoltAddr, retryConfig RetryConfig, prettyID string, session GroupSession) (*Group, error) {
+	if session.ID == "" {
+		return nil, fmt.Errorf("session without group ID")
+	}
+	g, err := newGroup(woltAddrs, retryConfig, prettyID)
+	if err != nil {
+		return nil, err
+	}
+	g.id = session.ID
+	g.jar.SetCookies(g.woltAddrs.apiAddrParsed, session.Cookies)
+	return g, nil
+}
@@ -0,0 +1,80 @@
+package wolt
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// trackingPathRe matches the path of the tracking link of a regular (not group) order, after an optional locale (e.g. /en/s/track/abc)
+var trackingPathRe = regexp.MustCompile(`(?i)/(?:s/)?track(?:ing)?/([A-Z0-9_-]+)/?$`)
+
+// TrackingIDFromLink returns the tracking ID of the regular (not group) order of a Wolt tracking link, and whether it's a tracking
+// link. Tracking IDs are case-sensitive, unlike group IDs.
+func TrackingIDFromLink(link string) (string, bool) {
+	parsed, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || !isWoltHost(parsed.Hostname()) {
+		return "", false
+	}
+	match := trackingPathRe.FindStringSubmatch(parsed.Path)
+	if match == nil {
+		return "", false
+	}
+	return match[1], true
+}
+
+// Tracking follows the delivery of a regular (not group) order by its tracking ID, which anyone with the tracking link can view
+type Tracking struct {
+	woltAddrs WoltAddr
+	id        string
+	client    *http.Client
+	headers   map[string]string
+}
+
+func NewTracking(woltAddrs WoltAddr, retryConfig RetryConfig, id string) (*Tracking, error) {
+	if err := woltAddrs.parse(); err != nil {
+		return nil, fmt.Errorf("parse wolt addrs: %w", err)
+	}
+	return &Tracking{
+		woltAddrs: woltAddrs,
+		id:        id,
+		client:    newRetryClient(retryConfig).StandardClient(),
+		headers:   defaultHeaders(woltAddrs),
+	}, nil
+}
+
+// Details returns the status and delivery progress of the order. It has no participants, as the order isn't a group order.
+func (t *Tracking) Details(ctx context.Context) (*OrderDetails, error) {
+	u := *t.woltAddrs.apiAddrParsed
+	u.Path = path.Join(u.Path, fmt.Sprintf("/v1/order_tracking/%s", t.id))
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+	for key, val := range t.headers {
+		req.Header.Set(key, val)
+	}
+
+	start := time.Now()
+	resp, err := t.client.Do(req)
+	observeRequest("tracking", start, resp, err)
+	if err != nil {
+		return nil, fmt.Errorf("sending https req: %w", &networkError{err: err})
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("tracking", resp)
+	}
+
+	output, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading output: %w", err)
+	}
+	return ParseTrackingDetails(output)
+}
@@ -0,0 +1,76 @@
+package wolt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/oriser/bolt/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrackingIDFromLink(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		link       string
+		trackingID string
+	}{
+		{"https://wolt.com/en/s/track/aB3-x_9", "aB3-x_9"},
+		{"https://wolt.com/he/track/aB3x9/", "aB3x9"},
+		{"https://track.wolt.com/tracking/aB3x9", "aB3x9"},
+		{"https://wolt.com/en/group/ABC123", ""},
+		{"https://example.com/track/aB3x9", ""},
+	} {
+		trackingID, ok := TrackingIDFromLink(tc.link)
+		assert.Equal(t, tc.trackingID, trackingID, tc.link)
+		assert.Equal(t, tc.trackingID != "", ok, tc.link)
+	}
+}
+
+func TestTrackingDetails(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/order_tracking/aB3x9" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"status": "purchased", "purchase": {"delivery_status": "production", "delivery_eta": {"$date": 1700000000000}}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	tracking, err := NewTracking(WoltAddr{BaseAddr: server.URL, APIBaseAddr: server.URL}, RetryConfig{}, "aB3x9")
+	require.NoError(t, err)
+	details, err := tracking.Details(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, DeliveryStatus("production"), details.Purchase.DeliveryStatus)
+	assert.Equal(t, int64(1700000000000), details.DeliveryEta.UnixMilli())
+
+	tracking, err = NewTracking(WoltAddr{BaseAddr: server.URL, APIBaseAddr: server.URL}, RetryConfig{}, "missing")
+	require.NoError(t, err)
+	_, err = tracking.Details(context.Background())
+	assert.Error(t, err)
+}
+
+func TestVenueBySlugCache(t *testing.T) {
+	t.Parallel()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(`{"results": [{"name": [{"lang": "en", "value": "Pizza"}], "location": {"coordinates": [34.78, 32.08]}, "timezone": "Asia/Jerusalem"}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	retryConfig := RetryConfig{VenueCache: cache.NewMemory(), VenueCacheTTL: time.Hour}
+	for i := 0; i < 2; i++ {
+		venue, err := VenueBySlug(context.Background(), WoltAddr{BaseAddr: server.URL, APIBaseAddr: server.URL}, retryConfig, "pizza")
+		require.NoError(t, err)
+		assert.Equal(t, "Pizza", venue.Name)
+	}
+	assert.Equal(t, 1, requests, "the venue is cached")
+}
@@ -1,9 +1,14 @@
 package wolt
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"math"
+	"net/http"
+	"net/url"
+	"path"
 	"time"
 )
 
@@ -17,14 +22,20 @@ type VenueName struct {
 	Value string `json:"value"`
 }
 
+// Discount is a promotion the venue currently offers, like free delivery or a percentage off some items
+type Discount struct {
+	Description []VenueName `json:"description"`
+}
+
 type Venue struct {
 	Alive    uint8 `json:"alive"`
 	Location struct {
 		Coordinates []float64 `json:"coordinates"`
 	} `json:"location"`
 	DeliverySpecs struct {
-		DeliveryEnabled bool        `json:"delivery_enabled"`
-		DeliveryPricing PriceRanges `json:"delivery_pricing"`
+		DeliveryEnabled         bool        `json:"delivery_enabled"`
+		DeliveryPricing         PriceRanges `json:"delivery_pricing"`
+		OrderMinimumNoSurcharge int         `json:"order_minimum_no_surcharge"`
 	} `json:"delivery_specs"`
 	Names         []VenueName `json:"name"`
 	Link          string      `json:"public_url"`
@@ -40,6 +51,14 @@ type Venue struct {
 	} `json:"preorder_times"`
 	City     string `json:"city"`
 	Timezone string `json:"timezone"`
+	Rush     struct {
+		Status bool `json:"status"`
+	} `json:"rush"`
+	CompletionEstimates struct {
+		Delivery string `json:"delivery"` // Range of minutes, like 20-40
+	} `json:"completion_estimates"`
+	Discounts []Discount `json:"discounts"`
+	Currency  string     `json:"currency"` // The ISO 4217 code of the currency of the venue's prices, like ILS
 
 	Name             string
 	ParsedCoordinate Coordinate     `json:"-"`
@@ -154,10 +173,121 @@ func (v *Venue) CalculateDeliveryRate(source Coordinate) (int, error) {
 	return price / 100, nil
 }
 
+// MinimumOrder returns the minimum order amount without a small order surcharge, or 0 if there's none
+func (v *Venue) MinimumOrder() float64 {
+	return float64(v.DeliverySpecs.OrderMinimumNoSurcharge) / 100
+}
+
 func (v *Venue) IsDelivering() bool {
 	return v.DeliverySpecs.DeliveryEnabled && v.Online && v.Alive != 0
 }
 
+// IsBusy returns whether Wolt marked the venue as in rush, which comes with surge delivery pricing and longer deliveries
+func (v *Venue) IsBusy() bool {
+	return v.Rush.Status
+}
+
 func (v *Venue) IsOpenForPreorderDelivery() bool {
 	return v.PreorderEnabled && v.PreorderTimes.Delivery != nil
 }
+
+// DeliveryEstimate returns the estimated delivery time range in minutes (like 20-40), or an empty string if it's unknown
+func (v *Venue) DeliveryEstimate() string {
+	return v.CompletionEstimates.Delivery
+}
+
+// Promotions returns the descriptions of the venue's current discounts, in English if available
+func (v *Venue) Promotions() []string {
+	promotions := make([]string, 0, len(v.Discounts))
+	for _, discount := range v.Discounts {
+		description := ""
+		for _, text := range discount.Description {
+			if text.Lang == "en" || description == "" {
+				description = text.Value
+			}
+		}
+		if description != "" {
+			promotions = append(promotions, description)
+		}
+	}
+	return promotions
+}
+
+// ErrVenueNotFound is returned for slugs of venues which don't exist
+type ErrVenueNotFound struct {
+	Slug string
+}
+
+func (e *ErrVenueNotFound) Error() string {
+	return fmt.Sprintf("venue %q not found", e.Slug)
+}
+
+// VenueBySlug fetches the venue with the given slug (the last part of the venue's Wolt link), without a group order
+func VenueBySlug(ctx context.Context, woltAddrs WoltAddr, retryConfig RetryConfig, slug string) (*Venue, error) {
+	output, err := cachedVenue(ctx, retryConfig, "venues:slug:"+slug, func() ([]byte, error) {
+		return getBySlug(ctx, woltAddrs, retryConfig, "venue_by_slug", "/v3/venues/slug", slug, "")
+	})
+	if err != nil {
+		return nil, err
+	}
+	v, err := ParseVenue(output)
+	if err != nil {
+		return nil, fmt.Errorf("parse venue: %w", err)
+	}
+	return v, nil
+}
+
+// cachedVenue returns the venue output cached under the key, or fetches and caches it when it isn't. Errors of the cache are ignored,
+// fetching the venue from Wolt.
+func cachedVenue(ctx context.Context, retryConfig RetryConfig, key string, fetch func() ([]byte, error)) ([]byte, error) {
+	if retryConfig.VenueCache == nil || retryConfig.VenueCacheTTL <= 0 {
+		return fetch()
+	}
+	if output, ok, err := retryConfig.VenueCache.Get(ctx, key); err == nil && ok {
+		return output, nil
+	}
+	output, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := ParseVenue(output); err == nil {
+		_ = retryConfig.VenueCache.Set(ctx, key, output, retryConfig.VenueCacheTTL)
+	}
+	return output, nil
+}
+
+// getBySlug sends a GET request for the venue with the given slug to the API path, returning ErrVenueNotFound if Wolt doesn't know it
+func getBySlug(ctx context.Context, woltAddrs WoltAddr, retryConfig RetryConfig, call, apiPath, slug, suffix string) ([]byte, error) {
+	if err := woltAddrs.parse(); err != nil {
+		return nil, fmt.Errorf("parse wolt addrs: %w", err)
+	}
+	u := *woltAddrs.apiAddrParsed
+	u.Path = path.Join(u.Path, apiPath, url.PathEscape(slug), suffix)
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("prepare %s request: %w", call, err)
+	}
+	for key, val := range defaultHeaders(woltAddrs) {
+		req.Header.Set(key, val)
+	}
+
+	start := time.Now()
+	resp, err := newRetryClient(retryConfig).StandardClient().Do(req)
+	observeRequest(call, start, resp, err)
+	if err != nil {
+		return nil, fmt.Errorf("send %s request: %w", call, &networkError{err: err})
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, &ErrVenueNotFound{Slug: slug}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(call, resp)
+	}
+
+	output, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading output: %w", err)
+	}
+	return output, nil
+}